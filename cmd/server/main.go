@@ -11,8 +11,12 @@ import (
 	"time"
 
 	"elterngeld-portal/config"
+	"elterngeld-portal/internal/anonymize"
+	"elterngeld-portal/internal/backup"
 	"elterngeld-portal/internal/database"
+	"elterngeld-portal/internal/postalcodes"
 	"elterngeld-portal/internal/server"
+	"elterngeld-portal/pkg/encryption"
 	"elterngeld-portal/pkg/logger"
 
 	"go.uber.org/zap"
@@ -39,10 +43,24 @@ import (
 // @name Authorization
 // @description Type "Bearer" followed by a space and JWT token.
 
+// @securityDefinitions.apikey ApiKeyAuth
+// @in header
+// @name X-API-Key
+// @description Personal API key for integrations such as Zapier and Make.
+
 var (
-	initDB  = flag.Bool("init-db", false, "Initialize database with migrations and exit")
-	migrate = flag.Bool("migrate", false, "Run database migrations and exit")
-	seed    = flag.Bool("seed", false, "Seed database with sample data and exit")
+	initDB     = flag.Bool("init-db", false, "Initialize database with migrations and exit")
+	migrate    = flag.Bool("migrate", false, "Run database migrations and exit")
+	seed       = flag.Bool("seed", false, "Seed database with sample data and exit")
+	seedLoad   = flag.Bool("seed-loadtest", false, "Seed the dataset of the load test and exit")
+	rotateKeys = flag.Bool("rotate-keys", false, "Re-encrypt personal data with the primary encryption key and exit")
+
+	importPostalCodes = flag.String("import-postal-codes", "", "Replace the postal code dataset with a CSV file and exit")
+
+	createBackup  = flag.Bool("backup", false, "Back up the database and document storage and exit")
+	listBackups   = flag.Bool("list-backups", false, "List the backup sets and exit")
+	restoreBackup = flag.String("restore", "", "Restore a backup set into the staging database and directory and exit")
+	anonymizeData = flag.Bool("anonymize", false, "Replace the personal data of a staging copy of the database and exit")
 )
 
 func main() {
@@ -63,6 +81,19 @@ func main() {
 	}
 	defer logger.Close()
 
+	// Load encryption keys for personal data
+	keyring, err := encryption.LoadKeyring(cfg.Encryption.Keys, cfg.Encryption.PrimaryKey)
+	if err != nil {
+		logger.Fatal("Invalid encryption keys", zap.Error(err))
+	}
+	if keyring == nil {
+		if cfg.IsProduction() {
+			logger.Fatal("ENCRYPTION_KEYS must be set in production")
+		}
+		logger.Warn("ENCRYPTION_KEYS not set, personal data is stored unencrypted")
+	}
+	encryption.SetDefault(keyring)
+
 	// Connect to database
 	if err := database.Connect(cfg, logger.Logger); err != nil {
 		logger.Fatal("Failed to connect to database", zap.Error(err))
@@ -84,6 +115,31 @@ func main() {
 		return
 	}
 
+	if *seedLoad {
+		handleSeedLoadTest(cfg)
+		return
+	}
+
+	if *rotateKeys {
+		handleRotateKeys(keyring)
+		return
+	}
+
+	if *importPostalCodes != "" {
+		handleImportPostalCodes(*importPostalCodes)
+		return
+	}
+
+	if *createBackup || *listBackups || *restoreBackup != "" {
+		handleBackup(cfg)
+		return
+	}
+
+	if *anonymizeData {
+		handleAnonymize(cfg)
+		return
+	}
+
 	// Normal server startup
 	startServer(cfg)
 }
@@ -132,6 +188,121 @@ func handleSeed(cfg *config.Config) {
 	fmt.Println("  User:    user@example.com / user123")
 }
 
+func handleSeedLoadTest(cfg *config.Config) {
+	// The load test accounts share a published password
+	if cfg.IsProduction() {
+		logger.Fatal("Load test data must not be seeded in production")
+	}
+
+	logger.Info("Seeding load test data...")
+
+	if err := database.SeedLoadTest(); err != nil {
+		logger.Fatal("Seeding load test data failed", zap.Error(err))
+	}
+
+	logger.Info("Load test data seeded successfully")
+	fmt.Println("Load test users:")
+	fmt.Printf("  Customer: %s / %s\n", database.LoadTestCustomerEmail, database.LoadTestPassword)
+	fmt.Printf("  Berater:  %s / %s\n", database.LoadTestBeraterEmail, database.LoadTestPassword)
+}
+
+func handleRotateKeys(keyring *encryption.Keyring) {
+	if keyring == nil {
+		logger.Fatal("ENCRYPTION_KEYS must be set to rotate keys")
+	}
+
+	logger.Info("Rotating encryption keys...", zap.String("primary_key", keyring.PrimaryKeyID()))
+
+	stats, err := database.RotateEncryptionKeys(keyring)
+	if err != nil {
+		logger.Fatal("Key rotation failed", zap.Error(err))
+	}
+
+	logger.Info("Key rotation completed successfully",
+		zap.Int("encrypted", stats.Encrypted),
+		zap.Int("rewrapped", stats.Rewrapped),
+		zap.Int("unchanged", stats.Unchanged),
+	)
+}
+
+func handleImportPostalCodes(path string) {
+	logger.Info("Importing postal codes...", zap.String("file", path))
+
+	file, err := os.Open(path)
+	if err != nil {
+		logger.Fatal("Failed to open postal code dataset", zap.Error(err))
+	}
+	defer file.Close()
+
+	service := postalcodes.NewService(database.DB, logger.Logger)
+	areas, err := service.Import(file)
+	if err != nil {
+		logger.Fatal("Postal code import failed", zap.Error(err))
+	}
+
+	// Customers who entered a postal code before the import get their Bundesland
+	updated, err := service.BackfillBundesland()
+	if err != nil {
+		logger.Fatal("Bundesland backfill failed", zap.Error(err))
+	}
+
+	logger.Info("Postal code import completed successfully",
+		zap.Int("areas", areas),
+		zap.Int64("users_updated", updated),
+	)
+}
+
+func handleBackup(cfg *config.Config) {
+	service := backup.NewService(database.DB, logger.Logger, cfg, nil)
+
+	switch {
+	case *createBackup:
+		logger.Info("Creating backup...", zap.String("path", cfg.Backup.Path))
+		manifest, err := service.Create(context.Background())
+		if err != nil {
+			logger.Fatal("Backup failed", zap.Error(err))
+		}
+		logger.Info("Backup created successfully", zap.String("backup_id", manifest.ID), zap.Int("files", manifest.Files))
+
+	case *listBackups:
+		backups, err := service.List()
+		if err != nil {
+			logger.Fatal("Failed to list backups", zap.Error(err))
+		}
+		for _, b := range backups {
+			fmt.Printf("%s  %s  database %d bytes  %d files (%d bytes)\n",
+				b.ID, b.CreatedAt.Format(time.RFC3339), b.Database.Size, b.Files, b.FileBytes)
+		}
+
+	default:
+		logger.Info("Restoring backup...", zap.String("backup_id", *restoreBackup))
+		result, err := service.Restore(context.Background(), *restoreBackup)
+		if err != nil {
+			logger.Fatal("Restore failed", zap.Error(err))
+		}
+		logger.Info("Backup restored successfully",
+			zap.String("database", result.Database),
+			zap.String("storage_path", result.StoragePath),
+			zap.Int("files", result.Files),
+		)
+	}
+}
+
+func handleAnonymize(cfg *config.Config) {
+	logger.Info("Anonymizing personal data...", zap.String("env", cfg.Server.Env))
+
+	result, err := anonymize.NewService(database.DB, logger.Logger, cfg).Run(context.Background())
+	if err != nil {
+		logger.Fatal("Anonymization failed", zap.Error(err))
+	}
+
+	logger.Info("Anonymization completed successfully",
+		zap.Int("tables", result.Tables),
+		zap.Int64("rows", result.Rows),
+		zap.Int64("values", result.Values),
+	)
+}
+
 func startServer(cfg *config.Config) {
 	logger.Info("Starting Elterngeld Portal API",
 		zap.String("version", "1.0.0"),
@@ -149,6 +320,11 @@ func startServer(cfg *config.Config) {
 	// Initialize and start server
 	srv := server.New(cfg, logger.Logger)
 
+	// Start background jobs; they stop on shutdown
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+	srv.StartBackgroundJobs(jobsCtx)
+
 	// Create HTTP server
 	httpServer := &http.Server{
 		Addr:    ":" + cfg.Server.Port,
@@ -183,14 +359,21 @@ func startServer(cfg *config.Config) {
 	logger.Info("Shutting down server...")
 
 	// Create a deadline for shutdown
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
 	defer cancel()
 
-	// Attempt graceful shutdown
+	// Attempt graceful shutdown; requests finish first as they may queue jobs
 	if err := httpServer.Shutdown(ctx); err != nil {
 		logger.Error("Server forced to shutdown", zap.Error(err))
 	}
 
+	// Stop the background jobs and let them finish what they started; work
+	// they cannot finish in time is requeued for the next instance
+	stopJobs()
+	if !srv.DrainBackgroundJobs(ctx) {
+		logger.Warn("Background jobs did not finish before the shutdown timeout")
+	}
+
 	// Close database connection
 	if err := database.Close(); err != nil {
 		logger.Error("Failed to close database connection", zap.Error(err))
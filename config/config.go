@@ -12,25 +12,62 @@ import (
 )
 
 type Config struct {
-	Server    ServerConfig
-	Database  DatabaseConfig
-	JWT       JWTConfig
-	Stripe    StripeConfig
-	Upload    UploadConfig
-	S3        S3Config
-	Email     EmailConfig
-	Admin     AdminConfig
-	Log       LogConfig
-	Migrate   MigrateConfig
-	Dev       DevConfig
-	CORS      CORSConfig
-	RateLimit RateLimitConfig
+	App          AppConfig
+	Server       ServerConfig
+	Database     DatabaseConfig
+	JWT          JWTConfig
+	Stripe       StripeConfig
+	Upload       UploadConfig
+	Encryption   EncryptionConfig
+	S3           S3Config
+	Email        EmailConfig
+	Admin        AdminConfig
+	Auth         AuthConfig
+	Password     PasswordConfig
+	Captcha      CaptchaConfig
+	Abuse        AbuseConfig
+	Address      AddressConfig
+	Chat         ChatConfig
+	Newsletter   NewsletterConfig
+	WhatsApp     WhatsAppConfig
+	Analytics    AnalyticsConfig
+	Digest       DigestConfig
+	Booking      BookingConfig
+	Tax          TaxConfig
+	Refund       RefundConfig
+	NoShow       NoShowConfig
+	LeadAging    LeadAgingConfig
+	Announcement AnnouncementConfig
+	Recruiting   RecruitingConfig
+	Export       ExportConfig
+	PDF          PDFConfig
+	Outbox       OutboxConfig
+	Scheduler    SchedulerConfig
+	Backup       BackupConfig
+	Anonymize    AnonymizeConfig
+	Log          LogConfig
+	Migrate      MigrateConfig
+	Dev          DevConfig
+	CORS         CORSConfig
+	Security     SecurityConfig
+	Cache        CacheConfig
+	RateLimit    RateLimitConfig
+}
+
+type AppConfig struct {
+	BaseURL     string
+	Timezone    string // default timezone for Beraters and clients without one
+	CompanyName string // employer and publisher name in job feeds
+	LogoURL     string
 }
 
 type ServerConfig struct {
-	Port string
-	Host string
-	Env  string
+	Port               string
+	Host               string
+	Env                string
+	MaxBodySize        int64         // request bodies above this size are rejected unless their route allows more
+	CompressionMinSize int           // responses below this size are sent uncompressed
+	ShutdownTimeout    time.Duration // in-flight requests and background jobs get this long to finish on shutdown
 }
 
 type DatabaseConfig struct {
@@ -55,12 +92,32 @@ type StripeConfig struct {
 	WebhookSecret string
 	SuccessURL    string
 	CancelURL     string
+	APIURL        string // API base URL, empty for Stripe itself; the integration tests use stripe-mock
 }
 
 type UploadConfig struct {
 	Path              string
 	MaxSize           int64
 	AllowedExtensions []string
+	MaxRequestSize    int64 // body size limit of upload routes, covers several files per request
+	UserQuota         int64 // storage each customer may use for their documents, 0 disables the quota
+
+	// Document previews and signed download links
+	SigningSecret   string
+	SignedURLExpiry time.Duration
+	ThumbnailSize   int
+	PDFRenderer     string
+	PDFStamper      string
+
+	// Text recognition and classification of uploaded documents
+	OCREngine   string
+	OCRLanguage string
+	PDFText     string
+}
+
+type EncryptionConfig struct {
+	Keys       string // comma separated "<id>:<base64 key>" pairs
+	PrimaryKey string
 }
 
 type S3Config struct {
@@ -81,6 +138,13 @@ type EmailConfig struct {
 	FromName      string
 	MailgunDomain string
 	MailgunAPIKey string
+
+	// Queued emails (receipts, confirmations, notifications) are sent and retried on this interval
+	QueueInterval time.Duration
+
+	// Inbound email processing; replies arrive via the provider's inbound webhook
+	InboundWebhookSecret string
+	InboundAttachmentDir string
 }
 
 type AdminConfig struct {
@@ -88,6 +152,164 @@ type AdminConfig struct {
 	Password string
 }
 
+type AuthConfig struct {
+	VerificationTokenTTL       time.Duration // how long an email verification link stays valid
+	VerificationResendCooldown time.Duration
+	VerificationMaxResends     int           // verification emails per account and day
+	PendingAccountTTL          time.Duration // unverified accounts are removed after this period
+	PendingCleanupInterval     time.Duration
+}
+
+type PasswordConfig struct {
+	MinLength     int
+	RequireUpper  bool
+	RequireLower  bool
+	RequireDigit  bool
+	RequireSymbol bool
+
+	// Breached password check against the HaveIBeenPwned range API
+	BreachCheck        bool
+	BreachAPIURL       string
+	BreachCheckTimeout time.Duration
+}
+
+type CaptchaConfig struct {
+	Provider  string // "recaptcha" or "turnstile"; empty disables captcha checks
+	SecretKey string
+	MinScore  float64 // minimum reCAPTCHA v3 score
+	VerifyURL string  // overrides the provider's siteverify endpoint
+	Timeout   time.Duration
+}
+
+type AbuseConfig struct {
+	ReviewThreshold   int           // submissions to public endpoints with this anomaly score are held for review; 0 disables the review queue
+	DisposableDomains []string      // disposable email providers in addition to the built-in list
+	Retention         time.Duration // how long checks of passed submissions are kept for burst and velocity detection
+	CleanupInterval   time.Duration // how often expired checks are removed
+
+	EmailCheckInterval time.Duration // how often new email addresses are checked for disposable and undeliverable domains
+	EmailCheckTimeout  time.Duration // timeout of the DNS lookups of one address
+}
+
+type AddressConfig struct {
+	Provider  string // nominatim; empty disables address validation
+	APIURL    string // overrides the provider's API base URL, e.g. a self-hosted Nominatim
+	UserAgent string // identifies the application, required by the Nominatim usage policy
+	Email     string // contact address sent along with Nominatim requests
+	Timeout   time.Duration
+}
+
+type ChatConfig struct {
+	WebhookTimeout   time.Duration // timeout for posting to Slack and Teams webhooks
+	LeadResponseSLA  time.Duration // default of the leads.response_sla setting, which admins can change at runtime
+	InboxClaimSLA    time.Duration // default of the leads.inbox_claim_sla setting
+	SLACheckInterval time.Duration
+}
+
+type NewsletterConfig struct {
+	Provider      string // mailchimp or brevo; empty disables the sync
+	APIKey        string
+	ListID        string
+	APIURL        string // overrides the provider's API base URL
+	WebhookSecret string // token expected on unsubscribe webhooks
+	SyncInterval  time.Duration
+	Timeout       time.Duration
+}
+
+type WhatsAppConfig struct {
+	Provider         string // 360dialog or twilio; empty disables WhatsApp messaging
+	APIKey           string // 360dialog API key or Twilio auth token
+	AccountSID       string // Twilio only
+	From             string // sender number in international format; Twilio only
+	APIURL           string // overrides the provider's API base URL
+	WebhookSecret    string // token expected on inbound message and status webhooks
+	ReminderTemplate string // approved template for booking reminders; a Twilio content SID
+	TemplateLanguage string
+	CheckInterval    time.Duration // how often due booking reminders are looked for
+	Timeout          time.Duration
+}
+
+type AnalyticsConfig struct {
+	SessionTimeout time.Duration // inactivity after which a visitor's next event starts a new session
+}
+
+type DigestConfig struct {
+	SendHour           int           // local hour from which the daily digests are sent
+	CheckInterval      time.Duration // how often due digests are looked for
+	LargePaymentAmount float64       // payments from this amount appear in the activity feed
+}
+
+type BookingConfig struct {
+	HoldTTL           time.Duration // how long a timeslot stays reserved for an unpaid booking; Stripe requires at least 30m
+	HoldCheckInterval time.Duration // how often expired holds are released
+	VATRate           float64       // Umsatzsteuer included in the package and addon prices
+}
+
+type TaxConfig struct {
+	Mode        string        // internal: VAT by customer country and USt-ID; stripe: Stripe Tax calculates it in checkout
+	HomeCountry string        // ISO code of the country the consultations are supplied from
+	VIESURL     string        // overrides the VIES API base URL used to validate USt-IDs
+	VIESTimeout time.Duration // VIES is often slow; without an answer the USt-ID counts as not validated
+}
+
+type RefundConfig struct {
+	ApprovalThreshold float64 // refunds above this amount need the approval of a second admin
+}
+
+type NoShowConfig struct {
+	GracePeriod     time.Duration // time after the end of a booking until it is flagged as no-show
+	CheckInterval   time.Duration // how often overdue bookings and due follow-ups are looked for
+	FollowUpEnabled bool          // email customers an offer to reschedule after a no-show
+	FollowUpDelay   time.Duration // time the Berater has to correct the flag before the follow-up is sent
+}
+
+type LeadAgingConfig struct {
+	CheckInterval time.Duration // how often the lead aging rules are applied
+}
+
+type AnnouncementConfig struct {
+	CheckInterval time.Duration // how often scheduled announcements are published
+}
+
+type RecruitingConfig struct {
+	RetentionMonths        int           // rejected applications outside the talent pool are anonymized after this many months
+	RetentionCheckInterval time.Duration // how often applications past the retention period are anonymized
+	SigningSecret          string        // signs the verification and tracking links sent to applicants
+}
+
+type ExportConfig struct {
+	StreamThreshold int // exports with more rows are streamed instead of buffered
+	BatchSize       int // rows loaded per keyset page
+}
+
+type PDFConfig struct {
+	Converter string        // wkhtmltopdf binary that converts the HTML templates to PDF
+	Timeout   time.Duration // bounds a single conversion
+	Path      string        // directory of generated PDFs
+}
+
+type OutboxConfig struct {
+	RelayInterval time.Duration // how often queued side effects are delivered and failed ones retried
+}
+
+type SchedulerConfig struct {
+	TickInterval time.Duration // how often the scheduler starts the recurring jobs that are due
+}
+
+type BackupConfig struct {
+	Path               string // directory of the encrypted backup sets
+	Schedule           string // cron expression of the automatic backups, in the app timezone
+	Keep               int    // number of backup sets kept, 0 keeps all
+	PgDump             string // pg_dump binary that dumps PostgreSQL databases
+	PgRestore          string // pg_restore binary that restores PostgreSQL dumps
+	RestoreDatabase    string // staging database restores go to: a postgres:// URL or a SQLite file
+	RestoreStoragePath string // staging directory restored documents are written to
+}
+
+type AnonymizeConfig struct {
+	Secret string // keys the replacements of personal data in staging copies; the same secret gives the same replacements
+}
+
 type LogConfig struct {
 	Level  string
 	Format string
@@ -107,6 +329,19 @@ type CORSConfig struct {
 	Credentials bool
 }
 
+type CacheConfig struct {
+	MaxAge time.Duration // how long browsers and proxies reuse public GET responses before revalidating them with their ETag
+}
+
+type SecurityConfig struct {
+	HSTSMaxAge            time.Duration // 0 disables Strict-Transport-Security
+	ContentSecurityPolicy string
+
+	// Cookies of browser clients that keep the refresh token in a cookie
+	CookieSecure bool
+	CookieDomain string
+}
+
 type RateLimitConfig struct {
 	Requests int
 	Window   int
@@ -121,10 +356,19 @@ func Load() error {
 	}
 
 	cfg := &Config{
+		App: AppConfig{
+			BaseURL:     strings.TrimRight(getEnv("APP_BASE_URL", "http://localhost:8080"), "/"),
+			Timezone:    getEnv("APP_TIMEZONE", "Europe/Berlin"),
+			CompanyName: getEnv("APP_COMPANY_NAME", "Elterngeld Portal"),
+			LogoURL:     getEnv("APP_LOGO_URL", ""),
+		},
 		Server: ServerConfig{
-			Port: getEnv("PORT", "8080"),
-			Host: getEnv("HOST", "localhost"),
-			Env:  getEnv("ENV", "development"),
+			Port:               getEnv("PORT", "8080"),
+			Host:               getEnv("HOST", "localhost"),
+			Env:                getEnv("ENV", "development"),
+			MaxBodySize:        parseInt64(getEnv("MAX_BODY_SIZE", "1048576")),
+			CompressionMinSize: parseInt(getEnv("COMPRESSION_MIN_SIZE", "1024")),
+			ShutdownTimeout:    parseDuration(getEnv("SHUTDOWN_TIMEOUT", "30s")),
 		},
 		Database: DatabaseConfig{
 			Driver:     getEnv("DB_DRIVER", "sqlite"),
@@ -146,11 +390,28 @@ func Load() error {
 			WebhookSecret: getEnv("STRIPE_WEBHOOK_SECRET", ""),
 			SuccessURL:    getEnv("STRIPE_SUCCESS_URL", "http://localhost:8080/payment/success"),
 			CancelURL:     getEnv("STRIPE_CANCEL_URL", "http://localhost:8080/payment/cancel"),
+			APIURL:        getEnv("STRIPE_API_URL", ""),
 		},
 		Upload: UploadConfig{
 			Path:              getEnv("UPLOAD_PATH", "./storage/uploads"),
 			MaxSize:           parseInt64(getEnv("MAX_UPLOAD_SIZE", "10485760")),
 			AllowedExtensions: strings.Split(getEnv("ALLOWED_EXTENSIONS", ".pdf,.png,.jpg,.jpeg"), ","),
+			MaxRequestSize:    parseInt64(getEnv("MAX_UPLOAD_REQUEST_SIZE", "52428800")),
+			UserQuota:         parseInt64(getEnv("UPLOAD_USER_QUOTA", "524288000")),
+
+			SigningSecret:   getEnv("DOCUMENT_SIGNING_SECRET", ""),
+			SignedURLExpiry: parseDuration(getEnv("DOCUMENT_SIGNED_URL_EXPIRY", "5m")),
+			ThumbnailSize:   parseInt(getEnv("DOCUMENT_THUMBNAIL_SIZE", "320")),
+			PDFRenderer:     getEnv("DOCUMENT_PDF_RENDERER", "pdftoppm"),
+			PDFStamper:      getEnv("DOCUMENT_PDF_STAMPER", "qpdf"),
+
+			OCREngine:   getEnv("DOCUMENT_OCR_ENGINE", "tesseract"),
+			OCRLanguage: getEnv("DOCUMENT_OCR_LANGUAGE", "deu"),
+			PDFText:     getEnv("DOCUMENT_PDF_TEXT", "pdftotext"),
+		},
+		Encryption: EncryptionConfig{
+			Keys:       getEnv("ENCRYPTION_KEYS", ""),
+			PrimaryKey: getEnv("ENCRYPTION_PRIMARY_KEY", ""),
 		},
 		S3: S3Config{
 			UseS3:           parseBool(getEnv("USE_S3", "false")),
@@ -169,11 +430,150 @@ func Load() error {
 			FromName:      getEnv("EMAIL_FROM_NAME", "Elterngeld Portal"),
 			MailgunDomain: getEnv("MAILGUN_DOMAIN", ""),
 			MailgunAPIKey: getEnv("MAILGUN_API_KEY", ""),
+
+			QueueInterval: parseDuration(getEnv("EMAIL_QUEUE_INTERVAL", "1m")),
+
+			InboundWebhookSecret: getEnv("INBOUND_EMAIL_WEBHOOK_SECRET", ""),
+			InboundAttachmentDir: getEnv("INBOUND_EMAIL_ATTACHMENT_DIR", "./storage/uploads/inbound"),
 		},
 		Admin: AdminConfig{
 			Email:    getEnv("ADMIN_EMAIL", "admin@elterngeld-portal.de"),
 			Password: getEnv("ADMIN_PASSWORD", "admin123"),
 		},
+		Auth: AuthConfig{
+			VerificationTokenTTL:       parseDuration(getEnv("EMAIL_VERIFICATION_TTL", "24h")),
+			VerificationResendCooldown: parseDuration(getEnv("EMAIL_VERIFICATION_RESEND_COOLDOWN", "2m")),
+			VerificationMaxResends:     parseInt(getEnv("EMAIL_VERIFICATION_MAX_RESENDS", "5")),
+			PendingAccountTTL:          parseDuration(getEnv("PENDING_ACCOUNT_TTL", "720h")),
+			PendingCleanupInterval:     parseDuration(getEnv("PENDING_ACCOUNT_CLEANUP_INTERVAL", "6h")),
+		},
+		Password: PasswordConfig{
+			MinLength:     parseInt(getEnv("PASSWORD_MIN_LENGTH", "8")),
+			RequireUpper:  parseBool(getEnv("PASSWORD_REQUIRE_UPPER", "true")),
+			RequireLower:  parseBool(getEnv("PASSWORD_REQUIRE_LOWER", "true")),
+			RequireDigit:  parseBool(getEnv("PASSWORD_REQUIRE_DIGIT", "true")),
+			RequireSymbol: parseBool(getEnv("PASSWORD_REQUIRE_SYMBOL", "false")),
+
+			BreachCheck:        parseBool(getEnv("PASSWORD_BREACH_CHECK", "false")),
+			BreachAPIURL:       getEnv("PASSWORD_BREACH_API_URL", "https://api.pwnedpasswords.com"),
+			BreachCheckTimeout: parseDuration(getEnv("PASSWORD_BREACH_CHECK_TIMEOUT", "3s")),
+		},
+		Captcha: CaptchaConfig{
+			Provider:  getEnv("CAPTCHA_PROVIDER", ""),
+			SecretKey: getEnv("CAPTCHA_SECRET_KEY", ""),
+			MinScore:  parseFloat(getEnv("CAPTCHA_MIN_SCORE", "0.5")),
+			VerifyURL: getEnv("CAPTCHA_VERIFY_URL", ""),
+			Timeout:   parseDuration(getEnv("CAPTCHA_TIMEOUT", "5s")),
+		},
+		Abuse: AbuseConfig{
+			ReviewThreshold:   parseInt(getEnv("ABUSE_REVIEW_THRESHOLD", "50")),
+			DisposableDomains: strings.Split(getEnv("ABUSE_DISPOSABLE_DOMAINS", ""), ","),
+			Retention:         parseDuration(getEnv("ABUSE_RETENTION", "720h")),
+			CleanupInterval:   parseDuration(getEnv("ABUSE_CLEANUP_INTERVAL", "1h")),
+
+			EmailCheckInterval: parseDuration(getEnv("EMAIL_CHECK_INTERVAL", "1m")),
+			EmailCheckTimeout:  parseDuration(getEnv("EMAIL_CHECK_TIMEOUT", "5s")),
+		},
+		Address: AddressConfig{
+			Provider:  getEnv("ADDRESS_PROVIDER", ""),
+			APIURL:    getEnv("ADDRESS_API_URL", ""),
+			UserAgent: getEnv("ADDRESS_USER_AGENT", "elterngeld-portal"),
+			Email:     getEnv("ADDRESS_EMAIL", ""),
+			Timeout:   parseDuration(getEnv("ADDRESS_TIMEOUT", "5s")),
+		},
+		Chat: ChatConfig{
+			WebhookTimeout:   parseDuration(getEnv("CHAT_WEBHOOK_TIMEOUT", "5s")),
+			LeadResponseSLA:  parseDuration(getEnv("LEAD_RESPONSE_SLA", "24h")),
+			InboxClaimSLA:    parseDuration(getEnv("INBOX_CLAIM_SLA", "4h")),
+			SLACheckInterval: parseDuration(getEnv("SLA_CHECK_INTERVAL", "15m")),
+		},
+		Newsletter: NewsletterConfig{
+			Provider:      getEnv("NEWSLETTER_PROVIDER", ""),
+			APIKey:        getEnv("NEWSLETTER_API_KEY", ""),
+			ListID:        getEnv("NEWSLETTER_LIST_ID", ""),
+			APIURL:        getEnv("NEWSLETTER_API_URL", ""),
+			WebhookSecret: getEnv("NEWSLETTER_WEBHOOK_SECRET", ""),
+			SyncInterval:  parseDuration(getEnv("NEWSLETTER_SYNC_INTERVAL", "1h")),
+			Timeout:       parseDuration(getEnv("NEWSLETTER_TIMEOUT", "10s")),
+		},
+		WhatsApp: WhatsAppConfig{
+			Provider:         getEnv("WHATSAPP_PROVIDER", ""),
+			APIKey:           getEnv("WHATSAPP_API_KEY", ""),
+			AccountSID:       getEnv("WHATSAPP_ACCOUNT_SID", ""),
+			From:             getEnv("WHATSAPP_FROM", ""),
+			APIURL:           getEnv("WHATSAPP_API_URL", ""),
+			WebhookSecret:    getEnv("WHATSAPP_WEBHOOK_SECRET", ""),
+			ReminderTemplate: getEnv("WHATSAPP_REMINDER_TEMPLATE", "booking_reminder"),
+			TemplateLanguage: getEnv("WHATSAPP_TEMPLATE_LANGUAGE", "de"),
+			CheckInterval:    parseDuration(getEnv("WHATSAPP_CHECK_INTERVAL", "15m")),
+			Timeout:          parseDuration(getEnv("WHATSAPP_TIMEOUT", "10s")),
+		},
+		Analytics: AnalyticsConfig{
+			SessionTimeout: parseDuration(getEnv("ANALYTICS_SESSION_TIMEOUT", "30m")),
+		},
+		Digest: DigestConfig{
+			SendHour:           parseInt(getEnv("DIGEST_SEND_HOUR", "7")),
+			CheckInterval:      parseDuration(getEnv("DIGEST_CHECK_INTERVAL", "15m")),
+			LargePaymentAmount: parseFloat(getEnv("DIGEST_LARGE_PAYMENT_AMOUNT", "500")),
+		},
+		Booking: BookingConfig{
+			HoldTTL:           parseDuration(getEnv("BOOKING_HOLD_TTL", "30m")),
+			HoldCheckInterval: parseDuration(getEnv("BOOKING_HOLD_CHECK_INTERVAL", "1m")),
+			VATRate:           parseFloat(getEnv("BOOKING_VAT_RATE", "0.19")),
+		},
+		Tax: TaxConfig{
+			Mode:        getEnv("TAX_MODE", "internal"),
+			HomeCountry: getEnv("TAX_HOME_COUNTRY", "DE"),
+			VIESURL:     getEnv("TAX_VIES_URL", ""),
+			VIESTimeout: parseDuration(getEnv("TAX_VIES_TIMEOUT", "10s")),
+		},
+		Refund: RefundConfig{
+			ApprovalThreshold: parseFloat(getEnv("REFUND_APPROVAL_THRESHOLD", "100")),
+		},
+		NoShow: NoShowConfig{
+			GracePeriod:     parseDuration(getEnv("NO_SHOW_GRACE_PERIOD", "2h")),
+			CheckInterval:   parseDuration(getEnv("NO_SHOW_CHECK_INTERVAL", "15m")),
+			FollowUpEnabled: parseBool(getEnv("NO_SHOW_FOLLOW_UP_ENABLED", "true")),
+			FollowUpDelay:   parseDuration(getEnv("NO_SHOW_FOLLOW_UP_DELAY", "24h")),
+		},
+		LeadAging: LeadAgingConfig{
+			CheckInterval: parseDuration(getEnv("LEAD_AGING_CHECK_INTERVAL", "1h")),
+		},
+		Announcement: AnnouncementConfig{
+			CheckInterval: parseDuration(getEnv("ANNOUNCEMENT_CHECK_INTERVAL", "1m")),
+		},
+		Recruiting: RecruitingConfig{
+			RetentionMonths:        parseInt(getEnv("RECRUITING_RETENTION_MONTHS", "6")),
+			RetentionCheckInterval: parseDuration(getEnv("RECRUITING_RETENTION_CHECK_INTERVAL", "24h")),
+			SigningSecret:          getEnv("RECRUITING_SIGNING_SECRET", ""),
+		},
+		Export: ExportConfig{
+			StreamThreshold: parseInt(getEnv("EXPORT_STREAM_THRESHOLD", "50000")),
+			BatchSize:       parseInt(getEnv("EXPORT_BATCH_SIZE", "1000")),
+		},
+		PDF: PDFConfig{
+			Converter: getEnv("PDF_CONVERTER", "wkhtmltopdf"),
+			Timeout:   parseDuration(getEnv("PDF_TIMEOUT", "60s")),
+			Path:      getEnv("PDF_PATH", "./storage/generated"),
+		},
+		Outbox: OutboxConfig{
+			RelayInterval: parseDuration(getEnv("OUTBOX_RELAY_INTERVAL", "15s")),
+		},
+		Scheduler: SchedulerConfig{
+			TickInterval: parseDuration(getEnv("SCHEDULER_TICK_INTERVAL", "15s")),
+		},
+		Backup: BackupConfig{
+			Path:               getEnv("BACKUP_PATH", "./storage/backups"),
+			Schedule:           getEnv("BACKUP_SCHEDULE", "0 3 * * *"),
+			Keep:               parseInt(getEnv("BACKUP_KEEP", "14")),
+			PgDump:             getEnv("BACKUP_PG_DUMP", "pg_dump"),
+			PgRestore:          getEnv("BACKUP_PG_RESTORE", "pg_restore"),
+			RestoreDatabase:    getEnv("BACKUP_RESTORE_DATABASE", ""),
+			RestoreStoragePath: getEnv("BACKUP_RESTORE_STORAGE_PATH", ""),
+		},
+		Anonymize: AnonymizeConfig{
+			Secret: getEnv("ANONYMIZE_SECRET", ""),
+		},
 		Log: LogConfig{
 			Level:  getEnv("LOG_LEVEL", "info"),
 			Format: getEnv("LOG_FORMAT", "json"),
@@ -189,6 +589,15 @@ func Load() error {
 			Origins:     strings.Split(getEnv("CORS_ORIGINS", "http://localhost:3000,http://localhost:8080"), ","),
 			Credentials: parseBool(getEnv("CORS_CREDENTIALS", "true")),
 		},
+		Security: SecurityConfig{
+			HSTSMaxAge:            parseDuration(getEnv("HSTS_MAX_AGE", "8760h")),
+			ContentSecurityPolicy: getEnv("CONTENT_SECURITY_POLICY", "default-src 'none'; frame-ancestors 'none'"),
+			CookieSecure:          parseBool(getEnv("SESSION_COOKIE_SECURE", "true")),
+			CookieDomain:          getEnv("SESSION_COOKIE_DOMAIN", ""),
+		},
+		Cache: CacheConfig{
+			MaxAge: parseDuration(getEnv("CACHE_MAX_AGE", "5m")),
+		},
 		RateLimit: RateLimitConfig{
 			Requests: parseInt(getEnv("RATE_LIMIT_REQUESTS", "100")),
 			Window:   parseInt(getEnv("RATE_LIMIT_WINDOW", "60")),
@@ -222,6 +631,14 @@ func parseInt64(s string) int64 {
 	return i
 }
 
+func parseFloat(s string) float64 {
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0
+	}
+	return f
+}
+
 func parseBool(s string) bool {
 	b, err := strconv.ParseBool(s)
 	if err != nil {
@@ -246,7 +663,7 @@ func ParseDuration(s string) time.Duration {
 func (c *Config) GetDSN() string {
 	switch c.Database.Driver {
 	case "postgres":
-		return fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
+		return fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s TimeZone=UTC",
 			c.Database.Host,
 			c.Database.Port,
 			c.Database.User,
@@ -0,0 +1,254 @@
+// Package abuse scores submissions to the public contact and registration
+// endpoints for signs of abuse: bursts and high velocity from one IP address,
+// repeated email addresses and disposable email providers. Submissions that
+// reach the review threshold are held in a review queue instead of creating
+// leads or sending verification emails right away.
+package abuse
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"elterngeld-portal/config"
+	"elterngeld-portal/internal/models"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrNotFound is returned for unknown submission checks
+	ErrNotFound = errors.New("submission check not found")
+	// ErrAlreadyReviewed is returned when a submission is no longer pending review
+	ErrAlreadyReviewed = errors.New("submission was already reviewed")
+)
+
+// Submissions from one IP address score once they reach these counts,
+// including the submission being checked
+const (
+	burstWindow    = 10 * time.Minute
+	burstLimit     = 3 // to the same endpoint
+	velocityWindow = time.Hour
+	velocityLimit  = 10 // to any public endpoint
+	emailWindow    = 24 * time.Hour
+	emailLimit     = 3 // with the same email address, from any IP
+)
+
+// weights is the score each reason adds to a submission
+var weights = map[models.SubmissionReason]int{
+	models.SubmissionReasonIPBurst:         40,
+	models.SubmissionReasonIPVelocity:      30,
+	models.SubmissionReasonEmailRepeat:     30,
+	models.SubmissionReasonDisposableEmail: 50,
+}
+
+// disposableDomains are well-known disposable email providers; more can be
+// added with ABUSE_DISPOSABLE_DOMAINS
+var disposableDomains = []string{
+	"10minutemail.com",
+	"discard.email",
+	"dispostable.com",
+	"emailondeck.com",
+	"fakeinbox.com",
+	"getnada.com",
+	"guerrillamail.com",
+	"guerrillamail.de",
+	"mailinator.com",
+	"maildrop.cc",
+	"mintemail.com",
+	"mytemp.email",
+	"sharklasers.com",
+	"spamgourmet.com",
+	"temp-mail.org",
+	"tempmail.com",
+	"throwawaymail.com",
+	"trashmail.com",
+	"trashmail.de",
+	"wegwerfemail.de",
+	"wegwerfmail.de",
+	"yopmail.com",
+}
+
+// Service scores public submissions and manages the review queue
+type Service struct {
+	db         *gorm.DB
+	logger     *zap.Logger
+	config     config.AbuseConfig
+	disposable map[string]bool
+	now        func() time.Time
+}
+
+func NewService(db *gorm.DB, logger *zap.Logger, cfg *config.Config) *Service {
+	disposable := map[string]bool{}
+	for _, domain := range append(disposableDomains, cfg.Abuse.DisposableDomains...) {
+		if domain = strings.ToLower(strings.TrimSpace(domain)); domain != "" {
+			disposable[domain] = true
+		}
+	}
+
+	return &Service{
+		db:         db,
+		logger:     logger,
+		config:     cfg.Abuse,
+		disposable: disposable,
+		now:        time.Now,
+	}
+}
+
+// Assess scores a submission against the earlier submissions. The returned
+// check is flagged for review when the score reaches the review threshold;
+// it is stored with Record once the submission itself has been saved.
+func (s *Service) Assess(endpoint models.SubmissionEndpoint, ipAddress, email string) (*models.SubmissionCheck, error) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	now := s.now()
+	check := &models.SubmissionCheck{
+		Endpoint:  endpoint,
+		IPAddress: ipAddress,
+		Email:     email,
+		CreatedAt: now,
+	}
+
+	var reasons []models.SubmissionReason
+
+	if ipAddress != "" {
+		burst, err := s.count(now.Add(-burstWindow), "ip_address = ? AND endpoint = ?", ipAddress, endpoint)
+		if err != nil {
+			return nil, err
+		}
+		if burst+1 >= burstLimit {
+			reasons = append(reasons, models.SubmissionReasonIPBurst)
+		}
+
+		velocity, err := s.count(now.Add(-velocityWindow), "ip_address = ?", ipAddress)
+		if err != nil {
+			return nil, err
+		}
+		if velocity+1 >= velocityLimit {
+			reasons = append(reasons, models.SubmissionReasonIPVelocity)
+		}
+	}
+
+	if email != "" {
+		repeats, err := s.count(now.Add(-emailWindow), "email = ?", email)
+		if err != nil {
+			return nil, err
+		}
+		if repeats+1 >= emailLimit {
+			reasons = append(reasons, models.SubmissionReasonEmailRepeat)
+		}
+
+		if s.IsDisposable(email) {
+			reasons = append(reasons, models.SubmissionReasonDisposableEmail)
+		}
+	}
+
+	for _, reason := range reasons {
+		check.Score += weights[reason]
+	}
+	check.SetReasons(reasons)
+	if s.config.ReviewThreshold > 0 && check.Score >= s.config.ReviewThreshold {
+		check.ReviewStatus = models.SubmissionReviewPending
+	}
+
+	return check, nil
+}
+
+// Record stores an assessed check, in the transaction that saves the submission
+func (s *Service) Record(tx *gorm.DB, check *models.SubmissionCheck) error {
+	if check.IsFlagged() {
+		s.logger.Warn("Public submission held for review",
+			zap.String("endpoint", string(check.Endpoint)),
+			zap.String("client_ip", check.IPAddress),
+			zap.Int("score", check.Score),
+			zap.String("reasons", check.Reasons))
+	}
+	return tx.Create(check).Error
+}
+
+// IsDisposable checks if the email address belongs to a disposable email
+// provider, including subdomains of one
+func (s *Service) IsDisposable(email string) bool {
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return false
+	}
+
+	domain := strings.ToLower(strings.TrimSpace(email[at+1:]))
+	for domain != "" {
+		if s.disposable[domain] {
+			return true
+		}
+		dot := strings.Index(domain, ".")
+		if dot < 0 {
+			break
+		}
+		domain = domain[dot+1:]
+	}
+	return false
+}
+
+// List returns the flagged submissions with the given review status, newest first
+func (s *Service) List(status models.SubmissionReviewStatus) ([]models.SubmissionCheck, error) {
+	var checks []models.SubmissionCheck
+	err := s.db.Where("review_status = ?", status).
+		Order("created_at DESC").
+		Find(&checks).Error
+	return checks, err
+}
+
+// Review approves or rejects a pending submission in the given transaction,
+// so the caller can create the held lead or remove the held account with it
+func (s *Service) Review(tx *gorm.DB, id, reviewerID uuid.UUID, approve bool) (*models.SubmissionCheck, error) {
+	var check models.SubmissionCheck
+	if err := tx.Where("id = ? AND review_status <> ?", id, "").First(&check).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	status := models.SubmissionReviewRejected
+	if approve {
+		status = models.SubmissionReviewApproved
+	}
+	now := s.now()
+
+	// Only one reviewer can decide on a submission
+	result := tx.Model(&models.SubmissionCheck{}).
+		Where("id = ? AND review_status = ?", id, models.SubmissionReviewPending).
+		Updates(map[string]interface{}{
+			"review_status":  status,
+			"reviewed_by_id": reviewerID,
+			"reviewed_at":    now,
+		})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrAlreadyReviewed
+	}
+
+	check.ReviewStatus = status
+	check.ReviewedByID = &reviewerID
+	check.ReviewedAt = &now
+	return &check, nil
+}
+
+// Prune removes checks of submissions that passed once they are older than
+// the retention period; flagged submissions are kept as review history
+func (s *Service) Prune() (int64, error) {
+	result := s.db.Where("review_status = ? AND created_at < ?", "", s.now().Add(-s.config.Retention)).
+		Delete(&models.SubmissionCheck{})
+	return result.RowsAffected, result.Error
+}
+
+func (s *Service) count(since time.Time, query string, args ...interface{}) (int64, error) {
+	var count int64
+	err := s.db.Model(&models.SubmissionCheck{}).
+		Where(query, args...).
+		Where("created_at >= ?", since).
+		Count(&count).Error
+	return count, err
+}
@@ -0,0 +1,149 @@
+package abuse
+
+import (
+	"testing"
+	"time"
+
+	"elterngeld-portal/config"
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/tests/testutils"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+func TestAssess(t *testing.T) {
+	db, service := setupTestService(t)
+	now := time.Now()
+	service.now = func() time.Time { return now }
+
+	submit := func(endpoint models.SubmissionEndpoint, ip, email string) *models.SubmissionCheck {
+		t.Helper()
+		check, err := service.Assess(endpoint, ip, email)
+		require.NoError(t, err)
+		require.NoError(t, service.Record(db, check))
+		return check
+	}
+
+	check := submit(models.SubmissionEndpointContact, "203.0.113.7", "anna@example.com")
+	assert.Equal(t, 0, check.Score)
+	assert.False(t, check.IsFlagged())
+
+	// A burst alone scores but stays below the threshold
+	submit(models.SubmissionEndpointContact, "203.0.113.7", "ben@example.com")
+	check = submit(models.SubmissionEndpointContact, "203.0.113.7", "carla@example.com")
+	assert.Equal(t, []models.SubmissionReason{models.SubmissionReasonIPBurst}, check.GetReasons())
+	assert.False(t, check.IsFlagged())
+
+	// Bursts on another endpoint are counted separately
+	check = submit(models.SubmissionEndpointRegister, "203.0.113.7", "dora@example.com")
+	assert.Equal(t, 0, check.Score)
+
+	// Disposable email providers are flagged, including their subdomains
+	check = submit(models.SubmissionEndpointRegister, "198.51.100.1", "spam@Mail.Mailinator.com")
+	assert.Equal(t, []models.SubmissionReason{models.SubmissionReasonDisposableEmail}, check.GetReasons())
+	assert.Equal(t, models.SubmissionReviewPending, check.ReviewStatus)
+	assert.True(t, service.IsDisposable("someone@wegwerf.example"))
+	assert.False(t, service.IsDisposable("someone@notmailinator.com"))
+
+	// The same email address from changing IPs adds up with the burst
+	for i := 0; i < 2; i++ {
+		submit(models.SubmissionEndpointContact, "192.0.2.1", "erik@example.com")
+	}
+	check = submit(models.SubmissionEndpointContact, "192.0.2.1", "ERIK@example.com ")
+	assert.ElementsMatch(t, []models.SubmissionReason{models.SubmissionReasonIPBurst, models.SubmissionReasonEmailRepeat}, check.GetReasons())
+	assert.Equal(t, 70, check.Score)
+	assert.True(t, check.IsFlagged())
+
+	// Older submissions no longer count
+	now = now.Add(emailWindow + time.Minute)
+	check = submit(models.SubmissionEndpointContact, "192.0.2.1", "erik@example.com")
+	assert.Equal(t, 0, check.Score)
+}
+
+func TestAssessVelocity(t *testing.T) {
+	db, service := setupTestService(t)
+	now := time.Now()
+	service.now = func() time.Time { return now }
+
+	var check *models.SubmissionCheck
+	for i := 0; i < velocityLimit; i++ {
+		// Spread over the hour so no burst is detected
+		now = now.Add(burstWindow / 2)
+		endpoint := models.SubmissionEndpointContact
+		if i%2 == 1 {
+			endpoint = models.SubmissionEndpointRegister
+		}
+		var err error
+		check, err = service.Assess(endpoint, "203.0.113.7", uuid.New().String()+"@example.com")
+		require.NoError(t, err)
+		require.NoError(t, service.Record(db, check))
+	}
+	assert.Contains(t, check.GetReasons(), models.SubmissionReasonIPVelocity)
+}
+
+func TestReview(t *testing.T) {
+	db, service := setupTestService(t)
+	reviewerID := uuid.New()
+
+	check, err := service.Assess(models.SubmissionEndpointContact, "203.0.113.7", "spam@yopmail.com")
+	require.NoError(t, err)
+	require.NoError(t, service.Record(db, check))
+	passed, err := service.Assess(models.SubmissionEndpointContact, "198.51.100.1", "anna@example.com")
+	require.NoError(t, err)
+	require.NoError(t, service.Record(db, passed))
+
+	pending, err := service.List(models.SubmissionReviewPending)
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	assert.Equal(t, check.ID, pending[0].ID)
+
+	_, err = service.Review(db, passed.ID, reviewerID, true)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	reviewed, err := service.Review(db, check.ID, reviewerID, false)
+	require.NoError(t, err)
+	assert.Equal(t, models.SubmissionReviewRejected, reviewed.ReviewStatus)
+	assert.Equal(t, reviewerID, *reviewed.ReviewedByID)
+
+	_, err = service.Review(db, check.ID, reviewerID, true)
+	assert.ErrorIs(t, err, ErrAlreadyReviewed)
+}
+
+func TestPrune(t *testing.T) {
+	db, service := setupTestService(t)
+	now := time.Now()
+	service.now = func() time.Time { return now }
+
+	for _, email := range []string{"anna@example.com", "spam@yopmail.com"} {
+		check, err := service.Assess(models.SubmissionEndpointContact, "203.0.113.7", email)
+		require.NoError(t, err)
+		require.NoError(t, service.Record(db, check))
+	}
+
+	now = now.Add(service.config.Retention + time.Minute)
+	pruned, err := service.Prune()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), pruned)
+
+	// Flagged submissions are kept
+	var remaining []models.SubmissionCheck
+	require.NoError(t, db.Find(&remaining).Error)
+	require.Len(t, remaining, 1)
+	assert.True(t, remaining[0].IsFlagged())
+}
+
+func setupTestService(t *testing.T) (*gorm.DB, *Service) {
+	t.Helper()
+	db := testutils.NewSQLiteDB(t, &models.SubmissionCheck{})
+
+	cfg := &config.Config{Abuse: config.AbuseConfig{
+		ReviewThreshold:   50,
+		DisposableDomains: []string{" Wegwerf.example", ""},
+		Retention:         30 * 24 * time.Hour,
+	}}
+	return db, NewService(db, zap.NewNop(), cfg)
+}
@@ -0,0 +1,201 @@
+// Package accesstokens manages personal access tokens. Power users and their
+// scripts send them as bearer tokens instead of a JWT; AuthMiddleware resolves
+// them to their owner and only lets them reach routes covered by their scopes.
+package accesstokens
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"elterngeld-portal/internal/models"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	// DefaultLifetime is how long a token is valid when no expiry is given
+	DefaultLifetime = 90 * 24 * time.Hour
+	// MaxLifetime caps the validity of a token, so forgotten tokens expire
+	MaxLifetime = 365 * 24 * time.Hour
+	// MaxActiveTokens is how many unexpired tokens a user may have at once
+	MaxActiveTokens = 20
+
+	// apiPrefix is stripped from routes before they are mapped to scopes
+	apiPrefix = "/api/v1/"
+)
+
+var (
+	// ErrInvalidToken is returned for unknown, revoked or expired tokens and tokens of deactivated users
+	ErrInvalidToken = errors.New("invalid access token")
+	// ErrTokenNotFound is returned when revoking a token that does not exist or belongs to someone else
+	ErrTokenNotFound = errors.New("access token not found")
+	// ErrInvalidScope is returned when creating a token with a scope that does not exist
+	ErrInvalidScope = errors.New("invalid access token scope")
+	// ErrInvalidExpiry is returned for expiry dates in the past or beyond MaxLifetime
+	ErrInvalidExpiry = errors.New("invalid access token expiry")
+	// ErrTooManyTokens is returned when a user already has MaxActiveTokens active tokens
+	ErrTooManyTokens = errors.New("too many access tokens")
+)
+
+// resourceScopes maps the first segment of an API route to the scopes that
+// allow reading and changing it. Routes of other resources, including token
+// management itself, cannot be used with a token.
+var resourceScopes = map[string][2]models.AccessTokenScope{
+	"leads":     {models.AccessTokenScopeLeadsRead, models.AccessTokenScopeLeadsWrite},
+	"bookings":  {models.AccessTokenScopeBookingsRead, models.AccessTokenScopeBookingsWrite},
+	"documents": {models.AccessTokenScopeDocumentsRead, models.AccessTokenScopeDocumentsWrite},
+	"todos":     {models.AccessTokenScopeTodosRead, models.AccessTokenScopeTodosWrite},
+}
+
+// Service creates, revokes and authenticates personal access tokens
+type Service struct {
+	db     *gorm.DB
+	logger *zap.Logger
+	now    func() time.Time
+}
+
+func NewService(db *gorm.DB, logger *zap.Logger) *Service {
+	return &Service{
+		db:     db,
+		logger: logger,
+		now:    time.Now,
+	}
+}
+
+// Create creates a token for ownerID and returns it together with the plain
+// token, which is only available at this point. Without an expiry the token
+// is valid for DefaultLifetime.
+func (s *Service) Create(ownerID uuid.UUID, name string, scopes []models.AccessTokenScope, expiresAt *time.Time) (*models.AccessToken, string, error) {
+	for _, scope := range scopes {
+		if !scope.IsValid() {
+			return nil, "", fmt.Errorf("%w: %s", ErrInvalidScope, scope)
+		}
+	}
+
+	now := s.now()
+	expiry := now.Add(DefaultLifetime)
+	if expiresAt != nil {
+		if !expiresAt.After(now) || expiresAt.After(now.Add(MaxLifetime)) {
+			return nil, "", ErrInvalidExpiry
+		}
+		expiry = *expiresAt
+	}
+
+	var active int64
+	if err := s.db.Model(&models.AccessToken{}).
+		Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", ownerID, now).
+		Count(&active).Error; err != nil {
+		return nil, "", err
+	}
+	if active >= MaxActiveTokens {
+		return nil, "", ErrTooManyTokens
+	}
+
+	token := &models.AccessToken{UserID: ownerID, Name: strings.TrimSpace(name)}
+	plain, err := token.Generate(models.AccessTokenPrefix)
+	if err != nil {
+		return nil, "", err
+	}
+	token.ExpiresAt = &expiry
+	token.SetScopes(scopes)
+
+	if err := s.db.Create(token).Error; err != nil {
+		return nil, "", fmt.Errorf("failed to create access token: %w", err)
+	}
+
+	s.logger.Info("Access token created",
+		zap.String("access_token_id", token.ID.String()),
+		zap.String("user_id", ownerID.String()),
+		zap.String("scopes", token.Scopes))
+
+	return token, plain, nil
+}
+
+// List returns the tokens of a user, newest first
+func (s *Service) List(ownerID uuid.UUID) ([]models.AccessToken, error) {
+	var tokens []models.AccessToken
+	if err := s.db.Where("user_id = ?", ownerID).Order("created_at DESC").Find(&tokens).Error; err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+// Revoke revokes a token of ownerID
+func (s *Service) Revoke(id, ownerID uuid.UUID) error {
+	revoked, err := models.RevokeKeys(s.db.Model(&models.AccessToken{}).Where("id = ? AND user_id = ?", id, ownerID), s.now())
+	if err != nil {
+		return fmt.Errorf("failed to revoke access token: %w", err)
+	}
+	if revoked == 0 {
+		return ErrTokenNotFound
+	}
+
+	s.logger.Info("Access token revoked", zap.String("access_token_id", id.String()), zap.String("user_id", ownerID.String()))
+	return nil
+}
+
+// IsAccessToken reports whether a bearer token is a personal access token rather than a JWT
+func IsAccessToken(bearer string) bool {
+	return strings.HasPrefix(bearer, models.AccessTokenPrefix)
+}
+
+// Authenticate resolves a plain token to its stored token with the owner loaded
+func (s *Service) Authenticate(plain string) (*models.AccessToken, error) {
+	if !IsAccessToken(plain) {
+		return nil, ErrInvalidToken
+	}
+
+	var token models.AccessToken
+	if err := s.db.Preload("User").Where("key_hash = ?", models.HashKey(plain)).First(&token).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidToken
+		}
+		// AuthMiddleware has no logger, so failures are logged here
+		s.logger.Error("Failed to load access token", zap.Error(err))
+		return nil, err
+	}
+	now := s.now()
+	if !token.IsActive(now) || !token.User.IsActive || token.User.DeactivatedAt != nil {
+		return nil, ErrInvalidToken
+	}
+
+	// Only record usage once a minute; scripts often send bursts of requests
+	if token.LastUsedAt == nil || now.Sub(*token.LastUsedAt) > time.Minute {
+		if err := s.db.Model(&token).UpdateColumn("last_used_at", now).Error; err != nil {
+			s.logger.Warn("Failed to record access token usage", zap.String("access_token_id", token.ID.String()), zap.Error(err))
+		}
+		token.LastUsedAt = &now
+	}
+
+	return &token, nil
+}
+
+// RequiredScope returns the scope a token needs for a request to a route
+// pattern such as /api/v1/leads/:id. Safe methods need the read scope of the
+// resource, all others the write scope. It reports false for routes that
+// cannot be used with a token at all.
+func RequiredScope(method, route string) (models.AccessTokenScope, bool) {
+	path, ok := strings.CutPrefix(route, apiPrefix)
+	if !ok {
+		return "", false
+	}
+	resource, _, _ := strings.Cut(path, "/")
+	read := method == http.MethodGet || method == http.MethodHead
+
+	if path == "auth/me" {
+		return models.AccessTokenScopeProfileRead, read
+	}
+	scopes, ok := resourceScopes[resource]
+	if !ok {
+		return "", false
+	}
+	if read {
+		return scopes[0], true
+	}
+	return scopes[1], true
+}
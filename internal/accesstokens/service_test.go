@@ -0,0 +1,118 @@
+package accesstokens
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/tests/testutils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+func TestAccessTokens(t *testing.T) {
+	db, service := setupTestService(t)
+	user := testutils.CreateTestUser(t, db, models.RoleUser)
+	other := testutils.CreateTestUser(t, db, models.RoleUser)
+	now := time.Now()
+	service.now = func() time.Time { return now }
+
+	token, plain, err := service.Create(user.ID, " CLI ", []models.AccessTokenScope{models.AccessTokenScopeLeadsRead}, nil)
+	require.NoError(t, err)
+	assert.True(t, IsAccessToken(plain))
+	assert.Equal(t, "CLI", token.Name)
+	assert.Equal(t, plain[:len(token.KeyPrefix)], token.KeyPrefix)
+	assert.NotContains(t, token.KeyHash, plain)
+	require.NotNil(t, token.ExpiresAt)
+	assert.WithinDuration(t, now.Add(DefaultLifetime), *token.ExpiresAt, time.Second)
+
+	authenticated, err := service.Authenticate(plain)
+	require.NoError(t, err)
+	assert.Equal(t, token.ID, authenticated.ID)
+	assert.Equal(t, user.ID, authenticated.User.ID)
+	assert.NotNil(t, authenticated.LastUsedAt)
+
+	_, err = service.Authenticate(models.AccessTokenPrefix + "unknown")
+	assert.ErrorIs(t, err, ErrInvalidToken)
+	_, err = service.Authenticate("eyJhbGciOiJIUzI1NiJ9")
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	_, _, err = service.Create(user.ID, "CLI", []models.AccessTokenScope{"users:write"}, nil)
+	assert.ErrorIs(t, err, ErrInvalidScope)
+	tooLate := now.Add(MaxLifetime + time.Hour)
+	_, _, err = service.Create(user.ID, "CLI", []models.AccessTokenScope{models.AccessTokenScopeLeadsRead}, &tooLate)
+	assert.ErrorIs(t, err, ErrInvalidExpiry)
+
+	// Tokens expire
+	now = now.Add(DefaultLifetime)
+	_, err = service.Authenticate(plain)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	// Only the owner can revoke a token
+	expiresAt := now.Add(time.Hour)
+	token, plain, err = service.Create(user.ID, "Script", []models.AccessTokenScope{models.AccessTokenScopeTodosWrite}, &expiresAt)
+	require.NoError(t, err)
+	assert.ErrorIs(t, service.Revoke(token.ID, other.ID), ErrTokenNotFound)
+	require.NoError(t, service.Revoke(token.ID, user.ID))
+	assert.ErrorIs(t, service.Revoke(token.ID, user.ID), ErrTokenNotFound)
+	_, err = service.Authenticate(plain)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	tokens, err := service.List(user.ID)
+	require.NoError(t, err)
+	assert.Len(t, tokens, 2)
+}
+
+func TestAuthenticateDeactivatedUser(t *testing.T) {
+	db, service := setupTestService(t)
+	user := testutils.CreateTestUser(t, db, models.RoleUser)
+
+	_, plain, err := service.Create(user.ID, "CLI", []models.AccessTokenScope{models.AccessTokenScopeProfileRead}, nil)
+	require.NoError(t, err)
+	require.NoError(t, db.Model(user).Update("is_active", false).Error)
+
+	_, err = service.Authenticate(plain)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestRequiredScope(t *testing.T) {
+	tests := []struct {
+		method string
+		route  string
+		scope  models.AccessTokenScope
+		ok     bool
+	}{
+		{http.MethodGet, "/api/v1/leads", models.AccessTokenScopeLeadsRead, true},
+		{http.MethodPost, "/api/v1/leads/:id/comments", models.AccessTokenScopeLeadsWrite, true},
+		{http.MethodGet, "/api/v1/bookings/:id", models.AccessTokenScopeBookingsRead, true},
+		{http.MethodDelete, "/api/v1/todos/:id", models.AccessTokenScopeTodosWrite, true},
+		{http.MethodGet, "/api/v1/auth/me", models.AccessTokenScopeProfileRead, true},
+		{http.MethodPut, "/api/v1/auth/me", "", false},
+		{http.MethodPost, "/api/v1/auth/change-password", "", false},
+		{http.MethodPost, "/api/v1/me/tokens", "", false},
+		{http.MethodGet, "/api/v1/admin/users", "", false},
+	}
+	for _, tt := range tests {
+		scope, ok := RequiredScope(tt.method, tt.route)
+		assert.Equal(t, tt.ok, ok, tt.route)
+		if tt.ok {
+			assert.Equal(t, tt.scope, scope, tt.route)
+		}
+	}
+
+	// Write scopes include reading
+	token := &models.AccessToken{}
+	token.SetScopes([]models.AccessTokenScope{models.AccessTokenScopeLeadsWrite})
+	assert.True(t, token.HasScope(models.AccessTokenScopeLeadsRead))
+	assert.False(t, token.HasScope(models.AccessTokenScopeBookingsRead))
+}
+
+func setupTestService(t *testing.T) (*gorm.DB, *Service) {
+	t.Helper()
+	db := testutils.NewSQLiteDB(t, &models.User{}, &models.AccessToken{})
+	return db, NewService(db, zap.NewNop())
+}
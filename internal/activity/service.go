@@ -0,0 +1,347 @@
+package activity
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"elterngeld-portal/config"
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/pkg/timeutil"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	// DefaultLimit is the number of feed items returned when no limit is given
+	DefaultLimit = 50
+	// MaxLimit caps the number of feed items per request
+	MaxLimit = 200
+	// digestLimit caps the number of items listed in a digest email
+	digestLimit = 100
+)
+
+// ItemType identifies the kind of event in the activity feed
+type ItemType string
+
+const (
+	ItemLeadCreated      ItemType = "lead.created"
+	ItemLargePayment     ItemType = "payment.large"
+	ItemBookingCancelled ItemType = "booking.cancelled"
+	ItemLeadSLABreached  ItemType = "lead.sla_breached"
+)
+
+// ItemTypes lists all feed item types
+var ItemTypes = []ItemType{ItemLeadCreated, ItemLargePayment, ItemBookingCancelled, ItemLeadSLABreached}
+
+// IsValid checks if the item type is known
+func (t ItemType) IsValid() bool {
+	for _, known := range ItemTypes {
+		if t == known {
+			return true
+		}
+	}
+	return false
+}
+
+// ErrInvalidItemType is returned for unknown feed item types
+var ErrInvalidItemType = errors.New("invalid activity type")
+
+// Item is an important event in the activity feed
+type Item struct {
+	Type        ItemType        `json:"type"`
+	OccurredAt  time.Time       `json:"occurred_at"`
+	ReferenceID uuid.UUID       `json:"reference_id"` // lead, payment or booking
+	LeadID      *uuid.UUID      `json:"lead_id,omitempty"`
+	BeraterID   *uuid.UUID      `json:"berater_id,omitempty"`
+	Title       string          `json:"title"`
+	Priority    models.Priority `json:"priority,omitempty"`
+	Amount      float64         `json:"amount,omitempty"`
+	Currency    string          `json:"currency,omitempty"`
+}
+
+// Filter narrows the activity feed down to a period, item types and a Berater
+type Filter struct {
+	Since     time.Time
+	Until     time.Time
+	Types     []ItemType // all types when empty
+	BeraterID *uuid.UUID // only items of leads and bookings assigned to this Berater
+	Limit     int
+}
+
+// DigestSender emails the daily digest to a Berater or admin
+type DigestSender interface {
+	SendDailyDigest(user *models.User, items []Item) error
+}
+
+// Service aggregates leads, payments, cancellations and SLA breaches into an
+// activity feed and emails it as a daily digest to those who opted in
+type Service struct {
+	db     *gorm.DB
+	logger *zap.Logger
+	sender DigestSender
+	config config.DigestConfig
+	now    func() time.Time
+}
+
+func NewService(db *gorm.DB, logger *zap.Logger, cfg *config.Config, sender DigestSender) *Service {
+	return &Service{
+		db:     db,
+		logger: logger,
+		sender: sender,
+		config: cfg.Digest,
+		now:    time.Now,
+	}
+}
+
+// Feed returns the events within the filter period, latest first
+func (s *Service) Feed(filter Filter) ([]Item, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = DefaultLimit
+	}
+	if filter.Limit > MaxLimit {
+		filter.Limit = MaxLimit
+	}
+	if filter.Until.IsZero() {
+		filter.Until = s.now()
+	}
+	types := filter.Types
+	if len(types) == 0 {
+		types = ItemTypes
+	}
+
+	var items []Item
+	for _, itemType := range types {
+		var found []Item
+		var err error
+		switch itemType {
+		case ItemLeadCreated:
+			found, err = s.leadItems(filter, ItemLeadCreated, "created_at")
+		case ItemLeadSLABreached:
+			found, err = s.leadItems(filter, ItemLeadSLABreached, "sla_breach_notified_at")
+		case ItemLargePayment:
+			found, err = s.paymentItems(filter)
+		case ItemBookingCancelled:
+			found, err = s.cancellationItems(filter)
+		default:
+			return nil, ErrInvalidItemType
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to load %s activity: %w", itemType, err)
+		}
+		items = append(items, found...)
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].OccurredAt.After(items[j].OccurredAt)
+	})
+	if len(items) > filter.Limit {
+		items = items[:filter.Limit]
+	}
+	if items == nil {
+		items = []Item{}
+	}
+	return items, nil
+}
+
+// leadItems lists leads by the time in the given column, which is either the
+// creation or the SLA breach time
+func (s *Service) leadItems(filter Filter, itemType ItemType, column string) ([]Item, error) {
+	query := s.db.Model(&models.Lead{}).
+		Where(column+" >= ? AND "+column+" < ?", filter.Since, filter.Until)
+	if filter.BeraterID != nil {
+		query = query.Where("berater_id = ?", *filter.BeraterID)
+	}
+
+	var leads []models.Lead
+	if err := query.Order(column + " DESC").Limit(filter.Limit).Find(&leads).Error; err != nil {
+		return nil, err
+	}
+
+	items := make([]Item, 0, len(leads))
+	for _, lead := range leads {
+		occurredAt := lead.CreatedAt
+		if itemType == ItemLeadSLABreached && lead.SLABreachNotifiedAt != nil {
+			occurredAt = *lead.SLABreachNotifiedAt
+		}
+		leadID := lead.ID
+		items = append(items, Item{
+			Type:        itemType,
+			OccurredAt:  occurredAt,
+			ReferenceID: lead.ID,
+			LeadID:      &leadID,
+			BeraterID:   lead.BeraterID,
+			Title:       lead.Title,
+			Priority:    lead.Priority,
+		})
+	}
+	return items, nil
+}
+
+// paymentItems lists successful payments from the configured amount by payment time
+func (s *Service) paymentItems(filter Filter) ([]Item, error) {
+	query := s.db.Model(&models.Payment{}).
+		Select("payments.*, leads.title AS lead_title, leads.berater_id AS lead_berater_id").
+		Joins("JOIN leads ON leads.id = payments.lead_id").
+		Where("payments.status = ? AND payments.amount >= ? AND payments.paid_at >= ? AND payments.paid_at < ?",
+			models.PaymentStatusSucceeded, s.config.LargePaymentAmount, filter.Since, filter.Until)
+	if filter.BeraterID != nil {
+		query = query.Where("leads.berater_id = ?", *filter.BeraterID)
+	}
+
+	var payments []struct {
+		models.Payment
+		LeadTitle     string
+		LeadBeraterID *uuid.UUID
+	}
+	if err := query.Order("payments.paid_at DESC").Limit(filter.Limit).Scan(&payments).Error; err != nil {
+		return nil, err
+	}
+
+	items := make([]Item, 0, len(payments))
+	for _, payment := range payments {
+		leadID := payment.LeadID
+		title := payment.Description
+		if title == "" {
+			title = payment.LeadTitle
+		}
+		items = append(items, Item{
+			Type:        ItemLargePayment,
+			OccurredAt:  *payment.PaidAt,
+			ReferenceID: payment.ID,
+			LeadID:      &leadID,
+			BeraterID:   payment.LeadBeraterID,
+			Title:       title,
+			Amount:      payment.Amount,
+			Currency:    payment.Currency,
+		})
+	}
+	return items, nil
+}
+
+// cancellationItems lists bookings by cancellation time
+func (s *Service) cancellationItems(filter Filter) ([]Item, error) {
+	query := s.db.Model(&models.Booking{}).
+		Where("cancelled_at >= ? AND cancelled_at < ?", filter.Since, filter.Until)
+	if filter.BeraterID != nil {
+		query = query.Where("berater_id = ?", *filter.BeraterID)
+	}
+
+	var bookings []models.Booking
+	if err := query.Order("cancelled_at DESC").Limit(filter.Limit).Find(&bookings).Error; err != nil {
+		return nil, err
+	}
+
+	items := make([]Item, 0, len(bookings))
+	for _, booking := range bookings {
+		items = append(items, Item{
+			Type:        ItemBookingCancelled,
+			OccurredAt:  *booking.CancelledAt,
+			ReferenceID: booking.ID,
+			LeadID:      booking.LeadID,
+			BeraterID:   booking.BeraterID,
+			Title:       booking.BookingReference,
+			Amount:      booking.TotalAmount,
+			Currency:    booking.Currency,
+		})
+	}
+	return items, nil
+}
+
+// DigestPreference is whether a user receives the daily digest
+type DigestPreference struct {
+	EmailDailyDigest bool       `json:"email_daily_digest"`
+	DigestSentAt     *time.Time `json:"digest_sent_at"`
+}
+
+// GetDigestPreference returns the digest setting of a user; users without
+// notification preferences do not receive the digest
+func (s *Service) GetDigestPreference(userID uuid.UUID) (*DigestPreference, error) {
+	var preference models.NotificationPreference
+	err := s.db.Where("user_id = ?", userID).First(&preference).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &DigestPreference{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &DigestPreference{EmailDailyDigest: preference.EmailDailyDigest, DigestSentAt: preference.DigestSentAt}, nil
+}
+
+// SetDigestPreference turns the daily digest on or off
+func (s *Service) SetDigestPreference(userID uuid.UUID, enabled bool) (*DigestPreference, error) {
+	var preference models.NotificationPreference
+	err := s.db.Where("user_id = ?", userID).First(&preference).Error
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		preference = models.NotificationPreference{UserID: userID, EmailDailyDigest: enabled}
+		if err := s.db.Create(&preference).Error; err != nil {
+			return nil, fmt.Errorf("failed to create notification preferences: %w", err)
+		}
+	case err != nil:
+		return nil, err
+	default:
+		if err := s.db.Model(&preference).Update("email_daily_digest", enabled).Error; err != nil {
+			return nil, fmt.Errorf("failed to update notification preferences: %w", err)
+		}
+	}
+	return &DigestPreference{EmailDailyDigest: preference.EmailDailyDigest, DigestSentAt: preference.DigestSentAt}, nil
+}
+
+// SendDigests emails the digest to every active Berater and admin who opted
+// in and has not received one today, once the send hour has passed in their
+// timezone. Digests cover the events since the previous digest, at most a
+// day; days without events are skipped.
+func (s *Service) SendDigests() {
+	now := s.now()
+
+	var preferences []models.NotificationPreference
+	if err := s.db.Preload("User").
+		Joins("JOIN users ON users.id = notification_preferences.user_id").
+		Where("notification_preferences.email_daily_digest = ? AND users.is_active = ? AND users.role IN ?",
+			true, true, []models.UserRole{models.RoleBerater, models.RoleJuniorBerater, models.RoleAdmin}).
+		Find(&preferences).Error; err != nil {
+		s.logger.Error("Failed to load digest recipients", zap.Error(err))
+		return
+	}
+
+	for i := range preferences {
+		preference := &preferences[i]
+		user := &preference.User
+		loc := user.TimeLocation()
+		if now.In(loc).Hour() < s.config.SendHour {
+			continue
+		}
+		if preference.DigestSentAt != nil && !preference.DigestSentAt.Before(timeutil.StartOfDay(now, loc)) {
+			continue
+		}
+
+		since := now.Add(-24 * time.Hour)
+		if preference.DigestSentAt != nil && preference.DigestSentAt.After(since) {
+			since = *preference.DigestSentAt
+		}
+		filter := Filter{Since: since, Until: now, Limit: digestLimit}
+		if !user.IsAdmin() {
+			filter.BeraterID = &user.ID
+		}
+
+		items, err := s.Feed(filter)
+		if err != nil {
+			s.logger.Error("Failed to build daily digest", zap.String("user_id", user.ID.String()), zap.Error(err))
+			continue
+		}
+		if len(items) > 0 {
+			if err := s.sender.SendDailyDigest(user, items); err != nil {
+				s.logger.Error("Failed to send daily digest", zap.String("user_id", user.ID.String()), zap.Error(err))
+				continue
+			}
+		}
+
+		if err := s.db.Model(preference).UpdateColumn("digest_sent_at", now).Error; err != nil {
+			s.logger.Error("Failed to mark daily digest as sent", zap.String("user_id", user.ID.String()), zap.Error(err))
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package activity
+
+import (
+	"testing"
+	"time"
+
+	"elterngeld-portal/config"
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/tests/testutils"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type recordingSender struct {
+	sent map[uuid.UUID][]Item
+}
+
+func (r *recordingSender) SendDailyDigest(user *models.User, items []Item) error {
+	r.sent[user.ID] = items
+	return nil
+}
+
+func TestFeed(t *testing.T) {
+	db, service, _ := setupTestService(t)
+	now := service.now()
+	customer := testutils.CreateTestUser(t, db, models.RoleUser)
+	berater := testutils.CreateTestUser(t, db, models.RoleBerater)
+
+	own := createLead(t, db, customer, &berater.ID, now.Add(-3*time.Hour))
+	other := createLead(t, db, customer, nil, now.Add(-2*time.Hour))
+	// Outside the period
+	createLead(t, db, customer, &berater.ID, now.Add(-48*time.Hour))
+
+	breachedAt := now.Add(-time.Hour)
+	require.NoError(t, db.Model(own).UpdateColumn("sla_breach_notified_at", breachedAt).Error)
+
+	createPayment(t, db, own, 750, now.Add(-30*time.Minute))
+	// Below the threshold
+	createPayment(t, db, other, 99, now.Add(-20*time.Minute))
+
+	cancelledAt := now.Add(-10 * time.Minute)
+	require.NoError(t, db.Create(&models.Booking{
+		UserID:      customer.ID,
+		BeraterID:   &berater.ID,
+		Title:       "Erstberatung",
+		Status:      models.BookingStatusCancelled,
+		ScheduledAt: now.Add(24 * time.Hour),
+		StartTime:   now.Add(24 * time.Hour),
+		EndTime:     now.Add(25 * time.Hour),
+		TotalAmount: 149,
+		Currency:    "EUR",
+		CancelledAt: &cancelledAt,
+	}).Error)
+
+	since := now.Add(-24 * time.Hour)
+
+	t.Run("all items latest first", func(t *testing.T) {
+		items, err := service.Feed(Filter{Since: since})
+		require.NoError(t, err)
+		require.Len(t, items, 5)
+		assert.Equal(t, ItemBookingCancelled, items[0].Type)
+		assert.Equal(t, ItemLargePayment, items[1].Type)
+		assert.Equal(t, 750.0, items[1].Amount)
+		assert.Equal(t, ItemLeadSLABreached, items[2].Type)
+		assert.Equal(t, ItemLeadCreated, items[3].Type)
+		assert.Equal(t, other.ID, items[3].ReferenceID)
+	})
+
+	t.Run("scoped to a Berater", func(t *testing.T) {
+		items, err := service.Feed(Filter{Since: since, BeraterID: &berater.ID})
+		require.NoError(t, err)
+		require.Len(t, items, 4)
+		for _, item := range items {
+			require.NotNil(t, item.BeraterID)
+			assert.Equal(t, berater.ID, *item.BeraterID)
+		}
+	})
+
+	t.Run("types and limit", func(t *testing.T) {
+		items, err := service.Feed(Filter{Since: since, Types: []ItemType{ItemLeadCreated}, Limit: 1})
+		require.NoError(t, err)
+		require.Len(t, items, 1)
+		assert.Equal(t, other.ID, items[0].ReferenceID)
+
+		_, err = service.Feed(Filter{Since: since, Types: []ItemType{"lead.deleted"}})
+		assert.ErrorIs(t, err, ErrInvalidItemType)
+	})
+}
+
+func TestSendDigests(t *testing.T) {
+	db, service, sender := setupTestService(t)
+	now := service.now()
+	customer := testutils.CreateTestUser(t, db, models.RoleUser)
+	berater := testutils.CreateTestUser(t, db, models.RoleBerater)
+	admin := testutils.CreateTestUser(t, db, models.RoleAdmin)
+	optedOut := testutils.CreateTestUser(t, db, models.RoleBerater)
+	testutils.CreateTestUser(t, db, models.RoleAdmin)
+
+	createLead(t, db, customer, &berater.ID, now.Add(-2*time.Hour))
+	createLead(t, db, customer, nil, now.Add(-time.Hour))
+
+	for _, user := range []*models.User{berater, admin, customer} {
+		_, err := service.SetDigestPreference(user.ID, true)
+		require.NoError(t, err)
+	}
+	_, err := service.SetDigestPreference(optedOut.ID, true)
+	require.NoError(t, err)
+	preference, err := service.SetDigestPreference(optedOut.ID, false)
+	require.NoError(t, err)
+	assert.False(t, preference.EmailDailyDigest)
+
+	// Before the send hour nothing goes out
+	service.now = func() time.Time { return time.Date(2026, 3, 10, 5, 0, 0, 0, time.UTC) }
+	service.SendDigests()
+	assert.Empty(t, sender.sent)
+
+	service.now = func() time.Time { return now }
+	service.SendDigests()
+	require.Len(t, sender.sent, 2)
+	assert.Len(t, sender.sent[berater.ID], 1)
+	assert.Len(t, sender.sent[admin.ID], 2)
+	assert.NotContains(t, sender.sent, customer.ID)
+	assert.NotContains(t, sender.sent, optedOut.ID)
+
+	digest, err := service.GetDigestPreference(berater.ID)
+	require.NoError(t, err)
+	assert.True(t, digest.EmailDailyDigest)
+	require.NotNil(t, digest.DigestSentAt)
+
+	// Only once a day
+	sender.sent = map[uuid.UUID][]Item{}
+	service.now = func() time.Time { return now.Add(2 * time.Hour) }
+	service.SendDigests()
+	assert.Empty(t, sender.sent)
+}
+
+func createLead(t *testing.T, db *gorm.DB, customer *models.User, beraterID *uuid.UUID, createdAt time.Time) *models.Lead {
+	t.Helper()
+	lead := &models.Lead{
+		UserID:    customer.ID,
+		BeraterID: beraterID,
+		Title:     "Elterngeldantrag",
+		Status:    models.LeadStatusNew,
+		Priority:  models.PriorityMedium,
+		CreatedAt: createdAt,
+	}
+	require.NoError(t, db.Create(lead).Error)
+	return lead
+}
+
+func createPayment(t *testing.T, db *gorm.DB, lead *models.Lead, amount float64, paidAt time.Time) {
+	t.Helper()
+	require.NoError(t, db.Create(&models.Payment{
+		LeadID:          lead.ID,
+		UserID:          lead.UserID,
+		Amount:          amount,
+		Currency:        "EUR",
+		Status:          models.PaymentStatusSucceeded,
+		StripeSessionID: uuid.New().String(),
+		PaidAt:          &paidAt,
+	}).Error)
+}
+
+func setupTestService(t *testing.T) (*gorm.DB, *Service, *recordingSender) {
+	t.Helper()
+	db := testutils.NewSQLiteDB(t,
+		&models.User{},
+		&models.Lead{},
+		&models.Payment{},
+		&models.Booking{},
+		&models.NotificationPreference{},
+	)
+
+	sender := &recordingSender{sent: map[uuid.UUID][]Item{}}
+	service := NewService(db, zap.NewNop(), &config.Config{
+		Digest: config.DigestConfig{SendHour: 7, LargePaymentAmount: 500},
+	}, sender)
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	service.now = func() time.Time { return now }
+	return db, service, sender
+}
@@ -0,0 +1,509 @@
+package activitylog
+
+import (
+	"fmt"
+
+	"elterngeld-portal/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// Event is a change recorded in the activity history. Every event knows its
+// activity type, title and structured metadata, so callers do not assemble
+// activities by hand.
+type Event interface {
+	Activity() *models.Activity
+}
+
+// newActivity starts an activity; actors are nil for anonymous visitors and
+// the system
+func newActivity(activityType models.ActivityType, actorID *uuid.UUID, leadID *uuid.UUID) *models.ActivityBuilder {
+	builder := models.NewActivityBuilder().
+		WithType(activityType).
+		WithTitle(activityType.GetDisplayName())
+	if actorID != nil {
+		builder = builder.WithUser(*actorID)
+	}
+	if leadID != nil {
+		builder = builder.WithLead(*leadID)
+	}
+	return builder
+}
+
+// LeadCreated is recorded when a lead is created by a user, a contact form or a booking
+type LeadCreated struct {
+	ActorID       *uuid.UUID
+	LeadID        uuid.UUID
+	Title         string
+	Source        models.LeadSource
+	ContactFormID *uuid.UUID
+}
+
+func (e LeadCreated) Activity() *models.Activity {
+	extra := map[string]interface{}{"source": e.Source}
+	if e.ContactFormID != nil {
+		extra["contact_form_id"] = e.ContactFormID.String()
+	}
+
+	return newActivity(models.ActivityTypeLeadCreated, e.ActorID, &e.LeadID).
+		WithDescription(fmt.Sprintf("Lead '%s' wurde erstellt", e.Title)).
+		WithMetadata(models.ActivityMetadata{
+			EntityType: "lead",
+			EntityID:   e.LeadID.String(),
+			ExtraData:  extra,
+		}).
+		Build()
+}
+
+// LeadUpdated is recorded when fields of a lead are changed
+type LeadUpdated struct {
+	ActorID uuid.UUID
+	LeadID  uuid.UUID
+	Fields  []string
+}
+
+func (e LeadUpdated) Activity() *models.Activity {
+	return newActivity(models.ActivityTypeLeadUpdated, &e.ActorID, &e.LeadID).
+		WithDescription(fmt.Sprintf("%d Felder wurden geändert", len(e.Fields))).
+		WithMetadata(models.ActivityMetadata{
+			EntityType: "lead",
+			EntityID:   e.LeadID.String(),
+			ExtraData:  map[string]interface{}{"fields": e.Fields},
+		}).
+		Build()
+}
+
+// LeadDeleted is recorded when a lead is moved to the trash
+type LeadDeleted struct {
+	ActorID uuid.UUID
+	LeadID  uuid.UUID
+	Title   string
+}
+
+func (e LeadDeleted) Activity() *models.Activity {
+	return newActivity(models.ActivityTypeLeadDeleted, &e.ActorID, &e.LeadID).
+		WithDescription(fmt.Sprintf("Lead '%s' wurde gelöscht", e.Title)).
+		WithMetadata(models.ActivityMetadata{
+			EntityType: "lead",
+			EntityID:   e.LeadID.String(),
+		}).
+		Build()
+}
+
+// LeadStatusChanged is recorded when the status of a lead changes
+type LeadStatusChanged struct {
+	ActorID uuid.UUID
+	LeadID  uuid.UUID
+	From    models.LeadStatus
+	To      models.LeadStatus
+	Notes   string
+}
+
+func (e LeadStatusChanged) Activity() *models.Activity {
+	metadata := models.ActivityMetadata{
+		Field:    "status",
+		OldValue: string(e.From),
+		NewValue: string(e.To),
+	}
+	if e.Notes != "" {
+		metadata.ExtraData = map[string]interface{}{"notes": e.Notes}
+	}
+
+	return newActivity(models.ActivityTypeLeadStatusChanged, &e.ActorID, &e.LeadID).
+		WithDescription(fmt.Sprintf("Status von '%s' zu '%s' geändert", e.From, e.To)).
+		WithMetadata(metadata).
+		Build()
+}
+
+// LeadAssigned is recorded when a lead is assigned to a Berater, by hand or
+// automatically
+type LeadAssigned struct {
+	ActorID     uuid.UUID
+	LeadID      uuid.UUID
+	BeraterID   uuid.UUID
+	BeraterName string
+	PreviousID  *uuid.UUID
+	Automatic   bool
+	Notes       string
+}
+
+func (e LeadAssigned) Activity() *models.Activity {
+	extra := map[string]interface{}{"automatic": e.Automatic}
+	if e.Notes != "" {
+		extra["notes"] = e.Notes
+	}
+	metadata := models.ActivityMetadata{
+		Field:     "berater_id",
+		NewValue:  e.BeraterID.String(),
+		ExtraData: extra,
+	}
+	if e.PreviousID != nil {
+		metadata.OldValue = e.PreviousID.String()
+	}
+
+	description := fmt.Sprintf("Lead wurde %s zugewiesen", e.BeraterName)
+	if e.Automatic {
+		description = fmt.Sprintf("Lead wurde automatisch %s zugewiesen", e.BeraterName)
+	}
+
+	return newActivity(models.ActivityTypeLeadAssigned, &e.ActorID, &e.LeadID).
+		WithDescription(description).
+		WithMetadata(metadata).
+		Build()
+}
+
+// LeadReleased is recorded when a Berater hands a claimed lead back to the
+// team inbox
+type LeadReleased struct {
+	ActorID   uuid.UUID
+	LeadID    uuid.UUID
+	BeraterID uuid.UUID
+}
+
+func (e LeadReleased) Activity() *models.Activity {
+	return newActivity(models.ActivityTypeLeadReleased, &e.ActorID, &e.LeadID).
+		WithDescription("Lead wurde zurück ins Team-Postfach gegeben").
+		WithMetadata(models.ActivityMetadata{
+			Field:    "berater_id",
+			OldValue: e.BeraterID.String(),
+		}).
+		Build()
+}
+
+// TodoCreated is recorded when a Berater assigns a todo to a customer
+type TodoCreated struct {
+	ActorID    uuid.UUID
+	LeadID     *uuid.UUID
+	TodoID     uuid.UUID
+	Title      string
+	AssigneeID uuid.UUID
+}
+
+func (e TodoCreated) Activity() *models.Activity {
+	return newActivity(models.ActivityTypeTodoCreated, &e.ActorID, e.LeadID).
+		WithDescription(fmt.Sprintf("Aufgabe '%s' wurde erstellt", e.Title)).
+		WithMetadata(models.ActivityMetadata{
+			EntityType: "todo",
+			EntityID:   e.TodoID.String(),
+			ExtraData:  map[string]interface{}{"assignee_id": e.AssigneeID.String()},
+		}).
+		Build()
+}
+
+// TodoUpdated is recorded when fields of a todo are changed
+type TodoUpdated struct {
+	ActorID uuid.UUID
+	LeadID  *uuid.UUID
+	TodoID  uuid.UUID
+	Title   string
+	Fields  []string
+}
+
+func (e TodoUpdated) Activity() *models.Activity {
+	return newActivity(models.ActivityTypeTodoUpdated, &e.ActorID, e.LeadID).
+		WithDescription(fmt.Sprintf("Aufgabe '%s' wurde geändert", e.Title)).
+		WithMetadata(models.ActivityMetadata{
+			EntityType: "todo",
+			EntityID:   e.TodoID.String(),
+			ExtraData:  map[string]interface{}{"fields": e.Fields},
+		}).
+		Build()
+}
+
+// TodoCompleted is recorded when a todo is marked as done
+type TodoCompleted struct {
+	ActorID uuid.UUID
+	LeadID  *uuid.UUID
+	TodoID  uuid.UUID
+	Title   string
+}
+
+func (e TodoCompleted) Activity() *models.Activity {
+	return newActivity(models.ActivityTypeTodoCompleted, &e.ActorID, e.LeadID).
+		WithDescription(fmt.Sprintf("Aufgabe '%s' wurde erledigt", e.Title)).
+		WithMetadata(models.ActivityMetadata{
+			EntityType: "todo",
+			EntityID:   e.TodoID.String(),
+		}).
+		Build()
+}
+
+// BookingCreated is recorded when a consultation is booked for a lead
+type BookingCreated struct {
+	ActorID   *uuid.UUID
+	LeadID    uuid.UUID
+	BookingID uuid.UUID
+	Reference string
+}
+
+func (e BookingCreated) Activity() *models.Activity {
+	return newActivity(models.ActivityTypeBookingCreated, e.ActorID, &e.LeadID).
+		WithDescription(fmt.Sprintf("Termin %s wurde gebucht", e.Reference)).
+		WithMetadata(models.ActivityMetadata{
+			EntityType: "booking",
+			EntityID:   e.BookingID.String(),
+			ExtraData:  map[string]interface{}{"booking_reference": e.Reference},
+		}).
+		Build()
+}
+
+// BookingStatusChanged is recorded when a booking of a lead moves to another
+// status; actors are nil for webhooks and scheduled jobs
+type BookingStatusChanged struct {
+	ActorID   *uuid.UUID
+	LeadID    uuid.UUID
+	BookingID uuid.UUID
+	Reference string
+	From      models.BookingStatus
+	To        models.BookingStatus
+	Note      string
+}
+
+func (e BookingStatusChanged) Activity() *models.Activity {
+	extra := map[string]interface{}{
+		"booking_reference": e.Reference,
+		"from":              e.From,
+		"to":                e.To,
+	}
+	if e.Note != "" {
+		extra["note"] = e.Note
+	}
+
+	return newActivity(models.ActivityTypeBookingStatus, e.ActorID, &e.LeadID).
+		WithDescription(fmt.Sprintf("Termin %s: %s → %s", e.Reference, e.From.GetDisplayName(), e.To.GetDisplayName())).
+		WithMetadata(models.ActivityMetadata{
+			EntityType: "booking",
+			EntityID:   e.BookingID.String(),
+			ExtraData:  extra,
+		}).
+		Build()
+}
+
+// ReplySent is recorded when a Berater replies to a contact form or lead by
+// email from the portal. Replies to contact forms without a lead keep their
+// body in the metadata, lead replies are on the lead's email thread.
+type ReplySent struct {
+	ActorID        uuid.UUID
+	LeadID         *uuid.UUID
+	ContactFormID  *uuid.UUID
+	EmailMessageID *uuid.UUID
+	To             string
+	Subject        string
+	Body           string
+	Attachments    []string
+}
+
+func (e ReplySent) Activity() *models.Activity {
+	extra := map[string]interface{}{
+		"to_email":    e.To,
+		"subject":     e.Subject,
+		"attachments": e.Attachments,
+	}
+	metadata := models.ActivityMetadata{EntityType: "email", ExtraData: extra}
+	switch {
+	case e.EmailMessageID != nil:
+		metadata.EntityID = e.EmailMessageID.String()
+	case e.ContactFormID != nil:
+		metadata.EntityType = "contact_form"
+		metadata.EntityID = e.ContactFormID.String()
+		extra["body"] = e.Body
+	}
+
+	return newActivity(models.ActivityTypeEmailSent, &e.ActorID, e.LeadID).
+		WithDescription(fmt.Sprintf("E-Mail an %s gesendet: %s", e.To, e.Subject)).
+		WithMetadata(metadata).
+		Build()
+}
+
+// WhatsAppSent is recorded when a Berater messages the customer of a lead on
+// WhatsApp or a booking reminder is sent; reminders have no actor
+type WhatsAppSent struct {
+	ActorID   *uuid.UUID
+	LeadID    uuid.UUID
+	MessageID uuid.UUID
+	Template  string
+	Body      string
+}
+
+func (e WhatsAppSent) Activity() *models.Activity {
+	description := "WhatsApp-Nachricht gesendet"
+	if e.Template != "" {
+		description = fmt.Sprintf("WhatsApp-Vorlage '%s' gesendet", e.Template)
+	}
+
+	return newActivity(models.ActivityTypeWhatsAppSent, e.ActorID, &e.LeadID).
+		WithDescription(description).
+		WithMetadata(models.ActivityMetadata{
+			EntityType: "whatsapp_message",
+			EntityID:   e.MessageID.String(),
+			ExtraData:  map[string]interface{}{"template": e.Template, "body": e.Body},
+		}).
+		Build()
+}
+
+// WhatsAppReceived is recorded when the customer of a lead writes on WhatsApp
+type WhatsAppReceived struct {
+	LeadID    uuid.UUID
+	MessageID uuid.UUID
+	Body      string
+}
+
+func (e WhatsAppReceived) Activity() *models.Activity {
+	return newActivity(models.ActivityTypeWhatsAppReceived, nil, &e.LeadID).
+		WithDescription("WhatsApp-Nachricht vom Kunden empfangen").
+		WithMetadata(models.ActivityMetadata{
+			EntityType: "whatsapp_message",
+			EntityID:   e.MessageID.String(),
+			ExtraData:  map[string]interface{}{"body": e.Body},
+		}).
+		Build()
+}
+
+// ApplicationSubmitted is recorded when the Elterngeld application of a lead
+// was submitted to the Elterngeldstelle
+type ApplicationSubmitted struct {
+	ActorID         uuid.UUID
+	LeadID          uuid.UUID
+	SubmissionID    uuid.UUID
+	Office          string
+	OfficeReference string
+}
+
+func (e ApplicationSubmitted) Activity() *models.Activity {
+	description := "Der Antrag wurde bei der Elterngeldstelle eingereicht"
+	if e.Office != "" {
+		description = fmt.Sprintf("Der Antrag wurde bei %s eingereicht", e.Office)
+	}
+
+	return newActivity(models.ActivityTypeApplicationSubmitted, &e.ActorID, &e.LeadID).
+		WithDescription(description).
+		WithMetadata(models.ActivityMetadata{
+			EntityType: "application_submission",
+			EntityID:   e.SubmissionID.String(),
+			ExtraData:  map[string]interface{}{"office": e.Office, "office_reference": e.OfficeReference},
+		}).
+		Build()
+}
+
+// DocumentsRequested is recorded when the Elterngeldstelle asks for additional documents
+type DocumentsRequested struct {
+	ActorID     uuid.UUID
+	LeadID      uuid.UUID
+	RequestID   uuid.UUID
+	Description string
+}
+
+func (e DocumentsRequested) Activity() *models.Activity {
+	return newActivity(models.ActivityTypeDocumentsRequested, &e.ActorID, &e.LeadID).
+		WithDescription(fmt.Sprintf("Die Elterngeldstelle fordert Unterlagen nach: %s", e.Description)).
+		WithMetadata(models.ActivityMetadata{
+			EntityType: "application_document_request",
+			EntityID:   e.RequestID.String(),
+		}).
+		Build()
+}
+
+// BescheidReceived is recorded when the Bescheid of the Elterngeldstelle arrived
+type BescheidReceived struct {
+	ActorID           uuid.UUID
+	LeadID            uuid.UUID
+	SubmissionID      uuid.UUID
+	GrantedTotal      float64
+	ObjectionDeadline string
+}
+
+func (e BescheidReceived) Activity() *models.Activity {
+	return newActivity(models.ActivityTypeBescheidReceived, &e.ActorID, &e.LeadID).
+		WithDescription(fmt.Sprintf("Bescheid über %.2f € erhalten, Widerspruchsfrist bis %s", e.GrantedTotal, e.ObjectionDeadline)).
+		WithMetadata(models.ActivityMetadata{
+			EntityType: "application_submission",
+			EntityID:   e.SubmissionID.String(),
+			ExtraData:  map[string]interface{}{"granted_total": e.GrantedTotal, "objection_deadline": e.ObjectionDeadline},
+		}).
+		Build()
+}
+
+// RefundRequested is recorded when a refund of a payment of the lead is
+// requested, with its justification
+type RefundRequested struct {
+	ActorID          uuid.UUID
+	LeadID           uuid.UUID
+	RequestID        uuid.UUID
+	Amount           float64
+	ToCredit         bool
+	Reason           string
+	RequiresApproval bool
+}
+
+func (e RefundRequested) Activity() *models.Activity {
+	description := fmt.Sprintf("Erstattung über %.2f € beantragt: %s", e.Amount, e.Reason)
+	if e.RequiresApproval {
+		description += " (Freigabe erforderlich)"
+	}
+
+	return newActivity(models.ActivityTypeRefundRequested, &e.ActorID, &e.LeadID).
+		WithDescription(description).
+		WithMetadata(models.ActivityMetadata{
+			EntityType: "refund_request",
+			EntityID:   e.RequestID.String(),
+			ExtraData:  map[string]interface{}{"amount": e.Amount, "to_credit": e.ToCredit, "requires_approval": e.RequiresApproval},
+		}).
+		Build()
+}
+
+// RefundDecided is recorded when a second approver approves or rejects a refund
+type RefundDecided struct {
+	ActorID   uuid.UUID
+	LeadID    uuid.UUID
+	RequestID uuid.UUID
+	Amount    float64
+	Approved  bool
+	Note      string
+}
+
+func (e RefundDecided) Activity() *models.Activity {
+	activityType := models.ActivityTypeRefundRejected
+	description := fmt.Sprintf("Erstattung über %.2f € abgelehnt", e.Amount)
+	if e.Approved {
+		activityType = models.ActivityTypeRefundApproved
+		description = fmt.Sprintf("Erstattung über %.2f € genehmigt", e.Amount)
+	}
+	if e.Note != "" {
+		description += ": " + e.Note
+	}
+
+	return newActivity(activityType, &e.ActorID, &e.LeadID).
+		WithDescription(description).
+		WithMetadata(models.ActivityMetadata{
+			EntityType: "refund_request",
+			EntityID:   e.RequestID.String(),
+			ExtraData:  map[string]interface{}{"amount": e.Amount},
+		}).
+		Build()
+}
+
+// RefundExecuted is recorded when a refund was paid back via Stripe or as credit
+type RefundExecuted struct {
+	ActorID        uuid.UUID
+	LeadID         uuid.UUID
+	RequestID      uuid.UUID
+	Amount         float64
+	ToCredit       bool
+	StripeRefundID string
+}
+
+func (e RefundExecuted) Activity() *models.Activity {
+	description := fmt.Sprintf("%.2f € wurden erstattet", e.Amount)
+	if e.ToCredit {
+		description = fmt.Sprintf("%.2f € wurden als Guthaben erstattet", e.Amount)
+	}
+
+	return newActivity(models.ActivityTypeRefundExecuted, &e.ActorID, &e.LeadID).
+		WithDescription(description).
+		WithMetadata(models.ActivityMetadata{
+			EntityType: "refund_request",
+			EntityID:   e.RequestID.String(),
+			ExtraData:  map[string]interface{}{"amount": e.Amount, "to_credit": e.ToCredit, "stripe_refund_id": e.StripeRefundID},
+		}).
+		Build()
+}
@@ -0,0 +1,153 @@
+// Package activitylog records the activity history of leads and users.
+// Changes are recorded as typed events in the transaction of the change
+// itself, so an activity exists exactly when the change was committed.
+package activitylog
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/internal/scopes"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	// DefaultLimit is the number of activities per page when no limit is given
+	DefaultLimit = 20
+	// MaxLimit caps the number of activities per page
+	MaxLimit = 100
+)
+
+var (
+	// ErrNotFound is returned when the activity does not exist or the viewer may not see it
+	ErrNotFound = errors.New("activity not found")
+	// ErrInvalidType is returned when filtering by an unknown activity type
+	ErrInvalidType = errors.New("invalid activity type")
+)
+
+// Filter narrows down the activity history
+type Filter struct {
+	LeadID *uuid.UUID
+	UserID *uuid.UUID // the user who caused the activities
+	Types  []models.ActivityType
+	Since  *time.Time
+	Until  *time.Time
+	Page   int
+	Limit  int
+}
+
+// Service records and lists activities
+type Service struct {
+	db     *gorm.DB
+	logger *zap.Logger
+}
+
+func NewService(db *gorm.DB, logger *zap.Logger) *Service {
+	return &Service{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// Record stores the events in tx, the transaction of the change they
+// describe. If recording fails the error must roll the change back.
+// Callers without a transaction pass nil.
+func (s *Service) Record(tx *gorm.DB, events ...Event) error {
+	if tx == nil {
+		tx = s.db
+	}
+	for _, event := range events {
+		activity := event.Activity()
+		if err := tx.Create(activity).Error; err != nil {
+			return fmt.Errorf("failed to record %s activity: %w", activity.Type, err)
+		}
+	}
+	return nil
+}
+
+// List returns a page of the activities the viewer may see, latest first,
+// and the number of matching activities
+func (s *Service) List(viewer scopes.Viewer, filter Filter) ([]models.ActivityResponse, int64, error) {
+	for _, activityType := range filter.Types {
+		if !activityType.IsValid() {
+			return nil, 0, ErrInvalidType
+		}
+	}
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+	if filter.Limit < 1 {
+		filter.Limit = DefaultLimit
+	}
+	if filter.Limit > MaxLimit {
+		filter.Limit = MaxLimit
+	}
+
+	query := s.db.Model(&models.Activity{}).Scopes(scopes.VisibleActivities(viewer))
+	if filter.LeadID != nil {
+		query = query.Where("activities.lead_id = ?", *filter.LeadID)
+	}
+	if filter.UserID != nil {
+		query = query.Where("activities.user_id = ?", *filter.UserID)
+	}
+	if len(filter.Types) > 0 {
+		query = query.Where("activities.type IN ?", filter.Types)
+	}
+	if filter.Since != nil {
+		query = query.Where("activities.created_at >= ?", *filter.Since)
+	}
+	if filter.Until != nil {
+		query = query.Where("activities.created_at < ?", *filter.Until)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var activities []models.Activity
+	err := query.Preload("User").
+		Order("activities.created_at DESC").Order("activities.id DESC").
+		Offset((filter.Page - 1) * filter.Limit).Limit(filter.Limit).
+		Find(&activities).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	responses := make([]models.ActivityResponse, 0, len(activities))
+	for i := range activities {
+		responses = append(responses, present(&activities[i], viewer))
+	}
+	return responses, total, nil
+}
+
+// Get returns an activity the viewer may see
+func (s *Service) Get(id uuid.UUID, viewer scopes.Viewer) (*models.ActivityResponse, error) {
+	var activity models.Activity
+	err := s.db.Scopes(scopes.VisibleActivities(viewer)).Preload("User").
+		Where("activities.id = ?", id).First(&activity).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	response := present(&activity, viewer)
+	return &response, nil
+}
+
+// present converts an activity for the viewer; only admins see from which
+// IP address an activity was caused
+func present(activity *models.Activity, viewer scopes.Viewer) models.ActivityResponse {
+	response := activity.ToResponse()
+	if viewer.Role != models.RoleAdmin {
+		response.IPAddress = ""
+	}
+	return response
+}
@@ -0,0 +1,191 @@
+package activitylog
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/internal/scopes"
+	"elterngeld-portal/tests/testutils"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+func TestRecord_SameTransaction(t *testing.T) {
+	db, service := setupTestService(t)
+	customer := testutils.CreateTestUser(t, db, models.RoleUser)
+	berater := testutils.CreateTestUser(t, db, models.RoleBerater)
+	lead := createLead(t, db, customer.ID, nil)
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		require.NoError(t, tx.Model(lead).Update("status", models.LeadStatusInProgress).Error)
+		require.NoError(t, service.Record(tx, LeadStatusChanged{
+			ActorID: berater.ID,
+			LeadID:  lead.ID,
+			From:    models.LeadStatusNew,
+			To:      models.LeadStatusInProgress,
+		}))
+		return errors.New("change failed")
+	})
+	require.Error(t, err)
+
+	// The activity is rolled back together with the change
+	var count int64
+	require.NoError(t, db.Model(&models.Activity{}).Count(&count).Error)
+	assert.Zero(t, count)
+
+	require.NoError(t, service.Record(nil, LeadStatusChanged{
+		ActorID: berater.ID,
+		LeadID:  lead.ID,
+		From:    models.LeadStatusNew,
+		To:      models.LeadStatusInProgress,
+		Notes:   "Unterlagen vollständig",
+	}))
+
+	var activity models.Activity
+	require.NoError(t, db.First(&activity).Error)
+	assert.Equal(t, models.ActivityTypeLeadStatusChanged, activity.Type)
+	assert.Equal(t, "Lead-Status geändert", activity.Title)
+	assert.Equal(t, berater.ID, *activity.UserID)
+
+	var metadata models.ActivityMetadata
+	require.NoError(t, activity.GetMetadata(&metadata))
+	assert.Equal(t, "status", metadata.Field)
+	assert.Equal(t, string(models.LeadStatusNew), metadata.OldValue)
+	assert.Equal(t, string(models.LeadStatusInProgress), metadata.NewValue)
+	assert.Equal(t, map[string]interface{}{"notes": "Unterlagen vollständig"}, metadata.ExtraData)
+}
+
+func TestRecord_AnonymousActor(t *testing.T) {
+	db, service := setupTestService(t)
+	customer := testutils.CreateTestUser(t, db, models.RoleUser)
+	lead := createLead(t, db, customer.ID, nil)
+	formID := uuid.New()
+
+	require.NoError(t, service.Record(nil, LeadCreated{LeadID: lead.ID, Title: lead.Title, Source: models.LeadSourceWebsite, ContactFormID: &formID}))
+
+	var activity models.Activity
+	require.NoError(t, db.First(&activity).Error)
+	assert.Nil(t, activity.UserID)
+
+	var metadata models.ActivityMetadata
+	require.NoError(t, activity.GetMetadata(&metadata))
+	assert.Equal(t, formID.String(), metadata.ExtraData.(map[string]interface{})["contact_form_id"])
+}
+
+func TestList_VisibilityAndFilters(t *testing.T) {
+	db, service := setupTestService(t)
+	customer := testutils.CreateTestUser(t, db, models.RoleUser)
+	other := testutils.CreateTestUser(t, db, models.RoleUser)
+	junior := testutils.CreateTestUser(t, db, models.RoleJuniorBerater)
+	berater := testutils.CreateTestUser(t, db, models.RoleBerater)
+	admin := testutils.CreateTestUser(t, db, models.RoleAdmin)
+
+	own := createLead(t, db, customer.ID, &junior.ID)
+	foreign := createLead(t, db, other.ID, &berater.ID)
+
+	require.NoError(t, service.Record(nil,
+		LeadCreated{ActorID: &customer.ID, LeadID: own.ID, Title: own.Title},
+		LeadAssigned{ActorID: admin.ID, LeadID: own.ID, BeraterID: junior.ID, BeraterName: junior.FullName()},
+		LeadCreated{ActorID: &other.ID, LeadID: foreign.ID, Title: foreign.Title},
+	))
+	login := models.NewActivityBuilder().WithType(models.ActivityTypeUserLogin).WithTitle("Login").
+		WithUser(other.ID).WithIPAddress("203.0.113.7").Build()
+	require.NoError(t, db.Create(login).Error)
+
+	tests := []struct {
+		name   string
+		viewer *models.User
+		filter Filter
+		total  int64
+	}{
+		{"customer sees activities of own leads", customer, Filter{}, 2},
+		{"other customer sees own lead and login", other, Filter{}, 2},
+		{"junior sees assigned leads only", junior, Filter{}, 2},
+		{"berater sees all leads but no logins of others", berater, Filter{}, 3},
+		{"admin sees everything", admin, Filter{}, 4},
+		{"filter by lead", admin, Filter{LeadID: &own.ID}, 2},
+		{"filter by actor", admin, Filter{UserID: &other.ID}, 2},
+		{"filter by type", admin, Filter{Types: []models.ActivityType{models.ActivityTypeLeadAssigned}}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			activities, total, err := service.List(viewer(tt.viewer), tt.filter)
+			require.NoError(t, err)
+			assert.Equal(t, tt.total, total)
+			assert.Len(t, activities, int(tt.total))
+		})
+	}
+
+	// Only admins see IP addresses
+	activities, _, err := service.List(viewer(other), Filter{Types: []models.ActivityType{models.ActivityTypeUserLogin}})
+	require.NoError(t, err)
+	require.Len(t, activities, 1)
+	assert.Empty(t, activities[0].IPAddress)
+
+	activity, err := service.Get(login.ID, viewer(admin))
+	require.NoError(t, err)
+	assert.Equal(t, "203.0.113.7", activity.IPAddress)
+
+	_, err = service.Get(login.ID, viewer(customer))
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	_, _, err = service.List(viewer(admin), Filter{Types: []models.ActivityType{"unknown"}})
+	assert.ErrorIs(t, err, ErrInvalidType)
+}
+
+func TestList_Pagination(t *testing.T) {
+	db, service := setupTestService(t)
+	customer := testutils.CreateTestUser(t, db, models.RoleUser)
+	lead := createLead(t, db, customer.ID, nil)
+
+	start := time.Date(2026, 5, 4, 9, 0, 0, 0, time.UTC)
+	for i := 0; i < 5; i++ {
+		activity := LeadUpdated{ActorID: customer.ID, LeadID: lead.ID, Fields: []string{"title"}}.Activity()
+		activity.CreatedAt = start.Add(time.Duration(i) * time.Minute)
+		require.NoError(t, db.Create(activity).Error)
+	}
+
+	activities, total, err := service.List(viewer(customer), Filter{Page: 2, Limit: 2})
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), total)
+	require.Len(t, activities, 2)
+	// Latest first
+	assert.Equal(t, start.Add(2*time.Minute), activities[0].CreatedAt.UTC())
+	assert.Equal(t, start.Add(time.Minute), activities[1].CreatedAt.UTC())
+
+	since := start.Add(3 * time.Minute)
+	_, total, err = service.List(viewer(customer), Filter{Since: &since})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), total)
+}
+
+func setupTestService(t *testing.T) (*gorm.DB, *Service) {
+	t.Helper()
+	db := testutils.NewSQLiteDB(t, &models.User{}, &models.Lead{}, &models.FamilyMember{}, &models.Activity{})
+
+	return db, NewService(db, zap.NewNop())
+}
+
+func viewer(user *models.User) scopes.Viewer {
+	return scopes.Viewer{ID: user.ID, Role: user.Role}
+}
+
+func createLead(t *testing.T, db *gorm.DB, userID uuid.UUID, beraterID *uuid.UUID) *models.Lead {
+	t.Helper()
+	lead := &models.Lead{
+		UserID:    userID,
+		BeraterID: beraterID,
+		Title:     "Elterngeld-Antrag",
+		Status:    models.LeadStatusNew,
+		Priority:  models.PriorityMedium,
+		Source:    models.LeadSourceWebsite,
+	}
+	require.NoError(t, db.Create(lead).Error)
+	return lead
+}
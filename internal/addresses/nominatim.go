@@ -0,0 +1,176 @@
+package addresses
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"elterngeld-portal/internal/models"
+)
+
+const nominatimAPIURL = "https://nominatim.openstreetmap.org"
+
+// publicInterval is the minimum time between requests to the public
+// Nominatim instance, as required by its usage policy
+const publicInterval = time.Second
+
+// Nominatim looks up addresses in OpenStreetMap. The public instance forbids
+// autocomplete, so suggestions need a self-hosted instance.
+type Nominatim struct {
+	baseURL   string
+	userAgent string
+	email     string
+	client    *http.Client
+	public    bool
+
+	mu   sync.Mutex
+	next time.Time // earliest time of the next request to the public instance
+}
+
+func NewNominatim(baseURL, userAgent, email string, client *http.Client) *Nominatim {
+	if baseURL == "" {
+		baseURL = nominatimAPIURL
+	}
+	baseURL = strings.TrimRight(baseURL, "/")
+
+	return &Nominatim{
+		baseURL:   baseURL,
+		userAgent: userAgent,
+		email:     email,
+		client:    client,
+		public:    baseURL == nominatimAPIURL,
+	}
+}
+
+func (n *Nominatim) Name() string {
+	return ProviderNominatim
+}
+
+func (n *Nominatim) Search(ctx context.Context, address Address) ([]Match, error) {
+	query := url.Values{}
+	query.Set("street", strings.TrimSpace(address.HouseNumber+" "+address.Street))
+	query.Set("postalcode", address.PostalCode)
+	query.Set("city", address.City)
+	return n.search(ctx, query, 1)
+}
+
+func (n *Nominatim) Suggest(ctx context.Context, query string, limit int) ([]Match, error) {
+	if n.public {
+		return nil, ErrAutocompleteUnavailable
+	}
+	return n.search(ctx, url.Values{"q": {query}}, limit)
+}
+
+// nominatimPlace is a search result with address details
+type nominatimPlace struct {
+	DisplayName string            `json:"display_name"`
+	Address     map[string]string `json:"address"`
+}
+
+func (n *Nominatim) search(ctx context.Context, query url.Values, limit int) ([]Match, error) {
+	query.Set("format", "jsonv2")
+	query.Set("addressdetails", "1")
+	query.Set("countrycodes", "de")
+	query.Set("limit", strconv.Itoa(limit))
+	if n.email != "" {
+		query.Set("email", n.email)
+	}
+
+	if err := n.wait(ctx); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, n.baseURL+"/search?"+query.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Accept-Language", "de")
+	req.Header.Set("User-Agent", n.userAgent)
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("nominatim request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("nominatim returned %d: %s", resp.StatusCode, strings.TrimSpace(string(detail)))
+	}
+
+	var places []nominatimPlace
+	if err := json.NewDecoder(resp.Body).Decode(&places); err != nil {
+		return nil, fmt.Errorf("failed to decode nominatim response: %w", err)
+	}
+
+	matches := make([]Match, 0, len(places))
+	for _, place := range places {
+		matches = append(matches, place.match())
+	}
+	return matches, nil
+}
+
+// wait spaces out requests to the public instance
+func (n *Nominatim) wait(ctx context.Context) error {
+	if !n.public {
+		return nil
+	}
+
+	n.mu.Lock()
+	now := time.Now()
+	at := n.next
+	if at.Before(now) {
+		at = now
+	}
+	n.next = at.Add(publicInterval)
+	n.mu.Unlock()
+
+	delay := time.Until(at)
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+func (p nominatimPlace) match() Match {
+	match := Match{
+		Address: Address{
+			Street:      firstOf(p.Address, "road", "pedestrian", "footway", "path", "square"),
+			HouseNumber: p.Address["house_number"],
+			PostalCode:  p.Address["postcode"],
+			City:        firstOf(p.Address, "city", "town", "village", "municipality"),
+			Country:     strings.ToUpper(p.Address["country_code"]),
+		},
+		Label: p.DisplayName,
+	}
+
+	// Subdivision codes look like DE-BY
+	if code := strings.TrimPrefix(p.Address["ISO3166-2-lvl4"], "DE-"); models.Bundesland(code).IsValid() {
+		match.Bundesland = models.Bundesland(code)
+	}
+	return match
+}
+
+func firstOf(values map[string]string, keys ...string) string {
+	for _, key := range keys {
+		if value := values[key]; value != "" {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,63 @@
+package addresses
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"elterngeld-portal/config"
+	"elterngeld-portal/internal/models"
+)
+
+// Supported address providers
+const (
+	ProviderNominatim = "nominatim"
+)
+
+// Address is a postal address as entered or as normalized by the provider
+type Address struct {
+	Street      string `json:"street"`
+	HouseNumber string `json:"house_number"`
+	PostalCode  string `json:"postal_code"`
+	City        string `json:"city"`
+	Country     string `json:"country"` // ISO 3166-1 alpha-2 code
+}
+
+// Match is an address known to the provider
+type Match struct {
+	Address
+	Bundesland models.Bundesland `json:"bundesland,omitempty"`
+	Label      string            `json:"label"`
+}
+
+// Provider looks up postal addresses
+type Provider interface {
+	Name() string
+	// Search looks up a complete address in Germany; no matches means the
+	// address does not exist
+	Search(ctx context.Context, address Address) ([]Match, error)
+	// Suggest returns addresses in Germany matching a partially typed query.
+	// It returns ErrAutocompleteUnavailable when the provider does not allow it.
+	Suggest(ctx context.Context, query string, limit int) ([]Match, error)
+}
+
+// NewProvider creates the client for the configured provider. It returns nil when
+// no provider is configured, which disables address validation.
+func NewProvider(cfg *config.Config) (Provider, error) {
+	provider := strings.ToLower(strings.TrimSpace(cfg.Address.Provider))
+	if provider == "" {
+		return nil, nil
+	}
+
+	client := &http.Client{Timeout: cfg.Address.Timeout}
+	switch provider {
+	case ProviderNominatim:
+		if cfg.Address.UserAgent == "" {
+			return nil, fmt.Errorf("ADDRESS_USER_AGENT is required for provider %q", provider)
+		}
+		return NewNominatim(cfg.Address.APIURL, cfg.Address.UserAgent, cfg.Address.Email, client), nil
+	default:
+		return nil, fmt.Errorf("unknown address provider %q", cfg.Address.Provider)
+	}
+}
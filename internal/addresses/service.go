@@ -0,0 +1,138 @@
+// Package addresses validates and completes postal addresses with an address
+// provider such as OpenStreetMap Nominatim. Validated addresses are normalized
+// and yield the Bundesland, which decides the responsible Elterngeldstelle.
+package addresses
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+
+	"elterngeld-portal/internal/models"
+
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrAutocompleteUnavailable is returned when no provider is configured or
+	// the provider does not allow autocomplete
+	ErrAutocompleteUnavailable = errors.New("address autocomplete is not available")
+	// ErrQueryTooShort is returned for autocomplete queries below MinQueryLength
+	ErrQueryTooShort = errors.New("address query is too short")
+)
+
+const (
+	// MinQueryLength is the number of characters from which suggestions are looked up
+	MinQueryLength = 3
+	// suggestionLimit is the number of suggestions returned per query
+	suggestionLimit = 5
+	// cacheSize is the number of validated addresses kept to avoid repeated lookups
+	cacheSize = 1000
+)
+
+// Result is the outcome of validating an address
+type Result struct {
+	Address    Address              `json:"address"` // normalized when verified, as entered otherwise
+	Bundesland models.Bundesland    `json:"bundesland,omitempty"`
+	Status     models.AddressStatus `json:"status"`
+}
+
+// Service validates addresses and suggests completions
+type Service struct {
+	logger   *zap.Logger
+	provider Provider
+
+	mu    sync.Mutex
+	cache map[string]*Result
+}
+
+func NewService(logger *zap.Logger, provider Provider) *Service {
+	return &Service{
+		logger:   logger,
+		provider: provider,
+		cache:    map[string]*Result{},
+	}
+}
+
+// Validate looks up an address. Addresses in Germany that the provider knows
+// are normalized and get their Bundesland; unknown ones are undeliverable.
+// Addresses abroad and addresses without a provider stay unchecked. Provider
+// failures are returned as error.
+func (s *Service) Validate(ctx context.Context, address Address) (*Result, error) {
+	address = Address{
+		Street:      strings.TrimSpace(address.Street),
+		HouseNumber: strings.TrimSpace(address.HouseNumber),
+		PostalCode:  strings.TrimSpace(address.PostalCode),
+		City:        strings.TrimSpace(address.City),
+		Country:     countryCode(address.Country),
+	}
+	if s.provider == nil || address.Country != "DE" {
+		return &Result{Address: address, Status: models.AddressStatusUnchecked}, nil
+	}
+
+	key := strings.ToLower(strings.Join([]string{address.Street, address.HouseNumber, address.PostalCode, address.City}, "|"))
+	s.mu.Lock()
+	cached, ok := s.cache[key]
+	s.mu.Unlock()
+	if ok {
+		result := *cached
+		return &result, nil
+	}
+
+	matches, err := s.provider.Search(ctx, address)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{Address: address, Status: models.AddressStatusUndeliverable}
+	if len(matches) > 0 {
+		match := matches[0]
+		result.Status = models.AddressStatusVerified
+		result.Bundesland = match.Bundesland
+		if match.Street != "" {
+			result.Address.Street = match.Street
+		}
+		if match.HouseNumber != "" {
+			result.Address.HouseNumber = match.HouseNumber
+		}
+		if match.PostalCode != "" {
+			result.Address.PostalCode = match.PostalCode
+		}
+		if match.City != "" {
+			result.Address.City = match.City
+		}
+	}
+
+	s.mu.Lock()
+	if len(s.cache) >= cacheSize {
+		s.cache = map[string]*Result{}
+	}
+	s.cache[key] = result
+	s.mu.Unlock()
+
+	copied := *result
+	return &copied, nil
+}
+
+// Suggest returns addresses in Germany matching a partially typed query
+func (s *Service) Suggest(ctx context.Context, query string) ([]Match, error) {
+	if s.provider == nil {
+		return nil, ErrAutocompleteUnavailable
+	}
+	query = strings.TrimSpace(query)
+	if len([]rune(query)) < MinQueryLength {
+		return nil, ErrQueryTooShort
+	}
+	return s.provider.Suggest(ctx, query, suggestionLimit)
+}
+
+// countryCode turns the country as entered into an ISO 3166-1 alpha-2 code;
+// addresses without a country are in Germany
+func countryCode(country string) string {
+	switch strings.ToLower(strings.TrimSpace(country)) {
+	case "", "de", "deu", "deutschland", "germany":
+		return "DE"
+	}
+	return strings.ToUpper(strings.TrimSpace(country))
+}
@@ -0,0 +1,126 @@
+package addresses
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"elterngeld-portal/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// fakeProvider knows a single address
+type fakeProvider struct {
+	searches int
+}
+
+func (p *fakeProvider) Name() string {
+	return "fake"
+}
+
+func (p *fakeProvider) Search(ctx context.Context, address Address) ([]Match, error) {
+	p.searches++
+	if address.PostalCode != "80331" {
+		return nil, nil
+	}
+	return []Match{{
+		Address:    Address{Street: "Marienplatz", HouseNumber: "8", PostalCode: "80331", City: "München", Country: "DE"},
+		Bundesland: models.BundeslandBayern,
+	}}, nil
+}
+
+func (p *fakeProvider) Suggest(ctx context.Context, query string, limit int) ([]Match, error) {
+	return []Match{{Label: "Marienplatz 8, 80331 München"}}, nil
+}
+
+func TestValidate(t *testing.T) {
+	provider := &fakeProvider{}
+	service := NewService(zap.NewNop(), provider)
+	ctx := context.Background()
+
+	result, err := service.Validate(ctx, Address{Street: "marienplatz ", HouseNumber: "8", PostalCode: "80331", City: "Muenchen", Country: "Deutschland"})
+	require.NoError(t, err)
+	assert.Equal(t, models.AddressStatusVerified, result.Status)
+	assert.Equal(t, models.BundeslandBayern, result.Bundesland)
+	assert.Equal(t, Address{Street: "Marienplatz", HouseNumber: "8", PostalCode: "80331", City: "München", Country: "DE"}, result.Address)
+
+	// Results are cached
+	_, err = service.Validate(ctx, Address{Street: "Marienplatz", HouseNumber: "8", PostalCode: "80331", City: "muenchen"})
+	require.NoError(t, err)
+	assert.Equal(t, 1, provider.searches)
+
+	result, err = service.Validate(ctx, Address{Street: "Gibtsnicht 1", PostalCode: "99999", City: "Nirgendwo"})
+	require.NoError(t, err)
+	assert.Equal(t, models.AddressStatusUndeliverable, result.Status)
+	assert.Empty(t, result.Bundesland)
+	assert.Equal(t, "99999", result.Address.PostalCode)
+
+	// Addresses abroad are not checked
+	result, err = service.Validate(ctx, Address{Street: "Mariahilfer Straße", HouseNumber: "1", PostalCode: "1060", City: "Wien", Country: "at"})
+	require.NoError(t, err)
+	assert.Equal(t, models.AddressStatusUnchecked, result.Status)
+	assert.Equal(t, "AT", result.Address.Country)
+	assert.Equal(t, 2, provider.searches)
+}
+
+func TestWithoutProvider(t *testing.T) {
+	service := NewService(zap.NewNop(), nil)
+
+	result, err := service.Validate(context.Background(), Address{Street: "Marienplatz", PostalCode: "80331", City: "München"})
+	require.NoError(t, err)
+	assert.Equal(t, models.AddressStatusUnchecked, result.Status)
+
+	_, err = service.Suggest(context.Background(), "Marienplatz")
+	assert.ErrorIs(t, err, ErrAutocompleteUnavailable)
+
+	service = NewService(zap.NewNop(), &fakeProvider{})
+	_, err = service.Suggest(context.Background(), " Ma ")
+	assert.ErrorIs(t, err, ErrQueryTooShort)
+}
+
+func TestNominatim(t *testing.T) {
+	var query map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = map[string]string{}
+		for key := range r.URL.Query() {
+			query[key] = r.URL.Query().Get(key)
+		}
+		assert.Equal(t, "elterngeld-portal-test", r.Header.Get("User-Agent"))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`[{
+			"display_name": "8, Marienplatz, Altstadt, München, Bayern, 80331, Deutschland",
+			"address": {
+				"house_number": "8",
+				"road": "Marienplatz",
+				"postcode": "80331",
+				"city": "München",
+				"state": "Bayern",
+				"ISO3166-2-lvl4": "DE-BY",
+				"country_code": "de"
+			}
+		}]`))
+	}))
+	defer server.Close()
+
+	provider := NewNominatim(server.URL+"/", "elterngeld-portal-test", "it@example.com", server.Client())
+	matches, err := provider.Search(context.Background(), Address{Street: "Marienplatz", HouseNumber: "8", PostalCode: "80331", City: "München"})
+	require.NoError(t, err)
+	require.Len(t, matches, 1)
+	assert.Equal(t, "8 Marienplatz", query["street"])
+	assert.Equal(t, "de", query["countrycodes"])
+	assert.Equal(t, "it@example.com", query["email"])
+	assert.Equal(t, models.BundeslandBayern, matches[0].Bundesland)
+	assert.Equal(t, Address{Street: "Marienplatz", HouseNumber: "8", PostalCode: "80331", City: "München", Country: "DE"}, matches[0].Address)
+
+	// Self-hosted instances allow autocomplete, the public one does not
+	_, err = provider.Suggest(context.Background(), "Marienpl", 5)
+	require.NoError(t, err)
+	assert.Equal(t, "Marienpl", query["q"])
+
+	_, err = NewNominatim("", "elterngeld-portal-test", "", server.Client()).Suggest(context.Background(), "Marienpl", 5)
+	assert.ErrorIs(t, err, ErrAutocompleteUnavailable)
+}
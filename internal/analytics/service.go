@@ -0,0 +1,326 @@
+package analytics
+
+import (
+	"errors"
+	"math"
+	"net/url"
+	"strings"
+	"time"
+
+	"elterngeld-portal/internal/models"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// MaxBatchSize limits the number of events per request
+const MaxBatchSize = 50
+
+var (
+	// ErrInvalidVisitorID is returned when no visitor ID is sent
+	ErrInvalidVisitorID = errors.New("invalid visitor id")
+	// ErrInvalidBatch is returned for empty or oversized batches
+	ErrInvalidBatch = errors.New("invalid event batch")
+	// ErrInvalidEventType is returned for events that are not collected
+	ErrInvalidEventType = errors.New("invalid event type")
+	// ErrInvalidCheckoutStep is returned for unknown checkout steps
+	ErrInvalidCheckoutStep = errors.New("invalid checkout step")
+)
+
+// FunnelStage is a step of the marketing funnel
+type FunnelStage string
+
+const (
+	StageVisit       FunnelStage = "visit"
+	StageCalculator  FunnelStage = "calculator"
+	StagePreTalk     FunnelStage = "pre_talk"
+	StagePaidBooking FunnelStage = "paid_booking"
+)
+
+// funnelStages are the funnel steps in order
+var funnelStages = []FunnelStage{StageVisit, StageCalculator, StagePreTalk, StagePaidBooking}
+
+var checkoutSteps = []string{
+	models.CheckoutStepStarted,
+	models.CheckoutStepDetails,
+	models.CheckoutStepPayment,
+	models.CheckoutStepCompleted,
+}
+
+// Service collects anonymous product analytics and builds funnel reports
+type Service struct {
+	db             *gorm.DB
+	logger         *zap.Logger
+	sessionTimeout time.Duration
+	now            func() time.Time
+}
+
+func NewService(db *gorm.DB, logger *zap.Logger, sessionTimeout time.Duration) *Service {
+	return &Service{
+		db:             db,
+		logger:         logger,
+		sessionTimeout: sessionTimeout,
+		now:            time.Now,
+	}
+}
+
+// EventInput is a tracked interaction sent by the frontend
+type EventInput struct {
+	Type models.AnalyticsEventType `json:"type" binding:"required"`
+	Path string                    `json:"path"`
+	Name string                    `json:"name"`
+	Step string                    `json:"step"`
+}
+
+// TrackInput is a batch of events of one visitor. Attribution is only used
+// when the batch starts a new session.
+type TrackInput struct {
+	VisitorID   uuid.UUID
+	Referrer    string
+	UTMSource   string
+	UTMMedium   string
+	UTMCampaign string
+	Events      []EventInput
+}
+
+// Track stores a batch of events in the current session of the visitor. A new
+// session starts after the session timeout without events.
+func (s *Service) Track(input TrackInput) (*models.AnalyticsSession, error) {
+	if input.VisitorID == uuid.Nil {
+		return nil, ErrInvalidVisitorID
+	}
+	if len(input.Events) == 0 || len(input.Events) > MaxBatchSize {
+		return nil, ErrInvalidBatch
+	}
+	for _, event := range input.Events {
+		if !event.Type.IsValid() {
+			return nil, ErrInvalidEventType
+		}
+		if event.Type == models.AnalyticsEventCheckoutStep && !isCheckoutStep(event.Step) {
+			return nil, ErrInvalidCheckoutStep
+		}
+	}
+
+	now := s.now()
+	var session models.AnalyticsSession
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("visitor_id = ? AND last_seen_at >= ?", input.VisitorID, now.Add(-s.sessionTimeout)).
+			Order("last_seen_at DESC").First(&session).Error
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			session = models.AnalyticsSession{
+				VisitorID:    input.VisitorID,
+				LandingPath:  cleanPath(input.Events[0].Path),
+				ReferrerHost: referrerHost(input.Referrer),
+				UTMSource:    truncate(input.UTMSource, 100),
+				UTMMedium:    truncate(input.UTMMedium, 100),
+				UTMCampaign:  truncate(input.UTMCampaign, 100),
+				EventCount:   len(input.Events),
+				StartedAt:    now,
+				LastSeenAt:   now,
+			}
+			if err := tx.Create(&session).Error; err != nil {
+				return err
+			}
+		case err != nil:
+			return err
+		default:
+			session.EventCount += len(input.Events)
+			session.LastSeenAt = now
+			if err := tx.Model(&session).Updates(map[string]interface{}{
+				"event_count":  gorm.Expr("event_count + ?", len(input.Events)),
+				"last_seen_at": now,
+			}).Error; err != nil {
+				return err
+			}
+		}
+
+		events := make([]models.AnalyticsEvent, len(input.Events))
+		for i, event := range input.Events {
+			events[i] = models.AnalyticsEvent{
+				SessionID:  session.ID,
+				VisitorID:  input.VisitorID,
+				Type:       event.Type,
+				Path:       cleanPath(event.Path),
+				Name:       truncate(event.Name, 100),
+				OccurredAt: now,
+			}
+			if event.Type == models.AnalyticsEventCheckoutStep {
+				events[i].Step = event.Step
+			}
+		}
+		return tx.Create(&events).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &session, nil
+}
+
+// FunnelFilter narrows a funnel report down to a period and campaign source
+type FunnelFilter struct {
+	From      time.Time
+	To        time.Time
+	UTMSource string
+}
+
+// FunnelStageReport counts the visitors that reached a stage and all before it
+type FunnelStageReport struct {
+	Stage    FunnelStage `json:"stage"`
+	Visitors int         `json:"visitors"`
+	// Conversion from the previous stage and from the first stage, in percent
+	StepConversion    float64 `json:"step_conversion"`
+	OverallConversion float64 `json:"overall_conversion"`
+}
+
+// CheckoutStepReport counts the visitors that reached a checkout step
+type CheckoutStepReport struct {
+	Step     string `json:"step"`
+	Visitors int    `json:"visitors"`
+}
+
+// FunnelReport is the conversion from visit to paid booking within a period
+type FunnelReport struct {
+	From          time.Time            `json:"from"`
+	To            time.Time            `json:"to"`
+	UTMSource     string               `json:"utm_source,omitempty"`
+	Sessions      int64                `json:"sessions"`
+	Stages        []FunnelStageReport  `json:"stages"`
+	CheckoutSteps []CheckoutStepReport `json:"checkout_steps"`
+}
+
+// Funnel reports how many visitors went from a visit to the calculator, a
+// pre-talk and a paid booking. Visitors count for a stage only if they also
+// reached all previous stages within the period, possibly in different sessions.
+func (s *Service) Funnel(filter FunnelFilter) (*FunnelReport, error) {
+	sessions := s.db.Model(&models.AnalyticsSession{}).Where("started_at >= ? AND started_at < ?", filter.From, filter.To)
+	if filter.UTMSource != "" {
+		sessions = sessions.Where("utm_source = ?", filter.UTMSource)
+	}
+
+	var sessionCount int64
+	if err := sessions.Session(&gorm.Session{}).Count(&sessionCount).Error; err != nil {
+		return nil, err
+	}
+
+	var rows []struct {
+		VisitorID uuid.UUID
+		Type      models.AnalyticsEventType
+		Step      string
+	}
+	query := s.db.Model(&models.AnalyticsEvent{}).
+		Select("visitor_id, type, step").
+		Where("occurred_at >= ? AND occurred_at < ?", filter.From, filter.To).
+		Group("visitor_id, type, step")
+	if filter.UTMSource != "" {
+		query = query.Where("visitor_id IN (?)", sessions.Session(&gorm.Session{}).Select("visitor_id"))
+	}
+	if err := query.Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	reached := make(map[uuid.UUID]map[FunnelStage]bool)
+	steps := make(map[string]map[uuid.UUID]bool)
+	for _, row := range rows {
+		if reached[row.VisitorID] == nil {
+			reached[row.VisitorID] = map[FunnelStage]bool{StageVisit: true}
+		}
+		switch row.Type {
+		case models.AnalyticsEventCalculatorUsed:
+			reached[row.VisitorID][StageCalculator] = true
+		case models.AnalyticsEventPreTalkBooked:
+			reached[row.VisitorID][StagePreTalk] = true
+		case models.AnalyticsEventCheckoutStep:
+			if steps[row.Step] == nil {
+				steps[row.Step] = make(map[uuid.UUID]bool)
+			}
+			steps[row.Step][row.VisitorID] = true
+			if row.Step == models.CheckoutStepCompleted {
+				reached[row.VisitorID][StagePaidBooking] = true
+			}
+		}
+	}
+
+	report := &FunnelReport{
+		From:      filter.From,
+		To:        filter.To,
+		UTMSource: filter.UTMSource,
+		Sessions:  sessionCount,
+	}
+	for i, stage := range funnelStages {
+		count := 0
+		for _, stages := range reached {
+			if reachedAll(stages, funnelStages[:i+1]) {
+				count++
+			}
+		}
+
+		stageReport := FunnelStageReport{Stage: stage, Visitors: count}
+		if i > 0 {
+			stageReport.StepConversion = percent(count, report.Stages[i-1].Visitors)
+			stageReport.OverallConversion = percent(count, report.Stages[0].Visitors)
+		} else if count > 0 {
+			stageReport.StepConversion = 100
+			stageReport.OverallConversion = 100
+		}
+		report.Stages = append(report.Stages, stageReport)
+	}
+	for _, step := range checkoutSteps {
+		report.CheckoutSteps = append(report.CheckoutSteps, CheckoutStepReport{Step: step, Visitors: len(steps[step])})
+	}
+
+	return report, nil
+}
+
+func reachedAll(reached map[FunnelStage]bool, stages []FunnelStage) bool {
+	for _, stage := range stages {
+		if !reached[stage] {
+			return false
+		}
+	}
+	return true
+}
+
+func percent(part, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return math.Round(float64(part)/float64(total)*1000) / 10
+}
+
+func isCheckoutStep(step string) bool {
+	for _, s := range checkoutSteps {
+		if s == step {
+			return true
+		}
+	}
+	return false
+}
+
+// cleanPath drops the query string and fragment, which may contain personal data
+func cleanPath(raw string) string {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return ""
+	}
+	return truncate(u.Path, 255)
+}
+
+// referrerHost keeps only the host of the referring page
+func referrerHost(raw string) string {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return ""
+	}
+	return truncate(strings.ToLower(u.Hostname()), 255)
+}
+
+func truncate(value string, max int) string {
+	value = strings.TrimSpace(value)
+	if len(value) > max {
+		return strings.ToValidUTF8(value[:max], "")
+	}
+	return value
+}
@@ -0,0 +1,182 @@
+package analytics
+
+import (
+	"testing"
+	"time"
+
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/tests/testutils"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+func TestTrackSessions(t *testing.T) {
+	db, service := setupTestService(t)
+	now := time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC)
+	service.now = func() time.Time { return now }
+	visitor := uuid.New()
+
+	first, err := service.Track(TrackInput{
+		VisitorID: visitor,
+		Referrer:  "https://www.Google.de/search?q=elterngeld",
+		UTMSource: "newsletter",
+		Events: []EventInput{
+			{Type: models.AnalyticsEventPageView, Path: "/rechner?email=max@example.com#top"},
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "/rechner", first.LandingPath)
+	assert.Equal(t, "www.google.de", first.ReferrerHost)
+	assert.Equal(t, "newsletter", first.UTMSource)
+
+	// Events within the timeout continue the session
+	now = now.Add(20 * time.Minute)
+	second, err := service.Track(TrackInput{
+		VisitorID: visitor,
+		UTMSource: "ads",
+		Events: []EventInput{
+			{Type: models.AnalyticsEventCalculatorUsed, Path: "/rechner", Name: "basis"},
+			{Type: models.AnalyticsEventCheckoutStep, Path: "/checkout", Step: models.CheckoutStepStarted},
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, first.ID, second.ID)
+	assert.Equal(t, 3, second.EventCount)
+	assert.Equal(t, "newsletter", second.UTMSource)
+
+	// A new session starts after the timeout
+	now = now.Add(31 * time.Minute)
+	third, err := service.Track(TrackInput{
+		VisitorID: visitor,
+		Events:    []EventInput{{Type: models.AnalyticsEventPageView, Path: "/"}},
+	})
+	require.NoError(t, err)
+	assert.NotEqual(t, first.ID, third.ID)
+
+	var sessions, events int64
+	db.Model(&models.AnalyticsSession{}).Where("visitor_id = ?", visitor).Count(&sessions)
+	db.Model(&models.AnalyticsEvent{}).Where("visitor_id = ?", visitor).Count(&events)
+	assert.Equal(t, int64(2), sessions)
+	assert.Equal(t, int64(4), events)
+
+	var stored models.AnalyticsSession
+	require.NoError(t, db.First(&stored, "id = ?", first.ID).Error)
+	assert.Equal(t, 3, stored.EventCount)
+}
+
+func TestTrackValidation(t *testing.T) {
+	_, service := setupTestService(t)
+	visitor := uuid.New()
+
+	tooMany := make([]EventInput, MaxBatchSize+1)
+	for i := range tooMany {
+		tooMany[i] = EventInput{Type: models.AnalyticsEventPageView}
+	}
+
+	tests := []struct {
+		name  string
+		input TrackInput
+		err   error
+	}{
+		{
+			name:  "missing visitor",
+			input: TrackInput{Events: []EventInput{{Type: models.AnalyticsEventPageView}}},
+			err:   ErrInvalidVisitorID,
+		},
+		{
+			name:  "empty batch",
+			input: TrackInput{VisitorID: visitor},
+			err:   ErrInvalidBatch,
+		},
+		{
+			name:  "oversized batch",
+			input: TrackInput{VisitorID: visitor, Events: tooMany},
+			err:   ErrInvalidBatch,
+		},
+		{
+			name:  "unknown event type",
+			input: TrackInput{VisitorID: visitor, Events: []EventInput{{Type: "click"}}},
+			err:   ErrInvalidEventType,
+		},
+		{
+			name:  "unknown checkout step",
+			input: TrackInput{VisitorID: visitor, Events: []EventInput{{Type: models.AnalyticsEventCheckoutStep, Step: "shipping"}}},
+			err:   ErrInvalidCheckoutStep,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := service.Track(tt.input)
+			assert.ErrorIs(t, err, tt.err)
+		})
+	}
+}
+
+func TestFunnel(t *testing.T) {
+	_, service := setupTestService(t)
+	now := time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC)
+	service.now = func() time.Time { return now }
+
+	track := func(source string, events ...EventInput) {
+		t.Helper()
+		_, err := service.Track(TrackInput{VisitorID: uuid.New(), UTMSource: source, Events: events})
+		require.NoError(t, err)
+	}
+	pageView := EventInput{Type: models.AnalyticsEventPageView, Path: "/"}
+	calculator := EventInput{Type: models.AnalyticsEventCalculatorUsed}
+	preTalk := EventInput{Type: models.AnalyticsEventPreTalkBooked}
+	checkout := func(step string) EventInput {
+		return EventInput{Type: models.AnalyticsEventCheckoutStep, Step: step}
+	}
+
+	track("ads", pageView)
+	track("ads", pageView, calculator)
+	track("ads", pageView, calculator, preTalk, checkout(models.CheckoutStepStarted))
+	track("newsletter", pageView, calculator, preTalk, checkout(models.CheckoutStepStarted), checkout(models.CheckoutStepCompleted))
+	// Skipped the pre-talk, so the paid booking does not count for the funnel
+	track("newsletter", pageView, checkout(models.CheckoutStepCompleted))
+
+	report, err := service.Funnel(FunnelFilter{From: now.Add(-time.Hour), To: now.Add(time.Hour)})
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), report.Sessions)
+	assert.Equal(t, []FunnelStageReport{
+		{Stage: StageVisit, Visitors: 5, StepConversion: 100, OverallConversion: 100},
+		{Stage: StageCalculator, Visitors: 3, StepConversion: 60, OverallConversion: 60},
+		{Stage: StagePreTalk, Visitors: 2, StepConversion: 66.7, OverallConversion: 40},
+		{Stage: StagePaidBooking, Visitors: 1, StepConversion: 50, OverallConversion: 20},
+	}, report.Stages)
+	assert.Equal(t, []CheckoutStepReport{
+		{Step: models.CheckoutStepStarted, Visitors: 2},
+		{Step: models.CheckoutStepDetails, Visitors: 0},
+		{Step: models.CheckoutStepPayment, Visitors: 0},
+		{Step: models.CheckoutStepCompleted, Visitors: 2},
+	}, report.CheckoutSteps)
+
+	t.Run("utm source", func(t *testing.T) {
+		report, err := service.Funnel(FunnelFilter{From: now.Add(-time.Hour), To: now.Add(time.Hour), UTMSource: "newsletter"})
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), report.Sessions)
+		assert.Equal(t, 2, report.Stages[0].Visitors)
+		assert.Equal(t, 1, report.Stages[3].Visitors)
+	})
+
+	t.Run("outside period", func(t *testing.T) {
+		report, err := service.Funnel(FunnelFilter{From: now.Add(time.Hour), To: now.Add(2 * time.Hour)})
+		require.NoError(t, err)
+		assert.Zero(t, report.Sessions)
+		assert.Zero(t, report.Stages[0].Visitors)
+		assert.Zero(t, report.Stages[0].StepConversion)
+	})
+}
+
+func setupTestService(t *testing.T) (*gorm.DB, *Service) {
+	t.Helper()
+	db := testutils.NewSQLiteDB(t, &models.AnalyticsSession{}, &models.AnalyticsEvent{})
+
+	return db, NewService(db, zap.NewNop(), 30*time.Minute)
+}
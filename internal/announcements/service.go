@@ -0,0 +1,387 @@
+// Package announcements broadcasts notices of the admins to all users, to
+// customers with upcoming bookings or to the Beraters. Announcements are
+// published at their scheduled time as in-app notifications and optionally
+// as emails through the email queue; for compliance notices the service
+// tracks who read them.
+package announcements
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"elterngeld-portal/internal/models"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// batchSize limits the rows created per insert when an announcement is published
+const batchSize = 500
+
+var (
+	// ErrNotFound is returned for unknown announcements and for announcements that were not published to the user
+	ErrNotFound = errors.New("announcement not found")
+	// ErrInvalidAudience is returned for unknown audiences
+	ErrInvalidAudience = errors.New("invalid audience")
+	// ErrAlreadyPublished is returned when a published announcement is changed or deleted
+	ErrAlreadyPublished = errors.New("announcement already published")
+)
+
+// Input holds the fields of an announcement. Without a publish time the
+// announcement is published right away.
+type Input struct {
+	Title      string                      `json:"title" binding:"required,max=200"`
+	Message    string                      `json:"message" binding:"required,max=10000"`
+	Audience   models.AnnouncementAudience `json:"audience" binding:"required"`
+	SendEmail  bool                        `json:"send_email"`
+	Compliance bool                        `json:"compliance"`
+	PublishAt  *time.Time                  `json:"publish_at"`
+}
+
+// Notice is an announcement as its recipient sees it
+type Notice struct {
+	ID          uuid.UUID  `json:"id"`
+	Title       string     `json:"title"`
+	Message     string     `json:"message"`
+	Compliance  bool       `json:"compliance"`
+	PublishedAt time.Time  `json:"published_at"`
+	ReadAt      *time.Time `json:"read_at"`
+}
+
+// Recipient is a user an announcement was published to
+type Recipient struct {
+	UserID uuid.UUID       `json:"user_id"`
+	Name   string          `json:"name"`
+	Email  string          `json:"email"`
+	Role   models.UserRole `json:"role"`
+	ReadAt *time.Time      `json:"read_at"`
+}
+
+// Report shows who read an announcement, e.g. as proof that a compliance
+// notice reached its audience
+type Report struct {
+	Announcement *models.Announcement `json:"announcement"`
+	Read         int                  `json:"read"`
+	Unread       int                  `json:"unread"`
+	Recipients   []Recipient          `json:"recipients"`
+}
+
+// Service manages announcements and publishes them in the background
+type Service struct {
+	db     *gorm.DB
+	logger *zap.Logger
+	now    func() time.Time
+}
+
+func NewService(db *gorm.DB, logger *zap.Logger) *Service {
+	return &Service{
+		db:     db,
+		logger: logger,
+		now:    time.Now,
+	}
+}
+
+// List returns all announcements, the latest first
+func (s *Service) List() ([]models.Announcement, error) {
+	var announcements []models.Announcement
+	if err := s.db.Order("publish_at DESC").Find(&announcements).Error; err != nil {
+		return nil, err
+	}
+	return announcements, nil
+}
+
+// Create schedules an announcement. Announcements due now are published by
+// the next run of the background job.
+func (s *Service) Create(input Input, createdByID uuid.UUID) (*models.Announcement, error) {
+	if !input.Audience.IsValid() {
+		return nil, ErrInvalidAudience
+	}
+
+	announcement := &models.Announcement{CreatedByID: createdByID}
+	s.apply(announcement, input)
+	if err := s.db.Create(announcement).Error; err != nil {
+		return nil, fmt.Errorf("failed to create announcement: %w", err)
+	}
+	return announcement, nil
+}
+
+// Update changes an announcement that was not published yet
+func (s *Service) Update(id uuid.UUID, input Input) (*models.Announcement, error) {
+	if !input.Audience.IsValid() {
+		return nil, ErrInvalidAudience
+	}
+	announcement, err := s.get(id)
+	if err != nil {
+		return nil, err
+	}
+	if announcement.IsPublished() {
+		return nil, ErrAlreadyPublished
+	}
+
+	s.apply(announcement, input)
+	// The publish job claims announcements by setting published_at, so an
+	// announcement published in the meantime is left alone
+	result := s.db.Model(announcement).Where("published_at IS NULL").
+		Select("title", "message", "audience", "send_email", "compliance", "publish_at").Updates(announcement)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to update announcement: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrAlreadyPublished
+	}
+	return announcement, nil
+}
+
+// Delete removes an announcement that was not published yet. Published
+// announcements are kept with their receipts.
+func (s *Service) Delete(id uuid.UUID) error {
+	result := s.db.Where("id = ? AND published_at IS NULL", id).Delete(&models.Announcement{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		if _, err := s.get(id); err != nil {
+			return err
+		}
+		return ErrAlreadyPublished
+	}
+	return nil
+}
+
+// Report returns the recipients of an announcement and whether they read it,
+// unread first
+func (s *Service) Report(id uuid.UUID) (*Report, error) {
+	announcement, err := s.get(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []struct {
+		UserID    uuid.UUID
+		FirstName string
+		LastName  string
+		Email     string
+		Role      models.UserRole
+		ReadAt    *time.Time
+	}
+	err = s.db.Table("announcement_receipts").
+		Select("announcement_receipts.user_id, users.first_name, users.last_name, users.email, users.role, announcement_receipts.read_at").
+		Joins("JOIN users ON users.id = announcement_receipts.user_id").
+		Where("announcement_receipts.announcement_id = ?", id).
+		Order("announcement_receipts.read_at IS NOT NULL, users.last_name, users.first_name").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to load receipts: %w", err)
+	}
+
+	report := &Report{Announcement: announcement, Recipients: make([]Recipient, 0, len(rows))}
+	for _, row := range rows {
+		report.Recipients = append(report.Recipients, Recipient{
+			UserID: row.UserID,
+			Name:   strings.TrimSpace(row.FirstName + " " + row.LastName),
+			Email:  row.Email,
+			Role:   row.Role,
+			ReadAt: row.ReadAt,
+		})
+		if row.ReadAt != nil {
+			report.Read++
+		} else {
+			report.Unread++
+		}
+	}
+	return report, nil
+}
+
+// ForUser returns the announcements published to a user, the latest first
+func (s *Service) ForUser(userID uuid.UUID, unreadOnly bool) ([]Notice, error) {
+	query := s.db.Table("announcement_receipts").
+		Select("announcements.id, announcements.title, announcements.message, announcements.compliance, announcements.published_at, announcement_receipts.read_at").
+		Joins("JOIN announcements ON announcements.id = announcement_receipts.announcement_id AND announcements.deleted_at IS NULL").
+		Where("announcement_receipts.user_id = ?", userID)
+	if unreadOnly {
+		query = query.Where("announcement_receipts.read_at IS NULL")
+	}
+
+	notices := []Notice{}
+	if err := query.Order("announcements.published_at DESC").Scan(&notices).Error; err != nil {
+		return nil, err
+	}
+	return notices, nil
+}
+
+// MarkRead records that a user read an announcement, together with its
+// in-app notification. Reading it again keeps the first time.
+func (s *Service) MarkRead(id, userID uuid.UUID) (*Notice, error) {
+	now := s.now()
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var receipt models.AnnouncementReceipt
+		if err := tx.Where("announcement_id = ? AND user_id = ?", id, userID).First(&receipt).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrNotFound
+			}
+			return err
+		}
+		if receipt.ReadAt != nil {
+			return nil
+		}
+		if err := tx.Model(&receipt).Update("read_at", now).Error; err != nil {
+			return fmt.Errorf("failed to mark announcement read: %w", err)
+		}
+		return tx.Model(&models.Notification{}).
+			Where("user_id = ? AND type = ? AND data = ? AND read_at IS NULL", userID, models.NotificationTypeInApp, notificationData(id)).
+			Update("read_at", now).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	notices, err := s.ForUser(userID, false)
+	if err != nil {
+		return nil, err
+	}
+	for i := range notices {
+		if notices[i].ID == id {
+			return &notices[i], nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+// PublishDue publishes the announcements whose publish time has come and
+// returns the number of published announcements
+func (s *Service) PublishDue() (int, error) {
+	var due []models.Announcement
+	if err := s.db.Where("published_at IS NULL AND publish_at <= ?", s.now()).
+		Order("publish_at ASC").Find(&due).Error; err != nil {
+		return 0, fmt.Errorf("failed to load due announcements: %w", err)
+	}
+
+	published := 0
+	for i := range due {
+		if err := s.publish(&due[i]); err != nil {
+			s.logger.Error("Failed to publish announcement", zap.String("announcement_id", due[i].ID.String()), zap.Error(err))
+			continue
+		}
+		published++
+	}
+	return published, nil
+}
+
+// publish sends an announcement to its audience in one transaction, so a
+// failed run leaves it unpublished and it is sent again by the next run.
+// Setting published_at claims it, so instances running at the same time do
+// not send it twice.
+func (s *Service) publish(announcement *models.Announcement) error {
+	now := s.now()
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		claim := tx.Model(announcement).Where("published_at IS NULL").Update("published_at", now)
+		if claim.Error != nil {
+			return claim.Error
+		}
+		if claim.RowsAffected == 0 {
+			return nil
+		}
+
+		var users []models.User
+		if err := s.audience(tx, announcement.Audience, now).Select("id", "email").Find(&users).Error; err != nil {
+			return fmt.Errorf("failed to load audience: %w", err)
+		}
+
+		data := notificationData(announcement.ID)
+		receipts := make([]models.AnnouncementReceipt, 0, len(users))
+		notifications := make([]models.Notification, 0, len(users))
+		for _, user := range users {
+			receipts = append(receipts, models.AnnouncementReceipt{AnnouncementID: announcement.ID, UserID: user.ID})
+			notifications = append(notifications, models.Notification{
+				UserID:    user.ID,
+				Type:      models.NotificationTypeInApp,
+				Status:    models.NotificationStatusDelivered,
+				Title:     announcement.Title,
+				Message:   announcement.Message,
+				Data:      data,
+				Recipient: user.NotificationAddress(),
+			})
+			if announcement.SendEmail {
+				notifications = append(notifications, models.Notification{
+					UserID:    user.ID,
+					Type:      models.NotificationTypeEmail,
+					Status:    models.NotificationStatusPending,
+					Title:     announcement.Title,
+					Message:   announcement.Message,
+					Data:      data,
+					Template:  string(models.EmailTemplateAnnouncement),
+					Recipient: user.NotificationAddress(),
+				})
+			}
+		}
+		if len(users) > 0 {
+			if err := tx.CreateInBatches(&receipts, batchSize).Error; err != nil {
+				return fmt.Errorf("failed to create receipts: %w", err)
+			}
+			if err := tx.CreateInBatches(&notifications, batchSize).Error; err != nil {
+				return fmt.Errorf("failed to create notifications: %w", err)
+			}
+		}
+		if err := tx.Model(announcement).Update("recipients", len(users)).Error; err != nil {
+			return err
+		}
+
+		announcement.PublishedAt = &now
+		announcement.Recipients = len(users)
+		s.logger.Info("Announcement published",
+			zap.String("announcement_id", announcement.ID.String()),
+			zap.String("audience", string(announcement.Audience)),
+			zap.Int("recipients", len(users)),
+			zap.Bool("email", announcement.SendEmail))
+		return nil
+	})
+}
+
+// audience selects the active users of an audience
+func (s *Service) audience(tx *gorm.DB, audience models.AnnouncementAudience, now time.Time) *gorm.DB {
+	query := tx.Model(&models.User{}).Where("is_active = ?", true)
+	switch audience {
+	case models.AnnouncementAudienceCustomers:
+		return query.Where("role = ?", models.RoleUser).
+			Where(`EXISTS (SELECT 1 FROM bookings WHERE bookings.user_id = users.id AND bookings.deleted_at IS NULL
+				AND bookings.status IN ? AND bookings.scheduled_at >= ?)`,
+				[]models.BookingStatus{models.BookingStatusPending, models.BookingStatusConfirmed}, now)
+	case models.AnnouncementAudienceBeraters:
+		return query.Where("role IN ?", []models.UserRole{models.RoleBerater, models.RoleJuniorBerater})
+	default:
+		return query
+	}
+}
+
+func (s *Service) apply(announcement *models.Announcement, input Input) {
+	announcement.Title = strings.TrimSpace(input.Title)
+	announcement.Message = strings.TrimSpace(input.Message)
+	announcement.Audience = input.Audience
+	announcement.SendEmail = input.SendEmail
+	announcement.Compliance = input.Compliance
+	announcement.PublishAt = s.now()
+	if input.PublishAt != nil && input.PublishAt.After(announcement.PublishAt) {
+		announcement.PublishAt = *input.PublishAt
+	}
+}
+
+func (s *Service) get(id uuid.UUID) (*models.Announcement, error) {
+	var announcement models.Announcement
+	if err := s.db.First(&announcement, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &announcement, nil
+}
+
+// notificationData links the notifications of an announcement to it
+func notificationData(id uuid.UUID) string {
+	data, _ := json.Marshal(map[string]interface{}{"announcement_id": id})
+	return string(data)
+}
@@ -0,0 +1,155 @@
+package announcements
+
+import (
+	"testing"
+	"time"
+
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/tests/testutils"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+func TestPublishDue_Audiences(t *testing.T) {
+	service, db := setupTestService(t)
+	now := time.Date(2026, 5, 4, 10, 0, 0, 0, time.UTC)
+	service.now = func() time.Time { return now }
+
+	admin := createTestUser(t, db, models.RoleAdmin, true)
+	berater := createTestUser(t, db, models.RoleBerater, true)
+	junior := createTestUser(t, db, models.RoleJuniorBerater, true)
+	booked := createTestUser(t, db, models.RoleUser, true)
+	past := createTestUser(t, db, models.RoleUser, true)
+	inactive := createTestUser(t, db, models.RoleUser, false)
+	createTestBooking(t, db, booked, models.BookingStatusConfirmed, now.Add(48*time.Hour))
+	createTestBooking(t, db, past, models.BookingStatusCompleted, now.Add(-48*time.Hour))
+	createTestBooking(t, db, inactive, models.BookingStatusConfirmed, now.Add(48*time.Hour))
+
+	_, err := service.Create(Input{Title: "x", Message: "x", Audience: "partners"}, admin.ID)
+	assert.ErrorIs(t, err, ErrInvalidAudience)
+
+	everyone, err := service.Create(Input{Title: "Neue Öffnungszeiten", Message: "Ab Juni ...", Audience: models.AnnouncementAudienceAll}, admin.ID)
+	require.NoError(t, err)
+	customers, err := service.Create(Input{Title: "Neue AGB", Message: "Bitte lesen", Audience: models.AnnouncementAudienceCustomers, SendEmail: true, Compliance: true}, admin.ID)
+	require.NoError(t, err)
+	later := now.Add(24 * time.Hour)
+	beraters, err := service.Create(Input{Title: "Teammeeting", Message: "Freitag", Audience: models.AnnouncementAudienceBeraters, PublishAt: &later}, admin.ID)
+	require.NoError(t, err)
+
+	published, err := service.PublishDue()
+	require.NoError(t, err)
+	assert.Equal(t, 2, published)
+
+	require.NoError(t, db.First(everyone, "id = ?", everyone.ID).Error)
+	assert.True(t, everyone.IsPublished())
+	assert.Equal(t, 5, everyone.Recipients)
+	require.NoError(t, db.First(customers, "id = ?", customers.ID).Error)
+	assert.Equal(t, 1, customers.Recipients)
+
+	var emails []models.Notification
+	require.NoError(t, db.Where("type = ?", models.NotificationTypeEmail).Find(&emails).Error)
+	require.Len(t, emails, 1)
+	assert.Equal(t, booked.ID, emails[0].UserID)
+	assert.Equal(t, string(models.EmailTemplateAnnouncement), emails[0].Template)
+	assert.Equal(t, models.NotificationStatusPending, emails[0].Status)
+
+	// Publishing again does not send anything twice
+	published, err = service.PublishDue()
+	require.NoError(t, err)
+	assert.Equal(t, 0, published)
+
+	_, err = service.Update(everyone.ID, Input{Title: "x", Message: "x", Audience: models.AnnouncementAudienceAll})
+	assert.ErrorIs(t, err, ErrAlreadyPublished)
+	assert.ErrorIs(t, service.Delete(everyone.ID), ErrAlreadyPublished)
+
+	// Scheduled announcements wait for their publish time
+	updated, err := service.Update(beraters.ID, Input{Title: "Teammeeting", Message: "Donnerstag", Audience: models.AnnouncementAudienceBeraters, PublishAt: &later})
+	require.NoError(t, err)
+	assert.Equal(t, "Donnerstag", updated.Message)
+	now = later
+	published, err = service.PublishDue()
+	require.NoError(t, err)
+	assert.Equal(t, 1, published)
+	report, err := service.Report(beraters.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, report.Unread)
+	ids := []uuid.UUID{report.Recipients[0].UserID, report.Recipients[1].UserID}
+	assert.ElementsMatch(t, []uuid.UUID{berater.ID, junior.ID}, ids)
+}
+
+func TestMarkRead(t *testing.T) {
+	service, db := setupTestService(t)
+	admin := createTestUser(t, db, models.RoleAdmin, true)
+	customer := createTestUser(t, db, models.RoleUser, true)
+	createTestBooking(t, db, customer, models.BookingStatusPending, time.Now().Add(time.Hour))
+	other := createTestUser(t, db, models.RoleUser, true)
+
+	announcement, err := service.Create(Input{Title: "Neue AGB", Message: "Bitte lesen", Audience: models.AnnouncementAudienceCustomers, Compliance: true}, admin.ID)
+	require.NoError(t, err)
+	_, err = service.PublishDue()
+	require.NoError(t, err)
+
+	notices, err := service.ForUser(customer.ID, true)
+	require.NoError(t, err)
+	require.Len(t, notices, 1)
+	assert.True(t, notices[0].Compliance)
+	assert.Nil(t, notices[0].ReadAt)
+
+	_, err = service.MarkRead(announcement.ID, other.ID)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	notice, err := service.MarkRead(announcement.ID, customer.ID)
+	require.NoError(t, err)
+	require.NotNil(t, notice.ReadAt)
+	first := *notice.ReadAt
+
+	notice, err = service.MarkRead(announcement.ID, customer.ID)
+	require.NoError(t, err)
+	assert.True(t, first.Equal(*notice.ReadAt))
+
+	notices, err = service.ForUser(customer.ID, true)
+	require.NoError(t, err)
+	assert.Empty(t, notices)
+
+	var notification models.Notification
+	require.NoError(t, db.Where("user_id = ? AND type = ?", customer.ID, models.NotificationTypeInApp).First(&notification).Error)
+	assert.NotNil(t, notification.ReadAt)
+
+	report, err := service.Report(announcement.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, report.Read)
+	assert.Equal(t, 0, report.Unread)
+}
+
+func setupTestService(t *testing.T) (*Service, *gorm.DB) {
+	t.Helper()
+	db := testutils.NewSQLiteDB(t,
+		&models.User{},
+		&models.Booking{},
+		&models.Notification{},
+		&models.Announcement{},
+		&models.AnnouncementReceipt{},
+	)
+
+	return NewService(db, zap.NewNop()), db
+}
+
+func createTestUser(t *testing.T, db *gorm.DB, role models.UserRole, active bool) *models.User {
+	t.Helper()
+	user := testutils.CreateTestUser(t, db, role)
+	if !active {
+		require.NoError(t, db.Model(user).Update("is_active", false).Error)
+		user.IsActive = false
+	}
+	return user
+}
+
+func createTestBooking(t *testing.T, db *gorm.DB, user *models.User, status models.BookingStatus, at time.Time) {
+	t.Helper()
+	booking := &models.Booking{UserID: user.ID, Title: "Beratung", Status: status, ScheduledAt: at, Duration: 60}
+	require.NoError(t, db.Create(booking).Error)
+}
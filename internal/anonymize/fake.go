@@ -0,0 +1,310 @@
+package anonymize
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// kind is the kind of personal data in a column; it decides what the
+// replacement looks like
+type kind int
+
+const (
+	kindFirstName kind = iota
+	kindLastName
+	kindFullName
+	kindEmail
+	kindPhone
+	kindStreet
+	kindBirthDate
+	kindText
+	kindFileName
+	kindURL
+	kindIP
+	kindReference
+	// kindJSON anonymizes the values of a JSON document by their keys
+	kindJSON
+)
+
+var kindNames = map[kind]string{
+	kindFirstName: "first_name",
+	kindLastName:  "last_name",
+	kindFullName:  "full_name",
+	kindEmail:     "email",
+	kindPhone:     "phone",
+	kindStreet:    "street",
+	kindBirthDate: "birth_date",
+	kindText:      "text",
+	kindFileName:  "file_name",
+	kindURL:       "url",
+	kindIP:        "ip",
+	kindReference: "reference",
+	kindJSON:      "json",
+}
+
+var firstNames = []string{
+	"Anna", "Ben", "Clara", "David", "Emma", "Felix", "Greta", "Hannah", "Jonas", "Julia",
+	"Karl", "Lea", "Leon", "Lina", "Lukas", "Marie", "Max", "Mia", "Noah", "Paul",
+	"Sophie", "Tim", "Laura", "Elias", "Emily", "Finn", "Ida", "Jakob", "Lara", "Moritz",
+	"Nele", "Oskar", "Paula", "Theo", "Zoe", "Anton", "Frieda", "Henry", "Mila", "Levi",
+}
+
+var lastNames = []string{
+	"Müller", "Schmidt", "Schneider", "Fischer", "Weber", "Meyer", "Wagner", "Becker", "Schulz", "Hoffmann",
+	"Schäfer", "Koch", "Bauer", "Richter", "Klein", "Wolf", "Schröder", "Neumann", "Schwarz", "Zimmermann",
+	"Braun", "Krüger", "Hofmann", "Hartmann", "Lange", "Schmitt", "Werner", "Krause", "Meier", "Lehmann",
+	"Schmid", "Schulze", "Maier", "Köhler", "Herrmann", "König", "Walter", "Mayer", "Huber", "Kaiser",
+}
+
+var streets = []string{
+	"Hauptstraße", "Schulstraße", "Gartenstraße", "Bahnhofstraße", "Dorfstraße", "Bergstraße", "Birkenweg",
+	"Lindenstraße", "Kirchstraße", "Waldstraße", "Ringstraße", "Schillerstraße", "Goethestraße", "Am Markt",
+	"Wiesenweg", "Rosenweg", "Mühlenweg", "Feldstraße", "Parkstraße", "Eichenweg",
+}
+
+var words = []string{
+	"Antrag", "Elterngeld", "Unterlagen", "Rückfrage", "Termin", "Beratung", "Nachweis", "Bescheid",
+	"Einkommen", "Monate", "Partnerschaftsbonus", "Geburtsurkunde", "Arbeitgeber", "Bescheinigung",
+	"Elternzeit", "Basiselterngeld", "ElterngeldPlus", "bitte", "noch", "fehlt", "erhalten", "geprüft",
+	"eingereicht", "nachreichen", "vereinbart", "telefonisch", "schriftlich", "kurzfristig", "vollständig",
+	"und", "der", "die", "das", "mit", "für", "zum", "vom", "am", "nach",
+}
+
+// faker derives the replacements from a keyed hash of the original value,
+// so a value is replaced the same way wherever and whenever it occurs
+type faker struct {
+	secret []byte
+}
+
+// stream is a deterministic source of numbers for one value
+type stream struct {
+	f       *faker
+	seed    string
+	block   []byte
+	counter uint32
+}
+
+func (f *faker) stream(k kind, value string) *stream {
+	return &stream{f: f, seed: kindNames[k] + "\x00" + value}
+}
+
+func (s *stream) next() uint32 {
+	if len(s.block) < 4 {
+		mac := hmac.New(sha256.New, s.f.secret)
+		mac.Write(binary.BigEndian.AppendUint32(nil, s.counter))
+		mac.Write([]byte(s.seed))
+		s.block = mac.Sum(nil)
+		s.counter++
+	}
+	n := binary.BigEndian.Uint32(s.block)
+	s.block = s.block[4:]
+	return n
+}
+
+func (s *stream) intn(n int) int {
+	return int(s.next() % uint32(n))
+}
+
+func (s *stream) pick(list []string) string {
+	return list[s.intn(len(list))]
+}
+
+func (s *stream) hex(n int) string {
+	var b []byte
+	for len(b) < n {
+		b = binary.BigEndian.AppendUint32(b, s.next())
+	}
+	return hex.EncodeToString(b)[:n]
+}
+
+// replace returns the replacement of a non-empty value
+func (f *faker) replace(k kind, value string) string {
+	switch k {
+	case kindFirstName:
+		return f.stream(k, normalize(value)).pick(firstNames)
+	case kindLastName:
+		return f.stream(k, normalize(value)).pick(lastNames)
+	case kindFullName:
+		// Names are replaced part by part, so a full name matches the first and
+		// last name stored separately elsewhere
+		parts := strings.Fields(value)
+		if len(parts) == 1 {
+			return f.replace(kindFirstName, parts[0])
+		}
+		return f.replace(kindFirstName, parts[0]) + " " + f.replace(kindLastName, parts[len(parts)-1])
+	case kindEmail:
+		s := f.stream(k, normalize(value))
+		local := strings.ToLower(s.pick(firstNames) + "." + s.pick(lastNames))
+		local = strings.NewReplacer("ä", "ae", "ö", "oe", "ü", "ue", "ß", "ss").Replace(local)
+		return local + "." + s.hex(8) + "@example.com"
+	case kindPhone:
+		return f.digits(k, value, 4)
+	case kindStreet:
+		// Postal code and city after the street are kept for regional reports
+		s := f.stream(k, normalize(value))
+		street := fmt.Sprintf("%s %d", s.pick(streets), s.intn(120)+1)
+		if i := strings.Index(value, ","); i >= 0 {
+			street += value[i:]
+		}
+		return street
+	case kindBirthDate:
+		return f.birthDate(value)
+	case kindText:
+		return f.text(value)
+	case kindFileName:
+		return "dokument-" + f.stream(k, value).hex(8) + strings.ToLower(filepath.Ext(value))
+	case kindURL:
+		return "https://example.com/" + f.stream(k, value).hex(12)
+	case kindIP:
+		return fmt.Sprintf("192.0.2.%d", f.stream(k, value).intn(254)+1)
+	case kindReference:
+		return f.digits(k, value, 0)
+	case kindJSON:
+		return f.json(value)
+	default:
+		return value
+	}
+}
+
+// digits replaces the letters and digits of a value after the first keep
+// characters, keeping its format
+func (f *faker) digits(k kind, value string, keep int) string {
+	s := f.stream(k, normalize(value))
+	var b strings.Builder
+	for i, r := range []rune(value) {
+		switch {
+		case i < keep:
+			b.WriteRune(r)
+		case unicode.IsDigit(r):
+			b.WriteByte(byte('0' + s.intn(10)))
+		case unicode.IsUpper(r):
+			b.WriteByte(byte('A' + s.intn(26)))
+		case unicode.IsLower(r):
+			b.WriteByte(byte('a' + s.intn(26)))
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// birthDate moves a date of birth to another day of the same year, which
+// keeps ages realistic. Dates of birth are stored as JSON timestamps.
+func (f *faker) birthDate(value string) string {
+	var date time.Time
+	if err := json.Unmarshal([]byte(value), &date); err != nil {
+		parsed, err := time.Parse("2006-01-02", value[:min(len(value), 10)])
+		if err != nil {
+			return ""
+		}
+		date = parsed
+	}
+	day := f.stream(kindBirthDate, value).intn(365)
+	replaced := time.Date(date.Year(), 1, 1, 0, 0, 0, 0, time.UTC).AddDate(0, 0, day)
+	encoded, _ := json.Marshal(replaced)
+	return string(encoded)
+}
+
+// text replaces free text by filler words of about the same length
+func (f *faker) text(value string) string {
+	s := f.stream(kindText, value)
+	length := utf8.RuneCountInString(value)
+	var b strings.Builder
+	for written := 0; written < length; {
+		if written > 0 {
+			b.WriteByte(' ')
+			written++
+		}
+		word := s.pick(words)
+		b.WriteString(word)
+		written += utf8.RuneCountInString(word)
+	}
+	text := []rune(b.String())
+	if len(text) > length {
+		text = text[:length]
+	}
+	return strings.TrimSpace(string(text))
+}
+
+// jsonKinds map the keys of JSON documents to the kind of their values. A
+// key matches when it contains the hint, like "customer_email" or "FromName".
+var jsonKinds = []struct {
+	hint string
+	kind kind
+}{
+	{"email", kindEmail},
+	{"recipient", kindEmail},
+	{"phone", kindPhone},
+	{"first_name", kindFirstName},
+	{"firstname", kindFirstName},
+	{"last_name", kindLastName},
+	{"lastname", kindLastName},
+	{"name", kindFullName},
+	{"address", kindStreet},
+	{"street", kindStreet},
+	{"ip_address", kindIP},
+	{"ipaddress", kindIP},
+	{"note", kindText},
+	{"message", kindText},
+	{"body", kindText},
+	{"comment", kindText},
+	{"description", kindText},
+	{"text", kindText},
+	{"value", kindText},
+	{"answer", kindText},
+}
+
+// json replaces the string values of a JSON document whose keys suggest
+// personal data. Documents that do not parse are replaced as text.
+func (f *faker) json(value string) string {
+	var doc interface{}
+	if err := json.Unmarshal([]byte(value), &doc); err != nil {
+		return f.text(value)
+	}
+	encoded, err := json.Marshal(f.walk(doc, ""))
+	if err != nil {
+		return "{}"
+	}
+	return string(encoded)
+}
+
+func (f *faker) walk(v interface{}, key string) interface{} {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		for k, child := range value {
+			value[k] = f.walk(child, k)
+		}
+		return value
+	case []interface{}:
+		for i, child := range value {
+			value[i] = f.walk(child, key)
+		}
+		return value
+	case string:
+		if value == "" {
+			return value
+		}
+		lower := strings.ToLower(key)
+		for _, hint := range jsonKinds {
+			if strings.Contains(lower, hint.hint) {
+				return f.replace(hint.kind, value)
+			}
+		}
+		return value
+	default:
+		return value
+	}
+}
+
+func normalize(value string) string {
+	return strings.ToLower(strings.TrimSpace(value))
+}
@@ -0,0 +1,223 @@
+package anonymize
+
+import "elterngeld-portal/internal/models"
+
+// rule lists the fields of a model that hold personal data. Fields are named
+// as in the model and resolved to their columns through the GORM schema.
+type rule struct {
+	model  interface{}
+	fields map[string]kind
+}
+
+// rules cover every model with personal data. New fields with names, contact
+// details, addresses or free text must be added here.
+var rules = []rule{
+	{&models.User{}, map[string]kind{
+		"Email":              kindEmail,
+		"NotificationEmail":  kindEmail,
+		"FirstName":          kindFirstName,
+		"LastName":           kindLastName,
+		"Phone":              kindPhone,
+		"DateOfBirth":        kindBirthDate,
+		"Address":            kindStreet,
+		"Profession":         kindText,
+		"EmailSignature":     kindText,
+		"Bio":                kindText,
+		"PhotoURL":           kindURL,
+		"CalendarURL":        kindURL,
+		"MeetingURL":         kindURL,
+		"DeactivationReason": kindText,
+	}},
+	{&models.BeraterInvitation{}, map[string]kind{
+		"Email":     kindEmail,
+		"FirstName": kindFirstName,
+		"LastName":  kindLastName,
+	}},
+	{&models.Lead{}, map[string]kind{
+		"Description":        kindText,
+		"ChildName":          kindFirstName,
+		"ChildBirthDate":     kindBirthDate,
+		"NextFollowUpNote":   kindText,
+		"QualificationNotes": kindText,
+		"InternalNotes":      kindText,
+		"LostReason":         kindText,
+	}},
+	{&models.Reminder{}, map[string]kind{
+		"Description": kindText,
+	}},
+	{&models.Comment{}, map[string]kind{
+		"Content": kindText,
+	}},
+	{&models.CommentRevision{}, map[string]kind{
+		"Content": kindText,
+	}},
+	{&models.EmailThread{}, map[string]kind{
+		"Subject": kindText,
+	}},
+	{&models.EmailMessage{}, map[string]kind{
+		"FromEmail": kindEmail,
+		"ToEmail":   kindEmail,
+		"Subject":   kindText,
+		"Body":      kindText,
+	}},
+	{&models.Booking{}, map[string]kind{
+		"CustomerName":     kindFullName,
+		"CustomerEmail":    kindEmail,
+		"CustomerPhone":    kindPhone,
+		"CustomerAddress":  kindStreet,
+		"CustomerNotes":    kindText,
+		"MeetingLink":      kindURL,
+		"InternalNotes":    kindText,
+		"CancellationNote": kindText,
+		"RatingComment":    kindText,
+		"CompanyName":      kindText,
+		"VATID":            kindReference,
+		"BillingAddress":   kindStreet,
+	}},
+	{&models.Todo{}, map[string]kind{
+		"Description": kindText,
+	}},
+	{&models.ConsultationSummaryItem{}, map[string]kind{
+		"Text": kindText,
+	}},
+	{&models.Payment{}, map[string]kind{
+		"BillingName":    kindFullName,
+		"BillingEmail":   kindEmail,
+		"BillingAddress": kindStreet,
+		"CustomerVATID":  kindReference,
+		"ReceiptURL":     kindURL,
+	}},
+	{&models.RefundRequest{}, map[string]kind{
+		"Reason":       kindText,
+		"DecisionNote": kindText,
+	}},
+	{&models.PaymentLink{}, map[string]kind{
+		"Description": kindText,
+	}},
+	{&models.Document{}, map[string]kind{
+		"OriginalName": kindFileName,
+		"Description":  kindText,
+		"OCRText":      kindText,
+	}},
+	{&models.Family{}, map[string]kind{
+		"Name": kindLastName,
+	}},
+	{&models.FamilyMember{}, map[string]kind{
+		"FirstName": kindFirstName,
+		"LastName":  kindLastName,
+		"Email":     kindEmail,
+	}},
+	{&models.FamilyChild{}, map[string]kind{
+		"FirstName": kindFirstName,
+		"BirthDate": kindBirthDate,
+	}},
+	{&models.ApplicationSubmission{}, map[string]kind{
+		"OfficeReference": kindReference,
+	}},
+	{&models.QuestionnaireResponse{}, map[string]kind{
+		"Answers":      kindJSON,
+		"ScoreReasons": kindText,
+	}},
+	{&models.Activity{}, map[string]kind{
+		"Description": kindText,
+		"Metadata":    kindJSON,
+		"IPAddress":   kindIP,
+	}},
+	{&models.InboundEmail{}, map[string]kind{
+		"FromEmail": kindEmail,
+		"FromName":  kindFullName,
+		"Subject":   kindText,
+		"Body":      kindText,
+	}},
+	{&models.EmailAttachment{}, map[string]kind{
+		"OriginalName": kindFileName,
+	}},
+	{&models.ContactForm{}, map[string]kind{
+		"Name":      kindFullName,
+		"Email":     kindEmail,
+		"Phone":     kindPhone,
+		"Company":   kindText,
+		"Subject":   kindText,
+		"Message":   kindText,
+		"IPAddress": kindIP,
+	}},
+	{&models.Notification{}, map[string]kind{
+		"Message":       kindText,
+		"Data":          kindJSON,
+		"TemplateData":  kindJSON,
+		"Recipient":     kindEmail,
+		"CCRecipients":  kindText,
+		"BCCRecipients": kindText,
+	}},
+	{&models.EmailVerification{}, map[string]kind{
+		"Email": kindEmail,
+	}},
+	{&models.PasswordReset{}, map[string]kind{
+		"Email": kindEmail,
+	}},
+	{&models.NewsletterContact{}, map[string]kind{
+		"Email": kindEmail,
+	}},
+	{&models.SubmissionCheck{}, map[string]kind{
+		"Email":     kindEmail,
+		"IPAddress": kindIP,
+	}},
+	{&models.ShortLink{}, map[string]kind{
+		"RecipientEmail": kindEmail,
+	}},
+	{&models.ShortLinkClick{}, map[string]kind{
+		"IPAddress": kindIP,
+	}},
+	{&models.ServiceKey{}, map[string]kind{
+		"LastUsedIP": kindIP,
+	}},
+	{&models.UserPermission{}, map[string]kind{
+		"Reason": kindText,
+	}},
+	{&models.WhatsAppConsent{}, map[string]kind{
+		"Phone": kindPhone,
+	}},
+	{&models.WhatsAppMessage{}, map[string]kind{
+		"Phone": kindPhone,
+		"Body":  kindText,
+	}},
+	{&models.WebhookEvent{}, map[string]kind{
+		"Payload": kindJSON,
+	}},
+	{&models.PDFJob{}, map[string]kind{
+		"Data": kindJSON,
+	}},
+	{&models.JobApplication{}, map[string]kind{
+		"FirstName":      kindFirstName,
+		"LastName":       kindLastName,
+		"Email":          kindEmail,
+		"Phone":          kindPhone,
+		"CoverLetter":    kindText,
+		"ResumeURL":      kindURL,
+		"PortfolioURL":   kindURL,
+		"LinkedInURL":    kindURL,
+		"GitHubURL":      kindURL,
+		"WebsiteURL":     kindURL,
+		"CurrentCompany": kindText,
+		"MotivationText": kindText,
+		"Questions":      kindJSON,
+		"ReferralName":   kindFullName,
+		"ReviewNotes":    kindText,
+		"RejectionNote":  kindText,
+	}},
+	{&models.JobApplicationDocument{}, map[string]kind{
+		"FileName": kindFileName,
+	}},
+	{&models.JobApplicationActivity{}, map[string]kind{
+		"Description": kindText,
+		"Details":     kindJSON,
+		"OldValue":    kindText,
+		"NewValue":    kindText,
+	}},
+	{&models.ApplicationEvaluation{}, map[string]kind{
+		"Notes": kindText,
+	}},
+	{&models.ApplicationRating{}, map[string]kind{
+		"Comment": kindText,
+	}},
+}
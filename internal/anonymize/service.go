@@ -0,0 +1,279 @@
+// Package anonymize replaces the personal data of a database copy, so a
+// production dump restored into staging can be used without exposing
+// customers, applicants or staff.
+//
+// Names, email addresses, phone numbers, street addresses, dates of birth,
+// IP addresses and free text are replaced by realistic fakes derived from a
+// keyed hash of the original value. The same value is replaced the same way
+// in every table and on every refresh, so records still match up: a booking
+// keeps the email address of its customer account. Without the secret the
+// fakes cannot be traced back to the originals.
+//
+// The anonymizer refuses to run in production. Document files are not part of
+// the database and are not anonymized; staging refreshes restore the
+// database only.
+package anonymize
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"elterngeld-portal/config"
+	"elterngeld-portal/pkg/encryption"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// DefaultBatchSize is the number of rows anonymized per transaction
+const DefaultBatchSize = 500
+
+var (
+	// ErrProduction is returned when anonymizing in the production environment
+	ErrProduction = errors.New("anonymizing is not allowed in production")
+	// ErrNoSecret is returned when no anonymization secret is configured
+	ErrNoSecret = errors.New("anonymization secret not configured")
+)
+
+// Result counts the anonymized data
+type Result struct {
+	Tables int   `json:"tables"`
+	Rows   int64 `json:"rows"`
+	Values int64 `json:"values"`
+}
+
+// Service anonymizes the configured database
+type Service struct {
+	db         *gorm.DB
+	logger     *zap.Logger
+	production bool
+	faker      *faker
+	batchSize  int
+}
+
+func NewService(db *gorm.DB, logger *zap.Logger, cfg *config.Config) *Service {
+	return &Service{
+		db:         db,
+		logger:     logger,
+		production: cfg.IsProduction(),
+		faker:      &faker{secret: []byte(cfg.Anonymize.Secret)},
+		batchSize:  DefaultBatchSize,
+	}
+}
+
+// column is a column with personal data
+type column struct {
+	name      string
+	kind      kind
+	encrypted bool
+}
+
+// table is a table with personal data and its columns
+type table struct {
+	name       string
+	primaryKey string
+	columns    []column
+}
+
+// Run anonymizes every table with personal data. Tables and columns missing
+// from the database, like those of features added after the dump was taken,
+// are skipped.
+func (s *Service) Run(ctx context.Context) (*Result, error) {
+	if s.production {
+		return nil, ErrProduction
+	}
+	if len(s.faker.secret) == 0 {
+		return nil, ErrNoSecret
+	}
+
+	tables, err := s.tables()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{}
+	for _, t := range tables {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+		rows, values, err := s.anonymizeTable(ctx, t)
+		if err != nil {
+			return result, fmt.Errorf("failed to anonymize %s: %w", t.name, err)
+		}
+		result.Tables++
+		result.Rows += rows
+		result.Values += values
+		s.logger.Info("Table anonymized", zap.String("table", t.name), zap.Int64("rows", rows), zap.Int64("values", values))
+	}
+	return result, nil
+}
+
+// tables resolves the rules to the tables and columns of the database
+func (s *Service) tables() ([]table, error) {
+	migrator := s.db.Migrator()
+	var tables []table
+	for _, r := range rules {
+		stmt := &gorm.Statement{DB: s.db}
+		if err := stmt.Parse(r.model); err != nil {
+			return nil, err
+		}
+		t, err := resolve(stmt.Schema, r)
+		if err != nil {
+			return nil, err
+		}
+		if !migrator.HasTable(t.name) {
+			s.logger.Debug("Skipping missing table", zap.String("table", t.name))
+			continue
+		}
+
+		present := t.columns[:0]
+		for _, c := range t.columns {
+			if migrator.HasColumn(t.name, c.name) {
+				present = append(present, c)
+			}
+		}
+		t.columns = present
+		if len(t.columns) > 0 {
+			tables = append(tables, t)
+		}
+	}
+	return tables, nil
+}
+
+func resolve(sch *schema.Schema, r rule) (table, error) {
+	if sch.PrioritizedPrimaryField == nil {
+		return table{}, fmt.Errorf("%s has no primary key", sch.Name)
+	}
+	t := table{name: sch.Table, primaryKey: sch.PrioritizedPrimaryField.DBName}
+	for name, k := range r.fields {
+		field := sch.LookUpField(name)
+		if field == nil || field.DBName == "" {
+			return table{}, fmt.Errorf("%s has no field %s", sch.Name, name)
+		}
+		t.columns = append(t.columns, column{
+			name:      field.DBName,
+			kind:      k,
+			encrypted: field.TagSettings["SERIALIZER"] == encryption.SerializerName,
+		})
+	}
+	return t, nil
+}
+
+// anonymizeTable replaces the personal data of a table in batches ordered by
+// primary key, including soft-deleted rows
+func (s *Service) anonymizeTable(ctx context.Context, t table) (int64, int64, error) {
+	selected := []string{t.primaryKey}
+	for _, c := range t.columns {
+		selected = append(selected, c.name)
+	}
+
+	var rowCount, valueCount int64
+	var after interface{}
+	for {
+		query := s.db.WithContext(ctx).Table(t.name).Select(selected).Order(t.primaryKey).Limit(s.batchSize)
+		if after != nil {
+			query = query.Where(t.primaryKey+" > ?", after)
+		}
+		rows, err := query.Rows()
+		if err != nil {
+			return rowCount, valueCount, err
+		}
+
+		type update struct {
+			key    interface{}
+			values map[string]interface{}
+		}
+		var updates []update
+		scanned := 0
+		for rows.Next() {
+			var key interface{}
+			values := make([]sql.NullString, len(t.columns))
+			dest := []interface{}{&key}
+			for i := range values {
+				dest = append(dest, &values[i])
+			}
+			if err := rows.Scan(dest...); err != nil {
+				rows.Close()
+				return rowCount, valueCount, err
+			}
+			scanned++
+			after = key
+
+			changed := map[string]interface{}{}
+			for i, c := range t.columns {
+				if !values[i].Valid || values[i].String == "" {
+					continue
+				}
+				replaced, err := s.replace(c, values[i].String)
+				if err != nil {
+					rows.Close()
+					return rowCount, valueCount, fmt.Errorf("column %s: %w", c.name, err)
+				}
+				changed[c.name] = replaced
+			}
+			if len(changed) > 0 {
+				updates = append(updates, update{key: key, values: changed})
+			}
+		}
+		if err := rows.Close(); err != nil {
+			return rowCount, valueCount, err
+		}
+
+		err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			for _, u := range updates {
+				if err := tx.Table(t.name).Where(t.primaryKey+" = ?", u.key).UpdateColumns(u.values).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return rowCount, valueCount, err
+		}
+		for _, u := range updates {
+			rowCount++
+			valueCount += int64(len(u.values))
+		}
+
+		if scanned < s.batchSize {
+			return rowCount, valueCount, nil
+		}
+	}
+}
+
+// replace returns the replacement of a stored value. Encrypted values are
+// decrypted first, so they are replaced like their plaintext elsewhere, and
+// encrypted again with the keyring of this environment. Dates of birth in
+// plain date columns are written as dates rather than JSON.
+func (s *Service) replace(c column, stored string) (interface{}, error) {
+	keyring := encryption.Default()
+	plaintext := stored
+	if c.encrypted && encryption.IsEncrypted(stored) && keyring != nil {
+		decrypted, err := keyring.Decrypt(stored)
+		if err != nil {
+			return "", err
+		}
+		plaintext = string(decrypted)
+	}
+
+	replaced := s.faker.replace(c.kind, plaintext)
+	if c.encrypted && keyring != nil && replaced != "" {
+		return keyring.Encrypt([]byte(replaced))
+	}
+	if c.kind == kindBirthDate && !c.encrypted {
+		if replaced == "" {
+			return nil, nil
+		}
+		var date time.Time
+		if err := json.Unmarshal([]byte(replaced), &date); err != nil {
+			return nil, err
+		}
+		return date, nil
+	}
+	return replaced, nil
+}
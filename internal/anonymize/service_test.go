@@ -0,0 +1,176 @@
+package anonymize
+
+import (
+	"context"
+	"crypto/rand"
+	"strings"
+	"testing"
+	"time"
+
+	"elterngeld-portal/config"
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/pkg/encryption"
+	"elterngeld-portal/tests/testutils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+func TestRules(t *testing.T) {
+	db, _ := setupTestService(t, "development")
+
+	// Every rule names fields that exist, so renamed fields are not missed
+	for _, r := range rules {
+		stmt := &gorm.Statement{DB: db}
+		require.NoError(t, stmt.Parse(r.model))
+		_, err := resolve(stmt.Schema, r)
+		assert.NoError(t, err)
+	}
+}
+
+func TestRun(t *testing.T) {
+	db, service := setupTestService(t, "development")
+	setTestKeyring(t)
+	service.batchSize = 2
+
+	birth := time.Date(1991, 8, 14, 0, 0, 0, 0, time.UTC)
+	user := models.User{
+		Email:       "anna.schmidt@web.de",
+		Password:    "secret",
+		FirstName:   "Anna",
+		LastName:    "Schmidt",
+		Phone:       "+49 170 1234567",
+		Role:        models.RoleUser,
+		DateOfBirth: &birth,
+		Address:     "Kastanienallee 7, 10435 Berlin",
+		City:        "Berlin",
+	}
+	require.NoError(t, db.Create(&user).Error)
+	for _, email := range []string{"b@example.org", "c@example.org"} {
+		require.NoError(t, db.Create(&models.User{Email: email, Password: "secret", FirstName: "Jan", LastName: "Roth", Role: models.RoleUser}).Error)
+	}
+	booking := models.Booking{
+		UserID:        user.ID,
+		CustomerName:  "Anna Schmidt",
+		CustomerEmail: "Anna.Schmidt@web.de",
+		CustomerNotes: "Zwillinge, Geburt am 3. Mai, Arbeitgeber Siemens",
+	}
+	require.NoError(t, db.Create(&booking).Error)
+	contact := models.ContactForm{
+		Name:      "Anna Schmidt",
+		Email:     "anna.schmidt@web.de",
+		Message:   "Bitte rufen Sie mich an",
+		IPAddress: "203.0.113.9",
+	}
+	require.NoError(t, db.Create(&contact).Error)
+	childBirth := time.Date(2025, 3, 2, 0, 0, 0, 0, time.UTC)
+	lead := models.Lead{
+		UserID:         user.ID,
+		Title:          "Elterngeld Plus",
+		Status:         models.LeadStatusCancelled,
+		ChildBirthDate: &childBirth,
+		LostReason:     "Hat sich nach der Trennung vom Vater für die Elternzeit bei Bosch entschieden",
+	}
+	require.NoError(t, db.Create(&lead).Error)
+	family := models.Family{Name: "Familie Schmidt", CreatedBy: user.ID}
+	require.NoError(t, db.Create(&family).Error)
+	child := models.FamilyChild{FamilyID: family.ID, FirstName: "Lena", BirthDate: &childBirth}
+	require.NoError(t, db.Create(&child).Error)
+
+	result, err := service.Run(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int64(8), result.Rows)
+
+	var anonymized models.User
+	require.NoError(t, db.First(&anonymized, "id = ?", user.ID).Error)
+	assert.NotEqual(t, "Anna", anonymized.FirstName)
+	assert.True(t, strings.HasSuffix(anonymized.Email, "@example.com"), anonymized.Email)
+	assert.NotContains(t, anonymized.Phone, "1234567")
+	assert.True(t, strings.HasPrefix(anonymized.Phone, "+49 "))
+	assert.NotContains(t, anonymized.Address, "Kastanienallee")
+	assert.True(t, strings.HasSuffix(anonymized.Address, ", 10435 Berlin"))
+	assert.Equal(t, "Berlin", anonymized.City)
+	require.NotNil(t, anonymized.DateOfBirth)
+	assert.Equal(t, 1991, anonymized.DateOfBirth.Year())
+
+	// Values are replaced the same way in every table
+	var anonymizedBooking models.Booking
+	require.NoError(t, db.First(&anonymizedBooking, "id = ?", booking.ID).Error)
+	assert.Equal(t, anonymized.Email, anonymizedBooking.CustomerEmail)
+	assert.Equal(t, anonymized.FirstName+" "+anonymized.LastName, anonymizedBooking.CustomerName)
+	assert.NotContains(t, anonymizedBooking.CustomerNotes, "Siemens")
+	assert.Empty(t, anonymizedBooking.InternalNotes)
+
+	var anonymizedContact models.ContactForm
+	require.NoError(t, db.First(&anonymizedContact, "id = ?", contact.ID).Error)
+	assert.Equal(t, anonymizedBooking.CustomerName, anonymizedContact.Name)
+	assert.True(t, strings.HasPrefix(anonymizedContact.IPAddress, "192.0.2."))
+
+	var anonymizedLead models.Lead
+	require.NoError(t, db.First(&anonymizedLead, "id = ?", lead.ID).Error)
+	assert.NotContains(t, anonymizedLead.LostReason, "Bosch")
+	require.NotNil(t, anonymizedLead.ChildBirthDate)
+	assert.Equal(t, 2025, anonymizedLead.ChildBirthDate.Year())
+
+	var anonymizedChild models.FamilyChild
+	require.NoError(t, db.First(&anonymizedChild, "id = ?", child.ID).Error)
+	assert.NotEqual(t, "Lena", anonymizedChild.FirstName)
+	require.NotNil(t, anonymizedChild.BirthDate)
+	assert.Equal(t, 2025, anonymizedChild.BirthDate.Year())
+	assert.Equal(t, anonymizedLead.ChildBirthDate.Unix(), anonymizedChild.BirthDate.Unix())
+
+	// Encrypted columns stay encrypted
+	var stored string
+	require.NoError(t, db.Raw("SELECT address FROM users WHERE id = ?", user.ID).Scan(&stored).Error)
+	assert.True(t, encryption.IsEncrypted(stored))
+
+	// A refresh with the same secret gives the same data
+	other := &faker{secret: service.faker.secret}
+	assert.Equal(t, anonymized.Email, other.replace(kindEmail, "anna.schmidt@web.de"))
+	assert.NotEqual(t, anonymized.Email, (&faker{secret: []byte("other")}).replace(kindEmail, "anna.schmidt@web.de"))
+}
+
+func TestRun_Refused(t *testing.T) {
+	_, service := setupTestService(t, "production")
+	_, err := service.Run(context.Background())
+	assert.ErrorIs(t, err, ErrProduction)
+
+	_, service = setupTestService(t, "staging")
+	service.faker.secret = nil
+	_, err = service.Run(context.Background())
+	assert.ErrorIs(t, err, ErrNoSecret)
+}
+
+func TestReplaceJSON(t *testing.T) {
+	f := &faker{secret: []byte("staging")}
+	replaced := f.replace(kindJSON, `{"customer_email":"anna@web.de","amount":4900,"items":[{"description":"Beratung zu Zwillingen"}],"type":"checkout.session.completed"}`)
+	assert.NotContains(t, replaced, "anna@web.de")
+	assert.NotContains(t, replaced, "Zwillingen")
+	assert.Contains(t, replaced, `"amount":4900`)
+	assert.Contains(t, replaced, "checkout.session.completed")
+}
+
+func setTestKeyring(t *testing.T) {
+	t.Helper()
+	key := make([]byte, 32)
+	_, err := rand.Read(key)
+	require.NoError(t, err)
+	keyring, err := encryption.NewKeyring(map[string][]byte{"staging": key}, "staging")
+	require.NoError(t, err)
+	encryption.SetDefault(keyring)
+	t.Cleanup(func() { encryption.SetDefault(nil) })
+}
+
+func setupTestService(t *testing.T, env string) (*gorm.DB, *Service) {
+	t.Helper()
+	db := testutils.NewSQLiteDB(t, &models.User{}, &models.Booking{}, &models.ContactForm{},
+		&models.Lead{}, &models.Family{}, &models.FamilyChild{})
+
+	cfg := &config.Config{
+		Server:    config.ServerConfig{Env: env},
+		Anonymize: config.AnonymizeConfig{Secret: "staging-secret"},
+	}
+	return db, NewService(db, zap.NewNop(), cfg)
+}
@@ -0,0 +1,490 @@
+// Package applicants manages job applicants: applications submitted on the
+// website, which applicants confirm and track with a signed link, the
+// application list HR works through, the talent pool applicants join with their
+// explicit consent and the GDPR retention of rejected applications, which are
+// anonymized after the retention period.
+package applicants
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"elterngeld-portal/config"
+	"elterngeld-portal/internal/models"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	// DefaultLimit is the page size of the application lists
+	DefaultLimit = 20
+	// MaxLimit is the largest page size of the application lists
+	MaxLimit = 100
+)
+
+// anonymizedName replaces the first name of anonymized applicants
+const anonymizedName = "Anonymisiert"
+
+var (
+	// ErrApplicationNotFound is returned for unknown applications
+	ErrApplicationNotFound = errors.New("application not found")
+	// ErrAnonymized is returned for applications whose personal data was already removed
+	ErrAnonymized = errors.New("application has been anonymized")
+	// ErrConsentRequired is returned when joining the talent pool without the consent wording
+	ErrConsentRequired = errors.New("talent pool requires the consent of the applicant")
+	// ErrInvalidSort is returned for unknown sort fields or orders
+	ErrInvalidSort = errors.New("invalid sort")
+	// ErrDirectApplyDisabled is returned when applying to a job that only takes applications elsewhere
+	ErrDirectApplyDisabled = errors.New("job does not accept direct applications")
+	// ErrInvalidToken is returned for tracking links with a wrong signature or of anonymized applications
+	ErrInvalidToken = errors.New("invalid application token")
+)
+
+// sortColumns are the fields the application list can be sorted by
+var sortColumns = map[string]string{
+	"created_at": "created_at",
+	"score":      "score",
+	"last_name":  "last_name",
+}
+
+// Filter narrows down the application list. Applications without a score are
+// listed last when sorting by score and left out when filtering by it.
+type Filter struct {
+	JobID      *uuid.UUID
+	Status     models.ApplicationStatus
+	MinScore   *float64
+	MaxScore   *float64
+	Unverified bool   // also list applications whose email address was not confirmed
+	SortBy     string // created_at, score or last_name; created_at by default
+	SortOrder  string // asc or desc; desc by default
+}
+
+// ApplicationInput is an application submitted on the website
+type ApplicationInput struct {
+	FirstName         string `json:"first_name" binding:"required,max=100"`
+	LastName          string `json:"last_name" binding:"required,max=100"`
+	Email             string `json:"email" binding:"required,email,max=255"`
+	Phone             string `json:"phone" binding:"max=50"`
+	Location          string `json:"location" binding:"max=100"`
+	CoverLetter       string `json:"cover_letter" binding:"max=10000"`
+	PortfolioURL      string `json:"portfolio_url" binding:"omitempty,url,max=500"`
+	LinkedInURL       string `json:"linkedin_url" binding:"omitempty,url,max=500"`
+	MotivationText    string `json:"motivation_text" binding:"max=10000"`
+	PrivacyConsent    bool   `json:"privacy_consent" binding:"required"`
+	NewsletterConsent bool   `json:"newsletter_consent"`
+	UtmSource         string `json:"utm_source" binding:"max=100"`
+	UtmMedium         string `json:"utm_medium" binding:"max=100"`
+	UtmCampaign       string `json:"utm_campaign" binding:"max=100"`
+}
+
+// Tracking is what applicants see about their application on the status page
+type Tracking struct {
+	JobTitle      string                   `json:"job_title"`
+	Status        models.ApplicationStatus `json:"status"`
+	EmailVerified bool                     `json:"email_verified"`
+	InterviewDate *time.Time               `json:"interview_date,omitempty"`
+	SubmittedAt   time.Time                `json:"submitted_at"`
+	UpdatedAt     time.Time                `json:"updated_at"`
+}
+
+// ConsentInput records how an applicant agreed to join the talent pool
+type ConsentInput struct {
+	ConsentText string `json:"consent_text" binding:"required,max=2000"` // wording the applicant agreed to
+	Source      string `json:"source" binding:"required,oneof=application_form email phone"`
+}
+
+// Service manages applications, the talent pool and the retention of applications
+type Service struct {
+	db     *gorm.DB
+	logger *zap.Logger
+	config config.RecruitingConfig
+	secret []byte
+	now    func() time.Time
+}
+
+func NewService(db *gorm.DB, logger *zap.Logger, cfg *config.Config) *Service {
+	secret := cfg.Recruiting.SigningSecret
+	if secret == "" {
+		secret = cfg.JWT.Secret
+	}
+
+	return &Service{
+		db:     db,
+		logger: logger,
+		config: cfg.Recruiting,
+		secret: []byte(secret),
+		now:    time.Now,
+	}
+}
+
+// Submit stores an application to an open job. It returns the application and
+// the signed token of the links applicants confirm their email address and
+// track the status with. The application is listed for HR once the email
+// address is confirmed.
+func (s *Service) Submit(job *models.Job, input ApplicationInput) (*models.JobApplication, string, error) {
+	if !job.AllowDirectApply {
+		return nil, "", ErrDirectApplyDisabled
+	}
+
+	now := s.now()
+	application := &models.JobApplication{
+		JobID:             job.ID,
+		FirstName:         strings.TrimSpace(input.FirstName),
+		LastName:          strings.TrimSpace(input.LastName),
+		Email:             strings.ToLower(strings.TrimSpace(input.Email)),
+		Phone:             strings.TrimSpace(input.Phone),
+		Location:          strings.TrimSpace(input.Location),
+		Status:            models.ApplicationStatusSubmitted,
+		CoverLetter:       strings.TrimSpace(input.CoverLetter),
+		PortfolioURL:      input.PortfolioURL,
+		LinkedInURL:       input.LinkedInURL,
+		MotivationText:    strings.TrimSpace(input.MotivationText),
+		PrivacyConsent:    input.PrivacyConsent,
+		NewsletterConsent: input.NewsletterConsent,
+		Source:            "website",
+		UtmSource:         input.UtmSource,
+		UtmMedium:         input.UtmMedium,
+		UtmCampaign:       input.UtmCampaign,
+		CreatedAt:         now,
+		UpdatedAt:         now,
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(application).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Job{}).Where("id = ?", job.ID).
+			Update("application_count", gorm.Expr("application_count + 1")).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.JobApplicationActivity{
+			ApplicationID: application.ID,
+			Type:          "submitted",
+			Description:   "Bewerbung über die Website eingegangen",
+			CreatedAt:     now,
+		}).Error
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to submit application: %w", err)
+	}
+
+	application.Job = *job
+	return application, s.token(application), nil
+}
+
+// VerifyEmail confirms the email address of the application the token belongs
+// to. Confirming it again has no effect.
+func (s *Service) VerifyEmail(token string) (*models.JobApplication, error) {
+	application, err := s.findByToken(token)
+	if err != nil {
+		return nil, err
+	}
+	if application.EmailVerifiedAt != nil {
+		return application, nil
+	}
+
+	now := s.now()
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(application).Update("email_verified_at", &now).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.JobApplicationActivity{
+			ApplicationID: application.ID,
+			Type:          "email_verified",
+			Description:   "E-Mail-Adresse bestätigt",
+			CreatedAt:     now,
+		}).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify application email: %w", err)
+	}
+
+	application.EmailVerifiedAt = &now
+	return application, nil
+}
+
+// Track returns the status of the application the token belongs to
+func (s *Service) Track(token string) (*Tracking, error) {
+	application, err := s.findByToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	var job models.Job
+	if err := s.db.Unscoped().Select("title").First(&job, "id = ?", application.JobID).Error; err != nil {
+		return nil, fmt.Errorf("failed to load job: %w", err)
+	}
+
+	return &Tracking{
+		JobTitle:      job.Title,
+		Status:        application.Status,
+		EmailVerified: application.EmailVerifiedAt != nil,
+		InterviewDate: application.InterviewDate,
+		SubmittedAt:   application.CreatedAt,
+		UpdatedAt:     application.UpdatedAt,
+	}, nil
+}
+
+// AddToTalentPool adds an applicant to the talent pool with their consent.
+// Applications in the talent pool are kept after a rejection.
+func (s *Service) AddToTalentPool(applicationID uuid.UUID, input ConsentInput, recordedBy uuid.UUID) (*models.JobApplication, error) {
+	consentText := strings.TrimSpace(input.ConsentText)
+	if consentText == "" {
+		return nil, ErrConsentRequired
+	}
+
+	application, err := s.find(applicationID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(application).Updates(map[string]interface{}{
+			"talent_pool":                true,
+			"talent_pool_consent_at":     &now,
+			"talent_pool_consent_text":   consentText,
+			"talent_pool_consent_source": input.Source,
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.JobApplicationActivity{
+			ApplicationID: application.ID,
+			UserID:        &recordedBy,
+			Type:          "talent_pool_added",
+			Description:   "In den Talentpool aufgenommen",
+			NewValue:      input.Source,
+			CreatedAt:     now,
+		}).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to add application to talent pool: %w", err)
+	}
+
+	application.TalentPool = true
+	application.TalentPoolConsentAt = &now
+	application.TalentPoolConsentText = consentText
+	application.TalentPoolConsentSource = input.Source
+	return application, nil
+}
+
+// RemoveFromTalentPool removes an applicant from the talent pool, e.g. because
+// they withdrew their consent. A rejected application then falls under the
+// retention period again.
+func (s *Service) RemoveFromTalentPool(applicationID uuid.UUID, removedBy uuid.UUID) (*models.JobApplication, error) {
+	application, err := s.find(applicationID)
+	if err != nil {
+		return nil, err
+	}
+	if !application.TalentPool {
+		return application, nil
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(application).Updates(map[string]interface{}{
+			"talent_pool":                false,
+			"talent_pool_consent_at":     nil,
+			"talent_pool_consent_text":   "",
+			"talent_pool_consent_source": "",
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.JobApplicationActivity{
+			ApplicationID: application.ID,
+			UserID:        &removedBy,
+			Type:          "talent_pool_removed",
+			Description:   "Aus dem Talentpool entfernt",
+			CreatedAt:     s.now(),
+		}).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to remove application from talent pool: %w", err)
+	}
+
+	application.TalentPool = false
+	application.TalentPoolConsentAt = nil
+	application.TalentPoolConsentText = ""
+	application.TalentPoolConsentSource = ""
+	return application, nil
+}
+
+// List returns a page of the applications that were not anonymized. Unless
+// the filter asks for them, applications with an unconfirmed email address are
+// left out.
+func (s *Service) List(filter Filter, page, limit int) ([]models.JobApplication, int64, error) {
+	column, ok := sortColumns[filter.SortBy]
+	if filter.SortBy == "" {
+		column, ok = "created_at", true
+	}
+	order := strings.ToUpper(filter.SortOrder)
+	if order == "" {
+		order = "DESC"
+	}
+	if !ok || (order != "ASC" && order != "DESC") {
+		return nil, 0, ErrInvalidSort
+	}
+
+	query := s.db.Model(&models.JobApplication{}).Where("anonymized_at IS NULL")
+	if filter.JobID != nil {
+		query = query.Where("job_id = ?", *filter.JobID)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if !filter.Unverified {
+		query = query.Where("email_verified_at IS NOT NULL")
+	}
+	if filter.MinScore != nil {
+		query = query.Where("score >= ?", *filter.MinScore)
+	}
+	if filter.MaxScore != nil {
+		query = query.Where("score <= ?", *filter.MaxScore)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count applications: %w", err)
+	}
+
+	applications := []models.JobApplication{}
+	if err := query.Preload("Job").
+		Order(fmt.Sprintf("%s IS NULL, %s %s, id ASC", column, column, order)).
+		Offset((page - 1) * limit).Limit(limit).
+		Find(&applications).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to load applications: %w", err)
+	}
+	return applications, total, nil
+}
+
+// TalentPool returns a page of the applications in the talent pool, most recent consent first
+func (s *Service) TalentPool(page, limit int) ([]models.JobApplication, int64, error) {
+	query := s.db.Model(&models.JobApplication{}).Where("talent_pool = ? AND anonymized_at IS NULL", true)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count talent pool: %w", err)
+	}
+
+	applications := []models.JobApplication{}
+	if err := query.Preload("Job").
+		Order("talent_pool_consent_at DESC").
+		Offset((page - 1) * limit).Limit(limit).
+		Find(&applications).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to load talent pool: %w", err)
+	}
+	return applications, total, nil
+}
+
+// AnonymizeExpired removes the personal data of rejected applications outside
+// the talent pool that were decided more than the retention period ago, along
+// with their documents. Job, status, source and dates stay for the recruiting
+// statistics. It returns the number of anonymized applications.
+func (s *Service) AnonymizeExpired() (int, error) {
+	if s.config.RetentionMonths <= 0 {
+		return 0, nil
+	}
+
+	now := s.now()
+	cutoff := now.AddDate(0, -s.config.RetentionMonths, 0)
+
+	var applications []models.JobApplication
+	if err := s.db.Where("status = ? AND talent_pool = ? AND anonymized_at IS NULL AND COALESCE(reviewed_at, updated_at) < ?",
+		models.ApplicationStatusRejected, false, cutoff).
+		Find(&applications).Error; err != nil {
+		return 0, fmt.Errorf("failed to load expired applications: %w", err)
+	}
+
+	anonymized := 0
+	for i := range applications {
+		if err := s.anonymize(&applications[i], now); err != nil {
+			s.logger.Error("Failed to anonymize application",
+				zap.String("application_id", applications[i].ID.String()),
+				zap.Error(err))
+			continue
+		}
+		anonymized++
+	}
+
+	if anonymized > 0 {
+		s.logger.Info("Anonymized rejected applications", zap.Int("count", anonymized))
+	}
+	return anonymized, nil
+}
+
+func (s *Service) anonymize(application *models.JobApplication, now time.Time) error {
+	var documents []models.JobApplicationDocument
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("application_id = ?", application.ID).Find(&documents).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("application_id = ?", application.ID).Delete(&models.JobApplicationDocument{}).Error; err != nil {
+			return err
+		}
+
+		// Activity details may hold notes about the applicant
+		if err := tx.Model(&models.JobApplicationActivity{}).
+			Where("application_id = ?", application.ID).
+			Update("details", "").Error; err != nil {
+			return err
+		}
+
+		return tx.Model(application).Updates(map[string]interface{}{
+			"first_name":        anonymizedName,
+			"last_name":         "",
+			"email":             "",
+			"phone":             "",
+			"location":          "",
+			"cover_letter":      "",
+			"resume_url":        "",
+			"portfolio_url":     "",
+			"linked_in_url":     "",
+			"git_hub_url":       "",
+			"website_url":       "",
+			"current_position":  "",
+			"current_company":   "",
+			"expected_salary":   nil,
+			"availability_date": nil,
+			"notice_period":     "",
+			"motivation_text":   "",
+			"questions":         "",
+			"source_details":    "",
+			"referral_name":     "",
+			"review_notes":      "",
+			"rejection_note":    "",
+			"anonymized_at":     &now,
+		}).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	// Files are removed after the commit; a leftover file is logged, not retried
+	for _, document := range documents {
+		if err := os.Remove(document.FilePath); err != nil && !errors.Is(err, os.ErrNotExist) {
+			s.logger.Warn("Failed to remove application document",
+				zap.String("document_id", document.ID.String()),
+				zap.Error(err))
+		}
+	}
+	return nil
+}
+
+// find returns an application that was not anonymized
+func (s *Service) find(applicationID uuid.UUID) (*models.JobApplication, error) {
+	var application models.JobApplication
+	if err := s.db.First(&application, "id = ?", applicationID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrApplicationNotFound
+		}
+		return nil, err
+	}
+	if application.AnonymizedAt != nil {
+		return nil, ErrAnonymized
+	}
+	return &application, nil
+}
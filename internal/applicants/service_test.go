@@ -0,0 +1,294 @@
+package applicants
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"elterngeld-portal/config"
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/tests/testutils"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+func TestTalentPool(t *testing.T) {
+	service, db := setupTestService(t)
+	application := createApplication(t, db, models.ApplicationStatusRejected, nil)
+	admin := uuid.New()
+
+	_, err := service.AddToTalentPool(application.ID, ConsentInput{ConsentText: "  ", Source: "email"}, admin)
+	assert.ErrorIs(t, err, ErrConsentRequired)
+
+	added, err := service.AddToTalentPool(application.ID, ConsentInput{
+		ConsentText: "Ich bin einverstanden, dass meine Bewerbung für zukünftige Stellen gespeichert wird.",
+		Source:      "email",
+	}, admin)
+	require.NoError(t, err)
+	assert.True(t, added.TalentPool)
+	assert.NotNil(t, added.TalentPoolConsentAt)
+
+	pool, total, err := service.TalentPool(1, DefaultLimit)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+	require.Len(t, pool, 1)
+	assert.Equal(t, "email", pool[0].TalentPoolConsentSource)
+
+	removed, err := service.RemoveFromTalentPool(application.ID, admin)
+	require.NoError(t, err)
+	assert.False(t, removed.TalentPool)
+
+	var stored models.JobApplication
+	require.NoError(t, db.First(&stored, "id = ?", application.ID).Error)
+	assert.False(t, stored.TalentPool)
+	assert.Nil(t, stored.TalentPoolConsentAt)
+	assert.Empty(t, stored.TalentPoolConsentText)
+
+	var activities int64
+	db.Model(&models.JobApplicationActivity{}).Where("application_id = ?", application.ID).Count(&activities)
+	assert.Equal(t, int64(2), activities)
+
+	_, err = service.AddToTalentPool(uuid.New(), ConsentInput{ConsentText: "Ja", Source: "phone"}, admin)
+	assert.ErrorIs(t, err, ErrApplicationNotFound)
+}
+
+func TestList(t *testing.T) {
+	service, db := setupTestService(t)
+	high, low := 4.5, 2.0
+
+	strong := createApplication(t, db, models.ApplicationStatusScreening, nil)
+	weak := createApplication(t, db, models.ApplicationStatusScreening, nil)
+	unrated := createApplication(t, db, models.ApplicationStatusSubmitted, nil)
+	require.NoError(t, db.Model(strong).Update("score", high).Error)
+	require.NoError(t, db.Model(weak).Update("score", low).Error)
+
+	applications, total, err := service.List(Filter{SortBy: "score"}, 1, DefaultLimit)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), total)
+	require.Len(t, applications, 3)
+	assert.Equal(t, []uuid.UUID{strong.ID, weak.ID, unrated.ID}, []uuid.UUID{applications[0].ID, applications[1].ID, applications[2].ID})
+
+	applications, _, err = service.List(Filter{SortBy: "score", SortOrder: "asc"}, 1, DefaultLimit)
+	require.NoError(t, err)
+	assert.Equal(t, []uuid.UUID{weak.ID, strong.ID, unrated.ID}, []uuid.UUID{applications[0].ID, applications[1].ID, applications[2].ID})
+
+	minScore := 3.0
+	applications, total, err = service.List(Filter{MinScore: &minScore, Status: models.ApplicationStatusScreening}, 1, DefaultLimit)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+	assert.Equal(t, strong.ID, applications[0].ID)
+
+	unverified := createApplication(t, db, models.ApplicationStatusSubmitted, nil)
+	require.NoError(t, db.Model(unverified).Update("email_verified_at", nil).Error)
+	_, total, err = service.List(Filter{}, 1, DefaultLimit)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), total)
+	_, total, err = service.List(Filter{Unverified: true}, 1, DefaultLimit)
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), total)
+
+	_, _, err = service.List(Filter{SortBy: "email"}, 1, DefaultLimit)
+	assert.ErrorIs(t, err, ErrInvalidSort)
+	_, _, err = service.List(Filter{SortOrder: "sideways"}, 1, DefaultLimit)
+	assert.ErrorIs(t, err, ErrInvalidSort)
+}
+
+func TestSubmitVerifyAndTrack(t *testing.T) {
+	service, db := setupTestService(t)
+	job := createJob(t, db)
+
+	application, token, err := service.Submit(job, ApplicationInput{
+		FirstName:      " Erika ",
+		LastName:       "Muster",
+		Email:          "Erika@Example.com",
+		CoverLetter:    "Sehr geehrte Damen und Herren, ...",
+		PrivacyConsent: true,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Erika", application.FirstName)
+	assert.Equal(t, "erika@example.com", application.Email)
+	assert.Nil(t, application.EmailVerifiedAt)
+
+	var stored models.Job
+	require.NoError(t, db.First(&stored, "id = ?", job.ID).Error)
+	assert.Equal(t, 1, stored.ApplicationCount)
+
+	tracking, err := service.Track(token)
+	require.NoError(t, err)
+	assert.Equal(t, job.Title, tracking.JobTitle)
+	assert.Equal(t, models.ApplicationStatusSubmitted, tracking.Status)
+	assert.False(t, tracking.EmailVerified)
+
+	verified, err := service.VerifyEmail(token)
+	require.NoError(t, err)
+	require.NotNil(t, verified.EmailVerifiedAt)
+	_, err = service.VerifyEmail(token)
+	require.NoError(t, err)
+
+	tracking, err = service.Track(token)
+	require.NoError(t, err)
+	assert.True(t, tracking.EmailVerified)
+
+	var activities int64
+	db.Model(&models.JobApplicationActivity{}).Where("application_id = ?", application.ID).Count(&activities)
+	assert.Equal(t, int64(2), activities)
+
+	for _, invalid := range []string{"", "garbage", application.ID.String() + ".forged", uuid.New().String() + "." + token[37:]} {
+		_, err = service.Track(invalid)
+		assert.ErrorIs(t, err, ErrInvalidToken, invalid)
+	}
+
+	// Anonymizing the application invalidates its links
+	require.NoError(t, db.Model(application).Updates(map[string]interface{}{"email": "", "anonymized_at": time.Now()}).Error)
+	_, err = service.Track(token)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestSubmitDirectApplyDisabled(t *testing.T) {
+	service, db := setupTestService(t)
+	job := createJob(t, db)
+	require.NoError(t, db.Model(job).Update("allow_direct_apply", false).Error)
+	job.AllowDirectApply = false
+
+	_, _, err := service.Submit(job, ApplicationInput{FirstName: "Erika", LastName: "Muster", Email: "erika@example.com", PrivacyConsent: true})
+	assert.ErrorIs(t, err, ErrDirectApplyDisabled)
+}
+
+func TestAnonymizeExpired(t *testing.T) {
+	service, db := setupTestService(t)
+	now := time.Date(2025, 9, 1, 3, 0, 0, 0, time.UTC)
+	service.now = func() time.Time { return now }
+
+	longAgo := now.AddDate(0, -7, 0)
+	recently := now.AddDate(0, -2, 0)
+
+	expired := createApplication(t, db, models.ApplicationStatusRejected, &longAgo)
+	recent := createApplication(t, db, models.ApplicationStatusRejected, &recently)
+	pooled := createApplication(t, db, models.ApplicationStatusRejected, &longAgo)
+	open := createApplication(t, db, models.ApplicationStatusReviewing, &longAgo)
+	_, err := service.AddToTalentPool(pooled.ID, ConsentInput{ConsentText: "Ja", Source: "application_form"}, uuid.New())
+	require.NoError(t, err)
+
+	resume := filepath.Join(t.TempDir(), "lebenslauf.pdf")
+	require.NoError(t, os.WriteFile(resume, []byte("%PDF"), 0o600))
+	require.NoError(t, db.Create(&models.JobApplicationDocument{
+		ApplicationID: expired.ID,
+		FileName:      "lebenslauf.pdf",
+		FileSize:      4,
+		FileType:      "application/pdf",
+		FilePath:      resume,
+		DocumentType:  "resume",
+		UploadedAt:    longAgo,
+	}).Error)
+	require.NoError(t, db.Create(&models.JobApplicationActivity{
+		ApplicationID: expired.ID,
+		Type:          "note_added",
+		Description:   "Notiz",
+		Details:       `{"note":"Sehr gutes Telefonat mit Frau Muster"}`,
+		CreatedAt:     longAgo,
+	}).Error)
+
+	count, err := service.AnonymizeExpired()
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	var stored models.JobApplication
+	require.NoError(t, db.First(&stored, "id = ?", expired.ID).Error)
+	assert.Equal(t, anonymizedName, stored.FirstName)
+	assert.Empty(t, stored.LastName)
+	assert.Empty(t, stored.Email)
+	assert.Empty(t, stored.CoverLetter)
+	assert.Equal(t, models.ApplicationStatusRejected, stored.Status)
+	assert.NotNil(t, stored.AnonymizedAt)
+
+	var documents int64
+	db.Unscoped().Model(&models.JobApplicationDocument{}).Where("application_id = ?", expired.ID).Count(&documents)
+	assert.Zero(t, documents)
+	assert.NoFileExists(t, resume)
+
+	var activity models.JobApplicationActivity
+	require.NoError(t, db.Where("application_id = ?", expired.ID).First(&activity).Error)
+	assert.Empty(t, activity.Details)
+
+	for _, kept := range []*models.JobApplication{recent, pooled, open} {
+		var application models.JobApplication
+		require.NoError(t, db.First(&application, "id = ?", kept.ID).Error)
+		assert.Equal(t, "Erika", application.FirstName)
+		assert.Nil(t, application.AnonymizedAt)
+	}
+
+	// Anonymized applications are left alone
+	count, err = service.AnonymizeExpired()
+	require.NoError(t, err)
+	assert.Zero(t, count)
+
+	_, err = service.AddToTalentPool(expired.ID, ConsentInput{ConsentText: "Ja", Source: "email"}, uuid.New())
+	assert.ErrorIs(t, err, ErrAnonymized)
+}
+
+func TestAnonymizeExpiredDisabled(t *testing.T) {
+	service, db := setupTestService(t)
+	service.config.RetentionMonths = 0
+	longAgo := time.Now().AddDate(-2, 0, 0)
+	createApplication(t, db, models.ApplicationStatusRejected, &longAgo)
+
+	count, err := service.AnonymizeExpired()
+	require.NoError(t, err)
+	assert.Zero(t, count)
+}
+
+func setupTestService(t *testing.T) (*Service, *gorm.DB) {
+	t.Helper()
+	db := testutils.NewSQLiteDB(t,
+		&models.User{},
+		&models.Job{},
+		&models.JobApplication{},
+		&models.JobApplicationDocument{},
+		&models.JobApplicationActivity{},
+	)
+
+	cfg := &config.Config{Recruiting: config.RecruitingConfig{RetentionMonths: 6, SigningSecret: "test-secret"}}
+	return NewService(db, zap.NewNop(), cfg), db
+}
+
+func createJob(t *testing.T, db *gorm.DB) *models.Job {
+	t.Helper()
+	job := &models.Job{
+		Title:       "Elterngeldberater (m/w/d)",
+		Slug:        uuid.New().String(),
+		Description: "...",
+		Type:        models.JobTypeFullTime,
+		Level:       models.JobLevelMid,
+		Location:    "München",
+		CreatedBy:   uuid.New(),
+	}
+	require.NoError(t, db.Create(job).Error)
+
+	var stored models.Job
+	require.NoError(t, db.First(&stored, "id = ?", job.ID).Error)
+	return &stored
+}
+
+func createApplication(t *testing.T, db *gorm.DB, status models.ApplicationStatus, reviewedAt *time.Time) *models.JobApplication {
+	t.Helper()
+	job := createJob(t, db)
+
+	verifiedAt := time.Now()
+	application := &models.JobApplication{
+		JobID:           job.ID,
+		FirstName:       "Erika",
+		LastName:        "Muster",
+		Email:           "erika@example.com",
+		CoverLetter:     "Sehr geehrte Damen und Herren, ...",
+		Status:          status,
+		ReviewedAt:      reviewedAt,
+		EmailVerifiedAt: &verifiedAt,
+	}
+	require.NoError(t, db.Create(application).Error)
+	return application
+}
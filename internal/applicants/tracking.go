@@ -0,0 +1,54 @@
+package applicants
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"elterngeld-portal/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// token builds the signed token of the form <application id>.<signature>. The
+// signature covers the email address, so the links stop working once the
+// application is anonymized.
+func (s *Service) token(application *models.JobApplication) string {
+	return fmt.Sprintf("%s.%s", application.ID, s.sign(application.ID, application.Email))
+}
+
+func (s *Service) sign(applicationID uuid.UUID, email string) string {
+	mac := hmac.New(sha256.New, s.secret)
+	fmt.Fprintf(mac, "application|%s|%s", applicationID, strings.ToLower(email))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// findByToken returns the application of a token with a valid signature
+func (s *Service) findByToken(token string) (*models.JobApplication, error) {
+	id, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+	applicationID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	var application models.JobApplication
+	if err := s.db.First(&application, "id = ?", applicationID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidToken
+		}
+		return nil, err
+	}
+
+	expected := s.sign(application.ID, application.Email)
+	if application.AnonymizedAt != nil || !hmac.Equal([]byte(expected), []byte(signature)) {
+		return nil, ErrInvalidToken
+	}
+	return &application, nil
+}
@@ -0,0 +1,82 @@
+package availability
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/pkg/timeutil"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrBufferConflict is returned when a timeslot overlaps another appointment or leaves less than the Berater's buffer to it
+	ErrBufferConflict = errors.New("timeslot is too close to another appointment")
+	// ErrDailyLimitReached is returned when the Berater already has the maximum number of bookings on that day
+	ErrDailyLimitReached = errors.New("daily booking limit reached")
+)
+
+// Schedule holds the appointments of Beraters around a period, to check
+// timeslots against their buffer time and daily booking limit
+type Schedule struct {
+	bookings map[uuid.UUID][]models.Booking
+}
+
+// LoadSchedule loads the bookings that were not cancelled of the given
+// Beraters around [from, to). The booking with excludeBookingID is left out,
+// so a booking does not collide with itself.
+func (s *Service) LoadSchedule(db *gorm.DB, beraterIDs []uuid.UUID, from, to time.Time, excludeBookingID uuid.UUID) (*Schedule, error) {
+	schedule := &Schedule{bookings: make(map[uuid.UUID][]models.Booking)}
+	if len(beraterIDs) == 0 {
+		return schedule, nil
+	}
+
+	// Padded by two days to cover whole local days and buffers around the edges
+	var bookings []models.Booking
+	if err := db.Select("id", "berater_id", "timeslot_id", "start_time", "end_time").
+		Where("berater_id IN ? AND status <> ? AND id <> ? AND start_time < ? AND end_time > ?",
+			beraterIDs, models.BookingStatusCancelled, excludeBookingID, to.AddDate(0, 0, 2), from.AddDate(0, 0, -2)).
+		Find(&bookings).Error; err != nil {
+		return nil, fmt.Errorf("failed to load Berater bookings: %w", err)
+	}
+	for _, booking := range bookings {
+		schedule.bookings[*booking.BeraterID] = append(schedule.bookings[*booking.BeraterID], booking)
+	}
+	return schedule, nil
+}
+
+// Add records a booking of a Berater, so later checks take it into account
+// when several bookings are placed at once
+func (sc *Schedule) Add(beraterID uuid.UUID, booking models.Booking) {
+	sc.bookings[beraterID] = append(sc.bookings[beraterID], booking)
+}
+
+// Check verifies that a booking of the timeslot respects the Berater's booking
+// limits. Bookings of the same timeslot (group appointments) may overlap and
+// do not need a buffer to each other but count towards the daily limit.
+func (sc *Schedule) Check(berater *models.User, slot *models.Timeslot) error {
+	buffer := time.Duration(berater.BookingBufferMinutes) * time.Minute
+	loc := berater.TimeLocation()
+	day := timeutil.FormatDate(slot.StartTime, loc)
+
+	sameDay := 0
+	for _, booking := range sc.bookings[berater.ID] {
+		if timeutil.FormatDate(booking.StartTime, loc) == day {
+			sameDay++
+		}
+		if booking.TimeslotID != nil && *booking.TimeslotID == slot.ID {
+			continue
+		}
+		if booking.StartTime.Before(slot.EndTime.Add(buffer)) && booking.EndTime.Add(buffer).After(slot.StartTime) {
+			return ErrBufferConflict
+		}
+	}
+
+	if berater.MaxBookingsPerDay > 0 && sameDay >= berater.MaxBookingsPerDay {
+		return ErrDailyLimitReached
+	}
+	return nil
+}
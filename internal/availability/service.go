@@ -0,0 +1,172 @@
+package availability
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"elterngeld-portal/internal/holidays"
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/pkg/timeutil"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// DefaultHorizonDays is how far ahead timeslots are generated from availability rules
+const DefaultHorizonDays = 28
+
+// ErrInvalidRule is returned when an availability rule does not describe a valid window
+var ErrInvalidRule = errors.New("invalid availability rule")
+
+// Service manages the weekly availability of Beraters and the timeslots generated from it
+type Service struct {
+	db       *gorm.DB
+	logger   *zap.Logger
+	holidays *holidays.Service
+}
+
+func NewService(db *gorm.DB, logger *zap.Logger, holidayService *holidays.Service) *Service {
+	return &Service{
+		db:       db,
+		logger:   logger,
+		holidays: holidayService,
+	}
+}
+
+// Rules returns the availability rules of a Berater ordered by weekday and start time
+func (s *Service) Rules(beraterID uuid.UUID) ([]models.AvailabilityRule, error) {
+	var rules []models.AvailabilityRule
+	if err := s.db.Where("berater_id = ?", beraterID).Order("weekday ASC, start_time ASC").Find(&rules).Error; err != nil {
+		return nil, fmt.Errorf("failed to load availability rules: %w", err)
+	}
+	return rules, nil
+}
+
+// ReplaceRules validates and stores the availability rules of a Berater, replacing existing ones
+func (s *Service) ReplaceRules(beraterID uuid.UUID, rules []models.AvailabilityRule) ([]models.AvailabilityRule, error) {
+	for i := range rules {
+		if err := rules[i].Validate(); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidRule, err)
+		}
+		rules[i].ID = uuid.Nil
+		rules[i].BeraterID = beraterID
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("berater_id = ?", beraterID).Delete(&models.AvailabilityRule{}).Error; err != nil {
+			return err
+		}
+		if len(rules) == 0 {
+			return nil
+		}
+		return tx.Create(&rules).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to store availability rules: %w", err)
+	}
+
+	return rules, nil
+}
+
+// GenerateTimeslots creates timeslots from the Berater's availability rules for the
+// given number of days starting with the day of from. Public holidays in the
+// Berater's Bundesland are skipped and slots that already exist are left
+// untouched. It returns the number of created timeslots.
+func (s *Service) GenerateTimeslots(berater *models.User, from time.Time, days int) (int, error) {
+	rules, err := s.Rules(berater.ID)
+	if err != nil {
+		return 0, err
+	}
+	if len(rules) == 0 || days <= 0 {
+		return 0, nil
+	}
+
+	loc := berater.TimeLocation()
+	rangeStart, rangeEnd := timeutil.DayRange(from, days, loc)
+	calendar := s.holidays.Calendar(timeutil.FormatDate(rangeStart, loc), timeutil.FormatDate(rangeEnd, loc))
+
+	// Existing slots are keyed by start time to keep generation idempotent
+	var existing []models.Timeslot
+	if err := s.db.Select("start_time").Where("berater_id = ? AND start_time >= ? AND start_time < ?",
+		berater.ID, rangeStart, rangeEnd).Find(&existing).Error; err != nil {
+		return 0, fmt.Errorf("failed to load existing timeslots: %w", err)
+	}
+	taken := make(map[int64]bool, len(existing))
+	for _, slot := range existing {
+		taken[slot.StartTime.Unix()] = true
+	}
+
+	var timeslots []models.Timeslot
+	for day := 0; day < days; day++ {
+		current := timeutil.AddDays(from, day, loc)
+		if _, isHoliday, err := calendar.Lookup(berater.Bundesland, timeutil.FormatDate(current, loc)); err != nil {
+			return 0, err
+		} else if isHoliday {
+			continue
+		}
+
+		weekday := int(current.In(loc).Weekday())
+		for _, rule := range rules {
+			if rule.Weekday != weekday {
+				continue
+			}
+			start, end, err := rule.Minutes()
+			if err != nil {
+				return 0, err
+			}
+			for minute := start; minute+rule.SlotDuration <= end; minute += rule.SlotDuration {
+				startTime := timeutil.At(current, minute/60, minute%60, loc)
+				if taken[startTime.Unix()] {
+					continue
+				}
+				taken[startTime.Unix()] = true
+
+				timeslots = append(timeslots, models.Timeslot{
+					BeraterID:   berater.ID,
+					StartTime:   startTime,
+					EndTime:     startTime.Add(time.Duration(rule.SlotDuration) * time.Minute),
+					Duration:    rule.SlotDuration,
+					Timezone:    loc.String(),
+					IsAvailable: true,
+					MaxBookings: 1,
+					Title:       "Beratungstermin",
+					IsOnline:    rule.IsOnline,
+				})
+			}
+		}
+	}
+
+	if len(timeslots) == 0 {
+		return 0, nil
+	}
+	// GORM replaces a false IsOnline with the column default on insert, so
+	// offline slots are updated after creating them
+	var offline []uuid.UUID
+	for i := range timeslots {
+		timeslots[i].ID = uuid.New()
+		if !timeslots[i].IsOnline {
+			offline = append(offline, timeslots[i].ID)
+		}
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.CreateInBatches(&timeslots, 100).Error; err != nil {
+			return err
+		}
+		if len(offline) == 0 {
+			return nil
+		}
+		return tx.Model(&models.Timeslot{}).Where("id IN ?", offline).Update("is_online", false).Error
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to create timeslots: %w", err)
+	}
+
+	s.logger.Info("Generated timeslots from availability",
+		zap.String("berater_id", berater.ID.String()),
+		zap.Int("count", len(timeslots)))
+
+	return len(timeslots), nil
+}
@@ -0,0 +1,175 @@
+package availability
+
+import (
+	"testing"
+	"time"
+
+	"elterngeld-portal/internal/holidays"
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/pkg/timeutil"
+	"elterngeld-portal/tests/testutils"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+func TestGenerateTimeslots(t *testing.T) {
+	db, service := setupTestService(t)
+	berater := &models.User{
+		Email:      "berater@example.com",
+		FirstName:  "Ben",
+		LastName:   "Berater",
+		Role:       models.RoleBerater,
+		Bundesland: models.BundeslandBerlin,
+		Timezone:   "Europe/Berlin",
+	}
+	require.NoError(t, db.Create(berater).Error)
+
+	_, err := service.ReplaceRules(berater.ID, []models.AvailabilityRule{
+		{Weekday: int(time.Monday), StartTime: "09:00", EndTime: "12:00", SlotDuration: 90, IsOnline: false},
+		{Weekday: int(time.Wednesday), StartTime: "14:00", EndTime: "15:00", IsOnline: true},
+	})
+	require.NoError(t, err)
+
+	// Monday 2025-04-21 is Ostermontag, Wednesday 2025-04-23 a working day
+	loc := berater.TimeLocation()
+	from := time.Date(2025, 4, 21, 0, 0, 0, 0, loc)
+	created, err := service.GenerateTimeslots(berater, from, 7)
+	require.NoError(t, err)
+	assert.Equal(t, 1, created)
+
+	var timeslots []models.Timeslot
+	require.NoError(t, db.Where("berater_id = ?", berater.ID).Find(&timeslots).Error)
+	require.Len(t, timeslots, 1)
+	assert.Equal(t, "2025-04-23", timeutil.FormatDate(timeslots[0].StartTime, loc))
+	assert.Equal(t, 60, timeslots[0].Duration)
+
+	// The following week has two offline 90 minute slots on Monday
+	created, err = service.GenerateTimeslots(berater, from, 14)
+	require.NoError(t, err)
+	assert.Equal(t, 3, created)
+
+	var offline int64
+	require.NoError(t, db.Model(&models.Timeslot{}).Where("berater_id = ? AND is_online = ?", berater.ID, false).Count(&offline).Error)
+	assert.Equal(t, int64(2), offline)
+
+	// Generation is idempotent
+	created, err = service.GenerateTimeslots(berater, from, 14)
+	require.NoError(t, err)
+	assert.Equal(t, 0, created)
+}
+
+func TestReplaceRules_Invalid(t *testing.T) {
+	_, service := setupTestService(t)
+
+	for _, rule := range []models.AvailabilityRule{
+		{Weekday: 7, StartTime: "09:00", EndTime: "10:00"},
+		{Weekday: 1, StartTime: "9 Uhr", EndTime: "10:00"},
+		{Weekday: 1, StartTime: "10:00", EndTime: "09:00"},
+		{Weekday: 1, StartTime: "09:00", EndTime: "09:30", SlotDuration: 60},
+	} {
+		_, err := service.ReplaceRules(uuid.New(), []models.AvailabilityRule{rule})
+		assert.ErrorIs(t, err, ErrInvalidRule)
+	}
+}
+
+func TestScheduleCheck(t *testing.T) {
+	db, service := setupTestService(t)
+	berater := &models.User{
+		Email:                "berater@example.com",
+		FirstName:            "Ben",
+		LastName:             "Berater",
+		Role:                 models.RoleBerater,
+		Timezone:             "Europe/Berlin",
+		BookingBufferMinutes: 30,
+		MaxBookingsPerDay:    3,
+	}
+	customer := &models.User{Email: "kunde@example.com", FirstName: "Kim", LastName: "Kunde", Role: models.RoleUser}
+	require.NoError(t, db.Create(berater).Error)
+	require.NoError(t, db.Create(customer).Error)
+
+	loc := berater.TimeLocation()
+	slot := func(hour, minute int) *models.Timeslot {
+		start := time.Date(2025, 6, 2, hour, minute, 0, 0, loc)
+		return &models.Timeslot{ID: uuid.New(), BeraterID: berater.ID, StartTime: start, EndTime: start.Add(time.Hour)}
+	}
+	book := func(slot *models.Timeslot, status models.BookingStatus) {
+		require.NoError(t, db.Create(&models.Booking{
+			UserID:      customer.ID,
+			BeraterID:   &berater.ID,
+			TimeslotID:  &slot.ID,
+			Title:       "Beratung",
+			Status:      status,
+			ScheduledAt: slot.StartTime,
+			StartTime:   slot.StartTime,
+			EndTime:     slot.EndTime,
+		}).Error)
+	}
+
+	group := slot(10, 0)
+	book(group, models.BookingStatusConfirmed)
+	book(slot(14, 0), models.BookingStatusCancelled)
+
+	schedule, err := service.LoadSchedule(db, []uuid.UUID{berater.ID}, group.StartTime, group.EndTime, uuid.Nil)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name string
+		slot *models.Timeslot
+		err  error
+	}{
+		{"same group appointment", group, nil},
+		{"directly after", slot(11, 0), ErrBufferConflict},
+		{"inside buffer before", slot(8, 45), ErrBufferConflict},
+		{"after buffer", slot(11, 30), nil},
+		{"before buffer", slot(8, 30), nil},
+		{"cancelled booking ignored", slot(14, 0), nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := schedule.Check(berater, tt.slot)
+			if tt.err != nil {
+				assert.ErrorIs(t, err, tt.err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+
+	t.Run("daily limit", func(t *testing.T) {
+		book(slot(12, 0), models.BookingStatusPending)
+		book(slot(16, 0), models.BookingStatusConfirmed)
+
+		schedule, err := service.LoadSchedule(db, []uuid.UUID{berater.ID}, group.StartTime, group.EndTime, uuid.Nil)
+		require.NoError(t, err)
+		assert.ErrorIs(t, schedule.Check(berater, slot(18, 0)), ErrDailyLimitReached)
+
+		nextDay := slot(10, 0)
+		nextDay.StartTime = nextDay.StartTime.AddDate(0, 0, 1)
+		nextDay.EndTime = nextDay.EndTime.AddDate(0, 0, 1)
+		assert.NoError(t, schedule.Check(berater, nextDay))
+
+		berater.MaxBookingsPerDay = 0
+		assert.NoError(t, schedule.Check(berater, slot(18, 0)))
+	})
+
+	t.Run("overlap without buffer", func(t *testing.T) {
+		berater.BookingBufferMinutes = 0
+		assert.ErrorIs(t, schedule.Check(berater, slot(10, 30)), ErrBufferConflict)
+		assert.NoError(t, schedule.Check(berater, slot(11, 0)))
+
+		added := slot(19, 0)
+		schedule.Add(berater.ID, models.Booking{TimeslotID: &added.ID, StartTime: added.StartTime, EndTime: added.EndTime})
+		assert.ErrorIs(t, schedule.Check(berater, slot(19, 30)), ErrBufferConflict)
+	})
+}
+
+func setupTestService(t *testing.T) (*gorm.DB, *Service) {
+	t.Helper()
+	db := testutils.NewSQLiteDB(t, &models.User{}, &models.Timeslot{}, &models.Booking{}, &models.HolidayOverride{}, &models.AvailabilityRule{})
+
+	return db, NewService(db, zap.NewNop(), holidays.NewService(db, zap.NewNop()))
+}
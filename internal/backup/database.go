@@ -0,0 +1,165 @@
+package backup
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	formatPgDump = "pg_dump"
+	formatSQLite = "sqlite.gz"
+)
+
+// postgresTarget is a PostgreSQL database a dump is read from or restored to
+type postgresTarget struct {
+	host, port, user, password, name, sslMode string
+}
+
+// env passes the connection to pg_dump and pg_restore in the libpq
+// variables, which keeps the password out of the process list
+func (t postgresTarget) env() []string {
+	env := append(os.Environ(),
+		"PGHOST="+t.host,
+		"PGPORT="+t.port,
+		"PGUSER="+t.user,
+		"PGPASSWORD="+t.password,
+		"PGDATABASE="+t.name,
+	)
+	if t.sslMode != "" {
+		env = append(env, "PGSSLMODE="+t.sslMode)
+	}
+	return env
+}
+
+func (t postgresTarget) same(other postgresTarget) bool {
+	return strings.EqualFold(t.host, other.host) && t.port == other.port && t.name == other.name
+}
+
+func (t postgresTarget) String() string {
+	return fmt.Sprintf("postgres://%s:%s/%s", t.host, t.port, t.name)
+}
+
+// parsePostgresURL parses a postgres:// URL of the restore database
+func parsePostgresURL(raw string) (postgresTarget, bool) {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "postgres" && u.Scheme != "postgresql") {
+		return postgresTarget{}, false
+	}
+	password, _ := u.User.Password()
+	target := postgresTarget{
+		host:     u.Hostname(),
+		port:     u.Port(),
+		user:     u.User.Username(),
+		password: password,
+		name:     strings.TrimPrefix(u.Path, "/"),
+		sslMode:  u.Query().Get("sslmode"),
+	}
+	if target.port == "" {
+		target.port = "5432"
+	}
+	return target, target.host != "" && target.name != ""
+}
+
+// dumpDatabase writes a consistent copy of the live database to w and
+// returns its format. PostgreSQL is dumped by pg_dump in its compressed
+// custom format from a single snapshot; SQLite is copied with VACUUM INTO,
+// which reads in one transaction while the server keeps writing.
+func (s *Service) dumpDatabase(ctx context.Context, dir string, w io.Writer) (string, error) {
+	if s.database.Driver == "postgres" {
+		err := s.runPg(ctx, s.pgDump, s.live.env(), nil, w,
+			"--format=custom", "--no-owner", "--no-privileges")
+		return formatPgDump, err
+	}
+
+	copyPath := filepath.Join(dir, "database.sqlite")
+	defer os.Remove(copyPath)
+	if err := s.db.WithContext(ctx).Exec("VACUUM INTO ?", copyPath).Error; err != nil {
+		return "", fmt.Errorf("failed to copy database: %w", err)
+	}
+	file, err := os.Open(copyPath)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	gz := gzip.NewWriter(w)
+	if _, err := io.Copy(gz, file); err != nil {
+		return "", err
+	}
+	return formatSQLite, gz.Close()
+}
+
+// restoreDatabase restores a dump into the staging database. PostgreSQL
+// dumps replace the objects they contain in a single transaction; SQLite
+// copies replace the staging file once they are complete.
+func (s *Service) restoreDatabase(ctx context.Context, format string, r io.Reader) error {
+	switch format {
+	case formatPgDump:
+		target, ok := parsePostgresURL(s.stagingDatabase)
+		if !ok {
+			return fmt.Errorf("%w: PostgreSQL dumps need a postgres:// restore database", ErrNoRestoreTarget)
+		}
+		return s.runPg(ctx, s.pgRestore, target.env(), r, io.Discard,
+			"--clean", "--if-exists", "--no-owner", "--no-privileges", "--single-transaction", "--exit-on-error",
+			"--dbname="+target.name)
+
+	case formatSQLite:
+		if _, ok := parsePostgresURL(s.stagingDatabase); ok {
+			return fmt.Errorf("%w: SQLite copies need a SQLite restore database", ErrNoRestoreTarget)
+		}
+		if err := os.MkdirAll(filepath.Dir(s.stagingDatabase), 0o755); err != nil {
+			return err
+		}
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrCorrupt, err)
+		}
+		tmp := s.stagingDatabase + ".restoring"
+		file, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+		if err != nil {
+			return err
+		}
+		defer os.Remove(tmp)
+		if _, err := io.Copy(file, gz); err != nil {
+			file.Close()
+			return err
+		}
+		if err := file.Close(); err != nil {
+			return err
+		}
+		return os.Rename(tmp, s.stagingDatabase)
+
+	default:
+		return fmt.Errorf("%w: unknown database format %q", ErrCorrupt, format)
+	}
+}
+
+// runPg runs pg_dump or pg_restore
+func (s *Service) runPg(ctx context.Context, binary string, env []string, stdin io.Reader, stdout io.Writer, args ...string) error {
+	if binary == "" {
+		return fmt.Errorf("%w: no PostgreSQL client configured", ErrToolUnavailable)
+	}
+	path, err := exec.LookPath(binary)
+	if err != nil {
+		return fmt.Errorf("%w: %s is not installed", ErrToolUnavailable, binary)
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, path, args...)
+	cmd.Env = env
+	cmd.Stdin = stdin
+	cmd.Stdout = stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s failed: %w: %s", filepath.Base(path), err, bytes.TrimSpace(stderr.Bytes()))
+	}
+	return nil
+}
@@ -0,0 +1,539 @@
+// Package backup creates encrypted backup sets of the database and the
+// document storage and restores them into a staging environment.
+//
+// A backup set is a directory named after its ID in the backup path:
+//
+//	manifest.json       what the set contains, with checksums and wrapped keys
+//	database.enc        pg_dump custom format dump, or a gzipped SQLite copy
+//	storage.enc         gzipped tar of the uploaded documents and generated PDFs
+//
+// Each artifact is encrypted with its own data key, which is wrapped by the
+// primary key of ENCRYPTION_KEYS like the encrypted personal data; backups
+// can therefore only be created and restored with the keyring configured.
+// Sets are written to a ".partial" directory that is renamed when complete,
+// so an interrupted backup never shows up as a backup set.
+//
+// Restores never touch the live database or storage. They go to the
+// configured staging database and directory, from which data can be checked
+// or copied back by hand.
+package backup
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"elterngeld-portal/config"
+	"elterngeld-portal/internal/outbox"
+	"elterngeld-portal/pkg/encryption"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// JobName is the name of the scheduled backup job
+const JobName = "backup"
+
+const (
+	manifestName   = "manifest.json"
+	partialSuffix  = ".partial"
+	databaseName   = "database.enc"
+	storageName    = "storage.enc"
+	formatTarGzip  = "tar.gz"
+	staleAfter     = 24 * time.Hour
+	idTimeLayout   = "20060102T150405Z"
+	dirPermissions = 0o700
+)
+
+var (
+	// ErrEncryptionDisabled is returned when backing up or restoring without ENCRYPTION_KEYS
+	ErrEncryptionDisabled = errors.New("backups need encryption keys")
+	// ErrBackupNotFound is returned for unknown backup sets
+	ErrBackupNotFound = errors.New("backup not found")
+	// ErrNoRestoreTarget is returned when no staging database or directory fits the backup
+	ErrNoRestoreTarget = errors.New("no restore target configured")
+	// ErrRestoreToLive is returned when a restore target is the live database or storage
+	ErrRestoreToLive = errors.New("restore target is the live system")
+	// ErrCorrupt is returned for backup sets that fail their checksums or do not decrypt
+	ErrCorrupt = errors.New("backup is corrupt")
+	// ErrToolUnavailable is returned when pg_dump or pg_restore is not configured or installed
+	ErrToolUnavailable = errors.New("backup tool unavailable")
+	// ErrBusy is returned when a backup or restore is already running
+	ErrBusy = errors.New("backup or restore already running")
+)
+
+// idPattern matches backup IDs, which are used as directory names
+var idPattern = regexp.MustCompile(`^\d{8}T\d{6}Z-[0-9a-f]{8}$`)
+
+// Manifest describes a backup set
+type Manifest struct {
+	ID         string    `json:"id"`
+	CreatedAt  time.Time `json:"created_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	Driver     string    `json:"driver"`
+	KeyID      string    `json:"key_id"` // master key wrapping the data keys
+	Database   Artifact  `json:"database"`
+	Storage    Artifact  `json:"storage"`
+	Files      int       `json:"files"`
+	FileBytes  int64     `json:"file_bytes"`
+}
+
+// Artifact is an encrypted file of a backup set
+type Artifact struct {
+	File   string `json:"file"`
+	Format string `json:"format"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"` // of the encrypted file
+	Key    string `json:"key"`    // data key, wrapped by the master key
+}
+
+// RestoreResult describes a completed restore
+type RestoreResult struct {
+	BackupID    string `json:"backup_id"`
+	Database    string `json:"database"`
+	StoragePath string `json:"storage_path"`
+	Files       int    `json:"files"`
+}
+
+// Service creates, lists and restores backup sets
+type Service struct {
+	db              *gorm.DB
+	logger          *zap.Logger
+	relay           *outbox.Service
+	now             func() time.Time
+	path            string
+	keep            int
+	database        config.DatabaseConfig
+	live            postgresTarget
+	roots           []storageRoot
+	pgDump          string
+	pgRestore       string
+	stagingDatabase string
+	stagingStorage  string
+
+	// running allows one backup or restore at a time; the scheduler runs
+	// backups on one instance only
+	running sync.Mutex
+}
+
+func NewService(db *gorm.DB, logger *zap.Logger, cfg *config.Config, relay *outbox.Service) *Service {
+	uploadPath := cfg.Upload.Path
+	if uploadPath == "" {
+		uploadPath = "./storage/uploads"
+	}
+	return &Service{
+		db:     db,
+		logger: logger,
+		relay:  relay,
+		now:    time.Now,
+		path:   cfg.Backup.Path,
+		keep:   cfg.Backup.Keep,
+		live: postgresTarget{
+			host:     cfg.Database.Host,
+			port:     cfg.Database.Port,
+			user:     cfg.Database.User,
+			password: cfg.Database.Password,
+			name:     cfg.Database.Name,
+			sslMode:  cfg.Database.SSLMode,
+		},
+		database: cfg.Database,
+		roots: []storageRoot{
+			{name: "uploads", path: uploadPath},
+			{name: "generated", path: cfg.PDF.Path},
+		},
+		pgDump:          cfg.Backup.PgDump,
+		pgRestore:       cfg.Backup.PgRestore,
+		stagingDatabase: cfg.Backup.RestoreDatabase,
+		stagingStorage:  cfg.Backup.RestoreStoragePath,
+	}
+}
+
+// Create writes a new backup set: the database dump first, then the storage
+// snapshot. Sets beyond the number kept are removed afterwards.
+func (s *Service) Create(ctx context.Context) (*Manifest, error) {
+	keyring := encryption.Default()
+	if keyring == nil {
+		return nil, ErrEncryptionDisabled
+	}
+	if !s.running.TryLock() {
+		return nil, ErrBusy
+	}
+	defer s.running.Unlock()
+
+	now := s.now().UTC()
+	manifest := &Manifest{
+		ID:        now.Format(idTimeLayout) + "-" + strings.ReplaceAll(uuid.NewString(), "-", "")[:8],
+		CreatedAt: now,
+		Driver:    s.database.Driver,
+		KeyID:     keyring.PrimaryKeyID(),
+	}
+	dir := filepath.Join(s.path, manifest.ID+partialSuffix)
+	if err := os.MkdirAll(dir, dirPermissions); err != nil {
+		return nil, fmt.Errorf("failed to create backup directory: %w", err)
+	}
+	complete := false
+	defer func() {
+		if !complete {
+			os.RemoveAll(dir)
+		}
+	}()
+
+	var err error
+	manifest.Database, err = s.writeArtifact(keyring, dir, databaseName, func(w io.Writer) (string, error) {
+		return s.dumpDatabase(ctx, dir, w)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to back up database: %w", err)
+	}
+
+	manifest.Storage, err = s.writeArtifact(keyring, dir, storageName, func(w io.Writer) (string, error) {
+		files, err := s.snapshotStorage(w)
+		for _, file := range files {
+			manifest.FileBytes += file.Size
+		}
+		manifest.Files = len(files)
+		return formatTarGzip, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to back up storage: %w", err)
+	}
+
+	manifest.FinishedAt = s.now().UTC()
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(dir, manifestName), data, 0o600); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(dir, filepath.Join(s.path, manifest.ID)); err != nil {
+		return nil, fmt.Errorf("failed to complete backup: %w", err)
+	}
+	complete = true
+
+	s.logger.Info("Backup created",
+		zap.String("backup_id", manifest.ID),
+		zap.Int64("database_bytes", manifest.Database.Size),
+		zap.Int64("storage_bytes", manifest.Storage.Size),
+		zap.Int("files", manifest.Files),
+	)
+
+	if err := s.prune(); err != nil {
+		s.logger.Error("Failed to remove old backups", zap.Error(err))
+	}
+	return manifest, nil
+}
+
+// writeArtifact encrypts what write produces into a new file of the set
+func (s *Service) writeArtifact(keyring *encryption.Keyring, dir, name string, write func(w io.Writer) (string, error)) (Artifact, error) {
+	dataKey := make([]byte, dataKeySize)
+	if _, err := io.ReadFull(rand.Reader, dataKey); err != nil {
+		return Artifact{}, fmt.Errorf("failed to generate data key: %w", err)
+	}
+	wrapped, err := keyring.Encrypt(dataKey)
+	if err != nil {
+		return Artifact{}, err
+	}
+
+	file, err := os.OpenFile(filepath.Join(dir, name), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+	if err != nil {
+		return Artifact{}, err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	counter := &countingWriter{w: io.MultiWriter(file, hash)}
+	encrypted, err := newEncryptWriter(counter, dataKey)
+	if err != nil {
+		return Artifact{}, err
+	}
+	format, err := write(encrypted)
+	if err != nil {
+		return Artifact{}, err
+	}
+	if err := encrypted.Close(); err != nil {
+		return Artifact{}, err
+	}
+	if err := file.Sync(); err != nil {
+		return Artifact{}, err
+	}
+
+	return Artifact{
+		File:   name,
+		Format: format,
+		Size:   counter.n,
+		SHA256: hex.EncodeToString(hash.Sum(nil)),
+		Key:    wrapped,
+	}, file.Close()
+}
+
+// openArtifact checks the checksum of an artifact and opens it for
+// decryption. The caller closes the returned file.
+func (s *Service) openArtifact(keyring *encryption.Keyring, dir string, artifact Artifact) (io.Reader, *os.File, error) {
+	file, err := os.Open(filepath.Join(dir, filepath.Base(artifact.File)))
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrCorrupt, err)
+	}
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		file.Close()
+		return nil, nil, err
+	}
+	if hex.EncodeToString(hash.Sum(nil)) != artifact.SHA256 {
+		file.Close()
+		return nil, nil, fmt.Errorf("%w: checksum of %s does not match", ErrCorrupt, artifact.File)
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		file.Close()
+		return nil, nil, err
+	}
+
+	dataKey, err := keyring.Decrypt(artifact.Key)
+	if err != nil {
+		file.Close()
+		return nil, nil, fmt.Errorf("failed to unwrap data key of %s: %w", artifact.File, err)
+	}
+	reader, err := newDecryptReader(file, dataKey)
+	if err != nil {
+		file.Close()
+		return nil, nil, err
+	}
+	return reader, file, nil
+}
+
+// List returns the backup sets, latest first
+func (s *Service) List() ([]Manifest, error) {
+	entries, err := os.ReadDir(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return []Manifest{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list backups: %w", err)
+	}
+
+	manifests := []Manifest{}
+	for _, entry := range entries {
+		if !entry.IsDir() || !idPattern.MatchString(entry.Name()) {
+			continue
+		}
+		manifest, err := s.Get(entry.Name())
+		if err != nil {
+			s.logger.Warn("Skipping unreadable backup", zap.String("backup_id", entry.Name()), zap.Error(err))
+			continue
+		}
+		manifests = append(manifests, *manifest)
+	}
+	sort.Slice(manifests, func(i, j int) bool {
+		return manifests[i].ID > manifests[j].ID
+	})
+	return manifests, nil
+}
+
+// Get returns the manifest of a backup set
+func (s *Service) Get(id string) (*Manifest, error) {
+	if !idPattern.MatchString(id) {
+		return nil, ErrBackupNotFound
+	}
+	data, err := os.ReadFile(filepath.Join(s.path, id, manifestName))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrBackupNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var manifest Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("%w: invalid manifest: %v", ErrCorrupt, err)
+	}
+	if manifest.ID != id {
+		return nil, fmt.Errorf("%w: manifest belongs to %s", ErrCorrupt, manifest.ID)
+	}
+	return &manifest, nil
+}
+
+// CheckRestore checks that a backup set exists and that restore targets
+// outside the live system are configured for it
+func (s *Service) CheckRestore(id string) (*Manifest, error) {
+	if encryption.Default() == nil {
+		return nil, ErrEncryptionDisabled
+	}
+	manifest, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.stagingDatabase == "" || s.stagingStorage == "" {
+		return nil, ErrNoRestoreTarget
+	}
+	if target, ok := parsePostgresURL(s.stagingDatabase); ok {
+		if manifest.Database.Format != formatPgDump {
+			return nil, fmt.Errorf("%w: a SQLite backup needs a SQLite restore database", ErrNoRestoreTarget)
+		}
+		if s.database.Driver == "postgres" && target.same(s.live) {
+			return nil, ErrRestoreToLive
+		}
+	} else {
+		if manifest.Database.Format != formatSQLite {
+			return nil, fmt.Errorf("%w: a PostgreSQL backup needs a postgres:// restore database", ErrNoRestoreTarget)
+		}
+		if s.database.Driver != "postgres" && overlaps(s.stagingDatabase, s.database.SQLitePath) {
+			return nil, ErrRestoreToLive
+		}
+	}
+	for _, root := range s.roots {
+		if overlaps(s.stagingStorage, root.path) {
+			return nil, ErrRestoreToLive
+		}
+	}
+	return manifest, nil
+}
+
+// RequestRestore queues the restore of a backup set after checking it. The
+// restore runs in the background, as it takes as long as the backup.
+func (s *Service) RequestRestore(id string, userID uuid.UUID) (*Manifest, error) {
+	manifest, err := s.CheckRestore(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.relay.Enqueue(s.db, outbox.TopicBackupRestore, id, outbox.BackupRestoreMessage{
+		BackupID:    id,
+		RequestedBy: userID,
+	}); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// Restore restores a backup set into the staging database and directory.
+// Both artifacts are checked against their checksums before anything is
+// written.
+func (s *Service) Restore(ctx context.Context, id string) (*RestoreResult, error) {
+	manifest, err := s.CheckRestore(id)
+	if err != nil {
+		return nil, err
+	}
+	if !s.running.TryLock() {
+		return nil, ErrBusy
+	}
+	defer s.running.Unlock()
+
+	keyring := encryption.Default()
+	dir := filepath.Join(s.path, id)
+	database, databaseFile, err := s.openArtifact(keyring, dir, manifest.Database)
+	if err != nil {
+		return nil, err
+	}
+	defer databaseFile.Close()
+	storage, storageFile, err := s.openArtifact(keyring, dir, manifest.Storage)
+	if err != nil {
+		return nil, err
+	}
+	defer storageFile.Close()
+
+	if err := s.restoreDatabase(ctx, manifest.Database.Format, database); err != nil {
+		return nil, fmt.Errorf("failed to restore database: %w", err)
+	}
+	files, err := s.restoreStorage(storage)
+	if err != nil {
+		return nil, fmt.Errorf("failed to restore storage: %w", err)
+	}
+
+	result := &RestoreResult{
+		BackupID:    id,
+		Database:    s.stagingDatabase,
+		StoragePath: s.stagingStorage,
+		Files:       files,
+	}
+	if target, ok := parsePostgresURL(s.stagingDatabase); ok {
+		result.Database = target.String()
+	}
+	s.logger.Info("Backup restored",
+		zap.String("backup_id", id),
+		zap.String("database", result.Database),
+		zap.String("storage_path", result.StoragePath),
+		zap.Int("files", files),
+	)
+	return result, nil
+}
+
+// prune removes the sets beyond the number kept and partial sets left by
+// interrupted backups
+func (s *Service) prune() error {
+	entries, err := os.ReadDir(s.path)
+	if err != nil {
+		return err
+	}
+
+	var ids []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.IsDir() {
+			continue
+		}
+		if id, ok := strings.CutSuffix(name, partialSuffix); ok && idPattern.MatchString(id) {
+			if started, err := time.Parse(idTimeLayout, id[:len(idTimeLayout)]); err == nil && s.now().Sub(started) > staleAfter {
+				if err := os.RemoveAll(filepath.Join(s.path, name)); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if idPattern.MatchString(name) {
+			ids = append(ids, name)
+		}
+	}
+
+	if s.keep <= 0 || len(ids) <= s.keep {
+		return nil
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(ids)))
+	for _, id := range ids[s.keep:] {
+		if err := os.RemoveAll(filepath.Join(s.path, id)); err != nil {
+			return err
+		}
+		s.logger.Info("Old backup removed", zap.String("backup_id", id))
+	}
+	return nil
+}
+
+// overlaps reports whether one path is the other or contains it
+func overlaps(a, b string) bool {
+	if a == "" || b == "" {
+		return false
+	}
+	absA, errA := filepath.Abs(a)
+	absB, errB := filepath.Abs(b)
+	if errA != nil || errB != nil {
+		return true
+	}
+	within := func(path, dir string) bool {
+		rel, err := filepath.Rel(dir, path)
+		return err == nil && filepath.IsLocal(rel)
+	}
+	return within(absA, absB) || within(absB, absA)
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
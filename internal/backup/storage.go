@@ -0,0 +1,185 @@
+package backup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// fileListName is the archive entry listing the snapshot's files. It is
+// written last, once every file was read, and checked on restore.
+const fileListName = "files.json"
+
+// storageRoot is a document directory in the storage snapshot. Its files are
+// archived under the root's name.
+type storageRoot struct {
+	name string
+	path string
+}
+
+// SnapshotFile is a file of a storage snapshot
+type SnapshotFile struct {
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// snapshotStorage writes a gzipped tar of the document directories to w.
+// Uploaded documents and generated PDFs are written once and never changed,
+// so a snapshot taken after the database dump contains every file the dump
+// refers to; files added in between are restored without a reference.
+func (s *Service) snapshotStorage(w io.Writer) ([]SnapshotFile, error) {
+	gz := gzip.NewWriter(w)
+	archive := tar.NewWriter(gz)
+
+	var files []SnapshotFile
+	for _, root := range s.roots {
+		err := filepath.WalkDir(root.path, func(file string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				// A document deleted while walking is not part of the snapshot
+				if errors.Is(err, fs.ErrNotExist) {
+					return nil
+				}
+				return err
+			}
+			if !entry.Type().IsRegular() {
+				return nil
+			}
+			rel, err := filepath.Rel(root.path, file)
+			if err != nil {
+				return err
+			}
+			name := path.Join(root.name, filepath.ToSlash(rel))
+			snapshot, err := addFile(archive, file, name)
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("failed to archive %s: %w", name, err)
+			}
+			files = append(files, *snapshot)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	list, err := json.Marshal(files)
+	if err != nil {
+		return nil, err
+	}
+	if err := archive.WriteHeader(&tar.Header{Name: fileListName, Mode: 0o600, Size: int64(len(list)), ModTime: s.now()}); err != nil {
+		return nil, err
+	}
+	if _, err := archive.Write(list); err != nil {
+		return nil, err
+	}
+	if err := archive.Close(); err != nil {
+		return nil, err
+	}
+	return files, gz.Close()
+}
+
+func addFile(archive *tar.Writer, file, name string) (*SnapshotFile, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	header, err := tar.FileInfoHeader(info, "")
+	if err != nil {
+		return nil, err
+	}
+	header.Name = name
+	if err := archive.WriteHeader(header); err != nil {
+		return nil, err
+	}
+	hash := sha256.New()
+	if _, err := io.CopyN(io.MultiWriter(archive, hash), f, info.Size()); err != nil {
+		return nil, err
+	}
+	return &SnapshotFile{Path: name, Size: info.Size(), SHA256: hex.EncodeToString(hash.Sum(nil))}, nil
+}
+
+// restoreStorage extracts a storage snapshot into the staging directory and
+// checks every file against the snapshot's file list. It returns the number
+// of files restored.
+func (s *Service) restoreStorage(r io.Reader) (int, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrCorrupt, err)
+	}
+	archive := tar.NewReader(gz)
+
+	restored := map[string]string{}
+	var list []SnapshotFile
+	for {
+		header, err := archive.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+		if header.Name == fileListName {
+			if err := json.NewDecoder(archive).Decode(&list); err != nil {
+				return 0, fmt.Errorf("%w: invalid file list: %v", ErrCorrupt, err)
+			}
+			continue
+		}
+		if header.Typeflag != tar.TypeReg || !filepath.IsLocal(header.Name) {
+			return 0, fmt.Errorf("%w: unexpected entry %q", ErrCorrupt, header.Name)
+		}
+
+		target := filepath.Join(s.stagingStorage, filepath.FromSlash(header.Name))
+		if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			return 0, err
+		}
+		sum, err := extractFile(archive, target, header.FileInfo().Mode().Perm())
+		if err != nil {
+			return 0, fmt.Errorf("failed to restore %s: %w", header.Name, err)
+		}
+		restored[header.Name] = sum
+	}
+
+	if list == nil && len(restored) > 0 {
+		return 0, fmt.Errorf("%w: file list is missing", ErrCorrupt)
+	}
+	for _, file := range list {
+		if restored[file.Path] != file.SHA256 {
+			return 0, fmt.Errorf("%w: %s does not match the snapshot", ErrCorrupt, file.Path)
+		}
+	}
+	if len(list) != len(restored) {
+		return 0, fmt.Errorf("%w: snapshot has files missing from its file list", ErrCorrupt)
+	}
+	return len(restored), nil
+}
+
+func extractFile(r io.Reader, target string, perm fs.FileMode) (string, error) {
+	file, err := os.OpenFile(target, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, perm|0o600)
+	if err != nil {
+		return "", err
+	}
+	hash := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(file, hash), r); err != nil {
+		file.Close()
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), file.Close()
+}
@@ -0,0 +1,194 @@
+package backup
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Artifacts are too large to encrypt in one piece, so they are sealed in
+// chunks with AES-256-GCM under a data key of their own:
+//
+//	<8 byte nonce prefix> { <4 byte header> <sealed chunk> }
+//
+// The header holds the length of the sealed chunk; its top bit marks the last
+// chunk. Every chunk's nonce is the prefix followed by the chunk number and
+// the header is authenticated with it, so chunks cannot be reordered, dropped
+// or appended and a truncated artifact fails instead of restoring partially.
+const (
+	chunkSize   = 64 << 10
+	prefixSize  = 8
+	headerSize  = 4
+	finalChunk  = 1 << 31
+	dataKeySize = 32
+)
+
+// encryptWriter encrypts everything written to it into w. Close seals the
+// last chunk and must be called for the artifact to be readable.
+type encryptWriter struct {
+	w       io.Writer
+	aead    cipher.AEAD
+	prefix  []byte
+	counter uint32
+	buf     []byte
+	closed  bool
+}
+
+func newEncryptWriter(w io.Writer, dataKey []byte) (*encryptWriter, error) {
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	prefix := make([]byte, prefixSize)
+	if _, err := io.ReadFull(rand.Reader, prefix); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	if _, err := w.Write(prefix); err != nil {
+		return nil, err
+	}
+	return &encryptWriter{w: w, aead: aead, prefix: prefix, buf: make([]byte, 0, chunkSize)}, nil
+}
+
+func (e *encryptWriter) Write(p []byte) (int, error) {
+	if e.closed {
+		return 0, errors.New("write to closed artifact")
+	}
+	written := 0
+	for len(p) > 0 {
+		// A full chunk is only sealed once more data follows, so the last
+		// chunk is always sealed by Close with the final flag
+		if len(e.buf) == chunkSize {
+			if err := e.seal(false); err != nil {
+				return written, err
+			}
+		}
+		n := copy(e.buf[len(e.buf):chunkSize], p)
+		e.buf = e.buf[:len(e.buf)+n]
+		p = p[n:]
+		written += n
+	}
+	return written, nil
+}
+
+func (e *encryptWriter) Close() error {
+	if e.closed {
+		return nil
+	}
+	e.closed = true
+	return e.seal(true)
+}
+
+func (e *encryptWriter) seal(final bool) error {
+	if e.counter == ^uint32(0) {
+		return errors.New("artifact too large")
+	}
+	length := uint32(len(e.buf) + e.aead.Overhead())
+	if final {
+		length |= finalChunk
+	}
+	header := binary.BigEndian.AppendUint32(nil, length)
+	sealed := e.aead.Seal(nil, chunkNonce(e.prefix, e.counter), e.buf, header)
+	e.counter++
+	e.buf = e.buf[:0]
+	if _, err := e.w.Write(header); err != nil {
+		return err
+	}
+	_, err := e.w.Write(sealed)
+	return err
+}
+
+// decryptReader decrypts an artifact written by encryptWriter. It returns
+// ErrCorrupt for tampered or truncated artifacts.
+type decryptReader struct {
+	r       io.Reader
+	aead    cipher.AEAD
+	prefix  []byte
+	counter uint32
+	buf     []byte
+	final   bool
+}
+
+func newDecryptReader(r io.Reader, dataKey []byte) (*decryptReader, error) {
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	prefix := make([]byte, prefixSize)
+	if _, err := io.ReadFull(r, prefix); err != nil {
+		return nil, fmt.Errorf("%w: missing nonce", ErrCorrupt)
+	}
+	return &decryptReader{r: r, aead: aead, prefix: prefix}, nil
+}
+
+func (d *decryptReader) Read(p []byte) (int, error) {
+	for len(d.buf) == 0 {
+		if d.final {
+			return 0, io.EOF
+		}
+		if err := d.open(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, d.buf)
+	d.buf = d.buf[n:]
+	return n, nil
+}
+
+func (d *decryptReader) open() error {
+	header := make([]byte, headerSize)
+	if _, err := io.ReadFull(d.r, header); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return fmt.Errorf("%w: artifact is truncated", ErrCorrupt)
+		}
+		return err
+	}
+	length := binary.BigEndian.Uint32(header)
+	final := length&finalChunk != 0
+	length &^= finalChunk
+	if length < uint32(d.aead.Overhead()) || length > chunkSize+uint32(d.aead.Overhead()) {
+		return fmt.Errorf("%w: invalid chunk length", ErrCorrupt)
+	}
+
+	sealed := make([]byte, length)
+	if _, err := io.ReadFull(d.r, sealed); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return fmt.Errorf("%w: artifact is truncated", ErrCorrupt)
+		}
+		return err
+	}
+	plaintext, err := d.aead.Open(sealed[:0], chunkNonce(d.prefix, d.counter), sealed, header)
+	if err != nil {
+		return fmt.Errorf("%w: chunk %d does not decrypt", ErrCorrupt, d.counter)
+	}
+	d.counter++
+	d.buf = plaintext
+
+	if final {
+		d.final = true
+		// Nothing may follow the last chunk
+		if _, err := io.ReadFull(d.r, make([]byte, 1)); err == nil {
+			return fmt.Errorf("%w: data after the last chunk", ErrCorrupt)
+		}
+	}
+	return nil
+}
+
+// chunkNonce is the nonce of the nth chunk
+func chunkNonce(prefix []byte, n uint32) []byte {
+	return binary.BigEndian.AppendUint32(append([]byte(nil), prefix...), n)
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	if len(key) != dataKeySize {
+		return nil, fmt.Errorf("%w: data key must be %d bytes", ErrCorrupt, dataKeySize)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,213 @@
+package beraters
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"elterngeld-portal/internal/models"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// ErrNoMatch is returned when no bookable Berater is available for a match
+var ErrNoMatch = errors.New("no berater available")
+
+// Weights of the match score. Topic expertise dominates, the region and language
+// break ties between experts, and workload spreads leads over equally suited Beraters.
+const (
+	topicWeight      = 10.0
+	bundeslandWeight = 4.0
+	languageWeight   = 3.0 // halved for another language than the customer's preferred one
+	ratingWeight     = 0.5 // per rating star
+	openLeadPenalty  = 0.5 // per open lead
+)
+
+// MatchCriteria describes the customer's situation a Berater is matched against
+type MatchCriteria struct {
+	Topics     []models.Specialization
+	Bundesland models.Bundesland
+	Languages  []string // preferred first
+}
+
+// Suggestion is a Berater ranked for a customer's situation
+type Suggestion struct {
+	Berater       models.BeraterProfile   `json:"berater"`
+	Score         float64                 `json:"score"`
+	MatchedTopics []models.Specialization `json:"matched_topics"`
+	OpenLeads     int64                   `json:"open_leads"`
+
+	// Language is the customer's language the consultation can be held in;
+	// without one the consultation needs an interpreter
+	Language            string `json:"language,omitempty"`
+	InterpreterRequired bool   `json:"interpreter_required"`
+}
+
+// Suggest ranks all bookable Beraters for the criteria, best match first. A limit
+// of zero or less returns all Beraters.
+func (s *Service) Suggest(criteria MatchCriteria, limit int) ([]Suggestion, error) {
+	beraters, err := s.bookable(s.db)
+	if err != nil {
+		return nil, err
+	}
+	if len(beraters) == 0 {
+		return []Suggestion{}, nil
+	}
+
+	ids := make([]uuid.UUID, 0, len(beraters))
+	for _, berater := range beraters {
+		ids = append(ids, berater.ID)
+	}
+	ratings, err := s.Ratings(ids)
+	if err != nil {
+		return nil, err
+	}
+	openLeads, err := s.openLeads(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	suggestions := make([]Suggestion, 0, len(beraters))
+	for _, berater := range beraters {
+		suggestion := Suggestion{
+			Berater:       berater.ToPublicProfile(ratings[berater.ID]),
+			MatchedTopics: []models.Specialization{},
+			OpenLeads:     openLeads[berater.ID],
+		}
+
+		for _, topic := range criteria.Topics {
+			if hasSpecialization(&berater, topic) {
+				suggestion.MatchedTopics = append(suggestion.MatchedTopics, topic)
+				suggestion.Score += topicWeight
+			}
+		}
+		if criteria.Bundesland != "" && servesBundesland(&berater, criteria.Bundesland) {
+			suggestion.Score += bundeslandWeight
+		}
+		if len(criteria.Languages) > 0 {
+			suggestion.Language = models.SharedLanguage(criteria.Languages, berater.GetWorkingLanguages())
+			switch {
+			case suggestion.Language == "":
+				suggestion.InterpreterRequired = true
+			case strings.EqualFold(suggestion.Language, criteria.Languages[0]):
+				suggestion.Score += languageWeight
+			default:
+				suggestion.Score += languageWeight / 2
+			}
+		}
+		if rating := ratings[berater.ID]; rating != nil {
+			suggestion.Score += rating.Average * ratingWeight
+		}
+		suggestion.Score -= float64(suggestion.OpenLeads) * openLeadPenalty
+
+		suggestions = append(suggestions, suggestion)
+	}
+
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		if suggestions[i].Score != suggestions[j].Score {
+			return suggestions[i].Score > suggestions[j].Score
+		}
+		return suggestions[i].OpenLeads < suggestions[j].OpenLeads
+	})
+
+	if limit > 0 && len(suggestions) > limit {
+		suggestions = suggestions[:limit]
+	}
+	return suggestions, nil
+}
+
+// CriteriaForLead derives the match criteria from a lead's topics and its customer
+func (s *Service) CriteriaForLead(lead *models.Lead) (MatchCriteria, error) {
+	criteria := MatchCriteria{Topics: lead.GetTopics()}
+
+	var customer models.User
+	if err := s.db.Select("id", "bundesland", "postal_code", "language", "spoken_languages").First(&customer, "id = ?", lead.UserID).Error; err != nil {
+		return criteria, fmt.Errorf("failed to load customer: %w", err)
+	}
+	criteria.Bundesland = customer.Bundesland
+	// Customers who have not completed their address yet are routed by postal code
+	if criteria.Bundesland == "" && customer.PostalCode != "" && s.postalCodes != nil {
+		state, err := s.postalCodes.Bundesland(customer.PostalCode)
+		if err != nil {
+			s.logger.Warn("Failed to derive Bundesland from postal code", zap.String("lead_id", lead.ID.String()), zap.Error(err))
+		}
+		criteria.Bundesland = state
+	}
+	criteria.Languages = customer.PreferredLanguages()
+
+	return criteria, nil
+}
+
+// AutoAssign assigns the best matching Berater to a lead and returns them together
+// with the winning suggestion. record is called in the transaction of the
+// assignment, e.g. to record it in the activity history, and may be nil.
+func (s *Service) AutoAssign(lead *models.Lead, record func(tx *gorm.DB, berater *models.User) error) (*models.User, *Suggestion, error) {
+	criteria, err := s.CriteriaForLead(lead)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	suggestions, err := s.Suggest(criteria, 1)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(suggestions) == 0 {
+		return nil, nil, ErrNoMatch
+	}
+	best := suggestions[0]
+
+	var berater models.User
+	if err := s.db.First(&berater, "id = ?", best.Berater.ID).Error; err != nil {
+		return nil, nil, fmt.Errorf("failed to load berater: %w", err)
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Lead{}).Where("id = ?", lead.ID).Updates(map[string]interface{}{
+			"berater_id": berater.ID,
+			"updated_at": time.Now(),
+		}).Error; err != nil {
+			return fmt.Errorf("failed to assign lead: %w", err)
+		}
+		if record != nil {
+			return record(tx, &berater)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	lead.BeraterID = &berater.ID
+
+	s.logger.Info("Lead auto-assigned",
+		zap.String("lead_id", lead.ID.String()),
+		zap.String("berater_id", berater.ID.String()),
+		zap.Float64("score", best.Score))
+
+	return &berater, &best, nil
+}
+
+// openLeads counts the leads per Berater that are neither completed nor cancelled
+func (s *Service) openLeads(beraterIDs []uuid.UUID) (map[uuid.UUID]int64, error) {
+	var rows []struct {
+		BeraterID uuid.UUID
+		Count     int64
+	}
+	if err := s.db.Model(&models.Lead{}).
+		Select("berater_id, COUNT(*) AS count").
+		Where("berater_id IN ? AND status NOT IN ?", beraterIDs,
+			[]models.LeadStatus{models.LeadStatusCompleted, models.LeadStatusCancelled}).
+		Group("berater_id").
+		Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to count open leads: %w", err)
+	}
+
+	counts := make(map[uuid.UUID]int64, len(rows))
+	for _, row := range rows {
+		counts[row.BeraterID] = row.Count
+	}
+	return counts, nil
+}
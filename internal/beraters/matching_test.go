@@ -0,0 +1,183 @@
+package beraters
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/internal/postalcodes"
+	"elterngeld-portal/tests/testutils"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+func TestSuggest_RanksByTopicsRegionAndLanguage(t *testing.T) {
+	db, service := setupTestService(t)
+
+	beamte := createBerater(t, db, "Anna", models.OnboardingStatusApproved, func(u *models.User) {
+		u.SetSpecializations([]models.Specialization{models.SpecializationBeamte, models.SpecializationZwillinge})
+		u.SetServiceBundeslaender([]models.Bundesland{models.BundeslandBerlin})
+		u.SetWorkingLanguages([]string{"de"})
+	})
+	selbststaendige := createBerater(t, db, "Bernd", models.OnboardingStatusApproved, func(u *models.User) {
+		u.SetSpecializations([]models.Specialization{models.SpecializationSelbststaendige})
+		u.SetServiceBundeslaender([]models.Bundesland{models.BundeslandBayern})
+		u.SetWorkingLanguages([]string{"de", "en"})
+	})
+	createBerater(t, db, "Clara", models.OnboardingStatusProfilePending, func(u *models.User) {
+		u.SetSpecializations([]models.Specialization{models.SpecializationBeamte, models.SpecializationZwillinge})
+	})
+
+	suggestions, err := service.Suggest(MatchCriteria{
+		Topics: []models.Specialization{models.SpecializationBeamte, models.SpecializationZwillinge},
+	}, 0)
+	require.NoError(t, err)
+	require.Len(t, suggestions, 2)
+	assert.Equal(t, beamte.ID, suggestions[0].Berater.ID)
+	assert.Equal(t, []models.Specialization{models.SpecializationBeamte, models.SpecializationZwillinge}, suggestions[0].MatchedTopics)
+	assert.Equal(t, 20.0, suggestions[0].Score)
+
+	// Region and language add to the topic score; languages match case-insensitively
+	suggestions, err = service.Suggest(MatchCriteria{
+		Topics:     []models.Specialization{models.SpecializationSelbststaendige},
+		Bundesland: models.BundeslandBayern,
+		Languages:  []string{"EN"},
+	}, 1)
+	require.NoError(t, err)
+	require.Len(t, suggestions, 1)
+	assert.Equal(t, selbststaendige.ID, suggestions[0].Berater.ID)
+	assert.Equal(t, 17.0, suggestions[0].Score)
+	assert.Equal(t, "en", suggestions[0].Language)
+}
+
+func TestSuggest_FlagsInterpreter(t *testing.T) {
+	db, service := setupTestService(t)
+
+	german := createBerater(t, db, "Anna", models.OnboardingStatusApproved, func(u *models.User) {
+		u.SetWorkingLanguages([]string{"de"})
+	})
+	english := createBerater(t, db, "Bernd", models.OnboardingStatusApproved, func(u *models.User) {
+		u.SetWorkingLanguages([]string{"de", "en"})
+	})
+
+	// Another of the customer's languages counts half
+	suggestions, err := service.Suggest(MatchCriteria{Languages: []string{"tr", "en"}}, 0)
+	require.NoError(t, err)
+	require.Len(t, suggestions, 2)
+	assert.Equal(t, english.ID, suggestions[0].Berater.ID)
+	assert.Equal(t, 1.5, suggestions[0].Score)
+	assert.Equal(t, "en", suggestions[0].Language)
+	assert.False(t, suggestions[0].InterpreterRequired)
+	assert.Equal(t, german.ID, suggestions[1].Berater.ID)
+	assert.Empty(t, suggestions[1].Language)
+	assert.True(t, suggestions[1].InterpreterRequired)
+}
+
+func TestSuggest_PrefersLowerWorkload(t *testing.T) {
+	db, service := setupTestService(t)
+
+	busy := createBerater(t, db, "Anna", models.OnboardingStatusApproved, nil)
+	free := createBerater(t, db, "Bernd", models.OnboardingStatusApproved, nil)
+	createLead(t, db, &busy.ID, models.LeadStatusInProgress, nil)
+	createLead(t, db, &busy.ID, models.LeadStatusNew, nil)
+	// Closed leads do not count towards the workload
+	createLead(t, db, &free.ID, models.LeadStatusCompleted, nil)
+
+	suggestions, err := service.Suggest(MatchCriteria{}, 0)
+	require.NoError(t, err)
+	require.Len(t, suggestions, 2)
+	assert.Equal(t, free.ID, suggestions[0].Berater.ID)
+	assert.Equal(t, int64(0), suggestions[0].OpenLeads)
+	assert.Equal(t, int64(2), suggestions[1].OpenLeads)
+}
+
+func TestAutoAssign(t *testing.T) {
+	db, service := setupTestService(t)
+
+	createBerater(t, db, "Anna", models.OnboardingStatusApproved, func(u *models.User) {
+		u.SetSpecializations([]models.Specialization{models.SpecializationSelbststaendige})
+	})
+	expert := createBerater(t, db, "Bernd", models.OnboardingStatusApproved, func(u *models.User) {
+		u.SetSpecializations([]models.Specialization{models.SpecializationWiderspruch})
+	})
+
+	lead := createLead(t, db, nil, models.LeadStatusNew, []models.Specialization{models.SpecializationWiderspruch})
+
+	berater, suggestion, err := service.AutoAssign(lead, nil)
+	require.NoError(t, err)
+	assert.Equal(t, expert.ID, berater.ID)
+	assert.Equal(t, []models.Specialization{models.SpecializationWiderspruch}, suggestion.MatchedTopics)
+
+	var stored models.Lead
+	require.NoError(t, db.First(&stored, "id = ?", lead.ID).Error)
+	require.NotNil(t, stored.BeraterID)
+	assert.Equal(t, expert.ID, *stored.BeraterID)
+}
+
+func TestAutoAssign_RecordFailureRollsBack(t *testing.T) {
+	db, service := setupTestService(t)
+
+	createBerater(t, db, "Anna", models.OnboardingStatusApproved, nil)
+	lead := createLead(t, db, nil, models.LeadStatusNew, nil)
+
+	_, _, err := service.AutoAssign(lead, func(tx *gorm.DB, berater *models.User) error {
+		return errors.New("activity not recorded")
+	})
+	require.Error(t, err)
+	assert.Nil(t, lead.BeraterID)
+
+	var stored models.Lead
+	require.NoError(t, db.First(&stored, "id = ?", lead.ID).Error)
+	assert.Nil(t, stored.BeraterID)
+}
+
+func TestAutoAssign_NoBerater(t *testing.T) {
+	db, service := setupTestService(t)
+
+	lead := createLead(t, db, nil, models.LeadStatusNew, nil)
+
+	_, _, err := service.AutoAssign(lead, nil)
+	assert.ErrorIs(t, err, ErrNoMatch)
+}
+
+func TestCriteriaForLead_DerivesBundeslandFromPostalCode(t *testing.T) {
+	db, service := setupTestService(t)
+	require.NoError(t, db.AutoMigrate(&models.PostalCodeArea{}))
+	service.postalCodes = postalcodes.NewService(db, zap.NewNop())
+	_, err := service.postalCodes.Import(strings.NewReader("plz,ort,bundesland\n80331,München,Bayern\n"))
+	require.NoError(t, err)
+
+	lead := createLead(t, db, nil, models.LeadStatusNew, []models.Specialization{models.SpecializationBeamte})
+	require.NoError(t, db.Model(&models.User{}).Where("id = ?", lead.UserID).Update("postal_code", "80331").Error)
+
+	criteria, err := service.CriteriaForLead(lead)
+	require.NoError(t, err)
+	assert.Equal(t, models.BundeslandBayern, criteria.Bundesland)
+	assert.Equal(t, []models.Specialization{models.SpecializationBeamte}, criteria.Topics)
+
+	// The Bundesland of the customer's address wins
+	require.NoError(t, db.Model(&models.User{}).Where("id = ?", lead.UserID).Update("bundesland", models.BundeslandBerlin).Error)
+	criteria, err = service.CriteriaForLead(lead)
+	require.NoError(t, err)
+	assert.Equal(t, models.BundeslandBerlin, criteria.Bundesland)
+}
+
+func createLead(t *testing.T, db *gorm.DB, beraterID *uuid.UUID, status models.LeadStatus, topics []models.Specialization) *models.Lead {
+	t.Helper()
+	customer := testutils.CreateTestUser(t, db, models.RoleUser)
+
+	lead := &models.Lead{
+		UserID:    customer.ID,
+		BeraterID: beraterID,
+		Title:     "Elterngeldantrag",
+		Status:    status,
+	}
+	lead.SetTopics(topics)
+	require.NoError(t, db.Create(lead).Error)
+	return lead
+}
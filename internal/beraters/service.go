@@ -0,0 +1,168 @@
+package beraters
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/internal/postalcodes"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// ErrNotFound is returned when no bookable Berater matches an ID
+var ErrNotFound = errors.New("berater not found")
+
+// Filter narrows down the list of bookable Beraters. Empty fields match all Beraters.
+type Filter struct {
+	Specialization models.Specialization
+	Bundesland     models.Bundesland
+	Language       string
+}
+
+// Service provides the public, customer facing view on Beraters
+type Service struct {
+	db          *gorm.DB
+	logger      *zap.Logger
+	postalCodes *postalcodes.Service
+}
+
+func NewService(db *gorm.DB, logger *zap.Logger, postalCodeService *postalcodes.Service) *Service {
+	return &Service{
+		db:          db,
+		logger:      logger,
+		postalCodes: postalCodeService,
+	}
+}
+
+// List returns the public profiles of all bookable Beraters matching the filter
+func (s *Service) List(filter Filter) ([]models.BeraterProfile, error) {
+	beraters, err := s.bookable(s.db)
+	if err != nil {
+		return nil, err
+	}
+
+	matching := make([]models.User, 0, len(beraters))
+	for _, berater := range beraters {
+		if filter.matches(&berater) {
+			matching = append(matching, berater)
+		}
+	}
+
+	return s.profiles(matching)
+}
+
+// Profile returns the public profile of a bookable Berater
+func (s *Service) Profile(beraterID uuid.UUID) (*models.BeraterProfile, error) {
+	beraters, err := s.bookable(s.db.Where("id = ?", beraterID))
+	if err != nil {
+		return nil, err
+	}
+	if len(beraters) == 0 {
+		return nil, ErrNotFound
+	}
+
+	profiles, err := s.profiles(beraters)
+	if err != nil {
+		return nil, err
+	}
+	return &profiles[0], nil
+}
+
+// Ratings returns the aggregated customer ratings per Berater. Beraters without
+// ratings are missing from the result.
+func (s *Service) Ratings(beraterIDs []uuid.UUID) (map[uuid.UUID]*models.BeraterRating, error) {
+	ratings := make(map[uuid.UUID]*models.BeraterRating, len(beraterIDs))
+	if len(beraterIDs) == 0 {
+		return ratings, nil
+	}
+
+	var rows []models.BeraterRating
+	if err := s.db.Model(&models.Booking{}).
+		Select("berater_id, AVG(rating) AS average, COUNT(rating) AS count").
+		Where("berater_id IN ? AND rating IS NOT NULL", beraterIDs).
+		Group("berater_id").
+		Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to aggregate ratings: %w", err)
+	}
+
+	for i := range rows {
+		rows[i].Average = math.Round(rows[i].Average*10) / 10
+		ratings[rows[i].BeraterID] = &rows[i]
+	}
+	return ratings, nil
+}
+
+func (s *Service) bookable(query *gorm.DB) ([]models.User, error) {
+	var beraters []models.User
+	if err := query.Where("role IN ? AND is_active = ? AND onboarding_status = ?",
+		[]models.UserRole{models.RoleBerater, models.RoleJuniorBerater}, true, models.OnboardingStatusApproved).
+		Order("first_name ASC, last_name ASC").
+		Find(&beraters).Error; err != nil {
+		return nil, fmt.Errorf("failed to load beraters: %w", err)
+	}
+	return beraters, nil
+}
+
+func (s *Service) profiles(beraters []models.User) ([]models.BeraterProfile, error) {
+	ids := make([]uuid.UUID, 0, len(beraters))
+	for _, berater := range beraters {
+		ids = append(ids, berater.ID)
+	}
+
+	ratings, err := s.Ratings(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	profiles := make([]models.BeraterProfile, 0, len(beraters))
+	for _, berater := range beraters {
+		profiles = append(profiles, berater.ToPublicProfile(ratings[berater.ID]))
+	}
+	return profiles, nil
+}
+
+// Profile lists are stored as JSON text, so filters are applied in memory
+func (f Filter) matches(berater *models.User) bool {
+	if f.Specialization != "" && !hasSpecialization(berater, f.Specialization) {
+		return false
+	}
+	if f.Language != "" && !containsFold(berater.GetWorkingLanguages(), f.Language) {
+		return false
+	}
+	if f.Bundesland != "" && !servesBundesland(berater, f.Bundesland) {
+		return false
+	}
+	return true
+}
+
+func hasSpecialization(berater *models.User, specialization models.Specialization) bool {
+	for _, s := range berater.GetSpecializations() {
+		if s == specialization {
+			return true
+		}
+	}
+	return false
+}
+
+func servesBundesland(berater *models.User, state models.Bundesland) bool {
+	for _, s := range berater.GetServiceBundeslaender() {
+		if s == state {
+			return true
+		}
+	}
+	return false
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,123 @@
+package beraters
+
+import (
+	"testing"
+	"time"
+
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/tests/testutils"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+func TestList_OnlyBookableBeraters(t *testing.T) {
+	db, service := setupTestService(t)
+
+	anna := createBerater(t, db, "Anna", models.OnboardingStatusApproved, func(u *models.User) {
+		u.SetSpecializations([]models.Specialization{models.SpecializationSelbststaendige})
+		u.SetServiceBundeslaender([]models.Bundesland{models.BundeslandBayern})
+		u.SetWorkingLanguages([]string{"de", "en"})
+	})
+	bernd := createBerater(t, db, "Bernd", models.OnboardingStatusApproved, func(u *models.User) {
+		u.SetServiceBundeslaender([]models.Bundesland{models.BundeslandBerlin})
+		u.SetWorkingLanguages([]string{"de"})
+	})
+	createBerater(t, db, "Clara", models.OnboardingStatusApprovalPending, nil)
+	inactive := createBerater(t, db, "Dora", models.OnboardingStatusApproved, nil)
+	require.NoError(t, db.Model(inactive).Update("is_active", false).Error)
+
+	profiles, err := service.List(Filter{})
+	require.NoError(t, err)
+	require.Len(t, profiles, 2)
+	assert.Equal(t, anna.ID, profiles[0].ID)
+	assert.Equal(t, bernd.ID, profiles[1].ID)
+
+	profiles, err = service.List(Filter{Specialization: models.SpecializationSelbststaendige})
+	require.NoError(t, err)
+	require.Len(t, profiles, 1)
+	assert.Equal(t, anna.ID, profiles[0].ID)
+
+	profiles, err = service.List(Filter{Bundesland: models.BundeslandBerlin})
+	require.NoError(t, err)
+	require.Len(t, profiles, 1)
+	assert.Equal(t, bernd.ID, profiles[0].ID)
+
+	profiles, err = service.List(Filter{Language: "en"})
+	require.NoError(t, err)
+	require.Len(t, profiles, 1)
+	assert.Equal(t, anna.ID, profiles[0].ID)
+}
+
+func TestProfile_IncludesRating(t *testing.T) {
+	db, service := setupTestService(t)
+
+	berater := createBerater(t, db, "Anna", models.OnboardingStatusApproved, nil)
+	pending := createBerater(t, db, "Clara", models.OnboardingStatusProfilePending, nil)
+
+	profile, err := service.Profile(berater.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Anna Berater", profile.Name)
+	assert.Nil(t, profile.Rating)
+
+	for _, rating := range []int{5, 4, 4} {
+		createRatedBooking(t, db, berater.ID, rating)
+	}
+	// Unrated bookings do not count
+	createRatedBooking(t, db, berater.ID, 0)
+
+	profile, err = service.Profile(berater.ID)
+	require.NoError(t, err)
+	require.NotNil(t, profile.Rating)
+	assert.Equal(t, 4.3, profile.Rating.Average)
+	assert.Equal(t, int64(3), profile.Rating.Count)
+
+	_, err = service.Profile(pending.ID)
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = service.Profile(uuid.New())
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func createBerater(t *testing.T, db *gorm.DB, firstName string, status models.OnboardingStatus, modify func(*models.User)) *models.User {
+	t.Helper()
+	berater := &models.User{
+		Email:            firstName + "@example.com",
+		Password:         "berater-passwort",
+		FirstName:        firstName,
+		LastName:         "Berater",
+		Role:             models.RoleBerater,
+		IsActive:         true,
+		OnboardingStatus: status,
+	}
+	if modify != nil {
+		modify(berater)
+	}
+	require.NoError(t, db.Create(berater).Error)
+	return berater
+}
+
+func createRatedBooking(t *testing.T, db *gorm.DB, beraterID uuid.UUID, rating int) {
+	t.Helper()
+	booking := &models.Booking{
+		UserID:      uuid.New(),
+		BeraterID:   &beraterID,
+		Title:       "Beratung",
+		Status:      models.BookingStatusCompleted,
+		ScheduledAt: time.Now().Add(-48 * time.Hour),
+		Duration:    60,
+	}
+	if rating > 0 {
+		booking.Rating = &rating
+	}
+	require.NoError(t, db.Create(booking).Error)
+}
+
+func setupTestService(t *testing.T) (*gorm.DB, *Service) {
+	t.Helper()
+	db := testutils.NewSQLiteDB(t, &models.User{}, &models.Booking{}, &models.Lead{})
+
+	return db, NewService(db, zap.NewNop(), nil)
+}
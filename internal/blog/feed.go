@@ -0,0 +1,169 @@
+package blog
+
+import (
+	"encoding/xml"
+	"fmt"
+	"time"
+)
+
+const (
+	feedTitle       = "Elterngeld-Portal Blog"
+	feedDescription = "Neuigkeiten und Ratgeber rund um Elterngeld, ElterngeldPlus und Elternzeit"
+)
+
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Atom    string     `xml:"xmlns:atom,attr"`
+	DC      string     `xml:"xmlns:dc,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string   `xml:"title"`
+	Link          string   `xml:"link"`
+	Description   string   `xml:"description"`
+	Language      string   `xml:"language"`
+	LastBuildDate string   `xml:"lastBuildDate,omitempty"`
+	Self          atomLink `xml:"atom:link"`
+	Items         []rssItem
+}
+
+type rssItem struct {
+	XMLName     xml.Name `xml:"item"`
+	Title       string   `xml:"title"`
+	Link        string   `xml:"link"`
+	GUID        rssGUID  `xml:"guid"`
+	Description string   `xml:"description,omitempty"`
+	Category    string   `xml:"category,omitempty"`
+	Author      string   `xml:"dc:creator,omitempty"`
+	PubDate     string   `xml:"pubDate"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Updated string      `xml:"updated"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomEntry struct {
+	Title     string     `xml:"title"`
+	ID        string     `xml:"id"`
+	Link      atomLink   `xml:"link"`
+	Published string     `xml:"published"`
+	Updated   string     `xml:"updated"`
+	Summary   string     `xml:"summary,omitempty"`
+	Category  *atomTerm  `xml:"category,omitempty"`
+	Author    atomAuthor `xml:"author"`
+}
+
+type atomTerm struct {
+	Term string `xml:"term,attr"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+// RSS renders the latest public posts as RSS 2.0 feed
+func (s *Service) RSS() ([]byte, error) {
+	posts, _, err := s.Published("", 1, feedSize)
+	if err != nil {
+		return nil, err
+	}
+
+	feed := rssFeed{
+		Version: "2.0",
+		Atom:    "http://www.w3.org/2005/Atom",
+		DC:      "http://purl.org/dc/elements/1.1/",
+		Channel: rssChannel{
+			Title:       feedTitle,
+			Link:        s.blogURL(),
+			Description: feedDescription,
+			Language:    "de-DE",
+			Self:        atomLink{Href: s.baseURL + "/api/v1/blog/rss.xml", Rel: "self", Type: "application/rss+xml"},
+		},
+	}
+	if len(posts) > 0 {
+		feed.Channel.LastBuildDate = posts[0].PublishedAt.Format(time.RFC1123Z)
+	}
+	for _, post := range posts {
+		feed.Channel.Items = append(feed.Channel.Items, rssItem{
+			Title:       post.Title,
+			Link:        s.postURL(post.Slug),
+			GUID:        rssGUID{IsPermaLink: false, Value: "urn:uuid:" + post.ID.String()},
+			Description: post.Excerpt,
+			Category:    post.Category,
+			Author:      post.Author.Name,
+			PubDate:     post.PublishedAt.Format(time.RFC1123Z),
+		})
+	}
+	return marshalFeed(feed)
+}
+
+// Atom renders the latest public posts as Atom feed
+func (s *Service) Atom() ([]byte, error) {
+	posts, _, err := s.Published("", 1, feedSize)
+	if err != nil {
+		return nil, err
+	}
+
+	feed := atomFeed{
+		Title:   feedTitle,
+		ID:      s.blogURL(),
+		Updated: s.now().UTC().Format(time.RFC3339),
+		Links: []atomLink{
+			{Href: s.blogURL(), Rel: "alternate"},
+			{Href: s.baseURL + "/api/v1/blog/atom.xml", Rel: "self"},
+		},
+	}
+	if len(posts) > 0 {
+		feed.Updated = posts[0].PublishedAt.UTC().Format(time.RFC3339)
+	}
+	for _, post := range posts {
+		entry := atomEntry{
+			Title:     post.Title,
+			ID:        "urn:uuid:" + post.ID.String(),
+			Link:      atomLink{Href: s.postURL(post.Slug), Rel: "alternate"},
+			Published: post.PublishedAt.UTC().Format(time.RFC3339),
+			Updated:   post.UpdatedAt.UTC().Format(time.RFC3339),
+			Summary:   post.Excerpt,
+			Author:    atomAuthor{Name: post.Author.Name},
+		}
+		if post.Category != "" {
+			entry.Category = &atomTerm{Term: post.Category}
+		}
+		feed.Entries = append(feed.Entries, entry)
+	}
+	return marshalFeed(feed)
+}
+
+func (s *Service) blogURL() string {
+	return s.baseURL + "/blog"
+}
+
+func (s *Service) postURL(slug string) string {
+	return fmt.Sprintf("%s/blog/%s", s.baseURL, slug)
+}
+
+func marshalFeed(feed interface{}) ([]byte, error) {
+	body, err := xml.MarshalIndent(feed, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to render feed: %w", err)
+	}
+	return append([]byte(xml.Header), body...), nil
+}
@@ -0,0 +1,327 @@
+// Package blog manages the blog and news posts of the content-marketing site
+// and renders them as RSS and Atom feeds.
+package blog
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"elterngeld-portal/config"
+	"elterngeld-portal/internal/content"
+	"elterngeld-portal/internal/models"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	DefaultLimit = 10
+	MaxLimit     = 50
+
+	// feedSize is the number of latest posts in the feeds
+	feedSize = 20
+)
+
+var (
+	// ErrPostNotFound is returned for unknown posts and, on public reads, drafts and scheduled posts
+	ErrPostNotFound = errors.New("post not found")
+	// ErrInvalidSlug is returned for slugs other than lowercase words joined by dashes
+	ErrInvalidSlug = errors.New("invalid slug")
+	// ErrDuplicateSlug is returned when another post uses the slug
+	ErrDuplicateSlug = errors.New("slug already exists")
+	// ErrInvalidAuthor is returned when the author is not a Berater
+	ErrInvalidAuthor = errors.New("author must be a Berater")
+)
+
+// Service manages blog posts
+type Service struct {
+	db      *gorm.DB
+	logger  *zap.Logger
+	baseURL string
+	now     func() time.Time
+}
+
+func NewService(db *gorm.DB, logger *zap.Logger, cfg *config.Config) *Service {
+	return &Service{
+		db:      db,
+		logger:  logger,
+		baseURL: strings.TrimRight(cfg.App.BaseURL, "/"),
+		now:     time.Now,
+	}
+}
+
+// PostInput holds the editable fields of a post. Without a slug one is
+// generated from the title.
+type PostInput struct {
+	Slug          string    `json:"slug" binding:"max=150"`
+	Title         string    `json:"title" binding:"required,max=200"`
+	Excerpt       string    `json:"excerpt"`
+	Body          string    `json:"body" binding:"required"`
+	Category      string    `json:"category" binding:"max=100"`
+	CoverImageURL string    `json:"cover_image_url" binding:"omitempty,url,max=500"`
+	AuthorID      uuid.UUID `json:"author_id" binding:"required"`
+}
+
+// Filter narrows down the admin list. Empty fields do not filter.
+type Filter struct {
+	Status   models.PostStatus
+	Category string
+	AuthorID *uuid.UUID
+}
+
+// PostSummary is a post in public lists and feeds
+type PostSummary struct {
+	ID            uuid.UUID             `json:"id"`
+	Slug          string                `json:"slug"`
+	Title         string                `json:"title"`
+	Excerpt       string                `json:"excerpt,omitempty"`
+	Category      string                `json:"category,omitempty"`
+	CoverImageURL string                `json:"cover_image_url,omitempty"`
+	PublishedAt   time.Time             `json:"published_at"`
+	UpdatedAt     time.Time             `json:"updated_at"`
+	Author        models.BeraterProfile `json:"author"`
+}
+
+// PublicPost is a post with its Markdown body
+type PublicPost struct {
+	PostSummary
+	Body string `json:"body"`
+}
+
+// Category is a category with the number of public posts in it
+type Category struct {
+	Name  string `json:"name"`
+	Count int64  `json:"count"`
+}
+
+// List returns a page of posts for the admin, including drafts and scheduled posts
+func (s *Service) List(filter Filter, page, limit int) ([]models.Post, int64, error) {
+	query := s.db.Model(&models.Post{})
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.Category != "" {
+		query = query.Where("category = ?", filter.Category)
+	}
+	if filter.AuthorID != nil {
+		query = query.Where("author_id = ?", *filter.AuthorID)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count posts: %w", err)
+	}
+
+	posts := []models.Post{}
+	if err := query.Order("updated_at DESC").Offset((page - 1) * limit).Limit(limit).
+		Find(&posts).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to load posts: %w", err)
+	}
+	return posts, total, nil
+}
+
+// Get returns a post in any state
+func (s *Service) Get(id uuid.UUID) (*models.Post, error) {
+	var post models.Post
+	if err := s.db.First(&post, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPostNotFound
+		}
+		return nil, err
+	}
+	return &post, nil
+}
+
+// Create adds a draft post
+func (s *Service) Create(input PostInput, createdBy uuid.UUID) (*models.Post, error) {
+	post := &models.Post{
+		Status:    models.PostStatusDraft,
+		CreatedBy: createdBy,
+	}
+	if err := s.apply(post, input, createdBy); err != nil {
+		return nil, err
+	}
+
+	if err := s.db.Create(post).Error; err != nil {
+		return nil, fmt.Errorf("failed to create post: %w", err)
+	}
+	return post, nil
+}
+
+// Update replaces the fields of a post. Changes to a public post are visible right away.
+func (s *Service) Update(id uuid.UUID, input PostInput, updatedBy uuid.UUID) (*models.Post, error) {
+	post, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.apply(post, input, updatedBy); err != nil {
+		return nil, err
+	}
+
+	if err := s.db.Select("slug", "title", "excerpt", "body", "category", "cover_image_url", "author_id", "updated_by").
+		Updates(post).Error; err != nil {
+		return nil, fmt.Errorf("failed to update post: %w", err)
+	}
+	return post, nil
+}
+
+// Publish makes a post public at the given time, or right away without one.
+// A time in the future schedules the post; publishing again reschedules it.
+func (s *Service) Publish(id uuid.UUID, at *time.Time, publishedBy uuid.UUID) (*models.Post, error) {
+	post, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+
+	publishAt := s.now()
+	if at != nil {
+		publishAt = *at
+	}
+	post.Status = models.PostStatusPublished
+	post.PublishedAt = &publishAt
+	post.UpdatedBy = publishedBy
+	if err := s.db.Model(post).Select("status", "published_at", "updated_by").Updates(post).Error; err != nil {
+		return nil, fmt.Errorf("failed to publish post: %w", err)
+	}
+	return post, nil
+}
+
+// Unpublish turns a published or scheduled post back into a draft
+func (s *Service) Unpublish(id uuid.UUID, updatedBy uuid.UUID) (*models.Post, error) {
+	post, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+
+	post.Status = models.PostStatusDraft
+	post.PublishedAt = nil
+	post.UpdatedBy = updatedBy
+	if err := s.db.Model(post).Select("status", "published_at", "updated_by").Updates(post).Error; err != nil {
+		return nil, fmt.Errorf("failed to unpublish post: %w", err)
+	}
+	return post, nil
+}
+
+// Delete removes a post
+func (s *Service) Delete(id uuid.UUID) error {
+	result := s.db.Delete(&models.Post{}, "id = ?", id)
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete post: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrPostNotFound
+	}
+	return nil
+}
+
+// Published returns a page of public posts, newest first
+func (s *Service) Published(category string, page, limit int) ([]PostSummary, int64, error) {
+	query := s.public()
+	if category != "" {
+		query = query.Where("category = ?", category)
+	}
+
+	var total int64
+	if err := query.Model(&models.Post{}).Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count posts: %w", err)
+	}
+
+	var posts []models.Post
+	if err := query.Preload("Author").Order("published_at DESC").Offset((page - 1) * limit).Limit(limit).
+		Find(&posts).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to load posts: %w", err)
+	}
+
+	summaries := make([]PostSummary, len(posts))
+	for i := range posts {
+		summaries[i] = summary(&posts[i])
+	}
+	return summaries, total, nil
+}
+
+// PublishedBySlug returns a public post
+func (s *Service) PublishedBySlug(slug string) (*PublicPost, error) {
+	var post models.Post
+	if err := s.public().Preload("Author").Where("slug = ?", slug).First(&post).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPostNotFound
+		}
+		return nil, err
+	}
+	return &PublicPost{PostSummary: summary(&post), Body: post.Body}, nil
+}
+
+// Categories returns the categories of public posts by name
+func (s *Service) Categories() ([]Category, error) {
+	categories := []Category{}
+	if err := s.public().Model(&models.Post{}).Where("category <> ''").
+		Select("category AS name, COUNT(*) AS count").Group("category").Order("category ASC").
+		Scan(&categories).Error; err != nil {
+		return nil, fmt.Errorf("failed to load categories: %w", err)
+	}
+	return categories, nil
+}
+
+// public limits a query to published posts whose publication time has come
+func (s *Service) public() *gorm.DB {
+	return s.db.Where("status = ? AND published_at <= ?", models.PostStatusPublished, s.now())
+}
+
+// apply validates the input and copies it to the post
+func (s *Service) apply(post *models.Post, input PostInput, updatedBy uuid.UUID) error {
+	slug := strings.TrimSpace(input.Slug)
+	if slug == "" {
+		slug = content.Slugify(input.Title)
+	}
+	if !content.IsValidSlug(slug) {
+		return ErrInvalidSlug
+	}
+
+	var count int64
+	if err := s.db.Model(&models.Post{}).Where("slug = ? AND id <> ?", slug, post.ID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrDuplicateSlug
+	}
+
+	var author models.User
+	if err := s.db.Select("id", "role").Where("id = ?", input.AuthorID).Limit(1).Find(&author).Error; err != nil {
+		return err
+	}
+	if !author.IsBerater() && !author.IsJuniorBerater() {
+		return ErrInvalidAuthor
+	}
+
+	post.Slug = slug
+	post.Title = strings.TrimSpace(input.Title)
+	post.Excerpt = strings.TrimSpace(input.Excerpt)
+	post.Body = input.Body
+	post.Category = strings.TrimSpace(input.Category)
+	post.CoverImageURL = strings.TrimSpace(input.CoverImageURL)
+	post.AuthorID = author.ID
+	post.UpdatedBy = updatedBy
+	return nil
+}
+
+func summary(post *models.Post) PostSummary {
+	result := PostSummary{
+		ID:            post.ID,
+		Slug:          post.Slug,
+		Title:         post.Title,
+		Excerpt:       post.Excerpt,
+		Category:      post.Category,
+		CoverImageURL: post.CoverImageURL,
+		UpdatedAt:     post.UpdatedAt,
+	}
+	if post.PublishedAt != nil {
+		result.PublishedAt = *post.PublishedAt
+	}
+	if post.Author != nil {
+		result.Author = post.Author.ToPublicProfile(nil)
+	}
+	return result
+}
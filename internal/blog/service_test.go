@@ -0,0 +1,140 @@
+package blog
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"elterngeld-portal/config"
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/tests/testutils"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+func TestPublish_Scheduled(t *testing.T) {
+	service, db := setupTestService(t)
+	author := testutils.CreateTestUser(t, db, models.RoleBerater)
+	admin := uuid.New()
+
+	now := time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)
+	service.now = func() time.Time { return now }
+
+	post, err := service.Create(PostInput{
+		Title:    "ElterngeldPlus richtig kombinieren",
+		Excerpt:  "So holen Eltern das Maximum heraus.",
+		Body:     "...",
+		Category: "Ratgeber",
+		AuthorID: author.ID,
+	}, admin)
+	require.NoError(t, err)
+	assert.Equal(t, models.PostStatusDraft, post.Status)
+	assert.Equal(t, "elterngeldplus-richtig-kombinieren", post.Slug)
+
+	_, err = service.PublishedBySlug(post.Slug)
+	assert.ErrorIs(t, err, ErrPostNotFound)
+
+	publishAt := now.Add(2 * time.Hour)
+	post, err = service.Publish(post.ID, &publishAt, admin)
+	require.NoError(t, err)
+	assert.True(t, post.IsScheduled(now))
+
+	// Scheduled posts are not public before their time
+	posts, total, err := service.Published("", 1, DefaultLimit)
+	require.NoError(t, err)
+	assert.Empty(t, posts)
+	assert.Zero(t, total)
+
+	now = now.Add(3 * time.Hour)
+	found, err := service.PublishedBySlug(post.Slug)
+	require.NoError(t, err)
+	assert.Equal(t, post.ID, found.ID)
+	assert.Equal(t, author.FullName(), found.Author.Name)
+
+	_, err = service.Unpublish(post.ID, admin)
+	require.NoError(t, err)
+	_, err = service.PublishedBySlug(post.Slug)
+	assert.ErrorIs(t, err, ErrPostNotFound)
+}
+
+func TestCreate_Validation(t *testing.T) {
+	service, db := setupTestService(t)
+	berater := testutils.CreateTestUser(t, db, models.RoleBerater)
+	customer := testutils.CreateTestUser(t, db, models.RoleUser)
+	admin := uuid.New()
+
+	_, err := service.Create(PostInput{Title: "Neu ab 2025", Body: "...", AuthorID: berater.ID}, admin)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		input    PostInput
+		expected error
+	}{
+		{"duplicate slug", PostInput{Slug: "neu-ab-2025", Title: "Kopie", Body: "...", AuthorID: berater.ID}, ErrDuplicateSlug},
+		{"invalid slug", PostInput{Slug: "Mit Leerzeichen", Title: "Post", Body: "...", AuthorID: berater.ID}, ErrInvalidSlug},
+		{"customer as author", PostInput{Title: "Gastbeitrag", Body: "...", AuthorID: customer.ID}, ErrInvalidAuthor},
+		{"unknown author", PostInput{Title: "Unbekannt", Body: "...", AuthorID: uuid.New()}, ErrInvalidAuthor},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := service.Create(tt.input, admin)
+			assert.ErrorIs(t, err, tt.expected)
+		})
+	}
+}
+
+func TestCategoriesAndFeeds(t *testing.T) {
+	service, db := setupTestService(t)
+	author := testutils.CreateTestUser(t, db, models.RoleJuniorBerater)
+	admin := uuid.New()
+
+	for i, category := range []string{"News", "Ratgeber", "Ratgeber", ""} {
+		post, err := service.Create(PostInput{
+			Title:    fmt.Sprintf("Beitrag %d", i+1),
+			Excerpt:  "Kurz & knapp",
+			Body:     "...",
+			Category: category,
+			AuthorID: author.ID,
+		}, admin)
+		require.NoError(t, err)
+		publishAt := time.Now().Add(time.Duration(i-10) * time.Minute)
+		_, err = service.Publish(post.ID, &publishAt, admin)
+		require.NoError(t, err)
+	}
+	draft, err := service.Create(PostInput{Title: "Entwurf", Body: "...", Category: "News", AuthorID: author.ID}, admin)
+	require.NoError(t, err)
+
+	categories, err := service.Categories()
+	require.NoError(t, err)
+	assert.Equal(t, []Category{{Name: "News", Count: 1}, {Name: "Ratgeber", Count: 2}}, categories)
+
+	posts, total, err := service.Published("Ratgeber", 1, DefaultLimit)
+	require.NoError(t, err)
+	assert.EqualValues(t, 2, total)
+	assert.Equal(t, "beitrag-3", posts[0].Slug)
+
+	rss, err := service.RSS()
+	require.NoError(t, err)
+	assert.Contains(t, string(rss), `<rss version="2.0"`)
+	assert.Contains(t, string(rss), "<link>https://elterngeld.example/blog/beitrag-4</link>")
+	assert.Contains(t, string(rss), "Kurz &amp; knapp")
+	assert.NotContains(t, string(rss), draft.Slug)
+
+	atom, err := service.Atom()
+	require.NoError(t, err)
+	assert.Contains(t, string(atom), `<feed xmlns="http://www.w3.org/2005/Atom">`)
+	assert.Contains(t, string(atom), `href="https://elterngeld.example/blog/beitrag-1"`)
+}
+
+func setupTestService(t *testing.T) (*Service, *gorm.DB) {
+	t.Helper()
+	db := testutils.NewSQLiteDB(t, &models.User{}, &models.Post{})
+
+	cfg := &config.Config{App: config.AppConfig{BaseURL: "https://elterngeld.example/"}}
+	return NewService(db, zap.NewNop(), cfg), db
+}
@@ -0,0 +1,39 @@
+package bookingstate
+
+import (
+	"elterngeld-portal/internal/activitylog"
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/internal/outbox"
+
+	"gorm.io/gorm"
+)
+
+// RecordActivity records status changes of bookings in the activity history
+// of their lead
+func RecordActivity(activityLog *activitylog.Service) Hook {
+	return func(tx *gorm.DB, booking *models.Booking, change Change) error {
+		if booking.LeadID == nil {
+			return nil
+		}
+		return activityLog.Record(tx, activitylog.BookingStatusChanged{
+			ActorID:   change.ActorID,
+			LeadID:    *booking.LeadID,
+			BookingID: booking.ID,
+			Reference: booking.BookingReference,
+			From:      change.From,
+			To:        change.To,
+			Note:      change.Note,
+		})
+	}
+}
+
+// SendConfirmation queues the confirmation email of a confirmed booking
+func SendConfirmation(outboxService *outbox.Service) Hook {
+	return func(tx *gorm.DB, booking *models.Booking, change Change) error {
+		message := outbox.PaymentMessage{BookingID: booking.ID}
+		if booking.PaymentID != nil {
+			message.PaymentID = *booking.PaymentID
+		}
+		return outboxService.Enqueue(tx, outbox.TopicEmailBookingConfirmation, booking.ID.String(), message)
+	}
+}
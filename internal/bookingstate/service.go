@@ -0,0 +1,244 @@
+// Package bookingstate is the state machine of bookings. Every status change
+// of a booking goes through it: it knows which transitions exist, checks
+// their guards (a booking is only confirmed once it is paid) and runs the
+// side effects registered for them, such as the confirmation email, in the
+// transaction of the change.
+package bookingstate
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"elterngeld-portal/internal/models"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrBookingNotFound is returned for transitions of unknown bookings
+	ErrBookingNotFound = errors.New("booking not found")
+	// ErrNotAllowed is returned for transitions the state machine does not have
+	ErrNotAllowed = errors.New("booking status transition not allowed")
+	// ErrPaymentRequired is returned when a booking with a price is confirmed before it was paid
+	ErrPaymentRequired = errors.New("booking must be paid before it is confirmed")
+	// ErrNoAppointment is returned when a booking without an appointment is confirmed
+	ErrNoAppointment = errors.New("booking has no appointment yet")
+	// ErrNotEnded is returned when a booking is completed or flagged as no-show before its appointment ended
+	ErrNotEnded = errors.New("booking has not ended yet")
+	// ErrStale is returned when the booking changed its status in the meantime
+	ErrStale = errors.New("booking status changed in the meantime")
+)
+
+// transitions are the status changes a booking can go through. A no-show
+// can still be corrected to completed by the Berater; completed and
+// cancelled bookings are final.
+var transitions = map[models.BookingStatus][]models.BookingStatus{
+	models.BookingStatusPending:   {models.BookingStatusConfirmed, models.BookingStatusCancelled},
+	models.BookingStatusConfirmed: {models.BookingStatusCompleted, models.BookingStatusCancelled, models.BookingStatusNoShow},
+	models.BookingStatusNoShow:    {models.BookingStatusCompleted},
+}
+
+// Change describes a transition of a booking
+type Change struct {
+	From    models.BookingStatus
+	To      models.BookingStatus
+	ActorID *uuid.UUID      // nil for webhooks and scheduled jobs
+	Note    string          // e.g. why the booking was cancelled
+	Payment *models.Payment // the payment confirming the booking
+	At      time.Time
+
+	// Updates are further columns of the booking changed with the status
+	Updates map[string]interface{}
+}
+
+// Hook is a side effect of a transition. It runs in the transaction of the
+// change; its error rolls the change back.
+type Hook func(tx *gorm.DB, booking *models.Booking, change Change) error
+
+// Option is a transition of a booking and whether its guards allow it now
+type Option struct {
+	To          models.BookingStatus `json:"to"`
+	DisplayName string               `json:"display_name"`
+	Allowed     bool                 `json:"allowed"`
+	Reason      string               `json:"reason,omitempty"` // why a guard blocks the transition
+}
+
+type Service struct {
+	db      *gorm.DB
+	logger  *zap.Logger
+	hooks   []Hook
+	onEnter map[models.BookingStatus][]Hook
+	now     func() time.Time
+}
+
+func NewService(db *gorm.DB, logger *zap.Logger) *Service {
+	return &Service{
+		db:      db,
+		logger:  logger,
+		onEnter: make(map[models.BookingStatus][]Hook),
+		now:     time.Now,
+	}
+}
+
+// OnChange registers a hook run for every transition
+func (s *Service) OnChange(hook Hook) {
+	s.hooks = append(s.hooks, hook)
+}
+
+// OnEnter registers a hook run when a booking moves to status
+func (s *Service) OnEnter(status models.BookingStatus, hook Hook) {
+	s.onEnter[status] = append(s.onEnter[status], hook)
+}
+
+// Transition moves a booking to another status within tx. The booking is
+// only changed if it still has the status it was loaded with, so concurrent
+// changes return ErrStale instead of overwriting each other.
+func (s *Service) Transition(tx *gorm.DB, booking *models.Booking, to models.BookingStatus, change Change) error {
+	change.From = booking.Status
+	change.To = to
+	if change.At.IsZero() {
+		change.At = s.now()
+	}
+	if err := s.check(tx, booking, change); err != nil {
+		return err
+	}
+
+	updates := map[string]interface{}{
+		"status":     to,
+		"updated_at": change.At,
+	}
+	switch to {
+	case models.BookingStatusConfirmed:
+		updates["confirmed_at"] = change.At
+		if change.Payment != nil {
+			updates["payment_id"] = change.Payment.ID
+		}
+	case models.BookingStatusCompleted:
+		updates["completed_at"] = change.At
+	case models.BookingStatusCancelled:
+		updates["cancelled_at"] = change.At
+		if change.Note != "" {
+			updates["cancellation_note"] = change.Note
+		}
+	case models.BookingStatusNoShow:
+		if booking.NoShowFlaggedAt == nil {
+			updates["no_show_flagged_at"] = change.At
+		}
+	}
+	for column, value := range change.Updates {
+		updates[column] = value
+	}
+
+	result := tx.Model(&models.Booking{}).
+		Where("id = ? AND status = ?", booking.ID, change.From).
+		Updates(updates)
+	if result.Error != nil {
+		return fmt.Errorf("failed to change booking status: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrStale
+	}
+	if err := tx.First(booking, "id = ?", booking.ID).Error; err != nil {
+		return fmt.Errorf("failed to reload booking: %w", err)
+	}
+
+	for _, hook := range append(append([]Hook{}, s.hooks...), s.onEnter[to]...) {
+		if err := hook(tx, booking, change); err != nil {
+			return err
+		}
+	}
+
+	s.logger.Info("Booking status changed",
+		zap.String("booking_id", booking.ID.String()),
+		zap.String("from", string(change.From)),
+		zap.String("to", string(to)))
+	return nil
+}
+
+// Options lists the transitions of a booking with whether they are allowed now
+func (s *Service) Options(bookingID uuid.UUID) ([]Option, error) {
+	var booking models.Booking
+	if err := s.db.First(&booking, "id = ?", bookingID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrBookingNotFound
+		}
+		return nil, err
+	}
+
+	now := s.now()
+	options := []Option{}
+	for _, to := range transitions[booking.Status] {
+		option := Option{To: to, DisplayName: to.GetDisplayName(), Allowed: true}
+		if err := s.check(s.db, &booking, Change{From: booking.Status, To: to, At: now}); err != nil {
+			if !errors.Is(err, ErrPaymentRequired) && !errors.Is(err, ErrNoAppointment) && !errors.Is(err, ErrNotEnded) {
+				return nil, err
+			}
+			option.Allowed = false
+			option.Reason = err.Error()
+		}
+		options = append(options, option)
+	}
+	return options, nil
+}
+
+// CanTransition checks if the state machine has a transition between the statuses
+func CanTransition(from, to models.BookingStatus) bool {
+	for _, status := range transitions[from] {
+		if status == to {
+			return true
+		}
+	}
+	return false
+}
+
+// check runs the guards of a transition
+func (s *Service) check(tx *gorm.DB, booking *models.Booking, change Change) error {
+	if !CanTransition(change.From, change.To) {
+		return fmt.Errorf("%w: %s → %s", ErrNotAllowed, change.From, change.To)
+	}
+
+	switch change.To {
+	case models.BookingStatusConfirmed:
+		if booking.StartTime.IsZero() {
+			return ErrNoAppointment
+		}
+		paid, err := s.paid(tx, booking, change.Payment)
+		if err != nil {
+			return err
+		}
+		if !paid {
+			return ErrPaymentRequired
+		}
+	case models.BookingStatusCompleted, models.BookingStatusNoShow:
+		if booking.EndTime.After(change.At) {
+			return ErrNotEnded
+		}
+	}
+	return nil
+}
+
+// paid checks if a booking is paid: free bookings always are, others need a
+// paid payment, either the one confirming the booking or the one linked
+func (s *Service) paid(tx *gorm.DB, booking *models.Booking, payment *models.Payment) (bool, error) {
+	if booking.TotalAmount <= 0 {
+		return true, nil
+	}
+	if payment != nil {
+		return payment.IsPaid(), nil
+	}
+	if booking.PaymentID == nil {
+		return false, nil
+	}
+
+	var linked models.Payment
+	if err := tx.Select("id", "status").First(&linked, "id = ?", *booking.PaymentID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	return linked.IsPaid(), nil
+}
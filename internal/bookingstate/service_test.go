@@ -0,0 +1,202 @@
+package bookingstate
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"elterngeld-portal/internal/activitylog"
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/tests/testutils"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+func TestTransition_Confirm(t *testing.T) {
+	db, service, now := setupTestService(t)
+	booking := createBooking(t, db, now.Add(48*time.Hour), 149)
+
+	var entered []Change
+	service.OnEnter(models.BookingStatusConfirmed, func(tx *gorm.DB, booking *models.Booking, change Change) error {
+		entered = append(entered, change)
+		return nil
+	})
+
+	// A booking with a price needs a paid payment
+	err := db.Transaction(func(tx *gorm.DB) error {
+		return service.Transition(tx, booking, models.BookingStatusConfirmed, Change{})
+	})
+	assert.ErrorIs(t, err, ErrPaymentRequired)
+	pending := &models.Payment{ID: uuid.New(), Status: models.PaymentStatusPending}
+	err = db.Transaction(func(tx *gorm.DB) error {
+		return service.Transition(tx, booking, models.BookingStatusConfirmed, Change{Payment: pending})
+	})
+	assert.ErrorIs(t, err, ErrPaymentRequired)
+	assert.Empty(t, entered)
+
+	payment := createPayment(t, db, booking)
+	err = db.Transaction(func(tx *gorm.DB) error {
+		return service.Transition(tx, booking, models.BookingStatusConfirmed, Change{Payment: payment})
+	})
+	require.NoError(t, err)
+	assert.Equal(t, models.BookingStatusConfirmed, booking.Status)
+	assert.Equal(t, payment.ID, *booking.PaymentID)
+	require.NotNil(t, booking.ConfirmedAt)
+	require.Len(t, entered, 1)
+	assert.Equal(t, models.BookingStatusPending, entered[0].From)
+
+	// The status change is recorded on the lead
+	var activity models.Activity
+	require.NoError(t, db.Where("lead_id = ?", *booking.LeadID).First(&activity).Error)
+	assert.Equal(t, models.ActivityTypeBookingStatus, activity.Type)
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		return service.Transition(tx, booking, models.BookingStatusPending, Change{})
+	})
+	assert.ErrorIs(t, err, ErrNotAllowed)
+}
+
+func TestTransition_Guards(t *testing.T) {
+	db, service, now := setupTestService(t)
+
+	// Bookings of payment links have no appointment until the Berater schedules it
+	unscheduled := createBooking(t, db, time.Time{}, 0)
+	err := db.Transaction(func(tx *gorm.DB) error {
+		return service.Transition(tx, unscheduled, models.BookingStatusConfirmed, Change{})
+	})
+	assert.ErrorIs(t, err, ErrNoAppointment)
+
+	free := createBooking(t, db, now.Add(-2*time.Hour), 0)
+	require.NoError(t, db.Transaction(func(tx *gorm.DB) error {
+		return service.Transition(tx, free, models.BookingStatusConfirmed, Change{})
+	}), "free bookings need no payment")
+
+	upcoming := createBooking(t, db, now.Add(time.Hour), 0)
+	require.NoError(t, db.Transaction(func(tx *gorm.DB) error {
+		return service.Transition(tx, upcoming, models.BookingStatusConfirmed, Change{})
+	}))
+	err = db.Transaction(func(tx *gorm.DB) error {
+		return service.Transition(tx, upcoming, models.BookingStatusCompleted, Change{})
+	})
+	assert.ErrorIs(t, err, ErrNotEnded)
+
+	require.NoError(t, db.Transaction(func(tx *gorm.DB) error {
+		return service.Transition(tx, free, models.BookingStatusNoShow, Change{})
+	}))
+	assert.NotNil(t, free.NoShowFlaggedAt)
+	require.NoError(t, db.Transaction(func(tx *gorm.DB) error {
+		return service.Transition(tx, free, models.BookingStatusCompleted, Change{})
+	}), "the Berater corrects the no-show")
+	assert.NotNil(t, free.CompletedAt)
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		return service.Transition(tx, free, models.BookingStatusCancelled, Change{})
+	})
+	assert.ErrorIs(t, err, ErrNotAllowed, "completed bookings are final")
+}
+
+func TestTransition_StaleAndHooks(t *testing.T) {
+	db, service, now := setupTestService(t)
+	booking := createBooking(t, db, now.Add(48*time.Hour), 0)
+
+	// A change made in the meantime wins
+	stale := *booking
+	require.NoError(t, db.Model(booking).Update("status", models.BookingStatusCancelled).Error)
+	err := db.Transaction(func(tx *gorm.DB) error {
+		return service.Transition(tx, &stale, models.BookingStatusConfirmed, Change{})
+	})
+	assert.ErrorIs(t, err, ErrStale)
+
+	// A failing hook rolls the change back
+	other := createBooking(t, db, now.Add(48*time.Hour), 0)
+	service.OnEnter(models.BookingStatusCancelled, func(tx *gorm.DB, booking *models.Booking, change Change) error {
+		return errors.New("mail server down")
+	})
+	err = db.Transaction(func(tx *gorm.DB) error {
+		return service.Transition(tx, other, models.BookingStatusCancelled, Change{Note: "Kunde hat abgesagt"})
+	})
+	require.Error(t, err)
+	var stored models.Booking
+	require.NoError(t, db.First(&stored, "id = ?", other.ID).Error)
+	assert.Equal(t, models.BookingStatusPending, stored.Status)
+}
+
+func TestOptions(t *testing.T) {
+	db, service, now := setupTestService(t)
+	booking := createBooking(t, db, now.Add(48*time.Hour), 149)
+
+	options, err := service.Options(booking.ID)
+	require.NoError(t, err)
+	require.Len(t, options, 2)
+	assert.Equal(t, models.BookingStatusConfirmed, options[0].To)
+	assert.False(t, options[0].Allowed)
+	assert.Equal(t, ErrPaymentRequired.Error(), options[0].Reason)
+	assert.Equal(t, models.BookingStatusCancelled, options[1].To)
+	assert.True(t, options[1].Allowed)
+
+	payment := createPayment(t, db, booking)
+	require.NoError(t, db.Model(booking).Update("payment_id", payment.ID).Error)
+	options, err = service.Options(booking.ID)
+	require.NoError(t, err)
+	assert.True(t, options[0].Allowed, "paid")
+
+	require.NoError(t, db.Model(booking).Update("status", models.BookingStatusCancelled).Error)
+	options, err = service.Options(booking.ID)
+	require.NoError(t, err)
+	assert.Empty(t, options)
+
+	_, err = service.Options(uuid.New())
+	assert.ErrorIs(t, err, ErrBookingNotFound)
+}
+
+func setupTestService(t *testing.T) (*gorm.DB, *Service, time.Time) {
+	t.Helper()
+	db := testutils.NewSQLiteDB(t, &models.User{}, &models.Lead{}, &models.Booking{}, &models.Payment{}, &models.Activity{})
+
+	service := NewService(db, zap.NewNop())
+	service.OnChange(RecordActivity(activitylog.NewService(db, zap.NewNop())))
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	service.now = func() time.Time { return now }
+	return db, service, now
+}
+
+func createBooking(t *testing.T, db *gorm.DB, start time.Time, price float64) *models.Booking {
+	t.Helper()
+	customer := testutils.CreateTestUser(t, db, models.RoleUser)
+	lead := &models.Lead{UserID: customer.ID, Title: "Elterngeld-Beratung"}
+	require.NoError(t, db.Create(lead).Error)
+
+	booking := &models.Booking{
+		UserID:      customer.ID,
+		LeadID:      &lead.ID,
+		Title:       "Erstberatung",
+		Status:      models.BookingStatusPending,
+		ScheduledAt: start,
+		StartTime:   start,
+		TotalAmount: price,
+		Currency:    "EUR",
+	}
+	if !start.IsZero() {
+		booking.EndTime = start.Add(time.Hour)
+	}
+	require.NoError(t, db.Create(booking).Error)
+	return booking
+}
+
+func createPayment(t *testing.T, db *gorm.DB, booking *models.Booking) *models.Payment {
+	t.Helper()
+	payment := &models.Payment{
+		UserID:          booking.UserID,
+		LeadID:          *booking.LeadID,
+		Amount:          booking.TotalAmount,
+		Currency:        "EUR",
+		Status:          models.PaymentStatusSucceeded,
+		StripeSessionID: "cs_" + uuid.New().String(),
+	}
+	require.NoError(t, db.Create(payment).Error)
+	return payment
+}
@@ -0,0 +1,117 @@
+package calculator
+
+import (
+	"sort"
+	"time"
+)
+
+// Age limits of the Kindergeld entitlement: all children until 18, children in
+// education or training until 25 (§ 32 EStG)
+const (
+	kindergeldAgeLimit          = 18
+	kindergeldEducationAgeLimit = 25
+)
+
+// kindergeldRates are the monthly amounts per child by year, for the first,
+// second, third and every further child. Since 2023 all children get the same
+// amount. Years after the last entry use its rates.
+var kindergeldRates = []struct {
+	year  int
+	rates [4]float64
+}{
+	{2021, [4]float64{219, 219, 225, 250}},
+	{2022, [4]float64{219, 219, 225, 250}},
+	{2023, [4]float64{250, 250, 250, 250}},
+	{2024, [4]float64{250, 250, 250, 250}},
+	{2025, [4]float64{255, 255, 255, 255}},
+	{2026, [4]float64{259, 259, 259, 259}},
+}
+
+// Child is a child the Kindergeld is calculated for
+type Child struct {
+	BirthDate   time.Time
+	InEducation bool // in school, training or studies, extending the entitlement until 25
+}
+
+// KindergeldChild is the Kindergeld of one child in the month
+type KindergeldChild struct {
+	BirthDate     string  `json:"birth_date"`
+	Ordinal       int     `json:"ordinal,omitempty"` // position among the eligible children, oldest first
+	Eligible      bool    `json:"eligible"`
+	Amount        float64 `json:"amount"`
+	EligibleUntil string  `json:"eligible_until"` // last month with Kindergeld (YYYY-MM)
+}
+
+// KindergeldResult is the Kindergeld of a family in a month
+type KindergeldResult struct {
+	Month    string            `json:"month"` // YYYY-MM
+	Children []KindergeldChild `json:"children"`
+	Total    float64           `json:"total"`
+}
+
+// Kindergeld calculates the Kindergeld of the children in the month containing
+// month. A child gets Kindergeld for every month in which it meets the
+// conditions on at least one day (§ 66 EStG). Children are listed oldest first.
+// A zero month means the current month.
+func (s *Service) Kindergeld(children []Child, month time.Time) (*KindergeldResult, error) {
+	if month.IsZero() {
+		month = s.now()
+	}
+	start := time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 1, -1)
+
+	rates, err := kindergeldRatesFor(start.Year())
+	if err != nil {
+		return nil, err
+	}
+
+	sorted := make([]Child, len(children))
+	copy(sorted, children)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].BirthDate.Before(sorted[j].BirthDate)
+	})
+
+	result := &KindergeldResult{
+		Month:    start.Format("2006-01"),
+		Children: make([]KindergeldChild, 0, len(sorted)),
+	}
+	eligible := 0
+	for _, child := range sorted {
+		birth := date(child.BirthDate)
+		limit := kindergeldAgeLimit
+		if child.InEducation {
+			limit = kindergeldEducationAgeLimit
+		}
+		// The entitlement ends the day before the birthday at the age limit
+		lastDay := birth.AddDate(limit, 0, -1)
+
+		entry := KindergeldChild{
+			BirthDate:     birth.Format("2006-01-02"),
+			EligibleUntil: lastDay.Format("2006-01"),
+		}
+		if !birth.After(end) && !lastDay.Before(start) {
+			eligible++
+			entry.Eligible = true
+			entry.Ordinal = eligible
+			entry.Amount = rates[min(eligible, len(rates))-1]
+			result.Total += entry.Amount
+		}
+		result.Children = append(result.Children, entry)
+	}
+	result.Total = RoundCents(result.Total)
+
+	return result, nil
+}
+
+func kindergeldRatesFor(year int) ([4]float64, error) {
+	if year < kindergeldRates[0].year {
+		return [4]float64{}, ErrUnsupportedYear
+	}
+	rates := kindergeldRates[0].rates
+	for _, entry := range kindergeldRates {
+		if entry.year <= year {
+			rates = entry.rates
+		}
+	}
+	return rates, nil
+}
@@ -0,0 +1,134 @@
+package calculator
+
+import (
+	"time"
+)
+
+// Protection periods after the birth in weeks: 8 as a rule, 12 after premature
+// and multiple births and for children with a disability (§ 3 MuSchG)
+const (
+	ProtectionWeeks         = 8
+	ExtendedProtectionWeeks = 12
+)
+
+// MaxMaternityBenefitPerDay is the Mutterschaftsgeld of the statutory health
+// insurance per day; the employer pays the rest of the net wage as supplement
+const MaxMaternityBenefitPerDay = 13.0
+
+// maxExtensionDays are the six weeks of protection before the birth, which are
+// added after premature births when they could not be taken
+const maxExtensionDays = 42
+
+// MaternityInput describes the Mutterschaftsleistungen after the birth
+type MaternityInput struct {
+	BirthDate  time.Time
+	Elterngeld float64 // monthly Basiselterngeld before the offset
+
+	// Mutterschaftsgeld of the health insurance and the employer's supplement
+	// per calendar day
+	MaternityBenefitPerDay   float64
+	EmployerSupplementPerDay float64
+
+	ProtectionWeeks int // ProtectionWeeks or ExtendedProtectionWeeks
+	ExtensionDays   int // protection days before a premature birth that are added after it
+}
+
+// MaternityMonth is a Lebensmonat of the child with Mutterschaftsleistungen
+type MaternityMonth struct {
+	Lebensmonat       int     `json:"lebensmonat"`
+	From              string  `json:"from"`
+	To                string  `json:"to"`
+	ProtectedDays     int     `json:"protected_days"`
+	MaternityBenefits float64 `json:"maternity_benefits"`
+	Elterngeld        float64 `json:"elterngeld"`
+	Offset            float64 `json:"offset"`
+	ElterngeldAfter   float64 `json:"elterngeld_after"`
+}
+
+// MaternityResult shows how Mutterschaftsleistungen reduce the Elterngeld.
+// The Lebensmonate with Mutterschaftsleistungen count as Basiselterngeld
+// months of the mother (§ 4 BEEG), even when no Elterngeld remains.
+type MaternityResult struct {
+	ProtectionEnd string           `json:"protection_end"` // last day of the protection period
+	Months        []MaternityMonth `json:"months"`
+	TotalOffset   float64          `json:"total_offset"`
+}
+
+// MaternityOffset offsets the Mutterschaftsleistungen after the birth against
+// the Elterngeld of the Lebensmonate they are paid in (§ 3 BEEG). Benefits for
+// part of a Lebensmonat only reduce the Elterngeld of those days.
+func (s *Service) MaternityOffset(input MaternityInput) (*MaternityResult, error) {
+	if input.BirthDate.IsZero() || input.Elterngeld < 0 || input.MaternityBenefitPerDay < 0 || input.EmployerSupplementPerDay < 0 {
+		return nil, ErrInvalidInput
+	}
+	if input.ProtectionWeeks != ProtectionWeeks && input.ProtectionWeeks != ExtendedProtectionWeeks {
+		return nil, ErrInvalidInput
+	}
+	if input.ExtensionDays < 0 || input.ExtensionDays > maxExtensionDays {
+		return nil, ErrInvalidInput
+	}
+
+	birth := date(input.BirthDate)
+	// The protection period includes the day of birth
+	protectionEnd := birth.AddDate(0, 0, input.ProtectionWeeks*7+input.ExtensionDays)
+	perDay := input.MaternityBenefitPerDay + input.EmployerSupplementPerDay
+
+	result := &MaternityResult{
+		ProtectionEnd: protectionEnd.Format("2006-01-02"),
+		Months:        []MaternityMonth{},
+	}
+	for lebensmonat := 1; ; lebensmonat++ {
+		from, to := LebensmonatRange(birth, lebensmonat)
+		if from.After(protectionEnd) {
+			break
+		}
+
+		last := to
+		if protectionEnd.Before(last) {
+			last = protectionEnd
+		}
+		protectedDays := days(from, last)
+		benefits := perDay * float64(protectedDays)
+		share := input.Elterngeld * float64(protectedDays) / float64(days(from, to))
+		offset := RoundCents(min(share, benefits))
+
+		result.Months = append(result.Months, MaternityMonth{
+			Lebensmonat:       lebensmonat,
+			From:              from.Format("2006-01-02"),
+			To:                to.Format("2006-01-02"),
+			ProtectedDays:     protectedDays,
+			MaternityBenefits: RoundCents(benefits),
+			Elterngeld:        RoundCents(input.Elterngeld),
+			Offset:            offset,
+			ElterngeldAfter:   RoundCents(input.Elterngeld - offset),
+		})
+		result.TotalOffset += offset
+	}
+	result.TotalOffset = RoundCents(result.TotalOffset)
+
+	return result, nil
+}
+
+// LebensmonatRange returns the first and last day of a Lebensmonat of a child.
+// A Lebensmonat ends the day before the day of the month the child was born on;
+// in shorter months without that day it ends on the last day (§ 188 BGB). The
+// birth date is expected at midnight UTC.
+func LebensmonatRange(birth time.Time, lebensmonat int) (time.Time, time.Time) {
+	return lebensmonatEnd(birth, lebensmonat-1).AddDate(0, 0, 1), lebensmonatEnd(birth, lebensmonat)
+}
+
+func lebensmonatEnd(birth time.Time, months int) time.Time {
+	if months == 0 {
+		return birth.AddDate(0, 0, -1)
+	}
+	firstOfMonth := time.Date(birth.Year(), birth.Month()+time.Month(months), 1, 0, 0, 0, 0, time.UTC)
+	if birth.Day() > firstOfMonth.AddDate(0, 1, -1).Day() {
+		return firstOfMonth.AddDate(0, 1, -1)
+	}
+	return firstOfMonth.AddDate(0, 0, birth.Day()-2)
+}
+
+// days counts the calendar days from from to to, both included
+func days(from, to time.Time) int {
+	return int(to.Sub(from).Hours()/24) + 1
+}
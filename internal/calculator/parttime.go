@@ -0,0 +1,96 @@
+package calculator
+
+import (
+	"math"
+)
+
+// Elterngeld amounts and limits of the BEEG
+const (
+	// MaxWeeklyHours is the average working time per week up to which Elterngeld is paid
+	MaxWeeklyHours = 32
+
+	maxIncomeBefore = 2770.0 // income before the birth above this is not compensated
+	minBasis        = 300.0
+	maxBasis        = 1800.0
+	minPlus         = 150.0
+	maxPlus         = 900.0
+)
+
+// PartTimeInput is the income of a parent before the birth and during a
+// Bezugsmonat in which they work part-time, both as monthly Elterngeld-Netto
+type PartTimeInput struct {
+	IncomeBefore float64
+	IncomeAfter  float64
+	WeeklyHours  float64
+}
+
+// PartTimeResult compares the Elterngeld of a Bezugsmonat with and without
+// part-time work. Parents working more than MaxWeeklyHours get no Elterngeld.
+type PartTimeResult struct {
+	Rate             float64 `json:"rate"` // replacement rate in percent
+	BasisWithoutWork float64 `json:"basis_without_work"`
+	Basis            float64 `json:"basis"`
+	ElterngeldPlus   float64 `json:"elterngeld_plus"`
+	BasisReduction   float64 `json:"basis_reduction"`
+	IncomeWithBasis  float64 `json:"income_with_basis"` // part-time income plus Basiselterngeld
+	IncomeWithPlus   float64 `json:"income_with_plus"`  // part-time income plus ElterngeldPlus
+	ExceedsHourLimit bool    `json:"exceeds_hour_limit"`
+}
+
+// PartTime calculates the Elterngeld of a Bezugsmonat with part-time income.
+// Elterngeld replaces the rate of the income lost (§ 2 Abs. 3 BEEG); the rate
+// depends on the income before the birth. ElterngeldPlus is at most half the
+// Basiselterngeld without income after the birth (§ 4a BEEG). Geschwisterbonus
+// and Mehrlingszuschlag are not included.
+func (s *Service) PartTime(input PartTimeInput) (*PartTimeResult, error) {
+	if input.IncomeBefore < 0 || input.IncomeAfter < 0 || input.WeeklyHours < 0 {
+		return nil, ErrInvalidInput
+	}
+
+	rate := replacementRate(input.IncomeBefore)
+	before := math.Min(input.IncomeBefore, maxIncomeBefore)
+	withoutWork := clamp(rate*before, minBasis, maxBasis)
+
+	result := &PartTimeResult{
+		Rate:             RoundCents(rate * 100),
+		BasisWithoutWork: RoundCents(withoutWork),
+	}
+	if input.WeeklyHours > MaxWeeklyHours {
+		result.ExceedsHourLimit = true
+		result.BasisReduction = result.BasisWithoutWork
+		result.IncomeWithBasis = RoundCents(input.IncomeAfter)
+		result.IncomeWithPlus = RoundCents(input.IncomeAfter)
+		return result, nil
+	}
+
+	difference := math.Max(before-input.IncomeAfter, 0)
+	basis := clamp(rate*difference, minBasis, maxBasis)
+	plus := clamp(math.Min(rate*difference, withoutWork/2), minPlus, maxPlus)
+
+	result.Basis = RoundCents(basis)
+	result.ElterngeldPlus = RoundCents(plus)
+	result.BasisReduction = RoundCents(withoutWork - basis)
+	result.IncomeWithBasis = RoundCents(input.IncomeAfter + basis)
+	result.IncomeWithPlus = RoundCents(input.IncomeAfter + plus)
+
+	return result, nil
+}
+
+// replacementRate is the share of the lost income Elterngeld replaces: 67
+// percent, rising by 0.1 points per 2 euros below 1,000 euros up to 100 percent
+// and falling by 0.1 points per 2 euros above 1,200 euros down to 65 percent
+// (§ 2 Abs. 2 BEEG)
+func replacementRate(incomeBefore float64) float64 {
+	tenths := 670.0
+	switch {
+	case incomeBefore < 1000:
+		tenths += math.Floor((1000 - incomeBefore) / 2)
+	case incomeBefore > 1200:
+		tenths -= math.Floor((incomeBefore - 1200) / 2)
+	}
+	return clamp(tenths, 650, 1000) / 1000
+}
+
+func clamp(value, lower, upper float64) float64 {
+	return math.Max(lower, math.Min(value, upper))
+}
@@ -0,0 +1,45 @@
+// Package calculator computes the benefits families ask about alongside
+// Elterngeld: the Kindergeld for their children, how Mutterschaftsgeld is
+// offset against Elterngeld after the birth and how working part-time during
+// the Bezugsmonate reduces Elterngeld. The results are estimates for the
+// portal's calculators, not binding decisions of the Familienkasse or the
+// Elterngeldstelle.
+package calculator
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrUnsupportedYear is returned for months before the first known Kindergeld rates
+	ErrUnsupportedYear = errors.New("no Kindergeld rates for this year")
+	// ErrInvalidInput is returned for amounts, dates or durations that cannot occur
+	ErrInvalidInput = errors.New("invalid calculator input")
+)
+
+// Service runs the calculators
+type Service struct {
+	logger *zap.Logger
+	now    func() time.Time
+}
+
+func NewService(logger *zap.Logger) *Service {
+	return &Service{
+		logger: logger,
+		now:    time.Now,
+	}
+}
+
+// RoundCents rounds an amount in euros to whole cents
+func RoundCents(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
+
+// date strips the time of day, so calendar days can be compared
+func date(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,147 @@
+package calculator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func day(value string) time.Time {
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestKindergeld(t *testing.T) {
+	service := NewService(zap.NewNop())
+	children := []Child{
+		{BirthDate: day("2010-05-10")},
+		{BirthDate: day("2007-03-20")},                    // turned 18 in March 2025
+		{BirthDate: day("2003-01-15"), InEducation: true}, // studies until 25
+		{BirthDate: day("2026-10-31")},                    // born on the last day of the month
+	}
+
+	result, err := service.Kindergeld(children, day("2026-10-01"))
+	require.NoError(t, err)
+	assert.Equal(t, "2026-10", result.Month)
+	assert.Equal(t, 777.0, result.Total)
+	require.Len(t, result.Children, 4)
+	assert.Equal(t, KindergeldChild{BirthDate: "2003-01-15", Ordinal: 1, Eligible: true, Amount: 259, EligibleUntil: "2028-01"}, result.Children[0])
+	assert.Equal(t, KindergeldChild{BirthDate: "2007-03-20", EligibleUntil: "2025-03"}, result.Children[1])
+	assert.Equal(t, 2, result.Children[2].Ordinal)
+	assert.Equal(t, 3, result.Children[3].Ordinal)
+
+	// Until 2022 the third child got more than the first two
+	result, err = service.Kindergeld(children, day("2022-06-15"))
+	require.NoError(t, err)
+	assert.Equal(t, 219.0+219.0+225.0, result.Total)
+
+	// Kindergeld is paid for the month of the 18th birthday
+	birthday := []Child{{BirthDate: day("2008-10-15")}}
+	result, err = service.Kindergeld(birthday, day("2026-10-31"))
+	require.NoError(t, err)
+	assert.Equal(t, 259.0, result.Total)
+	result, err = service.Kindergeld(birthday, day("2026-11-01"))
+	require.NoError(t, err)
+	assert.Equal(t, 0.0, result.Total)
+
+	_, err = service.Kindergeld(children, day("2020-12-01"))
+	assert.ErrorIs(t, err, ErrUnsupportedYear)
+}
+
+func TestMaternityOffset(t *testing.T) {
+	service := NewService(zap.NewNop())
+
+	result, err := service.MaternityOffset(MaternityInput{
+		BirthDate:                day("2026-01-15"),
+		Elterngeld:               1200,
+		MaternityBenefitPerDay:   13,
+		EmployerSupplementPerDay: 67,
+		ProtectionWeeks:          ProtectionWeeks,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "2026-03-12", result.ProtectionEnd)
+	assert.Equal(t, []MaternityMonth{
+		{Lebensmonat: 1, From: "2026-01-15", To: "2026-02-14", ProtectedDays: 31, MaternityBenefits: 2480, Elterngeld: 1200, Offset: 1200, ElterngeldAfter: 0},
+		{Lebensmonat: 2, From: "2026-02-15", To: "2026-03-14", ProtectedDays: 26, MaternityBenefits: 2080, Elterngeld: 1200, Offset: 1114.29, ElterngeldAfter: 85.71},
+	}, result.Months)
+	assert.Equal(t, 2314.29, result.TotalOffset)
+
+	// Without employer supplement only the Mutterschaftsgeld is offset
+	result, err = service.MaternityOffset(MaternityInput{
+		BirthDate:              day("2026-01-31"),
+		Elterngeld:             1200,
+		MaternityBenefitPerDay: 13,
+		ProtectionWeeks:        ExtendedProtectionWeeks,
+		ExtensionDays:          10,
+	})
+	require.NoError(t, err)
+	require.Len(t, result.Months, 4)
+	assert.Equal(t, "2026-02-28", result.Months[0].To)
+	assert.Equal(t, "2026-03-01", result.Months[1].From)
+	assert.Equal(t, "2026-03-30", result.Months[1].To)
+	assert.Equal(t, 377.0, result.Months[0].Offset)
+	assert.Equal(t, 823.0, result.Months[0].ElterngeldAfter)
+
+	_, err = service.MaternityOffset(MaternityInput{BirthDate: day("2026-01-15"), Elterngeld: 1200, ProtectionWeeks: 6})
+	assert.ErrorIs(t, err, ErrInvalidInput)
+}
+
+func TestPartTime(t *testing.T) {
+	service := NewService(zap.NewNop())
+
+	result, err := service.PartTime(PartTimeInput{IncomeBefore: 2000, IncomeAfter: 1000, WeeklyHours: 20})
+	require.NoError(t, err)
+	assert.Equal(t, &PartTimeResult{
+		Rate:             65,
+		BasisWithoutWork: 1300,
+		Basis:            650,
+		ElterngeldPlus:   650,
+		BasisReduction:   650,
+		IncomeWithBasis:  1650,
+		IncomeWithPlus:   1650,
+	}, result)
+
+	// Income before the birth is capped, ElterngeldPlus at half the Basiselterngeld
+	result, err = service.PartTime(PartTimeInput{IncomeBefore: 3500, IncomeAfter: 500, WeeklyHours: 15})
+	require.NoError(t, err)
+	assert.Equal(t, 1800.0, result.BasisWithoutWork)
+	assert.Equal(t, 1475.5, result.Basis)
+	assert.Equal(t, 900.0, result.ElterngeldPlus)
+
+	// Low incomes get a higher rate and at least the minimum amounts
+	result, err = service.PartTime(PartTimeInput{IncomeBefore: 900, IncomeAfter: 500, WeeklyHours: 10})
+	require.NoError(t, err)
+	assert.Equal(t, 72.0, result.Rate)
+	assert.Equal(t, 648.0, result.BasisWithoutWork)
+	assert.Equal(t, 300.0, result.Basis)
+	assert.Equal(t, 288.0, result.ElterngeldPlus)
+
+	result, err = service.PartTime(PartTimeInput{IncomeBefore: 2000, IncomeAfter: 1800, WeeklyHours: 35})
+	require.NoError(t, err)
+	assert.True(t, result.ExceedsHourLimit)
+	assert.Equal(t, 0.0, result.Basis)
+	assert.Equal(t, 1300.0, result.BasisReduction)
+
+	_, err = service.PartTime(PartTimeInput{IncomeBefore: -1})
+	assert.ErrorIs(t, err, ErrInvalidInput)
+}
+
+func TestReplacementRate(t *testing.T) {
+	for income, expected := range map[float64]float64{
+		0:    1.0,
+		340:  1.0,
+		998:  0.671,
+		1100: 0.67,
+		1202: 0.669,
+		1240: 0.65,
+		5000: 0.65,
+	} {
+		assert.InDelta(t, expected, replacementRate(income), 1e-9, "income %v", income)
+	}
+}
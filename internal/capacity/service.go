@@ -0,0 +1,300 @@
+package capacity
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"time"
+
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/pkg/timeutil"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrInvalidPeriod is returned for unparsable, reversed or too long report periods
+	ErrInvalidPeriod = errors.New("invalid period")
+)
+
+// maxWeeks limits the report to about a year of weekly rows
+const maxWeeks = 53
+
+// Service compares the timeslot capacity Beraters offer with booking demand
+type Service struct {
+	db     *gorm.DB
+	logger *zap.Logger
+	now    func() time.Time
+}
+
+func NewService(db *gorm.DB, logger *zap.Logger) *Service {
+	return &Service{
+		db:     db,
+		logger: logger,
+		now:    time.Now,
+	}
+}
+
+// ReportFilter is the period of a capacity report, as calendar days in a
+// location. The period is widened to whole weeks from Monday to Sunday.
+type ReportFilter struct {
+	From      string
+	To        string
+	BeraterID *uuid.UUID
+	Location  *time.Location
+}
+
+// Row holds offered capacity and demand of a Berater, a week or the whole period.
+// Capacity counts the places of available timeslots and Booked the bookings of
+// those timeslots that were not cancelled. UnfilledSlots are timeslots that
+// started without any booking. Shortfall is the demand (booked and waiting)
+// exceeding capacity, the number of places missing to serve everyone.
+type Row struct {
+	WeekStart     string     `json:"week_start,omitempty"` // Monday of the week, empty for period totals
+	BeraterID     *uuid.UUID `json:"berater_id,omitempty"` // empty for weekly totals and unassigned demand
+	BeraterName   string     `json:"berater_name,omitempty"`
+	Timeslots     int        `json:"timeslots"`
+	Capacity      int        `json:"capacity"`
+	Booked        int        `json:"booked"`
+	FreePlaces    int        `json:"free_places"`
+	UnfilledSlots int        `json:"unfilled_slots"`
+	Waitlist      int        `json:"waitlist"`
+	Utilization   float64    `json:"utilization"` // booked places in percent of capacity
+	Shortfall     int        `json:"shortfall"`
+}
+
+// rowKey identifies the row of a Berater in a week; unassigned demand has a nil Berater
+type rowKey struct {
+	week    string
+	berater uuid.UUID
+}
+
+// Report is the capacity of each Berater per week with weekly and per-Berater totals
+type Report struct {
+	From     string `json:"from"`
+	To       string `json:"to"`
+	Rows     []Row  `json:"rows"`
+	Weeks    []Row  `json:"weeks"`
+	Beraters []Row  `json:"beraters"`
+	Total    Row    `json:"total"`
+}
+
+// Report builds the capacity report. Timeslots and their bookings count in the
+// week the timeslot starts. The portal has no separate waitlist: customers
+// who booked without getting a timeslot yet (pending or confirmed bookings
+// without timeslot) are waiting for an appointment and count in the week they
+// booked, for the Berater assigned to them or as unassigned demand.
+func (s *Service) Report(filter ReportFilter) (*Report, error) {
+	loc := filter.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	from, err := timeutil.ParseDate(filter.From, loc)
+	if err != nil {
+		return nil, ErrInvalidPeriod
+	}
+	to, err := timeutil.ParseDate(filter.To, loc)
+	if err != nil || to.Before(from) {
+		return nil, ErrInvalidPeriod
+	}
+	start := weekStart(from, loc)
+	end := timeutil.AddDays(weekStart(to, loc), 7, loc)
+	if end.After(timeutil.AddDays(start, maxWeeks*7, loc)) {
+		return nil, ErrInvalidPeriod
+	}
+
+	rows := make(map[rowKey]*Row)
+	row := func(at time.Time, beraterID *uuid.UUID) *Row {
+		k := rowKey{week: timeutil.FormatDate(weekStart(at, loc), loc)}
+		if beraterID != nil {
+			k.berater = *beraterID
+		}
+		if rows[k] == nil {
+			rows[k] = &Row{WeekStart: k.week}
+			if beraterID != nil {
+				id := *beraterID
+				rows[k].BeraterID = &id
+			}
+		}
+		return rows[k]
+	}
+
+	var timeslots []struct {
+		ID          uuid.UUID
+		BeraterID   uuid.UUID
+		StartTime   time.Time
+		MaxBookings int
+		Booked      int
+	}
+	query := s.db.Model(&models.Timeslot{}).
+		Select("timeslots.id, timeslots.berater_id, timeslots.start_time, timeslots.max_bookings, "+
+			"(SELECT COUNT(*) FROM bookings WHERE bookings.timeslot_id = timeslots.id AND bookings.status <> ?) AS booked",
+			models.BookingStatusCancelled).
+		Where("timeslots.is_available = ? AND timeslots.purpose = ? AND timeslots.start_time >= ? AND timeslots.start_time < ?",
+			true, models.TimeslotPurposeConsultation, start, end)
+	if filter.BeraterID != nil {
+		query = query.Where("timeslots.berater_id = ?", *filter.BeraterID)
+	}
+	if err := query.Scan(&timeslots).Error; err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	for _, slot := range timeslots {
+		beraterID := slot.BeraterID
+		r := row(slot.StartTime, &beraterID)
+		r.Timeslots++
+		r.Capacity += slot.MaxBookings
+		r.Booked += slot.Booked
+		if free := slot.MaxBookings - slot.Booked; free > 0 {
+			r.FreePlaces += free
+		}
+		if slot.Booked == 0 && slot.StartTime.Before(now) {
+			r.UnfilledSlots++
+		}
+	}
+
+	var waiting []struct {
+		BeraterID *uuid.UUID
+		CreatedAt time.Time
+	}
+	query = s.db.Model(&models.Booking{}).
+		Select("berater_id, created_at").
+		Where("timeslot_id IS NULL AND status IN ? AND created_at >= ? AND created_at < ?",
+			[]models.BookingStatus{models.BookingStatusPending, models.BookingStatusConfirmed}, start, end)
+	if filter.BeraterID != nil {
+		query = query.Where("berater_id = ?", *filter.BeraterID)
+	}
+	if err := query.Scan(&waiting).Error; err != nil {
+		return nil, err
+	}
+	for _, booking := range waiting {
+		row(booking.CreatedAt, booking.BeraterID).Waitlist++
+	}
+
+	names, err := s.beraterNames(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &Report{
+		From:     timeutil.FormatDate(start, loc),
+		To:       timeutil.FormatDate(timeutil.AddDays(end, -1, loc), loc),
+		Rows:     []Row{},
+		Weeks:    []Row{},
+		Beraters: []Row{},
+	}
+	weeks := make(map[string]*Row)
+	beraters := make(map[uuid.UUID]*Row)
+	for _, r := range rows {
+		if r.BeraterID != nil {
+			r.BeraterName = names[*r.BeraterID]
+		}
+		finish(r)
+		report.Rows = append(report.Rows, *r)
+
+		if weeks[r.WeekStart] == nil {
+			weeks[r.WeekStart] = &Row{WeekStart: r.WeekStart}
+		}
+		add(weeks[r.WeekStart], r)
+
+		var beraterID uuid.UUID
+		if r.BeraterID != nil {
+			beraterID = *r.BeraterID
+		}
+		if beraters[beraterID] == nil {
+			beraters[beraterID] = &Row{BeraterID: r.BeraterID, BeraterName: r.BeraterName}
+		}
+		add(beraters[beraterID], r)
+		add(&report.Total, r)
+	}
+	// Every week of the period is listed, so weeks without capacity stand out
+	for week := start; week.Before(end); week = timeutil.AddDays(week, 7, loc) {
+		weekStart := timeutil.FormatDate(week, loc)
+		if weeks[weekStart] == nil {
+			weeks[weekStart] = &Row{WeekStart: weekStart}
+		}
+		finish(weeks[weekStart])
+		report.Weeks = append(report.Weeks, *weeks[weekStart])
+	}
+	for _, r := range beraters {
+		finish(r)
+		report.Beraters = append(report.Beraters, *r)
+	}
+	finish(&report.Total)
+	sortRows(report.Rows)
+	sortRows(report.Beraters)
+
+	return report, nil
+}
+
+// beraterNames loads the names of the Beraters in the report
+func (s *Service) beraterNames(rows map[rowKey]*Row) (map[uuid.UUID]string, error) {
+	var ids []uuid.UUID
+	for _, r := range rows {
+		if r.BeraterID != nil {
+			ids = append(ids, *r.BeraterID)
+		}
+	}
+	names := make(map[uuid.UUID]string)
+	if len(ids) == 0 {
+		return names, nil
+	}
+
+	var users []models.User
+	if err := s.db.Where("id IN ?", ids).Find(&users).Error; err != nil {
+		return nil, err
+	}
+	for _, user := range users {
+		names[user.ID] = user.FullName()
+	}
+	return names, nil
+}
+
+// weekStart returns the local midnight of the Monday of the week containing t
+func weekStart(t time.Time, loc *time.Location) time.Time {
+	offset := (int(t.In(loc).Weekday()) + 6) % 7
+	return timeutil.StartOfDay(timeutil.AddDays(t, -offset, loc), loc)
+}
+
+func add(total, r *Row) {
+	total.Timeslots += r.Timeslots
+	total.Capacity += r.Capacity
+	total.Booked += r.Booked
+	total.FreePlaces += r.FreePlaces
+	total.UnfilledSlots += r.UnfilledSlots
+	total.Waitlist += r.Waitlist
+}
+
+func finish(r *Row) {
+	r.Utilization = percent(r.Booked, r.Capacity)
+	r.Shortfall = 0
+	if demand := r.Booked + r.Waitlist; demand > r.Capacity {
+		r.Shortfall = demand - r.Capacity
+	}
+}
+
+// sortRows orders by week, then by shortfall, so the most overloaded Beraters come first
+func sortRows(rows []Row) {
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].WeekStart != rows[j].WeekStart {
+			return rows[i].WeekStart < rows[j].WeekStart
+		}
+		if rows[i].Shortfall != rows[j].Shortfall {
+			return rows[i].Shortfall > rows[j].Shortfall
+		}
+		if rows[i].Utilization != rows[j].Utilization {
+			return rows[i].Utilization > rows[j].Utilization
+		}
+		return rows[i].BeraterName < rows[j].BeraterName
+	})
+}
+
+func percent(part, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return math.Round(float64(part)/float64(total)*1000) / 10
+}
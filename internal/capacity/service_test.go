@@ -0,0 +1,154 @@
+package capacity
+
+import (
+	"testing"
+	"time"
+
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/tests/testutils"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+func TestReport(t *testing.T) {
+	db, service, now := setupTestService(t)
+	customer := testutils.CreateTestUser(t, db, models.RoleUser)
+	busy := testutils.CreateTestUser(t, db, models.RoleBerater)
+	idle := testutils.CreateTestUser(t, db, models.RoleBerater)
+
+	// Week of Monday 2025-03-03: the busy Berater is fully booked with two people waiting
+	full := createTimeslot(t, db, busy, time.Date(2025, 3, 4, 10, 0, 0, 0, time.UTC), 2)
+	createBooking(t, db, customer, &busy.ID, &full.ID, models.BookingStatusConfirmed, full.StartTime)
+	createBooking(t, db, customer, &busy.ID, &full.ID, models.BookingStatusPending, full.StartTime)
+	createBooking(t, db, customer, &busy.ID, &full.ID, models.BookingStatusCancelled, full.StartTime)
+	createBooking(t, db, customer, &busy.ID, nil, models.BookingStatusPending, full.StartTime)
+	createBooking(t, db, customer, nil, nil, models.BookingStatusConfirmed, full.StartTime)
+	createBooking(t, db, customer, nil, nil, models.BookingStatusCancelled, full.StartTime)
+
+	// The idle Berater offers slots nobody booked, one of them still ahead
+	createTimeslot(t, db, idle, time.Date(2025, 3, 5, 9, 0, 0, 0, time.UTC), 1)
+	createTimeslot(t, db, idle, time.Date(2025, 3, 7, 14, 0, 0, 0, time.UTC), 1)
+	*now = time.Date(2025, 3, 6, 12, 0, 0, 0, time.UTC)
+
+	// Following week: one of three places booked
+	next := createTimeslot(t, db, busy, time.Date(2025, 3, 10, 10, 0, 0, 0, time.UTC), 3)
+	createBooking(t, db, customer, &busy.ID, &next.ID, models.BookingStatusConfirmed, next.StartTime)
+
+	// Unavailable slots and slots outside the period are ignored
+	closed := createTimeslot(t, db, idle, time.Date(2025, 3, 11, 10, 0, 0, 0, time.UTC), 4)
+	require.NoError(t, db.Model(closed).Update("is_available", false).Error)
+	createTimeslot(t, db, idle, time.Date(2025, 3, 24, 10, 0, 0, 0, time.UTC), 4)
+
+	report, err := service.Report(ReportFilter{From: "2025-03-05", To: "2025-03-12", Location: time.UTC})
+	require.NoError(t, err)
+	assert.Equal(t, "2025-03-03", report.From)
+	assert.Equal(t, "2025-03-16", report.To)
+
+	require.Len(t, report.Weeks, 2)
+	first := report.Weeks[0]
+	assert.Equal(t, "2025-03-03", first.WeekStart)
+	assert.Equal(t, 3, first.Timeslots)
+	assert.Equal(t, 4, first.Capacity)
+	assert.Equal(t, 2, first.Booked)
+	assert.Equal(t, 2, first.FreePlaces)
+	assert.Equal(t, 1, first.UnfilledSlots)
+	assert.Equal(t, 2, first.Waitlist)
+	assert.Equal(t, 50.0, first.Utilization)
+	assert.Equal(t, 0, first.Shortfall)
+	assert.Equal(t, 33.3, report.Weeks[1].Utilization)
+
+	require.Len(t, report.Rows, 4)
+	top := report.Rows[0]
+	assert.Equal(t, "2025-03-03", top.WeekStart)
+	assert.Equal(t, busy.ID, *top.BeraterID)
+	assert.Equal(t, busy.FullName(), top.BeraterName)
+	assert.Equal(t, 100.0, top.Utilization)
+	assert.Equal(t, 1, top.Waitlist)
+	assert.Equal(t, 1, top.Shortfall)
+
+	var unassigned *Row
+	for i := range report.Beraters {
+		if report.Beraters[i].BeraterID == nil {
+			unassigned = &report.Beraters[i]
+		}
+	}
+	require.NotNil(t, unassigned)
+	assert.Equal(t, 1, unassigned.Waitlist)
+	assert.Equal(t, 1, unassigned.Shortfall)
+
+	assert.Equal(t, 4, report.Total.Timeslots)
+	assert.Equal(t, 7, report.Total.Capacity)
+	assert.Equal(t, 3, report.Total.Booked)
+	assert.Equal(t, 2, report.Total.Waitlist)
+
+	t.Run("filter by Berater", func(t *testing.T) {
+		report, err := service.Report(ReportFilter{From: "2025-03-03", To: "2025-03-16", BeraterID: &idle.ID})
+		require.NoError(t, err)
+		require.Len(t, report.Beraters, 1)
+		assert.Equal(t, idle.ID, *report.Beraters[0].BeraterID)
+		assert.Equal(t, 2, report.Total.Capacity)
+		assert.Equal(t, 0, report.Total.Waitlist)
+	})
+
+	t.Run("invalid period", func(t *testing.T) {
+		for _, filter := range []ReportFilter{
+			{From: "2025-03-10", To: "2025-03-03"},
+			{From: "03/03/2025", To: "2025-03-10"},
+			{From: "2024-01-01", To: "2025-03-10"},
+		} {
+			_, err := service.Report(filter)
+			assert.ErrorIs(t, err, ErrInvalidPeriod)
+		}
+	})
+}
+
+func createTimeslot(t *testing.T, db *gorm.DB, berater *models.User, start time.Time, places int) *models.Timeslot {
+	t.Helper()
+	timeslot := &models.Timeslot{
+		ID:          uuid.New(),
+		BeraterID:   berater.ID,
+		Date:        start,
+		StartTime:   start,
+		EndTime:     start.Add(time.Hour),
+		Duration:    60,
+		IsAvailable: true,
+		MaxBookings: places,
+	}
+	require.NoError(t, db.Create(timeslot).Error)
+	return timeslot
+}
+
+func createBooking(t *testing.T, db *gorm.DB, customer *models.User, beraterID, timeslotID *uuid.UUID, status models.BookingStatus, at time.Time) *models.Booking {
+	t.Helper()
+	booking := &models.Booking{
+		UserID:      customer.ID,
+		BeraterID:   beraterID,
+		TimeslotID:  timeslotID,
+		Title:       "Erstberatung",
+		Status:      status,
+		ScheduledAt: at,
+		StartTime:   at,
+		EndTime:     at.Add(time.Hour),
+		CreatedAt:   at.Add(-24 * time.Hour),
+	}
+	require.NoError(t, db.Create(booking).Error)
+	return booking
+}
+
+func setupTestService(t *testing.T) (*gorm.DB, *Service, *time.Time) {
+	t.Helper()
+	db := testutils.NewSQLiteDB(t,
+		&models.User{},
+		&models.Timeslot{},
+		&models.Booking{},
+	)
+
+	now := time.Now()
+	service := NewService(db, zap.NewNop())
+	service.now = func() time.Time { return now }
+	return db, service, &now
+}
@@ -0,0 +1,447 @@
+package casefile
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/pkg/pdf"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrLeadNotFound is returned for unknown leads
+	ErrLeadNotFound = errors.New("lead not found")
+	// ErrAccessDenied is returned when the requester may not export the lead
+	ErrAccessDenied = errors.New("access denied")
+	// ErrInvalidFormat is returned for export formats other than PDF and ZIP
+	ErrInvalidFormat = errors.New("invalid export format")
+)
+
+// Format is the file format of an exported case file
+type Format string
+
+const (
+	FormatPDF Format = "pdf"
+	FormatZIP Format = "zip" // the PDF together with the case file as JSON
+)
+
+// Service compiles the case file of a lead for handover to the customer or
+// to authorities. Every export is recorded in the lead's activity log.
+type Service struct {
+	db     *gorm.DB
+	logger *zap.Logger
+	now    func() time.Time
+}
+
+func NewService(db *gorm.DB, logger *zap.Logger) *Service {
+	return &Service{
+		db:     db,
+		logger: logger,
+		now:    time.Now,
+	}
+}
+
+// Options control what an export contains
+type Options struct {
+	Format          Format
+	IncludeInternal bool // include internal comments, e.g. for authorities
+	IPAddress       string
+	UserAgent       string
+}
+
+// Export is a rendered case file
+type Export struct {
+	FileName    string
+	ContentType string
+	Data        []byte
+}
+
+// CaseFile holds everything recorded about a lead
+type CaseFile struct {
+	GeneratedAt time.Time       `json:"generated_at"`
+	GeneratedBy string          `json:"generated_by"`
+	Lead        LeadSummary     `json:"lead"`
+	Timeline    []TimelineEntry `json:"timeline"`
+	Comments    []CommentEntry  `json:"comments"`
+	Documents   []DocumentEntry `json:"documents"`
+	Emails      []EmailEntry    `json:"emails"`
+	Consents    []ConsentEntry  `json:"consents"`
+}
+
+// LeadSummary holds the master data of the case
+type LeadSummary struct {
+	ID                uuid.UUID         `json:"id"`
+	Title             string            `json:"title"`
+	ApplicationNumber string            `json:"application_number,omitempty"`
+	Status            models.LeadStatus `json:"status"`
+	CustomerName      string            `json:"customer_name"`
+	CustomerEmail     string            `json:"customer_email"`
+	BeraterName       string            `json:"berater_name,omitempty"`
+	ChildName         string            `json:"child_name,omitempty"`
+	CreatedAt         time.Time         `json:"created_at"`
+	CompletedAt       *time.Time        `json:"completed_at,omitempty"`
+}
+
+// TimelineEntry is an entry of the lead's activity log
+type TimelineEntry struct {
+	At          time.Time           `json:"at"`
+	Type        models.ActivityType `json:"type"`
+	Title       string              `json:"title"`
+	Description string              `json:"description,omitempty"`
+	By          string              `json:"by,omitempty"`
+}
+
+// CommentEntry is a comment on the lead
+type CommentEntry struct {
+	At         time.Time `json:"at"`
+	By         string    `json:"by"`
+	Content    string    `json:"content"`
+	IsInternal bool      `json:"is_internal"`
+}
+
+// DocumentEntry lists an uploaded document; the files themselves are not part of the export
+type DocumentEntry struct {
+	UploadedAt   time.Time           `json:"uploaded_at"`
+	Name         string              `json:"name"`
+	DocumentType models.DocumentType `json:"document_type"`
+	Size         int64               `json:"size"`
+}
+
+// EmailEntry is an email exchanged about the lead
+type EmailEntry struct {
+	At      time.Time `json:"at"`
+	Inbound bool      `json:"inbound"`
+	From    string    `json:"from"`
+	To      string    `json:"to"`
+	Subject string    `json:"subject"`
+}
+
+// ConsentEntry is the state of a consent of the customer
+type ConsentEntry struct {
+	Name      string     `json:"name"`
+	Granted   bool       `json:"granted"`
+	ChangedAt *time.Time `json:"changed_at,omitempty"`
+}
+
+// Export compiles and renders the case file of a lead. Admins may export any
+// lead, Beraters only the leads assigned to them. The export is logged with
+// the requester, format and client before the file is handed out.
+func (s *Service) Export(leadID uuid.UUID, requester *models.User, opts Options) (*Export, error) {
+	if opts.Format != FormatPDF && opts.Format != FormatZIP {
+		return nil, ErrInvalidFormat
+	}
+
+	caseFile, err := s.Build(leadID, requester, opts.IncludeInternal)
+	if err != nil {
+		return nil, err
+	}
+
+	document := RenderPDF(caseFile, requester.TimeLocation())
+	baseName := "fallakte-" + caseFile.Lead.ID.String()[:8]
+	if caseFile.Lead.ApplicationNumber != "" {
+		baseName = "fallakte-" + caseFile.Lead.ApplicationNumber
+	}
+
+	export := &Export{FileName: baseName + ".pdf", ContentType: "application/pdf", Data: document}
+	if opts.Format == FormatZIP {
+		data, err := renderZIP(caseFile, document, baseName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to render case file archive: %w", err)
+		}
+		export = &Export{FileName: baseName + ".zip", ContentType: "application/zip", Data: data}
+	}
+
+	activity := models.CreateLeadExportedActivity(requester.ID, leadID, string(opts.Format), opts.IncludeInternal, opts.IPAddress, opts.UserAgent)
+	if err := s.db.Create(activity).Error; err != nil {
+		return nil, fmt.Errorf("failed to log case file export: %w", err)
+	}
+	s.logger.Info("Case file exported",
+		zap.String("lead_id", leadID.String()),
+		zap.String("user_id", requester.ID.String()),
+		zap.String("format", string(opts.Format)),
+		zap.Bool("include_internal", opts.IncludeInternal))
+
+	return export, nil
+}
+
+// Build compiles the case file of a lead
+func (s *Service) Build(leadID uuid.UUID, requester *models.User, includeInternal bool) (*CaseFile, error) {
+	var lead models.Lead
+	err := s.db.Preload("User").Preload("Berater").First(&lead, "id = ?", leadID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrLeadNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !requester.IsAdmin() && (lead.BeraterID == nil || *lead.BeraterID != requester.ID) {
+		return nil, ErrAccessDenied
+	}
+
+	caseFile := &CaseFile{
+		GeneratedAt: s.now(),
+		GeneratedBy: requester.FullName(),
+		Lead: LeadSummary{
+			ID:                lead.ID,
+			Title:             lead.Title,
+			ApplicationNumber: lead.ApplicationNumber,
+			Status:            lead.Status,
+			CustomerName:      lead.User.FullName(),
+			CustomerEmail:     lead.User.Email,
+			ChildName:         lead.ChildName,
+			CreatedAt:         lead.CreatedAt,
+			CompletedAt:       lead.CompletedAt,
+		},
+		Timeline:  []TimelineEntry{},
+		Comments:  []CommentEntry{},
+		Documents: []DocumentEntry{},
+		Emails:    []EmailEntry{},
+	}
+	if lead.Berater != nil {
+		caseFile.Lead.BeraterName = lead.Berater.FullName()
+	}
+
+	var activities []models.Activity
+	if err := s.db.Preload("User").Where("lead_id = ?", lead.ID).
+		Order("created_at ASC").Find(&activities).Error; err != nil {
+		return nil, fmt.Errorf("failed to load activities: %w", err)
+	}
+	for _, activity := range activities {
+		entry := TimelineEntry{At: activity.CreatedAt, Type: activity.Type, Title: activity.Title, Description: activity.Description}
+		if activity.User != nil {
+			entry.By = activity.User.FullName()
+		}
+		caseFile.Timeline = append(caseFile.Timeline, entry)
+	}
+
+	commentQuery := s.db.Preload("User").Where("lead_id = ?", lead.ID)
+	if !includeInternal {
+		commentQuery = commentQuery.Where("is_internal = ?", false)
+	}
+	var comments []models.Comment
+	if err := commentQuery.Order("created_at ASC").Find(&comments).Error; err != nil {
+		return nil, fmt.Errorf("failed to load comments: %w", err)
+	}
+	for _, comment := range comments {
+		caseFile.Comments = append(caseFile.Comments, CommentEntry{
+			At:         comment.CreatedAt,
+			By:         comment.User.FullName(),
+			Content:    comment.Content,
+			IsInternal: comment.IsInternal,
+		})
+	}
+
+	// Documents the customers keep to themselves are not part of the case file
+	documentQuery := s.db.Where("lead_id = ? AND visibility <> ?", lead.ID, models.DocumentVisibilityCustomer)
+	if !includeInternal {
+		documentQuery = documentQuery.Where("visibility <> ?", models.DocumentVisibilityBerater)
+	}
+	var documents []models.Document
+	if err := documentQuery.Order("created_at ASC").Find(&documents).Error; err != nil {
+		return nil, fmt.Errorf("failed to load documents: %w", err)
+	}
+	for _, document := range documents {
+		caseFile.Documents = append(caseFile.Documents, DocumentEntry{
+			UploadedAt:   document.CreatedAt,
+			Name:         document.OriginalName,
+			DocumentType: document.DocumentType,
+			Size:         document.FileSize,
+		})
+	}
+
+	var messages []models.EmailMessage
+	if err := s.db.Joins("JOIN email_threads ON email_threads.id = email_messages.thread_id").
+		Where("email_threads.lead_id = ?", lead.ID).
+		Order("email_messages.sent_at ASC").Find(&messages).Error; err != nil {
+		return nil, fmt.Errorf("failed to load emails: %w", err)
+	}
+	for _, message := range messages {
+		caseFile.Emails = append(caseFile.Emails, EmailEntry{
+			At:      message.SentAt,
+			Inbound: message.IsInbound,
+			From:    message.FromEmail,
+			To:      message.ToEmail,
+			Subject: message.Subject,
+		})
+	}
+
+	consents, err := s.consents(lead.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load consents: %w", err)
+	}
+	caseFile.Consents = consents
+
+	return caseFile, nil
+}
+
+// consents returns the marketing consents of the customer
+func (s *Service) consents(userID uuid.UUID) ([]ConsentEntry, error) {
+	marketing := ConsentEntry{Name: "Marketing-E-Mails"}
+	var preference models.NotificationPreference
+	err := s.db.Where("user_id = ?", userID).First(&preference).Error
+	switch {
+	case err == nil:
+		marketing.Granted = preference.EmailMarketingNotifications
+		marketing.ChangedAt = &preference.UpdatedAt
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		return nil, err
+	}
+
+	newsletter := ConsentEntry{Name: "Newsletter"}
+	var contact models.NewsletterContact
+	err = s.db.Where("user_id = ?", userID).First(&contact).Error
+	switch {
+	case err == nil:
+		newsletter.Granted = contact.Status != models.NewsletterContactStatusUnsubscribed
+		newsletter.ChangedAt = &contact.UpdatedAt
+		if contact.UnsubscribedAt != nil {
+			newsletter.ChangedAt = contact.UnsubscribedAt
+		}
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		return nil, err
+	}
+
+	return []ConsentEntry{marketing, newsletter}, nil
+}
+
+// RenderPDF renders the case file as a German PDF with times in the given location
+func RenderPDF(caseFile *CaseFile, loc *time.Location) []byte {
+	format := func(t time.Time) string { return t.In(loc).Format("02.01.2006 15:04") }
+
+	doc := pdf.New()
+	doc.SetFooter(fmt.Sprintf("Fallakte %s · erstellt am %s von %s",
+		caseFile.Lead.Title, format(caseFile.GeneratedAt), caseFile.GeneratedBy))
+	doc.Title("Fallakte: " + caseFile.Lead.Title)
+
+	lead := caseFile.Lead
+	doc.Field("Vorgangs-ID", lead.ID.String())
+	if lead.ApplicationNumber != "" {
+		doc.Field("Antragsnummer", lead.ApplicationNumber)
+	}
+	doc.Field("Status", lead.Status.GetDisplayName())
+	doc.Field("Kunde", fmt.Sprintf("%s <%s>", lead.CustomerName, lead.CustomerEmail))
+	if lead.BeraterName != "" {
+		doc.Field("Berater", lead.BeraterName)
+	}
+	if lead.ChildName != "" {
+		doc.Field("Kind", lead.ChildName)
+	}
+	doc.Field("Angelegt", format(lead.CreatedAt))
+	if lead.CompletedAt != nil {
+		doc.Field("Abgeschlossen", format(*lead.CompletedAt))
+	}
+
+	doc.Heading("Verlauf")
+	if len(caseFile.Timeline) == 0 {
+		doc.Text("Keine Einträge.")
+	}
+	for _, entry := range caseFile.Timeline {
+		text := format(entry.At) + "  " + entry.Title
+		if entry.By != "" {
+			text += " (" + entry.By + ")"
+		}
+		if entry.Description != "" {
+			text += " – " + entry.Description
+		}
+		doc.Text(text)
+	}
+
+	doc.Heading("Kommentare")
+	if len(caseFile.Comments) == 0 {
+		doc.Text("Keine Kommentare.")
+	}
+	for _, comment := range caseFile.Comments {
+		header := format(comment.At) + "  " + comment.By
+		if comment.IsInternal {
+			header += " (intern)"
+		}
+		doc.Text(header)
+		doc.Text(comment.Content)
+		doc.Space()
+	}
+
+	doc.Heading("Dokumente")
+	if len(caseFile.Documents) == 0 {
+		doc.Text("Keine Dokumente.")
+	}
+	for _, document := range caseFile.Documents {
+		doc.Text(fmt.Sprintf("%s  %s (%s, %s)", format(document.UploadedAt), document.Name,
+			document.DocumentType.DisplayName(), formatSize(document.Size)))
+	}
+
+	doc.Heading("E-Mails")
+	if len(caseFile.Emails) == 0 {
+		doc.Text("Keine E-Mails.")
+	}
+	for _, email := range caseFile.Emails {
+		direction := "gesendet an " + email.To
+		if email.Inbound {
+			direction = "empfangen von " + email.From
+		}
+		doc.Text(fmt.Sprintf("%s  %s: %s", format(email.At), direction, email.Subject))
+	}
+
+	doc.Heading("Einwilligungen")
+	for _, consent := range caseFile.Consents {
+		state := "nicht erteilt"
+		if consent.Granted {
+			state = "erteilt"
+		}
+		if consent.ChangedAt != nil {
+			state += ", Stand " + format(*consent.ChangedAt)
+		}
+		doc.Field(consent.Name, state)
+	}
+
+	return doc.Bytes()
+}
+
+// renderZIP packs the PDF together with the case file as JSON
+func renderZIP(caseFile *CaseFile, document []byte, baseName string) ([]byte, error) {
+	data, err := json.MarshalIndent(caseFile, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	files := []struct {
+		name    string
+		content []byte
+	}{
+		{baseName + ".pdf", document},
+		{baseName + ".json", data},
+	}
+	for _, f := range files {
+		file, err := archive.CreateHeader(&zip.FileHeader{Name: f.name, Method: zip.Deflate, Modified: caseFile.GeneratedAt})
+		if err != nil {
+			return nil, err
+		}
+		if _, err := file.Write(f.content); err != nil {
+			return nil, err
+		}
+	}
+	if err := archive.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func formatSize(size int64) string {
+	switch {
+	case size >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(size)/(1<<20))
+	case size >= 1<<10:
+		return fmt.Sprintf("%.0f KB", float64(size)/(1<<10))
+	default:
+		return fmt.Sprintf("%d Bytes", size)
+	}
+}
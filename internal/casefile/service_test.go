@@ -0,0 +1,198 @@
+package casefile
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io"
+	"testing"
+	"time"
+
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/tests/testutils"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+func TestExportAccess(t *testing.T) {
+	db, service := setupTestService(t)
+	customer := testutils.CreateTestUser(t, db, models.RoleUser)
+	berater := testutils.CreateTestUser(t, db, models.RoleBerater)
+	otherBerater := testutils.CreateTestUser(t, db, models.RoleBerater)
+	admin := testutils.CreateTestUser(t, db, models.RoleAdmin)
+	lead := createLead(t, db, customer, &berater.ID)
+
+	tests := []struct {
+		name      string
+		leadID    uuid.UUID
+		requester *models.User
+		format    Format
+		err       error
+	}{
+		{"assigned Berater", lead.ID, berater, FormatPDF, nil},
+		{"admin", lead.ID, admin, FormatZIP, nil},
+		{"other Berater", lead.ID, otherBerater, FormatPDF, ErrAccessDenied},
+		{"customer", lead.ID, customer, FormatPDF, ErrAccessDenied},
+		{"unknown lead", uuid.New(), admin, FormatPDF, ErrLeadNotFound},
+		{"unknown format", lead.ID, admin, "docx", ErrInvalidFormat},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := service.Export(tt.leadID, tt.requester, Options{Format: tt.format})
+			if tt.err != nil {
+				assert.ErrorIs(t, err, tt.err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+
+	// Only the successful exports are logged
+	var exports []models.Activity
+	require.NoError(t, db.Where("lead_id = ? AND type = ?", lead.ID, models.ActivityTypeLeadExported).
+		Order("created_at ASC").Find(&exports).Error)
+	require.Len(t, exports, 2)
+	require.NotNil(t, exports[0].UserID)
+	assert.Equal(t, berater.ID, *exports[0].UserID)
+	require.NotNil(t, exports[1].UserID)
+	assert.Equal(t, admin.ID, *exports[1].UserID)
+}
+
+func TestExport(t *testing.T) {
+	db, service := setupTestService(t)
+	customer := testutils.CreateTestUser(t, db, models.RoleUser)
+	berater := testutils.CreateTestUser(t, db, models.RoleBerater)
+	lead := createLead(t, db, customer, &berater.ID)
+
+	require.NoError(t, db.Create(models.CreateLeadCreatedActivity(customer.ID, lead.ID, lead.Title)).Error)
+	require.NoError(t, db.Create(&models.Comment{ID: uuid.New(), LeadID: lead.ID, UserID: berater.ID, Content: "Bescheid angefordert"}).Error)
+	require.NoError(t, db.Create(&models.Comment{ID: uuid.New(), LeadID: lead.ID, UserID: berater.ID, Content: "Kunde schwer erreichbar", IsInternal: true}).Error)
+	require.NoError(t, db.Create(&models.Document{
+		ID:            uuid.New(),
+		LeadID:        lead.ID,
+		UserID:        customer.ID,
+		FileName:      "geburtsurkunde.pdf",
+		OriginalName:  "Geburtsurkunde.pdf",
+		FilePath:      "/uploads/geburtsurkunde.pdf",
+		FileSize:      2048,
+		ContentType:   "application/pdf",
+		FileExtension: ".pdf",
+		DocumentType:  models.DocumentTypeBirthCertificate,
+	}).Error)
+	require.NoError(t, db.Create(&models.Document{
+		ID:            uuid.New(),
+		LeadID:        lead.ID,
+		UserID:        berater.ID,
+		FileName:      "berechnung.pdf",
+		OriginalName:  "Berechnung.pdf",
+		FilePath:      "/uploads/berechnung.pdf",
+		FileSize:      1024,
+		ContentType:   "application/pdf",
+		FileExtension: ".pdf",
+		DocumentType:  models.DocumentTypeOther,
+		Visibility:    models.DocumentVisibilityBerater,
+	}).Error)
+	thread := &models.EmailThread{ID: uuid.New(), LeadID: lead.ID, Subject: "Ihr Antrag", ThreadID: "thread-1", LastMessageAt: time.Now()}
+	require.NoError(t, db.Create(thread).Error)
+	require.NoError(t, db.Create(&models.EmailMessage{
+		ID:        uuid.New(),
+		ThreadID:  thread.ID,
+		MessageID: "message-1",
+		FromEmail: customer.Email,
+		ToEmail:   "antrag@elterngeld-portal.de",
+		Subject:   "Ihr Antrag",
+		IsInbound: true,
+		SentAt:    time.Now(),
+	}).Error)
+	require.NoError(t, db.Create(&models.NotificationPreference{UserID: customer.ID, EmailMarketingNotifications: true}).Error)
+
+	t.Run("case file", func(t *testing.T) {
+		caseFile, err := service.Build(lead.ID, berater, false)
+		require.NoError(t, err)
+		assert.Equal(t, customer.Email, caseFile.Lead.CustomerEmail)
+		assert.Equal(t, berater.FullName(), caseFile.Lead.BeraterName)
+		assert.Len(t, caseFile.Timeline, 1)
+		require.Len(t, caseFile.Comments, 1)
+		assert.Equal(t, "Bescheid angefordert", caseFile.Comments[0].Content)
+		require.Len(t, caseFile.Documents, 1)
+		assert.Equal(t, "Geburtsurkunde.pdf", caseFile.Documents[0].Name)
+		require.Len(t, caseFile.Emails, 1)
+		assert.True(t, caseFile.Emails[0].Inbound)
+		require.Len(t, caseFile.Consents, 2)
+		assert.True(t, caseFile.Consents[0].Granted)
+		assert.False(t, caseFile.Consents[1].Granted)
+
+		withInternal, err := service.Build(lead.ID, berater, true)
+		require.NoError(t, err)
+		assert.Len(t, withInternal.Comments, 2)
+		assert.Len(t, withInternal.Documents, 2)
+	})
+
+	t.Run("pdf", func(t *testing.T) {
+		export, err := service.Export(lead.ID, berater, Options{Format: FormatPDF})
+		require.NoError(t, err)
+		assert.Equal(t, "application/pdf", export.ContentType)
+		assert.Equal(t, "fallakte-"+lead.ApplicationNumber+".pdf", export.FileName)
+		assert.True(t, bytes.HasPrefix(export.Data, []byte("%PDF-")))
+		assert.Contains(t, string(export.Data), "(Dokumente) Tj")
+		assert.NotContains(t, string(export.Data), "schwer erreichbar")
+	})
+
+	t.Run("zip", func(t *testing.T) {
+		export, err := service.Export(lead.ID, berater, Options{Format: FormatZIP, IncludeInternal: true})
+		require.NoError(t, err)
+		assert.Equal(t, "application/zip", export.ContentType)
+
+		archive, err := zip.NewReader(bytes.NewReader(export.Data), int64(len(export.Data)))
+		require.NoError(t, err)
+		require.Len(t, archive.File, 2)
+		assert.Equal(t, "fallakte-"+lead.ApplicationNumber+".pdf", archive.File[0].Name)
+
+		file, err := archive.File[1].Open()
+		require.NoError(t, err)
+		defer file.Close()
+		data, err := io.ReadAll(file)
+		require.NoError(t, err)
+
+		var caseFile CaseFile
+		require.NoError(t, json.Unmarshal(data, &caseFile))
+		assert.Len(t, caseFile.Comments, 2)
+		// The earlier PDF export is part of the timeline
+		assert.Equal(t, models.ActivityTypeLeadExported, caseFile.Timeline[len(caseFile.Timeline)-1].Type)
+	})
+}
+
+func createLead(t *testing.T, db *gorm.DB, customer *models.User, beraterID *uuid.UUID) *models.Lead {
+	t.Helper()
+	lead := &models.Lead{
+		UserID:            customer.ID,
+		BeraterID:         beraterID,
+		Title:             "Elterngeldantrag",
+		Status:            models.LeadStatusInProgress,
+		ApplicationNumber: "EG-2026-" + uuid.New().String()[:6],
+	}
+	require.NoError(t, db.Create(lead).Error)
+	return lead
+}
+
+func setupTestService(t *testing.T) (*gorm.DB, *Service) {
+	t.Helper()
+	db := testutils.NewSQLiteDB(t,
+		&models.User{},
+		&models.Lead{},
+		&models.Activity{},
+		&models.Comment{},
+		&models.Document{},
+		&models.EmailThread{},
+		&models.EmailMessage{},
+		&models.NotificationPreference{},
+		&models.NewsletterContact{},
+	)
+
+	return db, NewService(db, zap.NewNop())
+}
@@ -0,0 +1,126 @@
+package catalog
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"elterngeld-portal/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CouponInput describes a new coupon with either a percentage or an amount off
+type CouponInput struct {
+	Code           string
+	Description    string
+	PercentOff     float64
+	AmountOff      float64
+	PackageID      *uuid.UUID
+	MaxRedemptions int
+	ExpiresAt      *time.Time
+}
+
+// CreateCoupon creates a coupon code customers enter when booking
+func (s *Service) CreateCoupon(input CouponInput, admin *models.User) (*models.Coupon, error) {
+	code := normalizeCode(input.Code)
+	percentOff, amountOff := roundCents(input.PercentOff), roundCents(input.AmountOff)
+	if code == "" || len(code) > 32 || input.MaxRedemptions < 0 {
+		return nil, ErrInvalidCoupon
+	}
+	// Exactly one of the discounts, and no percentage above 100
+	if math.IsNaN(percentOff) || math.IsNaN(amountOff) || math.IsInf(amountOff, 0) ||
+		percentOff < 0 || amountOff < 0 || percentOff > 100 || (percentOff > 0) == (amountOff > 0) {
+		return nil, ErrInvalidCoupon
+	}
+	if input.PackageID != nil {
+		if err := s.db.Select("id").First(&models.Package{}, "id = ?", *input.PackageID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, ErrPackageNotFound
+			}
+			return nil, fmt.Errorf("failed to load package: %w", err)
+		}
+	}
+
+	var existing int64
+	if err := s.db.Unscoped().Model(&models.Coupon{}).Where("code = ?", code).Count(&existing).Error; err != nil {
+		return nil, fmt.Errorf("failed to check coupon code: %w", err)
+	}
+	if existing > 0 {
+		return nil, ErrCouponCodeTaken
+	}
+
+	coupon := models.Coupon{
+		Code:           code,
+		Description:    strings.TrimSpace(input.Description),
+		PercentOff:     percentOff,
+		AmountOff:      amountOff,
+		PackageID:      input.PackageID,
+		MaxRedemptions: input.MaxRedemptions,
+		ExpiresAt:      input.ExpiresAt,
+		CreatedBy:      admin.ID,
+	}
+	if err := s.db.Create(&coupon).Error; err != nil {
+		return nil, fmt.Errorf("failed to create coupon: %w", err)
+	}
+	return &coupon, nil
+}
+
+// ListCoupons returns all coupons, newest first
+func (s *Service) ListCoupons() ([]models.Coupon, error) {
+	var coupons []models.Coupon
+	if err := s.db.Order("created_at DESC").Find(&coupons).Error; err != nil {
+		return nil, fmt.Errorf("failed to load coupons: %w", err)
+	}
+	return coupons, nil
+}
+
+// RedeemCoupon counts the redemption of the coupon of an order. Call it in
+// the transaction that creates the booking; it checks again that the coupon
+// has redemptions left, so concurrent bookings cannot use up more than the
+// coupon allows.
+func (s *Service) RedeemCoupon(tx *gorm.DB, order *Order) error {
+	if order.Coupon == nil {
+		return nil
+	}
+	var coupon models.Coupon
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&coupon, "id = ?", order.Coupon.ID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrCouponNotFound
+		}
+		return fmt.Errorf("failed to load coupon: %w", err)
+	}
+	if !coupon.IsRedeemable(s.now()) {
+		return ErrCouponNotRedeemable
+	}
+	if err := tx.Model(&coupon).Update("redemption_count", gorm.Expr("redemption_count + 1")).Error; err != nil {
+		return fmt.Errorf("failed to redeem coupon: %w", err)
+	}
+	return nil
+}
+
+// coupon returns the redeemable coupon with a code for a package
+func (s *Service) coupon(db *gorm.DB, code string, packageID uuid.UUID) (*models.Coupon, error) {
+	var coupon models.Coupon
+	if err := db.Where("code = ?", code).First(&coupon).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCouponNotFound
+		}
+		return nil, fmt.Errorf("failed to load coupon: %w", err)
+	}
+	if coupon.PackageID != nil && *coupon.PackageID != packageID {
+		return nil, ErrCouponNotFound
+	}
+	if !coupon.IsRedeemable(s.now()) {
+		return nil, ErrCouponNotRedeemable
+	}
+	return &coupon, nil
+}
+
+func normalizeCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
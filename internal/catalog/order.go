@@ -0,0 +1,213 @@
+package catalog
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"elterngeld-portal/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// LineItemKind is what a position of an order is for
+type LineItemKind string
+
+const (
+	LineItemPackage LineItemKind = "paket"
+	LineItemAddon   LineItemKind = "zusatzleistung"
+	LineItemCoupon  LineItemKind = "rabatt" // negative, the discount of the coupon
+)
+
+// LineItem is a position of an order at its gross price. Addons included in
+// the package have a price of 0.
+type LineItem struct {
+	Kind        LineItemKind `json:"kind"`
+	ID          uuid.UUID    `json:"id"`
+	Name        string       `json:"name"`
+	Description string       `json:"description"`
+	Price       float64      `json:"price"`
+	Included    bool         `json:"included"`
+}
+
+// Order is a package with the addons booked with it and the coupon applied
+// to their price
+type Order struct {
+	Package   *models.Package
+	Addons    []models.Addon
+	Coupon    *models.Coupon
+	LineItems []LineItem
+	Subtotal  float64 // package and addons
+	Discount  float64
+	Total     float64 // after the discount
+	Currency  string
+}
+
+// Order prices a package with the chosen addons and an optional coupon code.
+// Every addon must be active and offered with the package; the addons
+// included in the package are always part of the order, at no charge. Pass a
+// transaction as db to price a booking in progress.
+func (s *Service) Order(db *gorm.DB, packageID uuid.UUID, addonIDs []uuid.UUID, couponCode string) (*Order, error) {
+	var pkg models.Package
+	if err := db.Where("id = ? AND is_active = ?", packageID, true).First(&pkg).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPackageNotFound
+		}
+		return nil, fmt.Errorf("failed to load package: %w", err)
+	}
+
+	var links []models.PackageAddon
+	if err := db.Where("package_id = ?", pkg.ID).Find(&links).Error; err != nil {
+		return nil, fmt.Errorf("failed to load package addons: %w", err)
+	}
+	included := make(map[uuid.UUID]bool, len(links))
+	ids := make([]uuid.UUID, 0, len(links))
+	for _, link := range links {
+		included[link.AddonID] = link.IsDefault
+		ids = append(ids, link.AddonID)
+	}
+
+	var offered []models.Addon
+	if len(ids) > 0 {
+		if err := db.Where("id IN ? AND is_active = ?", ids, true).
+			Order("sort_order ASC, price ASC").Find(&offered).Error; err != nil {
+			return nil, fmt.Errorf("failed to load addons: %w", err)
+		}
+	}
+
+	chosen := make(map[uuid.UUID]bool, len(addonIDs))
+	for _, id := range addonIDs {
+		chosen[id] = true
+	}
+	var addons []models.Addon
+	for _, addon := range offered {
+		if included[addon.ID] || chosen[addon.ID] {
+			addons = append(addons, addon)
+			delete(chosen, addon.ID)
+		}
+	}
+	if len(chosen) > 0 {
+		return nil, ErrAddonNotAvailable
+	}
+
+	order := &Order{
+		Package:  &pkg,
+		Addons:   addons,
+		Subtotal: pkg.Price,
+		Currency: pkg.Currency,
+		LineItems: []LineItem{{
+			Kind:        LineItemPackage,
+			ID:          pkg.ID,
+			Name:        pkg.Name,
+			Description: pkg.Description,
+			Price:       pkg.Price,
+		}},
+	}
+	for _, addon := range addons {
+		item := addonItem(addon, addon.Price, included[addon.ID])
+		order.LineItems = append(order.LineItems, item)
+		order.Subtotal += item.Price
+	}
+	order.Subtotal = roundCents(order.Subtotal)
+	order.Total = order.Subtotal
+
+	if code := normalizeCode(couponCode); code != "" {
+		coupon, err := s.coupon(db, code, pkg.ID)
+		if err != nil {
+			return nil, err
+		}
+		order.Coupon = coupon
+		order.Discount = roundCents(coupon.Discount(order.Subtotal))
+		order.Total = roundCents(order.Subtotal - order.Discount)
+		order.LineItems = append(order.LineItems, couponItem(coupon, order.Discount))
+	}
+	return order, nil
+}
+
+// AttachAddons stores the addons of an order with a booking at the price
+// they were ordered at
+func (s *Service) AttachAddons(tx *gorm.DB, bookingID uuid.UUID, order *Order) error {
+	for _, item := range order.LineItems {
+		if item.Kind != LineItemAddon {
+			continue
+		}
+		if err := tx.Create(&models.BookingAddon{
+			BookingID: bookingID,
+			AddonID:   item.ID,
+			Price:     item.Price,
+			Included:  item.Included,
+		}).Error; err != nil {
+			return fmt.Errorf("failed to save booking addon: %w", err)
+		}
+	}
+	return nil
+}
+
+// LineItems returns the positions a booking is charged for: its package, the
+// addons at the price they were booked at and the coupon discount. The
+// package position is the rest of the booking total, so the positions always
+// add up to it.
+func (s *Service) LineItems(db *gorm.DB, booking *models.Booking) ([]LineItem, error) {
+	var booked []struct {
+		models.Addon
+		BookedPrice float64
+		Included    bool
+	}
+	if err := db.Model(&models.Addon{}).Unscoped().
+		Select("addons.*, booking_addons.price AS booked_price, booking_addons.included").
+		Joins("JOIN booking_addons ON booking_addons.addon_id = addons.id").
+		Where("booking_addons.booking_id = ?", booking.ID).
+		Order("addons.sort_order ASC, addons.price ASC").
+		Scan(&booked).Error; err != nil {
+		return nil, fmt.Errorf("failed to load booking addons: %w", err)
+	}
+
+	packageItem := LineItem{Kind: LineItemPackage, Name: booking.Title, Price: booking.TotalAmount + booking.DiscountAmount}
+	if booking.Package != nil {
+		packageItem.ID = booking.Package.ID
+		packageItem.Name = booking.Package.Name
+		packageItem.Description = booking.Package.Description
+	}
+	items := []LineItem{packageItem}
+	for _, addon := range booked {
+		items = append(items, addonItem(addon.Addon, addon.BookedPrice, addon.Included))
+		items[0].Price -= addon.BookedPrice
+	}
+	items[0].Price = roundCents(items[0].Price)
+
+	if booking.DiscountAmount > 0 {
+		coupon := &models.Coupon{}
+		if booking.CouponID != nil {
+			if err := db.Unscoped().First(coupon, "id = ?", *booking.CouponID).Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, fmt.Errorf("failed to load coupon: %w", err)
+			}
+		}
+		items = append(items, couponItem(coupon, booking.DiscountAmount))
+	}
+	return items, nil
+}
+
+func couponItem(coupon *models.Coupon, discount float64) LineItem {
+	return LineItem{
+		Kind:        LineItemCoupon,
+		ID:          coupon.ID,
+		Name:        strings.TrimSpace("Rabattcode " + coupon.Code),
+		Description: coupon.Description,
+		Price:       -discount,
+	}
+}
+
+func addonItem(addon models.Addon, price float64, included bool) LineItem {
+	if included {
+		price = 0
+	}
+	return LineItem{
+		Kind:        LineItemAddon,
+		ID:          addon.ID,
+		Name:        addon.Name,
+		Description: addon.Description,
+		Price:       price,
+		Included:    included,
+	}
+}
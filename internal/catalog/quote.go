@@ -0,0 +1,77 @@
+package catalog
+
+import (
+	"math"
+
+	"elterngeld-portal/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// QuoteInput is what a customer is about to book
+type QuoteInput struct {
+	PackageID  uuid.UUID
+	AddonIDs   []uuid.UUID
+	CouponCode string
+	UserID     *uuid.UUID // applies the customer's credit when set
+}
+
+// Quote is the price of an order the way it is charged: the line items, the
+// coupon discount, the VAT contained in the total and the credit applied
+// before the rest is paid via Stripe
+type Quote struct {
+	LineItems     []LineItem `json:"line_items"`
+	Subtotal      float64    `json:"subtotal"` // package and addons
+	Discount      float64    `json:"discount"`
+	Total         Price      `json:"total"` // after the discount
+	CreditApplied float64    `json:"credit_applied"`
+	AmountDue     float64    `json:"amount_due"`
+	Currency      string     `json:"currency"`
+}
+
+// Quote prices an order without creating anything. It uses the same rules as
+// a booking, so the customer sees the price they will be charged.
+func (s *Service) Quote(input QuoteInput) (*Quote, error) {
+	order, err := s.Order(s.db, input.PackageID, input.AddonIDs, input.CouponCode)
+	if err != nil {
+		return nil, err
+	}
+
+	credit := 0.0
+	if input.UserID != nil {
+		if credit, err = s.credit.Balance(*input.UserID); err != nil {
+			return nil, err
+		}
+	}
+	return s.quote(order.LineItems, order.Currency, credit), nil
+}
+
+// BookingQuote prices a booking from the line items it was booked with.
+// Checkout charges this quote; the credit is applied by the checkout itself.
+func (s *Service) BookingQuote(db *gorm.DB, booking *models.Booking) (*Quote, error) {
+	items, err := s.LineItems(db, booking)
+	if err != nil {
+		return nil, err
+	}
+	return s.quote(items, booking.Currency, 0), nil
+}
+
+// quote adds up line items and applies up to credit to the total
+func (s *Service) quote(items []LineItem, currency string, credit float64) *Quote {
+	quote := &Quote{LineItems: items, Currency: currency}
+	for _, item := range items {
+		if item.Kind == LineItemCoupon {
+			quote.Discount -= item.Price
+		} else {
+			quote.Subtotal += item.Price
+		}
+	}
+	quote.Subtotal = roundCents(quote.Subtotal)
+	quote.Discount = roundCents(quote.Discount)
+	total := roundCents(quote.Subtotal - quote.Discount)
+	quote.Total = s.Price(total, currency)
+	quote.CreditApplied = roundCents(math.Max(0, math.Min(credit, total)))
+	quote.AmountDue = roundCents(total - quote.CreditApplied)
+	return quote
+}
@@ -0,0 +1,361 @@
+// Package catalog serves the bookable packages and addons with their prices
+// for the pricing page: feature lists, which addons each package offers or
+// includes, prices split into net and Umsatzsteuer and the metadata of the
+// package comparison. It is also the one place bookings are priced: orders
+// of a package with addons and a coupon, quotes and checkout line items.
+package catalog
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"elterngeld-portal/config"
+	"elterngeld-portal/internal/credit"
+	"elterngeld-portal/internal/models"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrPackageNotFound is returned for unknown and inactive packages
+	ErrPackageNotFound = errors.New("package not found")
+	// ErrAddonNotAvailable is returned for addons that are inactive or not offered with the package
+	ErrAddonNotAvailable = errors.New("addon not available for package")
+	// ErrCouponNotFound is returned for unknown coupon codes and coupons of another package
+	ErrCouponNotFound = errors.New("coupon not found")
+	// ErrCouponNotRedeemable is returned when a coupon has expired or is used up
+	ErrCouponNotRedeemable = errors.New("coupon is no longer redeemable")
+	// ErrInvalidCoupon is returned for coupons without a code or a valid discount
+	ErrInvalidCoupon = errors.New("invalid coupon")
+	// ErrCouponCodeTaken is returned when a coupon with the code already exists
+	ErrCouponCodeTaken = errors.New("coupon code already exists")
+)
+
+// AddonAvailability is how an addon can be booked with a package
+type AddonAvailability string
+
+const (
+	AddonIncluded    AddonAvailability = "inklusive" // part of the package price
+	AddonOptional    AddonAvailability = "optional"
+	AddonUnavailable AddonAvailability = "nicht_verfuegbar"
+)
+
+// Service reads the catalog. Package and addon prices are gross prices
+// including the configured VAT rate.
+type Service struct {
+	db      *gorm.DB
+	logger  *zap.Logger
+	credit  *credit.Service
+	vatRate float64
+	now     func() time.Time
+}
+
+func NewService(db *gorm.DB, logger *zap.Logger, cfg *config.Config, creditService *credit.Service) *Service {
+	return &Service{
+		db:      db,
+		logger:  logger,
+		credit:  creditService,
+		vatRate: cfg.Booking.VATRate,
+		now:     time.Now,
+	}
+}
+
+// Price is a gross price split into its net amount and Umsatzsteuer
+type Price struct {
+	Net       float64 `json:"net"`
+	Tax       float64 `json:"tax"`
+	Gross     float64 `json:"gross"`
+	Currency  string  `json:"currency"`
+	Formatted string  `json:"formatted"` // gross
+}
+
+// Pricing is everything the pricing page shows
+type Pricing struct {
+	Currency   string         `json:"currency"`
+	VATRate    float64        `json:"vat_rate"`
+	Packages   []PackageOffer `json:"packages"`
+	Addons     []AddonOffer   `json:"addons"`
+	Comparison Comparison     `json:"comparison"`
+}
+
+// PackageOffer is a bookable package with its features and addons
+type PackageOffer struct {
+	ID               uuid.UUID          `json:"id"`
+	Name             string             `json:"name"`
+	Description      string             `json:"description"`
+	Type             models.PackageType `json:"type"`
+	TypeName         string             `json:"type_name"`
+	Price            Price              `json:"price"`
+	Features         []string           `json:"features"`
+	RequiresTimeslot bool               `json:"requires_timeslot"`
+	ConsultationTime int                `json:"consultation_time"`
+	HasFreePreTalk   bool               `json:"has_free_pre_talk"`
+	PreTalkDuration  int                `json:"pre_talk_duration"`
+	BadgeText        string             `json:"badge_text"`
+	BadgeColor       string             `json:"badge_color"`
+	Addons           []PackageAddon     `json:"addons"`
+	Upgrade          *Upgrade           `json:"upgrade,omitempty"` // compared to the previous package
+}
+
+// PackageAddon is an addon that can be booked with a package
+type PackageAddon struct {
+	AddonID  uuid.UUID `json:"addon_id"`
+	Included bool      `json:"included"`
+}
+
+// Upgrade is what a package adds to the package shown before it
+type Upgrade struct {
+	FromPackageID   uuid.UUID `json:"from_package_id"`
+	PriceDifference Price     `json:"price_difference"`
+	AddedFeatures   []string  `json:"added_features"`
+	AddedAddons     []string  `json:"added_addons"` // names of addons included on top
+}
+
+// AddonOffer is an addon with its price
+type AddonOffer struct {
+	ID          uuid.UUID `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Category    string    `json:"category"`
+	Price       Price     `json:"price"`
+}
+
+// Comparison lays out the packages side by side. Rows list a value per
+// package in the order of PackageIDs.
+type Comparison struct {
+	PackageIDs []uuid.UUID  `json:"package_ids"`
+	Features   []FeatureRow `json:"features"`
+	Addons     []AddonRow   `json:"addons"`
+	PriceFrom  *Price       `json:"price_from"` // of the cheapest package, nil without packages
+	CheapestID *uuid.UUID   `json:"cheapest_id"`
+}
+
+// FeatureRow marks the packages that have a feature
+type FeatureRow struct {
+	Feature  string `json:"feature"`
+	Included []bool `json:"included"`
+}
+
+// AddonRow shows how an addon can be booked with each package
+type AddonRow struct {
+	AddonID      uuid.UUID           `json:"addon_id"`
+	Name         string              `json:"name"`
+	Availability []AddonAvailability `json:"availability"`
+}
+
+// Packages returns the active packages in display order
+func (s *Service) Packages() ([]models.Package, error) {
+	var packages []models.Package
+	if err := s.db.Where("is_active = ?", true).Order("sort_order ASC, price ASC").Find(&packages).Error; err != nil {
+		return nil, fmt.Errorf("failed to load packages: %w", err)
+	}
+	return packages, nil
+}
+
+// PackageAddons returns an active package with the active addons that can
+// be booked with it
+func (s *Service) PackageAddons(packageID uuid.UUID) (*models.Package, []AddonOffer, error) {
+	var pkg models.Package
+	if err := s.db.Where("id = ? AND is_active = ?", packageID, true).First(&pkg).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrPackageNotFound
+		}
+		return nil, nil, fmt.Errorf("failed to load package: %w", err)
+	}
+
+	var addons []models.Addon
+	if err := s.db.Joins("JOIN package_addons ON package_addons.addon_id = addons.id").
+		Where("package_addons.package_id = ? AND addons.is_active = ?", pkg.ID, true).
+		Order("addons.sort_order ASC, addons.price ASC").Find(&addons).Error; err != nil {
+		return nil, nil, fmt.Errorf("failed to load addons: %w", err)
+	}
+
+	offers := make([]AddonOffer, 0, len(addons))
+	for _, addon := range addons {
+		offers = append(offers, s.addonOffer(addon))
+	}
+	return &pkg, offers, nil
+}
+
+// Pricing returns the active packages and addons with the comparison of the
+// packages. Addons that no active package offers are left out.
+func (s *Service) Pricing() (*Pricing, error) {
+	packages, err := s.Packages()
+	if err != nil {
+		return nil, err
+	}
+
+	var addons []models.Addon
+	if err := s.db.Where("is_active = ?", true).Order("sort_order ASC, price ASC").Find(&addons).Error; err != nil {
+		return nil, fmt.Errorf("failed to load addons: %w", err)
+	}
+
+	packageIDs := make([]uuid.UUID, 0, len(packages))
+	for _, pkg := range packages {
+		packageIDs = append(packageIDs, pkg.ID)
+	}
+	var links []models.PackageAddon
+	if len(packageIDs) > 0 {
+		if err := s.db.Where("package_id IN ?", packageIDs).Find(&links).Error; err != nil {
+			return nil, fmt.Errorf("failed to load package addons: %w", err)
+		}
+	}
+	// included[package][addon] is true for addons in the package price
+	included := make(map[uuid.UUID]map[uuid.UUID]bool, len(packages))
+	for _, link := range links {
+		if included[link.PackageID] == nil {
+			included[link.PackageID] = map[uuid.UUID]bool{}
+		}
+		included[link.PackageID][link.AddonID] = link.IsDefault
+	}
+
+	pricing := &Pricing{
+		Currency: "EUR",
+		VATRate:  s.vatRate,
+		Packages: make([]PackageOffer, 0, len(packages)),
+		Addons:   []AddonOffer{},
+		Comparison: Comparison{
+			PackageIDs: packageIDs,
+			Features:   []FeatureRow{},
+			Addons:     []AddonRow{},
+		},
+	}
+
+	for i, pkg := range packages {
+		offer := PackageOffer{
+			ID:               pkg.ID,
+			Name:             pkg.Name,
+			Description:      pkg.Description,
+			Type:             pkg.Type,
+			TypeName:         pkg.Type.GetDisplayName(),
+			Price:            s.Price(pkg.Price, pkg.Currency),
+			Features:         pkg.GetFeaturesArray(),
+			RequiresTimeslot: pkg.RequiresTimeslot,
+			ConsultationTime: pkg.ConsultationTime,
+			HasFreePreTalk:   pkg.HasFreePreTalk,
+			PreTalkDuration:  pkg.PreTalkDuration,
+			BadgeText:        pkg.BadgeText,
+			BadgeColor:       pkg.BadgeColor,
+			Addons:           []PackageAddon{},
+		}
+		for _, addon := range addons {
+			if isDefault, ok := included[pkg.ID][addon.ID]; ok {
+				offer.Addons = append(offer.Addons, PackageAddon{AddonID: addon.ID, Included: isDefault})
+			}
+		}
+		if i > 0 {
+			offer.Upgrade = s.upgrade(packages[i-1], pkg, addons, included)
+		}
+		pricing.Packages = append(pricing.Packages, offer)
+
+		if pricing.Comparison.PriceFrom == nil || pkg.Price < pricing.Comparison.PriceFrom.Gross {
+			price, id := offer.Price, pkg.ID
+			pricing.Comparison.PriceFrom = &price
+			pricing.Comparison.CheapestID = &id
+		}
+	}
+	if len(packages) > 0 {
+		pricing.Currency = packages[0].Currency
+	}
+
+	pricing.Comparison.Features = featureRows(packages)
+	for _, addon := range addons {
+		row := AddonRow{AddonID: addon.ID, Name: addon.Name, Availability: make([]AddonAvailability, 0, len(packages))}
+		offered := false
+		for _, pkg := range packages {
+			isDefault, ok := included[pkg.ID][addon.ID]
+			switch {
+			case !ok:
+				row.Availability = append(row.Availability, AddonUnavailable)
+			case isDefault:
+				row.Availability = append(row.Availability, AddonIncluded)
+			default:
+				row.Availability = append(row.Availability, AddonOptional)
+			}
+			offered = offered || ok
+		}
+		if offered {
+			pricing.Addons = append(pricing.Addons, s.addonOffer(addon))
+			pricing.Comparison.Addons = append(pricing.Comparison.Addons, row)
+		}
+	}
+	return pricing, nil
+}
+
+// Price splits a gross price into its net amount and Umsatzsteuer
+func (s *Service) Price(gross float64, currency string) Price {
+	if currency == "" {
+		currency = "EUR"
+	}
+	net := roundCents(gross / (1 + s.vatRate))
+	pkg := models.Package{Price: gross, Currency: currency}
+	return Price{
+		Net:       net,
+		Tax:       roundCents(gross - net),
+		Gross:     roundCents(gross),
+		Currency:  currency,
+		Formatted: pkg.FormatPrice(),
+	}
+}
+
+func (s *Service) addonOffer(addon models.Addon) AddonOffer {
+	return AddonOffer{
+		ID:          addon.ID,
+		Name:        addon.Name,
+		Description: addon.Description,
+		Category:    addon.Category,
+		Price:       s.Price(addon.Price, addon.Currency),
+	}
+}
+
+// upgrade compares a package with the one shown before it
+func (s *Service) upgrade(previous, pkg models.Package, addons []models.Addon, included map[uuid.UUID]map[uuid.UUID]bool) *Upgrade {
+	upgrade := &Upgrade{
+		FromPackageID:   previous.ID,
+		PriceDifference: s.Price(pkg.Price-previous.Price, pkg.Currency),
+		AddedFeatures:   []string{},
+		AddedAddons:     []string{},
+	}
+
+	had := map[string]bool{}
+	for _, feature := range previous.GetFeaturesArray() {
+		had[feature] = true
+	}
+	for _, feature := range pkg.GetFeaturesArray() {
+		if !had[feature] {
+			upgrade.AddedFeatures = append(upgrade.AddedFeatures, feature)
+		}
+	}
+	for _, addon := range addons {
+		if included[pkg.ID][addon.ID] && !included[previous.ID][addon.ID] {
+			upgrade.AddedAddons = append(upgrade.AddedAddons, addon.Name)
+		}
+	}
+	return upgrade
+}
+
+// featureRows lists every feature once, in the order the packages name them
+func featureRows(packages []models.Package) []FeatureRow {
+	rows := []FeatureRow{}
+	index := map[string]int{}
+	for i, pkg := range packages {
+		for _, feature := range pkg.GetFeaturesArray() {
+			row, ok := index[feature]
+			if !ok {
+				row = len(rows)
+				index[feature] = row
+				rows = append(rows, FeatureRow{Feature: feature, Included: make([]bool, len(packages))})
+			}
+			rows[row].Included[i] = true
+		}
+	}
+	return rows
+}
+
+func roundCents(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
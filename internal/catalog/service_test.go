@@ -0,0 +1,263 @@
+package catalog
+
+import (
+	"testing"
+	"time"
+
+	"elterngeld-portal/config"
+	"elterngeld-portal/internal/credit"
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/tests/testutils"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+func TestPricing_ComparesPackages(t *testing.T) {
+	db, service := setupTestService(t)
+	basic := createPackage(t, db, "Basis Beratung", models.PackageTypeBasic, 99, 1, `["Erstberatung", "E-Mail Support"]`)
+	premium := createPackage(t, db, "Premium Beratung", models.PackageTypePremium, 199, 2, `["Erstberatung", "E-Mail Support", "Telefon Support"]`)
+	inactive := createPackage(t, db, "Alt", models.PackageTypeBasic, 49, 0, `[]`)
+	require.NoError(t, db.Model(inactive).Update("is_active", false).Error)
+
+	express := createAddon(t, db, "Expresszuschlag", 49, 1)
+	review := createAddon(t, db, "Dokumentenprüfung", 29, 2)
+	createAddon(t, db, "Nirgends angeboten", 10, 3)
+	linkAddon(t, db, basic, express, false)
+	linkAddon(t, db, premium, express, false)
+	linkAddon(t, db, premium, review, true)
+
+	pricing, err := service.Pricing()
+	require.NoError(t, err)
+	assert.Equal(t, 0.19, pricing.VATRate)
+	require.Len(t, pricing.Packages, 2)
+	assert.Equal(t, []uuid.UUID{basic.ID, premium.ID}, pricing.Comparison.PackageIDs)
+
+	offer := pricing.Packages[0]
+	assert.Equal(t, []string{"Erstberatung", "E-Mail Support"}, offer.Features)
+	assert.Equal(t, Price{Net: 83.19, Tax: 15.81, Gross: 99, Currency: "EUR", Formatted: "€99.00"}, offer.Price)
+	assert.Equal(t, []PackageAddon{{AddonID: express.ID}}, offer.Addons)
+	assert.Nil(t, offer.Upgrade)
+
+	upgrade := pricing.Packages[1].Upgrade
+	require.NotNil(t, upgrade)
+	assert.Equal(t, basic.ID, upgrade.FromPackageID)
+	assert.Equal(t, 100.0, upgrade.PriceDifference.Gross)
+	assert.Equal(t, []string{"Telefon Support"}, upgrade.AddedFeatures)
+	assert.Equal(t, []string{"Dokumentenprüfung"}, upgrade.AddedAddons)
+
+	assert.Equal(t, []FeatureRow{
+		{Feature: "Erstberatung", Included: []bool{true, true}},
+		{Feature: "E-Mail Support", Included: []bool{true, true}},
+		{Feature: "Telefon Support", Included: []bool{false, true}},
+	}, pricing.Comparison.Features)
+	assert.Equal(t, []AddonRow{
+		{AddonID: express.ID, Name: "Expresszuschlag", Availability: []AddonAvailability{AddonOptional, AddonOptional}},
+		{AddonID: review.ID, Name: "Dokumentenprüfung", Availability: []AddonAvailability{AddonUnavailable, AddonIncluded}},
+	}, pricing.Comparison.Addons)
+	require.Len(t, pricing.Addons, 2, "addons no package offers are left out")
+	assert.Equal(t, basic.ID, *pricing.Comparison.CheapestID)
+	assert.Equal(t, 99.0, pricing.Comparison.PriceFrom.Gross)
+}
+
+func TestPackageAddons(t *testing.T) {
+	db, service := setupTestService(t)
+	pkg := createPackage(t, db, "Basis Beratung", models.PackageTypeBasic, 99, 1, `[]`)
+	express := createAddon(t, db, "Expresszuschlag", 49, 1)
+	retired := createAddon(t, db, "Eingestellt", 19, 2)
+	require.NoError(t, db.Model(retired).Update("is_active", false).Error)
+	linkAddon(t, db, pkg, express, false)
+	linkAddon(t, db, pkg, retired, false)
+
+	found, addons, err := service.PackageAddons(pkg.ID)
+	require.NoError(t, err)
+	assert.Equal(t, pkg.ID, found.ID)
+	require.Len(t, addons, 1)
+	assert.Equal(t, express.ID, addons[0].ID)
+	assert.Equal(t, 41.18, addons[0].Price.Net)
+
+	_, _, err = service.PackageAddons(uuid.New())
+	assert.ErrorIs(t, err, ErrPackageNotFound)
+}
+
+func TestOrder_LineItems(t *testing.T) {
+	db, service := setupTestService(t)
+	pkg := createPackage(t, db, "Premium Beratung", models.PackageTypePremium, 199, 1, `[]`)
+	other := createPackage(t, db, "Basis Beratung", models.PackageTypeBasic, 99, 2, `[]`)
+	express := createAddon(t, db, "Expresszuschlag", 49, 1)
+	review := createAddon(t, db, "Dokumentenprüfung", 29, 2)
+	retired := createAddon(t, db, "Eingestellt", 19, 3)
+	appeal := createAddon(t, db, "Einspruchsverfahren", 89, 4)
+	require.NoError(t, db.Model(retired).Update("is_active", false).Error)
+	linkAddon(t, db, pkg, express, false)
+	linkAddon(t, db, pkg, review, true)
+	linkAddon(t, db, pkg, retired, false)
+	linkAddon(t, db, other, appeal, false)
+
+	_, err := service.Order(db, uuid.New(), nil, "")
+	assert.ErrorIs(t, err, ErrPackageNotFound)
+	_, err = service.Order(db, pkg.ID, []uuid.UUID{appeal.ID}, "")
+	assert.ErrorIs(t, err, ErrAddonNotAvailable, "addons of another package")
+	_, err = service.Order(db, pkg.ID, []uuid.UUID{retired.ID}, "")
+	assert.ErrorIs(t, err, ErrAddonNotAvailable, "inactive addons")
+
+	// Included addons are always part of the order, at no charge
+	order, err := service.Order(db, pkg.ID, []uuid.UUID{express.ID, express.ID, review.ID}, "")
+	require.NoError(t, err)
+	assert.Equal(t, 248.0, order.Total)
+	assert.Equal(t, []LineItem{
+		{Kind: LineItemPackage, ID: pkg.ID, Name: "Premium Beratung", Price: 199},
+		{Kind: LineItemAddon, ID: express.ID, Name: "Expresszuschlag", Price: 49},
+		{Kind: LineItemAddon, ID: review.ID, Name: "Dokumentenprüfung", Price: 0, Included: true},
+	}, order.LineItems)
+
+	start := time.Now().Add(48 * time.Hour)
+	booking := &models.Booking{
+		UserID:      uuid.New(),
+		PackageID:   &pkg.ID,
+		Title:       "Beratung",
+		Status:      models.BookingStatusPending,
+		ScheduledAt: start,
+		StartTime:   start,
+		EndTime:     start.Add(time.Hour),
+		TotalAmount: order.Total,
+		Currency:    "EUR",
+	}
+	require.NoError(t, db.Create(booking).Error)
+	require.NoError(t, service.AttachAddons(db, booking.ID, order))
+
+	// The booked prices stay when the catalog changes
+	require.NoError(t, db.Model(express).Update("price", 59).Error)
+	require.NoError(t, db.Model(pkg).Update("price", 249).Error)
+	booking.Package = pkg
+	items, err := service.LineItems(db, booking)
+	require.NoError(t, err)
+	assert.Equal(t, order.LineItems, items)
+}
+
+func TestQuote_CouponTaxAndCredit(t *testing.T) {
+	db, service := setupTestService(t)
+	pkg := createPackage(t, db, "Premium Beratung", models.PackageTypePremium, 199, 1, `[]`)
+	other := createPackage(t, db, "Basis Beratung", models.PackageTypeBasic, 99, 2, `[]`)
+	express := createAddon(t, db, "Expresszuschlag", 49, 1)
+	linkAddon(t, db, pkg, express, false)
+	admin := &models.User{ID: uuid.New()}
+
+	_, err := service.CreateCoupon(CouponInput{Code: "both", PercentOff: 10, AmountOff: 5}, admin)
+	assert.ErrorIs(t, err, ErrInvalidCoupon)
+	_, err = service.CreateCoupon(CouponInput{Code: "much", PercentOff: 120}, admin)
+	assert.ErrorIs(t, err, ErrInvalidCoupon)
+	coupon, err := service.CreateCoupon(CouponInput{Code: " herbst20 ", PercentOff: 20, MaxRedemptions: 1}, admin)
+	require.NoError(t, err)
+	assert.Equal(t, "HERBST20", coupon.Code)
+	_, err = service.CreateCoupon(CouponInput{Code: "HERBST20", AmountOff: 5}, admin)
+	assert.ErrorIs(t, err, ErrCouponCodeTaken)
+	_, err = service.CreateCoupon(CouponInput{Code: "BASIS", AmountOff: 500, PackageID: &other.ID}, admin)
+	require.NoError(t, err)
+
+	_, err = service.Quote(QuoteInput{PackageID: pkg.ID, CouponCode: "UNBEKANNT"})
+	assert.ErrorIs(t, err, ErrCouponNotFound)
+	_, err = service.Quote(QuoteInput{PackageID: pkg.ID, CouponCode: "BASIS"})
+	assert.ErrorIs(t, err, ErrCouponNotFound, "coupons of another package")
+
+	customer := uuid.New()
+	require.NoError(t, db.Create(&models.CreditEntry{UserID: customer, Type: models.CreditEntryGoodwill, Amount: 50}).Error)
+	quote, err := service.Quote(QuoteInput{PackageID: pkg.ID, AddonIDs: []uuid.UUID{express.ID}, CouponCode: "herbst20", UserID: &customer})
+	require.NoError(t, err)
+	assert.Equal(t, 248.0, quote.Subtotal)
+	assert.Equal(t, 49.6, quote.Discount)
+	assert.Equal(t, Price{Net: 166.72, Tax: 31.68, Gross: 198.4, Currency: "EUR", Formatted: "€198.40"}, quote.Total)
+	assert.Equal(t, 50.0, quote.CreditApplied)
+	assert.Equal(t, 148.4, quote.AmountDue)
+	require.Len(t, quote.LineItems, 3)
+	assert.Equal(t, LineItem{Kind: LineItemCoupon, ID: coupon.ID, Name: "Rabattcode HERBST20", Price: -49.6}, quote.LineItems[2])
+
+	// A discount above the price is capped, nothing is left for credit
+	basis, err := service.Quote(QuoteInput{PackageID: other.ID, CouponCode: "basis", UserID: &customer})
+	require.NoError(t, err)
+	assert.Equal(t, 99.0, basis.Discount)
+	assert.Equal(t, 0.0, basis.Total.Gross)
+	assert.Equal(t, 0.0, basis.CreditApplied)
+
+	// A booking is charged the quote it was booked with
+	order, err := service.Order(db, pkg.ID, []uuid.UUID{express.ID}, "HERBST20")
+	require.NoError(t, err)
+	start := time.Now().Add(48 * time.Hour)
+	booking := &models.Booking{
+		UserID:         customer,
+		PackageID:      &pkg.ID,
+		Package:        pkg,
+		Title:          "Beratung",
+		Status:         models.BookingStatusPending,
+		ScheduledAt:    start,
+		StartTime:      start,
+		EndTime:        start.Add(time.Hour),
+		TotalAmount:    order.Total,
+		DiscountAmount: order.Discount,
+		CouponID:       &order.Coupon.ID,
+		Currency:       "EUR",
+	}
+	require.NoError(t, db.Omit("Package").Create(booking).Error)
+	require.NoError(t, service.AttachAddons(db, booking.ID, order))
+	require.NoError(t, service.RedeemCoupon(db, order))
+	assert.ErrorIs(t, service.RedeemCoupon(db, order), ErrCouponNotRedeemable, "the coupon is used up")
+	_, err = service.Quote(QuoteInput{PackageID: pkg.ID, CouponCode: "HERBST20"})
+	assert.ErrorIs(t, err, ErrCouponNotRedeemable)
+
+	booked, err := service.BookingQuote(db, booking)
+	require.NoError(t, err)
+	assert.Equal(t, order.LineItems, booked.LineItems)
+	assert.Equal(t, 198.4, booked.Total.Gross)
+	assert.Equal(t, 198.4, booked.AmountDue)
+}
+
+func setupTestService(t *testing.T) (*gorm.DB, *Service) {
+	t.Helper()
+	db := testutils.NewSQLiteDB(t, &models.Package{}, &models.Addon{}, &models.PackageAddon{}, &models.Booking{}, &models.BookingAddon{},
+		&models.Coupon{}, &models.User{}, &models.CreditEntry{})
+
+	cfg := &config.Config{Booking: config.BookingConfig{VATRate: 0.19}}
+	return db, NewService(db, zap.NewNop(), cfg, credit.NewService(db, zap.NewNop()))
+}
+
+func createPackage(t *testing.T, db *gorm.DB, name string, packageType models.PackageType, price float64, sortOrder int, features string) *models.Package {
+	t.Helper()
+	id := uuid.New()
+	pkg := &models.Package{
+		ID:              id,
+		Name:            name,
+		Type:            packageType,
+		Price:           price,
+		IsActive:        true,
+		Features:        features,
+		SortOrder:       sortOrder,
+		StripeProductID: "prod_" + id.String(),
+		StripePriceID:   "price_" + id.String(),
+	}
+	require.NoError(t, db.Create(pkg).Error)
+	return pkg
+}
+
+func createAddon(t *testing.T, db *gorm.DB, name string, price float64, sortOrder int) *models.Addon {
+	t.Helper()
+	id := uuid.New()
+	addon := &models.Addon{
+		ID:              id,
+		Name:            name,
+		Price:           price,
+		IsActive:        true,
+		SortOrder:       sortOrder,
+		StripeProductID: "prod_" + id.String(),
+		StripePriceID:   "price_" + id.String(),
+	}
+	require.NoError(t, db.Create(addon).Error)
+	return addon
+}
+
+func linkAddon(t *testing.T, db *gorm.DB, pkg *models.Package, addon *models.Addon, included bool) {
+	t.Helper()
+	require.NoError(t, db.Create(&models.PackageAddon{PackageID: pkg.ID, AddonID: addon.ID, IsDefault: included}).Error)
+}
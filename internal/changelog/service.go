@@ -0,0 +1,208 @@
+// Package changelog manages the product update feed behind the "What's new"
+// widget: new features, improvements, fixes and maintenance windows. Admins
+// write the entries; signed-in users read the published entries meant for
+// their role.
+package changelog
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"elterngeld-portal/internal/models"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	DefaultLimit = 10
+	MaxLimit     = 50
+)
+
+var (
+	// ErrNotFound is returned for unknown product updates
+	ErrNotFound = errors.New("product update not found")
+	// ErrInvalidKind is returned for kinds other than feature, improvement, fix and maintenance
+	ErrInvalidKind = errors.New("invalid product update kind")
+	// ErrInvalidAudience is returned for audiences other than all, customers and staff
+	ErrInvalidAudience = errors.New("invalid product update audience")
+	// ErrInvalidWindow is returned when a maintenance entry has no valid window or another kind has one
+	ErrInvalidWindow = errors.New("invalid maintenance window")
+)
+
+// Input holds the editable fields of a product update. Without a publish
+// time the entry is a draft; the audience defaults to all users.
+type Input struct {
+	Kind        models.ProductUpdateKind     `json:"kind" binding:"required"`
+	Audience    models.ProductUpdateAudience `json:"audience"`
+	Title       string                       `json:"title" binding:"required,max=200"`
+	Body        string                       `json:"body" binding:"required"`
+	Link        string                       `json:"link" binding:"omitempty,url,max=500"`
+	StartsAt    *time.Time                   `json:"starts_at"`
+	EndsAt      *time.Time                   `json:"ends_at"`
+	PublishedAt *time.Time                   `json:"published_at"`
+}
+
+// Feed is what the "What's new" widget shows
+type Feed struct {
+	Updates     []models.ProductUpdate `json:"updates"`     // latest first
+	Maintenance []models.ProductUpdate `json:"maintenance"` // current and upcoming maintenance windows, the next first
+	Unseen      int64                  `json:"unseen"`      // updates published after the time the user last opened the widget
+}
+
+// Service manages product updates
+type Service struct {
+	db     *gorm.DB
+	logger *zap.Logger
+	now    func() time.Time
+}
+
+func NewService(db *gorm.DB, logger *zap.Logger) *Service {
+	return &Service{
+		db:     db,
+		logger: logger,
+		now:    time.Now,
+	}
+}
+
+// Feed returns the published product updates for a role. With since, the
+// time the user last opened the widget, it counts the updates published
+// after it.
+func (s *Service) Feed(role models.UserRole, since *time.Time, limit int) (*Feed, error) {
+	now := s.now()
+	visible := func() *gorm.DB {
+		return s.db.Model(&models.ProductUpdate{}).
+			Where("published_at IS NOT NULL AND published_at <= ?", now).
+			Where("audience IN ?", audiences(role))
+	}
+
+	feed := &Feed{Updates: []models.ProductUpdate{}, Maintenance: []models.ProductUpdate{}}
+	if err := visible().Order("published_at DESC").Limit(limit).Find(&feed.Updates).Error; err != nil {
+		return nil, fmt.Errorf("failed to load product updates: %w", err)
+	}
+	if err := visible().Where("kind = ? AND ends_at > ?", models.ProductUpdateKindMaintenance, now).
+		Order("starts_at ASC").Find(&feed.Maintenance).Error; err != nil {
+		return nil, fmt.Errorf("failed to load maintenance windows: %w", err)
+	}
+	if since != nil {
+		if err := visible().Where("published_at > ?", *since).Count(&feed.Unseen).Error; err != nil {
+			return nil, fmt.Errorf("failed to count product updates: %w", err)
+		}
+	}
+	return feed, nil
+}
+
+// audiences returns the audiences whose updates a role sees. Admins see all
+// updates, also those meant for customers only.
+func audiences(role models.UserRole) []models.ProductUpdateAudience {
+	switch role {
+	case models.RoleAdmin:
+		return []models.ProductUpdateAudience{models.ProductUpdateAudienceAll, models.ProductUpdateAudienceCustomers, models.ProductUpdateAudienceStaff}
+	case models.RoleUser:
+		return []models.ProductUpdateAudience{models.ProductUpdateAudienceAll, models.ProductUpdateAudienceCustomers}
+	default:
+		return []models.ProductUpdateAudience{models.ProductUpdateAudienceAll, models.ProductUpdateAudienceStaff}
+	}
+}
+
+// List returns a page of product updates for the admin, drafts and
+// scheduled entries included, latest first
+func (s *Service) List(page, limit int) ([]models.ProductUpdate, int64, error) {
+	var total int64
+	if err := s.db.Model(&models.ProductUpdate{}).Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count product updates: %w", err)
+	}
+
+	updates := []models.ProductUpdate{}
+	// Drafts have no publish time and are listed first
+	if err := s.db.Order("published_at IS NOT NULL, published_at DESC, created_at DESC").
+		Offset((page - 1) * limit).Limit(limit).Find(&updates).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to load product updates: %w", err)
+	}
+	return updates, total, nil
+}
+
+// Get returns a product update, published or not
+func (s *Service) Get(id uuid.UUID) (*models.ProductUpdate, error) {
+	var update models.ProductUpdate
+	if err := s.db.First(&update, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &update, nil
+}
+
+// Create adds a product update
+func (s *Service) Create(input Input, createdBy uuid.UUID) (*models.ProductUpdate, error) {
+	update := &models.ProductUpdate{CreatedBy: createdBy}
+	if err := apply(update, input, createdBy); err != nil {
+		return nil, err
+	}
+	if err := s.db.Create(update).Error; err != nil {
+		return nil, fmt.Errorf("failed to create product update: %w", err)
+	}
+	return update, nil
+}
+
+// Update replaces the fields of a product update
+func (s *Service) Update(id uuid.UUID, input Input, updatedBy uuid.UUID) (*models.ProductUpdate, error) {
+	update, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := apply(update, input, updatedBy); err != nil {
+		return nil, err
+	}
+	if err := s.db.Save(update).Error; err != nil {
+		return nil, fmt.Errorf("failed to update product update: %w", err)
+	}
+	return update, nil
+}
+
+// Delete removes a product update
+func (s *Service) Delete(id uuid.UUID) error {
+	result := s.db.Delete(&models.ProductUpdate{}, "id = ?", id)
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete product update: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// apply validates the input and copies it to the product update
+func apply(update *models.ProductUpdate, input Input, updatedBy uuid.UUID) error {
+	if !input.Kind.IsValid() {
+		return ErrInvalidKind
+	}
+	if input.Audience == "" {
+		input.Audience = models.ProductUpdateAudienceAll
+	}
+	if !input.Audience.IsValid() {
+		return ErrInvalidAudience
+	}
+	if input.Kind == models.ProductUpdateKindMaintenance {
+		if input.StartsAt == nil || input.EndsAt == nil || !input.EndsAt.After(*input.StartsAt) {
+			return ErrInvalidWindow
+		}
+	} else if input.StartsAt != nil || input.EndsAt != nil {
+		return ErrInvalidWindow
+	}
+
+	update.Kind = input.Kind
+	update.Audience = input.Audience
+	update.Title = strings.TrimSpace(input.Title)
+	update.Body = input.Body
+	update.Link = strings.TrimSpace(input.Link)
+	update.StartsAt = input.StartsAt
+	update.EndsAt = input.EndsAt
+	update.PublishedAt = input.PublishedAt
+	update.UpdatedBy = updatedBy
+	return nil
+}
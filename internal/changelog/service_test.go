@@ -0,0 +1,96 @@
+package changelog
+
+import (
+	"testing"
+	"time"
+
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/tests/testutils"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestCreate_Validation(t *testing.T) {
+	service := setupTestService(t)
+	admin := uuid.New()
+	start := time.Date(2026, 5, 9, 22, 0, 0, 0, time.UTC)
+	end := start.Add(2 * time.Hour)
+
+	_, err := service.Create(Input{Kind: "news", Title: "x", Body: "x"}, admin)
+	assert.ErrorIs(t, err, ErrInvalidKind)
+	_, err = service.Create(Input{Kind: models.ProductUpdateKindFeature, Audience: "partners", Title: "x", Body: "x"}, admin)
+	assert.ErrorIs(t, err, ErrInvalidAudience)
+	_, err = service.Create(Input{Kind: models.ProductUpdateKindMaintenance, Title: "x", Body: "x", StartsAt: &end, EndsAt: &start}, admin)
+	assert.ErrorIs(t, err, ErrInvalidWindow)
+	_, err = service.Create(Input{Kind: models.ProductUpdateKindFix, Title: "x", Body: "x", StartsAt: &start, EndsAt: &end}, admin)
+	assert.ErrorIs(t, err, ErrInvalidWindow)
+
+	update, err := service.Create(Input{Kind: models.ProductUpdateKindFeature, Title: " Bezugsplan als PDF ", Body: "..."}, admin)
+	require.NoError(t, err)
+	assert.Equal(t, models.ProductUpdateAudienceAll, update.Audience)
+	assert.Equal(t, "Bezugsplan als PDF", update.Title)
+	assert.Nil(t, update.PublishedAt)
+
+	updated, err := service.Update(update.ID, Input{Kind: models.ProductUpdateKindFeature, Title: "Bezugsplan als PDF", Body: "...", PublishedAt: &start}, uuid.New())
+	require.NoError(t, err)
+	assert.NotNil(t, updated.PublishedAt)
+	assert.Equal(t, admin, updated.CreatedBy)
+
+	require.NoError(t, service.Delete(update.ID))
+	assert.ErrorIs(t, service.Delete(update.ID), ErrNotFound)
+}
+
+func TestFeed(t *testing.T) {
+	service := setupTestService(t)
+	now := time.Date(2026, 5, 4, 10, 0, 0, 0, time.UTC)
+	service.now = func() time.Time { return now }
+	admin := uuid.New()
+	at := func(d time.Duration) *time.Time {
+		t := now.Add(d)
+		return &t
+	}
+
+	create := func(input Input) *models.ProductUpdate {
+		update, err := service.Create(input, admin)
+		require.NoError(t, err)
+		return update
+	}
+	feature := create(Input{Kind: models.ProductUpdateKindFeature, Title: "Szenarien", Body: "...", PublishedAt: at(-48 * time.Hour)})
+	staff := create(Input{Kind: models.ProductUpdateKindImprovement, Audience: models.ProductUpdateAudienceStaff, Title: "Posteingang", Body: "...", PublishedAt: at(-time.Hour)})
+	maintenance := create(Input{Kind: models.ProductUpdateKindMaintenance, Title: "Wartung", Body: "...",
+		StartsAt: at(24 * time.Hour), EndsAt: at(26 * time.Hour), PublishedAt: at(-2 * time.Hour)})
+	create(Input{Kind: models.ProductUpdateKindMaintenance, Title: "Vorbei", Body: "...",
+		StartsAt: at(-30 * time.Hour), EndsAt: at(-28 * time.Hour), PublishedAt: at(-72 * time.Hour)})
+	create(Input{Kind: models.ProductUpdateKindFix, Title: "Entwurf", Body: "..."})
+	create(Input{Kind: models.ProductUpdateKindFeature, Title: "Geplant", Body: "...", PublishedAt: at(time.Hour)})
+
+	feed, err := service.Feed(models.RoleUser, at(-24*time.Hour), DefaultLimit)
+	require.NoError(t, err)
+	require.Len(t, feed.Updates, 3)
+	assert.Equal(t, maintenance.ID, feed.Updates[0].ID)
+	assert.Equal(t, feature.ID, feed.Updates[1].ID)
+	require.Len(t, feed.Maintenance, 1)
+	assert.Equal(t, maintenance.ID, feed.Maintenance[0].ID)
+	assert.Equal(t, int64(1), feed.Unseen)
+
+	feed, err = service.Feed(models.RoleBerater, nil, 2)
+	require.NoError(t, err)
+	require.Len(t, feed.Updates, 2)
+	assert.Equal(t, staff.ID, feed.Updates[0].ID)
+	assert.Zero(t, feed.Unseen)
+
+	updates, total, err := service.List(1, 10)
+	require.NoError(t, err)
+	assert.Equal(t, int64(6), total)
+	assert.Equal(t, "Entwurf", updates[0].Title)
+}
+
+func setupTestService(t *testing.T) *Service {
+	t.Helper()
+	db := testutils.NewSQLiteDB(t, &models.ProductUpdate{})
+
+	return NewService(db, zap.NewNop())
+}
@@ -0,0 +1,202 @@
+package chatnotify
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"elterngeld-portal/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrChannelNotFound is returned for unknown chat channels
+	ErrChannelNotFound = errors.New("chat channel not found")
+	// ErrRuleNotFound is returned for unknown routing rules
+	ErrRuleNotFound = errors.New("routing rule not found")
+	// ErrInvalidWebhookURL is returned when a webhook URL does not belong to the provider
+	ErrInvalidWebhookURL = errors.New("invalid webhook url")
+	// ErrInvalidProvider is returned for providers other than Slack and Teams
+	ErrInvalidProvider = errors.New("invalid chat provider")
+	// ErrInvalidEvent is returned for events that cannot be routed
+	ErrInvalidEvent = errors.New("invalid chat event")
+	// ErrInvalidPriority is returned for unknown minimum priorities of routing rules
+	ErrInvalidPriority = errors.New("invalid priority")
+)
+
+// teamsHosts are the domains Teams incoming webhooks and workflows are served from
+var teamsHosts = []string{".webhook.office.com", ".logic.azure.com", ".powerplatform.com"}
+
+// ChannelInput holds the fields of a chat channel
+type ChannelInput struct {
+	Name       string              `json:"name" binding:"required,max=100"`
+	Provider   models.ChatProvider `json:"provider" binding:"required"`
+	WebhookURL string              `json:"webhook_url" binding:"required"`
+	IsActive   *bool               `json:"is_active"`
+}
+
+// RuleInput holds the fields of a routing rule
+type RuleInput struct {
+	Event       models.ChatEvent `json:"event" binding:"required"`
+	MinPriority models.Priority  `json:"min_priority"`
+	BeraterID   *uuid.UUID       `json:"berater_id"`
+}
+
+// ListChannels returns all chat channels with their routing rules
+func (n *Notifier) ListChannels() ([]models.ChatChannel, error) {
+	var channels []models.ChatChannel
+	if err := n.db.Preload("Rules").Order("name ASC").Find(&channels).Error; err != nil {
+		return nil, err
+	}
+	return channels, nil
+}
+
+// CreateChannel adds a chat channel. New channels are active unless stated otherwise.
+func (n *Notifier) CreateChannel(input ChannelInput) (*models.ChatChannel, error) {
+	if err := validateWebhookURL(input.Provider, input.WebhookURL); err != nil {
+		return nil, err
+	}
+
+	channel := &models.ChatChannel{
+		Name:       strings.TrimSpace(input.Name),
+		Provider:   input.Provider,
+		WebhookURL: input.WebhookURL,
+		IsActive:   input.IsActive == nil || *input.IsActive,
+	}
+	if err := n.db.Create(channel).Error; err != nil {
+		return nil, fmt.Errorf("failed to create chat channel: %w", err)
+	}
+	return channel, nil
+}
+
+// UpdateChannel replaces the settings of a chat channel
+func (n *Notifier) UpdateChannel(id uuid.UUID, input ChannelInput) (*models.ChatChannel, error) {
+	channel, err := n.getChannel(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateWebhookURL(input.Provider, input.WebhookURL); err != nil {
+		return nil, err
+	}
+
+	channel.Name = strings.TrimSpace(input.Name)
+	channel.Provider = input.Provider
+	channel.WebhookURL = input.WebhookURL
+	if input.IsActive != nil {
+		channel.IsActive = *input.IsActive
+	}
+	if err := n.db.Select("name", "provider", "webhook_url", "is_active").Save(channel).Error; err != nil {
+		return nil, fmt.Errorf("failed to update chat channel: %w", err)
+	}
+	return channel, nil
+}
+
+// DeleteChannel removes a chat channel together with its routing rules
+func (n *Notifier) DeleteChannel(id uuid.UUID) error {
+	channel, err := n.getChannel(id)
+	if err != nil {
+		return err
+	}
+
+	return n.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("channel_id = ?", channel.ID).Delete(&models.ChatRoutingRule{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(channel).Error
+	})
+}
+
+// AddRule routes an event to a chat channel
+func (n *Notifier) AddRule(channelID uuid.UUID, input RuleInput) (*models.ChatRoutingRule, error) {
+	if _, err := n.getChannel(channelID); err != nil {
+		return nil, err
+	}
+
+	switch input.Event {
+	case models.ChatEventLeadCreated, models.ChatEventBookingPaid, models.ChatEventLeadSLABreached, models.ChatEventInboxUnclaimed:
+	default:
+		return nil, ErrInvalidEvent
+	}
+	if input.MinPriority != "" && input.MinPriority.Rank() == 0 {
+		return nil, ErrInvalidPriority
+	}
+
+	rule := &models.ChatRoutingRule{
+		ChannelID:   channelID,
+		Event:       input.Event,
+		MinPriority: input.MinPriority,
+		BeraterID:   input.BeraterID,
+	}
+	if err := n.db.Create(rule).Error; err != nil {
+		return nil, fmt.Errorf("failed to create routing rule: %w", err)
+	}
+	return rule, nil
+}
+
+// DeleteRule removes a routing rule from a chat channel
+func (n *Notifier) DeleteRule(channelID, ruleID uuid.UUID) error {
+	result := n.db.Where("id = ? AND channel_id = ?", ruleID, channelID).Delete(&models.ChatRoutingRule{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrRuleNotFound
+	}
+	return nil
+}
+
+// Test posts a test message to a chat channel, also if it is inactive
+func (n *Notifier) Test(id uuid.UUID) error {
+	channel, err := n.getChannel(id)
+	if err != nil {
+		return err
+	}
+
+	return n.deliver(channel, Message{
+		Title: "Testnachricht aus dem Elterngeld-Portal",
+		Text:  fmt.Sprintf("Der Kanal „%s“ ist korrekt eingerichtet.", channel.Name),
+		URL:   n.baseURL + "/dashboard",
+		Color: colorInfo,
+	})
+}
+
+func (n *Notifier) getChannel(id uuid.UUID) (*models.ChatChannel, error) {
+	var channel models.ChatChannel
+	if err := n.db.First(&channel, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrChannelNotFound
+		}
+		return nil, err
+	}
+	return &channel, nil
+}
+
+// validateWebhookURL makes sure notifications are only posted to the webhook
+// endpoints of the provider
+func validateWebhookURL(provider models.ChatProvider, raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return ErrInvalidWebhookURL
+	}
+	host := strings.ToLower(u.Hostname())
+
+	switch provider {
+	case models.ChatProviderSlack:
+		if host != "hooks.slack.com" {
+			return ErrInvalidWebhookURL
+		}
+	case models.ChatProviderTeams:
+		for _, suffix := range teamsHosts {
+			if strings.HasSuffix(host, suffix) {
+				return nil
+			}
+		}
+		return ErrInvalidWebhookURL
+	default:
+		return ErrInvalidProvider
+	}
+	return nil
+}
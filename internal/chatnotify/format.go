@@ -0,0 +1,138 @@
+package chatnotify
+
+import (
+	"elterngeld-portal/internal/models"
+)
+
+// Message is a provider independent chat notification
+type Message struct {
+	Title string
+	Text  string
+	Facts []Fact
+	URL   string // link to the case in the portal
+	Color string // hex color without "#", used as accent
+}
+
+// Fact is a labelled value shown below the message text
+type Fact struct {
+	Name  string
+	Value string
+}
+
+const (
+	colorInfo    = "2E7D32"
+	colorWarning = "E65100"
+	colorUrgent  = "C62828"
+)
+
+// payload builds the webhook body for a provider
+func payload(provider models.ChatProvider, msg Message) interface{} {
+	if provider == models.ChatProviderTeams {
+		return teamsPayload(msg)
+	}
+	return slackPayload(msg)
+}
+
+// slackPayload formats a message with Block Kit; text is the fallback for
+// notifications and clients without block support
+func slackPayload(msg Message) map[string]interface{} {
+	blocks := []map[string]interface{}{
+		{
+			"type": "header",
+			"text": map[string]interface{}{"type": "plain_text", "text": msg.Title},
+		},
+	}
+	if msg.Text != "" {
+		blocks = append(blocks, map[string]interface{}{
+			"type": "section",
+			"text": map[string]interface{}{"type": "mrkdwn", "text": msg.Text},
+		})
+	}
+	if len(msg.Facts) > 0 {
+		fields := make([]map[string]interface{}, 0, len(msg.Facts))
+		for _, fact := range msg.Facts {
+			fields = append(fields, map[string]interface{}{
+				"type": "mrkdwn",
+				"text": "*" + fact.Name + "*\n" + fact.Value,
+			})
+		}
+		blocks = append(blocks, map[string]interface{}{"type": "section", "fields": fields})
+	}
+	if msg.URL != "" {
+		blocks = append(blocks, map[string]interface{}{
+			"type": "actions",
+			"elements": []map[string]interface{}{{
+				"type": "button",
+				"text": map[string]interface{}{"type": "plain_text", "text": "Im Portal öffnen"},
+				"url":  msg.URL,
+			}},
+		})
+	}
+
+	return map[string]interface{}{
+		"text": msg.Title,
+		"attachments": []map[string]interface{}{{
+			"color":  "#" + msg.Color,
+			"blocks": blocks,
+		}},
+	}
+}
+
+// teamsPayload formats a message as an Adaptive Card, which both Teams
+// Workflows and the older Office 365 connectors accept
+func teamsPayload(msg Message) map[string]interface{} {
+	body := []map[string]interface{}{
+		{
+			"type":   "TextBlock",
+			"text":   msg.Title,
+			"weight": "Bolder",
+			"size":   "Medium",
+			"wrap":   true,
+			"color":  teamsColor(msg.Color),
+		},
+	}
+	if msg.Text != "" {
+		body = append(body, map[string]interface{}{"type": "TextBlock", "text": msg.Text, "wrap": true})
+	}
+	if len(msg.Facts) > 0 {
+		facts := make([]map[string]interface{}, 0, len(msg.Facts))
+		for _, fact := range msg.Facts {
+			facts = append(facts, map[string]interface{}{"title": fact.Name, "value": fact.Value})
+		}
+		body = append(body, map[string]interface{}{"type": "FactSet", "facts": facts})
+	}
+
+	card := map[string]interface{}{
+		"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+		"type":    "AdaptiveCard",
+		"version": "1.4",
+		"body":    body,
+	}
+	if msg.URL != "" {
+		card["actions"] = []map[string]interface{}{{
+			"type":  "Action.OpenUrl",
+			"title": "Im Portal öffnen",
+			"url":   msg.URL,
+		}}
+	}
+
+	return map[string]interface{}{
+		"type": "message",
+		"attachments": []map[string]interface{}{{
+			"contentType": "application/vnd.microsoft.card.adaptive",
+			"content":     card,
+		}},
+	}
+}
+
+// teamsColor maps accent colors to the named colors of Adaptive Cards
+func teamsColor(color string) string {
+	switch color {
+	case colorUrgent:
+		return "Attention"
+	case colorWarning:
+		return "Warning"
+	default:
+		return "Good"
+	}
+}
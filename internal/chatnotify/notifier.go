@@ -0,0 +1,312 @@
+package chatnotify
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"elterngeld-portal/config"
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/internal/settings"
+	"elterngeld-portal/pkg/timeutil"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// slaBatchSize limits the number of SLA breaches posted per check
+const slaBatchSize = 100
+
+// Notifier posts lead and booking events to the Slack and Teams channels whose
+// routing rules match. Messages contain case metadata and a portal link but no
+// contact data of customers.
+type Notifier struct {
+	db       *gorm.DB
+	logger   *zap.Logger
+	client   *http.Client
+	baseURL  string
+	location *time.Location
+	sla      func() time.Duration // admins can change the lead response SLA at runtime
+	claimSLA func() time.Duration // and the claim SLA of the team inbox
+	now      func() time.Time
+}
+
+func NewNotifier(db *gorm.DB, logger *zap.Logger, cfg *config.Config, settingsService *settings.Service) *Notifier {
+	leadResponseSLA := func() time.Duration { return settingsService.Duration(settings.KeyLeadResponseSLA) }
+	inboxClaimSLA := func() time.Duration { return settingsService.Duration(settings.KeyInboxClaimSLA) }
+	return &Notifier{
+		db:       db,
+		logger:   logger,
+		client:   &http.Client{Timeout: cfg.Chat.WebhookTimeout},
+		baseURL:  strings.TrimRight(cfg.App.BaseURL, "/"),
+		location: timeutil.LoadLocation(cfg.App.Timezone),
+		sla:      leadResponseSLA,
+		claimSLA: inboxClaimSLA,
+		now:      time.Now,
+	}
+}
+
+// LeadCreated posts a new lead to channels with a matching lead.created rule,
+// typically limited to high-priority leads
+func (n *Notifier) LeadCreated(lead *models.Lead) error {
+	title := "Neuer Lead"
+	color := colorInfo
+	if lead.Priority.Rank() >= models.PriorityHigh.Rank() {
+		title = fmt.Sprintf("Neuer Lead mit Priorität %s", lead.Priority.GetDisplayName())
+		color = colorWarning
+	}
+	if lead.Priority == models.PriorityUrgent {
+		color = colorUrgent
+	}
+
+	return n.dispatch(models.ChatEventLeadCreated, lead.Priority, lead.BeraterID, Message{
+		Title: title,
+		Facts: n.leadFacts(lead),
+		URL:   fmt.Sprintf("%s/dashboard/leads/%s", n.baseURL, lead.ID),
+		Color: color,
+	})
+}
+
+// BookingPaid posts a paid booking to channels with a matching booking.paid rule
+func (n *Notifier) BookingPaid(booking *models.Booking, payment *models.Payment) error {
+	facts := []Fact{
+		{Name: "Buchung", Value: booking.BookingReference},
+		{Name: "Termin", Value: booking.ScheduledAt.In(n.location).Format("02.01.2006 15:04")},
+		{Name: "Betrag", Value: fmt.Sprintf("%.2f %s", payment.Amount, payment.Currency)},
+		{Name: "Berater", Value: n.beraterName(booking.BeraterID)},
+	}
+
+	var priority models.Priority
+	if booking.LeadID != nil {
+		var lead models.Lead
+		if err := n.db.Select("priority").First(&lead, "id = ?", *booking.LeadID).Error; err == nil {
+			priority = lead.Priority
+		}
+	}
+
+	return n.dispatch(models.ChatEventBookingPaid, priority, booking.BeraterID, Message{
+		Title: "Buchung bezahlt: " + booking.Title,
+		Facts: facts,
+		URL:   fmt.Sprintf("%s/dashboard/bookings/%s", n.baseURL, booking.ID),
+		Color: colorInfo,
+	})
+}
+
+// CheckSLA posts new leads that did not get a first contact within the lead
+// response SLA. Every lead is posted once.
+func (n *Notifier) CheckSLA() {
+	sla := n.sla()
+	if sla <= 0 {
+		return
+	}
+
+	now := n.now()
+	var leads []models.Lead
+	if err := n.db.Where("status = ? AND last_contact_at IS NULL AND sla_breach_notified_at IS NULL AND created_at <= ?",
+		models.LeadStatusNew, now.Add(-sla)).
+		Order("created_at ASC").Limit(slaBatchSize).Find(&leads).Error; err != nil {
+		n.logger.Error("Failed to load leads for SLA check", zap.Error(err))
+		return
+	}
+
+	for i := range leads {
+		lead := &leads[i]
+		waiting := now.Sub(lead.CreatedAt).Truncate(time.Hour)
+
+		facts := append(n.leadFacts(lead),
+			Fact{Name: "Eingegangen", Value: lead.CreatedAt.In(n.location).Format("02.01.2006 15:04")},
+			Fact{Name: "Wartezeit", Value: fmt.Sprintf("%.0f Stunden", waiting.Hours())})
+
+		n.dispatch(models.ChatEventLeadSLABreached, lead.Priority, lead.BeraterID, Message{
+			Title: "SLA verletzt: Lead ohne Erstkontakt",
+			Text:  fmt.Sprintf("Der Lead wartet seit mehr als %.0f Stunden auf eine erste Kontaktaufnahme.", sla.Hours()),
+			Facts: facts,
+			URL:   fmt.Sprintf("%s/dashboard/leads/%s", n.baseURL, lead.ID),
+			Color: colorUrgent,
+		})
+
+		if err := n.db.Model(lead).UpdateColumn("sla_breach_notified_at", now).Error; err != nil {
+			n.logger.Error("Failed to mark SLA breach as notified", zap.String("lead_id", lead.ID.String()), zap.Error(err))
+		}
+	}
+}
+
+// CheckUnclaimed posts contact forms and unassigned leads in the team inbox
+// that nobody claimed within the claim SLA. Every item is posted once.
+func (n *Notifier) CheckUnclaimed() {
+	sla := n.claimSLA()
+	if sla <= 0 {
+		return
+	}
+
+	now := n.now()
+	cutoff := now.Add(-sla)
+	text := fmt.Sprintf("Niemand hat die Anfrage innerhalb von %.0f Stunden übernommen.", sla.Hours())
+
+	var forms []models.ContactForm
+	if err := n.db.Where("claimed_by IS NULL AND is_processed = ? AND unclaimed_notified_at IS NULL AND created_at <= ?", false, cutoff).
+		Order("created_at ASC").Limit(slaBatchSize).Find(&forms).Error; err != nil {
+		n.logger.Error("Failed to load contact forms for claim SLA check", zap.Error(err))
+		return
+	}
+	for i := range forms {
+		form := &forms[i]
+		n.dispatch(models.ChatEventInboxUnclaimed, "", nil, Message{
+			Title: "Team-Postfach: Kontaktanfrage nicht übernommen",
+			Text:  text,
+			Facts: []Fact{
+				{Name: "Betreff", Value: form.Subject},
+				{Name: "Quelle", Value: form.Source},
+				{Name: "Eingegangen", Value: form.CreatedAt.In(n.location).Format("02.01.2006 15:04")},
+			},
+			URL:   fmt.Sprintf("%s/dashboard/inbox/contact-forms/%s", n.baseURL, form.ID),
+			Color: colorWarning,
+		})
+
+		if err := n.db.Model(form).UpdateColumn("unclaimed_notified_at", now).Error; err != nil {
+			n.logger.Error("Failed to mark unclaimed contact form as notified", zap.String("contact_form_id", form.ID.String()), zap.Error(err))
+		}
+	}
+
+	var leads []models.Lead
+	if err := n.db.Where("berater_id IS NULL AND status = ? AND unclaimed_notified_at IS NULL AND created_at <= ?", models.LeadStatusNew, cutoff).
+		Order("created_at ASC").Limit(slaBatchSize).Find(&leads).Error; err != nil {
+		n.logger.Error("Failed to load leads for claim SLA check", zap.Error(err))
+		return
+	}
+	for i := range leads {
+		lead := &leads[i]
+		facts := append(n.leadFacts(lead),
+			Fact{Name: "Eingegangen", Value: lead.CreatedAt.In(n.location).Format("02.01.2006 15:04")})
+
+		n.dispatch(models.ChatEventInboxUnclaimed, lead.Priority, nil, Message{
+			Title: "Team-Postfach: Lead nicht übernommen",
+			Text:  text,
+			Facts: facts,
+			URL:   fmt.Sprintf("%s/dashboard/leads/%s", n.baseURL, lead.ID),
+			Color: colorWarning,
+		})
+
+		if err := n.db.Model(lead).UpdateColumn("unclaimed_notified_at", now).Error; err != nil {
+			n.logger.Error("Failed to mark unclaimed lead as notified", zap.String("lead_id", lead.ID.String()), zap.Error(err))
+		}
+	}
+}
+
+// dispatch posts a message to every active channel with a rule matching the
+// event and returns the errors of the channels it could not be posted to
+func (n *Notifier) dispatch(event models.ChatEvent, priority models.Priority, beraterID *uuid.UUID, msg Message) error {
+	var channels []models.ChatChannel
+	if err := n.db.Preload("Rules", "event = ?", event).
+		Where("is_active = ?", true).Find(&channels).Error; err != nil {
+		n.logger.Error("Failed to load chat channels", zap.String("event", string(event)), zap.Error(err))
+		return err
+	}
+
+	var errs []error
+	for i := range channels {
+		channel := &channels[i]
+		for _, rule := range channel.Rules {
+			if rule.Matches(event, priority, beraterID) {
+				if err := n.deliver(channel, msg); err != nil {
+					errs = append(errs, fmt.Errorf("channel %s: %w", channel.Name, err))
+				}
+				break
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// deliver posts a message to a channel and records the outcome on the channel
+func (n *Notifier) deliver(channel *models.ChatChannel, msg Message) error {
+	err := n.post(channel.Provider, channel.WebhookURL, msg)
+
+	updates := map[string]interface{}{"last_error": ""}
+	if err != nil {
+		updates["last_error"] = err.Error()
+		n.logger.Error("Failed to post chat notification",
+			zap.String("channel_id", channel.ID.String()),
+			zap.String("provider", string(channel.Provider)),
+			zap.Error(err))
+	} else {
+		updates["last_delivered_at"] = n.now()
+	}
+	if dbErr := n.db.Model(channel).UpdateColumns(updates).Error; dbErr != nil {
+		n.logger.Warn("Failed to record chat delivery", zap.String("channel_id", channel.ID.String()), zap.Error(dbErr))
+	}
+
+	return err
+}
+
+func (n *Notifier) post(provider models.ChatProvider, webhookURL string, msg Message) error {
+	body, err := json.Marshal(payload(provider, msg))
+	if err != nil {
+		return err
+	}
+
+	resp, err := n.client.Post(webhookURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		// The URL contains the webhook secret, so it is not part of the error
+		if urlErr, ok := err.(interface{ Unwrap() error }); ok {
+			err = urlErr.Unwrap()
+		}
+		return fmt.Errorf("webhook request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("webhook returned %d: %s", resp.StatusCode, strings.TrimSpace(string(detail)))
+	}
+	return nil
+}
+
+func (n *Notifier) leadFacts(lead *models.Lead) []Fact {
+	facts := []Fact{
+		{Name: "Antragsnummer", Value: lead.ApplicationNumber},
+		{Name: "Titel", Value: lead.Title},
+		{Name: "Priorität", Value: lead.Priority.GetDisplayName()},
+		{Name: "Quelle", Value: string(lead.Source)},
+		{Name: "Berater", Value: n.beraterName(lead.BeraterID)},
+	}
+	return append(facts, n.qualificationFacts(lead.ID)...)
+}
+
+// qualificationFacts show the Berater the score and the recommended package
+// from the latest scored questionnaire answers of a lead, e.g. of its
+// pre-talk booking
+func (n *Notifier) qualificationFacts(leadID uuid.UUID) []Fact {
+	var response models.QuestionnaireResponse
+	err := n.db.Preload("RecommendedPackage").Where("lead_id = ? AND score IS NOT NULL", leadID).
+		Order("submitted_at DESC").First(&response).Error
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			n.logger.Warn("Failed to load lead qualification", zap.String("lead_id", leadID.String()), zap.Error(err))
+		}
+		return nil
+	}
+
+	facts := []Fact{{Name: "Qualifizierung", Value: fmt.Sprintf("%d Punkte", *response.Score)}}
+	if response.RecommendedPackage != nil {
+		facts = append(facts, Fact{Name: "Empfohlenes Paket", Value: response.RecommendedPackage.Name})
+	}
+	return facts
+}
+
+func (n *Notifier) beraterName(beraterID *uuid.UUID) string {
+	if beraterID == nil {
+		return "Nicht zugewiesen"
+	}
+	var berater models.User
+	if err := n.db.Select("first_name", "last_name").First(&berater, "id = ?", *beraterID).Error; err != nil {
+		return "Unbekannt"
+	}
+	return strings.TrimSpace(berater.FirstName + " " + berater.LastName)
+}
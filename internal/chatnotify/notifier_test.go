@@ -0,0 +1,363 @@
+package chatnotify
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/tests/testutils"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// chatServer records the payloads posted to a fake webhook endpoint
+type chatServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	payloads []map[string]interface{}
+	status   int
+}
+
+func newChatServer(t *testing.T) *chatServer {
+	t.Helper()
+	s := &chatServer{status: http.StatusOK}
+	s.Server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]interface{}
+		_ = json.NewDecoder(r.Body).Decode(&payload)
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.payloads = append(s.payloads, payload)
+		w.WriteHeader(s.status)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *chatServer) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.payloads)
+}
+
+func TestLeadCreated_RoutesByRule(t *testing.T) {
+	db, notifier := setupTestNotifier(t)
+	server := newChatServer(t)
+	notifier.client = server.Client()
+
+	customer := createTestUser(t, db, "kunde@example.com", models.RoleUser)
+	berater := createTestUser(t, db, "berater@example.com", models.RoleBerater)
+
+	urgent := createTestChannel(t, db, "Dringend", models.ChatProviderSlack, server.URL+"/urgent",
+		models.ChatRoutingRule{Event: models.ChatEventLeadCreated, MinPriority: models.PriorityHigh})
+	personal := createTestChannel(t, db, "Berater", models.ChatProviderTeams, server.URL+"/personal",
+		models.ChatRoutingRule{Event: models.ChatEventLeadCreated, BeraterID: &berater.ID})
+	inactive := createTestChannel(t, db, "Inaktiv", models.ChatProviderSlack, server.URL+"/inactive",
+		models.ChatRoutingRule{Event: models.ChatEventLeadCreated})
+	require.NoError(t, db.Model(inactive).Update("is_active", false).Error)
+
+	// Medium priority, unassigned: no channel matches
+	notifier.LeadCreated(&models.Lead{ID: uuid.New(), UserID: customer.ID, Title: "Antrag", Priority: models.PriorityMedium})
+	assert.Equal(t, 0, server.count())
+
+	// High priority lead of the Berater: both channels match
+	lead := &models.Lead{
+		ID:                uuid.New(),
+		UserID:            customer.ID,
+		BeraterID:         &berater.ID,
+		ApplicationNumber: "EG-2025-0001",
+		Title:             "Elterngeldantrag",
+		Priority:          models.PriorityUrgent,
+	}
+	require.NoError(t, notifier.LeadCreated(lead))
+	require.Equal(t, 2, server.count())
+
+	body, err := json.Marshal(server.payloads)
+	require.NoError(t, err)
+	assert.Contains(t, string(body), "EG-2025-0001")
+	assert.Contains(t, string(body), "https://portal.example.com/dashboard/leads/"+lead.ID.String())
+	assert.NotContains(t, string(body), customer.Email)
+
+	for _, channel := range []*models.ChatChannel{urgent, personal} {
+		var stored models.ChatChannel
+		require.NoError(t, db.First(&stored, "id = ?", channel.ID).Error)
+		assert.NotNil(t, stored.LastDeliveredAt)
+		assert.Empty(t, stored.LastError)
+	}
+}
+
+func TestLeadCreated_ShowsQualification(t *testing.T) {
+	db, notifier := setupTestNotifier(t)
+	server := newChatServer(t)
+	notifier.client = server.Client()
+	createTestChannel(t, db, "Leads", models.ChatProviderSlack, server.URL,
+		models.ChatRoutingRule{Event: models.ChatEventLeadCreated})
+
+	customer := createTestUser(t, db, "kunde@example.com", models.RoleUser)
+	lead := &models.Lead{UserID: customer.ID, Title: "Vorgespräch", Priority: models.PriorityHigh, Status: models.LeadStatusNew}
+	require.NoError(t, db.Create(lead).Error)
+	pkg := &models.Package{Name: "Premium", Type: models.PackageTypePremium, Price: 249, IsActive: true}
+	require.NoError(t, db.Create(pkg).Error)
+	questionnaire := &models.Questionnaire{PackageID: pkg.ID, Version: 1, Title: "Vorgespräch", CreatedByID: customer.ID}
+	require.NoError(t, db.Create(questionnaire).Error)
+	score := 55
+	require.NoError(t, db.Create(&models.QuestionnaireResponse{
+		QuestionnaireID:      questionnaire.ID,
+		LeadID:               lead.ID,
+		Answers:              []byte(`{}`),
+		SubmittedAt:          time.Now(),
+		Score:                &score,
+		RecommendedPackageID: &pkg.ID,
+	}).Error)
+
+	require.NoError(t, notifier.LeadCreated(lead))
+	require.Equal(t, 1, server.count())
+
+	body, err := json.Marshal(server.payloads)
+	require.NoError(t, err)
+	assert.Contains(t, string(body), "55 Punkte")
+	assert.Contains(t, string(body), "Premium")
+}
+
+func TestBookingPaid(t *testing.T) {
+	db, notifier := setupTestNotifier(t)
+	server := newChatServer(t)
+	notifier.client = server.Client()
+	createTestChannel(t, db, "Buchungen", models.ChatProviderTeams, server.URL,
+		models.ChatRoutingRule{Event: models.ChatEventBookingPaid})
+
+	booking := &models.Booking{
+		ID:               uuid.New(),
+		Title:            "Erstberatung",
+		BookingReference: "BK-1234",
+		ScheduledAt:      time.Date(2025, 3, 10, 9, 0, 0, 0, time.UTC),
+	}
+	require.NoError(t, notifier.BookingPaid(booking, &models.Payment{Amount: 149, Currency: "EUR"}))
+	require.Equal(t, 1, server.count())
+
+	attachments := server.payloads[0]["attachments"].([]interface{})
+	card := attachments[0].(map[string]interface{})
+	assert.Equal(t, "application/vnd.microsoft.card.adaptive", card["contentType"])
+
+	body, err := json.Marshal(card)
+	require.NoError(t, err)
+	assert.Contains(t, string(body), "149.00 EUR")
+	assert.Contains(t, string(body), "10.03.2025 10:00")
+}
+
+func TestCheckSLA(t *testing.T) {
+	db, notifier := setupTestNotifier(t)
+	server := newChatServer(t)
+	notifier.client = server.Client()
+	createTestChannel(t, db, "SLA", models.ChatProviderSlack, server.URL,
+		models.ChatRoutingRule{Event: models.ChatEventLeadSLABreached})
+
+	now := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+	notifier.now = func() time.Time { return now }
+	customer := createTestUser(t, db, "kunde@example.com", models.RoleUser)
+	contacted := now.Add(-time.Hour)
+
+	overdue := &models.Lead{UserID: customer.ID, Title: "Überfällig", CreatedAt: now.Add(-30 * time.Hour)}
+	recent := &models.Lead{UserID: customer.ID, Title: "Neu", CreatedAt: now.Add(-time.Hour)}
+	answered := &models.Lead{UserID: customer.ID, Title: "Kontaktiert", CreatedAt: now.Add(-30 * time.Hour), LastContactAt: &contacted}
+	for _, lead := range []*models.Lead{overdue, recent, answered} {
+		require.NoError(t, db.Create(lead).Error)
+	}
+
+	notifier.CheckSLA()
+	require.Equal(t, 1, server.count())
+
+	var stored models.Lead
+	require.NoError(t, db.First(&stored, "id = ?", overdue.ID).Error)
+	assert.NotNil(t, stored.SLABreachNotifiedAt)
+
+	// Breaches are only posted once
+	notifier.CheckSLA()
+	assert.Equal(t, 1, server.count())
+}
+
+func TestCheckUnclaimed(t *testing.T) {
+	db, notifier := setupTestNotifier(t)
+	server := newChatServer(t)
+	notifier.client = server.Client()
+	createTestChannel(t, db, "Postfach", models.ChatProviderSlack, server.URL,
+		models.ChatRoutingRule{Event: models.ChatEventInboxUnclaimed})
+
+	now := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+	notifier.now = func() time.Time { return now }
+	customer := createTestUser(t, db, "kunde@example.com", models.RoleUser)
+	berater := createTestUser(t, db, "berater@example.com", models.RoleBerater)
+	claimedAt := now.Add(-6 * time.Hour)
+
+	waiting := &models.ContactForm{Name: "Anna", Email: "anna@example.com", Subject: "Elterngeld Plus", Message: "...", CreatedAt: now.Add(-5 * time.Hour)}
+	claimed := &models.ContactForm{Name: "Ben", Email: "ben@example.com", Subject: "Rückfrage", Message: "...", CreatedAt: now.Add(-8 * time.Hour), ClaimedBy: &berater.ID, ClaimedAt: &claimedAt}
+	fresh := &models.ContactForm{Name: "Cem", Email: "cem@example.com", Subject: "Frage", Message: "...", CreatedAt: now.Add(-time.Hour)}
+	for _, form := range []*models.ContactForm{waiting, claimed, fresh} {
+		require.NoError(t, db.Create(form).Error)
+	}
+
+	unassigned := &models.Lead{UserID: customer.ID, Title: "Offen", CreatedAt: now.Add(-5 * time.Hour)}
+	assigned := &models.Lead{UserID: customer.ID, BeraterID: &berater.ID, Title: "Zugewiesen", CreatedAt: now.Add(-5 * time.Hour)}
+	for _, lead := range []*models.Lead{unassigned, assigned} {
+		require.NoError(t, db.Create(lead).Error)
+	}
+
+	notifier.CheckUnclaimed()
+	require.Equal(t, 2, server.count())
+
+	var stored models.ContactForm
+	require.NoError(t, db.First(&stored, "id = ?", waiting.ID).Error)
+	assert.NotNil(t, stored.UnclaimedNotifiedAt)
+
+	// Unclaimed items are only posted once
+	notifier.CheckUnclaimed()
+	assert.Equal(t, 2, server.count())
+}
+
+func TestDeliveryFailureIsRecorded(t *testing.T) {
+	db, notifier := setupTestNotifier(t)
+	server := newChatServer(t)
+	server.status = http.StatusBadRequest
+	notifier.client = server.Client()
+	channel := createTestChannel(t, db, "Kaputt", models.ChatProviderSlack, server.URL)
+
+	assert.Error(t, notifier.Test(channel.ID))
+
+	var stored models.ChatChannel
+	require.NoError(t, db.First(&stored, "id = ?", channel.ID).Error)
+	assert.Contains(t, stored.LastError, "400")
+	assert.Nil(t, stored.LastDeliveredAt)
+
+	assert.ErrorIs(t, notifier.Test(uuid.New()), ErrChannelNotFound)
+}
+
+func TestChannelManagement(t *testing.T) {
+	_, notifier := setupTestNotifier(t)
+
+	_, err := notifier.CreateChannel(ChannelInput{Name: "Slack", Provider: models.ChatProviderSlack, WebhookURL: "https://example.com/hook"})
+	assert.ErrorIs(t, err, ErrInvalidWebhookURL)
+	_, err = notifier.CreateChannel(ChannelInput{Name: "Slack", Provider: models.ChatProviderSlack, WebhookURL: "http://hooks.slack.com/services/T/B/X"})
+	assert.ErrorIs(t, err, ErrInvalidWebhookURL)
+	_, err = notifier.CreateChannel(ChannelInput{Name: "Chat", Provider: "discord", WebhookURL: "https://discord.com/api/webhooks/1"})
+	assert.ErrorIs(t, err, ErrInvalidProvider)
+
+	channel, err := notifier.CreateChannel(ChannelInput{Name: "Team", Provider: models.ChatProviderSlack, WebhookURL: "https://hooks.slack.com/services/T/B/X"})
+	require.NoError(t, err)
+	assert.True(t, channel.IsActive)
+
+	inactive := false
+	channel, err = notifier.UpdateChannel(channel.ID, ChannelInput{
+		Name:       "Team",
+		Provider:   models.ChatProviderTeams,
+		WebhookURL: "https://contoso.webhook.office.com/webhookb2/abc",
+		IsActive:   &inactive,
+	})
+	require.NoError(t, err)
+	assert.False(t, channel.IsActive)
+
+	rule, err := notifier.AddRule(channel.ID, RuleInput{Event: models.ChatEventLeadCreated, MinPriority: models.PriorityHigh})
+	require.NoError(t, err)
+	_, err = notifier.AddRule(channel.ID, RuleInput{Event: "lead.deleted"})
+	assert.ErrorIs(t, err, ErrInvalidEvent)
+	_, err = notifier.AddRule(channel.ID, RuleInput{Event: models.ChatEventLeadCreated, MinPriority: "sehr hoch"})
+	assert.ErrorIs(t, err, ErrInvalidPriority)
+	_, err = notifier.AddRule(uuid.New(), RuleInput{Event: models.ChatEventLeadCreated})
+	assert.ErrorIs(t, err, ErrChannelNotFound)
+
+	channels, err := notifier.ListChannels()
+	require.NoError(t, err)
+	require.Len(t, channels, 1)
+	assert.Equal(t, "https://contoso.webhook.office.com/webhookb2/abc", channels[0].WebhookURL)
+	require.Len(t, channels[0].Rules, 1)
+
+	assert.ErrorIs(t, notifier.DeleteRule(uuid.New(), rule.ID), ErrRuleNotFound)
+	require.NoError(t, notifier.DeleteRule(channel.ID, rule.ID))
+	require.NoError(t, notifier.DeleteChannel(channel.ID))
+	assert.ErrorIs(t, notifier.DeleteChannel(channel.ID), ErrChannelNotFound)
+}
+
+func TestRuleMatches(t *testing.T) {
+	beraterID := uuid.New()
+	otherID := uuid.New()
+
+	tests := []struct {
+		name      string
+		rule      models.ChatRoutingRule
+		event     models.ChatEvent
+		priority  models.Priority
+		beraterID *uuid.UUID
+		expected  bool
+	}{
+		{"any_lead", models.ChatRoutingRule{Event: models.ChatEventLeadCreated}, models.ChatEventLeadCreated, models.PriorityLow, nil, true},
+		{"other_event", models.ChatRoutingRule{Event: models.ChatEventBookingPaid}, models.ChatEventLeadCreated, models.PriorityLow, nil, false},
+		{"below_priority", models.ChatRoutingRule{Event: models.ChatEventLeadCreated, MinPriority: models.PriorityHigh}, models.ChatEventLeadCreated, models.PriorityMedium, nil, false},
+		{"above_priority", models.ChatRoutingRule{Event: models.ChatEventLeadCreated, MinPriority: models.PriorityHigh}, models.ChatEventLeadCreated, models.PriorityUrgent, nil, true},
+		{"own_berater", models.ChatRoutingRule{Event: models.ChatEventLeadCreated, BeraterID: &beraterID}, models.ChatEventLeadCreated, models.PriorityLow, &beraterID, true},
+		{"other_berater", models.ChatRoutingRule{Event: models.ChatEventLeadCreated, BeraterID: &beraterID}, models.ChatEventLeadCreated, models.PriorityLow, &otherID, false},
+		{"unassigned", models.ChatRoutingRule{Event: models.ChatEventLeadCreated, BeraterID: &beraterID}, models.ChatEventLeadCreated, models.PriorityLow, nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, tt.rule.Matches(tt.event, tt.priority, tt.beraterID))
+		})
+	}
+}
+
+func setupTestNotifier(t *testing.T) (*gorm.DB, *Notifier) {
+	t.Helper()
+	db := testutils.NewSQLiteDB(t,
+		&models.User{},
+		&models.Lead{},
+		&models.ContactForm{},
+		&models.ChatChannel{},
+		&models.ChatRoutingRule{},
+		&models.Package{},
+		&models.Questionnaire{},
+		&models.QuestionnaireQuestion{},
+		&models.QuestionnaireResponse{},
+	)
+
+	location, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+
+	return db, &Notifier{
+		db:       db,
+		logger:   zap.NewNop(),
+		client:   http.DefaultClient,
+		baseURL:  "https://portal.example.com",
+		location: location,
+		sla:      func() time.Duration { return 24 * time.Hour },
+		claimSLA: func() time.Duration { return 4 * time.Hour },
+		now:      time.Now,
+	}
+}
+
+func createTestChannel(t *testing.T, db *gorm.DB, name string, provider models.ChatProvider, webhookURL string, rules ...models.ChatRoutingRule) *models.ChatChannel {
+	t.Helper()
+	channel := &models.ChatChannel{
+		Name:       name,
+		Provider:   provider,
+		WebhookURL: webhookURL,
+		IsActive:   true,
+		Rules:      rules,
+	}
+	require.NoError(t, db.Create(channel).Error)
+	return channel
+}
+
+func createTestUser(t *testing.T, db *gorm.DB, email string, role models.UserRole) *models.User {
+	t.Helper()
+	user := testutils.CreateTestUser(t, db, role)
+	require.NoError(t, db.Model(user).Update("email", email).Error)
+	user.Email = email
+	return user
+}
@@ -0,0 +1,155 @@
+package comments
+
+import (
+	"errors"
+	"strings"
+
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/internal/scopes"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Update replaces the content of a comment. The previous content is kept as
+// a revision, only the author and admins edit comments.
+func (s *Service) Update(commentID uuid.UUID, viewer scopes.Viewer, content string) (*models.Comment, error) {
+	comment, err := s.editable(commentID, viewer)
+	if err != nil {
+		return nil, err
+	}
+	content = strings.TrimSpace(content)
+	if content == "" {
+		return nil, ErrEmptyComment
+	}
+	if content == comment.Content {
+		return comment, nil
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		revision := &models.CommentRevision{
+			CommentID:  comment.ID,
+			Content:    comment.Content,
+			EditedByID: viewer.ID,
+		}
+		if err := tx.Create(revision).Error; err != nil {
+			return err
+		}
+
+		now := s.now()
+		comment.Content = content
+		comment.EditedAt = &now
+		return tx.Model(comment).Updates(map[string]interface{}{
+			"content":   content,
+			"edited_at": now,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return comment, nil
+}
+
+// Delete removes a comment from the feed, only the author and admins delete
+// comments. Comments are soft-deleted and keep their revisions.
+func (s *Service) Delete(commentID uuid.UUID, viewer scopes.Viewer) error {
+	comment, err := s.editable(commentID, viewer)
+	if err != nil {
+		return err
+	}
+	return s.db.Delete(comment).Error
+}
+
+// History returns the earlier versions of a comment the viewer can see, oldest first
+func (s *Service) History(commentID uuid.UUID, viewer scopes.Viewer) ([]models.CommentRevision, error) {
+	if _, err := s.visible(commentID, viewer); err != nil {
+		return nil, err
+	}
+
+	revisions := []models.CommentRevision{}
+	err := s.db.Preload("EditedBy").
+		Where("comment_id = ?", commentID).
+		Order("created_at ASC").
+		Find(&revisions).Error
+	return revisions, err
+}
+
+// AddReaction adds the viewer's reaction to a comment. Reacting twice with
+// the same emoji has no further effect.
+func (s *Service) AddReaction(commentID uuid.UUID, viewer scopes.Viewer, reaction models.Reaction) error {
+	if !reaction.IsValid() {
+		return ErrUnknownReaction
+	}
+	if _, err := s.visible(commentID, viewer); err != nil {
+		return err
+	}
+
+	return s.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.CommentReaction{
+		CommentID: commentID,
+		UserID:    viewer.ID,
+		Reaction:  reaction,
+	}).Error
+}
+
+// RemoveReaction removes the viewer's reaction from a comment
+func (s *Service) RemoveReaction(commentID uuid.UUID, viewer scopes.Viewer, reaction models.Reaction) error {
+	if !reaction.IsValid() {
+		return ErrUnknownReaction
+	}
+	if _, err := s.visible(commentID, viewer); err != nil {
+		return err
+	}
+
+	return s.db.Where("comment_id = ? AND user_id = ? AND reaction = ?", commentID, viewer.ID, reaction).
+		Delete(&models.CommentReaction{}).Error
+}
+
+// visible loads a comment the viewer can see
+func (s *Service) visible(commentID uuid.UUID, viewer scopes.Viewer) (*models.Comment, error) {
+	var comment models.Comment
+	err := s.db.Scopes(scopes.VisibleComments(viewer)).Where("comments.id = ?", commentID).First(&comment).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCommentNotFound
+		}
+		return nil, err
+	}
+	return &comment, nil
+}
+
+// editable loads a comment the viewer can see and change
+func (s *Service) editable(commentID uuid.UUID, viewer scopes.Viewer) (*models.Comment, error) {
+	comment, err := s.visible(commentID, viewer)
+	if err != nil {
+		return nil, err
+	}
+	if comment.UserID != viewer.ID && viewer.Role != models.RoleAdmin {
+		return nil, ErrNotAuthor
+	}
+	return comment, nil
+}
+
+// summarize counts reactions per kind in the order of models.Reactions
+func summarize(reactions []models.CommentReaction, viewerID uuid.UUID) []ReactionSummary {
+	counts := make(map[models.Reaction]*ReactionSummary)
+	for _, reaction := range reactions {
+		summary, ok := counts[reaction.Reaction]
+		if !ok {
+			summary = &ReactionSummary{Reaction: reaction.Reaction, Emoji: reaction.Reaction.Emoji()}
+			counts[reaction.Reaction] = summary
+		}
+		summary.Count++
+		if reaction.UserID == viewerID {
+			summary.Reacted = true
+		}
+	}
+
+	result := []ReactionSummary{}
+	for _, reaction := range models.Reactions {
+		if summary, ok := counts[reaction]; ok {
+			result = append(result, *summary)
+		}
+	}
+	return result
+}
@@ -0,0 +1,360 @@
+// Package comments creates, edits and deletes lead comments and lists them
+// with their reactions and signed links to the attached documents.
+// Attachments are either documents already uploaded to the lead or files
+// uploaded together with the comment.
+package comments
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"elterngeld-portal/config"
+	"elterngeld-portal/internal/documents"
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/internal/quota"
+	"elterngeld-portal/internal/scopes"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// MaxAttachments is the maximum number of attachments on a single comment
+const MaxAttachments = 10
+
+var (
+	// ErrEmptyComment is returned when a comment has no content
+	ErrEmptyComment = errors.New("comment content is required")
+	// ErrTooManyAttachments is returned when a comment exceeds MaxAttachments
+	ErrTooManyAttachments = errors.New("too many attachments")
+	// ErrDocumentNotFound is returned when a referenced document does not belong to the lead
+	ErrDocumentNotFound = errors.New("document not found on lead")
+	// ErrFileTooLarge is returned when an uploaded file exceeds the upload size limit
+	ErrFileTooLarge = errors.New("file exceeds maximum upload size")
+	// ErrFileTypeNotAllowed is returned when an uploaded file has a disallowed extension
+	ErrFileTypeNotAllowed = errors.New("file type not allowed")
+	// ErrCommentNotFound is returned when the comment does not exist or the viewer cannot see it
+	ErrCommentNotFound = errors.New("comment not found")
+	// ErrNotAuthor is returned when someone other than the author or an admin changes a comment
+	ErrNotAuthor = errors.New("only the author or an admin can change the comment")
+	// ErrUnknownReaction is returned for reactions that are not in models.Reactions
+	ErrUnknownReaction = errors.New("unknown reaction")
+)
+
+// Upload is a file uploaded together with a comment
+type Upload struct {
+	FileName    string
+	ContentType string
+	Size        int64
+	Content     io.Reader
+}
+
+// Input is a new comment with its attachments
+type Input struct {
+	Content     string
+	IsInternal  bool
+	DocumentIDs []uuid.UUID
+	Uploads     []Upload
+}
+
+// Attachment is a document attached to a comment with the links the viewer opens it with
+type Attachment struct {
+	DocumentID   uuid.UUID           `json:"document_id"`
+	OriginalName string              `json:"original_name"`
+	ContentType  string              `json:"content_type"`
+	FileSize     int64               `json:"file_size"`
+	DocumentType models.DocumentType `json:"document_type"`
+	documents.SignedLinks
+}
+
+// ReactionSummary counts the reactions of one kind on a comment
+type ReactionSummary struct {
+	Reaction models.Reaction `json:"reaction"`
+	Emoji    string          `json:"emoji"`
+	Count    int             `json:"count"`
+	// Reacted is set when the viewer is among those who reacted
+	Reacted bool `json:"reacted"`
+}
+
+// Comment is a lead comment with its attachments and reactions
+type Comment struct {
+	models.Comment
+	Attachments []Attachment      `json:"attachments"`
+	Reactions   []ReactionSummary `json:"reactions"`
+}
+
+// Service manages lead comments and their attachments
+type Service struct {
+	db                *gorm.DB
+	logger            *zap.Logger
+	documents         *documents.Service
+	quota             *quota.Service
+	uploadPath        string
+	maxSize           int64
+	allowedExtensions []string
+	now               func() time.Time
+}
+
+func NewService(db *gorm.DB, logger *zap.Logger, cfg *config.Config, documentService *documents.Service, quotaService *quota.Service) *Service {
+	uploadPath := cfg.Upload.Path
+	if uploadPath == "" {
+		uploadPath = "./storage/uploads"
+	}
+
+	return &Service{
+		db:                db,
+		logger:            logger,
+		documents:         documentService,
+		quota:             quotaService,
+		uploadPath:        uploadPath,
+		maxSize:           cfg.Upload.MaxSize,
+		allowedExtensions: cfg.Upload.AllowedExtensions,
+		now:               time.Now,
+	}
+}
+
+// Create adds a comment by the author to the lead. Referenced documents must
+// belong to the lead, uploads are stored as new documents of the lead and
+// count towards the author's storage quota.
+func (s *Service) Create(lead *models.Lead, authorID uuid.UUID, input Input) (*models.Comment, error) {
+	content := strings.TrimSpace(input.Content)
+	if content == "" {
+		return nil, ErrEmptyComment
+	}
+	documentIDs := unique(input.DocumentIDs)
+	if len(documentIDs)+len(input.Uploads) > MaxAttachments {
+		return nil, ErrTooManyAttachments
+	}
+	for _, upload := range input.Uploads {
+		if err := s.validate(upload); err != nil {
+			return nil, err
+		}
+	}
+
+	var stored []string
+	comment := &models.Comment{
+		ID:         uuid.New(),
+		LeadID:     lead.ID,
+		UserID:     authorID,
+		Content:    content,
+		IsInternal: input.IsInternal,
+	}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var size int64
+		for _, upload := range input.Uploads {
+			size += upload.Size
+		}
+		if size > 0 {
+			if err := s.quota.Check(tx, authorID, size); err != nil {
+				return err
+			}
+		}
+
+		var count int64
+		if len(documentIDs) > 0 {
+			if err := tx.Model(&models.Document{}).Where("id IN ? AND lead_id = ?", documentIDs, lead.ID).
+				Count(&count).Error; err != nil {
+				return err
+			}
+			if int(count) != len(documentIDs) {
+				return ErrDocumentNotFound
+			}
+		}
+
+		if err := tx.Create(comment).Error; err != nil {
+			return fmt.Errorf("failed to create comment: %w", err)
+		}
+
+		for _, upload := range input.Uploads {
+			document, err := s.store(lead.ID, authorID, upload)
+			if document != nil {
+				stored = append(stored, document.FilePath)
+			}
+			if err != nil {
+				return err
+			}
+			// Uploads to internal comments stay hidden from the customers like the comment
+			document.Visibility = models.DocumentVisibilityShared
+			if comment.IsInternal {
+				document.Visibility = models.DocumentVisibilityBerater
+			}
+			if err := tx.Create(document).Error; err != nil {
+				return fmt.Errorf("failed to create document: %w", err)
+			}
+			if err := tx.Create(models.CreateDocumentUploadedActivity(authorID, lead.ID, document.OriginalName, document.DocumentType)).Error; err != nil {
+				return err
+			}
+			documentIDs = append(documentIDs, document.ID)
+		}
+
+		for i, documentID := range documentIDs {
+			attachment := &models.CommentAttachment{
+				CommentID:  comment.ID,
+				DocumentID: documentID,
+				Position:   i,
+			}
+			if err := tx.Create(attachment).Error; err != nil {
+				return fmt.Errorf("failed to attach document: %w", err)
+			}
+		}
+
+		return tx.Create(models.CreateCommentAddedActivity(authorID, lead.ID, comment.ID)).Error
+	})
+	if err != nil {
+		s.removeFiles(stored)
+		return nil, err
+	}
+
+	return comment, nil
+}
+
+// List returns the comments on a lead the viewer may see, oldest first, with
+// attachment links signed for the viewer
+func (s *Service) List(leadID uuid.UUID, viewer scopes.Viewer) ([]Comment, error) {
+	var comments []models.Comment
+	err := s.withRelations(s.db.Scopes(scopes.VisibleComments(viewer))).
+		Where("comments.lead_id = ?", leadID).
+		Order("created_at ASC").
+		Find(&comments).Error
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]Comment, 0, len(comments))
+	for _, comment := range comments {
+		result = append(result, s.present(comment, viewer))
+	}
+	return result, nil
+}
+
+// Get returns a single comment with attachment links signed for the viewer
+func (s *Service) Get(commentID uuid.UUID, viewer scopes.Viewer) (*Comment, error) {
+	var comment models.Comment
+	if err := s.withRelations(s.db).First(&comment, "id = ?", commentID).Error; err != nil {
+		return nil, err
+	}
+
+	result := s.present(comment, viewer)
+	return &result, nil
+}
+
+// withRelations preloads what a comment is presented with
+func (s *Service) withRelations(db *gorm.DB) *gorm.DB {
+	return db.Preload("User").
+		Preload("Attachments", func(db *gorm.DB) *gorm.DB { return db.Order("position ASC") }).
+		Preload("Attachments.Document").
+		Preload("Reactions")
+}
+
+// present adds signed attachment links and reaction counts for the viewer.
+// Attached documents the viewer may not see are left out.
+func (s *Service) present(comment models.Comment, viewer scopes.Viewer) Comment {
+	attachments := make([]Attachment, 0, len(comment.Attachments))
+	for _, attachment := range comment.Attachments {
+		// Attachments of deleted documents are not preloaded
+		if attachment.Document.ID == uuid.Nil {
+			continue
+		}
+		document := attachment.Document
+		if document.UserID != viewer.ID && !document.VisibleTo(viewer.Role) {
+			continue
+		}
+		attachments = append(attachments, Attachment{
+			DocumentID:   document.ID,
+			OriginalName: document.OriginalName,
+			ContentType:  document.ContentType,
+			FileSize:     document.FileSize,
+			DocumentType: document.DocumentType,
+			SignedLinks:  s.documents.Links(&document, viewer.ID),
+		})
+	}
+	reactions := summarize(comment.Reactions, viewer.ID)
+	comment.Attachments = nil
+	comment.Reactions = nil
+	return Comment{Comment: comment, Attachments: attachments, Reactions: reactions}
+}
+
+func (s *Service) validate(upload Upload) error {
+	if s.maxSize > 0 && upload.Size > s.maxSize {
+		return ErrFileTooLarge
+	}
+	if len(s.allowedExtensions) == 0 {
+		return nil
+	}
+	ext := strings.ToLower(filepath.Ext(upload.FileName))
+	for _, allowed := range s.allowedExtensions {
+		if ext == strings.ToLower(strings.TrimSpace(allowed)) {
+			return nil
+		}
+	}
+	return ErrFileTypeNotAllowed
+}
+
+// store writes an upload to disk and returns the document for it. The
+// document is returned with its path even if writing failed half way so the
+// caller can clean up.
+func (s *Service) store(leadID, authorID uuid.UUID, upload Upload) (*models.Document, error) {
+	originalName := filepath.Base(upload.FileName)
+	if originalName == "." || originalName == string(filepath.Separator) {
+		originalName = "anhang"
+	}
+	ext := strings.ToLower(filepath.Ext(originalName))
+	fileName := uuid.New().String() + ext
+	path := filepath.Join(s.uploadPath, fileName)
+
+	if err := os.MkdirAll(s.uploadPath, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create upload directory: %w", err)
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to store %s: %w", originalName, err)
+	}
+	defer file.Close()
+
+	document := &models.Document{
+		LeadID:        leadID,
+		UserID:        authorID,
+		FileName:      fileName,
+		OriginalName:  originalName,
+		FilePath:      path,
+		ContentType:   upload.ContentType,
+		FileExtension: ext,
+		DocumentType:  models.DocumentTypeOther,
+		Description:   "Anhang zu einem Kommentar",
+		OCRStatus:     models.OCRStatusPending,
+	}
+	document.FileSize, err = io.Copy(file, upload.Content)
+	if err != nil {
+		return document, fmt.Errorf("failed to store %s: %w", originalName, err)
+	}
+	if s.maxSize > 0 && document.FileSize > s.maxSize {
+		return document, ErrFileTooLarge
+	}
+	return document, nil
+}
+
+// removeFiles deletes stored uploads after a failed comment
+func (s *Service) removeFiles(paths []string) {
+	for _, path := range paths {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			s.logger.Warn("Failed to remove comment upload", zap.String("path", path), zap.Error(err))
+		}
+	}
+}
+
+func unique(ids []uuid.UUID) []uuid.UUID {
+	seen := make(map[uuid.UUID]bool, len(ids))
+	result := make([]uuid.UUID, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			result = append(result, id)
+		}
+	}
+	return result
+}
@@ -0,0 +1,329 @@
+package comments
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"elterngeld-portal/config"
+	"elterngeld-portal/internal/documents"
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/internal/quota"
+	"elterngeld-portal/internal/scopes"
+	"elterngeld-portal/tests/testutils"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+func TestCreateWithAttachments(t *testing.T) {
+	db, service, uploadPath := setupTestService(t)
+	customer := testutils.CreateTestUser(t, db, models.RoleUser)
+	berater := testutils.CreateTestUser(t, db, models.RoleBerater)
+	lead := createLead(t, db, customer.ID)
+	bescheid := createDocument(t, db, lead.ID, customer.ID, "bescheid.pdf", models.DocumentTypeBescheid)
+
+	comment, err := service.Create(lead, berater.ID, Input{
+		Content:     "  Siehe Bescheid, Seite 2  ",
+		IsInternal:  true,
+		DocumentIDs: []uuid.UUID{bescheid.ID, bescheid.ID},
+		Uploads: []Upload{{
+			FileName:    "../screenshot.png",
+			ContentType: "image/png",
+			Size:        4,
+			Content:     strings.NewReader("\x89PNG"),
+		}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Siehe Bescheid, Seite 2", comment.Content)
+	assert.True(t, comment.IsInternal)
+
+	var attachments []models.CommentAttachment
+	require.NoError(t, db.Preload("Document").Where("comment_id = ?", comment.ID).Order("position").Find(&attachments).Error)
+	require.Len(t, attachments, 2)
+	assert.Equal(t, bescheid.ID, attachments[0].DocumentID)
+
+	upload := attachments[1].Document
+	assert.Equal(t, lead.ID, upload.LeadID)
+	assert.Equal(t, berater.ID, upload.UserID)
+	assert.Equal(t, "screenshot.png", upload.OriginalName)
+	assert.Equal(t, models.DocumentVisibilityBerater, upload.Visibility, "uploads to internal comments stay internal")
+	assert.Equal(t, int64(4), upload.FileSize)
+	assert.Equal(t, filepath.Dir(upload.FilePath), uploadPath)
+	data, err := os.ReadFile(upload.FilePath)
+	require.NoError(t, err)
+	assert.Equal(t, "\x89PNG", string(data))
+
+	var count int64
+	db.Model(&models.Activity{}).Where("lead_id = ? AND type = ?", lead.ID, models.ActivityTypeCommentAdded).Count(&count)
+	assert.Equal(t, int64(1), count)
+	db.Model(&models.Activity{}).Where("lead_id = ? AND type = ?", lead.ID, models.ActivityTypeDocumentUploaded).Count(&count)
+	assert.Equal(t, int64(1), count)
+}
+
+func TestCreate_Validation(t *testing.T) {
+	db, service, uploadPath := setupTestService(t)
+	customer := testutils.CreateTestUser(t, db, models.RoleUser)
+	berater := testutils.CreateTestUser(t, db, models.RoleBerater)
+	lead, other := createLead(t, db, customer.ID), createLead(t, db, customer.ID)
+	foreign := createDocument(t, db, other.ID, customer.ID, "fremd.pdf", models.DocumentTypeOther)
+
+	_, err := service.Create(lead, berater.ID, Input{Content: "   "})
+	assert.ErrorIs(t, err, ErrEmptyComment)
+
+	_, err = service.Create(lead, berater.ID, Input{Content: "Notiz", DocumentIDs: []uuid.UUID{foreign.ID}})
+	assert.ErrorIs(t, err, ErrDocumentNotFound)
+
+	_, err = service.Create(lead, berater.ID, Input{Content: "Notiz", Uploads: []Upload{{FileName: "tool.exe", Content: strings.NewReader("MZ")}}})
+	assert.ErrorIs(t, err, ErrFileTypeNotAllowed)
+
+	_, err = service.Create(lead, berater.ID, Input{Content: "Notiz", Uploads: []Upload{{FileName: "scan.pdf", Size: 2048, Content: strings.NewReader("")}}})
+	assert.ErrorIs(t, err, ErrFileTooLarge)
+
+	ids := make([]uuid.UUID, MaxAttachments+1)
+	for i := range ids {
+		ids[i] = uuid.New()
+	}
+	_, err = service.Create(lead, berater.ID, Input{Content: "Notiz", DocumentIDs: ids})
+	assert.ErrorIs(t, err, ErrTooManyAttachments)
+
+	// A failed comment leaves neither rows nor uploaded files behind
+	_, err = service.Create(lead, berater.ID, Input{
+		Content:     "Notiz",
+		DocumentIDs: []uuid.UUID{foreign.ID},
+		Uploads:     []Upload{{FileName: "scan.pdf", Size: 3, Content: strings.NewReader("pdf")}},
+	})
+	assert.ErrorIs(t, err, ErrDocumentNotFound)
+
+	var count int64
+	db.Model(&models.Comment{}).Count(&count)
+	assert.Zero(t, count)
+	entries, err := os.ReadDir(uploadPath)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestCreate_StorageQuota(t *testing.T) {
+	db, service, uploadPath := setupTestService(t)
+	customer := testutils.CreateTestUser(t, db, models.RoleUser)
+	lead := createLead(t, db, customer.ID)
+	scan := createDocument(t, db, lead.ID, customer.ID, "scan.pdf", models.DocumentTypeOther)
+	require.NoError(t, db.Model(scan).Update("file_size", 4000).Error)
+
+	_, err := service.Create(lead, customer.ID, Input{
+		Content: "Noch ein Nachweis",
+		Uploads: []Upload{{FileName: "nachweis.pdf", Size: 100, Content: strings.NewReader(strings.Repeat("x", 100))}},
+	})
+	assert.ErrorIs(t, err, quota.ErrQuotaExceeded)
+	entries, err := os.ReadDir(uploadPath)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+
+	// Comments without uploads are not limited
+	_, err = service.Create(lead, customer.ID, Input{Content: "Nachweis folgt", DocumentIDs: []uuid.UUID{scan.ID}})
+	assert.NoError(t, err)
+}
+
+func TestList(t *testing.T) {
+	db, service, _ := setupTestService(t)
+	customer := testutils.CreateTestUser(t, db, models.RoleUser)
+	berater := testutils.CreateTestUser(t, db, models.RoleBerater)
+	lead := createLead(t, db, customer.ID)
+	bescheid := createDocument(t, db, lead.ID, customer.ID, "bescheid.pdf", models.DocumentTypeBescheid)
+	payslip := createDocument(t, db, lead.ID, customer.ID, "lohn.pdf", models.DocumentTypePayslip)
+
+	_, err := service.Create(lead, berater.ID, Input{Content: "Bitte prüfen", DocumentIDs: []uuid.UUID{bescheid.ID, payslip.ID}})
+	require.NoError(t, err)
+	_, err = service.Create(lead, berater.ID, Input{Content: "Intern", IsInternal: true, DocumentIDs: []uuid.UUID{payslip.ID}})
+	require.NoError(t, err)
+
+	comments, err := service.List(lead.ID, scopes.Viewer{ID: berater.ID, Role: berater.Role})
+	require.NoError(t, err)
+	require.Len(t, comments, 2)
+	assert.Equal(t, "Bitte prüfen", comments[0].Content)
+	assert.Equal(t, berater.ID, comments[0].User.ID)
+	require.Len(t, comments[0].Attachments, 2)
+
+	attachment := comments[0].Attachments[0]
+	assert.Equal(t, bescheid.ID, attachment.DocumentID)
+	assert.Equal(t, "bescheid.pdf", attachment.OriginalName)
+	assert.True(t, attachment.Watermarked)
+	assert.Contains(t, attachment.DownloadURL, "https://portal.example.com/files/documents/"+bescheid.ID.String()+"/download?")
+	assert.Contains(t, attachment.DownloadURL, "viewer="+berater.ID.String())
+	assert.NotEmpty(t, attachment.PreviewURL)
+
+	// Customers do not see internal comments, their links are signed for them
+	comments, err = service.List(lead.ID, scopes.Viewer{ID: customer.ID, Role: customer.Role})
+	require.NoError(t, err)
+	require.Len(t, comments, 1)
+	assert.Contains(t, comments[0].Attachments[0].DownloadURL, "viewer="+customer.ID.String())
+
+	// Attachments of deleted documents are left out
+	require.NoError(t, db.Delete(payslip).Error)
+	comment, err := service.Get(comments[0].ID, scopes.Viewer{ID: customer.ID, Role: customer.Role})
+	require.NoError(t, err)
+	require.Len(t, comment.Attachments, 1)
+	assert.Equal(t, bescheid.ID, comment.Attachments[0].DocumentID)
+
+	// Internal documents attached to a public comment are left out for customers
+	calculation := createDocument(t, db, lead.ID, berater.ID, "berechnung.pdf", models.DocumentTypeOther)
+	require.NoError(t, db.Model(calculation).Update("visibility", models.DocumentVisibilityBerater).Error)
+	shared, err := service.Create(lead, berater.ID, Input{Content: "Berechnung anbei", DocumentIDs: []uuid.UUID{calculation.ID}})
+	require.NoError(t, err)
+	comment, err = service.Get(shared.ID, scopes.Viewer{ID: customer.ID, Role: customer.Role})
+	require.NoError(t, err)
+	assert.Empty(t, comment.Attachments)
+}
+
+func TestUpdateAndDelete(t *testing.T) {
+	db, service, _ := setupTestService(t)
+	customer := testutils.CreateTestUser(t, db, models.RoleUser)
+	berater := testutils.CreateTestUser(t, db, models.RoleBerater)
+	admin := testutils.CreateTestUser(t, db, models.RoleAdmin)
+	lead := createLead(t, db, customer.ID)
+	author := scopes.Viewer{ID: berater.ID, Role: berater.Role}
+
+	comment, err := service.Create(lead, berater.ID, Input{Content: "Erster Entwurf"})
+	require.NoError(t, err)
+
+	edited := time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC)
+	service.now = func() time.Time { return edited }
+	updated, err := service.Update(comment.ID, author, " Zweiter Entwurf ")
+	require.NoError(t, err)
+	assert.Equal(t, "Zweiter Entwurf", updated.Content)
+	require.NotNil(t, updated.EditedAt)
+
+	// Saving the same content again keeps the history as it is
+	_, err = service.Update(comment.ID, author, "Zweiter Entwurf")
+	require.NoError(t, err)
+	_, err = service.Update(comment.ID, scopes.Viewer{ID: admin.ID, Role: admin.Role}, "Korrigiert")
+	require.NoError(t, err)
+
+	revisions, err := service.History(comment.ID, author)
+	require.NoError(t, err)
+	require.Len(t, revisions, 2)
+	assert.Equal(t, "Erster Entwurf", revisions[0].Content)
+	assert.Equal(t, berater.ID, revisions[0].EditedByID)
+	assert.Equal(t, "Zweiter Entwurf", revisions[1].Content)
+	assert.Equal(t, admin.ID, revisions[1].EditedBy.ID)
+
+	stored, err := service.Get(comment.ID, scopes.Viewer{ID: berater.ID, Role: berater.Role})
+	require.NoError(t, err)
+	assert.Equal(t, "Korrigiert", stored.Content)
+	assert.True(t, stored.EditedAt.Equal(edited))
+
+	// Only the author and admins change comments
+	other := testutils.CreateTestUser(t, db, models.RoleJuniorBerater)
+	_, err = service.Update(comment.ID, scopes.Viewer{ID: other.ID, Role: other.Role}, "Übernommen")
+	assert.ErrorIs(t, err, ErrNotAuthor)
+	assert.ErrorIs(t, service.Delete(comment.ID, scopes.Viewer{ID: customer.ID, Role: customer.Role}), ErrNotAuthor)
+	_, err = service.Update(comment.ID, author, "  ")
+	assert.ErrorIs(t, err, ErrEmptyComment)
+	_, err = service.Update(uuid.New(), author, "Notiz")
+	assert.ErrorIs(t, err, ErrCommentNotFound)
+
+	require.NoError(t, service.Delete(comment.ID, author))
+	comments, err := service.List(lead.ID, author)
+	require.NoError(t, err)
+	assert.Empty(t, comments)
+	assert.ErrorIs(t, service.Delete(comment.ID, author), ErrCommentNotFound)
+}
+
+func TestReactions(t *testing.T) {
+	db, service, _ := setupTestService(t)
+	customer := testutils.CreateTestUser(t, db, models.RoleUser)
+	berater := testutils.CreateTestUser(t, db, models.RoleBerater)
+	lead := createLead(t, db, customer.ID)
+	customerViewer := scopes.Viewer{ID: customer.ID, Role: customer.Role}
+	beraterViewer := scopes.Viewer{ID: berater.ID, Role: berater.Role}
+
+	comment, err := service.Create(lead, berater.ID, Input{Content: "Antrag ist eingereicht"})
+	require.NoError(t, err)
+	internal, err := service.Create(lead, berater.ID, Input{Content: "Intern", IsInternal: true})
+	require.NoError(t, err)
+
+	require.NoError(t, service.AddReaction(comment.ID, customerViewer, models.ReactionHeart))
+	require.NoError(t, service.AddReaction(comment.ID, customerViewer, models.ReactionHeart))
+	require.NoError(t, service.AddReaction(comment.ID, beraterViewer, models.ReactionHeart))
+	require.NoError(t, service.AddReaction(comment.ID, beraterViewer, models.ReactionThumbsUp))
+	assert.ErrorIs(t, service.AddReaction(comment.ID, customerViewer, "rocket"), ErrUnknownReaction)
+	assert.ErrorIs(t, service.AddReaction(internal.ID, customerViewer, models.ReactionHeart), ErrCommentNotFound)
+
+	comments, err := service.List(lead.ID, customerViewer)
+	require.NoError(t, err)
+	require.Len(t, comments, 1)
+	assert.Equal(t, []ReactionSummary{
+		{Reaction: models.ReactionThumbsUp, Emoji: "👍", Count: 1},
+		{Reaction: models.ReactionHeart, Emoji: "❤️", Count: 2, Reacted: true},
+	}, comments[0].Reactions)
+
+	require.NoError(t, service.RemoveReaction(comment.ID, customerViewer, models.ReactionHeart))
+	stored, err := service.Get(comment.ID, scopes.Viewer{ID: customer.ID, Role: customer.Role})
+	require.NoError(t, err)
+	assert.Equal(t, 1, stored.Reactions[1].Count)
+	assert.False(t, stored.Reactions[1].Reacted)
+}
+
+func setupTestService(t *testing.T) (*gorm.DB, *Service, string) {
+	t.Helper()
+	db := testutils.NewSQLiteDB(t,
+		&models.User{},
+		&models.Lead{},
+		&models.FamilyMember{},
+		&models.Comment{},
+		&models.CommentAttachment{},
+		&models.CommentRevision{},
+		&models.CommentReaction{},
+		&models.Document{},
+		&models.Activity{},
+	)
+
+	uploadPath := t.TempDir()
+	cfg := &config.Config{
+		App: config.AppConfig{BaseURL: "https://portal.example.com/"},
+		JWT: config.JWTConfig{Secret: "test-secret"},
+		Upload: config.UploadConfig{
+			Path:              uploadPath,
+			MaxSize:           1024,
+			AllowedExtensions: []string{".pdf", ".png", ".jpg"},
+			UserQuota:         4096,
+		},
+	}
+	logger := zap.NewNop()
+	return db, NewService(db, logger, cfg, documents.NewService(db, logger, cfg), quota.NewService(db, logger, cfg)), uploadPath
+}
+
+func createLead(t *testing.T, db *gorm.DB, userID uuid.UUID) *models.Lead {
+	t.Helper()
+	lead := &models.Lead{
+		UserID:   userID,
+		Title:    "Elterngeld",
+		Status:   models.LeadStatusNew,
+		Priority: models.PriorityMedium,
+		Source:   models.LeadSourceWebsite,
+	}
+	require.NoError(t, db.Create(lead).Error)
+	return lead
+}
+
+func createDocument(t *testing.T, db *gorm.DB, leadID, userID uuid.UUID, name string, documentType models.DocumentType) *models.Document {
+	t.Helper()
+	document := &models.Document{
+		LeadID:        leadID,
+		UserID:        userID,
+		FileName:      uuid.New().String() + ".pdf",
+		OriginalName:  name,
+		FilePath:      filepath.Join(t.TempDir(), name),
+		FileSize:      1,
+		ContentType:   "application/pdf",
+		FileExtension: ".pdf",
+		DocumentType:  documentType,
+	}
+	require.NoError(t, db.Create(document).Error)
+	return document
+}
@@ -0,0 +1,314 @@
+// Package content manages the marketing content of the website: FAQ entries,
+// info pages about Elterngeld and guides per Bundesland. Admins edit entries
+// as drafts and publish them; the public endpoints only serve published ones.
+package content
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"elterngeld-portal/internal/models"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	DefaultLimit = 20
+	MaxLimit     = 100
+)
+
+var (
+	// ErrEntryNotFound is returned for unknown entries and, on public reads, drafts
+	ErrEntryNotFound = errors.New("content entry not found")
+	// ErrInvalidKind is returned for kinds other than faq, page and guide
+	ErrInvalidKind = errors.New("invalid content kind")
+	// ErrInvalidSlug is returned for slugs other than lowercase words joined by dashes
+	ErrInvalidSlug = errors.New("invalid slug")
+	// ErrDuplicateSlug is returned when another entry of the kind uses the slug
+	ErrDuplicateSlug = errors.New("slug already exists")
+	// ErrInvalidBundesland is returned when a guide has no valid Bundesland or another kind has one
+	ErrInvalidBundesland = errors.New("invalid Bundesland")
+)
+
+var slugPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// umlauts are transliterated in generated slugs, like in German URLs
+var umlauts = strings.NewReplacer("ä", "ae", "ö", "oe", "ü", "ue", "ß", "ss")
+
+// Service manages content entries
+type Service struct {
+	db     *gorm.DB
+	logger *zap.Logger
+	now    func() time.Time
+}
+
+func NewService(db *gorm.DB, logger *zap.Logger) *Service {
+	return &Service{
+		db:     db,
+		logger: logger,
+		now:    time.Now,
+	}
+}
+
+// EntryInput holds the editable fields of an entry. Without a slug one is
+// generated from the title.
+type EntryInput struct {
+	Kind       models.ContentKind `json:"kind" binding:"required"`
+	Slug       string             `json:"slug" binding:"max=150"`
+	Title      string             `json:"title" binding:"required,max=200"`
+	Summary    string             `json:"summary"`
+	Body       string             `json:"body" binding:"required"`
+	Category   string             `json:"category" binding:"max=100"`
+	Bundesland models.Bundesland  `json:"bundesland"`
+	Position   int                `json:"position"`
+}
+
+// Filter narrows down entry lists. Empty fields do not filter.
+type Filter struct {
+	Kind       models.ContentKind
+	Status     models.ContentStatus
+	Category   string
+	Bundesland models.Bundesland
+}
+
+// List returns a page of entries for the admin, latest changes first
+func (s *Service) List(filter Filter, page, limit int) ([]models.ContentEntry, int64, error) {
+	query := s.filtered(filter)
+
+	var total int64
+	if err := query.Model(&models.ContentEntry{}).Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count content entries: %w", err)
+	}
+
+	entries := []models.ContentEntry{}
+	if err := query.Order("updated_at DESC").Offset((page - 1) * limit).Limit(limit).
+		Find(&entries).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to load content entries: %w", err)
+	}
+	return entries, total, nil
+}
+
+// Get returns an entry, draft or published
+func (s *Service) Get(id uuid.UUID) (*models.ContentEntry, error) {
+	var entry models.ContentEntry
+	if err := s.db.First(&entry, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrEntryNotFound
+		}
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// Published returns the published entries of a kind in display order
+func (s *Service) Published(filter Filter) ([]models.ContentEntry, error) {
+	if !filter.Kind.IsValid() {
+		return nil, ErrInvalidKind
+	}
+	filter.Status = models.ContentStatusPublished
+
+	entries := []models.ContentEntry{}
+	if err := s.filtered(filter).Order("category ASC, position ASC, title ASC").
+		Find(&entries).Error; err != nil {
+		return nil, fmt.Errorf("failed to load content entries: %w", err)
+	}
+	return entries, nil
+}
+
+// PublishedBySlug returns a published entry. Drafts are not found.
+func (s *Service) PublishedBySlug(kind models.ContentKind, slug string) (*models.ContentEntry, error) {
+	if !kind.IsValid() {
+		return nil, ErrInvalidKind
+	}
+
+	var entry models.ContentEntry
+	if err := s.db.Where("kind = ? AND slug = ? AND status = ?", kind, slug, models.ContentStatusPublished).
+		First(&entry).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrEntryNotFound
+		}
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// Create adds a draft entry
+func (s *Service) Create(input EntryInput, createdBy uuid.UUID) (*models.ContentEntry, error) {
+	entry := &models.ContentEntry{
+		Status:    models.ContentStatusDraft,
+		CreatedBy: createdBy,
+	}
+	if err := s.apply(entry, input, createdBy); err != nil {
+		return nil, err
+	}
+
+	if err := s.db.Create(entry).Error; err != nil {
+		return nil, fmt.Errorf("failed to create content entry: %w", err)
+	}
+	return entry, nil
+}
+
+// Update replaces the fields of an entry. Changes to a published entry are
+// public right away.
+func (s *Service) Update(id uuid.UUID, input EntryInput, updatedBy uuid.UUID) (*models.ContentEntry, error) {
+	entry, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.apply(entry, input, updatedBy); err != nil {
+		return nil, err
+	}
+
+	if err := s.db.Select("kind", "slug", "title", "summary", "body", "category", "bundesland", "position", "updated_by").
+		Updates(entry).Error; err != nil {
+		return nil, fmt.Errorf("failed to update content entry: %w", err)
+	}
+	return entry, nil
+}
+
+// Publish makes an entry public. Publishing a published entry keeps its
+// original publication date.
+func (s *Service) Publish(id uuid.UUID, publishedBy uuid.UUID) (*models.ContentEntry, error) {
+	entry, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if entry.Status == models.ContentStatusPublished {
+		return entry, nil
+	}
+
+	now := s.now()
+	entry.Status = models.ContentStatusPublished
+	entry.PublishedAt = &now
+	entry.UpdatedBy = publishedBy
+	if err := s.db.Model(entry).Select("status", "published_at", "updated_by").Updates(entry).Error; err != nil {
+		return nil, fmt.Errorf("failed to publish content entry: %w", err)
+	}
+	return entry, nil
+}
+
+// Unpublish turns an entry back into a draft, hiding it from the website
+func (s *Service) Unpublish(id uuid.UUID, updatedBy uuid.UUID) (*models.ContentEntry, error) {
+	entry, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if entry.Status == models.ContentStatusDraft {
+		return entry, nil
+	}
+
+	entry.Status = models.ContentStatusDraft
+	entry.PublishedAt = nil
+	entry.UpdatedBy = updatedBy
+	if err := s.db.Model(entry).Select("status", "published_at", "updated_by").Updates(entry).Error; err != nil {
+		return nil, fmt.Errorf("failed to unpublish content entry: %w", err)
+	}
+	return entry, nil
+}
+
+// Delete removes an entry
+func (s *Service) Delete(id uuid.UUID) error {
+	result := s.db.Delete(&models.ContentEntry{}, "id = ?", id)
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete content entry: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrEntryNotFound
+	}
+	return nil
+}
+
+// apply validates the input and copies it to the entry
+func (s *Service) apply(entry *models.ContentEntry, input EntryInput, updatedBy uuid.UUID) error {
+	if !input.Kind.IsValid() {
+		return ErrInvalidKind
+	}
+	if input.Kind == models.ContentKindGuide {
+		if !input.Bundesland.IsValid() {
+			return ErrInvalidBundesland
+		}
+	} else if input.Bundesland != "" {
+		return ErrInvalidBundesland
+	}
+
+	slug := strings.TrimSpace(input.Slug)
+	if slug == "" {
+		slug = Slugify(input.Title)
+	}
+	if !IsValidSlug(slug) {
+		return ErrInvalidSlug
+	}
+
+	var count int64
+	if err := s.db.Model(&models.ContentEntry{}).Where("kind = ? AND slug = ? AND id <> ?", input.Kind, slug, entry.ID).
+		Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrDuplicateSlug
+	}
+
+	entry.Kind = input.Kind
+	entry.Slug = slug
+	entry.Title = strings.TrimSpace(input.Title)
+	entry.Summary = strings.TrimSpace(input.Summary)
+	entry.Body = input.Body
+	entry.Category = strings.TrimSpace(input.Category)
+	entry.Bundesland = input.Bundesland
+	entry.Position = input.Position
+	entry.UpdatedBy = updatedBy
+	return nil
+}
+
+func (s *Service) filtered(filter Filter) *gorm.DB {
+	query := s.db
+	if filter.Kind != "" {
+		query = query.Where("kind = ?", filter.Kind)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.Category != "" {
+		query = query.Where("category = ?", filter.Category)
+	}
+	if filter.Bundesland != "" {
+		query = query.Where("bundesland = ?", filter.Bundesland)
+	}
+	return query
+}
+
+// IsValidSlug checks if the slug consists of lowercase words and digits joined by dashes
+func IsValidSlug(slug string) bool {
+	return slugPattern.MatchString(slug)
+}
+
+// Slugify turns a title into a slug, e.g. "Elterngeld für Selbstständige"
+// into "elterngeld-fuer-selbststaendige"
+func Slugify(title string) string {
+	title = umlauts.Replace(strings.ToLower(title))
+
+	var slug strings.Builder
+	dash := false
+	for _, r := range title {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			if dash && slug.Len() > 0 {
+				slug.WriteByte('-')
+			}
+			slug.WriteRune(r)
+			dash = false
+			continue
+		}
+		dash = true
+	}
+
+	result := slug.String()
+	if len(result) > 150 {
+		result = strings.TrimRight(result[:150], "-")
+	}
+	return result
+}
@@ -0,0 +1,131 @@
+package content
+
+import (
+	"testing"
+
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/tests/testutils"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestPublishWorkflow(t *testing.T) {
+	service := setupTestService(t)
+	admin := uuid.New()
+
+	entry, err := service.Create(EntryInput{
+		Kind:     models.ContentKindFAQ,
+		Title:    "Wie lange bekomme ich Elterngeld?",
+		Body:     "Basiselterngeld gibt es bis zu 14 Monate.",
+		Category: "Grundlagen",
+	}, admin)
+	require.NoError(t, err)
+	assert.Equal(t, models.ContentStatusDraft, entry.Status)
+	assert.Equal(t, "wie-lange-bekomme-ich-elterngeld", entry.Slug)
+
+	// Drafts are not public
+	_, err = service.PublishedBySlug(models.ContentKindFAQ, entry.Slug)
+	assert.ErrorIs(t, err, ErrEntryNotFound)
+	faqs, err := service.Published(Filter{Kind: models.ContentKindFAQ})
+	require.NoError(t, err)
+	assert.Empty(t, faqs)
+
+	published, err := service.Publish(entry.ID, admin)
+	require.NoError(t, err)
+	require.NotNil(t, published.PublishedAt)
+
+	found, err := service.PublishedBySlug(models.ContentKindFAQ, entry.Slug)
+	require.NoError(t, err)
+	assert.Equal(t, entry.ID, found.ID)
+
+	// Edits of published entries are public right away
+	_, err = service.Update(entry.ID, EntryInput{
+		Kind:  models.ContentKindFAQ,
+		Slug:  entry.Slug,
+		Title: "Wie lange bekomme ich Elterngeld?",
+		Body:  "Basiselterngeld gibt es bis zu 14 Monate, ElterngeldPlus doppelt so lange.",
+	}, admin)
+	require.NoError(t, err)
+	found, err = service.PublishedBySlug(models.ContentKindFAQ, entry.Slug)
+	require.NoError(t, err)
+	assert.Contains(t, found.Body, "ElterngeldPlus")
+
+	_, err = service.Unpublish(entry.ID, admin)
+	require.NoError(t, err)
+	_, err = service.PublishedBySlug(models.ContentKindFAQ, entry.Slug)
+	assert.ErrorIs(t, err, ErrEntryNotFound)
+
+	require.NoError(t, service.Delete(entry.ID))
+	assert.ErrorIs(t, service.Delete(entry.ID), ErrEntryNotFound)
+}
+
+func TestCreate_Validation(t *testing.T) {
+	service := setupTestService(t)
+	admin := uuid.New()
+
+	_, err := service.Create(EntryInput{Kind: models.ContentKindPage, Title: "Elterngeld für Selbstständige", Body: "..."}, admin)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		input    EntryInput
+		expected error
+	}{
+		{"unknown kind", EntryInput{Kind: "blog", Title: "Neu", Body: "..."}, ErrInvalidKind},
+		{"duplicate slug", EntryInput{Kind: models.ContentKindPage, Slug: "elterngeld-fuer-selbststaendige", Title: "Kopie", Body: "..."}, ErrDuplicateSlug},
+		{"invalid slug", EntryInput{Kind: models.ContentKindPage, Slug: "Mit Leerzeichen", Title: "Seite", Body: "..."}, ErrInvalidSlug},
+		{"guide without Bundesland", EntryInput{Kind: models.ContentKindGuide, Title: "Leitfaden", Body: "..."}, ErrInvalidBundesland},
+		{"page with Bundesland", EntryInput{Kind: models.ContentKindPage, Title: "Bayern", Body: "...", Bundesland: models.BundeslandBayern}, ErrInvalidBundesland},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := service.Create(tt.input, admin)
+			assert.ErrorIs(t, err, tt.expected)
+		})
+	}
+
+	// The same slug can be used by another kind
+	_, err = service.Create(EntryInput{Kind: models.ContentKindFAQ, Title: "Elterngeld für Selbstständige", Body: "..."}, admin)
+	assert.NoError(t, err)
+}
+
+func TestPublished_GuidesByBundesland(t *testing.T) {
+	service := setupTestService(t)
+	admin := uuid.New()
+
+	for _, state := range []models.Bundesland{models.BundeslandBayern, models.BundeslandBerlin} {
+		guide, err := service.Create(EntryInput{
+			Kind:       models.ContentKindGuide,
+			Title:      "Elterngeld in " + state.GetDisplayName(),
+			Body:       "...",
+			Bundesland: state,
+		}, admin)
+		require.NoError(t, err)
+		_, err = service.Publish(guide.ID, admin)
+		require.NoError(t, err)
+	}
+
+	guides, err := service.Published(Filter{Kind: models.ContentKindGuide, Bundesland: models.BundeslandBerlin})
+	require.NoError(t, err)
+	require.Len(t, guides, 1)
+	assert.Equal(t, "elterngeld-in-berlin", guides[0].Slug)
+
+	_, err = service.Published(Filter{})
+	assert.ErrorIs(t, err, ErrInvalidKind)
+}
+
+func TestSlugify(t *testing.T) {
+	assert.Equal(t, "elterngeld-fuer-selbststaendige", Slugify("Elterngeld für Selbstständige"))
+	assert.Equal(t, "was-ist-elterngeldplus", Slugify("  Was ist ElterngeldPlus?! "))
+	assert.Equal(t, "mutterschutz-2025", Slugify("Mutterschutz & 2025"))
+}
+
+func setupTestService(t *testing.T) *Service {
+	t.Helper()
+	db := testutils.NewSQLiteDB(t, &models.ContentEntry{})
+
+	return NewService(db, zap.NewNop())
+}
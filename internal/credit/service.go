@@ -0,0 +1,475 @@
+package credit
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+	"time"
+
+	"elterngeld-portal/internal/models"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrInvalidAmount is returned for credit amounts that are not positive
+	ErrInvalidAmount = errors.New("invalid credit amount")
+	// ErrPaymentNotFound is returned when the refunded payment does not exist
+	ErrPaymentNotFound = errors.New("payment not found")
+	// ErrNotRefundable is returned when the payment is not paid or already fully refunded
+	ErrNotRefundable = errors.New("payment cannot be refunded")
+	// ErrRefundTooHigh is returned when a refund exceeds the amount left to refund
+	ErrRefundTooHigh = errors.New("refund exceeds the refundable amount")
+	// ErrVoucherNotFound is returned for unknown voucher codes
+	ErrVoucherNotFound = errors.New("voucher not found")
+	// ErrVoucherNotRedeemable is returned when a voucher has expired or is used up
+	ErrVoucherNotRedeemable = errors.New("voucher is no longer redeemable")
+	// ErrVoucherAlreadyRedeemed is returned when the customer already redeemed the voucher
+	ErrVoucherAlreadyRedeemed = errors.New("voucher already redeemed")
+	// ErrVoucherCodeTaken is returned when a voucher with the code already exists
+	ErrVoucherCodeTaken = errors.New("voucher code already exists")
+)
+
+// voucherAlphabet leaves out characters that are easily confused when typed
+const voucherAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// Statement is the credit balance of a customer with its ledger, latest entry first
+type Statement struct {
+	UserID   uuid.UUID            `json:"user_id"`
+	Balance  float64              `json:"balance"`
+	Currency string               `json:"currency"`
+	Entries  []models.CreditEntry `json:"entries"`
+}
+
+// VoucherInput describes a new voucher; the code is generated when empty
+type VoucherInput struct {
+	Code           string
+	Amount         float64
+	Description    string
+	MaxRedemptions int
+	ExpiresAt      *time.Time
+}
+
+// CheckoutResult is the split of a booking price into credit and the remainder to charge
+type CheckoutResult struct {
+	Applied   float64         `json:"credit_applied"`
+	Remaining float64         `json:"remaining"`
+	Payment   *models.Payment `json:"payment,omitempty"` // set when credit paid the whole price
+}
+
+// Service manages the credit ledger of customers: refunds to credit,
+// goodwill credits and vouchers, applied automatically at checkout
+type Service struct {
+	db     *gorm.DB
+	logger *zap.Logger
+	now    func() time.Time
+}
+
+func NewService(db *gorm.DB, logger *zap.Logger) *Service {
+	return &Service{
+		db:     db,
+		logger: logger,
+		now:    time.Now,
+	}
+}
+
+// Balance returns the credit balance of a customer
+func (s *Service) Balance(userID uuid.UUID) (float64, error) {
+	return balance(s.db, userID)
+}
+
+// Statement returns the balance and all ledger entries of a customer
+func (s *Service) Statement(userID uuid.UUID) (*Statement, error) {
+	statement := &Statement{UserID: userID, Currency: "EUR", Entries: []models.CreditEntry{}}
+	if err := s.db.Where("user_id = ?", userID).Order("created_at DESC").Find(&statement.Entries).Error; err != nil {
+		return nil, fmt.Errorf("failed to load credit entries: %w", err)
+	}
+	for _, entry := range statement.Entries {
+		statement.Balance += entry.Amount
+	}
+	statement.Balance = round(statement.Balance)
+	return statement, nil
+}
+
+// Grant adds goodwill credit to a customer's account
+func (s *Service) Grant(userID uuid.UUID, amount float64, description string, admin *models.User) (*models.CreditEntry, error) {
+	amount = round(amount)
+	if amount <= 0 || math.IsNaN(amount) || math.IsInf(amount, 0) {
+		return nil, ErrInvalidAmount
+	}
+
+	entry := models.CreditEntry{
+		UserID:      userID,
+		Type:        models.CreditEntryGoodwill,
+		Amount:      amount,
+		Description: strings.TrimSpace(description),
+		CreatedBy:   &admin.ID,
+	}
+	if err := s.db.Create(&entry).Error; err != nil {
+		return nil, fmt.Errorf("failed to grant credit: %w", err)
+	}
+
+	s.logger.Info("Goodwill credit granted",
+		zap.String("user_id", userID.String()),
+		zap.String("admin_id", admin.ID.String()),
+		zap.Float64("amount", amount))
+	return &entry, nil
+}
+
+// RefundToCredit refunds a paid payment as account credit instead of to the
+// card. Without an amount the rest of the price, including credit applied
+// at checkout, is refunded.
+func (s *Service) RefundToCredit(paymentID uuid.UUID, amount *float64, reason string, actor *models.User) (*models.Payment, *models.CreditEntry, error) {
+	var payment models.Payment
+	var entry models.CreditEntry
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&payment, "id = ?", paymentID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrPaymentNotFound
+			}
+			return err
+		}
+
+		total := round(payment.Amount + payment.CreditAmount)
+		refundable := round(total - payment.RefundAmount)
+		if !payment.IsPaid() || refundable <= 0 {
+			return ErrNotRefundable
+		}
+		refund := refundable
+		if amount != nil {
+			refund = round(*amount)
+			if refund <= 0 {
+				return ErrInvalidAmount
+			}
+			if refund > refundable {
+				return ErrRefundTooHigh
+			}
+		}
+
+		now := s.now()
+		payment.RefundAmount = round(payment.RefundAmount + refund)
+		payment.RefundReason = strings.TrimSpace(reason)
+		payment.RefundedAt = &now
+		if payment.RefundAmount >= total {
+			payment.Status = models.PaymentStatusRefunded
+		}
+		if err := tx.Save(&payment).Error; err != nil {
+			return err
+		}
+
+		entry = models.CreditEntry{
+			UserID:      payment.UserID,
+			Type:        models.CreditEntryRefund,
+			Amount:      refund,
+			Currency:    payment.Currency,
+			Description: payment.RefundReason,
+			PaymentID:   &payment.ID,
+			CreatedBy:   &actor.ID,
+		}
+		return tx.Create(&entry).Error
+	})
+	if err != nil {
+		if errors.Is(err, ErrPaymentNotFound) || errors.Is(err, ErrNotRefundable) ||
+			errors.Is(err, ErrInvalidAmount) || errors.Is(err, ErrRefundTooHigh) {
+			return nil, nil, err
+		}
+		return nil, nil, fmt.Errorf("failed to refund payment to credit: %w", err)
+	}
+
+	s.logger.Info("Payment refunded to credit",
+		zap.String("payment_id", payment.ID.String()),
+		zap.String("actor_id", actor.ID.String()),
+		zap.Float64("amount", entry.Amount))
+	return &payment, &entry, nil
+}
+
+// CreateVoucher creates a voucher code customers can redeem for credit
+func (s *Service) CreateVoucher(input VoucherInput, admin *models.User) (*models.Voucher, error) {
+	amount := round(input.Amount)
+	if amount <= 0 || math.IsNaN(amount) || math.IsInf(amount, 0) || input.MaxRedemptions < 0 {
+		return nil, ErrInvalidAmount
+	}
+
+	code := normalizeCode(input.Code)
+	if code == "" {
+		generated, err := generateCode()
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate voucher code: %w", err)
+		}
+		code = generated
+	}
+	var existing int64
+	if err := s.db.Unscoped().Model(&models.Voucher{}).Where("code = ?", code).Count(&existing).Error; err != nil {
+		return nil, fmt.Errorf("failed to check voucher code: %w", err)
+	}
+	if existing > 0 {
+		return nil, ErrVoucherCodeTaken
+	}
+
+	voucher := models.Voucher{
+		Code:           code,
+		Amount:         amount,
+		Description:    strings.TrimSpace(input.Description),
+		MaxRedemptions: input.MaxRedemptions,
+		ExpiresAt:      input.ExpiresAt,
+		CreatedBy:      admin.ID,
+	}
+	if err := s.db.Create(&voucher).Error; err != nil {
+		return nil, fmt.Errorf("failed to create voucher: %w", err)
+	}
+	return &voucher, nil
+}
+
+// ListVouchers returns all vouchers, newest first
+func (s *Service) ListVouchers() ([]models.Voucher, error) {
+	var vouchers []models.Voucher
+	if err := s.db.Order("created_at DESC").Find(&vouchers).Error; err != nil {
+		return nil, fmt.Errorf("failed to load vouchers: %w", err)
+	}
+	return vouchers, nil
+}
+
+// RedeemVoucher adds the value of a voucher to the customer's credit. Each
+// customer can redeem a voucher once.
+func (s *Service) RedeemVoucher(userID uuid.UUID, code string) (*models.CreditEntry, error) {
+	var entry models.CreditEntry
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var voucher models.Voucher
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("code = ?", normalizeCode(code)).First(&voucher).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrVoucherNotFound
+			}
+			return err
+		}
+		if !voucher.IsRedeemable(s.now()) {
+			return ErrVoucherNotRedeemable
+		}
+
+		var redeemed int64
+		if err := tx.Model(&models.CreditEntry{}).
+			Where("user_id = ? AND voucher_id = ?", userID, voucher.ID).Count(&redeemed).Error; err != nil {
+			return err
+		}
+		if redeemed > 0 {
+			return ErrVoucherAlreadyRedeemed
+		}
+
+		entry = models.CreditEntry{
+			UserID:      userID,
+			Type:        models.CreditEntryVoucher,
+			Amount:      voucher.Amount,
+			Currency:    voucher.Currency,
+			Description: voucher.Description,
+			VoucherID:   &voucher.ID,
+		}
+		if err := tx.Create(&entry).Error; err != nil {
+			return err
+		}
+		return tx.Model(&voucher).Update("redemption_count", gorm.Expr("redemption_count + 1")).Error
+	})
+	if err != nil {
+		if errors.Is(err, ErrVoucherNotFound) || errors.Is(err, ErrVoucherNotRedeemable) || errors.Is(err, ErrVoucherAlreadyRedeemed) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to redeem voucher: %w", err)
+	}
+	return &entry, nil
+}
+
+// Checkout applies the customer's credit to the price of a booking before it
+// is charged. Credit applied by an earlier checkout of the same booking is
+// reused, so retrying a checkout does not use credit twice. When credit
+// covers the whole price the booking is paid right away with a payment of
+// method credit; otherwise paymentID is recorded for the Stripe payment of
+// the remainder. paid is called in the transaction of a credit payment to
+// confirm the booking and may be nil; its error rolls the checkout back.
+func (s *Service) Checkout(booking *models.Booking, paymentID uuid.UUID, paid func(tx *gorm.DB, payment *models.Payment) error) (*CheckoutResult, error) {
+	result := &CheckoutResult{}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// Concurrent checkouts of a customer must not spend the same credit
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").
+			First(&models.User{}, "id = ?", booking.UserID).Error; err != nil {
+			return err
+		}
+
+		available, err := balance(tx, booking.UserID)
+		if err != nil {
+			return err
+		}
+		applied, err := appliedTo(tx, booking.ID)
+		if err != nil {
+			return err
+		}
+
+		price := round(booking.TotalAmount)
+		target := math.Min(price, round(available+applied))
+		if target < 0 {
+			target = 0
+		}
+		if change := round(target - applied); change != 0 {
+			entryType := models.CreditEntryCheckout
+			if change < 0 {
+				entryType = models.CreditEntryRestore
+			}
+			if err := tx.Create(&models.CreditEntry{
+				UserID:      booking.UserID,
+				Type:        entryType,
+				Amount:      -change,
+				Currency:    booking.Currency,
+				Description: booking.Title,
+				BookingID:   &booking.ID,
+				PaymentID:   &paymentID,
+			}).Error; err != nil {
+				return err
+			}
+		}
+
+		result.Applied = target
+		result.Remaining = round(price - target)
+		if result.Remaining > 0 || target == 0 {
+			return nil
+		}
+
+		now := s.now()
+		payment := models.Payment{
+			ID:           paymentID,
+			UserID:       booking.UserID,
+			Currency:     booking.Currency,
+			CreditAmount: target,
+			Status:       models.PaymentStatusSucceeded,
+			Method:       models.PaymentMethodCredit,
+			Description:  booking.Title,
+			PaidAt:       &now,
+			// Stripe session IDs are unique, so payments without Stripe get a reference of their own
+			StripeSessionID: "credit_" + paymentID.String(),
+		}
+		if booking.LeadID != nil {
+			payment.LeadID = *booking.LeadID
+		}
+		if err := tx.Create(&payment).Error; err != nil {
+			return err
+		}
+		result.Payment = &payment
+
+		if paid != nil {
+			return paid(tx, &payment)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to apply credit: %w", err)
+	}
+
+	if result.Applied > 0 {
+		s.logger.Info("Credit applied at checkout",
+			zap.String("booking_id", booking.ID.String()),
+			zap.Float64("applied", result.Applied),
+			zap.Float64("remaining", result.Remaining))
+	}
+	return result, nil
+}
+
+// Restore returns the credit applied to a booking that will not be paid
+func (s *Service) Restore(bookingID uuid.UUID) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var booking models.Booking
+		if err := tx.Select("id", "user_id", "currency", "title").First(&booking, "id = ?", bookingID).Error; err != nil {
+			return err
+		}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").
+			First(&models.User{}, "id = ?", booking.UserID).Error; err != nil {
+			return err
+		}
+		applied, err := appliedTo(tx, booking.ID)
+		if err != nil || applied <= 0 {
+			return err
+		}
+		return tx.Create(&models.CreditEntry{
+			UserID:      booking.UserID,
+			Type:        models.CreditEntryRestore,
+			Amount:      applied,
+			Currency:    booking.Currency,
+			Description: booking.Title,
+			BookingID:   &booking.ID,
+		}).Error
+	})
+}
+
+// RestoreCancelled returns the credit applied to bookings that were cancelled
+// before they were paid, e.g. when their timeslot hold expired. It returns the
+// number of bookings whose credit was restored.
+func (s *Service) RestoreCancelled() (int, error) {
+	var bookingIDs []uuid.UUID
+	if err := s.db.Model(&models.CreditEntry{}).
+		Joins("JOIN bookings ON bookings.id = credit_entries.booking_id").
+		Where("bookings.status = ? AND credit_entries.type IN ?", models.BookingStatusCancelled,
+			[]models.CreditEntryType{models.CreditEntryCheckout, models.CreditEntryRestore}).
+		Group("credit_entries.booking_id").
+		Having("SUM(credit_entries.amount) < 0").
+		Pluck("credit_entries.booking_id", &bookingIDs).Error; err != nil {
+		return 0, fmt.Errorf("failed to find cancelled bookings with credit: %w", err)
+	}
+
+	restored := 0
+	for _, bookingID := range bookingIDs {
+		if err := s.Restore(bookingID); err != nil {
+			s.logger.Error("Failed to restore credit of cancelled booking", zap.String("booking_id", bookingID.String()), zap.Error(err))
+			continue
+		}
+		restored++
+	}
+	if restored > 0 {
+		s.logger.Info("Restored credit of cancelled bookings", zap.Int("count", restored))
+	}
+	return restored, nil
+}
+
+func balance(db *gorm.DB, userID uuid.UUID) (float64, error) {
+	var total float64
+	if err := db.Model(&models.CreditEntry{}).Select("COALESCE(SUM(amount), 0)").
+		Where("user_id = ?", userID).Scan(&total).Error; err != nil {
+		return 0, fmt.Errorf("failed to load credit balance: %w", err)
+	}
+	return round(total), nil
+}
+
+// appliedTo returns the credit currently applied to a booking
+func appliedTo(db *gorm.DB, bookingID uuid.UUID) (float64, error) {
+	var total float64
+	if err := db.Model(&models.CreditEntry{}).Select("COALESCE(SUM(amount), 0)").
+		Where("booking_id = ? AND type IN ?", bookingID,
+			[]models.CreditEntryType{models.CreditEntryCheckout, models.CreditEntryRestore}).
+		Scan(&total).Error; err != nil {
+		return 0, fmt.Errorf("failed to load applied credit: %w", err)
+	}
+	return round(-total), nil
+}
+
+func normalizeCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// generateCode generates a random voucher code without ambiguous characters
+func generateCode() (string, error) {
+	code := make([]byte, 10)
+	max := big.NewInt(int64(len(voucherAlphabet)))
+	for i := range code {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		code[i] = voucherAlphabet[n.Int64()]
+	}
+	return string(code), nil
+}
+
+func round(value float64) float64 {
+	return math.Round(value*100) / 100
+}
@@ -0,0 +1,216 @@
+package credit
+
+import (
+	"testing"
+	"time"
+
+	"elterngeld-portal/internal/bookingstate"
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/tests/testutils"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+func TestCheckout(t *testing.T) {
+	db, service := setupTestService(t)
+	customer := testutils.CreateTestUser(t, db, models.RoleUser)
+	admin := testutils.CreateTestUser(t, db, models.RoleAdmin)
+
+	_, err := service.Grant(customer.ID, 50, "Entschuldigung für die Terminverschiebung", admin)
+	require.NoError(t, err)
+
+	t.Run("partial credit", func(t *testing.T) {
+		booking := createBooking(t, db, customer, 120)
+		result, err := service.Checkout(booking, uuid.New(), nil)
+		require.NoError(t, err)
+		assert.Equal(t, 50.0, result.Applied)
+		assert.Equal(t, 70.0, result.Remaining)
+		assert.Nil(t, result.Payment)
+
+		// A retried checkout reuses the credit applied before
+		result, err = service.Checkout(booking, uuid.New(), nil)
+		require.NoError(t, err)
+		assert.Equal(t, 50.0, result.Applied)
+
+		balance, err := service.Balance(customer.ID)
+		require.NoError(t, err)
+		assert.Equal(t, 0.0, balance)
+
+		// Cancelled bookings get their credit back
+		require.NoError(t, db.Model(booking).Update("status", models.BookingStatusCancelled).Error)
+		restored, err := service.RestoreCancelled()
+		require.NoError(t, err)
+		assert.Equal(t, 1, restored)
+		restored, err = service.RestoreCancelled()
+		require.NoError(t, err)
+		assert.Equal(t, 0, restored)
+
+		balance, err = service.Balance(customer.ID)
+		require.NoError(t, err)
+		assert.Equal(t, 50.0, balance)
+	})
+
+	t.Run("credit covers the price", func(t *testing.T) {
+		booking := createBooking(t, db, customer, 30)
+		var paid *models.Payment
+		states := bookingstate.NewService(db, zap.NewNop())
+		result, err := service.Checkout(booking, uuid.New(), func(tx *gorm.DB, payment *models.Payment) error {
+			paid = payment
+			return states.Transition(tx, booking, models.BookingStatusConfirmed, bookingstate.Change{Payment: payment})
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 30.0, result.Applied)
+		assert.Equal(t, 0.0, result.Remaining)
+		require.NotNil(t, result.Payment)
+		assert.Equal(t, result.Payment.ID, paid.ID)
+		assert.Equal(t, models.PaymentMethodCredit, result.Payment.Method)
+		assert.True(t, result.Payment.IsPaid())
+
+		var stored models.Booking
+		require.NoError(t, db.First(&stored, "id = ?", booking.ID).Error)
+		assert.Equal(t, models.BookingStatusConfirmed, stored.Status)
+		assert.Equal(t, result.Payment.ID, *stored.PaymentID)
+
+		// Refunding to credit returns the credit used
+		payment, entry, err := service.RefundToCredit(result.Payment.ID, nil, "Termin abgesagt", admin)
+		require.NoError(t, err)
+		assert.Equal(t, 30.0, entry.Amount)
+		assert.Equal(t, models.PaymentStatusRefunded, payment.Status)
+
+		_, _, err = service.RefundToCredit(result.Payment.ID, nil, "", admin)
+		assert.ErrorIs(t, err, ErrNotRefundable)
+	})
+
+	t.Run("without credit", func(t *testing.T) {
+		other := testutils.CreateTestUser(t, db, models.RoleUser)
+		result, err := service.Checkout(createBooking(t, db, other, 80), uuid.New(), nil)
+		require.NoError(t, err)
+		assert.Equal(t, 0.0, result.Applied)
+		assert.Equal(t, 80.0, result.Remaining)
+	})
+
+	statement, err := service.Statement(customer.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 50.0, statement.Balance)
+	assert.Len(t, statement.Entries, 5)
+}
+
+func TestRefundToCredit(t *testing.T) {
+	db, service := setupTestService(t)
+	customer := testutils.CreateTestUser(t, db, models.RoleUser)
+	admin := testutils.CreateTestUser(t, db, models.RoleAdmin)
+
+	payment := models.Payment{UserID: customer.ID, Amount: 100, Status: models.PaymentStatusSucceeded, StripeSessionID: "cs_1"}
+	require.NoError(t, db.Create(&payment).Error)
+	pending := models.Payment{UserID: customer.ID, Amount: 100, Status: models.PaymentStatusPending, StripeSessionID: "cs_2"}
+	require.NoError(t, db.Create(&pending).Error)
+
+	amount := func(value float64) *float64 { return &value }
+	tests := []struct {
+		name    string
+		payment uuid.UUID
+		amount  *float64
+		err     error
+	}{
+		{"unknown payment", uuid.New(), nil, ErrPaymentNotFound},
+		{"pending payment", pending.ID, nil, ErrNotRefundable},
+		{"negative amount", payment.ID, amount(-5), ErrInvalidAmount},
+		{"too high", payment.ID, amount(150), ErrRefundTooHigh},
+		{"partial refund", payment.ID, amount(40), nil},
+		{"rest exceeded", payment.ID, amount(70), ErrRefundTooHigh},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := service.RefundToCredit(tt.payment, tt.amount, "Kulanz", admin)
+			assert.ErrorIs(t, err, tt.err)
+		})
+	}
+
+	refunded, entry, err := service.RefundToCredit(payment.ID, nil, "Kulanz", admin)
+	require.NoError(t, err)
+	assert.Equal(t, 60.0, entry.Amount)
+	assert.Equal(t, 100.0, refunded.RefundAmount)
+	assert.Equal(t, models.PaymentStatusRefunded, refunded.Status)
+
+	balance, err := service.Balance(customer.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 100.0, balance)
+}
+
+func TestRedeemVoucher(t *testing.T) {
+	db, service := setupTestService(t)
+	customer := testutils.CreateTestUser(t, db, models.RoleUser)
+	otherCustomer := testutils.CreateTestUser(t, db, models.RoleUser)
+	admin := testutils.CreateTestUser(t, db, models.RoleAdmin)
+
+	voucher, err := service.CreateVoucher(VoucherInput{Code: " willkommen25 ", Amount: 25, MaxRedemptions: 1}, admin)
+	require.NoError(t, err)
+	assert.Equal(t, "WILLKOMMEN25", voucher.Code)
+
+	_, err = service.CreateVoucher(VoucherInput{Code: "Willkommen25", Amount: 10}, admin)
+	assert.ErrorIs(t, err, ErrVoucherCodeTaken)
+	_, err = service.CreateVoucher(VoucherInput{Amount: 0}, admin)
+	assert.ErrorIs(t, err, ErrInvalidAmount)
+
+	generated, err := service.CreateVoucher(VoucherInput{Amount: 10}, admin)
+	require.NoError(t, err)
+	assert.Len(t, generated.Code, 10)
+
+	yesterday := time.Now().Add(-24 * time.Hour)
+	expired, err := service.CreateVoucher(VoucherInput{Amount: 10, ExpiresAt: &yesterday}, admin)
+	require.NoError(t, err)
+
+	entry, err := service.RedeemVoucher(customer.ID, "willkommen25")
+	require.NoError(t, err)
+	assert.Equal(t, 25.0, entry.Amount)
+	assert.Equal(t, models.CreditEntryVoucher, entry.Type)
+
+	_, err = service.RedeemVoucher(otherCustomer.ID, "WILLKOMMEN25")
+	assert.ErrorIs(t, err, ErrVoucherNotRedeemable)
+	_, err = service.RedeemVoucher(customer.ID, generated.Code)
+	require.NoError(t, err)
+	_, err = service.RedeemVoucher(customer.ID, generated.Code)
+	assert.ErrorIs(t, err, ErrVoucherAlreadyRedeemed)
+	_, err = service.RedeemVoucher(customer.ID, expired.Code)
+	assert.ErrorIs(t, err, ErrVoucherNotRedeemable)
+	_, err = service.RedeemVoucher(customer.ID, "UNBEKANNT")
+	assert.ErrorIs(t, err, ErrVoucherNotFound)
+
+	balance, err := service.Balance(customer.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 35.0, balance)
+}
+
+func createBooking(t *testing.T, db *gorm.DB, customer *models.User, price float64) *models.Booking {
+	t.Helper()
+	start := time.Now().Add(48 * time.Hour)
+	booking := &models.Booking{
+		UserID:      customer.ID,
+		Title:       "Erstberatung",
+		Status:      models.BookingStatusPending,
+		ScheduledAt: start,
+		StartTime:   start,
+		EndTime:     start.Add(time.Hour),
+		TotalAmount: price,
+		Currency:    "EUR",
+	}
+	require.NoError(t, db.Create(booking).Error)
+	return booking
+}
+
+func setupTestService(t *testing.T) (*gorm.DB, *Service) {
+	t.Helper()
+	db := testutils.NewSQLiteDB(t,
+		&models.User{},
+		&models.Booking{},
+		&models.Payment{},
+		&models.CreditEntry{},
+		&models.Voucher{},
+	)
+
+	return db, NewService(db, zap.NewNop())
+}
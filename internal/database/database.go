@@ -9,6 +9,7 @@ import (
 
 	"elterngeld-portal/config"
 	"elterngeld-portal/internal/models"
+	"elterngeld-portal/pkg/encryption"
 
 	"go.uber.org/zap"
 	"gorm.io/driver/postgres"
@@ -110,9 +111,80 @@ func AutoMigrate() error {
 		&models.RefreshToken{},
 		&models.Lead{},
 		&models.Comment{},
+		&models.CommentAttachment{},
+		&models.CommentRevision{},
+		&models.CommentReaction{},
 		&models.Document{},
 		&models.Activity{},
 		&models.Payment{},
+		&models.ContactForm{},
+		&models.Notification{},
+		&models.EmailVerification{},
+		&models.EmailThread{},
+		&models.EmailMessage{},
+		&models.InboundEmail{},
+		&models.EmailAttachment{},
+		&models.ShortLink{},
+		&models.ShortLinkClick{},
+		&models.HolidayOverride{},
+		&models.BeraterInvitation{},
+		&models.AvailabilityRule{},
+		&models.WebhookEvent{},
+		&models.OutboxMessage{},
+		&models.SystemSetting{},
+		&models.ContentEntry{},
+		&models.Post{},
+		&models.InterviewInvitation{},
+		&models.EvaluationCriterion{},
+		&models.ApplicationEvaluation{},
+		&models.ApplicationRating{},
+		&models.APIKey{},
+		&models.AccessToken{},
+		&models.ServiceKey{},
+		&models.SubmissionCheck{},
+		&models.PostalCodeArea{},
+		&models.ElterngeldScenario{},
+		&models.ApplicationSubmission{},
+		&models.ApplicationDocumentRequest{},
+		&models.PDFJob{},
+		&models.PDFBranding{},
+		&models.Announcement{},
+		&models.AnnouncementReceipt{},
+		&models.ProductUpdate{},
+		&models.JobLock{},
+		&models.ScheduledJob{},
+		&models.ScheduledJobRun{},
+		&models.ChatChannel{},
+		&models.ChatRoutingRule{},
+		&models.NewsletterContact{},
+		&models.AnalyticsSession{},
+		&models.AnalyticsEvent{},
+		&models.Experiment{},
+		&models.ExperimentVariant{},
+		&models.ExperimentExposure{},
+		&models.ExperimentConversion{},
+		&models.MarketingSpend{},
+		&models.TimeslotHold{},
+		&models.ConsultationSummary{},
+		&models.ConsultationSummaryItem{},
+		&models.CreditEntry{},
+		&models.Voucher{},
+		&models.Coupon{},
+		&models.PaymentLink{},
+		&models.RefundRequest{},
+		&models.PaymentDispute{},
+		&models.LeadAgingRule{},
+		&models.SavedView{},
+		&models.SavedViewDefault{},
+		&models.Snippet{},
+		&models.WhatsAppConsent{},
+		&models.WhatsAppMessage{},
+		&models.Family{},
+		&models.FamilyMember{},
+		&models.FamilyChild{},
+		&models.Questionnaire{},
+		&models.QuestionnaireQuestion{},
+		&models.QuestionnaireResponse{},
 	}
 
 	// Run migrations
@@ -130,6 +202,16 @@ func AutoMigrate() error {
 	return nil
 }
 
+// RotateEncryptionKeys rewraps all encrypted personal data with the primary key of
+// the keyring and encrypts values that were stored before encryption was enabled
+func RotateEncryptionKeys(keyring *encryption.Keyring) (encryption.RotationStats, error) {
+	if DB == nil {
+		return encryption.RotationStats{}, fmt.Errorf("database not initialized")
+	}
+
+	return keyring.Rotate(DB, &models.User{}, &models.Booking{}, &models.Payment{}, &models.ChatChannel{})
+}
+
 // createCustomIndexes creates custom database indexes
 func createCustomIndexes() error {
 	// Add custom indexes for better performance
@@ -1,12 +1,15 @@
 package database
 
 import (
+	"bytes"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"elterngeld-portal/config"
 	"elterngeld-portal/internal/models"
+	"elterngeld-portal/pkg/encryption"
 
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
@@ -477,6 +480,30 @@ func TestSeedData_AdminExists(t *testing.T) {
 	assert.Equal(t, int64(1), count)
 }
 
+func TestSeedLoadTest(t *testing.T) {
+	setupTestDB(t)
+	defer cleanupTestDB(t)
+
+	require.NoError(t, SeedLoadTest())
+
+	var berater models.User
+	require.NoError(t, DB.First(&berater, "email = ?", LoadTestBeraterEmail).Error)
+	assert.True(t, berater.IsBookable())
+	assert.True(t, berater.CheckPassword(LoadTestPassword))
+
+	var timeslots, leads int64
+	require.NoError(t, DB.Model(&models.Timeslot{}).Where("berater_id = ?", berater.ID).Count(&timeslots).Error)
+	require.NoError(t, DB.Model(&models.Lead{}).Where("berater_id = ?", berater.ID).Count(&leads).Error)
+	assert.Greater(t, timeslots, int64(0))
+	assert.Equal(t, int64(loadTestLeads), leads)
+
+	// Seeding again keeps the existing dataset
+	require.NoError(t, SeedLoadTest())
+	var count int64
+	require.NoError(t, DB.Model(&models.Lead{}).Where("berater_id = ?", berater.ID).Count(&count).Error)
+	assert.Equal(t, leads, count)
+}
+
 func TestConcurrentDatabaseOperations(t *testing.T) {
 	setupTestDB(t)
 	defer cleanupTestDB(t)
@@ -550,6 +577,57 @@ func TestDatabaseEdgeCases(t *testing.T) {
 
 // Helper functions
 
+func TestRotateEncryptionKeys(t *testing.T) {
+	setupTestDB(t)
+	defer cleanupTestDB(t)
+	t.Cleanup(func() { encryption.SetDefault(nil) })
+
+	// Stored before encryption was enabled
+	encryption.SetDefault(nil)
+	channel := &models.ChatChannel{
+		Name:       "Vertrieb",
+		Provider:   models.ChatProviderSlack,
+		WebhookURL: "https://hooks.slack.com/services/T000/B000/XXXX",
+		IsActive:   true,
+	}
+	require.NoError(t, DB.Create(channel).Error)
+	user := &models.User{Email: "anna@example.com", Password: "password123", FirstName: "Anna", LastName: "Schmidt", Role: models.RoleUser}
+	require.NoError(t, DB.Create(user).Error)
+	lead := &models.Lead{UserID: user.ID, Title: "Elterngeld Beratung"}
+	require.NoError(t, DB.Create(lead).Error)
+	payment := &models.Payment{
+		LeadID:         lead.ID,
+		UserID:         user.ID,
+		Amount:         249,
+		BillingAddress: "Musterstraße 1\n10115 Berlin",
+	}
+	require.NoError(t, DB.Create(payment).Error)
+
+	keyring, err := encryption.NewKeyring(map[string][]byte{"k1": bytes.Repeat([]byte{1}, 32)}, "k1")
+	require.NoError(t, err)
+	encryption.SetDefault(keyring)
+
+	stats, err := RotateEncryptionKeys(keyring)
+	require.NoError(t, err)
+	assert.Equal(t, 2, stats.Encrypted)
+
+	var webhookURL string
+	require.NoError(t, DB.Table("chat_channels").Where("id = ?", channel.ID).Pluck("webhook_url", &webhookURL).Error)
+	assert.True(t, strings.HasPrefix(webhookURL, "enc:v1:k1:"), webhookURL)
+
+	var stored models.ChatChannel
+	require.NoError(t, DB.First(&stored, "id = ?", channel.ID).Error)
+	assert.Equal(t, channel.WebhookURL, stored.WebhookURL)
+
+	var billingAddress string
+	require.NoError(t, DB.Table("payments").Where("id = ?", payment.ID).Pluck("billing_address", &billingAddress).Error)
+	assert.True(t, strings.HasPrefix(billingAddress, "enc:v1:k1:"), billingAddress)
+
+	var storedPayment models.Payment
+	require.NoError(t, DB.First(&storedPayment, "id = ?", payment.ID).Error)
+	assert.Equal(t, payment.BillingAddress, storedPayment.BillingAddress)
+}
+
 func createTestSQLiteConfig(t *testing.T) *config.Config {
 	tempDir := t.TempDir()
 	dbPath := filepath.Join(tempDir, "test.db")
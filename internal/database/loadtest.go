@@ -0,0 +1,188 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"elterngeld-portal/internal/holidays"
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/pkg/timeutil"
+)
+
+// Accounts of the load test dataset, used by loadtest/hot-endpoints.js
+const (
+	LoadTestCustomerEmail = "loadtest.customer@example.com"
+	LoadTestBeraterEmail  = "loadtest.berater@example.com"
+	LoadTestPassword      = "Loadtest-2024"
+)
+
+const (
+	loadTestLeads = 1000 // leads of the Berater, enough to page through the lead list
+	loadTestDays  = 30   // days with bookable timeslots
+)
+
+// loadTestTables are needed by the load test but not created by AutoMigrate
+var loadTestTables = []interface{}{
+	&models.Package{},
+	&models.Addon{},
+	&models.PackageAddon{},
+	&models.Timeslot{},
+	&models.BookingAddon{},
+}
+
+// SeedLoadTest creates the dataset the load test scenarios run against: a
+// customer, a Berater with a month of timeslots and many leads, and a
+// bookable package. It does nothing when the dataset exists already.
+func SeedLoadTest() error {
+	if DB == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	var existing models.User
+	err := DB.Where("email = ?", LoadTestBeraterEmail).First(&existing).Error
+	if err == nil {
+		log.Println("Load test data already exists, skipping")
+		return nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+
+	if err := DB.AutoMigrate(loadTestTables...); err != nil {
+		return fmt.Errorf("failed to migrate load test tables: %w", err)
+	}
+
+	return DB.Transaction(func(tx *gorm.DB) error {
+		customer := &models.User{
+			Email:         LoadTestCustomerEmail,
+			Password:      LoadTestPassword,
+			FirstName:     "Lena",
+			LastName:      "Lasttest",
+			Role:          models.RoleUser,
+			IsActive:      true,
+			EmailVerified: true,
+		}
+		berater := &models.User{
+			Email:            LoadTestBeraterEmail,
+			Password:         LoadTestPassword,
+			FirstName:        "Lars",
+			LastName:         "Lasttest",
+			Role:             models.RoleBerater,
+			IsActive:         true,
+			EmailVerified:    true,
+			OnboardingStatus: models.OnboardingStatusApproved,
+			Bundesland:       models.BundeslandBerlin,
+		}
+		if err := tx.Create(customer).Error; err != nil {
+			return fmt.Errorf("failed to create load test customer: %w", err)
+		}
+		if err := tx.Create(berater).Error; err != nil {
+			return fmt.Errorf("failed to create load test Berater: %w", err)
+		}
+
+		servicePackage := &models.Package{
+			Name:             "Lasttest Beratung",
+			Description:      "Paket für Lasttests",
+			Type:             models.PackageTypeBasic,
+			Price:            99.00,
+			Currency:         "EUR",
+			IsActive:         true,
+			RequiresTimeslot: true,
+			ConsultationTime: 60,
+			StripeProductID:  "prod_loadtest",
+			StripePriceID:    "price_loadtest",
+		}
+		if err := tx.Create(servicePackage).Error; err != nil {
+			return fmt.Errorf("failed to create load test package: %w", err)
+		}
+
+		if err := seedLoadTestTimeslots(tx, berater); err != nil {
+			return err
+		}
+		if err := seedLoadTestLeads(tx, customer, berater); err != nil {
+			return err
+		}
+
+		log.Printf("Load test data created, package %s", servicePackage.ID)
+		return nil
+	})
+}
+
+// seedLoadTestTimeslots creates hourly timeslots on the working days ahead
+func seedLoadTestTimeslots(tx *gorm.DB, berater *models.User) error {
+	loc := berater.TimeLocation()
+	start := time.Now()
+	calendar := holidays.NewService(tx, zap.NewNop()).Calendar(
+		timeutil.FormatDate(start, loc),
+		timeutil.FormatDate(start.AddDate(0, 0, loadTestDays+1), loc),
+	)
+
+	var timeslots []models.Timeslot
+	for day := 1; day <= loadTestDays; day++ {
+		current := timeutil.AddDays(start, day, loc)
+		if weekday := current.In(loc).Weekday(); weekday == time.Saturday || weekday == time.Sunday {
+			continue
+		}
+		if _, isHoliday, err := calendar.Lookup(berater.Bundesland, timeutil.FormatDate(current, loc)); err != nil {
+			return err
+		} else if isHoliday {
+			continue
+		}
+
+		for hour := 9; hour < 17; hour++ {
+			startTime := timeutil.At(current, hour, 0, loc)
+			timeslots = append(timeslots, models.Timeslot{
+				BeraterID:   berater.ID,
+				Date:        timeutil.StartOfDay(current, loc),
+				StartTime:   startTime,
+				EndTime:     startTime.Add(time.Hour),
+				Duration:    60,
+				Timezone:    loc.String(),
+				Purpose:     models.TimeslotPurposeConsultation,
+				IsAvailable: true,
+				MaxBookings: 1,
+				Title:       "Beratungstermin",
+				IsOnline:    true,
+			})
+		}
+	}
+
+	if err := tx.CreateInBatches(timeslots, 100).Error; err != nil {
+		return fmt.Errorf("failed to create load test timeslots: %w", err)
+	}
+	return nil
+}
+
+// seedLoadTestLeads creates the leads of the Berater with spread out dates,
+// so the lead list sorts and pages like on a busy instance
+func seedLoadTestLeads(tx *gorm.DB, customer, berater *models.User) error {
+	statuses := []models.LeadStatus{models.LeadStatusNew, models.LeadStatusInProgress, models.LeadStatusCompleted}
+	priorities := []models.Priority{models.PriorityLow, models.PriorityMedium, models.PriorityHigh}
+
+	leads := make([]models.Lead, loadTestLeads)
+	for i := range leads {
+		createdAt := time.Now().Add(-time.Duration(i) * time.Hour)
+		leads[i] = models.Lead{
+			ID:        uuid.New(),
+			UserID:    customer.ID,
+			BeraterID: &berater.ID,
+			Title:     fmt.Sprintf("Lasttest Anfrage %d", i+1),
+			Status:    statuses[i%len(statuses)],
+			Priority:  priorities[i%len(priorities)],
+			Source:    models.LeadSourceWebsite,
+			CreatedAt: createdAt,
+			UpdatedAt: createdAt,
+		}
+	}
+
+	if err := tx.CreateInBatches(leads, 100).Error; err != nil {
+		return fmt.Errorf("failed to create load test leads: %w", err)
+	}
+	return nil
+}
@@ -6,10 +6,13 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"go.uber.org/zap"
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 
+	"elterngeld-portal/internal/holidays"
 	"elterngeld-portal/internal/models"
+	"elterngeld-portal/pkg/timeutil"
 )
 
 // SeedDatabase seeds the database with initial data
@@ -108,6 +111,7 @@ func seedUsers(db *gorm.DB) error {
 			Address:   "Beratergasse 5",
 			PostalCode: "10117",
 			City:      "Berlin",
+			Bundesland: models.BundeslandBerlin,
 			EmailVerified: true,
 			EmailVerifiedAt: func() *time.Time { t := time.Now(); return &t }(),
 		},
@@ -123,6 +127,7 @@ func seedUsers(db *gorm.DB) error {
 			Address:   "Juniorstraße 10",
 			PostalCode: "10119",
 			City:      "Berlin",
+			Bundesland: models.BundeslandBerlin,
 			EmailVerified: true,
 			EmailVerifiedAt: func() *time.Time { t := time.Now(); return &t }(),
 		},
@@ -175,6 +180,20 @@ func seedUsers(db *gorm.DB) error {
 		},
 	}
 
+	// Berater profiles used for matching leads to Beraters
+	for i := range users {
+		if users[i].Role != models.RoleBerater && users[i].Role != models.RoleJuniorBerater {
+			continue
+		}
+		users[i].SetServiceBundeslaender([]models.Bundesland{models.BundeslandBerlin, models.BundeslandBrandenburg})
+		users[i].SetWorkingLanguages([]string{"de", "en"})
+		if users[i].Role == models.RoleBerater {
+			users[i].SetSpecializations([]models.Specialization{models.SpecializationSelbststaendige, models.SpecializationWiderspruch})
+		} else {
+			users[i].SetSpecializations([]models.Specialization{models.SpecializationBeamte, models.SpecializationZwillinge})
+		}
+	}
+
 	// Hash passwords
 	for i := range users {
 		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(users[i].Password), bcrypt.DefaultCost)
@@ -274,6 +293,9 @@ func seedPermissions(db *gorm.DB) error {
 		// Payments
 		{Name: "payments.read", Resource: models.PermissionResourcePayments, Action: models.PermissionActionRead, Description: "Zahlungen anzeigen"},
 		{Name: "payments.manage", Resource: models.PermissionResourcePayments, Action: models.PermissionActionManage, Description: "Zahlungen verwalten"},
+
+		// Personal data
+		{Name: models.PermissionPIIRead, Resource: models.PermissionResourcePII, Action: models.PermissionActionRead, Description: "Ungekürzte Kontaktdaten (E-Mail, Telefon, Adresse) anzeigen"},
 	}
 
 	for i := range permissions {
@@ -395,7 +417,7 @@ func assignPermissionsToRoles(db *gorm.DB, adminID uuid.UUID) error {
 			"todos.create", "todos.update", "todos.read",
 			"documents.read", "documents.create",
 			"contact_forms.read", "contact_forms.update",
-			"users.read", "packages.read",
+			"users.read", "packages.read", models.PermissionPIIRead,
 		},
 		"admin": {
 			"dashboard.admin.read", "dashboard.read",
@@ -649,36 +671,48 @@ func seedTimeslots(db *gorm.DB) error {
 		return nil
 	}
 
-	// Create timeslots for the next 30 days
+	// Create timeslots for the next 30 days, skipping public holidays
 	startDate := time.Now()
-	endDate := startDate.Add(30 * 24 * time.Hour)
+	calendar := holidays.NewService(db, zap.NewNop()).Calendar(
+		timeutil.FormatDate(startDate.AddDate(0, 0, -1), time.UTC),
+		timeutil.FormatDate(startDate.AddDate(0, 0, 31), time.UTC),
+	)
 
 	var timeslots []models.Timeslot
 
-	for current := startDate; current.Before(endDate); current = current.Add(24 * time.Hour) {
-		// Skip weekends
-		if current.Weekday() == time.Saturday || current.Weekday() == time.Sunday {
-			continue
-		}
-
-		// Create morning and afternoon slots for each berater
+	for day := 0; day < 30; day++ {
+		// Create morning and afternoon slots for each berater in their own timezone
 		for _, berater := range beraters {
+			loc := berater.TimeLocation()
+			current := timeutil.AddDays(startDate, day, loc)
+
+			// Skip weekends
+			if weekday := current.In(loc).Weekday(); weekday == time.Saturday || weekday == time.Sunday {
+				continue
+			}
+			if _, isHoliday, err := calendar.Lookup(berater.Bundesland, timeutil.FormatDate(current, loc)); err != nil {
+				return err
+			} else if isHoliday {
+				continue
+			}
+
 			// Morning slots (9:00-12:00)
 			morningSlots := []struct{ hour, minute int }{
 				{9, 0}, {10, 0}, {11, 0},
 			}
 
 			for _, slot := range morningSlots {
-				startTime := time.Date(current.Year(), current.Month(), current.Day(), slot.hour, slot.minute, 0, 0, current.Location())
+				startTime := timeutil.At(current, slot.hour, slot.minute, loc)
 				endTime := startTime.Add(60 * time.Minute)
 
 				timeslot := models.Timeslot{
 					ID:              uuid.New(),
 					BeraterID:       berater.ID,
-					Date:            current,
+					Date:            timeutil.StartOfDay(current, loc),
 					StartTime:       startTime,
 					EndTime:         endTime,
 					Duration:        60,
+					Timezone:        loc.String(),
 					IsAvailable:     true,
 					MaxBookings:     1,
 					CurrentBookings: 0,
@@ -697,16 +731,17 @@ func seedTimeslots(db *gorm.DB) error {
 			}
 
 			for _, slot := range afternoonSlots {
-				startTime := time.Date(current.Year(), current.Month(), current.Day(), slot.hour, slot.minute, 0, 0, current.Location())
+				startTime := timeutil.At(current, slot.hour, slot.minute, loc)
 				endTime := startTime.Add(60 * time.Minute)
 
 				timeslot := models.Timeslot{
 					ID:              uuid.New(),
 					BeraterID:       berater.ID,
-					Date:            current,
+					Date:            timeutil.StartOfDay(current, loc),
 					StartTime:       startTime,
 					EndTime:         endTime,
 					Duration:        60,
+					Timezone:        loc.String(),
 					IsAvailable:     true,
 					MaxBookings:     1,
 					CurrentBookings: 0,
@@ -1249,6 +1284,7 @@ func seedJobApplications(db *gorm.DB) error {
 		}
 		applications[i].CreatedAt = time.Now().Add(-time.Duration((i+1)*24) * time.Hour)
 		applications[i].UpdatedAt = time.Now()
+		applications[i].EmailVerifiedAt = &applications[i].CreatedAt
 	}
 
 	return db.Create(&applications).Error
@@ -0,0 +1,243 @@
+// Package digest batches the email notifications of users who chose an hourly
+// or daily digest for a category into a single summary email. The email queue
+// holds those notifications back as batched and the digest job sends them
+// once the chosen cadence is due.
+package digest
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"elterngeld-portal/config"
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/pkg/timeutil"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrInvalidCategory is returned for unknown notification categories
+	ErrInvalidCategory = errors.New("invalid notification category")
+	// ErrInvalidMode is returned for digest modes other than immediate, hourly and daily
+	ErrInvalidMode = errors.New("invalid digest mode")
+)
+
+// hourlyDelay is how long the oldest notification of an hourly digest waits,
+// so users get at most one hourly digest per hour
+const hourlyDelay = time.Hour
+
+// Sender emails the batched notifications of a user as one summary
+type Sender interface {
+	SendNotificationDigest(user *models.User, notifications []models.Notification) error
+}
+
+// Modes is the digest mode of each notification category
+type Modes map[models.NotificationCategory]models.DigestMode
+
+// Service manages the digest modes of users and sends due digests
+type Service struct {
+	db       *gorm.DB
+	logger   *zap.Logger
+	sender   Sender
+	sendHour int
+	now      func() time.Time
+}
+
+func NewService(db *gorm.DB, logger *zap.Logger, cfg *config.Config, sender Sender) *Service {
+	return &Service{
+		db:       db,
+		logger:   logger,
+		sender:   sender,
+		sendHour: cfg.Digest.SendHour,
+		now:      time.Now,
+	}
+}
+
+// GetModes returns the digest mode of every category; users without
+// notification preferences get every notification immediately
+func (s *Service) GetModes(userID uuid.UUID) (Modes, error) {
+	var preference models.NotificationPreference
+	err := s.db.Where("user_id = ?", userID).First(&preference).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	return modesOf(&preference), nil
+}
+
+// SetModes changes the digest mode of the given categories; other categories keep theirs
+func (s *Service) SetModes(userID uuid.UUID, modes Modes) (Modes, error) {
+	updates := map[string]interface{}{}
+	for category, mode := range modes {
+		column, ok := columns[category]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidCategory, category)
+		}
+		if !mode.IsValid() {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidMode, mode)
+		}
+		updates[column] = mode
+	}
+
+	var preference models.NotificationPreference
+	err := s.db.Where("user_id = ?", userID).First(&preference).Error
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		preference = models.NotificationPreference{UserID: userID}
+		if err := s.db.Create(&preference).Error; err != nil {
+			return nil, fmt.Errorf("failed to create notification preferences: %w", err)
+		}
+	case err != nil:
+		return nil, err
+	}
+
+	if len(updates) > 0 {
+		if err := s.db.Model(&preference).Updates(updates).Error; err != nil {
+			return nil, fmt.Errorf("failed to update notification preferences: %w", err)
+		}
+	}
+	return modesOf(&preference), nil
+}
+
+// columns maps the categories to their preference columns
+var columns = map[models.NotificationCategory]string{
+	models.NotificationCategoryBooking:  "booking_digest",
+	models.NotificationCategoryPayment:  "payment_digest",
+	models.NotificationCategoryTodo:     "todo_digest",
+	models.NotificationCategoryReminder: "reminder_digest",
+	models.NotificationCategoryLead:     "lead_digest",
+}
+
+func modesOf(preference *models.NotificationPreference) Modes {
+	modes := make(Modes, len(models.NotificationCategories))
+	for _, category := range models.NotificationCategories {
+		modes[category] = preference.DigestModeFor(category)
+	}
+	return modes
+}
+
+// SendDigests sends the batched notifications of every user whose digest is
+// due and returns the number of digests sent. Hourly notifications are sent
+// once the oldest has waited an hour, daily ones after the send hour in the
+// user's timezone. Notifications of categories switched back to immediate go
+// out with the next run.
+func (s *Service) SendDigests() (int, error) {
+	var userIDs []uuid.UUID
+	if err := s.db.Model(&models.Notification{}).
+		Where("type = ? AND status = ?", models.NotificationTypeEmail, models.NotificationStatusBatched).
+		Distinct("user_id").Pluck("user_id", &userIDs).Error; err != nil {
+		return 0, fmt.Errorf("failed to find batched notifications: %w", err)
+	}
+
+	now := s.now()
+	sent := 0
+	for _, userID := range userIDs {
+		ok, err := s.sendDigest(userID, now)
+		if err != nil {
+			s.logger.Error("Failed to send notification digest", zap.String("user_id", userID.String()), zap.Error(err))
+			continue
+		}
+		if ok {
+			sent++
+		}
+	}
+
+	if sent > 0 {
+		s.logger.Info("Sent notification digests", zap.Int("count", sent))
+	}
+	return sent, nil
+}
+
+// sendDigest sends the due batched notifications of a user and reports whether a digest was sent
+func (s *Service) sendDigest(userID uuid.UUID, now time.Time) (bool, error) {
+	var user models.User
+	if err := s.db.First(&user, "id = ?", userID).Error; err != nil {
+		return false, fmt.Errorf("failed to load user: %w", err)
+	}
+	var preference models.NotificationPreference
+	if err := s.db.Where("user_id = ?", userID).First(&preference).Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, fmt.Errorf("failed to load notification preferences: %w", err)
+	}
+
+	var batched []models.Notification
+	if err := s.db.Where("user_id = ? AND type = ? AND status = ?", userID, models.NotificationTypeEmail, models.NotificationStatusBatched).
+		Order("created_at ASC").Find(&batched).Error; err != nil {
+		return false, fmt.Errorf("failed to load batched notifications: %w", err)
+	}
+
+	loc := user.TimeLocation()
+	dailyDue := now.In(loc).Hour() >= s.sendHour &&
+		(preference.NotificationDigestSentAt == nil || preference.NotificationDigestSentAt.Before(timeutil.StartOfDay(now, loc)))
+	modes := make([]models.DigestMode, len(batched))
+	var oldestHourly time.Time
+	for i := range batched {
+		modes[i] = models.DigestModeImmediate
+		if category, ok := models.EmailTemplate(batched[i].Template).DigestCategory(); ok {
+			modes[i] = preference.DigestModeFor(category)
+		}
+		// Notifications are ordered by age, so the first hourly one is the oldest
+		if modes[i] == models.DigestModeHourly && oldestHourly.IsZero() {
+			oldestHourly = batched[i].CreatedAt
+		}
+	}
+	hourlyDue := !oldestHourly.IsZero() && !oldestHourly.After(now.Add(-hourlyDelay))
+
+	var due []models.Notification
+	includesDaily := false
+	for i, notification := range batched {
+		switch modes[i] {
+		case models.DigestModeHourly:
+			if !hourlyDue {
+				continue
+			}
+		case models.DigestModeDaily:
+			if !dailyDue {
+				continue
+			}
+			includesDaily = true
+		}
+
+		// Claim the notification, so parallel runs do not send it twice
+		claim := s.db.Model(&models.Notification{}).
+			Where("id = ? AND status = ?", notification.ID, models.NotificationStatusBatched).
+			Update("status", models.NotificationStatusSending)
+		if claim.Error != nil {
+			return false, claim.Error
+		}
+		if claim.RowsAffected == 1 {
+			due = append(due, notification)
+		}
+	}
+	if len(due) == 0 {
+		return false, nil
+	}
+
+	ids := make([]uuid.UUID, len(due))
+	for i := range due {
+		ids[i] = due[i].ID
+	}
+
+	if err := s.sender.SendNotificationDigest(&user, due); err != nil {
+		if resetErr := s.db.Model(&models.Notification{}).Where("id IN ?", ids).
+			Update("status", models.NotificationStatusBatched).Error; resetErr != nil {
+			s.logger.Error("Failed to reset notifications of failed digest", zap.String("user_id", userID.String()), zap.Error(resetErr))
+		}
+		return false, err
+	}
+
+	if err := s.db.Model(&models.Notification{}).Where("id IN ?", ids).Updates(map[string]interface{}{
+		"status":        models.NotificationStatusSent,
+		"sent_at":       now,
+		"error_message": "",
+	}).Error; err != nil {
+		s.logger.Error("Failed to mark digest notifications as sent", zap.String("user_id", userID.String()), zap.Error(err))
+	}
+	if includesDaily && preference.ID != uuid.Nil {
+		if err := s.db.Model(&preference).UpdateColumn("notification_digest_sent_at", now).Error; err != nil {
+			s.logger.Error("Failed to mark daily notification digest as sent", zap.String("user_id", userID.String()), zap.Error(err))
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,156 @@
+package digest
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"elterngeld-portal/config"
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/tests/testutils"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type fakeSender struct {
+	fail    bool
+	digests [][]models.Notification
+}
+
+func (s *fakeSender) SendNotificationDigest(user *models.User, notifications []models.Notification) error {
+	if s.fail {
+		return errors.New("smtp unavailable")
+	}
+	s.digests = append(s.digests, notifications)
+	return nil
+}
+
+func TestSetModes(t *testing.T) {
+	service, db, _ := setupTestService(t)
+	user := testutils.CreateTestUser(t, db, models.RoleBerater)
+
+	modes, err := service.GetModes(user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.DigestModeImmediate, modes[models.NotificationCategoryLead])
+
+	_, err = service.SetModes(user.ID, Modes{"newsletter": models.DigestModeDaily})
+	assert.ErrorIs(t, err, ErrInvalidCategory)
+	_, err = service.SetModes(user.ID, Modes{models.NotificationCategoryLead: "weekly"})
+	assert.ErrorIs(t, err, ErrInvalidMode)
+
+	modes, err = service.SetModes(user.ID, Modes{
+		models.NotificationCategoryLead: models.DigestModeDaily,
+		models.NotificationCategoryTodo: models.DigestModeHourly,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, models.DigestModeDaily, modes[models.NotificationCategoryLead])
+	assert.Equal(t, models.DigestModeHourly, modes[models.NotificationCategoryTodo])
+	assert.Equal(t, models.DigestModeImmediate, modes[models.NotificationCategoryPayment])
+
+	// Other categories keep their mode
+	modes, err = service.SetModes(user.ID, Modes{models.NotificationCategoryTodo: models.DigestModeImmediate})
+	require.NoError(t, err)
+	assert.Equal(t, models.DigestModeDaily, modes[models.NotificationCategoryLead])
+	assert.Equal(t, models.DigestModeImmediate, modes[models.NotificationCategoryTodo])
+}
+
+func TestSendDigests(t *testing.T) {
+	service, db, sender := setupTestService(t)
+	// 06:00 in Berlin, before the daily send hour
+	now := time.Date(2026, 3, 10, 5, 0, 0, 0, time.UTC)
+	service.now = func() time.Time { return now }
+
+	user := testutils.CreateTestUser(t, db, models.RoleBerater)
+	_, err := service.SetModes(user.ID, Modes{
+		models.NotificationCategoryTodo: models.DigestModeHourly,
+		models.NotificationCategoryLead: models.DigestModeDaily,
+	})
+	require.NoError(t, err)
+
+	todo := createBatched(t, db, user, models.EmailTemplateTodoAssigned, now.Add(-30*time.Minute))
+	lead := createBatched(t, db, user, models.EmailTemplateLeadAssigned, now.Add(-3*time.Hour))
+
+	// The hourly digest waits until the oldest notification is an hour old
+	sent, err := service.SendDigests()
+	require.NoError(t, err)
+	assert.Equal(t, 0, sent)
+
+	now = now.Add(30 * time.Minute)
+	sender.fail = true
+	sent, err = service.SendDigests()
+	require.NoError(t, err)
+	assert.Equal(t, 0, sent)
+	// Notifications of a failed digest are batched again
+	assert.Equal(t, models.NotificationStatusBatched, statusOf(t, db, todo.ID))
+
+	sender.fail = false
+	sent, err = service.SendDigests()
+	require.NoError(t, err)
+	assert.Equal(t, 1, sent)
+	require.Len(t, sender.digests, 1)
+	require.Len(t, sender.digests[0], 1)
+	assert.Equal(t, todo.ID, sender.digests[0][0].ID)
+	assert.Equal(t, models.NotificationStatusSent, statusOf(t, db, todo.ID))
+	assert.Equal(t, models.NotificationStatusBatched, statusOf(t, db, lead.ID))
+
+	// The daily digest is sent from the send hour, once a day
+	now = time.Date(2026, 3, 10, 6, 0, 0, 0, time.UTC)
+	sent, err = service.SendDigests()
+	require.NoError(t, err)
+	assert.Equal(t, 1, sent)
+	require.Len(t, sender.digests, 2)
+	assert.Equal(t, lead.ID, sender.digests[1][0].ID)
+
+	createBatched(t, db, user, models.EmailTemplateContactForm, now)
+	now = now.Add(2 * time.Hour)
+	sent, err = service.SendDigests()
+	require.NoError(t, err)
+	assert.Equal(t, 0, sent)
+
+	// Categories switched back to immediate go out with the next run
+	_, err = service.SetModes(user.ID, Modes{models.NotificationCategoryLead: models.DigestModeImmediate})
+	require.NoError(t, err)
+	sent, err = service.SendDigests()
+	require.NoError(t, err)
+	assert.Equal(t, 1, sent)
+}
+
+func setupTestService(t *testing.T) (*Service, *gorm.DB, *fakeSender) {
+	t.Helper()
+	db := testutils.NewSQLiteDB(t,
+		&models.User{},
+		&models.Notification{},
+		&models.NotificationPreference{},
+	)
+
+	cfg := &config.Config{Digest: config.DigestConfig{SendHour: 7}}
+	sender := &fakeSender{}
+	return NewService(db, zap.NewNop(), cfg, sender), db, sender
+}
+
+func createBatched(t *testing.T, db *gorm.DB, user *models.User, template models.EmailTemplate, createdAt time.Time) *models.Notification {
+	t.Helper()
+	notification := &models.Notification{
+		UserID:    user.ID,
+		Type:      models.NotificationTypeEmail,
+		Status:    models.NotificationStatusBatched,
+		Title:     "Neue Benachrichtigung",
+		Message:   "Es gibt Neuigkeiten.",
+		Template:  string(template),
+		Recipient: user.Email,
+		CreatedAt: createdAt,
+	}
+	require.NoError(t, db.Create(notification).Error)
+	return notification
+}
+
+func statusOf(t *testing.T, db *gorm.DB, id uuid.UUID) models.NotificationStatus {
+	t.Helper()
+	var notification models.Notification
+	require.NoError(t, db.First(&notification, "id = ?", id).Error)
+	return notification.Status
+}
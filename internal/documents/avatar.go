@@ -0,0 +1,122 @@
+package documents
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"os"
+	"path/filepath"
+
+	"elterngeld-portal/internal/models"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	// avatarSize is the edge length of stored avatars in pixels
+	avatarSize = 256
+	// maxAvatarPixels bounds the decoded size of uploads, so a small file
+	// cannot expand into an image too large to hold in memory
+	maxAvatarPixels = 40_000_000
+)
+
+// SaveAvatar stores an uploaded image as the user's avatar and points their
+// photo URL at it. The image is cropped to a square and re-encoded as JPEG,
+// which also drops any metadata of the original.
+func (s *Service) SaveAvatar(user *models.User, data []byte) error {
+	dimensions, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidAvatar, err)
+	}
+	if dimensions.Width*dimensions.Height > maxAvatarPixels {
+		return fmt.Errorf("%w: %dx%d pixels", ErrInvalidAvatar, dimensions.Width, dimensions.Height)
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidAvatar, err)
+	}
+	avatar, err := encodeJPEG(scaleToFit(cropSquare(src), avatarSize), 85)
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Join(s.uploadPath, "avatars")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create avatar directory: %w", err)
+	}
+	path := filepath.Join(dir, user.ID.String()+".jpg")
+	if err := os.WriteFile(path, avatar, 0o644); err != nil {
+		return fmt.Errorf("failed to store avatar: %w", err)
+	}
+
+	// The version parameter makes clients and email providers fetch a replaced avatar
+	photoURL := fmt.Sprintf("%s/files/avatars/%s?v=%d", s.baseURL, user.ID, s.now().Unix())
+	if err := s.db.Model(user).Updates(map[string]interface{}{
+		"avatar_path": path,
+		"photo_url":   photoURL,
+	}).Error; err != nil {
+		return fmt.Errorf("failed to update user: %w", err)
+	}
+	user.AvatarPath, user.PhotoURL = path, photoURL
+
+	s.logger.Info("Avatar uploaded", zap.String("user_id", user.ID.String()))
+	return nil
+}
+
+// Avatar returns the JPEG avatar a user uploaded
+func (s *Service) Avatar(userID uuid.UUID) ([]byte, error) {
+	var user models.User
+	if err := s.db.Select("id", "avatar_path").First(&user, "id = ?", userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAvatarNotFound
+		}
+		return nil, err
+	}
+	if user.AvatarPath == "" {
+		return nil, ErrAvatarNotFound
+	}
+
+	data, err := os.ReadFile(user.AvatarPath)
+	if os.IsNotExist(err) {
+		return nil, ErrAvatarNotFound
+	}
+	return data, err
+}
+
+// DeleteAvatar removes the user's avatar and the photo URL pointing at it
+func (s *Service) DeleteAvatar(user *models.User) error {
+	if user.AvatarPath == "" {
+		return ErrAvatarNotFound
+	}
+	if err := os.Remove(user.AvatarPath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove avatar: %w", err)
+	}
+	if err := s.db.Model(user).Updates(map[string]interface{}{
+		"avatar_path": "",
+		"photo_url":   "",
+	}).Error; err != nil {
+		return fmt.Errorf("failed to update user: %w", err)
+	}
+	user.AvatarPath, user.PhotoURL = "", ""
+	return nil
+}
+
+// cropSquare cuts the centered square out of an image
+func cropSquare(src image.Image) image.Image {
+	bounds := src.Bounds()
+	side := min(bounds.Dx(), bounds.Dy())
+	x0 := bounds.Min.X + (bounds.Dx()-side)/2
+	y0 := bounds.Min.Y + (bounds.Dy()-side)/2
+
+	sub, ok := src.(interface {
+		SubImage(r image.Rectangle) image.Image
+	})
+	if !ok {
+		return src
+	}
+	return sub.SubImage(image.Rect(x0, y0, x0+side, y0+side))
+}
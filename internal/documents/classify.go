@@ -0,0 +1,274 @@
+package documents
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"elterngeld-portal/internal/models"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	// minClassificationScore is the keyword weight a text needs to be classified at all
+	minClassificationScore = 4.0
+	// confidentScore is the keyword weight from which a classification is certain
+	confidentScore = 10.0
+	// autoApplyConfidence is the confidence from which the recognized type replaces an
+	// unspecified document type and the document is matched to open todos
+	autoApplyConfidence = 0.6
+)
+
+// Classification is the document type recognized from a document's text
+type Classification struct {
+	Type       models.DocumentType `json:"type"`
+	Confidence float64             `json:"confidence"`
+	Keywords   []string            `json:"keywords"`
+}
+
+// ProcessResult is the outcome of recognizing and classifying a document
+type ProcessResult struct {
+	Document       *models.Document `json:"-"`
+	Classification Classification   `json:"classification"`
+	Todo           *models.Todo     `json:"todo,omitempty"`
+}
+
+type keyword struct {
+	term   string
+	weight float64
+}
+
+// classRule describes how a document type is recognized and which todos ask for it.
+// Terms are matched against normalized text: lower case with umlauts written out.
+type classRule struct {
+	documentType models.DocumentType
+	keywords     []keyword
+	todoTerms    []string
+}
+
+var classRules = []classRule{
+	{
+		documentType: models.DocumentTypePayslip,
+		keywords: []keyword{
+			{"gehaltsabrechnung", 5}, {"entgeltabrechnung", 5}, {"lohnabrechnung", 5},
+			{"verdienstabrechnung", 5}, {"bezuegeabrechnung", 5}, {"gesamtbrutto", 2},
+			{"bruttobezuege", 2}, {"nettobezuege", 2}, {"nettoverdienst", 2},
+			{"auszahlungsbetrag", 2}, {"personalnummer", 2}, {"steuerklasse", 1},
+			{"lohnsteuer", 1}, {"solidaritaetszuschlag", 1},
+		},
+		todoTerms: []string{"gehaltsabrechnung", "lohnabrechnung", "entgeltabrechnung",
+			"verdienstabrechnung", "gehaltsnachweis", "einkommensnachweis"},
+	},
+	{
+		documentType: models.DocumentTypeBirthCertificate,
+		keywords: []keyword{
+			{"geburtsurkunde", 5}, {"geburtsbescheinigung", 5}, {"geburtsregister", 3},
+			{"standesamt", 2}, {"geburtsort", 1}, {"geboren am", 1}, {"name des kindes", 1},
+		},
+		todoTerms: []string{"geburtsurkunde", "geburtsbescheinigung"},
+	},
+	{
+		documentType: models.DocumentTypeTaxAssessment,
+		keywords: []keyword{
+			{"einkommensteuerbescheid", 5}, {"steuerbescheid", 4}, {"zu versteuerndes einkommen", 3},
+			{"finanzamt", 2}, {"festsetzung", 2}, {"veranlagungszeitraum", 2},
+			{"steuernummer", 1}, {"einkuenfte aus", 1},
+		},
+		todoTerms: []string{"steuerbescheid"},
+	},
+	{
+		documentType: models.DocumentTypeHealthInsurance,
+		keywords: []keyword{
+			{"mutterschaftsgeld", 4}, {"krankenkasse", 3}, {"mitgliedsbescheinigung", 3},
+			{"krankenversicherung", 2}, {"versichertennummer", 2}, {"mutterschutzfrist", 2},
+			{"bescheinigung ueber", 1},
+		},
+		todoTerms: []string{"krankenkasse", "mutterschaftsgeld"},
+	},
+}
+
+// Classify recognizes the document type from a document's text by weighted keywords.
+// Texts that match no type well enough are classified as sonstiges without confidence.
+func Classify(text string) Classification {
+	normalized := normalizeText(text)
+
+	var best, second float64
+	result := Classification{Type: models.DocumentTypeOther, Keywords: []string{}}
+	for _, rule := range classRules {
+		var score float64
+		matched := []string{}
+		for _, kw := range rule.keywords {
+			if strings.Contains(normalized, kw.term) {
+				score += kw.weight
+				matched = append(matched, kw.term)
+			}
+		}
+
+		if score > best {
+			best, second = score, best
+			result.Type, result.Keywords = rule.documentType, matched
+		} else if score > second {
+			second = score
+		}
+	}
+
+	if best < minClassificationScore {
+		return Classification{Type: models.DocumentTypeOther, Keywords: []string{}}
+	}
+
+	result.Confidence = min(best/confidentScore, 1)
+	// Texts that read like two document types at once are left to a Berater
+	if second >= best*0.75 {
+		result.Confidence /= 2
+	}
+	return result
+}
+
+// Process recognizes the text of a document, classifies it and, if the classification
+// is confident, assigns the document to the open todo of its lead asking for it
+func (s *Service) Process(documentID uuid.UUID) (*ProcessResult, error) {
+	var doc models.Document
+	if err := s.db.First(&doc, "id = ?", documentID).Error; err != nil {
+		return nil, err
+	}
+
+	text, err := s.recognizeText(&doc)
+	if err != nil {
+		status := models.OCRStatusFailed
+		if errors.Is(err, ErrOCRUnavailable) {
+			status = models.OCRStatusUnavailable
+		}
+		if updateErr := s.db.Model(&doc).Update("ocr_status", status).Error; updateErr != nil {
+			s.logger.Warn("Failed to store OCR status", zap.String("document_id", doc.ID.String()), zap.Error(updateErr))
+		}
+		return nil, err
+	}
+
+	result := &ProcessResult{Document: &doc, Classification: Classify(text)}
+	confident := result.Classification.Confidence >= autoApplyConfidence
+
+	updates := map[string]interface{}{
+		"ocr_status":                models.OCRStatusCompleted,
+		"ocr_text":                  text,
+		"classified_type":           result.Classification.Type,
+		"classification_confidence": result.Classification.Confidence,
+		"updated_at":                s.now(),
+	}
+	// A type chosen by the uploader is never overridden
+	if confident && doc.DocumentType == models.DocumentTypeOther {
+		updates["document_type"] = result.Classification.Type
+	}
+	if err := s.db.Model(&doc).Updates(updates).Error; err != nil {
+		return nil, fmt.Errorf("failed to store classification: %w", err)
+	}
+	doc.OCRStatus = models.OCRStatusCompleted
+	doc.ClassifiedType = result.Classification.Type
+	doc.ClassificationConfidence = result.Classification.Confidence
+	if documentType, ok := updates["document_type"]; ok {
+		doc.DocumentType = documentType.(models.DocumentType)
+	}
+
+	if confident && doc.TodoID == nil {
+		todo, err := s.matchTodo(&doc, result.Classification.Type)
+		if err != nil {
+			return nil, err
+		}
+		if todo != nil {
+			if err := s.assignTodo(&doc, todo); err != nil {
+				return nil, err
+			}
+			result.Todo = todo
+		}
+	}
+
+	s.logger.Info("Document classified",
+		zap.String("document_id", doc.ID.String()),
+		zap.String("classified_type", string(result.Classification.Type)),
+		zap.Float64("confidence", result.Classification.Confidence),
+		zap.Bool("matched_todo", result.Todo != nil))
+
+	return result, nil
+}
+
+// matchTodo finds the most urgent open todo of the document's lead or customer that
+// asks for a document of the given type
+func (s *Service) matchTodo(doc *models.Document, documentType models.DocumentType) (*models.Todo, error) {
+	var rule *classRule
+	for i := range classRules {
+		if classRules[i].documentType == documentType {
+			rule = &classRules[i]
+		}
+	}
+	if rule == nil {
+		return nil, nil
+	}
+
+	var todos []models.Todo
+	if err := s.db.Where("is_completed = ? AND (lead_id = ? OR user_id = ?)", false, doc.LeadID, doc.UserID).
+		Order("due_date IS NULL, due_date ASC, created_at ASC").
+		Find(&todos).Error; err != nil {
+		return nil, fmt.Errorf("failed to load todos: %w", err)
+	}
+
+	for i := range todos {
+		text := normalizeText(todos[i].Title + " " + todos[i].Description)
+		for _, term := range rule.todoTerms {
+			if strings.Contains(text, term) {
+				return &todos[i], nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+// assignTodo links the document to the todo and completes it
+func (s *Service) assignTodo(doc *models.Document, todo *models.Todo) error {
+	now := s.now()
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(doc).Update("todo_id", todo.ID).Error; err != nil {
+			return err
+		}
+		doc.TodoID = &todo.ID
+		if err := tx.Model(todo).Updates(map[string]interface{}{
+			"is_completed": true,
+			"completed_at": now,
+			"updated_at":   now,
+		}).Error; err != nil {
+			return err
+		}
+
+		leadID := doc.LeadID
+		if todo.LeadID != nil {
+			leadID = *todo.LeadID
+		}
+		if leadID == uuid.Nil {
+			return nil
+		}
+		return tx.Create(models.CreateDocumentMatchedActivity(leadID, doc.ID, doc.OriginalName,
+			doc.ClassifiedType, todo.Title)).Error
+	})
+}
+
+// normalizeText lowercases text, writes out umlauts and collapses everything that is
+// not a letter or digit into single spaces, so keywords match regardless of OCR layout
+func normalizeText(text string) string {
+	text = strings.NewReplacer("ä", "ae", "ö", "oe", "ü", "ue", "ß", "ss").Replace(strings.ToLower(text))
+
+	var buf strings.Builder
+	space := true
+	for _, r := range text {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			buf.WriteRune(r)
+			space = false
+		} else if !space {
+			buf.WriteByte(' ')
+			space = true
+		}
+	}
+	return strings.TrimSpace(buf.String())
+}
@@ -0,0 +1,160 @@
+package documents
+
+import (
+	"image/color"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"elterngeld-portal/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+const payslipText = `Max Mustermann GmbH
+Entgeltabrechnung für März 2025
+Personalnummer 4711   Steuerklasse IV
+Gesamtbrutto 3.850,00   Lohnsteuer 512,33   Solidaritätszuschlag 0,00
+Auszahlungsbetrag 2.541,17`
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name      string
+		text      string
+		expected  models.DocumentType
+		confident bool
+	}{
+		{"payslip", payslipText, models.DocumentTypePayslip, true},
+		{"birth certificate", "Standesamt München\nGEBURTSURKUNDE\n(Geburtsregister Nr. 1234/2025)\nGeburtsort: München", models.DocumentTypeBirthCertificate, true},
+		{"tax assessment", "Finanzamt Köln-Ost\nBescheid für 2024 über Einkommensteuer\nFestsetzung\nZu versteuerndes Einkommen 48.211 €", models.DocumentTypeTaxAssessment, true},
+		{"health insurance", "Techniker Krankenkasse\nBescheinigung über Mutterschaftsgeld\nVersichertennummer A123456789", models.DocumentTypeHealthInsurance, true},
+		{"ocr noise", "Entgelt-\nabrechnung  ·  PERSONAL NUMMER", models.DocumentTypeOther, false},
+		{"unrelated", "Mietvertrag zwischen den Parteien", models.DocumentTypeOther, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			classification := Classify(tt.text)
+			assert.Equal(t, tt.expected, classification.Type)
+			assert.Equal(t, tt.confident, classification.Confidence >= autoApplyConfidence,
+				"confidence %.2f", classification.Confidence)
+		})
+	}
+}
+
+func TestClassify_AmbiguousTextIsNotConfident(t *testing.T) {
+	classification := Classify("Lohnabrechnung\nKrankenkasse Mitgliedsbescheinigung\nGesamtbrutto")
+
+	assert.Equal(t, models.DocumentTypePayslip, classification.Type)
+	assert.Less(t, classification.Confidence, autoApplyConfidence)
+}
+
+func TestProcess_ClassifiesAndCompletesTodo(t *testing.T) {
+	db, service := setupTestService(t)
+	require.NoError(t, db.AutoMigrate(&models.Todo{}, &models.Activity{}))
+	service.ocrEngine = fakeOCREngine(t, payslipText)
+
+	doc := createTestDocument(t, db, writePNG(t, 200, 100, color.White), "image/png", models.DocumentTypeOther)
+	dueSoon := time.Now().AddDate(0, 0, 3)
+	otherTodo := createTestTodo(t, db, doc, "Geburtsurkunde hochladen", nil)
+	laterTodo := createTestTodo(t, db, doc, "Weitere Gehaltsabrechnungen nachreichen", nil)
+	todo := createTestTodo(t, db, doc, "Letzte 12 Gehaltsabrechnungen hochladen", &dueSoon)
+
+	result, err := service.Process(doc.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.DocumentTypePayslip, result.Classification.Type)
+	require.NotNil(t, result.Todo)
+	assert.Equal(t, todo.ID, result.Todo.ID)
+
+	var stored models.Document
+	require.NoError(t, db.First(&stored, "id = ?", doc.ID).Error)
+	assert.Equal(t, models.OCRStatusCompleted, stored.OCRStatus)
+	assert.Equal(t, models.DocumentTypePayslip, stored.DocumentType)
+	assert.Equal(t, models.DocumentTypePayslip, stored.ClassifiedType)
+	assert.Contains(t, stored.OCRText, "Entgeltabrechnung")
+	require.NotNil(t, stored.TodoID)
+	assert.Equal(t, todo.ID, *stored.TodoID)
+
+	var completed models.Todo
+	require.NoError(t, db.First(&completed, "id = ?", todo.ID).Error)
+	assert.True(t, completed.IsCompleted)
+	assert.NotNil(t, completed.CompletedAt)
+
+	for _, open := range []*models.Todo{otherTodo, laterTodo} {
+		var reloaded models.Todo
+		require.NoError(t, db.First(&reloaded, "id = ?", open.ID).Error)
+		assert.False(t, reloaded.IsCompleted, reloaded.Title)
+	}
+
+	var activityCount int64
+	db.Model(&models.Activity{}).Where("lead_id = ? AND type = ?", doc.LeadID, models.ActivityTypeDocumentMatched).Count(&activityCount)
+	assert.Equal(t, int64(1), activityCount)
+}
+
+func TestProcess_KeepsUploaderTypeAndSkipsUncertainMatches(t *testing.T) {
+	db, service := setupTestService(t)
+	require.NoError(t, db.AutoMigrate(&models.Todo{}, &models.Activity{}))
+	service.ocrEngine = fakeOCREngine(t, "Gehaltsabrechnung")
+
+	doc := createTestDocument(t, db, writePNG(t, 200, 100, color.White), "image/png", models.DocumentTypeIncomeProof)
+	todo := createTestTodo(t, db, doc, "Gehaltsabrechnung hochladen", nil)
+
+	result, err := service.Process(doc.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.DocumentTypePayslip, result.Classification.Type)
+	assert.Less(t, result.Classification.Confidence, autoApplyConfidence)
+	assert.Nil(t, result.Todo)
+
+	var stored models.Document
+	require.NoError(t, db.First(&stored, "id = ?", doc.ID).Error)
+	assert.Equal(t, models.DocumentTypeIncomeProof, stored.DocumentType)
+	assert.Nil(t, stored.TodoID)
+
+	var reloaded models.Todo
+	require.NoError(t, db.First(&reloaded, "id = ?", todo.ID).Error)
+	assert.False(t, reloaded.IsCompleted)
+}
+
+func TestProcess_OCRUnavailable(t *testing.T) {
+	db, service := setupTestService(t)
+	service.ocrEngine = "missing-ocr-engine"
+
+	doc := createTestDocument(t, db, writePNG(t, 200, 100, color.White), "image/png", models.DocumentTypeOther)
+
+	_, err := service.Process(doc.ID)
+	assert.ErrorIs(t, err, ErrOCRUnavailable)
+
+	var stored models.Document
+	require.NoError(t, db.First(&stored, "id = ?", doc.ID).Error)
+	assert.Equal(t, models.OCRStatusUnavailable, stored.OCRStatus)
+}
+
+// fakeOCREngine writes an executable that prints the given text like tesseract does
+// with the stdout output base
+func fakeOCREngine(t *testing.T, text string) string {
+	dir := t.TempDir()
+	textPath := filepath.Join(dir, "text.txt")
+	require.NoError(t, os.WriteFile(textPath, []byte(text), 0o644))
+
+	script := filepath.Join(dir, "tesseract")
+	require.NoError(t, os.WriteFile(script, []byte("#!/bin/sh\ncat '"+textPath+"'\n"), 0o755))
+	return script
+}
+
+func createTestTodo(t *testing.T, db *gorm.DB, doc *models.Document, title string, dueDate *time.Time) *models.Todo {
+	leadID := doc.LeadID
+	todo := &models.Todo{
+		ID:        uuid.New(),
+		LeadID:    &leadID,
+		UserID:    doc.UserID,
+		CreatedBy: uuid.New(),
+		Title:     title,
+		DueDate:   dueDate,
+	}
+	require.NoError(t, db.Create(todo).Error)
+	return todo
+}
@@ -0,0 +1,106 @@
+package documents
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"elterngeld-portal/internal/models"
+)
+
+const (
+	// minTextLetters is the number of letters below which a PDF is treated as a scan
+	// without text layer and run through OCR instead
+	minTextLetters = 50
+	// ocrPages bounds the pages recognized per document; the first pages identify it
+	ocrPages = 3
+)
+
+// recognizeText returns the text of a document. Digital PDFs are read directly,
+// scans and images are recognized with the configured OCR engine.
+func (s *Service) recognizeText(doc *models.Document) (string, error) {
+	switch {
+	case doc.IsPDF():
+		return s.extractPDFText(doc.FilePath)
+	case isDecodableImage(doc.ContentType):
+		return s.ocr(doc.FilePath)
+	default:
+		return "", ErrOCRUnavailable
+	}
+}
+
+func (s *Service) extractPDFText(path string) (string, error) {
+	if extractor, err := lookupTool(s.pdfText, ErrOCRUnavailable); err == nil {
+		text, err := toolOutput(extractor, "-layout", "-l", strconv.Itoa(ocrPages), path, "-")
+		if err != nil {
+			return "", err
+		}
+		if countLetters(string(text)) >= minTextLetters {
+			return string(text), nil
+		}
+	}
+
+	renderer, err := lookupTool(s.pdfRenderer, ErrOCRUnavailable)
+	if err != nil {
+		return "", err
+	}
+
+	dir, err := os.MkdirTemp("", "document-ocr-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(dir)
+
+	if err := runTool(renderer, "-png", "-r", "300", "-f", "1", "-l", strconv.Itoa(ocrPages),
+		path, filepath.Join(dir, "page")); err != nil {
+		return "", err
+	}
+
+	pages, err := filepath.Glob(filepath.Join(dir, "page*.png"))
+	if err != nil {
+		return "", err
+	}
+	sort.Strings(pages)
+
+	var text strings.Builder
+	for _, page := range pages {
+		pageText, err := s.ocr(page)
+		if err != nil {
+			return "", err
+		}
+		text.WriteString(pageText)
+		text.WriteString("\n")
+	}
+	return text.String(), nil
+}
+
+func (s *Service) ocr(path string) (string, error) {
+	engine, err := lookupTool(s.ocrEngine, ErrOCRUnavailable)
+	if err != nil {
+		return "", err
+	}
+
+	args := []string{path, "stdout"}
+	if s.ocrLanguage != "" {
+		args = append(args, "-l", s.ocrLanguage)
+	}
+
+	text, err := toolOutput(engine, args...)
+	if err != nil {
+		return "", err
+	}
+	return string(text), nil
+}
+
+func countLetters(text string) int {
+	count := 0
+	for _, r := range text {
+		if unicode.IsLetter(r) {
+			count++
+		}
+	}
+	return count
+}
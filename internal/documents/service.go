@@ -0,0 +1,244 @@
+package documents
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"elterngeld-portal/config"
+	"elterngeld-portal/internal/models"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrInvalidSignature is returned when a signed document link was tampered with
+	ErrInvalidSignature = errors.New("invalid document link signature")
+	// ErrLinkExpired is returned when a signed document link is past its expiry
+	ErrLinkExpired = errors.New("document link expired")
+	// ErrPreviewUnavailable is returned when no preview can be rendered for a document
+	ErrPreviewUnavailable = errors.New("document preview unavailable")
+	// ErrWatermarkUnsupported is returned when a sensitive document's format cannot be stamped
+	ErrWatermarkUnsupported = errors.New("document format cannot be watermarked")
+	// ErrOCRUnavailable is returned when no text can be recognized for a document
+	ErrOCRUnavailable = errors.New("text recognition unavailable")
+	// ErrInvalidAvatar is returned when an uploaded avatar is not a supported image
+	ErrInvalidAvatar = errors.New("avatar is not a supported image")
+	// ErrAvatarNotFound is returned when a user has not uploaded an avatar
+	ErrAvatarNotFound = errors.New("avatar not found")
+	// ErrDocumentNotFound is returned when the document does not exist or the viewer may not see it
+	ErrDocumentNotFound = errors.New("document not found")
+	// ErrInvalidVisibility is returned for unknown visibilities and those the viewer may not choose
+	ErrInvalidVisibility = errors.New("invalid document visibility")
+	// ErrSharingNotAllowed is returned when the viewer may not change who sees the document
+	ErrSharingNotAllowed = errors.New("sharing of the document not allowed")
+	// ErrAlreadyShared is returned when a shared document is shared again
+	ErrAlreadyShared = errors.New("document already shared")
+)
+
+// Variant is the representation of a document a signed link grants access to
+type Variant string
+
+const (
+	VariantPreview  Variant = "preview"
+	VariantDownload Variant = "download"
+)
+
+// IsValid checks if the variant is known
+func (v Variant) IsValid() bool {
+	return v == VariantPreview || v == VariantDownload
+}
+
+// SignedLinks are the short-lived URLs a viewer can open a document with
+type SignedLinks struct {
+	PreviewURL  string    `json:"preview_url,omitempty"`
+	DownloadURL string    `json:"download_url"`
+	Watermarked bool      `json:"watermarked"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// Content is a rendered document ready to be delivered to a viewer
+type Content struct {
+	Data        []byte
+	ContentType string
+	FileName    string
+}
+
+// Service renders document previews and watermarked downloads, signs the short-lived
+// links they are delivered through, classifies uploads by their recognized text
+// and controls who a document is shared with
+type Service struct {
+	db            *gorm.DB
+	logger        *zap.Logger
+	baseURL       string
+	secret        []byte
+	expiry        time.Duration
+	uploadPath    string
+	thumbnailSize int
+	pdfRenderer   string
+	pdfStamper    string
+	pdfText       string
+	ocrEngine     string
+	ocrLanguage   string
+	now           func() time.Time
+}
+
+func NewService(db *gorm.DB, logger *zap.Logger, cfg *config.Config) *Service {
+	secret := cfg.Upload.SigningSecret
+	if secret == "" {
+		secret = cfg.JWT.Secret
+	}
+	expiry := cfg.Upload.SignedURLExpiry
+	if expiry <= 0 {
+		expiry = 5 * time.Minute
+	}
+	thumbnailSize := cfg.Upload.ThumbnailSize
+	if thumbnailSize <= 0 {
+		thumbnailSize = 320
+	}
+
+	return &Service{
+		db:            db,
+		logger:        logger,
+		baseURL:       strings.TrimRight(cfg.App.BaseURL, "/"),
+		secret:        []byte(secret),
+		expiry:        expiry,
+		uploadPath:    cfg.Upload.Path,
+		thumbnailSize: thumbnailSize,
+		pdfRenderer:   cfg.Upload.PDFRenderer,
+		pdfStamper:    cfg.Upload.PDFStamper,
+		pdfText:       cfg.Upload.PDFText,
+		ocrEngine:     cfg.Upload.OCREngine,
+		ocrLanguage:   cfg.Upload.OCRLanguage,
+		now:           time.Now,
+	}
+}
+
+// CanPreview checks if a thumbnail can be rendered for the document
+func CanPreview(doc *models.Document) bool {
+	return doc.IsPDF() || isDecodableImage(doc.ContentType)
+}
+
+// Links signs preview and download URLs of a document for a viewer
+func (s *Service) Links(doc *models.Document, viewerID uuid.UUID) SignedLinks {
+	expiresAt := s.now().Add(s.expiry).Truncate(time.Second)
+
+	links := SignedLinks{
+		DownloadURL: s.url(doc.ID, VariantDownload, viewerID, expiresAt),
+		Watermarked: doc.RequiresWatermark(),
+		ExpiresAt:   expiresAt,
+	}
+	if CanPreview(doc) {
+		links.PreviewURL = s.url(doc.ID, VariantPreview, viewerID, expiresAt)
+	}
+	return links
+}
+
+// Verify checks the signature and expiry of a signed document link
+func (s *Service) Verify(documentID uuid.UUID, variant Variant, viewerID uuid.UUID, expires int64, signature string) error {
+	if !variant.IsValid() || !s.validSignature(documentID, variant, viewerID, expires, signature) {
+		return ErrInvalidSignature
+	}
+	if s.now().Unix() > expires {
+		return ErrLinkExpired
+	}
+	return nil
+}
+
+// Thumbnail returns a JPEG thumbnail of the document. Thumbnails are rendered on
+// first access and cached next to the uploads.
+func (s *Service) Thumbnail(doc *models.Document) ([]byte, error) {
+	if doc.ThumbnailPath != "" {
+		if data, err := os.ReadFile(doc.ThumbnailPath); err == nil {
+			return data, nil
+		}
+	}
+
+	var (
+		data []byte
+		err  error
+	)
+	switch {
+	case isDecodableImage(doc.ContentType):
+		data, err = s.imageThumbnail(doc.FilePath)
+	case doc.IsPDF():
+		data, err = s.pdfThumbnail(doc.FilePath)
+	default:
+		return nil, ErrPreviewUnavailable
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s.cacheThumbnail(doc, data)
+	return data, nil
+}
+
+// Open returns the document content for a viewer. Sensitive documents are stamped
+// with the viewer's name and the time of the download.
+func (s *Service) Open(doc *models.Document, viewer *models.User) (*Content, error) {
+	data, err := os.ReadFile(doc.FilePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read document: %w", err)
+	}
+
+	content := &Content{
+		Data:        data,
+		ContentType: doc.ContentType,
+		FileName:    doc.OriginalName,
+	}
+	if !doc.RequiresWatermark() {
+		return content, nil
+	}
+
+	text := WatermarkText(viewer, s.now())
+	switch {
+	case doc.IsPDF():
+		content.Data, err = s.watermarkPDF(data, text)
+	case isDecodableImage(doc.ContentType):
+		content.Data, content.ContentType, err = watermarkImage(data, text)
+	default:
+		// Sensitive documents never leave the portal without a stamp
+		err = ErrWatermarkUnsupported
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Watermarked document delivered",
+		zap.String("document_id", doc.ID.String()),
+		zap.String("viewer_id", viewer.ID.String()))
+
+	return content, nil
+}
+
+// WatermarkText returns the stamp identifying a viewer and the time of a download
+func WatermarkText(viewer *models.User, at time.Time) string {
+	return fmt.Sprintf("%s - %s", viewer.FullName(), at.In(viewer.TimeLocation()).Format("02.01.2006 15:04"))
+}
+
+func (s *Service) cacheThumbnail(doc *models.Document, data []byte) {
+	dir := filepath.Join(s.uploadPath, "thumbnails")
+	path := filepath.Join(dir, doc.ID.String()+".jpg")
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		s.logger.Warn("Failed to create thumbnail directory", zap.Error(err))
+		return
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		s.logger.Warn("Failed to cache thumbnail", zap.Error(err))
+		return
+	}
+
+	doc.ThumbnailPath = path
+	if err := s.db.Model(doc).Update("thumbnail_path", path).Error; err != nil {
+		s.logger.Warn("Failed to store thumbnail path",
+			zap.String("document_id", doc.ID.String()),
+			zap.Error(err))
+	}
+}
@@ -0,0 +1,378 @@
+package documents
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"elterngeld-portal/config"
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/internal/scopes"
+	"elterngeld-portal/tests/testutils"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+func TestLinks_VerifySignature(t *testing.T) {
+	_, service := setupTestService(t)
+	now := time.Date(2025, 3, 10, 9, 0, 0, 0, time.UTC)
+	service.now = func() time.Time { return now }
+
+	doc := &models.Document{ID: uuid.New(), ContentType: "application/pdf", DocumentType: models.DocumentTypeBescheid}
+	viewerID := uuid.New()
+
+	links := service.Links(doc, viewerID)
+	assert.True(t, links.Watermarked)
+	assert.Equal(t, now.Add(5*time.Minute), links.ExpiresAt)
+	assert.True(t, strings.HasPrefix(links.DownloadURL, "https://portal.example.com/files/documents/"+doc.ID.String()+"/download?"))
+	require.NotEmpty(t, links.PreviewURL)
+
+	variant, viewer, expires, signature := parseLink(t, links.DownloadURL)
+	assert.Equal(t, VariantDownload, variant)
+	assert.Equal(t, viewerID, viewer)
+	assert.NoError(t, service.Verify(doc.ID, variant, viewer, expires, signature))
+
+	// A download signature must not unlock other variants, viewers or documents
+	assert.ErrorIs(t, service.Verify(doc.ID, VariantPreview, viewer, expires, signature), ErrInvalidSignature)
+	assert.ErrorIs(t, service.Verify(doc.ID, variant, uuid.New(), expires, signature), ErrInvalidSignature)
+	assert.ErrorIs(t, service.Verify(uuid.New(), variant, viewer, expires, signature), ErrInvalidSignature)
+	assert.ErrorIs(t, service.Verify(doc.ID, variant, viewer, expires+3600, signature), ErrInvalidSignature)
+
+	now = now.Add(6 * time.Minute)
+	assert.ErrorIs(t, service.Verify(doc.ID, variant, viewer, expires, signature), ErrLinkExpired)
+}
+
+func TestLinks_NoPreviewForOfficeDocuments(t *testing.T) {
+	_, service := setupTestService(t)
+
+	doc := &models.Document{ID: uuid.New(), ContentType: "application/msword", DocumentType: models.DocumentTypeOther}
+	links := service.Links(doc, uuid.New())
+
+	assert.Empty(t, links.PreviewURL)
+	assert.NotEmpty(t, links.DownloadURL)
+	assert.False(t, links.Watermarked)
+}
+
+func TestThumbnail_ScalesImageAndCaches(t *testing.T) {
+	db, service := setupTestService(t)
+	doc := createTestDocument(t, db, writePNG(t, 800, 400, color.White), "image/png", models.DocumentTypeBirthCertificate)
+
+	data, err := service.Thumbnail(doc)
+	require.NoError(t, err)
+
+	thumbnail, err := jpeg.Decode(bytes.NewReader(data))
+	require.NoError(t, err)
+	assert.Equal(t, 320, thumbnail.Bounds().Dx())
+	assert.Equal(t, 160, thumbnail.Bounds().Dy())
+
+	var stored models.Document
+	require.NoError(t, db.First(&stored, "id = ?", doc.ID).Error)
+	require.NotEmpty(t, stored.ThumbnailPath)
+	assert.FileExists(t, stored.ThumbnailPath)
+
+	// The cached thumbnail is served even when the original is gone
+	require.NoError(t, os.Remove(stored.FilePath))
+	cached, err := service.Thumbnail(&stored)
+	require.NoError(t, err)
+	assert.Equal(t, data, cached)
+}
+
+func TestThumbnail_Unavailable(t *testing.T) {
+	db, service := setupTestService(t)
+	service.pdfRenderer = "missing-pdf-renderer"
+
+	pdf := createTestDocument(t, db, writeFile(t, "antrag.pdf", stampPDF("Antrag")), "application/pdf", models.DocumentTypeApplication)
+	_, err := service.Thumbnail(pdf)
+	assert.ErrorIs(t, err, ErrPreviewUnavailable)
+
+	text := createTestDocument(t, db, writeFile(t, "notiz.txt", []byte("Notiz")), "text/plain", models.DocumentTypeOther)
+	_, err = service.Thumbnail(text)
+	assert.ErrorIs(t, err, ErrPreviewUnavailable)
+}
+
+func TestOpen_WatermarksSensitiveImages(t *testing.T) {
+	db, service := setupTestService(t)
+	viewer := &models.User{ID: uuid.New(), FirstName: "Jürgen", LastName: "Groß", Timezone: "Europe/Berlin"}
+	path := writePNG(t, 600, 300, color.White)
+	original, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	plain := createTestDocument(t, db, path, "image/png", models.DocumentTypeIncomeProof)
+	content, err := service.Open(plain, viewer)
+	require.NoError(t, err)
+	assert.Equal(t, original, content.Data)
+
+	bescheid := createTestDocument(t, db, path, "image/png", models.DocumentTypeBescheid)
+	content, err = service.Open(bescheid, viewer)
+	require.NoError(t, err)
+	assert.Equal(t, "image/png", content.ContentType)
+
+	stamped, err := png.Decode(bytes.NewReader(content.Data))
+	require.NoError(t, err)
+	assert.Equal(t, image.Rect(0, 0, 600, 300), stamped.Bounds())
+	assert.Greater(t, countNonWhite(stamped), 1000)
+}
+
+func TestOpen_RefusesUnstampableSensitiveDocuments(t *testing.T) {
+	db, service := setupTestService(t)
+	viewer := &models.User{ID: uuid.New(), FirstName: "Anna", LastName: "Berater"}
+
+	doc := createTestDocument(t, db, writeFile(t, "bescheid.docx", []byte("docx")), "application/msword", models.DocumentTypeOther)
+	doc.IsSensitive = true
+	_, err := service.Open(doc, viewer)
+	assert.ErrorIs(t, err, ErrWatermarkUnsupported)
+
+	service.pdfStamper = ""
+	pdf := createTestDocument(t, db, writeFile(t, "bescheid.pdf", stampPDF("Bescheid")), "application/pdf", models.DocumentTypeBescheid)
+	_, err = service.Open(pdf, viewer)
+	assert.ErrorIs(t, err, ErrWatermarkUnsupported)
+}
+
+func TestWatermarkText_UsesViewerTimezone(t *testing.T) {
+	viewer := &models.User{FirstName: "Jürgen", LastName: "Groß", Timezone: "Europe/Berlin"}
+	at := time.Date(2025, 7, 1, 12, 30, 0, 0, time.UTC)
+
+	assert.Equal(t, "Jürgen Groß - 01.07.2025 14:30", WatermarkText(viewer, at))
+	assert.Equal(t, "JUERGEN GROSS - 01.07.2025 14:30", string(fontText(WatermarkText(viewer, at))))
+	assert.Equal(t, "?", string(fontText("€")))
+}
+
+func TestStampPDF_ValidCrossReferences(t *testing.T) {
+	pdf := stampPDF("Jürgen (Groß)")
+
+	assert.True(t, bytes.HasPrefix(pdf, []byte("%PDF-1.4\n")))
+	assert.True(t, bytes.HasSuffix(pdf, []byte("%%EOF\n")))
+	assert.Contains(t, string(pdf), `(J\374rgen \(Gro\337\)) Tj`)
+
+	entries := regexp.MustCompile(`(\d{10}) 00000 n`).FindAllStringSubmatch(string(pdf), -1)
+	require.Len(t, entries, 6)
+	for i, entry := range entries {
+		offset, err := strconv.Atoi(entry[1])
+		require.NoError(t, err)
+		assert.True(t, bytes.HasPrefix(pdf[offset:], []byte(fmt.Sprintf("%d 0 obj", i+1))), "object %d", i+1)
+	}
+
+	startxref := regexp.MustCompile(`startxref\n(\d+)`).FindStringSubmatch(string(pdf))
+	require.Len(t, startxref, 2)
+	offset, err := strconv.Atoi(startxref[1])
+	require.NoError(t, err)
+	assert.True(t, bytes.HasPrefix(pdf[offset:], []byte("xref\n")))
+}
+
+func TestSaveAvatar_CropsAndServes(t *testing.T) {
+	db, service := setupTestService(t)
+	require.NoError(t, db.AutoMigrate(&models.User{}))
+	user := &models.User{Email: "anna@example.com", FirstName: "Anna", LastName: "Kunde"}
+	require.NoError(t, db.Create(user).Error)
+
+	data, err := os.ReadFile(writePNG(t, 1200, 600, color.White))
+	require.NoError(t, err)
+	require.NoError(t, service.SaveAvatar(user, data))
+	assert.True(t, strings.HasPrefix(user.PhotoURL, "https://portal.example.com/files/avatars/"+user.ID.String()+"?v="))
+
+	served, err := service.Avatar(user.ID)
+	require.NoError(t, err)
+	avatar, err := jpeg.Decode(bytes.NewReader(served))
+	require.NoError(t, err)
+	assert.Equal(t, 256, avatar.Bounds().Dx())
+	assert.Equal(t, 256, avatar.Bounds().Dy())
+
+	var stored models.User
+	require.NoError(t, db.First(&stored, "id = ?", user.ID).Error)
+	assert.Equal(t, user.PhotoURL, stored.PhotoURL)
+
+	require.NoError(t, service.DeleteAvatar(&stored))
+	assert.NoFileExists(t, user.AvatarPath)
+	_, err = service.Avatar(user.ID)
+	assert.ErrorIs(t, err, ErrAvatarNotFound)
+}
+
+func TestSaveAvatar_RejectsNonImages(t *testing.T) {
+	db, service := setupTestService(t)
+	require.NoError(t, db.AutoMigrate(&models.User{}))
+	user := &models.User{Email: "anna@example.com", FirstName: "Anna", LastName: "Kunde"}
+	require.NoError(t, db.Create(user).Error)
+
+	err := service.SaveAvatar(user, stampPDF("Kein Bild"))
+	assert.ErrorIs(t, err, ErrInvalidAvatar)
+	assert.Empty(t, user.PhotoURL)
+}
+
+func TestShare_NotifiesCustomer(t *testing.T) {
+	db, service := setupTestService(t)
+	customer, berater, lead := setupSharing(t, db)
+
+	internal := createLeadDocument(t, db, lead, berater, models.DefaultDocumentVisibility(berater.Role))
+	require.Equal(t, models.DocumentVisibilityBerater, internal.Visibility)
+
+	doc, err := service.Share(internal.ID, sharingViewer(berater))
+	require.NoError(t, err)
+	assert.Equal(t, models.DocumentVisibilityShared, doc.Visibility)
+	require.NotNil(t, doc.SharedAt)
+	assert.Equal(t, berater.ID, *doc.SharedByID)
+
+	var notifications []models.Notification
+	require.NoError(t, db.Find(&notifications).Error)
+	require.Len(t, notifications, 1)
+	assert.Equal(t, customer.ID, notifications[0].UserID)
+	assert.Contains(t, notifications[0].Message, internal.OriginalName)
+
+	_, err = service.Share(internal.ID, sharingViewer(berater))
+	assert.ErrorIs(t, err, ErrAlreadyShared)
+}
+
+func TestSetVisibility_Permissions(t *testing.T) {
+	db, service := setupTestService(t)
+	customer, berater, lead := setupSharing(t, db)
+	upload := createLeadDocument(t, db, lead, customer, models.DefaultDocumentVisibility(customer.Role))
+	internal := createLeadDocument(t, db, lead, berater, models.DocumentVisibilityBerater)
+
+	// Customers keep their uploads to themselves but cannot make them internal
+	_, err := service.SetVisibility(upload.ID, sharingViewer(customer), models.DocumentVisibilityBerater)
+	assert.ErrorIs(t, err, ErrInvalidVisibility)
+	doc, err := service.SetVisibility(upload.ID, sharingViewer(customer), models.DocumentVisibilityCustomer)
+	require.NoError(t, err)
+	assert.Equal(t, models.DocumentVisibilityCustomer, doc.Visibility)
+
+	// The Berater no longer sees the upload, the customer does not see the working document
+	_, err = service.SetVisibility(upload.ID, sharingViewer(berater), models.DocumentVisibilityShared)
+	assert.ErrorIs(t, err, ErrDocumentNotFound)
+	_, err = service.Share(internal.ID, sharingViewer(customer))
+	assert.ErrorIs(t, err, ErrDocumentNotFound)
+
+	// Sharing the upload again notifies the Berater
+	_, err = service.Share(upload.ID, sharingViewer(customer))
+	require.NoError(t, err)
+	var notification models.Notification
+	require.NoError(t, db.First(&notification).Error)
+	assert.Equal(t, berater.ID, notification.UserID)
+
+	// Customers cannot withdraw documents of the Berater
+	_, err = service.Share(internal.ID, sharingViewer(berater))
+	require.NoError(t, err)
+	_, err = service.SetVisibility(internal.ID, sharingViewer(customer), models.DocumentVisibilityCustomer)
+	assert.ErrorIs(t, err, ErrSharingNotAllowed)
+
+	_, err = service.SetVisibility(internal.ID, sharingViewer(berater), "oeffentlich")
+	assert.ErrorIs(t, err, ErrInvalidVisibility)
+}
+
+func setupTestService(t *testing.T) (*gorm.DB, *Service) {
+	db := testutils.NewSQLiteDB(t, &models.Document{})
+
+	cfg := &config.Config{
+		App:    config.AppConfig{BaseURL: "https://portal.example.com/"},
+		JWT:    config.JWTConfig{Secret: "test-secret"},
+		Upload: config.UploadConfig{Path: t.TempDir()},
+	}
+	return db, NewService(db, zap.NewNop(), cfg)
+}
+
+func createTestDocument(t *testing.T, db *gorm.DB, path, contentType string, documentType models.DocumentType) *models.Document {
+	doc := &models.Document{
+		LeadID:       uuid.New(),
+		UserID:       uuid.New(),
+		FileName:     filepath.Base(path),
+		OriginalName: filepath.Base(path),
+		FilePath:     path,
+		ContentType:  contentType,
+		DocumentType: documentType,
+	}
+	require.NoError(t, db.Create(doc).Error)
+	return doc
+}
+
+func writePNG(t *testing.T, width, height int, background color.Color) string {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(img, img.Bounds(), image.NewUniform(background), image.Point{}, draw.Src)
+
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, img))
+	return writeFile(t, "scan.png", buf.Bytes())
+}
+
+func writeFile(t *testing.T, name string, data []byte) string {
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, data, 0o644))
+	return path
+}
+
+func parseLink(t *testing.T, link string) (Variant, uuid.UUID, int64, string) {
+	parsed, err := url.Parse(link)
+	require.NoError(t, err)
+
+	query := parsed.Query()
+	viewer, err := uuid.Parse(query.Get("viewer"))
+	require.NoError(t, err)
+	expires, err := strconv.ParseInt(query.Get("expires"), 10, 64)
+	require.NoError(t, err)
+
+	return Variant(filepath.Base(parsed.Path)), viewer, expires, query.Get("signature")
+}
+
+func countNonWhite(img image.Image) int {
+	count := 0
+	bounds := img.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			if r, g, b, _ := img.At(x, y).RGBA(); r != 0xffff || g != 0xffff || b != 0xffff {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+func setupSharing(t *testing.T, db *gorm.DB) (customer, berater *models.User, lead *models.Lead) {
+	require.NoError(t, db.AutoMigrate(&models.User{}, &models.Lead{}, &models.FamilyMember{}, &models.Notification{}))
+	customer = &models.User{Email: "anna@example.com", FirstName: "Anna", LastName: "Kunde", Role: models.RoleUser}
+	berater = &models.User{Email: "bernd@example.com", FirstName: "Bernd", LastName: "Berater", Role: models.RoleBerater}
+	require.NoError(t, db.Create(customer).Error)
+	require.NoError(t, db.Create(berater).Error)
+
+	lead = &models.Lead{
+		UserID:    customer.ID,
+		BeraterID: &berater.ID,
+		Title:     "Elterngeld",
+		Status:    models.LeadStatusNew,
+		Priority:  models.PriorityMedium,
+		Source:    models.LeadSourceWebsite,
+	}
+	require.NoError(t, db.Create(lead).Error)
+	return customer, berater, lead
+}
+
+func createLeadDocument(t *testing.T, db *gorm.DB, lead *models.Lead, uploader *models.User, visibility models.DocumentVisibility) *models.Document {
+	doc := &models.Document{
+		LeadID:       lead.ID,
+		UserID:       uploader.ID,
+		FileName:     uuid.NewString() + ".pdf",
+		OriginalName: "Berechnung.pdf",
+		FilePath:     "/tmp/berechnung.pdf",
+		ContentType:  "application/pdf",
+		DocumentType: models.DocumentTypeOther,
+		Visibility:   visibility,
+	}
+	require.NoError(t, db.Create(doc).Error)
+	return doc
+}
+
+func sharingViewer(user *models.User) scopes.Viewer {
+	return scopes.Viewer{ID: user.ID, Role: user.Role}
+}
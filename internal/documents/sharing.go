@@ -0,0 +1,159 @@
+package documents
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/internal/scopes"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// SetVisibility changes who sees a document on its lead. Customers decide on
+// their own uploads whether the Berater sees them, staff whether their
+// working documents are shown to the customers.
+func (s *Service) SetVisibility(documentID uuid.UUID, viewer scopes.Viewer, visibility models.DocumentVisibility) (*models.Document, error) {
+	if !visibility.IsValid() {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidVisibility, visibility)
+	}
+
+	doc, err := s.shareable(documentID, viewer, visibility)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.updateVisibility(doc, viewer, visibility); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// Share shows a document to the other side of the lead and notifies them:
+// the customer when staff share a working document, the Berater when a
+// customer shares an upload they kept to themselves.
+func (s *Service) Share(documentID uuid.UUID, viewer scopes.Viewer) (*models.Document, error) {
+	doc, err := s.shareable(documentID, viewer, models.DocumentVisibilityShared)
+	if err != nil {
+		return nil, err
+	}
+	if doc.Visibility == models.DocumentVisibilityShared {
+		return nil, ErrAlreadyShared
+	}
+	if err := s.updateVisibility(doc, viewer, models.DocumentVisibilityShared); err != nil {
+		return nil, err
+	}
+
+	s.notifyShared(doc, viewer)
+	return doc, nil
+}
+
+// shareable loads a document whose visibility the viewer may change to the
+// given one
+func (s *Service) shareable(documentID uuid.UUID, viewer scopes.Viewer, visibility models.DocumentVisibility) (*models.Document, error) {
+	var doc models.Document
+	if err := s.db.Scopes(scopes.VisibleDocuments(viewer)).First(&doc, "documents.id = ?", documentID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrDocumentNotFound
+		}
+		return nil, fmt.Errorf("failed to load document: %w", err)
+	}
+
+	switch viewer.Role {
+	case models.RoleAdmin:
+		return &doc, nil
+	case models.RoleUser:
+		// Customers cannot hide documents from themselves or withdraw those of the Berater
+		if doc.UserID != viewer.ID {
+			return nil, ErrSharingNotAllowed
+		}
+		if visibility == models.DocumentVisibilityBerater {
+			return nil, fmt.Errorf("%w: customers cannot make documents internal", ErrInvalidVisibility)
+		}
+		return &doc, nil
+	default:
+		if visibility == models.DocumentVisibilityCustomer {
+			return nil, fmt.Errorf("%w: only customers keep documents to themselves", ErrInvalidVisibility)
+		}
+		var editable int64
+		if err := s.db.Model(&models.Lead{}).Scopes(scopes.EditableLeads(viewer)).
+			Where("leads.id = ?", doc.LeadID).Count(&editable).Error; err != nil {
+			return nil, fmt.Errorf("failed to check lead: %w", err)
+		}
+		if editable == 0 {
+			return nil, ErrSharingNotAllowed
+		}
+		return &doc, nil
+	}
+}
+
+// updateVisibility stores the visibility and, when the document becomes
+// shared, who shared it
+func (s *Service) updateVisibility(doc *models.Document, viewer scopes.Viewer, visibility models.DocumentVisibility) error {
+	if doc.Visibility == visibility {
+		return nil
+	}
+
+	updates := map[string]interface{}{"visibility": visibility}
+	if visibility == models.DocumentVisibilityShared {
+		now := s.now()
+		updates["shared_at"] = now
+		updates["shared_by_id"] = viewer.ID
+		doc.SharedAt, doc.SharedByID = &now, &viewer.ID
+	}
+	if err := s.db.Model(doc).Updates(updates).Error; err != nil {
+		return fmt.Errorf("failed to update document: %w", err)
+	}
+	doc.Visibility = visibility
+
+	s.logger.Info("Document visibility changed",
+		zap.String("document_id", doc.ID.String()),
+		zap.String("visibility", string(visibility)),
+		zap.String("changed_by", viewer.ID.String()))
+	return nil
+}
+
+// notifyShared tells the other side of the lead about a shared document
+func (s *Service) notifyShared(doc *models.Document, viewer scopes.Viewer) {
+	var lead models.Lead
+	if err := s.db.Select("id", "user_id", "berater_id", "title").First(&lead, "id = ?", doc.LeadID).Error; err != nil {
+		s.logger.Warn("Failed to load lead of shared document", zap.String("document_id", doc.ID.String()), zap.Error(err))
+		return
+	}
+
+	recipientID := &lead.UserID
+	title := "Neues Dokument von Ihrem Berater"
+	message := fmt.Sprintf("Ihr Berater hat das Dokument '%s' mit Ihnen geteilt.", doc.OriginalName)
+	if viewer.Role == models.RoleUser {
+		recipientID = lead.BeraterID
+		title = "Dokument vom Kunden geteilt"
+		message = fmt.Sprintf("Zum Lead '%s' wurde das Dokument '%s' geteilt.", lead.Title, doc.OriginalName)
+	}
+	if recipientID == nil || *recipientID == viewer.ID {
+		return
+	}
+
+	var recipient models.User
+	if err := s.db.First(&recipient, "id = ?", *recipientID).Error; err != nil {
+		s.logger.Warn("Failed to load recipient of shared document", zap.String("document_id", doc.ID.String()), zap.Error(err))
+		return
+	}
+
+	data, _ := json.Marshal(map[string]interface{}{
+		"document_id": doc.ID,
+		"lead_id":     doc.LeadID,
+	})
+	notification := models.Notification{
+		UserID:    recipient.ID,
+		Type:      models.NotificationTypeInApp,
+		Title:     title,
+		Message:   message,
+		Data:      string(data),
+		Recipient: recipient.NotificationAddress(),
+	}
+	if err := s.db.Create(&notification).Error; err != nil {
+		s.logger.Error("Failed to create shared document notification", zap.String("document_id", doc.ID.String()), zap.Error(err))
+	}
+}
@@ -0,0 +1,36 @@
+package documents
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// url builds a signed link of the form /files/documents/:id/:variant?viewer=&expires=&signature=
+func (s *Service) url(documentID uuid.UUID, variant Variant, viewerID uuid.UUID, expiresAt time.Time) string {
+	expires := expiresAt.Unix()
+
+	query := url.Values{}
+	query.Set("viewer", viewerID.String())
+	query.Set("expires", strconv.FormatInt(expires, 10))
+	query.Set("signature", s.sign(documentID, variant, viewerID, expires))
+
+	return fmt.Sprintf("%s/files/documents/%s/%s?%s", s.baseURL, documentID, variant, query.Encode())
+}
+
+func (s *Service) sign(documentID uuid.UUID, variant Variant, viewerID uuid.UUID, expires int64) string {
+	mac := hmac.New(sha256.New, s.secret)
+	fmt.Fprintf(mac, "%s|%s|%s|%d", documentID, variant, viewerID, expires)
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func (s *Service) validSignature(documentID uuid.UUID, variant Variant, viewerID uuid.UUID, expires int64, signature string) bool {
+	expected := s.sign(documentID, variant, viewerID, expires)
+	return hmac.Equal([]byte(expected), []byte(signature))
+}
@@ -0,0 +1,152 @@
+package documents
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/gif" // registers the GIF decoder
+	"image/jpeg"
+	_ "image/png" // registers the PNG decoder
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// toolTimeout bounds external renderers so a malformed PDF cannot block a request
+const toolTimeout = 30 * time.Second
+
+func isDecodableImage(contentType string) bool {
+	switch contentType {
+	case "image/jpeg", "image/jpg", "image/png", "image/gif":
+		return true
+	default:
+		return false
+	}
+}
+
+func (s *Service) imageThumbnail(path string) ([]byte, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open image: %w", err)
+	}
+	defer file.Close()
+
+	src, _, err := image.Decode(file)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrPreviewUnavailable, err)
+	}
+
+	return encodeJPEG(scaleToFit(src, s.thumbnailSize), 80)
+}
+
+// pdfThumbnail renders the first page of a PDF with the configured poppler pdftoppm binary
+func (s *Service) pdfThumbnail(path string) ([]byte, error) {
+	renderer, err := lookupTool(s.pdfRenderer, ErrPreviewUnavailable)
+	if err != nil {
+		return nil, err
+	}
+
+	dir, err := os.MkdirTemp("", "document-preview-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	out := filepath.Join(dir, "page")
+	if err := runTool(renderer, "-jpeg", "-singlefile", "-f", "1", "-l", "1",
+		"-scale-to", strconv.Itoa(s.thumbnailSize), path, out); err != nil {
+		return nil, err
+	}
+
+	return os.ReadFile(out + ".jpg")
+}
+
+// scaleToFit downsamples an image with a box filter so that its longer side is at
+// most size pixels. Smaller images are returned unchanged.
+func scaleToFit(src image.Image, size int) image.Image {
+	bounds := src.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width <= size && height <= size {
+		return src
+	}
+
+	dstWidth, dstHeight := size, height*size/width
+	if height > width {
+		dstWidth, dstHeight = width*size/height, size
+	}
+	dstWidth, dstHeight = max(dstWidth, 1), max(dstHeight, 1)
+
+	dst := image.NewRGBA(image.Rect(0, 0, dstWidth, dstHeight))
+	for y := 0; y < dstHeight; y++ {
+		y0 := bounds.Min.Y + y*height/dstHeight
+		y1 := max(bounds.Min.Y+(y+1)*height/dstHeight, y0+1)
+		for x := 0; x < dstWidth; x++ {
+			x0 := bounds.Min.X + x*width/dstWidth
+			x1 := max(bounds.Min.X+(x+1)*width/dstWidth, x0+1)
+
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := src.At(sx, sy).RGBA()
+					r, g, b, a = r+uint64(cr), g+uint64(cg), b+uint64(cb), a+uint64(ca)
+					n++
+				}
+			}
+			dst.Set(x, y, color.RGBA64{
+				R: uint16(r / n), G: uint16(g / n), B: uint16(b / n), A: uint16(a / n),
+			})
+		}
+	}
+	return dst
+}
+
+// encodeJPEG flattens transparent areas onto white, as JPEG has no alpha channel
+func encodeJPEG(img image.Image, quality int) ([]byte, error) {
+	flat := image.NewRGBA(img.Bounds())
+	draw.Draw(flat, flat.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.Draw(flat, flat.Bounds(), img, img.Bounds().Min, draw.Over)
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, flat, &jpeg.Options{Quality: quality}); err != nil {
+		return nil, fmt.Errorf("failed to encode jpeg: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// lookupTool resolves an optional external binary, reporting unavailable if it is
+// not configured or not installed
+func lookupTool(name string, unavailable error) (string, error) {
+	if name == "" {
+		return "", unavailable
+	}
+	path, err := exec.LookPath(name)
+	if err != nil {
+		return "", fmt.Errorf("%w: %s is not installed", unavailable, name)
+	}
+	return path, nil
+}
+
+func runTool(path string, args ...string) error {
+	_, err := toolOutput(path, args...)
+	return err
+}
+
+// toolOutput runs an external binary and returns what it wrote to stdout
+func toolOutput(path string, args ...string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), toolTimeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, path, args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("%s failed: %w: %s", filepath.Base(path), err, bytes.TrimSpace(stderr.Bytes()))
+	}
+	return stdout.Bytes(), nil
+}
@@ -0,0 +1,164 @@
+package documents
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// watermarkColor is a translucent grey that stays readable on light and dark scans
+var watermarkColor = color.NRGBA{R: 128, G: 128, B: 128, A: 110}
+
+// watermarkImage stamps the text in rows across the whole image. JPEGs are
+// re-encoded as JPEG, all other formats as PNG.
+func watermarkImage(data []byte, text string) ([]byte, string, error) {
+	src, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %v", ErrWatermarkUnsupported, err)
+	}
+
+	bounds := src.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(dst, dst.Bounds(), src, bounds.Min, draw.Src)
+
+	stampText(dst, fontText(text))
+
+	if format == "jpeg" {
+		encoded, err := encodeJPEG(dst, 90)
+		return encoded, "image/jpeg", err
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, dst); err != nil {
+		return nil, "", fmt.Errorf("failed to encode png: %w", err)
+	}
+	return buf.Bytes(), "image/png", nil
+}
+
+// stampText draws the text centered in rows, scaled to cover about two thirds of
+// the image width so it cannot be cropped away without destroying the content
+func stampText(dst *image.RGBA, text []rune) {
+	if len(text) == 0 {
+		return
+	}
+
+	width, height := dst.Bounds().Dx(), dst.Bounds().Dy()
+	textWidth := len(text)*(glyphWidth+1) - 1
+	scale := max(width*2/3/textWidth, 1)
+
+	rowHeight := glyphHeight * scale
+	step := max(rowHeight*4, height/5)
+	left := (width - textWidth*scale) / 2
+	ink := image.NewUniform(watermarkColor)
+
+	for top := (step - rowHeight) / 2; top < height; top += step {
+		for i, r := range text {
+			glyph := glyphs[r]
+			x0 := left + i*(glyphWidth+1)*scale
+			for row := 0; row < glyphHeight; row++ {
+				for col := 0; col < glyphWidth; col++ {
+					if glyph[row]&(1<<(glyphWidth-1-col)) == 0 {
+						continue
+					}
+					pixel := image.Rect(x0+col*scale, top+row*scale, x0+(col+1)*scale, top+(row+1)*scale)
+					draw.Draw(dst, pixel, ink, image.Point{}, draw.Over)
+				}
+			}
+		}
+	}
+}
+
+// watermarkPDF overlays a generated stamp page onto every page of the PDF with the
+// configured qpdf binary
+func (s *Service) watermarkPDF(data []byte, text string) ([]byte, error) {
+	stamper, err := lookupTool(s.pdfStamper, ErrWatermarkUnsupported)
+	if err != nil {
+		return nil, err
+	}
+
+	dir, err := os.MkdirTemp("", "document-watermark-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	in := filepath.Join(dir, "in.pdf")
+	stamp := filepath.Join(dir, "stamp.pdf")
+	out := filepath.Join(dir, "out.pdf")
+	if err := os.WriteFile(in, data, 0o600); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(stamp, stampPDF(text), 0o600); err != nil {
+		return nil, err
+	}
+
+	if err := runTool(stamper, "--warning-exit-0", in, "--overlay", stamp, "--repeat=1", "--", out); err != nil {
+		return nil, err
+	}
+
+	return os.ReadFile(out)
+}
+
+// stampPDF builds a single A4 page with the text repeated diagonally in translucent
+// grey Helvetica, used as overlay for watermarked PDFs
+func stampPDF(text string) []byte {
+	var content strings.Builder
+	content.WriteString("q /GS1 gs 0.5 g BT /F1 22 Tf\n")
+	for _, y := range []int{120, 360, 600} {
+		fmt.Fprintf(&content, "0.7071 0.7071 -0.7071 0.7071 90 %d Tm (%s) Tj\n", y, pdfString(text))
+	}
+	content.WriteString("ET Q\n")
+
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 595 842] " +
+			"/Resources << /Font << /F1 4 0 R >> /ExtGState << /GS1 5 0 R >> >> /Contents 6 0 R >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>",
+		"<< /Type /ExtGState /ca 0.35 >>",
+		fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", content.Len(), content.String()),
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, object := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, object)
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+
+	return buf.Bytes()
+}
+
+// pdfString escapes text for a PDF literal string in WinAnsiEncoding. Characters
+// outside Latin-1 are replaced by '?'.
+func pdfString(text string) string {
+	var buf strings.Builder
+	for _, r := range text {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			buf.WriteByte('\\')
+			buf.WriteRune(r)
+		case r >= 0x20 && r < 0x7f:
+			buf.WriteRune(r)
+		case r >= 0xa0 && r <= 0xff:
+			fmt.Fprintf(&buf, "\\%03o", r)
+		default:
+			buf.WriteByte('?')
+		}
+	}
+	return buf.String()
+}
@@ -2,13 +2,24 @@ package email
 
 import (
 	"bytes"
+	"encoding/base64"
 	"fmt"
 	"html/template"
+	"mime"
+	"mime/multipart"
 	"net/smtp"
+	"net/textproto"
 	"strings"
+	"time"
 
 	"elterngeld-portal/config"
+	"elterngeld-portal/internal/activity"
+	"elterngeld-portal/internal/mailqueue"
 	"elterngeld-portal/internal/models"
+	"elterngeld-portal/internal/replies"
+	"elterngeld-portal/internal/shortlink"
+	"elterngeld-portal/pkg/i18n"
+	"elterngeld-portal/pkg/ical"
 
 	"go.uber.org/zap"
 )
@@ -17,6 +28,7 @@ type EmailService struct {
 	config *config.Config
 	logger *zap.Logger
 	auth   smtp.Auth
+	links  *shortlink.Service
 }
 
 type EmailData struct {
@@ -24,6 +36,26 @@ type EmailData struct {
 	Subject  string
 	Template string
 	Data     interface{}
+
+	// Optional sender personalization (e.g. the assigned Berater)
+	FromName string
+	ReplyTo  string
+
+	// Language of the recipient, defaults to German
+	Language i18n.Language
+
+	// Optional threading headers, so the recipient's answer can be matched to the conversation
+	MessageID string
+	InReplyTo string
+
+	Attachments []Attachment
+}
+
+// Attachment is a file sent along with an email
+type Attachment struct {
+	FileName    string
+	ContentType string
+	Content     []byte
 }
 
 // Template data structures
@@ -67,21 +99,151 @@ type LeadAssignmentData struct {
 	DashboardURL  string
 }
 
+// BeraterSignatureData holds the personal signature block of a Berater
+type BeraterSignatureData struct {
+	Name           string
+	Email          string
+	Phone          string
+	SignatureLines []string
+	PhotoURL       string
+	CalendarURL    string
+}
+
+type LeadAssignmentConfirmationData struct {
+	Name              string
+	LeadTitle         string
+	ApplicationNumber string
+	Berater           *BeraterSignatureData
+	SupportEmail      string
+}
+
+type OfferData struct {
+	Name              string
+	ApplicationNumber string
+	PackageName       string
+	Price             float64
+	Currency          string
+	ValidUntil        string
+	Message           string
+	OfferURL          string
+	Berater           *BeraterSignatureData
+	SupportEmail      string
+}
+
+type BeraterInvitationData struct {
+	Name          string
+	InvitedBy     string
+	InvitationURL string
+	ExpiresAt     string
+	SupportEmail  string
+}
+
 type PaymentConfirmationData struct {
+	Name          string
+	BookingRef    string
+	Amount        float64
+	CreditAmount  float64
+	Currency      string
+	PackageName   string
+	PaymentDate   string
+	InvoiceNumber string
+	InvoiceURL    string
+	SupportEmail  string
+}
+
+// DailyDigestData holds the activity feed items of a daily digest
+type DailyDigestData struct {
 	Name         string
-	BookingRef   string
-	Amount       float64
-	Currency     string
-	PackageName  string
-	PaymentDate  string
-	InvoiceURL   string
+	Items        []DailyDigestItem
+	DashboardURL string
+}
+
+// DailyDigestItem is an activity feed item prepared for the digest email
+type DailyDigestItem struct {
+	Type     string
+	Title    string
+	Time     string
+	Amount   float64
+	Currency string
+	URL      string
+}
+
+// NotificationEmailData holds a notification sent without a dedicated template
+type NotificationEmailData struct {
+	Title        string
+	Message      string
+	DashboardURL string
 	SupportEmail string
 }
 
+// NotificationDigestData holds the batched notifications of a notification digest
+type NotificationDigestData struct {
+	Name         string
+	Items        []NotificationDigestItem
+	DashboardURL string
+}
+
+// NotificationDigestItem is a batched notification prepared for the digest email
+type NotificationDigestItem struct {
+	Title   string
+	Message string
+	Time    string
+}
+
+// NoShowFollowUpData holds the rescheduling offer after a missed appointment
+type NoShowFollowUpData struct {
+	Name          string
+	BookingRef    string
+	Title         string
+	AppointmentAt string
+	RescheduleURL string
+	SupportEmail  string
+}
+
+// ApplicationConfirmationData holds the links an applicant confirms their email
+// address and tracks their application with
+type ApplicationConfirmationData struct {
+	Name         string
+	JobTitle     string
+	VerifyURL    string
+	TrackingURL  string
+	SupportEmail string
+}
+
+// InterviewInvitationData holds the link an applicant uses to pick an interview slot
+type InterviewInvitationData struct {
+	Name          string
+	JobTitle      string
+	InvitationURL string
+	ExpiresAt     string
+	SupportEmail  string
+}
+
+// InterviewScheduledData holds the details of a booked job interview
+type InterviewScheduledData struct {
+	Name          string
+	JobTitle      string
+	Applicant     string
+	Interviewer   string
+	AppointmentAt string
+	Duration      int
+	Location      string
+	IsOnline      bool
+	SupportEmail  string
+}
+
+// ReplyData holds a reply a Berater wrote in the portal
+type ReplyData struct {
+	Name      string
+	Body      string
+	Reference string
+	Berater   *BeraterSignatureData
+}
+
 func NewEmailService(config *config.Config, logger *zap.Logger) *EmailService {
 	var auth smtp.Auth
-	if config.SMTP.Username != "" && config.SMTP.Password != "" {
-		auth = smtp.PlainAuth("", config.SMTP.Username, config.SMTP.Password, config.SMTP.Host)
+	if config.Email.SMTPUser != "" && config.Email.SMTPPassword != "" {
+		auth = smtp.PlainAuth("", config.Email.SMTPUser, config.Email.SMTPPassword, config.Email.SMTPHost)
 	}
 
 	return &EmailService{
@@ -91,22 +253,53 @@ func NewEmailService(config *config.Config, logger *zap.Logger) *EmailService {
 	}
 }
 
+// WithShortLinks enables click tracking for call-to-action links in outgoing emails
+func (e *EmailService) WithShortLinks(links *shortlink.Service) *EmailService {
+	e.links = links
+	return e
+}
+
+// trackedURL replaces targetURL with a tracked short link. The original URL is
+// used if short links are disabled or the link cannot be created.
+func (e *EmailService) trackedURL(targetURL string, opts shortlink.Options) string {
+	if e.links == nil || targetURL == "" {
+		return targetURL
+	}
+
+	url, err := e.links.Shorten(targetURL, opts)
+	if err != nil {
+		e.logger.Warn("Failed to create short link", zap.Error(err))
+		return targetURL
+	}
+	return url
+}
+
 // SendWelcomeEmail sends welcome email with verification link
 func (e *EmailService) SendWelcomeEmail(user *models.User, verificationToken string) error {
-	verificationURL := fmt.Sprintf("%s/auth/verify-email?token=%s", e.config.App.BaseURL, verificationToken)
+	verificationURL := e.trackedURL(
+		fmt.Sprintf("%s/auth/verify-email?token=%s", e.config.App.BaseURL, verificationToken),
+		shortlink.Options{
+			Purpose:        models.ShortLinkPurposeVerification,
+			RecipientEmail: user.Email,
+			UserID:         &user.ID,
+		},
+	)
 	
+	lang := recipientLanguage(user)
+
 	data := WelcomeEmailData{
 		Name:             user.FirstName + " " + user.LastName,
 		Email:            user.Email,
 		VerificationURL:  verificationURL,
-		SupportEmail:     e.config.SMTP.FromEmail,
+		SupportEmail:     e.config.Email.From,
 	}
 
 	emailData := EmailData{
 		To:       []string{user.Email},
-		Subject:  "Willkommen beim Elterngeld-Portal - E-Mail bestätigen",
+		Subject:  i18n.T(lang, "Welcome to Elterngeld-Portal - Please confirm your email"),
 		Template: "welcome",
 		Data:     data,
+		Language: lang,
 	}
 
 	return e.sendEmail(emailData)
@@ -114,27 +307,30 @@ func (e *EmailService) SendWelcomeEmail(user *models.User, verificationToken str
 
 // SendBookingConfirmation sends booking confirmation email
 func (e *EmailService) SendBookingConfirmation(booking *models.Booking, user *models.User) error {
-	var timeslotInfo string
-	if booking.Timeslot != nil {
-		timeslotInfo = booking.Timeslot.DateTime.Format("02.01.2006 um 15:04")
+	lang := recipientLanguage(user)
+
+	packageName := booking.Title
+	if booking.Package != nil {
+		packageName = booking.Package.Name
 	}
 
 	data := BookingConfirmationData{
-		Name:            user.FirstName + " " + user.LastName,
-		BookingRef:      booking.BookingReference,
-		PackageName:     booking.Package.Name,
-		TotalPrice:      booking.TotalPrice,
-		Currency:        booking.Currency,
-		TimeslotDate:    timeslotInfo,
-		OnlineMeetingURL: booking.OnlineMeetingURL,
-		SupportEmail:    e.config.SMTP.FromEmail,
+		Name:             user.FirstName + " " + user.LastName,
+		BookingRef:       booking.BookingReference,
+		PackageName:      packageName,
+		TotalPrice:       booking.TotalAmount,
+		Currency:         booking.Currency,
+		TimeslotDate:     i18n.FormatDateTime(lang, booking.StartTime.In(user.TimeLocation())),
+		OnlineMeetingURL: booking.MeetingLink,
+		SupportEmail:     e.config.Email.From,
 	}
 
 	emailData := EmailData{
-		To:       []string{user.Email},
-		Subject:  fmt.Sprintf("Buchungsbestätigung - %s", booking.BookingReference),
+		To:       []string{user.NotificationAddress()},
+		Subject:  i18n.T(lang, "Booking confirmation - %s", booking.BookingReference),
 		Template: "booking_confirmation",
 		Data:     data,
+		Language: lang,
 	}
 
 	return e.sendEmail(emailData)
@@ -142,9 +338,11 @@ func (e *EmailService) SendBookingConfirmation(booking *models.Booking, user *mo
 
 // SendTodoNotification sends todo notification to user
 func (e *EmailService) SendTodoNotification(todo *models.Todo, user *models.User, assignedBy *models.User) error {
+	lang := recipientLanguage(user)
+
 	var dueDate string
 	if todo.DueDate != nil {
-		dueDate = todo.DueDate.Format("02.01.2006")
+		dueDate = i18n.FormatDate(lang, *todo.DueDate)
 	}
 
 	dashboardURL := fmt.Sprintf("%s/dashboard/todos", e.config.App.BaseURL)
@@ -154,67 +352,174 @@ func (e *EmailService) SendTodoNotification(todo *models.Todo, user *models.User
 		TodoTitle:    todo.Title,
 		TodoDesc:     todo.Description,
 		DueDate:      dueDate,
-		Priority:     todo.Priority,
+		Priority:     todoPriority(todo, lang),
 		AssignedBy:   assignedBy.FirstName + " " + assignedBy.LastName,
 		DashboardURL: dashboardURL,
-		SupportEmail: e.config.SMTP.FromEmail,
+		SupportEmail: e.config.Email.From,
 	}
 
 	emailData := EmailData{
-		To:       []string{user.Email},
-		Subject:  fmt.Sprintf("Neue Aufgabe zugewiesen: %s", todo.Title),
+		To:       []string{user.NotificationAddress()},
+		Subject:  i18n.T(lang, "New task assigned: %s", todo.Title),
 		Template: "todo_notification",
 		Data:     data,
+		Language: lang,
 	}
 
 	return e.sendEmail(emailData)
 }
 
+// todoPriority returns the priority of the lead a todo belongs to, as todos
+// have none of their own
+func todoPriority(todo *models.Todo, lang i18n.Language) string {
+	if todo.Lead == nil {
+		return models.PriorityMedium.GetLocalizedName(lang)
+	}
+	return todo.Lead.Priority.GetLocalizedName(lang)
+}
+
 // SendLeadAssignment sends lead assignment notification to berater
 func (e *EmailService) SendLeadAssignment(lead *models.Lead, berater *models.User) error {
+	lang := recipientLanguage(berater)
 	dashboardURL := fmt.Sprintf("%s/dashboard/leads/%s", e.config.App.BaseURL, lead.ID.String())
 
 	data := LeadAssignmentData{
 		BeraterName:   berater.FirstName + " " + berater.LastName,
 		LeadTitle:     lead.Title,
 		LeadDesc:      lead.Description,
-		CustomerName:  lead.ContactEmail, // Use email if no name available
-		CustomerEmail: lead.ContactEmail,
-		Priority:      string(lead.Priority),
+		CustomerName:  strings.TrimSpace(lead.User.FirstName + " " + lead.User.LastName),
+		CustomerEmail: lead.User.Email,
+		Priority:      lead.Priority.GetLocalizedName(lang),
 		DashboardURL:  dashboardURL,
 	}
 
-	if lead.User != nil {
-		data.CustomerName = lead.User.FirstName + " " + lead.User.LastName
+	if data.CustomerName == "" {
+		data.CustomerName = data.CustomerEmail
 	}
 
 	emailData := EmailData{
-		To:       []string{berater.Email},
-		Subject:  fmt.Sprintf("Neuer Lead zugewiesen: %s", lead.Title),
+		To:       []string{berater.NotificationAddress()},
+		Subject:  i18n.T(lang, "New lead assigned: %s", lead.Title),
 		Template: "lead_assignment",
 		Data:     data,
+		Language: lang,
+	}
+
+	return e.sendEmail(emailData)
+}
+
+// SendLeadAssignmentConfirmation informs the customer about their personal Berater
+func (e *EmailService) SendLeadAssignmentConfirmation(lead *models.Lead, customer *models.User, berater *models.User) error {
+	lang := recipientLanguage(customer)
+	signature := NewBeraterSignature(berater)
+
+	data := LeadAssignmentConfirmationData{
+		Name:              customer.FirstName + " " + customer.LastName,
+		LeadTitle:         lead.Title,
+		ApplicationNumber: lead.ApplicationNumber,
+		Berater:           signature,
+		SupportEmail:      e.config.Email.From,
+	}
+
+	emailData := EmailData{
+		To:       []string{customer.NotificationAddress()},
+		Subject:  i18n.T(lang, "Your personal advisor: %s - %s", signature.Name, lead.ApplicationNumber),
+		Template: "lead_assignment_confirmation",
+		Data:     data,
+		Language: lang,
+		FromName: signature.Name,
+		ReplyTo:  berater.Email,
+	}
+
+	return e.sendEmail(emailData)
+}
+
+// SendOffer sends a personal offer from the assigned Berater to the customer
+func (e *EmailService) SendOffer(lead *models.Lead, customer *models.User, berater *models.User, offer OfferData) error {
+	lang := recipientLanguage(customer)
+	signature := NewBeraterSignature(berater)
+
+	offer.Name = customer.FirstName + " " + customer.LastName
+	offer.ApplicationNumber = lead.ApplicationNumber
+	offer.Berater = signature
+	offer.SupportEmail = e.config.Email.From
+	offer.OfferURL = e.trackedURL(offer.OfferURL, shortlink.Options{
+		Purpose:        models.ShortLinkPurposeOffer,
+		RecipientEmail: customer.Email,
+		UserID:         &customer.ID,
+		LeadID:         &lead.ID,
+	})
+	if offer.Currency == "" {
+		offer.Currency = "EUR"
+	}
+
+	emailData := EmailData{
+		To:       []string{customer.NotificationAddress()},
+		Subject:  i18n.T(lang, "Your offer: %s - %s", offer.PackageName, lead.ApplicationNumber),
+		Template: "offer",
+		Data:     offer,
+		Language: lang,
+		FromName: signature.Name,
+		ReplyTo:  berater.Email,
 	}
 
 	return e.sendEmail(emailData)
 }
 
-// SendPaymentConfirmation sends payment confirmation email
-func (e *EmailService) SendPaymentConfirmation(payment *models.Payment, booking *models.Booking, user *models.User) error {
+// NewBeraterSignature builds the signature block for a Berater.
+// Without a custom signature the Berater's name and contact details are used.
+func NewBeraterSignature(berater *models.User) *BeraterSignatureData {
+	signature := &BeraterSignatureData{
+		Name:        strings.TrimSpace(berater.FirstName + " " + berater.LastName),
+		Email:       berater.Email,
+		Phone:       berater.Phone,
+		PhotoURL:    berater.PhotoURL,
+		CalendarURL: berater.CalendarURL,
+	}
+
+	for _, line := range strings.Split(strings.ReplaceAll(berater.EmailSignature, "\r\n", "\n"), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			signature.SignatureLines = append(signature.SignatureLines, line)
+		}
+	}
+
+	return signature
+}
+
+// SendPaymentReceipt sends the payment receipt with the invoice attached
+func (e *EmailService) SendPaymentReceipt(payment *models.Payment, booking *models.Booking, user *models.User, invoice []byte) error {
+	lang := recipientLanguage(user)
+
+	packageName := booking.Title
+	if booking.Package != nil {
+		packageName = booking.Package.Name
+	}
+
 	data := PaymentConfirmationData{
-		Name:         user.FirstName + " " + user.LastName,
-		BookingRef:   booking.BookingReference,
-		Amount:       payment.Amount,
-		Currency:     payment.Currency,
-		PackageName:  booking.Package.Name,
-		PaymentDate:  payment.CompletedAt.Format("02.01.2006"),
-		SupportEmail: e.config.SMTP.FromEmail,
+		Name:          user.FirstName + " " + user.LastName,
+		BookingRef:    booking.BookingReference,
+		Amount:        payment.Amount,
+		CreditAmount:  payment.CreditAmount,
+		Currency:      payment.Currency,
+		PackageName:   packageName,
+		InvoiceNumber: mailqueue.InvoiceNumber(payment, booking),
+		SupportEmail:  e.config.Email.From,
+	}
+	if payment.PaidAt != nil {
+		data.PaymentDate = i18n.FormatDate(lang, payment.PaidAt.In(user.TimeLocation()))
 	}
 
 	emailData := EmailData{
-		To:       []string{user.Email},
-		Subject:  fmt.Sprintf("Zahlungsbestätigung - %s", booking.BookingReference),
+		To:       []string{user.NotificationAddress()},
+		Subject:  i18n.T(lang, "Payment confirmation - %s", booking.BookingReference),
 		Template: "payment_confirmation",
 		Data:     data,
+		Language: lang,
+		Attachments: []Attachment{{
+			FileName:    mailqueue.InvoiceFileName(payment, booking),
+			ContentType: "application/pdf",
+			Content:     invoice,
+		}},
 	}
 
 	return e.sendEmail(emailData)
@@ -222,19 +527,44 @@ func (e *EmailService) SendPaymentConfirmation(payment *models.Payment, booking
 
 // SendPasswordReset sends password reset email
 func (e *EmailService) SendPasswordReset(user *models.User, resetToken string) error {
+	lang := recipientLanguage(user)
 	resetURL := fmt.Sprintf("%s/auth/reset-password?token=%s", e.config.App.BaseURL, resetToken)
 	
 	data := map[string]interface{}{
 		"Name":         user.FirstName + " " + user.LastName,
 		"ResetURL":     resetURL,
-		"SupportEmail": e.config.SMTP.FromEmail,
+		"SupportEmail": e.config.Email.From,
 	}
 
 	emailData := EmailData{
 		To:       []string{user.Email},
-		Subject:  "Passwort zurücksetzen - Elterngeld-Portal",
+		Subject:  i18n.T(lang, "Reset your password - Elterngeld-Portal"),
 		Template: "password_reset",
 		Data:     data,
+		Language: lang,
+	}
+
+	return e.sendEmail(emailData)
+}
+
+// SendBeraterInvitation sends the onboarding invitation to a new Berater
+func (e *EmailService) SendBeraterInvitation(invitation *models.BeraterInvitation, token string, invitedBy *models.User) error {
+	lang := i18n.DefaultLanguage
+
+	data := BeraterInvitationData{
+		Name:          invitation.FirstName + " " + invitation.LastName,
+		InvitedBy:     invitedBy.FullName(),
+		InvitationURL: fmt.Sprintf("%s/berater/onboarding?token=%s", e.config.App.BaseURL, token),
+		ExpiresAt:     i18n.FormatDate(lang, invitation.ExpiresAt),
+		SupportEmail:  e.config.Email.From,
+	}
+
+	emailData := EmailData{
+		To:       []string{invitation.Email},
+		Subject:  i18n.T(lang, "Your invitation as a Berater - Elterngeld-Portal"),
+		Template: "berater_invitation",
+		Data:     data,
+		Language: lang,
 	}
 
 	return e.sendEmail(emailData)
@@ -246,12 +576,12 @@ func (e *EmailService) SendContactFormConfirmation(contactForm *models.ContactFo
 		"Name":            contactForm.Name,
 		"Subject":         contactForm.Subject,
 		"ReferenceNumber": "CF-" + contactForm.ID.String()[:8],
-		"SupportEmail":    e.config.SMTP.FromEmail,
+		"SupportEmail":    e.config.Email.From,
 	}
 
 	emailData := EmailData{
 		To:       []string{contactForm.Email},
-		Subject:  "Kontaktanfrage erhalten - Elterngeld-Portal",
+		Subject:  i18n.T(i18n.DefaultLanguage, "Contact request received - Elterngeld-Portal"),
 		Template: "contact_confirmation",
 		Data:     data,
 	}
@@ -259,6 +589,254 @@ func (e *EmailService) SendContactFormConfirmation(contactForm *models.ContactFo
 	return e.sendEmail(emailData)
 }
 
+// SendDailyDigest sends the activity feed of the last day to a Berater or admin
+func (e *EmailService) SendDailyDigest(user *models.User, items []activity.Item) error {
+	lang := recipientLanguage(user)
+	loc := user.TimeLocation()
+
+	data := DailyDigestData{
+		Name:         user.FirstName + " " + user.LastName,
+		DashboardURL: fmt.Sprintf("%s/dashboard", e.config.App.BaseURL),
+	}
+	for _, item := range items {
+		digestItem := DailyDigestItem{
+			Type:     string(item.Type),
+			Title:    item.Title,
+			Time:     item.OccurredAt.In(loc).Format("02.01.2006 15:04"),
+			Amount:   item.Amount,
+			Currency: item.Currency,
+		}
+		if item.LeadID != nil {
+			digestItem.URL = fmt.Sprintf("%s/dashboard/leads/%s", e.config.App.BaseURL, item.LeadID.String())
+		}
+		data.Items = append(data.Items, digestItem)
+	}
+
+	emailData := EmailData{
+		To:       []string{user.NotificationAddress()},
+		Subject:  i18n.T(lang, "Your daily digest: %d new activities", len(items)),
+		Template: "daily_digest",
+		Data:     data,
+		Language: lang,
+	}
+
+	return e.sendEmail(emailData)
+}
+
+// SendNoShowFollowUp offers a customer who missed their appointment to book a new one
+func (e *EmailService) SendNoShowFollowUp(booking *models.Booking, customer *models.User) error {
+	lang := recipientLanguage(customer)
+
+	rescheduleURL := e.trackedURL(
+		fmt.Sprintf("%s/buchung?reschedule=%s", e.config.App.BaseURL, booking.ID.String()),
+		shortlink.Options{
+			Purpose:        models.ShortLinkPurposeReminder,
+			RecipientEmail: customer.Email,
+			UserID:         &customer.ID,
+			LeadID:         booking.LeadID,
+		},
+	)
+
+	data := NoShowFollowUpData{
+		Name:          customer.FirstName + " " + customer.LastName,
+		BookingRef:    booking.BookingReference,
+		Title:         booking.Title,
+		AppointmentAt: booking.StartTime.In(customer.TimeLocation()).Format("02.01.2006 15:04"),
+		RescheduleURL: rescheduleURL,
+		SupportEmail:  e.config.Email.From,
+	}
+
+	emailData := EmailData{
+		To:       []string{customer.NotificationAddress()},
+		Subject:  i18n.T(lang, "We missed you - book a new appointment"),
+		Template: "no_show_follow_up",
+		Data:     data,
+		Language: lang,
+	}
+
+	return e.sendEmail(emailData)
+}
+
+// SendApplicationConfirmation confirms a submitted application and asks the
+// applicant to verify their email address
+func (e *EmailService) SendApplicationConfirmation(application *models.JobApplication, job *models.Job, token string) error {
+	lang := i18n.DefaultLanguage
+
+	data := ApplicationConfirmationData{
+		Name:         application.FirstName + " " + application.LastName,
+		JobTitle:     job.Title,
+		VerifyURL:    fmt.Sprintf("%s/jobs/applications/verify?token=%s", e.config.App.BaseURL, token),
+		TrackingURL:  fmt.Sprintf("%s/jobs/applications/track?token=%s", e.config.App.BaseURL, token),
+		SupportEmail: e.config.Email.From,
+	}
+
+	emailData := EmailData{
+		To:       []string{application.Email},
+		Subject:  i18n.T(lang, "Please confirm your application - %s", job.Title),
+		Template: "application_confirmation",
+		Data:     data,
+		Language: lang,
+	}
+
+	return e.sendEmail(emailData)
+}
+
+// SendInterviewInvitation sends an applicant the link to pick an interview slot
+func (e *EmailService) SendInterviewInvitation(application *models.JobApplication, job *models.Job, token string, expiresAt time.Time) error {
+	lang := i18n.DefaultLanguage
+
+	data := InterviewInvitationData{
+		Name:          application.FirstName + " " + application.LastName,
+		JobTitle:      job.Title,
+		InvitationURL: fmt.Sprintf("%s/jobs/interview?token=%s", e.config.App.BaseURL, token),
+		ExpiresAt:     i18n.FormatDate(lang, expiresAt),
+		SupportEmail:  e.config.Email.From,
+	}
+
+	emailData := EmailData{
+		To:       []string{application.Email},
+		Subject:  i18n.T(lang, "Invitation to an interview - %s", job.Title),
+		Template: "interview_invitation",
+		Data:     data,
+		Language: lang,
+	}
+
+	return e.sendEmail(emailData)
+}
+
+// SendInterviewScheduled confirms a booked interview to the applicant and the
+// interviewer, each with the calendar invitation attached
+func (e *EmailService) SendInterviewScheduled(application *models.JobApplication, job *models.Job, slot *models.Timeslot, interviewer *models.User, invite []byte) error {
+	applicant := application.FirstName + " " + application.LastName
+	attachments := []Attachment{{
+		FileName:    "interview.ics",
+		ContentType: ical.ContentType,
+		Content:     invite,
+	}}
+
+	recipients := []struct {
+		name     string
+		email    string
+		lang     i18n.Language
+		location *time.Location
+		template string
+	}{
+		{applicant, application.Email, i18n.DefaultLanguage, interviewer.TimeLocation(), "interview_scheduled"},
+		{interviewer.FullName(), interviewer.Email, recipientLanguage(interviewer), interviewer.TimeLocation(), "interview_scheduled_interviewer"},
+	}
+
+	for _, recipient := range recipients {
+		data := InterviewScheduledData{
+			Name:          recipient.name,
+			JobTitle:      job.Title,
+			Applicant:     applicant,
+			Interviewer:   interviewer.FullName(),
+			AppointmentAt: slot.StartTime.In(recipient.location).Format("02.01.2006 15:04"),
+			Duration:      slot.Duration,
+			Location:      slot.Location,
+			IsOnline:      slot.IsOnline,
+			SupportEmail:  e.config.Email.From,
+		}
+
+		emailData := EmailData{
+			To:          []string{recipient.email},
+			Subject:     i18n.T(recipient.lang, "Interview confirmed - %s", job.Title),
+			Template:    recipient.template,
+			Data:        data,
+			Language:    recipient.lang,
+			Attachments: attachments,
+		}
+		if err := e.sendEmail(emailData); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// SendReply sends a reply a Berater wrote to a contact form or lead, with the
+// uploaded files attached
+func (e *EmailService) SendReply(reply *replies.Email) error {
+	lang := i18n.ParseOrDefault(reply.Language)
+	signature := NewBeraterSignature(reply.Berater)
+
+	data := ReplyData{
+		Name:      reply.Name,
+		Body:      reply.Body,
+		Reference: reply.Reference,
+		Berater:   signature,
+	}
+
+	emailData := EmailData{
+		To:        []string{reply.To},
+		Subject:   reply.Subject,
+		Template:  "reply",
+		Data:      data,
+		Language:  lang,
+		FromName:  signature.Name,
+		ReplyTo:   reply.Berater.Email,
+		MessageID: reply.MessageID,
+		InReplyTo: reply.InReplyTo,
+	}
+	for _, attachment := range reply.Attachments {
+		emailData.Attachments = append(emailData.Attachments, Attachment{
+			FileName:    attachment.FileName,
+			ContentType: attachment.ContentType,
+			Content:     attachment.Content,
+		})
+	}
+
+	return e.sendEmail(emailData)
+}
+
+// SendNotification sends a queued notification that has no dedicated template
+func (e *EmailService) SendNotification(notification *models.Notification) error {
+	data := NotificationEmailData{
+		Title:        notification.Title,
+		Message:      notification.Message,
+		DashboardURL: fmt.Sprintf("%s/dashboard", e.config.App.BaseURL),
+		SupportEmail: e.config.Email.From,
+	}
+
+	emailData := EmailData{
+		To:       []string{notification.Recipient},
+		Subject:  notification.Title,
+		Template: "notification",
+		Data:     data,
+		Language: i18n.DefaultLanguage,
+	}
+
+	return e.sendEmail(emailData)
+}
+
+// SendNotificationDigest sends the batched notifications of a user as one summary
+func (e *EmailService) SendNotificationDigest(user *models.User, notifications []models.Notification) error {
+	lang := recipientLanguage(user)
+	loc := user.TimeLocation()
+
+	data := NotificationDigestData{
+		Name:         user.FirstName + " " + user.LastName,
+		DashboardURL: fmt.Sprintf("%s/dashboard", e.config.App.BaseURL),
+	}
+	for _, notification := range notifications {
+		data.Items = append(data.Items, NotificationDigestItem{
+			Title:   notification.Title,
+			Message: notification.Message,
+			Time:    notification.CreatedAt.In(loc).Format("02.01.2006 15:04"),
+		})
+	}
+
+	emailData := EmailData{
+		To:       []string{user.NotificationAddress()},
+		Subject:  i18n.T(lang, "Your notification summary: %d new notifications", len(notifications)),
+		Template: "notification_digest",
+		Data:     data,
+		Language: lang,
+	}
+
+	return e.sendEmail(emailData)
+}
+
 // sendEmail sends an email using the configured SMTP settings
 func (e *EmailService) sendEmail(emailData EmailData) error {
 	// In development mode, just log the email instead of sending
@@ -266,24 +844,25 @@ func (e *EmailService) sendEmail(emailData EmailData) error {
 		e.logger.Info("Email would be sent in production",
 			zap.Strings("to", emailData.To),
 			zap.String("subject", emailData.Subject),
-			zap.String("template", emailData.Template))
+			zap.String("template", emailData.Template),
+			zap.Int("attachments", len(emailData.Attachments)))
 		return nil
 	}
 
 	// Load and parse template
-	body, err := e.renderTemplate(emailData.Template, emailData.Data)
+	body, err := e.renderTemplate(emailData.Template, emailData.Language, emailData.Data)
 	if err != nil {
 		return fmt.Errorf("failed to render email template: %w", err)
 	}
 
 	// Prepare email message
-	message := e.buildMessage(emailData.To, emailData.Subject, body)
+	message := e.buildMessage(emailData, body)
 
 	// Send email
-	addr := fmt.Sprintf("%s:%d", e.config.SMTP.Host, e.config.SMTP.Port)
+	addr := fmt.Sprintf("%s:%d", e.config.Email.SMTPHost, e.config.Email.SMTPPort)
 	to := emailData.To
 
-	err = smtp.SendMail(addr, e.auth, e.config.SMTP.FromEmail, to, []byte(message))
+	err = smtp.SendMail(addr, e.auth, e.config.Email.From, to, []byte(message))
 	if err != nil {
 		e.logger.Error("Failed to send email", 
 			zap.Error(err),
@@ -299,8 +878,16 @@ func (e *EmailService) sendEmail(emailData EmailData) error {
 	return nil
 }
 
-// renderTemplate renders an email template with the provided data
-func (e *EmailService) renderTemplate(templateName string, data interface{}) (string, error) {
+// recipientLanguage returns the preferred language of an email recipient
+func recipientLanguage(user *models.User) i18n.Language {
+	if user == nil {
+		return i18n.DefaultLanguage
+	}
+	return i18n.ParseOrDefault(user.Language)
+}
+
+// renderTemplate renders an email template in the given language with the provided data
+func (e *EmailService) renderTemplate(templateName string, lang i18n.Language, data interface{}) (string, error) {
 	// Define email templates inline for simplicity
 	// In production, these would be loaded from files
 	templates := map[string]string{
@@ -341,7 +928,7 @@ func (e *EmailService) renderTemplate(templateName string, data interface{}) (st
             <h3>Buchungsdetails:</h3>
             <p><strong>Buchungsnummer:</strong> {{.BookingRef}}</p>
             <p><strong>Paket:</strong> {{.PackageName}}</p>
-            <p><strong>Betrag:</strong> {{.TotalPrice}} {{.Currency}}</p>
+            <p><strong>Betrag:</strong> {{currency .TotalPrice .Currency}}</p>
             {{if .TimeslotDate}}<p><strong>Termin:</strong> {{.TimeslotDate}}</p>{{end}}
             {{if .OnlineMeetingURL}}<p><strong>Online-Meeting:</strong> <a href="{{.OnlineMeetingURL}}">Zum Meeting</a></p>{{end}}
         </div>
@@ -402,6 +989,74 @@ func (e *EmailService) renderTemplate(templateName string, data interface{}) (st
         <p>Ihr Elterngeld-Portal Team</p>
     </div>
 </body>
+</html>`,
+
+		"lead_assignment_confirmation": `
+<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="UTF-8">
+    <title>Ihr persönlicher Berater</title>
+</head>
+<body style="font-family: Arial, sans-serif; line-height: 1.6; color: #333;">
+    <div style="max-width: 600px; margin: 0 auto; padding: 20px;">
+        <h1 style="color: #2c5aa0;">Ihr persönlicher Berater</h1>
+        <p>Hallo {{.Name}},</p>
+        <p>ich bin ab sofort Ihr persönlicher Ansprechpartner für Ihren Elterngeld-Antrag und begleite Sie durch alle weiteren Schritte.</p>
+        <div style="background-color: #f8f9fa; padding: 20px; border-radius: 8px; margin: 20px 0;">
+            <h3>{{.LeadTitle}}</h3>
+            <p><strong>Antragsnummer:</strong> {{.ApplicationNumber}}</p>
+        </div>
+        {{if .Berater.CalendarURL}}<div style="text-align: center; margin: 30px 0;">
+            <a href="{{.Berater.CalendarURL}}" style="background-color: #2c5aa0; color: white; padding: 12px 24px; text-decoration: none; border-radius: 4px; display: inline-block;">Termin vereinbaren</a>
+        </div>{{end}}
+        <p>Antworten Sie einfach auf diese E-Mail, wenn Sie Fragen haben.</p>
+        {{template "berater_signature" .Berater}}
+    </div>
+</body>
+</html>`,
+
+		"offer": `
+<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="UTF-8">
+    <title>Ihr Angebot</title>
+</head>
+<body style="font-family: Arial, sans-serif; line-height: 1.6; color: #333;">
+    <div style="max-width: 600px; margin: 0 auto; padding: 20px;">
+        <h1 style="color: #2c5aa0;">Ihr persönliches Angebot</h1>
+        <p>Hallo {{.Name}},</p>
+        {{if .Message}}<p>{{.Message}}</p>{{else}}<p>wie besprochen sende ich Ihnen hiermit Ihr persönliches Angebot.</p>{{end}}
+        <div style="background-color: #f8f9fa; padding: 20px; border-radius: 8px; margin: 20px 0;">
+            <h3>{{.PackageName}}</h3>
+            <p><strong>Antragsnummer:</strong> {{.ApplicationNumber}}</p>
+            <p><strong>Preis:</strong> {{currency .Price .Currency}}</p>
+            {{if .ValidUntil}}<p><strong>Gültig bis:</strong> {{.ValidUntil}}</p>{{end}}
+        </div>
+        {{if .OfferURL}}<div style="text-align: center; margin: 30px 0;">
+            <a href="{{.OfferURL}}" style="background-color: #2c5aa0; color: white; padding: 12px 24px; text-decoration: none; border-radius: 4px; display: inline-block;">Angebot ansehen</a>
+        </div>{{end}}
+        {{if .Berater.CalendarURL}}<p>Sie möchten das Angebot persönlich besprechen? <a href="{{.Berater.CalendarURL}}">Buchen Sie hier einen Termin</a>.</p>{{end}}
+        {{template "berater_signature" .Berater}}
+    </div>
+</body>
+</html>`,
+
+		"reply": `
+<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="UTF-8">
+    <title>Ihre Anfrage</title>
+</head>
+<body style="font-family: Arial, sans-serif; line-height: 1.6; color: #333;">
+    <div style="max-width: 600px; margin: 0 auto; padding: 20px;">
+        <div style="white-space: pre-line;">{{.Body}}</div>
+        {{template "berater_signature" .Berater}}
+        {{if .Reference}}<p style="margin-top: 20px; color: #999; font-size: 12px;">Referenz: {{.Reference}} - bitte bei Rückfragen angeben</p>{{end}}
+    </div>
+</body>
 </html>`,
 
 		"payment_confirmation": `
@@ -420,9 +1075,11 @@ func (e *EmailService) renderTemplate(templateName string, data interface{}) (st
             <h3>Zahlungsdetails:</h3>
             <p><strong>Buchungsnummer:</strong> {{.BookingRef}}</p>
             <p><strong>Paket:</strong> {{.PackageName}}</p>
-            <p><strong>Betrag:</strong> {{.Amount}} {{.Currency}}</p>
+            {{if .CreditAmount}}<p><strong>Verrechnetes Guthaben:</strong> {{currency .CreditAmount .Currency}}</p>{{end}}
+            <p><strong>Betrag:</strong> {{currency .Amount .Currency}}</p>
             <p><strong>Zahlungsdatum:</strong> {{.PaymentDate}}</p>
         </div>
+        {{if .InvoiceNumber}}<p>Die Rechnung {{.InvoiceNumber}} finden Sie im Anhang.</p>{{end}}
         <p>Bei Fragen erreichen Sie uns unter {{.SupportEmail}}.</p>
         <p>Ihr Elterngeld-Portal Team</p>
     </div>
@@ -449,6 +1106,28 @@ func (e *EmailService) renderTemplate(templateName string, data interface{}) (st
         <p>Ihr Elterngeld-Portal Team</p>
     </div>
 </body>
+</html>`,
+
+		"berater_invitation": `
+<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="UTF-8">
+    <title>Einladung als Berater</title>
+</head>
+<body style="font-family: Arial, sans-serif; line-height: 1.6; color: #333;">
+    <div style="max-width: 600px; margin: 0 auto; padding: 20px;">
+        <h1 style="color: #2c5aa0;">Willkommen im Berater-Team!</h1>
+        <p>Hallo {{.Name}},</p>
+        <p>{{.InvitedBy}} hat Sie als Berater im Elterngeld-Portal eingeladen. Legen Sie Ihr Passwort fest und vervollständigen Sie Ihr Profil, Ihren Kalender und Ihre Verfügbarkeiten:</p>
+        <div style="text-align: center; margin: 30px 0;">
+            <a href="{{.InvitationURL}}" style="background-color: #2c5aa0; color: white; padding: 12px 24px; text-decoration: none; border-radius: 4px; display: inline-block;">Einladung annehmen</a>
+        </div>
+        <p>Die Einladung ist bis zum {{.ExpiresAt}} gültig. Nach der Freigabe durch einen Administrator können Kunden Termine bei Ihnen buchen.</p>
+        <p>Bei Fragen erreichen Sie uns unter {{.SupportEmail}}.</p>
+        <p>Ihr Elterngeld-Portal Team</p>
+    </div>
+</body>
 </html>`,
 
 		"contact_confirmation": `
@@ -471,6 +1150,194 @@ func (e *EmailService) renderTemplate(templateName string, data interface{}) (st
         <p>Ihr Elterngeld-Portal Team</p>
     </div>
 </body>
+</html>`,
+		"daily_digest": `
+<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="UTF-8">
+    <title>Tägliche Übersicht</title>
+</head>
+<body style="font-family: Arial, sans-serif; line-height: 1.6; color: #333;">
+    <div style="max-width: 600px; margin: 0 auto; padding: 20px;">
+        <h1 style="color: #2c5aa0;">Ihre tägliche Übersicht</h1>
+        <p>Hallo {{.Name}},</p>
+        <p>das ist seit Ihrer letzten Übersicht passiert:</p>
+        <div style="background-color: #f8f9fa; padding: 20px; border-radius: 8px; margin: 20px 0;">
+            {{range .Items}}<p>
+                <strong>{{if eq .Type "lead.created"}}Neuer Lead{{else if eq .Type "payment.large"}}Große Zahlung{{else if eq .Type "booking.cancelled"}}Stornierte Buchung{{else if eq .Type "lead.sla_breached"}}SLA verletzt{{end}}:</strong>
+                {{if .URL}}<a href="{{.URL}}">{{.Title}}</a>{{else}}{{.Title}}{{end}}{{if .Amount}} · {{currency .Amount .Currency}}{{end}}<br>
+                <span style="color: #666;">{{.Time}}</span>
+            </p>{{end}}
+        </div>
+        <div style="text-align: center; margin: 30px 0;">
+            <a href="{{.DashboardURL}}" style="background-color: #2c5aa0; color: white; padding: 12px 24px; text-decoration: none; border-radius: 4px; display: inline-block;">Zum Dashboard</a>
+        </div>
+        <p>Sie erhalten diese E-Mail, weil Sie die tägliche Übersicht in Ihren Benachrichtigungseinstellungen aktiviert haben.</p>
+        <p>Ihr Elterngeld-Portal Team</p>
+    </div>
+</body>
+</html>`,
+		"no_show_follow_up": `
+<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="UTF-8">
+    <title>Neuer Termin</title>
+</head>
+<body style="font-family: Arial, sans-serif; line-height: 1.6; color: #333;">
+    <div style="max-width: 600px; margin: 0 auto; padding: 20px;">
+        <h1 style="color: #2c5aa0;">Wir haben Sie vermisst</h1>
+        <p>Hallo {{.Name}},</p>
+        <p>leider konnten wir Sie zu Ihrem Termin „{{.Title}}“ am {{.AppointmentAt}} (Buchungsnummer {{.BookingRef}}) nicht erreichen.</p>
+        <p>Das kann passieren. Wählen Sie einfach einen neuen Termin, der Ihnen besser passt:</p>
+        <div style="text-align: center; margin: 30px 0;">
+            <a href="{{.RescheduleURL}}" style="background-color: #2c5aa0; color: white; padding: 12px 24px; text-decoration: none; border-radius: 4px; display: inline-block;">Neuen Termin wählen</a>
+        </div>
+        <p>Bei Fragen erreichen Sie uns unter {{.SupportEmail}}.</p>
+        <p>Ihr Elterngeld-Portal Team</p>
+    </div>
+</body>
+</html>`,
+
+		"application_confirmation": `
+<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="UTF-8">
+    <title>Bitte bestätigen Sie Ihre Bewerbung</title>
+</head>
+<body style="font-family: Arial, sans-serif; line-height: 1.6; color: #333;">
+    <div style="max-width: 600px; margin: 0 auto; padding: 20px;">
+        <h1 style="color: #2c5aa0;">Vielen Dank für Ihre Bewerbung</h1>
+        <p>Hallo {{.Name}},</p>
+        <p>wir haben Ihre Bewerbung als {{.JobTitle}} erhalten. Bitte bestätigen Sie Ihre E-Mail-Adresse, damit wir Ihre Bewerbung bearbeiten können:</p>
+        <div style="text-align: center; margin: 30px 0;">
+            <a href="{{.VerifyURL}}" style="background-color: #2c5aa0; color: white; padding: 12px 24px; text-decoration: none; border-radius: 4px; display: inline-block;">E-Mail-Adresse bestätigen</a>
+        </div>
+        <p>Den aktuellen Stand Ihrer Bewerbung können Sie jederzeit hier einsehen: <a href="{{.TrackingURL}}">{{.TrackingURL}}</a></p>
+        <p>Falls Sie sich nicht bei uns beworben haben, können Sie diese E-Mail ignorieren.</p>
+        <p>Bei Fragen erreichen Sie uns unter {{.SupportEmail}}.</p>
+        <p>Ihr Elterngeld-Portal Team</p>
+    </div>
+</body>
+</html>`,
+
+		"interview_invitation": `
+<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="UTF-8">
+    <title>Einladung zum Vorstellungsgespräch</title>
+</head>
+<body style="font-family: Arial, sans-serif; line-height: 1.6; color: #333;">
+    <div style="max-width: 600px; margin: 0 auto; padding: 20px;">
+        <h1 style="color: #2c5aa0;">Einladung zum Vorstellungsgespräch</h1>
+        <p>Hallo {{.Name}},</p>
+        <p>vielen Dank für Ihre Bewerbung als {{.JobTitle}}. Wir möchten Sie gerne persönlich kennenlernen.</p>
+        <p>Bitte wählen Sie einen passenden Termin für das Vorstellungsgespräch aus:</p>
+        <div style="text-align: center; margin: 30px 0;">
+            <a href="{{.InvitationURL}}" style="background-color: #2c5aa0; color: white; padding: 12px 24px; text-decoration: none; border-radius: 4px; display: inline-block;">Termin wählen</a>
+        </div>
+        <p>Der Link ist bis zum {{.ExpiresAt}} gültig.</p>
+        <p>Bei Fragen erreichen Sie uns unter {{.SupportEmail}}.</p>
+        <p>Ihr Elterngeld-Portal Team</p>
+    </div>
+</body>
+</html>`,
+
+		"interview_scheduled": `
+<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="UTF-8">
+    <title>Vorstellungsgespräch bestätigt</title>
+</head>
+<body style="font-family: Arial, sans-serif; line-height: 1.6; color: #333;">
+    <div style="max-width: 600px; margin: 0 auto; padding: 20px;">
+        <h1 style="color: #2c5aa0;">Ihr Vorstellungsgespräch ist bestätigt</h1>
+        <p>Hallo {{.Name}},</p>
+        <p>wir freuen uns auf das Gespräch zur Stelle {{.JobTitle}}.</p>
+        <div style="background-color: #f5f5f5; padding: 15px; border-radius: 5px; margin: 20px 0;">
+            <p><strong>Termin:</strong> {{.AppointmentAt}} Uhr ({{.Duration}} Minuten)</p>
+            <p><strong>Gesprächspartner:</strong> {{.Interviewer}}</p>
+            <p><strong>Ort:</strong> {{if .Location}}{{.Location}}{{else if .IsOnline}}Online, den Link erhalten Sie vor dem Gespräch{{end}}</p>
+        </div>
+        <p>Die Kalendereinladung finden Sie im Anhang.</p>
+        <p>Bei Fragen erreichen Sie uns unter {{.SupportEmail}}.</p>
+        <p>Ihr Elterngeld-Portal Team</p>
+    </div>
+</body>
+</html>`,
+
+		"interview_scheduled_interviewer": `
+<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="UTF-8">
+    <title>Vorstellungsgespräch gebucht</title>
+</head>
+<body style="font-family: Arial, sans-serif; line-height: 1.6; color: #333;">
+    <div style="max-width: 600px; margin: 0 auto; padding: 20px;">
+        <h1 style="color: #2c5aa0;">Neues Vorstellungsgespräch</h1>
+        <p>Hallo {{.Name}},</p>
+        <p>{{.Applicant}} hat einen Termin für das Vorstellungsgespräch zur Stelle {{.JobTitle}} gewählt.</p>
+        <div style="background-color: #f5f5f5; padding: 15px; border-radius: 5px; margin: 20px 0;">
+            <p><strong>Termin:</strong> {{.AppointmentAt}} Uhr ({{.Duration}} Minuten)</p>
+            <p><strong>Ort:</strong> {{if .Location}}{{.Location}}{{else if .IsOnline}}Online{{end}}</p>
+        </div>
+        <p>Die Kalendereinladung finden Sie im Anhang.</p>
+        <p>Ihr Elterngeld-Portal Team</p>
+    </div>
+</body>
+</html>`,
+
+		"notification": `
+<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="UTF-8">
+    <title>{{.Title}}</title>
+</head>
+<body style="font-family: Arial, sans-serif; line-height: 1.6; color: #333;">
+    <div style="max-width: 600px; margin: 0 auto; padding: 20px;">
+        <h1 style="color: #2c5aa0;">{{.Title}}</h1>
+        <p>{{.Message}}</p>
+        <div style="text-align: center; margin: 30px 0;">
+            <a href="{{.DashboardURL}}" style="background-color: #2c5aa0; color: white; padding: 12px 24px; text-decoration: none; border-radius: 4px; display: inline-block;">Zum Dashboard</a>
+        </div>
+        <p>Bei Fragen erreichen Sie uns unter {{.SupportEmail}}.</p>
+        <p>Ihr Elterngeld-Portal Team</p>
+    </div>
+</body>
+</html>`,
+
+		"notification_digest": `
+<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="UTF-8">
+    <title>Ihre Benachrichtigungen</title>
+</head>
+<body style="font-family: Arial, sans-serif; line-height: 1.6; color: #333;">
+    <div style="max-width: 600px; margin: 0 auto; padding: 20px;">
+        <h1 style="color: #2c5aa0;">Ihre Benachrichtigungen</h1>
+        <p>Hallo {{.Name}},</p>
+        <p>hier sind Ihre Benachrichtigungen seit der letzten Zusammenfassung:</p>
+        <div style="background-color: #f8f9fa; padding: 20px; border-radius: 8px; margin: 20px 0;">
+            {{range .Items}}<p>
+                <strong>{{.Title}}</strong><br>
+                {{.Message}}<br>
+                <span style="color: #666;">{{.Time}}</span>
+            </p>{{end}}
+        </div>
+        <div style="text-align: center; margin: 30px 0;">
+            <a href="{{.DashboardURL}}" style="background-color: #2c5aa0; color: white; padding: 12px 24px; text-decoration: none; border-radius: 4px; display: inline-block;">Zum Dashboard</a>
+        </div>
+        <p>Sie erhalten diese Zusammenfassung, weil Sie für einige Benachrichtigungen eine stündliche oder tägliche Zusammenfassung gewählt haben. Das können Sie jederzeit in Ihren Benachrichtigungseinstellungen ändern.</p>
+        <p>Ihr Elterngeld-Portal Team</p>
+    </div>
+</body>
 </html>`,
 	}
 
@@ -479,11 +1346,27 @@ func (e *EmailService) renderTemplate(templateName string, data interface{}) (st
 		return "", fmt.Errorf("template %s not found", templateName)
 	}
 
-	// Parse and execute template
-	tmpl, err := template.New(templateName).Parse(templateStr)
+	// Use the English variant if available, German otherwise
+	signatureTemplate := beraterSignatureTemplate
+	if englishStr, ok := englishTemplates[templateName]; ok && lang == i18n.English {
+		templateStr = englishStr
+		signatureTemplate = englishBeraterSignatureTemplate
+	}
+
+	funcs := template.FuncMap{
+		"currency": func(amount float64, currency string) string {
+			return i18n.FormatCurrency(lang, amount, currency)
+		},
+	}
+
+	// Parse and execute template together with the shared partials
+	tmpl, err := template.New(templateName).Funcs(funcs).Parse(templateStr)
 	if err != nil {
 		return "", fmt.Errorf("failed to parse template: %w", err)
 	}
+	if _, err := tmpl.New("berater_signature").Parse(signatureTemplate); err != nil {
+		return "", fmt.Errorf("failed to parse signature template: %w", err)
+	}
 
 	var buf bytes.Buffer
 	if err := tmpl.Execute(&buf, data); err != nil {
@@ -493,20 +1376,87 @@ func (e *EmailService) renderTemplate(templateName string, data interface{}) (st
 	return buf.String(), nil
 }
 
+// beraterSignatureTemplate renders the personal signature block of a Berater
+const beraterSignatureTemplate = `
+<table style="margin-top: 30px; border-top: 1px solid #e0e0e0; padding-top: 15px;">
+    <tr>
+        {{if .PhotoURL}}<td style="vertical-align: top; padding-right: 15px;">
+            <img src="{{.PhotoURL}}" alt="{{.Name}}" width="64" height="64" style="border-radius: 50%;">
+        </td>{{end}}
+        <td style="vertical-align: top;">
+            <p style="margin: 0;">Herzliche Grüße</p>
+            <p style="margin: 0;"><strong>{{.Name}}</strong></p>
+            {{if .SignatureLines}}{{range .SignatureLines}}<p style="margin: 0; color: #666;">{{.}}</p>{{end}}{{else}}<p style="margin: 0; color: #666;">Elterngeld-Berater</p>
+            <p style="margin: 0; color: #666;">{{.Email}}{{if .Phone}} · {{.Phone}}{{end}}</p>{{end}}
+            {{if .CalendarURL}}<p style="margin: 5px 0 0 0;"><a href="{{.CalendarURL}}">Termin vereinbaren</a></p>{{end}}
+        </td>
+    </tr>
+</table>`
+
 // buildMessage builds the email message with headers
-func (e *EmailService) buildMessage(to []string, subject, body string) string {
+func (e *EmailService) buildMessage(emailData EmailData, body string) string {
 	headers := make(map[string]string)
-	headers["From"] = e.config.SMTP.FromEmail
-	headers["To"] = strings.Join(to, ", ")
-	headers["Subject"] = subject
+	headers["From"] = e.config.Email.From
+	if emailData.FromName != "" {
+		headers["From"] = fmt.Sprintf("%s via Elterngeld-Portal <%s>", emailData.FromName, e.config.Email.From)
+	}
+	if emailData.ReplyTo != "" {
+		headers["Reply-To"] = emailData.ReplyTo
+	}
+	headers["To"] = strings.Join(emailData.To, ", ")
+	headers["Subject"] = emailData.Subject
 	headers["MIME-Version"] = "1.0"
-	headers["Content-Type"] = "text/html; charset=UTF-8"
+	if emailData.MessageID != "" {
+		headers["Message-ID"] = emailData.MessageID
+	}
+	if emailData.InReplyTo != "" {
+		headers["In-Reply-To"] = emailData.InReplyTo
+		headers["References"] = emailData.InReplyTo
+	}
+
+	content := body
+	if len(emailData.Attachments) == 0 {
+		headers["Content-Type"] = "text/html; charset=UTF-8"
+	} else {
+		var boundary string
+		content, boundary = buildMultipart(body, emailData.Attachments)
+		headers["Content-Type"] = fmt.Sprintf("multipart/mixed; boundary=%q", boundary)
+	}
 
 	message := ""
 	for k, v := range headers {
 		message += fmt.Sprintf("%s: %s\r\n", k, v)
 	}
-	message += "\r\n" + body
+	message += "\r\n" + content
 
 	return message
+}
+
+// buildMultipart wraps the HTML body and the attachments in a multipart/mixed
+// body and returns it with its boundary. Writes to the buffer cannot fail.
+func buildMultipart(body string, attachments []Attachment) (string, string) {
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+
+	part, _ := writer.CreatePart(textproto.MIMEHeader{"Content-Type": {"text/html; charset=UTF-8"}})
+	part.Write([]byte(body))
+
+	for _, attachment := range attachments {
+		header := textproto.MIMEHeader{}
+		header.Set("Content-Type", attachment.ContentType)
+		header.Set("Content-Transfer-Encoding", "base64")
+		header.Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": attachment.FileName}))
+		part, _ := writer.CreatePart(header)
+
+		// Base64 lines must not be longer than 76 characters
+		encoded := base64.StdEncoding.EncodeToString(attachment.Content)
+		for len(encoded) > 76 {
+			part.Write([]byte(encoded[:76] + "\r\n"))
+			encoded = encoded[76:]
+		}
+		part.Write([]byte(encoded + "\r\n"))
+	}
+	writer.Close()
+
+	return buf.String(), writer.Boundary()
 }
\ No newline at end of file
@@ -0,0 +1,83 @@
+package email
+
+import (
+	"testing"
+
+	"elterngeld-portal/config"
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/pkg/i18n"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestNewBeraterSignature(t *testing.T) {
+	berater := &models.User{
+		FirstName:      "Anna",
+		LastName:       "Schmidt",
+		Email:          "anna@example.com",
+		EmailSignature: "Elterngeld-Expertin\r\n\r\n  Team Berlin  \n",
+	}
+
+	signature := NewBeraterSignature(berater)
+	assert.Equal(t, "Anna Schmidt", signature.Name)
+	assert.Equal(t, []string{"Elterngeld-Expertin", "Team Berlin"}, signature.SignatureLines)
+}
+
+func TestRenderBeraterSignature(t *testing.T) {
+	service := NewEmailService(&config.Config{}, zap.NewNop())
+	offer := OfferData{
+		Name:              "Maria Muster",
+		ApplicationNumber: "EG-2026-0001",
+		PackageName:       "Premium",
+		Price:             249,
+		Currency:          "EUR",
+		OfferURL:          "https://portal.example.com/r/abc",
+	}
+
+	t.Run("custom_signature", func(t *testing.T) {
+		offer.Berater = NewBeraterSignature(&models.User{
+			FirstName:      "Anna",
+			LastName:       "Schmidt",
+			Email:          "anna@example.com",
+			Phone:          "030 1234567",
+			EmailSignature: "Elterngeld-Expertin\nTeam Berlin",
+			PhotoURL:       "https://portal.example.com/avatars/anna.jpg",
+			CalendarURL:    "https://cal.example.com/anna",
+		})
+
+		body, err := service.renderTemplate("offer", i18n.German, offer)
+		require.NoError(t, err)
+		assert.Contains(t, body, "<strong>Anna Schmidt</strong>")
+		assert.Contains(t, body, `<p style="margin: 0; color: #666;">Elterngeld-Expertin</p>`)
+		assert.Contains(t, body, `<p style="margin: 0; color: #666;">Team Berlin</p>`)
+		assert.Contains(t, body, `<img src="https://portal.example.com/avatars/anna.jpg" alt="Anna Schmidt"`)
+		assert.Contains(t, body, `<a href="https://cal.example.com/anna">Termin vereinbaren</a>`)
+		// The custom signature replaces the default contact details
+		assert.NotContains(t, body, "Elterngeld-Berater")
+		assert.NotContains(t, body, "030 1234567")
+	})
+
+	t.Run("default_signature", func(t *testing.T) {
+		offer.Berater = NewBeraterSignature(&models.User{
+			FirstName: "Anna",
+			LastName:  "Schmidt",
+			Email:     "anna@example.com",
+			Phone:     "030 1234567",
+		})
+
+		body, err := service.renderTemplate("offer", i18n.German, offer)
+		require.NoError(t, err)
+		assert.Contains(t, body, "<strong>Anna Schmidt</strong>")
+		assert.Contains(t, body, "Elterngeld-Berater")
+		assert.Contains(t, body, "anna@example.com · 030 1234567")
+		assert.NotContains(t, body, "<img")
+		assert.NotContains(t, body, "Termin vereinbaren")
+
+		body, err = service.renderTemplate("offer", i18n.English, offer)
+		require.NoError(t, err)
+		assert.Contains(t, body, "Elterngeld advisor")
+		assert.Contains(t, body, "anna@example.com · 030 1234567")
+	})
+}
@@ -0,0 +1,424 @@
+package email
+
+// englishTemplates holds the English variants of the email templates.
+// Templates without an English variant are sent in German.
+var englishTemplates = map[string]string{
+	"welcome": `
+<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="UTF-8">
+    <title>Welcome</title>
+</head>
+<body style="font-family: Arial, sans-serif; line-height: 1.6; color: #333;">
+    <div style="max-width: 600px; margin: 0 auto; padding: 20px;">
+        <h1 style="color: #2c5aa0;">Welcome to Elterngeld-Portal!</h1>
+        <p>Hello {{.Name}},</p>
+        <p>thank you for registering with Elterngeld-Portal. Please confirm your email address to activate your account:</p>
+        <div style="text-align: center; margin: 30px 0;">
+            <a href="{{.VerificationURL}}" style="background-color: #2c5aa0; color: white; padding: 12px 24px; text-decoration: none; border-radius: 4px; display: inline-block;">Confirm email</a>
+        </div>
+        <p>If you have any questions, feel free to contact us at {{.SupportEmail}}.</p>
+        <p>Your Elterngeld-Portal team</p>
+    </div>
+</body>
+</html>`,
+
+	"booking_confirmation": `
+<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="UTF-8">
+    <title>Booking confirmation</title>
+</head>
+<body style="font-family: Arial, sans-serif; line-height: 1.6; color: #333;">
+    <div style="max-width: 600px; margin: 0 auto; padding: 20px;">
+        <h1 style="color: #2c5aa0;">Booking confirmation</h1>
+        <p>Hello {{.Name}},</p>
+        <p>your booking has been confirmed!</p>
+        <div style="background-color: #f8f9fa; padding: 20px; border-radius: 8px; margin: 20px 0;">
+            <h3>Booking details:</h3>
+            <p><strong>Booking number:</strong> {{.BookingRef}}</p>
+            <p><strong>Package:</strong> {{.PackageName}}</p>
+            <p><strong>Amount:</strong> {{currency .TotalPrice .Currency}}</p>
+            {{if .TimeslotDate}}<p><strong>Appointment:</strong> {{.TimeslotDate}}</p>{{end}}
+            {{if .OnlineMeetingURL}}<p><strong>Online meeting:</strong> <a href="{{.OnlineMeetingURL}}">Join meeting</a></p>{{end}}
+        </div>
+        <p>If you have any questions, contact us at {{.SupportEmail}}.</p>
+        <p>Your Elterngeld-Portal team</p>
+    </div>
+</body>
+</html>`,
+
+	"todo_notification": `
+<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="UTF-8">
+    <title>New task</title>
+</head>
+<body style="font-family: Arial, sans-serif; line-height: 1.6; color: #333;">
+    <div style="max-width: 600px; margin: 0 auto; padding: 20px;">
+        <h1 style="color: #2c5aa0;">New task assigned</h1>
+        <p>Hello {{.Name}},</p>
+        <p>{{.AssignedBy}} has assigned a new task to you:</p>
+        <div style="background-color: #f8f9fa; padding: 20px; border-radius: 8px; margin: 20px 0;">
+            <h3>{{.TodoTitle}}</h3>
+            <p>{{.TodoDesc}}</p>
+            {{if .DueDate}}<p><strong>Due on:</strong> {{.DueDate}}</p>{{end}}
+            <p><strong>Priority:</strong> {{.Priority}}</p>
+        </div>
+        <div style="text-align: center; margin: 30px 0;">
+            <a href="{{.DashboardURL}}" style="background-color: #2c5aa0; color: white; padding: 12px 24px; text-decoration: none; border-radius: 4px; display: inline-block;">Go to dashboard</a>
+        </div>
+        <p>If you have any questions, contact us at {{.SupportEmail}}.</p>
+        <p>Your Elterngeld-Portal team</p>
+    </div>
+</body>
+</html>`,
+
+	"lead_assignment": `
+<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="UTF-8">
+    <title>New lead assigned</title>
+</head>
+<body style="font-family: Arial, sans-serif; line-height: 1.6; color: #333;">
+    <div style="max-width: 600px; margin: 0 auto; padding: 20px;">
+        <h1 style="color: #2c5aa0;">New lead assigned</h1>
+        <p>Hello {{.BeraterName}},</p>
+        <p>a new lead has been assigned to you:</p>
+        <div style="background-color: #f8f9fa; padding: 20px; border-radius: 8px; margin: 20px 0;">
+            <h3>{{.LeadTitle}}</h3>
+            <p>{{.LeadDesc}}</p>
+            <p><strong>Customer:</strong> {{.CustomerName}} ({{.CustomerEmail}})</p>
+            <p><strong>Priority:</strong> {{.Priority}}</p>
+        </div>
+        <div style="text-align: center; margin: 30px 0;">
+            <a href="{{.DashboardURL}}" style="background-color: #2c5aa0; color: white; padding: 12px 24px; text-decoration: none; border-radius: 4px; display: inline-block;">Open lead</a>
+        </div>
+        <p>Your Elterngeld-Portal team</p>
+    </div>
+</body>
+</html>`,
+
+	"lead_assignment_confirmation": `
+<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="UTF-8">
+    <title>Your personal advisor</title>
+</head>
+<body style="font-family: Arial, sans-serif; line-height: 1.6; color: #333;">
+    <div style="max-width: 600px; margin: 0 auto; padding: 20px;">
+        <h1 style="color: #2c5aa0;">Your personal advisor</h1>
+        <p>Hello {{.Name}},</p>
+        <p>from now on I am your personal contact for your Elterngeld application and will guide you through all further steps.</p>
+        <div style="background-color: #f8f9fa; padding: 20px; border-radius: 8px; margin: 20px 0;">
+            <h3>{{.LeadTitle}}</h3>
+            <p><strong>Application number:</strong> {{.ApplicationNumber}}</p>
+        </div>
+        {{if .Berater.CalendarURL}}<div style="text-align: center; margin: 30px 0;">
+            <a href="{{.Berater.CalendarURL}}" style="background-color: #2c5aa0; color: white; padding: 12px 24px; text-decoration: none; border-radius: 4px; display: inline-block;">Book an appointment</a>
+        </div>{{end}}
+        <p>Simply reply to this email if you have any questions.</p>
+        {{template "berater_signature" .Berater}}
+    </div>
+</body>
+</html>`,
+
+	"offer": `
+<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="UTF-8">
+    <title>Your offer</title>
+</head>
+<body style="font-family: Arial, sans-serif; line-height: 1.6; color: #333;">
+    <div style="max-width: 600px; margin: 0 auto; padding: 20px;">
+        <h1 style="color: #2c5aa0;">Your personal offer</h1>
+        <p>Hello {{.Name}},</p>
+        {{if .Message}}<p>{{.Message}}</p>{{else}}<p>as discussed, please find your personal offer below.</p>{{end}}
+        <div style="background-color: #f8f9fa; padding: 20px; border-radius: 8px; margin: 20px 0;">
+            <h3>{{.PackageName}}</h3>
+            <p><strong>Application number:</strong> {{.ApplicationNumber}}</p>
+            <p><strong>Price:</strong> {{currency .Price .Currency}}</p>
+            {{if .ValidUntil}}<p><strong>Valid until:</strong> {{.ValidUntil}}</p>{{end}}
+        </div>
+        {{if .OfferURL}}<div style="text-align: center; margin: 30px 0;">
+            <a href="{{.OfferURL}}" style="background-color: #2c5aa0; color: white; padding: 12px 24px; text-decoration: none; border-radius: 4px; display: inline-block;">View offer</a>
+        </div>{{end}}
+        {{if .Berater.CalendarURL}}<p>Would you like to discuss the offer in person? <a href="{{.Berater.CalendarURL}}">Book an appointment here</a>.</p>{{end}}
+        {{template "berater_signature" .Berater}}
+    </div>
+</body>
+</html>`,
+
+	"reply": `
+<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="UTF-8">
+    <title>Your inquiry</title>
+</head>
+<body style="font-family: Arial, sans-serif; line-height: 1.6; color: #333;">
+    <div style="max-width: 600px; margin: 0 auto; padding: 20px;">
+        <div style="white-space: pre-line;">{{.Body}}</div>
+        {{template "berater_signature" .Berater}}
+        {{if .Reference}}<p style="margin-top: 20px; color: #999; font-size: 12px;">Reference: {{.Reference}} - please quote it in any reply</p>{{end}}
+    </div>
+</body>
+</html>`,
+
+	"payment_confirmation": `
+<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="UTF-8">
+    <title>Payment confirmation</title>
+</head>
+<body style="font-family: Arial, sans-serif; line-height: 1.6; color: #333;">
+    <div style="max-width: 600px; margin: 0 auto; padding: 20px;">
+        <h1 style="color: #2c5aa0;">Payment confirmation</h1>
+        <p>Hello {{.Name}},</p>
+        <p>your payment has been processed successfully!</p>
+        <div style="background-color: #f8f9fa; padding: 20px; border-radius: 8px; margin: 20px 0;">
+            <h3>Payment details:</h3>
+            <p><strong>Booking number:</strong> {{.BookingRef}}</p>
+            <p><strong>Package:</strong> {{.PackageName}}</p>
+            {{if .CreditAmount}}<p><strong>Credit applied:</strong> {{currency .CreditAmount .Currency}}</p>{{end}}
+            <p><strong>Amount:</strong> {{currency .Amount .Currency}}</p>
+            <p><strong>Payment date:</strong> {{.PaymentDate}}</p>
+        </div>
+        {{if .InvoiceNumber}}<p>Please find invoice {{.InvoiceNumber}} attached.</p>{{end}}
+        <p>If you have any questions, contact us at {{.SupportEmail}}.</p>
+        <p>Your Elterngeld-Portal team</p>
+    </div>
+</body>
+</html>`,
+
+	"password_reset": `
+<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="UTF-8">
+    <title>Reset password</title>
+</head>
+<body style="font-family: Arial, sans-serif; line-height: 1.6; color: #333;">
+    <div style="max-width: 600px; margin: 0 auto; padding: 20px;">
+        <h1 style="color: #2c5aa0;">Reset password</h1>
+        <p>Hello {{.Name}},</p>
+        <p>you have requested to reset your password. Click the following link to create a new password:</p>
+        <div style="text-align: center; margin: 30px 0;">
+            <a href="{{.ResetURL}}" style="background-color: #2c5aa0; color: white; padding: 12px 24px; text-decoration: none; border-radius: 4px; display: inline-block;">Reset password</a>
+        </div>
+        <p>This link is valid for 1 hour. If you did not make this request, please ignore this email.</p>
+        <p>If you have any questions, contact us at {{.SupportEmail}}.</p>
+        <p>Your Elterngeld-Portal team</p>
+    </div>
+</body>
+</html>`,
+
+	"berater_invitation": `
+<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="UTF-8">
+    <title>Invitation as a Berater</title>
+</head>
+<body style="font-family: Arial, sans-serif; line-height: 1.6; color: #333;">
+    <div style="max-width: 600px; margin: 0 auto; padding: 20px;">
+        <h1 style="color: #2c5aa0;">Welcome to the advisor team!</h1>
+        <p>Hello {{.Name}},</p>
+        <p>{{.InvitedBy}} has invited you to join Elterngeld-Portal as an advisor. Set your password and complete your profile, calendar and availability:</p>
+        <div style="text-align: center; margin: 30px 0;">
+            <a href="{{.InvitationURL}}" style="background-color: #2c5aa0; color: white; padding: 12px 24px; text-decoration: none; border-radius: 4px; display: inline-block;">Accept invitation</a>
+        </div>
+        <p>The invitation is valid until {{.ExpiresAt}}. Customers can book appointments with you once an administrator has approved your profile.</p>
+        <p>If you have any questions, contact us at {{.SupportEmail}}.</p>
+        <p>Your Elterngeld-Portal team</p>
+    </div>
+</body>
+</html>`,
+	"daily_digest": `
+<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="UTF-8">
+    <title>Daily digest</title>
+</head>
+<body style="font-family: Arial, sans-serif; line-height: 1.6; color: #333;">
+    <div style="max-width: 600px; margin: 0 auto; padding: 20px;">
+        <h1 style="color: #2c5aa0;">Your daily digest</h1>
+        <p>Hello {{.Name}},</p>
+        <p>this is what happened since your last digest:</p>
+        <div style="background-color: #f8f9fa; padding: 20px; border-radius: 8px; margin: 20px 0;">
+            {{range .Items}}<p>
+                <strong>{{if eq .Type "lead.created"}}New lead{{else if eq .Type "payment.large"}}Large payment{{else if eq .Type "booking.cancelled"}}Cancelled booking{{else if eq .Type "lead.sla_breached"}}SLA breached{{end}}:</strong>
+                {{if .URL}}<a href="{{.URL}}">{{.Title}}</a>{{else}}{{.Title}}{{end}}{{if .Amount}} · {{currency .Amount .Currency}}{{end}}<br>
+                <span style="color: #666;">{{.Time}}</span>
+            </p>{{end}}
+        </div>
+        <div style="text-align: center; margin: 30px 0;">
+            <a href="{{.DashboardURL}}" style="background-color: #2c5aa0; color: white; padding: 12px 24px; text-decoration: none; border-radius: 4px; display: inline-block;">Go to dashboard</a>
+        </div>
+        <p>You receive this email because you enabled the daily digest in your notification settings.</p>
+        <p>Your Elterngeld-Portal team</p>
+    </div>
+</body>
+</html>`,
+	"no_show_follow_up": `
+<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="UTF-8">
+    <title>New appointment</title>
+</head>
+<body style="font-family: Arial, sans-serif; line-height: 1.6; color: #333;">
+    <div style="max-width: 600px; margin: 0 auto; padding: 20px;">
+        <h1 style="color: #2c5aa0;">We missed you</h1>
+        <p>Hello {{.Name}},</p>
+        <p>unfortunately we could not reach you for your appointment "{{.Title}}" on {{.AppointmentAt}} (booking reference {{.BookingRef}}).</p>
+        <p>That can happen. Simply choose a new appointment that suits you better:</p>
+        <div style="text-align: center; margin: 30px 0;">
+            <a href="{{.RescheduleURL}}" style="background-color: #2c5aa0; color: white; padding: 12px 24px; text-decoration: none; border-radius: 4px; display: inline-block;">Choose a new appointment</a>
+        </div>
+        <p>If you have any questions, contact us at {{.SupportEmail}}.</p>
+        <p>Your Elterngeld-Portal team</p>
+    </div>
+</body>
+</html>`,
+	"application_confirmation": `
+<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="UTF-8">
+    <title>Please confirm your application</title>
+</head>
+<body style="font-family: Arial, sans-serif; line-height: 1.6; color: #333;">
+    <div style="max-width: 600px; margin: 0 auto; padding: 20px;">
+        <h1 style="color: #2c5aa0;">Thank you for your application</h1>
+        <p>Hello {{.Name}},</p>
+        <p>we have received your application as {{.JobTitle}}. Please confirm your email address so we can process your application:</p>
+        <div style="text-align: center; margin: 30px 0;">
+            <a href="{{.VerifyURL}}" style="background-color: #2c5aa0; color: white; padding: 12px 24px; text-decoration: none; border-radius: 4px; display: inline-block;">Confirm email address</a>
+        </div>
+        <p>You can check the current status of your application at any time here: <a href="{{.TrackingURL}}">{{.TrackingURL}}</a></p>
+        <p>If you did not apply with us, you can ignore this email.</p>
+        <p>If you have any questions, contact us at {{.SupportEmail}}.</p>
+        <p>Your Elterngeld-Portal team</p>
+    </div>
+</body>
+</html>`,
+	"interview_invitation": `
+<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="UTF-8">
+    <title>Invitation to an interview</title>
+</head>
+<body style="font-family: Arial, sans-serif; line-height: 1.6; color: #333;">
+    <div style="max-width: 600px; margin: 0 auto; padding: 20px;">
+        <h1 style="color: #2c5aa0;">Invitation to an interview</h1>
+        <p>Hello {{.Name}},</p>
+        <p>thank you for your application as {{.JobTitle}}. We would like to get to know you in person.</p>
+        <p>Please choose a suitable date for the interview:</p>
+        <div style="text-align: center; margin: 30px 0;">
+            <a href="{{.InvitationURL}}" style="background-color: #2c5aa0; color: white; padding: 12px 24px; text-decoration: none; border-radius: 4px; display: inline-block;">Choose a date</a>
+        </div>
+        <p>The link is valid until {{.ExpiresAt}}.</p>
+        <p>If you have any questions, contact us at {{.SupportEmail}}.</p>
+        <p>Your Elterngeld-Portal team</p>
+    </div>
+</body>
+</html>`,
+	"interview_scheduled": `
+<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="UTF-8">
+    <title>Interview confirmed</title>
+</head>
+<body style="font-family: Arial, sans-serif; line-height: 1.6; color: #333;">
+    <div style="max-width: 600px; margin: 0 auto; padding: 20px;">
+        <h1 style="color: #2c5aa0;">Your interview is confirmed</h1>
+        <p>Hello {{.Name}},</p>
+        <p>we look forward to talking with you about the position {{.JobTitle}}.</p>
+        <div style="background-color: #f5f5f5; padding: 15px; border-radius: 5px; margin: 20px 0;">
+            <p><strong>Date:</strong> {{.AppointmentAt}} ({{.Duration}} minutes)</p>
+            <p><strong>Interviewer:</strong> {{.Interviewer}}</p>
+            <p><strong>Location:</strong> {{if .Location}}{{.Location}}{{else if .IsOnline}}Online, you will receive the link before the interview{{end}}</p>
+        </div>
+        <p>The calendar invitation is attached.</p>
+        <p>If you have any questions, contact us at {{.SupportEmail}}.</p>
+        <p>Your Elterngeld-Portal team</p>
+    </div>
+</body>
+</html>`,
+	"interview_scheduled_interviewer": `
+<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="UTF-8">
+    <title>Interview booked</title>
+</head>
+<body style="font-family: Arial, sans-serif; line-height: 1.6; color: #333;">
+    <div style="max-width: 600px; margin: 0 auto; padding: 20px;">
+        <h1 style="color: #2c5aa0;">New interview</h1>
+        <p>Hello {{.Name}},</p>
+        <p>{{.Applicant}} has chosen a date for the interview for the position {{.JobTitle}}.</p>
+        <div style="background-color: #f5f5f5; padding: 15px; border-radius: 5px; margin: 20px 0;">
+            <p><strong>Date:</strong> {{.AppointmentAt}} ({{.Duration}} minutes)</p>
+            <p><strong>Location:</strong> {{if .Location}}{{.Location}}{{else if .IsOnline}}Online{{end}}</p>
+        </div>
+        <p>The calendar invitation is attached.</p>
+        <p>Your Elterngeld-Portal team</p>
+    </div>
+</body>
+</html>`,
+	"notification_digest": `
+<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="UTF-8">
+    <title>Your notifications</title>
+</head>
+<body style="font-family: Arial, sans-serif; line-height: 1.6; color: #333;">
+    <div style="max-width: 600px; margin: 0 auto; padding: 20px;">
+        <h1 style="color: #2c5aa0;">Your notifications</h1>
+        <p>Hello {{.Name}},</p>
+        <p>here are your notifications since the last summary:</p>
+        <div style="background-color: #f8f9fa; padding: 20px; border-radius: 8px; margin: 20px 0;">
+            {{range .Items}}<p>
+                <strong>{{.Title}}</strong><br>
+                {{.Message}}<br>
+                <span style="color: #666;">{{.Time}}</span>
+            </p>{{end}}
+        </div>
+        <div style="text-align: center; margin: 30px 0;">
+            <a href="{{.DashboardURL}}" style="background-color: #2c5aa0; color: white; padding: 12px 24px; text-decoration: none; border-radius: 4px; display: inline-block;">Go to dashboard</a>
+        </div>
+        <p>You receive this summary because you chose an hourly or daily summary for some notifications. You can change this in your notification settings at any time.</p>
+        <p>Your Elterngeld-Portal team</p>
+    </div>
+</body>
+</html>`,
+}
+
+// englishBeraterSignatureTemplate renders the personal signature block of a Berater in English
+const englishBeraterSignatureTemplate = `
+<table style="margin-top: 30px; border-top: 1px solid #e0e0e0; padding-top: 15px;">
+    <tr>
+        {{if .PhotoURL}}<td style="vertical-align: top; padding-right: 15px;">
+            <img src="{{.PhotoURL}}" alt="{{.Name}}" width="64" height="64" style="border-radius: 50%;">
+        </td>{{end}}
+        <td style="vertical-align: top;">
+            <p style="margin: 0;">Kind regards</p>
+            <p style="margin: 0;"><strong>{{.Name}}</strong></p>
+            {{if .SignatureLines}}{{range .SignatureLines}}<p style="margin: 0; color: #666;">{{.}}</p>{{end}}{{else}}<p style="margin: 0; color: #666;">Elterngeld advisor</p>
+            <p style="margin: 0; color: #666;">{{.Email}}{{if .Phone}} · {{.Phone}}{{end}}</p>{{end}}
+            {{if .CalendarURL}}<p style="margin: 5px 0 0 0;"><a href="{{.CalendarURL}}">Book an appointment</a></p>{{end}}
+        </td>
+    </tr>
+</table>`
@@ -0,0 +1,158 @@
+// Package emailcheck checks in the background whether the email addresses of
+// users can receive mail: addresses of disposable email providers and of
+// domains without mail servers are marked, and the status is copied to the
+// user's leads so Beraters can skip unreachable contacts.
+package emailcheck
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"time"
+
+	"elterngeld-portal/config"
+	"elterngeld-portal/internal/models"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// batchSize is the number of unchecked users looked up per run
+const batchSize = 100
+
+// Resolver looks up the DNS records of a domain; net.DefaultResolver is one
+type Resolver interface {
+	LookupMX(ctx context.Context, name string) ([]*net.MX, error)
+	LookupHost(ctx context.Context, host string) ([]string, error)
+}
+
+// DisposableChecker tells addresses of disposable email providers apart
+type DisposableChecker interface {
+	IsDisposable(email string) bool
+}
+
+// Service checks the email addresses of users and marks their leads
+type Service struct {
+	db         *gorm.DB
+	logger     *zap.Logger
+	resolver   Resolver
+	disposable DisposableChecker
+	timeout    time.Duration
+	now        func() time.Time
+}
+
+func NewService(db *gorm.DB, logger *zap.Logger, cfg *config.Config, resolver Resolver, disposable DisposableChecker) *Service {
+	return &Service{
+		db:         db,
+		logger:     logger,
+		resolver:   resolver,
+		disposable: disposable,
+		timeout:    cfg.Abuse.EmailCheckTimeout,
+		now:        time.Now,
+	}
+}
+
+// Check returns the status of an email address. DNS failures other than a
+// missing domain are returned as error so the address is checked again later.
+func (s *Service) Check(ctx context.Context, email string) (models.EmailStatus, error) {
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return models.EmailStatusUndeliverable, nil
+	}
+	domain := strings.TrimSuffix(strings.ToLower(strings.TrimSpace(email[at+1:])), ".")
+	if domain == "" {
+		return models.EmailStatusUndeliverable, nil
+	}
+	if s.disposable.IsDisposable(email) {
+		return models.EmailStatusDisposable, nil
+	}
+
+	if s.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.timeout)
+		defer cancel()
+	}
+
+	records, err := s.resolver.LookupMX(ctx, domain)
+	switch {
+	case err == nil && len(records) == 1 && records[0].Host == ".":
+		// A null MX record: the domain explicitly accepts no mail
+		return models.EmailStatusUndeliverable, nil
+	case err == nil && len(records) > 0:
+		return models.EmailStatusValid, nil
+	case err != nil && !isNotFound(err):
+		return "", err
+	}
+
+	// Without MX records mail is delivered to the address records of the domain
+	if _, err := s.resolver.LookupHost(ctx, domain); err != nil {
+		if isNotFound(err) {
+			return models.EmailStatusUndeliverable, nil
+		}
+		return "", err
+	}
+	return models.EmailStatusValid, nil
+}
+
+// CheckPending checks a batch of users whose email address has not been
+// checked yet and copies the result to their leads. It returns the number of
+// users checked.
+func (s *Service) CheckPending(ctx context.Context) (int, error) {
+	var users []models.User
+	if err := s.db.Select("id", "email").
+		Where("COALESCE(email_status, '') = ''").
+		Order("created_at").
+		Limit(batchSize).
+		Find(&users).Error; err != nil {
+		return 0, err
+	}
+
+	// Addresses of the same domain share their result within a run
+	byDomain := map[string]models.EmailStatus{}
+	checked := 0
+	for _, user := range users {
+		if ctx.Err() != nil {
+			break
+		}
+
+		domain := strings.ToLower(user.Email[strings.LastIndex(user.Email, "@")+1:])
+		status, ok := byDomain[domain]
+		if !ok {
+			var err error
+			if status, err = s.Check(ctx, user.Email); err != nil {
+				s.logger.Warn("Failed to check email domain", zap.String("domain", domain), zap.Error(err))
+				continue
+			}
+			byDomain[domain] = status
+		}
+
+		if err := s.mark(&user, status); err != nil {
+			return checked, err
+		}
+		checked++
+	}
+
+	// Leads created after their user was checked
+	err := s.db.Exec(`UPDATE leads SET email_status = (SELECT users.email_status FROM users WHERE users.id = leads.user_id)
+		WHERE COALESCE(email_status, '') = ''
+		AND EXISTS (SELECT 1 FROM users WHERE users.id = leads.user_id AND COALESCE(users.email_status, '') <> '')`).Error
+	return checked, err
+}
+
+func (s *Service) mark(user *models.User, status models.EmailStatus) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.User{}).Where("id = ?", user.ID).Updates(map[string]interface{}{
+			"email_status":     status,
+			"email_checked_at": s.now(),
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.Lead{}).Where("user_id = ?", user.ID).Update("email_status", status).Error
+	})
+}
+
+func isNotFound(err error) bool {
+	var dnsErr *net.DNSError
+	return errors.As(err, &dnsErr) && dnsErr.IsNotFound
+}
@@ -0,0 +1,163 @@
+package emailcheck
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+
+	"elterngeld-portal/config"
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/tests/testutils"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// fakeResolver knows the MX and address records of a few domains; unknown
+// domains do not exist
+type fakeResolver struct {
+	mx      map[string][]*net.MX
+	hosts   map[string][]string
+	failing map[string]bool
+	lookups int
+}
+
+func (r *fakeResolver) LookupMX(ctx context.Context, name string) ([]*net.MX, error) {
+	r.lookups++
+	if r.failing[name] {
+		return nil, &net.DNSError{Err: "server misbehaving", Name: name, IsTemporary: true}
+	}
+	if records, ok := r.mx[name]; ok {
+		return records, nil
+	}
+	return nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+}
+
+func (r *fakeResolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	if addrs, ok := r.hosts[host]; ok {
+		return addrs, nil
+	}
+	return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+}
+
+type fakeDisposable struct{}
+
+func (fakeDisposable) IsDisposable(email string) bool {
+	return strings.HasSuffix(email, "@yopmail.com")
+}
+
+func TestCheck(t *testing.T) {
+	_, service, resolver := setupTestService(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		email  string
+		status models.EmailStatus
+	}{
+		{"anna@example.com", models.EmailStatusValid},
+		{"ben@Example.com.", models.EmailStatusValid},
+		{"carla@a-records-only.de", models.EmailStatusValid},
+		{"dora@no-mail.de", models.EmailStatusUndeliverable},
+		{"erik@does-not-exist.de", models.EmailStatusUndeliverable},
+		{"spam@yopmail.com", models.EmailStatusDisposable},
+		{"no-domain@", models.EmailStatusUndeliverable},
+	}
+	for _, tt := range tests {
+		status, err := service.Check(ctx, tt.email)
+		require.NoError(t, err, tt.email)
+		assert.Equal(t, tt.status, status, tt.email)
+	}
+
+	// Temporary DNS failures are retried later instead of marking the address
+	resolver.failing["flaky.de"] = true
+	_, err := service.Check(ctx, "frida@flaky.de")
+	assert.Error(t, err)
+}
+
+func TestCheckPending(t *testing.T) {
+	db, service, resolver := setupTestService(t)
+	ctx := context.Background()
+
+	valid := createTestUser(t, db, "anna@example.com")
+	sameDomain := createTestUser(t, db, "ben@example.com")
+	undeliverable := createTestUser(t, db, "dora@does-not-exist.de")
+	flaky := createTestUser(t, db, "frida@flaky.de")
+	resolver.failing["flaky.de"] = true
+
+	lead := createTestLead(t, db, undeliverable.ID)
+
+	checked, err := service.CheckPending(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 3, checked)
+	// Addresses of the same domain are looked up once
+	assert.Equal(t, 3, resolver.lookups)
+
+	assertStatus := func(user *models.User, status models.EmailStatus) {
+		t.Helper()
+		var reloaded models.User
+		require.NoError(t, db.First(&reloaded, "id = ?", user.ID).Error)
+		assert.Equal(t, status, reloaded.EmailStatus, user.Email)
+	}
+	assertStatus(valid, models.EmailStatusValid)
+	assertStatus(sameDomain, models.EmailStatusValid)
+	assertStatus(undeliverable, models.EmailStatusUndeliverable)
+	assertStatus(flaky, models.EmailStatusUnchecked)
+
+	var reloaded models.Lead
+	require.NoError(t, db.First(&reloaded, "id = ?", lead.ID).Error)
+	assert.Equal(t, models.EmailStatusUndeliverable, reloaded.EmailStatus)
+
+	// Leads created later get the status of their already checked user
+	later := createTestLead(t, db, valid.ID)
+	delete(resolver.failing, "flaky.de")
+	resolver.mx["flaky.de"] = []*net.MX{{Host: "mx.flaky.de.", Pref: 10}}
+
+	checked, err = service.CheckPending(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, checked)
+	assertStatus(flaky, models.EmailStatusValid)
+	var reloadedLater models.Lead
+	require.NoError(t, db.First(&reloadedLater, "id = ?", later.ID).Error)
+	assert.Equal(t, models.EmailStatusValid, reloadedLater.EmailStatus)
+}
+
+func setupTestService(t *testing.T) (*gorm.DB, *Service, *fakeResolver) {
+	t.Helper()
+	db := testutils.NewSQLiteDB(t, &models.User{}, &models.Lead{})
+
+	resolver := &fakeResolver{
+		mx: map[string][]*net.MX{
+			"example.com": {{Host: "mx.example.com.", Pref: 10}},
+			"no-mail.de":  {{Host: ".", Pref: 0}},
+		},
+		hosts: map[string][]string{
+			"a-records-only.de": {"203.0.113.7"},
+		},
+		failing: map[string]bool{},
+	}
+	cfg := &config.Config{}
+	return db, NewService(db, zap.NewNop(), cfg, resolver, fakeDisposable{}), resolver
+}
+
+func createTestUser(t *testing.T, db *gorm.DB, email string) *models.User {
+	t.Helper()
+	user := testutils.CreateTestUser(t, db, models.RoleUser)
+	require.NoError(t, db.Model(user).Update("email", email).Error)
+	user.Email = email
+	return user
+}
+
+func createTestLead(t *testing.T, db *gorm.DB, userID uuid.UUID) *models.Lead {
+	t.Helper()
+	lead := &models.Lead{
+		UserID: userID,
+		Title:  fmt.Sprintf("Elterngeld %s", uuid.New().String()[:8]),
+	}
+	require.NoError(t, db.Create(lead).Error)
+	return lead
+}
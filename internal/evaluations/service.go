@@ -0,0 +1,352 @@
+// Package evaluations lets reviewers rate job applications on configurable
+// criteria and record structured feedback per recruiting stage. The average
+// of the evaluation scores is kept on the application so the application
+// list can be sorted and filtered by it.
+package evaluations
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"elterngeld-portal/internal/models"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrCriterionNotFound is returned for unknown evaluation criteria
+	ErrCriterionNotFound = errors.New("evaluation criterion not found")
+	// ErrDuplicateCriterion is returned when another criterion has the same name
+	ErrDuplicateCriterion = errors.New("evaluation criterion already exists")
+	// ErrCriterionInUse is returned when deleting a criterion that was rated; deactivate it instead
+	ErrCriterionInUse = errors.New("evaluation criterion has ratings")
+	// ErrApplicationNotFound is returned for unknown or anonymized applications
+	ErrApplicationNotFound = errors.New("application not found")
+	// ErrInvalidRating is returned for ratings of unknown, inactive or repeated criteria
+	ErrInvalidRating = errors.New("ratings must cover distinct active criteria")
+	// ErrEmptyEvaluation is returned for evaluations without ratings, recommendation or notes
+	ErrEmptyEvaluation = errors.New("evaluation has no ratings, recommendation or notes")
+)
+
+// CriterionInput describes an evaluation criterion. New criteria are active
+// unless IsActive is false.
+type CriterionInput struct {
+	Name        string `json:"name" binding:"required,max=100"`
+	Description string `json:"description" binding:"max=1000"`
+	Weight      int    `json:"weight" binding:"required,min=1,max=10"`
+	Position    int    `json:"position"`
+	IsActive    *bool  `json:"is_active"`
+}
+
+// RatingInput is the rating of one criterion
+type RatingInput struct {
+	CriterionID uuid.UUID `json:"criterion_id" binding:"required"`
+	Rating      int       `json:"rating" binding:"required,min=1,max=5"`
+	Comment     string    `json:"comment" binding:"max=2000"`
+}
+
+// EvaluationInput is the feedback of a reviewer in one stage. Submitting it
+// again for the same stage replaces the earlier feedback.
+type EvaluationInput struct {
+	Stage          models.ApplicationStatus `json:"stage" binding:"required,oneof=reviewing screening interview"`
+	Recommendation models.Recommendation    `json:"recommendation" binding:"omitempty,oneof=strong_yes yes no strong_no"`
+	Notes          string                   `json:"notes" binding:"max=10000"`
+	Ratings        []RatingInput            `json:"ratings" binding:"dive"`
+}
+
+// Service manages evaluation criteria and application evaluations
+type Service struct {
+	db     *gorm.DB
+	logger *zap.Logger
+	now    func() time.Time
+}
+
+func NewService(db *gorm.DB, logger *zap.Logger) *Service {
+	return &Service{
+		db:     db,
+		logger: logger,
+		now:    time.Now,
+	}
+}
+
+// Criteria returns the evaluation criteria by position
+func (s *Service) Criteria(activeOnly bool) ([]models.EvaluationCriterion, error) {
+	query := s.db.Order("position ASC, name ASC")
+	if activeOnly {
+		query = query.Where("is_active = ?", true)
+	}
+
+	criteria := []models.EvaluationCriterion{}
+	if err := query.Find(&criteria).Error; err != nil {
+		return nil, fmt.Errorf("failed to load evaluation criteria: %w", err)
+	}
+	return criteria, nil
+}
+
+// CreateCriterion adds an evaluation criterion
+func (s *Service) CreateCriterion(input CriterionInput) (*models.EvaluationCriterion, error) {
+	name := strings.TrimSpace(input.Name)
+	if err := s.checkName(name, uuid.Nil); err != nil {
+		return nil, err
+	}
+
+	criterion := &models.EvaluationCriterion{
+		Name:        name,
+		Description: strings.TrimSpace(input.Description),
+		Weight:      input.Weight,
+		Position:    input.Position,
+		IsActive:    input.IsActive == nil || *input.IsActive,
+	}
+	if err := s.db.Create(criterion).Error; err != nil {
+		return nil, fmt.Errorf("failed to create evaluation criterion: %w", err)
+	}
+	return criterion, nil
+}
+
+// UpdateCriterion changes an evaluation criterion. A new weight applies to
+// evaluations submitted from then on.
+func (s *Service) UpdateCriterion(id uuid.UUID, input CriterionInput) (*models.EvaluationCriterion, error) {
+	criterion, err := s.findCriterion(id)
+	if err != nil {
+		return nil, err
+	}
+
+	name := strings.TrimSpace(input.Name)
+	if err := s.checkName(name, criterion.ID); err != nil {
+		return nil, err
+	}
+
+	updates := map[string]interface{}{
+		"name":        name,
+		"description": strings.TrimSpace(input.Description),
+		"weight":      input.Weight,
+		"position":    input.Position,
+	}
+	if input.IsActive != nil {
+		updates["is_active"] = *input.IsActive
+	}
+	if err := s.db.Model(criterion).Updates(updates).Error; err != nil {
+		return nil, fmt.Errorf("failed to update evaluation criterion: %w", err)
+	}
+	return s.findCriterion(id)
+}
+
+// DeleteCriterion removes a criterion that was never rated
+func (s *Service) DeleteCriterion(id uuid.UUID) error {
+	criterion, err := s.findCriterion(id)
+	if err != nil {
+		return err
+	}
+
+	var ratings int64
+	if err := s.db.Model(&models.ApplicationRating{}).Where("criterion_id = ?", id).Count(&ratings).Error; err != nil {
+		return err
+	}
+	if ratings > 0 {
+		return ErrCriterionInUse
+	}
+
+	if err := s.db.Delete(criterion).Error; err != nil {
+		return fmt.Errorf("failed to delete evaluation criterion: %w", err)
+	}
+	return nil
+}
+
+// Evaluate records the feedback of a reviewer on an application and updates
+// the score of the application
+func (s *Service) Evaluate(applicationID, reviewerID uuid.UUID, input EvaluationInput) (*models.ApplicationEvaluation, error) {
+	notes := strings.TrimSpace(input.Notes)
+	if len(input.Ratings) == 0 && input.Recommendation == "" && notes == "" {
+		return nil, ErrEmptyEvaluation
+	}
+
+	var application models.JobApplication
+	if err := s.db.Where("id = ? AND anonymized_at IS NULL", applicationID).First(&application).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrApplicationNotFound
+		}
+		return nil, err
+	}
+
+	score, err := s.score(input.Ratings)
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	evaluation := &models.ApplicationEvaluation{}
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("application_id = ? AND reviewer_id = ? AND stage = ?", applicationID, reviewerID, input.Stage).
+			First(evaluation).Error
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			evaluation = &models.ApplicationEvaluation{
+				ApplicationID:  applicationID,
+				ReviewerID:     reviewerID,
+				Stage:          input.Stage,
+				Recommendation: input.Recommendation,
+				Notes:          notes,
+				Score:          score,
+			}
+			if err := tx.Create(evaluation).Error; err != nil {
+				return err
+			}
+		case err != nil:
+			return err
+		default:
+			if err := tx.Model(evaluation).Updates(map[string]interface{}{
+				"recommendation": input.Recommendation,
+				"notes":          notes,
+				"score":          score,
+			}).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("evaluation_id = ?", evaluation.ID).Delete(&models.ApplicationRating{}).Error; err != nil {
+				return err
+			}
+		}
+
+		ratings := make([]models.ApplicationRating, len(input.Ratings))
+		for i, rating := range input.Ratings {
+			ratings[i] = models.ApplicationRating{
+				EvaluationID: evaluation.ID,
+				CriterionID:  rating.CriterionID,
+				Rating:       rating.Rating,
+				Comment:      strings.TrimSpace(rating.Comment),
+			}
+		}
+		if len(ratings) > 0 {
+			if err := tx.Create(&ratings).Error; err != nil {
+				return err
+			}
+		}
+
+		if err := updateApplicationScore(tx, applicationID); err != nil {
+			return err
+		}
+
+		return tx.Create(&models.JobApplicationActivity{
+			ApplicationID: applicationID,
+			UserID:        &reviewerID,
+			Type:          "evaluation",
+			Description:   "Bewertung abgegeben",
+			NewValue:      string(input.Stage),
+			CreatedAt:     now,
+		}).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to save evaluation: %w", err)
+	}
+
+	return s.find(evaluation.ID)
+}
+
+// Evaluations returns the evaluations of an application with their ratings, oldest first
+func (s *Service) Evaluations(applicationID uuid.UUID) ([]models.ApplicationEvaluation, error) {
+	var count int64
+	if err := s.db.Model(&models.JobApplication{}).Where("id = ?", applicationID).Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count == 0 {
+		return nil, ErrApplicationNotFound
+	}
+
+	evaluations := []models.ApplicationEvaluation{}
+	if err := s.db.Preload("Reviewer").Preload("Ratings.Criterion").
+		Where("application_id = ?", applicationID).
+		Order("created_at ASC").Find(&evaluations).Error; err != nil {
+		return nil, fmt.Errorf("failed to load evaluations: %w", err)
+	}
+	return evaluations, nil
+}
+
+// score validates the ratings and returns their average weighted by the
+// criteria, nil without ratings
+func (s *Service) score(ratings []RatingInput) (*float64, error) {
+	if len(ratings) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]uuid.UUID, 0, len(ratings))
+	seen := make(map[uuid.UUID]bool, len(ratings))
+	for _, rating := range ratings {
+		if seen[rating.CriterionID] || rating.Rating < models.MinRating || rating.Rating > models.MaxRating {
+			return nil, ErrInvalidRating
+		}
+		seen[rating.CriterionID] = true
+		ids = append(ids, rating.CriterionID)
+	}
+
+	var criteria []models.EvaluationCriterion
+	if err := s.db.Where("id IN ? AND is_active = ?", ids, true).Find(&criteria).Error; err != nil {
+		return nil, err
+	}
+	if len(criteria) != len(ids) {
+		return nil, ErrInvalidRating
+	}
+
+	weights := make(map[uuid.UUID]int, len(criteria))
+	for _, criterion := range criteria {
+		weights[criterion.ID] = criterion.Weight
+	}
+
+	var sum, total float64
+	for _, rating := range ratings {
+		weight := float64(weights[rating.CriterionID])
+		sum += float64(rating.Rating) * weight
+		total += weight
+	}
+	score := sum / total
+	return &score, nil
+}
+
+// updateApplicationScore sets the score of an application to the average of its evaluation scores
+func updateApplicationScore(tx *gorm.DB, applicationID uuid.UUID) error {
+	var result struct {
+		Score *float64
+	}
+	if err := tx.Model(&models.ApplicationEvaluation{}).
+		Select("AVG(score) AS score").
+		Where("application_id = ? AND score IS NOT NULL", applicationID).
+		Scan(&result).Error; err != nil {
+		return err
+	}
+	return tx.Model(&models.JobApplication{}).Where("id = ?", applicationID).Update("score", result.Score).Error
+}
+
+func (s *Service) find(id uuid.UUID) (*models.ApplicationEvaluation, error) {
+	var evaluation models.ApplicationEvaluation
+	if err := s.db.Preload("Reviewer").Preload("Ratings.Criterion").First(&evaluation, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &evaluation, nil
+}
+
+func (s *Service) findCriterion(id uuid.UUID) (*models.EvaluationCriterion, error) {
+	var criterion models.EvaluationCriterion
+	if err := s.db.First(&criterion, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCriterionNotFound
+		}
+		return nil, err
+	}
+	return &criterion, nil
+}
+
+// checkName returns ErrDuplicateCriterion when another criterion has the name
+func (s *Service) checkName(name string, exceptID uuid.UUID) error {
+	var count int64
+	if err := s.db.Model(&models.EvaluationCriterion{}).
+		Where("LOWER(name) = LOWER(?) AND id <> ?", name, exceptID).
+		Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrDuplicateCriterion
+	}
+	return nil
+}
@@ -0,0 +1,181 @@
+package evaluations
+
+import (
+	"testing"
+
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/tests/testutils"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+func TestCriteria(t *testing.T) {
+	service, _ := setupTestService(t)
+	inactive := false
+
+	experience, err := service.CreateCriterion(CriterionInput{Name: "Berufserfahrung", Weight: 2, Position: 1})
+	require.NoError(t, err)
+	assert.True(t, experience.IsActive)
+
+	_, err = service.CreateCriterion(CriterionInput{Name: "berufserfahrung", Weight: 1})
+	assert.ErrorIs(t, err, ErrDuplicateCriterion)
+
+	_, err = service.CreateCriterion(CriterionInput{Name: "Teamfähigkeit", Weight: 1, Position: 2, IsActive: &inactive})
+	require.NoError(t, err)
+
+	active, err := service.Criteria(true)
+	require.NoError(t, err)
+	require.Len(t, active, 1)
+	assert.Equal(t, "Berufserfahrung", active[0].Name)
+
+	all, err := service.Criteria(false)
+	require.NoError(t, err)
+	assert.Len(t, all, 2)
+
+	updated, err := service.UpdateCriterion(experience.ID, CriterionInput{Name: "Erfahrung", Weight: 3, Position: 1, IsActive: &inactive})
+	require.NoError(t, err)
+	assert.Equal(t, "Erfahrung", updated.Name)
+	assert.Equal(t, 3, updated.Weight)
+	assert.False(t, updated.IsActive)
+
+	require.NoError(t, service.DeleteCriterion(experience.ID))
+	assert.ErrorIs(t, service.DeleteCriterion(experience.ID), ErrCriterionNotFound)
+}
+
+func TestEvaluate(t *testing.T) {
+	service, db := setupTestService(t)
+	application := createApplication(t, db)
+	firstReviewer := testutils.CreateTestUser(t, db, models.RoleAdmin)
+	secondReviewer := testutils.CreateTestUser(t, db, models.RoleAdmin)
+
+	experience, err := service.CreateCriterion(CriterionInput{Name: "Berufserfahrung", Weight: 3})
+	require.NoError(t, err)
+	communication, err := service.CreateCriterion(CriterionInput{Name: "Kommunikation", Weight: 1})
+	require.NoError(t, err)
+
+	evaluation, err := service.Evaluate(application.ID, firstReviewer.ID, EvaluationInput{
+		Stage: models.ApplicationStatusScreening,
+		Ratings: []RatingInput{
+			{CriterionID: experience.ID, Rating: 5},
+			{CriterionID: communication.ID, Rating: 1, Comment: "Sehr knapp am Telefon"},
+		},
+	})
+	require.NoError(t, err)
+	require.NotNil(t, evaluation.Score)
+	assert.InDelta(t, 4.0, *evaluation.Score, 0.001)
+	assert.Len(t, evaluation.Ratings, 2)
+	assert.Equal(t, 4.0, applicationScore(t, db, application.ID))
+
+	// Interview notes without ratings do not change the score
+	_, err = service.Evaluate(application.ID, firstReviewer.ID, EvaluationInput{
+		Stage:          models.ApplicationStatusInterview,
+		Recommendation: models.RecommendationYes,
+		Notes:          "Überzeugendes Gespräch, kennt das BEEG sehr gut.",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 4.0, applicationScore(t, db, application.ID))
+
+	_, err = service.Evaluate(application.ID, secondReviewer.ID, EvaluationInput{
+		Stage:   models.ApplicationStatusInterview,
+		Ratings: []RatingInput{{CriterionID: experience.ID, Rating: 2}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 3.0, applicationScore(t, db, application.ID))
+
+	// Submitting again for the same stage replaces the earlier feedback
+	_, err = service.Evaluate(application.ID, secondReviewer.ID, EvaluationInput{
+		Stage:   models.ApplicationStatusInterview,
+		Ratings: []RatingInput{{CriterionID: communication.ID, Rating: 4}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 4.0, applicationScore(t, db, application.ID))
+
+	evaluations, err := service.Evaluations(application.ID)
+	require.NoError(t, err)
+	require.Len(t, evaluations, 3)
+	assert.Equal(t, "Überzeugendes Gespräch, kennt das BEEG sehr gut.", evaluations[1].Notes)
+	require.Len(t, evaluations[2].Ratings, 1)
+	assert.Equal(t, "Kommunikation", evaluations[2].Ratings[0].Criterion.Name)
+
+	assert.ErrorIs(t, service.DeleteCriterion(communication.ID), ErrCriterionInUse)
+}
+
+func TestEvaluateRejectsInvalidInput(t *testing.T) {
+	service, db := setupTestService(t)
+	application := createApplication(t, db)
+	reviewer := testutils.CreateTestUser(t, db, models.RoleAdmin)
+	inactive := false
+	criterion, err := service.CreateCriterion(CriterionInput{Name: "Berufserfahrung", Weight: 1})
+	require.NoError(t, err)
+	retired, err := service.CreateCriterion(CriterionInput{Name: "Alt", Weight: 1, IsActive: &inactive})
+	require.NoError(t, err)
+
+	_, err = service.Evaluate(application.ID, reviewer.ID, EvaluationInput{Stage: models.ApplicationStatusScreening, Notes: " "})
+	assert.ErrorIs(t, err, ErrEmptyEvaluation)
+
+	_, err = service.Evaluate(application.ID, reviewer.ID, EvaluationInput{
+		Stage:   models.ApplicationStatusScreening,
+		Ratings: []RatingInput{{CriterionID: criterion.ID, Rating: 3}, {CriterionID: criterion.ID, Rating: 4}},
+	})
+	assert.ErrorIs(t, err, ErrInvalidRating)
+
+	_, err = service.Evaluate(application.ID, reviewer.ID, EvaluationInput{
+		Stage:   models.ApplicationStatusScreening,
+		Ratings: []RatingInput{{CriterionID: retired.ID, Rating: 3}},
+	})
+	assert.ErrorIs(t, err, ErrInvalidRating)
+
+	_, err = service.Evaluate(uuid.New(), reviewer.ID, EvaluationInput{Stage: models.ApplicationStatusScreening, Notes: "Notiz"})
+	assert.ErrorIs(t, err, ErrApplicationNotFound)
+}
+
+func setupTestService(t *testing.T) (*Service, *gorm.DB) {
+	t.Helper()
+	db := testutils.NewSQLiteDB(t,
+		&models.User{},
+		&models.Job{},
+		&models.JobApplication{},
+		&models.JobApplicationActivity{},
+		&models.EvaluationCriterion{},
+		&models.ApplicationEvaluation{},
+		&models.ApplicationRating{},
+	)
+
+	return NewService(db, zap.NewNop()), db
+}
+
+func createApplication(t *testing.T, db *gorm.DB) *models.JobApplication {
+	t.Helper()
+	job := &models.Job{
+		Title:       "Elterngeldberater (m/w/d)",
+		Slug:        uuid.New().String(),
+		Description: "...",
+		Type:        models.JobTypeFullTime,
+		Level:       models.JobLevelMid,
+		Location:    "München",
+		CreatedBy:   uuid.New(),
+	}
+	require.NoError(t, db.Create(job).Error)
+
+	application := &models.JobApplication{
+		JobID:     job.ID,
+		FirstName: "Erika",
+		LastName:  "Muster",
+		Email:     "erika@example.com",
+		Status:    models.ApplicationStatusScreening,
+	}
+	require.NoError(t, db.Create(application).Error)
+	return application
+}
+
+func applicationScore(t *testing.T, db *gorm.DB, id uuid.UUID) float64 {
+	t.Helper()
+	var application models.JobApplication
+	require.NoError(t, db.First(&application, "id = ?", id).Error)
+	require.NotNil(t, application.Score)
+	return *application.Score
+}
@@ -0,0 +1,477 @@
+package experiments
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+	"time"
+
+	"elterngeld-portal/internal/models"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrExperimentNotFound is returned for unknown experiments
+	ErrExperimentNotFound = errors.New("experiment not found")
+	// ErrExperimentNotRunning is returned when visitors are not assigned to the experiment
+	ErrExperimentNotRunning = errors.New("experiment is not running")
+	// ErrExperimentNotDraft is returned when a started experiment is changed or started again
+	ErrExperimentNotDraft = errors.New("experiment is not a draft")
+	// ErrDuplicateKey is returned when another experiment uses the key
+	ErrDuplicateKey = errors.New("experiment key already exists")
+	// ErrInvalidKey is returned for keys other than lowercase letters, digits, dashes and underscores
+	ErrInvalidKey = errors.New("invalid key")
+	// ErrInvalidVariants is returned for fewer than two variants, duplicate keys or weights below one
+	ErrInvalidVariants = errors.New("invalid variants")
+	// ErrInvalidConfig is returned when a variant config is not a JSON object
+	ErrInvalidConfig = errors.New("invalid variant config")
+	// ErrMissingSubject is returned when neither a visitor nor a user is known
+	ErrMissingSubject = errors.New("missing visitor or user")
+)
+
+var keyPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// Service runs A/B tests of the package and pricing display
+type Service struct {
+	db     *gorm.DB
+	logger *zap.Logger
+	now    func() time.Time
+}
+
+func NewService(db *gorm.DB, logger *zap.Logger) *Service {
+	return &Service{
+		db:     db,
+		logger: logger,
+		now:    time.Now,
+	}
+}
+
+// VariantInput holds the fields of a variant
+type VariantInput struct {
+	Key    string          `json:"key" binding:"required,max=50"`
+	Name   string          `json:"name" binding:"required,max=100"`
+	Weight int             `json:"weight" binding:"required"`
+	Config json.RawMessage `json:"config" swaggertype:"object"`
+}
+
+// ExperimentInput holds the fields of an experiment and its variants
+type ExperimentInput struct {
+	Key         string         `json:"key" binding:"required,max=100"`
+	Name        string         `json:"name" binding:"required,max=200"`
+	Description string         `json:"description"`
+	Variants    []VariantInput `json:"variants" binding:"required,dive"`
+}
+
+// Assignment is the variant a visitor sees
+type Assignment struct {
+	Experiment string          `json:"experiment"`
+	Variant    string          `json:"variant"`
+	Config     json.RawMessage `json:"config,omitempty" swaggertype:"object"`
+}
+
+// List returns all experiments with their variants, newest first
+func (s *Service) List() ([]models.Experiment, error) {
+	var experiments []models.Experiment
+	if err := s.db.Preload("Variants", func(db *gorm.DB) *gorm.DB {
+		return db.Order("position ASC")
+	}).Order("created_at DESC").Find(&experiments).Error; err != nil {
+		return nil, err
+	}
+	return experiments, nil
+}
+
+// Get returns an experiment with its variants
+func (s *Service) Get(id uuid.UUID) (*models.Experiment, error) {
+	var experiment models.Experiment
+	err := s.db.Preload("Variants", func(db *gorm.DB) *gorm.DB {
+		return db.Order("position ASC")
+	}).First(&experiment, "id = ?", id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrExperimentNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &experiment, nil
+}
+
+// Create adds a draft experiment
+func (s *Service) Create(input ExperimentInput) (*models.Experiment, error) {
+	variants, err := buildVariants(input.Variants)
+	if err != nil {
+		return nil, err
+	}
+	key := strings.TrimSpace(input.Key)
+	if !keyPattern.MatchString(key) {
+		return nil, ErrInvalidKey
+	}
+
+	var count int64
+	if err := s.db.Model(&models.Experiment{}).Where("key = ?", key).Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		return nil, ErrDuplicateKey
+	}
+
+	experiment := &models.Experiment{
+		Key:         key,
+		Name:        strings.TrimSpace(input.Name),
+		Description: strings.TrimSpace(input.Description),
+		Status:      models.ExperimentStatusDraft,
+		Variants:    variants,
+	}
+	if err := s.db.Create(experiment).Error; err != nil {
+		return nil, fmt.Errorf("failed to create experiment: %w", err)
+	}
+	return experiment, nil
+}
+
+// Update replaces the settings and variants of a draft experiment. The key
+// cannot be changed, since the frontend refers to it.
+func (s *Service) Update(id uuid.UUID, input ExperimentInput) (*models.Experiment, error) {
+	experiment, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if experiment.Status != models.ExperimentStatusDraft {
+		return nil, ErrExperimentNotDraft
+	}
+	variants, err := buildVariants(input.Variants)
+	if err != nil {
+		return nil, err
+	}
+
+	experiment.Name = strings.TrimSpace(input.Name)
+	experiment.Description = strings.TrimSpace(input.Description)
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Select("name", "description").Save(experiment).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("experiment_id = ?", experiment.ID).Delete(&models.ExperimentVariant{}).Error; err != nil {
+			return err
+		}
+		for i := range variants {
+			variants[i].ExperimentID = experiment.ID
+		}
+		return tx.Create(&variants).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to update experiment: %w", err)
+	}
+
+	experiment.Variants = variants
+	return experiment, nil
+}
+
+// Start begins assigning visitors to the variants of a draft experiment
+func (s *Service) Start(id uuid.UUID) (*models.Experiment, error) {
+	experiment, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if experiment.Status != models.ExperimentStatusDraft {
+		return nil, ErrExperimentNotDraft
+	}
+
+	now := s.now()
+	experiment.Status = models.ExperimentStatusRunning
+	experiment.StartedAt = &now
+	if err := s.db.Select("status", "started_at").Save(experiment).Error; err != nil {
+		return nil, fmt.Errorf("failed to start experiment: %w", err)
+	}
+	return experiment, nil
+}
+
+// Stop ends a running experiment. Its results no longer change.
+func (s *Service) Stop(id uuid.UUID) (*models.Experiment, error) {
+	experiment, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if !experiment.IsRunning() {
+		return nil, ErrExperimentNotRunning
+	}
+
+	now := s.now()
+	experiment.Status = models.ExperimentStatusStopped
+	experiment.StoppedAt = &now
+	if err := s.db.Select("status", "stopped_at").Save(experiment).Error; err != nil {
+		return nil, fmt.Errorf("failed to stop experiment: %w", err)
+	}
+	return experiment, nil
+}
+
+// Assign returns the variant of a running experiment for a visitor and logs
+// the exposure. The anonymous visitor ID is preferred over the user ID, so
+// visitors keep their variant when they sign in.
+func (s *Service) Assign(key string, visitorID, userID *uuid.UUID) (*Assignment, error) {
+	subjectID := visitorID
+	if subjectID == nil || *subjectID == uuid.Nil {
+		subjectID = userID
+	}
+	if subjectID == nil || *subjectID == uuid.Nil {
+		return nil, ErrMissingSubject
+	}
+
+	var experiment models.Experiment
+	err := s.db.Preload("Variants", func(db *gorm.DB) *gorm.DB {
+		return db.Order("position ASC")
+	}).Where("key = ?", key).First(&experiment).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrExperimentNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !experiment.IsRunning() || len(experiment.Variants) == 0 {
+		return nil, ErrExperimentNotRunning
+	}
+
+	var exposure models.ExperimentExposure
+	err = s.db.Where("experiment_id = ? AND subject_id = ?", experiment.ID, *subjectID).First(&exposure).Error
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		// Later exposures keep the variant even if the weights change
+		variant := pickVariant(experiment.Key, *subjectID, experiment.Variants)
+		now := s.now()
+		exposure = models.ExperimentExposure{
+			ExperimentID:   experiment.ID,
+			VariantID:      variant.ID,
+			SubjectID:      *subjectID,
+			UserID:         userID,
+			ExposureCount:  1,
+			FirstExposedAt: now,
+			LastExposedAt:  now,
+		}
+		result := s.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&exposure)
+		if result.Error != nil {
+			return nil, fmt.Errorf("failed to log exposure: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			// A concurrent request of the same visitor logged the exposure first
+			return s.Assign(key, visitorID, userID)
+		}
+	case err != nil:
+		return nil, err
+	default:
+		updates := map[string]interface{}{
+			"exposure_count":  gorm.Expr("exposure_count + 1"),
+			"last_exposed_at": s.now(),
+		}
+		if exposure.UserID == nil && userID != nil {
+			updates["user_id"] = *userID
+		}
+		if err := s.db.Model(&exposure).Updates(updates).Error; err != nil {
+			return nil, fmt.Errorf("failed to log exposure: %w", err)
+		}
+	}
+
+	for _, variant := range experiment.Variants {
+		if variant.ID == exposure.VariantID {
+			return &Assignment{Experiment: experiment.Key, Variant: variant.Key, Config: variant.Config}, nil
+		}
+	}
+	return nil, ErrExperimentNotRunning
+}
+
+// RecordBooking attributes a new booking to the running experiments its user was exposed to
+func (s *Service) RecordBooking(booking *models.Booking) error {
+	return s.attribute(models.ExperimentConversionBooking, booking.UserID, booking.ID, booking.TotalAmount, booking.Currency)
+}
+
+// RecordPayment attributes a successful payment to the running experiments its user was exposed to
+func (s *Service) RecordPayment(payment *models.Payment) error {
+	return s.attribute(models.ExperimentConversionPayment, payment.UserID, payment.ID, payment.Amount, payment.Currency)
+}
+
+func (s *Service) attribute(kind models.ExperimentConversionKind, userID, referenceID uuid.UUID, amount float64, currency string) error {
+	now := s.now()
+
+	var exposures []models.ExperimentExposure
+	if err := s.db.Joins("JOIN experiments ON experiments.id = experiment_exposures.experiment_id").
+		Where("experiment_exposures.user_id = ? AND experiments.status = ? AND experiment_exposures.first_exposed_at <= ?",
+			userID, models.ExperimentStatusRunning, now).
+		Find(&exposures).Error; err != nil {
+		return err
+	}
+	if len(exposures) == 0 {
+		return nil
+	}
+
+	conversions := make([]models.ExperimentConversion, len(exposures))
+	for i, exposure := range exposures {
+		conversions[i] = models.ExperimentConversion{
+			ExperimentID: exposure.ExperimentID,
+			VariantID:    exposure.VariantID,
+			ExposureID:   exposure.ID,
+			UserID:       userID,
+			Kind:         kind,
+			ReferenceID:  referenceID,
+			Amount:       amount,
+			Currency:     currency,
+			ConvertedAt:  now,
+		}
+	}
+	// Replayed payment webhooks must not count twice
+	if err := s.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&conversions).Error; err != nil {
+		return fmt.Errorf("failed to record conversion: %w", err)
+	}
+
+	s.logger.Info("Experiment conversion recorded",
+		zap.String("kind", string(kind)),
+		zap.String("reference_id", referenceID.String()),
+		zap.Int("experiments", len(exposures)))
+	return nil
+}
+
+// VariantResult holds the exposures and conversions of a variant. Rates are
+// in percent of the exposed visitors.
+type VariantResult struct {
+	VariantID   uuid.UUID `json:"variant_id"`
+	Key         string    `json:"key"`
+	Name        string    `json:"name"`
+	Weight      int       `json:"weight"`
+	Exposures   int64     `json:"exposures"`
+	Bookings    int64     `json:"bookings"`
+	BookingRate float64   `json:"booking_rate"`
+	Payments    int64     `json:"payments"`
+	PaymentRate float64   `json:"payment_rate"`
+	Revenue     float64   `json:"revenue"`
+}
+
+// Results is the outcome of an experiment per variant
+type Results struct {
+	Experiment *models.Experiment `json:"experiment"`
+	Variants   []VariantResult    `json:"variants"`
+}
+
+// Results counts the exposed visitors, bookings and payments of every variant
+func (s *Service) Results(id uuid.UUID) (*Results, error) {
+	experiment, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var exposures []struct {
+		VariantID uuid.UUID
+		Count     int64
+	}
+	if err := s.db.Model(&models.ExperimentExposure{}).
+		Select("variant_id, COUNT(*) AS count").
+		Where("experiment_id = ?", experiment.ID).
+		Group("variant_id").
+		Scan(&exposures).Error; err != nil {
+		return nil, err
+	}
+
+	var conversions []struct {
+		VariantID uuid.UUID
+		Kind      models.ExperimentConversionKind
+		Count     int64
+		Revenue   float64
+	}
+	if err := s.db.Model(&models.ExperimentConversion{}).
+		Select("variant_id, kind, COUNT(*) AS count, COALESCE(SUM(amount), 0) AS revenue").
+		Where("experiment_id = ?", experiment.ID).
+		Group("variant_id, kind").
+		Scan(&conversions).Error; err != nil {
+		return nil, err
+	}
+
+	results := &Results{Experiment: experiment}
+	for _, variant := range experiment.Variants {
+		result := VariantResult{VariantID: variant.ID, Key: variant.Key, Name: variant.Name, Weight: variant.Weight}
+		for _, row := range exposures {
+			if row.VariantID == variant.ID {
+				result.Exposures = row.Count
+			}
+		}
+		for _, row := range conversions {
+			if row.VariantID != variant.ID {
+				continue
+			}
+			switch row.Kind {
+			case models.ExperimentConversionBooking:
+				result.Bookings = row.Count
+			case models.ExperimentConversionPayment:
+				result.Payments = row.Count
+				result.Revenue = math.Round(row.Revenue*100) / 100
+			}
+		}
+		result.BookingRate = percent(result.Bookings, result.Exposures)
+		result.PaymentRate = percent(result.Payments, result.Exposures)
+		results.Variants = append(results.Variants, result)
+	}
+
+	return results, nil
+}
+
+func buildVariants(inputs []VariantInput) ([]models.ExperimentVariant, error) {
+	if len(inputs) < 2 {
+		return nil, ErrInvalidVariants
+	}
+
+	variants := make([]models.ExperimentVariant, len(inputs))
+	keys := make(map[string]bool, len(inputs))
+	for i, input := range inputs {
+		key := strings.TrimSpace(input.Key)
+		if !keyPattern.MatchString(key) || keys[key] || input.Weight < 1 {
+			return nil, ErrInvalidVariants
+		}
+		keys[key] = true
+
+		config := input.Config
+		if len(config) == 0 || string(config) == "null" {
+			config = json.RawMessage("{}")
+		}
+		var object map[string]interface{}
+		if err := json.Unmarshal(config, &object); err != nil {
+			return nil, ErrInvalidConfig
+		}
+
+		variants[i] = models.ExperimentVariant{
+			Key:      key,
+			Name:     strings.TrimSpace(input.Name),
+			Weight:   input.Weight,
+			Position: i,
+			Config:   config,
+		}
+	}
+	return variants, nil
+}
+
+// pickVariant maps the subject to a variant by hashing it together with the
+// experiment key, so a visitor lands in independent buckets per experiment
+func pickVariant(experimentKey string, subjectID uuid.UUID, variants []models.ExperimentVariant) models.ExperimentVariant {
+	total := 0
+	for _, variant := range variants {
+		total += variant.Weight
+	}
+
+	sum := sha256.Sum256([]byte(experimentKey + ":" + subjectID.String()))
+	bucket := int(binary.BigEndian.Uint64(sum[:8]) % uint64(total))
+	for _, variant := range variants {
+		if bucket < variant.Weight {
+			return variant
+		}
+		bucket -= variant.Weight
+	}
+	return variants[len(variants)-1]
+}
+
+func percent(part, total int64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return math.Round(float64(part)/float64(total)*1000) / 10
+}
@@ -0,0 +1,270 @@
+package experiments
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/tests/testutils"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+func TestCreateValidation(t *testing.T) {
+	_, service := setupTestService(t)
+
+	control := VariantInput{Key: "control", Name: "Control", Weight: 1}
+	tests := []struct {
+		name  string
+		input ExperimentInput
+		err   error
+	}{
+		{
+			name:  "invalid key",
+			input: ExperimentInput{Key: "Pricing Page", Name: "Pricing", Variants: []VariantInput{control, {Key: "b", Name: "B", Weight: 1}}},
+			err:   ErrInvalidKey,
+		},
+		{
+			name:  "single variant",
+			input: ExperimentInput{Key: "pricing", Name: "Pricing", Variants: []VariantInput{control}},
+			err:   ErrInvalidVariants,
+		},
+		{
+			name:  "duplicate variant keys",
+			input: ExperimentInput{Key: "pricing", Name: "Pricing", Variants: []VariantInput{control, control}},
+			err:   ErrInvalidVariants,
+		},
+		{
+			name:  "zero weight",
+			input: ExperimentInput{Key: "pricing", Name: "Pricing", Variants: []VariantInput{control, {Key: "b", Name: "B"}}},
+			err:   ErrInvalidVariants,
+		},
+		{
+			name: "config is not an object",
+			input: ExperimentInput{Key: "pricing", Name: "Pricing", Variants: []VariantInput{
+				control, {Key: "b", Name: "B", Weight: 1, Config: json.RawMessage(`[1, 2]`)},
+			}},
+			err: ErrInvalidConfig,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := service.Create(tt.input)
+			assert.ErrorIs(t, err, tt.err)
+		})
+	}
+
+	t.Run("duplicate key", func(t *testing.T) {
+		createRunningExperiment(t, service, "duplicate")
+		_, err := service.Create(ExperimentInput{Key: "duplicate", Name: "Again", Variants: []VariantInput{control, {Key: "b", Name: "B", Weight: 1}}})
+		assert.ErrorIs(t, err, ErrDuplicateKey)
+	})
+}
+
+func TestLifecycle(t *testing.T) {
+	_, service := setupTestService(t)
+
+	experiment, err := service.Create(ExperimentInput{
+		Key:  "package-order",
+		Name: "Package order",
+		Variants: []VariantInput{
+			{Key: "control", Name: "Control", Weight: 1},
+			{Key: "premium-first", Name: "Premium first", Weight: 1},
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, models.ExperimentStatusDraft, experiment.Status)
+
+	_, err = service.Assign("package-order", uuidPtr(uuid.New()), nil)
+	assert.ErrorIs(t, err, ErrExperimentNotRunning)
+	_, err = service.Stop(experiment.ID)
+	assert.ErrorIs(t, err, ErrExperimentNotRunning)
+
+	updated, err := service.Update(experiment.ID, ExperimentInput{
+		Key:  "ignored",
+		Name: "Package order v2",
+		Variants: []VariantInput{
+			{Key: "control", Name: "Control", Weight: 2},
+			{Key: "premium-first", Name: "Premium first", Weight: 1},
+			{Key: "basic-first", Name: "Basic first", Weight: 1},
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "package-order", updated.Key)
+	assert.Len(t, updated.Variants, 3)
+
+	started, err := service.Start(experiment.ID)
+	require.NoError(t, err)
+	assert.True(t, started.IsRunning())
+	assert.NotNil(t, started.StartedAt)
+
+	_, err = service.Update(experiment.ID, ExperimentInput{Name: "Too late"})
+	assert.ErrorIs(t, err, ErrExperimentNotDraft)
+	_, err = service.Start(experiment.ID)
+	assert.ErrorIs(t, err, ErrExperimentNotDraft)
+
+	stopped, err := service.Stop(experiment.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.ExperimentStatusStopped, stopped.Status)
+
+	_, err = service.Get(uuid.New())
+	assert.ErrorIs(t, err, ErrExperimentNotFound)
+}
+
+func TestAssign(t *testing.T) {
+	db, service := setupTestService(t)
+	experiment := createRunningExperiment(t, service, "pricing")
+
+	_, err := service.Assign("pricing", nil, nil)
+	assert.ErrorIs(t, err, ErrMissingSubject)
+	_, err = service.Assign("unknown", uuidPtr(uuid.New()), nil)
+	assert.ErrorIs(t, err, ErrExperimentNotFound)
+
+	t.Run("stable per visitor", func(t *testing.T) {
+		visitor := uuid.New()
+		first, err := service.Assign("pricing", &visitor, nil)
+		require.NoError(t, err)
+		for i := 0; i < 3; i++ {
+			again, err := service.Assign("pricing", &visitor, nil)
+			require.NoError(t, err)
+			assert.Equal(t, first.Variant, again.Variant)
+		}
+
+		var exposure models.ExperimentExposure
+		require.NoError(t, db.Where("experiment_id = ? AND subject_id = ?", experiment.ID, visitor).First(&exposure).Error)
+		assert.Equal(t, 4, exposure.ExposureCount)
+		assert.Nil(t, exposure.UserID)
+
+		// Signing in keeps the variant and links the user
+		user := uuid.New()
+		signedIn, err := service.Assign("pricing", &visitor, &user)
+		require.NoError(t, err)
+		assert.Equal(t, first.Variant, signedIn.Variant)
+		require.NoError(t, db.First(&exposure, "id = ?", exposure.ID).Error)
+		require.NotNil(t, exposure.UserID)
+		assert.Equal(t, user, *exposure.UserID)
+	})
+
+	t.Run("weights", func(t *testing.T) {
+		counts := map[string]int{}
+		for i := 0; i < 1000; i++ {
+			assignment, err := service.Assign("pricing", uuidPtr(uuid.New()), nil)
+			require.NoError(t, err)
+			counts[assignment.Variant]++
+		}
+		// 3:1 split between control and the monthly price display
+		assert.InDelta(t, 750, counts["control"], 80)
+		assert.InDelta(t, 250, counts["monthly"], 80)
+	})
+
+	t.Run("config", func(t *testing.T) {
+		var config map[string]interface{}
+		for {
+			assignment, err := service.Assign("pricing", uuidPtr(uuid.New()), nil)
+			require.NoError(t, err)
+			if assignment.Variant == "monthly" {
+				require.NoError(t, json.Unmarshal(assignment.Config, &config))
+				break
+			}
+		}
+		assert.Equal(t, true, config["show_monthly_price"])
+	})
+}
+
+func TestAttribution(t *testing.T) {
+	db, service := setupTestService(t)
+	experiment := createRunningExperiment(t, service, "pricing")
+	other := createRunningExperiment(t, service, "checkout")
+
+	user := createTestUser(t, db, "kunde@example.com")
+	unexposed := createTestUser(t, db, "ohne@example.com")
+
+	assignment, err := service.Assign("pricing", uuidPtr(uuid.New()), &user.ID)
+	require.NoError(t, err)
+	_, err = service.Assign("checkout", nil, &user.ID)
+	require.NoError(t, err)
+	_, err = service.Stop(other.ID)
+	require.NoError(t, err)
+
+	booking := &models.Booking{ID: uuid.New(), UserID: user.ID, TotalAmount: 149, Currency: "EUR"}
+	payment := &models.Payment{ID: uuid.New(), UserID: user.ID, Amount: 149, Currency: "EUR"}
+	require.NoError(t, service.RecordBooking(booking))
+	require.NoError(t, service.RecordPayment(payment))
+	// Replayed webhooks do not count twice
+	require.NoError(t, service.RecordPayment(payment))
+	require.NoError(t, service.RecordPayment(&models.Payment{ID: uuid.New(), UserID: unexposed.ID, Amount: 99, Currency: "EUR"}))
+
+	var conversions []models.ExperimentConversion
+	require.NoError(t, db.Find(&conversions).Error)
+	require.Len(t, conversions, 2)
+	for _, conversion := range conversions {
+		assert.Equal(t, experiment.ID, conversion.ExperimentID)
+		assert.Equal(t, user.ID, conversion.UserID)
+	}
+
+	results, err := service.Results(experiment.ID)
+	require.NoError(t, err)
+	require.Len(t, results.Variants, 2)
+	for _, variant := range results.Variants {
+		if variant.Key != assignment.Variant {
+			assert.Zero(t, variant.Exposures)
+			assert.Zero(t, variant.Payments)
+			continue
+		}
+		assert.Equal(t, int64(1), variant.Exposures)
+		assert.Equal(t, int64(1), variant.Bookings)
+		assert.Equal(t, int64(1), variant.Payments)
+		assert.Equal(t, 100.0, variant.PaymentRate)
+		assert.Equal(t, 149.0, variant.Revenue)
+	}
+}
+
+func createRunningExperiment(t *testing.T, service *Service, key string) *models.Experiment {
+	t.Helper()
+	experiment, err := service.Create(ExperimentInput{
+		Key:  key,
+		Name: "Pricing display",
+		Variants: []VariantInput{
+			{Key: "control", Name: "Control", Weight: 3},
+			{Key: "monthly", Name: "Monthly price", Weight: 1, Config: json.RawMessage(`{"show_monthly_price": true}`)},
+		},
+	})
+	require.NoError(t, err)
+	experiment, err = service.Start(experiment.ID)
+	require.NoError(t, err)
+	return experiment
+}
+
+func createTestUser(t *testing.T, db *gorm.DB, email string) *models.User {
+	t.Helper()
+	user := testutils.CreateTestUser(t, db, models.RoleUser)
+	require.NoError(t, db.Model(user).Update("email", email).Error)
+	user.Email = email
+	return user
+}
+
+func uuidPtr(id uuid.UUID) *uuid.UUID {
+	return &id
+}
+
+func setupTestService(t *testing.T) (*gorm.DB, *Service) {
+	t.Helper()
+	db := testutils.NewSQLiteDB(t,
+		&models.User{},
+		&models.Experiment{},
+		&models.ExperimentVariant{},
+		&models.ExperimentExposure{},
+		&models.ExperimentConversion{},
+	)
+
+	service := NewService(db, zap.NewNop())
+	now := time.Now()
+	service.now = func() time.Time { return now }
+	return db, service
+}
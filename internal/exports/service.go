@@ -0,0 +1,147 @@
+// Package exports writes CSV exports of large lists. Rows are read in keyset
+// pages so memory use does not grow with the export; exports above a
+// threshold are streamed to the client as they are written.
+package exports
+
+import (
+	"io"
+	"strconv"
+	"time"
+
+	"elterngeld-portal/config"
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/internal/scopes"
+	"elterngeld-portal/pkg/pii"
+	"elterngeld-portal/pkg/timeutil"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// LeadFilter narrows down a lead export
+type LeadFilter struct {
+	Status    models.LeadStatus
+	Source    models.LeadSource
+	BeraterID *uuid.UUID
+	From      *time.Time // created at or after
+	To        *time.Time // created before
+}
+
+// Service writes CSV exports
+type Service struct {
+	db              *gorm.DB
+	logger          *zap.Logger
+	streamThreshold int
+	batchSize       int
+}
+
+func NewService(db *gorm.DB, logger *zap.Logger, cfg *config.Config) *Service {
+	streamThreshold := cfg.Export.StreamThreshold
+	if streamThreshold <= 0 {
+		streamThreshold = 50000
+	}
+	batchSize := cfg.Export.BatchSize
+	if batchSize <= 0 {
+		batchSize = 1000
+	}
+
+	return &Service{
+		db:              db,
+		logger:          logger,
+		streamThreshold: streamThreshold,
+		batchSize:       batchSize,
+	}
+}
+
+// ShouldStream checks if an export of the given size is streamed instead of
+// buffered. Buffered exports are sent with their length and can still fail
+// with an error response, streamed ones cannot.
+func (s *Service) ShouldStream(rows int64) bool {
+	return rows > int64(s.streamThreshold)
+}
+
+// leadColumns are the columns of a lead export. Emails are masked for staff
+// without the pii.read permission like in JSON responses.
+func leadColumns(maskPII bool) []Column[models.Lead] {
+	return []Column[models.Lead]{
+		{"Aktenzeichen", func(l *models.Lead) string { return l.ApplicationNumber }},
+		{"Titel", func(l *models.Lead) string { return l.Title }},
+		{"Status", func(l *models.Lead) string { return string(l.Status) }},
+		{"Priorität", func(l *models.Lead) string { return string(l.Priority) }},
+		{"Quelle", func(l *models.Lead) string { return string(l.Source) }},
+		{"UTM-Kampagne", func(l *models.Lead) string { return l.UtmCampaign }},
+		{"Kunde", func(l *models.Lead) string { return l.User.FullName() }},
+		{"E-Mail", func(l *models.Lead) string {
+			if maskPII {
+				return pii.MaskEmail(l.User.Email)
+			}
+			return l.User.Email
+		}},
+		{"Berater", func(l *models.Lead) string {
+			if l.Berater == nil {
+				return ""
+			}
+			return l.Berater.FullName()
+		}},
+		{"Lead-Score", func(l *models.Lead) string { return strconv.Itoa(l.LeadScore) }},
+		{"Geschätzter Wert", func(l *models.Lead) string { return strconv.FormatFloat(l.EstimatedValue, 'f', 2, 64) }},
+		{"Erstellt am", func(l *models.Lead) string { return formatTime(&l.CreatedAt) }},
+		{"Letzter Kontakt", func(l *models.Lead) string { return formatTime(l.LastContactAt) }},
+		{"Abgeschlossen am", func(l *models.Lead) string { return formatTime(l.CompletedAt) }},
+	}
+}
+
+// leads selects the leads of an export the viewer may see
+func (s *Service) leads(viewer scopes.Viewer, filter LeadFilter) *gorm.DB {
+	query := s.db.Model(&models.Lead{}).Scopes(scopes.VisibleLeads(viewer))
+	if filter.Status != "" {
+		query = query.Where("leads.status = ?", filter.Status)
+	}
+	if filter.Source != "" {
+		query = query.Where("leads.source = ?", filter.Source)
+	}
+	if filter.BeraterID != nil {
+		query = query.Where("leads.berater_id = ?", *filter.BeraterID)
+	}
+	if filter.From != nil {
+		query = query.Where("leads.created_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("leads.created_at < ?", *filter.To)
+	}
+	return query
+}
+
+// CountLeads returns the number of rows a lead export has
+func (s *Service) CountLeads(viewer scopes.Viewer, filter LeadFilter) (int64, error) {
+	var count int64
+	err := s.leads(viewer, filter).Count(&count).Error
+	return count, err
+}
+
+// WriteLeads writes the leads the viewer may see as CSV, oldest first.
+// flush is called after every page and may be nil.
+func (s *Service) WriteLeads(w io.Writer, viewer scopes.Viewer, filter LeadFilter, flush func()) (int, error) {
+	maskPII := viewer.Role != models.RoleUser &&
+		!models.HasPermission(s.db, viewer.ID, viewer.Role, models.PermissionPIIRead)
+
+	query := s.leads(viewer, filter).Preload("User").Preload("Berater")
+	written, err := writeCSV(w, query, "leads", s.batchSize, leadColumns(maskPII),
+		func(l *models.Lead) cursor { return cursor{CreatedAt: l.CreatedAt, ID: l.ID} }, flush)
+	if err != nil {
+		return written, err
+	}
+
+	s.logger.Info("Leads exported",
+		zap.String("user_id", viewer.ID.String()),
+		zap.Int("rows", written))
+	return written, nil
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil || t.IsZero() {
+		return ""
+	}
+	return t.In(timeutil.LoadLocation(timeutil.DefaultTimezone)).Format("02.01.2006 15:04")
+}
@@ -0,0 +1,151 @@
+package exports
+
+import (
+	"bytes"
+	"encoding/csv"
+	"strings"
+	"testing"
+	"time"
+
+	"elterngeld-portal/config"
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/internal/scopes"
+	"elterngeld-portal/pkg/pii"
+	"elterngeld-portal/tests/testutils"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+func TestWriteLeads_KeysetPages(t *testing.T) {
+	db, service := setupTestService(t, 2)
+	customer := testutils.CreateTestUser(t, db, models.RoleUser)
+	berater := testutils.CreateTestUser(t, db, models.RoleBerater)
+	admin := testutils.CreateTestUser(t, db, models.RoleAdmin)
+
+	// Leads created in the same instant must neither be skipped nor repeated across pages
+	created := time.Date(2026, 3, 2, 9, 30, 0, 0, time.UTC)
+	var titles []string
+	for i := 0; i < 7; i++ {
+		at := created
+		if i >= 4 {
+			at = created.Add(time.Duration(i) * time.Hour)
+		}
+		lead := createLead(t, db, customer.ID, "Lead "+string(rune('A'+i)), at)
+		if i == 0 {
+			assign(t, db, lead, berater.ID)
+		}
+		titles = append(titles, lead.Title)
+	}
+
+	var buf bytes.Buffer
+	flushes := 0
+	written, err := service.WriteLeads(&buf, scopes.Viewer{ID: admin.ID, Role: admin.Role}, LeadFilter{}, func() { flushes++ })
+	require.NoError(t, err)
+	assert.Equal(t, 7, written)
+	assert.Equal(t, 4, flushes)
+
+	assert.True(t, strings.HasPrefix(buf.String(), bom))
+	records := parse(t, &buf)
+	require.Len(t, records, 8)
+	assert.Equal(t, "Aktenzeichen", records[0][0])
+
+	var exported []string
+	for _, record := range records[1:] {
+		exported = append(exported, record[1])
+	}
+	assert.ElementsMatch(t, titles, exported)
+	assert.Equal(t, []string{"Lead E", "Lead F", "Lead G"}, exported[4:])
+
+	byTitle := map[string][]string{}
+	for _, record := range records[1:] {
+		byTitle[record[1]] = record
+	}
+	assert.Equal(t, berater.FirstName+" "+berater.LastName, byTitle["Lead A"][8])
+	assert.Equal(t, "02.03.2026 10:30", byTitle["Lead A"][11])
+	assert.Equal(t, "", byTitle["Lead B"][8])
+}
+
+func TestWriteLeads_FiltersAndVisibility(t *testing.T) {
+	db, service := setupTestService(t, 100)
+	customer := testutils.CreateTestUser(t, db, models.RoleUser)
+	other := testutils.CreateTestUser(t, db, models.RoleUser)
+	berater := testutils.CreateTestUser(t, db, models.RoleBerater)
+
+	now := time.Now()
+	own := createLead(t, db, customer.ID, "=HYPERLINK(\"http://example.com\")", now.Add(-48*time.Hour))
+	createLead(t, db, customer.ID, "Neu", now)
+	createLead(t, db, other.ID, "Fremd", now)
+	require.NoError(t, db.Model(own).Update("status", models.LeadStatusInProgress).Error)
+
+	count, err := service.CountLeads(scopes.Viewer{ID: customer.ID, Role: customer.Role}, LeadFilter{})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+
+	from := now.Add(-time.Hour)
+	count, err = service.CountLeads(scopes.Viewer{ID: berater.ID, Role: berater.Role}, LeadFilter{From: &from})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+
+	var buf bytes.Buffer
+	_, err = service.WriteLeads(&buf, scopes.Viewer{ID: customer.ID, Role: customer.Role}, LeadFilter{Status: models.LeadStatusInProgress}, nil)
+	require.NoError(t, err)
+	records := parse(t, &buf)
+	require.Len(t, records, 2)
+	// Formulas are not evaluated by spreadsheet programs
+	assert.Equal(t, "'=HYPERLINK(\"http://example.com\")", records[1][1])
+	assert.Equal(t, customer.Email, records[1][7])
+
+	// Staff without the pii.read permission get masked emails
+	junior := testutils.CreateTestUser(t, db, models.RoleJuniorBerater)
+	buf.Reset()
+	_, err = service.WriteLeads(&buf, scopes.Viewer{ID: junior.ID, Role: junior.Role}, LeadFilter{Status: models.LeadStatusInProgress}, nil)
+	require.NoError(t, err)
+	records = parse(t, &buf)
+	require.Len(t, records, 2)
+	assert.Equal(t, pii.MaskEmail(customer.Email), records[1][7])
+	assert.NotEqual(t, customer.Email, records[1][7])
+}
+
+func TestShouldStream(t *testing.T) {
+	_, service := setupTestService(t, 100)
+	assert.False(t, service.ShouldStream(50000))
+	assert.True(t, service.ShouldStream(50001))
+}
+
+func setupTestService(t *testing.T, batchSize int) (*gorm.DB, *Service) {
+	t.Helper()
+	db := testutils.NewSQLiteDB(t, &models.User{}, &models.Lead{}, &models.FamilyMember{})
+
+	cfg := &config.Config{Export: config.ExportConfig{BatchSize: batchSize}}
+	return db, NewService(db, zap.NewNop(), cfg)
+}
+
+func parse(t *testing.T, buf *bytes.Buffer) [][]string {
+	t.Helper()
+	records, err := csv.NewReader(strings.NewReader(strings.TrimPrefix(buf.String(), bom))).ReadAll()
+	require.NoError(t, err)
+	return records
+}
+
+func createLead(t *testing.T, db *gorm.DB, userID uuid.UUID, title string, createdAt time.Time) *models.Lead {
+	t.Helper()
+	lead := &models.Lead{
+		UserID:    userID,
+		Title:     title,
+		Status:    models.LeadStatusNew,
+		Priority:  models.PriorityMedium,
+		Source:    models.LeadSourceWebsite,
+		CreatedAt: createdAt,
+	}
+	require.NoError(t, db.Create(lead).Error)
+	return lead
+}
+
+func assign(t *testing.T, db *gorm.DB, lead *models.Lead, beraterID uuid.UUID) {
+	t.Helper()
+	require.NoError(t, db.Model(lead).Update("berater_id", beraterID).Error)
+}
@@ -0,0 +1,96 @@
+package exports
+
+import (
+	"encoding/csv"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// bom makes spreadsheet programs read the CSV as UTF-8
+const bom = "\ufeff"
+
+// Column is a CSV column of an export
+type Column[T any] struct {
+	Header string
+	Value  func(*T) string
+}
+
+// cursor is the sort key of the last row of a page, the next page starts after it
+type cursor struct {
+	CreatedAt time.Time
+	ID        uuid.UUID
+}
+
+// writeCSV writes the rows selected by query in keyset pages ordered by
+// created_at and id. Only one page is held in memory at a time, flush is
+// called after every page so streamed responses reach the client right away.
+func writeCSV[T any](w io.Writer, query *gorm.DB, table string, batchSize int, columns []Column[T], key func(*T) cursor, flush func()) (int, error) {
+	if _, err := io.WriteString(w, bom); err != nil {
+		return 0, err
+	}
+	writer := csv.NewWriter(w)
+
+	header := make([]string, len(columns))
+	for i, column := range columns {
+		header[i] = column.Header
+	}
+	if err := writer.Write(header); err != nil {
+		return 0, err
+	}
+
+	var (
+		after   *cursor
+		written int
+		record  = make([]string, len(columns))
+	)
+	for {
+		page := query.Session(&gorm.Session{}).
+			Order(table + ".created_at ASC").Order(table + ".id ASC").
+			Limit(batchSize)
+		if after != nil {
+			page = page.Where("("+table+".created_at > ? OR ("+table+".created_at = ? AND "+table+".id > ?))",
+				after.CreatedAt, after.CreatedAt, after.ID)
+		}
+
+		var rows []T
+		if err := page.Find(&rows).Error; err != nil {
+			return written, err
+		}
+
+		for i := range rows {
+			for j, column := range columns {
+				record[j] = sanitize(column.Value(&rows[i]))
+			}
+			if err := writer.Write(record); err != nil {
+				return written, err
+			}
+		}
+		written += len(rows)
+
+		writer.Flush()
+		if err := writer.Error(); err != nil {
+			return written, err
+		}
+		if flush != nil {
+			flush()
+		}
+
+		if len(rows) < batchSize {
+			return written, nil
+		}
+		last := key(&rows[len(rows)-1])
+		after = &last
+	}
+}
+
+// sanitize keeps spreadsheet programs from evaluating cells as formulas
+func sanitize(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
@@ -0,0 +1,506 @@
+// Package families groups the parents and children of an Elterngeld case with
+// its leads, bookings and the application. Both parents of a family see the
+// leads and bookings attached to it, see scopes.VisibleLeads, so a case no
+// longer belongs to the single customer who happened to open it.
+package families
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/internal/scopes"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrFamilyNotFound is returned when the family does not exist or the viewer may not access it
+	ErrFamilyNotFound = errors.New("family not found")
+	// ErrMemberNotFound is returned when the parent is not a member of the family
+	ErrMemberNotFound = errors.New("family member not found")
+	// ErrChildNotFound is returned when the child does not belong to the family
+	ErrChildNotFound = errors.New("child not found")
+	// ErrLeadNotFound is returned when the lead does not exist or the viewer may not change it
+	ErrLeadNotFound = errors.New("lead not found")
+	// ErrAlreadyMember is returned when the parent already belongs to the family
+	ErrAlreadyMember = errors.New("already a member of the family")
+	// ErrApplicantRequired is returned when the applicant would be removed from the family
+	ErrApplicantRequired = errors.New("the applicant cannot be removed")
+	// ErrLeadOfOtherCustomer is returned when a lead of a customer outside the family is attached
+	ErrLeadOfOtherCustomer = errors.New("the lead belongs to a customer outside the family")
+	// ErrInvalidInput is returned for missing applicants and implausible birth dates
+	ErrInvalidInput = errors.New("invalid input")
+)
+
+const (
+	// DefaultLimit and MaxLimit bound the page size of family lists
+	DefaultLimit = 20
+	MaxLimit     = 100
+	// maxParents is the number of parents a family can have; Elterngeld knows two
+	maxParents = 2
+)
+
+// CreateInput opens a family case. Customers are the applicant of the
+// families they open; staff open a family for a customer.
+type CreateInput struct {
+	Name        string       `json:"name" binding:"required,max=200"`
+	ApplicantID *uuid.UUID   `json:"applicant_id"` // required when staff open the family
+	Children    []ChildInput `json:"children" binding:"dive"`
+}
+
+// MemberInput adds the other parent. A parent with an account of the same
+// email is linked to it and sees the family's leads and bookings.
+type MemberInput struct {
+	FirstName string `json:"first_name" binding:"required,max=100"`
+	LastName  string `json:"last_name" binding:"required,max=100"`
+	Email     string `json:"email" binding:"omitempty,email,max=255"`
+}
+
+// ChildInput records a born or expected child
+type ChildInput struct {
+	FirstName         string     `json:"first_name" binding:"max=100"`
+	BirthDate         *time.Time `json:"birth_date"`
+	ExpectedBirthDate *time.Time `json:"expected_birth_date"`
+	MultipleBirth     bool       `json:"multiple_birth"`
+}
+
+// Case is a family with the applications of its leads
+type Case struct {
+	*models.Family
+	Applications []models.ApplicationSubmission `json:"applications"`
+}
+
+// ListFilter pages through the families a viewer may see
+type ListFilter struct {
+	Search string // part of the family name
+	Page   int
+	Limit  int
+}
+
+// Service manages families and the leads and bookings attached to them
+type Service struct {
+	db     *gorm.DB
+	logger *zap.Logger
+	now    func() time.Time
+}
+
+func NewService(db *gorm.DB, logger *zap.Logger) *Service {
+	return &Service{
+		db:     db,
+		logger: logger,
+		now:    time.Now,
+	}
+}
+
+// Create opens a family with its applicant as the first parent
+func (s *Service) Create(viewer scopes.Viewer, input CreateInput) (*Case, error) {
+	applicantID := viewer.ID
+	if viewer.Role != models.RoleUser {
+		if input.ApplicantID == nil {
+			return nil, fmt.Errorf("%w: staff have to name the applicant", ErrInvalidInput)
+		}
+		applicantID = *input.ApplicantID
+	}
+	for _, child := range input.Children {
+		if err := s.validateChild(child); err != nil {
+			return nil, err
+		}
+	}
+
+	var family *models.Family
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var applicant models.User
+		if err := tx.Where("id = ? AND role = ?", applicantID, models.RoleUser).First(&applicant).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("%w: the applicant has to be a customer", ErrInvalidInput)
+			}
+			return fmt.Errorf("failed to load applicant: %w", err)
+		}
+
+		family = &models.Family{Name: strings.TrimSpace(input.Name), CreatedBy: viewer.ID}
+		if err := tx.Create(family).Error; err != nil {
+			return fmt.Errorf("failed to create family: %w", err)
+		}
+
+		member := &models.FamilyMember{
+			FamilyID:  family.ID,
+			UserID:    &applicant.ID,
+			Role:      models.FamilyRoleApplicant,
+			FirstName: applicant.FirstName,
+			LastName:  applicant.LastName,
+			Email:     applicant.Email,
+		}
+		if err := tx.Create(member).Error; err != nil {
+			return fmt.Errorf("failed to add applicant: %w", err)
+		}
+
+		for _, input := range input.Children {
+			if err := tx.Create(newChild(family.ID, input)).Error; err != nil {
+				return fmt.Errorf("failed to add child: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Family created",
+		zap.String("family_id", family.ID.String()),
+		zap.String("created_by", viewer.ID.String()))
+
+	return s.Get(family.ID, viewer)
+}
+
+// Get returns a family with its parents, children, leads, bookings and the
+// applications of its leads
+func (s *Service) Get(id uuid.UUID, viewer scopes.Viewer) (*Case, error) {
+	family, err := s.family(s.db.Preload("Members", func(db *gorm.DB) *gorm.DB {
+		return db.Order("family_members.created_at ASC")
+	}).Preload("Children", func(db *gorm.DB) *gorm.DB {
+		return db.Order("family_children.created_at ASC")
+	}).Preload("Leads", func(db *gorm.DB) *gorm.DB {
+		return db.Scopes(scopes.VisibleLeads(viewer)).Order("leads.created_at DESC")
+	}).Preload("Bookings", func(db *gorm.DB) *gorm.DB {
+		return db.Scopes(scopes.VisibleBookings(viewer)).Order("bookings.scheduled_at DESC")
+	}), id, scopes.VisibleFamilies(viewer))
+	if err != nil {
+		return nil, err
+	}
+
+	familyCase := &Case{Family: family, Applications: []models.ApplicationSubmission{}}
+	if len(family.Leads) == 0 {
+		return familyCase, nil
+	}
+	leadIDs := make([]uuid.UUID, 0, len(family.Leads))
+	for _, lead := range family.Leads {
+		leadIDs = append(leadIDs, lead.ID)
+	}
+	if err := s.db.Where("lead_id IN ?", leadIDs).Order("submitted_at DESC").
+		Find(&familyCase.Applications).Error; err != nil {
+		return nil, fmt.Errorf("failed to load applications: %w", err)
+	}
+	return familyCase, nil
+}
+
+// List returns a page of the families the viewer may see with their parents
+func (s *Service) List(viewer scopes.Viewer, filter ListFilter) ([]models.Family, int64, error) {
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+	if filter.Limit < 1 || filter.Limit > MaxLimit {
+		filter.Limit = DefaultLimit
+	}
+
+	query := s.db.Model(&models.Family{}).Scopes(scopes.VisibleFamilies(viewer))
+	if search := strings.TrimSpace(filter.Search); search != "" {
+		query = query.Where("LOWER(families.name) LIKE ?", "%"+strings.ToLower(search)+"%")
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count families: %w", err)
+	}
+
+	families := []models.Family{}
+	err := query.Preload("Members").Order("families.created_at DESC").
+		Offset((filter.Page - 1) * filter.Limit).Limit(filter.Limit).Find(&families).Error
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list families: %w", err)
+	}
+	return families, total, nil
+}
+
+// Rename changes the name of a family
+func (s *Service) Rename(id uuid.UUID, viewer scopes.Viewer, name string) (*Case, error) {
+	family, err := s.family(s.db, id, scopes.EditableFamilies(viewer))
+	if err != nil {
+		return nil, err
+	}
+	if err := s.db.Model(family).Update("name", strings.TrimSpace(name)).Error; err != nil {
+		return nil, fmt.Errorf("failed to rename family: %w", err)
+	}
+	return s.Get(id, viewer)
+}
+
+// Delete dissolves a family. Its leads and bookings stay with the customers
+// who created them.
+func (s *Service) Delete(id uuid.UUID, viewer scopes.Viewer) error {
+	family, err := s.family(s.db, id, scopes.EditableFamilies(viewer))
+	if err != nil {
+		return err
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Lead{}).Where("family_id = ?", family.ID).Update("family_id", nil).Error; err != nil {
+			return fmt.Errorf("failed to detach leads: %w", err)
+		}
+		if err := tx.Model(&models.Booking{}).Where("family_id = ?", family.ID).Update("family_id", nil).Error; err != nil {
+			return fmt.Errorf("failed to detach bookings: %w", err)
+		}
+		if err := tx.Where("family_id = ?", family.ID).Delete(&models.FamilyMember{}).Error; err != nil {
+			return fmt.Errorf("failed to remove members: %w", err)
+		}
+		if err := tx.Where("family_id = ?", family.ID).Delete(&models.FamilyChild{}).Error; err != nil {
+			return fmt.Errorf("failed to remove children: %w", err)
+		}
+		if err := tx.Delete(family).Error; err != nil {
+			return fmt.Errorf("failed to delete family: %w", err)
+		}
+		return nil
+	})
+}
+
+// AddMember adds the other parent to a family. When a customer account with
+// the email exists, the parent is linked to it.
+func (s *Service) AddMember(id uuid.UUID, viewer scopes.Viewer, input MemberInput) (*models.FamilyMember, error) {
+	family, err := s.family(s.db, id, scopes.EditableFamilies(viewer))
+	if err != nil {
+		return nil, err
+	}
+
+	member := &models.FamilyMember{
+		FamilyID:  family.ID,
+		Role:      models.FamilyRoleParent,
+		FirstName: strings.TrimSpace(input.FirstName),
+		LastName:  strings.TrimSpace(input.LastName),
+		Email:     strings.ToLower(strings.TrimSpace(input.Email)),
+	}
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		var members []models.FamilyMember
+		if err := tx.Where("family_id = ?", family.ID).Find(&members).Error; err != nil {
+			return fmt.Errorf("failed to load members: %w", err)
+		}
+		if len(members) >= maxParents {
+			return fmt.Errorf("%w: a family has at most %d parents", ErrInvalidInput, maxParents)
+		}
+
+		if member.Email != "" {
+			for _, existing := range members {
+				if existing.Email == member.Email {
+					return ErrAlreadyMember
+				}
+			}
+
+			var account models.User
+			err := tx.Select("id").Where("LOWER(email) = ? AND role = ?", member.Email, models.RoleUser).First(&account).Error
+			switch {
+			case err == nil:
+				member.UserID = &account.ID
+			case !errors.Is(err, gorm.ErrRecordNotFound):
+				return fmt.Errorf("failed to look up account: %w", err)
+			}
+		}
+
+		if err := tx.Create(member).Error; err != nil {
+			return fmt.Errorf("failed to add member: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Family member added",
+		zap.String("family_id", family.ID.String()),
+		zap.String("member_id", member.ID.String()),
+		zap.Bool("has_account", member.HasAccount()))
+
+	return member, nil
+}
+
+// RemoveMember removes the other parent from a family. The applicant stays
+// until the family is deleted.
+func (s *Service) RemoveMember(id, memberID uuid.UUID, viewer scopes.Viewer) error {
+	family, err := s.family(s.db, id, scopes.EditableFamilies(viewer))
+	if err != nil {
+		return err
+	}
+
+	var member models.FamilyMember
+	if err := s.db.Where("id = ? AND family_id = ?", memberID, family.ID).First(&member).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrMemberNotFound
+		}
+		return fmt.Errorf("failed to load member: %w", err)
+	}
+	if member.Role == models.FamilyRoleApplicant {
+		return ErrApplicantRequired
+	}
+
+	if err := s.db.Delete(&member).Error; err != nil {
+		return fmt.Errorf("failed to remove member: %w", err)
+	}
+	return nil
+}
+
+// AddChild records a born or expected child of a family
+func (s *Service) AddChild(id uuid.UUID, viewer scopes.Viewer, input ChildInput) (*models.FamilyChild, error) {
+	family, err := s.family(s.db, id, scopes.EditableFamilies(viewer))
+	if err != nil {
+		return nil, err
+	}
+	if err := s.validateChild(input); err != nil {
+		return nil, err
+	}
+
+	child := newChild(family.ID, input)
+	if err := s.db.Create(child).Error; err != nil {
+		return nil, fmt.Errorf("failed to add child: %w", err)
+	}
+	return child, nil
+}
+
+// UpdateChild corrects a child, typically to record the birth after the due date
+func (s *Service) UpdateChild(id, childID uuid.UUID, viewer scopes.Viewer, input ChildInput) (*models.FamilyChild, error) {
+	family, err := s.family(s.db, id, scopes.EditableFamilies(viewer))
+	if err != nil {
+		return nil, err
+	}
+	if err := s.validateChild(input); err != nil {
+		return nil, err
+	}
+
+	child, err := s.child(family.ID, childID)
+	if err != nil {
+		return nil, err
+	}
+	err = s.db.Model(child).Updates(map[string]interface{}{
+		"first_name":          strings.TrimSpace(input.FirstName),
+		"birth_date":          input.BirthDate,
+		"expected_birth_date": input.ExpectedBirthDate,
+		"multiple_birth":      input.MultipleBirth,
+	}).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to update child: %w", err)
+	}
+	return s.child(family.ID, childID)
+}
+
+// RemoveChild removes a child recorded by mistake
+func (s *Service) RemoveChild(id, childID uuid.UUID, viewer scopes.Viewer) error {
+	family, err := s.family(s.db, id, scopes.EditableFamilies(viewer))
+	if err != nil {
+		return err
+	}
+	child, err := s.child(family.ID, childID)
+	if err != nil {
+		return err
+	}
+	if err := s.db.Delete(child).Error; err != nil {
+		return fmt.Errorf("failed to remove child: %w", err)
+	}
+	return nil
+}
+
+// AttachLead adds a lead and its bookings to a family. The lead has to belong
+// to one of the family's parents.
+func (s *Service) AttachLead(id, leadID uuid.UUID, viewer scopes.Viewer) error {
+	family, err := s.family(s.db, id, scopes.EditableFamilies(viewer))
+	if err != nil {
+		return err
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var lead models.Lead
+		if err := tx.Scopes(scopes.EditableLeads(viewer)).First(&lead, "leads.id = ?", leadID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrLeadNotFound
+			}
+			return fmt.Errorf("failed to load lead: %w", err)
+		}
+
+		var parents int64
+		if err := tx.Model(&models.FamilyMember{}).Where("family_id = ? AND user_id = ?", family.ID, lead.UserID).
+			Count(&parents).Error; err != nil {
+			return fmt.Errorf("failed to check members: %w", err)
+		}
+		if parents == 0 {
+			return ErrLeadOfOtherCustomer
+		}
+
+		return setFamily(tx, lead.ID, &family.ID)
+	})
+}
+
+// DetachLead removes a lead and its bookings from a family
+func (s *Service) DetachLead(id, leadID uuid.UUID, viewer scopes.Viewer) error {
+	family, err := s.family(s.db, id, scopes.EditableFamilies(viewer))
+	if err != nil {
+		return err
+	}
+
+	var lead models.Lead
+	err = s.db.Scopes(scopes.EditableLeads(viewer)).
+		First(&lead, "leads.id = ? AND leads.family_id = ?", leadID, family.ID).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrLeadNotFound
+		}
+		return fmt.Errorf("failed to load lead: %w", err)
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		return setFamily(tx, lead.ID, nil)
+	})
+}
+
+// setFamily moves a lead and its bookings to a family, or out of it with nil
+func setFamily(tx *gorm.DB, leadID uuid.UUID, familyID *uuid.UUID) error {
+	if err := tx.Model(&models.Lead{}).Where("id = ?", leadID).Update("family_id", familyID).Error; err != nil {
+		return fmt.Errorf("failed to update lead: %w", err)
+	}
+	if err := tx.Model(&models.Booking{}).Where("lead_id = ?", leadID).Update("family_id", familyID).Error; err != nil {
+		return fmt.Errorf("failed to update bookings: %w", err)
+	}
+	return nil
+}
+
+// family loads a family the scope allows
+func (s *Service) family(tx *gorm.DB, id uuid.UUID, scope func(*gorm.DB) *gorm.DB) (*models.Family, error) {
+	var family models.Family
+	if err := tx.Scopes(scope).First(&family, "families.id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrFamilyNotFound
+		}
+		return nil, fmt.Errorf("failed to load family: %w", err)
+	}
+	return &family, nil
+}
+
+func (s *Service) child(familyID, childID uuid.UUID) (*models.FamilyChild, error) {
+	var child models.FamilyChild
+	if err := s.db.Where("id = ? AND family_id = ?", childID, familyID).First(&child).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrChildNotFound
+		}
+		return nil, fmt.Errorf("failed to load child: %w", err)
+	}
+	return &child, nil
+}
+
+// validateChild requires a birth date in the past or a due date
+func (s *Service) validateChild(input ChildInput) error {
+	if input.BirthDate == nil && input.ExpectedBirthDate == nil {
+		return fmt.Errorf("%w: a birth date or the expected birth date is required", ErrInvalidInput)
+	}
+	if input.BirthDate != nil && input.BirthDate.After(s.now()) {
+		return fmt.Errorf("%w: the birth date is in the future", ErrInvalidInput)
+	}
+	return nil
+}
+
+func newChild(familyID uuid.UUID, input ChildInput) *models.FamilyChild {
+	return &models.FamilyChild{
+		FamilyID:          familyID,
+		FirstName:         strings.TrimSpace(input.FirstName),
+		BirthDate:         input.BirthDate,
+		ExpectedBirthDate: input.ExpectedBirthDate,
+		MultipleBirth:     input.MultipleBirth,
+	}
+}
@@ -0,0 +1,232 @@
+package families
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/internal/scopes"
+	"elterngeld-portal/tests/testutils"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+var now = time.Date(2025, 3, 10, 9, 0, 0, 0, time.UTC)
+
+func TestCreate_ApplicantIsFirstParent(t *testing.T) {
+	db, service := setupTestService(t)
+	mother := testutils.CreateTestUser(t, db, models.RoleUser)
+	birth := now.AddDate(0, -1, 0)
+
+	familyCase, err := service.Create(viewer(mother), CreateInput{
+		Name:     "Familie Müller",
+		Children: []ChildInput{{FirstName: "Mia", BirthDate: &birth}},
+	})
+	require.NoError(t, err)
+
+	require.Len(t, familyCase.Members, 1)
+	assert.Equal(t, models.FamilyRoleApplicant, familyCase.Members[0].Role)
+	assert.Equal(t, mother.ID, *familyCase.Members[0].UserID)
+	require.Len(t, familyCase.Children, 1)
+	assert.Equal(t, "Mia", familyCase.Children[0].FirstName)
+	assert.Empty(t, familyCase.Applications)
+
+	// Children need a birth date in the past or a due date
+	_, err = service.AddChild(familyCase.ID, viewer(mother), ChildInput{FirstName: "Ohne Datum"})
+	assert.ErrorIs(t, err, ErrInvalidInput)
+	future := now.AddDate(0, 1, 0)
+	_, err = service.AddChild(familyCase.ID, viewer(mother), ChildInput{BirthDate: &future})
+	assert.ErrorIs(t, err, ErrInvalidInput)
+	child, err := service.AddChild(familyCase.ID, viewer(mother), ChildInput{ExpectedBirthDate: &future, MultipleBirth: true})
+	require.NoError(t, err)
+
+	// The birth is recorded after the due date
+	child, err = service.UpdateChild(familyCase.ID, child.ID, viewer(mother), ChildInput{FirstName: "Ben", BirthDate: &birth, MultipleBirth: true})
+	require.NoError(t, err)
+	assert.Equal(t, "Ben", child.FirstName)
+	assert.Nil(t, child.ExpectedBirthDate)
+}
+
+func TestCreate_StaffNameTheApplicant(t *testing.T) {
+	db, service := setupTestService(t)
+	berater := testutils.CreateTestUser(t, db, models.RoleBerater)
+	customer := testutils.CreateTestUser(t, db, models.RoleUser)
+
+	_, err := service.Create(viewer(berater), CreateInput{Name: "Familie Schmidt"})
+	assert.ErrorIs(t, err, ErrInvalidInput)
+
+	// Only customers can be applicants
+	_, err = service.Create(viewer(berater), CreateInput{Name: "Familie Schmidt", ApplicantID: &berater.ID})
+	assert.ErrorIs(t, err, ErrInvalidInput)
+
+	familyCase, err := service.Create(viewer(berater), CreateInput{Name: "Familie Schmidt", ApplicantID: &customer.ID})
+	require.NoError(t, err)
+	assert.Equal(t, customer.ID, *familyCase.Members[0].UserID)
+	assert.Equal(t, berater.ID, familyCase.CreatedBy)
+}
+
+func TestMembers_SharedLeadsAndBookings(t *testing.T) {
+	db, service := setupTestService(t)
+	mother := testutils.CreateTestUser(t, db, models.RoleUser)
+	father := testutils.CreateTestUser(t, db, models.RoleUser)
+	stranger := testutils.CreateTestUser(t, db, models.RoleUser)
+
+	familyCase, err := service.Create(viewer(mother), CreateInput{Name: "Familie Weber"})
+	require.NoError(t, err)
+	lead := createLead(t, db, mother.ID)
+	booking := createBooking(t, db, mother.ID, lead.ID)
+
+	// The father has an account and is linked by its email
+	member, err := service.AddMember(familyCase.ID, viewer(mother), MemberInput{FirstName: "Jonas", LastName: "Weber", Email: " " + father.Email})
+	require.NoError(t, err)
+	require.True(t, member.HasAccount())
+	assert.Equal(t, father.ID, *member.UserID)
+
+	_, err = service.AddMember(familyCase.ID, viewer(mother), MemberInput{FirstName: "Jonas", LastName: "Weber", Email: father.Email})
+	assert.ErrorIs(t, err, ErrInvalidInput, "a family has two parents")
+
+	// Before the lead is attached only the mother sees it
+	_, err = service.Get(familyCase.ID, viewer(stranger))
+	assert.ErrorIs(t, err, ErrFamilyNotFound)
+	var visible int64
+	require.NoError(t, db.Model(&models.Lead{}).Scopes(scopes.VisibleLeads(viewer(father))).Count(&visible).Error)
+	assert.Zero(t, visible)
+
+	require.NoError(t, service.AttachLead(familyCase.ID, lead.ID, viewer(mother)))
+
+	familyCase, err = service.Get(familyCase.ID, viewer(father))
+	require.NoError(t, err)
+	require.Len(t, familyCase.Leads, 1)
+	assert.Equal(t, lead.ID, familyCase.Leads[0].ID)
+	require.Len(t, familyCase.Bookings, 1)
+	assert.Equal(t, booking.ID, familyCase.Bookings[0].ID)
+
+	submission := models.ApplicationSubmission{LeadID: lead.ID, SubmittedByID: mother.ID, SubmittedAt: now, Status: models.SubmissionStatusSubmitted}
+	require.NoError(t, db.Create(&submission).Error)
+	familyCase, err = service.Get(familyCase.ID, viewer(mother))
+	require.NoError(t, err)
+	require.Len(t, familyCase.Applications, 1)
+	assert.Equal(t, submission.ID, familyCase.Applications[0].ID)
+
+	// The applicant stays, the other parent can be removed and loses access
+	assert.ErrorIs(t, service.RemoveMember(familyCase.ID, familyCase.Members[0].ID, viewer(father)), ErrApplicantRequired)
+	require.NoError(t, service.RemoveMember(familyCase.ID, member.ID, viewer(mother)))
+	_, err = service.Get(familyCase.ID, viewer(father))
+	assert.ErrorIs(t, err, ErrFamilyNotFound)
+}
+
+func TestAttachLead_OnlyLeadsOfParents(t *testing.T) {
+	db, service := setupTestService(t)
+	mother := testutils.CreateTestUser(t, db, models.RoleUser)
+	stranger := testutils.CreateTestUser(t, db, models.RoleUser)
+	berater := testutils.CreateTestUser(t, db, models.RoleBerater)
+
+	familyCase, err := service.Create(viewer(mother), CreateInput{Name: "Familie Becker"})
+	require.NoError(t, err)
+	strangerLead := createLead(t, db, stranger.ID)
+
+	// Customers cannot even see the lead, staff are refused to mix up cases
+	assert.ErrorIs(t, service.AttachLead(familyCase.ID, strangerLead.ID, viewer(mother)), ErrLeadNotFound)
+	assert.ErrorIs(t, service.AttachLead(familyCase.ID, strangerLead.ID, viewer(berater)), ErrLeadOfOtherCustomer)
+
+	lead := createLead(t, db, mother.ID)
+	require.NoError(t, service.AttachLead(familyCase.ID, lead.ID, viewer(berater)))
+	require.NoError(t, service.DetachLead(familyCase.ID, lead.ID, viewer(mother)))
+	assert.ErrorIs(t, service.DetachLead(familyCase.ID, lead.ID, viewer(mother)), ErrLeadNotFound)
+}
+
+func TestDelete_KeepsLeads(t *testing.T) {
+	db, service := setupTestService(t)
+	mother := testutils.CreateTestUser(t, db, models.RoleUser)
+	stranger := testutils.CreateTestUser(t, db, models.RoleUser)
+
+	familyCase, err := service.Create(viewer(mother), CreateInput{Name: "Familie Wolf"})
+	require.NoError(t, err)
+	lead := createLead(t, db, mother.ID)
+	booking := createBooking(t, db, mother.ID, lead.ID)
+	require.NoError(t, service.AttachLead(familyCase.ID, lead.ID, viewer(mother)))
+
+	assert.ErrorIs(t, service.Delete(familyCase.ID, viewer(stranger)), ErrFamilyNotFound)
+	require.NoError(t, service.Delete(familyCase.ID, viewer(mother)))
+
+	var storedLead models.Lead
+	require.NoError(t, db.First(&storedLead, "id = ?", lead.ID).Error)
+	assert.Nil(t, storedLead.FamilyID)
+	var storedBooking models.Booking
+	require.NoError(t, db.First(&storedBooking, "id = ?", booking.ID).Error)
+	assert.Nil(t, storedBooking.FamilyID)
+
+	families, total, err := service.List(viewer(mother), ListFilter{})
+	require.NoError(t, err)
+	assert.Zero(t, total)
+	assert.Empty(t, families)
+}
+
+func TestList_SearchAndPaging(t *testing.T) {
+	db, service := setupTestService(t)
+	admin := testutils.CreateTestUser(t, db, models.RoleAdmin)
+	for i := 0; i < 3; i++ {
+		customer := testutils.CreateTestUser(t, db, models.RoleUser)
+		_, err := service.Create(viewer(customer), CreateInput{Name: fmt.Sprintf("Familie Nummer %d", i)})
+		require.NoError(t, err)
+	}
+
+	families, total, err := service.List(viewer(admin), ListFilter{Page: 2, Limit: 2})
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), total)
+	assert.Len(t, families, 1)
+
+	families, total, err = service.List(viewer(admin), ListFilter{Search: "nummer 1"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+	require.Len(t, families, 1)
+	assert.Len(t, families[0].Members, 1)
+}
+
+func setupTestService(t *testing.T) (*gorm.DB, *Service) {
+	t.Helper()
+	db := testutils.NewSQLiteDB(t, &models.User{}, &models.Lead{}, &models.Booking{}, &models.ApplicationSubmission{},
+		&models.Family{}, &models.FamilyMember{}, &models.FamilyChild{})
+
+	service := NewService(db, zap.NewNop())
+	service.now = func() time.Time { return now }
+	return db, service
+}
+
+func viewer(user *models.User) scopes.Viewer {
+	return scopes.Viewer{ID: user.ID, Role: user.Role}
+}
+
+func createLead(t *testing.T, db *gorm.DB, userID uuid.UUID) *models.Lead {
+	t.Helper()
+	lead := &models.Lead{
+		UserID:   userID,
+		Title:    "Elterngeld",
+		Status:   models.LeadStatusNew,
+		Priority: models.PriorityMedium,
+		Source:   models.LeadSourceWebsite,
+	}
+	require.NoError(t, db.Create(lead).Error)
+	return lead
+}
+
+func createBooking(t *testing.T, db *gorm.DB, userID, leadID uuid.UUID) *models.Booking {
+	t.Helper()
+	start := now.Add(48 * time.Hour)
+	booking := &models.Booking{
+		UserID:      userID,
+		LeadID:      &leadID,
+		Title:       "Beratung",
+		Status:      models.BookingStatusConfirmed,
+		ScheduledAt: start,
+		StartTime:   start,
+		EndTime:     start.Add(time.Hour),
+	}
+	require.NoError(t, db.Create(booking).Error)
+	return booking
+}
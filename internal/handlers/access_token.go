@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"elterngeld-portal/internal/accesstokens"
+	"elterngeld-portal/internal/middleware"
+	"elterngeld-portal/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type AccessTokenHandler struct {
+	db     *gorm.DB
+	logger *zap.Logger
+	tokens *accesstokens.Service
+}
+
+func NewAccessTokenHandler(db *gorm.DB, logger *zap.Logger, tokenService *accesstokens.Service) *AccessTokenHandler {
+	return &AccessTokenHandler{
+		db:     db,
+		logger: logger,
+		tokens: tokenService,
+	}
+}
+
+// CreateAccessTokenRequest represents the request for creating a personal access token
+type CreateAccessTokenRequest struct {
+	Name      string                    `json:"name" binding:"required,max=100"`
+	Scopes    []models.AccessTokenScope `json:"scopes" binding:"required,min=1"`
+	ExpiresAt *time.Time                `json:"expires_at"`
+}
+
+// ListAccessTokens handles listing the current user's personal access tokens
+// @Summary List access tokens
+// @Description Get the personal access tokens of the current user, including revoked and expired ones
+// @Tags tokens
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/me/tokens [get]
+func (h *AccessTokenHandler) ListAccessTokens(c *gin.Context) {
+	userID, ok := middleware.GetCurrentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "User not authenticated")})
+		return
+	}
+
+	tokens, err := h.tokens.List(userID)
+	if err != nil {
+		h.handleAccessTokenError(c, err, "Failed to fetch access tokens")
+		return
+	}
+
+	responses := make([]models.AccessTokenResponse, len(tokens))
+	for i := range tokens {
+		responses[i] = tokens[i].ToResponse()
+	}
+
+	c.JSON(http.StatusOK, gin.H{"tokens": responses})
+}
+
+// CreateAccessToken handles creating a personal access token
+// @Summary Create access token
+// @Description Create a scoped personal access token that can be sent as bearer token instead of a login. Without expires_at it is valid for 90 days, at most for a year. The token is only returned once.
+// @Tags tokens
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body CreateAccessTokenRequest true "Token data"
+// @Success 201 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/v1/me/tokens [post]
+func (h *AccessTokenHandler) CreateAccessToken(c *gin.Context) {
+	userID, ok := middleware.GetCurrentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "User not authenticated")})
+		return
+	}
+
+	var req CreateAccessTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid request data"), "details": err.Error()})
+		return
+	}
+
+	token, plain, err := h.tokens.Create(userID, req.Name, req.Scopes, req.ExpiresAt)
+	if err != nil {
+		h.handleAccessTokenError(c, err, "Failed to create access token")
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"token":        token.ToResponse(),
+		"access_token": plain,
+	})
+}
+
+// RevokeAccessToken handles revoking a personal access token
+// @Summary Revoke access token
+// @Description Revoke one of the current user's personal access tokens; it stops working immediately
+// @Tags tokens
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Token ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/me/tokens/{id} [delete]
+func (h *AccessTokenHandler) RevokeAccessToken(c *gin.Context) {
+	userID, ok := middleware.GetCurrentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "User not authenticated")})
+		return
+	}
+
+	tokenID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid access token ID")})
+		return
+	}
+
+	if err := h.tokens.Revoke(tokenID, userID); err != nil {
+		h.handleAccessTokenError(c, err, "Failed to revoke access token")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": middleware.T(c, "Access token revoked")})
+}
+
+func (h *AccessTokenHandler) handleAccessTokenError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, accesstokens.ErrTokenNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "Access token not found")})
+	case errors.Is(err, accesstokens.ErrInvalidScope):
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Unknown access token scope")})
+	case errors.Is(err, accesstokens.ErrInvalidExpiry):
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Expiry date must be in the future and at most one year ahead")})
+	case errors.Is(err, accesstokens.ErrTooManyTokens):
+		c.JSON(http.StatusConflict, gin.H{"error": middleware.T(c, "You have reached the maximum number of access tokens")})
+	default:
+		h.logger.Error(message, zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, message)})
+	}
+}
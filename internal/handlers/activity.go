@@ -0,0 +1,303 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"elterngeld-portal/internal/activity"
+	"elterngeld-portal/internal/activitylog"
+	"elterngeld-portal/internal/middleware"
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/internal/scopes"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type ActivityHandler struct {
+	db       *gorm.DB
+	logger   *zap.Logger
+	activity *activity.Service
+	log      *activitylog.Service
+}
+
+func NewActivityHandler(db *gorm.DB, logger *zap.Logger, activityService *activity.Service, activityLog *activitylog.Service) *ActivityHandler {
+	return &ActivityHandler{
+		db:       db,
+		logger:   logger,
+		activity: activityService,
+		log:      activityLog,
+	}
+}
+
+// ListActivities handles listing the activity history
+// @Summary List activities
+// @Description Get the activities on the leads the current user may see and their own activities, latest first. Admins see all activities.
+// @Tags activities
+// @Security BearerAuth
+// @Produce json
+// @Param lead_id query string false "Only activities of this lead"
+// @Param user_id query string false "Only activities caused by this user"
+// @Param types query string false "Comma-separated activity types, e.g. lead_status_changed,comment_added"
+// @Param since query string false "Start time (RFC3339)"
+// @Param until query string false "End time (RFC3339)"
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page (max 100)" default(20)
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/v1/activities [get]
+// @Router /api/v1/admin/activities [get]
+func (h *ActivityHandler) ListActivities(c *gin.Context) {
+	viewer, ok := scopes.FromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "User not authenticated")})
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(activitylog.DefaultLimit)))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > activitylog.MaxLimit {
+		limit = activitylog.DefaultLimit
+	}
+	filter := activitylog.Filter{Page: page, Limit: limit}
+
+	if value := c.Query("lead_id"); value != "" {
+		leadID, err := uuid.Parse(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid lead ID")})
+			return
+		}
+		filter.LeadID = &leadID
+	}
+	if value := c.Query("user_id"); value != "" {
+		userID, err := uuid.Parse(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid user ID")})
+			return
+		}
+		filter.UserID = &userID
+	}
+	for param, target := range map[string]**time.Time{"since": &filter.Since, "until": &filter.Until} {
+		if value := c.Query(param); value != "" {
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid time format. Use RFC3339")})
+				return
+			}
+			*target = &t
+		}
+	}
+	if types := c.Query("types"); types != "" {
+		for _, value := range strings.Split(types, ",") {
+			filter.Types = append(filter.Types, models.ActivityType(strings.TrimSpace(value)))
+		}
+	}
+
+	activities, total, err := h.log.List(viewer, filter)
+	if err != nil {
+		h.handleActivityError(c, err, "Failed to fetch activities")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"activities": activities,
+		"pagination": gin.H{
+			"page":  page,
+			"limit": limit,
+			"total": total,
+			"pages": (total + int64(limit) - 1) / int64(limit),
+		},
+	})
+}
+
+// GetActivity handles getting a single activity
+// @Summary Get activity
+// @Description Get an activity with its structured metadata
+// @Tags activities
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Activity ID"
+// @Success 200 {object} models.ActivityResponse
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/activities/{id} [get]
+func (h *ActivityHandler) GetActivity(c *gin.Context) {
+	viewer, ok := scopes.FromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "User not authenticated")})
+		return
+	}
+
+	activityID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid activity ID")})
+		return
+	}
+
+	entry, err := h.log.Get(activityID, viewer)
+	if err != nil {
+		h.handleActivityError(c, err, "Failed to fetch activity")
+		return
+	}
+
+	c.JSON(http.StatusOK, entry)
+}
+
+// GetActivityFeed handles the activity feed (Beraters and admins)
+// @Summary Get activity feed
+// @Description New leads, large payments, cancelled bookings and SLA breaches, latest first. Beraters only see their own leads and bookings.
+// @Tags activities
+// @Security BearerAuth
+// @Produce json
+// @Param since query string false "Start time (RFC3339), defaults to 7 days ago"
+// @Param until query string false "End time (RFC3339), defaults to now"
+// @Param types query string false "Comma-separated item types (lead.created, payment.large, booking.cancelled, lead.sla_breached)"
+// @Param berater_id query string false "Only items of this Berater (admins only)"
+// @Param limit query int false "Maximum number of items (default 50, max 200)"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/v1/admin/activity-feed [get]
+// @Router /api/v1/activity-feed [get]
+func (h *ActivityHandler) GetActivityFeed(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "User not authenticated")})
+		return
+	}
+	role, _ := middleware.GetCurrentUserRole(c)
+
+	now := time.Now()
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(activity.DefaultLimit)))
+	filter := activity.Filter{Since: now.AddDate(0, 0, -7), Until: now, Limit: limit}
+
+	var err error
+	if since := c.Query("since"); since != "" {
+		if filter.Since, err = time.Parse(time.RFC3339, since); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid time format. Use RFC3339")})
+			return
+		}
+	}
+	if until := c.Query("until"); until != "" {
+		if filter.Until, err = time.Parse(time.RFC3339, until); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid time format. Use RFC3339")})
+			return
+		}
+	}
+	if filter.Until.Before(filter.Since) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "End date must not be before start date")})
+		return
+	}
+
+	if types := c.Query("types"); types != "" {
+		for _, value := range strings.Split(types, ",") {
+			itemType := activity.ItemType(strings.TrimSpace(value))
+			if !itemType.IsValid() {
+				c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid activity type")})
+				return
+			}
+			filter.Types = append(filter.Types, itemType)
+		}
+	}
+
+	if role == models.RoleAdmin {
+		if value := c.Query("berater_id"); value != "" {
+			beraterID, err := uuid.Parse(value)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid Berater ID")})
+				return
+			}
+			filter.BeraterID = &beraterID
+		}
+	} else {
+		filter.BeraterID = &userID
+	}
+
+	items, err := h.activity.Feed(filter)
+	if err != nil {
+		h.handleActivityError(c, err, "Failed to fetch activity feed")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"items": items})
+}
+
+// GetDigestPreference handles reading the daily digest setting of the current user
+// @Summary Get daily digest setting
+// @Description Whether the current Berater or admin receives the activity feed as a daily email
+// @Tags activities
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} activity.DigestPreference
+// @Router /api/v1/activity-feed/digest [get]
+func (h *ActivityHandler) GetDigestPreference(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "User not authenticated")})
+		return
+	}
+
+	preference, err := h.activity.GetDigestPreference(userID)
+	if err != nil {
+		h.handleActivityError(c, err, "Failed to fetch notification preferences")
+		return
+	}
+
+	c.JSON(http.StatusOK, preference)
+}
+
+// UpdateDigestPreferenceRequest turns the daily digest on or off
+type UpdateDigestPreferenceRequest struct {
+	EmailDailyDigest *bool `json:"email_daily_digest" binding:"required"`
+}
+
+// UpdateDigestPreference handles changing the daily digest setting of the current user
+// @Summary Update daily digest setting
+// @Description Turn the daily activity digest email on or off for the current Berater or admin
+// @Tags activities
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body UpdateDigestPreferenceRequest true "Digest setting"
+// @Success 200 {object} activity.DigestPreference
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/v1/activity-feed/digest [put]
+func (h *ActivityHandler) UpdateDigestPreference(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "User not authenticated")})
+		return
+	}
+
+	var req UpdateDigestPreferenceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid request data"), "details": err.Error()})
+		return
+	}
+
+	preference, err := h.activity.SetDigestPreference(userID, *req.EmailDailyDigest)
+	if err != nil {
+		h.handleActivityError(c, err, "Failed to update notification preferences")
+		return
+	}
+
+	c.JSON(http.StatusOK, preference)
+}
+
+func (h *ActivityHandler) handleActivityError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, activity.ErrInvalidItemType), errors.Is(err, activitylog.ErrInvalidType):
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid activity type")})
+	case errors.Is(err, activitylog.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "Activity not found")})
+	default:
+		h.logger.Error(message, zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, message)})
+	}
+}
@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"elterngeld-portal/internal/addresses"
+	"elterngeld-portal/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type AddressHandler struct {
+	db        *gorm.DB
+	logger    *zap.Logger
+	addresses *addresses.Service
+}
+
+func NewAddressHandler(db *gorm.DB, logger *zap.Logger, addressService *addresses.Service) *AddressHandler {
+	return &AddressHandler{
+		db:        db,
+		logger:    logger,
+		addresses: addressService,
+	}
+}
+
+// ValidateAddressRequest is an address as entered in the contact-info step
+type ValidateAddressRequest struct {
+	Street      string `json:"street" binding:"required"`
+	HouseNumber string `json:"house_number"`
+	PostalCode  string `json:"postal_code" binding:"required"`
+	City        string `json:"city" binding:"required"`
+	Country     string `json:"country"` // defaults to Germany
+}
+
+// ValidateAddress handles checking an address before it is saved
+// @Summary Validate address
+// @Description Normalize street, postal code and city and derive the Bundesland. Status is verified for known addresses, undeliverable for unknown ones and empty when the address could not be checked.
+// @Tags addresses
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body ValidateAddressRequest true "Address"
+// @Success 200 {object} addresses.Result
+// @Failure 400 {object} map[string]interface{}
+// @Failure 503 {object} map[string]interface{}
+// @Router /api/v1/addresses/validate [post]
+func (h *AddressHandler) ValidateAddress(c *gin.Context) {
+	var req ValidateAddressRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid request data"), "details": err.Error()})
+		return
+	}
+
+	result, err := h.addresses.Validate(c.Request.Context(), addresses.Address{
+		Street:      req.Street,
+		HouseNumber: req.HouseNumber,
+		PostalCode:  req.PostalCode,
+		City:        req.City,
+		Country:     req.Country,
+	})
+	if err != nil {
+		h.logger.Warn("Failed to validate address", zap.Error(err))
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": middleware.T(c, "Address validation is currently unavailable")})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// AutocompleteAddress handles suggesting addresses while the customer types
+// @Summary Autocomplete address
+// @Description Suggest addresses in Germany for a partially typed address (at least 3 characters), including their Bundesland
+// @Tags addresses
+// @Security BearerAuth
+// @Produce json
+// @Param q query string true "Partially typed address"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 501 {object} map[string]interface{}
+// @Failure 503 {object} map[string]interface{}
+// @Router /api/v1/addresses/autocomplete [get]
+func (h *AddressHandler) AutocompleteAddress(c *gin.Context) {
+	suggestions, err := h.addresses.Suggest(c.Request.Context(), c.Query("q"))
+	switch {
+	case errors.Is(err, addresses.ErrQueryTooShort):
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Query must be at least %d characters long", addresses.MinQueryLength)})
+		return
+	case errors.Is(err, addresses.ErrAutocompleteUnavailable):
+		c.JSON(http.StatusNotImplemented, gin.H{"error": middleware.T(c, "Address autocomplete is not available")})
+		return
+	case err != nil:
+		h.logger.Warn("Failed to suggest addresses", zap.Error(err))
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": middleware.T(c, "Address validation is currently unavailable")})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"suggestions": suggestions})
+}
@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"elterngeld-portal/internal/analytics"
+	"elterngeld-portal/internal/middleware"
+	"elterngeld-portal/pkg/timeutil"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type AnalyticsHandler struct {
+	db        *gorm.DB
+	logger    *zap.Logger
+	analytics *analytics.Service
+}
+
+func NewAnalyticsHandler(db *gorm.DB, logger *zap.Logger, analyticsService *analytics.Service) *AnalyticsHandler {
+	return &AnalyticsHandler{
+		db:        db,
+		logger:    logger,
+		analytics: analyticsService,
+	}
+}
+
+// TrackEventsRequest represents a batch of anonymous analytics events
+type TrackEventsRequest struct {
+	VisitorID   uuid.UUID              `json:"visitor_id" binding:"required"`
+	Consent     bool                   `json:"consent"` // analytics consent from the cookie banner
+	Referrer    string                 `json:"referrer"`
+	UTMSource   string                 `json:"utm_source"`
+	UTMMedium   string                 `json:"utm_medium"`
+	UTMCampaign string                 `json:"utm_campaign"`
+	Events      []analytics.EventInput `json:"events" binding:"required,min=1,dive"`
+}
+
+// TrackEvents handles collecting anonymous analytics events
+// @Summary Track analytics events
+// @Description Collect page views, calculator usage, pre-talk bookings and checkout steps for funnel reports. Events are only stored with analytics consent and without Global Privacy Control; no IP address or account is linked.
+// @Tags analytics
+// @Accept json
+// @Produce json
+// @Param request body TrackEventsRequest true "Events"
+// @Success 202 {object} map[string]interface{}
+// @Success 204 "Not stored without consent"
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/v1/events [post]
+func (h *AnalyticsHandler) TrackEvents(c *gin.Context) {
+	var req TrackEventsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid request data"), "details": err.Error()})
+		return
+	}
+
+	// Events are dropped silently, so the frontend does not need to know the consent state
+	if !req.Consent || c.GetHeader("Sec-GPC") == "1" {
+		c.Status(http.StatusNoContent)
+		return
+	}
+
+	session, err := h.analytics.Track(analytics.TrackInput{
+		VisitorID:   req.VisitorID,
+		Referrer:    req.Referrer,
+		UTMSource:   req.UTMSource,
+		UTMMedium:   req.UTMMedium,
+		UTMCampaign: req.UTMCampaign,
+		Events:      req.Events,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, analytics.ErrInvalidVisitorID), errors.Is(err, analytics.ErrInvalidBatch):
+			c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid request data")})
+		case errors.Is(err, analytics.ErrInvalidEventType):
+			c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid event")})
+		case errors.Is(err, analytics.ErrInvalidCheckoutStep):
+			c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid checkout step")})
+		default:
+			h.logger.Error("Failed to track analytics events", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to track events")})
+		}
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"session_id": session.ID})
+}
+
+// GetFunnelReport handles the conversion funnel report (admin only)
+// @Summary Get funnel report
+// @Description Visitors from visit to calculator, pre-talk and paid booking, with conversion rates and checkout steps
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Param from query string false "First day (YYYY-MM-DD), defaults to 30 days ago"
+// @Param to query string false "Last day (YYYY-MM-DD), defaults to today"
+// @Param utm_source query string false "Only visitors with a session from this campaign source"
+// @Success 200 {object} analytics.FunnelReport
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/v1/admin/analytics/funnel [get]
+func (h *AnalyticsHandler) GetFunnelReport(c *gin.Context) {
+	loc := middleware.GetTimezone(c)
+	today := timeutil.StartOfDay(time.Now(), loc)
+
+	from := timeutil.AddDays(today, -29, loc)
+	if value := c.Query("from"); value != "" {
+		date, err := timeutil.ParseDate(value, loc)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid date format. Use YYYY-MM-DD")})
+			return
+		}
+		from = date
+	}
+	to := today
+	if value := c.Query("to"); value != "" {
+		date, err := timeutil.ParseDate(value, loc)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid date format. Use YYYY-MM-DD")})
+			return
+		}
+		to = date
+	}
+	if to.Before(from) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "End date must not be before start date")})
+		return
+	}
+
+	report, err := h.analytics.Funnel(analytics.FunnelFilter{
+		From:      from,
+		To:        timeutil.AddDays(to, 1, loc),
+		UTMSource: c.Query("utm_source"),
+	})
+	if err != nil {
+		h.logger.Error("Failed to build funnel report", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to build funnel report")})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
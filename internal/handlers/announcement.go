@@ -0,0 +1,247 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"elterngeld-portal/internal/announcements"
+	"elterngeld-portal/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type AnnouncementHandler struct {
+	db            *gorm.DB
+	logger        *zap.Logger
+	announcements *announcements.Service
+}
+
+func NewAnnouncementHandler(db *gorm.DB, logger *zap.Logger, announcementService *announcements.Service) *AnnouncementHandler {
+	return &AnnouncementHandler{
+		db:            db,
+		logger:        logger,
+		announcements: announcementService,
+	}
+}
+
+// ListMyAnnouncements handles listing the announcements published to the current user
+// @Summary List my announcements
+// @Description Get the announcements published to the current user, the latest first; compliance notices have to be marked read
+// @Tags announcements
+// @Security BearerAuth
+// @Produce json
+// @Param unread query bool false "Only unread announcements"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/announcements [get]
+func (h *AnnouncementHandler) ListMyAnnouncements(c *gin.Context) {
+	userID, ok := middleware.GetCurrentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "User not authenticated")})
+		return
+	}
+
+	notices, err := h.announcements.ForUser(userID, c.Query("unread") == "true")
+	if err != nil {
+		h.handleAnnouncementError(c, err, "Failed to fetch announcements")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"announcements": notices})
+}
+
+// MarkAnnouncementRead handles confirming that the current user read an announcement
+// @Summary Mark announcement read
+// @Description Record that the current user read an announcement; the first confirmation is kept
+// @Tags announcements
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Announcement ID"
+// @Success 200 {object} announcements.Notice
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/announcements/{id}/read [post]
+func (h *AnnouncementHandler) MarkAnnouncementRead(c *gin.Context) {
+	userID, ok := middleware.GetCurrentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "User not authenticated")})
+		return
+	}
+	announcementID, ok := h.announcementID(c)
+	if !ok {
+		return
+	}
+
+	notice, err := h.announcements.MarkRead(announcementID, userID)
+	if err != nil {
+		h.handleAnnouncementError(c, err, "Failed to mark announcement read")
+		return
+	}
+
+	c.JSON(http.StatusOK, notice)
+}
+
+// ListAnnouncements handles listing all announcements (admin only)
+// @Summary List announcements
+// @Description Get the scheduled and published announcements, the latest first
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/admin/announcements [get]
+func (h *AnnouncementHandler) ListAnnouncements(c *gin.Context) {
+	list, err := h.announcements.List()
+	if err != nil {
+		h.handleAnnouncementError(c, err, "Failed to fetch announcements")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"announcements": list})
+}
+
+// CreateAnnouncement handles scheduling an announcement (admin only)
+// @Summary Create announcement
+// @Description Broadcast a notice to all users, to customers with upcoming bookings or to the Beraters, as in-app notification and optionally as email. Without a publish time it is published within the next check interval.
+// @Tags admin
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body announcements.Input true "Announcement"
+// @Success 201 {object} models.Announcement
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/v1/admin/announcements [post]
+func (h *AnnouncementHandler) CreateAnnouncement(c *gin.Context) {
+	userID, ok := middleware.GetCurrentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "User not authenticated")})
+		return
+	}
+
+	var req announcements.Input
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid request data"), "details": err.Error()})
+		return
+	}
+
+	announcement, err := h.announcements.Create(req, userID)
+	if err != nil {
+		h.handleAnnouncementError(c, err, "Failed to create announcement")
+		return
+	}
+
+	h.logger.Info("Announcement scheduled",
+		zap.String("announcement_id", announcement.ID.String()),
+		zap.String("audience", string(announcement.Audience)),
+		zap.Time("publish_at", announcement.PublishAt))
+
+	c.JSON(http.StatusCreated, announcement)
+}
+
+// UpdateAnnouncement handles changing a scheduled announcement (admin only)
+// @Summary Update announcement
+// @Description Change an announcement that was not published yet
+// @Tags admin
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Announcement ID"
+// @Param request body announcements.Input true "Announcement"
+// @Success 200 {object} models.Announcement
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/v1/admin/announcements/{id} [put]
+func (h *AnnouncementHandler) UpdateAnnouncement(c *gin.Context) {
+	announcementID, ok := h.announcementID(c)
+	if !ok {
+		return
+	}
+
+	var req announcements.Input
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid request data"), "details": err.Error()})
+		return
+	}
+
+	announcement, err := h.announcements.Update(announcementID, req)
+	if err != nil {
+		h.handleAnnouncementError(c, err, "Failed to update announcement")
+		return
+	}
+
+	c.JSON(http.StatusOK, announcement)
+}
+
+// DeleteAnnouncement handles removing a scheduled announcement (admin only)
+// @Summary Delete announcement
+// @Description Remove an announcement that was not published yet; published announcements are kept with their read receipts
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Announcement ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/v1/admin/announcements/{id} [delete]
+func (h *AnnouncementHandler) DeleteAnnouncement(c *gin.Context) {
+	announcementID, ok := h.announcementID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.announcements.Delete(announcementID); err != nil {
+		h.handleAnnouncementError(c, err, "Failed to delete announcement")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": middleware.T(c, "Announcement deleted")})
+}
+
+// GetAnnouncementReport handles showing who read an announcement (admin only)
+// @Summary Get announcement read report
+// @Description Get the recipients of a published announcement and when they read it, unread first
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Announcement ID"
+// @Success 200 {object} announcements.Report
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/admin/announcements/{id}/report [get]
+func (h *AnnouncementHandler) GetAnnouncementReport(c *gin.Context) {
+	announcementID, ok := h.announcementID(c)
+	if !ok {
+		return
+	}
+
+	report, err := h.announcements.Report(announcementID)
+	if err != nil {
+		h.handleAnnouncementError(c, err, "Failed to fetch announcement report")
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+func (h *AnnouncementHandler) announcementID(c *gin.Context) (uuid.UUID, bool) {
+	announcementID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid announcement ID")})
+		return uuid.Nil, false
+	}
+	return announcementID, true
+}
+
+func (h *AnnouncementHandler) handleAnnouncementError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, announcements.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "Announcement not found")})
+	case errors.Is(err, announcements.ErrInvalidAudience):
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid announcement audience")})
+	case errors.Is(err, announcements.ErrAlreadyPublished):
+		c.JSON(http.StatusConflict, gin.H{"error": middleware.T(c, "Announcement has already been published")})
+	default:
+		h.logger.Error(message, zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, message)})
+	}
+}
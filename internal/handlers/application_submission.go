@@ -0,0 +1,254 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"elterngeld-portal/internal/middleware"
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/internal/scopes"
+	"elterngeld-portal/internal/submissions"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type ApplicationSubmissionHandler struct {
+	db          *gorm.DB
+	logger      *zap.Logger
+	submissions *submissions.Service
+}
+
+func NewApplicationSubmissionHandler(db *gorm.DB, logger *zap.Logger, submissionService *submissions.Service) *ApplicationSubmissionHandler {
+	return &ApplicationSubmissionHandler{
+		db:          db,
+		logger:      logger,
+		submissions: submissionService,
+	}
+}
+
+// ListSubmissions handles listing submitted applications with their next deadline
+// @Summary List submitted applications
+// @Description List the applications submitted to the Elterngeldstelle with open document requests and the next deadline, the earliest first
+// @Tags leads
+// @Security BearerAuth
+// @Produce json
+// @Param status query string false "Filter by status (eingereicht, unterlagen_nachgefordert, beschieden)"
+// @Param flagged query bool false "Only Bescheide that fall significantly short of the calculation"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/v1/application-submissions [get]
+func (h *ApplicationSubmissionHandler) ListSubmissions(c *gin.Context) {
+	viewer, ok := scopes.FromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "User not authenticated")})
+		return
+	}
+
+	result, err := h.submissions.List(viewer, submissions.ListFilter{
+		Status:  models.SubmissionStatus(c.Query("status")),
+		Flagged: c.Query("flagged") == "true",
+	})
+	if err != nil {
+		h.handleSubmissionError(c, err, "Failed to fetch submissions")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"submissions": result})
+}
+
+// GetSubmission handles getting the tracking of a lead's submitted application
+// @Summary Get lead submission
+// @Description Get the submission of a lead's application with document requests, the Bescheid and deadlines
+// @Tags leads
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Lead ID"
+// @Success 200 {object} submissions.Tracking
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/leads/{id}/submission [get]
+func (h *ApplicationSubmissionHandler) GetSubmission(c *gin.Context) {
+	viewer, leadID, ok := h.leadRequest(c)
+	if !ok {
+		return
+	}
+
+	tracking, err := h.submissions.Get(leadID, viewer)
+	if err != nil {
+		h.handleSubmissionError(c, err, "Failed to fetch submission")
+		return
+	}
+
+	c.JSON(http.StatusOK, tracking)
+}
+
+// SubmitApplication handles recording the submission of a lead's application
+// @Summary Record lead submission
+// @Description Record when and where the application was submitted. The Berater is reminded to follow up if no Bescheid arrives.
+// @Tags leads
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Lead ID"
+// @Param request body submissions.SubmitInput true "Submission"
+// @Success 200 {object} submissions.Tracking
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/leads/{id}/submission [put]
+func (h *ApplicationSubmissionHandler) SubmitApplication(c *gin.Context) {
+	viewer, leadID, ok := h.leadRequest(c)
+	if !ok {
+		return
+	}
+
+	var req submissions.SubmitInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid request data"), "details": err.Error()})
+		return
+	}
+
+	tracking, err := h.submissions.Submit(leadID, viewer, req)
+	if err != nil {
+		h.handleSubmissionError(c, err, "Failed to save submission")
+		return
+	}
+
+	c.JSON(http.StatusOK, tracking)
+}
+
+// RequestDocuments handles recording a request of the Elterngeldstelle for documents
+// @Summary Record document request
+// @Description Record that the Elterngeldstelle asks for additional documents. With a due date the Berater is reminded before it.
+// @Tags leads
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Lead ID"
+// @Param request body submissions.DocumentRequestInput true "Document request"
+// @Success 201 {object} models.ApplicationDocumentRequest
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/v1/leads/{id}/submission/document-requests [post]
+func (h *ApplicationSubmissionHandler) RequestDocuments(c *gin.Context) {
+	viewer, leadID, ok := h.leadRequest(c)
+	if !ok {
+		return
+	}
+
+	var req submissions.DocumentRequestInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid request data"), "details": err.Error()})
+		return
+	}
+
+	request, err := h.submissions.RequestDocuments(leadID, viewer, req)
+	if err != nil {
+		h.handleSubmissionError(c, err, "Failed to save document request")
+		return
+	}
+
+	c.JSON(http.StatusCreated, request)
+}
+
+// FulfillDocumentRequest handles marking requested documents as sent
+// @Summary Fulfill document request
+// @Description Mark the requested documents as sent to the Elterngeldstelle and complete their reminder
+// @Tags leads
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Lead ID"
+// @Param requestId path string true "Document request ID"
+// @Success 200 {object} models.ApplicationDocumentRequest
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/leads/{id}/submission/document-requests/{requestId}/fulfill [post]
+func (h *ApplicationSubmissionHandler) FulfillDocumentRequest(c *gin.Context) {
+	viewer, leadID, ok := h.leadRequest(c)
+	if !ok {
+		return
+	}
+
+	requestID, err := uuid.Parse(c.Param("requestId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid document request ID")})
+		return
+	}
+
+	request, err := h.submissions.FulfillDocumentRequest(leadID, requestID, viewer)
+	if err != nil {
+		h.handleSubmissionError(c, err, "Failed to save document request")
+		return
+	}
+
+	c.JSON(http.StatusOK, request)
+}
+
+// RecordBescheid handles recording the Bescheid of the Elterngeldstelle
+// @Summary Record Bescheid
+// @Description Record the Bescheid with the granted amounts. The Widerspruchsfrist ends a month after it was received; the Berater is reminded a week before.
+// @Tags leads
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Lead ID"
+// @Param request body submissions.BescheidInput true "Bescheid"
+// @Success 200 {object} submissions.Tracking
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/leads/{id}/submission/bescheid [put]
+func (h *ApplicationSubmissionHandler) RecordBescheid(c *gin.Context) {
+	viewer, leadID, ok := h.leadRequest(c)
+	if !ok {
+		return
+	}
+
+	var req submissions.BescheidInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid request data"), "details": err.Error()})
+		return
+	}
+
+	tracking, err := h.submissions.RecordBescheid(leadID, viewer, req)
+	if err != nil {
+		h.handleSubmissionError(c, err, "Failed to save Bescheid")
+		return
+	}
+
+	c.JSON(http.StatusOK, tracking)
+}
+
+func (h *ApplicationSubmissionHandler) leadRequest(c *gin.Context) (scopes.Viewer, uuid.UUID, bool) {
+	viewer, ok := scopes.FromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "User not authenticated")})
+		return scopes.Viewer{}, uuid.Nil, false
+	}
+
+	leadID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid lead ID")})
+		return scopes.Viewer{}, uuid.Nil, false
+	}
+
+	return viewer, leadID, true
+}
+
+func (h *ApplicationSubmissionHandler) handleSubmissionError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, submissions.ErrLeadNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "Lead not found")})
+	case errors.Is(err, submissions.ErrNotSubmitted):
+		c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "The application has not been submitted yet")})
+	case errors.Is(err, submissions.ErrRequestNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "Document request not found")})
+	case errors.Is(err, submissions.ErrAlreadyDecided):
+		c.JSON(http.StatusConflict, gin.H{"error": middleware.T(c, "The Bescheid has already been received")})
+	case errors.Is(err, submissions.ErrInvalidInput):
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid request data"), "details": err.Error()})
+	default:
+		h.logger.Error(message, zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, message)})
+	}
+}
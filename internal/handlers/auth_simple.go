@@ -1,12 +1,19 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"time"
 
 	"elterngeld-portal/config"
+	"elterngeld-portal/internal/abuse"
+	"elterngeld-portal/internal/documents"
+	"elterngeld-portal/internal/middleware"
 	"elterngeld-portal/internal/models"
+	"elterngeld-portal/internal/sessions"
+	"elterngeld-portal/internal/verification"
 	"elterngeld-portal/pkg/auth"
+	"elterngeld-portal/pkg/i18n"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -20,45 +27,86 @@ type AuthHandler struct {
 	logger     *zap.Logger
 	jwtService *auth.JWTService
 	config     *config.Config
+
+	verification   *verification.Service
+	passwordPolicy *auth.PasswordPolicy
+	sessions       *sessions.Service
+	abuse          *abuse.Service
+	documents      *documents.Service
 }
 
-func NewAuthHandler(db *gorm.DB, logger *zap.Logger, jwtService *auth.JWTService, config *config.Config) *AuthHandler {
+func NewAuthHandler(db *gorm.DB, logger *zap.Logger, jwtService *auth.JWTService, config *config.Config, verificationService *verification.Service, passwordPolicy *auth.PasswordPolicy, sessionService *sessions.Service, abuseService *abuse.Service, documentService *documents.Service) *AuthHandler {
 	return &AuthHandler{
 		db:         db,
 		logger:     logger,
 		jwtService: jwtService,
 		config:     config,
+
+		verification:   verificationService,
+		passwordPolicy: passwordPolicy,
+		sessions:       sessionService,
+		abuse:          abuseService,
+		documents:      documentService,
 	}
 }
 
 type RegisterRequest struct {
 	Email     string `json:"email" binding:"required,email"`
-	Password  string `json:"password" binding:"required,min=8"`
+	Password  string `json:"password" binding:"required"` // checked against the password policy
 	FirstName string `json:"first_name" binding:"required"`
 	LastName  string `json:"last_name" binding:"required"`
 	Phone     string `json:"phone,omitempty"`
 }
 
+type ResendVerificationRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required"`
+}
+
 type LoginRequest struct {
 	Email    string `json:"email" binding:"required,email"`
 	Password string `json:"password" binding:"required"`
+	// UseCookie keeps the refresh token in an httpOnly cookie instead of the response body
+	UseCookie bool `json:"use_cookie"`
 }
 
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token"` // empty for clients in cookie mode
+}
+
+// AuthResponse carries a new session. In cookie mode the refresh token is
+// only set as cookie, and the CSRF token has to be sent in the X-CSRF-Token
+// header when refreshing.
 type AuthResponse struct {
 	User         *models.User `json:"user"`
 	AccessToken  string       `json:"access_token"`
-	RefreshToken string       `json:"refresh_token"`
+	RefreshToken string       `json:"refresh_token,omitempty"`
+	CSRFToken    string       `json:"csrf_token,omitempty"`
 	ExpiresAt    time.Time    `json:"expires_at"`
 }
 
 func (h *AuthHandler) Register(c *gin.Context) {
 	var req RegisterRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid request data")})
+		return
+	}
+
+	if !checkPassword(c, h.passwordPolicy, h.logger, req.Password) {
 		return
 	}
 
 	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+
+	// New users keep the language they registered in
+	language := middleware.GetLanguage(c)
+	if language == "" {
+		language = i18n.DefaultLanguage
+	}
 	
 	user := models.User{
 		ID:        uuid.New(),
@@ -67,79 +115,331 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		FirstName: req.FirstName,
 		LastName:  req.LastName,
 		Phone:     req.Phone,
+		Language:  string(language),
 		Role:      models.RoleUser,
 		IsActive:  false,
 		EmailVerified: false,
 	}
 
 	if err := h.db.Create(&user).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to create user")})
 		return
 	}
 
-	c.JSON(http.StatusCreated, gin.H{"message": "User registered successfully"})
+	// Suspicious registrations only get their verification email once an
+	// admin approved them; the response does not tell them apart
+	check, err := h.abuse.Assess(models.SubmissionEndpointRegister, c.ClientIP(), req.Email)
+	if err != nil {
+		h.logger.Error("Failed to check registration for abuse", zap.Error(err))
+	} else {
+		check.UserID = &user.ID
+		if err := h.abuse.Record(h.db, check); err != nil {
+			h.logger.Error("Failed to record registration check", zap.String("user_id", user.ID.String()), zap.Error(err))
+		} else if check.IsFlagged() {
+			c.JSON(http.StatusCreated, gin.H{"message": middleware.T(c, "User registered successfully")})
+			return
+		}
+	}
+
+	// The account exists either way; the user can request a new link
+	if err := h.verification.Issue(&user); err != nil {
+		h.logger.Error("Failed to send verification email", zap.String("user_id", user.ID.String()), zap.Error(err))
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"message": middleware.T(c, "User registered successfully")})
 }
 
 func (h *AuthHandler) Login(c *gin.Context) {
 	var req LoginRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid request data")})
 		return
 	}
 
 	var user models.User
 	if err := h.db.Where("email = ?", req.Email).First(&user).Error; err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+		c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "Invalid credentials")})
 		return
 	}
 
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+		c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "Invalid credentials")})
+		return
+	}
+
+	if user.IsDeactivated() {
+		c.JSON(http.StatusForbidden, gin.H{"error": middleware.T(c, "Account has been deactivated")})
+		return
+	}
+
+	session, err := h.sessions.Create(&user)
+	if err != nil {
+		h.logger.Error("Failed to create session", zap.String("user_id", user.ID.String()), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to log in")})
+		return
+	}
+
+	h.respondWithSession(c, &user, session, req.UseCookie)
+}
+
+// RefreshToken exchanges a refresh token for a new session. Clients in
+// cookie mode send no body; their refresh token comes from the cookie and
+// the request is checked by the CSRF middleware.
+func (h *AuthHandler) RefreshToken(c *gin.Context) {
+	var req RefreshTokenRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid request data")})
+			return
+		}
+	}
+
+	useCookie := false
+	if req.RefreshToken == "" {
+		token, err := c.Cookie(middleware.RefreshCookieName)
+		if err != nil || token == "" {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "Refresh token is required")})
+			return
+		}
+		req.RefreshToken, useCookie = token, true
+	}
+
+	user, session, err := h.sessions.Refresh(req.RefreshToken)
+	if err != nil {
+		if useCookie {
+			h.clearSessionCookies(c)
+		}
+		switch {
+		case errors.Is(err, sessions.ErrInvalidRefreshToken):
+			c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "Invalid refresh token")})
+		case errors.Is(err, sessions.ErrAccountDeactivated):
+			c.JSON(http.StatusForbidden, gin.H{"error": middleware.T(c, "Account has been deactivated")})
+		default:
+			h.logger.Error("Failed to refresh session", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to refresh session")})
+		}
 		return
 	}
 
-	accessToken, _ := h.jwtService.GenerateAccessToken(user.ID.String(), string(user.Role))
-	refreshToken, _ := h.jwtService.GenerateRefreshToken(user.ID.String())
+	h.respondWithSession(c, user, session, useCookie)
+}
+
+// IssueCSRFToken sets a new CSRF cookie for clients in cookie mode that lost
+// the token, for example after a page reload
+func (h *AuthHandler) IssueCSRFToken(c *gin.Context) {
+	if _, err := c.Cookie(middleware.RefreshCookieName); err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "Refresh token is required")})
+		return
+	}
 
+	csrfToken, err := middleware.GenerateCSRFToken()
+	if err != nil {
+		h.logger.Error("Failed to generate CSRF token", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to issue CSRF token")})
+		return
+	}
+	h.setCookie(c, middleware.CSRFCookieName, csrfToken, "/", int(h.jwtService.RefreshTTL().Seconds()), false)
+
+	c.JSON(http.StatusOK, gin.H{"csrf_token": csrfToken})
+}
+
+func (h *AuthHandler) respondWithSession(c *gin.Context, user *models.User, session *sessions.Session, useCookie bool) {
 	user.Password = ""
 	user.ResetToken = ""
 
-	c.JSON(http.StatusOK, AuthResponse{
-		User:         &user,
-		AccessToken:  accessToken,
-		RefreshToken: refreshToken,
-		ExpiresAt:    time.Now().Add(h.jwtService.GetAccessTokenExpiry()),
-	})
+	response := AuthResponse{
+		User:         user,
+		AccessToken:  session.AccessToken,
+		RefreshToken: session.RefreshToken,
+		ExpiresAt:    session.ExpiresAt,
+	}
+
+	if useCookie {
+		csrfToken, err := middleware.GenerateCSRFToken()
+		if err != nil {
+			h.logger.Error("Failed to generate CSRF token", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to issue CSRF token")})
+			return
+		}
+
+		maxAge := int(time.Until(session.RefreshExpiresAt).Seconds())
+		h.setCookie(c, middleware.RefreshCookieName, session.RefreshToken, "/api/v1/auth", maxAge, true)
+		h.setCookie(c, middleware.CSRFCookieName, csrfToken, "/", maxAge, false)
+		response.RefreshToken = ""
+		response.CSRFToken = csrfToken
+	}
+
+	c.JSON(http.StatusOK, response)
 }
 
-func (h *AuthHandler) RefreshToken(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"message": "Not implemented"})
+// setCookie sets a strict same-site cookie. A negative maxAge deletes it.
+func (h *AuthHandler) setCookie(c *gin.Context, name, value, path string, maxAge int, httpOnly bool) {
+	c.SetSameSite(http.SameSiteStrictMode)
+	c.SetCookie(name, value, maxAge, path, h.config.Security.CookieDomain, h.config.Security.CookieSecure, httpOnly)
+}
+
+func (h *AuthHandler) clearSessionCookies(c *gin.Context) {
+	h.setCookie(c, middleware.RefreshCookieName, "", "/api/v1/auth", -1, true)
+	h.setCookie(c, middleware.CSRFCookieName, "", "/", -1, false)
 }
 
 func (h *AuthHandler) ForgotPassword(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"message": "Not implemented"})
+	c.JSON(http.StatusOK, gin.H{"message": middleware.T(c, "Not implemented")})
 }
 
 func (h *AuthHandler) ResetPassword(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"message": "Not implemented"})
+	c.JSON(http.StatusOK, gin.H{"message": middleware.T(c, "Not implemented")})
 }
 
+// Logout revokes the refresh token from the request body or the session
+// cookie and clears the cookies
 func (h *AuthHandler) Logout(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"message": "Logged out successfully"})
-}
+	var req RefreshTokenRequest
+	if c.Request.ContentLength != 0 {
+		_ = c.ShouldBindJSON(&req)
+	}
+	if req.RefreshToken == "" {
+		req.RefreshToken, _ = c.Cookie(middleware.RefreshCookieName)
+	}
 
-func (h *AuthHandler) GetMe(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"message": "Not implemented"})
-}
+	if req.RefreshToken != "" {
+		if err := h.sessions.Revoke(req.RefreshToken); err != nil {
+			h.logger.Error("Failed to revoke session", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to log out")})
+			return
+		}
+	}
+	h.clearSessionCookies(c)
 
-func (h *AuthHandler) UpdateMe(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"message": "Not implemented"})
+	c.JSON(http.StatusOK, gin.H{"message": middleware.T(c, "Logged out successfully")})
 }
 
 func (h *AuthHandler) ChangePassword(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"message": "Not implemented"})
+	userID, ok := middleware.GetCurrentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "User not authenticated")})
+		return
+	}
+
+	var req ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid request data")})
+		return
+	}
+
+	var user models.User
+	if err := h.db.First(&user, "id = ?", userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "User not found")})
+		return
+	}
+
+	if !user.CheckPassword(req.CurrentPassword) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Current password is incorrect")})
+		return
+	}
+
+	if !checkPassword(c, h.passwordPolicy, h.logger, req.NewPassword) {
+		return
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to change password")})
+		return
+	}
+
+	// Other sessions have to log in with the new password
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&user).UpdateColumn("password", string(hashedPassword)).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.RefreshToken{}).Where("user_id = ? AND is_revoked = ?", user.ID, false).
+			Update("is_revoked", true).Error
+	})
+	if err != nil {
+		h.logger.Error("Failed to change password", zap.String("user_id", user.ID.String()), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to change password")})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": middleware.T(c, "Password changed successfully")})
 }
 
 func (h *AuthHandler) VerifyEmail(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"message": "Not implemented"})
+	token := c.Query("token")
+	if token == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Verification token is required")})
+		return
+	}
+
+	if _, err := h.verification.Verify(token); err != nil {
+		switch {
+		case errors.Is(err, verification.ErrInvalidToken):
+			c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid verification link")})
+		case errors.Is(err, verification.ErrTokenExpired):
+			c.JSON(http.StatusGone, gin.H{
+				"error":   middleware.T(c, "Verification link has expired"),
+				"message": middleware.T(c, "A new verification email has been sent"),
+			})
+		default:
+			h.logger.Error("Failed to verify email", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to verify email")})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": middleware.T(c, "Email verified successfully")})
+}
+
+func (h *AuthHandler) ResendVerification(c *gin.Context) {
+	var req ResendVerificationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid request data")})
+		return
+	}
+
+	if err := h.verification.Resend(req.Email); err != nil {
+		switch {
+		case errors.Is(err, verification.ErrResendTooSoon), errors.Is(err, verification.ErrResendLimitReached):
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": middleware.T(c, "Too many verification emails requested, please try again later")})
+		default:
+			h.logger.Error("Failed to resend verification email", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to send verification email")})
+		}
+		return
+	}
+
+	// Same response for unknown and verified addresses
+	c.JSON(http.StatusOK, gin.H{"message": middleware.T(c, "If the account exists and is not verified yet, a new verification email has been sent")})
+}
+
+// checkPassword validates a new password against the password policy and responds
+// with localized guidance when it is rejected. It returns false if a response was written.
+func checkPassword(c *gin.Context, policy *auth.PasswordPolicy, logger *zap.Logger, password string) bool {
+	err := policy.Validate(c.Request.Context(), password)
+	if err == nil {
+		return true
+	}
+
+	var passwordErr *auth.PasswordError
+	switch {
+	case errors.As(err, &passwordErr):
+		guidance := make([]string, len(passwordErr.Violations))
+		for i, violation := range passwordErr.Violations {
+			guidance[i] = middleware.T(c, violation.Message, violation.Args...)
+		}
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   middleware.T(c, "Password does not meet the requirements"),
+			"details": guidance,
+		})
+		return false
+	case errors.Is(err, auth.ErrBreachCheckUnavailable):
+		// An unreachable breach API must not block registrations
+		logger.Warn("Breached password check failed", zap.Error(err))
+		return true
+	default:
+		logger.Error("Failed to validate password", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to validate password")})
+		return false
+	}
 }
@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"elterngeld-portal/internal/backup"
+	"elterngeld-portal/internal/middleware"
+	"elterngeld-portal/internal/scheduler"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type BackupHandler struct {
+	db        *gorm.DB
+	logger    *zap.Logger
+	backups   *backup.Service
+	scheduler *scheduler.Service
+}
+
+func NewBackupHandler(db *gorm.DB, logger *zap.Logger, backupService *backup.Service, schedulerService *scheduler.Service) *BackupHandler {
+	return &BackupHandler{
+		db:        db,
+		logger:    logger,
+		backups:   backupService,
+		scheduler: schedulerService,
+	}
+}
+
+// ListBackups handles listing the backup sets (admin only)
+// @Summary List backups
+// @Description Get the encrypted backup sets of the database and document storage, latest first
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/admin/backups [get]
+func (h *BackupHandler) ListBackups(c *gin.Context) {
+	backups, err := h.backups.List()
+	if err != nil {
+		h.handleBackupError(c, err, "Failed to fetch backups")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"backups": backups})
+}
+
+// CreateBackup handles starting a backup outside its schedule (admin only)
+// @Summary Create backup
+// @Description Back up the database and document storage now. The backup runs as the scheduled backup job; its outcome shows in the job's run history.
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Success 202 {object} scheduler.Job
+// @Failure 503 {object} map[string]interface{}
+// @Router /api/v1/admin/backups [post]
+func (h *BackupHandler) CreateBackup(c *gin.Context) {
+	userID, ok := middleware.GetCurrentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "User not authenticated")})
+		return
+	}
+
+	job, err := h.scheduler.Trigger(backup.JobName, userID)
+	if err != nil {
+		h.handleBackupError(c, err, "Failed to start backup")
+		return
+	}
+
+	h.logger.Info("Backup triggered by admin", zap.String("triggered_by", userID.String()))
+	c.JSON(http.StatusAccepted, job)
+}
+
+// RestoreBackup handles restoring a backup set into the staging environment (admin only)
+// @Summary Restore backup
+// @Description Restore a backup set into the configured staging database and directory in the background. The live database and storage are never restored to.
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Backup ID"
+// @Success 202 {object} backup.Manifest
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Failure 503 {object} map[string]interface{}
+// @Router /api/v1/admin/backups/{id}/restore [post]
+func (h *BackupHandler) RestoreBackup(c *gin.Context) {
+	userID, ok := middleware.GetCurrentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "User not authenticated")})
+		return
+	}
+
+	manifest, err := h.backups.RequestRestore(c.Param("id"), userID)
+	if err != nil {
+		h.handleBackupError(c, err, "Failed to start restore")
+		return
+	}
+
+	h.logger.Info("Backup restore requested by admin", zap.String("backup_id", manifest.ID), zap.String("requested_by", userID.String()))
+	c.JSON(http.StatusAccepted, manifest)
+}
+
+func (h *BackupHandler) handleBackupError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, backup.ErrBackupNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "Backup not found")})
+	case errors.Is(err, backup.ErrRestoreToLive):
+		c.JSON(http.StatusConflict, gin.H{"error": middleware.T(c, "Cannot restore into the live system")})
+	case errors.Is(err, backup.ErrCorrupt):
+		c.JSON(http.StatusConflict, gin.H{"error": middleware.T(c, "Backup is corrupt")})
+	case errors.Is(err, backup.ErrEncryptionDisabled), errors.Is(err, scheduler.ErrJobNotFound):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": middleware.T(c, "Backups are not configured")})
+	case errors.Is(err, backup.ErrNoRestoreTarget):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": middleware.T(c, "No restore target configured")})
+	default:
+		h.logger.Error(message, zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, message)})
+	}
+}
@@ -0,0 +1,193 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"elterngeld-portal/internal/beraters"
+	"elterngeld-portal/internal/middleware"
+	"elterngeld-portal/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type BeraterHandler struct {
+	db       *gorm.DB
+	logger   *zap.Logger
+	beraters *beraters.Service
+}
+
+func NewBeraterHandler(db *gorm.DB, logger *zap.Logger, beraterService *beraters.Service) *BeraterHandler {
+	return &BeraterHandler{
+		db:       db,
+		logger:   logger,
+		beraters: beraterService,
+	}
+}
+
+// ListBeraters handles listing the public profiles of bookable Beraters
+// @Summary List Beraters
+// @Description List the public profiles of all bookable Beraters, optionally filtered
+// @Tags beraters
+// @Produce json
+// @Param specialization query string false "Specialization tag (e.g. beamte)"
+// @Param bundesland query string false "Bundesland code the Berater serves (e.g. BY)"
+// @Param language query string false "Working language (e.g. en)"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/v1/beraters [get]
+func (h *BeraterHandler) ListBeraters(c *gin.Context) {
+	filter := beraters.Filter{
+		Specialization: models.Specialization(c.Query("specialization")),
+		Bundesland:     models.Bundesland(c.Query("bundesland")),
+		Language:       c.Query("language"),
+	}
+	if filter.Bundesland != "" && !filter.Bundesland.IsValid() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid Bundesland")})
+		return
+	}
+	if filter.Specialization != "" && !filter.Specialization.IsValid() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid specialization")})
+		return
+	}
+	if filter.Language != "" && !models.IsConsultationLanguage(filter.Language) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid language")})
+		return
+	}
+
+	profiles, err := h.beraters.List(filter)
+	if err != nil {
+		h.logger.Error("Failed to fetch beraters", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to fetch beraters")})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"beraters": profiles})
+}
+
+// GetBeraterProfile handles retrieving the public profile of a Berater
+// @Summary Get Berater profile
+// @Description Get the public profile of a bookable Berater including the average customer rating
+// @Tags beraters
+// @Produce json
+// @Param id path string true "Berater ID"
+// @Success 200 {object} models.BeraterProfile
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/beraters/{id} [get]
+func (h *BeraterHandler) GetBeraterProfile(c *gin.Context) {
+	beraterID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid Berater ID")})
+		return
+	}
+
+	profile, err := h.beraters.Profile(beraterID)
+	if err != nil {
+		if errors.Is(err, beraters.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "Berater not found")})
+		} else {
+			h.logger.Error("Failed to fetch berater", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to fetch beraters")})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, profile)
+}
+
+// SuggestBeraters handles suggesting the best Beraters for a customer's situation
+// @Summary Suggest Beraters
+// @Description Rank bookable Beraters by the customer's topics, Bundesland and language, e.g. to preselect a consultant during booking
+// @Tags beraters
+// @Produce json
+// @Param topics query string false "Comma separated specialization tags (e.g. beamte,zwillinge)"
+// @Param bundesland query string false "Bundesland code of the customer (e.g. BY)"
+// @Param language query string false "Comma separated languages of the customer, preferred first (e.g. tr,en)"
+// @Param limit query int false "Maximum number of suggestions (default: 3)"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/v1/beraters/suggestions [get]
+func (h *BeraterHandler) SuggestBeraters(c *gin.Context) {
+	criteria := beraters.MatchCriteria{
+		Bundesland: models.Bundesland(c.Query("bundesland")),
+	}
+	if criteria.Bundesland != "" && !criteria.Bundesland.IsValid() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid Bundesland")})
+		return
+	}
+	if languages := c.Query("language"); languages != "" {
+		for _, value := range strings.Split(languages, ",") {
+			language := strings.ToLower(strings.TrimSpace(value))
+			if !models.IsConsultationLanguage(language) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid language")})
+				return
+			}
+			criteria.Languages = append(criteria.Languages, language)
+		}
+	}
+	if topics := c.Query("topics"); topics != "" {
+		for _, value := range strings.Split(topics, ",") {
+			topic := models.Specialization(strings.TrimSpace(value))
+			if !topic.IsValid() {
+				c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid specialization")})
+				return
+			}
+			criteria.Topics = append(criteria.Topics, topic)
+		}
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "3"))
+
+	suggestions, err := h.beraters.Suggest(criteria, limit)
+	if err != nil {
+		h.logger.Error("Failed to match beraters", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to match beraters")})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"suggestions": suggestions})
+}
+
+// ListSpecializations handles listing the specialization tags
+// @Summary List specializations
+// @Description List the specialization tags Beraters and leads can be tagged with
+// @Tags beraters
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/specializations [get]
+func (h *BeraterHandler) ListSpecializations(c *gin.Context) {
+	specializations := make([]gin.H, 0, len(models.Specializations))
+	for _, specialization := range models.Specializations {
+		specializations = append(specializations, gin.H{
+			"value": specialization,
+			"label": specialization.GetDisplayName(),
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"specializations": specializations})
+}
+
+// ListLanguages handles listing the consultation languages
+// @Summary List languages
+// @Description List the languages customers and Beraters can state for consultations
+// @Tags beraters
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/languages [get]
+func (h *BeraterHandler) ListLanguages(c *gin.Context) {
+	languages := make([]gin.H, 0, len(models.ConsultationLanguages))
+	for _, language := range models.ConsultationLanguages {
+		languages = append(languages, gin.H{
+			"value": language,
+			"label": models.LanguageDisplayName(language),
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"languages": languages})
+}
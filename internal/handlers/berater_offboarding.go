@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"elterngeld-portal/internal/middleware"
+	"elterngeld-portal/internal/offboarding"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type BeraterOffboardingHandler struct {
+	db          *gorm.DB
+	logger      *zap.Logger
+	offboarding *offboarding.Service
+}
+
+func NewBeraterOffboardingHandler(db *gorm.DB, logger *zap.Logger, offboardingService *offboarding.Service) *BeraterOffboardingHandler {
+	return &BeraterOffboardingHandler{
+		db:          db,
+		logger:      logger,
+		offboarding: offboardingService,
+	}
+}
+
+// DeactivateBeraterRequest represents the Berater deactivation request
+type DeactivateBeraterRequest struct {
+	Reason string `json:"reason" binding:"max=1000"`
+}
+
+// DeactivateBerater handles offboarding a Berater who leaves (admin only)
+// @Summary Deactivate Berater
+// @Description Block the Berater's login, cancel their future timeslots and return the leads and bookings to reassign
+// @Tags admin
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Berater ID"
+// @Param request body DeactivateBeraterRequest false "Deactivation reason"
+// @Success 200 {object} offboarding.Result
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/v1/admin/beraters/{id}/deactivate [post]
+func (h *BeraterOffboardingHandler) DeactivateBerater(c *gin.Context) {
+	beraterID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid user ID")})
+		return
+	}
+
+	adminID, ok := middleware.GetCurrentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "User not authenticated")})
+		return
+	}
+
+	var req DeactivateBeraterRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid request data"), "details": err.Error()})
+			return
+		}
+	}
+
+	result, err := h.offboarding.Deactivate(beraterID, adminID, req.Reason)
+	if err != nil {
+		h.respondOffboardingError(c, err, "Failed to deactivate berater")
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// GetOffboardingWorklist handles listing the leads and bookings of a Berater that need reassignment (admin only)
+// @Summary Get Berater offboarding worklist
+// @Description List the open leads and upcoming bookings still assigned to a Berater
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Berater ID"
+// @Success 200 {object} offboarding.Worklist
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/admin/beraters/{id}/offboarding [get]
+func (h *BeraterOffboardingHandler) GetOffboardingWorklist(c *gin.Context) {
+	beraterID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid user ID")})
+		return
+	}
+
+	worklist, err := h.offboarding.Worklist(beraterID)
+	if err != nil {
+		h.respondOffboardingError(c, err, "Failed to fetch offboarding worklist")
+		return
+	}
+
+	c.JSON(http.StatusOK, worklist)
+}
+
+func (h *BeraterOffboardingHandler) respondOffboardingError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, offboarding.ErrBeraterNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "Berater not found")})
+	case errors.Is(err, offboarding.ErrAlreadyDeactivated):
+		c.JSON(http.StatusConflict, gin.H{"error": middleware.T(c, "Berater is already deactivated")})
+	default:
+		h.logger.Error(message, zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, message)})
+	}
+}
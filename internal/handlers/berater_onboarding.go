@@ -0,0 +1,544 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"elterngeld-portal/internal/availability"
+	"elterngeld-portal/internal/email"
+	"elterngeld-portal/internal/middleware"
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/internal/onboarding"
+	"elterngeld-portal/pkg/auth"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type BeraterOnboardingHandler struct {
+	db           *gorm.DB
+	logger       *zap.Logger
+	onboarding   *onboarding.Service
+	availability *availability.Service
+	emailService *email.EmailService
+
+	passwordPolicy *auth.PasswordPolicy
+}
+
+func NewBeraterOnboardingHandler(db *gorm.DB, logger *zap.Logger, onboardingService *onboarding.Service, availabilityService *availability.Service, emailService *email.EmailService, passwordPolicy *auth.PasswordPolicy) *BeraterOnboardingHandler {
+	return &BeraterOnboardingHandler{
+		db:           db,
+		logger:       logger,
+		onboarding:   onboardingService,
+		availability: availabilityService,
+		emailService: emailService,
+
+		passwordPolicy: passwordPolicy,
+	}
+}
+
+// InviteBeraterRequest represents the Berater invitation request
+type InviteBeraterRequest struct {
+	Email     string          `json:"email" binding:"required,email"`
+	FirstName string          `json:"first_name" binding:"required"`
+	LastName  string          `json:"last_name" binding:"required"`
+	Role      models.UserRole `json:"role,omitempty" binding:"omitempty,oneof=berater junior_berater"`
+}
+
+// AcceptInvitationRequest represents the invitation acceptance request
+type AcceptInvitationRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required"` // checked against the password policy
+}
+
+// OnboardingProfileRequest represents the Berater onboarding profile update
+type OnboardingProfileRequest struct {
+	Phone                *string                 `json:"phone,omitempty"`
+	Specializations      []models.Specialization `json:"specializations,omitempty" binding:"omitempty,dive,oneof=selbststaendige beamte zwillinge widerspruch"`
+	ServiceBundeslaender []models.Bundesland     `json:"service_bundeslaender,omitempty" binding:"omitempty,dive,oneof=BW BY BE BB HB HH HE MV NI NW RP SL SN ST SH TH"`
+	WorkingLanguages     []string                `json:"working_languages,omitempty" binding:"omitempty,dive,oneof=de en tr ar ru uk pl fa ro"`
+	Bundesland           *models.Bundesland      `json:"bundesland,omitempty" binding:"omitempty,oneof=BW BY BE BB HB HH HE MV NI NW RP SL SN ST SH TH"`
+	Timezone             *string                 `json:"timezone,omitempty" binding:"omitempty,timezone"`
+	EmailSignature       *string                 `json:"email_signature,omitempty"`
+	PhotoURL             *string                 `json:"photo_url,omitempty" binding:"omitempty,url"`
+	CalendarURL          *string                 `json:"calendar_url,omitempty" binding:"omitempty,url"`
+	Bio                  *string                 `json:"bio,omitempty" binding:"omitempty,max=2000"`
+}
+
+// AvailabilityRuleRequest represents a weekly availability window
+type AvailabilityRuleRequest struct {
+	Weekday      int    `json:"weekday" binding:"min=0,max=6"`
+	StartTime    string `json:"start_time" binding:"required"`
+	EndTime      string `json:"end_time" binding:"required"`
+	SlotDuration int    `json:"slot_duration,omitempty" binding:"omitempty,min=15,max=480"`
+	IsOnline     bool   `json:"is_online"`
+}
+
+// SetAvailabilityRequest represents the weekly availability of a Berater
+type SetAvailabilityRequest struct {
+	Rules []AvailabilityRuleRequest `json:"rules" binding:"dive"`
+}
+
+// InviteBerater handles inviting a new Berater (admin only)
+// @Summary Invite Berater
+// @Description Send an onboarding invitation to a new Berater
+// @Tags admin
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body InviteBeraterRequest true "Invitation data"
+// @Success 201 {object} models.BeraterInvitation
+// @Failure 400 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/v1/admin/berater-invitations [post]
+func (h *BeraterOnboardingHandler) InviteBerater(c *gin.Context) {
+	admin, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	var req InviteBeraterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid request data"), "details": err.Error()})
+		return
+	}
+
+	invitation, token, err := h.onboarding.Invite(onboarding.InviteInput{
+		Email:     req.Email,
+		FirstName: req.FirstName,
+		LastName:  req.LastName,
+		Role:      req.Role,
+	}, admin.ID)
+	if err != nil {
+		if errors.Is(err, onboarding.ErrEmailTaken) {
+			c.JSON(http.StatusConflict, gin.H{"error": middleware.T(c, "User with this email already exists")})
+			return
+		}
+		h.logger.Error("Failed to create berater invitation", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to create invitation")})
+		return
+	}
+
+	if err := h.emailService.SendBeraterInvitation(invitation, token, admin); err != nil {
+		h.logger.Error("Failed to send berater invitation", zap.Error(err), zap.String("invitation_id", invitation.ID.String()))
+	}
+
+	c.JSON(http.StatusCreated, invitation)
+}
+
+// ListInvitations handles listing Berater invitations (admin only)
+// @Summary List Berater invitations
+// @Description List Berater invitations, optionally only pending ones
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Param pending query bool false "Only pending invitations"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/admin/berater-invitations [get]
+func (h *BeraterOnboardingHandler) ListInvitations(c *gin.Context) {
+	var invitations []models.BeraterInvitation
+	if err := h.db.Order("created_at DESC").Find(&invitations).Error; err != nil {
+		h.logger.Error("Failed to fetch invitations", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to fetch invitations")})
+		return
+	}
+
+	if c.Query("pending") == "true" {
+		pending := []models.BeraterInvitation{}
+		for _, invitation := range invitations {
+			if invitation.IsPending() {
+				pending = append(pending, invitation)
+			}
+		}
+		invitations = pending
+	}
+
+	c.JSON(http.StatusOK, gin.H{"invitations": invitations})
+}
+
+// RevokeInvitation handles revoking a pending Berater invitation (admin only)
+// @Summary Revoke Berater invitation
+// @Description Revoke a pending Berater invitation
+// @Tags admin
+// @Security BearerAuth
+// @Param id path string true "Invitation ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/admin/berater-invitations/{id} [delete]
+func (h *BeraterOnboardingHandler) RevokeInvitation(c *gin.Context) {
+	invitationID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid invitation ID")})
+		return
+	}
+
+	if err := h.onboarding.Revoke(invitationID); err != nil {
+		h.respondInvitationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": middleware.T(c, "Invitation revoked successfully")})
+}
+
+// GetInvitation handles looking up an invitation by its token
+// @Summary Get Berater invitation
+// @Description Validate an invitation link and return the invited name and email
+// @Tags onboarding
+// @Produce json
+// @Param token path string true "Invitation token"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 410 {object} map[string]interface{}
+// @Router /api/v1/onboarding/invitations/{token} [get]
+func (h *BeraterOnboardingHandler) GetInvitation(c *gin.Context) {
+	invitation, err := h.onboarding.FindPending(c.Param("token"))
+	if err != nil {
+		h.respondInvitationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"email":      invitation.Email,
+		"first_name": invitation.FirstName,
+		"last_name":  invitation.LastName,
+		"role":       invitation.Role,
+		"expires_at": invitation.ExpiresAt,
+	})
+}
+
+// AcceptInvitation handles creating the Berater account for an invitation
+// @Summary Accept Berater invitation
+// @Description Set a password and create the Berater account for an invitation
+// @Tags onboarding
+// @Accept json
+// @Produce json
+// @Param request body AcceptInvitationRequest true "Token and password"
+// @Success 201 {object} models.UserResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 410 {object} map[string]interface{}
+// @Router /api/v1/onboarding/accept [post]
+func (h *BeraterOnboardingHandler) AcceptInvitation(c *gin.Context) {
+	var req AcceptInvitationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid request data"), "details": err.Error()})
+		return
+	}
+
+	if !checkPassword(c, h.passwordPolicy, h.logger, req.Password) {
+		return
+	}
+
+	user, err := h.onboarding.Accept(req.Token, req.Password)
+	if err != nil {
+		h.respondInvitationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, user.ToResponse())
+}
+
+// GetOnboarding handles retrieving the onboarding progress of the current Berater
+// @Summary Get onboarding progress
+// @Description Get the onboarding status, completed steps, profile and availability of the current Berater
+// @Tags onboarding
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/berater/onboarding [get]
+func (h *BeraterOnboardingHandler) GetOnboarding(c *gin.Context) {
+	berater, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	h.respondOnboarding(c, berater)
+}
+
+// UpdateOnboardingProfile handles updating the onboarding profile of the current Berater
+// @Summary Update onboarding profile
+// @Description Update bio, specializations, Bundesländer, languages, calendar and signature of the current Berater
+// @Tags onboarding
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body OnboardingProfileRequest true "Profile data"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/v1/berater/onboarding/profile [put]
+func (h *BeraterOnboardingHandler) UpdateOnboardingProfile(c *gin.Context) {
+	berater, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	var req OnboardingProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid request data"), "details": err.Error()})
+		return
+	}
+
+	err := h.onboarding.UpdateProfile(berater, onboarding.ProfileInput{
+		Phone:                req.Phone,
+		Specializations:      req.Specializations,
+		ServiceBundeslaender: req.ServiceBundeslaender,
+		WorkingLanguages:     req.WorkingLanguages,
+		Bundesland:           req.Bundesland,
+		Timezone:             req.Timezone,
+		EmailSignature:       req.EmailSignature,
+		PhotoURL:             req.PhotoURL,
+		CalendarURL:          req.CalendarURL,
+		Bio:                  req.Bio,
+	})
+	if err != nil {
+		h.respondOnboardingError(c, err, "Failed to update profile")
+		return
+	}
+
+	h.respondOnboarding(c, berater)
+}
+
+// SetAvailability handles replacing the weekly availability of the current Berater
+// @Summary Set availability
+// @Description Replace the weekly availability windows timeslots are generated from
+// @Tags onboarding
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body SetAvailabilityRequest true "Availability rules"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/v1/berater/onboarding/availability [put]
+func (h *BeraterOnboardingHandler) SetAvailability(c *gin.Context) {
+	berater, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	var req SetAvailabilityRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid request data"), "details": err.Error()})
+		return
+	}
+
+	rules := make([]models.AvailabilityRule, 0, len(req.Rules))
+	for _, rule := range req.Rules {
+		rules = append(rules, models.AvailabilityRule{
+			Weekday:      rule.Weekday,
+			StartTime:    rule.StartTime,
+			EndTime:      rule.EndTime,
+			SlotDuration: rule.SlotDuration,
+			IsOnline:     rule.IsOnline,
+		})
+	}
+
+	if _, err := h.onboarding.SetAvailability(berater, rules); err != nil {
+		h.respondOnboardingError(c, err, "Failed to update availability")
+		return
+	}
+
+	h.respondOnboarding(c, berater)
+}
+
+// SubmitOnboarding handles submitting the onboarding of the current Berater for approval
+// @Summary Submit onboarding
+// @Description Submit a completed onboarding for admin approval
+// @Tags onboarding
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Failure 422 {object} map[string]interface{}
+// @Router /api/v1/berater/onboarding/submit [post]
+func (h *BeraterOnboardingHandler) SubmitOnboarding(c *gin.Context) {
+	berater, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	if err := h.onboarding.Submit(berater); err != nil {
+		h.respondOnboardingError(c, err, "Failed to submit onboarding")
+		return
+	}
+
+	h.respondOnboarding(c, berater)
+}
+
+// ListOnboardingBeraters handles listing Beraters by onboarding status (admin only)
+// @Summary List Beraters in onboarding
+// @Description List Beraters by onboarding status (default: awaiting approval)
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Param status query string false "Onboarding status"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/admin/beraters/onboarding [get]
+func (h *BeraterOnboardingHandler) ListOnboardingBeraters(c *gin.Context) {
+	status := c.DefaultQuery("status", string(models.OnboardingStatusApprovalPending))
+
+	var beraters []models.User
+	if err := h.db.Where("role IN ? AND onboarding_status = ?",
+		[]models.UserRole{models.RoleBerater, models.RoleJuniorBerater}, status).
+		Order("created_at ASC").Find(&beraters).Error; err != nil {
+		h.logger.Error("Failed to fetch beraters", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to fetch users")})
+		return
+	}
+
+	responses := make([]models.UserResponse, 0, len(beraters))
+	for _, berater := range beraters {
+		responses = append(responses, berater.ToResponse())
+	}
+
+	c.JSON(http.StatusOK, gin.H{"beraters": responses})
+}
+
+// ApproveBerater handles approving a Berater's onboarding (admin only)
+// @Summary Approve Berater
+// @Description Approve a submitted onboarding, making the Berater bookable and generating timeslots
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Berater ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/v1/admin/beraters/{id}/approve [post]
+func (h *BeraterOnboardingHandler) ApproveBerater(c *gin.Context) {
+	admin, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+	berater, ok := h.findBerater(c)
+	if !ok {
+		return
+	}
+
+	created, err := h.onboarding.Approve(berater, admin.ID)
+	if err != nil {
+		h.respondOnboardingError(c, err, "Failed to approve berater")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"berater":           berater.ToResponse(),
+		"timeslots_created": created,
+	})
+}
+
+// RejectBerater handles sending a Berater's onboarding back for changes (admin only)
+// @Summary Reject Berater
+// @Description Reject a submitted onboarding so the Berater can revise and resubmit it
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Berater ID"
+// @Success 200 {object} models.UserResponse
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/v1/admin/beraters/{id}/reject [post]
+func (h *BeraterOnboardingHandler) RejectBerater(c *gin.Context) {
+	berater, ok := h.findBerater(c)
+	if !ok {
+		return
+	}
+
+	if err := h.onboarding.Reject(berater); err != nil {
+		h.respondOnboardingError(c, err, "Failed to reject berater")
+		return
+	}
+
+	c.JSON(http.StatusOK, berater.ToResponse())
+}
+
+func (h *BeraterOnboardingHandler) currentUser(c *gin.Context) (*models.User, bool) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "User not authenticated")})
+		return nil, false
+	}
+
+	var user models.User
+	if err := h.db.First(&user, "id = ?", userID).Error; err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "User not found")})
+		return nil, false
+	}
+
+	return &user, true
+}
+
+func (h *BeraterOnboardingHandler) findBerater(c *gin.Context) (*models.User, bool) {
+	beraterID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid user ID")})
+		return nil, false
+	}
+
+	var berater models.User
+	if err := h.db.Where("id = ? AND role IN ?", beraterID,
+		[]models.UserRole{models.RoleBerater, models.RoleJuniorBerater}).First(&berater).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "Berater not found")})
+		} else {
+			h.logger.Error("Failed to fetch berater", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to fetch user")})
+		}
+		return nil, false
+	}
+
+	return &berater, true
+}
+
+func (h *BeraterOnboardingHandler) respondOnboarding(c *gin.Context, berater *models.User) {
+	progress, err := h.onboarding.Progress(berater)
+	if err != nil {
+		h.logger.Error("Failed to fetch onboarding progress", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to fetch onboarding progress")})
+		return
+	}
+
+	rules, err := h.availability.Rules(berater.ID)
+	if err != nil {
+		h.logger.Error("Failed to fetch availability", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to fetch onboarding progress")})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"onboarding":   progress,
+		"profile":      berater.ToResponse(),
+		"availability": rules,
+	})
+}
+
+func (h *BeraterOnboardingHandler) respondInvitationError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, onboarding.ErrInvitationNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "Invitation not found")})
+	case errors.Is(err, onboarding.ErrInvitationNotPending):
+		c.JSON(http.StatusGone, gin.H{"error": middleware.T(c, "Invitation is no longer valid")})
+	case errors.Is(err, onboarding.ErrEmailTaken):
+		c.JSON(http.StatusConflict, gin.H{"error": middleware.T(c, "User with this email already exists")})
+	default:
+		h.logger.Error("Failed to process invitation", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to process invitation")})
+	}
+}
+
+func (h *BeraterOnboardingHandler) respondOnboardingError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, onboarding.ErrNotBerater):
+		c.JSON(http.StatusForbidden, gin.H{"error": middleware.T(c, "Insufficient permissions")})
+	case errors.Is(err, onboarding.ErrInvalidStatus):
+		c.JSON(http.StatusConflict, gin.H{"error": middleware.T(c, "Not allowed in the current onboarding status")})
+	case errors.Is(err, onboarding.ErrIncomplete):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": middleware.T(c, "Onboarding is incomplete"), "details": err.Error()})
+	case errors.Is(err, availability.ErrInvalidRule):
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid availability"), "details": err.Error()})
+	default:
+		h.logger.Error(message, zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, message)})
+	}
+}
@@ -0,0 +1,408 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"elterngeld-portal/internal/blog"
+	"elterngeld-portal/internal/middleware"
+	"elterngeld-portal/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type BlogHandler struct {
+	db     *gorm.DB
+	logger *zap.Logger
+	blog   *blog.Service
+}
+
+func NewBlogHandler(db *gorm.DB, logger *zap.Logger, blogService *blog.Service) *BlogHandler {
+	return &BlogHandler{
+		db:     db,
+		logger: logger,
+		blog:   blogService,
+	}
+}
+
+// PublishPostRequest optionally schedules the publication
+type PublishPostRequest struct {
+	PublishAt *time.Time `json:"publish_at"` // empty publishes right away
+}
+
+// ListPublishedPosts handles listing the public blog posts
+// @Summary List blog posts
+// @Description Get the public blog and news posts, newest first. Scheduled posts appear once their time has come.
+// @Tags blog
+// @Produce json
+// @Param page query int false "Page number"
+// @Param limit query int false "Items per page"
+// @Param category query string false "Filter by category"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/blog/posts [get]
+func (h *BlogHandler) ListPublishedPosts(c *gin.Context) {
+	page, limit := blogPagination(c)
+
+	posts, total, err := h.blog.Published(c.Query("category"), page, limit)
+	if err != nil {
+		h.logger.Error("Failed to fetch posts", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to fetch posts")})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"posts": posts,
+		"pagination": gin.H{
+			"page":  page,
+			"limit": limit,
+			"total": total,
+			"pages": (total + int64(limit) - 1) / int64(limit),
+		},
+	})
+}
+
+// GetPublishedPost handles getting a public blog post by its slug
+// @Summary Get blog post
+// @Tags blog
+// @Produce json
+// @Param slug path string true "Slug"
+// @Success 200 {object} blog.PublicPost
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/blog/posts/{slug} [get]
+func (h *BlogHandler) GetPublishedPost(c *gin.Context) {
+	post, err := h.blog.PublishedBySlug(c.Param("slug"))
+	if err != nil {
+		h.handleBlogError(c, err, "Failed to fetch post")
+		return
+	}
+
+	middleware.SetLastModified(c, post.UpdatedAt)
+	c.JSON(http.StatusOK, post)
+}
+
+// ListCategories handles listing the blog categories with their number of posts
+// @Summary List blog categories
+// @Tags blog
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/blog/categories [get]
+func (h *BlogHandler) ListCategories(c *gin.Context) {
+	categories, err := h.blog.Categories()
+	if err != nil {
+		h.logger.Error("Failed to fetch categories", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to fetch categories")})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"categories": categories})
+}
+
+// RSSFeed handles the RSS 2.0 feed of the latest posts
+// @Summary Blog RSS feed
+// @Tags blog
+// @Produce xml
+// @Success 200 {string} string
+// @Router /api/v1/blog/rss.xml [get]
+func (h *BlogHandler) RSSFeed(c *gin.Context) {
+	h.feed(c, h.blog.RSS, "application/rss+xml; charset=utf-8")
+}
+
+// AtomFeed handles the Atom feed of the latest posts
+// @Summary Blog Atom feed
+// @Tags blog
+// @Produce xml
+// @Success 200 {string} string
+// @Router /api/v1/blog/atom.xml [get]
+func (h *BlogHandler) AtomFeed(c *gin.Context) {
+	h.feed(c, h.blog.Atom, "application/atom+xml; charset=utf-8")
+}
+
+// ListPosts handles listing blog posts including drafts and scheduled posts (admin only)
+// @Summary List blog posts
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Param page query int false "Page number"
+// @Param limit query int false "Items per page"
+// @Param status query string false "Filter by status: draft or published"
+// @Param category query string false "Filter by category"
+// @Param author_id query string false "Filter by author"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/admin/blog/posts [get]
+func (h *BlogHandler) ListPosts(c *gin.Context) {
+	page, limit := blogPagination(c)
+
+	filter := blog.Filter{
+		Status:   models.PostStatus(c.Query("status")),
+		Category: c.Query("category"),
+	}
+	if authorID := c.Query("author_id"); authorID != "" {
+		id, err := uuid.Parse(authorID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid author ID")})
+			return
+		}
+		filter.AuthorID = &id
+	}
+
+	posts, total, err := h.blog.List(filter, page, limit)
+	if err != nil {
+		h.logger.Error("Failed to fetch posts", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to fetch posts")})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"posts": posts,
+		"pagination": gin.H{
+			"page":  page,
+			"limit": limit,
+			"total": total,
+			"pages": (total + int64(limit) - 1) / int64(limit),
+		},
+	})
+}
+
+// CreatePost handles adding a draft blog post (admin only)
+// @Summary Create blog post
+// @Description Add a post as draft. Without a slug one is generated from the title. The author must be a Berater.
+// @Tags admin
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body blog.PostInput true "Post"
+// @Success 201 {object} models.Post
+// @Failure 400 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/v1/admin/blog/posts [post]
+func (h *BlogHandler) CreatePost(c *gin.Context) {
+	userID, ok := middleware.GetCurrentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "User not authenticated")})
+		return
+	}
+
+	var req blog.PostInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid request data"), "details": err.Error()})
+		return
+	}
+
+	post, err := h.blog.Create(req, userID)
+	if err != nil {
+		h.handleBlogError(c, err, "Failed to create post")
+		return
+	}
+
+	c.JSON(http.StatusCreated, post)
+}
+
+// GetPost handles getting a blog post in any state (admin only)
+// @Summary Get blog post
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Post ID"
+// @Success 200 {object} models.Post
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/admin/blog/posts/{id} [get]
+func (h *BlogHandler) GetPost(c *gin.Context) {
+	postID, ok := h.postID(c)
+	if !ok {
+		return
+	}
+
+	post, err := h.blog.Get(postID)
+	if err != nil {
+		h.handleBlogError(c, err, "Failed to fetch post")
+		return
+	}
+
+	c.JSON(http.StatusOK, post)
+}
+
+// UpdatePost handles changing a blog post (admin only)
+// @Summary Update blog post
+// @Description Replace the fields of a post. Changes to public posts are visible right away.
+// @Tags admin
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Post ID"
+// @Param request body blog.PostInput true "Post"
+// @Success 200 {object} models.Post
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/v1/admin/blog/posts/{id} [put]
+func (h *BlogHandler) UpdatePost(c *gin.Context) {
+	userID, ok := middleware.GetCurrentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "User not authenticated")})
+		return
+	}
+	postID, ok := h.postID(c)
+	if !ok {
+		return
+	}
+
+	var req blog.PostInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid request data"), "details": err.Error()})
+		return
+	}
+
+	post, err := h.blog.Update(postID, req, userID)
+	if err != nil {
+		h.handleBlogError(c, err, "Failed to update post")
+		return
+	}
+
+	c.JSON(http.StatusOK, post)
+}
+
+// PublishPost handles publishing or scheduling a blog post (admin only)
+// @Summary Publish blog post
+// @Description Publish a post right away, or at publish_at if given. Publishing again reschedules the post.
+// @Tags admin
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Post ID"
+// @Param request body PublishPostRequest false "Publication time"
+// @Success 200 {object} models.Post
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/admin/blog/posts/{id}/publish [post]
+func (h *BlogHandler) PublishPost(c *gin.Context) {
+	userID, ok := middleware.GetCurrentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "User not authenticated")})
+		return
+	}
+	postID, ok := h.postID(c)
+	if !ok {
+		return
+	}
+
+	var req PublishPostRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid request data"), "details": err.Error()})
+			return
+		}
+	}
+
+	post, err := h.blog.Publish(postID, req.PublishAt, userID)
+	if err != nil {
+		h.handleBlogError(c, err, "Failed to publish post")
+		return
+	}
+
+	c.JSON(http.StatusOK, post)
+}
+
+// UnpublishPost handles turning a published or scheduled post back into a draft (admin only)
+// @Summary Unpublish blog post
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Post ID"
+// @Success 200 {object} models.Post
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/admin/blog/posts/{id}/unpublish [post]
+func (h *BlogHandler) UnpublishPost(c *gin.Context) {
+	userID, ok := middleware.GetCurrentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "User not authenticated")})
+		return
+	}
+	postID, ok := h.postID(c)
+	if !ok {
+		return
+	}
+
+	post, err := h.blog.Unpublish(postID, userID)
+	if err != nil {
+		h.handleBlogError(c, err, "Failed to unpublish post")
+		return
+	}
+
+	c.JSON(http.StatusOK, post)
+}
+
+// DeletePost handles removing a blog post (admin only)
+// @Summary Delete blog post
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Post ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/admin/blog/posts/{id} [delete]
+func (h *BlogHandler) DeletePost(c *gin.Context) {
+	postID, ok := h.postID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.blog.Delete(postID); err != nil {
+		h.handleBlogError(c, err, "Failed to delete post")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": middleware.T(c, "Post deleted successfully")})
+}
+
+func (h *BlogHandler) feed(c *gin.Context, render func() ([]byte, error), contentType string) {
+	body, err := render()
+	if err != nil {
+		h.logger.Error("Failed to render feed", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to render feed")})
+		return
+	}
+
+	c.Header("Cache-Control", "public, max-age=900")
+	c.Data(http.StatusOK, contentType, body)
+}
+
+func (h *BlogHandler) postID(c *gin.Context) (uuid.UUID, bool) {
+	postID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid post ID")})
+		return uuid.Nil, false
+	}
+	return postID, true
+}
+
+func (h *BlogHandler) handleBlogError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, blog.ErrPostNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "Post not found")})
+	case errors.Is(err, blog.ErrInvalidSlug):
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Slug may only contain lowercase letters, digits and dashes")})
+	case errors.Is(err, blog.ErrDuplicateSlug):
+		c.JSON(http.StatusConflict, gin.H{"error": middleware.T(c, "Slug already exists")})
+	case errors.Is(err, blog.ErrInvalidAuthor):
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Author must be a Berater")})
+	default:
+		h.logger.Error(message, zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, message)})
+	}
+}
+
+func blogPagination(c *gin.Context) (int, int) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(blog.DefaultLimit)))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > blog.MaxLimit {
+		limit = blog.DefaultLimit
+	}
+	return page, limit
+}
@@ -1,27 +1,56 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 	"time"
 
+	"elterngeld-portal/internal/addresses"
+	"elterngeld-portal/internal/availability"
+	"elterngeld-portal/internal/catalog"
+	"elterngeld-portal/internal/experiments"
+	"elterngeld-portal/internal/holidays"
+	"elterngeld-portal/internal/holds"
+	"elterngeld-portal/internal/middleware"
 	"elterngeld-portal/internal/models"
+	"elterngeld-portal/internal/postalcodes"
+	"elterngeld-portal/internal/scopes"
+	"elterngeld-portal/internal/settings"
+	"elterngeld-portal/pkg/timeutil"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type BookingHandler struct {
-	db     *gorm.DB
-	logger *zap.Logger
+	db          *gorm.DB
+	logger      *zap.Logger
+	holidays    *holidays.Service
+	experiments *experiments.Service
+	holds        *holds.Service
+	availability *availability.Service
+	settings     *settings.Service
+	addresses    *addresses.Service
+	postalCodes  *postalcodes.Service
+	catalog      *catalog.Service
 }
 
-func NewBookingHandler(db *gorm.DB, logger *zap.Logger) *BookingHandler {
+func NewBookingHandler(db *gorm.DB, logger *zap.Logger, holidayService *holidays.Service, experimentService *experiments.Service, holdService *holds.Service, availabilityService *availability.Service, settingsService *settings.Service, addressService *addresses.Service, postalCodeService *postalcodes.Service, catalogService *catalog.Service) *BookingHandler {
 	return &BookingHandler{
-		db:     db,
-		logger: logger,
+		db:           db,
+		logger:       logger,
+		holidays:     holidayService,
+		experiments:  experimentService,
+		holds:        holdService,
+		availability: availabilityService,
+		settings:     settingsService,
+		addresses:    addressService,
+		postalCodes:  postalCodeService,
+		catalog:      catalogService,
 	}
 }
 
@@ -29,9 +58,25 @@ func NewBookingHandler(db *gorm.DB, logger *zap.Logger) *BookingHandler {
 type CreateBookingRequest struct {
 	PackageID     uuid.UUID   `json:"package_id" binding:"required"`
 	AddOnIDs      []uuid.UUID `json:"addon_ids,omitempty"`
+	CouponCode    string      `json:"coupon_code,omitempty" binding:"max=32"`
 	TimeslotID    *uuid.UUID  `json:"timeslot_id,omitempty"`
+	BeraterID     *uuid.UUID  `json:"berater_id,omitempty"` // optional choice of consultant
 	PreferredDate *time.Time  `json:"preferred_date,omitempty"`
 	Notes         string      `json:"notes,omitempty"`
+	FamilyID      *uuid.UUID  `json:"family_id,omitempty"` // books for a family the customer belongs to
+}
+
+// QuoteRequest is an order a customer wants the price of before booking
+type QuoteRequest struct {
+	PackageID  uuid.UUID   `json:"package_id" binding:"required"`
+	AddOnIDs   []uuid.UUID `json:"addon_ids,omitempty"`
+	CouponCode string      `json:"coupon_code,omitempty" binding:"max=32"`
+}
+
+// RateBookingRequest represents the customer rating of a completed consultation
+type RateBookingRequest struct {
+	Rating  int    `json:"rating" binding:"required,min=1,max=5"`
+	Comment string `json:"comment,omitempty" binding:"max=2000"`
 }
 
 // UpdateContactInfoRequest represents the contact info update after booking
@@ -53,34 +98,60 @@ type UpdateContactInfoRequest struct {
 type BookingResponse struct {
 	*models.Booking
 	Package   *models.Package    `json:"package,omitempty"`
-	AddOns    []models.Package   `json:"addons,omitempty"`
+	LineItems []catalog.LineItem `json:"line_items,omitempty"` // package and addons at their booked prices
 	Timeslot  *models.Timeslot   `json:"timeslot,omitempty"`
+	Hold      *models.TimeslotHold `json:"hold,omitempty"` // reservation of the timeslot until payment
 	Lead      *models.Lead       `json:"lead,omitempty"`
 	Payments  []models.Payment   `json:"payments,omitempty"`
 	Documents []models.Document  `json:"documents,omitempty"`
+
+	// Whether the customer can still cancel or reschedule within the configured windows
+	CanCancel     bool `json:"can_cancel"`
+	CanReschedule bool `json:"can_reschedule"`
 }
 
 // ListPackages handles listing available packages for pricing page
 // @Summary List packages
-// @Description Get list of available service packages for pricing page
+// @Description Get list of available service packages for pricing page, with their features as a list
 // @Tags packages
 // @Produce json
 // @Success 200 {object} map[string]interface{}
 // @Router /api/v1/packages [get]
 func (h *BookingHandler) ListPackages(c *gin.Context) {
-	var packages []models.Package
-	if err := h.db.Where("type = ? AND is_active = ?", models.PackageTypeService, true).
-		Order("sort_order ASC, price ASC").Find(&packages).Error; err != nil {
+	packages, err := h.catalog.Packages()
+	if err != nil {
 		h.logger.Error("Failed to fetch packages", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch packages"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to fetch packages")})
 		return
 	}
 
+	responses := make([]models.PackageResponse, 0, len(packages))
+	for i := range packages {
+		responses = append(responses, packages[i].ToResponse())
+	}
 	c.JSON(http.StatusOK, gin.H{
-		"packages": packages,
+		"packages": responses,
 	})
 }
 
+// GetPricing handles getting the data of the pricing page
+// @Summary Get pricing
+// @Description Get the active packages with their features, the addons each package offers or includes, prices with net amount and Umsatzsteuer, and the comparison of the packages
+// @Tags packages
+// @Produce json
+// @Success 200 {object} catalog.Pricing
+// @Router /api/v1/pricing [get]
+func (h *BookingHandler) GetPricing(c *gin.Context) {
+	pricing, err := h.catalog.Pricing()
+	if err != nil {
+		h.logger.Error("Failed to fetch pricing", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to fetch packages")})
+		return
+	}
+
+	c.JSON(http.StatusOK, pricing)
+}
+
 // GetPackageAddOns handles getting add-ons for a specific package
 // @Summary Get package add-ons
 // @Description Get available add-ons for a specific package
@@ -91,31 +162,25 @@ func (h *BookingHandler) ListPackages(c *gin.Context) {
 // @Failure 404 {object} map[string]interface{}
 // @Router /api/v1/packages/{id}/addons [get]
 func (h *BookingHandler) GetPackageAddOns(c *gin.Context) {
-	packageID := c.Param("id")
-
-	// Verify package exists
-	var servicePackage models.Package
-	if err := h.db.Where("id = ? AND type = ?", packageID, models.PackageTypeService).First(&servicePackage).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Package not found"})
-		} else {
-			h.logger.Error("Failed to fetch package", zap.Error(err))
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch package"})
-		}
+	packageID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "Package not found")})
 		return
 	}
 
-	// Get add-ons
-	var addOns []models.Package
-	if err := h.db.Where("type = ? AND is_active = ?", models.PackageTypeAddOn, true).
-		Order("sort_order ASC, price ASC").Find(&addOns).Error; err != nil {
-		h.logger.Error("Failed to fetch add-ons", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch add-ons"})
+	servicePackage, addOns, err := h.catalog.PackageAddons(packageID)
+	if err != nil {
+		if errors.Is(err, catalog.ErrPackageNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "Package not found")})
+		} else {
+			h.logger.Error("Failed to fetch add-ons", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to fetch add-ons")})
+		}
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"package": servicePackage,
+		"package": servicePackage.ToResponse(),
 		"addons":  addOns,
 	})
 }
@@ -128,13 +193,15 @@ func (h *BookingHandler) GetPackageAddOns(c *gin.Context) {
 // @Param package_id query string true "Package ID"
 // @Param date query string false "Date (YYYY-MM-DD)"
 // @Param days query int false "Number of days to look ahead (default: 30)"
+// @Param tz query string false "IANA timezone of the client (default: Europe/Berlin)"
+// @Param berater_id query string false "Only timeslots of this Berater"
 // @Success 200 {object} map[string]interface{}
 // @Failure 400 {object} map[string]interface{}
 // @Router /api/v1/timeslots/available [get]
 func (h *BookingHandler) GetAvailableTimeslots(c *gin.Context) {
 	packageID := c.Query("package_id")
 	if packageID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Package ID is required"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Package ID is required")})
 		return
 	}
 
@@ -142,10 +209,10 @@ func (h *BookingHandler) GetAvailableTimeslots(c *gin.Context) {
 	var servicePackage models.Package
 	if err := h.db.Where("id = ?", packageID).First(&servicePackage).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Package not found"})
+			c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "Package not found")})
 		} else {
 			h.logger.Error("Failed to fetch package", zap.Error(err))
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch package"})
+			c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to fetch package")})
 		}
 		return
 	}
@@ -160,59 +227,173 @@ func (h *BookingHandler) GetAvailableTimeslots(c *gin.Context) {
 		return
 	}
 
-	// Parse date and days parameters
-	dateStr := c.DefaultQuery("date", time.Now().Format("2006-01-02"))
+	// Parse date and days parameters in the client's timezone
+	loc := middleware.GetTimezone(c)
+	dateStr := c.DefaultQuery("date", timeutil.FormatDate(time.Now(), loc))
 	days, _ := strconv.Atoi(c.DefaultQuery("days", "30"))
 
-	startDate, err := time.Parse("2006-01-02", dateStr)
+	date, err := timeutil.ParseDate(dateStr, loc)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date format. Use YYYY-MM-DD"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid date format. Use YYYY-MM-DD")})
 		return
 	}
 
-	endDate := startDate.AddDate(0, 0, days)
+	startDate, endDate := timeutil.DayRange(date, days, loc)
 
 	// Get available timeslots
 	var timeslots []models.Timeslot
-	query := h.db.Where("date_time >= ? AND date_time <= ? AND is_available = ?", 
-		startDate, endDate, true)
+	query := h.db.Preload("Berater").Where("start_time >= ? AND start_time < ? AND is_available = ? AND purpose = ?", 
+		startDate, endDate, true, models.TimeslotPurposeConsultation)
 
 	// If package has duration, filter by compatible timeslots
-	if servicePackage.DurationMinutes > 0 {
-		query = query.Where("duration_minutes >= ?", servicePackage.DurationMinutes)
+	if servicePackage.ConsultationTime > 0 {
+		query = query.Where("duration >= ?", servicePackage.ConsultationTime)
+	}
+
+	// Customers may choose a specific Berater
+	if beraterIDStr := c.Query("berater_id"); beraterIDStr != "" {
+		beraterID, err := uuid.Parse(beraterIDStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid Berater ID")})
+			return
+		}
+		query = query.Where("berater_id = ?", beraterID)
 	}
 
-	if err := query.Order("date_time ASC").Find(&timeslots).Error; err != nil {
+	if err := query.Order("start_time ASC").Find(&timeslots).Error; err != nil {
 		h.logger.Error("Failed to fetch timeslots", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch timeslots"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to fetch timeslots")})
+		return
+	}
+
+	// Public holidays are looked up per Berater Bundesland. The range is padded by a
+	// day as a slot's local date may differ from the client's.
+	calendar := h.holidays.Calendar(
+		timeutil.FormatDate(startDate.AddDate(0, 0, -1), time.UTC),
+		timeutil.FormatDate(endDate.AddDate(0, 0, 1), time.UTC),
+	)
+
+	// Check current bookings and checkout holds to filter out unavailable slots
+	timeslotIDs := make([]uuid.UUID, len(timeslots))
+	for i, slot := range timeslots {
+		timeslotIDs[i] = slot.ID
+	}
+	occupancy, err := h.holds.Occupancy(h.db, timeslotIDs)
+	if err != nil {
+		h.logger.Error("Failed to fetch timeslot occupancy", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to fetch timeslots")})
+		return
+	}
+
+	// Existing appointments decide which slots respect the Beraters' buffer times and daily limits
+	beraterIDs := []uuid.UUID{}
+	seen := make(map[uuid.UUID]bool)
+	for _, slot := range timeslots {
+		if !seen[slot.BeraterID] {
+			seen[slot.BeraterID] = true
+			beraterIDs = append(beraterIDs, slot.BeraterID)
+		}
+	}
+	schedule, err := h.availability.LoadSchedule(h.db, beraterIDs, startDate, endDate, uuid.Nil)
+	if err != nil {
+		h.logger.Error("Failed to fetch Berater schedules", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to fetch timeslots")})
 		return
 	}
 
-	// Check current bookings to filter out unavailable slots
-	availableTimeslots := []models.Timeslot{}
+	availableTimeslots := []models.TimeslotResponse{}
 	for _, slot := range timeslots {
-		var bookingCount int64
-		h.db.Model(&models.Booking{}).Where("timeslot_id = ? AND status NOT IN (?)", 
-			slot.ID, []string{"cancelled", "completed"}).Count(&bookingCount)
-		
-		if bookingCount < int64(slot.MaxBookings) {
-			availableTimeslots = append(availableTimeslots, slot)
+		// Beraters are only bookable once their onboarding has been approved
+		if !slot.Berater.IsBookable() {
+			continue
+		}
+		if err := schedule.Check(&slot.Berater, &slot); err != nil {
+			continue
+		}
+
+		_, isHoliday, err := calendar.Lookup(slot.Berater.Bundesland, timeutil.FormatDate(slot.StartTime, slot.TimeLocation()))
+		if err != nil {
+			h.logger.Error("Failed to fetch holidays", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to fetch timeslots")})
+			return
+		}
+		if isHoliday {
+			continue
+		}
+
+		// Slots taken only by holds are listed with the time they may free up again
+		taken := occupancy[slot.ID]
+		if taken.Booked >= slot.MaxBookings {
+			continue
+		}
+		response := slot.ToResponseIn(loc)
+		response.CurrentBookings = taken.Booked
+		response.HeldSlots = taken.Held
+		response.AvailableSlots = slot.MaxBookings - taken.Taken()
+		if response.AvailableSlots < 0 {
+			response.AvailableSlots = 0
 		}
+		if response.AvailableSlots == 0 && taken.HeldUntil != nil {
+			heldUntil := taken.HeldUntil.In(loc)
+			response.HeldUntil = &heldUntil
+		}
+		availableTimeslots = append(availableTimeslots, response)
 	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"package":   servicePackage,
 		"timeslots": availableTimeslots,
 		"period": gin.H{
-			"start": startDate.Format("2006-01-02"),
-			"end":   endDate.Format("2006-01-02"),
+			"start":    timeutil.FormatDate(startDate, loc),
+			"end":      timeutil.FormatDate(endDate.Add(-time.Nanosecond), loc),
+			"timezone": loc.String(),
 		},
 	})
 }
 
+// QuoteBooking handles pricing an order without booking it
+// @Summary Quote booking
+// @Description Price a package with add-ons and a coupon code the way the booking will be charged: discount, VAT contained in the total and the credit of the current user applied before payment. Nothing is created.
+// @Tags bookings
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body QuoteRequest true "Order"
+// @Success 200 {object} catalog.Quote
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/v1/bookings/quote [post]
+func (h *BookingHandler) QuoteBooking(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "User not authenticated")})
+		return
+	}
+
+	var req QuoteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid request data"), "details": err.Error()})
+		return
+	}
+
+	quote, err := h.catalog.Quote(catalog.QuoteInput{
+		PackageID:  req.PackageID,
+		AddonIDs:   req.AddOnIDs,
+		CouponCode: req.CouponCode,
+		UserID:     &userID,
+	})
+	if err != nil {
+		handleCatalogError(c, h.logger, err, "Failed to calculate price")
+		return
+	}
+
+	c.JSON(http.StatusOK, quote)
+}
+
 // CreateBooking handles creating a new booking
 // @Summary Create booking
-// @Description Create a new booking with package, add-ons and optional timeslot
+// @Description Create a new booking with package, add-ons, an optional coupon code and optional timeslot. The price is calculated on the server.
 // @Tags bookings
 // @Security BearerAuth
 // @Accept json
@@ -221,17 +402,18 @@ func (h *BookingHandler) GetAvailableTimeslots(c *gin.Context) {
 // @Success 201 {object} BookingResponse
 // @Failure 400 {object} map[string]interface{}
 // @Failure 401 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
 // @Router /api/v1/bookings [post]
 func (h *BookingHandler) CreateBooking(c *gin.Context) {
 	userID, exists := c.Get("user_id")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "User not authenticated")})
 		return
 	}
 
 	var req CreateBookingRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data", "details": err.Error()})
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid request data"), "details": err.Error()})
 		return
 	}
 
@@ -243,71 +425,121 @@ func (h *BookingHandler) CreateBooking(c *gin.Context) {
 		}
 	}()
 
-	// Verify package exists
-	var servicePackage models.Package
-	if err := tx.Where("id = ? AND type = ?", req.PackageID, models.PackageTypeService).First(&servicePackage).Error; err != nil {
+	// Price the package with its add-ons and coupon from the catalog; the
+	// client never sends prices
+	order, err := h.catalog.Order(tx, req.PackageID, req.AddOnIDs, req.CouponCode)
+	if err != nil {
 		tx.Rollback()
-		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Package not found"})
-		} else {
-			h.logger.Error("Failed to fetch package", zap.Error(err))
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch package"})
-		}
+		handleCatalogError(c, h.logger, err, "Failed to fetch package")
 		return
 	}
+	servicePackage := *order.Package
+	totalPrice := order.Total
 
-	// Verify add-ons if provided
-	var addOns []models.Package
-	totalPrice := servicePackage.Price
-	if len(req.AddOnIDs) > 0 {
-		if err := tx.Where("id IN ? AND type = ?", req.AddOnIDs, models.PackageTypeAddOn).Find(&addOns).Error; err != nil {
+	// Verify timeslot if provided; its Berater conducts the consultation
+	var timeslot *models.Timeslot
+	var beraterID *uuid.UUID
+	var assigned *models.User
+	if req.TimeslotID != nil {
+		timeslot = &models.Timeslot{}
+		if err := tx.Where("id = ? AND is_available = ? AND purpose = ?", *req.TimeslotID, true, models.TimeslotPurposeConsultation).First(timeslot).Error; err != nil {
 			tx.Rollback()
-			h.logger.Error("Failed to fetch add-ons", zap.Error(err))
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch add-ons"})
+			if err == gorm.ErrRecordNotFound {
+				c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "Timeslot not found or not available")})
+			} else {
+				h.logger.Error("Failed to fetch timeslot", zap.Error(err))
+				c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to fetch timeslot")})
+			}
 			return
 		}
 
-		if len(addOns) != len(req.AddOnIDs) {
+		// The Berater is locked so concurrent bookings cannot both pass the booking limits
+		var berater models.User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select("id", "role", "is_active", "onboarding_status", "bundesland", "timezone", "booking_buffer_minutes", "max_bookings_per_day", "working_languages").
+			First(&berater, "id = ?", timeslot.BeraterID).Error; err != nil {
 			tx.Rollback()
-			c.JSON(http.StatusBadRequest, gin.H{"error": "One or more add-ons not found"})
+			h.logger.Error("Failed to fetch berater", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to fetch timeslot")})
 			return
 		}
-
-		// Calculate total price
-		for _, addOn := range addOns {
-			totalPrice += addOn.Price
+		if !berater.IsBookable() {
+			tx.Rollback()
+			c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "Timeslot not found or not available")})
+			return
 		}
-	}
+		if req.BeraterID != nil && *req.BeraterID != berater.ID {
+			tx.Rollback()
+			c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Timeslot does not belong to the selected Berater")})
+			return
+		}
+		beraterID = &berater.ID
+		assigned = &berater
 
-	// Verify timeslot if provided
-	var timeslot *models.Timeslot
-	if req.TimeslotID != nil {
-		timeslot = &models.Timeslot{}
-		if err := tx.Where("id = ? AND is_available = ?", *req.TimeslotID, true).First(timeslot).Error; err != nil {
+		// Slots created before a holiday override was added must not be bookable
+		holiday, err := h.holidays.IsHoliday(timeslot.StartTime, timeslot.TimeLocation(), berater.Bundesland)
+		if err != nil {
 			tx.Rollback()
-			if err == gorm.ErrRecordNotFound {
-				c.JSON(http.StatusNotFound, gin.H{"error": "Timeslot not found or not available"})
-			} else {
-				h.logger.Error("Failed to fetch timeslot", zap.Error(err))
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch timeslot"})
-			}
+			h.logger.Error("Failed to fetch holidays", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to fetch timeslot")})
+			return
+		}
+		if holiday != nil {
+			tx.Rollback()
+			c.JSON(http.StatusConflict, gin.H{"error": middleware.T(c, "Timeslot falls on a public holiday")})
 			return
 		}
 
-		// Check if timeslot is still available
-		var bookingCount int64
-		tx.Model(&models.Booking{}).Where("timeslot_id = ? AND status NOT IN (?)", 
-			timeslot.ID, []string{"cancelled", "completed"}).Count(&bookingCount)
-		
-		if bookingCount >= int64(timeslot.MaxBookings) {
+		schedule, err := h.availability.LoadSchedule(tx, []uuid.UUID{berater.ID}, timeslot.StartTime, timeslot.EndTime, uuid.Nil)
+		if err != nil {
 			tx.Rollback()
-			c.JSON(http.StatusConflict, gin.H{"error": "Timeslot is no longer available"})
+			h.logger.Error("Failed to fetch Berater schedule", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to fetch timeslot")})
+			return
+		}
+		if err := schedule.Check(&berater, timeslot); err != nil {
+			tx.Rollback()
+			if errors.Is(err, availability.ErrDailyLimitReached) {
+				c.JSON(http.StatusConflict, gin.H{"error": middleware.T(c, "The Berater has no more appointments available on this day")})
+			} else {
+				c.JSON(http.StatusConflict, gin.H{"error": middleware.T(c, "Timeslot is too close to another appointment of the Berater")})
+			}
 			return
 		}
 	} else if servicePackage.RequiresTimeslot {
 		tx.Rollback()
-		c.JSON(http.StatusBadRequest, gin.H{"error": "This package requires timeslot selection"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "This package requires timeslot selection")})
 		return
+	} else if req.BeraterID != nil {
+		var berater models.User
+		if err := tx.Select("id", "role", "is_active", "onboarding_status", "working_languages").
+			First(&berater, "id = ?", *req.BeraterID).Error; err != nil || !berater.IsBookable() {
+			tx.Rollback()
+			if err != nil && err != gorm.ErrRecordNotFound {
+				h.logger.Error("Failed to fetch berater", zap.Error(err))
+				c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to fetch user")})
+			} else {
+				c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "Berater not found")})
+			}
+			return
+		}
+		beraterID = &berater.ID
+		assigned = &berater
+	}
+
+	// Bookings for a family are shared with the other parent
+	if req.FamilyID != nil {
+		var member models.FamilyMember
+		if err := tx.Where("family_id = ? AND user_id = ?", *req.FamilyID, userID).First(&member).Error; err != nil {
+			tx.Rollback()
+			if err == gorm.ErrRecordNotFound {
+				c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "Family not found")})
+			} else {
+				h.logger.Error("Failed to fetch family", zap.Error(err))
+				c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to fetch family")})
+			}
+			return
+		}
 	}
 
 	// Generate booking reference
@@ -317,48 +549,80 @@ func (h *BookingHandler) CreateBooking(c *gin.Context) {
 	booking := models.Booking{
 		ID:               uuid.New(),
 		UserID:           userID.(uuid.UUID),
-		PackageID:        req.PackageID,
+		PackageID:        &servicePackage.ID,
 		TimeslotID:       req.TimeslotID,
+		BeraterID:        beraterID,
+		FamilyID:         req.FamilyID,
 		BookingReference: bookingRef,
 		Status:           models.BookingStatusPending,
-		TotalPrice:       totalPrice,
-		Currency:         "EUR",
+		TotalAmount:      totalPrice,
+		DiscountAmount:   order.Discount,
+		Currency:         order.Currency,
 		BookingDate:      time.Now(),
 		PreferredDate:    req.PreferredDate,
 		Notes:            req.Notes,
 		CreatedAt:        time.Now(),
 		UpdatedAt:        time.Now(),
 	}
+	if order.Coupon != nil {
+		booking.CouponID = &order.Coupon.ID
+	}
+
+	// Flag the booking for an interpreter if the Berater speaks none of the customer's languages
+	if assigned != nil {
+		var customer models.User
+		if err := tx.Select("id", "language", "spoken_languages").First(&customer, "id = ?", booking.UserID).Error; err != nil {
+			tx.Rollback()
+			h.logger.Error("Failed to fetch customer", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to create booking")})
+			return
+		}
+		booking.MatchLanguages(&customer, assigned)
+	}
 
 	if err := tx.Create(&booking).Error; err != nil {
 		tx.Rollback()
 		h.logger.Error("Failed to create booking", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create booking"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to create booking")})
 		return
 	}
 
-	// Create booking add-ons
-	for _, addOn := range addOns {
-		bookingAddOn := models.BookingAddOn{
-			ID:        uuid.New(),
-			BookingID: booking.ID,
-			PackageID: addOn.ID,
-			Price:     addOn.Price,
-			CreatedAt: time.Now(),
-		}
-		if err := tx.Create(&bookingAddOn).Error; err != nil {
+	// Reserve the timeslot until the booking is paid or the hold expires
+	var hold *models.TimeslotHold
+	if timeslot != nil {
+		var err error
+		hold, err = h.holds.Place(tx, &booking, timeslot)
+		if err != nil {
 			tx.Rollback()
-			h.logger.Error("Failed to create booking add-on", zap.Error(err))
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create booking"})
+			if errors.Is(err, holds.ErrTimeslotFull) {
+				c.JSON(http.StatusConflict, gin.H{"error": middleware.T(c, "Timeslot is no longer available")})
+			} else {
+				h.logger.Error("Failed to hold timeslot", zap.Error(err))
+				c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to create booking")})
+			}
 			return
 		}
 	}
 
+	// Create booking add-ons at the price they were ordered at
+	if err := h.catalog.AttachAddons(tx, booking.ID, order); err != nil {
+		tx.Rollback()
+		h.logger.Error("Failed to create booking add-on", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to create booking")})
+		return
+	}
+	if err := h.catalog.RedeemCoupon(tx, order); err != nil {
+		tx.Rollback()
+		handleCatalogError(c, h.logger, err, "Failed to create booking")
+		return
+	}
+
 	// Create associated lead
 	lead := models.Lead{
 		ID:           uuid.New(),
 		UserID:       &userID.(uuid.UUID),
 		BookingID:    &booking.ID,
+		FamilyID:     req.FamilyID,
 		Source:       models.LeadSourceBooking,
 		Status:       models.LeadStatusNew,
 		Priority:     models.LeadPriorityMedium,
@@ -372,7 +636,7 @@ func (h *BookingHandler) CreateBooking(c *gin.Context) {
 	if err := tx.Create(&lead).Error; err != nil {
 		tx.Rollback()
 		h.logger.Error("Failed to create lead", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create booking"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to create booking")})
 		return
 	}
 
@@ -381,14 +645,14 @@ func (h *BookingHandler) CreateBooking(c *gin.Context) {
 	if err := tx.Save(&booking).Error; err != nil {
 		tx.Rollback()
 		h.logger.Error("Failed to update booking with lead ID", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create booking"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to create booking")})
 		return
 	}
 
 	// Commit transaction
 	if err := tx.Commit().Error; err != nil {
 		h.logger.Error("Failed to commit booking transaction", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create booking"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to create booking")})
 		return
 	}
 
@@ -397,12 +661,17 @@ func (h *BookingHandler) CreateBooking(c *gin.Context) {
 		zap.String("user_id", userID.(uuid.UUID).String()),
 		zap.String("package_id", req.PackageID.String()))
 
+	if err := h.experiments.RecordBooking(&booking); err != nil {
+		h.logger.Error("Failed to attribute booking to experiments", zap.Error(err))
+	}
+
 	// Prepare response
 	response := &BookingResponse{
 		Booking:  &booking,
-		Package:  &servicePackage,
-		AddOns:   addOns,
-		Timeslot: timeslot,
+		Package:   &servicePackage,
+		LineItems: order.LineItems,
+		Timeslot:  timeslot,
+		Hold:     hold,
 		Lead:     &lead,
 	}
 
@@ -424,7 +693,7 @@ func (h *BookingHandler) CreateBooking(c *gin.Context) {
 func (h *BookingHandler) GetUserBookings(c *gin.Context) {
 	userID, exists := c.Get("user_id")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "User not authenticated")})
 		return
 	}
 
@@ -450,7 +719,7 @@ func (h *BookingHandler) GetUserBookings(c *gin.Context) {
 	if err := query.Preload("Package").Preload("Timeslot").Preload("Lead").
 		Offset(offset).Limit(limit).Order("created_at DESC").Find(&bookings).Error; err != nil {
 		h.logger.Error("Failed to fetch user bookings", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch bookings"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to fetch bookings")})
 		return
 	}
 
@@ -477,58 +746,52 @@ func (h *BookingHandler) GetUserBookings(c *gin.Context) {
 // @Failure 404 {object} map[string]interface{}
 // @Router /api/v1/bookings/{id} [get]
 func (h *BookingHandler) GetBooking(c *gin.Context) {
-	userID, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+	viewer, ok := scopes.FromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "User not authenticated")})
 		return
 	}
 
 	bookingID := c.Param("id")
 
 	var booking models.Booking
-	query := h.db.Where("id = ?", bookingID)
-
-	// Non-admin users can only see their own bookings
-	userRole, _ := c.Get("user_role")
-	if userRole != "admin" && userRole != "berater" {
-		query = query.Where("user_id = ?", userID)
-	}
+	query := h.db.Scopes(scopes.VisibleBookings(viewer)).Where("id = ?", bookingID)
 
 	if err := query.Preload("Package").Preload("Timeslot").Preload("Lead").
 		Preload("Payments").Preload("Documents").First(&booking).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Booking not found"})
+			c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "Booking not found")})
 		} else {
 			h.logger.Error("Failed to fetch booking", zap.Error(err))
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch booking"})
+			c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to fetch booking")})
 		}
 		return
 	}
 
-	// Get add-ons
-	var addOns []models.Package
-	h.db.Table("booking_add_ons").
-		Select("packages.*").
-		Joins("JOIN packages ON packages.id = booking_add_ons.package_id").
-		Where("booking_add_ons.booking_id = ?", booking.ID).
-		Find(&addOns)
+	lineItems, err := h.catalog.LineItems(h.db, &booking)
+	if err != nil {
+		h.logger.Error("Failed to fetch booking add-ons", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to fetch booking")})
+		return
+	}
 
 	response := &BookingResponse{
 		Booking:   &booking,
 		Package:   booking.Package,
-		AddOns:    addOns,
+		LineItems: lineItems,
 		Timeslot:  booking.Timeslot,
 		Lead:      booking.Lead,
 		Payments:  booking.Payments,
 		Documents: booking.Documents,
 	}
+	response.CanCancel, response.CanReschedule = h.changeWindows(&booking)
 
 	c.JSON(http.StatusOK, response)
 }
 
 // UpdateBookingContactInfo handles updating contact information after booking
 // @Summary Update booking contact info
-// @Description Update contact information for a booking (must be done after booking)
+// @Description Update contact information for a booking (must be done after booking). The address is normalized by the address provider, which also sets the customer's Bundesland (derived from the postal code when the provider cannot tell it); address_status flags addresses the provider does not know.
 // @Tags bookings
 // @Security BearerAuth
 // @Accept json
@@ -543,7 +806,7 @@ func (h *BookingHandler) GetBooking(c *gin.Context) {
 func (h *BookingHandler) UpdateBookingContactInfo(c *gin.Context) {
 	userID, exists := c.Get("user_id")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "User not authenticated")})
 		return
 	}
 
@@ -552,30 +815,54 @@ func (h *BookingHandler) UpdateBookingContactInfo(c *gin.Context) {
 	var booking models.Booking
 	if err := h.db.Where("id = ? AND user_id = ?", bookingID, userID).First(&booking).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Booking not found"})
+			c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "Booking not found")})
 		} else {
 			h.logger.Error("Failed to fetch booking", zap.Error(err))
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch booking"})
+			c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to fetch booking")})
 		}
 		return
 	}
 
 	var req UpdateContactInfoRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data", "details": err.Error()})
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid request data"), "details": err.Error()})
 		return
 	}
 
+	// Normalize the address and derive the Bundesland; when the provider
+	// fails the address is saved as entered
+	address := addresses.Address{
+		Street:      req.Street,
+		HouseNumber: req.HouseNumber,
+		PostalCode:  req.PostalCode,
+		City:        req.City,
+		Country:     req.Country,
+	}
+	validated, err := h.addresses.Validate(c.Request.Context(), address)
+	if err != nil {
+		h.logger.Warn("Failed to validate address", zap.String("booking_id", bookingID), zap.Error(err))
+		validated = &addresses.Result{Address: address, Status: models.AddressStatusUnchecked}
+	}
+	// Without a verified address the postal code tells the Bundesland
+	if validated.Bundesland == "" && validated.Address.Country == "DE" {
+		state, err := h.postalCodes.Bundesland(validated.Address.PostalCode)
+		if err != nil {
+			h.logger.Warn("Failed to derive Bundesland from postal code", zap.String("booking_id", bookingID), zap.Error(err))
+		}
+		validated.Bundesland = state
+	}
+
 	// Update booking contact information
 	updates := map[string]interface{}{
 		"contact_first_name":  req.FirstName,
 		"contact_last_name":   req.LastName,
 		"contact_phone":       req.Phone,
-		"contact_street":      req.Street,
-		"contact_house_number": req.HouseNumber,
-		"contact_postal_code": req.PostalCode,
-		"contact_city":        req.City,
-		"contact_country":     req.Country,
+		"contact_street":      validated.Address.Street,
+		"contact_house_number": validated.Address.HouseNumber,
+		"contact_postal_code": validated.Address.PostalCode,
+		"contact_city":        validated.Address.City,
+		"contact_country":     validated.Address.Country,
+		"address_status":      validated.Status,
 		"contact_completed":   true,
 		"updated_at":          time.Now(),
 	}
@@ -592,17 +879,105 @@ func (h *BookingHandler) UpdateBookingContactInfo(c *gin.Context) {
 
 	if err := h.db.Model(&booking).Updates(updates).Error; err != nil {
 		h.logger.Error("Failed to update contact info", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update contact information"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to update contact information")})
 		return
 	}
 
+	// The customer's Bundesland decides the responsible Elterngeldstelle
+	if validated.Bundesland != "" {
+		if err := h.db.Model(&models.User{}).Where("id = ?", userID).Update("bundesland", validated.Bundesland).Error; err != nil {
+			h.logger.Error("Failed to update customer Bundesland", zap.String("booking_id", bookingID), zap.Error(err))
+		}
+	}
+
 	// Fetch updated booking
 	if err := h.db.First(&booking, "id = ?", bookingID).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch updated booking"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to fetch updated booking")})
 		return
 	}
 
 	h.logger.Info("Contact info updated", zap.String("booking_id", bookingID))
 
 	c.JSON(http.StatusOK, booking)
-}
\ No newline at end of file
+}
+// RateBooking handles the customer rating of a completed consultation
+// @Summary Rate booking
+// @Description Rate a completed consultation (1-5); ratings are shown on the Berater's public profile
+// @Tags bookings
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Booking ID"
+// @Param request body RateBookingRequest true "Rating"
+// @Success 200 {object} models.BookingResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/v1/bookings/{id}/rating [post]
+func (h *BookingHandler) RateBooking(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "User not authenticated")})
+		return
+	}
+
+	var req RateBookingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid request data"), "details": err.Error()})
+		return
+	}
+
+	var booking models.Booking
+	if err := h.db.Where("id = ? AND user_id = ?", c.Param("id"), userID).First(&booking).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "Booking not found")})
+		} else {
+			h.logger.Error("Failed to fetch booking", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to fetch booking")})
+		}
+		return
+	}
+
+	if booking.Status != models.BookingStatusCompleted {
+		c.JSON(http.StatusConflict, gin.H{"error": middleware.T(c, "Only completed bookings can be rated")})
+		return
+	}
+	if booking.Rating != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": middleware.T(c, "Booking has already been rated")})
+		return
+	}
+
+	// Only the first of concurrent ratings is stored
+	now := time.Now()
+	result := h.db.Model(&models.Booking{}).
+		Where("id = ? AND rating IS NULL", booking.ID).
+		Updates(map[string]interface{}{
+			"rating":         req.Rating,
+			"rating_comment": req.Comment,
+			"rated_at":       now,
+		})
+	if result.Error != nil {
+		h.logger.Error("Failed to rate booking", zap.Error(result.Error))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to rate booking")})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": middleware.T(c, "Booking has already been rated")})
+		return
+	}
+	booking.Rating = &req.Rating
+	booking.RatingComment = req.Comment
+	booking.RatedAt = &now
+
+	response := booking.ToResponse()
+	response.CanCancel, response.CanReschedule = h.changeWindows(&booking)
+
+	c.JSON(http.StatusOK, response)
+}
+
+// changeWindows checks if the booking can still be cancelled or rescheduled
+// within the windows configured in the system settings
+func (h *BookingHandler) changeWindows(booking *models.Booking) (canCancel, canReschedule bool) {
+	return booking.CanCancelBefore(h.settings.Duration(settings.KeyCancellationWindow)),
+		booking.CanRescheduleBefore(h.settings.Duration(settings.KeyRescheduleWindow))
+}
@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"elterngeld-portal/internal/middleware"
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/internal/scopes"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// SetInterpreterRequest flags a booking for an interpreter, e.g. when the
+// customer turns out to need one despite the language match, or not
+type SetInterpreterRequest struct {
+	Required *bool `json:"required" binding:"required"`
+	// Language the interpreter translates to, defaults to the customer's preferred language
+	Language string `json:"language,omitempty" binding:"omitempty,oneof=de en tr ar ru uk pl fa ro"`
+}
+
+// SetInterpreter handles changing whether a booking needs an interpreter
+// @Summary Set booking interpreter
+// @Description Flag or unflag a booking for an interpreter. Bookings are flagged automatically when the Berater speaks none of the customer's languages.
+// @Tags bookings
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Booking ID"
+// @Param request body SetInterpreterRequest true "Interpreter"
+// @Success 200 {object} models.BookingResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/bookings/{id}/interpreter [put]
+func (h *BookingHandler) SetInterpreter(c *gin.Context) {
+	viewer, ok := scopes.FromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "User not authenticated")})
+		return
+	}
+
+	bookingID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid booking ID")})
+		return
+	}
+
+	var req SetInterpreterRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid request data"), "details": err.Error()})
+		return
+	}
+
+	var booking models.Booking
+	if err := h.db.Scopes(scopes.VisibleBookings(viewer)).First(&booking, "id = ?", bookingID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "Booking not found")})
+		} else {
+			h.logger.Error("Failed to fetch booking", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to fetch booking")})
+		}
+		return
+	}
+
+	language := ""
+	if *req.Required {
+		language = req.Language
+		if language == "" {
+			var customer models.User
+			if err := h.db.Select("id", "language", "spoken_languages").First(&customer, "id = ?", booking.UserID).Error; err != nil {
+				h.logger.Error("Failed to fetch customer", zap.Error(err))
+				c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to update booking")})
+				return
+			}
+			language = customer.PreferredLanguages()[0]
+		}
+	}
+
+	if err := h.db.Model(&booking).Updates(map[string]interface{}{
+		"interpreter_required": *req.Required,
+		"interpreter_language": language,
+		"updated_at":           time.Now(),
+	}).Error; err != nil {
+		h.logger.Error("Failed to update booking interpreter", zap.String("booking_id", booking.ID.String()), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to update booking")})
+		return
+	}
+	booking.InterpreterRequired = *req.Required
+	booking.InterpreterLanguage = language
+
+	c.JSON(http.StatusOK, booking.ToResponse())
+}
@@ -0,0 +1,102 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"elterngeld-portal/internal/middleware"
+	"elterngeld-portal/internal/reassignment"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type BookingReassignmentHandler struct {
+	db           *gorm.DB
+	logger       *zap.Logger
+	reassignment *reassignment.Service
+}
+
+func NewBookingReassignmentHandler(db *gorm.DB, logger *zap.Logger, reassignmentService *reassignment.Service) *BookingReassignmentHandler {
+	return &BookingReassignmentHandler{
+		db:           db,
+		logger:       logger,
+		reassignment: reassignmentService,
+	}
+}
+
+// ReassignBookingsRequest represents the booking reassignment request. Either
+// booking IDs or a period must be given.
+type ReassignBookingsRequest struct {
+	ToBeraterID uuid.UUID   `json:"to_berater_id" binding:"required"`
+	BookingIDs  []uuid.UUID `json:"booking_ids"`
+	From        string      `json:"from" binding:"omitempty,datetime=2006-01-02"`
+	To          string      `json:"to" binding:"omitempty,datetime=2006-01-02"`
+	Reason      string      `json:"reason" binding:"max=500"`
+}
+
+// ReassignBookings handles moving upcoming bookings to another Berater, e.g. during vacation or sickness (admin only)
+// @Summary Reassign bookings to another Berater
+// @Description Move the given bookings, or all upcoming bookings in a period, with their timeslots to another Berater and notify the customers
+// @Tags admin
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Berater ID"
+// @Param request body ReassignBookingsRequest true "Reassignment"
+// @Success 200 {object} reassignment.Result
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/v1/admin/beraters/{id}/reassign-bookings [post]
+func (h *BookingReassignmentHandler) ReassignBookings(c *gin.Context) {
+	beraterID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid user ID")})
+		return
+	}
+
+	adminID, ok := middleware.GetCurrentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "User not authenticated")})
+		return
+	}
+
+	var req ReassignBookingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid request data"), "details": err.Error()})
+		return
+	}
+
+	result, err := h.reassignment.Reassign(reassignment.Input{
+		FromBeraterID: beraterID,
+		ToBeraterID:   req.ToBeraterID,
+		BookingIDs:    req.BookingIDs,
+		From:          req.From,
+		To:            req.To,
+		Location:      middleware.GetTimezone(c),
+		Reason:        req.Reason,
+	}, adminID)
+	if err != nil {
+		switch {
+		case errors.Is(err, reassignment.ErrBeraterNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "Berater not found")})
+		case errors.Is(err, reassignment.ErrSameBerater):
+			c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Bookings cannot be reassigned to the same Berater")})
+		case errors.Is(err, reassignment.ErrBeraterNotBookable):
+			c.JSON(http.StatusConflict, gin.H{"error": middleware.T(c, "Berater is not available for bookings")})
+		case errors.Is(err, reassignment.ErrInvalidPeriod):
+			c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid period")})
+		case errors.Is(err, reassignment.ErrBookingNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "Booking not found")})
+		default:
+			h.logger.Error("Failed to reassign bookings", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to reassign bookings")})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"elterngeld-portal/internal/bookingstate"
+	"elterngeld-portal/internal/middleware"
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/internal/scopes"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type BookingStateHandler struct {
+	db     *gorm.DB
+	logger *zap.Logger
+	states *bookingstate.Service
+}
+
+func NewBookingStateHandler(db *gorm.DB, logger *zap.Logger, bookingStates *bookingstate.Service) *BookingStateHandler {
+	return &BookingStateHandler{
+		db:     db,
+		logger: logger,
+		states: bookingStates,
+	}
+}
+
+// GetBookingTransitions handles listing the status changes of a booking
+// @Summary List booking status transitions
+// @Description List the statuses a booking can move to from its current status, and whether each is allowed now, e.g. a booking is only confirmed once it is paid
+// @Tags bookings
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Booking ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/bookings/{id}/transitions [get]
+func (h *BookingStateHandler) GetBookingTransitions(c *gin.Context) {
+	viewer, ok := scopes.FromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "User not authenticated")})
+		return
+	}
+
+	bookingID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid booking ID")})
+		return
+	}
+
+	var booking models.Booking
+	if err := h.db.Scopes(scopes.VisibleBookings(viewer)).Select("id", "status").First(&booking, "id = ?", bookingID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "Booking not found")})
+		} else {
+			h.logger.Error("Failed to fetch booking", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to fetch booking")})
+		}
+		return
+	}
+
+	options, err := h.states.Options(booking.ID)
+	if err != nil {
+		if errors.Is(err, bookingstate.ErrBookingNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "Booking not found")})
+			return
+		}
+		h.logger.Error("Failed to list booking transitions", zap.String("booking_id", booking.ID.String()), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to fetch booking")})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":      booking.Status,
+		"transitions": options,
+	})
+}
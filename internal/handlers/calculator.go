@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"elterngeld-portal/internal/calculator"
+	"elterngeld-portal/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type CalculatorHandler struct {
+	db         *gorm.DB
+	logger     *zap.Logger
+	calculator *calculator.Service
+}
+
+func NewCalculatorHandler(db *gorm.DB, logger *zap.Logger, calculatorService *calculator.Service) *CalculatorHandler {
+	return &CalculatorHandler{
+		db:         db,
+		logger:     logger,
+		calculator: calculatorService,
+	}
+}
+
+// KindergeldChildRequest is a child in the Kindergeld calculator
+type KindergeldChildRequest struct {
+	BirthDate   string `json:"birth_date" binding:"required,datetime=2006-01-02"`
+	InEducation bool   `json:"in_education"`
+}
+
+// KindergeldRequest represents the Kindergeld calculation request
+type KindergeldRequest struct {
+	Month    string                   `json:"month,omitempty" binding:"omitempty,datetime=2006-01"` // defaults to the current month
+	Children []KindergeldChildRequest `json:"children" binding:"required,min=1,max=20,dive"`
+}
+
+// MaternityOffsetRequest represents the Mutterschaftsgeld offset calculation request
+type MaternityOffsetRequest struct {
+	BirthDate                string   `json:"birth_date" binding:"required,datetime=2006-01-02"`
+	Elterngeld               float64  `json:"elterngeld" binding:"min=0"`
+	MaternityBenefitPerDay   *float64 `json:"maternity_benefit_per_day,omitempty" binding:"omitempty,min=0"` // defaults to 13 euros
+	EmployerSupplementPerDay float64  `json:"employer_supplement_per_day" binding:"min=0"`
+	ProtectionWeeks          int      `json:"protection_weeks,omitempty" binding:"omitempty,oneof=8 12"` // defaults to 8
+	ExtensionDays            int      `json:"extension_days,omitempty" binding:"min=0,max=42"`
+}
+
+// PartTimeRequest represents the part-time Elterngeld calculation request
+type PartTimeRequest struct {
+	IncomeBefore float64 `json:"income_before" binding:"min=0"`
+	IncomeAfter  float64 `json:"income_after" binding:"min=0"`
+	WeeklyHours  float64 `json:"weekly_hours" binding:"min=0,max=168"`
+}
+
+// CalculateKindergeld handles the Kindergeld calculator
+// @Summary Calculate Kindergeld
+// @Description Kindergeld per child and in total for a month. Children get Kindergeld until 18, in education or training until 25.
+// @Tags calculator
+// @Accept json
+// @Produce json
+// @Param request body KindergeldRequest true "Children"
+// @Success 200 {object} calculator.KindergeldResult
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/v1/calculator/kindergeld [post]
+func (h *CalculatorHandler) CalculateKindergeld(c *gin.Context) {
+	var req KindergeldRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid request data"), "details": err.Error()})
+		return
+	}
+
+	var month time.Time
+	if req.Month != "" {
+		month, _ = time.Parse("2006-01", req.Month)
+	}
+	children := make([]calculator.Child, 0, len(req.Children))
+	for _, child := range req.Children {
+		birthDate, _ := time.Parse("2006-01-02", child.BirthDate)
+		children = append(children, calculator.Child{BirthDate: birthDate, InEducation: child.InEducation})
+	}
+
+	result, err := h.calculator.Kindergeld(children, month)
+	if err != nil {
+		h.handleCalculatorError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// CalculateMaternityOffset handles the Mutterschaftsgeld offset calculator
+// @Summary Calculate Mutterschaftsgeld offset
+// @Description How Mutterschaftsgeld and the employer's supplement after the birth reduce the Elterngeld of each Lebensmonat. These Lebensmonate count as Basiselterngeld months of the mother.
+// @Tags calculator
+// @Accept json
+// @Produce json
+// @Param request body MaternityOffsetRequest true "Birth and benefits"
+// @Success 200 {object} calculator.MaternityResult
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/v1/calculator/maternity-offset [post]
+func (h *CalculatorHandler) CalculateMaternityOffset(c *gin.Context) {
+	var req MaternityOffsetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid request data"), "details": err.Error()})
+		return
+	}
+
+	birthDate, _ := time.Parse("2006-01-02", req.BirthDate)
+	input := calculator.MaternityInput{
+		BirthDate:                birthDate,
+		Elterngeld:               req.Elterngeld,
+		MaternityBenefitPerDay:   calculator.MaxMaternityBenefitPerDay,
+		EmployerSupplementPerDay: req.EmployerSupplementPerDay,
+		ProtectionWeeks:          calculator.ProtectionWeeks,
+		ExtensionDays:            req.ExtensionDays,
+	}
+	if req.MaternityBenefitPerDay != nil {
+		input.MaternityBenefitPerDay = *req.MaternityBenefitPerDay
+	}
+	if req.ProtectionWeeks != 0 {
+		input.ProtectionWeeks = req.ProtectionWeeks
+	}
+
+	result, err := h.calculator.MaternityOffset(input)
+	if err != nil {
+		h.handleCalculatorError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// CalculatePartTime handles the part-time Elterngeld calculator
+// @Summary Calculate Elterngeld with part-time work
+// @Description Basiselterngeld and ElterngeldPlus of a Bezugsmonat with part-time income compared to not working. Parents working more than 32 hours a week get no Elterngeld.
+// @Tags calculator
+// @Accept json
+// @Produce json
+// @Param request body PartTimeRequest true "Monthly net income before the birth and during the Bezugsmonat"
+// @Success 200 {object} calculator.PartTimeResult
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/v1/calculator/part-time [post]
+func (h *CalculatorHandler) CalculatePartTime(c *gin.Context) {
+	var req PartTimeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid request data"), "details": err.Error()})
+		return
+	}
+
+	result, err := h.calculator.PartTime(calculator.PartTimeInput{
+		IncomeBefore: req.IncomeBefore,
+		IncomeAfter:  req.IncomeAfter,
+		WeeklyHours:  req.WeeklyHours,
+	})
+	if err != nil {
+		h.handleCalculatorError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+func (h *CalculatorHandler) handleCalculatorError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, calculator.ErrUnsupportedYear):
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Kindergeld rates are not available for this year")})
+	case errors.Is(err, calculator.ErrInvalidInput):
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid request data")})
+	default:
+		h.logger.Error("Failed to calculate", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to calculate")})
+	}
+}
@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"elterngeld-portal/internal/capacity"
+	"elterngeld-portal/internal/middleware"
+	"elterngeld-portal/pkg/timeutil"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type CapacityHandler struct {
+	db       *gorm.DB
+	logger   *zap.Logger
+	capacity *capacity.Service
+}
+
+func NewCapacityHandler(db *gorm.DB, logger *zap.Logger, capacityService *capacity.Service) *CapacityHandler {
+	return &CapacityHandler{
+		db:       db,
+		logger:   logger,
+		capacity: capacityService,
+	}
+}
+
+// GetCapacityReport handles the capacity planning report (admin only)
+// @Summary Get capacity report
+// @Description Offered timeslot capacity against demand per week and Berater: booked places, customers waiting for an appointment, unfilled slots, utilization and shortfall
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Param from query string false "First day (YYYY-MM-DD), defaults to four weeks ago"
+// @Param to query string false "Last day (YYYY-MM-DD), defaults to four weeks ahead"
+// @Param berater_id query string false "Only this Berater"
+// @Success 200 {object} capacity.Report
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/v1/admin/capacity [get]
+func (h *CapacityHandler) GetCapacityReport(c *gin.Context) {
+	loc := middleware.GetTimezone(c)
+	today := time.Now()
+
+	filter := capacity.ReportFilter{
+		From:     c.DefaultQuery("from", timeutil.FormatDate(timeutil.AddDays(today, -28, loc), loc)),
+		To:       c.DefaultQuery("to", timeutil.FormatDate(timeutil.AddDays(today, 27, loc), loc)),
+		Location: loc,
+	}
+	if value := c.Query("berater_id"); value != "" {
+		beraterID, err := uuid.Parse(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid Berater ID")})
+			return
+		}
+		filter.BeraterID = &beraterID
+	}
+
+	report, err := h.capacity.Report(filter)
+	if err != nil {
+		if errors.Is(err, capacity.ErrInvalidPeriod) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid period")})
+			return
+		}
+		h.logger.Error("Failed to build capacity report", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to build capacity report")})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
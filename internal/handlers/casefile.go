@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"errors"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"elterngeld-portal/internal/casefile"
+	"elterngeld-portal/internal/middleware"
+	"elterngeld-portal/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type CaseFileHandler struct {
+	db        *gorm.DB
+	logger    *zap.Logger
+	caseFiles *casefile.Service
+}
+
+func NewCaseFileHandler(db *gorm.DB, logger *zap.Logger, caseFileService *casefile.Service) *CaseFileHandler {
+	return &CaseFileHandler{
+		db:        db,
+		logger:    logger,
+		caseFiles: caseFileService,
+	}
+}
+
+// ExportLead handles exporting the case file of a lead (assigned Berater or admin)
+// @Summary Export case file
+// @Description Download the complete case file of a lead (timeline, comments, documents list, emails and consents) for handover to the customer or authorities. Every export is recorded in the lead's activity log.
+// @Tags leads
+// @Security BearerAuth
+// @Produce application/pdf
+// @Produce application/zip
+// @Param id path string true "Lead ID"
+// @Param format query string false "pdf (default) or zip with the PDF and the case file as JSON"
+// @Param include_internal query bool false "Include internal comments"
+// @Success 200 {file} binary
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/leads/{id}/export [get]
+func (h *CaseFileHandler) ExportLead(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "User not authenticated")})
+		return
+	}
+
+	leadID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid lead ID")})
+		return
+	}
+
+	includeInternal, err := strconv.ParseBool(c.DefaultQuery("include_internal", "false"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid request data")})
+		return
+	}
+
+	var requester models.User
+	if err := h.db.First(&requester, "id = ?", userID).Error; err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "User not found")})
+		return
+	}
+
+	export, err := h.caseFiles.Export(leadID, &requester, casefile.Options{
+		Format:          casefile.Format(c.DefaultQuery("format", string(casefile.FormatPDF))),
+		IncludeInternal: includeInternal,
+		IPAddress:       c.ClientIP(),
+		UserAgent:       c.Request.UserAgent(),
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, casefile.ErrLeadNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "Lead not found")})
+		case errors.Is(err, casefile.ErrAccessDenied):
+			c.JSON(http.StatusForbidden, gin.H{"error": middleware.T(c, "Access denied")})
+		case errors.Is(err, casefile.ErrInvalidFormat):
+			c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid export format. Use pdf or zip")})
+		default:
+			h.logger.Error("Failed to export case file", zap.String("lead_id", leadID.String()), zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to export case file")})
+		}
+		return
+	}
+
+	// Case files contain personal data and must not be cached by shared proxies
+	c.Header("Cache-Control", "private, no-store")
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": export.FileName}))
+	c.Data(http.StatusOK, export.ContentType, export.Data)
+}
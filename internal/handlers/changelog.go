@@ -0,0 +1,253 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"elterngeld-portal/internal/changelog"
+	"elterngeld-portal/internal/middleware"
+	"elterngeld-portal/internal/scopes"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type ChangelogHandler struct {
+	db        *gorm.DB
+	logger    *zap.Logger
+	changelog *changelog.Service
+}
+
+func NewChangelogHandler(db *gorm.DB, logger *zap.Logger, changelogService *changelog.Service) *ChangelogHandler {
+	return &ChangelogHandler{
+		db:        db,
+		logger:    logger,
+		changelog: changelogService,
+	}
+}
+
+// GetProductUpdateFeed handles getting the "What's new" feed of the current user
+// @Summary Get product update feed
+// @Description Get the latest published product updates for the current user's role and the current and upcoming maintenance windows. With since, the time the user last opened the widget, the updates published after it are counted as unseen.
+// @Tags product-updates
+// @Security BearerAuth
+// @Produce json
+// @Param since query string false "Time the user last opened the feed (RFC3339)"
+// @Param limit query int false "Number of updates"
+// @Success 200 {object} changelog.Feed
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/v1/product-updates [get]
+func (h *ChangelogHandler) GetProductUpdateFeed(c *gin.Context) {
+	viewer, ok := scopes.FromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "User not authenticated")})
+		return
+	}
+
+	var since *time.Time
+	if value := c.Query("since"); value != "" {
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid time format. Use RFC3339")})
+			return
+		}
+		since = &t
+	}
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(changelog.DefaultLimit)))
+	if limit < 1 || limit > changelog.MaxLimit {
+		limit = changelog.DefaultLimit
+	}
+
+	feed, err := h.changelog.Feed(viewer.Role, since, limit)
+	if err != nil {
+		h.handleChangelogError(c, err, "Failed to fetch product updates")
+		return
+	}
+
+	c.JSON(http.StatusOK, feed)
+}
+
+// ListProductUpdates handles listing the product updates including drafts (admin only)
+// @Summary List product updates
+// @Description Get the product updates, drafts first, then by publish time
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Param page query int false "Page number"
+// @Param limit query int false "Items per page"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/admin/product-updates [get]
+func (h *ChangelogHandler) ListProductUpdates(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(changelog.DefaultLimit)))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > changelog.MaxLimit {
+		limit = changelog.DefaultLimit
+	}
+
+	updates, total, err := h.changelog.List(page, limit)
+	if err != nil {
+		h.handleChangelogError(c, err, "Failed to fetch product updates")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"updates": updates,
+		"pagination": gin.H{
+			"page":  page,
+			"limit": limit,
+			"total": total,
+			"pages": (total + int64(limit) - 1) / int64(limit),
+		},
+	})
+}
+
+// CreateProductUpdate handles adding a product update (admin only)
+// @Summary Create product update
+// @Description Add a feature, improvement, fix or maintenance window to the feed. Without a publish time it is saved as draft; a publish time in the future schedules it. Maintenance windows need a start and an end.
+// @Tags admin
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body changelog.Input true "Product update"
+// @Success 201 {object} models.ProductUpdate
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/v1/admin/product-updates [post]
+func (h *ChangelogHandler) CreateProductUpdate(c *gin.Context) {
+	userID, ok := middleware.GetCurrentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "User not authenticated")})
+		return
+	}
+
+	var req changelog.Input
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid request data"), "details": err.Error()})
+		return
+	}
+
+	update, err := h.changelog.Create(req, userID)
+	if err != nil {
+		h.handleChangelogError(c, err, "Failed to create product update")
+		return
+	}
+
+	c.JSON(http.StatusCreated, update)
+}
+
+// GetProductUpdate handles getting a product update including drafts (admin only)
+// @Summary Get product update
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Product update ID"
+// @Success 200 {object} models.ProductUpdate
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/admin/product-updates/{id} [get]
+func (h *ChangelogHandler) GetProductUpdate(c *gin.Context) {
+	updateID, ok := h.updateID(c)
+	if !ok {
+		return
+	}
+
+	update, err := h.changelog.Get(updateID)
+	if err != nil {
+		h.handleChangelogError(c, err, "Failed to fetch product update")
+		return
+	}
+
+	c.JSON(http.StatusOK, update)
+}
+
+// UpdateProductUpdate handles changing a product update (admin only)
+// @Summary Update product update
+// @Description Replace the fields of a product update; removing the publish time turns it back into a draft
+// @Tags admin
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Product update ID"
+// @Param request body changelog.Input true "Product update"
+// @Success 200 {object} models.ProductUpdate
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/admin/product-updates/{id} [put]
+func (h *ChangelogHandler) UpdateProductUpdate(c *gin.Context) {
+	userID, ok := middleware.GetCurrentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "User not authenticated")})
+		return
+	}
+	updateID, ok := h.updateID(c)
+	if !ok {
+		return
+	}
+
+	var req changelog.Input
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid request data"), "details": err.Error()})
+		return
+	}
+
+	update, err := h.changelog.Update(updateID, req, userID)
+	if err != nil {
+		h.handleChangelogError(c, err, "Failed to update product update")
+		return
+	}
+
+	c.JSON(http.StatusOK, update)
+}
+
+// DeleteProductUpdate handles removing a product update (admin only)
+// @Summary Delete product update
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Product update ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/admin/product-updates/{id} [delete]
+func (h *ChangelogHandler) DeleteProductUpdate(c *gin.Context) {
+	updateID, ok := h.updateID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.changelog.Delete(updateID); err != nil {
+		h.handleChangelogError(c, err, "Failed to delete product update")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": middleware.T(c, "Product update deleted")})
+}
+
+func (h *ChangelogHandler) updateID(c *gin.Context) (uuid.UUID, bool) {
+	updateID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid product update ID")})
+		return uuid.Nil, false
+	}
+	return updateID, true
+}
+
+func (h *ChangelogHandler) handleChangelogError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, changelog.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "Product update not found")})
+	case errors.Is(err, changelog.ErrInvalidKind):
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid product update kind")})
+	case errors.Is(err, changelog.ErrInvalidAudience):
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid product update audience")})
+	case errors.Is(err, changelog.ErrInvalidWindow):
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Maintenance windows need a start before their end")})
+	default:
+		h.logger.Error(message, zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, message)})
+	}
+}
@@ -0,0 +1,254 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"elterngeld-portal/internal/chatnotify"
+	"elterngeld-portal/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type ChatNotificationHandler struct {
+	db       *gorm.DB
+	logger   *zap.Logger
+	notifier *chatnotify.Notifier
+}
+
+func NewChatNotificationHandler(db *gorm.DB, logger *zap.Logger, notifier *chatnotify.Notifier) *ChatNotificationHandler {
+	return &ChatNotificationHandler{
+		db:       db,
+		logger:   logger,
+		notifier: notifier,
+	}
+}
+
+// ListChatChannels handles listing Slack and Teams channels (admin only)
+// @Summary List chat channels
+// @Description Get the Slack and Microsoft Teams channels with their routing rules
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/admin/chat-channels [get]
+func (h *ChatNotificationHandler) ListChatChannels(c *gin.Context) {
+	channels, err := h.notifier.ListChannels()
+	if err != nil {
+		h.logger.Error("Failed to fetch chat channels", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to fetch chat channels")})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"chat_channels": channels})
+}
+
+// CreateChatChannel handles adding a Slack or Teams incoming webhook (admin only)
+// @Summary Create chat channel
+// @Description Add a Slack or Microsoft Teams incoming webhook for notifications
+// @Tags admin
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body chatnotify.ChannelInput true "Chat channel"
+// @Success 201 {object} models.ChatChannel
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/v1/admin/chat-channels [post]
+func (h *ChatNotificationHandler) CreateChatChannel(c *gin.Context) {
+	var req chatnotify.ChannelInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid request data"), "details": err.Error()})
+		return
+	}
+
+	channel, err := h.notifier.CreateChannel(req)
+	if err != nil {
+		h.handleChatError(c, err, "Failed to create chat channel")
+		return
+	}
+
+	h.logger.Info("Chat channel created",
+		zap.String("channel_id", channel.ID.String()),
+		zap.String("provider", string(channel.Provider)))
+
+	c.JSON(http.StatusCreated, channel)
+}
+
+// UpdateChatChannel handles changing a chat channel (admin only)
+// @Summary Update chat channel
+// @Description Change the name, provider, webhook URL or status of a chat channel
+// @Tags admin
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Chat channel ID"
+// @Param request body chatnotify.ChannelInput true "Chat channel"
+// @Success 200 {object} models.ChatChannel
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/admin/chat-channels/{id} [put]
+func (h *ChatNotificationHandler) UpdateChatChannel(c *gin.Context) {
+	channelID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid chat channel ID")})
+		return
+	}
+
+	var req chatnotify.ChannelInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid request data"), "details": err.Error()})
+		return
+	}
+
+	channel, err := h.notifier.UpdateChannel(channelID, req)
+	if err != nil {
+		h.handleChatError(c, err, "Failed to update chat channel")
+		return
+	}
+
+	c.JSON(http.StatusOK, channel)
+}
+
+// DeleteChatChannel handles removing a chat channel (admin only)
+// @Summary Delete chat channel
+// @Description Remove a chat channel together with its routing rules
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Chat channel ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/admin/chat-channels/{id} [delete]
+func (h *ChatNotificationHandler) DeleteChatChannel(c *gin.Context) {
+	channelID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid chat channel ID")})
+		return
+	}
+
+	if err := h.notifier.DeleteChannel(channelID); err != nil {
+		h.handleChatError(c, err, "Failed to delete chat channel")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": middleware.T(c, "Chat channel deleted")})
+}
+
+// AddChatRoutingRule handles routing an event to a chat channel (admin only)
+// @Summary Add routing rule
+// @Description Post an event (lead.created, booking.paid, lead.sla_breached) to a chat channel, optionally only from a minimum priority or for one Berater
+// @Tags admin
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Chat channel ID"
+// @Param request body chatnotify.RuleInput true "Routing rule"
+// @Success 201 {object} models.ChatRoutingRule
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/admin/chat-channels/{id}/rules [post]
+func (h *ChatNotificationHandler) AddChatRoutingRule(c *gin.Context) {
+	channelID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid chat channel ID")})
+		return
+	}
+
+	var req chatnotify.RuleInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid request data"), "details": err.Error()})
+		return
+	}
+
+	rule, err := h.notifier.AddRule(channelID, req)
+	if err != nil {
+		h.handleChatError(c, err, "Failed to create routing rule")
+		return
+	}
+
+	c.JSON(http.StatusCreated, rule)
+}
+
+// DeleteChatRoutingRule handles removing a routing rule (admin only)
+// @Summary Delete routing rule
+// @Description Stop posting an event to a chat channel
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Chat channel ID"
+// @Param rule_id path string true "Routing rule ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/admin/chat-channels/{id}/rules/{rule_id} [delete]
+func (h *ChatNotificationHandler) DeleteChatRoutingRule(c *gin.Context) {
+	channelID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid chat channel ID")})
+		return
+	}
+	ruleID, err := uuid.Parse(c.Param("rule_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid routing rule ID")})
+		return
+	}
+
+	if err := h.notifier.DeleteRule(channelID, ruleID); err != nil {
+		h.handleChatError(c, err, "Failed to delete routing rule")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": middleware.T(c, "Routing rule deleted")})
+}
+
+// TestChatChannel handles posting a test message to a chat channel (admin only)
+// @Summary Test chat channel
+// @Description Post a test message to check the webhook of a chat channel
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Chat channel ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 502 {object} map[string]interface{}
+// @Router /api/v1/admin/chat-channels/{id}/test [post]
+func (h *ChatNotificationHandler) TestChatChannel(c *gin.Context) {
+	channelID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid chat channel ID")})
+		return
+	}
+
+	if err := h.notifier.Test(channelID); err != nil {
+		if errors.Is(err, chatnotify.ErrChannelNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "Chat channel not found")})
+			return
+		}
+		c.JSON(http.StatusBadGateway, gin.H{"error": middleware.T(c, "Test message could not be delivered"), "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": middleware.T(c, "Test message sent")})
+}
+
+func (h *ChatNotificationHandler) handleChatError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, chatnotify.ErrChannelNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "Chat channel not found")})
+	case errors.Is(err, chatnotify.ErrRuleNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "Routing rule not found")})
+	case errors.Is(err, chatnotify.ErrInvalidWebhookURL):
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid webhook URL for this provider")})
+	case errors.Is(err, chatnotify.ErrInvalidProvider):
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid chat provider")})
+	case errors.Is(err, chatnotify.ErrInvalidEvent):
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid event")})
+	case errors.Is(err, chatnotify.ErrInvalidPriority):
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid priority")})
+	default:
+		h.logger.Error(message, zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, message)})
+	}
+}
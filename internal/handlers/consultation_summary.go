@@ -0,0 +1,209 @@
+package handlers
+
+import (
+	"errors"
+	"mime"
+	"net/http"
+
+	"elterngeld-portal/internal/middleware"
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/internal/summaries"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type ConsultationSummaryHandler struct {
+	db        *gorm.DB
+	logger    *zap.Logger
+	summaries *summaries.Service
+}
+
+func NewConsultationSummaryHandler(db *gorm.DB, logger *zap.Logger, summaryService *summaries.Service) *ConsultationSummaryHandler {
+	return &ConsultationSummaryHandler{
+		db:        db,
+		logger:    logger,
+		summaries: summaryService,
+	}
+}
+
+// SaveSummaryRequest is the consultation summary written by the Berater
+type SaveSummaryRequest struct {
+	Topics          []string `json:"topics" binding:"max=20,dive,max=2000"`
+	Recommendations []string `json:"recommendations" binding:"max=20,dive,max=2000"`
+	NextSteps       []string `json:"next_steps" binding:"max=20,dive,max=2000"`
+	Shared          bool     `json:"shared"` // show the summary to the customer
+}
+
+// SaveSummary handles writing the summary of a consultation (Berater of the booking or admin)
+// @Summary Save consultation summary
+// @Description Create or replace the summary of a consultation: topics covered, recommendations and next steps. Shared summaries appear in the customer's dashboard and can be downloaded as PDF.
+// @Tags bookings
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Booking ID"
+// @Param request body SaveSummaryRequest true "Summary"
+// @Success 200 {object} models.ConsultationSummaryResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/v1/bookings/{id}/summary [put]
+func (h *ConsultationSummaryHandler) SaveSummary(c *gin.Context) {
+	user, bookingID, ok := h.requestContext(c)
+	if !ok {
+		return
+	}
+
+	var req SaveSummaryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid request data"), "details": err.Error()})
+		return
+	}
+
+	summary, err := h.summaries.Save(bookingID, user, summaries.Input{
+		Topics:          req.Topics,
+		Recommendations: req.Recommendations,
+		NextSteps:       req.NextSteps,
+		Shared:          req.Shared,
+	})
+	if err != nil {
+		h.handleSummaryError(c, err, "Failed to save consultation summary")
+		return
+	}
+
+	c.JSON(http.StatusOK, summary.ToResponse())
+}
+
+// GetSummary handles reading the summary of a consultation
+// @Summary Get consultation summary
+// @Description Get the summary of a consultation. Customers only see summaries shared with them.
+// @Tags bookings
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Booking ID"
+// @Success 200 {object} models.ConsultationSummaryResponse
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/bookings/{id}/summary [get]
+func (h *ConsultationSummaryHandler) GetSummary(c *gin.Context) {
+	user, bookingID, ok := h.requestContext(c)
+	if !ok {
+		return
+	}
+
+	summary, err := h.summaries.Get(bookingID, user)
+	if err != nil {
+		h.handleSummaryError(c, err, "Failed to fetch consultation summary")
+		return
+	}
+
+	c.JSON(http.StatusOK, summary.ToResponse())
+}
+
+// DownloadSummary handles downloading the summary of a consultation as PDF
+// @Summary Download consultation summary
+// @Description Download the summary of a consultation as PDF. Customers only get summaries shared with them.
+// @Tags bookings
+// @Security BearerAuth
+// @Produce application/pdf
+// @Param id path string true "Booking ID"
+// @Success 200 {file} binary
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/bookings/{id}/summary/pdf [get]
+func (h *ConsultationSummaryHandler) DownloadSummary(c *gin.Context) {
+	user, bookingID, ok := h.requestContext(c)
+	if !ok {
+		return
+	}
+
+	summary, err := h.summaries.Get(bookingID, user)
+	if err != nil {
+		h.handleSummaryError(c, err, "Failed to fetch consultation summary")
+		return
+	}
+
+	fileName := "beratungsprotokoll.pdf"
+	if summary.Booking != nil && summary.Booking.BookingReference != "" {
+		fileName = "beratungsprotokoll-" + summary.Booking.BookingReference + ".pdf"
+	}
+
+	// Summaries contain personal data and must not be cached by shared proxies
+	c.Header("Cache-Control", "private, no-store")
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": fileName}))
+	c.Data(http.StatusOK, "application/pdf", summaries.RenderPDF(summary, user.TimeLocation()))
+}
+
+// ListMySummaries handles listing the consultation summaries shared with the current customer
+// @Summary List my consultation summaries
+// @Description Consultation summaries shared with the current user, latest consultation first, for the customer dashboard
+// @Tags bookings
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/consultation-summaries [get]
+func (h *ConsultationSummaryHandler) ListMySummaries(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "User not authenticated")})
+		return
+	}
+
+	shared, err := h.summaries.ListShared(userID)
+	if err != nil {
+		h.handleSummaryError(c, err, "Failed to fetch consultation summaries")
+		return
+	}
+
+	responses := make([]models.ConsultationSummaryResponse, len(shared))
+	for i := range shared {
+		responses[i] = shared[i].ToResponse()
+	}
+
+	c.JSON(http.StatusOK, gin.H{"summaries": responses})
+}
+
+// requestContext loads the current user and parses the booking ID; it writes the error response if either fails
+func (h *ConsultationSummaryHandler) requestContext(c *gin.Context) (*models.User, uuid.UUID, bool) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "User not authenticated")})
+		return nil, uuid.Nil, false
+	}
+
+	bookingID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid booking ID")})
+		return nil, uuid.Nil, false
+	}
+
+	var user models.User
+	if err := h.db.First(&user, "id = ?", userID).Error; err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "User not found")})
+		return nil, uuid.Nil, false
+	}
+
+	return &user, bookingID, true
+}
+
+func (h *ConsultationSummaryHandler) handleSummaryError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, summaries.ErrBookingNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "Booking not found")})
+	case errors.Is(err, summaries.ErrSummaryNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "Consultation summary not found")})
+	case errors.Is(err, summaries.ErrAccessDenied):
+		c.JSON(http.StatusForbidden, gin.H{"error": middleware.T(c, "Access denied")})
+	case errors.Is(err, summaries.ErrNotHeld):
+		c.JSON(http.StatusConflict, gin.H{"error": middleware.T(c, "Summaries can only be written for consultations that took place")})
+	case errors.Is(err, summaries.ErrEmptySummary):
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "The summary needs at least one topic, recommendation or next step")})
+	default:
+		h.logger.Error(message, zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, message)})
+	}
+}
@@ -1,10 +1,17 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"time"
 
+	"elterngeld-portal/internal/abuse"
+	"elterngeld-portal/internal/activitylog"
+	"elterngeld-portal/internal/inbox"
+	"elterngeld-portal/internal/middleware"
 	"elterngeld-portal/internal/models"
+	"elterngeld-portal/internal/outbox"
+	"elterngeld-portal/internal/questionnaires"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -13,14 +20,24 @@ import (
 )
 
 type ContactHandler struct {
-	db     *gorm.DB
-	logger *zap.Logger
+	db         *gorm.DB
+	logger     *zap.Logger
+	activities *activitylog.Service
+	outbox     *outbox.Service
+	abuse      *abuse.Service
+	intake     *questionnaires.Service
+	inbox      *inbox.Service
 }
 
-func NewContactHandler(db *gorm.DB, logger *zap.Logger) *ContactHandler {
+func NewContactHandler(db *gorm.DB, logger *zap.Logger, activityLog *activitylog.Service, outboxService *outbox.Service, abuseService *abuse.Service, questionnaireService *questionnaires.Service, inboxService *inbox.Service) *ContactHandler {
 	return &ContactHandler{
-		db:     db,
-		logger: logger,
+		db:         db,
+		logger:     logger,
+		activities: activityLog,
+		outbox:     outboxService,
+		abuse:      abuseService,
+		intake:     questionnaireService,
+		inbox:      inboxService,
 	}
 }
 
@@ -54,6 +71,9 @@ type PreTalkBookingRequest struct {
 	TimeslotID   uuid.UUID  `json:"timeslot_id" binding:"required"`
 	Message      string     `json:"message,omitempty"`
 	
+	// Intake answers, scored to prioritize the lead and recommend a package
+	Questionnaire *questionnaires.SubmitInput `json:"questionnaire,omitempty"`
+	
 	// UTM tracking
 	UTMSource    string `json:"utm_source,omitempty"`
 	UTMCampaign  string `json:"utm_campaign,omitempty"`
@@ -62,7 +82,7 @@ type PreTalkBookingRequest struct {
 
 // SubmitContactForm handles contact form submissions
 // @Summary Submit contact form
-// @Description Submit a contact form (creates a lead automatically)
+// @Description Submit a contact form (creates a lead automatically). Suspicious submissions are held for review and only become a lead once approved.
 // @Tags contact
 // @Accept json
 // @Produce json
@@ -74,7 +94,7 @@ func (h *ContactHandler) SubmitContactForm(c *gin.Context) {
 	var req ContactFormRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		h.logger.Error("Invalid contact form request", zap.Error(err))
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data", "details": err.Error()})
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid request data"), "details": err.Error()})
 		return
 	}
 
@@ -102,6 +122,19 @@ func (h *ContactHandler) SubmitContactForm(c *gin.Context) {
 		userID = &existingUser.ID
 	}
 
+	// Website submissions are scored for abuse; partner systems sending
+	// forms with a service key are trusted. A failed check does not lose the
+	// submission.
+	var check *models.SubmissionCheck
+	if c.GetString("api_key_name") == "" {
+		assessed, err := h.abuse.Assess(models.SubmissionEndpointContact, c.ClientIP(), req.Email)
+		if err != nil {
+			h.logger.Error("Failed to check contact form for abuse", zap.Error(err))
+		} else {
+			check = assessed
+		}
+	}
+
 	// Start database transaction
 	tx := h.db.Begin()
 	defer func() {
@@ -121,14 +154,14 @@ func (h *ContactHandler) SubmitContactForm(c *gin.Context) {
 		Subject:          req.Subject,
 		Message:          req.Message,
 		PreferredDate:    req.PreferredDate,
-		UTMSource:        req.UTMSource,
-		UTMCampaign:      req.UTMCampaign,
-		UTMMedium:        req.UTMMedium,
-		UTMTerm:          req.UTMTerm,
-		UTMContent:       req.UTMContent,
-		PageURL:          req.PageURL,
-		Referrer:         req.Referrer,
-		Status:           models.ContactFormStatusNew,
+		UtmSource:        req.UTMSource,
+		UtmCampaign:      req.UTMCampaign,
+		UtmMedium:        req.UTMMedium,
+		UtmTerm:          req.UTMTerm,
+		UtmContent:       req.UTMContent,
+		URL:              req.PageURL,
+		UserAgent:        c.Request.UserAgent(),
+		IPAddress:        c.ClientIP(),
 		CreatedAt:        time.Now(),
 		UpdatedAt:        time.Now(),
 	}
@@ -136,91 +169,48 @@ func (h *ContactHandler) SubmitContactForm(c *gin.Context) {
 	if err := tx.Create(&contactForm).Error; err != nil {
 		tx.Rollback()
 		h.logger.Error("Failed to create contact form", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save contact form"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to save contact form")})
 		return
 	}
 
-	// Create associated lead
-	leadTitle := "Contact Form: " + req.Subject
-	leadDescription := "Contact form submission from " + req.Name + "\n\n" + req.Message
-
-	// Determine lead source
-	var leadSource models.LeadSource = models.LeadSourceWebsite
-	if req.UTMSource != "" {
-		switch req.UTMSource {
-		case "google":
-			leadSource = models.LeadSourceGoogle
-		case "facebook":
-			leadSource = models.LeadSourceSocial
-		case "email":
-			leadSource = models.LeadSourceEmail
-		default:
-			leadSource = models.LeadSourceWebsite
+	if check != nil {
+		check.ContactFormID = &contactForm.ID
+		if err := h.abuse.Record(tx, check); err != nil {
+			tx.Rollback()
+			h.logger.Error("Failed to record contact form check", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to process contact form")})
+			return
 		}
 	}
 
-	lead := models.Lead{
-		ID:               uuid.New(),
-		UserID:           userID,
-		ContactFormID:    &contactForm.ID,
-		Source:           leadSource,
-		Status:           models.LeadStatusNew,
-		Priority:         models.LeadPriorityMedium,
-		Title:            leadTitle,
-		Description:      leadDescription,
-		CompanyName:      req.Company,
-		ContactEmail:     req.Email,
-		ContactPhone:     req.Phone,
-		UTMSource:        req.UTMSource,
-		UTMCampaign:      req.UTMCampaign,
-		UTMMedium:        req.UTMMedium,
-		UTMTerm:          req.UTMTerm,
-		UTMContent:       req.UTMContent,
-		FollowUpDate:     req.PreferredDate,
-		CreatedAt:        time.Now(),
-		UpdatedAt:        time.Now(),
-	}
+	// Flagged submissions wait in the review queue without a lead
+	if check != nil && check.IsFlagged() {
+		if err := tx.Commit().Error; err != nil {
+			h.logger.Error("Failed to commit contact form transaction", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to process contact form")})
+			return
+		}
 
-	if err := tx.Create(&lead).Error; err != nil {
-		tx.Rollback()
-		h.logger.Error("Failed to create lead from contact form", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process contact form"})
+		c.JSON(http.StatusCreated, gin.H{
+			"message":          "Contact form submitted successfully",
+			"contact_form_id":  contactForm.ID,
+			"reference_number": "CF-" + contactForm.ID.String()[:8],
+		})
 		return
 	}
 
-	// Update contact form with lead ID
-	contactForm.LeadID = &lead.ID
-	if err := tx.Save(&contactForm).Error; err != nil {
+	lead, err := h.inbox.CreateContactFormLead(tx, &contactForm)
+	if err != nil {
 		tx.Rollback()
-		h.logger.Error("Failed to update contact form with lead ID", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process contact form"})
+		h.logger.Error("Failed to create lead from contact form", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to process contact form")})
 		return
 	}
 
-	// Create activity log
-	var activityUserID uuid.UUID
-	if userID != nil {
-		activityUserID = *userID
-	} else {
-		// Create a system activity for anonymous submissions
-		activityUserID = uuid.New() // This should be a system user ID in production
-	}
-
-	activity := models.Activity{
-		ID:          uuid.New(),
-		UserID:      activityUserID,
-		LeadID:      &lead.ID,
-		Type:        models.ActivityTypeLeadCreated,
-		Description: "Lead created from contact form submission",
-		Metadata:    "Contact form ID: " + contactForm.ID.String(),
-		CreatedAt:   time.Now(),
-	}
-	tx.Create(&activity)
-
 	// Commit transaction
 	if err := tx.Commit().Error; err != nil {
 		h.logger.Error("Failed to commit contact form transaction", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process contact form"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to process contact form")})
 		return
 	}
 
@@ -254,7 +244,7 @@ func (h *ContactHandler) BookPreTalk(c *gin.Context) {
 	var req PreTalkBookingRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		h.logger.Error("Invalid pre-talk booking request", zap.Error(err))
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data", "details": err.Error()})
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid request data"), "details": err.Error()})
 		return
 	}
 
@@ -268,12 +258,12 @@ func (h *ContactHandler) BookPreTalk(c *gin.Context) {
 
 	// Verify timeslot exists and is available
 	var timeslot models.Timeslot
-	if err := h.db.Where("id = ? AND is_available = ?", req.TimeslotID, true).First(&timeslot).Error; err != nil {
+	if err := h.db.Where("id = ? AND is_available = ? AND purpose = ?", req.TimeslotID, true, models.TimeslotPurposeConsultation).First(&timeslot).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Timeslot not found or not available"})
+			c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Timeslot not found or not available")})
 		} else {
 			h.logger.Error("Failed to fetch timeslot", zap.Error(err))
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify timeslot"})
+			c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to verify timeslot")})
 		}
 		return
 	}
@@ -284,7 +274,7 @@ func (h *ContactHandler) BookPreTalk(c *gin.Context) {
 		timeslot.ID, []string{"cancelled", "completed"}).Count(&bookingCount)
 	
 	if bookingCount >= int64(timeslot.MaxBookings) {
-		c.JSON(http.StatusConflict, gin.H{"error": "Timeslot is no longer available"})
+		c.JSON(http.StatusConflict, gin.H{"error": middleware.T(c, "Timeslot is no longer available")})
 		return
 	}
 
@@ -298,8 +288,8 @@ func (h *ContactHandler) BookPreTalk(c *gin.Context) {
 
 	// Find free consultation package
 	var preTalkPackage models.Package
-	if err := tx.Where("type = ? AND name ILIKE ? AND price = ?", 
-		models.PackageTypeService, "%vorgespräch%", 0.0).First(&preTalkPackage).Error; err != nil {
+	if err := tx.Where("is_active = ? AND LOWER(name) LIKE ? AND price = ?", 
+		true, "%vorgespräch%", 0.0).First(&preTalkPackage).Error; err != nil {
 		// If no free package exists, create a placeholder
 		h.logger.Warn("No free consultation package found, using placeholder")
 	}
@@ -333,7 +323,7 @@ func (h *ContactHandler) BookPreTalk(c *gin.Context) {
 	if err := tx.Create(&booking).Error; err != nil {
 		tx.Rollback()
 		h.logger.Error("Failed to create pre-talk booking", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create booking"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to create booking")})
 		return
 	}
 
@@ -365,7 +355,7 @@ func (h *ContactHandler) BookPreTalk(c *gin.Context) {
 	if err := tx.Create(&lead).Error; err != nil {
 		tx.Rollback()
 		h.logger.Error("Failed to create lead from pre-talk booking", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process booking"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to process booking")})
 		return
 	}
 
@@ -374,33 +364,48 @@ func (h *ContactHandler) BookPreTalk(c *gin.Context) {
 	if err := tx.Save(&booking).Error; err != nil {
 		tx.Rollback()
 		h.logger.Error("Failed to update booking with lead ID", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process booking"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to process booking")})
 		return
 	}
 
-	// Create activity log
-	var activityUserID uuid.UUID
-	if userID != nil {
-		activityUserID = *userID
-	} else {
-		activityUserID = uuid.New() // System user for anonymous bookings
+	if req.Questionnaire != nil {
+		if _, err := h.intake.SubmitPreTalk(tx, lead.ID, userID, *req.Questionnaire); err != nil {
+			tx.Rollback()
+			switch {
+			case errors.Is(err, questionnaires.ErrNotFound):
+				c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "Questionnaire not found")})
+			case errors.Is(err, questionnaires.ErrInvalidAnswers):
+				c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid answers"), "details": err.Error()})
+			default:
+				h.logger.Error("Failed to save pre-talk questionnaire", zap.Error(err))
+				c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to process booking")})
+			}
+			return
+		}
+	}
+
+	// Anonymous bookings are recorded without an actor
+	if err := h.activities.Record(tx,
+		activitylog.LeadCreated{ActorID: userID, LeadID: lead.ID, Title: lead.Title, Source: lead.Source},
+		activitylog.BookingCreated{ActorID: userID, LeadID: lead.ID, BookingID: booking.ID, Reference: booking.BookingReference},
+	); err != nil {
+		tx.Rollback()
+		h.logger.Error("Failed to record pre-talk booking activity", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to process booking")})
+		return
 	}
 
-	activity := models.Activity{
-		ID:          uuid.New(),
-		UserID:      activityUserID,
-		LeadID:      &lead.ID,
-		Type:        models.ActivityTypeBookingCreated,
-		Description: "Free consultation booking created",
-		Metadata:    "Booking ref: " + booking.BookingReference,
-		CreatedAt:   time.Now(),
+	if err := h.outbox.Enqueue(tx, outbox.TopicChatLeadCreated, lead.ID.String(), outbox.LeadMessage{LeadID: lead.ID}); err != nil {
+		tx.Rollback()
+		h.logger.Error("Failed to queue pre-talk booking notification", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to process booking")})
+		return
 	}
-	tx.Create(&activity)
 
 	// Commit transaction
 	if err := tx.Commit().Error; err != nil {
 		h.logger.Error("Failed to commit pre-talk booking transaction", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process booking"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to process booking")})
 		return
 	}
 
@@ -438,13 +443,13 @@ func (h *ContactHandler) BookPreTalk(c *gin.Context) {
 func (h *ContactHandler) GetContactForms(c *gin.Context) {
 	userRole, exists := c.Get("user_role")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "User not authenticated")})
 		return
 	}
 
 	// Only beraters and admins can view contact forms
 	if userRole != "berater" && userRole != "junior_berater" && userRole != "admin" {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+		c.JSON(http.StatusForbidden, gin.H{"error": middleware.T(c, "Insufficient permissions")})
 		return
 	}
 
@@ -480,7 +485,7 @@ func (h *ContactHandler) GetContactForms(c *gin.Context) {
 	if err := query.Preload("User").Preload("Lead").
 		Offset(offset).Limit(limit).Order("created_at DESC").Find(&contactForms).Error; err != nil {
 		h.logger.Error("Failed to fetch contact forms", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch contact forms"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to fetch contact forms")})
 		return
 	}
 
@@ -513,13 +518,13 @@ func (h *ContactHandler) GetContactForms(c *gin.Context) {
 func (h *ContactHandler) UpdateContactFormStatus(c *gin.Context) {
 	userRole, exists := c.Get("user_role")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "User not authenticated")})
 		return
 	}
 
 	// Only beraters and admins can update contact form status
 	if userRole != "berater" && userRole != "junior_berater" && userRole != "admin" {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+		c.JSON(http.StatusForbidden, gin.H{"error": middleware.T(c, "Insufficient permissions")})
 		return
 	}
 
@@ -528,29 +533,29 @@ func (h *ContactHandler) UpdateContactFormStatus(c *gin.Context) {
 	var contactForm models.ContactForm
 	if err := h.db.Where("id = ?", contactFormID).First(&contactForm).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Contact form not found"})
+			c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "Contact form not found")})
 		} else {
 			h.logger.Error("Failed to fetch contact form", zap.Error(err))
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch contact form"})
+			c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to fetch contact form")})
 		}
 		return
 	}
 
 	var req map[string]interface{}
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid request data")})
 		return
 	}
 
 	newStatus, exists := req["status"]
 	if !exists {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Status is required"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Status is required")})
 		return
 	}
 
 	statusStr, ok := newStatus.(string)
 	if !ok {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status format"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid status format")})
 		return
 	}
 
@@ -565,7 +570,7 @@ func (h *ContactHandler) UpdateContactFormStatus(c *gin.Context) {
 	}
 
 	if !isValid {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid status")})
 		return
 	}
 
@@ -581,7 +586,7 @@ func (h *ContactHandler) UpdateContactFormStatus(c *gin.Context) {
 
 	if err := h.db.Save(&contactForm).Error; err != nil {
 		h.logger.Error("Failed to update contact form status", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update status"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to update status")})
 		return
 	}
 
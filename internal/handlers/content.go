@@ -0,0 +1,316 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"elterngeld-portal/internal/content"
+	"elterngeld-portal/internal/middleware"
+	"elterngeld-portal/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type ContentHandler struct {
+	db      *gorm.DB
+	logger  *zap.Logger
+	content *content.Service
+}
+
+func NewContentHandler(db *gorm.DB, logger *zap.Logger, contentService *content.Service) *ContentHandler {
+	return &ContentHandler{
+		db:      db,
+		logger:  logger,
+		content: contentService,
+	}
+}
+
+// ListPublishedContent handles listing the published FAQ entries, info pages or Bundesland guides
+// @Summary List published content
+// @Description Get the published entries of a kind in display order, e.g. the FAQ grouped by category
+// @Tags content
+// @Produce json
+// @Param kind path string true "Content kind: faq, page or guide"
+// @Param category query string false "Filter by category"
+// @Param bundesland query string false "Filter guides by Bundesland code, e.g. BY"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/v1/content/{kind} [get]
+func (h *ContentHandler) ListPublishedContent(c *gin.Context) {
+	entries, err := h.content.Published(content.Filter{
+		Kind:       models.ContentKind(c.Param("kind")),
+		Category:   c.Query("category"),
+		Bundesland: models.Bundesland(c.Query("bundesland")),
+	})
+	if err != nil {
+		h.handleContentError(c, err, "Failed to fetch content")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"entries": entries})
+}
+
+// GetPublishedContent handles getting a published entry by its slug
+// @Summary Get published content
+// @Tags content
+// @Produce json
+// @Param kind path string true "Content kind: faq, page or guide"
+// @Param slug path string true "Slug"
+// @Success 200 {object} models.ContentEntry
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/content/{kind}/{slug} [get]
+func (h *ContentHandler) GetPublishedContent(c *gin.Context) {
+	entry, err := h.content.PublishedBySlug(models.ContentKind(c.Param("kind")), c.Param("slug"))
+	if err != nil {
+		h.handleContentError(c, err, "Failed to fetch content")
+		return
+	}
+
+	middleware.SetLastModified(c, entry.UpdatedAt)
+	c.JSON(http.StatusOK, entry)
+}
+
+// ListContent handles listing content entries including drafts (admin only)
+// @Summary List content entries
+// @Description Get FAQ entries, info pages and Bundesland guides, latest changes first
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Param page query int false "Page number"
+// @Param limit query int false "Items per page"
+// @Param kind query string false "Filter by kind: faq, page or guide"
+// @Param status query string false "Filter by status: draft or published"
+// @Param category query string false "Filter by category"
+// @Param bundesland query string false "Filter by Bundesland code"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/admin/content [get]
+func (h *ContentHandler) ListContent(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(content.DefaultLimit)))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > content.MaxLimit {
+		limit = content.DefaultLimit
+	}
+
+	entries, total, err := h.content.List(content.Filter{
+		Kind:       models.ContentKind(c.Query("kind")),
+		Status:     models.ContentStatus(c.Query("status")),
+		Category:   c.Query("category"),
+		Bundesland: models.Bundesland(c.Query("bundesland")),
+	}, page, limit)
+	if err != nil {
+		h.logger.Error("Failed to fetch content", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to fetch content")})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"entries": entries,
+		"pagination": gin.H{
+			"page":  page,
+			"limit": limit,
+			"total": total,
+			"pages": (total + int64(limit) - 1) / int64(limit),
+		},
+	})
+}
+
+// CreateContent handles adding a draft content entry (admin only)
+// @Summary Create content entry
+// @Description Add an FAQ entry, info page or Bundesland guide as draft. Without a slug one is generated from the title.
+// @Tags admin
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body content.EntryInput true "Content entry"
+// @Success 201 {object} models.ContentEntry
+// @Failure 400 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/v1/admin/content [post]
+func (h *ContentHandler) CreateContent(c *gin.Context) {
+	userID, ok := middleware.GetCurrentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "User not authenticated")})
+		return
+	}
+
+	var req content.EntryInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid request data"), "details": err.Error()})
+		return
+	}
+
+	entry, err := h.content.Create(req, userID)
+	if err != nil {
+		h.handleContentError(c, err, "Failed to create content")
+		return
+	}
+
+	c.JSON(http.StatusCreated, entry)
+}
+
+// GetContent handles getting a content entry including drafts (admin only)
+// @Summary Get content entry
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Content entry ID"
+// @Success 200 {object} models.ContentEntry
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/admin/content/{id} [get]
+func (h *ContentHandler) GetContent(c *gin.Context) {
+	entryID, ok := h.entryID(c)
+	if !ok {
+		return
+	}
+
+	entry, err := h.content.Get(entryID)
+	if err != nil {
+		h.handleContentError(c, err, "Failed to fetch content")
+		return
+	}
+
+	c.JSON(http.StatusOK, entry)
+}
+
+// UpdateContent handles changing a content entry (admin only)
+// @Summary Update content entry
+// @Description Replace the fields of an entry. Changes to published entries are public right away.
+// @Tags admin
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Content entry ID"
+// @Param request body content.EntryInput true "Content entry"
+// @Success 200 {object} models.ContentEntry
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/v1/admin/content/{id} [put]
+func (h *ContentHandler) UpdateContent(c *gin.Context) {
+	userID, ok := middleware.GetCurrentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "User not authenticated")})
+		return
+	}
+	entryID, ok := h.entryID(c)
+	if !ok {
+		return
+	}
+
+	var req content.EntryInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid request data"), "details": err.Error()})
+		return
+	}
+
+	entry, err := h.content.Update(entryID, req, userID)
+	if err != nil {
+		h.handleContentError(c, err, "Failed to update content")
+		return
+	}
+
+	c.JSON(http.StatusOK, entry)
+}
+
+// PublishContent handles making a content entry public (admin only)
+// @Summary Publish content entry
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Content entry ID"
+// @Success 200 {object} models.ContentEntry
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/admin/content/{id}/publish [post]
+func (h *ContentHandler) PublishContent(c *gin.Context) {
+	h.changeStatus(c, h.content.Publish, "Failed to publish content")
+}
+
+// UnpublishContent handles turning a content entry back into a draft (admin only)
+// @Summary Unpublish content entry
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Content entry ID"
+// @Success 200 {object} models.ContentEntry
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/admin/content/{id}/unpublish [post]
+func (h *ContentHandler) UnpublishContent(c *gin.Context) {
+	h.changeStatus(c, h.content.Unpublish, "Failed to unpublish content")
+}
+
+// DeleteContent handles removing a content entry (admin only)
+// @Summary Delete content entry
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Content entry ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/admin/content/{id} [delete]
+func (h *ContentHandler) DeleteContent(c *gin.Context) {
+	entryID, ok := h.entryID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.content.Delete(entryID); err != nil {
+		h.handleContentError(c, err, "Failed to delete content")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": middleware.T(c, "Content deleted successfully")})
+}
+
+func (h *ContentHandler) changeStatus(c *gin.Context, change func(id, userID uuid.UUID) (*models.ContentEntry, error), message string) {
+	userID, ok := middleware.GetCurrentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "User not authenticated")})
+		return
+	}
+	entryID, ok := h.entryID(c)
+	if !ok {
+		return
+	}
+
+	entry, err := change(entryID, userID)
+	if err != nil {
+		h.handleContentError(c, err, message)
+		return
+	}
+
+	c.JSON(http.StatusOK, entry)
+}
+
+func (h *ContentHandler) entryID(c *gin.Context) (uuid.UUID, bool) {
+	entryID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid content ID")})
+		return uuid.Nil, false
+	}
+	return entryID, true
+}
+
+func (h *ContentHandler) handleContentError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, content.ErrEntryNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "Content not found")})
+	case errors.Is(err, content.ErrInvalidKind):
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid content kind")})
+	case errors.Is(err, content.ErrInvalidSlug):
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Slug may only contain lowercase letters, digits and dashes")})
+	case errors.Is(err, content.ErrDuplicateSlug):
+		c.JSON(http.StatusConflict, gin.H{"error": middleware.T(c, "Slug already exists")})
+	case errors.Is(err, content.ErrInvalidBundesland):
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Guides need a valid Bundesland, other content none")})
+	default:
+		h.logger.Error(message, zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, message)})
+	}
+}
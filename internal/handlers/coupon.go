@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"elterngeld-portal/internal/catalog"
+	"elterngeld-portal/internal/middleware"
+	"elterngeld-portal/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type CouponHandler struct {
+	db      *gorm.DB
+	logger  *zap.Logger
+	catalog *catalog.Service
+}
+
+func NewCouponHandler(db *gorm.DB, logger *zap.Logger, catalogService *catalog.Service) *CouponHandler {
+	return &CouponHandler{
+		db:      db,
+		logger:  logger,
+		catalog: catalogService,
+	}
+}
+
+// CreateCouponRequest describes a new coupon with either a percentage or an amount off
+type CreateCouponRequest struct {
+	Code           string     `json:"code" binding:"required,alphanum,max=32"`
+	Description    string     `json:"description,omitempty" binding:"max=500"`
+	PercentOff     float64    `json:"percent_off,omitempty" binding:"min=0,max=100"`
+	AmountOff      float64    `json:"amount_off,omitempty" binding:"min=0"`
+	PackageID      *uuid.UUID `json:"package_id,omitempty"`                      // only valid for this package
+	MaxRedemptions int        `json:"max_redemptions,omitempty" binding:"min=0"` // 0 = unlimited
+	ExpiresAt      *time.Time `json:"expires_at,omitempty"`
+}
+
+// ListCoupons handles listing coupons (admin only)
+// @Summary List coupons
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/admin/coupons [get]
+func (h *CouponHandler) ListCoupons(c *gin.Context) {
+	coupons, err := h.catalog.ListCoupons()
+	if err != nil {
+		handleCatalogError(c, h.logger, err, "Failed to fetch coupons")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"coupons": coupons})
+}
+
+// CreateCoupon handles creating a coupon code (admin only)
+// @Summary Create coupon
+// @Description Coupons lower the price of a booking by a percentage or a fixed amount
+// @Tags admin
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body CreateCouponRequest true "Coupon"
+// @Success 201 {object} models.Coupon
+// @Failure 400 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/v1/admin/coupons [post]
+func (h *CouponHandler) CreateCoupon(c *gin.Context) {
+	adminID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "User not authenticated")})
+		return
+	}
+	var admin models.User
+	if err := h.db.First(&admin, "id = ?", adminID).Error; err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "User not found")})
+		return
+	}
+
+	var req CreateCouponRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid request data"), "details": err.Error()})
+		return
+	}
+
+	coupon, err := h.catalog.CreateCoupon(catalog.CouponInput{
+		Code:           req.Code,
+		Description:    req.Description,
+		PercentOff:     req.PercentOff,
+		AmountOff:      req.AmountOff,
+		PackageID:      req.PackageID,
+		MaxRedemptions: req.MaxRedemptions,
+		ExpiresAt:      req.ExpiresAt,
+	}, &admin)
+	if err != nil {
+		handleCatalogError(c, h.logger, err, "Failed to create coupon")
+		return
+	}
+
+	c.JSON(http.StatusCreated, coupon)
+}
+
+// handleCatalogError responds to errors of pricing an order or managing coupons
+func handleCatalogError(c *gin.Context, logger *zap.Logger, err error, message string) {
+	switch {
+	case errors.Is(err, catalog.ErrPackageNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "Package not found")})
+	case errors.Is(err, catalog.ErrAddonNotAvailable):
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "One or more add-ons not found")})
+	case errors.Is(err, catalog.ErrCouponNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "Coupon not found")})
+	case errors.Is(err, catalog.ErrCouponNotRedeemable):
+		c.JSON(http.StatusConflict, gin.H{"error": middleware.T(c, "Coupon has expired or was used up")})
+	case errors.Is(err, catalog.ErrInvalidCoupon):
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "A coupon needs either a percentage or an amount off")})
+	case errors.Is(err, catalog.ErrCouponCodeTaken):
+		c.JSON(http.StatusConflict, gin.H{"error": middleware.T(c, "Coupon code already exists")})
+	default:
+		logger.Error(message, zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, message)})
+	}
+}
@@ -0,0 +1,291 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"elterngeld-portal/internal/credit"
+	"elterngeld-portal/internal/middleware"
+	"elterngeld-portal/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type CreditHandler struct {
+	db     *gorm.DB
+	logger *zap.Logger
+	credit *credit.Service
+}
+
+func NewCreditHandler(db *gorm.DB, logger *zap.Logger, creditService *credit.Service) *CreditHandler {
+	return &CreditHandler{
+		db:     db,
+		logger: logger,
+		credit: creditService,
+	}
+}
+
+// RedeemVoucherRequest is a voucher code entered by a customer
+type RedeemVoucherRequest struct {
+	Code string `json:"code" binding:"required,max=32"`
+}
+
+// GrantCreditRequest is goodwill credit granted by an admin
+type GrantCreditRequest struct {
+	Amount      float64 `json:"amount" binding:"required,gt=0"`
+	Description string  `json:"description" binding:"required,max=500"`
+}
+
+// CreateVoucherRequest describes a new voucher; the code is generated when empty
+type CreateVoucherRequest struct {
+	Code           string     `json:"code,omitempty" binding:"omitempty,alphanum,max=32"`
+	Amount         float64    `json:"amount" binding:"required,gt=0"`
+	Description    string     `json:"description,omitempty" binding:"max=500"`
+	MaxRedemptions int        `json:"max_redemptions,omitempty" binding:"min=0"` // 0 = unlimited
+	ExpiresAt      *time.Time `json:"expires_at,omitempty"`
+}
+
+// GetMyCredit handles the credit statement of the current user
+// @Summary Get my credit
+// @Description Credit balance and ledger of the current user. Credit is applied automatically at checkout.
+// @Tags credit
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} credit.Statement
+// @Failure 401 {object} map[string]interface{}
+// @Router /api/v1/credit [get]
+func (h *CreditHandler) GetMyCredit(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "User not authenticated")})
+		return
+	}
+
+	statement, err := h.credit.Statement(userID)
+	if err != nil {
+		h.handleCreditError(c, err, "Failed to fetch credit")
+		return
+	}
+
+	c.JSON(http.StatusOK, statement)
+}
+
+// RedeemVoucher handles redeeming a voucher code for credit
+// @Summary Redeem voucher
+// @Description Add the value of a voucher to the current user's credit
+// @Tags credit
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body RedeemVoucherRequest true "Voucher code"
+// @Success 200 {object} credit.Statement
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/v1/credit/vouchers [post]
+func (h *CreditHandler) RedeemVoucher(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "User not authenticated")})
+		return
+	}
+
+	var req RedeemVoucherRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid request data"), "details": err.Error()})
+		return
+	}
+
+	if _, err := h.credit.RedeemVoucher(userID, req.Code); err != nil {
+		h.handleCreditError(c, err, "Failed to redeem voucher")
+		return
+	}
+
+	statement, err := h.credit.Statement(userID)
+	if err != nil {
+		h.handleCreditError(c, err, "Failed to fetch credit")
+		return
+	}
+
+	c.JSON(http.StatusOK, statement)
+}
+
+// GetUserCredit handles the credit statement of a customer (admin only)
+// @Summary Get customer credit
+// @Description Credit balance and ledger of a customer
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "User ID"
+// @Success 200 {object} credit.Statement
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/admin/users/{id}/credit [get]
+func (h *CreditHandler) GetUserCredit(c *gin.Context) {
+	customer, ok := h.findUser(c)
+	if !ok {
+		return
+	}
+
+	statement, err := h.credit.Statement(customer.ID)
+	if err != nil {
+		h.handleCreditError(c, err, "Failed to fetch credit")
+		return
+	}
+
+	c.JSON(http.StatusOK, statement)
+}
+
+// GrantCredit handles granting goodwill credit to a customer (admin only)
+// @Summary Grant goodwill credit
+// @Description Add goodwill credit to a customer's account
+// @Tags admin
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "User ID"
+// @Param request body GrantCreditRequest true "Credit"
+// @Success 201 {object} models.CreditEntry
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/admin/users/{id}/credit [post]
+func (h *CreditHandler) GrantCredit(c *gin.Context) {
+	admin, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+	customer, ok := h.findUser(c)
+	if !ok {
+		return
+	}
+
+	var req GrantCreditRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid request data"), "details": err.Error()})
+		return
+	}
+
+	entry, err := h.credit.Grant(customer.ID, req.Amount, req.Description, admin)
+	if err != nil {
+		h.handleCreditError(c, err, "Failed to grant credit")
+		return
+	}
+
+	c.JSON(http.StatusCreated, entry)
+}
+
+// ListVouchers handles listing vouchers (admin only)
+// @Summary List vouchers
+// @Description All voucher codes with their redemptions, newest first
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/admin/vouchers [get]
+func (h *CreditHandler) ListVouchers(c *gin.Context) {
+	vouchers, err := h.credit.ListVouchers()
+	if err != nil {
+		h.handleCreditError(c, err, "Failed to fetch vouchers")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"vouchers": vouchers})
+}
+
+// CreateVoucher handles creating a voucher code (admin only)
+// @Summary Create voucher
+// @Description Create a voucher code customers redeem for credit
+// @Tags admin
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body CreateVoucherRequest true "Voucher"
+// @Success 201 {object} models.Voucher
+// @Failure 400 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/v1/admin/vouchers [post]
+func (h *CreditHandler) CreateVoucher(c *gin.Context) {
+	admin, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	var req CreateVoucherRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid request data"), "details": err.Error()})
+		return
+	}
+
+	voucher, err := h.credit.CreateVoucher(credit.VoucherInput{
+		Code:           req.Code,
+		Amount:         req.Amount,
+		Description:    req.Description,
+		MaxRedemptions: req.MaxRedemptions,
+		ExpiresAt:      req.ExpiresAt,
+	}, admin)
+	if err != nil {
+		h.handleCreditError(c, err, "Failed to create voucher")
+		return
+	}
+
+	c.JSON(http.StatusCreated, voucher)
+}
+
+func (h *CreditHandler) currentUser(c *gin.Context) (*models.User, bool) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "User not authenticated")})
+		return nil, false
+	}
+
+	var user models.User
+	if err := h.db.First(&user, "id = ?", userID).Error; err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "User not found")})
+		return nil, false
+	}
+
+	return &user, true
+}
+
+func (h *CreditHandler) findUser(c *gin.Context) (*models.User, bool) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid user ID")})
+		return nil, false
+	}
+
+	var user models.User
+	if err := h.db.First(&user, "id = ?", userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "User not found")})
+		} else {
+			h.logger.Error("Failed to fetch user", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to fetch user")})
+		}
+		return nil, false
+	}
+
+	return &user, true
+}
+
+func (h *CreditHandler) handleCreditError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, credit.ErrInvalidAmount):
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Amount must be positive")})
+	case errors.Is(err, credit.ErrVoucherNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "Voucher not found")})
+	case errors.Is(err, credit.ErrVoucherNotRedeemable):
+		c.JSON(http.StatusConflict, gin.H{"error": middleware.T(c, "Voucher has expired or was used up")})
+	case errors.Is(err, credit.ErrVoucherAlreadyRedeemed):
+		c.JSON(http.StatusConflict, gin.H{"error": middleware.T(c, "Voucher already redeemed")})
+	case errors.Is(err, credit.ErrVoucherCodeTaken):
+		c.JSON(http.StatusConflict, gin.H{"error": middleware.T(c, "Voucher code already exists")})
+	default:
+		h.logger.Error(message, zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, message)})
+	}
+}
@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"elterngeld-portal/internal/digest"
+	"elterngeld-portal/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type DigestHandler struct {
+	db     *gorm.DB
+	logger *zap.Logger
+	digest *digest.Service
+}
+
+func NewDigestHandler(db *gorm.DB, logger *zap.Logger, digestService *digest.Service) *DigestHandler {
+	return &DigestHandler{
+		db:     db,
+		logger: logger,
+		digest: digestService,
+	}
+}
+
+// UpdateDigestModesRequest sets the digest mode of notification categories
+type UpdateDigestModesRequest struct {
+	Modes digest.Modes `json:"modes" binding:"required"`
+}
+
+// GetDigestModes handles reading the notification digest modes of the current user
+// @Summary Get notification digest modes
+// @Description Whether the email notifications of each category (booking, payment, todo, reminder, lead) are sent immediately or as an hourly or daily summary
+// @Tags notifications
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/notifications/digest [get]
+func (h *DigestHandler) GetDigestModes(c *gin.Context) {
+	userID, ok := middleware.GetCurrentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "User not authenticated")})
+		return
+	}
+
+	modes, err := h.digest.GetModes(userID)
+	if err != nil {
+		h.handleDigestError(c, err, "Failed to fetch notification preferences")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"modes": modes})
+}
+
+// UpdateDigestModes handles changing the notification digest modes of the current user
+// @Summary Update notification digest modes
+// @Description Choose immediate, hourly or daily emails per notification category. Categories that are left out keep their mode.
+// @Tags notifications
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body UpdateDigestModesRequest true "Digest modes"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/v1/notifications/digest [put]
+func (h *DigestHandler) UpdateDigestModes(c *gin.Context) {
+	userID, ok := middleware.GetCurrentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "User not authenticated")})
+		return
+	}
+
+	var req UpdateDigestModesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid request data"), "details": err.Error()})
+		return
+	}
+
+	modes, err := h.digest.SetModes(userID, req.Modes)
+	if err != nil {
+		h.handleDigestError(c, err, "Failed to update notification preferences")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"modes": modes})
+}
+
+func (h *DigestHandler) handleDigestError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, digest.ErrInvalidCategory):
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid notification category")})
+	case errors.Is(err, digest.ErrInvalidMode):
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid digest mode")})
+	default:
+		h.logger.Error(message, zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, message)})
+	}
+}
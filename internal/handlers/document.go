@@ -1,7 +1,9 @@
 package handlers
 
 import (
+	"errors"
 	"io"
+	"mime"
 	"mime/multipart"
 	"net/http"
 	"os"
@@ -11,7 +13,11 @@ import (
 	"time"
 
 	"elterngeld-portal/config"
+	"elterngeld-portal/internal/documents"
+	"elterngeld-portal/internal/middleware"
 	"elterngeld-portal/internal/models"
+	"elterngeld-portal/internal/quota"
+	"elterngeld-portal/internal/scopes"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -20,31 +26,36 @@ import (
 )
 
 type DocumentHandler struct {
-	db     *gorm.DB
-	logger *zap.Logger
-	config *config.Config
+	db        *gorm.DB
+	logger    *zap.Logger
+	config    *config.Config
+	documents *documents.Service
+	quota     *quota.Service
 }
 
-func NewDocumentHandler(db *gorm.DB, logger *zap.Logger, config *config.Config) *DocumentHandler {
+func NewDocumentHandler(db *gorm.DB, logger *zap.Logger, config *config.Config, documentService *documents.Service, quotaService *quota.Service) *DocumentHandler {
 	return &DocumentHandler{
-		db:     db,
-		logger: logger,
-		config: config,
+		db:        db,
+		logger:    logger,
+		config:    config,
+		documents: documentService,
+		quota:     quotaService,
 	}
 }
 
 // UploadDocumentRequest represents the document upload request
 type UploadDocumentRequest struct {
-	LeadID    *uuid.UUID `form:"lead_id,omitempty"`
-	BookingID *uuid.UUID `form:"booking_id,omitempty"`
-	Category  string     `form:"category" binding:"required"`
-	IsPublic  bool       `form:"is_public,omitempty"`
-	Notes     string     `form:"notes,omitempty"`
+	LeadID      *uuid.UUID `form:"lead_id,omitempty"`
+	BookingID   *uuid.UUID `form:"booking_id,omitempty"`
+	Category    string     `form:"category" binding:"required"`
+	IsPublic    bool       `form:"is_public,omitempty"`
+	Notes       string     `form:"notes,omitempty"`
+	IsSensitive bool       `form:"is_sensitive,omitempty"`
 }
 
 // ListDocuments handles listing documents with filtering
 // @Summary List documents
-// @Description Get list of documents with filtering options
+// @Description Get list of documents with filtering options. Customers do not see the internal documents of the Berater, staff do not see documents customers keep to themselves.
 // @Tags documents
 // @Security BearerAuth
 // @Produce json
@@ -53,18 +64,17 @@ type UploadDocumentRequest struct {
 // @Param category query string false "Filter by category"
 // @Param lead_id query string false "Filter by lead ID"
 // @Param booking_id query string false "Filter by booking ID"
+// @Param visibility query string false "Filter by visibility (kunde, berater, geteilt)"
 // @Success 200 {object} map[string]interface{}
 // @Failure 401 {object} map[string]interface{}
 // @Router /api/v1/documents [get]
 func (h *DocumentHandler) ListDocuments(c *gin.Context) {
-	userID, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+	viewer, ok := scopes.FromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "User not authenticated")})
 		return
 	}
 
-	userRole, _ := c.Get("user_role")
-
 	// Parse pagination
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
@@ -74,24 +84,10 @@ func (h *DocumentHandler) ListDocuments(c *gin.Context) {
 	category := c.Query("category")
 	leadID := c.Query("lead_id")
 	bookingID := c.Query("booking_id")
+	visibility := c.Query("visibility")
 
-	// Build query
-	query := h.db.Model(&models.Document{})
-
-	// Role-based filtering
-	if userRole == "user" {
-		// Users can only see their own documents
-		query = query.Where("user_id = ?", userID)
-	} else if userRole == "junior_berater" {
-		// Junior beraters can see documents from assigned leads
-		query = query.Joins("LEFT JOIN leads ON documents.lead_id = leads.id").
-			Where("documents.user_id = ? OR leads.assigned_to_id = ? OR documents.is_public = ?", 
-				userID, userID, true)
-	} else if userRole == "berater" {
-		// Beraters can see most documents
-		query = query.Where("is_public = ? OR user_id = ?", true, userID)
-	}
-	// Admins can see all documents
+	// Build query limited to the documents the viewer may see
+	query := h.db.Model(&models.Document{}).Scopes(scopes.VisibleDocuments(viewer))
 
 	// Apply filters
 	if category != "" {
@@ -103,6 +99,9 @@ func (h *DocumentHandler) ListDocuments(c *gin.Context) {
 	if bookingID != "" {
 		query = query.Where("booking_id = ?", bookingID)
 	}
+	if visibility != "" {
+		query = query.Where("documents.visibility = ?", visibility)
+	}
 
 	// Get total count
 	var total int64
@@ -113,7 +112,7 @@ func (h *DocumentHandler) ListDocuments(c *gin.Context) {
 	if err := query.Preload("User").Preload("Lead").Preload("Booking").
 		Offset(offset).Limit(limit).Order("created_at DESC").Find(&documents).Error; err != nil {
 		h.logger.Error("Failed to fetch documents", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch documents"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to fetch documents")})
 		return
 	}
 
@@ -144,25 +143,34 @@ func (h *DocumentHandler) ListDocuments(c *gin.Context) {
 // @Success 201 {object} models.Document
 // @Failure 400 {object} map[string]interface{}
 // @Failure 401 {object} map[string]interface{}
+// @Failure 413 {object} map[string]interface{} "Request too large or storage quota exceeded"
 // @Router /api/v1/documents [post]
 func (h *DocumentHandler) UploadDocument(c *gin.Context) {
 	userID, exists := c.Get("user_id")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "User not authenticated")})
 		return
 	}
 
+	userRole, _ := middleware.GetCurrentUserRole(c)
+
 	// Parse form data
 	var req UploadDocumentRequest
 	if err := c.ShouldBind(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid form data", "details": err.Error()})
+		if middleware.BodyTooLarge(c, err) {
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid form data"), "details": err.Error()})
 		return
 	}
 
 	// Get uploaded file
 	file, fileHeader, err := c.Request.FormFile("file")
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "No file uploaded"})
+		if middleware.BodyTooLarge(c, err) {
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "No file uploaded")})
 		return
 	}
 	defer file.Close()
@@ -177,7 +185,7 @@ func (h *DocumentHandler) UploadDocument(c *gin.Context) {
 	if req.LeadID != nil {
 		var lead models.Lead
 		if err := h.db.Where("id = ?", *req.LeadID).First(&lead).Error; err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid lead ID"})
+			c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid lead ID")})
 			return
 		}
 	}
@@ -185,7 +193,7 @@ func (h *DocumentHandler) UploadDocument(c *gin.Context) {
 	if req.BookingID != nil {
 		var booking models.Booking
 		if err := h.db.Where("id = ?", *req.BookingID).First(&booking).Error; err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid booking ID"})
+			c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid booking ID")})
 			return
 		}
 	}
@@ -198,7 +206,7 @@ func (h *DocumentHandler) UploadDocument(c *gin.Context) {
 	filePath, err := h.storeFile(file, filename)
 	if err != nil {
 		h.logger.Error("Failed to store file", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store file"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to store file")})
 		return
 	}
 
@@ -216,13 +224,31 @@ func (h *DocumentHandler) UploadDocument(c *gin.Context) {
 		Category:     req.Category,
 		IsPublic:     req.IsPublic,
 		Notes:        req.Notes,
+		IsSensitive:  req.IsSensitive,
+		Visibility:   models.DefaultDocumentVisibility(userRole),
+		OCRStatus:    models.OCRStatusPending,
 		CreatedAt:    time.Now(),
 		UpdatedAt:    time.Now(),
 	}
 
-	if err := h.db.Create(&document).Error; err != nil {
+	// The quota is checked in the transaction of the document, so parallel
+	// uploads cannot exceed it together
+	err = h.db.Transaction(func(tx *gorm.DB) error {
+		if err := h.quota.Check(tx, userID.(uuid.UUID), fileHeader.Size); err != nil {
+			return err
+		}
+		return tx.Create(&document).Error
+	})
+	if err != nil {
+		if removeErr := os.Remove(filePath); removeErr != nil && !os.IsNotExist(removeErr) {
+			h.logger.Warn("Failed to remove rejected upload", zap.String("path", filePath), zap.Error(removeErr))
+		}
+		if errors.Is(err, quota.ErrQuotaExceeded) {
+			quotaExceeded(c)
+			return
+		}
 		h.logger.Error("Failed to create document record", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save document"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to save document")})
 		return
 	}
 
@@ -231,9 +257,45 @@ func (h *DocumentHandler) UploadDocument(c *gin.Context) {
 		zap.String("filename", document.Filename),
 		zap.String("user_id", userID.(uuid.UUID).String()))
 
+	h.classifyInBackground(document.ID)
+
 	c.JSON(http.StatusCreated, document)
 }
 
+// GetStorageQuota handles getting the storage used by the current user's documents
+// @Summary Get storage quota
+// @Description Get the storage used by the current user's documents and the remaining quota. Staff have no quota (limit 0).
+// @Tags documents
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} quota.Usage
+// @Failure 401 {object} map[string]interface{}
+// @Router /api/v1/documents/quota [get]
+func (h *DocumentHandler) GetStorageQuota(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "User not authenticated")})
+		return
+	}
+
+	usage, err := h.quota.Usage(userID.(uuid.UUID))
+	if err != nil {
+		h.logger.Error("Failed to fetch storage usage", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to fetch storage usage")})
+		return
+	}
+
+	c.JSON(http.StatusOK, usage)
+}
+
+// quotaExceeded responds to an upload that does not fit into the user's storage quota
+func quotaExceeded(c *gin.Context) {
+	c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+		"error": middleware.T(c, "Storage quota exceeded"),
+		"code":  "QUOTA_EXCEEDED",
+	})
+}
+
 // GetDocument handles getting a specific document
 // @Summary Get document by ID
 // @Description Get document information
@@ -246,35 +308,23 @@ func (h *DocumentHandler) UploadDocument(c *gin.Context) {
 // @Failure 404 {object} map[string]interface{}
 // @Router /api/v1/documents/{id} [get]
 func (h *DocumentHandler) GetDocument(c *gin.Context) {
-	userID, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+	viewer, ok := scopes.FromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "User not authenticated")})
 		return
 	}
 
 	documentID := c.Param("id")
-	userRole, _ := c.Get("user_role")
 
 	var document models.Document
-	query := h.db.Where("id = ?", documentID)
-
-	// Role-based access control
-	if userRole == "user" {
-		query = query.Where("user_id = ?", userID)
-	} else if userRole == "junior_berater" {
-		query = query.Joins("LEFT JOIN leads ON documents.lead_id = leads.id").
-			Where("documents.user_id = ? OR leads.assigned_to_id = ? OR documents.is_public = ?", 
-				userID, userID, true)
-	} else if userRole == "berater" {
-		query = query.Where("is_public = ? OR user_id = ?", true, userID)
-	}
+	query := h.db.Scopes(scopes.VisibleDocuments(viewer)).Where("documents.id = ?", documentID)
 
 	if err := query.Preload("User").Preload("Lead").Preload("Booking").First(&document).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Document not found"})
+			c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "Document not found")})
 		} else {
 			h.logger.Error("Failed to fetch document", zap.Error(err))
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch document"})
+			c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to fetch document")})
 		}
 		return
 	}
@@ -294,35 +344,37 @@ func (h *DocumentHandler) GetDocument(c *gin.Context) {
 // @Failure 404 {object} map[string]interface{}
 // @Router /api/v1/documents/{id}/download [get]
 func (h *DocumentHandler) DownloadDocument(c *gin.Context) {
-	userID, exists := c.Get("user_id")
+	userID, exists := middleware.GetCurrentUserID(c)
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "User not authenticated")})
 		return
 	}
+	userRole, _ := middleware.GetCurrentUserRole(c)
 
 	documentID := c.Param("id")
-	userRole, _ := c.Get("user_role")
 
 	var document models.Document
-	query := h.db.Where("id = ?", documentID)
-
 	// Same access control as GetDocument
-	if userRole == "user" {
-		query = query.Where("user_id = ?", userID)
-	} else if userRole == "junior_berater" {
-		query = query.Joins("LEFT JOIN leads ON documents.lead_id = leads.id").
-			Where("documents.user_id = ? OR leads.assigned_to_id = ? OR documents.is_public = ?", 
-				userID, userID, true)
-	} else if userRole == "berater" {
-		query = query.Where("is_public = ? OR user_id = ?", true, userID)
-	}
+	query := h.db.Scopes(scopes.VisibleDocuments(scopes.Viewer{ID: userID, Role: userRole})).
+		Where("documents.id = ?", documentID)
 
 	if err := query.First(&document).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Document not found"})
+			c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "Document not found")})
 		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch document"})
+			c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to fetch document")})
+		}
+		return
+	}
+
+	// Sensitive documents are only delivered with the viewer's watermark
+	if document.RequiresWatermark() {
+		var viewer models.User
+		if err := h.db.First(&viewer, "id = ?", userID).Error; err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "User not found")})
+			return
 		}
+		h.serveDownload(c, &document, &viewer)
 		return
 	}
 
@@ -353,7 +405,7 @@ func (h *DocumentHandler) DownloadDocument(c *gin.Context) {
 func (h *DocumentHandler) UpdateDocument(c *gin.Context) {
 	userID, exists := c.Get("user_id")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "User not authenticated")})
 		return
 	}
 
@@ -370,21 +422,21 @@ func (h *DocumentHandler) UpdateDocument(c *gin.Context) {
 
 	if err := query.First(&document).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Document not found"})
+			c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "Document not found")})
 		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch document"})
+			c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to fetch document")})
 		}
 		return
 	}
 
 	var updates map[string]interface{}
 	if err := c.ShouldBindJSON(&updates); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid request data")})
 		return
 	}
 
 	// Only allow certain fields to be updated
-	allowedFields := []string{"category", "is_public", "notes"}
+	allowedFields := []string{"category", "is_public", "notes", "is_sensitive"}
 	filteredUpdates := make(map[string]interface{})
 	for _, field := range allowedFields {
 		if value, exists := updates[field]; exists {
@@ -393,7 +445,7 @@ func (h *DocumentHandler) UpdateDocument(c *gin.Context) {
 	}
 
 	if len(filteredUpdates) == 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "No valid fields to update"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "No valid fields to update")})
 		return
 	}
 
@@ -401,13 +453,13 @@ func (h *DocumentHandler) UpdateDocument(c *gin.Context) {
 
 	if err := h.db.Model(&document).Updates(filteredUpdates).Error; err != nil {
 		h.logger.Error("Failed to update document", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update document"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to update document")})
 		return
 	}
 
 	// Fetch updated document
 	if err := h.db.First(&document, "id = ?", documentID).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch updated document"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to fetch updated document")})
 		return
 	}
 
@@ -428,7 +480,7 @@ func (h *DocumentHandler) UpdateDocument(c *gin.Context) {
 func (h *DocumentHandler) DeleteDocument(c *gin.Context) {
 	userID, exists := c.Get("user_id")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "User not authenticated")})
 		return
 	}
 
@@ -445,9 +497,9 @@ func (h *DocumentHandler) DeleteDocument(c *gin.Context) {
 
 	if err := query.First(&document).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Document not found"})
+			c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "Document not found")})
 		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch document"})
+			c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to fetch document")})
 		}
 		return
 	}
@@ -455,7 +507,7 @@ func (h *DocumentHandler) DeleteDocument(c *gin.Context) {
 	// Delete database record (soft delete)
 	if err := h.db.Delete(&document).Error; err != nil {
 		h.logger.Error("Failed to delete document record", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete document"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to delete document")})
 		return
 	}
 
@@ -464,7 +516,341 @@ func (h *DocumentHandler) DeleteDocument(c *gin.Context) {
 
 	h.logger.Info("Document deleted successfully", zap.String("document_id", documentID))
 
-	c.JSON(http.StatusOK, gin.H{"message": "Document deleted successfully"})
+	c.JSON(http.StatusOK, gin.H{"message": middleware.T(c, "Document deleted successfully")})
+}
+
+// UpdateDocumentVisibilityRequest changes who sees a document on its lead
+type UpdateDocumentVisibilityRequest struct {
+	Visibility models.DocumentVisibility `json:"visibility" binding:"required,oneof=kunde berater geteilt"`
+}
+
+// UpdateDocumentVisibility handles changing who sees a document
+// @Summary Update document visibility
+// @Description Customers keep their uploads to themselves (kunde) or share them (geteilt), staff keep working documents internal (berater) or share them. Nobody is notified, see POST /api/v1/documents/{id}/share.
+// @Tags documents
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Document ID"
+// @Param request body UpdateDocumentVisibilityRequest true "Visibility"
+// @Success 200 {object} models.Document
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/documents/{id}/visibility [put]
+func (h *DocumentHandler) UpdateDocumentVisibility(c *gin.Context) {
+	viewer, documentID, ok := h.documentRequest(c)
+	if !ok {
+		return
+	}
+
+	var req UpdateDocumentVisibilityRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid request data"), "details": err.Error()})
+		return
+	}
+
+	document, err := h.documents.SetVisibility(documentID, viewer, req.Visibility)
+	if err != nil {
+		h.handleSharingError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, document)
+}
+
+// ShareDocument handles sharing a document with the other side of its lead
+// @Summary Share document
+// @Description Share an internal working document with the customers of the lead, or an upload a customer kept to themselves with the Berater. The other side is notified.
+// @Tags documents
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Document ID"
+// @Success 200 {object} models.Document
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/v1/documents/{id}/share [post]
+func (h *DocumentHandler) ShareDocument(c *gin.Context) {
+	viewer, documentID, ok := h.documentRequest(c)
+	if !ok {
+		return
+	}
+
+	document, err := h.documents.Share(documentID, viewer)
+	if err != nil {
+		h.handleSharingError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, document)
+}
+
+func (h *DocumentHandler) documentRequest(c *gin.Context) (scopes.Viewer, uuid.UUID, bool) {
+	viewer, ok := scopes.FromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "User not authenticated")})
+		return scopes.Viewer{}, uuid.Nil, false
+	}
+
+	documentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid document ID")})
+		return scopes.Viewer{}, uuid.Nil, false
+	}
+
+	return viewer, documentID, true
+}
+
+func (h *DocumentHandler) handleSharingError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, documents.ErrDocumentNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "Document not found")})
+	case errors.Is(err, documents.ErrInvalidVisibility):
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid document visibility"), "details": err.Error()})
+	case errors.Is(err, documents.ErrSharingNotAllowed):
+		c.JSON(http.StatusForbidden, gin.H{"error": middleware.T(c, "You may not change who sees this document")})
+	case errors.Is(err, documents.ErrAlreadyShared):
+		c.JSON(http.StatusConflict, gin.H{"error": middleware.T(c, "Document already shared")})
+	default:
+		h.logger.Error("Failed to update document visibility", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to update document")})
+	}
+}
+
+// CreateDocumentLinks handles signing short-lived preview and download links
+// @Summary Create signed document links
+// @Description Create short-lived signed URLs for the thumbnail preview and the download of a document. Downloads of sensitive documents such as Bescheide are watermarked with the viewer's name and the time of the download.
+// @Tags documents
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Document ID"
+// @Success 200 {object} documents.SignedLinks
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/documents/{id}/links [post]
+func (h *DocumentHandler) CreateDocumentLinks(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "User not authenticated")})
+		return
+	}
+
+	documentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid document ID")})
+		return
+	}
+
+	var viewer models.User
+	if err := h.db.First(&viewer, "id = ?", userID).Error; err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "User not found")})
+		return
+	}
+
+	document, err := h.viewableDocument(documentID, &viewer)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "Document not found")})
+		} else {
+			h.logger.Error("Failed to fetch document", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to fetch document")})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, h.documents.Links(document, viewer.ID))
+}
+
+// ServeSignedDocument handles delivering a document through a signed link
+// @Summary Open signed document link
+// @Description Deliver the thumbnail preview or the download of a document through a short-lived signed URL created by POST /api/v1/documents/{id}/links
+// @Tags documents
+// @Produce image/jpeg,application/octet-stream
+// @Param id path string true "Document ID"
+// @Param variant path string true "preview or download"
+// @Param viewer query string true "Viewer ID"
+// @Param expires query int true "Expiry as Unix timestamp"
+// @Param signature query string true "Link signature"
+// @Success 200 {file} file "Document preview or file"
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 410 {object} map[string]interface{}
+// @Failure 422 {object} map[string]interface{}
+// @Router /files/documents/{id}/{variant} [get]
+func (h *DocumentHandler) ServeSignedDocument(c *gin.Context) {
+	documentID, idErr := uuid.Parse(c.Param("id"))
+	viewerID, viewerErr := uuid.Parse(c.Query("viewer"))
+	expires, expiresErr := strconv.ParseInt(c.Query("expires"), 10, 64)
+	if idErr != nil || viewerErr != nil || expiresErr != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": middleware.T(c, "Invalid document link")})
+		return
+	}
+
+	variant := documents.Variant(c.Param("variant"))
+	if err := h.documents.Verify(documentID, variant, viewerID, expires, c.Query("signature")); err != nil {
+		if errors.Is(err, documents.ErrLinkExpired) {
+			c.JSON(http.StatusGone, gin.H{"error": middleware.T(c, "Document link has expired")})
+		} else {
+			c.JSON(http.StatusForbidden, gin.H{"error": middleware.T(c, "Invalid document link")})
+		}
+		return
+	}
+
+	// Access is checked again so that revoked permissions take effect before the link expires
+	var viewer models.User
+	if err := h.db.First(&viewer, "id = ? AND is_active = ?", viewerID, true).Error; err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": middleware.T(c, "Invalid document link")})
+		return
+	}
+
+	document, err := h.viewableDocument(documentID, &viewer)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "Document not found")})
+		} else {
+			h.logger.Error("Failed to fetch document", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to fetch document")})
+		}
+		return
+	}
+
+	if variant == documents.VariantDownload {
+		h.serveDownload(c, document, &viewer)
+		return
+	}
+
+	thumbnail, err := h.documents.Thumbnail(document)
+	if err != nil {
+		if errors.Is(err, documents.ErrPreviewUnavailable) {
+			c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "Preview not available")})
+		} else {
+			h.logger.Error("Failed to render preview", zap.String("document_id", document.ID.String()), zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to render preview")})
+		}
+		return
+	}
+
+	c.Header("Cache-Control", "private, max-age=300")
+	c.Data(http.StatusOK, "image/jpeg", thumbnail)
+}
+
+// ClassifyDocument handles re-running text recognition and classification of a document
+// @Summary Classify document
+// @Description Recognize the text of a document, classify it (Gehaltsabrechnung, Geburtsurkunde, Steuerbescheid, Krankenkassenbescheinigung) and assign it to the open todo asking for it
+// @Tags documents
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Document ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 422 {object} map[string]interface{}
+// @Router /api/v1/documents/{id}/classify [post]
+func (h *DocumentHandler) ClassifyDocument(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "User not authenticated")})
+		return
+	}
+
+	documentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid document ID")})
+		return
+	}
+
+	var viewer models.User
+	if err := h.db.First(&viewer, "id = ?", userID).Error; err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "User not found")})
+		return
+	}
+
+	if _, err := h.viewableDocument(documentID, &viewer); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "Document not found")})
+		} else {
+			h.logger.Error("Failed to fetch document", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to fetch document")})
+		}
+		return
+	}
+
+	result, err := h.documents.Process(documentID)
+	if err != nil {
+		if errors.Is(err, documents.ErrOCRUnavailable) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": middleware.T(c, "Text recognition is not available for this document")})
+		} else {
+			h.logger.Error("Failed to classify document", zap.String("document_id", documentID.String()), zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to classify document")})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"document":       result.Document.ToResponse(h.config.App.BaseURL),
+		"classification": result.Classification,
+		"todo":           result.Todo,
+	})
+}
+
+// classifyInBackground recognizes and classifies a new upload outside the request, as
+// OCR of a scan takes several seconds. Documents that cannot be recognized stay
+// unclassified for manual sorting.
+func (h *DocumentHandler) classifyInBackground(documentID uuid.UUID) {
+	go func() {
+		if _, err := h.documents.Process(documentID); err != nil && !errors.Is(err, documents.ErrOCRUnavailable) {
+			h.logger.Warn("Failed to classify document", zap.String("document_id", documentID.String()), zap.Error(err))
+		}
+	}()
+}
+
+// serveDownload delivers a document as attachment, watermarked if it is sensitive
+func (h *DocumentHandler) serveDownload(c *gin.Context, document *models.Document, viewer *models.User) {
+	content, err := h.documents.Open(document, viewer)
+	if err != nil {
+		if errors.Is(err, documents.ErrWatermarkUnsupported) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": middleware.T(c, "Document cannot be watermarked")})
+		} else {
+			h.logger.Error("Failed to open document", zap.String("document_id", document.ID.String()), zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to open document")})
+		}
+		return
+	}
+
+	// Watermarked copies are personal and must not be cached by shared proxies
+	c.Header("Cache-Control", "private, no-store")
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": content.FileName}))
+	c.Data(http.StatusOK, content.ContentType, content.Data)
+}
+
+// viewableDocument loads a document the viewer may open: customers their own
+// documents and those of their leads, Beraters the documents of leads assigned to them.
+// The visibility of the document applies on top, see models.Document.VisibleTo.
+func (h *DocumentHandler) viewableDocument(documentID uuid.UUID, viewer *models.User) (*models.Document, error) {
+	query := h.db.Where("id = ?", documentID)
+
+	switch {
+	case viewer.IsAdmin():
+	case viewer.IsBerater() || viewer.IsJuniorBerater():
+		query = query.Where("user_id = ? OR lead_id IN (?)", viewer.ID,
+			h.db.Model(&models.Lead{}).Select("id").Where("berater_id = ?", viewer.ID))
+	default:
+		query = query.Where("user_id = ? OR lead_id IN (?)", viewer.ID,
+			h.db.Model(&models.Lead{}).Select("id").Where("user_id = ?", viewer.ID))
+	}
+
+	var document models.Document
+	if err := query.First(&document).Error; err != nil {
+		return nil, err
+	}
+	// Documents the other side of the lead keeps to itself are treated as missing
+	if document.UserID != viewer.ID && !document.VisibleTo(viewer.Role) {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &document, nil
 }
 
 // validateFile validates uploaded file
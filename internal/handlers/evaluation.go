@@ -0,0 +1,231 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"elterngeld-portal/internal/evaluations"
+	"elterngeld-portal/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type EvaluationHandler struct {
+	db          *gorm.DB
+	logger      *zap.Logger
+	evaluations *evaluations.Service
+}
+
+func NewEvaluationHandler(db *gorm.DB, logger *zap.Logger, evaluationService *evaluations.Service) *EvaluationHandler {
+	return &EvaluationHandler{
+		db:          db,
+		logger:      logger,
+		evaluations: evaluationService,
+	}
+}
+
+// ListCriteria handles listing the evaluation criteria (admin only)
+// @Summary List evaluation criteria
+// @Description List the criteria applications are rated on, optionally only the active ones
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Param active query bool false "Only active criteria"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/admin/evaluation-criteria [get]
+func (h *EvaluationHandler) ListCriteria(c *gin.Context) {
+	criteria, err := h.evaluations.Criteria(c.Query("active") == "true")
+	if err != nil {
+		h.logger.Error("Failed to fetch evaluation criteria", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to fetch evaluation criteria")})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"criteria": criteria})
+}
+
+// CreateCriterion handles adding an evaluation criterion (admin only)
+// @Summary Create evaluation criterion
+// @Tags admin
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body evaluations.CriterionInput true "Criterion data"
+// @Success 201 {object} models.EvaluationCriterion
+// @Failure 400 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/v1/admin/evaluation-criteria [post]
+func (h *EvaluationHandler) CreateCriterion(c *gin.Context) {
+	var req evaluations.CriterionInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid request data"), "details": err.Error()})
+		return
+	}
+
+	criterion, err := h.evaluations.CreateCriterion(req)
+	if err != nil {
+		h.handleEvaluationError(c, err, "Failed to create evaluation criterion")
+		return
+	}
+
+	c.JSON(http.StatusCreated, criterion)
+}
+
+// UpdateCriterion handles changing an evaluation criterion (admin only)
+// @Summary Update evaluation criterion
+// @Description Change an evaluation criterion; a new weight applies to evaluations submitted from then on
+// @Tags admin
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Criterion ID"
+// @Param request body evaluations.CriterionInput true "Criterion data"
+// @Success 200 {object} models.EvaluationCriterion
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/v1/admin/evaluation-criteria/{id} [put]
+func (h *EvaluationHandler) UpdateCriterion(c *gin.Context) {
+	criterionID, ok := h.criterionID(c)
+	if !ok {
+		return
+	}
+
+	var req evaluations.CriterionInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid request data"), "details": err.Error()})
+		return
+	}
+
+	criterion, err := h.evaluations.UpdateCriterion(criterionID, req)
+	if err != nil {
+		h.handleEvaluationError(c, err, "Failed to update evaluation criterion")
+		return
+	}
+
+	c.JSON(http.StatusOK, criterion)
+}
+
+// DeleteCriterion handles removing an evaluation criterion (admin only)
+// @Summary Delete evaluation criterion
+// @Description Delete a criterion that was never rated; rated criteria can be deactivated instead
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Criterion ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/v1/admin/evaluation-criteria/{id} [delete]
+func (h *EvaluationHandler) DeleteCriterion(c *gin.Context) {
+	criterionID, ok := h.criterionID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.evaluations.DeleteCriterion(criterionID); err != nil {
+		h.handleEvaluationError(c, err, "Failed to delete evaluation criterion")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": middleware.T(c, "Evaluation criterion deleted")})
+}
+
+// ListEvaluations handles listing the evaluations of an application (admin only)
+// @Summary List application evaluations
+// @Description List the ratings, recommendations and notes reviewers gave on an application per stage
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Application ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/admin/job-applications/{id}/evaluations [get]
+func (h *EvaluationHandler) ListEvaluations(c *gin.Context) {
+	applicationID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid application ID")})
+		return
+	}
+
+	list, err := h.evaluations.Evaluations(applicationID)
+	if err != nil {
+		h.handleEvaluationError(c, err, "Failed to fetch evaluations")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"evaluations": list})
+}
+
+// Evaluate handles a reviewer's feedback on an application (admin only)
+// @Summary Evaluate application
+// @Description Rate an application on the active criteria and record a recommendation and notes for a stage; submitting again for the same stage replaces the reviewer's earlier feedback
+// @Tags admin
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Application ID"
+// @Param request body evaluations.EvaluationInput true "Evaluation"
+// @Success 200 {object} models.ApplicationEvaluation
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/admin/job-applications/{id}/evaluations [put]
+func (h *EvaluationHandler) Evaluate(c *gin.Context) {
+	userID, ok := middleware.GetCurrentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "User not authenticated")})
+		return
+	}
+
+	applicationID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid application ID")})
+		return
+	}
+
+	var req evaluations.EvaluationInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid request data"), "details": err.Error()})
+		return
+	}
+
+	evaluation, err := h.evaluations.Evaluate(applicationID, userID, req)
+	if err != nil {
+		h.handleEvaluationError(c, err, "Failed to save evaluation")
+		return
+	}
+
+	c.JSON(http.StatusOK, evaluation)
+}
+
+func (h *EvaluationHandler) criterionID(c *gin.Context) (uuid.UUID, bool) {
+	criterionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid criterion ID")})
+		return uuid.Nil, false
+	}
+	return criterionID, true
+}
+
+func (h *EvaluationHandler) handleEvaluationError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, evaluations.ErrCriterionNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "Evaluation criterion not found")})
+	case errors.Is(err, evaluations.ErrDuplicateCriterion):
+		c.JSON(http.StatusConflict, gin.H{"error": middleware.T(c, "Evaluation criterion already exists")})
+	case errors.Is(err, evaluations.ErrCriterionInUse):
+		c.JSON(http.StatusConflict, gin.H{"error": middleware.T(c, "Evaluation criterion has ratings, deactivate it instead")})
+	case errors.Is(err, evaluations.ErrApplicationNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "Application not found")})
+	case errors.Is(err, evaluations.ErrInvalidRating):
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Ratings must cover distinct active criteria")})
+	case errors.Is(err, evaluations.ErrEmptyEvaluation):
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Evaluation needs ratings, a recommendation or notes")})
+	default:
+		h.logger.Error(message, zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, message)})
+	}
+}
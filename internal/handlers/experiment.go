@@ -0,0 +1,293 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"elterngeld-portal/internal/experiments"
+	"elterngeld-portal/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type ExperimentHandler struct {
+	db          *gorm.DB
+	logger      *zap.Logger
+	experiments *experiments.Service
+}
+
+func NewExperimentHandler(db *gorm.DB, logger *zap.Logger, experimentService *experiments.Service) *ExperimentHandler {
+	return &ExperimentHandler{
+		db:          db,
+		logger:      logger,
+		experiments: experimentService,
+	}
+}
+
+// AssignVariantRequest identifies the visitor of an experiment
+type AssignVariantRequest struct {
+	VisitorID *uuid.UUID `json:"visitor_id,omitempty"` // optional for signed-in users
+}
+
+// AssignVariant handles assigning a visitor to an experiment variant
+// @Summary Assign experiment variant
+// @Description Get the variant of a running experiment for the visitor and log the exposure. Visitors always get the same variant; signing in links the exposure to the account for conversion attribution.
+// @Tags experiments
+// @Accept json
+// @Produce json
+// @Param key path string true "Experiment key"
+// @Param request body AssignVariantRequest false "Visitor"
+// @Success 200 {object} experiments.Assignment
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/experiments/{key}/assign [post]
+func (h *ExperimentHandler) AssignVariant(c *gin.Context) {
+	var req AssignVariantRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid request data"), "details": err.Error()})
+			return
+		}
+	}
+
+	var userID *uuid.UUID
+	if id, ok := middleware.GetCurrentUserID(c); ok {
+		userID = &id
+	}
+
+	assignment, err := h.experiments.Assign(c.Param("key"), req.VisitorID, userID)
+	if err != nil {
+		h.handleExperimentError(c, err, "Failed to assign experiment variant")
+		return
+	}
+
+	c.JSON(http.StatusOK, assignment)
+}
+
+// ListExperiments handles listing A/B tests (admin only)
+// @Summary List experiments
+// @Description Get all experiments with their variants
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/admin/experiments [get]
+func (h *ExperimentHandler) ListExperiments(c *gin.Context) {
+	list, err := h.experiments.List()
+	if err != nil {
+		h.logger.Error("Failed to fetch experiments", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to fetch experiments")})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"experiments": list})
+}
+
+// CreateExperiment handles defining an A/B test (admin only)
+// @Summary Create experiment
+// @Description Define a draft experiment with at least two weighted variants and their display config
+// @Tags admin
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body experiments.ExperimentInput true "Experiment"
+// @Success 201 {object} models.Experiment
+// @Failure 400 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/v1/admin/experiments [post]
+func (h *ExperimentHandler) CreateExperiment(c *gin.Context) {
+	var req experiments.ExperimentInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid request data"), "details": err.Error()})
+		return
+	}
+
+	experiment, err := h.experiments.Create(req)
+	if err != nil {
+		h.handleExperimentError(c, err, "Failed to create experiment")
+		return
+	}
+
+	h.logger.Info("Experiment created",
+		zap.String("experiment_id", experiment.ID.String()),
+		zap.String("key", experiment.Key))
+
+	c.JSON(http.StatusCreated, experiment)
+}
+
+// GetExperiment handles getting an A/B test (admin only)
+// @Summary Get experiment
+// @Description Get an experiment with its variants
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Experiment ID"
+// @Success 200 {object} models.Experiment
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/admin/experiments/{id} [get]
+func (h *ExperimentHandler) GetExperiment(c *gin.Context) {
+	experimentID, ok := h.experimentID(c)
+	if !ok {
+		return
+	}
+
+	experiment, err := h.experiments.Get(experimentID)
+	if err != nil {
+		h.handleExperimentError(c, err, "Failed to fetch experiment")
+		return
+	}
+
+	c.JSON(http.StatusOK, experiment)
+}
+
+// UpdateExperiment handles changing a draft A/B test (admin only)
+// @Summary Update experiment
+// @Description Change the name, description and variants of an experiment that has not been started
+// @Tags admin
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Experiment ID"
+// @Param request body experiments.ExperimentInput true "Experiment"
+// @Success 200 {object} models.Experiment
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/v1/admin/experiments/{id} [put]
+func (h *ExperimentHandler) UpdateExperiment(c *gin.Context) {
+	experimentID, ok := h.experimentID(c)
+	if !ok {
+		return
+	}
+
+	var req experiments.ExperimentInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid request data"), "details": err.Error()})
+		return
+	}
+
+	experiment, err := h.experiments.Update(experimentID, req)
+	if err != nil {
+		h.handleExperimentError(c, err, "Failed to update experiment")
+		return
+	}
+
+	c.JSON(http.StatusOK, experiment)
+}
+
+// StartExperiment handles starting an A/B test (admin only)
+// @Summary Start experiment
+// @Description Start assigning visitors to the variants of a draft experiment
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Experiment ID"
+// @Success 200 {object} models.Experiment
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/v1/admin/experiments/{id}/start [post]
+func (h *ExperimentHandler) StartExperiment(c *gin.Context) {
+	experimentID, ok := h.experimentID(c)
+	if !ok {
+		return
+	}
+
+	experiment, err := h.experiments.Start(experimentID)
+	if err != nil {
+		h.handleExperimentError(c, err, "Failed to start experiment")
+		return
+	}
+
+	h.logger.Info("Experiment started", zap.String("experiment_id", experiment.ID.String()))
+
+	c.JSON(http.StatusOK, experiment)
+}
+
+// StopExperiment handles stopping an A/B test (admin only)
+// @Summary Stop experiment
+// @Description Stop a running experiment; visitors no longer get a variant and the results are final
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Experiment ID"
+// @Success 200 {object} models.Experiment
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/v1/admin/experiments/{id}/stop [post]
+func (h *ExperimentHandler) StopExperiment(c *gin.Context) {
+	experimentID, ok := h.experimentID(c)
+	if !ok {
+		return
+	}
+
+	experiment, err := h.experiments.Stop(experimentID)
+	if err != nil {
+		h.handleExperimentError(c, err, "Failed to stop experiment")
+		return
+	}
+
+	h.logger.Info("Experiment stopped", zap.String("experiment_id", experiment.ID.String()))
+
+	c.JSON(http.StatusOK, experiment)
+}
+
+// GetExperimentResults handles the results of an A/B test (admin only)
+// @Summary Get experiment results
+// @Description Exposed visitors, attributed bookings and payments, conversion rates and revenue per variant
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Experiment ID"
+// @Success 200 {object} experiments.Results
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/admin/experiments/{id}/results [get]
+func (h *ExperimentHandler) GetExperimentResults(c *gin.Context) {
+	experimentID, ok := h.experimentID(c)
+	if !ok {
+		return
+	}
+
+	results, err := h.experiments.Results(experimentID)
+	if err != nil {
+		h.handleExperimentError(c, err, "Failed to fetch experiment results")
+		return
+	}
+
+	c.JSON(http.StatusOK, results)
+}
+
+func (h *ExperimentHandler) experimentID(c *gin.Context) (uuid.UUID, bool) {
+	experimentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid experiment ID")})
+		return uuid.Nil, false
+	}
+	return experimentID, true
+}
+
+func (h *ExperimentHandler) handleExperimentError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, experiments.ErrExperimentNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "Experiment not found")})
+	case errors.Is(err, experiments.ErrExperimentNotRunning):
+		c.JSON(http.StatusConflict, gin.H{"error": middleware.T(c, "Experiment is not running")})
+	case errors.Is(err, experiments.ErrExperimentNotDraft):
+		c.JSON(http.StatusConflict, gin.H{"error": middleware.T(c, "Experiment has already been started")})
+	case errors.Is(err, experiments.ErrDuplicateKey):
+		c.JSON(http.StatusConflict, gin.H{"error": middleware.T(c, "Experiment key already exists")})
+	case errors.Is(err, experiments.ErrInvalidKey):
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid experiment key")})
+	case errors.Is(err, experiments.ErrInvalidVariants):
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "An experiment needs at least two variants with unique keys and positive weights")})
+	case errors.Is(err, experiments.ErrInvalidConfig):
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Variant config must be a JSON object")})
+	case errors.Is(err, experiments.ErrMissingSubject):
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Visitor ID is required")})
+	default:
+		h.logger.Error(message, zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, message)})
+	}
+}
@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"elterngeld-portal/internal/exports"
+	"elterngeld-portal/internal/middleware"
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/internal/scopes"
+	"elterngeld-portal/pkg/timeutil"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type ExportHandler struct {
+	db      *gorm.DB
+	logger  *zap.Logger
+	exports *exports.Service
+}
+
+func NewExportHandler(db *gorm.DB, logger *zap.Logger, exportService *exports.Service) *ExportHandler {
+	return &ExportHandler{
+		db:      db,
+		logger:  logger,
+		exports: exportService,
+	}
+}
+
+// ExportLeads handles exporting leads as CSV (Berater/Admin only)
+// @Summary Export leads as CSV
+// @Description Export the visible leads as CSV, oldest first. Exports above the configured row threshold (default 50,000) or with stream=true are streamed with chunked transfer encoding and carry no Content-Length.
+// @Tags leads
+// @Security BearerAuth
+// @Produce text/csv
+// @Param status query string false "Only leads with this status"
+// @Param source query string false "Only leads of this source"
+// @Param berater_id query string false "Only leads assigned to this Berater"
+// @Param from query string false "Created on or after this day (YYYY-MM-DD)"
+// @Param to query string false "Created on or before this day (YYYY-MM-DD)"
+// @Param stream query bool false "Stream the export regardless of its size"
+// @Success 200 {file} file "CSV file"
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Router /api/v1/leads/export [get]
+func (h *ExportHandler) ExportLeads(c *gin.Context) {
+	viewer, ok := scopes.FromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "User not authenticated")})
+		return
+	}
+
+	loc := middleware.GetTimezone(c)
+	filter := exports.LeadFilter{
+		Status: models.LeadStatus(c.Query("status")),
+		Source: models.LeadSource(c.Query("source")),
+	}
+	if value := c.Query("berater_id"); value != "" {
+		beraterID, err := uuid.Parse(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid Berater ID")})
+			return
+		}
+		filter.BeraterID = &beraterID
+	}
+	if value := c.Query("from"); value != "" {
+		from, err := timeutil.ParseDate(value, loc)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid period")})
+			return
+		}
+		filter.From = &from
+	}
+	if value := c.Query("to"); value != "" {
+		to, err := timeutil.ParseDate(value, loc)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid period")})
+			return
+		}
+		end := timeutil.AddDays(to, 1, loc)
+		filter.To = &end
+	}
+
+	count, err := h.exports.CountLeads(viewer, filter)
+	if err != nil {
+		h.logger.Error("Failed to count leads for export", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to export leads")})
+		return
+	}
+
+	fileName := fmt.Sprintf("leads-%s.csv", time.Now().In(loc).Format("2006-01-02"))
+	stream, _ := strconv.ParseBool(c.Query("stream"))
+	if !stream && !h.exports.ShouldStream(count) {
+		var buf bytes.Buffer
+		if _, err := h.exports.WriteLeads(&buf, viewer, filter, nil); err != nil {
+			h.logger.Error("Failed to export leads", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to export leads")})
+			return
+		}
+		c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fileName))
+		c.Data(http.StatusOK, "text/csv; charset=utf-8", buf.Bytes())
+		return
+	}
+
+	// Streamed exports are written page by page, without a Content-Length the
+	// response uses chunked transfer encoding
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fileName))
+	c.Header("X-Export-Rows", strconv.FormatInt(count, 10))
+	c.Status(http.StatusOK)
+
+	written, err := h.exports.WriteLeads(c.Writer, viewer, filter, c.Writer.Flush)
+	if err != nil {
+		// The status line is already sent, the client sees a truncated file
+		h.logger.Error("Lead export stream aborted",
+			zap.Int("rows_written", written),
+			zap.Int64("rows_expected", count),
+			zap.Error(err))
+		c.Abort()
+	}
+}
@@ -0,0 +1,484 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"elterngeld-portal/internal/families"
+	"elterngeld-portal/internal/middleware"
+	"elterngeld-portal/internal/scopes"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type FamilyHandler struct {
+	db       *gorm.DB
+	logger   *zap.Logger
+	families *families.Service
+}
+
+func NewFamilyHandler(db *gorm.DB, logger *zap.Logger, familyService *families.Service) *FamilyHandler {
+	return &FamilyHandler{
+		db:       db,
+		logger:   logger,
+		families: familyService,
+	}
+}
+
+// RenameFamilyRequest changes the name of a family
+type RenameFamilyRequest struct {
+	Name string `json:"name" binding:"required,max=200"`
+}
+
+// ListFamilies handles listing families
+// @Summary List families
+// @Description List the families the current user may see with their parents, latest first. Customers see the families they belong to.
+// @Tags families
+// @Security BearerAuth
+// @Produce json
+// @Param search query string false "Part of the family name"
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page (max 100)" default(20)
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/families [get]
+func (h *FamilyHandler) ListFamilies(c *gin.Context) {
+	viewer, ok := scopes.FromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "User not authenticated")})
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(families.DefaultLimit)))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > families.MaxLimit {
+		limit = families.DefaultLimit
+	}
+
+	result, total, err := h.families.List(viewer, families.ListFilter{Search: c.Query("search"), Page: page, Limit: limit})
+	if err != nil {
+		h.handleFamilyError(c, err, "Failed to fetch families")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"families": result,
+		"pagination": gin.H{
+			"page":  page,
+			"limit": limit,
+			"total": total,
+			"pages": (total + int64(limit) - 1) / int64(limit),
+		},
+	})
+}
+
+// CreateFamily handles opening a family
+// @Summary Create family
+// @Description Open a family with its children. Customers become its applicant, staff name the customer who applies.
+// @Tags families
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body families.CreateInput true "Family"
+// @Success 201 {object} families.Case
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/v1/families [post]
+func (h *FamilyHandler) CreateFamily(c *gin.Context) {
+	viewer, ok := scopes.FromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "User not authenticated")})
+		return
+	}
+
+	var req families.CreateInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid request data"), "details": err.Error()})
+		return
+	}
+
+	familyCase, err := h.families.Create(viewer, req)
+	if err != nil {
+		h.handleFamilyError(c, err, "Failed to save family")
+		return
+	}
+
+	c.JSON(http.StatusCreated, familyCase)
+}
+
+// GetFamily handles getting a family case
+// @Summary Get family
+// @Description Get a family with its parents, children, leads, bookings and the applications of its leads
+// @Tags families
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Family ID"
+// @Success 200 {object} families.Case
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/families/{id} [get]
+func (h *FamilyHandler) GetFamily(c *gin.Context) {
+	viewer, familyID, ok := h.familyRequest(c)
+	if !ok {
+		return
+	}
+
+	familyCase, err := h.families.Get(familyID, viewer)
+	if err != nil {
+		h.handleFamilyError(c, err, "Failed to fetch family")
+		return
+	}
+
+	c.JSON(http.StatusOK, familyCase)
+}
+
+// UpdateFamily handles renaming a family
+// @Summary Rename family
+// @Tags families
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Family ID"
+// @Param request body RenameFamilyRequest true "Name"
+// @Success 200 {object} families.Case
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/families/{id} [put]
+func (h *FamilyHandler) UpdateFamily(c *gin.Context) {
+	viewer, familyID, ok := h.familyRequest(c)
+	if !ok {
+		return
+	}
+
+	var req RenameFamilyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid request data"), "details": err.Error()})
+		return
+	}
+
+	familyCase, err := h.families.Rename(familyID, viewer, req.Name)
+	if err != nil {
+		h.handleFamilyError(c, err, "Failed to save family")
+		return
+	}
+
+	c.JSON(http.StatusOK, familyCase)
+}
+
+// DeleteFamily handles dissolving a family
+// @Summary Delete family
+// @Description Dissolve a family. Its leads and bookings stay with the customers who created them.
+// @Tags families
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Family ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/families/{id} [delete]
+func (h *FamilyHandler) DeleteFamily(c *gin.Context) {
+	viewer, familyID, ok := h.familyRequest(c)
+	if !ok {
+		return
+	}
+
+	if err := h.families.Delete(familyID, viewer); err != nil {
+		h.handleFamilyError(c, err, "Failed to delete family")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": middleware.T(c, "Family deleted successfully")})
+}
+
+// AddFamilyMember handles adding the other parent
+// @Summary Add parent
+// @Description Add the other parent to a family. A customer account with the same email is linked and sees the family's leads and bookings.
+// @Tags families
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Family ID"
+// @Param request body families.MemberInput true "Parent"
+// @Success 201 {object} models.FamilyMember
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/v1/families/{id}/members [post]
+func (h *FamilyHandler) AddFamilyMember(c *gin.Context) {
+	viewer, familyID, ok := h.familyRequest(c)
+	if !ok {
+		return
+	}
+
+	var req families.MemberInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid request data"), "details": err.Error()})
+		return
+	}
+
+	member, err := h.families.AddMember(familyID, viewer, req)
+	if err != nil {
+		h.handleFamilyError(c, err, "Failed to save family")
+		return
+	}
+
+	c.JSON(http.StatusCreated, member)
+}
+
+// RemoveFamilyMember handles removing the other parent
+// @Summary Remove parent
+// @Description Remove the other parent from a family. The applicant cannot be removed.
+// @Tags families
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Family ID"
+// @Param memberId path string true "Member ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/v1/families/{id}/members/{memberId} [delete]
+func (h *FamilyHandler) RemoveFamilyMember(c *gin.Context) {
+	viewer, familyID, ok := h.familyRequest(c)
+	if !ok {
+		return
+	}
+
+	memberID, err := uuid.Parse(c.Param("memberId"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "Family member not found")})
+		return
+	}
+
+	if err := h.families.RemoveMember(familyID, memberID, viewer); err != nil {
+		h.handleFamilyError(c, err, "Failed to save family")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": middleware.T(c, "Family member removed successfully")})
+}
+
+// AddFamilyChild handles recording a child
+// @Summary Add child
+// @Description Record a born child with its birth date or an expected child with the due date
+// @Tags families
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Family ID"
+// @Param request body families.ChildInput true "Child"
+// @Success 201 {object} models.FamilyChild
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/families/{id}/children [post]
+func (h *FamilyHandler) AddFamilyChild(c *gin.Context) {
+	viewer, familyID, ok := h.familyRequest(c)
+	if !ok {
+		return
+	}
+
+	var req families.ChildInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid request data"), "details": err.Error()})
+		return
+	}
+
+	child, err := h.families.AddChild(familyID, viewer, req)
+	if err != nil {
+		h.handleFamilyError(c, err, "Failed to save family")
+		return
+	}
+
+	c.JSON(http.StatusCreated, child)
+}
+
+// UpdateFamilyChild handles correcting a child
+// @Summary Update child
+// @Description Correct a child, typically to record the birth after the due date
+// @Tags families
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Family ID"
+// @Param childId path string true "Child ID"
+// @Param request body families.ChildInput true "Child"
+// @Success 200 {object} models.FamilyChild
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/families/{id}/children/{childId} [put]
+func (h *FamilyHandler) UpdateFamilyChild(c *gin.Context) {
+	viewer, familyID, ok := h.familyRequest(c)
+	if !ok {
+		return
+	}
+
+	childID, err := uuid.Parse(c.Param("childId"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "Child not found")})
+		return
+	}
+
+	var req families.ChildInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid request data"), "details": err.Error()})
+		return
+	}
+
+	child, err := h.families.UpdateChild(familyID, childID, viewer, req)
+	if err != nil {
+		h.handleFamilyError(c, err, "Failed to save family")
+		return
+	}
+
+	c.JSON(http.StatusOK, child)
+}
+
+// RemoveFamilyChild handles removing a child recorded by mistake
+// @Summary Remove child
+// @Tags families
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Family ID"
+// @Param childId path string true "Child ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/families/{id}/children/{childId} [delete]
+func (h *FamilyHandler) RemoveFamilyChild(c *gin.Context) {
+	viewer, familyID, ok := h.familyRequest(c)
+	if !ok {
+		return
+	}
+
+	childID, err := uuid.Parse(c.Param("childId"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "Child not found")})
+		return
+	}
+
+	if err := h.families.RemoveChild(familyID, childID, viewer); err != nil {
+		h.handleFamilyError(c, err, "Failed to save family")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": middleware.T(c, "Child removed successfully")})
+}
+
+// AttachFamilyLead handles adding a lead to a family
+// @Summary Attach lead
+// @Description Add a lead and its bookings to a family. The lead has to belong to one of the family's parents.
+// @Tags families
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Family ID"
+// @Param leadId path string true "Lead ID"
+// @Success 200 {object} families.Case
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/v1/families/{id}/leads/{leadId} [post]
+func (h *FamilyHandler) AttachFamilyLead(c *gin.Context) {
+	viewer, familyID, leadID, ok := h.familyLeadRequest(c)
+	if !ok {
+		return
+	}
+
+	if err := h.families.AttachLead(familyID, leadID, viewer); err != nil {
+		h.handleFamilyError(c, err, "Failed to save family")
+		return
+	}
+
+	familyCase, err := h.families.Get(familyID, viewer)
+	if err != nil {
+		h.handleFamilyError(c, err, "Failed to fetch family")
+		return
+	}
+
+	c.JSON(http.StatusOK, familyCase)
+}
+
+// DetachFamilyLead handles removing a lead from a family
+// @Summary Detach lead
+// @Description Remove a lead and its bookings from a family
+// @Tags families
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Family ID"
+// @Param leadId path string true "Lead ID"
+// @Success 200 {object} families.Case
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/families/{id}/leads/{leadId} [delete]
+func (h *FamilyHandler) DetachFamilyLead(c *gin.Context) {
+	viewer, familyID, leadID, ok := h.familyLeadRequest(c)
+	if !ok {
+		return
+	}
+
+	if err := h.families.DetachLead(familyID, leadID, viewer); err != nil {
+		h.handleFamilyError(c, err, "Failed to save family")
+		return
+	}
+
+	familyCase, err := h.families.Get(familyID, viewer)
+	if err != nil {
+		h.handleFamilyError(c, err, "Failed to fetch family")
+		return
+	}
+
+	c.JSON(http.StatusOK, familyCase)
+}
+
+func (h *FamilyHandler) familyRequest(c *gin.Context) (scopes.Viewer, uuid.UUID, bool) {
+	viewer, ok := scopes.FromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "User not authenticated")})
+		return scopes.Viewer{}, uuid.Nil, false
+	}
+
+	familyID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid family ID")})
+		return scopes.Viewer{}, uuid.Nil, false
+	}
+
+	return viewer, familyID, true
+}
+
+func (h *FamilyHandler) familyLeadRequest(c *gin.Context) (scopes.Viewer, uuid.UUID, uuid.UUID, bool) {
+	viewer, familyID, ok := h.familyRequest(c)
+	if !ok {
+		return scopes.Viewer{}, uuid.Nil, uuid.Nil, false
+	}
+
+	leadID, err := uuid.Parse(c.Param("leadId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid lead ID")})
+		return scopes.Viewer{}, uuid.Nil, uuid.Nil, false
+	}
+
+	return viewer, familyID, leadID, true
+}
+
+func (h *FamilyHandler) handleFamilyError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, families.ErrFamilyNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "Family not found")})
+	case errors.Is(err, families.ErrMemberNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "Family member not found")})
+	case errors.Is(err, families.ErrChildNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "Child not found")})
+	case errors.Is(err, families.ErrLeadNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "Lead not found")})
+	case errors.Is(err, families.ErrAlreadyMember):
+		c.JSON(http.StatusConflict, gin.H{"error": middleware.T(c, "Already a member of the family")})
+	case errors.Is(err, families.ErrApplicantRequired):
+		c.JSON(http.StatusConflict, gin.H{"error": middleware.T(c, "The applicant cannot be removed")})
+	case errors.Is(err, families.ErrLeadOfOtherCustomer):
+		c.JSON(http.StatusConflict, gin.H{"error": middleware.T(c, "The lead belongs to a customer outside the family")})
+	case errors.Is(err, families.ErrInvalidInput):
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid request data"), "details": err.Error()})
+	default:
+		h.logger.Error(message, zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, message)})
+	}
+}
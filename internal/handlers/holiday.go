@@ -0,0 +1,197 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"elterngeld-portal/internal/holidays"
+	"elterngeld-portal/internal/middleware"
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/internal/postalcodes"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type HolidayHandler struct {
+	db          *gorm.DB
+	logger      *zap.Logger
+	holidays    *holidays.Service
+	postalCodes *postalcodes.Service
+}
+
+func NewHolidayHandler(db *gorm.DB, logger *zap.Logger, holidayService *holidays.Service, postalCodeService *postalcodes.Service) *HolidayHandler {
+	return &HolidayHandler{
+		db:          db,
+		logger:      logger,
+		holidays:    holidayService,
+		postalCodes: postalCodeService,
+	}
+}
+
+// CreateHolidayOverrideRequest represents the holiday override creation request
+type CreateHolidayOverrideRequest struct {
+	Date       string            `json:"date" binding:"required,datetime=2006-01-02"`
+	Bundesland models.Bundesland `json:"bundesland,omitempty" binding:"omitempty,oneof=BW BY BE BB HB HH HE MV NI NW RP SL SN ST SH TH"`
+	Name       string            `json:"name,omitempty"`
+	IsHoliday  *bool             `json:"is_holiday,omitempty"`
+	Note       string            `json:"note,omitempty"`
+}
+
+// ListHolidays handles listing public holidays
+// @Summary List public holidays
+// @Description List the public holidays of a year in a Bundesland, including admin overrides
+// @Tags holidays
+// @Produce json
+// @Param year query int false "Year (default: current year)"
+// @Param bundesland query string false "Bundesland code (e.g. BY); nationwide holidays only if empty"
+// @Param postal_code query string false "Postal code to derive the Bundesland from when bundesland is empty"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/v1/holidays [get]
+func (h *HolidayHandler) ListHolidays(c *gin.Context) {
+	year, err := strconv.Atoi(c.DefaultQuery("year", strconv.Itoa(time.Now().Year())))
+	if err != nil || year < 1900 || year > 2100 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid year")})
+		return
+	}
+
+	state := models.Bundesland(c.Query("bundesland"))
+	if state != "" && !state.IsValid() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid Bundesland")})
+		return
+	}
+
+	// Postal codes spanning several Bundesländer fall back to nationwide holidays
+	if postalCode := c.Query("postal_code"); state == "" && postalCode != "" {
+		state, err = h.postalCodes.Bundesland(postalCode)
+		if err != nil {
+			h.logger.Error("Failed to derive Bundesland from postal code", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to fetch holidays")})
+			return
+		}
+	}
+
+	result, err := h.holidays.ForYear(year, state)
+	if err != nil {
+		h.logger.Error("Failed to fetch holidays", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to fetch holidays")})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"year":       year,
+		"bundesland": state,
+		"holidays":   result,
+	})
+}
+
+// ListHolidayOverrides handles listing holiday overrides (admin only)
+// @Summary List holiday overrides
+// @Description List admin overrides of the public holiday calendar
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Param year query int false "Filter by year"
+// @Param bundesland query string false "Filter by Bundesland code"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/admin/holiday-overrides [get]
+func (h *HolidayHandler) ListHolidayOverrides(c *gin.Context) {
+	query := h.db.Model(&models.HolidayOverride{})
+	if year, err := strconv.Atoi(c.Query("year")); err == nil {
+		query = query.Where("date LIKE ?", strconv.Itoa(year)+"-%")
+	}
+	if state := c.Query("bundesland"); state != "" {
+		query = query.Where("bundesland = ?", state)
+	}
+
+	var overrides []models.HolidayOverride
+	if err := query.Order("date ASC").Find(&overrides).Error; err != nil {
+		h.logger.Error("Failed to fetch holiday overrides", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to fetch holiday overrides")})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"overrides": overrides})
+}
+
+// CreateHolidayOverride handles adding or removing a public holiday (admin only)
+// @Summary Create holiday override
+// @Description Add a holiday (e.g. a regional or company holiday) or turn a statutory holiday into a working day
+// @Tags admin
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body CreateHolidayOverrideRequest true "Holiday override"
+// @Success 201 {object} models.HolidayOverride
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/v1/admin/holiday-overrides [post]
+func (h *HolidayHandler) CreateHolidayOverride(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "User not authenticated")})
+		return
+	}
+
+	var req CreateHolidayOverrideRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid request data"), "details": err.Error()})
+		return
+	}
+
+	override := models.HolidayOverride{
+		Date:       req.Date,
+		Bundesland: req.Bundesland,
+		Name:       req.Name,
+		IsHoliday:  req.IsHoliday == nil || *req.IsHoliday,
+		Note:       req.Note,
+		CreatedBy:  userID.(uuid.UUID),
+	}
+
+	if err := h.db.Create(&override).Error; err != nil {
+		h.logger.Error("Failed to create holiday override", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to create holiday override")})
+		return
+	}
+
+	h.logger.Info("Holiday override created",
+		zap.String("override_id", override.ID.String()),
+		zap.String("date", override.Date),
+		zap.String("bundesland", string(override.Bundesland)),
+		zap.Bool("is_holiday", override.IsHoliday))
+
+	c.JSON(http.StatusCreated, override)
+}
+
+// DeleteHolidayOverride handles removing a holiday override (admin only)
+// @Summary Delete holiday override
+// @Description Delete an admin override of the public holiday calendar
+// @Tags admin
+// @Security BearerAuth
+// @Param id path string true "Holiday override ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/admin/holiday-overrides/{id} [delete]
+func (h *HolidayHandler) DeleteHolidayOverride(c *gin.Context) {
+	overrideID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid holiday override ID")})
+		return
+	}
+
+	result := h.db.Delete(&models.HolidayOverride{}, "id = ?", overrideID)
+	if result.Error != nil {
+		h.logger.Error("Failed to delete holiday override", zap.Error(result.Error))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to delete holiday override")})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "Holiday override not found")})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": middleware.T(c, "Holiday override deleted successfully")})
+}
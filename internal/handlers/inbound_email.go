@@ -0,0 +1,214 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"elterngeld-portal/internal/inbound"
+	"elterngeld-portal/internal/middleware"
+	"elterngeld-portal/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type InboundEmailHandler struct {
+	db        *gorm.DB
+	logger    *zap.Logger
+	processor *inbound.Processor
+}
+
+func NewInboundEmailHandler(db *gorm.DB, logger *zap.Logger, processor *inbound.Processor) *InboundEmailHandler {
+	return &InboundEmailHandler{
+		db:        db,
+		logger:    logger,
+		processor: processor,
+	}
+}
+
+// InboundEmailWebhookRequest represents an inbound email forwarded by the mail provider
+type InboundEmailWebhookRequest struct {
+	MessageID   string                          `json:"message_id"`
+	InReplyTo   string                          `json:"in_reply_to"`
+	References  []string                        `json:"references"`
+	From        string                          `json:"from" binding:"required"`
+	FromName    string                          `json:"from_name"`
+	To          string                          `json:"to"`
+	Subject     string                          `json:"subject"`
+	Text        string                          `json:"text"`
+	HTML        string                          `json:"html"`
+	ReceivedAt  *time.Time                      `json:"received_at"`
+	Attachments []InboundEmailAttachmentPayload `json:"attachments"`
+}
+
+// InboundEmailAttachmentPayload represents a base64 encoded attachment in the webhook payload
+type InboundEmailAttachmentPayload struct {
+	FileName    string `json:"filename" binding:"required"`
+	ContentType string `json:"content_type"`
+	Content     string `json:"content" binding:"required"` // base64 encoded
+}
+
+// ReceiveInboundEmail handles inbound email webhooks from the mail provider
+// @Summary Receive inbound email
+// @Description Store an inbound email and attach it to the matching lead or contact form
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Param request body InboundEmailWebhookRequest true "Inbound email"
+// @Success 200 {object} models.InboundEmailResponse
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/v1/webhooks/inbound-email [post]
+func (h *InboundEmailHandler) ReceiveInboundEmail(c *gin.Context) {
+	var req InboundEmailWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid request body"), "details": err.Error()})
+		return
+	}
+
+	msg := &inbound.Message{
+		MessageID:  req.MessageID,
+		InReplyTo:  req.InReplyTo,
+		References: req.References,
+		From:       req.From,
+		FromName:   req.FromName,
+		To:         req.To,
+		Subject:    req.Subject,
+		TextBody:   req.Text,
+		HTMLBody:   req.HTML,
+	}
+	if req.ReceivedAt != nil {
+		msg.ReceivedAt = *req.ReceivedAt
+	}
+
+	for _, attachment := range req.Attachments {
+		content, err := base64.StdEncoding.DecodeString(attachment.Content)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid attachment encoding"), "details": attachment.FileName})
+			return
+		}
+		msg.Attachments = append(msg.Attachments, inbound.Attachment{
+			FileName:    attachment.FileName,
+			ContentType: attachment.ContentType,
+			Content:     content,
+		})
+	}
+
+	email, err := h.processor.Process(msg)
+	if err != nil {
+		h.logger.Error("Failed to process inbound email", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to process inbound email")})
+		return
+	}
+
+	c.JSON(http.StatusOK, email.ToResponse())
+}
+
+// ListInboundEmails handles listing received emails
+// @Summary List inbound emails
+// @Description Get list of received emails, e.g. unmatched emails that need manual assignment
+// @Tags inbound-emails
+// @Security BearerAuth
+// @Produce json
+// @Param page query int false "Page number"
+// @Param limit query int false "Items per page"
+// @Param status query string false "Filter by status (matched, unmatched, failed)"
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Router /api/v1/admin/inbound-emails [get]
+func (h *InboundEmailHandler) ListInboundEmails(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+	offset := (page - 1) * limit
+
+	query := h.db.Model(&models.InboundEmail{})
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var emails []models.InboundEmail
+	if err := query.Preload("Attachments").Offset(offset).Limit(limit).
+		Order("received_at DESC").Find(&emails).Error; err != nil {
+		h.logger.Error("Failed to fetch inbound emails", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to fetch inbound emails")})
+		return
+	}
+
+	responses := make([]models.InboundEmailResponse, len(emails))
+	for i := range emails {
+		responses[i] = emails[i].ToResponse()
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"inbound_emails": responses,
+		"pagination": gin.H{
+			"page":  page,
+			"limit": limit,
+			"total": total,
+			"pages": (total + int64(limit) - 1) / int64(limit),
+		},
+	})
+}
+
+// AssignInboundEmail handles manual assignment of an unmatched email to a lead
+// @Summary Assign inbound email to lead
+// @Description Attach an unmatched inbound email to a lead timeline
+// @Tags inbound-emails
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Inbound email ID"
+// @Param request body models.AssignInboundEmailRequest true "Lead assignment"
+// @Success 200 {object} models.InboundEmailResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/v1/admin/inbound-emails/{id}/assign [post]
+func (h *InboundEmailHandler) AssignInboundEmail(c *gin.Context) {
+	emailID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid inbound email ID")})
+		return
+	}
+
+	var req models.AssignInboundEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid request body"), "details": err.Error()})
+		return
+	}
+
+	email, err := h.processor.AssignToLead(emailID, req.LeadID)
+	if err != nil {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "Inbound email or lead not found")})
+		case errors.Is(err, inbound.ErrAlreadyAttached):
+			c.JSON(http.StatusConflict, gin.H{"error": middleware.T(c, "Inbound email is already attached to a lead")})
+		default:
+			h.logger.Error("Failed to assign inbound email", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to assign inbound email")})
+		}
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	h.logger.Info("Inbound email assigned to lead",
+		zap.String("inbound_email_id", email.ID.String()),
+		zap.String("lead_id", req.LeadID.String()),
+		zap.Any("assigned_by", userID))
+
+	c.JSON(http.StatusOK, email.ToResponse())
+}
@@ -0,0 +1,194 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"elterngeld-portal/internal/inbox"
+	"elterngeld-portal/internal/middleware"
+	"elterngeld-portal/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type InboxHandler struct {
+	db     *gorm.DB
+	logger *zap.Logger
+	inbox  *inbox.Service
+}
+
+func NewInboxHandler(db *gorm.DB, logger *zap.Logger, inboxService *inbox.Service) *InboxHandler {
+	return &InboxHandler{
+		db:     db,
+		logger: logger,
+		inbox:  inboxService,
+	}
+}
+
+// GetInbox handles listing the team inbox (Berater and admins)
+// @Summary Get team inbox
+// @Description List the unprocessed contact forms nobody claimed and the new leads without a Berater, oldest first
+// @Tags contact
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} inbox.Inbox
+// @Router /api/v1/contact/inbox [get]
+func (h *InboxHandler) GetInbox(c *gin.Context) {
+	items, err := h.inbox.Unclaimed()
+	if err != nil {
+		h.logger.Error("Failed to fetch team inbox", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to fetch team inbox")})
+		return
+	}
+
+	c.JSON(http.StatusOK, items)
+}
+
+// ClaimContactForm handles claiming a contact form before replying to it (Berater and admins)
+// @Summary Claim contact form
+// @Description Assign a contact form to the current user; fails if someone else claimed it first
+// @Tags contact
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Contact Form ID"
+// @Success 200 {object} models.ContactForm
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/v1/contact/forms/{id}/claim [post]
+func (h *InboxHandler) ClaimContactForm(c *gin.Context) {
+	userID, id, ok := h.claimRequest(c, "Invalid contact form ID")
+	if !ok {
+		return
+	}
+
+	form, err := h.inbox.ClaimContactForm(id, userID)
+	if err != nil {
+		h.handleInboxError(c, err, "Failed to claim contact form")
+		return
+	}
+
+	c.JSON(http.StatusOK, form)
+}
+
+// ReleaseContactForm handles handing a claimed contact form back to the team inbox (Berater and admins)
+// @Summary Release contact form
+// @Description Hand a contact form back to the team inbox; only its claimer and admins can release it
+// @Tags contact
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Contact Form ID"
+// @Success 200 {object} models.ContactForm
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/contact/forms/{id}/release [post]
+func (h *InboxHandler) ReleaseContactForm(c *gin.Context) {
+	userID, id, ok := h.claimRequest(c, "Invalid contact form ID")
+	if !ok {
+		return
+	}
+
+	form, err := h.inbox.ReleaseContactForm(id, userID, middleware.IsAdmin(c))
+	if err != nil {
+		h.handleInboxError(c, err, "Failed to release contact form")
+		return
+	}
+
+	c.JSON(http.StatusOK, form)
+}
+
+// ClaimLead handles a Berater taking on an unassigned lead
+// @Summary Claim lead
+// @Description Assign an unassigned lead to the current Berater; fails if someone else claimed it first
+// @Tags leads
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Lead ID"
+// @Success 200 {object} models.Lead
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/v1/leads/{id}/claim [post]
+func (h *InboxHandler) ClaimLead(c *gin.Context) {
+	userID, id, ok := h.claimRequest(c, "Invalid lead ID")
+	if !ok {
+		return
+	}
+
+	var user models.User
+	if err := h.db.First(&user, "id = ?", userID).Error; err != nil {
+		h.logger.Error("Failed to fetch user", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to claim lead")})
+		return
+	}
+
+	lead, err := h.inbox.ClaimLead(id, &user)
+	if err != nil {
+		h.handleInboxError(c, err, "Failed to claim lead")
+		return
+	}
+
+	c.JSON(http.StatusOK, lead)
+}
+
+// ReleaseLead handles handing a lead back to the team inbox
+// @Summary Release lead
+// @Description Remove the Berater from a lead and hand it back to the team inbox; only its Berater and admins can release it
+// @Tags leads
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Lead ID"
+// @Success 200 {object} models.Lead
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/leads/{id}/release [post]
+func (h *InboxHandler) ReleaseLead(c *gin.Context) {
+	userID, id, ok := h.claimRequest(c, "Invalid lead ID")
+	if !ok {
+		return
+	}
+
+	lead, err := h.inbox.ReleaseLead(id, userID, middleware.IsAdmin(c))
+	if err != nil {
+		h.handleInboxError(c, err, "Failed to release lead")
+		return
+	}
+
+	c.JSON(http.StatusOK, lead)
+}
+
+// claimRequest returns the current user and the item ID of a claim or release request
+func (h *InboxHandler) claimRequest(c *gin.Context, invalidID string) (uuid.UUID, uuid.UUID, bool) {
+	userID, ok := middleware.GetCurrentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "User not authenticated")})
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, invalidID)})
+		return uuid.Nil, uuid.Nil, false
+	}
+	return userID, id, true
+}
+
+func (h *InboxHandler) handleInboxError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, inbox.ErrContactFormNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "Contact form not found")})
+	case errors.Is(err, inbox.ErrLeadNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "Lead not found")})
+	case errors.Is(err, inbox.ErrAlreadyClaimed):
+		c.JSON(http.StatusConflict, gin.H{"error": middleware.T(c, "Someone else has already claimed this item")})
+	case errors.Is(err, inbox.ErrNotClaimer):
+		c.JSON(http.StatusForbidden, gin.H{"error": middleware.T(c, "Only the claimer or an admin can release this item")})
+	case errors.Is(err, inbox.ErrCannotClaimLead):
+		c.JSON(http.StatusForbidden, gin.H{"error": middleware.T(c, "Only Berater can claim leads")})
+	default:
+		h.logger.Error(message, zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, message)})
+	}
+}
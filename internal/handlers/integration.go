@@ -0,0 +1,334 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"elterngeld-portal/internal/integrations"
+	"elterngeld-portal/internal/middleware"
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/internal/outbox"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type IntegrationHandler struct {
+	db           *gorm.DB
+	logger       *zap.Logger
+	integrations *integrations.Service
+	outbox       *outbox.Service
+}
+
+func NewIntegrationHandler(db *gorm.DB, logger *zap.Logger, integrationService *integrations.Service, outboxService *outbox.Service) *IntegrationHandler {
+	return &IntegrationHandler{
+		db:           db,
+		logger:       logger,
+		integrations: integrationService,
+		outbox:       outboxService,
+	}
+}
+
+// CreateAPIKeyRequest represents the request for creating an integration API key
+type CreateAPIKeyRequest struct {
+	Name      string               `json:"name" binding:"required,max=100"`
+	Scopes    []models.APIKeyScope `json:"scopes" binding:"required,min=1,dive,oneof=leads:read leads:write bookings:read comments:write"`
+	ExpiresAt *time.Time           `json:"expires_at"`
+}
+
+// IntegrationLeadRequest represents the request of the create lead action
+type IntegrationLeadRequest struct {
+	Email          string          `json:"email" binding:"required,email"`
+	FirstName      string          `json:"first_name"`
+	LastName       string          `json:"last_name"`
+	Phone          string          `json:"phone"`
+	Title          string          `json:"title" binding:"required"`
+	Description    string          `json:"description"`
+	ChildName      string          `json:"child_name"`
+	ChildBirthDate *time.Time      `json:"child_birth_date"`
+	Priority       models.Priority `json:"priority" binding:"omitempty,oneof=niedrig mittel hoch dringend"`
+	SourceDetails  string          `json:"source_details"`
+}
+
+// IntegrationCommentRequest represents the request of the add comment action
+type IntegrationCommentRequest struct {
+	Content    string `json:"content" binding:"required"`
+	IsInternal bool   `json:"is_internal"`
+}
+
+// ListAPIKeys handles listing the current user's integration API keys
+// @Summary List API keys
+// @Description Get the API keys the current staff member created for Zapier, Make and other integrations
+// @Tags integrations
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/integrations/api-keys [get]
+func (h *IntegrationHandler) ListAPIKeys(c *gin.Context) {
+	userID, _ := middleware.GetCurrentUserID(c)
+
+	keys, err := h.integrations.ListAPIKeys(userID)
+	if err != nil {
+		h.logger.Error("Failed to fetch API keys", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to fetch API keys")})
+		return
+	}
+
+	responses := make([]models.APIKeyResponse, len(keys))
+	for i := range keys {
+		responses[i] = keys[i].ToResponse()
+	}
+
+	c.JSON(http.StatusOK, gin.H{"api_keys": responses})
+}
+
+// CreateAPIKey handles creating an integration API key
+// @Summary Create API key
+// @Description Create a scoped API key for Zapier, Make or another integration. The key is only returned once.
+// @Tags integrations
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body CreateAPIKeyRequest true "API key data"
+// @Success 201 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Router /api/v1/integrations/api-keys [post]
+func (h *IntegrationHandler) CreateAPIKey(c *gin.Context) {
+	var req CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid request data"), "details": err.Error()})
+		return
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Expiry date must be in the future")})
+		return
+	}
+
+	userID, _ := middleware.GetCurrentUserID(c)
+	var owner models.User
+	if err := h.db.First(&owner, "id = ?", userID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "User not found")})
+		return
+	}
+
+	key, plain, err := h.integrations.CreateAPIKey(&owner, req.Name, req.Scopes, req.ExpiresAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, integrations.ErrUnknownScope):
+			c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Unknown API key scope")})
+		case errors.Is(err, integrations.ErrScopeNotPermitted):
+			c.JSON(http.StatusForbidden, gin.H{"error": middleware.T(c, "Scope exceeds your permissions")})
+		default:
+			h.logger.Error("Failed to create API key", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to create API key")})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"api_key": key.ToResponse(),
+		"key":     plain,
+	})
+}
+
+// RevokeAPIKey handles revoking an integration API key
+// @Summary Revoke API key
+// @Description Revoke one of the current user's API keys; admins can revoke any key
+// @Tags integrations
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "API key ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/integrations/api-keys/{id} [delete]
+func (h *IntegrationHandler) RevokeAPIKey(c *gin.Context) {
+	keyID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid API key ID")})
+		return
+	}
+
+	userID, _ := middleware.GetCurrentUserID(c)
+	if err := h.integrations.RevokeAPIKey(keyID, userID, middleware.IsAdmin(c)); err != nil {
+		if errors.Is(err, integrations.ErrAPIKeyNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "API key not found")})
+			return
+		}
+		h.logger.Error("Failed to revoke API key", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to revoke API key")})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": middleware.T(c, "API key revoked")})
+}
+
+// Me handles the connection test of integrations
+// @Summary Test API key
+// @Description Return the API key and its owner; used by Zapier and Make to test a connection
+// @Tags integrations
+// @Security ApiKeyAuth
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Router /api/v1/integrations/me [get]
+func (h *IntegrationHandler) Me(c *gin.Context) {
+	key, _ := middleware.GetCurrentAPIKey(c)
+
+	c.JSON(http.StatusOK, gin.H{
+		"api_key": key.ToResponse(),
+		"user":    key.User.ToResponse(),
+	})
+}
+
+// NewLeadsTrigger handles the new leads polling trigger
+// @Summary New leads trigger
+// @Description Poll leads created after the cursor of the previous poll, oldest first. Without a cursor the most recent leads are returned.
+// @Tags integrations
+// @Security ApiKeyAuth
+// @Produce json
+// @Param cursor query string false "next_cursor of the previous poll"
+// @Param limit query int false "Maximum number of leads (default: 50, max: 100)"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Router /api/v1/integrations/triggers/leads [get]
+func (h *IntegrationHandler) NewLeadsTrigger(c *gin.Context) {
+	key, _ := middleware.GetCurrentAPIKey(c)
+	limit, _ := strconv.Atoi(c.Query("limit"))
+
+	page, err := h.integrations.NewLeads(key, c.Query("cursor"), limit)
+	if err != nil {
+		h.respondPollError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+// NewBookingsTrigger handles the new bookings polling trigger
+// @Summary New bookings trigger
+// @Description Poll bookings created after the cursor of the previous poll, oldest first. Without a cursor the most recent bookings are returned.
+// @Tags integrations
+// @Security ApiKeyAuth
+// @Produce json
+// @Param cursor query string false "next_cursor of the previous poll"
+// @Param limit query int false "Maximum number of bookings (default: 50, max: 100)"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Router /api/v1/integrations/triggers/bookings [get]
+func (h *IntegrationHandler) NewBookingsTrigger(c *gin.Context) {
+	key, _ := middleware.GetCurrentAPIKey(c)
+	limit, _ := strconv.Atoi(c.Query("limit"))
+
+	page, err := h.integrations.NewBookings(key, c.Query("cursor"), limit)
+	if err != nil {
+		h.respondPollError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+func (h *IntegrationHandler) respondPollError(c *gin.Context, err error) {
+	if errors.Is(err, integrations.ErrInvalidCursor) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid cursor")})
+		return
+	}
+	h.logger.Error("Failed to poll integration trigger", zap.String("path", c.FullPath()), zap.Error(err))
+	c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Internal server error")})
+}
+
+// CreateLeadAction handles the create lead action
+// @Summary Create lead action
+// @Description Create a lead for a customer identified by email; unknown customers get an account and can set a password via password reset
+// @Tags integrations
+// @Security ApiKeyAuth
+// @Accept json
+// @Produce json
+// @Param request body IntegrationLeadRequest true "Lead data"
+// @Success 201 {object} models.Lead
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/v1/integrations/actions/leads [post]
+func (h *IntegrationHandler) CreateLeadAction(c *gin.Context) {
+	var req IntegrationLeadRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid request data"), "details": err.Error()})
+		return
+	}
+
+	key, _ := middleware.GetCurrentAPIKey(c)
+	lead, err := h.integrations.CreateLead(key, integrations.LeadInput{
+		Email:          req.Email,
+		FirstName:      req.FirstName,
+		LastName:       req.LastName,
+		Phone:          req.Phone,
+		Title:          req.Title,
+		Description:    req.Description,
+		ChildName:      req.ChildName,
+		ChildBirthDate: req.ChildBirthDate,
+		Priority:       req.Priority,
+		SourceDetails:  req.SourceDetails,
+	}, func(tx *gorm.DB, lead *models.Lead) error {
+		return h.outbox.Enqueue(tx, outbox.TopicChatLeadCreated, lead.ID.String(), outbox.LeadMessage{LeadID: lead.ID})
+	})
+	if err != nil {
+		if errors.Is(err, integrations.ErrStaffEmail) {
+			c.JSON(http.StatusConflict, gin.H{"error": middleware.T(c, "Email belongs to a staff account")})
+			return
+		}
+		h.logger.Error("Failed to create lead from integration", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to create lead")})
+		return
+	}
+
+	c.JSON(http.StatusCreated, lead)
+}
+
+// AddCommentAction handles the add comment action
+// @Summary Add comment action
+// @Description Add a comment to a lead visible to the API key owner
+// @Tags integrations
+// @Security ApiKeyAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Lead ID"
+// @Param request body IntegrationCommentRequest true "Comment data"
+// @Success 201 {object} models.Comment
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/integrations/actions/leads/{id}/comments [post]
+func (h *IntegrationHandler) AddCommentAction(c *gin.Context) {
+	leadID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid lead ID")})
+		return
+	}
+
+	var req IntegrationCommentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid request data"), "details": err.Error()})
+		return
+	}
+
+	key, _ := middleware.GetCurrentAPIKey(c)
+	comment, err := h.integrations.AddComment(key, leadID, req.Content, req.IsInternal)
+	if err != nil {
+		if errors.Is(err, integrations.ErrLeadNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "Lead not found")})
+			return
+		}
+		h.logger.Error("Failed to add comment from integration", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to create comment")})
+		return
+	}
+
+	c.JSON(http.StatusCreated, comment)
+}
@@ -0,0 +1,237 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"elterngeld-portal/internal/email"
+	"elterngeld-portal/internal/interviews"
+	"elterngeld-portal/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type InterviewHandler struct {
+	db           *gorm.DB
+	logger       *zap.Logger
+	interviews   *interviews.Service
+	emailService *email.EmailService
+}
+
+func NewInterviewHandler(db *gorm.DB, logger *zap.Logger, interviewService *interviews.Service, emailService *email.EmailService) *InterviewHandler {
+	return &InterviewHandler{
+		db:           db,
+		logger:       logger,
+		interviews:   interviewService,
+		emailService: emailService,
+	}
+}
+
+// ScheduleInterviewRequest represents the slot an applicant picks
+type ScheduleInterviewRequest struct {
+	TimeslotID uuid.UUID `json:"timeslot_id" binding:"required"`
+}
+
+// ListSlots handles listing upcoming interview slots (admin only)
+// @Summary List interview slots
+// @Description List interview slots from now on, optionally only those no applicant picked yet
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Param open query bool false "Only open slots"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/admin/interview-slots [get]
+func (h *InterviewHandler) ListSlots(c *gin.Context) {
+	slots, err := h.interviews.ListSlots(time.Now(), c.Query("open") == "true")
+	if err != nil {
+		h.logger.Error("Failed to fetch interview slots", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to fetch interview slots")})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"slots": slots})
+}
+
+// CreateSlot handles publishing an interview slot (admin only)
+// @Summary Create interview slot
+// @Description Publish an interview slot applicants can pick; without an interviewer the current user conducts the interview
+// @Tags admin
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body interviews.SlotInput true "Slot data"
+// @Success 201 {object} models.Timeslot
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/v1/admin/interview-slots [post]
+func (h *InterviewHandler) CreateSlot(c *gin.Context) {
+	userID, ok := middleware.GetCurrentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "User not authenticated")})
+		return
+	}
+
+	var req interviews.SlotInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid request data"), "details": err.Error()})
+		return
+	}
+
+	slot, err := h.interviews.CreateSlot(req, userID)
+	if err != nil {
+		h.handleInterviewError(c, err, "Failed to create interview slot")
+		return
+	}
+
+	c.JSON(http.StatusCreated, slot)
+}
+
+// DeleteSlot handles removing an interview slot (admin only)
+// @Summary Delete interview slot
+// @Description Delete an interview slot no applicant picked yet
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Slot ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/v1/admin/interview-slots/{id} [delete]
+func (h *InterviewHandler) DeleteSlot(c *gin.Context) {
+	slotID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid timeslot ID")})
+		return
+	}
+
+	if err := h.interviews.DeleteSlot(slotID); err != nil {
+		h.handleInterviewError(c, err, "Failed to delete interview slot")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": middleware.T(c, "Interview slot deleted")})
+}
+
+// Invite handles inviting an applicant to pick an interview slot (admin only)
+// @Summary Invite applicant to an interview
+// @Description Email the applicant a link to pick one of the open interview slots; earlier links stop working
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Application ID"
+// @Success 201 {object} models.InterviewInvitation
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/v1/admin/job-applications/{id}/interview-invitation [post]
+func (h *InterviewHandler) Invite(c *gin.Context) {
+	userID, ok := middleware.GetCurrentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "User not authenticated")})
+		return
+	}
+
+	applicationID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid application ID")})
+		return
+	}
+
+	invitation, token, err := h.interviews.Invite(applicationID, userID)
+	if err != nil {
+		h.handleInterviewError(c, err, "Failed to create invitation")
+		return
+	}
+
+	application := invitation.Application
+	if err := h.emailService.SendInterviewInvitation(application, &application.Job, token, invitation.ExpiresAt); err != nil {
+		h.logger.Error("Failed to send interview invitation", zap.Error(err), zap.String("invitation_id", invitation.ID.String()))
+	}
+
+	c.JSON(http.StatusCreated, invitation)
+}
+
+// GetInvitation handles opening an interview invitation link
+// @Summary Get interview invitation
+// @Description Get the job of an interview invitation and the slots the applicant can pick
+// @Tags jobs
+// @Produce json
+// @Param token path string true "Invitation token"
+// @Success 200 {object} interviews.Invitation
+// @Failure 404 {object} map[string]interface{}
+// @Failure 410 {object} map[string]interface{}
+// @Router /api/v1/interviews/{token} [get]
+func (h *InterviewHandler) GetInvitation(c *gin.Context) {
+	invitation, err := h.interviews.Open(c.Param("token"))
+	if err != nil {
+		h.handleInterviewError(c, err, "Failed to fetch invitation")
+		return
+	}
+
+	c.JSON(http.StatusOK, invitation)
+}
+
+// Schedule handles an applicant picking an interview slot
+// @Summary Schedule interview
+// @Description Book an interview slot with an invitation link; applicant and interviewer receive a calendar invitation
+// @Tags jobs
+// @Accept json
+// @Produce json
+// @Param token path string true "Invitation token"
+// @Param request body ScheduleInterviewRequest true "Chosen slot"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Failure 410 {object} map[string]interface{}
+// @Router /api/v1/interviews/{token}/schedule [post]
+func (h *InterviewHandler) Schedule(c *gin.Context) {
+	var req ScheduleInterviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid request data"), "details": err.Error()})
+		return
+	}
+
+	interview, err := h.interviews.Schedule(c.Param("token"), req.TimeslotID)
+	if err != nil {
+		h.handleInterviewError(c, err, "Failed to schedule interview")
+		return
+	}
+
+	invite := h.interviews.CalendarInvite(interview)
+	if err := h.emailService.SendInterviewScheduled(interview.Application, interview.Job, interview.Slot, interview.Interviewer, invite); err != nil {
+		h.logger.Error("Failed to send interview confirmation", zap.Error(err), zap.String("application_id", interview.Application.ID.String()))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  middleware.T(c, "Interview scheduled"),
+		"timeslot": interview.Slot.ToResponse(),
+	})
+}
+
+func (h *InterviewHandler) handleInterviewError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, interviews.ErrSlotNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "Interview slot not found")})
+	case errors.Is(err, interviews.ErrSlotInPast):
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Interview slot must be in the future")})
+	case errors.Is(err, interviews.ErrSlotBooked):
+		c.JSON(http.StatusConflict, gin.H{"error": middleware.T(c, "Interview slot is already booked")})
+	case errors.Is(err, interviews.ErrSlotUnavailable):
+		c.JSON(http.StatusConflict, gin.H{"error": middleware.T(c, "Interview slot is no longer available")})
+	case errors.Is(err, interviews.ErrInvalidInterviewer):
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Interviewer must be an active staff member")})
+	case errors.Is(err, interviews.ErrApplicationNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "Application not found")})
+	case errors.Is(err, interviews.ErrCannotInvite):
+		c.JSON(http.StatusConflict, gin.H{"error": middleware.T(c, "Application cannot be invited to an interview")})
+	case errors.Is(err, interviews.ErrInvitationNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "Invitation not found")})
+	case errors.Is(err, interviews.ErrInvitationClosed):
+		c.JSON(http.StatusGone, gin.H{"error": middleware.T(c, "Invitation is no longer valid")})
+	default:
+		h.logger.Error(message, zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, message)})
+	}
+}
@@ -0,0 +1,360 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"elterngeld-portal/internal/applicants"
+	"elterngeld-portal/internal/email"
+	"elterngeld-portal/internal/jobfeed"
+	"elterngeld-portal/internal/middleware"
+	"elterngeld-portal/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type JobApplicationHandler struct {
+	db           *gorm.DB
+	logger       *zap.Logger
+	applicants   *applicants.Service
+	jobs         *jobfeed.Service
+	emailService *email.EmailService
+}
+
+func NewJobApplicationHandler(db *gorm.DB, logger *zap.Logger, applicantService *applicants.Service, jobService *jobfeed.Service, emailService *email.EmailService) *JobApplicationHandler {
+	return &JobApplicationHandler{
+		db:           db,
+		logger:       logger,
+		applicants:   applicantService,
+		jobs:         jobService,
+		emailService: emailService,
+	}
+}
+
+// Apply handles an application submitted on the website
+// @Summary Apply to a job
+// @Description Submit an application to an open job; the applicant receives an email with a link to confirm their email address and track the application
+// @Tags jobs
+// @Accept json
+// @Produce json
+// @Param slug path string true "Job slug"
+// @Param request body applicants.ApplicationInput true "Application"
+// @Success 201 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/v1/jobs/{slug}/applications [post]
+func (h *JobApplicationHandler) Apply(c *gin.Context) {
+	var req applicants.ApplicationInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid request data"), "details": err.Error()})
+		return
+	}
+
+	job, err := h.jobs.OpenBySlug(c.Param("slug"))
+	if err != nil {
+		if errors.Is(err, jobfeed.ErrJobNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "Job not found")})
+			return
+		}
+		h.logger.Error("Failed to fetch job", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to submit application")})
+		return
+	}
+
+	application, token, err := h.applicants.Submit(job, req)
+	if err != nil {
+		if errors.Is(err, applicants.ErrDirectApplyDisabled) {
+			c.JSON(http.StatusConflict, gin.H{"error": middleware.T(c, "This job does not accept direct applications")})
+			return
+		}
+		h.logger.Error("Failed to submit application", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to submit application")})
+		return
+	}
+
+	if err := h.emailService.SendApplicationConfirmation(application, job, token); err != nil {
+		h.logger.Error("Failed to send application confirmation",
+			zap.String("application_id", application.ID.String()),
+			zap.Error(err))
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": middleware.T(c, "Application received, please confirm your email address"),
+	})
+}
+
+// VerifyApplicationEmail handles the link from the application confirmation email
+// @Summary Confirm application email
+// @Description Confirm the email address of an application with the signed link from the confirmation email
+// @Tags jobs
+// @Produce json
+// @Param token query string true "Signed application token"
+// @Success 200 {object} applicants.Tracking
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/v1/applications/verify [get]
+func (h *JobApplicationHandler) VerifyApplicationEmail(c *gin.Context) {
+	token := c.Query("token")
+	if _, err := h.applicants.VerifyEmail(token); err != nil {
+		h.handleTrackingError(c, err, "Failed to verify email")
+		return
+	}
+
+	tracking, err := h.applicants.Track(token)
+	if err != nil {
+		h.handleTrackingError(c, err, "Failed to fetch application status")
+		return
+	}
+
+	c.JSON(http.StatusOK, tracking)
+}
+
+// TrackApplication handles the status page of an application
+// @Summary Track application
+// @Description Show the applicant the status of their application using the signed link from the confirmation email
+// @Tags jobs
+// @Produce json
+// @Param token query string true "Signed application token"
+// @Success 200 {object} applicants.Tracking
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/v1/applications/track [get]
+func (h *JobApplicationHandler) TrackApplication(c *gin.Context) {
+	tracking, err := h.applicants.Track(c.Query("token"))
+	if err != nil {
+		h.handleTrackingError(c, err, "Failed to fetch application status")
+		return
+	}
+
+	c.JSON(http.StatusOK, tracking)
+}
+
+// ListApplications handles listing the job applications (admin only)
+// @Summary List job applications
+// @Description List the applications that were confirmed by email and not anonymized, optionally filtered by job, status and aggregate evaluation score; unscored applications come last when sorting by score
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Param job_id query string false "Job ID"
+// @Param status query string false "Application status"
+// @Param unverified query bool false "Include applications whose email address was not confirmed"
+// @Param min_score query number false "Minimum aggregate score"
+// @Param max_score query number false "Maximum aggregate score"
+// @Param sort_by query string false "created_at, score or last_name" default(created_at)
+// @Param sort_order query string false "asc or desc" default(desc)
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(20)
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/v1/admin/job-applications [get]
+func (h *JobApplicationHandler) ListApplications(c *gin.Context) {
+	page, limit := applicationPagination(c)
+
+	filter := applicants.Filter{
+		Status:     models.ApplicationStatus(c.Query("status")),
+		Unverified: c.Query("unverified") == "true",
+		SortBy:     c.Query("sort_by"),
+		SortOrder:  c.Query("sort_order"),
+	}
+	if jobID := c.Query("job_id"); jobID != "" {
+		id, err := uuid.Parse(jobID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid job ID")})
+			return
+		}
+		filter.JobID = &id
+	}
+	var ok bool
+	if filter.MinScore, ok = scoreQuery(c, "min_score"); !ok {
+		return
+	}
+	if filter.MaxScore, ok = scoreQuery(c, "max_score"); !ok {
+		return
+	}
+
+	applications, total, err := h.applicants.List(filter, page, limit)
+	if err != nil {
+		if errors.Is(err, applicants.ErrInvalidSort) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid sort")})
+			return
+		}
+		h.logger.Error("Failed to fetch applications", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to fetch applications")})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"applications": applications,
+		"pagination": gin.H{
+			"page":  page,
+			"limit": limit,
+			"total": total,
+			"pages": (total + int64(limit) - 1) / int64(limit),
+		},
+	})
+}
+
+// ListTalentPool handles listing the applicants in the talent pool (admin only)
+// @Summary List talent pool
+// @Description List the applications whose applicants agreed to be considered for future jobs
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(20)
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/admin/talent-pool [get]
+func (h *JobApplicationHandler) ListTalentPool(c *gin.Context) {
+	page, limit := applicationPagination(c)
+
+	applications, total, err := h.applicants.TalentPool(page, limit)
+	if err != nil {
+		h.logger.Error("Failed to fetch talent pool", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to fetch talent pool")})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"applications": applications,
+		"pagination": gin.H{
+			"page":  page,
+			"limit": limit,
+			"total": total,
+			"pages": (total + int64(limit) - 1) / int64(limit),
+		},
+	})
+}
+
+// AddToTalentPool handles recording an applicant's consent to the talent pool (admin only)
+// @Summary Add applicant to talent pool
+// @Description Record the consent wording the applicant agreed to and where; the application is then kept after a rejection
+// @Tags admin
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Application ID"
+// @Param request body applicants.ConsentInput true "Consent of the applicant"
+// @Success 200 {object} models.JobApplication
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 410 {object} map[string]interface{}
+// @Router /api/v1/admin/job-applications/{id}/talent-pool [put]
+func (h *JobApplicationHandler) AddToTalentPool(c *gin.Context) {
+	userID, ok := middleware.GetCurrentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "User not authenticated")})
+		return
+	}
+
+	applicationID, ok := h.applicationID(c)
+	if !ok {
+		return
+	}
+
+	var req applicants.ConsentInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid request data"), "details": err.Error()})
+		return
+	}
+
+	application, err := h.applicants.AddToTalentPool(applicationID, req, userID)
+	if err != nil {
+		h.handleTalentPoolError(c, err, "Failed to update talent pool")
+		return
+	}
+
+	c.JSON(http.StatusOK, application)
+}
+
+// RemoveFromTalentPool handles removing an applicant from the talent pool (admin only)
+// @Summary Remove applicant from talent pool
+// @Description Remove an applicant from the talent pool, e.g. after they withdrew their consent; a rejected application then falls under the retention period again
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Application ID"
+// @Success 200 {object} models.JobApplication
+// @Failure 404 {object} map[string]interface{}
+// @Failure 410 {object} map[string]interface{}
+// @Router /api/v1/admin/job-applications/{id}/talent-pool [delete]
+func (h *JobApplicationHandler) RemoveFromTalentPool(c *gin.Context) {
+	userID, ok := middleware.GetCurrentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "User not authenticated")})
+		return
+	}
+
+	applicationID, ok := h.applicationID(c)
+	if !ok {
+		return
+	}
+
+	application, err := h.applicants.RemoveFromTalentPool(applicationID, userID)
+	if err != nil {
+		h.handleTalentPoolError(c, err, "Failed to update talent pool")
+		return
+	}
+
+	c.JSON(http.StatusOK, application)
+}
+
+func (h *JobApplicationHandler) applicationID(c *gin.Context) (uuid.UUID, bool) {
+	applicationID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid application ID")})
+		return uuid.Nil, false
+	}
+	return applicationID, true
+}
+
+func (h *JobApplicationHandler) handleTalentPoolError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, applicants.ErrApplicationNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "Application not found")})
+	case errors.Is(err, applicants.ErrAnonymized):
+		c.JSON(http.StatusGone, gin.H{"error": middleware.T(c, "Application has been anonymized")})
+	case errors.Is(err, applicants.ErrConsentRequired):
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Talent pool requires the consent of the applicant")})
+	default:
+		h.logger.Error(message, zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, message)})
+	}
+}
+
+func applicationPagination(c *gin.Context) (int, int) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(applicants.DefaultLimit)))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > applicants.MaxLimit {
+		limit = applicants.DefaultLimit
+	}
+	return page, limit
+}
+
+// scoreQuery parses an optional score query parameter
+func scoreQuery(c *gin.Context, name string) (*float64, bool) {
+	value := c.Query(name)
+	if value == "" {
+		return nil, true
+	}
+	score, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid score")})
+		return nil, false
+	}
+	return &score, true
+}
+
+func (h *JobApplicationHandler) handleTrackingError(c *gin.Context, err error, message string) {
+	if errors.Is(err, applicants.ErrInvalidToken) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid application link")})
+		return
+	}
+	h.logger.Error(message, zap.Error(err))
+	c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, message)})
+}
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"elterngeld-portal/internal/jobfeed"
+	"elterngeld-portal/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type JobFeedHandler struct {
+	db      *gorm.DB
+	logger  *zap.Logger
+	jobfeed *jobfeed.Service
+}
+
+func NewJobFeedHandler(db *gorm.DB, logger *zap.Logger, jobfeedService *jobfeed.Service) *JobFeedHandler {
+	return &JobFeedHandler{
+		db:      db,
+		logger:  logger,
+		jobfeed: jobfeedService,
+	}
+}
+
+// IndeedFeed handles the XML feed of the open jobs for Indeed
+// @Summary Indeed job feed
+// @Description Get the published jobs that are neither expired nor past their application deadline in the Indeed XML format
+// @Tags jobs
+// @Produce xml
+// @Success 200 {string} string
+// @Router /api/v1/jobs/indeed.xml [get]
+func (h *JobFeedHandler) IndeedFeed(c *gin.Context) {
+	body, err := h.jobfeed.IndeedXML()
+	if err != nil {
+		h.logger.Error("Failed to render job feed", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to render feed")})
+		return
+	}
+
+	c.Header("Cache-Control", "public, max-age=900")
+	c.Data(http.StatusOK, "application/xml; charset=utf-8", body)
+}
+
+// JobPosting handles the schema.org JobPosting of an open job
+// @Summary Job posting structured data
+// @Description Get the JSON-LD JobPosting document for embedding in the job page, as read by Google for Jobs
+// @Tags jobs
+// @Produce json
+// @Param slug path string true "Job slug"
+// @Success 200 {object} jobfeed.JobPosting
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/jobs/{slug}/jsonld [get]
+func (h *JobFeedHandler) JobPosting(c *gin.Context) {
+	posting, err := h.jobfeed.JobPosting(c.Param("slug"))
+	if err != nil {
+		if errors.Is(err, jobfeed.ErrJobNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "Job not found")})
+			return
+		}
+		h.logger.Error("Failed to fetch job", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to fetch job")})
+		return
+	}
+
+	body, err := json.Marshal(posting)
+	if err != nil {
+		h.logger.Error("Failed to render job posting", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to fetch job")})
+		return
+	}
+
+	c.Header("Cache-Control", "public, max-age=900")
+	c.Data(http.StatusOK, "application/ld+json; charset=utf-8", body)
+}
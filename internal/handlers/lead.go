@@ -1,11 +1,21 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
+	"sort"
 	"strconv"
 	"time"
 
+	"elterngeld-portal/internal/activitylog"
+	"elterngeld-portal/internal/beraters"
+	"elterngeld-portal/internal/comments"
+	"elterngeld-portal/internal/middleware"
 	"elterngeld-portal/internal/models"
+	"elterngeld-portal/internal/outbox"
+	"elterngeld-portal/internal/quota"
+	"elterngeld-portal/internal/scopes"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -14,14 +24,22 @@ import (
 )
 
 type LeadHandler struct {
-	db     *gorm.DB
-	logger *zap.Logger
+	db         *gorm.DB
+	logger     *zap.Logger
+	beraters   *beraters.Service
+	comments   *comments.Service
+	activities *activitylog.Service
+	outbox     *outbox.Service
 }
 
-func NewLeadHandler(db *gorm.DB, logger *zap.Logger) *LeadHandler {
+func NewLeadHandler(db *gorm.DB, logger *zap.Logger, beraterService *beraters.Service, commentService *comments.Service, activityLog *activitylog.Service, outboxService *outbox.Service) *LeadHandler {
 	return &LeadHandler{
-		db:     db,
-		logger: logger,
+		db:         db,
+		logger:     logger,
+		beraters:   beraterService,
+		comments:   commentService,
+		activities: activityLog,
+		outbox:     outboxService,
 	}
 }
 
@@ -39,6 +57,9 @@ type CreateLeadRequest struct {
 	UTMCampaign   string              `json:"utm_campaign,omitempty"`
 	UTMMedium     string              `json:"utm_medium,omitempty"`
 	Notes         string              `json:"notes,omitempty"`
+
+	// Specializations the case requires, used to match a Berater
+	Topics []models.Specialization `json:"topics,omitempty" binding:"omitempty,dive,oneof=selbststaendige beamte zwillinge widerspruch"`
 }
 
 // UpdateLeadRequest represents the lead update request
@@ -52,6 +73,8 @@ type UpdateLeadRequest struct {
 	ContactPhone   string              `json:"contact_phone,omitempty"`
 	Notes          string              `json:"notes,omitempty"`
 	FollowUpDate   *time.Time          `json:"follow_up_date,omitempty"`
+
+	Topics []models.Specialization `json:"topics,omitempty" binding:"omitempty,dive,oneof=selbststaendige beamte zwillinge widerspruch"`
 }
 
 // UpdateLeadStatusRequest represents the lead status update request
@@ -68,6 +91,13 @@ type AssignLeadRequest struct {
 
 // CreateCommentRequest represents the comment creation request
 type CreateCommentRequest struct {
+	Content     string      `json:"content" form:"content" binding:"required"`
+	IsInternal  bool        `json:"is_internal" form:"is_internal"`
+	DocumentIDs []uuid.UUID `json:"document_ids" form:"document_ids"`
+}
+
+// UpdateCommentRequest represents the comment update request
+type UpdateCommentRequest struct {
 	Content string `json:"content" binding:"required"`
 }
 
@@ -95,19 +125,18 @@ type LeadResponse struct {
 // @Param priority query string false "Filter by priority"
 // @Param source query string false "Filter by source"
 // @Param assigned_to query string false "Filter by assigned user"
+// @Param email_status query string false "Filter by client email status (valid, disposable, undeliverable, or invalid for both)"
 // @Param search query string false "Search in title or description"
 // @Success 200 {object} map[string]interface{}
 // @Failure 401 {object} map[string]interface{}
 // @Router /api/v1/leads [get]
 func (h *LeadHandler) ListLeads(c *gin.Context) {
-	userID, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+	viewer, ok := scopes.FromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "User not authenticated")})
 		return
 	}
 
-	userRole, _ := c.Get("user_role")
-
 	// Parse pagination
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
@@ -118,30 +147,17 @@ func (h *LeadHandler) ListLeads(c *gin.Context) {
 	priority := c.Query("priority")
 	source := c.Query("source")
 	assignedTo := c.Query("assigned_to")
+	emailStatus := c.Query("email_status")
 	search := c.Query("search")
 	myLeads := c.Query("my_leads") == "true"
 
 	// Build query
-	query := h.db.Model(&models.Lead{})
-
-	// Role-based filtering
-	if userRole == "user" {
-		// Users can only see their own leads
-		query = query.Where("user_id = ?", userID)
-	} else if userRole == "junior_berater" {
-		// Junior beraters can see assigned leads or unassigned ones
-		if myLeads {
-			query = query.Where("assigned_to_id = ?", userID)
-		} else {
-			query = query.Where("assigned_to_id = ? OR assigned_to_id IS NULL", userID)
-		}
-	} else if userRole == "berater" {
-		// Beraters can see all leads but have option to filter their own
-		if myLeads {
-			query = query.Where("assigned_to_id = ?", userID)
-		}
+	query := h.db.Model(&models.Lead{}).Scopes(scopes.VisibleLeads(viewer))
+
+	// Berater can narrow the list down to the leads assigned to them
+	if myLeads && viewer.Role != models.RoleUser {
+		query = query.Where("leads.berater_id = ?", viewer.ID)
 	}
-	// Admins can see all leads without restrictions
 
 	// Apply filters
 	if status != "" {
@@ -156,6 +172,11 @@ func (h *LeadHandler) ListLeads(c *gin.Context) {
 	if assignedTo != "" {
 		query = query.Where("assigned_to_id = ?", assignedTo)
 	}
+	if emailStatus == "invalid" {
+		query = query.Where("leads.email_status IN ?", []models.EmailStatus{models.EmailStatusDisposable, models.EmailStatusUndeliverable})
+	} else if emailStatus != "" {
+		query = query.Where("leads.email_status = ?", emailStatus)
+	}
 	if search != "" {
 		query = query.Where("title ILIKE ? OR description ILIKE ?", "%"+search+"%", "%"+search+"%")
 	}
@@ -169,7 +190,7 @@ func (h *LeadHandler) ListLeads(c *gin.Context) {
 	if err := query.Preload("User").Preload("AssignedTo").Preload("Booking").
 		Offset(offset).Limit(limit).Order("created_at DESC").Find(&leads).Error; err != nil {
 		h.logger.Error("Failed to fetch leads", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch leads"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to fetch leads")})
 		return
 	}
 
@@ -199,13 +220,13 @@ func (h *LeadHandler) ListLeads(c *gin.Context) {
 func (h *LeadHandler) CreateLead(c *gin.Context) {
 	userID, exists := c.Get("user_id")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "User not authenticated")})
 		return
 	}
 
 	var req CreateLeadRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data", "details": err.Error()})
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid request data"), "details": err.Error()})
 		return
 	}
 
@@ -235,24 +256,29 @@ func (h *LeadHandler) CreateLead(c *gin.Context) {
 		CreatedAt:      time.Now(),
 		UpdatedAt:      time.Now(),
 	}
+	lead.SetTopics(req.Topics)
 
-	if err := h.db.Create(&lead).Error; err != nil {
+	actorID := userID.(uuid.UUID)
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&lead).Error; err != nil {
+			return err
+		}
+		if err := h.activities.Record(tx, activitylog.LeadCreated{
+			ActorID: &actorID,
+			LeadID:  lead.ID,
+			Title:   lead.Title,
+			Source:  lead.Source,
+		}); err != nil {
+			return err
+		}
+		return h.outbox.Enqueue(tx, outbox.TopicChatLeadCreated, lead.ID.String(), outbox.LeadMessage{LeadID: lead.ID})
+	})
+	if err != nil {
 		h.logger.Error("Failed to create lead", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create lead"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to create lead")})
 		return
 	}
 
-	// Create activity log
-	activity := models.Activity{
-		ID:           uuid.New(),
-		UserID:       userID.(uuid.UUID),
-		LeadID:       &lead.ID,
-		Type:         models.ActivityTypeLeadCreated,
-		Description:  "Lead created: " + lead.Title,
-		CreatedAt:    time.Now(),
-	}
-	h.db.Create(&activity)
-
 	h.logger.Info("Lead created successfully", 
 		zap.String("lead_id", lead.ID.String()),
 		zap.String("user_id", userID.(uuid.UUID).String()))
@@ -277,40 +303,33 @@ func (h *LeadHandler) CreateLead(c *gin.Context) {
 // @Failure 404 {object} map[string]interface{}
 // @Router /api/v1/leads/{id} [get]
 func (h *LeadHandler) GetLead(c *gin.Context) {
-	userID, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+	viewer, ok := scopes.FromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "User not authenticated")})
 		return
 	}
 
 	leadID := c.Param("id")
-	userRole, _ := c.Get("user_role")
 
 	var lead models.Lead
-	query := h.db.Where("id = ?", leadID)
-
-	// Role-based access control
-	if userRole == "user" {
-		query = query.Where("user_id = ?", userID)
-	} else if userRole == "junior_berater" {
-		query = query.Where("assigned_to_id = ? OR assigned_to_id IS NULL", userID)
-	}
-	// Beraters and Admins can see all leads
+	query := h.db.Scopes(scopes.VisibleLeads(viewer)).Where("id = ?", leadID)
 
 	if err := query.Preload("User").Preload("AssignedTo").Preload("Booking").
-		Preload("Activities").Preload("Documents").First(&lead).Error; err != nil {
+		Preload("Activities").Preload("Documents", func(db *gorm.DB) *gorm.DB {
+		return db.Scopes(scopes.VisibleDocuments(viewer))
+	}).First(&lead).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Lead not found"})
+			c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "Lead not found")})
 		} else {
 			h.logger.Error("Failed to fetch lead", zap.Error(err))
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch lead"})
+			c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to fetch lead")})
 		}
 		return
 	}
 
 	// Get comments
 	var comments []models.Comment
-	h.db.Where("lead_id = ?", lead.ID).Preload("User").Order("created_at ASC").Find(&comments)
+	h.db.Scopes(scopes.VisibleComments(viewer)).Where("lead_id = ?", lead.ID).Preload("User").Order("created_at ASC").Find(&comments)
 
 	// Get todos
 	var todos []models.Todo
@@ -347,36 +366,29 @@ func (h *LeadHandler) GetLead(c *gin.Context) {
 func (h *LeadHandler) UpdateLead(c *gin.Context) {
 	userID, exists := c.Get("user_id")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "User not authenticated")})
 		return
 	}
 
 	leadID := c.Param("id")
-	userRole, _ := c.Get("user_role")
+	viewer, _ := scopes.FromContext(c)
 
 	var lead models.Lead
-	query := h.db.Where("id = ?", leadID)
-
-	// Role-based access control
-	if userRole == "user" {
-		query = query.Where("user_id = ?", userID)
-	} else if userRole == "junior_berater" {
-		query = query.Where("assigned_to_id = ?", userID)
-	}
+	query := h.db.Scopes(scopes.EditableLeads(viewer)).Where("id = ?", leadID)
 
 	if err := query.First(&lead).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Lead not found"})
+			c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "Lead not found")})
 		} else {
 			h.logger.Error("Failed to fetch lead", zap.Error(err))
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch lead"})
+			c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to fetch lead")})
 		}
 		return
 	}
 
 	var req UpdateLeadRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data", "details": err.Error()})
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid request data"), "details": err.Error()})
 		return
 	}
 
@@ -409,34 +421,42 @@ func (h *LeadHandler) UpdateLead(c *gin.Context) {
 	if req.FollowUpDate != nil {
 		updates["follow_up_date"] = req.FollowUpDate
 	}
+	if req.Topics != nil {
+		lead.SetTopics(req.Topics)
+		updates["topics"] = lead.Topics
+	}
 
 	if len(updates) == 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "No valid fields to update"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "No valid fields to update")})
 		return
 	}
 
+	fields := make([]string, 0, len(updates))
+	for field := range updates {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
 	updates["updated_at"] = time.Now()
 
-	if err := h.db.Model(&lead).Updates(updates).Error; err != nil {
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&lead).Updates(updates).Error; err != nil {
+			return err
+		}
+		return h.activities.Record(tx, activitylog.LeadUpdated{
+			ActorID: userID.(uuid.UUID),
+			LeadID:  lead.ID,
+			Fields:  fields,
+		})
+	})
+	if err != nil {
 		h.logger.Error("Failed to update lead", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update lead"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to update lead")})
 		return
 	}
 
-	// Create activity log
-	activity := models.Activity{
-		ID:          uuid.New(),
-		UserID:      userID.(uuid.UUID),
-		LeadID:      &lead.ID,
-		Type:        models.ActivityTypeLeadUpdated,
-		Description: "Lead updated",
-		CreatedAt:   time.Now(),
-	}
-	h.db.Create(&activity)
-
 	// Fetch updated lead
 	if err := h.db.First(&lead, "id = ?", leadID).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch updated lead"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to fetch updated lead")})
 		return
 	}
 
@@ -457,7 +477,7 @@ func (h *LeadHandler) UpdateLead(c *gin.Context) {
 func (h *LeadHandler) DeleteLead(c *gin.Context) {
 	userID, exists := c.Get("user_id")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "User not authenticated")})
 		return
 	}
 
@@ -469,41 +489,40 @@ func (h *LeadHandler) DeleteLead(c *gin.Context) {
 
 	// Only beraters and admins can delete leads
 	if userRole != "berater" && userRole != "admin" {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+		c.JSON(http.StatusForbidden, gin.H{"error": middleware.T(c, "Insufficient permissions")})
 		return
 	}
 
 	if err := query.First(&lead).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Lead not found"})
+			c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "Lead not found")})
 		} else {
 			h.logger.Error("Failed to fetch lead", zap.Error(err))
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch lead"})
+			c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to fetch lead")})
 		}
 		return
 	}
 
 	// Soft delete
-	if err := h.db.Delete(&lead).Error; err != nil {
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&lead).Error; err != nil {
+			return err
+		}
+		return h.activities.Record(tx, activitylog.LeadDeleted{
+			ActorID: userID.(uuid.UUID),
+			LeadID:  lead.ID,
+			Title:   lead.Title,
+		})
+	})
+	if err != nil {
 		h.logger.Error("Failed to delete lead", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete lead"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to delete lead")})
 		return
 	}
 
-	// Create activity log
-	activity := models.Activity{
-		ID:          uuid.New(),
-		UserID:      userID.(uuid.UUID),
-		LeadID:      &lead.ID,
-		Type:        models.ActivityTypeLeadDeleted,
-		Description: "Lead deleted",
-		CreatedAt:   time.Now(),
-	}
-	h.db.Create(&activity)
-
 	h.logger.Info("Lead deleted successfully", zap.String("lead_id", leadID))
 
-	c.JSON(http.StatusOK, gin.H{"message": "Lead deleted successfully"})
+	c.JSON(http.StatusOK, gin.H{"message": middleware.T(c, "Lead deleted successfully")})
 }
 
 // UpdateLeadStatus handles updating lead status
@@ -523,37 +542,33 @@ func (h *LeadHandler) DeleteLead(c *gin.Context) {
 func (h *LeadHandler) UpdateLeadStatus(c *gin.Context) {
 	userID, exists := c.Get("user_id")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "User not authenticated")})
 		return
 	}
 
 	leadID := c.Param("id")
-	userRole, _ := c.Get("user_role")
-
-	var lead models.Lead
-	query := h.db.Where("id = ?", leadID)
-
-	// Role-based access control
-	if userRole == "user" {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Users cannot update lead status"})
+	viewer, _ := scopes.FromContext(c)
+	if viewer.Role == models.RoleUser {
+		c.JSON(http.StatusForbidden, gin.H{"error": middleware.T(c, "Users cannot update lead status")})
 		return
-	} else if userRole == "junior_berater" {
-		query = query.Where("assigned_to_id = ?", userID)
 	}
 
+	var lead models.Lead
+	query := h.db.Scopes(scopes.EditableLeads(viewer)).Where("id = ?", leadID)
+
 	if err := query.First(&lead).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Lead not found"})
+			c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "Lead not found")})
 		} else {
 			h.logger.Error("Failed to fetch lead", zap.Error(err))
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch lead"})
+			c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to fetch lead")})
 		}
 		return
 	}
 
 	var req UpdateLeadStatusRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data", "details": err.Error()})
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid request data"), "details": err.Error()})
 		return
 	}
 
@@ -573,24 +588,24 @@ func (h *LeadHandler) UpdateLeadStatus(c *gin.Context) {
 		lead.DisqualifiedAt = &now
 	}
 
-	if err := h.db.Save(&lead).Error; err != nil {
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&lead).Error; err != nil {
+			return err
+		}
+		return h.activities.Record(tx, activitylog.LeadStatusChanged{
+			ActorID: userID.(uuid.UUID),
+			LeadID:  lead.ID,
+			From:    oldStatus,
+			To:      req.Status,
+			Notes:   req.Notes,
+		})
+	})
+	if err != nil {
 		h.logger.Error("Failed to update lead status", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update lead status"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to update lead status")})
 		return
 	}
 
-	// Create activity log
-	activity := models.Activity{
-		ID:          uuid.New(),
-		UserID:      userID.(uuid.UUID),
-		LeadID:      &lead.ID,
-		Type:        models.ActivityTypeStatusChanged,
-		Description: "Status changed from " + string(oldStatus) + " to " + string(req.Status),
-		Metadata:    req.Notes,
-		CreatedAt:   time.Now(),
-	}
-	h.db.Create(&activity)
-
 	h.logger.Info("Lead status updated", 
 		zap.String("lead_id", leadID),
 		zap.String("old_status", string(oldStatus)),
@@ -616,7 +631,7 @@ func (h *LeadHandler) UpdateLeadStatus(c *gin.Context) {
 func (h *LeadHandler) AssignLead(c *gin.Context) {
 	userID, exists := c.Get("user_id")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "User not authenticated")})
 		return
 	}
 
@@ -625,17 +640,17 @@ func (h *LeadHandler) AssignLead(c *gin.Context) {
 	var lead models.Lead
 	if err := h.db.Where("id = ?", leadID).First(&lead).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Lead not found"})
+			c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "Lead not found")})
 		} else {
 			h.logger.Error("Failed to fetch lead", zap.Error(err))
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch lead"})
+			c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to fetch lead")})
 		}
 		return
 	}
 
 	var req AssignLeadRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data", "details": err.Error()})
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid request data"), "details": err.Error()})
 		return
 	}
 
@@ -644,10 +659,10 @@ func (h *LeadHandler) AssignLead(c *gin.Context) {
 	if err := h.db.Where("id = ? AND role IN ?", req.AssignedToID, 
 		[]string{"berater", "junior_berater"}).First(&assignedUser).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user or user cannot be assigned leads"})
+			c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid user or user cannot be assigned leads")})
 		} else {
 			h.logger.Error("Failed to fetch assigned user", zap.Error(err))
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify assigned user"})
+			c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to verify assigned user")})
 		}
 		return
 	}
@@ -658,37 +673,50 @@ func (h *LeadHandler) AssignLead(c *gin.Context) {
 	*lead.AssignedAt = time.Now()
 	lead.UpdatedAt = time.Now()
 
-	if err := h.db.Save(&lead).Error; err != nil {
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&lead).Error; err != nil {
+			return err
+		}
+		if err := h.activities.Record(tx, activitylog.LeadAssigned{
+			ActorID:     userID.(uuid.UUID),
+			LeadID:      lead.ID,
+			BeraterID:   assignedUser.ID,
+			BeraterName: assignedUser.FullName(),
+			PreviousID:  oldAssignedTo,
+			Notes:       req.Notes,
+		}); err != nil {
+			return err
+		}
+		return h.enqueueAssignmentEmails(tx, lead.ID, assignedUser.ID, lead.UpdatedAt)
+	})
+	if err != nil {
 		h.logger.Error("Failed to assign lead", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to assign lead"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to assign lead")})
 		return
 	}
 
-	// Create activity log
-	description := "Lead assigned to " + assignedUser.FirstName + " " + assignedUser.LastName
-	activity := models.Activity{
-		ID:          uuid.New(),
-		UserID:      userID.(uuid.UUID),
-		LeadID:      &lead.ID,
-		Type:        models.ActivityTypeLeadAssigned,
-		Description: description,
-		Metadata:    req.Notes,
-		CreatedAt:   time.Now(),
-	}
-	h.db.Create(&activity)
-
 	h.logger.Info("Lead assigned", 
 		zap.String("lead_id", leadID),
 		zap.String("assigned_to", req.AssignedToID.String()))
 
-	// TODO: Send assignment notification email
-
 	c.JSON(http.StatusOK, lead)
 }
 
+// enqueueAssignmentEmails queues the emails that notify the Berater of a lead
+// and introduce them to the customer. The assignment time tells a later
+// assignment to the same Berater apart.
+func (h *LeadHandler) enqueueAssignmentEmails(tx *gorm.DB, leadID, beraterID uuid.UUID, assignedAt time.Time) error {
+	key := fmt.Sprintf("%s:%s:%d", leadID, beraterID, assignedAt.UnixNano())
+	message := outbox.AssignmentMessage{LeadID: leadID, BeraterID: beraterID}
+	if err := h.outbox.Enqueue(tx, outbox.TopicEmailLeadAssigned, key, message); err != nil {
+		return err
+	}
+	return h.outbox.Enqueue(tx, outbox.TopicEmailLeadIntroduction, key, message)
+}
+
 // ListLeadComments handles listing comments for a lead
 // @Summary List lead comments
-// @Description Get comments for a specific lead
+// @Description Get comments for a specific lead with short-lived signed links to their attachments
 // @Tags leads
 // @Security BearerAuth
 // @Produce json
@@ -701,39 +729,29 @@ func (h *LeadHandler) ListLeadComments(c *gin.Context) {
 	leadID := c.Param("id")
 
 	// Verify lead exists and user has access
-	userID, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+	viewer, ok := scopes.FromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "User not authenticated")})
 		return
 	}
 
-	userRole, _ := c.Get("user_role")
-
 	var lead models.Lead
-	query := h.db.Where("id = ?", leadID)
-
-	// Role-based access control
-	if userRole == "user" {
-		query = query.Where("user_id = ?", userID)
-	} else if userRole == "junior_berater" {
-		query = query.Where("assigned_to_id = ? OR assigned_to_id IS NULL", userID)
-	}
+	query := h.db.Scopes(scopes.VisibleLeads(viewer)).Where("id = ?", leadID)
 
 	if err := query.First(&lead).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Lead not found"})
+			c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "Lead not found")})
 		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch lead"})
+			c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to fetch lead")})
 		}
 		return
 	}
 
-	// Get comments
-	var comments []models.Comment
-	if err := h.db.Where("lead_id = ?", leadID).Preload("User").
-		Order("created_at ASC").Find(&comments).Error; err != nil {
+	// Get comments with signed links to their attachments
+	comments, err := h.comments.List(lead.ID, viewer)
+	if err != nil {
 		h.logger.Error("Failed to fetch comments", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch comments"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to fetch comments")})
 		return
 	}
 
@@ -744,83 +762,384 @@ func (h *LeadHandler) ListLeadComments(c *gin.Context) {
 
 // CreateLeadComment handles creating a comment for a lead
 // @Summary Create lead comment
-// @Description Add a comment to a lead
+// @Description Add a comment to a lead. Documents of the lead are attached by ID, files are uploaded as multipart form data and stored as documents of the lead.
 // @Tags leads
 // @Security BearerAuth
-// @Accept json
+// @Accept json,multipart/form-data
 // @Produce json
 // @Param id path string true "Lead ID"
 // @Param request body CreateCommentRequest true "Comment data"
-// @Success 201 {object} models.Comment
+// @Param files formData file false "Files to attach"
+// @Success 201 {object} comments.Comment
 // @Failure 400 {object} map[string]interface{}
 // @Failure 401 {object} map[string]interface{}
 // @Failure 404 {object} map[string]interface{}
 // @Router /api/v1/leads/{id}/comments [post]
 func (h *LeadHandler) CreateLeadComment(c *gin.Context) {
-	userID, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+	viewer, ok := scopes.FromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "User not authenticated")})
 		return
 	}
 
 	leadID := c.Param("id")
 
 	// Verify lead exists and user has access
-	userRole, _ := c.Get("user_role")
-
 	var lead models.Lead
-	query := h.db.Where("id = ?", leadID)
-
-	if userRole == "user" {
-		query = query.Where("user_id = ?", userID)
-	} else if userRole == "junior_berater" {
-		query = query.Where("assigned_to_id = ? OR assigned_to_id IS NULL", userID)
-	}
+	query := h.db.Scopes(scopes.VisibleLeads(viewer)).Where("id = ?", leadID)
 
 	if err := query.First(&lead).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Lead not found"})
+			c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "Lead not found")})
 		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch lead"})
+			c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to fetch lead")})
 		}
 		return
 	}
 
 	var req CreateCommentRequest
+	if err := c.ShouldBind(&req); err != nil {
+		if middleware.BodyTooLarge(c, err) {
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid request data"), "details": err.Error()})
+		return
+	}
+
+	// Only staff write internal notes
+	input := comments.Input{
+		Content:     req.Content,
+		IsInternal:  req.IsInternal && viewer.Role != models.RoleUser,
+		DocumentIDs: req.DocumentIDs,
+	}
+
+	form, err := c.MultipartForm()
+	if middleware.BodyTooLarge(c, err) {
+		return
+	}
+	if err == nil {
+		for _, fileHeader := range form.File["files"] {
+			file, err := fileHeader.Open()
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "No file uploaded")})
+				return
+			}
+			defer file.Close()
+
+			input.Uploads = append(input.Uploads, comments.Upload{
+				FileName:    fileHeader.Filename,
+				ContentType: fileHeader.Header.Get("Content-Type"),
+				Size:        fileHeader.Size,
+				Content:     file,
+			})
+		}
+	}
+
+	comment, err := h.comments.Create(&lead, viewer.ID, input)
+	if err != nil {
+		h.handleCommentError(c, err)
+		return
+	}
+
+	result, err := h.comments.Get(comment.ID, viewer)
+	if err != nil {
+		h.logger.Error("Failed to fetch comment", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to create comment")})
+		return
+	}
+
+	c.JSON(http.StatusCreated, result)
+}
+
+// UpdateLeadComment handles editing a comment
+// @Summary Update lead comment
+// @Description Replace the content of a comment, the previous version is kept in the comment's history (author or admin only)
+// @Tags leads
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param commentId path string true "Comment ID"
+// @Param request body UpdateCommentRequest true "Comment content"
+// @Success 200 {object} comments.Comment
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/leads/comments/{commentId} [patch]
+func (h *LeadHandler) UpdateLeadComment(c *gin.Context) {
+	viewer, commentID, ok := h.commentRequest(c)
+	if !ok {
+		return
+	}
+
+	var req UpdateCommentRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data", "details": err.Error()})
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid request data"), "details": err.Error()})
+		return
+	}
+
+	if _, err := h.comments.Update(commentID, viewer, req.Content); err != nil {
+		h.handleCommentError(c, err)
+		return
+	}
+
+	comment, err := h.comments.Get(commentID, viewer)
+	if err != nil {
+		h.handleCommentError(c, err)
 		return
 	}
 
-	// Create comment
-	comment := models.Comment{
-		ID:        uuid.New(),
-		UserID:    userID.(uuid.UUID),
-		LeadID:    &lead.ID,
-		Content:   req.Content,
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
+	c.JSON(http.StatusOK, comment)
+}
+
+// DeleteLeadComment handles deleting a comment
+// @Summary Delete lead comment
+// @Description Remove a comment from the lead's feed (author or admin only)
+// @Tags leads
+// @Security BearerAuth
+// @Produce json
+// @Param commentId path string true "Comment ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/leads/comments/{commentId} [delete]
+func (h *LeadHandler) DeleteLeadComment(c *gin.Context) {
+	viewer, commentID, ok := h.commentRequest(c)
+	if !ok {
+		return
+	}
+
+	if err := h.comments.Delete(commentID, viewer); err != nil {
+		h.handleCommentError(c, err)
+		return
 	}
 
-	if err := h.db.Create(&comment).Error; err != nil {
-		h.logger.Error("Failed to create comment", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create comment"})
+	c.JSON(http.StatusOK, gin.H{"message": middleware.T(c, "Comment deleted")})
+}
+
+// GetLeadCommentHistory handles listing the earlier versions of a comment
+// @Summary Get lead comment history
+// @Description Get the earlier versions of an edited comment, oldest first
+// @Tags leads
+// @Security BearerAuth
+// @Produce json
+// @Param commentId path string true "Comment ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/leads/comments/{commentId}/history [get]
+func (h *LeadHandler) GetLeadCommentHistory(c *gin.Context) {
+	viewer, commentID, ok := h.commentRequest(c)
+	if !ok {
 		return
 	}
 
-	// Create activity log
-	activity := models.Activity{
-		ID:          uuid.New(),
-		UserID:      userID.(uuid.UUID),
-		LeadID:      &lead.ID,
-		Type:        models.ActivityTypeCommentAdded,
-		Description: "Comment added",
-		CreatedAt:   time.Now(),
+	revisions, err := h.comments.History(commentID, viewer)
+	if err != nil {
+		h.handleCommentError(c, err)
+		return
 	}
-	h.db.Create(&activity)
 
-	// Load user relation
-	h.db.Preload("User").First(&comment, comment.ID)
+	c.JSON(http.StatusOK, gin.H{"revisions": revisions})
+}
+
+// AddLeadCommentReaction handles reacting to a comment
+// @Summary React to lead comment
+// @Description Add the current user's emoji reaction to a comment
+// @Tags leads
+// @Security BearerAuth
+// @Produce json
+// @Param commentId path string true "Comment ID"
+// @Param reaction path string true "Reaction" Enums(thumbs_up, heart, check, eyes, party, question)
+// @Success 200 {object} comments.Comment
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/leads/comments/{commentId}/reactions/{reaction} [put]
+func (h *LeadHandler) AddLeadCommentReaction(c *gin.Context) {
+	h.react(c, h.comments.AddReaction)
+}
 
-	c.JSON(http.StatusCreated, comment)
-}
\ No newline at end of file
+// RemoveLeadCommentReaction handles withdrawing a reaction from a comment
+// @Summary Remove lead comment reaction
+// @Description Remove the current user's emoji reaction from a comment
+// @Tags leads
+// @Security BearerAuth
+// @Produce json
+// @Param commentId path string true "Comment ID"
+// @Param reaction path string true "Reaction" Enums(thumbs_up, heart, check, eyes, party, question)
+// @Success 200 {object} comments.Comment
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/leads/comments/{commentId}/reactions/{reaction} [delete]
+func (h *LeadHandler) RemoveLeadCommentReaction(c *gin.Context) {
+	h.react(c, h.comments.RemoveReaction)
+}
+
+// react applies a reaction change and responds with the updated comment
+func (h *LeadHandler) react(c *gin.Context, change func(uuid.UUID, scopes.Viewer, models.Reaction) error) {
+	viewer, commentID, ok := h.commentRequest(c)
+	if !ok {
+		return
+	}
+
+	if err := change(commentID, viewer, models.Reaction(c.Param("reaction"))); err != nil {
+		h.handleCommentError(c, err)
+		return
+	}
+
+	comment, err := h.comments.Get(commentID, viewer)
+	if err != nil {
+		h.handleCommentError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, comment)
+}
+
+// commentRequest reads the viewer and the comment ID of a comment request
+func (h *LeadHandler) commentRequest(c *gin.Context) (scopes.Viewer, uuid.UUID, bool) {
+	viewer, ok := scopes.FromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "User not authenticated")})
+		return scopes.Viewer{}, uuid.Nil, false
+	}
+
+	commentID, err := uuid.Parse(c.Param("commentId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid comment ID")})
+		return scopes.Viewer{}, uuid.Nil, false
+	}
+
+	return viewer, commentID, true
+}
+
+// handleCommentError maps comment errors to responses
+func (h *LeadHandler) handleCommentError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, comments.ErrCommentNotFound), errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "Comment not found")})
+	case errors.Is(err, comments.ErrNotAuthor):
+		c.JSON(http.StatusForbidden, gin.H{"error": middleware.T(c, "Only the author or an admin can change this comment")})
+	case errors.Is(err, comments.ErrUnknownReaction):
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Unknown reaction")})
+	case errors.Is(err, comments.ErrEmptyComment):
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Comment content is required")})
+	case errors.Is(err, comments.ErrTooManyAttachments):
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Too many attachments")})
+	case errors.Is(err, comments.ErrDocumentNotFound):
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Attached document not found on lead")})
+	case errors.Is(err, comments.ErrFileTooLarge):
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "File size exceeds maximum allowed size")})
+	case errors.Is(err, comments.ErrFileTypeNotAllowed):
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "File type not allowed")})
+	case errors.Is(err, quota.ErrQuotaExceeded):
+		quotaExceeded(c)
+	default:
+		h.logger.Error("Failed to process comment", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to process comment")})
+	}
+}
+
+// GetLeadBeraterSuggestions handles ranking Beraters for a lead
+// @Summary Suggest Beraters for lead
+// @Description Rank bookable Beraters by the lead's topics, the customer's Bundesland and language, rating and workload (Berater/Admin only)
+// @Tags leads
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Lead ID"
+// @Param limit query int false "Maximum number of suggestions (default: 5)"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/leads/{id}/berater-suggestions [get]
+func (h *LeadHandler) GetLeadBeraterSuggestions(c *gin.Context) {
+	var lead models.Lead
+	if err := h.db.Where("id = ?", c.Param("id")).First(&lead).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "Lead not found")})
+		} else {
+			h.logger.Error("Failed to fetch lead", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to fetch lead")})
+		}
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "5"))
+
+	criteria, err := h.beraters.CriteriaForLead(&lead)
+	if err != nil {
+		h.logger.Error("Failed to match beraters", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to match beraters")})
+		return
+	}
+	suggestions, err := h.beraters.Suggest(criteria, limit)
+	if err != nil {
+		h.logger.Error("Failed to match beraters", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to match beraters")})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"topics":      criteria.Topics,
+		"suggestions": suggestions,
+	})
+}
+
+// AutoAssignLead handles assigning a lead to the best matching Berater
+// @Summary Auto-assign lead
+// @Description Assign the lead to the best matching bookable Berater (Berater/Admin only)
+// @Tags leads
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Lead ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/v1/leads/{id}/auto-assign [post]
+func (h *LeadHandler) AutoAssignLead(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "User not authenticated")})
+		return
+	}
+
+	leadID := c.Param("id")
+
+	var lead models.Lead
+	if err := h.db.Where("id = ?", leadID).First(&lead).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "Lead not found")})
+		} else {
+			h.logger.Error("Failed to fetch lead", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to fetch lead")})
+		}
+		return
+	}
+
+	actorID := userID.(uuid.UUID)
+	previousID := lead.BeraterID
+	_, suggestion, err := h.beraters.AutoAssign(&lead, func(tx *gorm.DB, berater *models.User) error {
+		if err := h.activities.Record(tx, activitylog.LeadAssigned{
+			ActorID:     actorID,
+			LeadID:      lead.ID,
+			BeraterID:   berater.ID,
+			BeraterName: berater.FullName(),
+			PreviousID:  previousID,
+			Automatic:   true,
+		}); err != nil {
+			return err
+		}
+		return h.enqueueAssignmentEmails(tx, lead.ID, berater.ID, time.Now())
+	})
+	if err != nil {
+		if errors.Is(err, beraters.ErrNoMatch) {
+			c.JSON(http.StatusConflict, gin.H{"error": middleware.T(c, "No Berater available for this lead")})
+			return
+		}
+		h.logger.Error("Failed to auto-assign lead", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to assign lead")})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"lead":       lead.ToResponse(),
+		"suggestion": suggestion,
+	})
+}
@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"elterngeld-portal/internal/leadaging"
+	"elterngeld-portal/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type LeadAgingHandler struct {
+	db     *gorm.DB
+	logger *zap.Logger
+	aging  *leadaging.Service
+}
+
+func NewLeadAgingHandler(db *gorm.DB, logger *zap.Logger, agingService *leadaging.Service) *LeadAgingHandler {
+	return &LeadAgingHandler{
+		db:     db,
+		logger: logger,
+		aging:  agingService,
+	}
+}
+
+// ListLeadAgingRules handles listing the lead aging rules (admin only)
+// @Summary List lead aging rules
+// @Description Get the rules that mark leads without activity inactive, send win-back emails and close them as lost
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/admin/lead-aging-rules [get]
+func (h *LeadAgingHandler) ListLeadAgingRules(c *gin.Context) {
+	rules, err := h.aging.ListRules()
+	if err != nil {
+		h.logger.Error("Failed to fetch lead aging rules", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to fetch lead aging rules")})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"rules": rules})
+}
+
+// CreateLeadAgingRule handles adding a lead aging rule (admin only)
+// @Summary Create lead aging rule
+// @Description Add the aging rule for an open lead status
+// @Tags admin
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body leadaging.RuleInput true "Lead aging rule"
+// @Success 201 {object} models.LeadAgingRule
+// @Failure 400 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/v1/admin/lead-aging-rules [post]
+func (h *LeadAgingHandler) CreateLeadAgingRule(c *gin.Context) {
+	var req leadaging.RuleInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid request data"), "details": err.Error()})
+		return
+	}
+
+	rule, err := h.aging.CreateRule(req)
+	if err != nil {
+		h.handleLeadAgingError(c, err, "Failed to create lead aging rule")
+		return
+	}
+
+	h.logger.Info("Lead aging rule created",
+		zap.String("rule_id", rule.ID.String()),
+		zap.String("status", string(rule.Status)))
+
+	c.JSON(http.StatusCreated, rule)
+}
+
+// UpdateLeadAgingRule handles changing a lead aging rule (admin only)
+// @Summary Update lead aging rule
+// @Description Change the periods, win-back emails, lost reason or status of a lead aging rule
+// @Tags admin
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Lead aging rule ID"
+// @Param request body leadaging.RuleInput true "Lead aging rule"
+// @Success 200 {object} models.LeadAgingRule
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/v1/admin/lead-aging-rules/{id} [put]
+func (h *LeadAgingHandler) UpdateLeadAgingRule(c *gin.Context) {
+	ruleID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid lead aging rule ID")})
+		return
+	}
+
+	var req leadaging.RuleInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid request data"), "details": err.Error()})
+		return
+	}
+
+	rule, err := h.aging.UpdateRule(ruleID, req)
+	if err != nil {
+		h.handleLeadAgingError(c, err, "Failed to update lead aging rule")
+		return
+	}
+
+	c.JSON(http.StatusOK, rule)
+}
+
+// DeleteLeadAgingRule handles removing a lead aging rule (admin only)
+// @Summary Delete lead aging rule
+// @Description Remove a lead aging rule; leads it marked inactive stay marked until they show new activity
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Lead aging rule ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/admin/lead-aging-rules/{id} [delete]
+func (h *LeadAgingHandler) DeleteLeadAgingRule(c *gin.Context) {
+	ruleID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid lead aging rule ID")})
+		return
+	}
+
+	if err := h.aging.DeleteRule(ruleID); err != nil {
+		h.handleLeadAgingError(c, err, "Failed to delete lead aging rule")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": middleware.T(c, "Lead aging rule deleted")})
+}
+
+// ApplyLeadAgingRules handles applying the lead aging rules right away (admin only)
+// @Summary Apply lead aging rules
+// @Description Apply the active lead aging rules now instead of waiting for the next background run
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} leadaging.Summary
+// @Router /api/v1/admin/lead-aging-rules/apply [post]
+func (h *LeadAgingHandler) ApplyLeadAgingRules(c *gin.Context) {
+	summary, err := h.aging.Apply()
+	if err != nil {
+		h.logger.Error("Failed to apply lead aging rules", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to apply lead aging rules")})
+		return
+	}
+
+	c.JSON(http.StatusOK, summary)
+}
+
+func (h *LeadAgingHandler) handleLeadAgingError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, leadaging.ErrRuleNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "Lead aging rule not found")})
+	case errors.Is(err, leadaging.ErrInvalidStatus):
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Lead aging rules only apply to open lead statuses")})
+	case errors.Is(err, leadaging.ErrInvalidSequence):
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Leads must not be closed before the last win-back email")})
+	case errors.Is(err, leadaging.ErrRuleExists):
+		c.JSON(http.StatusConflict, gin.H{"error": middleware.T(c, "A lead aging rule for this status already exists")})
+	default:
+		h.logger.Error(message, zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, message)})
+	}
+}
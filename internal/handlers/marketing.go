@@ -0,0 +1,199 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"elterngeld-portal/internal/marketing"
+	"elterngeld-portal/internal/middleware"
+	"elterngeld-portal/pkg/timeutil"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type MarketingHandler struct {
+	db        *gorm.DB
+	logger    *zap.Logger
+	marketing *marketing.Service
+}
+
+func NewMarketingHandler(db *gorm.DB, logger *zap.Logger, marketingService *marketing.Service) *MarketingHandler {
+	return &MarketingHandler{
+		db:        db,
+		logger:    logger,
+		marketing: marketingService,
+	}
+}
+
+// ListMarketingSpend handles listing marketing spend (admin only)
+// @Summary List marketing spend
+// @Description Get the recorded costs of marketing channels and campaigns
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Param from query string false "Only entries ending on or after this day (YYYY-MM-DD)"
+// @Param to query string false "Only entries starting on or before this day (YYYY-MM-DD)"
+// @Param channel query string false "Filter by channel (utm_source)"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/admin/marketing/spend [get]
+func (h *MarketingHandler) ListMarketingSpend(c *gin.Context) {
+	filter := marketing.SpendFilter{
+		From:    c.Query("from"),
+		To:      c.Query("to"),
+		Channel: c.Query("channel"),
+	}
+	for _, value := range []string{filter.From, filter.To} {
+		if _, err := time.Parse(timeutil.DateLayout, value); value != "" && err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid date format. Use YYYY-MM-DD")})
+			return
+		}
+	}
+
+	spend, err := h.marketing.ListSpend(filter)
+	if err != nil {
+		h.logger.Error("Failed to fetch marketing spend", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to fetch marketing spend")})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"marketing_spend": spend})
+}
+
+// CreateMarketingSpend handles recording marketing spend (admin only)
+// @Summary Create marketing spend
+// @Description Record the cost of a channel (utm_source) or campaign (utm_campaign) within a period
+// @Tags admin
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body marketing.SpendInput true "Marketing spend"
+// @Success 201 {object} models.MarketingSpend
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/v1/admin/marketing/spend [post]
+func (h *MarketingHandler) CreateMarketingSpend(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "User not authenticated")})
+		return
+	}
+
+	var req marketing.SpendInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid request data"), "details": err.Error()})
+		return
+	}
+
+	spend, err := h.marketing.CreateSpend(req, userID)
+	if err != nil {
+		h.handleMarketingError(c, err, "Failed to create marketing spend")
+		return
+	}
+
+	c.JSON(http.StatusCreated, spend)
+}
+
+// UpdateMarketingSpend handles changing marketing spend (admin only)
+// @Summary Update marketing spend
+// @Description Change the channel, campaign, period or cost of a spend entry
+// @Tags admin
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Marketing spend ID"
+// @Param request body marketing.SpendInput true "Marketing spend"
+// @Success 200 {object} models.MarketingSpend
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/admin/marketing/spend/{id} [put]
+func (h *MarketingHandler) UpdateMarketingSpend(c *gin.Context) {
+	spendID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid marketing spend ID")})
+		return
+	}
+
+	var req marketing.SpendInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid request data"), "details": err.Error()})
+		return
+	}
+
+	spend, err := h.marketing.UpdateSpend(spendID, req)
+	if err != nil {
+		h.handleMarketingError(c, err, "Failed to update marketing spend")
+		return
+	}
+
+	c.JSON(http.StatusOK, spend)
+}
+
+// DeleteMarketingSpend handles removing marketing spend (admin only)
+// @Summary Delete marketing spend
+// @Description Remove a spend entry
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Marketing spend ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/admin/marketing/spend/{id} [delete]
+func (h *MarketingHandler) DeleteMarketingSpend(c *gin.Context) {
+	spendID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid marketing spend ID")})
+		return
+	}
+
+	if err := h.marketing.DeleteSpend(spendID); err != nil {
+		h.handleMarketingError(c, err, "Failed to delete marketing spend")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": middleware.T(c, "Marketing spend deleted")})
+}
+
+// GetROIReport handles the marketing ROI report (admin only)
+// @Summary Get ROI report
+// @Description Spend, leads, paying customers and revenue per campaign and channel with CAC and ROAS
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Param from query string false "First day (YYYY-MM-DD), defaults to 30 days ago"
+// @Param to query string false "Last day (YYYY-MM-DD), defaults to today"
+// @Success 200 {object} marketing.ROIReport
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/v1/admin/marketing/roi [get]
+func (h *MarketingHandler) GetROIReport(c *gin.Context) {
+	loc := middleware.GetTimezone(c)
+	today := time.Now()
+
+	report, err := h.marketing.Report(marketing.ReportFilter{
+		From:     c.DefaultQuery("from", timeutil.FormatDate(timeutil.AddDays(today, -29, loc), loc)),
+		To:       c.DefaultQuery("to", timeutil.FormatDate(today, loc)),
+		Location: loc,
+	})
+	if err != nil {
+		h.handleMarketingError(c, err, "Failed to build ROI report")
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+func (h *MarketingHandler) handleMarketingError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, marketing.ErrSpendNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "Marketing spend not found")})
+	case errors.Is(err, marketing.ErrInvalidPeriod):
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid period")})
+	case errors.Is(err, marketing.ErrInvalidCost):
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Cost must not be negative")})
+	default:
+		h.logger.Error(message, zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, message)})
+	}
+}
@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"net/http"
+
+	"elterngeld-portal/internal/mergefields"
+	"elterngeld-portal/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type MergeFieldHandler struct {
+	db     *gorm.DB
+	logger *zap.Logger
+}
+
+func NewMergeFieldHandler(db *gorm.DB, logger *zap.Logger) *MergeFieldHandler {
+	return &MergeFieldHandler{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// ListMergeFields handles listing the merge fields (Berater and admins)
+// @Summary List merge fields
+// @Description Get the merge fields snippets and PDF templates may contain, written like {{customer.name}}
+// @Tags merge-fields
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/merge-fields [get]
+func (h *MergeFieldHandler) ListMergeFields(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"fields": mergefields.Fields})
+}
+
+// ValidateTemplateRequest holds the parts of a template, e.g. its subject and body
+type ValidateTemplateRequest struct {
+	Parts map[string]string `json:"parts" binding:"required"`
+}
+
+// ValidateTemplate handles checking the merge fields of a template (Berater and admins)
+// @Summary Validate merge fields
+// @Description Report the unknown merge fields of a template by part and line, with the field that was probably meant, before the template is saved
+// @Tags merge-fields
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body ValidateTemplateRequest true "Template parts"
+// @Success 200 {object} mergefields.Validation
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/v1/merge-fields/validate [post]
+func (h *MergeFieldHandler) ValidateTemplate(c *gin.Context) {
+	var req ValidateTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid request data"), "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, mergefields.Validate(req.Parts))
+}
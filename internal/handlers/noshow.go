@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"elterngeld-portal/internal/middleware"
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/internal/noshow"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type NoShowHandler struct {
+	db      *gorm.DB
+	logger  *zap.Logger
+	noShows *noshow.Service
+}
+
+func NewNoShowHandler(db *gorm.DB, logger *zap.Logger, noShowService *noshow.Service) *NoShowHandler {
+	return &NoShowHandler{
+		db:      db,
+		logger:  logger,
+		noShows: noShowService,
+	}
+}
+
+// RecordAttendanceRequest states whether the customer attended the booking
+type RecordAttendanceRequest struct {
+	Attended *bool `json:"attended" binding:"required"`
+}
+
+// RecordAttendance handles confirming a no-show or marking a booking as completed (Berater of the booking or admin)
+// @Summary Record booking attendance
+// @Description Confirm that the customer missed the appointment, which sends the rescheduling offer right away, or mark the booking as completed. Bookings not completed after their end time are flagged as no-show automatically.
+// @Tags bookings
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Booking ID"
+// @Param request body RecordAttendanceRequest true "Attendance"
+// @Success 200 {object} models.Booking
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/v1/bookings/{id}/attendance [post]
+func (h *NoShowHandler) RecordAttendance(c *gin.Context) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "User not authenticated")})
+		return
+	}
+
+	bookingID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid booking ID")})
+		return
+	}
+
+	var req RecordAttendanceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid request data"), "details": err.Error()})
+		return
+	}
+
+	var user models.User
+	if err := h.db.First(&user, "id = ?", userID).Error; err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "User not found")})
+		return
+	}
+
+	booking, err := h.noShows.RecordAttendance(bookingID, &user, *req.Attended)
+	if err != nil {
+		switch {
+		case errors.Is(err, noshow.ErrBookingNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "Booking not found")})
+		case errors.Is(err, noshow.ErrAccessDenied):
+			c.JSON(http.StatusForbidden, gin.H{"error": middleware.T(c, "Access denied")})
+		case errors.Is(err, noshow.ErrNotEnded):
+			c.JSON(http.StatusConflict, gin.H{"error": middleware.T(c, "The booking has not ended yet")})
+		case errors.Is(err, noshow.ErrInvalidStatus):
+			c.JSON(http.StatusConflict, gin.H{"error": middleware.T(c, "Attendance can only be recorded for confirmed bookings")})
+		default:
+			h.logger.Error("Failed to record attendance", zap.String("booking_id", bookingID.String()), zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to record attendance")})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, booking)
+}
@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"elterngeld-portal/internal/middleware"
+	"elterngeld-portal/internal/outbox"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type OutboxHandler struct {
+	db     *gorm.DB
+	logger *zap.Logger
+	outbox *outbox.Service
+}
+
+func NewOutboxHandler(db *gorm.DB, logger *zap.Logger, outboxService *outbox.Service) *OutboxHandler {
+	return &OutboxHandler{
+		db:     db,
+		logger: logger,
+		outbox: outboxService,
+	}
+}
+
+// ListDeadLetters handles listing outbox messages that used up their delivery attempts (admin only)
+// @Summary List dead-lettered outbox messages
+// @Description Get emails and chat notifications that could not be delivered, latest first
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Param page query int false "Page number"
+// @Param limit query int false "Items per page"
+// @Param topic query string false "Filter by topic, e.g. chat.booking_paid"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/admin/outbox [get]
+func (h *OutboxHandler) ListDeadLetters(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(outbox.DefaultLimit)))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > outbox.MaxLimit {
+		limit = outbox.DefaultLimit
+	}
+
+	messages, total, err := h.outbox.DeadLetters(c.Query("topic"), page, limit)
+	if err != nil {
+		h.logger.Error("Failed to fetch outbox messages", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to fetch outbox messages")})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"messages": messages,
+		"pagination": gin.H{
+			"page":  page,
+			"limit": limit,
+			"total": total,
+			"pages": (total + int64(limit) - 1) / int64(limit),
+		},
+	})
+}
+
+// RetryMessage handles delivering a dead-lettered outbox message again (admin only)
+// @Summary Retry outbox message
+// @Description Deliver a dead-lettered outbox message again with a fresh set of attempts
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Outbox message ID"
+// @Success 202 {object} models.OutboxMessage
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/v1/admin/outbox/{id}/retry [post]
+func (h *OutboxHandler) RetryMessage(c *gin.Context) {
+	messageID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid outbox message ID")})
+		return
+	}
+
+	message, err := h.outbox.Retry(messageID)
+	if err != nil {
+		switch {
+		case errors.Is(err, outbox.ErrMessageNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "Outbox message not found")})
+		case errors.Is(err, outbox.ErrNotDead):
+			c.JSON(http.StatusConflict, gin.H{"error": middleware.T(c, "Outbox message is not dead-lettered")})
+		default:
+			h.logger.Error("Failed to retry outbox message", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to retry outbox message")})
+		}
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	h.logger.Info("Outbox message retried by admin",
+		zap.String("outbox_message_id", message.ID.String()),
+		zap.Any("retried_by", userID))
+
+	c.JSON(http.StatusAccepted, message)
+}
@@ -1,40 +1,76 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
-	"io"
+	"errors"
+	"fmt"
+	"math"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"elterngeld-portal/config"
+	"elterngeld-portal/internal/bookingstate"
+	"elterngeld-portal/internal/catalog"
+	"elterngeld-portal/internal/credit"
+	"elterngeld-portal/internal/experiments"
+	"elterngeld-portal/internal/holds"
+	"elterngeld-portal/internal/ledger"
+	"elterngeld-portal/internal/middleware"
 	"elterngeld-portal/internal/models"
+	"elterngeld-portal/internal/outbox"
+	"elterngeld-portal/internal/paymentlinks"
+	"elterngeld-portal/internal/scopes"
+	"elterngeld-portal/internal/tax"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/stripe/stripe-go/v76"
 	"github.com/stripe/stripe-go/v76/checkout/session"
 	"github.com/stripe/stripe-go/v76/paymentintent"
-	"github.com/stripe/stripe-go/v76/refund"
-	"github.com/stripe/stripe-go/v76/webhook"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
 )
 
 type PaymentHandler struct {
-	db     *gorm.DB
-	logger *zap.Logger
-	config *config.Config
+	db          *gorm.DB
+	logger      *zap.Logger
+	config      *config.Config
+	experiments *experiments.Service
+	holds       *holds.Service
+	credit      *credit.Service
+	outbox      *outbox.Service
+	catalog     *catalog.Service
+	tax         *tax.Service
+	links       *paymentlinks.Service
+	ledger      *ledger.Service
+	bookings    *bookingstate.Service
 }
 
-func NewPaymentHandler(db *gorm.DB, logger *zap.Logger, config *config.Config) *PaymentHandler {
+func NewPaymentHandler(db *gorm.DB, logger *zap.Logger, config *config.Config, experimentService *experiments.Service, holdService *holds.Service, creditService *credit.Service, outboxService *outbox.Service, catalogService *catalog.Service, taxService *tax.Service, paymentLinkService *paymentlinks.Service, ledgerService *ledger.Service, bookingStates *bookingstate.Service) *PaymentHandler {
 	// Initialize Stripe
 	stripe.Key = config.Stripe.SecretKey
+	if config.Stripe.APIURL != "" {
+		stripe.SetBackend(stripe.APIBackend, stripe.GetBackendWithConfig(stripe.APIBackend, &stripe.BackendConfig{
+			URL: stripe.String(config.Stripe.APIURL),
+		}))
+	}
 	
 	return &PaymentHandler{
-		db:     db,
-		logger: logger,
-		config: config,
+		db:          db,
+		logger:      logger,
+		config:      config,
+		experiments: experimentService,
+		holds:       holdService,
+		credit:      creditService,
+		outbox:      outboxService,
+		catalog:     catalogService,
+		tax:         taxService,
+		links:       paymentLinkService,
+		ledger:      ledgerService,
+		bookings:    bookingStates,
 	}
 }
 
@@ -43,12 +79,12 @@ type CreateCheckoutRequest struct {
 	BookingID   uuid.UUID `json:"booking_id" binding:"required"`
 	SuccessURL  string    `json:"success_url,omitempty"`
 	CancelURL   string    `json:"cancel_url,omitempty"`
-}
+	Country     string    `json:"country,omitempty" binding:"omitempty,len=2"` // billing country, Germany when empty
+	VATID       string    `json:"vat_id,omitempty" binding:"max=20"`           // USt-ID of a business customer
 
-// RefundRequest represents the refund request
-type RefundRequest struct {
-	Amount *int64  `json:"amount,omitempty"` // Amount in cents, if nil refund full amount
-	Reason string  `json:"reason,omitempty"`
+	// Company billing details of business customers, printed on the invoice
+	CompanyName    string `json:"company_name,omitempty" binding:"max=200"`
+	BillingAddress string `json:"billing_address,omitempty" binding:"max=500"`
 }
 
 // ListPayments handles listing payments for a user
@@ -66,7 +102,7 @@ type RefundRequest struct {
 func (h *PaymentHandler) ListPayments(c *gin.Context) {
 	userID, exists := c.Get("user_id")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "User not authenticated")})
 		return
 	}
 
@@ -92,7 +128,7 @@ func (h *PaymentHandler) ListPayments(c *gin.Context) {
 	if err := query.Preload("Booking").Preload("Booking.Package").
 		Offset(offset).Limit(limit).Order("created_at DESC").Find(&payments).Error; err != nil {
 		h.logger.Error("Failed to fetch payments", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch payments"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to fetch payments")})
 		return
 	}
 
@@ -123,13 +159,13 @@ func (h *PaymentHandler) ListPayments(c *gin.Context) {
 func (h *PaymentHandler) CreateCheckout(c *gin.Context) {
 	userID, exists := c.Get("user_id")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "User not authenticated")})
 		return
 	}
 
 	var req CreateCheckoutRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data", "details": err.Error()})
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid request data"), "details": err.Error()})
 		return
 	}
 
@@ -138,10 +174,10 @@ func (h *PaymentHandler) CreateCheckout(c *gin.Context) {
 	if err := h.db.Where("id = ? AND user_id = ?", req.BookingID, userID).
 		Preload("Package").Preload("User").First(&booking).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Booking not found"})
+			c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "Booking not found")})
 		} else {
 			h.logger.Error("Failed to fetch booking", zap.Error(err))
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch booking"})
+			c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to fetch booking")})
 		}
 		return
 	}
@@ -150,46 +186,157 @@ func (h *PaymentHandler) CreateCheckout(c *gin.Context) {
 	var existingPayment models.Payment
 	if err := h.db.Where("booking_id = ? AND status = ?", booking.ID, models.PaymentStatusCompleted).
 		First(&existingPayment).Error; err == nil {
-		c.JSON(http.StatusConflict, gin.H{"error": "Booking already paid"})
+		c.JSON(http.StatusConflict, gin.H{"error": middleware.T(c, "Booking already paid")})
 		return
 	}
 
-	// Get add-ons for line items
-	var addOns []models.Package
-	h.db.Table("booking_add_ons").
-		Select("packages.*, booking_add_ons.price as addon_price").
-		Joins("JOIN packages ON packages.id = booking_add_ons.package_id").
-		Where("booking_add_ons.booking_id = ?", booking.ID).
-		Find(&addOns)
+	// Renew the timeslot hold so it covers the whole checkout session
+	hold, err := h.holds.Extend(booking.ID)
+	if err != nil {
+		if errors.Is(err, holds.ErrHoldExpired) {
+			c.JSON(http.StatusConflict, gin.H{"error": middleware.T(c, "The timeslot reservation has expired. Please book again.")})
+		} else {
+			h.logger.Error("Failed to extend timeslot hold", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to create checkout session")})
+		}
+		return
+	}
+	expiresAt := time.Now().Add(24 * time.Hour) // 24 hour expiry
+	if hold != nil {
+		// Payment must not complete after the timeslot was released
+		expiresAt = hold.ExpiresAt
+	}
 
-	// Prepare line items for Stripe
-	var lineItems []*stripe.CheckoutSessionLineItemParams
+	// The price is recomputed from the line items the booking was booked
+	// with, so credit and Stripe charge the same amount
+	quote, err := h.catalog.BookingQuote(h.db, &booking)
+	if err != nil {
+		h.logger.Error("Failed to price booking", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to create checkout session")})
+		return
+	}
 
-	// Main package
-	lineItems = append(lineItems, &stripe.CheckoutSessionLineItemParams{
-		PriceData: &stripe.CheckoutSessionLineItemPriceDataParams{
-			Currency: stripe.String(string(stripe.CurrencyEUR)),
-			ProductData: &stripe.CheckoutSessionLineItemPriceDataProductDataParams{
-				Name:        stripe.String(booking.Package.Name),
-				Description: stripe.String(booking.Package.Description),
-			},
-			UnitAmount: stripe.Int64(int64(booking.Package.Price * 100)), // Convert to cents
-		},
-		Quantity: stripe.Int64(1),
+	// VAT depends on the customer: EU businesses are charged net under
+	// reverse charge, customers outside the EU pay no German VAT. The USt-ID
+	// is checked in both modes; with Stripe Tax the VAT is calculated in
+	// checkout and recorded by the webhook.
+	customer, err := h.tax.Determine(c.Request.Context(), tax.Customer{Country: req.Country, VATID: req.VATID})
+	if err != nil {
+		switch {
+		case errors.Is(err, tax.ErrInvalidCountry):
+			c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid country")})
+		case errors.Is(err, tax.ErrInvalidVATID), errors.Is(err, tax.ErrVATIDRejected):
+			c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "The VAT ID is not valid")})
+		default:
+			h.logger.Error("Failed to determine tax", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to create checkout session")})
+		}
+		return
+	}
+	var determination *tax.Determination
+	if !h.tax.StripeTax() {
+		determination = customer
+	}
+
+	// The billing details of the last checkout are kept for the invoice; a
+	// business without a company name is billed under its name in VIES
+	booking.CompanyName = strings.TrimSpace(req.CompanyName)
+	if booking.CompanyName == "" && customer.VATID != "" {
+		booking.CompanyName = customer.BusinessName
+	}
+	booking.VATID = customer.VATID
+	booking.BillingAddress = strings.TrimSpace(req.BillingAddress)
+	booking.BillingCountry = customer.Country
+	if err := h.db.Model(&booking).Select("company_name", "vat_id", "billing_address", "billing_country").
+		Updates(&booking).Error; err != nil {
+		h.logger.Error("Failed to save billing details", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to create checkout session")})
+		return
+	}
+	amounts := h.tax.Amounts(determination, quote.Total.Gross)
+	booking.TotalAmount = amounts.Gross
+
+	// Account credit is applied before the remainder is charged via Stripe
+	paymentID := uuid.New()
+	creditResult, err := h.credit.Checkout(&booking, paymentID, func(tx *gorm.DB, payment *models.Payment) error {
+		h.tax.Record(payment, determination, amounts)
+		if err := tx.Save(payment).Error; err != nil {
+			return err
+		}
+		if err := h.bookings.Transition(tx, &booking, models.BookingStatusConfirmed, bookingstate.Change{Payment: payment}); err != nil {
+			return err
+		}
+		return h.enqueuePaidSideEffects(tx, &booking, payment)
 	})
+	if err != nil {
+		h.logger.Error("Failed to apply credit", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to create checkout session")})
+		return
+	}
+	if creditResult.Payment != nil {
+		h.completeCreditPayment(&booking, creditResult.Payment)
+		c.JSON(http.StatusOK, gin.H{
+			"paid_with_credit": true,
+			"credit_applied":   creditResult.Applied,
+			"payment_id":       creditResult.Payment.ID,
+		})
+		return
+	}
 
-	// Add-ons
-	for _, addOn := range addOns {
-		lineItems = append(lineItems, &stripe.CheckoutSessionLineItemPriceDataParams{
-			Currency: stripe.String(string(stripe.CurrencyEUR)),
-			ProductData: &stripe.CheckoutSessionLineItemPriceDataProductDataParams{
-				Name:        stripe.String(addOn.Name + " (Add-On)"),
-				Description: stripe.String(addOn.Description),
+	// Charge the package and add-ons at the prices they were booked at
+	var lineItems []*stripe.CheckoutSessionLineItemParams
+	for _, item := range quote.LineItems {
+		if item.Included || item.Kind == catalog.LineItemCoupon {
+			continue // part of the package price, or deducted below
+		}
+		name := item.Name
+		if item.Kind == catalog.LineItemAddon {
+			name += " (Add-On)"
+		}
+		productData := &stripe.CheckoutSessionLineItemPriceDataProductDataParams{Name: stripe.String(name)}
+		if item.Description != "" {
+			productData.Description = stripe.String(item.Description)
+		}
+		lineItems = append(lineItems, &stripe.CheckoutSessionLineItemParams{
+			PriceData: &stripe.CheckoutSessionLineItemPriceDataParams{
+				Currency:    stripe.String(string(stripe.CurrencyEUR)),
+				ProductData: productData,
+				UnitAmount:  stripe.Int64(int64(math.Round(item.Price * 100))), // Convert to cents
 			},
-			UnitAmount: stripe.Int64(int64(addOn.Price * 100)), // Convert to cents
+			Quantity: stripe.Int64(1),
 		})
 	}
 
+	// Stripe Checkout has no negative line items, so a coupon discount,
+	// applied credit or a net price turns the order into a single position
+	// with the remainder
+	if quote.Discount > 0 || creditResult.Applied > 0 || amounts.Gross != quote.Total.Gross {
+		var notes, deductions []string
+		if amounts.Tax == 0 && determination != nil {
+			notes = append(notes, "netto, "+determination.Treatment.GetDisplayName())
+		}
+		if quote.Discount > 0 {
+			deductions = append(deductions, fmt.Sprintf("%.2f € Rabatt", quote.Discount))
+		}
+		if creditResult.Applied > 0 {
+			deductions = append(deductions, fmt.Sprintf("%.2f € Guthaben", creditResult.Applied))
+		}
+		if len(deductions) > 0 {
+			notes = append(notes, "abzüglich "+strings.Join(deductions, " und "))
+		}
+		lineItems = []*stripe.CheckoutSessionLineItemParams{{
+			PriceData: &stripe.CheckoutSessionLineItemPriceDataParams{
+				Currency: stripe.String(string(stripe.CurrencyEUR)),
+				ProductData: &stripe.CheckoutSessionLineItemPriceDataProductDataParams{
+					Name:        stripe.String(quote.LineItems[0].Name),
+					Description: stripe.String(strings.Join(notes, "; ")),
+				},
+				UnitAmount: stripe.Int64(int64(math.Round(creditResult.Remaining * 100))),
+			},
+			Quantity: stripe.Int64(1),
+		}}
+	}
+
 	// Set default URLs if not provided
 	successURL := req.SuccessURL
 	if successURL == "" {
@@ -213,33 +360,39 @@ func (h *PaymentHandler) CreateCheckout(c *gin.Context) {
 			"booking_id": booking.ID.String(),
 			"user_id":    userID.(uuid.UUID).String(),
 		},
-		ExpiresAt: stripe.Int64(time.Now().Add(24 * time.Hour).Unix()), // 24 hour expiry
+		ExpiresAt: stripe.Int64(expiresAt.Unix()),
 	}
+	h.tax.ConfigureCheckout(params)
 
 	session, err := session.New(params)
 	if err != nil {
+		if err := h.credit.Restore(booking.ID); err != nil {
+			h.logger.Error("Failed to restore applied credit", zap.String("booking_id", booking.ID.String()), zap.Error(err))
+		}
 		h.logger.Error("Failed to create Stripe session", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create checkout session"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to create checkout session")})
 		return
 	}
 
 	// Create payment record
 	payment := models.Payment{
-		ID:               uuid.New(),
+		ID:               paymentID,
 		UserID:           userID.(uuid.UUID),
 		BookingID:        &booking.ID,
 		StripeSessionID:  &session.ID,
 		Status:           models.PaymentStatusPending,
-		Amount:           booking.TotalPrice,
+		Amount:           creditResult.Remaining,
+		CreditAmount:     creditResult.Applied,
 		Currency:         booking.Currency,
 		PaymentMethod:    models.PaymentMethodCard,
 		CreatedAt:        time.Now(),
 		UpdatedAt:        time.Now(),
 	}
+	h.tax.Record(&payment, determination, amounts)
 
 	if err := h.db.Create(&payment).Error; err != nil {
 		h.logger.Error("Failed to create payment record", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create payment"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to create payment")})
 		return
 	}
 
@@ -248,12 +401,41 @@ func (h *PaymentHandler) CreateCheckout(c *gin.Context) {
 		zap.String("booking_id", booking.ID.String()))
 
 	c.JSON(http.StatusOK, gin.H{
-		"checkout_url": session.URL,
-		"session_id":   session.ID,
-		"payment_id":   payment.ID,
+		"checkout_url":   session.URL,
+		"session_id":     session.ID,
+		"payment_id":     payment.ID,
+		"credit_applied": creditResult.Applied,
 	})
 }
 
+// completeCreditPayment finishes a booking paid entirely with account credit,
+// like the Stripe webhook does for card payments
+func (h *PaymentHandler) completeCreditPayment(booking *models.Booking, payment *models.Payment) {
+	if err := h.holds.Release(booking.ID, models.HoldReleasePaid); err != nil {
+		h.logger.Error("Failed to release timeslot hold", zap.String("booking_id", booking.ID.String()), zap.Error(err))
+	}
+
+	h.logger.Info("Booking paid with credit",
+		zap.String("payment_id", payment.ID.String()),
+		zap.String("booking_id", booking.ID.String()))
+
+	if err := h.experiments.RecordPayment(payment); err != nil {
+		h.logger.Error("Failed to attribute payment to experiments", zap.Error(err))
+	}
+}
+
+// enqueuePaidSideEffects queues the team notification and the payment receipt
+// of a paid booking; the booking confirmation is sent by the state machine
+// when the booking is confirmed. They are keyed by payment, so replayed
+// payment events do not send them twice.
+func (h *PaymentHandler) enqueuePaidSideEffects(tx *gorm.DB, booking *models.Booking, payment *models.Payment) error {
+	message := outbox.PaymentMessage{BookingID: booking.ID, PaymentID: payment.ID}
+	if err := h.outbox.Enqueue(tx, outbox.TopicChatBookingPaid, payment.ID.String(), message); err != nil {
+		return err
+	}
+	return h.outbox.Enqueue(tx, outbox.TopicEmailPaymentReceipt, payment.ID.String(), message)
+}
+
 // GetPayment handles getting a specific payment
 // @Summary Get payment by ID
 // @Description Get payment details
@@ -266,29 +448,23 @@ func (h *PaymentHandler) CreateCheckout(c *gin.Context) {
 // @Failure 404 {object} map[string]interface{}
 // @Router /api/v1/payments/{id} [get]
 func (h *PaymentHandler) GetPayment(c *gin.Context) {
-	userID, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+	viewer, ok := scopes.FromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "User not authenticated")})
 		return
 	}
 
 	paymentID := c.Param("id")
 
 	var payment models.Payment
-	query := h.db.Where("id = ?", paymentID).Preload("Booking").Preload("Booking.Package")
-
-	// Non-admin users can only see their own payments
-	userRole, _ := c.Get("user_role")
-	if userRole != "admin" && userRole != "berater" {
-		query = query.Where("user_id = ?", userID)
-	}
+	query := h.db.Scopes(scopes.VisiblePayments(viewer)).Where("id = ?", paymentID).Preload("Booking").Preload("Booking.Package")
 
 	if err := query.First(&payment).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Payment not found"})
+			c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "Payment not found")})
 		} else {
 			h.logger.Error("Failed to fetch payment", zap.Error(err))
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch payment"})
+			c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to fetch payment")})
 		}
 		return
 	}
@@ -296,137 +472,13 @@ func (h *PaymentHandler) GetPayment(c *gin.Context) {
 	c.JSON(http.StatusOK, payment)
 }
 
-// RefundPayment handles creating a refund for a payment
-// @Summary Refund payment
-// @Description Create a refund for a payment (Berater/Admin only)
-// @Tags payments
-// @Security BearerAuth
-// @Accept json
-// @Produce json
-// @Param id path string true "Payment ID"
-// @Param request body RefundRequest true "Refund data"
-// @Success 200 {object} map[string]interface{}
-// @Failure 400 {object} map[string]interface{}
-// @Failure 401 {object} map[string]interface{}
-// @Failure 404 {object} map[string]interface{}
-// @Router /api/v1/payments/{id}/refund [post]
-func (h *PaymentHandler) RefundPayment(c *gin.Context) {
-	paymentID := c.Param("id")
-
-	var payment models.Payment
-	if err := h.db.Where("id = ?", paymentID).Preload("Booking").First(&payment).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Payment not found"})
-		} else {
-			h.logger.Error("Failed to fetch payment", zap.Error(err))
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch payment"})
-		}
-		return
-	}
-
-	// Check if payment can be refunded
-	if payment.Status != models.PaymentStatusCompleted {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Payment is not completed"})
-		return
-	}
-
-	if payment.StripePaymentIntentID == nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "No Stripe payment intent found"})
-		return
-	}
-
-	var req RefundRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data", "details": err.Error()})
-		return
-	}
-
-	// Calculate refund amount
-	refundAmount := req.Amount
-	if refundAmount == nil {
-		// Full refund
-		refundAmount = stripe.Int64(int64(payment.Amount * 100)) // Convert to cents
-	}
-
-	// Create Stripe refund
-	refundParams := &stripe.RefundParams{
-		PaymentIntent: payment.StripePaymentIntentID,
-		Amount:        refundAmount,
-	}
-
-	if req.Reason != "" {
-		refundParams.Reason = stripe.String(req.Reason)
-	}
-
-	stripeRefund, err := refund.New(refundParams)
-	if err != nil {
-		h.logger.Error("Failed to create Stripe refund", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create refund"})
-		return
-	}
-
-	// Update payment status
-	refundAmountFloat := float64(*refundAmount) / 100
-	if refundAmountFloat >= payment.Amount {
-		payment.Status = models.PaymentStatusRefunded
-	} else {
-		payment.Status = models.PaymentStatusPartiallyRefunded
-	}
-
-	payment.StripeRefundID = &stripeRefund.ID
-	payment.RefundAmount = &refundAmountFloat
-	payment.RefundedAt = &time.Time{}
-	*payment.RefundedAt = time.Now()
-	payment.UpdatedAt = time.Now()
-
-	if err := h.db.Save(&payment).Error; err != nil {
-		h.logger.Error("Failed to update payment after refund", zap.Error(err))
-		// Note: Refund was successful in Stripe, but we failed to update our DB
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Refund created but failed to update record"})
-		return
-	}
-
-	h.logger.Info("Payment refunded", 
-		zap.String("payment_id", paymentID),
-		zap.Float64("amount", refundAmountFloat))
-
-	c.JSON(http.StatusOK, gin.H{
-		"message":      "Refund created successfully",
-		"refund_id":    stripeRefund.ID,
-		"amount":       refundAmountFloat,
-		"status":       stripeRefund.Status,
-		"payment":      payment,
-	})
-}
-
-// StripeWebhook handles Stripe webhook events
-// @Summary Stripe webhook
-// @Description Handle Stripe webhook events
-// @Tags webhooks
-// @Accept json
-// @Produce json
-// @Param stripe-signature header string true "Stripe signature"
-// @Success 200 {object} map[string]interface{}
-// @Failure 400 {object} map[string]interface{}
-// @Router /api/v1/webhooks/stripe [post]
-func (h *PaymentHandler) StripeWebhook(c *gin.Context) {
-	body, err := io.ReadAll(c.Request.Body)
-	if err != nil {
-		h.logger.Error("Failed to read webhook body", zap.Error(err))
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read request body"})
-		return
+// HandleStripeEvent processes a verified Stripe event stored by the webhook receiver
+func (h *PaymentHandler) HandleStripeEvent(webhookEvent *models.WebhookEvent) error {
+	var event stripe.Event
+	if err := json.Unmarshal([]byte(webhookEvent.Payload), &event); err != nil {
+		return fmt.Errorf("failed to parse stripe event: %w", err)
 	}
 
-	// Verify webhook signature
-	event, err := webhook.ConstructEvent(body, c.GetHeader("Stripe-Signature"), h.config.Stripe.WebhookSecret)
-	if err != nil {
-		h.logger.Error("Failed to verify webhook signature", zap.Error(err))
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid signature"})
-		return
-	}
-
-	h.logger.Info("Received Stripe webhook", zap.String("type", string(event.Type)))
-
 	// Handle different event types
 	switch event.Type {
 	case "checkout.session.completed":
@@ -439,11 +491,13 @@ func (h *PaymentHandler) StripeWebhook(c *gin.Context) {
 		h.handleInvoicePaymentSucceeded(event)
 	case "customer.subscription.created":
 		h.handleSubscriptionCreated(event)
+	case "charge.dispute.created", "charge.dispute.updated", "charge.dispute.closed":
+		h.handleDispute(event)
 	default:
 		h.logger.Info("Unhandled webhook event type", zap.String("type", string(event.Type)))
 	}
 
-	c.JSON(http.StatusOK, gin.H{"received": true})
+	return nil
 }
 
 // handleCheckoutSessionCompleted handles successful checkout sessions
@@ -454,6 +508,12 @@ func (h *PaymentHandler) handleCheckoutSessionCompleted(event stripe.Event) {
 		return
 	}
 
+	// Payment links create their booking once paid
+	if linkID, ok := session.Metadata["payment_link_id"]; ok {
+		h.completePaymentLink(&session, linkID)
+		return
+	}
+
 	// Get booking ID from metadata
 	bookingID, exists := session.Metadata["booking_id"]
 	if !exists {
@@ -468,12 +528,22 @@ func (h *PaymentHandler) handleCheckoutSessionCompleted(event stripe.Event) {
 		return
 	}
 
+	// Replayed events must not be attributed twice
+	alreadyPaid := payment.IsPaid()
+
 	// Update payment status
-	payment.Status = models.PaymentStatusCompleted
-	payment.StripePaymentIntentID = &session.PaymentIntent.ID
-	payment.CompletedAt = &time.Time{}
-	*payment.CompletedAt = time.Now()
-	payment.UpdatedAt = time.Now()
+	now := time.Now()
+	payment.Status = models.PaymentStatusSucceeded
+	if session.PaymentIntent != nil {
+		payment.StripePaymentIntent = session.PaymentIntent.ID
+	}
+	if payment.PaidAt == nil {
+		payment.PaidAt = &now
+	}
+	payment.UpdatedAt = now
+	if h.tax.StripeTax() {
+		h.tax.RecordStripe(&payment, &session)
+	}
 
 	if err := h.db.Save(&payment).Error; err != nil {
 		h.logger.Error("Failed to update payment", zap.Error(err))
@@ -487,10 +557,31 @@ func (h *PaymentHandler) handleCheckoutSessionCompleted(event stripe.Event) {
 		return
 	}
 
-	booking.Status = models.BookingStatusConfirmed
-	booking.UpdatedAt = time.Now()
+	// The paid booking now takes its timeslot place on its own
+	if err := h.holds.Release(booking.ID, models.HoldReleasePaid); err != nil {
+		if errors.Is(err, holds.ErrHoldExpired) {
+			h.logger.Error("Payment completed after the timeslot hold expired, check the timeslot for double booking",
+				zap.String("booking_id", bookingID))
+		} else {
+			h.logger.Error("Failed to release timeslot hold", zap.Error(err))
+		}
+	}
 
-	if err := h.db.Save(&booking).Error; err != nil {
+	// Cancelled bookings stay cancelled, the payment is kept for a refund
+	if booking.Status == models.BookingStatusCancelled {
+		h.logger.Error("Payment completed for a cancelled booking, refund the payment or book again",
+			zap.String("booking_id", bookingID), zap.String("payment_id", payment.ID.String()))
+	}
+
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if booking.Status == models.BookingStatusPending {
+			if err := h.bookings.Transition(tx, &booking, models.BookingStatusConfirmed, bookingstate.Change{Payment: &payment}); err != nil {
+				return err
+			}
+		}
+		return h.enqueuePaidSideEffects(tx, &booking, &payment)
+	})
+	if err != nil {
 		h.logger.Error("Failed to update booking status", zap.Error(err))
 		return
 	}
@@ -499,7 +590,53 @@ func (h *PaymentHandler) handleCheckoutSessionCompleted(event stripe.Event) {
 		zap.String("payment_id", payment.ID.String()),
 		zap.String("booking_id", bookingID))
 
-	// TODO: Send confirmation email
+	if !alreadyPaid {
+		if err := h.experiments.RecordPayment(&payment); err != nil {
+			h.logger.Error("Failed to attribute payment to experiments", zap.Error(err))
+		}
+	}
+}
+
+// completePaymentLink books a paid payment link and records the payment with
+// its tax
+func (h *PaymentHandler) completePaymentLink(session *stripe.CheckoutSession, linkID string) {
+	id, err := uuid.Parse(linkID)
+	if err != nil {
+		h.logger.Error("Invalid payment_link_id in session metadata", zap.String("session_id", session.ID))
+		return
+	}
+
+	checkout := paymentlinks.Checkout{SessionID: session.ID, Amount: float64(session.AmountTotal) / 100}
+	if session.PaymentIntent != nil {
+		checkout.PaymentIntentID = session.PaymentIntent.ID
+	}
+	_, payment, err := h.links.Complete(id, checkout, func(tx *gorm.DB, booking *models.Booking, payment *models.Payment) error {
+		if h.tax.StripeTax() {
+			h.tax.RecordStripe(payment, session)
+		} else if determination, err := h.tax.Determine(context.Background(), tax.Customer{}); err == nil {
+			h.tax.Record(payment, determination, h.tax.Amounts(determination, payment.Amount))
+		}
+		if err := tx.Save(payment).Error; err != nil {
+			return err
+		}
+		return h.enqueuePaidSideEffects(tx, booking, payment)
+	})
+	if err != nil {
+		if errors.Is(err, paymentlinks.ErrNotPayable) {
+			h.logger.Error("Payment link paid after it was paid or cancelled, refund the payment",
+				zap.String("payment_link_id", linkID), zap.String("session_id", session.ID))
+		} else {
+			h.logger.Error("Failed to complete payment link", zap.String("payment_link_id", linkID), zap.Error(err))
+		}
+		return
+	}
+	if payment == nil {
+		return // replayed event
+	}
+
+	if err := h.experiments.RecordPayment(payment); err != nil {
+		h.logger.Error("Failed to attribute payment to experiments", zap.Error(err))
+	}
 }
 
 // handlePaymentIntentSucceeded handles successful payment intents
@@ -547,6 +684,37 @@ func (h *PaymentHandler) handlePaymentIntentFailed(event stripe.Event) {
 	}
 }
 
+// handleDispute keeps chargebacks and inquiries of the customer's bank in sync
+func (h *PaymentHandler) handleDispute(event stripe.Event) {
+	var dispute stripe.Dispute
+	if err := json.Unmarshal(event.Data.Raw, &dispute); err != nil {
+		h.logger.Error("Failed to parse dispute", zap.Error(err))
+		return
+	}
+
+	update := ledger.DisputeUpdate{
+		StripeDisputeID: dispute.ID,
+		Amount:          float64(dispute.Amount) / 100,
+		Currency:        strings.ToUpper(string(dispute.Currency)),
+		Reason:          string(dispute.Reason),
+		Status:          string(dispute.Status),
+		OpenedAt:        time.Unix(dispute.Created, 0),
+	}
+	if dispute.PaymentIntent != nil {
+		update.PaymentIntentID = dispute.PaymentIntent.ID
+	}
+	if dispute.Charge != nil {
+		update.ChargeID = dispute.Charge.ID
+	}
+	if _, err := h.ledger.RecordDispute(update); err != nil {
+		if errors.Is(err, ledger.ErrPaymentNotFound) {
+			h.logger.Warn("Dispute for unknown payment", zap.String("dispute_id", dispute.ID))
+		} else {
+			h.logger.Error("Failed to record dispute", zap.String("dispute_id", dispute.ID), zap.Error(err))
+		}
+	}
+}
+
 // handleInvoicePaymentSucceeded handles successful invoice payments (for subscriptions)
 func (h *PaymentHandler) handleInvoicePaymentSucceeded(event stripe.Event) {
 	// TODO: Implement subscription handling if needed
@@ -570,7 +738,7 @@ func (h *PaymentHandler) handleSubscriptionCreated(event stripe.Event) {
 func (h *PaymentHandler) PaymentSuccessPage(c *gin.Context) {
 	sessionID := c.Query("session_id")
 	if sessionID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Session ID is required"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Session ID is required")})
 		return
 	}
 
@@ -578,14 +746,14 @@ func (h *PaymentHandler) PaymentSuccessPage(c *gin.Context) {
 	session, err := session.Get(sessionID, nil)
 	if err != nil {
 		h.logger.Error("Failed to retrieve session", zap.Error(err))
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid session"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid session")})
 		return
 	}
 
-	// Get booking info from metadata
+	// Get booking info from metadata; payment links are booked by the webhook
 	bookingID, exists := session.Metadata["booking_id"]
-	if !exists {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid session metadata"})
+	if _, isPaymentLink := session.Metadata["payment_link_id"]; !exists && !isPaymentLink {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid session metadata")})
 		return
 	}
 
@@ -608,6 +776,6 @@ func (h *PaymentHandler) PaymentSuccessPage(c *gin.Context) {
 func (h *PaymentHandler) PaymentCancelPage(c *gin.Context) {
 	// Return simple cancel page (in production, this would be a proper HTML template)
 	c.JSON(http.StatusOK, gin.H{
-		"message": "Payment was cancelled. You can try again later.",
+		"message": middleware.T(c, "Payment was cancelled. You can try again later."),
 	})
 }
\ No newline at end of file
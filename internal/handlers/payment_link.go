@@ -0,0 +1,247 @@
+package handlers
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"time"
+
+	"elterngeld-portal/config"
+	"elterngeld-portal/internal/catalog"
+	"elterngeld-portal/internal/middleware"
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/internal/paymentlinks"
+	"elterngeld-portal/internal/tax"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stripe/stripe-go/v76"
+	"github.com/stripe/stripe-go/v76/checkout/session"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type PaymentLinkHandler struct {
+	db     *gorm.DB
+	logger *zap.Logger
+	config *config.Config
+	links  *paymentlinks.Service
+	tax    *tax.Service
+}
+
+func NewPaymentLinkHandler(db *gorm.DB, logger *zap.Logger, config *config.Config, paymentLinkService *paymentlinks.Service, taxService *tax.Service) *PaymentLinkHandler {
+	return &PaymentLinkHandler{
+		db:     db,
+		logger: logger,
+		config: config,
+		links:  paymentLinkService,
+		tax:    taxService,
+	}
+}
+
+// CreatePaymentLinkRequest describes a payment link for a package offer or a custom amount
+type CreatePaymentLinkRequest struct {
+	PackageID   *uuid.UUID `json:"package_id,omitempty"`              // offer of a package at its price
+	Amount      float64    `json:"amount,omitempty" binding:"min=0"`  // gross, overrides the package price
+	Title       string     `json:"title,omitempty" binding:"max=200"` // the package name when empty
+	Description string     `json:"description,omitempty" binding:"max=1000"`
+	ValidDays   int        `json:"valid_days,omitempty" binding:"min=0,max=90"` // 14 days when empty
+	SendEmail   bool       `json:"send_email,omitempty"`                        // emails the link to the customer as your offer
+}
+
+// CreatePaymentLink handles creating a payment link for a lead
+// @Summary Create payment link
+// @Description Create a shareable Stripe payment link for a package offer or a custom amount, e.g. after a phone call. With send_email the link is emailed to the customer as an offer signed by the Berater. The booking is created when the link is paid.
+// @Tags leads
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Lead ID"
+// @Param request body CreatePaymentLinkRequest true "Payment link"
+// @Success 201 {object} paymentlinks.Link
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/leads/{id}/payment-links [post]
+func (h *PaymentLinkHandler) CreatePaymentLink(c *gin.Context) {
+	leadID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid lead ID")})
+		return
+	}
+
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "User not authenticated")})
+		return
+	}
+	var user models.User
+	if err := h.db.First(&user, "id = ?", userID).Error; err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "User not found")})
+		return
+	}
+
+	var req CreatePaymentLinkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid request data"), "details": err.Error()})
+		return
+	}
+
+	link, err := h.links.Create(paymentlinks.Input{
+		LeadID:      leadID,
+		PackageID:   req.PackageID,
+		Amount:      req.Amount,
+		Title:       req.Title,
+		Description: req.Description,
+		TTL:         time.Duration(req.ValidDays) * 24 * time.Hour,
+		SendEmail:   req.SendEmail,
+	}, &user)
+	if err != nil {
+		switch {
+		case errors.Is(err, paymentlinks.ErrLeadNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "Lead not found")})
+		case errors.Is(err, catalog.ErrPackageNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "Package not found")})
+		case errors.Is(err, paymentlinks.ErrInvalidLink):
+			c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "A payment link needs an amount and a title")})
+		default:
+			h.logger.Error("Failed to create payment link", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to create payment link")})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, link)
+}
+
+// ListPaymentLinks handles listing the payment links of a lead
+// @Summary List payment links of a lead
+// @Description List the payment links sent to a lead with their status and how often they were opened
+// @Tags leads
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Lead ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/v1/leads/{id}/payment-links [get]
+func (h *PaymentLinkHandler) ListPaymentLinks(c *gin.Context) {
+	leadID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid lead ID")})
+		return
+	}
+
+	links, err := h.links.List(leadID)
+	if err != nil {
+		h.logger.Error("Failed to fetch payment links", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to fetch payment links")})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"payment_links": links})
+}
+
+// CancelPaymentLink handles cancelling an open payment link
+// @Summary Cancel payment link
+// @Tags leads
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Lead ID"
+// @Param linkId path string true "Payment link ID"
+// @Success 200 {object} paymentlinks.Link
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/v1/leads/{id}/payment-links/{linkId}/cancel [post]
+func (h *PaymentLinkHandler) CancelPaymentLink(c *gin.Context) {
+	leadID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid lead ID")})
+		return
+	}
+	linkID, err := uuid.Parse(c.Param("linkId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid payment link ID")})
+		return
+	}
+
+	link, err := h.links.Cancel(leadID, linkID)
+	if err != nil {
+		switch {
+		case errors.Is(err, paymentlinks.ErrNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "Payment link not found")})
+		case errors.Is(err, paymentlinks.ErrNotPayable):
+			c.JSON(http.StatusConflict, gin.H{"error": middleware.T(c, "The payment link was already paid or cancelled")})
+		default:
+			h.logger.Error("Failed to cancel payment link", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to cancel payment link")})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, link)
+}
+
+// Pay handles opening a payment link: it starts a Stripe checkout for the
+// link and redirects the customer to it
+// @Summary Pay payment link
+// @Description Redirect to a Stripe checkout of an open payment link
+// @Tags payments
+// @Param token path string true "Payment link token"
+// @Success 303
+// @Failure 404 {object} map[string]interface{}
+// @Failure 410 {object} map[string]interface{}
+// @Router /pay/{token} [get]
+func (h *PaymentLinkHandler) Pay(c *gin.Context) {
+	link, err := h.links.Open(c.Param("token"))
+	if err != nil {
+		switch {
+		case errors.Is(err, paymentlinks.ErrNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "Payment link not found")})
+		case errors.Is(err, paymentlinks.ErrNotPayable):
+			c.JSON(http.StatusGone, gin.H{"error": middleware.T(c, "This payment link has expired or was already paid")})
+		default:
+			h.logger.Error("Failed to open payment link", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to create checkout session")})
+		}
+		return
+	}
+
+	productData := &stripe.CheckoutSessionLineItemPriceDataProductDataParams{Name: stripe.String(link.Title)}
+	if link.Description != "" {
+		productData.Description = stripe.String(link.Description)
+	}
+	params := &stripe.CheckoutSessionParams{
+		PaymentMethodTypes: stripe.StringSlice([]string{"card"}),
+		LineItems: []*stripe.CheckoutSessionLineItemParams{{
+			PriceData: &stripe.CheckoutSessionLineItemPriceDataParams{
+				Currency:    stripe.String(string(stripe.CurrencyEUR)),
+				ProductData: productData,
+				UnitAmount:  stripe.Int64(int64(math.Round(link.Amount * 100))), // Convert to cents
+			},
+			Quantity: stripe.Int64(1),
+		}},
+		Mode:       stripe.String(string(stripe.CheckoutSessionModePayment)),
+		SuccessURL: stripe.String(h.config.App.BaseURL + "/payment/success?session_id={CHECKOUT_SESSION_ID}"),
+		CancelURL:  stripe.String(h.config.App.BaseURL + "/payment/cancel"),
+		Metadata: map[string]string{
+			"payment_link_id": link.ID.String(),
+			"lead_id":         link.LeadID.String(),
+			"user_id":         link.UserID.String(),
+		},
+	}
+	if link.User != nil {
+		params.CustomerEmail = stripe.String(link.User.Email)
+	}
+	h.tax.ConfigureCheckout(params)
+
+	checkout, err := session.New(params)
+	if err != nil {
+		h.logger.Error("Failed to create Stripe session for payment link", zap.String("payment_link_id", link.ID.String()), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to create checkout session")})
+		return
+	}
+	if err := h.links.SetSession(link, checkout.ID); err != nil {
+		h.logger.Error("Failed to store checkout session of payment link", zap.String("payment_link_id", link.ID.String()), zap.Error(err))
+	}
+
+	c.Redirect(http.StatusSeeOther, checkout.URL)
+}
@@ -0,0 +1,235 @@
+package handlers
+
+import (
+	"errors"
+	"mime"
+	"net/http"
+
+	"elterngeld-portal/internal/mergefields"
+	"elterngeld-portal/internal/middleware"
+	"elterngeld-portal/internal/pdf"
+	"elterngeld-portal/internal/scopes"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type PDFHandler struct {
+	db     *gorm.DB
+	logger *zap.Logger
+	pdfs   *pdf.Service
+	fields *mergefields.Service
+}
+
+func NewPDFHandler(db *gorm.DB, logger *zap.Logger, pdfService *pdf.Service, mergeFieldService *mergefields.Service) *PDFHandler {
+	return &PDFHandler{
+		db:     db,
+		logger: logger,
+		pdfs:   pdfService,
+		fields: mergeFieldService,
+	}
+}
+
+// CreatePDFJobRequest is a PDF request whose template data may contain merge
+// fields, filled in for the contact form, lead or booking of the context
+type CreatePDFJobRequest struct {
+	pdf.Request
+	Context mergefields.Context `json:"context"`
+}
+
+// ListTemplates handles listing the PDF templates
+// @Summary List PDF templates
+// @Description Get the names of the templates PDFs can be generated from
+// @Tags pdf
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/pdf/templates [get]
+func (h *PDFHandler) ListTemplates(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"templates": pdf.Templates()})
+}
+
+// CreateJob handles queuing the generation of a PDF
+// @Summary Generate PDF
+// @Description Queue the generation of a PDF from a template with the branding of a tenant. Texts in the data may contain merge fields like {{customer.name}}, filled in for the context. Poll the job until it is completed, then download the PDF.
+// @Tags pdf
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body CreatePDFJobRequest true "Template, tenant, template data and merge context"
+// @Success 202 {object} models.PDFJob
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/v1/pdf/jobs [post]
+func (h *PDFHandler) CreateJob(c *gin.Context) {
+	viewer, ok := scopes.FromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "User not authenticated")})
+		return
+	}
+
+	var req CreatePDFJobRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid request data"), "details": err.Error()})
+		return
+	}
+
+	fields, err := h.fields.Resolve(viewer, req.Context)
+	if err != nil {
+		h.handlePDFError(c, err, "Failed to resolve merge fields")
+		return
+	}
+	req.Fields = fields
+
+	job, err := h.pdfs.Enqueue(nil, req.Request, viewer.ID)
+	if err != nil {
+		h.handlePDFError(c, err, "Failed to queue PDF")
+		return
+	}
+
+	c.JSON(http.StatusAccepted, job)
+}
+
+// GetJob handles getting the status of a PDF job
+// @Summary Get PDF job
+// @Description Get the status of a PDF the current user queued; admins see every job
+// @Tags pdf
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "PDF job ID"
+// @Success 200 {object} models.PDFJob
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/pdf/jobs/{id} [get]
+func (h *PDFHandler) GetJob(c *gin.Context) {
+	viewer, jobID, ok := h.jobRequest(c)
+	if !ok {
+		return
+	}
+
+	job, err := h.pdfs.Job(jobID, viewer)
+	if err != nil {
+		h.handlePDFError(c, err, "Failed to fetch PDF job")
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
+
+// DownloadJob handles downloading a generated PDF
+// @Summary Download generated PDF
+// @Description Download the PDF of a completed job
+// @Tags pdf
+// @Security BearerAuth
+// @Produce application/pdf
+// @Param id path string true "PDF job ID"
+// @Success 200 {file} file
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/v1/pdf/jobs/{id}/download [get]
+func (h *PDFHandler) DownloadJob(c *gin.Context) {
+	viewer, jobID, ok := h.jobRequest(c)
+	if !ok {
+		return
+	}
+
+	job, err := h.pdfs.Job(jobID, viewer)
+	if err != nil {
+		h.handlePDFError(c, err, "Failed to fetch PDF job")
+		return
+	}
+	content, err := h.pdfs.Content(job)
+	if err != nil {
+		h.handlePDFError(c, err, "Failed to read PDF")
+		return
+	}
+
+	// Generated PDFs contain personal data and must not be cached by shared proxies
+	c.Header("Cache-Control", "private, no-store")
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": job.FileName}))
+	c.Data(http.StatusOK, "application/pdf", content)
+}
+
+// ListBrandings handles listing the PDF brandings of the tenants (admin only)
+// @Summary List PDF brandings
+// @Description Get the letterheads of the tenants; tenants without one use the default branding
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/admin/pdf-brandings [get]
+func (h *PDFHandler) ListBrandings(c *gin.Context) {
+	brandings, err := h.pdfs.Brandings()
+	if err != nil {
+		h.handlePDFError(c, err, "Failed to fetch PDF brandings")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"brandings": brandings})
+}
+
+// UpdateBranding handles changing the PDF branding of a tenant (admin only)
+// @Summary Update PDF branding
+// @Description Set the company name, logo, color and footer of a tenant's PDFs. Logos are data URIs of PNG, JPEG or SVG images.
+// @Tags admin
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param tenant path string true "Tenant"
+// @Param request body pdf.BrandingInput true "Branding"
+// @Success 200 {object} pdf.Branding
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/v1/admin/pdf-brandings/{tenant} [put]
+func (h *PDFHandler) UpdateBranding(c *gin.Context) {
+	userID, ok := middleware.GetCurrentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "User not authenticated")})
+		return
+	}
+
+	var req pdf.BrandingInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid request data"), "details": err.Error()})
+		return
+	}
+
+	branding, err := h.pdfs.SetBranding(c.Param("tenant"), req, userID)
+	if err != nil {
+		h.handlePDFError(c, err, "Failed to update PDF branding")
+		return
+	}
+
+	c.JSON(http.StatusOK, branding)
+}
+
+func (h *PDFHandler) jobRequest(c *gin.Context) (scopes.Viewer, uuid.UUID, bool) {
+	viewer, ok := scopes.FromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "User not authenticated")})
+		return scopes.Viewer{}, uuid.Nil, false
+	}
+	jobID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid PDF job ID")})
+		return scopes.Viewer{}, uuid.Nil, false
+	}
+	return viewer, jobID, true
+}
+
+func (h *PDFHandler) handlePDFError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, pdf.ErrJobNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "PDF job not found")})
+	case errors.Is(err, pdf.ErrNotReady):
+		c.JSON(http.StatusConflict, gin.H{"error": middleware.T(c, "PDF has not been generated yet")})
+	case errors.Is(err, pdf.ErrUnknownTemplate):
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Unknown PDF template")})
+	case errors.Is(err, mergefields.ErrContextNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "Contact form, lead or booking not found")})
+	case errors.Is(err, pdf.ErrInvalidInput):
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid PDF data"), "details": err.Error()})
+	default:
+		h.logger.Error(message, zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, message)})
+	}
+}
@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"elterngeld-portal/internal/middleware"
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/internal/pipeline"
+	"elterngeld-portal/pkg/timeutil"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type PipelineHandler struct {
+	db       *gorm.DB
+	logger   *zap.Logger
+	pipeline *pipeline.Service
+}
+
+func NewPipelineHandler(db *gorm.DB, logger *zap.Logger, pipelineService *pipeline.Service) *PipelineHandler {
+	return &PipelineHandler{
+		db:       db,
+		logger:   logger,
+		pipeline: pipelineService,
+	}
+}
+
+// GetLeadFunnel handles the lead pipeline conversion funnel (admin only)
+// @Summary Get lead conversion funnel
+// @Description Leads created in the period per pipeline stage (new, contacted, qualified, booked, paid) with conversion, drop-off and median time between stages, overall and per lead source
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Param from query string false "First day (YYYY-MM-DD), defaults to four weeks ago"
+// @Param to query string false "Last day (YYYY-MM-DD), defaults to today"
+// @Param source query string false "Only leads of this source"
+// @Param campaign query string false "Only leads of this UTM campaign"
+// @Param berater_id query string false "Only leads assigned to this Berater"
+// @Success 200 {object} pipeline.FunnelReport
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/v1/admin/pipeline/funnel [get]
+func (h *PipelineHandler) GetLeadFunnel(c *gin.Context) {
+	loc := middleware.GetTimezone(c)
+	today := time.Now()
+
+	filter := pipeline.FunnelFilter{
+		From:     c.DefaultQuery("from", timeutil.FormatDate(timeutil.AddDays(today, -27, loc), loc)),
+		To:       c.DefaultQuery("to", timeutil.FormatDate(today, loc)),
+		Source:   models.LeadSource(c.Query("source")),
+		Campaign: c.Query("campaign"),
+		Location: loc,
+	}
+	if value := c.Query("berater_id"); value != "" {
+		beraterID, err := uuid.Parse(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid Berater ID")})
+			return
+		}
+		filter.BeraterID = &beraterID
+	}
+
+	report, err := h.pipeline.Funnel(filter)
+	if err != nil {
+		if errors.Is(err, pipeline.ErrInvalidPeriod) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid period")})
+			return
+		}
+		h.logger.Error("Failed to build lead funnel", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to build lead funnel")})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// GetRevenueForecast handles the expected revenue forecast (admin only)
+// @Summary Get revenue forecast
+// @Description Expected revenue of the open leads for the next 30, 60 and 90 days from their estimated value, lead score and the historical conversion of their pipeline stage, per Berater and package
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Param berater_id query string false "Only leads assigned to this Berater"
+// @Success 200 {object} pipeline.ForecastReport
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/v1/admin/pipeline/forecast [get]
+func (h *PipelineHandler) GetRevenueForecast(c *gin.Context) {
+	var filter pipeline.ForecastFilter
+	if value := c.Query("berater_id"); value != "" {
+		beraterID, err := uuid.Parse(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid Berater ID")})
+			return
+		}
+		filter.BeraterID = &beraterID
+	}
+
+	report, err := h.pipeline.Forecast(filter)
+	if err != nil {
+		h.logger.Error("Failed to build revenue forecast", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to build revenue forecast")})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"elterngeld-portal/internal/middleware"
+	"elterngeld-portal/internal/postalcodes"
+	"elterngeld-portal/pkg/timeutil"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type PostalCodeHandler struct {
+	db          *gorm.DB
+	logger      *zap.Logger
+	postalCodes *postalcodes.Service
+}
+
+func NewPostalCodeHandler(db *gorm.DB, logger *zap.Logger, postalCodeService *postalcodes.Service) *PostalCodeHandler {
+	return &PostalCodeHandler{
+		db:          db,
+		logger:      logger,
+		postalCodes: postalCodeService,
+	}
+}
+
+// LookupPostalCode handles deriving the Bundesland and Kreis of a postal code
+// @Summary Look up postal code
+// @Description Places, Kreis and Bundesland of a German postal code, e.g. to preselect the Bundesland in the Elterngeld calculator. Bundesland and Kreis are empty when the postal code spans several of them; areas lists them all.
+// @Tags postal-codes
+// @Produce json
+// @Param postal_code path string true "Postal code (five digits)"
+// @Success 200 {object} postalcodes.Jurisdiction
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/postal-codes/{postal_code} [get]
+func (h *PostalCodeHandler) LookupPostalCode(c *gin.Context) {
+	result, err := h.postalCodes.Lookup(c.Param("postal_code"))
+	if err != nil {
+		h.handlePostalCodeError(c, err, "Failed to look up postal code")
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// GetRegionReport handles the leads per region report (admin only)
+// @Summary Get region report
+// @Description Leads created in a period per Kreis and Bundesland, derived from the customers' postal codes
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Param from query string false "First day (YYYY-MM-DD), defaults to 30 days ago"
+// @Param to query string false "Last day (YYYY-MM-DD), defaults to today"
+// @Success 200 {object} postalcodes.RegionReport
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/v1/admin/regions [get]
+func (h *PostalCodeHandler) GetRegionReport(c *gin.Context) {
+	loc := middleware.GetTimezone(c)
+	today := time.Now()
+
+	report, err := h.postalCodes.Regions(postalcodes.RegionFilter{
+		From:     c.DefaultQuery("from", timeutil.FormatDate(timeutil.AddDays(today, -29, loc), loc)),
+		To:       c.DefaultQuery("to", timeutil.FormatDate(today, loc)),
+		Location: loc,
+	})
+	if err != nil {
+		h.handlePostalCodeError(c, err, "Failed to build region report")
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+func (h *PostalCodeHandler) handlePostalCodeError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, postalcodes.ErrInvalidPostalCode):
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid postal code")})
+	case errors.Is(err, postalcodes.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "Postal code not found")})
+	case errors.Is(err, postalcodes.ErrInvalidPeriod):
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid period")})
+	default:
+		h.logger.Error(message, zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, message)})
+	}
+}
@@ -0,0 +1,219 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"elterngeld-portal/internal/documents"
+	"elterngeld-portal/internal/middleware"
+	"elterngeld-portal/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// UpdateMeRequest changes the profile of the signed in user. Empty strings
+// clear the optional fields.
+type UpdateMeRequest struct {
+	FirstName *string `json:"first_name,omitempty" binding:"omitempty,min=1,max=100"`
+	LastName  *string `json:"last_name,omitempty" binding:"omitempty,min=1,max=100"`
+	Phone     *string `json:"phone,omitempty" binding:"omitempty,max=50"`
+	Language  *string `json:"language,omitempty" binding:"omitempty,oneof=de en"`
+	Timezone  *string `json:"timezone,omitempty" binding:"omitempty,timezone"`
+
+	// Languages the customer can hold a consultation in, preferred first; matched against the Beraters' working languages
+	SpokenLanguages []string `json:"spoken_languages,omitempty" binding:"omitempty,max=5,dive,oneof=de en tr ar ru uk pl fa ro"`
+
+	// Receives notifications instead of the login email
+	NotificationEmail *string `json:"notification_email,omitempty" binding:"omitempty,email,max=255"`
+
+	// Occupation before the birth, used by the Elterngeld calculator
+	Profession     *string                `json:"profession,omitempty" binding:"omitempty,max=100"`
+	EmploymentType *models.EmploymentType `json:"employment_type,omitempty" binding:"omitempty,oneof=angestellt selbststaendig beamtet mischeinkuenfte minijob ohne_einkommen"`
+}
+
+// GetMe returns the profile of the signed in user
+func (h *AuthHandler) GetMe(c *gin.Context) {
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, user.ToResponse())
+}
+
+// UpdateMe changes the profile of the signed in user
+func (h *AuthHandler) UpdateMe(c *gin.Context) {
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	var req UpdateMeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid request data"), "details": err.Error()})
+		return
+	}
+
+	updates := make(map[string]interface{})
+	if req.FirstName != nil {
+		updates["first_name"] = strings.TrimSpace(*req.FirstName)
+	}
+	if req.LastName != nil {
+		updates["last_name"] = strings.TrimSpace(*req.LastName)
+	}
+	if req.Phone != nil {
+		updates["phone"] = strings.TrimSpace(*req.Phone)
+	}
+	if req.Language != nil {
+		updates["language"] = *req.Language
+	}
+	if req.Timezone != nil {
+		updates["timezone"] = *req.Timezone
+	}
+	if req.SpokenLanguages != nil {
+		user.SetSpokenLanguages(models.CleanLanguages(req.SpokenLanguages))
+		updates["spoken_languages"] = user.SpokenLanguages
+	}
+	if req.NotificationEmail != nil {
+		notificationEmail := strings.ToLower(strings.TrimSpace(*req.NotificationEmail))
+		// The login email is the default, storing it again would hide later changes of it
+		if strings.EqualFold(notificationEmail, user.Email) {
+			notificationEmail = ""
+		}
+		updates["notification_email"] = notificationEmail
+	}
+	if req.Profession != nil {
+		updates["profession"] = strings.TrimSpace(*req.Profession)
+	}
+	if req.EmploymentType != nil {
+		updates["employment_type"] = *req.EmploymentType
+	}
+
+	if len(updates) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "No valid fields to update")})
+		return
+	}
+
+	updates["updated_at"] = time.Now()
+
+	if err := h.db.Model(user).Updates(updates).Error; err != nil {
+		h.logger.Error("Failed to update profile", zap.String("user_id", user.ID.String()), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to update user")})
+		return
+	}
+
+	c.JSON(http.StatusOK, user.ToResponse())
+}
+
+// UploadAvatar replaces the profile picture of the signed in user
+func (h *AuthHandler) UploadAvatar(c *gin.Context) {
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	file, fileHeader, err := c.Request.FormFile("avatar")
+	if err != nil {
+		if middleware.BodyTooLarge(c, err) {
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "No file uploaded")})
+		return
+	}
+	defer file.Close()
+
+	if h.config.Upload.MaxSize > 0 && fileHeader.Size > h.config.Upload.MaxSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "File size exceeds maximum allowed size")})
+		return
+	}
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		h.logger.Error("Failed to read avatar", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to store avatar")})
+		return
+	}
+
+	if err := h.documents.SaveAvatar(user, data); err != nil {
+		if errors.Is(err, documents.ErrInvalidAvatar) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Avatar must be an image")})
+			return
+		}
+		h.logger.Error("Failed to store avatar", zap.String("user_id", user.ID.String()), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to store avatar")})
+		return
+	}
+
+	c.JSON(http.StatusOK, user.ToResponse())
+}
+
+// DeleteAvatar removes the profile picture of the signed in user
+func (h *AuthHandler) DeleteAvatar(c *gin.Context) {
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	if err := h.documents.DeleteAvatar(user); err != nil {
+		if errors.Is(err, documents.ErrAvatarNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "Avatar not found")})
+			return
+		}
+		h.logger.Error("Failed to delete avatar", zap.String("user_id", user.ID.String()), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to delete avatar")})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": middleware.T(c, "Avatar deleted successfully")})
+}
+
+// ServeAvatar delivers an uploaded avatar. Avatars are public like the photo
+// URLs of Berater profiles they replace; the URL changes with every upload.
+func (h *AuthHandler) ServeAvatar(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "Avatar not found")})
+		return
+	}
+
+	data, err := h.documents.Avatar(userID)
+	if err != nil {
+		if errors.Is(err, documents.ErrAvatarNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "Avatar not found")})
+			return
+		}
+		h.logger.Error("Failed to read avatar", zap.String("user_id", userID.String()), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to fetch user")})
+		return
+	}
+
+	c.Header("Cache-Control", "public, max-age=86400")
+	c.Data(http.StatusOK, "image/jpeg", data)
+}
+
+// currentUser loads the signed in user, writing the error response if that fails
+func (h *AuthHandler) currentUser(c *gin.Context) (*models.User, bool) {
+	userID, ok := middleware.GetCurrentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "User not authenticated")})
+		return nil, false
+	}
+
+	var user models.User
+	if err := h.db.First(&user, "id = ?", userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "User not found")})
+		} else {
+			h.logger.Error("Failed to fetch user", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to fetch user")})
+		}
+		return nil, false
+	}
+	return &user, true
+}
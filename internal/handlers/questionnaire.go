@@ -0,0 +1,510 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"elterngeld-portal/internal/middleware"
+	"elterngeld-portal/internal/questionnaires"
+	"elterngeld-portal/internal/scopes"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type QuestionnaireHandler struct {
+	db             *gorm.DB
+	logger         *zap.Logger
+	questionnaires *questionnaires.Service
+}
+
+func NewQuestionnaireHandler(db *gorm.DB, logger *zap.Logger, questionnaireService *questionnaires.Service) *QuestionnaireHandler {
+	return &QuestionnaireHandler{
+		db:             db,
+		logger:         logger,
+		questionnaires: questionnaireService,
+	}
+}
+
+// ListQuestionnaires handles listing the questionnaire versions (admin only)
+// @Summary List questionnaires
+// @Description List the intake questionnaire versions, newest first, optionally of one package
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Param package_id query string false "Package ID"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/admin/questionnaires [get]
+func (h *QuestionnaireHandler) ListQuestionnaires(c *gin.Context) {
+	var packageID *uuid.UUID
+	if value := c.Query("package_id"); value != "" {
+		id, err := uuid.Parse(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid package ID")})
+			return
+		}
+		packageID = &id
+	}
+
+	list, err := h.questionnaires.List(packageID)
+	if err != nil {
+		h.logger.Error("Failed to fetch questionnaires", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to fetch questionnaires")})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"questionnaires": list})
+}
+
+// GetQuestionnaire handles getting a questionnaire version with its questions (admin only)
+// @Summary Get questionnaire
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Questionnaire ID"
+// @Success 200 {object} models.Questionnaire
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/admin/questionnaires/{id} [get]
+func (h *QuestionnaireHandler) GetQuestionnaire(c *gin.Context) {
+	questionnaireID, ok := h.questionnaireID(c)
+	if !ok {
+		return
+	}
+
+	questionnaire, err := h.questionnaires.Get(questionnaireID)
+	if err != nil {
+		h.handleQuestionnaireError(c, err, "Failed to fetch questionnaire")
+		return
+	}
+
+	c.JSON(http.StatusOK, questionnaire)
+}
+
+// CreateQuestionnaire handles starting the next version of a package's questionnaire (admin only)
+// @Summary Create questionnaire
+// @Description Start a draft as the next version of the intake questionnaire of a package; a package has at most one draft
+// @Tags admin
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body questionnaires.CreateInput true "Questionnaire"
+// @Success 201 {object} models.Questionnaire
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/v1/admin/questionnaires [post]
+func (h *QuestionnaireHandler) CreateQuestionnaire(c *gin.Context) {
+	userID, ok := middleware.GetCurrentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "User not authenticated")})
+		return
+	}
+
+	var req questionnaires.CreateInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid request data"), "details": err.Error()})
+		return
+	}
+
+	questionnaire, err := h.questionnaires.Create(userID, req)
+	if err != nil {
+		h.handleQuestionnaireError(c, err, "Failed to create questionnaire")
+		return
+	}
+
+	c.JSON(http.StatusCreated, questionnaire)
+}
+
+// UpdateQuestionnaire handles changing the title and introduction of a draft (admin only)
+// @Summary Update questionnaire
+// @Tags admin
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Questionnaire ID"
+// @Param request body questionnaires.UpdateInput true "Questionnaire"
+// @Success 200 {object} models.Questionnaire
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/v1/admin/questionnaires/{id} [put]
+func (h *QuestionnaireHandler) UpdateQuestionnaire(c *gin.Context) {
+	questionnaireID, ok := h.questionnaireID(c)
+	if !ok {
+		return
+	}
+
+	var req questionnaires.UpdateInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid request data"), "details": err.Error()})
+		return
+	}
+
+	questionnaire, err := h.questionnaires.Update(questionnaireID, req)
+	if err != nil {
+		h.handleQuestionnaireError(c, err, "Failed to update questionnaire")
+		return
+	}
+
+	c.JSON(http.StatusOK, questionnaire)
+}
+
+// DeleteQuestionnaire handles removing a draft (admin only)
+// @Summary Delete questionnaire
+// @Description Delete a draft; published versions are kept for the answers given to them
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Questionnaire ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/v1/admin/questionnaires/{id} [delete]
+func (h *QuestionnaireHandler) DeleteQuestionnaire(c *gin.Context) {
+	questionnaireID, ok := h.questionnaireID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.questionnaires.Delete(questionnaireID); err != nil {
+		h.handleQuestionnaireError(c, err, "Failed to delete questionnaire")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": middleware.T(c, "Questionnaire deleted")})
+}
+
+// PublishQuestionnaire handles publishing a draft (admin only)
+// @Summary Publish questionnaire
+// @Description Make a draft the version customers answer from now on. Published versions no longer change.
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Questionnaire ID"
+// @Success 200 {object} models.Questionnaire
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/v1/admin/questionnaires/{id}/publish [post]
+func (h *QuestionnaireHandler) PublishQuestionnaire(c *gin.Context) {
+	questionnaireID, ok := h.questionnaireID(c)
+	if !ok {
+		return
+	}
+
+	questionnaire, err := h.questionnaires.Publish(questionnaireID)
+	if err != nil {
+		h.handleQuestionnaireError(c, err, "Failed to publish questionnaire")
+		return
+	}
+
+	c.JSON(http.StatusOK, questionnaire)
+}
+
+// ReviseQuestionnaire handles copying a version into a new draft (admin only)
+// @Summary Revise questionnaire
+// @Description Copy a version with its questions into a new draft of the package
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Questionnaire ID"
+// @Success 201 {object} models.Questionnaire
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/v1/admin/questionnaires/{id}/revise [post]
+func (h *QuestionnaireHandler) ReviseQuestionnaire(c *gin.Context) {
+	userID, ok := middleware.GetCurrentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "User not authenticated")})
+		return
+	}
+
+	questionnaireID, ok := h.questionnaireID(c)
+	if !ok {
+		return
+	}
+
+	questionnaire, err := h.questionnaires.Revise(questionnaireID, userID)
+	if err != nil {
+		h.handleQuestionnaireError(c, err, "Failed to revise questionnaire")
+		return
+	}
+
+	c.JSON(http.StatusCreated, questionnaire)
+}
+
+// AddQuestion handles adding a question to a draft (admin only)
+// @Summary Add question
+// @Description Add a question; conditions refer to questions placed before it, prefill names the calculator input the answer fills
+// @Tags admin
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Questionnaire ID"
+// @Param request body questionnaires.QuestionInput true "Question"
+// @Success 201 {object} models.QuestionnaireQuestion
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/v1/admin/questionnaires/{id}/questions [post]
+func (h *QuestionnaireHandler) AddQuestion(c *gin.Context) {
+	questionnaireID, ok := h.questionnaireID(c)
+	if !ok {
+		return
+	}
+
+	var req questionnaires.QuestionInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid request data"), "details": err.Error()})
+		return
+	}
+
+	question, err := h.questionnaires.AddQuestion(questionnaireID, req)
+	if err != nil {
+		h.handleQuestionnaireError(c, err, "Failed to add question")
+		return
+	}
+
+	c.JSON(http.StatusCreated, question)
+}
+
+// UpdateQuestion handles changing a question of a draft (admin only)
+// @Summary Update question
+// @Tags admin
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Questionnaire ID"
+// @Param questionId path string true "Question ID"
+// @Param request body questionnaires.QuestionInput true "Question"
+// @Success 200 {object} models.QuestionnaireQuestion
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/v1/admin/questionnaires/{id}/questions/{questionId} [put]
+func (h *QuestionnaireHandler) UpdateQuestion(c *gin.Context) {
+	questionnaireID, questionID, ok := h.questionRequest(c)
+	if !ok {
+		return
+	}
+
+	var req questionnaires.QuestionInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid request data"), "details": err.Error()})
+		return
+	}
+
+	question, err := h.questionnaires.UpdateQuestion(questionnaireID, questionID, req)
+	if err != nil {
+		h.handleQuestionnaireError(c, err, "Failed to update question")
+		return
+	}
+
+	c.JSON(http.StatusOK, question)
+}
+
+// DeleteQuestion handles removing a question from a draft (admin only)
+// @Summary Delete question
+// @Description Remove a question no other question has a condition on
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Questionnaire ID"
+// @Param questionId path string true "Question ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/v1/admin/questionnaires/{id}/questions/{questionId} [delete]
+func (h *QuestionnaireHandler) DeleteQuestion(c *gin.Context) {
+	questionnaireID, questionID, ok := h.questionRequest(c)
+	if !ok {
+		return
+	}
+
+	if err := h.questionnaires.DeleteQuestion(questionnaireID, questionID); err != nil {
+		h.handleQuestionnaireError(c, err, "Failed to delete question")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": middleware.T(c, "Question deleted")})
+}
+
+// GetPackageQuestionnaire handles getting the questionnaire customers answer for a package
+// @Summary Get package questionnaire
+// @Description Get the latest published intake questionnaire of a package with its questions
+// @Tags packages
+// @Produce json
+// @Param id path string true "Package ID"
+// @Success 200 {object} models.Questionnaire
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/packages/{id}/questionnaire [get]
+func (h *QuestionnaireHandler) GetPackageQuestionnaire(c *gin.Context) {
+	packageID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid package ID")})
+		return
+	}
+
+	questionnaire, err := h.questionnaires.Current(packageID)
+	if err != nil {
+		h.handleQuestionnaireError(c, err, "Failed to fetch questionnaire")
+		return
+	}
+
+	c.JSON(http.StatusOK, questionnaire)
+}
+
+// GetLeadQuestionnaire handles getting the answered questionnaire of a lead
+// @Summary Get lead questionnaire answers
+// @Description Get the answers of the latest questionnaire the lead answered, in the order of the questions
+// @Tags leads
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Lead ID"
+// @Success 200 {object} questionnaires.Response
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/leads/{id}/questionnaire [get]
+func (h *QuestionnaireHandler) GetLeadQuestionnaire(c *gin.Context) {
+	viewer, leadID, ok := h.leadRequest(c)
+	if !ok {
+		return
+	}
+
+	response, err := h.questionnaires.Response(leadID, viewer)
+	if err != nil {
+		h.handleQuestionnaireError(c, err, "Failed to fetch questionnaire answers")
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// SubmitLeadQuestionnaire handles answering a questionnaire for a lead
+// @Summary Answer questionnaire
+// @Description Answer a published questionnaire for a lead by question key, replacing earlier answers to the same version. Answers to questions hidden by their condition are dropped.
+// @Tags leads
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Lead ID"
+// @Param request body questionnaires.SubmitInput true "Answers"
+// @Success 200 {object} questionnaires.Response
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/leads/{id}/questionnaire [put]
+func (h *QuestionnaireHandler) SubmitLeadQuestionnaire(c *gin.Context) {
+	viewer, leadID, ok := h.leadRequest(c)
+	if !ok {
+		return
+	}
+
+	var req questionnaires.SubmitInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid request data"), "details": err.Error()})
+		return
+	}
+
+	response, err := h.questionnaires.Submit(leadID, viewer, req)
+	if err != nil {
+		h.handleQuestionnaireError(c, err, "Failed to save questionnaire answers")
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// GetLeadPrefill handles getting the calculator inputs answered in the questionnaire of a lead
+// @Summary Get lead prefill
+// @Description Get the birth date, incomes and hours the lead answered in its questionnaire, to pre-fill the calculators, a first scenario and the application
+// @Tags leads
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Lead ID"
+// @Success 200 {object} questionnaires.Prefill
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/leads/{id}/questionnaire/prefill [get]
+func (h *QuestionnaireHandler) GetLeadPrefill(c *gin.Context) {
+	viewer, leadID, ok := h.leadRequest(c)
+	if !ok {
+		return
+	}
+
+	prefill, err := h.questionnaires.Prefill(leadID, viewer)
+	if err != nil {
+		h.handleQuestionnaireError(c, err, "Failed to fetch questionnaire answers")
+		return
+	}
+
+	c.JSON(http.StatusOK, prefill)
+}
+
+func (h *QuestionnaireHandler) questionnaireID(c *gin.Context) (uuid.UUID, bool) {
+	questionnaireID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid questionnaire ID")})
+		return uuid.Nil, false
+	}
+	return questionnaireID, true
+}
+
+func (h *QuestionnaireHandler) questionRequest(c *gin.Context) (uuid.UUID, uuid.UUID, bool) {
+	questionnaireID, ok := h.questionnaireID(c)
+	if !ok {
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	questionID, err := uuid.Parse(c.Param("questionId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid question ID")})
+		return uuid.Nil, uuid.Nil, false
+	}
+	return questionnaireID, questionID, true
+}
+
+func (h *QuestionnaireHandler) leadRequest(c *gin.Context) (scopes.Viewer, uuid.UUID, bool) {
+	viewer, ok := scopes.FromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "User not authenticated")})
+		return scopes.Viewer{}, uuid.Nil, false
+	}
+
+	leadID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid lead ID")})
+		return scopes.Viewer{}, uuid.Nil, false
+	}
+
+	return viewer, leadID, true
+}
+
+func (h *QuestionnaireHandler) handleQuestionnaireError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, questionnaires.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "Questionnaire not found")})
+	case errors.Is(err, questionnaires.ErrPackageNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "Package not found")})
+	case errors.Is(err, questionnaires.ErrQuestionNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "Question not found")})
+	case errors.Is(err, questionnaires.ErrLeadNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "Lead not found")})
+	case errors.Is(err, questionnaires.ErrNoResponse):
+		c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "No questionnaire has been answered yet")})
+	case errors.Is(err, questionnaires.ErrPublished):
+		c.JSON(http.StatusConflict, gin.H{"error": middleware.T(c, "Questionnaire already published, revise it instead")})
+	case errors.Is(err, questionnaires.ErrDraftExists):
+		c.JSON(http.StatusConflict, gin.H{"error": middleware.T(c, "The package already has a draft questionnaire")})
+	case errors.Is(err, questionnaires.ErrNoQuestions):
+		c.JSON(http.StatusConflict, gin.H{"error": middleware.T(c, "Questionnaire has no questions")})
+	case errors.Is(err, questionnaires.ErrDuplicateKey):
+		c.JSON(http.StatusConflict, gin.H{"error": middleware.T(c, "Question key already used")})
+	case errors.Is(err, questionnaires.ErrInvalidQuestion):
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid question"), "details": err.Error()})
+	case errors.Is(err, questionnaires.ErrInvalidAnswers):
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid answers"), "details": err.Error()})
+	default:
+		h.logger.Error(message, zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, message)})
+	}
+}
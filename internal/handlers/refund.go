@@ -0,0 +1,260 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"elterngeld-portal/internal/credit"
+	"elterngeld-portal/internal/middleware"
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/internal/refunds"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type RefundHandler struct {
+	db      *gorm.DB
+	logger  *zap.Logger
+	refunds *refunds.Service
+}
+
+func NewRefundHandler(db *gorm.DB, logger *zap.Logger, refundService *refunds.Service) *RefundHandler {
+	return &RefundHandler{
+		db:      db,
+		logger:  logger,
+		refunds: refundService,
+	}
+}
+
+// RefundRequest represents the refund request
+type RefundRequest struct {
+	Amount   *int64 `json:"amount,omitempty"`                   // Amount in cents, if nil refund full amount
+	Reason   string `json:"reason" binding:"required,max=1000"` // justification, shown to the approver
+	ToCredit bool   `json:"to_credit,omitempty"`                // refund as account credit instead of to the card
+}
+
+// RefundDecisionRequest represents the decision on a refund request
+type RefundDecisionRequest struct {
+	Note string `json:"note,omitempty" binding:"max=1000"` // required when rejecting
+}
+
+// RequestRefund handles requesting a refund of a payment
+// @Summary Refund payment
+// @Description Request a refund of a payment with a justification (Berater/Admin only). Refunds up to the approval threshold are executed right away, larger ones wait for the approval of a second admin.
+// @Tags payments
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Payment ID"
+// @Param request body RefundRequest true "Refund data"
+// @Success 200 {object} models.RefundRequest "Refund executed"
+// @Success 202 {object} models.RefundRequest "Refund waits for approval"
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 502 {object} map[string]interface{}
+// @Router /api/v1/payments/{id}/refund [post]
+func (h *RefundHandler) RequestRefund(c *gin.Context) {
+	paymentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid payment ID")})
+		return
+	}
+
+	user, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	var req RefundRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid request data"), "details": err.Error()})
+		return
+	}
+
+	input := refunds.RequestInput{PaymentID: paymentID, ToCredit: req.ToCredit, Reason: req.Reason}
+	if req.Amount != nil {
+		euros := float64(*req.Amount) / 100
+		input.Amount = &euros
+	}
+
+	request, err := h.refunds.Request(input, user)
+	if err != nil {
+		h.handleRefundError(c, request, err, "Failed to create refund")
+		return
+	}
+
+	if request.Status == models.RefundRequestStatusPending {
+		c.JSON(http.StatusAccepted, request)
+		return
+	}
+	c.JSON(http.StatusOK, request)
+}
+
+// ListRefundRequests handles listing refund requests (admin only)
+// @Summary List refund requests
+// @Description Get refund requests with requester, justification, decision and result, e.g. the ones waiting for approval
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Param status query string false "Status (beantragt, genehmigt, abgelehnt, ausgefuehrt, fehlgeschlagen)"
+// @Param payment_id query string false "Payment ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/v1/admin/refund-requests [get]
+func (h *RefundHandler) ListRefundRequests(c *gin.Context) {
+	var filter refunds.Filter
+	if status := c.Query("status"); status != "" {
+		filter.Status = models.RefundRequestStatus(status)
+		if !filter.Status.IsValid() {
+			c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid refund status")})
+			return
+		}
+	}
+	if paymentID := c.Query("payment_id"); paymentID != "" {
+		id, err := uuid.Parse(paymentID)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid payment ID")})
+			return
+		}
+		filter.PaymentID = &id
+	}
+
+	requests, err := h.refunds.List(filter)
+	if err != nil {
+		h.logger.Error("Failed to fetch refund requests", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to fetch refund requests")})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"refund_requests": requests})
+}
+
+// ApproveRefund handles approving a refund request of another user (admin only)
+// @Summary Approve refund request
+// @Description Approve a refund above the approval threshold and execute it. The requester cannot approve their own refund.
+// @Tags admin
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Refund request ID"
+// @Param request body RefundDecisionRequest false "Decision"
+// @Success 200 {object} models.RefundRequest
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Failure 502 {object} map[string]interface{}
+// @Router /api/v1/admin/refund-requests/{id}/approve [post]
+func (h *RefundHandler) ApproveRefund(c *gin.Context) {
+	requestID, user, req, ok := h.decisionParams(c)
+	if !ok {
+		return
+	}
+
+	request, err := h.refunds.Approve(requestID, user, req.Note)
+	if err != nil {
+		h.handleRefundError(c, request, err, "Failed to approve refund")
+		return
+	}
+
+	c.JSON(http.StatusOK, request)
+}
+
+// RejectRefund handles rejecting a refund request (admin only)
+// @Summary Reject refund request
+// @Description Reject a refund waiting for approval; the note tells the requester why
+// @Tags admin
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Refund request ID"
+// @Param request body RefundDecisionRequest true "Decision"
+// @Success 200 {object} models.RefundRequest
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/v1/admin/refund-requests/{id}/reject [post]
+func (h *RefundHandler) RejectRefund(c *gin.Context) {
+	requestID, user, req, ok := h.decisionParams(c)
+	if !ok {
+		return
+	}
+
+	request, err := h.refunds.Reject(requestID, user, req.Note)
+	if err != nil {
+		h.handleRefundError(c, request, err, "Failed to reject refund")
+		return
+	}
+
+	c.JSON(http.StatusOK, request)
+}
+
+func (h *RefundHandler) currentUser(c *gin.Context) (*models.User, bool) {
+	userID, exists := middleware.GetCurrentUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "User not authenticated")})
+		return nil, false
+	}
+	var user models.User
+	if err := h.db.First(&user, "id = ?", userID).Error; err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "User not found")})
+		return nil, false
+	}
+	return &user, true
+}
+
+func (h *RefundHandler) decisionParams(c *gin.Context) (uuid.UUID, *models.User, RefundDecisionRequest, bool) {
+	var req RefundDecisionRequest
+	requestID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid refund request ID")})
+		return uuid.Nil, nil, req, false
+	}
+
+	user, ok := h.currentUser(c)
+	if !ok {
+		return uuid.Nil, nil, req, false
+	}
+
+	// The note is optional for approvals, so an empty body is fine
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid request data"), "details": err.Error()})
+			return uuid.Nil, nil, req, false
+		}
+	}
+	return requestID, user, req, true
+}
+
+func (h *RefundHandler) handleRefundError(c *gin.Context, request *models.RefundRequest, err error, message string) {
+	switch {
+	case errors.Is(err, refunds.ErrPaymentNotFound), errors.Is(err, credit.ErrPaymentNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "Payment not found")})
+	case errors.Is(err, refunds.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "Refund request not found")})
+	case errors.Is(err, refunds.ErrNotRefundable):
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Payment cannot be refunded")})
+	case errors.Is(err, refunds.ErrNoPaymentIntent):
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "No Stripe payment intent found")})
+	case errors.Is(err, refunds.ErrInvalidAmount):
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Amount must be positive")})
+	case errors.Is(err, refunds.ErrRefundTooHigh):
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Refund exceeds the refundable amount")})
+	case errors.Is(err, refunds.ErrReasonRequired):
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "A justification is required")})
+	case errors.Is(err, refunds.ErrSameApprover):
+		c.JSON(http.StatusForbidden, gin.H{"error": middleware.T(c, "A refund must be approved by a second admin")})
+	case errors.Is(err, refunds.ErrNotPending):
+		c.JSON(http.StatusConflict, gin.H{"error": middleware.T(c, "Refund request was already decided")})
+	case errors.Is(err, refunds.ErrRefundFailed):
+		// The failed request is returned so the reason can be shown
+		c.JSON(http.StatusBadGateway, gin.H{"error": middleware.T(c, "The refund failed"), "refund_request": request})
+	default:
+		h.logger.Error(message, zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, message)})
+	}
+}
@@ -0,0 +1,184 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"elterngeld-portal/internal/middleware"
+	"elterngeld-portal/internal/replies"
+	"elterngeld-portal/internal/scopes"
+	"elterngeld-portal/internal/snippets"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type ReplyHandler struct {
+	db      *gorm.DB
+	logger  *zap.Logger
+	replies *replies.Service
+}
+
+func NewReplyHandler(db *gorm.DB, logger *zap.Logger, replyService *replies.Service) *ReplyHandler {
+	return &ReplyHandler{
+		db:      db,
+		logger:  logger,
+		replies: replyService,
+	}
+}
+
+// ReplyRequest represents an email reply; files are uploaded as multipart form data
+type ReplyRequest struct {
+	Subject   string     `json:"subject" form:"subject" binding:"max=255"`
+	Body      string     `json:"body" form:"body"`
+	SnippetID *uuid.UUID `json:"snippet_id" form:"snippet_id"`
+}
+
+// ReplyToContactForm handles emailing a reply to a contact form (Berater and admins)
+// @Summary Reply to contact form
+// @Description Email a reply to the sender of a contact form and mark it as replied. Without a body the snippet is sent with its placeholders filled in; the subject defaults to the form's subject.
+// @Tags contact
+// @Security BearerAuth
+// @Accept json,mpfd
+// @Produce json
+// @Param id path string true "Contact Form ID"
+// @Param request body ReplyRequest true "Reply"
+// @Success 201 {object} replies.Reply
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/v1/contact/forms/{id}/reply [post]
+func (h *ReplyHandler) ReplyToContactForm(c *gin.Context) {
+	viewer, id, input, ok := h.replyRequest(c, "Invalid contact form ID")
+	if !ok {
+		return
+	}
+	defer closeUploads(input)
+
+	reply, err := h.replies.ReplyToContactForm(id, viewer, input)
+	if err != nil {
+		h.handleReplyError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, reply)
+}
+
+// ReplyToLead handles emailing a reply to the customer of a lead (Berater and admins)
+// @Summary Reply to lead
+// @Description Email a reply to the customer of a lead and add it to the lead's email thread. Without a body the snippet is sent with its placeholders filled in.
+// @Tags leads
+// @Security BearerAuth
+// @Accept json,mpfd
+// @Produce json
+// @Param id path string true "Lead ID"
+// @Param request body ReplyRequest true "Reply"
+// @Success 201 {object} replies.Reply
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/leads/{id}/reply [post]
+func (h *ReplyHandler) ReplyToLead(c *gin.Context) {
+	viewer, id, input, ok := h.replyRequest(c, "Invalid lead ID")
+	if !ok {
+		return
+	}
+	defer closeUploads(input)
+
+	reply, err := h.replies.ReplyToLead(id, viewer, input)
+	if err != nil {
+		h.handleReplyError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, reply)
+}
+
+// replyRequest returns the current user, the ID of the contact form or lead
+// and the reply with its uploaded files. The caller closes the files.
+func (h *ReplyHandler) replyRequest(c *gin.Context, invalidID string) (scopes.Viewer, uuid.UUID, replies.Input, bool) {
+	viewer, ok := scopes.FromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "User not authenticated")})
+		return scopes.Viewer{}, uuid.Nil, replies.Input{}, false
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, invalidID)})
+		return scopes.Viewer{}, uuid.Nil, replies.Input{}, false
+	}
+
+	var req ReplyRequest
+	if err := c.ShouldBind(&req); err != nil {
+		if middleware.BodyTooLarge(c, err) {
+			return scopes.Viewer{}, uuid.Nil, replies.Input{}, false
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid request data"), "details": err.Error()})
+		return scopes.Viewer{}, uuid.Nil, replies.Input{}, false
+	}
+
+	input := replies.Input{
+		Subject:   req.Subject,
+		Body:      req.Body,
+		SnippetID: req.SnippetID,
+	}
+
+	form, err := c.MultipartForm()
+	if middleware.BodyTooLarge(c, err) {
+		return scopes.Viewer{}, uuid.Nil, replies.Input{}, false
+	}
+	if err == nil {
+		for _, fileHeader := range form.File["files"] {
+			file, err := fileHeader.Open()
+			if err != nil {
+				closeUploads(input)
+				c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "No file uploaded")})
+				return scopes.Viewer{}, uuid.Nil, replies.Input{}, false
+			}
+
+			input.Uploads = append(input.Uploads, replies.Upload{
+				FileName:    fileHeader.Filename,
+				ContentType: fileHeader.Header.Get("Content-Type"),
+				Size:        fileHeader.Size,
+				Content:     file,
+			})
+		}
+	}
+
+	return viewer, id, input, true
+}
+
+func closeUploads(input replies.Input) {
+	for _, upload := range input.Uploads {
+		if closer, ok := upload.Content.(io.Closer); ok {
+			closer.Close()
+		}
+	}
+}
+
+func (h *ReplyHandler) handleReplyError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, replies.ErrContactFormNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "Contact form not found")})
+	case errors.Is(err, replies.ErrLeadNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "Lead not found")})
+	case errors.Is(err, replies.ErrClaimedByOther):
+		c.JSON(http.StatusConflict, gin.H{"error": middleware.T(c, "Someone else has already claimed this item")})
+	case errors.Is(err, replies.ErrEmptyReply):
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Reply subject and body are required")})
+	case errors.Is(err, replies.ErrTooManyAttachments):
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Too many attachments")})
+	case errors.Is(err, replies.ErrFileTooLarge):
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "File size exceeds maximum allowed size")})
+	case errors.Is(err, replies.ErrFileTypeNotAllowed):
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "File type not allowed")})
+	case errors.Is(err, snippets.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "Snippet not found")})
+	default:
+		h.logger.Error("Failed to send reply", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to send reply")})
+	}
+}
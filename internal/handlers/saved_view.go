@@ -0,0 +1,311 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"elterngeld-portal/internal/middleware"
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/internal/savedviews"
+	"elterngeld-portal/internal/scopes"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type SavedViewHandler struct {
+	db     *gorm.DB
+	logger *zap.Logger
+	views  *savedviews.Service
+}
+
+func NewSavedViewHandler(db *gorm.DB, logger *zap.Logger, viewService *savedviews.Service) *SavedViewHandler {
+	return &SavedViewHandler{
+		db:     db,
+		logger: logger,
+		views:  viewService,
+	}
+}
+
+// CreateSavedViewRequest represents the saved view creation request
+type CreateSavedViewRequest struct {
+	Resource models.SavedViewResource `json:"resource" binding:"required"`
+	savedviews.Input
+}
+
+// ShareSavedViewRequest represents the saved view sharing request
+type ShareSavedViewRequest struct {
+	IsShared *bool `json:"is_shared" binding:"required"`
+}
+
+// ListSavedViews handles listing the saved views of a list
+// @Summary List saved views
+// @Description Get the current user's saved views of the lead or booking list and the views shared with the team
+// @Tags saved-views
+// @Security BearerAuth
+// @Produce json
+// @Param resource query string true "List" Enums(leads, bookings)
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/v1/saved-views [get]
+func (h *SavedViewHandler) ListSavedViews(c *gin.Context) {
+	viewer, ok := h.viewer(c)
+	if !ok {
+		return
+	}
+
+	views, err := h.views.List(viewer, models.SavedViewResource(c.Query("resource")))
+	if err != nil {
+		h.handleSavedViewError(c, err, "Failed to fetch saved views")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"views": views})
+}
+
+// CreateSavedView handles saving a view of a list
+// @Summary Create saved view
+// @Description Save the filters, sort order and columns of the lead or booking list
+// @Tags saved-views
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body CreateSavedViewRequest true "Saved view"
+// @Success 201 {object} savedviews.View
+// @Failure 400 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/v1/saved-views [post]
+func (h *SavedViewHandler) CreateSavedView(c *gin.Context) {
+	viewer, ok := h.viewer(c)
+	if !ok {
+		return
+	}
+
+	var req CreateSavedViewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid request data"), "details": err.Error()})
+		return
+	}
+
+	view, err := h.views.Create(viewer, req.Resource, req.Input)
+	if err != nil {
+		h.handleSavedViewError(c, err, "Failed to create saved view")
+		return
+	}
+
+	c.JSON(http.StatusCreated, view)
+}
+
+// UpdateSavedView handles changing a saved view
+// @Summary Update saved view
+// @Description Replace the name, filters, sort order and columns of one of the current user's views
+// @Tags saved-views
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Saved view ID"
+// @Param request body savedviews.Input true "Saved view"
+// @Success 200 {object} savedviews.View
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/v1/saved-views/{id} [put]
+func (h *SavedViewHandler) UpdateSavedView(c *gin.Context) {
+	viewer, viewID, ok := h.viewRequest(c)
+	if !ok {
+		return
+	}
+
+	var req savedviews.Input
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid request data"), "details": err.Error()})
+		return
+	}
+
+	view, err := h.views.Update(viewID, viewer, req)
+	if err != nil {
+		h.handleSavedViewError(c, err, "Failed to update saved view")
+		return
+	}
+
+	c.JSON(http.StatusOK, view)
+}
+
+// DeleteSavedView handles removing a saved view
+// @Summary Delete saved view
+// @Description Remove one of the current user's views; users who opened their list with it fall back to no saved view
+// @Tags saved-views
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Saved view ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/saved-views/{id} [delete]
+func (h *SavedViewHandler) DeleteSavedView(c *gin.Context) {
+	viewer, viewID, ok := h.viewRequest(c)
+	if !ok {
+		return
+	}
+
+	if err := h.views.Delete(viewID, viewer); err != nil {
+		h.handleSavedViewError(c, err, "Failed to delete saved view")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": middleware.T(c, "Saved view deleted")})
+}
+
+// ShareSavedView handles sharing a saved view with the team
+// @Summary Share saved view
+// @Description Offer one of the current user's views to the team or take it back (Berater and admins)
+// @Tags saved-views
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Saved view ID"
+// @Param request body ShareSavedViewRequest true "Sharing"
+// @Success 200 {object} savedviews.View
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/saved-views/{id}/share [put]
+func (h *SavedViewHandler) ShareSavedView(c *gin.Context) {
+	viewer, viewID, ok := h.viewRequest(c)
+	if !ok {
+		return
+	}
+
+	var req ShareSavedViewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid request data"), "details": err.Error()})
+		return
+	}
+
+	view, err := h.views.Share(viewID, viewer, *req.IsShared)
+	if err != nil {
+		h.handleSavedViewError(c, err, "Failed to share saved view")
+		return
+	}
+
+	c.JSON(http.StatusOK, view)
+}
+
+// SetDefaultSavedView handles choosing the view a list opens with
+// @Summary Set default saved view
+// @Description Open the current user's list with this view, own views and views shared with the team can be the default
+// @Tags saved-views
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Saved view ID"
+// @Success 200 {object} savedviews.View
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/saved-views/{id}/default [put]
+func (h *SavedViewHandler) SetDefaultSavedView(c *gin.Context) {
+	viewer, viewID, ok := h.viewRequest(c)
+	if !ok {
+		return
+	}
+
+	view, err := h.views.SetDefault(viewID, viewer)
+	if err != nil {
+		h.handleSavedViewError(c, err, "Failed to set default saved view")
+		return
+	}
+
+	c.JSON(http.StatusOK, view)
+}
+
+// GetDefaultSavedView handles getting the view a list opens with
+// @Summary Get default saved view
+// @Description Get the view the current user's lead or booking list opens with
+// @Tags saved-views
+// @Security BearerAuth
+// @Produce json
+// @Param resource path string true "List" Enums(leads, bookings)
+// @Success 200 {object} savedviews.View
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/saved-views/defaults/{resource} [get]
+func (h *SavedViewHandler) GetDefaultSavedView(c *gin.Context) {
+	viewer, ok := h.viewer(c)
+	if !ok {
+		return
+	}
+
+	view, err := h.views.Default(viewer, models.SavedViewResource(c.Param("resource")))
+	if err != nil {
+		h.handleSavedViewError(c, err, "Failed to fetch saved views")
+		return
+	}
+
+	c.JSON(http.StatusOK, view)
+}
+
+// ClearDefaultSavedView handles opening a list without a saved view again
+// @Summary Clear default saved view
+// @Description Open the current user's lead or booking list without a saved view
+// @Tags saved-views
+// @Security BearerAuth
+// @Produce json
+// @Param resource path string true "List" Enums(leads, bookings)
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/v1/saved-views/defaults/{resource} [delete]
+func (h *SavedViewHandler) ClearDefaultSavedView(c *gin.Context) {
+	viewer, ok := h.viewer(c)
+	if !ok {
+		return
+	}
+
+	if err := h.views.ClearDefault(viewer, models.SavedViewResource(c.Param("resource"))); err != nil {
+		h.handleSavedViewError(c, err, "Failed to set default saved view")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": middleware.T(c, "Default saved view cleared")})
+}
+
+func (h *SavedViewHandler) viewer(c *gin.Context) (scopes.Viewer, bool) {
+	viewer, ok := scopes.FromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "User not authenticated")})
+	}
+	return viewer, ok
+}
+
+func (h *SavedViewHandler) viewRequest(c *gin.Context) (scopes.Viewer, uuid.UUID, bool) {
+	viewer, ok := h.viewer(c)
+	if !ok {
+		return scopes.Viewer{}, uuid.Nil, false
+	}
+
+	viewID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid saved view ID")})
+		return scopes.Viewer{}, uuid.Nil, false
+	}
+
+	return viewer, viewID, true
+}
+
+func (h *SavedViewHandler) handleSavedViewError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, savedviews.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "Saved view not found")})
+	case errors.Is(err, savedviews.ErrNotOwner):
+		c.JSON(http.StatusForbidden, gin.H{"error": middleware.T(c, "Only the owner can change this saved view")})
+	case errors.Is(err, savedviews.ErrShareNotAllowed):
+		c.JSON(http.StatusForbidden, gin.H{"error": middleware.T(c, "Only team members can share saved views")})
+	case errors.Is(err, savedviews.ErrUnknownResource):
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Saved views are only available for leads and bookings")})
+	case errors.Is(err, savedviews.ErrInvalidConfiguration):
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid saved view configuration"), "details": err.Error()})
+	case errors.Is(err, savedviews.ErrNameTaken):
+		c.JSON(http.StatusConflict, gin.H{"error": middleware.T(c, "A saved view with this name already exists")})
+	default:
+		h.logger.Error(message, zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, message)})
+	}
+}
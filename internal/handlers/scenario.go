@@ -0,0 +1,368 @@
+package handlers
+
+import (
+	"errors"
+	"mime"
+	"net/http"
+	"strings"
+
+	"elterngeld-portal/internal/middleware"
+	"elterngeld-portal/internal/scenarios"
+	"elterngeld-portal/internal/scopes"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type ScenarioHandler struct {
+	db        *gorm.DB
+	logger    *zap.Logger
+	scenarios *scenarios.Service
+}
+
+func NewScenarioHandler(db *gorm.DB, logger *zap.Logger, scenarioService *scenarios.Service) *ScenarioHandler {
+	return &ScenarioHandler{
+		db:        db,
+		logger:    logger,
+		scenarios: scenarioService,
+	}
+}
+
+// ListScenarios handles listing the Elterngeld scenarios of a lead
+// @Summary List lead scenarios
+// @Description Get the Elterngeld scenarios of a lead with their calculation, oldest first
+// @Tags leads
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Lead ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/leads/{id}/scenarios [get]
+func (h *ScenarioHandler) ListScenarios(c *gin.Context) {
+	viewer, leadID, ok := h.leadRequest(c)
+	if !ok {
+		return
+	}
+
+	result, err := h.scenarios.List(leadID, viewer)
+	if err != nil {
+		h.handleScenarioError(c, err, "Failed to fetch scenarios")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"scenarios": result})
+}
+
+// CreateScenario handles adding an Elterngeld scenario to a lead
+// @Summary Create lead scenario
+// @Description Save a plan of how the parents split the Lebensmonate, e.g. "Mutter 12 Monate Basis". The result lists rule violations as warnings.
+// @Tags leads
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Lead ID"
+// @Param request body scenarios.Input true "Scenario"
+// @Success 201 {object} scenarios.Scenario
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/v1/leads/{id}/scenarios [post]
+func (h *ScenarioHandler) CreateScenario(c *gin.Context) {
+	viewer, leadID, ok := h.leadRequest(c)
+	if !ok {
+		return
+	}
+
+	var req scenarios.Input
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid request data"), "details": err.Error()})
+		return
+	}
+
+	scenario, err := h.scenarios.Create(leadID, viewer, req)
+	if err != nil {
+		h.handleScenarioError(c, err, "Failed to create scenario")
+		return
+	}
+
+	c.JSON(http.StatusCreated, scenario)
+}
+
+// UpdateScenario handles renaming a scenario and replacing its plan
+// @Summary Update lead scenario
+// @Description Rename a scenario and replace its plan. Changing the chosen scenario updates the lead's expected amount.
+// @Tags leads
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Lead ID"
+// @Param scenarioId path string true "Scenario ID"
+// @Param request body scenarios.Input true "Scenario"
+// @Success 200 {object} scenarios.Scenario
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/leads/{id}/scenarios/{scenarioId} [put]
+func (h *ScenarioHandler) UpdateScenario(c *gin.Context) {
+	viewer, leadID, scenarioID, ok := h.scenarioRequest(c)
+	if !ok {
+		return
+	}
+
+	var req scenarios.Input
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid request data"), "details": err.Error()})
+		return
+	}
+
+	scenario, err := h.scenarios.Update(leadID, scenarioID, viewer, req)
+	if err != nil {
+		h.handleScenarioError(c, err, "Failed to update scenario")
+		return
+	}
+
+	c.JSON(http.StatusOK, scenario)
+}
+
+// DeleteScenario handles removing a scenario from a lead
+// @Summary Delete lead scenario
+// @Description Remove a scenario. Removing the chosen scenario keeps the lead's expected amount.
+// @Tags leads
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Lead ID"
+// @Param scenarioId path string true "Scenario ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/leads/{id}/scenarios/{scenarioId} [delete]
+func (h *ScenarioHandler) DeleteScenario(c *gin.Context) {
+	viewer, leadID, scenarioID, ok := h.scenarioRequest(c)
+	if !ok {
+		return
+	}
+
+	if err := h.scenarios.Delete(leadID, scenarioID, viewer); err != nil {
+		h.handleScenarioError(c, err, "Failed to delete scenario")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": middleware.T(c, "Scenario deleted successfully")})
+}
+
+// CompareScenarios handles comparing scenarios of a lead side by side
+// @Summary Compare lead scenarios
+// @Description Calculate scenarios side by side with the family's Elterngeld per Lebensmonat in each scenario, and the scenarios with the highest total and the longest duration
+// @Tags leads
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Lead ID"
+// @Param ids query string false "Comma-separated scenario IDs in display order (default: all scenarios)"
+// @Success 200 {object} scenarios.Comparison
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/leads/{id}/scenarios/compare [get]
+func (h *ScenarioHandler) CompareScenarios(c *gin.Context) {
+	viewer, leadID, ok := h.leadRequest(c)
+	if !ok {
+		return
+	}
+
+	var scenarioIDs []uuid.UUID
+	if value := c.Query("ids"); value != "" {
+		for _, part := range strings.Split(value, ",") {
+			scenarioID, err := uuid.Parse(strings.TrimSpace(part))
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid scenario ID")})
+				return
+			}
+			scenarioIDs = append(scenarioIDs, scenarioID)
+		}
+	}
+
+	comparison, err := h.scenarios.Compare(leadID, viewer, scenarioIDs)
+	if err != nil {
+		h.handleScenarioError(c, err, "Failed to compare scenarios")
+		return
+	}
+
+	c.JSON(http.StatusOK, comparison)
+}
+
+// ChooseScenario handles choosing the scenario the application is prepared with
+// @Summary Choose lead scenario
+// @Description Choose the scenario to apply with. It replaces an earlier choice and sets the lead's expected amount to its total.
+// @Tags leads
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Lead ID"
+// @Param scenarioId path string true "Scenario ID"
+// @Success 200 {object} scenarios.Scenario
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/leads/{id}/scenarios/{scenarioId}/choose [post]
+func (h *ScenarioHandler) ChooseScenario(c *gin.Context) {
+	viewer, leadID, scenarioID, ok := h.scenarioRequest(c)
+	if !ok {
+		return
+	}
+
+	scenario, err := h.scenarios.Choose(leadID, scenarioID, viewer)
+	if err != nil {
+		h.handleScenarioError(c, err, "Failed to choose scenario")
+		return
+	}
+
+	c.JSON(http.StatusOK, scenario)
+}
+
+// GetChosenScenario handles getting the scenario the application is prepared with
+// @Summary Get chosen lead scenario
+// @Description Get the chosen scenario of a lead to prefill the application
+// @Tags leads
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Lead ID"
+// @Success 200 {object} scenarios.Scenario
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/leads/{id}/scenarios/chosen [get]
+func (h *ScenarioHandler) GetChosenScenario(c *gin.Context) {
+	viewer, leadID, ok := h.leadRequest(c)
+	if !ok {
+		return
+	}
+
+	scenario, err := h.scenarios.Chosen(leadID, viewer)
+	if err != nil {
+		h.handleScenarioError(c, err, "Failed to fetch scenarios")
+		return
+	}
+
+	c.JSON(http.StatusOK, scenario)
+}
+
+// GetPayoutPlan handles getting the Bezugsplan of the chosen scenario
+// @Summary Get lead payout plan
+// @Description Get the Bezugsplan of the chosen scenario: the Lebensmonate each parent is paid Elterngeld in, with dates once the child is born, amounts and Partnermonate
+// @Tags leads
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Lead ID"
+// @Success 200 {object} scenarios.PayoutPlan
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/leads/{id}/payout-plan [get]
+func (h *ScenarioHandler) GetPayoutPlan(c *gin.Context) {
+	viewer, leadID, ok := h.leadRequest(c)
+	if !ok {
+		return
+	}
+
+	plan, err := h.scenarios.PayoutPlan(leadID, viewer)
+	if err != nil {
+		h.handleScenarioError(c, err, "Failed to create payout plan")
+		return
+	}
+
+	c.JSON(http.StatusOK, plan)
+}
+
+// DownloadPayoutPlan handles downloading the Bezugsplan as PDF
+// @Summary Download lead payout plan
+// @Description Download the Bezugsplan of the chosen scenario as PDF
+// @Tags leads
+// @Security BearerAuth
+// @Produce application/pdf
+// @Param id path string true "Lead ID"
+// @Success 200 {file} binary
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/leads/{id}/payout-plan/pdf [get]
+func (h *ScenarioHandler) DownloadPayoutPlan(c *gin.Context) {
+	viewer, leadID, ok := h.leadRequest(c)
+	if !ok {
+		return
+	}
+
+	plan, err := h.scenarios.PayoutPlan(leadID, viewer)
+	if err != nil {
+		h.handleScenarioError(c, err, "Failed to create payout plan")
+		return
+	}
+
+	// Payout plans contain personal data and must not be cached by shared proxies
+	c.Header("Cache-Control", "private, no-store")
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": "bezugsplan.pdf"}))
+	c.Data(http.StatusOK, "application/pdf", scenarios.RenderPDF(plan))
+}
+
+// SharePayoutPlan handles sharing the Bezugsplan with the customer
+// @Summary Share lead payout plan
+// @Description Notify the customer that the Bezugsplan of the chosen scenario is available as PDF in their dashboard
+// @Tags leads
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Lead ID"
+// @Success 200 {object} scenarios.PayoutPlan
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/leads/{id}/payout-plan/share [post]
+func (h *ScenarioHandler) SharePayoutPlan(c *gin.Context) {
+	viewer, leadID, ok := h.leadRequest(c)
+	if !ok {
+		return
+	}
+
+	plan, err := h.scenarios.SharePayoutPlan(leadID, viewer)
+	if err != nil {
+		h.handleScenarioError(c, err, "Failed to share payout plan")
+		return
+	}
+
+	c.JSON(http.StatusOK, plan)
+}
+
+func (h *ScenarioHandler) leadRequest(c *gin.Context) (scopes.Viewer, uuid.UUID, bool) {
+	viewer, ok := scopes.FromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "User not authenticated")})
+		return scopes.Viewer{}, uuid.Nil, false
+	}
+
+	leadID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid lead ID")})
+		return scopes.Viewer{}, uuid.Nil, false
+	}
+
+	return viewer, leadID, true
+}
+
+func (h *ScenarioHandler) scenarioRequest(c *gin.Context) (scopes.Viewer, uuid.UUID, uuid.UUID, bool) {
+	viewer, leadID, ok := h.leadRequest(c)
+	if !ok {
+		return scopes.Viewer{}, uuid.Nil, uuid.Nil, false
+	}
+
+	scenarioID, err := uuid.Parse(c.Param("scenarioId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid scenario ID")})
+		return scopes.Viewer{}, uuid.Nil, uuid.Nil, false
+	}
+
+	return viewer, leadID, scenarioID, true
+}
+
+func (h *ScenarioHandler) handleScenarioError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, scenarios.ErrLeadNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "Lead not found")})
+	case errors.Is(err, scenarios.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "Scenario not found")})
+	case errors.Is(err, scenarios.ErrNoneChosen):
+		c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "No scenario has been chosen yet")})
+	case errors.Is(err, scenarios.ErrInvalidPlan):
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid scenario plan"), "details": err.Error()})
+	case errors.Is(err, scenarios.ErrTooManyScenarios):
+		c.JSON(http.StatusConflict, gin.H{"error": middleware.T(c, "A lead can have at most %d scenarios", scenarios.MaxScenarios)})
+	default:
+		h.logger.Error(message, zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, message)})
+	}
+}
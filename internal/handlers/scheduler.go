@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"elterngeld-portal/internal/middleware"
+	"elterngeld-portal/internal/scheduler"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type SchedulerHandler struct {
+	db        *gorm.DB
+	logger    *zap.Logger
+	scheduler *scheduler.Service
+}
+
+func NewSchedulerHandler(db *gorm.DB, logger *zap.Logger, schedulerService *scheduler.Service) *SchedulerHandler {
+	return &SchedulerHandler{
+		db:        db,
+		logger:    logger,
+		scheduler: schedulerService,
+	}
+}
+
+// ListScheduledJobs handles listing the recurring jobs (admin only)
+// @Summary List scheduled jobs
+// @Description Get the recurring jobs with their schedule, next run and the outcome of their last run
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/admin/scheduler/jobs [get]
+func (h *SchedulerHandler) ListScheduledJobs(c *gin.Context) {
+	jobs, err := h.scheduler.List()
+	if err != nil {
+		h.handleSchedulerError(c, err, "Failed to fetch scheduled jobs")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"jobs": jobs})
+}
+
+// ListJobRuns handles listing the run history of a recurring job (admin only)
+// @Summary List job runs
+// @Description Get the runs of a recurring job with their trigger, duration and outcome, latest first
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Param name path string true "Job name"
+// @Param page query int false "Page number"
+// @Param limit query int false "Items per page"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/admin/scheduler/jobs/{name}/runs [get]
+func (h *SchedulerHandler) ListJobRuns(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(scheduler.DefaultLimit)))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > scheduler.MaxLimit {
+		limit = scheduler.DefaultLimit
+	}
+
+	runs, total, err := h.scheduler.Runs(c.Param("name"), page, limit)
+	if err != nil {
+		h.handleSchedulerError(c, err, "Failed to fetch job runs")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"runs": runs,
+		"pagination": gin.H{
+			"page":  page,
+			"limit": limit,
+			"total": total,
+			"pages": (total + int64(limit) - 1) / int64(limit),
+		},
+	})
+}
+
+// TriggerScheduledJob handles running a recurring job outside its schedule (admin only)
+// @Summary Trigger scheduled job
+// @Description Run a recurring job now, even when it is paused. The run starts with the scheduler's next check.
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Param name path string true "Job name"
+// @Success 202 {object} scheduler.Job
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/admin/scheduler/jobs/{name}/run [post]
+func (h *SchedulerHandler) TriggerScheduledJob(c *gin.Context) {
+	userID, ok := middleware.GetCurrentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "User not authenticated")})
+		return
+	}
+
+	job, err := h.scheduler.Trigger(c.Param("name"), userID)
+	if err != nil {
+		h.handleSchedulerError(c, err, "Failed to trigger scheduled job")
+		return
+	}
+
+	h.logger.Info("Scheduled job triggered by admin", zap.String("job", job.Name), zap.String("triggered_by", userID.String()))
+	c.JSON(http.StatusAccepted, job)
+}
+
+// PauseScheduledJob handles pausing a recurring job (admin only)
+// @Summary Pause scheduled job
+// @Description Stop a recurring job from running on its schedule until it is resumed
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Param name path string true "Job name"
+// @Success 200 {object} scheduler.Job
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/admin/scheduler/jobs/{name}/pause [post]
+func (h *SchedulerHandler) PauseScheduledJob(c *gin.Context) {
+	userID, ok := middleware.GetCurrentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "User not authenticated")})
+		return
+	}
+
+	job, err := h.scheduler.Pause(c.Param("name"), userID)
+	if err != nil {
+		h.handleSchedulerError(c, err, "Failed to pause scheduled job")
+		return
+	}
+
+	h.logger.Info("Scheduled job paused by admin", zap.String("job", job.Name), zap.String("paused_by", userID.String()))
+	c.JSON(http.StatusOK, job)
+}
+
+// ResumeScheduledJob handles resuming a paused recurring job (admin only)
+// @Summary Resume scheduled job
+// @Description Let a paused job run on its schedule again, starting with the next time due; missed runs are not made up
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Param name path string true "Job name"
+// @Success 200 {object} scheduler.Job
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/admin/scheduler/jobs/{name}/resume [post]
+func (h *SchedulerHandler) ResumeScheduledJob(c *gin.Context) {
+	job, err := h.scheduler.Resume(c.Param("name"))
+	if err != nil {
+		h.handleSchedulerError(c, err, "Failed to resume scheduled job")
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
+
+func (h *SchedulerHandler) handleSchedulerError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, scheduler.ErrJobNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "Scheduled job not found")})
+	default:
+		h.logger.Error(message, zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, message)})
+	}
+}
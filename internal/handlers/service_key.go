@@ -0,0 +1,225 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"elterngeld-portal/internal/middleware"
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/internal/servicekeys"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type ServiceKeyHandler struct {
+	db     *gorm.DB
+	logger *zap.Logger
+	keys   *servicekeys.Service
+}
+
+func NewServiceKeyHandler(db *gorm.DB, logger *zap.Logger, keyService *servicekeys.Service) *ServiceKeyHandler {
+	return &ServiceKeyHandler{
+		db:     db,
+		logger: logger,
+		keys:   keyService,
+	}
+}
+
+// RotateServiceKeyRequest sets how long the replaced key keeps working, in
+// hours; without it the replaced key works for another 24 hours
+type RotateServiceKeyRequest struct {
+	GraceHours *int `json:"grace_hours"`
+}
+
+// ListServiceKeys handles listing the API keys of machine integrations (admin only)
+// @Summary List service keys
+// @Description Get the API keys of the website forms, partner systems and other machine integrations
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/admin/service-keys [get]
+func (h *ServiceKeyHandler) ListServiceKeys(c *gin.Context) {
+	keys, err := h.keys.List()
+	if err != nil {
+		h.handleServiceKeyError(c, err, "Failed to fetch service keys")
+		return
+	}
+
+	responses := make([]models.ServiceKeyResponse, len(keys))
+	for i := range keys {
+		responses[i] = keys[i].ToResponse()
+	}
+
+	c.JSON(http.StatusOK, gin.H{"service_keys": responses})
+}
+
+// CreateServiceKey handles creating an API key for a machine integration (admin only)
+// @Summary Create service key
+// @Description Create a scoped API key for a machine integration, optionally limited to IP addresses and CIDR ranges. The key is only returned once.
+// @Tags admin
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body servicekeys.Input true "Service key data"
+// @Success 201 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/v1/admin/service-keys [post]
+func (h *ServiceKeyHandler) CreateServiceKey(c *gin.Context) {
+	userID, ok := middleware.GetCurrentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "User not authenticated")})
+		return
+	}
+
+	var req servicekeys.Input
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid request data"), "details": err.Error()})
+		return
+	}
+
+	key, plain, err := h.keys.Create(req, userID)
+	if err != nil {
+		h.handleServiceKeyError(c, err, "Failed to create service key")
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"service_key": key.ToResponse(),
+		"key":         plain,
+	})
+}
+
+// UpdateServiceKey handles changing the scopes, allowlist, rate limit and expiry of a service key (admin only)
+// @Summary Update service key
+// @Description Change the name, scopes, IP allowlist, rate limit and expiry of a service key; the key itself stays the same
+// @Tags admin
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Service key ID"
+// @Param request body servicekeys.Input true "Service key data"
+// @Success 200 {object} models.ServiceKeyResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/admin/service-keys/{id} [put]
+func (h *ServiceKeyHandler) UpdateServiceKey(c *gin.Context) {
+	keyID, ok := h.keyID(c)
+	if !ok {
+		return
+	}
+
+	var req servicekeys.Input
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid request data"), "details": err.Error()})
+		return
+	}
+
+	key, err := h.keys.Update(keyID, req)
+	if err != nil {
+		h.handleServiceKeyError(c, err, "Failed to update service key")
+		return
+	}
+
+	c.JSON(http.StatusOK, key.ToResponse())
+}
+
+// RotateServiceKey handles replacing the secret of a service key (admin only)
+// @Summary Rotate service key
+// @Description Issue a new key for a service key. The replaced key keeps working for the grace period (default 24 hours, at most 168) so the integration can be switched over.
+// @Tags admin
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Service key ID"
+// @Param request body RotateServiceKeyRequest false "Grace period"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/admin/service-keys/{id}/rotate [post]
+func (h *ServiceKeyHandler) RotateServiceKey(c *gin.Context) {
+	keyID, ok := h.keyID(c)
+	if !ok {
+		return
+	}
+
+	var req RotateServiceKeyRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid request data"), "details": err.Error()})
+			return
+		}
+	}
+	grace := servicekeys.DefaultRotationGrace
+	if req.GraceHours != nil {
+		grace = time.Duration(*req.GraceHours) * time.Hour
+	}
+
+	key, plain, err := h.keys.Rotate(keyID, grace)
+	if err != nil {
+		h.handleServiceKeyError(c, err, "Failed to rotate service key")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"service_key": key.ToResponse(),
+		"key":         plain,
+	})
+}
+
+// RevokeServiceKey handles revoking a service key (admin only)
+// @Summary Revoke service key
+// @Description Revoke a service key; the key and a replaced key in its grace period stop working immediately
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Service key ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/admin/service-keys/{id} [delete]
+func (h *ServiceKeyHandler) RevokeServiceKey(c *gin.Context) {
+	keyID, ok := h.keyID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.keys.Revoke(keyID); err != nil {
+		h.handleServiceKeyError(c, err, "Failed to revoke service key")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": middleware.T(c, "Service key revoked")})
+}
+
+func (h *ServiceKeyHandler) keyID(c *gin.Context) (uuid.UUID, bool) {
+	keyID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid service key ID")})
+		return uuid.Nil, false
+	}
+	return keyID, true
+}
+
+func (h *ServiceKeyHandler) handleServiceKeyError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, servicekeys.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "Service key not found")})
+	case errors.Is(err, servicekeys.ErrInvalidScope):
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Unknown service key scope")})
+	case errors.Is(err, servicekeys.ErrInvalidAllowedIP):
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid IP address or range"), "details": err.Error()})
+	case errors.Is(err, servicekeys.ErrInvalidRateLimit):
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Rate limit must be between 0 and %d requests per minute", servicekeys.MaxRateLimit)})
+	case errors.Is(err, servicekeys.ErrInvalidExpiry):
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Expiry date must be in the future")})
+	case errors.Is(err, servicekeys.ErrInvalidGrace):
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Grace period must be between 0 and 168 hours")})
+	default:
+		h.logger.Error(message, zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, message)})
+	}
+}
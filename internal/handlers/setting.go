@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"elterngeld-portal/internal/middleware"
+	"elterngeld-portal/internal/settings"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type SettingHandler struct {
+	db       *gorm.DB
+	logger   *zap.Logger
+	settings *settings.Service
+}
+
+func NewSettingHandler(db *gorm.DB, logger *zap.Logger, settingsService *settings.Service) *SettingHandler {
+	return &SettingHandler{
+		db:       db,
+		logger:   logger,
+		settings: settingsService,
+	}
+}
+
+// UpdateSettingRequest represents a new value for a system setting. Durations
+// are given like "24h" or "90m".
+type UpdateSettingRequest struct {
+	Value string `json:"value"`
+}
+
+// ListSettings handles listing the system settings (admin only)
+// @Summary List system settings
+// @Description Get the system settings with their current values, defaults and types
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Param group query string false "Filter by group: booking, leads or support"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/admin/settings [get]
+func (h *SettingHandler) ListSettings(c *gin.Context) {
+	entries, err := h.settings.List(c.Query("group"))
+	if err != nil {
+		h.logger.Error("Failed to fetch settings", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to fetch settings")})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"settings": entries})
+}
+
+// GetSetting handles getting a system setting (admin only)
+// @Summary Get system setting
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Param key path string true "Setting key, e.g. booking.cancellation_window"
+// @Success 200 {object} settings.Entry
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/admin/settings/{key} [get]
+func (h *SettingHandler) GetSetting(c *gin.Context) {
+	entry, err := h.settings.Get(c.Param("key"))
+	if err != nil {
+		h.handleSettingError(c, err, "Failed to fetch settings")
+		return
+	}
+
+	c.JSON(http.StatusOK, entry)
+}
+
+// UpdateSetting handles changing a system setting (admin only)
+// @Summary Update system setting
+// @Description Change a system setting. The value is checked against the type of the setting and takes effect within a minute on all instances.
+// @Tags admin
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param key path string true "Setting key"
+// @Param request body UpdateSettingRequest true "New value"
+// @Success 200 {object} settings.Entry
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/admin/settings/{key} [put]
+func (h *SettingHandler) UpdateSetting(c *gin.Context) {
+	userID, ok := middleware.GetCurrentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "User not authenticated")})
+		return
+	}
+
+	var req UpdateSettingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid request data")})
+		return
+	}
+
+	entry, err := h.settings.Update(c.Param("key"), req.Value, userID)
+	if err != nil {
+		h.handleSettingError(c, err, "Failed to update setting")
+		return
+	}
+
+	c.JSON(http.StatusOK, entry)
+}
+
+// ResetSetting handles restoring the default of a system setting (admin only)
+// @Summary Reset system setting
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Param key path string true "Setting key"
+// @Success 200 {object} settings.Entry
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/admin/settings/{key} [delete]
+func (h *SettingHandler) ResetSetting(c *gin.Context) {
+	entry, err := h.settings.Reset(c.Param("key"))
+	if err != nil {
+		h.handleSettingError(c, err, "Failed to update setting")
+		return
+	}
+
+	c.JSON(http.StatusOK, entry)
+}
+
+// GetPublicSettings handles getting the settings shown to customers, such as
+// support contact details and cancellation windows
+// @Summary Get public settings
+// @Tags settings
+// @Produce json
+// @Success 200 {object} map[string]string
+// @Router /api/v1/settings/public [get]
+func (h *SettingHandler) GetPublicSettings(c *gin.Context) {
+	c.JSON(http.StatusOK, h.settings.Public())
+}
+
+func (h *SettingHandler) handleSettingError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, settings.ErrUnknownSetting):
+		c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "Setting not found")})
+	case errors.Is(err, settings.ErrInvalidValue):
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid setting value"), "details": err.Error()})
+	default:
+		h.logger.Error(message, zap.String("key", c.Param("key")), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, message)})
+	}
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"elterngeld-portal/internal/middleware"
+	"elterngeld-portal/internal/shortlink"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type ShortLinkHandler struct {
+	db     *gorm.DB
+	logger *zap.Logger
+	links  *shortlink.Service
+}
+
+func NewShortLinkHandler(db *gorm.DB, logger *zap.Logger, links *shortlink.Service) *ShortLinkHandler {
+	return &ShortLinkHandler{
+		db:     db,
+		logger: logger,
+		links:  links,
+	}
+}
+
+// Redirect handles short link redirects from email call-to-actions
+// @Summary Follow short link
+// @Description Record a click on a tracked email link and redirect to its target
+// @Tags short-links
+// @Param code path string true "Short link code"
+// @Success 302
+// @Failure 404 {object} map[string]interface{}
+// @Failure 410 {object} map[string]interface{}
+// @Router /r/{code} [get]
+func (h *ShortLinkHandler) Redirect(c *gin.Context) {
+	link, err := h.links.Resolve(c.Param("code"), shortlink.Click{
+		IPAddress: c.ClientIP(),
+		UserAgent: c.GetHeader("User-Agent"),
+		Referer:   c.GetHeader("Referer"),
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, shortlink.ErrNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "Link not found")})
+		case errors.Is(err, shortlink.ErrExpired):
+			c.JSON(http.StatusGone, gin.H{"error": middleware.T(c, "Link has expired")})
+		default:
+			h.logger.Error("Failed to resolve short link", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to resolve link")})
+		}
+		return
+	}
+
+	c.Redirect(http.StatusFound, link.TargetURL)
+}
+
+// GetLeadLinkStats handles retrieving click statistics for links sent to a lead
+// @Summary Get lead link statistics
+// @Description Get click statistics for tracked email links sent to a lead
+// @Tags leads
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Lead ID"
+// @Success 200 {object} shortlink.Stats
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/v1/leads/{id}/link-stats [get]
+func (h *ShortLinkHandler) GetLeadLinkStats(c *gin.Context) {
+	leadID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid lead ID")})
+		return
+	}
+
+	stats, err := h.links.LeadStats(leadID)
+	if err != nil {
+		h.logger.Error("Failed to fetch link statistics", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to fetch link statistics")})
+		return
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
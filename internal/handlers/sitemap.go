@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"net/http"
+
+	"elterngeld-portal/internal/middleware"
+	"elterngeld-portal/internal/sitemap"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type SitemapHandler struct {
+	db      *gorm.DB
+	logger  *zap.Logger
+	sitemap *sitemap.Service
+}
+
+func NewSitemapHandler(db *gorm.DB, logger *zap.Logger, sitemapService *sitemap.Service) *SitemapHandler {
+	return &SitemapHandler{
+		db:      db,
+		logger:  logger,
+		sitemap: sitemapService,
+	}
+}
+
+// Sitemap handles the sitemap.xml of the public website
+// @Summary Sitemap
+// @Description Get the sitemap with the static pages, active packages, public blog posts, published content and open jobs. It is rebuilt at most once per hour.
+// @Tags seo
+// @Produce xml
+// @Success 200 {string} string
+// @Router /sitemap.xml [get]
+func (h *SitemapHandler) Sitemap(c *gin.Context) {
+	body, err := h.sitemap.XML()
+	if err != nil {
+		h.logger.Error("Failed to build sitemap", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to build sitemap")})
+		return
+	}
+
+	c.Header("Cache-Control", "public, max-age=3600")
+	c.Data(http.StatusOK, "application/xml; charset=utf-8", body)
+}
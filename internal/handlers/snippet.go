@@ -0,0 +1,217 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"elterngeld-portal/internal/middleware"
+	"elterngeld-portal/internal/scopes"
+	"elterngeld-portal/internal/snippets"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type SnippetHandler struct {
+	db       *gorm.DB
+	logger   *zap.Logger
+	snippets *snippets.Service
+}
+
+func NewSnippetHandler(db *gorm.DB, logger *zap.Logger, snippetService *snippets.Service) *SnippetHandler {
+	return &SnippetHandler{
+		db:       db,
+		logger:   logger,
+		snippets: snippetService,
+	}
+}
+
+// ListSnippets handles listing the snippets available to the current user (Berater and admins)
+// @Summary List snippets
+// @Description Get the current user's personal snippets and the team snippets, by category and title
+// @Tags snippets
+// @Security BearerAuth
+// @Produce json
+// @Param category query string false "Category"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/snippets [get]
+func (h *SnippetHandler) ListSnippets(c *gin.Context) {
+	viewer, ok := h.viewer(c)
+	if !ok {
+		return
+	}
+
+	list, err := h.snippets.List(viewer, c.Query("category"))
+	if err != nil {
+		h.handleSnippetError(c, err, "Failed to fetch snippets")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"snippets": list, "placeholders": snippets.Placeholders})
+}
+
+// CreateSnippet handles adding a snippet (Berater and admins)
+// @Summary Create snippet
+// @Description Add a personal or team snippet; the body may contain merge fields like {{customer.name}}
+// @Tags snippets
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body snippets.Input true "Snippet"
+// @Success 201 {object} models.Snippet
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/v1/snippets [post]
+func (h *SnippetHandler) CreateSnippet(c *gin.Context) {
+	viewer, ok := h.viewer(c)
+	if !ok {
+		return
+	}
+
+	var req snippets.Input
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid request data"), "details": err.Error()})
+		return
+	}
+
+	snippet, err := h.snippets.Create(viewer, req)
+	if err != nil {
+		h.handleSnippetError(c, err, "Failed to create snippet")
+		return
+	}
+
+	c.JSON(http.StatusCreated, snippet)
+}
+
+// UpdateSnippet handles changing a snippet (owner and admins)
+// @Summary Update snippet
+// @Description Replace the title, body, category and scope of a snippet
+// @Tags snippets
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Snippet ID"
+// @Param request body snippets.Input true "Snippet"
+// @Success 200 {object} models.Snippet
+// @Failure 400 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/snippets/{id} [put]
+func (h *SnippetHandler) UpdateSnippet(c *gin.Context) {
+	viewer, snippetID, ok := h.snippetRequest(c)
+	if !ok {
+		return
+	}
+
+	var req snippets.Input
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid request data"), "details": err.Error()})
+		return
+	}
+
+	snippet, err := h.snippets.Update(snippetID, viewer, req)
+	if err != nil {
+		h.handleSnippetError(c, err, "Failed to update snippet")
+		return
+	}
+
+	c.JSON(http.StatusOK, snippet)
+}
+
+// DeleteSnippet handles deleting a snippet (owner and admins)
+// @Summary Delete snippet
+// @Description Delete a snippet
+// @Tags snippets
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Snippet ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/snippets/{id} [delete]
+func (h *SnippetHandler) DeleteSnippet(c *gin.Context) {
+	viewer, snippetID, ok := h.snippetRequest(c)
+	if !ok {
+		return
+	}
+
+	if err := h.snippets.Delete(snippetID, viewer); err != nil {
+		h.handleSnippetError(c, err, "Failed to delete snippet")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": middleware.T(c, "Snippet deleted successfully")})
+}
+
+// RenderSnippet handles filling in a snippet for a reply (Berater and admins)
+// @Summary Render snippet
+// @Description Fill in the placeholders of a snippet from the contact form, lead or booking being answered
+// @Tags snippets
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Snippet ID"
+// @Param request body snippets.RenderContext true "Reply context"
+// @Success 200 {object} snippets.Rendered
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/snippets/{id}/render [post]
+func (h *SnippetHandler) RenderSnippet(c *gin.Context) {
+	viewer, snippetID, ok := h.snippetRequest(c)
+	if !ok {
+		return
+	}
+
+	var req snippets.RenderContext
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid request data"), "details": err.Error()})
+		return
+	}
+
+	rendered, err := h.snippets.Render(snippetID, viewer, req)
+	if err != nil {
+		h.handleSnippetError(c, err, "Failed to render snippet")
+		return
+	}
+
+	c.JSON(http.StatusOK, rendered)
+}
+
+func (h *SnippetHandler) viewer(c *gin.Context) (scopes.Viewer, bool) {
+	viewer, ok := scopes.FromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "User not authenticated")})
+	}
+	return viewer, ok
+}
+
+func (h *SnippetHandler) snippetRequest(c *gin.Context) (scopes.Viewer, uuid.UUID, bool) {
+	viewer, ok := h.viewer(c)
+	if !ok {
+		return scopes.Viewer{}, uuid.Nil, false
+	}
+
+	snippetID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid snippet ID")})
+		return scopes.Viewer{}, uuid.Nil, false
+	}
+
+	return viewer, snippetID, true
+}
+
+func (h *SnippetHandler) handleSnippetError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, snippets.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "Snippet not found")})
+	case errors.Is(err, snippets.ErrNotOwner):
+		c.JSON(http.StatusForbidden, gin.H{"error": middleware.T(c, "Only the owner or an admin can change this snippet")})
+	case errors.Is(err, snippets.ErrUnknownPlaceholder):
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Unknown placeholder in snippet"), "details": err.Error()})
+	case errors.Is(err, snippets.ErrContextNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "Contact form, lead or booking not found")})
+	default:
+		h.logger.Error(message, zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, message)})
+	}
+}
@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"elterngeld-portal/internal/ledger"
+	"elterngeld-portal/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type StatementHandler struct {
+	db     *gorm.DB
+	logger *zap.Logger
+	ledger *ledger.Service
+}
+
+func NewStatementHandler(db *gorm.DB, logger *zap.Logger, ledgerService *ledger.Service) *StatementHandler {
+	return &StatementHandler{
+		db:     db,
+		logger: logger,
+		ledger: ledgerService,
+	}
+}
+
+// GetBookingStatement handles the payment history of a booking (Berater/Admin only)
+// @Summary Booking statement
+// @Description Charges, refunds, used credit and disputes of a booking, oldest first, with running balances
+// @Tags bookings
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Booking ID"
+// @Success 200 {object} ledger.Statement
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/bookings/{id}/statement [get]
+func (h *StatementHandler) GetBookingStatement(c *gin.Context) {
+	bookingID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid booking ID")})
+		return
+	}
+
+	statement, err := h.ledger.ForBooking(bookingID)
+	if err != nil {
+		if errors.Is(err, ledger.ErrBookingNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "Booking not found")})
+			return
+		}
+		h.logger.Error("Failed to build booking statement", zap.String("booking_id", bookingID.String()), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to fetch statement")})
+		return
+	}
+
+	c.JSON(http.StatusOK, statement)
+}
+
+// GetUserStatement handles the payment history of a customer (Berater/Admin only)
+// @Summary Customer statement
+// @Description Charges, refunds, account credit, vouchers and disputes of a customer across all bookings, oldest first, with running balances
+// @Tags users
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "User ID"
+// @Success 200 {object} ledger.Statement
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/users/{id}/statement [get]
+func (h *StatementHandler) GetUserStatement(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid user ID")})
+		return
+	}
+
+	statement, err := h.ledger.ForUser(userID)
+	if err != nil {
+		if errors.Is(err, ledger.ErrUserNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "User not found")})
+			return
+		}
+		h.logger.Error("Failed to build customer statement", zap.String("user_id", userID.String()), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to fetch statement")})
+		return
+	}
+
+	c.JSON(http.StatusOK, statement)
+}
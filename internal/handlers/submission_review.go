@@ -0,0 +1,198 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"elterngeld-portal/internal/abuse"
+	"elterngeld-portal/internal/inbox"
+	"elterngeld-portal/internal/middleware"
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/internal/verification"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type SubmissionReviewHandler struct {
+	db           *gorm.DB
+	logger       *zap.Logger
+	abuse        *abuse.Service
+	inbox        *inbox.Service
+	verification *verification.Service
+}
+
+func NewSubmissionReviewHandler(db *gorm.DB, logger *zap.Logger, abuseService *abuse.Service, inboxService *inbox.Service, verificationService *verification.Service) *SubmissionReviewHandler {
+	return &SubmissionReviewHandler{
+		db:           db,
+		logger:       logger,
+		abuse:        abuseService,
+		inbox:        inboxService,
+		verification: verificationService,
+	}
+}
+
+// ListSubmissionReviews handles listing the review queue of flagged public submissions (admin only)
+// @Summary List flagged submissions
+// @Description Get contact forms and registrations that were held for review because of their anomaly score
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Param status query string false "Review status (pending, approved, rejected)" default(pending)
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/v1/admin/submission-reviews [get]
+func (h *SubmissionReviewHandler) ListSubmissionReviews(c *gin.Context) {
+	status := models.SubmissionReviewStatus(c.DefaultQuery("status", string(models.SubmissionReviewPending)))
+	if !status.IsValid() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid review status")})
+		return
+	}
+
+	checks, err := h.abuse.List(status)
+	if err != nil {
+		h.handleSubmissionReviewError(c, err, "Failed to fetch flagged submissions")
+		return
+	}
+
+	responses := make([]models.SubmissionCheckResponse, len(checks))
+	for i := range checks {
+		responses[i] = checks[i].ToResponse()
+	}
+
+	c.JSON(http.StatusOK, gin.H{"submissions": responses})
+}
+
+// ApproveSubmission handles releasing a flagged submission (admin only)
+// @Summary Approve flagged submission
+// @Description Release a held submission: a contact form becomes a lead, a registration gets its verification email
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Submission check ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/v1/admin/submission-reviews/{id}/approve [post]
+func (h *SubmissionReviewHandler) ApproveSubmission(c *gin.Context) {
+	reviewerID, checkID, ok := h.reviewParams(c)
+	if !ok {
+		return
+	}
+
+	var check *models.SubmissionCheck
+	var lead *models.Lead
+	var user models.User
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		check, err = h.abuse.Review(tx, checkID, reviewerID, true)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case check.ContactFormID != nil:
+			var contactForm models.ContactForm
+			if err := tx.First(&contactForm, "id = ?", *check.ContactFormID).Error; err != nil {
+				return err
+			}
+			lead, err = h.inbox.CreateContactFormLead(tx, &contactForm)
+			return err
+		case check.UserID != nil:
+			return tx.First(&user, "id = ?", *check.UserID).Error
+		}
+		return nil
+	})
+	if err != nil {
+		h.handleSubmissionReviewError(c, err, "Failed to approve submission")
+		return
+	}
+
+	// The account exists either way; the user can request a new link
+	if check.UserID != nil {
+		if err := h.verification.Issue(&user); err != nil {
+			h.logger.Error("Failed to send verification email", zap.String("user_id", user.ID.String()), zap.Error(err))
+		}
+	}
+
+	response := gin.H{"submission": check.ToResponse()}
+	if lead != nil {
+		response["lead_id"] = lead.ID
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// RejectSubmission handles discarding a flagged submission (admin only)
+// @Summary Reject flagged submission
+// @Description Discard a held submission: a contact form is kept without a lead, an unverified account is deleted
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Submission check ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/v1/admin/submission-reviews/{id}/reject [post]
+func (h *SubmissionReviewHandler) RejectSubmission(c *gin.Context) {
+	reviewerID, checkID, ok := h.reviewParams(c)
+	if !ok {
+		return
+	}
+
+	var check *models.SubmissionCheck
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		check, err = h.abuse.Review(tx, checkID, reviewerID, false)
+		if err != nil || check.UserID == nil {
+			return err
+		}
+
+		// Hard delete so the email address can be registered again; accounts
+		// verified in the meantime are kept
+		var userIDs []uuid.UUID
+		if err := tx.Model(&models.User{}).
+			Where("id = ? AND email_verified = ?", *check.UserID, false).
+			Pluck("id", &userIDs).Error; err != nil || len(userIDs) == 0 {
+			return err
+		}
+		if err := tx.Unscoped().Where("user_id IN ?", userIDs).Delete(&models.RefreshToken{}).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Where("id IN ?", userIDs).Delete(&models.User{}).Error
+	})
+	if err != nil {
+		h.handleSubmissionReviewError(c, err, "Failed to reject submission")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"submission": check.ToResponse()})
+}
+
+func (h *SubmissionReviewHandler) reviewParams(c *gin.Context) (uuid.UUID, uuid.UUID, bool) {
+	reviewerID, ok := middleware.GetCurrentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "User not authenticated")})
+		return uuid.Nil, uuid.Nil, false
+	}
+
+	checkID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid submission ID")})
+		return uuid.Nil, uuid.Nil, false
+	}
+	return reviewerID, checkID, true
+}
+
+func (h *SubmissionReviewHandler) handleSubmissionReviewError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, abuse.ErrNotFound), errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "Submission not found")})
+	case errors.Is(err, abuse.ErrAlreadyReviewed):
+		c.JSON(http.StatusConflict, gin.H{"error": middleware.T(c, "Submission was already reviewed")})
+	default:
+		h.logger.Error(message, zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, message)})
+	}
+}
@@ -2,9 +2,12 @@ package handlers
 
 import (
 	"net/http"
+	"sort"
 	"strconv"
 	"time"
 
+	"elterngeld-portal/internal/activitylog"
+	"elterngeld-portal/internal/middleware"
 	"elterngeld-portal/internal/models"
 
 	"github.com/gin-gonic/gin"
@@ -14,14 +17,16 @@ import (
 )
 
 type TodoHandler struct {
-	db     *gorm.DB
-	logger *zap.Logger
+	db         *gorm.DB
+	logger     *zap.Logger
+	activities *activitylog.Service
 }
 
-func NewTodoHandler(db *gorm.DB, logger *zap.Logger) *TodoHandler {
+func NewTodoHandler(db *gorm.DB, logger *zap.Logger, activityLog *activitylog.Service) *TodoHandler {
 	return &TodoHandler{
-		db:     db,
-		logger: logger,
+		db:         db,
+		logger:     logger,
+		activities: activityLog,
 	}
 }
 
@@ -62,7 +67,7 @@ type UpdateTodoRequest struct {
 func (h *TodoHandler) ListTodos(c *gin.Context) {
 	userID, exists := c.Get("user_id")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "User not authenticated")})
 		return
 	}
 
@@ -114,7 +119,7 @@ func (h *TodoHandler) ListTodos(c *gin.Context) {
 	if err := query.Preload("User").Preload("AssignedBy").Preload("Lead").Preload("Booking").
 		Offset(offset).Limit(limit).Order("created_at DESC").Find(&todos).Error; err != nil {
 		h.logger.Error("Failed to fetch todos", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch todos"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to fetch todos")})
 		return
 	}
 
@@ -145,7 +150,7 @@ func (h *TodoHandler) ListTodos(c *gin.Context) {
 func (h *TodoHandler) CreateTodo(c *gin.Context) {
 	userID, exists := c.Get("user_id")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "User not authenticated")})
 		return
 	}
 
@@ -153,13 +158,13 @@ func (h *TodoHandler) CreateTodo(c *gin.Context) {
 
 	// Only beraters and admins can create todos for other users
 	if userRole != "berater" && userRole != "junior_berater" && userRole != "admin" {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+		c.JSON(http.StatusForbidden, gin.H{"error": middleware.T(c, "Insufficient permissions")})
 		return
 	}
 
 	var req CreateTodoRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data", "details": err.Error()})
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid request data"), "details": err.Error()})
 		return
 	}
 
@@ -167,10 +172,10 @@ func (h *TodoHandler) CreateTodo(c *gin.Context) {
 	var targetUser models.User
 	if err := h.db.Where("id = ?", req.UserID).First(&targetUser).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Target user not found"})
+			c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Target user not found")})
 		} else {
 			h.logger.Error("Failed to fetch target user", zap.Error(err))
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify target user"})
+			c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to verify target user")})
 		}
 		return
 	}
@@ -179,7 +184,7 @@ func (h *TodoHandler) CreateTodo(c *gin.Context) {
 	if req.LeadID != nil {
 		var lead models.Lead
 		if err := h.db.Where("id = ?", *req.LeadID).First(&lead).Error; err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid lead ID"})
+			c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid lead ID")})
 			return
 		}
 	}
@@ -187,7 +192,7 @@ func (h *TodoHandler) CreateTodo(c *gin.Context) {
 	if req.BookingID != nil {
 		var booking models.Booking
 		if err := h.db.Where("id = ?", *req.BookingID).First(&booking).Error; err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid booking ID"})
+			c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid booking ID")})
 			return
 		}
 	}
@@ -214,23 +219,24 @@ func (h *TodoHandler) CreateTodo(c *gin.Context) {
 		UpdatedAt:    time.Now(),
 	}
 
-	if err := h.db.Create(&todo).Error; err != nil {
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&todo).Error; err != nil {
+			return err
+		}
+		return h.activities.Record(tx, activitylog.TodoCreated{
+			ActorID:    userID.(uuid.UUID),
+			LeadID:     req.LeadID,
+			TodoID:     todo.ID,
+			Title:      todo.Title,
+			AssigneeID: req.UserID,
+		})
+	})
+	if err != nil {
 		h.logger.Error("Failed to create todo", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create todo"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to create todo")})
 		return
 	}
 
-	// Create activity log
-	activity := models.Activity{
-		ID:          uuid.New(),
-		UserID:      userID.(uuid.UUID),
-		LeadID:      req.LeadID,
-		Type:        models.ActivityTypeTodoCreated,
-		Description: "Todo created: " + todo.Title,
-		CreatedAt:   time.Now(),
-	}
-	h.db.Create(&activity)
-
 	h.logger.Info("Todo created successfully", 
 		zap.String("todo_id", todo.ID.String()),
 		zap.String("assigned_by", userID.(uuid.UUID).String()),
@@ -261,7 +267,7 @@ func (h *TodoHandler) CreateTodo(c *gin.Context) {
 func (h *TodoHandler) GetTodo(c *gin.Context) {
 	userID, exists := c.Get("user_id")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "User not authenticated")})
 		return
 	}
 
@@ -281,10 +287,10 @@ func (h *TodoHandler) GetTodo(c *gin.Context) {
 
 	if err := query.Preload("User").Preload("AssignedBy").Preload("Lead").Preload("Booking").First(&todo).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Todo not found"})
+			c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "Todo not found")})
 		} else {
 			h.logger.Error("Failed to fetch todo", zap.Error(err))
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch todo"})
+			c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to fetch todo")})
 		}
 		return
 	}
@@ -309,7 +315,7 @@ func (h *TodoHandler) GetTodo(c *gin.Context) {
 func (h *TodoHandler) UpdateTodo(c *gin.Context) {
 	userID, exists := c.Get("user_id")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "User not authenticated")})
 		return
 	}
 
@@ -328,17 +334,17 @@ func (h *TodoHandler) UpdateTodo(c *gin.Context) {
 
 	if err := query.First(&todo).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Todo not found"})
+			c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "Todo not found")})
 		} else {
 			h.logger.Error("Failed to fetch todo", zap.Error(err))
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch todo"})
+			c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to fetch todo")})
 		}
 		return
 	}
 
 	var req UpdateTodoRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data", "details": err.Error()})
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid request data"), "details": err.Error()})
 		return
 	}
 
@@ -366,34 +372,48 @@ func (h *TodoHandler) UpdateTodo(c *gin.Context) {
 	}
 
 	if len(updates) == 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "No valid fields to update"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "No valid fields to update")})
 		return
 	}
 
+	fields := make([]string, 0, len(updates))
+	for field := range updates {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
 	updates["updated_at"] = time.Now()
 
-	if err := h.db.Model(&todo).Updates(updates).Error; err != nil {
-		h.logger.Error("Failed to update todo", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update todo"})
-		return
+	var event activitylog.Event = activitylog.TodoUpdated{
+		ActorID: userID.(uuid.UUID),
+		LeadID:  todo.LeadID,
+		TodoID:  todo.ID,
+		Title:   todo.Title,
+		Fields:  fields,
+	}
+	if req.Status == string(models.TodoStatusCompleted) {
+		event = activitylog.TodoCompleted{
+			ActorID: userID.(uuid.UUID),
+			LeadID:  todo.LeadID,
+			TodoID:  todo.ID,
+			Title:   todo.Title,
+		}
 	}
 
-	// Create activity log if status changed
-	if req.Status != "" {
-		activity := models.Activity{
-			ID:          uuid.New(),
-			UserID:      userID.(uuid.UUID),
-			LeadID:      todo.LeadID,
-			Type:        models.ActivityTypeTodoUpdated,
-			Description: "Todo status changed to: " + req.Status,
-			CreatedAt:   time.Now(),
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&todo).Updates(updates).Error; err != nil {
+			return err
 		}
-		h.db.Create(&activity)
+		return h.activities.Record(tx, event)
+	})
+	if err != nil {
+		h.logger.Error("Failed to update todo", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to update todo")})
+		return
 	}
 
 	// Fetch updated todo
 	if err := h.db.Preload("User").Preload("AssignedBy").Preload("Lead").Preload("Booking").First(&todo, "id = ?", todoID).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch updated todo"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to fetch updated todo")})
 		return
 	}
 
@@ -414,7 +434,7 @@ func (h *TodoHandler) UpdateTodo(c *gin.Context) {
 func (h *TodoHandler) CompleteTodo(c *gin.Context) {
 	userID, exists := c.Get("user_id")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "User not authenticated")})
 		return
 	}
 
@@ -433,10 +453,10 @@ func (h *TodoHandler) CompleteTodo(c *gin.Context) {
 
 	if err := query.First(&todo).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Todo not found"})
+			c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "Todo not found")})
 		} else {
 			h.logger.Error("Failed to fetch todo", zap.Error(err))
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch todo"})
+			c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to fetch todo")})
 		}
 		return
 	}
@@ -447,23 +467,23 @@ func (h *TodoHandler) CompleteTodo(c *gin.Context) {
 	todo.CompletedAt = &now
 	todo.UpdatedAt = now
 
-	if err := h.db.Save(&todo).Error; err != nil {
+	err := h.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&todo).Error; err != nil {
+			return err
+		}
+		return h.activities.Record(tx, activitylog.TodoCompleted{
+			ActorID: userID.(uuid.UUID),
+			LeadID:  todo.LeadID,
+			TodoID:  todo.ID,
+			Title:   todo.Title,
+		})
+	})
+	if err != nil {
 		h.logger.Error("Failed to complete todo", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to complete todo"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to complete todo")})
 		return
 	}
 
-	// Create activity log
-	activity := models.Activity{
-		ID:          uuid.New(),
-		UserID:      userID.(uuid.UUID),
-		LeadID:      todo.LeadID,
-		Type:        models.ActivityTypeTodoCompleted,
-		Description: "Todo completed: " + todo.Title,
-		CreatedAt:   time.Now(),
-	}
-	h.db.Create(&activity)
-
 	h.logger.Info("Todo completed", zap.String("todo_id", todoID))
 
 	// Load relations for response
@@ -487,7 +507,7 @@ func (h *TodoHandler) CompleteTodo(c *gin.Context) {
 func (h *TodoHandler) DeleteTodo(c *gin.Context) {
 	userID, exists := c.Get("user_id")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "User not authenticated")})
 		return
 	}
 
@@ -496,7 +516,7 @@ func (h *TodoHandler) DeleteTodo(c *gin.Context) {
 
 	// Only beraters and admins can delete todos
 	if userRole != "berater" && userRole != "junior_berater" && userRole != "admin" {
-		c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+		c.JSON(http.StatusForbidden, gin.H{"error": middleware.T(c, "Insufficient permissions")})
 		return
 	}
 
@@ -510,10 +530,10 @@ func (h *TodoHandler) DeleteTodo(c *gin.Context) {
 
 	if err := query.First(&todo).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Todo not found"})
+			c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "Todo not found")})
 		} else {
 			h.logger.Error("Failed to fetch todo", zap.Error(err))
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch todo"})
+			c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to fetch todo")})
 		}
 		return
 	}
@@ -521,11 +541,11 @@ func (h *TodoHandler) DeleteTodo(c *gin.Context) {
 	// Soft delete
 	if err := h.db.Delete(&todo).Error; err != nil {
 		h.logger.Error("Failed to delete todo", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete todo"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to delete todo")})
 		return
 	}
 
 	h.logger.Info("Todo deleted successfully", zap.String("todo_id", todoID))
 
-	c.JSON(http.StatusOK, gin.H{"message": "Todo deleted successfully"})
+	c.JSON(http.StatusOK, gin.H{"message": middleware.T(c, "Todo deleted successfully")})
 }
\ No newline at end of file
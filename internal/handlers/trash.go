@@ -0,0 +1,172 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"elterngeld-portal/internal/middleware"
+	"elterngeld-portal/internal/trash"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type TrashHandler struct {
+	db     *gorm.DB
+	logger *zap.Logger
+	trash  *trash.Service
+}
+
+func NewTrashHandler(db *gorm.DB, logger *zap.Logger, trashService *trash.Service) *TrashHandler {
+	return &TrashHandler{
+		db:     db,
+		logger: logger,
+		trash:  trashService,
+	}
+}
+
+// PermanentDeleteRequest confirms a permanent delete by repeating the record ID
+type PermanentDeleteRequest struct {
+	Confirm string `json:"confirm" binding:"required"`
+}
+
+// ListTrash handles listing the soft-deleted records of a type (admin only)
+// @Summary List trash
+// @Description Get the deleted leads, bookings, jobs or contact forms, most recently deleted first
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Param type path string true "Record type" Enums(leads, bookings, jobs, contact-forms)
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(20)
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/admin/{type}/trash [get]
+func (h *TrashHandler) ListTrash(recordType trash.Type) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+		limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+		if page < 1 {
+			page = 1
+		}
+		if limit < 1 || limit > 100 {
+			limit = 20
+		}
+
+		items, total, err := h.trash.List(recordType, page, limit)
+		if err != nil {
+			h.logger.Error("Failed to fetch trash", zap.String("type", string(recordType)), zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to fetch trash")})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"items": items,
+			"pagination": gin.H{
+				"page":  page,
+				"limit": limit,
+				"total": total,
+				"pages": (total + int64(limit) - 1) / int64(limit),
+			},
+		})
+	}
+}
+
+// RestoreFromTrash handles restoring a soft-deleted record (admin only)
+// @Summary Restore from trash
+// @Description Restore a deleted lead, booking, job or contact form
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Param type path string true "Record type" Enums(leads, bookings, jobs, contact-forms)
+// @Param id path string true "Record ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/admin/{type}/{id}/restore [post]
+func (h *TrashHandler) RestoreFromTrash(recordType trash.Type) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		adminID, ok := middleware.GetCurrentUserID(c)
+		if !ok {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "User not authenticated")})
+			return
+		}
+
+		id, err := uuid.Parse(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid record ID")})
+			return
+		}
+
+		if err := h.trash.Restore(recordType, id, adminID); err != nil {
+			h.handleTrashError(c, err, "Failed to restore record")
+			return
+		}
+
+		h.logger.Info("Record restored from trash",
+			zap.String("type", string(recordType)),
+			zap.String("id", id.String()),
+			zap.String("admin_id", adminID.String()))
+
+		c.JSON(http.StatusOK, gin.H{"message": middleware.T(c, "Record restored")})
+	}
+}
+
+// DeletePermanently handles permanently deleting a soft-deleted record (admin only)
+// @Summary Delete permanently
+// @Description Permanently delete a record from the trash. The request must repeat the record ID as confirmation. Leads with payments or documents and paid bookings cannot be deleted permanently.
+// @Tags admin
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param type path string true "Record type" Enums(leads, bookings, jobs, contact-forms)
+// @Param id path string true "Record ID"
+// @Param request body PermanentDeleteRequest true "Confirmation"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/v1/admin/{type}/{id}/permanent [delete]
+func (h *TrashHandler) DeletePermanently(recordType trash.Type) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := uuid.Parse(c.Param("id"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid record ID")})
+			return
+		}
+
+		var req PermanentDeleteRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid request data"), "details": err.Error()})
+			return
+		}
+
+		if err := h.trash.DeletePermanently(recordType, id, req.Confirm); err != nil {
+			h.handleTrashError(c, err, "Failed to delete record permanently")
+			return
+		}
+
+		adminID, _ := middleware.GetCurrentUserID(c)
+		h.logger.Warn("Record deleted permanently",
+			zap.String("type", string(recordType)),
+			zap.String("id", id.String()),
+			zap.String("admin_id", adminID.String()))
+
+		c.JSON(http.StatusOK, gin.H{"message": middleware.T(c, "Record deleted permanently")})
+	}
+}
+
+func (h *TrashHandler) handleTrashError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, trash.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "Record not found in trash")})
+	case errors.Is(err, trash.ErrConfirmationMismatch):
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Confirm the permanent delete with the record ID")})
+	case errors.Is(err, trash.ErrHasPayments):
+		c.JSON(http.StatusConflict, gin.H{"error": middleware.T(c, "Records with payments or documents cannot be deleted permanently")})
+	default:
+		h.logger.Error(message, zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, message)})
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"strconv"
 	"time"
 
+	"elterngeld-portal/internal/middleware"
 	"elterngeld-portal/internal/models"
 
 	"github.com/gin-gonic/gin"
@@ -31,8 +32,28 @@ type UpdateUserRequest struct {
 	FirstName string `json:"first_name,omitempty"`
 	LastName  string `json:"last_name,omitempty"`
 	Phone     string `json:"phone,omitempty"`
-	Timezone  string `json:"timezone,omitempty"`
-	Language  string `json:"language,omitempty"`
+	Timezone  string `json:"timezone,omitempty" binding:"omitempty,timezone"`
+	Language  string `json:"language,omitempty" binding:"omitempty,oneof=de en"`
+
+	// Languages the customer can hold a consultation in, preferred first
+	SpokenLanguages []string `json:"spoken_languages,omitempty" binding:"omitempty,max=5,dive,oneof=de en tr ar ru uk pl fa ro"`
+
+	// Federal state deciding which public holidays block a Berater's timeslots
+	Bundesland models.Bundesland `json:"bundesland,omitempty" binding:"omitempty,oneof=BW BY BE BB HB HH HE MV NI NW RP SL SN ST SH TH"`
+
+	// Berater profile fields used in personalized emails and on the public profile
+	EmailSignature *string `json:"email_signature,omitempty"`
+	PhotoURL       *string `json:"photo_url,omitempty" binding:"omitempty,url"`
+	CalendarURL    *string `json:"calendar_url,omitempty" binding:"omitempty,url"`
+	MeetingURL     *string `json:"meeting_url,omitempty" binding:"omitempty,url"`
+	Bio            *string `json:"bio,omitempty" binding:"omitempty,max=2000"`
+
+	// Languages the Berater holds consultations in
+	WorkingLanguages []string `json:"working_languages,omitempty" binding:"omitempty,dive,oneof=de en tr ar ru uk pl fa ro"`
+
+	// Berater booking limits: free minutes between appointments and bookings per day (0 = no limit)
+	BookingBufferMinutes *int `json:"booking_buffer_minutes,omitempty" binding:"omitempty,min=0,max=240"`
+	MaxBookingsPerDay    *int `json:"max_bookings_per_day,omitempty" binding:"omitempty,min=0,max=50"`
 }
 
 // CreateUserRequest represents the admin create user request
@@ -96,7 +117,7 @@ func (h *UserHandler) ListUsers(c *gin.Context) {
 	var users []models.User
 	if err := query.Offset(offset).Limit(limit).Order("created_at DESC").Find(&users).Error; err != nil {
 		h.logger.Error("Failed to fetch users", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch users"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to fetch users")})
 		return
 	}
 
@@ -136,10 +157,10 @@ func (h *UserHandler) GetUser(c *gin.Context) {
 	var user models.User
 	if err := h.db.First(&user, "id = ?", userID).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "User not found")})
 		} else {
 			h.logger.Error("Failed to fetch user", zap.Error(err))
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch user"})
+			c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to fetch user")})
 		}
 		return
 	}
@@ -173,17 +194,17 @@ func (h *UserHandler) UpdateUser(c *gin.Context) {
 	var user models.User
 	if err := h.db.First(&user, "id = ?", userID).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "User not found")})
 		} else {
 			h.logger.Error("Failed to fetch user", zap.Error(err))
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch user"})
+			c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to fetch user")})
 		}
 		return
 	}
 
 	var req UpdateUserRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data", "details": err.Error()})
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid request data"), "details": err.Error()})
 		return
 	}
 
@@ -204,9 +225,41 @@ func (h *UserHandler) UpdateUser(c *gin.Context) {
 	if req.Language != "" {
 		updates["language"] = req.Language
 	}
+	if req.SpokenLanguages != nil {
+		user.SetSpokenLanguages(models.CleanLanguages(req.SpokenLanguages))
+		updates["spoken_languages"] = user.SpokenLanguages
+	}
+	if req.Bundesland != "" {
+		updates["bundesland"] = req.Bundesland
+	}
+	if req.EmailSignature != nil {
+		updates["email_signature"] = *req.EmailSignature
+	}
+	if req.PhotoURL != nil {
+		updates["photo_url"] = *req.PhotoURL
+	}
+	if req.CalendarURL != nil {
+		updates["calendar_url"] = *req.CalendarURL
+	}
+	if req.MeetingURL != nil {
+		updates["meeting_url"] = *req.MeetingURL
+	}
+	if req.Bio != nil {
+		updates["bio"] = *req.Bio
+	}
+	if req.WorkingLanguages != nil {
+		user.SetWorkingLanguages(models.CleanLanguages(req.WorkingLanguages))
+		updates["working_languages"] = user.WorkingLanguages
+	}
+	if req.BookingBufferMinutes != nil {
+		updates["booking_buffer_minutes"] = *req.BookingBufferMinutes
+	}
+	if req.MaxBookingsPerDay != nil {
+		updates["max_bookings_per_day"] = *req.MaxBookingsPerDay
+	}
 
 	if len(updates) == 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "No valid fields to update"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "No valid fields to update")})
 		return
 	}
 
@@ -214,13 +267,13 @@ func (h *UserHandler) UpdateUser(c *gin.Context) {
 
 	if err := h.db.Model(&user).Updates(updates).Error; err != nil {
 		h.logger.Error("Failed to update user", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to update user")})
 		return
 	}
 
 	// Fetch updated user
 	if err := h.db.First(&user, "id = ?", userID).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch updated user"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to fetch updated user")})
 		return
 	}
 
@@ -250,10 +303,10 @@ func (h *UserHandler) DeleteUser(c *gin.Context) {
 	var user models.User
 	if err := h.db.First(&user, "id = ?", userID).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "User not found")})
 		} else {
 			h.logger.Error("Failed to fetch user", zap.Error(err))
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch user"})
+			c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to fetch user")})
 		}
 		return
 	}
@@ -261,13 +314,13 @@ func (h *UserHandler) DeleteUser(c *gin.Context) {
 	// Soft delete the user
 	if err := h.db.Delete(&user).Error; err != nil {
 		h.logger.Error("Failed to delete user", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete user"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to delete user")})
 		return
 	}
 
 	h.logger.Info("User deleted successfully", zap.String("user_id", userID))
 
-	c.JSON(http.StatusOK, gin.H{"message": "User deleted successfully"})
+	c.JSON(http.StatusOK, gin.H{"message": middleware.T(c, "User deleted successfully")})
 }
 
 // AdminCreateUser handles creating a new user (Admin only)
@@ -288,14 +341,14 @@ func (h *UserHandler) AdminCreateUser(c *gin.Context) {
 	var req CreateUserRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		h.logger.Error("Invalid create user request", zap.Error(err))
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data", "details": err.Error()})
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid request data"), "details": err.Error()})
 		return
 	}
 
 	// Check if user already exists
 	var existingUser models.User
 	if err := h.db.Where("email = ?", req.Email).First(&existingUser).Error; err == nil {
-		c.JSON(http.StatusConflict, gin.H{"error": "User with this email already exists"})
+		c.JSON(http.StatusConflict, gin.H{"error": middleware.T(c, "User with this email already exists")})
 		return
 	}
 
@@ -303,7 +356,7 @@ func (h *UserHandler) AdminCreateUser(c *gin.Context) {
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
 	if err != nil {
 		h.logger.Error("Failed to hash password", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process password"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to process password")})
 		return
 	}
 
@@ -335,7 +388,7 @@ func (h *UserHandler) AdminCreateUser(c *gin.Context) {
 
 	if err := h.db.Create(&user).Error; err != nil {
 		h.logger.Error("Failed to create user", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to create user")})
 		return
 	}
 
@@ -370,30 +423,30 @@ func (h *UserHandler) AdminChangeUserRole(c *gin.Context) {
 	var user models.User
 	if err := h.db.First(&user, "id = ?", userID).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "User not found")})
 		} else {
 			h.logger.Error("Failed to fetch user", zap.Error(err))
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch user"})
+			c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to fetch user")})
 		}
 		return
 	}
 
 	var req map[string]interface{}
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid request data")})
 		return
 	}
 
 	newRole, exists := req["role"]
 	if !exists {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Role is required"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Role is required")})
 		return
 	}
 
 	// Validate role
 	roleStr, ok := newRole.(string)
 	if !ok {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid role format"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid role format")})
 		return
 	}
 
@@ -408,7 +461,7 @@ func (h *UserHandler) AdminChangeUserRole(c *gin.Context) {
 	}
 
 	if !isValid {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid role"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid role")})
 		return
 	}
 
@@ -418,7 +471,7 @@ func (h *UserHandler) AdminChangeUserRole(c *gin.Context) {
 
 	if err := h.db.Save(&user).Error; err != nil {
 		h.logger.Error("Failed to update user role", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user role"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to update user role")})
 		return
 	}
 
@@ -456,30 +509,30 @@ func (h *UserHandler) AdminChangeUserStatus(c *gin.Context) {
 	var user models.User
 	if err := h.db.First(&user, "id = ?", userID).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
-			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "User not found")})
 		} else {
 			h.logger.Error("Failed to fetch user", zap.Error(err))
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch user"})
+			c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to fetch user")})
 		}
 		return
 	}
 
 	var req map[string]interface{}
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid request data")})
 		return
 	}
 
 	newStatus, exists := req["status"]
 	if !exists {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Status is required"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Status is required")})
 		return
 	}
 
 	// Validate status
 	statusStr, ok := newStatus.(string)
 	if !ok {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status format"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid status format")})
 		return
 	}
 
@@ -494,7 +547,7 @@ func (h *UserHandler) AdminChangeUserStatus(c *gin.Context) {
 	}
 
 	if !isValid {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid status")})
 		return
 	}
 
@@ -510,7 +563,7 @@ func (h *UserHandler) AdminChangeUserStatus(c *gin.Context) {
 
 	if err := h.db.Save(&user).Error; err != nil {
 		h.logger.Error("Failed to update user status", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user status"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to update user status")})
 		return
 	}
 
@@ -0,0 +1,191 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"elterngeld-portal/internal/middleware"
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/internal/webhooks"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// maxWebhookPayloadSize limits the body of inbound webhooks
+const maxWebhookPayloadSize = 1 << 20
+
+type WebhookHandler struct {
+	db       *gorm.DB
+	logger   *zap.Logger
+	receiver *webhooks.Receiver
+}
+
+func NewWebhookHandler(db *gorm.DB, logger *zap.Logger, receiver *webhooks.Receiver) *WebhookHandler {
+	return &WebhookHandler{
+		db:       db,
+		logger:   logger,
+		receiver: receiver,
+	}
+}
+
+// Receive returns the endpoint for a registered webhook provider. The signature
+// is verified and the raw payload stored before the event is processed asynchronously.
+// @Summary Receive webhook
+// @Description Verify and store a webhook of a third-party integration, e.g. Stripe or the newsletter provider
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 413 {object} map[string]interface{}
+// @Router /api/v1/webhooks/stripe [post]
+// @Router /api/v1/webhooks/mailchimp [post]
+// @Router /api/v1/webhooks/brevo [post]
+func (h *WebhookHandler) Receive(provider string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		payload, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxWebhookPayloadSize))
+		if err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": middleware.T(c, "Request body too large")})
+				return
+			}
+			h.logger.Error("Failed to read webhook body", zap.String("provider", provider), zap.Error(err))
+			c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Failed to read request body")})
+			return
+		}
+
+		// Providers that only take a URL, e.g. Mailchimp, send their secret as query parameter
+		header := c.Request.Header
+		if token := c.Query("token"); token != "" {
+			header = header.Clone()
+			header.Set(webhooks.TokenHeader, token)
+		}
+
+		event, err := h.receiver.Receive(provider, header, payload)
+		if err != nil {
+			switch {
+			case errors.Is(err, webhooks.ErrInvalidSignature):
+				h.logger.Warn("Webhook signature verification failed",
+					zap.String("provider", provider),
+					zap.String("client_ip", c.ClientIP()),
+					zap.Error(err))
+				c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "Invalid signature")})
+			case errors.Is(err, webhooks.ErrInvalidPayload):
+				c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid request body")})
+			default:
+				// Providers retry deliveries that fail with a server error
+				h.logger.Error("Failed to receive webhook", zap.String("provider", provider), zap.Error(err))
+				c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to receive webhook")})
+			}
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"received": true, "id": event.ID})
+	}
+}
+
+// VerifyEndpoint answers the GET request some providers, e.g. Mailchimp, send to
+// check that a webhook URL exists before they save it
+func (h *WebhookHandler) VerifyEndpoint(c *gin.Context) {
+	c.Status(http.StatusOK)
+}
+
+// ListWebhookEvents handles listing received webhook events (admin only)
+// @Summary List webhook events
+// @Description Get received webhook events, e.g. failed events that need a replay
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Param page query int false "Page number"
+// @Param limit query int false "Items per page"
+// @Param provider query string false "Filter by provider"
+// @Param status query string false "Filter by status (pending, processing, processed, failed)"
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/admin/webhooks [get]
+func (h *WebhookHandler) ListWebhookEvents(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+	offset := (page - 1) * limit
+
+	query := h.db.Model(&models.WebhookEvent{})
+	if provider := c.Query("provider"); provider != "" {
+		query = query.Where("provider = ?", provider)
+	}
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var events []models.WebhookEvent
+	if err := query.Offset(offset).Limit(limit).Order("received_at DESC").Find(&events).Error; err != nil {
+		h.logger.Error("Failed to fetch webhook events", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to fetch webhook events")})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"webhook_events": events,
+		"pagination": gin.H{
+			"page":  page,
+			"limit": limit,
+			"total": total,
+			"pages": (total + int64(limit) - 1) / int64(limit),
+		},
+	})
+}
+
+// ReplayWebhookEvent handles processing a stored webhook event again (admin only)
+// @Summary Replay webhook event
+// @Description Process a stored webhook event again, e.g. after a failed attempt
+// @Tags admin
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Webhook event ID"
+// @Success 202 {object} models.WebhookEvent
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Router /api/v1/admin/webhooks/{id}/replay [post]
+func (h *WebhookHandler) ReplayWebhookEvent(c *gin.Context) {
+	eventID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid webhook event ID")})
+		return
+	}
+
+	event, err := h.receiver.Replay(eventID)
+	if err != nil {
+		switch {
+		case errors.Is(err, webhooks.ErrEventNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "Webhook event not found")})
+		case errors.Is(err, webhooks.ErrEventInProgress):
+			c.JSON(http.StatusConflict, gin.H{"error": middleware.T(c, "Webhook event is being processed")})
+		default:
+			h.logger.Error("Failed to replay webhook event", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, "Failed to replay webhook event")})
+		}
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	h.logger.Info("Webhook event replayed",
+		zap.String("webhook_event_id", event.ID.String()),
+		zap.Any("replayed_by", userID))
+
+	c.JSON(http.StatusAccepted, event)
+}
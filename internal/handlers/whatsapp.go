@@ -0,0 +1,192 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"elterngeld-portal/internal/middleware"
+	"elterngeld-portal/internal/scopes"
+	"elterngeld-portal/internal/whatsapp"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type WhatsAppHandler struct {
+	db       *gorm.DB
+	logger   *zap.Logger
+	whatsapp *whatsapp.Service
+}
+
+func NewWhatsAppHandler(db *gorm.DB, logger *zap.Logger, whatsappService *whatsapp.Service) *WhatsAppHandler {
+	return &WhatsAppHandler{
+		db:       db,
+		logger:   logger,
+		whatsapp: whatsappService,
+	}
+}
+
+// WhatsAppMessageRequest represents a WhatsApp message to the customer of a lead
+type WhatsAppMessageRequest struct {
+	Body string `json:"body" binding:"required,max=4096"`
+}
+
+// GetConsent handles getting the WhatsApp consent of the current user
+// @Summary Get WhatsApp consent
+// @Description Get whether the current user agreed to booking reminders and messages on WhatsApp, the number and the opt-in wording
+// @Tags whatsapp
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Router /api/v1/whatsapp/consent [get]
+func (h *WhatsAppHandler) GetConsent(c *gin.Context) {
+	userID, ok := middleware.GetCurrentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "User not authenticated")})
+		return
+	}
+
+	consent, err := h.whatsapp.Consent(userID)
+	if err != nil {
+		h.handleWhatsAppError(c, err, "Failed to fetch WhatsApp consent")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"consent":      consent,
+		"consent_text": whatsapp.ConsentText,
+		"available":    h.whatsapp.Enabled(),
+	})
+}
+
+// UpdateConsent handles opting the current user in or out of WhatsApp messages
+// @Summary Update WhatsApp consent
+// @Description Opt in to booking reminders and messages on WhatsApp with a mobile number, or withdraw the consent
+// @Tags whatsapp
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body whatsapp.ConsentInput true "Consent"
+// @Success 200 {object} models.WhatsAppConsent
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/v1/whatsapp/consent [put]
+func (h *WhatsAppHandler) UpdateConsent(c *gin.Context) {
+	userID, ok := middleware.GetCurrentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "User not authenticated")})
+		return
+	}
+
+	var req whatsapp.ConsentInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid request data"), "details": err.Error()})
+		return
+	}
+
+	consent, err := h.whatsapp.UpdateConsent(userID, req)
+	if err != nil {
+		h.handleWhatsAppError(c, err, "Failed to update WhatsApp consent")
+		return
+	}
+
+	c.JSON(http.StatusOK, consent)
+}
+
+// ListLeadMessages handles listing the WhatsApp conversation with the customer of a lead
+// @Summary List lead WhatsApp messages
+// @Description Get the WhatsApp messages exchanged with the customer of a lead, including booking reminders, oldest first
+// @Tags leads
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Lead ID"
+// @Success 200 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /api/v1/leads/{id}/whatsapp [get]
+func (h *WhatsAppHandler) ListLeadMessages(c *gin.Context) {
+	viewer, leadID, ok := h.leadRequest(c)
+	if !ok {
+		return
+	}
+
+	messages, err := h.whatsapp.Messages(leadID, viewer)
+	if err != nil {
+		h.handleWhatsAppError(c, err, "Failed to fetch WhatsApp messages")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"messages": messages})
+}
+
+// SendLeadMessage handles messaging the customer of a lead on WhatsApp (Berater and admins)
+// @Summary Send lead WhatsApp message
+// @Description Send a WhatsApp message to the customer of a lead. The customer must have opted in and written within the last 24 hours.
+// @Tags leads
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Lead ID"
+// @Param request body WhatsAppMessageRequest true "Message"
+// @Success 201 {object} models.WhatsAppMessage
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Failure 503 {object} map[string]interface{}
+// @Router /api/v1/leads/{id}/whatsapp [post]
+func (h *WhatsAppHandler) SendLeadMessage(c *gin.Context) {
+	viewer, leadID, ok := h.leadRequest(c)
+	if !ok {
+		return
+	}
+
+	var req WhatsAppMessageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid request data"), "details": err.Error()})
+		return
+	}
+
+	message, err := h.whatsapp.Send(c.Request.Context(), leadID, viewer, req.Body)
+	if err != nil {
+		h.handleWhatsAppError(c, err, "Failed to send WhatsApp message")
+		return
+	}
+
+	c.JSON(http.StatusCreated, message)
+}
+
+func (h *WhatsAppHandler) leadRequest(c *gin.Context) (scopes.Viewer, uuid.UUID, bool) {
+	viewer, ok := scopes.FromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": middleware.T(c, "User not authenticated")})
+		return scopes.Viewer{}, uuid.Nil, false
+	}
+
+	leadID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid lead ID")})
+		return scopes.Viewer{}, uuid.Nil, false
+	}
+
+	return viewer, leadID, true
+}
+
+func (h *WhatsAppHandler) handleWhatsAppError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, whatsapp.ErrLeadNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": middleware.T(c, "Lead not found")})
+	case errors.Is(err, whatsapp.ErrInvalidPhone):
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Invalid mobile number")})
+	case errors.Is(err, whatsapp.ErrEmptyMessage):
+		c.JSON(http.StatusBadRequest, gin.H{"error": middleware.T(c, "Message is required")})
+	case errors.Is(err, whatsapp.ErrNoConsent):
+		c.JSON(http.StatusConflict, gin.H{"error": middleware.T(c, "The customer has not opted in to WhatsApp messages")})
+	case errors.Is(err, whatsapp.ErrSessionExpired):
+		c.JSON(http.StatusConflict, gin.H{"error": middleware.T(c, "WhatsApp messages can only be sent within 24 hours of the customer's last message")})
+	case errors.Is(err, whatsapp.ErrNotConfigured):
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": middleware.T(c, "WhatsApp messaging is not configured")})
+	default:
+		h.logger.Error(message, zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": middleware.T(c, message)})
+	}
+}
@@ -0,0 +1,256 @@
+package holds
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"elterngeld-portal/config"
+	"elterngeld-portal/internal/bookingstate"
+	"elterngeld-portal/internal/models"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrTimeslotFull is returned when all places of a timeslot are booked or held
+	ErrTimeslotFull = errors.New("timeslot is fully booked")
+	// ErrHoldExpired is returned when checkout continues after the hold of a booking ended
+	ErrHoldExpired = errors.New("timeslot hold expired")
+)
+
+// Occupancy is how many places of a timeslot are taken
+type Occupancy struct {
+	Booked    int        `json:"booked"`
+	Held      int        `json:"held"`
+	HeldUntil *time.Time `json:"held_until,omitempty"` // when the last active hold ends
+}
+
+// Taken returns the number of places that cannot be booked
+func (o Occupancy) Taken() int {
+	return o.Booked + o.Held
+}
+
+// Service reserves timeslot places for pending bookings while the customer
+// pays. A booking with a timeslot only takes its place through the hold
+// until it is paid; unpaid bookings are cancelled when their hold expires.
+type Service struct {
+	db     *gorm.DB
+	logger *zap.Logger
+	states *bookingstate.Service
+	ttl    time.Duration
+	now    func() time.Time
+}
+
+func NewService(db *gorm.DB, logger *zap.Logger, cfg *config.Config, bookingStates *bookingstate.Service) *Service {
+	return &Service{
+		db:     db,
+		logger: logger,
+		states: bookingStates,
+		ttl:    cfg.Booking.HoldTTL,
+		now:    time.Now,
+	}
+}
+
+// Occupancy returns the booked and held places per timeslot. Pass a
+// transaction as db to read consistently with a booking in progress.
+func (s *Service) Occupancy(db *gorm.DB, timeslotIDs []uuid.UUID) (map[uuid.UUID]Occupancy, error) {
+	return s.occupancy(db, timeslotIDs, uuid.Nil)
+}
+
+// occupancy counts the taken places, leaving out the given booking
+func (s *Service) occupancy(db *gorm.DB, timeslotIDs []uuid.UUID, excludeBookingID uuid.UUID) (map[uuid.UUID]Occupancy, error) {
+	occupancy := make(map[uuid.UUID]Occupancy, len(timeslotIDs))
+	if len(timeslotIDs) == 0 {
+		return occupancy, nil
+	}
+
+	// Pending bookings with a hold are counted through the hold only, so they
+	// free their place as soon as the hold ends
+	var booked []struct {
+		TimeslotID uuid.UUID
+		Count      int
+	}
+	err := db.Model(&models.Booking{}).
+		Select("timeslot_id, COUNT(*) AS count").
+		Where("timeslot_id IN ? AND status NOT IN ?", timeslotIDs,
+			[]models.BookingStatus{models.BookingStatusCancelled, models.BookingStatusCompleted}).
+		Where("NOT (status = ? AND EXISTS (SELECT 1 FROM timeslot_holds WHERE timeslot_holds.booking_id = bookings.id))",
+			models.BookingStatusPending).
+		Where("id <> ?", excludeBookingID).
+		Group("timeslot_id").
+		Scan(&booked).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to count bookings: %w", err)
+	}
+	for _, row := range booked {
+		entry := occupancy[row.TimeslotID]
+		entry.Booked = row.Count
+		occupancy[row.TimeslotID] = entry
+	}
+
+	var holds []models.TimeslotHold
+	err = db.Joins("JOIN bookings ON bookings.id = timeslot_holds.booking_id").
+		Where("timeslot_holds.timeslot_id IN ? AND timeslot_holds.released_at IS NULL AND timeslot_holds.expires_at > ?",
+			timeslotIDs, s.now()).
+		Where("bookings.status = ?", models.BookingStatusPending).
+		Find(&holds).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to load holds: %w", err)
+	}
+	for _, hold := range holds {
+		entry := occupancy[hold.TimeslotID]
+		entry.Held++
+		if entry.HeldUntil == nil || hold.ExpiresAt.After(*entry.HeldUntil) {
+			expiresAt := hold.ExpiresAt
+			entry.HeldUntil = &expiresAt
+		}
+		occupancy[hold.TimeslotID] = entry
+	}
+
+	return occupancy, nil
+}
+
+// Place reserves a place on the timeslot for a new pending booking. It must
+// run in the transaction creating the booking; the timeslot row is locked so
+// concurrent checkouts cannot both take the last place.
+func (s *Service) Place(tx *gorm.DB, booking *models.Booking, timeslot *models.Timeslot) (*models.TimeslotHold, error) {
+	var locked models.Timeslot
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&locked, "id = ?", timeslot.ID).Error; err != nil {
+		return nil, fmt.Errorf("failed to lock timeslot: %w", err)
+	}
+
+	occupancy, err := s.occupancy(tx, []uuid.UUID{timeslot.ID}, booking.ID)
+	if err != nil {
+		return nil, err
+	}
+	if occupancy[timeslot.ID].Taken() >= timeslot.MaxBookings {
+		return nil, ErrTimeslotFull
+	}
+
+	hold := &models.TimeslotHold{
+		TimeslotID: timeslot.ID,
+		BookingID:  booking.ID,
+		UserID:     booking.UserID,
+		ExpiresAt:  s.now().Add(s.ttl),
+	}
+	if err := tx.Create(hold).Error; err != nil {
+		return nil, fmt.Errorf("failed to create hold: %w", err)
+	}
+	return hold, nil
+}
+
+// Extend renews the hold of a booking when its checkout starts, so the
+// customer has the full hold period to pay. It returns nil for bookings
+// without a hold and ErrHoldExpired once the hold has ended.
+func (s *Service) Extend(bookingID uuid.UUID) (*models.TimeslotHold, error) {
+	var hold models.TimeslotHold
+	if err := s.db.Where("booking_id = ?", bookingID).First(&hold).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to load hold: %w", err)
+	}
+
+	now := s.now()
+	if !hold.IsActive(now) {
+		return nil, ErrHoldExpired
+	}
+
+	// The hold may expire or be released between loading and extending it
+	expiresAt := now.Add(s.ttl)
+	result := s.db.Model(&models.TimeslotHold{}).
+		Where("id = ? AND expires_at > ? AND released_at IS NULL", hold.ID, now).
+		Update("expires_at", expiresAt)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to extend hold: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrHoldExpired
+	}
+	hold.ExpiresAt = expiresAt
+	return &hold, nil
+}
+
+// Release ends the hold of a booking, e.g. once it is paid. Bookings without
+// an active hold are left alone; ErrHoldExpired is returned if the hold had
+// already expired so the caller can flag a late payment.
+func (s *Service) Release(bookingID uuid.UUID, reason models.HoldReleaseReason) error {
+	var hold models.TimeslotHold
+	if err := s.db.Where("booking_id = ?", bookingID).First(&hold).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return fmt.Errorf("failed to load hold: %w", err)
+	}
+	if hold.ReleasedAt != nil {
+		if hold.ReleaseReason == models.HoldReleaseExpired && reason != models.HoldReleaseExpired {
+			return ErrHoldExpired
+		}
+		return nil
+	}
+
+	err := s.db.Model(&hold).Updates(map[string]interface{}{
+		"released_at":    s.now(),
+		"release_reason": reason,
+	}).Error
+	if err != nil {
+		return fmt.Errorf("failed to release hold: %w", err)
+	}
+	return nil
+}
+
+// ExpireHolds releases the holds that ran out and cancels their bookings if
+// they are still unpaid. It returns the number of cancelled bookings.
+func (s *Service) ExpireHolds() (int, error) {
+	now := s.now()
+
+	var holds []models.TimeslotHold
+	if err := s.db.Where("released_at IS NULL AND expires_at <= ?", now).Find(&holds).Error; err != nil {
+		return 0, fmt.Errorf("failed to find expired holds: %w", err)
+	}
+
+	cancelled := 0
+	for _, hold := range holds {
+		err := s.db.Transaction(func(tx *gorm.DB) error {
+			// A checkout extended in the meantime keeps its hold
+			result := tx.Model(&models.TimeslotHold{}).
+				Where("id = ? AND released_at IS NULL AND expires_at <= ?", hold.ID, now).
+				Updates(map[string]interface{}{"released_at": now, "release_reason": models.HoldReleaseExpired})
+			if result.Error != nil || result.RowsAffected == 0 {
+				return result.Error
+			}
+
+			var booking models.Booking
+			if err := tx.First(&booking, "id = ?", hold.BookingID).Error; err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return nil
+				}
+				return err
+			}
+			if booking.Status != models.BookingStatusPending {
+				return nil
+			}
+			err := s.states.Transition(tx, &booking, models.BookingStatusCancelled, bookingstate.Change{
+				Note: "Timeslot hold expired before payment",
+				At:   now,
+			})
+			if err != nil {
+				return err
+			}
+			cancelled++
+			return nil
+		})
+		if err != nil {
+			return cancelled, fmt.Errorf("failed to expire hold %s: %w", hold.ID, err)
+		}
+	}
+
+	if cancelled > 0 {
+		s.logger.Info("Cancelled unpaid bookings after their timeslot hold expired", zap.Int("count", cancelled))
+	}
+	return cancelled, nil
+}
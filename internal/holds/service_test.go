@@ -0,0 +1,183 @@
+package holds
+
+import (
+	"testing"
+	"time"
+
+	"elterngeld-portal/config"
+	"elterngeld-portal/internal/bookingstate"
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/tests/testutils"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+func TestPlace(t *testing.T) {
+	db, service, _ := setupTestService(t)
+	customer := testutils.CreateTestUser(t, db, models.RoleUser)
+	timeslot := createTimeslot(t, db, 2)
+
+	// One paid booking and one hold fill the slot
+	createBooking(t, db, customer, timeslot, models.BookingStatusConfirmed)
+	first := createBooking(t, db, customer, timeslot, models.BookingStatusPending)
+	hold, err := service.Place(db, first, timeslot)
+	require.NoError(t, err)
+	assert.Equal(t, first.ID, hold.BookingID)
+	assert.Equal(t, service.now().Add(30*time.Minute), hold.ExpiresAt)
+
+	second := createBooking(t, db, customer, timeslot, models.BookingStatusPending)
+	_, err = service.Place(db, second, timeslot)
+	assert.ErrorIs(t, err, ErrTimeslotFull)
+
+	occupancy, err := service.Occupancy(db, []uuid.UUID{timeslot.ID})
+	require.NoError(t, err)
+	// The second pending booking has no hold and counts as booked
+	assert.Equal(t, 2, occupancy[timeslot.ID].Booked)
+	assert.Equal(t, 1, occupancy[timeslot.ID].Held)
+	require.NotNil(t, occupancy[timeslot.ID].HeldUntil)
+	assert.True(t, hold.ExpiresAt.Equal(*occupancy[timeslot.ID].HeldUntil))
+}
+
+func TestHoldLifecycle(t *testing.T) {
+	db, service, now := setupTestService(t)
+	customer := testutils.CreateTestUser(t, db, models.RoleUser)
+	timeslot := createTimeslot(t, db, 1)
+
+	paid := createBooking(t, db, customer, timeslot, models.BookingStatusPending)
+	_, err := service.Place(db, paid, timeslot)
+	require.NoError(t, err)
+
+	t.Run("extend on checkout", func(t *testing.T) {
+		*now = now.Add(20 * time.Minute)
+		hold, err := service.Extend(paid.ID)
+		require.NoError(t, err)
+		assert.Equal(t, now.Add(30*time.Minute), hold.ExpiresAt)
+
+		withoutHold, err := service.Extend(uuid.New())
+		require.NoError(t, err)
+		assert.Nil(t, withoutHold)
+	})
+
+	t.Run("release on payment", func(t *testing.T) {
+		require.NoError(t, service.Release(paid.ID, models.HoldReleasePaid))
+		require.NoError(t, db.Model(paid).Update("status", models.BookingStatusConfirmed).Error)
+
+		occupancy, err := service.Occupancy(db, []uuid.UUID{timeslot.ID})
+		require.NoError(t, err)
+		assert.Equal(t, Occupancy{Booked: 1}, occupancy[timeslot.ID])
+
+		var hold models.TimeslotHold
+		require.NoError(t, db.First(&hold, "booking_id = ?", paid.ID).Error)
+		require.NotNil(t, hold.ReleasedAt)
+		assert.Equal(t, models.HoldReleasePaid, hold.ReleaseReason)
+	})
+
+	t.Run("expire unpaid booking", func(t *testing.T) {
+		other := createTimeslot(t, db, 1)
+		unpaid := createBooking(t, db, customer, other, models.BookingStatusPending)
+		_, err := service.Place(db, unpaid, other)
+		require.NoError(t, err)
+
+		// The place is free as soon as the hold ends, before the job runs
+		*now = now.Add(31 * time.Minute)
+		occupancy, err := service.Occupancy(db, []uuid.UUID{other.ID})
+		require.NoError(t, err)
+		assert.Equal(t, 0, occupancy[other.ID].Taken())
+
+		_, err = service.Extend(unpaid.ID)
+		assert.ErrorIs(t, err, ErrHoldExpired)
+
+		cancelled, err := service.ExpireHolds()
+		require.NoError(t, err)
+		assert.Equal(t, 1, cancelled)
+
+		var booking models.Booking
+		require.NoError(t, db.First(&booking, "id = ?", unpaid.ID).Error)
+		assert.Equal(t, models.BookingStatusCancelled, booking.Status)
+		assert.NotNil(t, booking.CancelledAt)
+
+		// A payment arriving after the expiry is reported
+		assert.ErrorIs(t, service.Release(unpaid.ID, models.HoldReleasePaid), ErrHoldExpired)
+
+		cancelled, err = service.ExpireHolds()
+		require.NoError(t, err)
+		assert.Equal(t, 0, cancelled)
+	})
+}
+
+func TestExtend_ReleasedConcurrently(t *testing.T) {
+	db, service, _ := setupTestService(t)
+	customer := testutils.CreateTestUser(t, db, models.RoleUser)
+	timeslot := createTimeslot(t, db, 1)
+	booking := createBooking(t, db, customer, timeslot, models.BookingStatusPending)
+	_, err := service.Place(db, booking, timeslot)
+	require.NoError(t, err)
+
+	// The payment webhook releases the hold right after Extend loaded it
+	releasedAt := service.now()
+	require.NoError(t, db.Callback().Query().After("gorm:query").Register("test:release_hold", func(tx *gorm.DB) {
+		if tx.Statement.Table == "timeslot_holds" {
+			tx.Session(&gorm.Session{NewDB: true}).Exec("UPDATE timeslot_holds SET released_at = ? WHERE booking_id = ?", releasedAt, booking.ID)
+		}
+	}))
+
+	_, err = service.Extend(booking.ID)
+	assert.ErrorIs(t, err, ErrHoldExpired)
+	require.NoError(t, db.Callback().Query().Remove("test:release_hold"))
+
+	var hold models.TimeslotHold
+	require.NoError(t, db.First(&hold, "booking_id = ?", booking.ID).Error)
+	assert.Equal(t, service.now().Add(30*time.Minute), hold.ExpiresAt)
+}
+
+func createTimeslot(t *testing.T, db *gorm.DB, maxBookings int) *models.Timeslot {
+	t.Helper()
+	start := time.Date(2026, 3, 12, 9, 0, 0, 0, time.UTC)
+	timeslot := &models.Timeslot{
+		BeraterID:   uuid.New(),
+		Date:        start,
+		StartTime:   start,
+		EndTime:     start.Add(time.Hour),
+		Duration:    60,
+		IsAvailable: true,
+		MaxBookings: maxBookings,
+	}
+	require.NoError(t, db.Create(timeslot).Error)
+	return timeslot
+}
+
+func createBooking(t *testing.T, db *gorm.DB, customer *models.User, timeslot *models.Timeslot, status models.BookingStatus) *models.Booking {
+	t.Helper()
+	booking := &models.Booking{
+		UserID:      customer.ID,
+		TimeslotID:  &timeslot.ID,
+		Title:       "Erstberatung",
+		Status:      status,
+		ScheduledAt: timeslot.StartTime,
+		StartTime:   timeslot.StartTime,
+		EndTime:     timeslot.EndTime,
+	}
+	require.NoError(t, db.Create(booking).Error)
+	return booking
+}
+
+func setupTestService(t *testing.T) (*gorm.DB, *Service, *time.Time) {
+	t.Helper()
+	db := testutils.NewSQLiteDB(t,
+		&models.User{},
+		&models.Timeslot{},
+		&models.Booking{},
+		&models.TimeslotHold{},
+	)
+
+	service := NewService(db, zap.NewNop(), &config.Config{
+		Booking: config.BookingConfig{HoldTTL: 30 * time.Minute},
+	}, bookingstate.NewService(db, zap.NewNop()))
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	service.now = func() time.Time { return now }
+	return db, service, &now
+}
@@ -0,0 +1,136 @@
+package holidays
+
+import (
+	"sort"
+	"time"
+
+	"elterngeld-portal/internal/models"
+)
+
+// DateLayout is the layout of holiday calendar dates
+const DateLayout = "2006-01-02"
+
+// Holiday is a public holiday on a calendar date
+type Holiday struct {
+	Date     string `json:"date"`
+	Name     string `json:"name"`
+	Override bool   `json:"override"` // true if the holiday was added by an admin
+}
+
+// rule describes a statutory public holiday
+type rule struct {
+	name     string
+	date     func(year int) time.Time
+	states   []models.Bundesland // nil means nationwide
+	fromYear int                 // first year the holiday applies, 0 for always
+}
+
+func fixed(month time.Month, day int) func(int) time.Time {
+	return func(year int) time.Time {
+		return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	}
+}
+
+func easterOffset(days int) func(int) time.Time {
+	return func(year int) time.Time {
+		return Easter(year).AddDate(0, 0, days)
+	}
+}
+
+// repentanceDay returns Buß- und Bettag, the Wednesday before November 23
+func repentanceDay(year int) time.Time {
+	date := time.Date(year, time.November, 22, 0, 0, 0, 0, time.UTC)
+	for date.Weekday() != time.Wednesday {
+		date = date.AddDate(0, 0, -1)
+	}
+	return date
+}
+
+var rules = []rule{
+	{name: "Neujahr", date: fixed(time.January, 1)},
+	{name: "Heilige Drei Könige", date: fixed(time.January, 6), states: []models.Bundesland{
+		models.BundeslandBadenWuerttemberg, models.BundeslandBayern, models.BundeslandSachsenAnhalt,
+	}},
+	{name: "Internationaler Frauentag", date: fixed(time.March, 8), states: []models.Bundesland{models.BundeslandBerlin}, fromYear: 2019},
+	{name: "Internationaler Frauentag", date: fixed(time.March, 8), states: []models.Bundesland{models.BundeslandMecklenburgVorpommern}, fromYear: 2023},
+	{name: "Karfreitag", date: easterOffset(-2)},
+	{name: "Ostersonntag", date: easterOffset(0), states: []models.Bundesland{models.BundeslandBrandenburg}},
+	{name: "Ostermontag", date: easterOffset(1)},
+	{name: "Tag der Arbeit", date: fixed(time.May, 1)},
+	{name: "Christi Himmelfahrt", date: easterOffset(39)},
+	{name: "Pfingstsonntag", date: easterOffset(49), states: []models.Bundesland{models.BundeslandBrandenburg}},
+	{name: "Pfingstmontag", date: easterOffset(50)},
+	{name: "Fronleichnam", date: easterOffset(60), states: []models.Bundesland{
+		models.BundeslandBadenWuerttemberg, models.BundeslandBayern, models.BundeslandHessen,
+		models.BundeslandNordrheinWestfalen, models.BundeslandRheinlandPfalz, models.BundeslandSaarland,
+	}},
+	{name: "Mariä Himmelfahrt", date: fixed(time.August, 15), states: []models.Bundesland{models.BundeslandSaarland}},
+	{name: "Weltkindertag", date: fixed(time.September, 20), states: []models.Bundesland{models.BundeslandThueringen}, fromYear: 2019},
+	{name: "Tag der Deutschen Einheit", date: fixed(time.October, 3)},
+	{name: "Reformationstag", date: fixed(time.October, 31), states: []models.Bundesland{
+		models.BundeslandBrandenburg, models.BundeslandMecklenburgVorpommern, models.BundeslandSachsen,
+		models.BundeslandSachsenAnhalt, models.BundeslandThueringen,
+	}},
+	{name: "Reformationstag", date: fixed(time.October, 31), states: []models.Bundesland{
+		models.BundeslandBremen, models.BundeslandHamburg, models.BundeslandNiedersachsen, models.BundeslandSchleswigHolstein,
+	}, fromYear: 2018},
+	{name: "Allerheiligen", date: fixed(time.November, 1), states: []models.Bundesland{
+		models.BundeslandBadenWuerttemberg, models.BundeslandBayern, models.BundeslandNordrheinWestfalen,
+		models.BundeslandRheinlandPfalz, models.BundeslandSaarland,
+	}},
+	{name: "Buß- und Bettag", date: repentanceDay, states: []models.Bundesland{models.BundeslandSachsen}},
+	{name: "1. Weihnachtstag", date: fixed(time.December, 25)},
+	{name: "2. Weihnachtstag", date: fixed(time.December, 26)},
+}
+
+// Easter returns Easter Sunday of the given year (Gregorian calendar)
+func Easter(year int) time.Time {
+	a := year % 19
+	b := year / 100
+	c := year % 100
+	d := b / 4
+	e := b % 4
+	f := (b + 8) / 25
+	g := (b - f + 1) / 3
+	h := (19*a + b - d - g + 15) % 30
+	i := c / 4
+	k := c % 4
+	l := (32 + 2*e + 2*i - h - k) % 7
+	m := (a + 11*h + 22*l) / 451
+	month := (h + l - 7*m + 114) / 31
+	day := (h+l-7*m+114)%31 + 1
+	return time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+}
+
+// Statutory returns the statutory public holidays of a year in a Bundesland,
+// sorted by date. Without a Bundesland only nationwide holidays are returned.
+func Statutory(year int, state models.Bundesland) []Holiday {
+	var result []Holiday
+	for _, r := range rules {
+		if r.fromYear > year || !r.appliesTo(state) {
+			continue
+		}
+		result = append(result, Holiday{
+			Date: r.date(year).Format(DateLayout),
+			Name: r.name,
+		})
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Date < result[j].Date
+	})
+
+	return result
+}
+
+func (r rule) appliesTo(state models.Bundesland) bool {
+	if r.states == nil {
+		return true
+	}
+	for _, s := range r.states {
+		if s == state {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,82 @@
+package holidays
+
+import (
+	"testing"
+	"time"
+
+	"elterngeld-portal/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEaster(t *testing.T) {
+	tests := []struct {
+		year     int
+		expected string
+	}{
+		{2019, "2019-04-21"},
+		{2024, "2024-03-31"},
+		{2025, "2025-04-20"},
+		{2026, "2026-04-05"},
+		{2038, "2038-04-25"},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, Easter(tt.year).Format(DateLayout))
+	}
+}
+
+func TestStatutory(t *testing.T) {
+	dates := func(holidays []Holiday) map[string]string {
+		result := make(map[string]string)
+		for _, h := range holidays {
+			result[h.Date] = h.Name
+		}
+		return result
+	}
+
+	t.Run("nationwide", func(t *testing.T) {
+		nationwide := Statutory(2025, "")
+		assert.Len(t, nationwide, 9)
+		assert.Equal(t, "Karfreitag", dates(nationwide)["2025-04-18"])
+		assert.Equal(t, "Christi Himmelfahrt", dates(nationwide)["2025-05-29"])
+		assert.Equal(t, "Pfingstmontag", dates(nationwide)["2025-06-09"])
+	})
+
+	t.Run("bayern", func(t *testing.T) {
+		bayern := dates(Statutory(2025, models.BundeslandBayern))
+		assert.Equal(t, "Heilige Drei Könige", bayern["2025-01-06"])
+		assert.Equal(t, "Fronleichnam", bayern["2025-06-19"])
+		assert.Equal(t, "Allerheiligen", bayern["2025-11-01"])
+		assert.NotContains(t, bayern, "2025-10-31")
+	})
+
+	t.Run("sachsen_repentance_day", func(t *testing.T) {
+		sachsen := dates(Statutory(2025, models.BundeslandSachsen))
+		assert.Equal(t, "Buß- und Bettag", sachsen["2025-11-19"])
+		assert.Equal(t, "Reformationstag", sachsen["2025-10-31"])
+		assert.Equal(t, "Buß- und Bettag", dates(Statutory(2023, models.BundeslandSachsen))["2023-11-22"])
+	})
+
+	t.Run("holidays_introduced_later", func(t *testing.T) {
+		assert.NotContains(t, dates(Statutory(2018, models.BundeslandBerlin)), "2018-03-08")
+		assert.Contains(t, dates(Statutory(2019, models.BundeslandBerlin)), "2019-03-08")
+		assert.NotContains(t, dates(Statutory(2017, models.BundeslandHamburg)), "2017-10-31")
+		assert.Contains(t, dates(Statutory(2018, models.BundeslandHamburg)), "2018-10-31")
+	})
+
+	t.Run("sorted", func(t *testing.T) {
+		holidays := Statutory(2025, models.BundeslandBrandenburg)
+		for i := 1; i < len(holidays); i++ {
+			assert.Less(t, holidays[i-1].Date, holidays[i].Date)
+		}
+	})
+}
+
+func TestRepentanceDay(t *testing.T) {
+	for year := 2020; year <= 2030; year++ {
+		day := repentanceDay(year)
+		assert.Equal(t, time.Wednesday, day.Weekday())
+		assert.True(t, day.Day() >= 16 && day.Day() <= 22)
+	}
+}
@@ -0,0 +1,133 @@
+package holidays
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"elterngeld-portal/internal/models"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// Service combines the statutory holidays with admin overrides
+type Service struct {
+	db     *gorm.DB
+	logger *zap.Logger
+}
+
+func NewService(db *gorm.DB, logger *zap.Logger) *Service {
+	return &Service{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// ForYear returns the public holidays of a year in a Bundesland, sorted by date
+func (s *Service) ForYear(year int, state models.Bundesland) ([]Holiday, error) {
+	byDate, err := s.Between(state, fmt.Sprintf("%04d-01-01", year), fmt.Sprintf("%04d-12-31", year))
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]Holiday, 0, len(byDate))
+	for _, holiday := range byDate {
+		result = append(result, holiday)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Date < result[j].Date
+	})
+
+	return result, nil
+}
+
+// Between returns the public holidays in a Bundesland between the calendar dates
+// from and to (inclusive, YYYY-MM-DD), keyed by date
+func (s *Service) Between(state models.Bundesland, from, to string) (map[string]Holiday, error) {
+	fromDate, err := time.Parse(DateLayout, from)
+	if err != nil {
+		return nil, fmt.Errorf("invalid start date: %w", err)
+	}
+	toDate, err := time.Parse(DateLayout, to)
+	if err != nil {
+		return nil, fmt.Errorf("invalid end date: %w", err)
+	}
+
+	result := make(map[string]Holiday)
+	for year := fromDate.Year(); year <= toDate.Year(); year++ {
+		for _, holiday := range Statutory(year, state) {
+			if holiday.Date >= from && holiday.Date <= to {
+				result[holiday.Date] = holiday
+			}
+		}
+	}
+
+	// Overrides for a specific Bundesland are applied after nationwide ones and win
+	var overrides []models.HolidayOverride
+	if err := s.db.Where("date >= ? AND date <= ? AND (bundesland = ? OR bundesland = ?)", from, to, "", state).
+		Order("bundesland ASC, created_at ASC").Find(&overrides).Error; err != nil {
+		return nil, fmt.Errorf("failed to load holiday overrides: %w", err)
+	}
+
+	for _, override := range overrides {
+		if !override.IsHoliday {
+			delete(result, override.Date)
+			continue
+		}
+		name := override.Name
+		if name == "" {
+			name = result[override.Date].Name
+		}
+		result[override.Date] = Holiday{Date: override.Date, Name: name, Override: true}
+	}
+
+	return result, nil
+}
+
+// IsHoliday reports whether the calendar day of t in loc is a public holiday in a Bundesland
+func (s *Service) IsHoliday(t time.Time, loc *time.Location, state models.Bundesland) (*Holiday, error) {
+	date := t.In(loc).Format(DateLayout)
+	byDate, err := s.Between(state, date, date)
+	if err != nil {
+		return nil, err
+	}
+	if holiday, ok := byDate[date]; ok {
+		return &holiday, nil
+	}
+	return nil, nil
+}
+
+// Calendar caches the holidays of several Bundesländer for a date range
+type Calendar struct {
+	service *Service
+	from    string
+	to      string
+	states  map[models.Bundesland]map[string]Holiday
+}
+
+// Calendar returns a cached holiday lookup for the calendar dates from and to (inclusive)
+func (s *Service) Calendar(from, to string) *Calendar {
+	return &Calendar{
+		service: s,
+		from:    from,
+		to:      to,
+		states:  make(map[models.Bundesland]map[string]Holiday),
+	}
+}
+
+// Lookup returns the holiday on a calendar date in a Bundesland, if any
+func (c *Calendar) Lookup(state models.Bundesland, date string) (Holiday, bool, error) {
+	byDate, ok := c.states[state]
+	if !ok {
+		var err error
+		byDate, err = c.service.Between(state, c.from, c.to)
+		if err != nil {
+			return Holiday{}, false, err
+		}
+		c.states[state] = byDate
+	}
+
+	holiday, ok := byDate[date]
+	return holiday, ok, nil
+}
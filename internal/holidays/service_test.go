@@ -0,0 +1,99 @@
+package holidays
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"elterngeld-portal/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestBetween_AppliesOverrides(t *testing.T) {
+	db, service := setupTestService(t)
+	adminID := uuid.New()
+
+	require.NoError(t, db.Create(&[]models.HolidayOverride{
+		// Nationwide company holiday
+		{Date: "2025-12-24", Name: "Heiligabend", IsHoliday: true, CreatedBy: adminID},
+		// Office in Bayern works on Heilige Drei Könige
+		{Date: "2025-01-06", Bundesland: models.BundeslandBayern, IsHoliday: false, CreatedBy: adminID},
+		// Regional holiday in Augsburg
+		{Date: "2025-08-08", Bundesland: models.BundeslandBayern, Name: "Augsburger Friedensfest", IsHoliday: true, CreatedBy: adminID},
+	}).Error)
+
+	bayern, err := service.Between(models.BundeslandBayern, "2025-01-01", "2025-12-31")
+	require.NoError(t, err)
+	assert.NotContains(t, bayern, "2025-01-06")
+	assert.Equal(t, Holiday{Date: "2025-08-08", Name: "Augsburger Friedensfest", Override: true}, bayern["2025-08-08"])
+	assert.Equal(t, "Heiligabend", bayern["2025-12-24"].Name)
+	assert.Equal(t, "Fronleichnam", bayern["2025-06-19"].Name)
+
+	berlin, err := service.Between(models.BundeslandBerlin, "2025-01-01", "2025-12-31")
+	require.NoError(t, err)
+	assert.NotContains(t, berlin, "2025-08-08")
+	assert.Contains(t, berlin, "2025-12-24")
+	assert.Contains(t, berlin, "2025-03-08")
+}
+
+func TestForYear(t *testing.T) {
+	_, service := setupTestService(t)
+
+	holidays, err := service.ForYear(2025, models.BundeslandNordrheinWestfalen)
+	require.NoError(t, err)
+	require.Len(t, holidays, 11)
+	assert.Equal(t, "2025-01-01", holidays[0].Date)
+	assert.Equal(t, "2025-12-26", holidays[len(holidays)-1].Date)
+}
+
+func TestIsHoliday_UsesLocalCalendarDay(t *testing.T) {
+	_, service := setupTestService(t)
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+
+	// 23:30 UTC on New Year's Eve is already Neujahr in Berlin
+	holiday, err := service.IsHoliday(time.Date(2024, time.December, 31, 23, 30, 0, 0, time.UTC), berlin, models.BundeslandBerlin)
+	require.NoError(t, err)
+	require.NotNil(t, holiday)
+	assert.Equal(t, "Neujahr", holiday.Name)
+
+	holiday, err = service.IsHoliday(time.Date(2024, time.December, 31, 23, 30, 0, 0, time.UTC), time.UTC, models.BundeslandBerlin)
+	require.NoError(t, err)
+	assert.Nil(t, holiday)
+}
+
+func TestCalendar_Lookup(t *testing.T) {
+	_, service := setupTestService(t)
+	calendar := service.Calendar("2025-10-01", "2025-11-30")
+
+	holiday, ok, err := calendar.Lookup(models.BundeslandSachsen, "2025-11-19")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "Buß- und Bettag", holiday.Name)
+
+	_, ok, err = calendar.Lookup(models.BundeslandBerlin, "2025-11-19")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	_, ok, err = calendar.Lookup("", "2025-10-03")
+	require.NoError(t, err)
+	assert.True(t, ok)
+}
+
+func setupTestService(t *testing.T) (*gorm.DB, *Service) {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, db.AutoMigrate(&models.HolidayOverride{}))
+
+	return db, NewService(db, zap.NewNop())
+}
@@ -0,0 +1,522 @@
+package inbound
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"elterngeld-portal/config"
+	"elterngeld-portal/internal/models"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrAlreadyAttached is returned when an inbound email is already part of a lead timeline
+var ErrAlreadyAttached = errors.New("inbound email is already attached to a lead")
+
+// Message represents a raw email received from the inbound email webhook of the mail provider
+type Message struct {
+	MessageID  string
+	InReplyTo  string
+	References []string
+	From       string
+	FromName   string
+	To         string
+	Subject    string
+	TextBody   string
+	HTMLBody   string
+	ReceivedAt time.Time
+
+	Attachments []Attachment
+}
+
+// Attachment represents a file attached to a raw email
+type Attachment struct {
+	FileName    string
+	ContentType string
+	Content     []byte
+}
+
+// Processor matches inbound emails to leads and contact forms
+type Processor struct {
+	db            *gorm.DB
+	logger        *zap.Logger
+	attachmentDir string
+}
+
+// matchResult holds the result of matching a message
+type matchResult struct {
+	by          models.InboundEmailMatch
+	reference   string
+	lead        *models.Lead
+	contactForm *models.ContactForm
+	thread      *models.EmailThread
+}
+
+// NewProcessor creates a new inbound email processor
+func NewProcessor(db *gorm.DB, logger *zap.Logger, cfg *config.Config) *Processor {
+	return &Processor{
+		db:            db,
+		logger:        logger,
+		attachmentDir: cfg.Email.InboundAttachmentDir,
+	}
+}
+
+// Process stores an inbound message and attaches it to the matching lead or contact form.
+// Processing is idempotent: a message ID that was already stored is returned unchanged.
+func (p *Processor) Process(msg *Message) (*models.InboundEmail, error) {
+	if msg.MessageID == "" {
+		msg.MessageID = fmt.Sprintf("<%s@inbound.elterngeld-portal>", uuid.New().String())
+	}
+	if msg.ReceivedAt.IsZero() {
+		msg.ReceivedAt = time.Now()
+	}
+
+	var existing models.InboundEmail
+	if err := p.db.Preload("Attachments").Where("message_id = ?", msg.MessageID).First(&existing).Error; err == nil {
+		p.logger.Info("Inbound email already processed", zap.String("message_id", msg.MessageID))
+		return &existing, nil
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("failed to check for duplicate email: %w", err)
+	}
+
+	email := &models.InboundEmail{
+		ID:         uuid.New(),
+		MessageID:  msg.MessageID,
+		InReplyTo:  msg.InReplyTo,
+		FromEmail:  models.NormalizeEmailAddress(msg.From),
+		FromName:   msg.FromName,
+		ToEmail:    models.NormalizeEmailAddress(msg.To),
+		Subject:    msg.Subject,
+		Body:       msg.TextBody,
+		Status:     models.InboundEmailStatusUnmatched,
+		ReceivedAt: msg.ReceivedAt,
+	}
+	if email.Body == "" && msg.HTMLBody != "" {
+		email.Body = msg.HTMLBody
+		email.IsHTML = true
+	}
+
+	m := p.match(msg, email.FromEmail)
+
+	attachments, err := p.storeAttachments(email.ID, msg.Attachments)
+	if err != nil {
+		return nil, err
+	}
+
+	tx := p.db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err := tx.Create(email).Error; err != nil {
+		tx.Rollback()
+		p.removeAttachments(attachments)
+		return nil, fmt.Errorf("failed to store inbound email: %w", err)
+	}
+
+	for i := range attachments {
+		attachments[i].InboundEmailID = email.ID
+		if err := tx.Create(&attachments[i]).Error; err != nil {
+			tx.Rollback()
+			p.removeAttachments(attachments)
+			return nil, fmt.Errorf("failed to store email attachment: %w", err)
+		}
+	}
+	email.Attachments = attachments
+
+	switch {
+	case m.lead != nil:
+		if err := p.attachToLead(tx, email, m.lead, m.thread, m.by); err != nil {
+			tx.Rollback()
+			p.removeAttachments(attachments)
+			return nil, err
+		}
+		email.Reference = m.reference
+		if m.contactForm != nil {
+			email.ContactFormID = &m.contactForm.ID
+		}
+	case m.contactForm != nil:
+		now := time.Now()
+		email.Status = models.InboundEmailStatusMatched
+		email.MatchedBy = m.by
+		email.Reference = m.reference
+		email.ContactFormID = &m.contactForm.ID
+		email.ProcessedAt = &now
+	}
+
+	if err := tx.Omit(clause.Associations).Save(email).Error; err != nil {
+		tx.Rollback()
+		p.removeAttachments(attachments)
+		return nil, fmt.Errorf("failed to update inbound email: %w", err)
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		p.removeAttachments(attachments)
+		return nil, fmt.Errorf("failed to commit inbound email: %w", err)
+	}
+
+	switch {
+	case m.lead != nil:
+		p.notifyBerater(m.lead, email)
+	case m.contactForm != nil && m.contactForm.ProcessedBy != nil:
+		p.notifyUser(*m.contactForm.ProcessedBy, email,
+			"Neue E-Mail zu Kontaktanfrage",
+			fmt.Sprintf("%s hat auf die Kontaktanfrage '%s' geantwortet.", email.FromEmail, m.contactForm.Subject))
+	}
+
+	p.logger.Info("Inbound email processed",
+		zap.String("message_id", email.MessageID),
+		zap.String("from", email.FromEmail),
+		zap.String("status", string(email.Status)),
+		zap.String("matched_by", string(email.MatchedBy)))
+
+	return email, nil
+}
+
+// AssignToLead attaches a previously unmatched inbound email to a lead
+func (p *Processor) AssignToLead(emailID, leadID uuid.UUID) (*models.InboundEmail, error) {
+	var email models.InboundEmail
+	if err := p.db.Preload("Attachments").First(&email, "id = ?", emailID).Error; err != nil {
+		return nil, err
+	}
+
+	if email.EmailMessageID != nil {
+		return nil, ErrAlreadyAttached
+	}
+
+	var lead models.Lead
+	if err := p.db.First(&lead, "id = ?", leadID).Error; err != nil {
+		return nil, err
+	}
+
+	tx := p.db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err := p.attachToLead(tx, &email, &lead, nil, models.InboundEmailMatchManual); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Omit(clause.Associations).Save(&email).Error; err != nil {
+		tx.Rollback()
+		return nil, fmt.Errorf("failed to update inbound email: %w", err)
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, fmt.Errorf("failed to commit inbound email: %w", err)
+	}
+
+	p.notifyBerater(&lead, &email)
+
+	return &email, nil
+}
+
+// match finds the lead or contact form a message belongs to.
+// Thread headers are checked first, then reference numbers, then the sender address.
+func (p *Processor) match(msg *Message, fromEmail string) matchResult {
+	if m, ok := p.matchByThread(msg); ok {
+		return m
+	}
+	if m, ok := p.matchByReference(msg); ok {
+		return m
+	}
+	if m, ok := p.matchBySender(fromEmail); ok {
+		return m
+	}
+	return matchResult{}
+}
+
+// matchByThread matches replies to messages that are already part of a lead timeline
+func (p *Processor) matchByThread(msg *Message) (matchResult, bool) {
+	ids := make([]string, 0, len(msg.References)+1)
+	if msg.InReplyTo != "" {
+		ids = append(ids, msg.InReplyTo)
+	}
+	ids = append(ids, msg.References...)
+	if len(ids) == 0 {
+		return matchResult{}, false
+	}
+
+	var message models.EmailMessage
+	if err := p.db.Preload("Thread.Lead").Where("message_id IN ?", ids).
+		Order("sent_at DESC").First(&message).Error; err != nil {
+		return matchResult{}, false
+	}
+
+	lead := message.Thread.Lead
+	thread := message.Thread
+	return matchResult{by: models.InboundEmailMatchThread, lead: &lead, thread: &thread}, true
+}
+
+// matchByReference matches application numbers, booking references and contact form references
+func (p *Processor) matchByReference(msg *Message) (matchResult, bool) {
+	kind, reference := models.FindEmailReference(msg.Subject, msg.TextBody, msg.HTMLBody)
+	if reference == "" {
+		return matchResult{}, false
+	}
+
+	m := matchResult{by: models.InboundEmailMatchReference, reference: reference}
+
+	switch kind {
+	case models.ReferenceKindLead:
+		var lead models.Lead
+		if err := p.db.Where("UPPER(application_number) = ?", strings.ToUpper(reference)).First(&lead).Error; err != nil {
+			return matchResult{}, false
+		}
+		m.lead = &lead
+
+	case models.ReferenceKindBooking:
+		var booking models.Booking
+		if err := p.db.Where("LOWER(booking_reference) = ?", strings.ToLower(reference)).First(&booking).Error; err != nil || booking.LeadID == nil {
+			return matchResult{}, false
+		}
+		var lead models.Lead
+		if err := p.db.First(&lead, "id = ?", *booking.LeadID).Error; err != nil {
+			return matchResult{}, false
+		}
+		m.lead = &lead
+
+	case models.ReferenceKindContactForm:
+		prefix := strings.ToLower(strings.TrimPrefix(strings.ToUpper(reference), "CF-"))
+		var contactForm models.ContactForm
+		if err := p.db.Where("id LIKE ?", prefix+"%").First(&contactForm).Error; err != nil {
+			return matchResult{}, false
+		}
+		m.contactForm = &contactForm
+		if contactForm.LeadID != nil {
+			var lead models.Lead
+			if err := p.db.First(&lead, "id = ?", *contactForm.LeadID).Error; err == nil {
+				m.lead = &lead
+			}
+		}
+
+	default:
+		return matchResult{}, false
+	}
+
+	return m, true
+}
+
+// matchBySender matches the sender address to the most recent open lead or contact form
+func (p *Processor) matchBySender(fromEmail string) (matchResult, bool) {
+	if fromEmail == "" {
+		return matchResult{}, false
+	}
+
+	var user models.User
+	if err := p.db.Where("LOWER(email) = ?", fromEmail).First(&user).Error; err == nil {
+		var lead models.Lead
+		err := p.db.Where("user_id = ? AND status NOT IN ?", user.ID,
+			[]models.LeadStatus{models.LeadStatusCompleted, models.LeadStatusCancelled}).
+			Order("updated_at DESC").First(&lead).Error
+		if err == nil {
+			return matchResult{by: models.InboundEmailMatchSender, lead: &lead}, true
+		}
+	}
+
+	var contactForm models.ContactForm
+	if err := p.db.Where("LOWER(email) = ?", fromEmail).Order("created_at DESC").First(&contactForm).Error; err != nil {
+		return matchResult{}, false
+	}
+
+	m := matchResult{by: models.InboundEmailMatchSender, contactForm: &contactForm}
+	if contactForm.LeadID != nil {
+		var lead models.Lead
+		if err := p.db.First(&lead, "id = ?", *contactForm.LeadID).Error; err == nil {
+			m.lead = &lead
+		}
+	}
+
+	return m, true
+}
+
+// attachToLead adds the email to the lead's email thread and timeline
+func (p *Processor) attachToLead(tx *gorm.DB, email *models.InboundEmail, lead *models.Lead, thread *models.EmailThread, by models.InboundEmailMatch) error {
+	subject := models.NormalizeEmailSubject(email.Subject)
+	if subject == "" {
+		subject = "(kein Betreff)"
+	}
+
+	if thread == nil {
+		var existing models.EmailThread
+		err := tx.Where("lead_id = ? AND subject = ? AND is_active = ?", lead.ID, subject, true).
+			Order("last_message_at DESC").First(&existing).Error
+		switch {
+		case err == nil:
+			thread = &existing
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			thread = &models.EmailThread{
+				LeadID:        lead.ID,
+				Subject:       subject,
+				ThreadID:      email.MessageID,
+				LastMessageAt: email.ReceivedAt,
+				IsActive:      true,
+			}
+			if err := tx.Create(thread).Error; err != nil {
+				return fmt.Errorf("failed to create email thread: %w", err)
+			}
+		default:
+			return fmt.Errorf("failed to load email thread: %w", err)
+		}
+	}
+
+	message := &models.EmailMessage{
+		ThreadID:  thread.ID,
+		MessageID: email.MessageID,
+		FromEmail: email.FromEmail,
+		ToEmail:   email.ToEmail,
+		Subject:   email.Subject,
+		Body:      email.Body,
+		IsHTML:    email.IsHTML,
+		IsInbound: true,
+		SentAt:    email.ReceivedAt,
+	}
+	if err := tx.Create(message).Error; err != nil {
+		return fmt.Errorf("failed to create email message: %w", err)
+	}
+
+	if err := tx.Model(thread).Updates(map[string]interface{}{
+		"message_count":   gorm.Expr("message_count + ?", 1),
+		"last_message_at": email.ReceivedAt,
+	}).Error; err != nil {
+		return fmt.Errorf("failed to update email thread: %w", err)
+	}
+
+	if len(email.Attachments) > 0 {
+		if err := tx.Model(&models.EmailAttachment{}).Where("inbound_email_id = ?", email.ID).
+			Update("email_message_id", message.ID).Error; err != nil {
+			return fmt.Errorf("failed to link email attachments: %w", err)
+		}
+		for i := range email.Attachments {
+			email.Attachments[i].EmailMessageID = &message.ID
+		}
+	}
+
+	if err := tx.Model(lead).Update("last_contact_at", email.ReceivedAt).Error; err != nil {
+		return fmt.Errorf("failed to update lead: %w", err)
+	}
+
+	activity := models.CreateEmailReceivedActivity(lead.ID, email.FromEmail, email.Subject, len(email.Attachments))
+	if err := tx.Create(activity).Error; err != nil {
+		return fmt.Errorf("failed to create activity: %w", err)
+	}
+
+	now := time.Now()
+	email.Status = models.InboundEmailStatusMatched
+	email.MatchedBy = by
+	email.LeadID = &lead.ID
+	email.EmailMessageID = &message.ID
+	email.ProcessedAt = &now
+
+	return nil
+}
+
+// storeAttachments writes attachment contents to disk
+func (p *Processor) storeAttachments(emailID uuid.UUID, attachments []Attachment) ([]models.EmailAttachment, error) {
+	if len(attachments) == 0 {
+		return nil, nil
+	}
+
+	dir := filepath.Join(p.attachmentDir, emailID.String())
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create attachment directory: %w", err)
+	}
+
+	stored := make([]models.EmailAttachment, 0, len(attachments))
+	for _, attachment := range attachments {
+		originalName := filepath.Base(attachment.FileName)
+		if originalName == "." || originalName == string(filepath.Separator) {
+			originalName = "attachment"
+		}
+
+		fileName := uuid.New().String() + strings.ToLower(filepath.Ext(originalName))
+		path := filepath.Join(dir, fileName)
+		if err := os.WriteFile(path, attachment.Content, 0644); err != nil {
+			p.removeAttachments(stored)
+			return nil, fmt.Errorf("failed to store attachment %s: %w", originalName, err)
+		}
+
+		stored = append(stored, models.EmailAttachment{
+			FileName:     fileName,
+			OriginalName: originalName,
+			FilePath:     path,
+			FileSize:     int64(len(attachment.Content)),
+			ContentType:  attachment.ContentType,
+		})
+	}
+
+	return stored, nil
+}
+
+// removeAttachments deletes stored attachment files after a failed import
+func (p *Processor) removeAttachments(attachments []models.EmailAttachment) {
+	for _, attachment := range attachments {
+		if err := os.Remove(attachment.FilePath); err != nil && !os.IsNotExist(err) {
+			p.logger.Warn("Failed to remove attachment", zap.String("path", attachment.FilePath), zap.Error(err))
+		}
+	}
+}
+
+// notifyBerater informs the assigned Berater about a new email on their lead
+func (p *Processor) notifyBerater(lead *models.Lead, email *models.InboundEmail) {
+	if lead.BeraterID == nil {
+		return
+	}
+
+	p.notifyUser(*lead.BeraterID, email,
+		fmt.Sprintf("Neue E-Mail zu Lead %s", lead.ApplicationNumber),
+		fmt.Sprintf("%s hat eine E-Mail zum Lead '%s' gesendet: %s", email.FromEmail, lead.Title, email.Subject))
+}
+
+// notifyUser creates in-app and email notifications for a user
+func (p *Processor) notifyUser(userID uuid.UUID, email *models.InboundEmail, title, message string) {
+	var user models.User
+	if err := p.db.First(&user, "id = ?", userID).Error; err != nil {
+		p.logger.Warn("Failed to load notification recipient", zap.String("user_id", userID.String()), zap.Error(err))
+		return
+	}
+
+	data, _ := json.Marshal(map[string]interface{}{
+		"inbound_email_id": email.ID,
+		"lead_id":          email.LeadID,
+		"contact_form_id":  email.ContactFormID,
+	})
+
+	notifications := []models.Notification{
+		{
+			UserID:    user.ID,
+			Type:      models.NotificationTypeInApp,
+			Title:     title,
+			Message:   message,
+			Data:      string(data),
+			Recipient: user.NotificationAddress(),
+		},
+		{
+			UserID:    user.ID,
+			Type:      models.NotificationTypeEmail,
+			Title:     title,
+			Message:   message,
+			Data:      string(data),
+			Template:  string(models.EmailTemplateLeadEmailReceived),
+			Recipient: user.NotificationAddress(),
+		},
+	}
+
+	if err := p.db.Create(&notifications).Error; err != nil {
+		p.logger.Error("Failed to create inbound email notification", zap.String("user_id", userID.String()), zap.Error(err))
+	}
+}
@@ -0,0 +1,208 @@
+package inbound
+
+import (
+	"path/filepath"
+	"testing"
+
+	"elterngeld-portal/config"
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/tests/testutils"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+func TestProcess_MatchByApplicationNumber(t *testing.T) {
+	db, processor := setupTestProcessor(t)
+	berater := createTestUser(t, db, "berater@example.com", models.RoleBerater)
+	customer := createTestUser(t, db, "kunde@example.com", models.RoleUser)
+	lead := createTestLead(t, db, customer, &berater.ID)
+
+	email, err := processor.Process(&Message{
+		MessageID: "<msg-1@example.com>",
+		From:      "Someone Else <other@example.com>",
+		Subject:   "Frage zu " + lead.ApplicationNumber,
+		TextBody:  "Hallo, anbei die Unterlagen.",
+		Attachments: []Attachment{
+			{FileName: "geburtsurkunde.pdf", ContentType: "application/pdf", Content: []byte("%PDF-1.4")},
+		},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, models.InboundEmailStatusMatched, email.Status)
+	assert.Equal(t, models.InboundEmailMatchReference, email.MatchedBy)
+	require.NotNil(t, email.LeadID)
+	assert.Equal(t, lead.ID, *email.LeadID)
+	require.Len(t, email.Attachments, 1)
+	assert.FileExists(t, email.Attachments[0].FilePath)
+
+	var thread models.EmailThread
+	require.NoError(t, db.Preload("Messages.Attachments").Where("lead_id = ?", lead.ID).First(&thread).Error)
+	assert.Equal(t, 1, thread.MessageCount)
+	require.Len(t, thread.Messages, 1)
+	assert.Len(t, thread.Messages[0].Attachments, 1)
+
+	var activityCount int64
+	db.Model(&models.Activity{}).Where("lead_id = ? AND type = ?", lead.ID, models.ActivityTypeEmailReceived).Count(&activityCount)
+	assert.Equal(t, int64(1), activityCount)
+
+	var notificationCount int64
+	db.Model(&models.Notification{}).Where("user_id = ?", berater.ID).Count(&notificationCount)
+	assert.Equal(t, int64(2), notificationCount)
+}
+
+func TestProcess_MatchByThreadAndSender(t *testing.T) {
+	db, processor := setupTestProcessor(t)
+	customer := createTestUser(t, db, "anna@example.com", models.RoleUser)
+	lead := createTestLead(t, db, customer, nil)
+
+	first, err := processor.Process(&Message{
+		MessageID: "<first@example.com>",
+		From:      "Anna <ANNA@example.com>",
+		Subject:   "Unterlagen",
+		TextBody:  "Erste Nachricht",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, models.InboundEmailMatchSender, first.MatchedBy)
+	require.NotNil(t, first.LeadID)
+	assert.Equal(t, lead.ID, *first.LeadID)
+
+	reply, err := processor.Process(&Message{
+		MessageID: "<reply@example.com>",
+		InReplyTo: "<first@example.com>",
+		From:      "unknown@example.com",
+		Subject:   "AW: Unterlagen",
+		TextBody:  "Antwort",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, models.InboundEmailMatchThread, reply.MatchedBy)
+
+	var threads []models.EmailThread
+	require.NoError(t, db.Where("lead_id = ?", lead.ID).Find(&threads).Error)
+	require.Len(t, threads, 1)
+	assert.Equal(t, 2, threads[0].MessageCount)
+}
+
+func TestProcess_ContactFormReference(t *testing.T) {
+	db, processor := setupTestProcessor(t)
+	contactForm := &models.ContactForm{
+		Name:    "Max Muster",
+		Email:   "max@example.com",
+		Subject: "Frage",
+		Message: "Wie hoch ist mein Elterngeld?",
+	}
+	require.NoError(t, db.Create(contactForm).Error)
+
+	email, err := processor.Process(&Message{
+		From:    "other@example.com",
+		Subject: "Re: Kontaktanfrage erhalten CF-" + contactForm.ID.String()[:8],
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, models.InboundEmailStatusMatched, email.Status)
+	assert.Nil(t, email.LeadID)
+	require.NotNil(t, email.ContactFormID)
+	assert.Equal(t, contactForm.ID, *email.ContactFormID)
+}
+
+func TestProcess_UnmatchedAndManualAssignment(t *testing.T) {
+	db, processor := setupTestProcessor(t)
+	customer := createTestUser(t, db, "kunde@example.com", models.RoleUser)
+	lead := createTestLead(t, db, customer, nil)
+
+	email, err := processor.Process(&Message{
+		MessageID: "<unknown@example.com>",
+		From:      "stranger@example.com",
+		Subject:   "Hallo",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, models.InboundEmailStatusUnmatched, email.Status)
+	assert.Nil(t, email.LeadID)
+
+	t.Run("duplicate_message_is_ignored", func(t *testing.T) {
+		again, err := processor.Process(&Message{MessageID: "<unknown@example.com>", From: "stranger@example.com"})
+		require.NoError(t, err)
+		assert.Equal(t, email.ID, again.ID)
+
+		var count int64
+		db.Model(&models.InboundEmail{}).Count(&count)
+		assert.Equal(t, int64(1), count)
+	})
+
+	t.Run("manual_assignment", func(t *testing.T) {
+		assigned, err := processor.AssignToLead(email.ID, lead.ID)
+		require.NoError(t, err)
+		assert.Equal(t, models.InboundEmailMatchManual, assigned.MatchedBy)
+		require.NotNil(t, assigned.LeadID)
+		assert.Equal(t, lead.ID, *assigned.LeadID)
+
+		_, err = processor.AssignToLead(email.ID, lead.ID)
+		assert.ErrorIs(t, err, ErrAlreadyAttached)
+	})
+}
+
+func TestFindEmailReference(t *testing.T) {
+	tests := []struct {
+		name      string
+		texts     []string
+		kind      models.ReferenceKind
+		reference string
+	}{
+		{"lead", []string{"Re: Antrag EG-2024-AB12CD"}, models.ReferenceKindLead, "EG-2024-AB12CD"},
+		{"booking", []string{"Termin BK-2024-1a2b3c4d"}, models.ReferenceKindBooking, "BK-2024-1a2b3c4d"},
+		{"contact_form", []string{"", "Ihre Anfrage CF-0a1b2c3d"}, models.ReferenceKindContactForm, "CF-0a1b2c3d"},
+		{"lead_takes_precedence", []string{"CF-0a1b2c3d", "EG-2024-AB12CD"}, models.ReferenceKindLead, "EG-2024-AB12CD"},
+		{"none", []string{"Hallo"}, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kind, reference := models.FindEmailReference(tt.texts...)
+			assert.Equal(t, tt.kind, kind)
+			assert.Equal(t, tt.reference, reference)
+		})
+	}
+}
+
+func setupTestProcessor(t *testing.T) (*gorm.DB, *Processor) {
+	db := testutils.NewSQLiteDB(t,
+		&models.User{},
+		&models.Lead{},
+		&models.Activity{},
+		&models.ContactForm{},
+		&models.Notification{},
+		&models.EmailThread{},
+		&models.EmailMessage{},
+		&models.InboundEmail{},
+		&models.EmailAttachment{},
+	)
+
+	cfg := &config.Config{
+		Email: config.EmailConfig{InboundAttachmentDir: filepath.Join(t.TempDir(), "inbound")},
+	}
+
+	return db, NewProcessor(db, zap.NewNop(), cfg)
+}
+
+func createTestUser(t *testing.T, db *gorm.DB, email string, role models.UserRole) *models.User {
+	t.Helper()
+	user := testutils.CreateTestUser(t, db, role)
+	require.NoError(t, db.Model(user).Update("email", email).Error)
+	user.Email = email
+	return user
+}
+
+func createTestLead(t *testing.T, db *gorm.DB, user *models.User, beraterID *uuid.UUID) *models.Lead {
+	lead := &models.Lead{
+		UserID:    user.ID,
+		BeraterID: beraterID,
+		Title:     "Elterngeld Antrag",
+		Status:    models.LeadStatusNew,
+		Priority:  models.PriorityMedium,
+	}
+	require.NoError(t, db.Create(lead).Error)
+	return lead
+}
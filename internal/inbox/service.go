@@ -0,0 +1,329 @@
+// Package inbox is the team inbox of contact forms and unassigned leads. A
+// Berater claims an item before replying to it, so two people never answer
+// the same inquiry; items nobody claims within the claim SLA are posted to
+// chat by the chatnotify package.
+package inbox
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"elterngeld-portal/internal/activitylog"
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/internal/outbox"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// MaxItems limits the contact forms and leads listed in the inbox
+const MaxItems = 100
+
+var (
+	// ErrContactFormNotFound is returned for unknown contact forms
+	ErrContactFormNotFound = errors.New("contact form not found")
+	// ErrLeadNotFound is returned for unknown leads
+	ErrLeadNotFound = errors.New("lead not found")
+	// ErrAlreadyClaimed is returned when someone else claimed the item first
+	ErrAlreadyClaimed = errors.New("item already claimed")
+	// ErrNotClaimer is returned when releasing an item claimed by someone else
+	ErrNotClaimer = errors.New("item is claimed by someone else")
+	// ErrCannotClaimLead is returned when a user who cannot be assigned leads claims one
+	ErrCannotClaimLead = errors.New("only Berater can claim leads")
+)
+
+// Inbox holds the contact forms and leads nobody has claimed yet, oldest first
+type Inbox struct {
+	ContactForms []models.ContactForm `json:"contact_forms"`
+	Leads        []models.Lead        `json:"leads"`
+}
+
+// Service lists and claims the items of the team inbox
+type Service struct {
+	db         *gorm.DB
+	logger     *zap.Logger
+	activities *activitylog.Service
+	relay      *outbox.Service
+	now        func() time.Time
+}
+
+func NewService(db *gorm.DB, logger *zap.Logger, activities *activitylog.Service, relay *outbox.Service) *Service {
+	return &Service{
+		db:         db,
+		logger:     logger,
+		activities: activities,
+		relay:      relay,
+		now:        time.Now,
+	}
+}
+
+// Unclaimed returns the unprocessed contact forms nobody claimed and the new
+// leads without a Berater
+func (s *Service) Unclaimed() (*Inbox, error) {
+	inbox := &Inbox{ContactForms: []models.ContactForm{}, Leads: []models.Lead{}}
+
+	if err := s.db.Where("claimed_by IS NULL AND is_processed = ?", false).
+		Order("created_at ASC").Limit(MaxItems).Find(&inbox.ContactForms).Error; err != nil {
+		return nil, fmt.Errorf("failed to load contact forms: %w", err)
+	}
+	if err := s.db.Where("berater_id IS NULL AND status = ?", models.LeadStatusNew).
+		Order("created_at ASC").Limit(MaxItems).Find(&inbox.Leads).Error; err != nil {
+		return nil, fmt.Errorf("failed to load leads: %w", err)
+	}
+	return inbox, nil
+}
+
+// ClaimContactForm assigns a contact form to the user. The claim is a single
+// conditional update, so of two concurrent claims only one succeeds; claiming
+// a form again is a no-op for its claimer.
+func (s *Service) ClaimContactForm(id, userID uuid.UUID) (*models.ContactForm, error) {
+	now := s.now()
+	result := s.db.Model(&models.ContactForm{}).
+		Where("id = ? AND claimed_by IS NULL", id).
+		Updates(map[string]interface{}{"claimed_by": userID, "claimed_at": now})
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to claim contact form: %w", result.Error)
+	}
+
+	form, err := s.findContactForm(id)
+	if err != nil {
+		return nil, err
+	}
+	if result.RowsAffected == 0 && (form.ClaimedBy == nil || *form.ClaimedBy != userID) {
+		return form, ErrAlreadyClaimed
+	}
+	return form, nil
+}
+
+// ReleaseContactForm hands a claimed contact form back to the inbox. Only its
+// claimer and admins can release it.
+func (s *Service) ReleaseContactForm(id, userID uuid.UUID, isAdmin bool) (*models.ContactForm, error) {
+	form, err := s.findContactForm(id)
+	if err != nil {
+		return nil, err
+	}
+	if form.ClaimedBy == nil {
+		return form, nil
+	}
+	if *form.ClaimedBy != userID && !isAdmin {
+		return nil, ErrNotClaimer
+	}
+
+	// The claim SLA starts over once the form is back in the inbox
+	if err := s.db.Model(form).Updates(map[string]interface{}{
+		"claimed_by":            nil,
+		"claimed_at":            nil,
+		"unclaimed_notified_at": nil,
+	}).Error; err != nil {
+		return nil, fmt.Errorf("failed to release contact form: %w", err)
+	}
+	form.ClaimedBy = nil
+	form.ClaimedAt = nil
+	form.UnclaimedNotifiedAt = nil
+	return form, nil
+}
+
+// ClaimLead assigns an unassigned lead to the Berater claiming it. Like
+// contact forms, only one of two concurrent claims succeeds.
+func (s *Service) ClaimLead(id uuid.UUID, berater *models.User) (*models.Lead, error) {
+	if berater.Role != models.RoleBerater && berater.Role != models.RoleJuniorBerater {
+		return nil, ErrCannotClaimLead
+	}
+
+	var claimed bool
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.Lead{}).
+			Where("id = ? AND berater_id IS NULL", id).
+			Updates(map[string]interface{}{"berater_id": berater.ID, "updated_at": s.now()})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		claimed = true
+		return s.activities.Record(tx, activitylog.LeadAssigned{
+			ActorID:     berater.ID,
+			LeadID:      id,
+			BeraterID:   berater.ID,
+			BeraterName: berater.FullName(),
+			Notes:       "Aus dem Team-Postfach übernommen",
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim lead: %w", err)
+	}
+
+	lead, err := s.findLead(id)
+	if err != nil {
+		return nil, err
+	}
+	if !claimed && (lead.BeraterID == nil || *lead.BeraterID != berater.ID) {
+		return lead, ErrAlreadyClaimed
+	}
+	return lead, nil
+}
+
+// ReleaseLead hands a lead back to the inbox. Only its Berater and admins can
+// release it.
+func (s *Service) ReleaseLead(id, userID uuid.UUID, isAdmin bool) (*models.Lead, error) {
+	lead, err := s.findLead(id)
+	if err != nil {
+		return nil, err
+	}
+	if lead.BeraterID == nil {
+		return lead, nil
+	}
+	if *lead.BeraterID != userID && !isAdmin {
+		return nil, ErrNotClaimer
+	}
+
+	previous := *lead.BeraterID
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		// The claim SLA starts over once the lead is back in the inbox
+		if err := tx.Model(lead).Updates(map[string]interface{}{
+			"berater_id":            nil,
+			"unclaimed_notified_at": nil,
+		}).Error; err != nil {
+			return err
+		}
+		return s.activities.Record(tx, activitylog.LeadReleased{
+			ActorID:   userID,
+			LeadID:    lead.ID,
+			BeraterID: previous,
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to release lead: %w", err)
+	}
+
+	lead.BeraterID = nil
+	lead.UnclaimedNotifiedAt = nil
+	return lead, nil
+}
+
+// CreateContactFormLead creates the lead of a contact form in the given
+// transaction, records it in the activity log and queues the chat
+// notification. Submitters without an account get a customer account, which
+// they activate with the password reset flow.
+func (s *Service) CreateContactFormLead(tx *gorm.DB, form *models.ContactForm) (*models.Lead, error) {
+	actorID := form.UserID
+	if form.UserID == nil {
+		customer, err := s.findOrCreateCustomer(tx, form)
+		if err != nil {
+			return nil, err
+		}
+		form.UserID = &customer.ID
+	}
+
+	source := models.LeadSourceWebsite
+	switch form.UtmSource {
+	case "facebook":
+		source = models.LeadSourceSocial
+	case "email":
+		source = models.LeadSourceEmail
+	}
+
+	from := form.Name
+	if form.Company != "" {
+		from += " (" + form.Company + ")"
+	}
+
+	// The preferred date of the submitter is the first follow-up
+	lead := &models.Lead{
+		UserID:         *form.UserID,
+		Source:         source,
+		Status:         models.LeadStatusNew,
+		Priority:       models.PriorityMedium,
+		Title:          "Contact Form: " + form.Subject,
+		Description:    "Contact form submission from " + from + "\n\n" + form.Message,
+		UtmSource:      form.UtmSource,
+		UtmMedium:      form.UtmMedium,
+		UtmCampaign:    form.UtmCampaign,
+		NextFollowUpAt: form.PreferredDate,
+	}
+	if err := tx.Create(lead).Error; err != nil {
+		return nil, fmt.Errorf("failed to create lead: %w", err)
+	}
+
+	form.LeadID = &lead.ID
+	form.LeadCreated = true
+	if err := tx.Save(form).Error; err != nil {
+		return nil, fmt.Errorf("failed to link lead to contact form: %w", err)
+	}
+
+	// Anonymous submissions are recorded without an actor
+	if err := s.activities.Record(tx, activitylog.LeadCreated{
+		ActorID:       actorID,
+		LeadID:        lead.ID,
+		Title:         lead.Title,
+		Source:        lead.Source,
+		ContactFormID: &form.ID,
+	}); err != nil {
+		return nil, fmt.Errorf("failed to record activity: %w", err)
+	}
+
+	if err := s.relay.Enqueue(tx, outbox.TopicChatLeadCreated, lead.ID.String(), outbox.LeadMessage{LeadID: lead.ID}); err != nil {
+		return nil, fmt.Errorf("failed to queue notification: %w", err)
+	}
+	return lead, nil
+}
+
+func (s *Service) findOrCreateCustomer(tx *gorm.DB, form *models.ContactForm) (*models.User, error) {
+	email := models.NormalizeEmailAddress(form.Email)
+
+	var customer models.User
+	err := tx.Where("LOWER(email) = ?", email).First(&customer).Error
+	if err == nil {
+		return &customer, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	firstName, lastName, _ := strings.Cut(strings.TrimSpace(form.Name), " ")
+	customer = models.User{
+		Email:     email,
+		Password:  hex.EncodeToString(secret),
+		FirstName: firstName,
+		LastName:  strings.TrimSpace(lastName),
+		Phone:     form.Phone,
+		Role:      models.RoleUser,
+		IsActive:  true,
+	}
+	if err := tx.Create(&customer).Error; err != nil {
+		return nil, fmt.Errorf("failed to create customer: %w", err)
+	}
+	return &customer, nil
+}
+
+func (s *Service) findContactForm(id uuid.UUID) (*models.ContactForm, error) {
+	var form models.ContactForm
+	if err := s.db.First(&form, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrContactFormNotFound
+		}
+		return nil, err
+	}
+	return &form, nil
+}
+
+func (s *Service) findLead(id uuid.UUID) (*models.Lead, error) {
+	var lead models.Lead
+	if err := s.db.First(&lead, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrLeadNotFound
+		}
+		return nil, err
+	}
+	return &lead, nil
+}
@@ -0,0 +1,185 @@
+package inbox
+
+import (
+	"testing"
+	"time"
+
+	"elterngeld-portal/internal/activitylog"
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/internal/outbox"
+	"elterngeld-portal/tests/testutils"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+func TestClaimContactForm(t *testing.T) {
+	service, db := setupTestService(t)
+	first := testutils.CreateTestUser(t, db, models.RoleBerater)
+	second := testutils.CreateTestUser(t, db, models.RoleBerater)
+	admin := testutils.CreateTestUser(t, db, models.RoleAdmin)
+	form := createContactForm(t, db)
+
+	inbox, err := service.Unclaimed()
+	require.NoError(t, err)
+	assert.Len(t, inbox.ContactForms, 1)
+
+	claimed, err := service.ClaimContactForm(form.ID, first.ID)
+	require.NoError(t, err)
+	require.NotNil(t, claimed.ClaimedBy)
+	assert.Equal(t, first.ID, *claimed.ClaimedBy)
+
+	// Claiming again is fine for the claimer, not for anyone else
+	_, err = service.ClaimContactForm(form.ID, first.ID)
+	require.NoError(t, err)
+	_, err = service.ClaimContactForm(form.ID, second.ID)
+	assert.ErrorIs(t, err, ErrAlreadyClaimed)
+
+	inbox, err = service.Unclaimed()
+	require.NoError(t, err)
+	assert.Empty(t, inbox.ContactForms)
+
+	_, err = service.ReleaseContactForm(form.ID, second.ID, false)
+	assert.ErrorIs(t, err, ErrNotClaimer)
+	released, err := service.ReleaseContactForm(form.ID, admin.ID, true)
+	require.NoError(t, err)
+	assert.Nil(t, released.ClaimedBy)
+
+	_, err = service.ClaimContactForm(form.ID, second.ID)
+	require.NoError(t, err)
+
+	_, err = service.ClaimContactForm(uuid.New(), first.ID)
+	assert.ErrorIs(t, err, ErrContactFormNotFound)
+}
+
+func TestClaimLead(t *testing.T) {
+	service, db := setupTestService(t)
+	customer := testutils.CreateTestUser(t, db, models.RoleUser)
+	berater := testutils.CreateTestUser(t, db, models.RoleBerater)
+	other := testutils.CreateTestUser(t, db, models.RoleJuniorBerater)
+	admin := testutils.CreateTestUser(t, db, models.RoleAdmin)
+
+	lead := &models.Lead{UserID: customer.ID, Title: "Elterngeld für Zwillinge"}
+	require.NoError(t, db.Create(lead).Error)
+
+	inbox, err := service.Unclaimed()
+	require.NoError(t, err)
+	assert.Len(t, inbox.Leads, 1)
+
+	_, err = service.ClaimLead(lead.ID, admin)
+	assert.ErrorIs(t, err, ErrCannotClaimLead)
+
+	claimed, err := service.ClaimLead(lead.ID, berater)
+	require.NoError(t, err)
+	require.NotNil(t, claimed.BeraterID)
+	assert.Equal(t, berater.ID, *claimed.BeraterID)
+
+	_, err = service.ClaimLead(lead.ID, other)
+	assert.ErrorIs(t, err, ErrAlreadyClaimed)
+
+	_, err = service.ReleaseLead(lead.ID, other.ID, false)
+	assert.ErrorIs(t, err, ErrNotClaimer)
+	released, err := service.ReleaseLead(lead.ID, berater.ID, false)
+	require.NoError(t, err)
+	assert.Nil(t, released.BeraterID)
+
+	var activities []models.Activity
+	require.NoError(t, db.Where("lead_id = ?", lead.ID).Order("created_at ASC").Find(&activities).Error)
+	require.Len(t, activities, 2)
+	assert.Equal(t, models.ActivityTypeLeadAssigned, activities[0].Type)
+	assert.Equal(t, models.ActivityTypeLeadReleased, activities[1].Type)
+
+	_, err = service.ClaimLead(uuid.New(), berater)
+	assert.ErrorIs(t, err, ErrLeadNotFound)
+}
+
+func TestCreateContactFormLead(t *testing.T) {
+	service, db := setupTestService(t)
+
+	t.Run("submitter with account", func(t *testing.T) {
+		customer := testutils.CreateTestUser(t, db, models.RoleUser)
+		preferred := time.Date(2026, 11, 3, 10, 0, 0, 0, time.UTC)
+		form := createContactForm(t, db)
+		form.UserID = &customer.ID
+		form.Company = "Schmidt GmbH"
+		form.PreferredDate = &preferred
+		form.UtmSource = "facebook"
+
+		lead, err := service.CreateContactFormLead(db, form)
+		require.NoError(t, err)
+
+		var stored models.Lead
+		require.NoError(t, db.First(&stored, "id = ?", lead.ID).Error)
+		assert.Equal(t, customer.ID, stored.UserID)
+		assert.Contains(t, stored.Description, "Schmidt GmbH")
+		require.NotNil(t, stored.NextFollowUpAt)
+		assert.True(t, preferred.Equal(*stored.NextFollowUpAt))
+		assert.Equal(t, models.LeadSourceSocial, stored.Source)
+		assert.Equal(t, "facebook", stored.UtmSource)
+
+		var linked models.ContactForm
+		require.NoError(t, db.First(&linked, "id = ?", form.ID).Error)
+		require.NotNil(t, linked.LeadID)
+		assert.Equal(t, lead.ID, *linked.LeadID)
+		assert.True(t, linked.LeadCreated)
+
+		var queued int64
+		require.NoError(t, db.Model(&models.OutboxMessage{}).Where("topic = ?", outbox.TopicChatLeadCreated).Count(&queued).Error)
+		assert.Equal(t, int64(1), queued)
+	})
+
+	t.Run("anonymous submitter", func(t *testing.T) {
+		form := &models.ContactForm{
+			Name:    "Jonas Weber",
+			Email:   "Jonas.Weber@example.com",
+			Subject: "Basiselterngeld",
+			Message: "Welche Unterlagen brauche ich?",
+		}
+		require.NoError(t, db.Create(form).Error)
+
+		lead, err := service.CreateContactFormLead(db, form)
+		require.NoError(t, err)
+
+		var customer models.User
+		require.NoError(t, db.First(&customer, "id = ?", lead.UserID).Error)
+		assert.Equal(t, "jonas.weber@example.com", customer.Email)
+		assert.Equal(t, "Jonas", customer.FirstName)
+		assert.Equal(t, "Weber", customer.LastName)
+		assert.Equal(t, models.RoleUser, customer.Role)
+
+		var linked models.ContactForm
+		require.NoError(t, db.First(&linked, "id = ?", form.ID).Error)
+		require.NotNil(t, linked.UserID)
+		assert.Equal(t, customer.ID, *linked.UserID)
+	})
+}
+
+func setupTestService(t *testing.T) (*Service, *gorm.DB) {
+	t.Helper()
+	db := testutils.NewSQLiteDB(t,
+		&models.User{},
+		&models.Lead{},
+		&models.Activity{},
+		&models.ContactForm{},
+		&models.OutboxMessage{},
+	)
+
+	relay := outbox.NewService(db, zap.NewNop())
+	relay.Register(outbox.TopicChatLeadCreated, func(message *models.OutboxMessage) error { return nil })
+	return NewService(db, zap.NewNop(), activitylog.NewService(db, zap.NewNop()), relay), db
+}
+
+func createContactForm(t *testing.T, db *gorm.DB) *models.ContactForm {
+	t.Helper()
+	form := &models.ContactForm{
+		Name:    "Anna Schmidt",
+		Email:   "anna@example.com",
+		Subject: "Elterngeld Plus",
+		Message: "Wie beantrage ich Elterngeld Plus?",
+	}
+	require.NoError(t, db.Create(form).Error)
+	return form
+}
@@ -0,0 +1,386 @@
+package integrations
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"elterngeld-portal/internal/models"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	// DefaultPageSize is the number of items returned per poll when no limit is given
+	DefaultPageSize = 50
+	// MaxPageSize caps the number of items returned per poll
+	MaxPageSize = 100
+)
+
+var (
+	// ErrInvalidAPIKey is returned for unknown, revoked or expired keys and keys of deactivated staff
+	ErrInvalidAPIKey = errors.New("invalid API key")
+	// ErrAPIKeyNotFound is returned when revoking a key that does not exist or belongs to someone else
+	ErrAPIKeyNotFound = errors.New("API key not found")
+	// ErrUnknownScope is returned when creating a key with a scope that does not exist
+	ErrUnknownScope = errors.New("unknown API key scope")
+	// ErrScopeNotPermitted is returned when the owner lacks the permission behind a requested scope
+	ErrScopeNotPermitted = errors.New("API key scope not permitted")
+	// ErrInvalidCursor is returned for cursors that were not issued by a trigger
+	ErrInvalidCursor = errors.New("invalid cursor")
+	// ErrLeadNotFound is returned when a lead does not exist or is not visible to the key owner
+	ErrLeadNotFound = errors.New("lead not found")
+	// ErrStaffEmail is returned when a lead is created for the email address of a staff account
+	ErrStaffEmail = errors.New("email belongs to a staff account")
+)
+
+// LeadInput describes a lead created by an integration action. The customer is
+// looked up by email and registered when no account exists yet.
+type LeadInput struct {
+	Email          string
+	FirstName      string
+	LastName       string
+	Phone          string
+	Title          string
+	Description    string
+	ChildName      string
+	ChildBirthDate *time.Time
+	Priority       models.Priority
+	SourceDetails  string
+}
+
+// Page is one result of a polling trigger. NextCursor is passed to the next
+// poll to only receive newer items.
+type Page[T any] struct {
+	Items      []T    `json:"items"`
+	NextCursor string `json:"next_cursor"`
+}
+
+// Service manages API keys of no-code integrations such as Zapier and Make and
+// implements their triggers and actions on behalf of the key owner
+type Service struct {
+	db     *gorm.DB
+	logger *zap.Logger
+	now    func() time.Time
+}
+
+func NewService(db *gorm.DB, logger *zap.Logger) *Service {
+	return &Service{
+		db:     db,
+		logger: logger,
+		now:    time.Now,
+	}
+}
+
+// CreateAPIKey creates a key for owner and returns it together with the plain
+// key, which is only available at this point
+func (s *Service) CreateAPIKey(owner *models.User, name string, scopes []models.APIKeyScope, expiresAt *time.Time) (*models.APIKey, string, error) {
+	for _, scope := range scopes {
+		permission, ok := models.APIKeyScopePermissions[scope]
+		if !ok {
+			return nil, "", fmt.Errorf("%w: %s", ErrUnknownScope, scope)
+		}
+		if !models.HasPermission(s.db, owner.ID, owner.Role, permission) {
+			return nil, "", fmt.Errorf("%w: %s", ErrScopeNotPermitted, scope)
+		}
+	}
+
+	key := &models.APIKey{UserID: owner.ID, Name: strings.TrimSpace(name)}
+	plain, err := key.Generate(models.APIKeyPrefix)
+	if err != nil {
+		return nil, "", err
+	}
+	key.ExpiresAt = expiresAt
+	key.SetScopes(scopes)
+
+	if err := s.db.Create(key).Error; err != nil {
+		return nil, "", fmt.Errorf("failed to create API key: %w", err)
+	}
+
+	s.logger.Info("API key created",
+		zap.String("api_key_id", key.ID.String()),
+		zap.String("user_id", owner.ID.String()),
+		zap.String("scopes", key.Scopes))
+
+	return key, plain, nil
+}
+
+// ListAPIKeys returns the keys of a staff member, newest first
+func (s *Service) ListAPIKeys(ownerID uuid.UUID) ([]models.APIKey, error) {
+	var keys []models.APIKey
+	if err := s.db.Where("user_id = ?", ownerID).Order("created_at DESC").Find(&keys).Error; err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// RevokeAPIKey revokes a key of ownerID; admins may revoke any key
+func (s *Service) RevokeAPIKey(id, ownerID uuid.UUID, admin bool) error {
+	query := s.db.Model(&models.APIKey{}).Where("id = ?", id)
+	if !admin {
+		query = query.Where("user_id = ?", ownerID)
+	}
+
+	revoked, err := models.RevokeKeys(query, s.now())
+	if err != nil {
+		return fmt.Errorf("failed to revoke API key: %w", err)
+	}
+	if revoked == 0 {
+		return ErrAPIKeyNotFound
+	}
+
+	s.logger.Info("API key revoked", zap.String("api_key_id", id.String()), zap.String("revoked_by", ownerID.String()))
+	return nil
+}
+
+// Authenticate resolves a plain key to its stored key with the owner loaded
+func (s *Service) Authenticate(plain string) (*models.APIKey, error) {
+	if !strings.HasPrefix(plain, models.APIKeyPrefix) {
+		return nil, ErrInvalidAPIKey
+	}
+
+	var key models.APIKey
+	if err := s.db.Preload("User").Where("key_hash = ?", models.HashKey(plain)).First(&key).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidAPIKey
+		}
+		return nil, err
+	}
+	now := s.now()
+	if !key.IsActive(now) || !key.User.IsActive || key.User.DeactivatedAt != nil {
+		return nil, ErrInvalidAPIKey
+	}
+
+	// Only record usage once a minute; Zapier polls every few minutes per Zap
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) > time.Minute {
+		if err := s.db.Model(&key).UpdateColumn("last_used_at", now).Error; err != nil {
+			s.logger.Warn("Failed to record API key usage", zap.String("api_key_id", key.ID.String()), zap.Error(err))
+		}
+		key.LastUsedAt = &now
+	}
+
+	return &key, nil
+}
+
+// Authorize checks that a key was granted a scope and that its owner still
+// holds the permission behind it
+func (s *Service) Authorize(key *models.APIKey, scope models.APIKeyScope) bool {
+	if !key.HasScope(scope) {
+		return false
+	}
+	permission, ok := models.APIKeyScopePermissions[scope]
+	return ok && models.HasPermission(s.db, key.User.ID, key.User.Role, permission)
+}
+
+// NewLeads returns leads visible to the key owner that were created after cursor.
+// Without a cursor the most recent leads are returned so the integration can
+// show sample data.
+func (s *Service) NewLeads(key *models.APIKey, cursor string, limit int) (*Page[models.Lead], error) {
+	query := s.visibleLeads(s.db.Model(&models.Lead{}), &key.User).Preload("User")
+
+	leads, next, err := poll(query, cursor, limit, func(lead models.Lead) (time.Time, uuid.UUID) {
+		return lead.CreatedAt, lead.ID
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Page[models.Lead]{Items: leads, NextCursor: next}, nil
+}
+
+// NewBookings returns bookings visible to the key owner that were created after cursor
+func (s *Service) NewBookings(key *models.APIKey, cursor string, limit int) (*Page[models.Booking], error) {
+	query := s.db.Model(&models.Booking{}).Preload("User")
+	if key.User.Role == models.RoleJuniorBerater {
+		query = query.Where("berater_id = ?", key.User.ID)
+	}
+
+	bookings, next, err := poll(query, cursor, limit, func(booking models.Booking) (time.Time, uuid.UUID) {
+		return booking.CreatedAt, booking.ID
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Page[models.Booking]{Items: bookings, NextCursor: next}, nil
+}
+
+// CreateLead creates a lead for the customer with the given email on behalf of
+// the key owner. record is called in the transaction of the new lead and may
+// be nil; its error rolls the lead back.
+func (s *Service) CreateLead(key *models.APIKey, input LeadInput, record func(tx *gorm.DB, lead *models.Lead) error) (*models.Lead, error) {
+	email := models.NormalizeEmailAddress(input.Email)
+	priority := input.Priority
+	if priority == "" {
+		priority = models.PriorityMedium
+	}
+
+	lead := &models.Lead{
+		Title:          strings.TrimSpace(input.Title),
+		Description:    input.Description,
+		Status:         models.LeadStatusNew,
+		Priority:       priority,
+		Source:         models.LeadSourceIntegration,
+		SourceDetails:  input.SourceDetails,
+		ChildName:      input.ChildName,
+		ChildBirthDate: input.ChildBirthDate,
+	}
+	if lead.SourceDetails == "" {
+		lead.SourceDetails = key.Name
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var customer models.User
+		err := tx.Where("LOWER(email) = ?", email).First(&customer).Error
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			// The customer sets a password with the password reset flow
+			secret := make([]byte, 32)
+			if _, err := rand.Read(secret); err != nil {
+				return err
+			}
+			customer = models.User{
+				Email:     email,
+				Password:  hex.EncodeToString(secret),
+				FirstName: strings.TrimSpace(input.FirstName),
+				LastName:  strings.TrimSpace(input.LastName),
+				Phone:     input.Phone,
+				Role:      models.RoleUser,
+				IsActive:  true,
+			}
+			if err := tx.Create(&customer).Error; err != nil {
+				return fmt.Errorf("failed to create customer: %w", err)
+			}
+		case err != nil:
+			return err
+		case customer.Role != models.RoleUser:
+			return ErrStaffEmail
+		}
+
+		lead.UserID = customer.ID
+		lead.User = customer
+		if err := tx.Omit("User").Create(lead).Error; err != nil {
+			return fmt.Errorf("failed to create lead: %w", err)
+		}
+
+		if err := tx.Create(models.CreateLeadCreatedActivity(key.UserID, lead.ID, lead.Title)).Error; err != nil {
+			return err
+		}
+		if record != nil {
+			return record(tx, lead)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Lead created by integration",
+		zap.String("lead_id", lead.ID.String()),
+		zap.String("api_key_id", key.ID.String()))
+
+	return lead, nil
+}
+
+// AddComment adds a comment to a lead visible to the key owner
+func (s *Service) AddComment(key *models.APIKey, leadID uuid.UUID, content string, internal bool) (*models.Comment, error) {
+	var lead models.Lead
+	if err := s.visibleLeads(s.db, &key.User).Where("id = ?", leadID).First(&lead).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrLeadNotFound
+		}
+		return nil, err
+	}
+
+	comment := &models.Comment{
+		LeadID:     lead.ID,
+		UserID:     key.UserID,
+		Content:    content,
+		IsInternal: internal,
+	}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(comment).Error; err != nil {
+			return fmt.Errorf("failed to create comment: %w", err)
+		}
+		return tx.Create(models.CreateCommentAddedActivity(key.UserID, lead.ID, comment.ID)).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return comment, nil
+}
+
+// visibleLeads restricts a lead query to the leads the key owner can see in the portal
+func (s *Service) visibleLeads(query *gorm.DB, owner *models.User) *gorm.DB {
+	if owner.Role == models.RoleJuniorBerater {
+		return query.Where("berater_id = ? OR berater_id IS NULL", owner.ID)
+	}
+	return query
+}
+
+// poll loads up to limit records created after cursor, oldest first, and returns
+// them with the cursor of the last record
+func poll[T any](query *gorm.DB, cursor string, limit int, key func(T) (time.Time, uuid.UUID)) ([]T, string, error) {
+	if limit < 1 || limit > MaxPageSize {
+		limit = DefaultPageSize
+	}
+
+	var items []T
+	if cursor == "" {
+		if err := query.Order("created_at DESC, id DESC").Limit(limit).Find(&items).Error; err != nil {
+			return nil, "", err
+		}
+		reverse(items)
+	} else {
+		createdAt, id, err := decodeCursor(cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		if err := query.Where("created_at > ? OR (created_at = ? AND id > ?)", createdAt, createdAt, id).
+			Order("created_at ASC, id ASC").Limit(limit).Find(&items).Error; err != nil {
+			return nil, "", err
+		}
+	}
+
+	if len(items) == 0 {
+		return items, cursor, nil
+	}
+	createdAt, id := key(items[len(items)-1])
+	return items, encodeCursor(createdAt, id), nil
+}
+
+func reverse[T any](items []T) {
+	for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
+		items[i], items[j] = items[j], items[i]
+	}
+}
+
+func encodeCursor(createdAt time.Time, id uuid.UUID) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(createdAt.UnixNano(), 10) + "_" + id.String()))
+}
+
+func decodeCursor(cursor string) (time.Time, uuid.UUID, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, uuid.Nil, ErrInvalidCursor
+	}
+	nanos, idValue, ok := strings.Cut(string(raw), "_")
+	if !ok {
+		return time.Time{}, uuid.Nil, ErrInvalidCursor
+	}
+	unixNano, err := strconv.ParseInt(nanos, 10, 64)
+	if err != nil {
+		return time.Time{}, uuid.Nil, ErrInvalidCursor
+	}
+	id, err := uuid.Parse(idValue)
+	if err != nil {
+		return time.Time{}, uuid.Nil, ErrInvalidCursor
+	}
+	return time.Unix(0, unixNano), id, nil
+}
@@ -0,0 +1,254 @@
+package integrations
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/tests/testutils"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+func TestAPIKeys(t *testing.T) {
+	db, service := setupTestService(t)
+	berater := createTestUser(t, db, "berater@example.com", models.RoleBerater)
+	junior := createTestUser(t, db, "junior@example.com", models.RoleJuniorBerater)
+
+	key, plain, err := service.CreateAPIKey(berater, "Zapier", []models.APIKeyScope{models.APIKeyScopeLeadsRead, models.APIKeyScopeLeadsWrite}, nil)
+	require.NoError(t, err)
+	assert.Contains(t, plain, models.APIKeyPrefix)
+	assert.True(t, len(plain) > len(key.KeyPrefix))
+	assert.NotContains(t, key.KeyHash, plain)
+
+	authenticated, err := service.Authenticate(plain)
+	require.NoError(t, err)
+	assert.Equal(t, key.ID, authenticated.ID)
+	assert.Equal(t, berater.ID, authenticated.User.ID)
+	assert.NotNil(t, authenticated.LastUsedAt)
+	assert.True(t, service.Authorize(authenticated, models.APIKeyScopeLeadsRead))
+	assert.False(t, service.Authorize(authenticated, models.APIKeyScopeBookingsRead))
+
+	_, err = service.Authenticate(models.APIKeyPrefix + "unknown")
+	assert.ErrorIs(t, err, ErrInvalidAPIKey)
+	_, err = service.Authenticate("")
+	assert.ErrorIs(t, err, ErrInvalidAPIKey)
+
+	// Junior Beraters cannot create leads, so neither can their keys
+	_, _, err = service.CreateAPIKey(junior, "Make", []models.APIKeyScope{models.APIKeyScopeLeadsWrite}, nil)
+	assert.ErrorIs(t, err, ErrScopeNotPermitted)
+	_, _, err = service.CreateAPIKey(berater, "Make", []models.APIKeyScope{"leads:delete"}, nil)
+	assert.ErrorIs(t, err, ErrUnknownScope)
+
+	// Only the owner or an admin can revoke a key
+	assert.ErrorIs(t, service.RevokeAPIKey(key.ID, junior.ID, false), ErrAPIKeyNotFound)
+	require.NoError(t, service.RevokeAPIKey(key.ID, berater.ID, false))
+	assert.ErrorIs(t, service.RevokeAPIKey(key.ID, berater.ID, false), ErrAPIKeyNotFound)
+	_, err = service.Authenticate(plain)
+	assert.ErrorIs(t, err, ErrInvalidAPIKey)
+
+	keys, err := service.ListAPIKeys(berater.ID)
+	require.NoError(t, err)
+	require.Len(t, keys, 1)
+	assert.NotNil(t, keys[0].RevokedAt)
+
+	// Expired keys and keys of deactivated staff are rejected
+	expiresAt := time.Now().Add(-time.Hour)
+	_, expired, err := service.CreateAPIKey(berater, "Expired", []models.APIKeyScope{models.APIKeyScopeLeadsRead}, &expiresAt)
+	require.NoError(t, err)
+	_, err = service.Authenticate(expired)
+	assert.ErrorIs(t, err, ErrInvalidAPIKey)
+
+	_, active, err := service.CreateAPIKey(berater, "Active", []models.APIKeyScope{models.APIKeyScopeLeadsRead}, nil)
+	require.NoError(t, err)
+	require.NoError(t, db.Model(berater).Update("deactivated_at", time.Now()).Error)
+	_, err = service.Authenticate(active)
+	assert.ErrorIs(t, err, ErrInvalidAPIKey)
+}
+
+func TestNewLeads(t *testing.T) {
+	db, service := setupTestService(t)
+	berater := createTestUser(t, db, "berater@example.com", models.RoleBerater)
+	junior := createTestUser(t, db, "junior@example.com", models.RoleJuniorBerater)
+	customer := createTestUser(t, db, "kunde@example.com", models.RoleUser)
+
+	base := time.Date(2025, 3, 10, 9, 0, 0, 0, time.Local)
+	var leads []*models.Lead
+	for i := 0; i < 5; i++ {
+		lead := &models.Lead{
+			UserID:    customer.ID,
+			Title:     "Elterngeldantrag",
+			CreatedAt: base.Add(time.Duration(i) * time.Minute),
+		}
+		if i == 1 {
+			lead.BeraterID = &berater.ID
+		}
+		require.NoError(t, db.Create(lead).Error)
+		leads = append(leads, lead)
+	}
+
+	key := &models.APIKey{User: *berater}
+
+	// The first poll returns the most recent leads, oldest first
+	page, err := service.NewLeads(key, "", 2)
+	require.NoError(t, err)
+	require.Len(t, page.Items, 2)
+	assert.Equal(t, leads[3].ID, page.Items[0].ID)
+	assert.Equal(t, leads[4].ID, page.Items[1].ID)
+	assert.Equal(t, customer.Email, page.Items[0].User.Email)
+
+	// Nothing new since the last poll
+	empty, err := service.NewLeads(key, page.NextCursor, 2)
+	require.NoError(t, err)
+	assert.Empty(t, empty.Items)
+	assert.Equal(t, page.NextCursor, empty.NextCursor)
+
+	// New leads, also with the same creation time, are returned once
+	later := &models.Lead{UserID: customer.ID, Title: "Widerspruch", CreatedAt: leads[4].CreatedAt}
+	require.NoError(t, db.Create(later).Error)
+	newest := &models.Lead{UserID: customer.ID, Title: "Partnermonate", CreatedAt: base.Add(time.Hour)}
+	require.NoError(t, db.Create(newest).Error)
+
+	var seen []uuid.UUID
+	cursor := page.NextCursor
+	for {
+		next, err := service.NewLeads(key, cursor, 1)
+		require.NoError(t, err)
+		if len(next.Items) == 0 {
+			break
+		}
+		seen = append(seen, next.Items[0].ID)
+		cursor = next.NextCursor
+	}
+	expected := []uuid.UUID{newest.ID}
+	if later.ID.String() > leads[4].ID.String() {
+		expected = []uuid.UUID{later.ID, newest.ID}
+	}
+	assert.Equal(t, expected, seen)
+
+	// Junior Beraters only see their own and unassigned leads
+	juniorPage, err := service.NewLeads(&models.APIKey{User: *junior}, "", 10)
+	require.NoError(t, err)
+	for _, lead := range juniorPage.Items {
+		assert.NotEqual(t, leads[1].ID, lead.ID)
+	}
+	assert.Len(t, juniorPage.Items, 6)
+
+	_, err = service.NewLeads(key, "not-a-cursor", 10)
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+}
+
+func TestCreateLead(t *testing.T) {
+	db, service := setupTestService(t)
+	berater := createTestUser(t, db, "berater@example.com", models.RoleBerater)
+	customer := createTestUser(t, db, "kunde@example.com", models.RoleUser)
+	key := &models.APIKey{ID: uuid.New(), UserID: berater.ID, Name: "Typeform via Zapier", User: *berater}
+
+	lead, err := service.CreateLead(key, LeadInput{Email: "Kunde@Example.com", Title: "Elterngeldantrag"}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, customer.ID, lead.UserID)
+	assert.Equal(t, models.LeadSourceIntegration, lead.Source)
+	assert.Equal(t, "Typeform via Zapier", lead.SourceDetails)
+	assert.Equal(t, models.PriorityMedium, lead.Priority)
+
+	// Unknown customers are registered
+	lead, err = service.CreateLead(key, LeadInput{
+		Email:     "neu@example.com",
+		FirstName: "Anna",
+		LastName:  "Neu",
+		Title:     "Elterngeld Plus",
+		Priority:  models.PriorityHigh,
+	}, nil)
+	require.NoError(t, err)
+
+	var registered models.User
+	require.NoError(t, db.First(&registered, "email = ?", "neu@example.com").Error)
+	assert.Equal(t, lead.UserID, registered.ID)
+	assert.Equal(t, models.RoleUser, registered.Role)
+	assert.Equal(t, "Anna", registered.FirstName)
+	assert.NotEmpty(t, registered.Password)
+
+	var activityCount int64
+	db.Model(&models.Activity{}).Where("lead_id = ? AND type = ?", lead.ID, models.ActivityTypeLeadCreated).Count(&activityCount)
+	assert.Equal(t, int64(1), activityCount)
+
+	_, err = service.CreateLead(key, LeadInput{Email: berater.Email, Title: "Test"}, nil)
+	assert.ErrorIs(t, err, ErrStaffEmail)
+
+	// A failing record rolls the lead back
+	_, err = service.CreateLead(key, LeadInput{Email: "kunde@example.com", Title: "Zurückgerollt"}, func(tx *gorm.DB, lead *models.Lead) error {
+		return errors.New("outbox unavailable")
+	})
+	require.Error(t, err)
+	var leadCount int64
+	require.NoError(t, db.Model(&models.Lead{}).Where("title = ?", "Zurückgerollt").Count(&leadCount).Error)
+	assert.Zero(t, leadCount)
+}
+
+func TestAddComment(t *testing.T) {
+	db, service := setupTestService(t)
+	berater := createTestUser(t, db, "berater@example.com", models.RoleBerater)
+	junior := createTestUser(t, db, "junior@example.com", models.RoleJuniorBerater)
+	customer := createTestUser(t, db, "kunde@example.com", models.RoleUser)
+
+	lead := &models.Lead{UserID: customer.ID, Title: "Elterngeldantrag", BeraterID: &berater.ID}
+	require.NoError(t, db.Create(lead).Error)
+
+	key := &models.APIKey{UserID: berater.ID, User: *berater}
+	comment, err := service.AddComment(key, lead.ID, "Unterlagen per Post erhalten", true)
+	require.NoError(t, err)
+	assert.Equal(t, lead.ID, comment.LeadID)
+	assert.Equal(t, berater.ID, comment.UserID)
+	assert.True(t, comment.IsInternal)
+
+	var activityCount int64
+	db.Model(&models.Activity{}).Where("lead_id = ? AND type = ?", lead.ID, models.ActivityTypeCommentAdded).Count(&activityCount)
+	assert.Equal(t, int64(1), activityCount)
+
+	// The lead is assigned to another Berater
+	_, err = service.AddComment(&models.APIKey{UserID: junior.ID, User: *junior}, lead.ID, "Test", false)
+	assert.ErrorIs(t, err, ErrLeadNotFound)
+	_, err = service.AddComment(key, uuid.New(), "Test", false)
+	assert.ErrorIs(t, err, ErrLeadNotFound)
+}
+
+func TestCursor(t *testing.T) {
+	createdAt := time.Date(2025, 3, 10, 9, 0, 0, 123456789, time.UTC)
+	id := uuid.New()
+
+	decodedAt, decodedID, err := decodeCursor(encodeCursor(createdAt, id))
+	require.NoError(t, err)
+	assert.True(t, createdAt.Equal(decodedAt))
+	assert.Equal(t, id, decodedID)
+
+	for _, cursor := range []string{"%%%", "MTIz", encodeCursor(createdAt, id)[:10]} {
+		_, _, err := decodeCursor(cursor)
+		assert.ErrorIs(t, err, ErrInvalidCursor, cursor)
+	}
+}
+
+func setupTestService(t *testing.T) (*gorm.DB, *Service) {
+	t.Helper()
+	db := testutils.NewSQLiteDB(t,
+		&models.User{},
+		&models.Lead{},
+		&models.Comment{},
+		&models.Activity{},
+		&models.APIKey{},
+	)
+
+	return db, NewService(db, zap.NewNop())
+}
+
+func createTestUser(t *testing.T, db *gorm.DB, email string, role models.UserRole) *models.User {
+	t.Helper()
+	user := testutils.CreateTestUser(t, db, role)
+	require.NoError(t, db.Model(user).Update("email", email).Error)
+	user.Email = email
+	return user
+}
@@ -0,0 +1,398 @@
+// Package interviews schedules job interviews on the timeslot infrastructure
+// of the consultations. HR publishes interview slots, invites applicants with
+// a tokenized link and the applicant picks one of the open slots; the
+// application then moves to the interview stage.
+package interviews
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"elterngeld-portal/config"
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/pkg/ical"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// InvitationTTL is how long an applicant can use an invitation link
+const InvitationTTL = 14 * 24 * time.Hour
+
+// defaultTitle is the title of interview slots created without one
+const defaultTitle = "Vorstellungsgespräch"
+
+var (
+	// ErrSlotNotFound is returned for unknown interview slots
+	ErrSlotNotFound = errors.New("interview slot not found")
+	// ErrSlotInPast is returned when creating a slot that has already started
+	ErrSlotInPast = errors.New("interview slot must be in the future")
+	// ErrSlotBooked is returned when deleting a slot an applicant already picked
+	ErrSlotBooked = errors.New("interview slot is already booked")
+	// ErrSlotUnavailable is returned when the chosen slot is taken, started or not an interview slot
+	ErrSlotUnavailable = errors.New("interview slot is no longer available")
+	// ErrInvalidInterviewer is returned when the interviewer is not an active admin or Berater
+	ErrInvalidInterviewer = errors.New("interviewer must be an active staff member")
+	// ErrApplicationNotFound is returned for unknown applications
+	ErrApplicationNotFound = errors.New("application not found")
+	// ErrCannotInvite is returned for applications that are past the screening stage
+	ErrCannotInvite = errors.New("application cannot be invited to an interview")
+	// ErrInvitationNotFound is returned when no invitation matches a token
+	ErrInvitationNotFound = errors.New("interview invitation not found")
+	// ErrInvitationClosed is returned for used, replaced or expired invitations
+	ErrInvitationClosed = errors.New("interview invitation is no longer valid")
+)
+
+// SlotInput describes a new interview slot. Without an interviewer the
+// creating user conducts the interview.
+type SlotInput struct {
+	InterviewerID *uuid.UUID `json:"interviewer_id"`
+	StartTime     time.Time  `json:"start_time" binding:"required"`
+	Duration      int        `json:"duration" binding:"required,min=15,max=240"` // in minutes
+	Title         string     `json:"title" binding:"max=200"`
+	Location      string     `json:"location" binding:"max=255"`
+	IsOnline      bool       `json:"is_online"`
+}
+
+// Invitation is what the applicant sees when opening the invitation link
+type Invitation struct {
+	ApplicantName string                    `json:"applicant_name"`
+	JobTitle      string                    `json:"job_title"`
+	ExpiresAt     time.Time                 `json:"expires_at"`
+	Slots         []models.TimeslotResponse `json:"slots"`
+}
+
+// ScheduledInterview is an interview an applicant booked
+type ScheduledInterview struct {
+	Invitation  *models.InterviewInvitation
+	Application *models.JobApplication
+	Job         *models.Job
+	Slot        *models.Timeslot
+	Interviewer *models.User
+}
+
+// Service manages interview slots and invitations
+type Service struct {
+	db     *gorm.DB
+	logger *zap.Logger
+	host   string
+	now    func() time.Time
+}
+
+func NewService(db *gorm.DB, logger *zap.Logger, cfg *config.Config) *Service {
+	host := strings.TrimRight(cfg.App.BaseURL, "/")
+	host = strings.TrimPrefix(strings.TrimPrefix(host, "https://"), "http://")
+	return &Service{
+		db:     db,
+		logger: logger,
+		host:   host,
+		now:    time.Now,
+	}
+}
+
+// CreateSlot publishes an interview slot
+func (s *Service) CreateSlot(input SlotInput, createdBy uuid.UUID) (*models.Timeslot, error) {
+	interviewerID := createdBy
+	if input.InterviewerID != nil {
+		interviewerID = *input.InterviewerID
+	}
+	var interviewer models.User
+	if err := s.db.Where("id = ?", interviewerID).Limit(1).Find(&interviewer).Error; err != nil {
+		return nil, err
+	}
+	if !interviewer.IsActive || (!interviewer.IsAdmin() && !interviewer.IsBerater() && !interviewer.IsJuniorBerater()) {
+		return nil, ErrInvalidInterviewer
+	}
+	if !input.StartTime.After(s.now()) {
+		return nil, ErrSlotInPast
+	}
+
+	title := strings.TrimSpace(input.Title)
+	if title == "" {
+		title = defaultTitle
+	}
+	slot := &models.Timeslot{
+		BeraterID:   interviewer.ID,
+		StartTime:   input.StartTime,
+		EndTime:     input.StartTime.Add(time.Duration(input.Duration) * time.Minute),
+		Duration:    input.Duration,
+		Timezone:    interviewer.Timezone,
+		Purpose:     models.TimeslotPurposeInterview,
+		IsAvailable: true,
+		MaxBookings: 1,
+		Title:       title,
+		Location:    strings.TrimSpace(input.Location),
+		IsOnline:    input.IsOnline,
+	}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(slot).Error; err != nil {
+			return err
+		}
+		// The column defaults to online, which GORM applies for false
+		if !input.IsOnline {
+			return tx.Model(slot).Update("is_online", false).Error
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create interview slot: %w", err)
+	}
+	return slot, nil
+}
+
+// ListSlots returns the interview slots starting from the given time, earliest first
+func (s *Service) ListSlots(from time.Time, openOnly bool) ([]models.Timeslot, error) {
+	query := s.db.Where("purpose = ? AND start_time >= ?", models.TimeslotPurposeInterview, from)
+	if openOnly {
+		query = query.Where("is_available = ? AND current_bookings < max_bookings", true)
+	}
+
+	slots := []models.Timeslot{}
+	if err := query.Preload("Berater").Order("start_time ASC").Find(&slots).Error; err != nil {
+		return nil, fmt.Errorf("failed to load interview slots: %w", err)
+	}
+	return slots, nil
+}
+
+// DeleteSlot removes an interview slot nobody picked yet
+func (s *Service) DeleteSlot(id uuid.UUID) error {
+	var slot models.Timeslot
+	if err := s.db.Where("id = ? AND purpose = ?", id, models.TimeslotPurposeInterview).First(&slot).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrSlotNotFound
+		}
+		return err
+	}
+	if slot.CurrentBookings > 0 {
+		return ErrSlotBooked
+	}
+
+	if err := s.db.Delete(&slot).Error; err != nil {
+		return fmt.Errorf("failed to delete interview slot: %w", err)
+	}
+	return nil
+}
+
+// Invite creates an invitation link for an application and returns it with
+// its token. Earlier invitations of the application stop working.
+func (s *Service) Invite(applicationID, invitedBy uuid.UUID) (*models.InterviewInvitation, string, error) {
+	var application models.JobApplication
+	if err := s.db.Preload("Job").First(&application, "id = ?", applicationID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, "", ErrApplicationNotFound
+		}
+		return nil, "", err
+	}
+	if !application.Status.CanBeInvited() {
+		return nil, "", ErrCannotInvite
+	}
+
+	token, err := generateToken()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to generate invitation token: %w", err)
+	}
+
+	now := s.now()
+	invitation := &models.InterviewInvitation{
+		ApplicationID: application.ID,
+		TokenHash:     models.HashInvitationToken(token),
+		ExpiresAt:     now.Add(InvitationTTL),
+		InvitedBy:     invitedBy,
+	}
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.InterviewInvitation{}).
+			Where("application_id = ? AND scheduled_at IS NULL AND revoked_at IS NULL", application.ID).
+			Update("revoked_at", &now).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(invitation).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.JobApplicationActivity{
+			ApplicationID: application.ID,
+			UserID:        &invitedBy,
+			Type:          "interview_invited",
+			Description:   "Einladung zum Vorstellungsgespräch versendet",
+			CreatedAt:     now,
+		}).Error
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create interview invitation: %w", err)
+	}
+	invitation.Application = &application
+	return invitation, token, nil
+}
+
+// Open returns the invitation of a token with the slots the applicant can pick
+func (s *Service) Open(token string) (*Invitation, error) {
+	invitation, err := s.find(s.db, token)
+	if err != nil {
+		return nil, err
+	}
+
+	var application models.JobApplication
+	if err := s.db.Preload("Job").First(&application, "id = ?", invitation.ApplicationID).Error; err != nil {
+		return nil, fmt.Errorf("failed to load application: %w", err)
+	}
+
+	var slots []models.Timeslot
+	if err := s.db.Where("purpose = ? AND is_available = ? AND current_bookings < max_bookings AND start_time > ?",
+		models.TimeslotPurposeInterview, true, s.now()).
+		Order("start_time ASC").Find(&slots).Error; err != nil {
+		return nil, fmt.Errorf("failed to load interview slots: %w", err)
+	}
+
+	result := &Invitation{
+		ApplicantName: application.FirstName + " " + application.LastName,
+		JobTitle:      application.Job.Title,
+		ExpiresAt:     invitation.ExpiresAt,
+		Slots:         make([]models.TimeslotResponse, len(slots)),
+	}
+	for i := range slots {
+		result.Slots[i] = slots[i].ToResponse()
+	}
+	return result, nil
+}
+
+// Schedule books the chosen slot for the applicant of an invitation and moves
+// the application to the interview stage
+func (s *Service) Schedule(token string, slotID uuid.UUID) (*ScheduledInterview, error) {
+	now := s.now()
+	result := &ScheduledInterview{}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		invitation, err := s.find(tx, token)
+		if err != nil {
+			return err
+		}
+
+		var application models.JobApplication
+		if err := tx.Preload("Job").First(&application, "id = ?", invitation.ApplicationID).Error; err != nil {
+			return fmt.Errorf("failed to load application: %w", err)
+		}
+		if !application.Status.CanBeInvited() {
+			return ErrCannotInvite
+		}
+
+		// Claiming the slot and the invitation in single updates keeps two
+		// applicants from picking the same slot and a link from being used twice
+		claim := tx.Model(&models.Timeslot{}).
+			Where("id = ? AND purpose = ? AND is_available = ? AND current_bookings < max_bookings AND start_time > ?",
+				slotID, models.TimeslotPurposeInterview, true, now).
+			Update("current_bookings", gorm.Expr("current_bookings + 1"))
+		if claim.Error != nil {
+			return claim.Error
+		}
+		if claim.RowsAffected == 0 {
+			return ErrSlotUnavailable
+		}
+
+		use := tx.Model(&models.InterviewInvitation{}).
+			Where("id = ? AND scheduled_at IS NULL AND revoked_at IS NULL", invitation.ID).
+			Updates(map[string]interface{}{"timeslot_id": slotID, "scheduled_at": &now})
+		if use.Error != nil {
+			return use.Error
+		}
+		if use.RowsAffected == 0 {
+			return ErrInvitationClosed
+		}
+		invitation.TimeslotID = &slotID
+		invitation.ScheduledAt = &now
+
+		var slot models.Timeslot
+		if err := tx.Preload("Berater").First(&slot, "id = ?", slotID).Error; err != nil {
+			return err
+		}
+
+		previous := application.Status
+		if err := tx.Model(&application).Updates(map[string]interface{}{
+			"status":              models.ApplicationStatusInterview,
+			"interview_scheduled": true,
+			"interview_date":      slot.StartTime,
+			"last_contact_at":     &now,
+		}).Error; err != nil {
+			return err
+		}
+		application.Status = models.ApplicationStatusInterview
+		application.InterviewScheduled = true
+		application.InterviewDate = &slot.StartTime
+
+		if err := tx.Create(&models.JobApplicationActivity{
+			ApplicationID: application.ID,
+			Type:          "status_change",
+			Description:   "Termin für das Vorstellungsgespräch gewählt",
+			OldValue:      string(previous),
+			NewValue:      string(models.ApplicationStatusInterview),
+			CreatedAt:     now,
+		}).Error; err != nil {
+			return err
+		}
+
+		result.Invitation = invitation
+		result.Application = &application
+		result.Job = &application.Job
+		result.Slot = &slot
+		result.Interviewer = &slot.Berater
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// CalendarInvite renders the calendar invitation sent to the applicant and the interviewer
+func (s *Service) CalendarInvite(interview *ScheduledInterview) []byte {
+	applicant := ical.Attendee{
+		Name:  interview.Application.FirstName + " " + interview.Application.LastName,
+		Email: interview.Application.Email,
+	}
+	interviewer := ical.Attendee{
+		Name:  interview.Interviewer.FullName(),
+		Email: interview.Interviewer.Email,
+	}
+
+	location := interview.Slot.Location
+	if location == "" && interview.Slot.IsOnline {
+		location = "Online"
+	}
+	return ical.Invite(ical.Event{
+		UID:         fmt.Sprintf("interview-%s@%s", interview.Invitation.ID, s.host),
+		Summary:     fmt.Sprintf("%s: %s", interview.Slot.Title, interview.Job.Title),
+		Description: fmt.Sprintf("%s mit %s für die Stelle %s", interview.Slot.Title, applicant.Name, interview.Job.Title),
+		Location:    location,
+		Start:       interview.Slot.StartTime,
+		End:         interview.Slot.EndTime,
+		Organizer:   interviewer,
+		Attendees:   []ical.Attendee{applicant, interviewer},
+		Created:     *interview.Invitation.ScheduledAt,
+	})
+}
+
+// find returns the open invitation of a token
+func (s *Service) find(db *gorm.DB, token string) (*models.InterviewInvitation, error) {
+	var invitation models.InterviewInvitation
+	if err := db.Where("token_hash = ?", models.HashInvitationToken(token)).First(&invitation).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvitationNotFound
+		}
+		return nil, err
+	}
+	if !invitation.IsOpen(s.now()) {
+		return nil, ErrInvitationClosed
+	}
+	return &invitation, nil
+}
+
+func generateToken() (string, error) {
+	bytes := make([]byte, 32)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(bytes), nil
+}
@@ -0,0 +1,191 @@
+package interviews
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"elterngeld-portal/config"
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/tests/testutils"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+func TestCreateSlot(t *testing.T) {
+	service, db := setupTestService(t)
+	admin := testutils.CreateTestUser(t, db, models.RoleAdmin)
+	customer := testutils.CreateTestUser(t, db, models.RoleUser)
+	start := time.Now().Add(48 * time.Hour).Truncate(time.Second)
+
+	slot, err := service.CreateSlot(SlotInput{StartTime: start, Duration: 45, Location: "Büro München"}, admin.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.TimeslotPurposeInterview, slot.Purpose)
+	assert.Equal(t, admin.ID, slot.BeraterID)
+	assert.Equal(t, defaultTitle, slot.Title)
+	assert.True(t, slot.EndTime.Equal(start.Add(45*time.Minute)))
+
+	var stored models.Timeslot
+	require.NoError(t, db.First(&stored, "id = ?", slot.ID).Error)
+	assert.False(t, stored.IsOnline)
+
+	_, err = service.CreateSlot(SlotInput{InterviewerID: &customer.ID, StartTime: start, Duration: 30}, admin.ID)
+	assert.ErrorIs(t, err, ErrInvalidInterviewer)
+
+	_, err = service.CreateSlot(SlotInput{StartTime: time.Now().Add(-time.Hour), Duration: 30}, admin.ID)
+	assert.ErrorIs(t, err, ErrSlotInPast)
+}
+
+func TestInvite(t *testing.T) {
+	service, db := setupTestService(t)
+	admin := testutils.CreateTestUser(t, db, models.RoleAdmin)
+	application := createApplication(t, db, models.ApplicationStatusScreening)
+
+	first, firstToken, err := service.Invite(application.ID, admin.ID)
+	require.NoError(t, err)
+	assert.Len(t, firstToken, 64)
+	assert.Equal(t, models.HashInvitationToken(firstToken), first.TokenHash)
+
+	_, secondToken, err := service.Invite(application.ID, admin.ID)
+	require.NoError(t, err)
+
+	_, err = service.Open(firstToken)
+	assert.ErrorIs(t, err, ErrInvitationClosed)
+	_, err = service.Open("unknown")
+	assert.ErrorIs(t, err, ErrInvitationNotFound)
+
+	invitation, err := service.Open(secondToken)
+	require.NoError(t, err)
+	assert.Equal(t, "Erika Muster", invitation.ApplicantName)
+	assert.Equal(t, "Elterngeldberater (m/w/d)", invitation.JobTitle)
+
+	rejected := createApplication(t, db, models.ApplicationStatusRejected)
+	_, _, err = service.Invite(rejected.ID, admin.ID)
+	assert.ErrorIs(t, err, ErrCannotInvite)
+
+	_, _, err = service.Invite(uuid.New(), admin.ID)
+	assert.ErrorIs(t, err, ErrApplicationNotFound)
+}
+
+func TestSchedule(t *testing.T) {
+	service, db := setupTestService(t)
+	admin := testutils.CreateTestUser(t, db, models.RoleAdmin)
+	start := time.Now().Add(72 * time.Hour).Truncate(time.Second)
+	slot, err := service.CreateSlot(SlotInput{StartTime: start, Duration: 60, IsOnline: true}, admin.ID)
+	require.NoError(t, err)
+
+	application := createApplication(t, db, models.ApplicationStatusReviewing)
+	_, token, err := service.Invite(application.ID, admin.ID)
+	require.NoError(t, err)
+
+	invitation, err := service.Open(token)
+	require.NoError(t, err)
+	require.Len(t, invitation.Slots, 1)
+
+	interview, err := service.Schedule(token, slot.ID)
+	require.NoError(t, err)
+	assert.Equal(t, admin.ID, interview.Interviewer.ID)
+
+	var stored models.JobApplication
+	require.NoError(t, db.First(&stored, "id = ?", application.ID).Error)
+	assert.Equal(t, models.ApplicationStatusInterview, stored.Status)
+	assert.True(t, stored.InterviewScheduled)
+	require.NotNil(t, stored.InterviewDate)
+	assert.True(t, stored.InterviewDate.Equal(start))
+
+	var activity models.JobApplicationActivity
+	require.NoError(t, db.Where("application_id = ? AND type = ?", application.ID, "status_change").First(&activity).Error)
+	assert.Equal(t, string(models.ApplicationStatusReviewing), activity.OldValue)
+
+	invite := string(service.CalendarInvite(interview))
+	assert.Contains(t, invite, fmt.Sprintf("UID:interview-%s@elterngeld.example", interview.Invitation.ID))
+	assert.Contains(t, invite, "mailto:erika@example.com")
+	assert.Contains(t, invite, "LOCATION:Online")
+
+	// The link is used up and the slot is taken
+	_, err = service.Schedule(token, slot.ID)
+	assert.ErrorIs(t, err, ErrInvitationClosed)
+
+	other := createApplication(t, db, models.ApplicationStatusSubmitted)
+	_, otherToken, err := service.Invite(other.ID, admin.ID)
+	require.NoError(t, err)
+	_, err = service.Schedule(otherToken, slot.ID)
+	assert.ErrorIs(t, err, ErrSlotUnavailable)
+
+	err = service.DeleteSlot(slot.ID)
+	assert.ErrorIs(t, err, ErrSlotBooked)
+}
+
+func TestScheduleRejectsConsultationSlots(t *testing.T) {
+	service, db := setupTestService(t)
+	admin := testutils.CreateTestUser(t, db, models.RoleAdmin)
+	berater := testutils.CreateTestUser(t, db, models.RoleBerater)
+	start := time.Now().Add(24 * time.Hour)
+	consultation := &models.Timeslot{
+		BeraterID:   berater.ID,
+		StartTime:   start,
+		EndTime:     start.Add(time.Hour),
+		Duration:    60,
+		IsAvailable: true,
+		MaxBookings: 1,
+	}
+	require.NoError(t, db.Create(consultation).Error)
+	assert.Equal(t, models.TimeslotPurposeConsultation, consultation.Purpose)
+
+	application := createApplication(t, db, models.ApplicationStatusSubmitted)
+	_, token, err := service.Invite(application.ID, admin.ID)
+	require.NoError(t, err)
+
+	invitation, err := service.Open(token)
+	require.NoError(t, err)
+	assert.Empty(t, invitation.Slots)
+
+	_, err = service.Schedule(token, consultation.ID)
+	assert.ErrorIs(t, err, ErrSlotUnavailable)
+
+	err = service.DeleteSlot(consultation.ID)
+	assert.ErrorIs(t, err, ErrSlotNotFound)
+}
+
+func setupTestService(t *testing.T) (*Service, *gorm.DB) {
+	t.Helper()
+	db := testutils.NewSQLiteDB(t,
+		&models.User{},
+		&models.Job{},
+		&models.JobApplication{},
+		&models.JobApplicationActivity{},
+		&models.Timeslot{},
+		&models.InterviewInvitation{},
+	)
+
+	cfg := &config.Config{App: config.AppConfig{BaseURL: "https://elterngeld.example/"}}
+	return NewService(db, zap.NewNop(), cfg), db
+}
+
+func createApplication(t *testing.T, db *gorm.DB, status models.ApplicationStatus) *models.JobApplication {
+	t.Helper()
+	job := &models.Job{
+		Title:       "Elterngeldberater (m/w/d)",
+		Slug:        uuid.New().String(),
+		Description: "...",
+		Type:        models.JobTypeFullTime,
+		Level:       models.JobLevelMid,
+		Location:    "München",
+		CreatedBy:   uuid.New(),
+	}
+	require.NoError(t, db.Create(job).Error)
+
+	application := &models.JobApplication{
+		JobID:     job.ID,
+		FirstName: "Erika",
+		LastName:  "Muster",
+		Email:     "erika@example.com",
+		Status:    status,
+	}
+	require.NoError(t, db.Create(application).Error)
+	return application
+}
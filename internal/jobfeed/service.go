@@ -0,0 +1,364 @@
+// Package jobfeed publishes the open job postings to aggregators: an XML feed
+// in the format crawled by Indeed and schema.org JobPosting documents that
+// Google for Jobs reads from the job pages.
+package jobfeed
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"elterngeld-portal/config"
+	"elterngeld-portal/internal/models"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// country is the ISO code of the country all positions are in
+const country = "DE"
+
+var (
+	// ErrJobNotFound is returned for unknown jobs and jobs that are not open
+	ErrJobNotFound = errors.New("job not found")
+)
+
+// Service renders the job feeds
+type Service struct {
+	db          *gorm.DB
+	logger      *zap.Logger
+	baseURL     string
+	companyName string
+	logoURL     string
+	now         func() time.Time
+}
+
+func NewService(db *gorm.DB, logger *zap.Logger, cfg *config.Config) *Service {
+	return &Service{
+		db:          db,
+		logger:      logger,
+		baseURL:     strings.TrimRight(cfg.App.BaseURL, "/"),
+		companyName: cfg.App.CompanyName,
+		logoURL:     cfg.App.LogoURL,
+		now:         time.Now,
+	}
+}
+
+// Open returns the published jobs that are neither expired nor past their
+// application deadline, newest first
+func (s *Service) Open() ([]models.Job, error) {
+	jobs := []models.Job{}
+	if err := s.open().Order("published_at DESC").Find(&jobs).Error; err != nil {
+		return nil, fmt.Errorf("failed to load jobs: %w", err)
+	}
+	return jobs, nil
+}
+
+// OpenBySlug returns an open job
+func (s *Service) OpenBySlug(slug string) (*models.Job, error) {
+	var job models.Job
+	if err := s.open().Where("slug = ?", slug).First(&job).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrJobNotFound
+		}
+		return nil, err
+	}
+	return &job, nil
+}
+
+func (s *Service) open() *gorm.DB {
+	now := s.now()
+	return s.db.Where("status = ? AND published_at IS NOT NULL", models.JobStatusPublished).
+		Where("expires_at IS NULL OR expires_at > ?", now).
+		Where("application_deadline IS NULL OR application_deadline > ?", now)
+}
+
+type indeedSource struct {
+	XMLName       xml.Name    `xml:"source"`
+	Publisher     string      `xml:"publisher"`
+	PublisherURL  string      `xml:"publisherurl"`
+	LastBuildDate string      `xml:"lastBuildDate"`
+	Jobs          []indeedJob `xml:"job"`
+}
+
+type indeedJob struct {
+	Title           cdata  `xml:"title"`
+	Date            cdata  `xml:"date"`
+	ReferenceNumber cdata  `xml:"referencenumber"`
+	URL             cdata  `xml:"url"`
+	Company         cdata  `xml:"company"`
+	City            cdata  `xml:"city"`
+	Country         cdata  `xml:"country"`
+	Email           *cdata `xml:"email,omitempty"`
+	Description     cdata  `xml:"description"`
+	Salary          *cdata `xml:"salary,omitempty"`
+	Education       *cdata `xml:"education,omitempty"`
+	JobType         cdata  `xml:"jobtype"`
+	Category        *cdata `xml:"category,omitempty"`
+	Experience      *cdata `xml:"experience,omitempty"`
+	RemoteType      *cdata `xml:"remotetype,omitempty"`
+	Expiration      *cdata `xml:"expirationdate,omitempty"`
+}
+
+// cdata wraps values in CDATA sections as the Indeed format asks for
+type cdata struct {
+	Value string `xml:",cdata"`
+}
+
+// optional returns nil for empty values so their element is left out
+func optional(value string) *cdata {
+	if value == "" {
+		return nil
+	}
+	return &cdata{Value: value}
+}
+
+// IndeedXML renders the open jobs as Indeed XML feed
+func (s *Service) IndeedXML() ([]byte, error) {
+	jobs, err := s.Open()
+	if err != nil {
+		return nil, err
+	}
+
+	feed := indeedSource{
+		Publisher:     s.companyName,
+		PublisherURL:  s.baseURL,
+		LastBuildDate: s.now().UTC().Format(time.RFC1123),
+	}
+	for i := range jobs {
+		job := &jobs[i]
+		entry := indeedJob{
+			Title:           cdata{job.Title},
+			Date:            cdata{job.PublishedAt.UTC().Format(time.RFC1123)},
+			ReferenceNumber: cdata{job.ID.String()},
+			URL:             cdata{s.jobURL(job)},
+			Company:         cdata{s.companyName},
+			City:            cdata{job.Location},
+			Country:         cdata{country},
+			Email:           optional(job.ContactEmail),
+			Description:     cdata{job.Description},
+			Salary:          optional(indeedSalary(job)),
+			Education:       optional(job.EducationRequired),
+			JobType:         cdata{indeedJobType(job.Type)},
+			Category:        optional(job.Department),
+			Experience:      optional(job.RequiredExperience),
+		}
+		if job.WorkLocation == models.WorkLocationRemote || job.IsRemote {
+			entry.RemoteType = optional("Fully remote")
+		} else if job.WorkLocation == models.WorkLocationHybrid {
+			entry.RemoteType = optional("Hybrid remote")
+		}
+		if validThrough := validThrough(job); validThrough != nil {
+			entry.Expiration = optional(validThrough.Format("2006-01-02"))
+		}
+		feed.Jobs = append(feed.Jobs, entry)
+	}
+
+	body, err := xml.MarshalIndent(feed, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to render job feed: %w", err)
+	}
+	return append([]byte(xml.Header), body...), nil
+}
+
+// JobPosting is a schema.org JobPosting as read by Google for Jobs
+type JobPosting struct {
+	Context            string          `json:"@context"`
+	Type               string          `json:"@type"`
+	Title              string          `json:"title"`
+	Description        string          `json:"description"`
+	Identifier         propertyValue   `json:"identifier"`
+	DatePosted         string          `json:"datePosted"`
+	ValidThrough       string          `json:"validThrough,omitempty"`
+	EmploymentType     string          `json:"employmentType"`
+	HiringOrganization organization    `json:"hiringOrganization"`
+	JobLocation        *place          `json:"jobLocation,omitempty"`
+	JobLocationType    string          `json:"jobLocationType,omitempty"`
+	ApplicantLocation  *countryValue   `json:"applicantLocationRequirements,omitempty"`
+	BaseSalary         *monetaryAmount `json:"baseSalary,omitempty"`
+	DirectApply        bool            `json:"directApply"`
+	URL                string          `json:"url"`
+	Industry           string          `json:"industry,omitempty"`
+	EducationRequired  string          `json:"educationRequirements,omitempty"`
+	ExperienceRequired string          `json:"experienceRequirements,omitempty"`
+}
+
+type propertyValue struct {
+	Type  string `json:"@type"`
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type organization struct {
+	Type   string `json:"@type"`
+	Name   string `json:"name"`
+	SameAs string `json:"sameAs"`
+	Logo   string `json:"logo,omitempty"`
+}
+
+type place struct {
+	Type    string        `json:"@type"`
+	Address postalAddress `json:"address"`
+}
+
+type postalAddress struct {
+	Type            string `json:"@type"`
+	AddressLocality string `json:"addressLocality"`
+	AddressCountry  string `json:"addressCountry"`
+}
+
+type countryValue struct {
+	Type string `json:"@type"`
+	Name string `json:"name"`
+}
+
+type monetaryAmount struct {
+	Type     string            `json:"@type"`
+	Currency string            `json:"currency"`
+	Value    quantitativeValue `json:"value"`
+}
+
+type quantitativeValue struct {
+	Type     string   `json:"@type"`
+	MinValue *float64 `json:"minValue,omitempty"`
+	MaxValue *float64 `json:"maxValue,omitempty"`
+	UnitText string   `json:"unitText"`
+}
+
+// JobPosting returns the JSON-LD document of an open job
+func (s *Service) JobPosting(slug string) (*JobPosting, error) {
+	job, err := s.OpenBySlug(slug)
+	if err != nil {
+		return nil, err
+	}
+
+	posting := &JobPosting{
+		Context:     "https://schema.org/",
+		Type:        "JobPosting",
+		Title:       job.Title,
+		Description: job.Description,
+		Identifier: propertyValue{
+			Type:  "PropertyValue",
+			Name:  s.companyName,
+			Value: job.ID.String(),
+		},
+		DatePosted:     job.PublishedAt.UTC().Format("2006-01-02"),
+		EmploymentType: schemaEmploymentType(job.Type),
+		HiringOrganization: organization{
+			Type:   "Organization",
+			Name:   s.companyName,
+			SameAs: s.baseURL,
+			Logo:   s.logoURL,
+		},
+		DirectApply:        job.AllowDirectApply,
+		URL:                s.jobURL(job),
+		Industry:           job.Department,
+		EducationRequired:  job.EducationRequired,
+		ExperienceRequired: job.RequiredExperience,
+	}
+	if validThrough := validThrough(job); validThrough != nil {
+		posting.ValidThrough = validThrough.UTC().Format(time.RFC3339)
+	}
+
+	remote := job.WorkLocation == models.WorkLocationRemote || job.IsRemote
+	if remote {
+		posting.JobLocationType = "TELECOMMUTE"
+		posting.ApplicantLocation = &countryValue{Type: "Country", Name: country}
+	}
+	if !remote || job.Location != "" {
+		posting.JobLocation = &place{
+			Type: "Place",
+			Address: postalAddress{
+				Type:            "PostalAddress",
+				AddressLocality: job.Location,
+				AddressCountry:  country,
+			},
+		}
+	}
+
+	if job.SalaryMin != nil || job.SalaryMax != nil {
+		currency := job.SalaryCurrency
+		if currency == "" {
+			currency = "EUR"
+		}
+		posting.BaseSalary = &monetaryAmount{
+			Type:     "MonetaryAmount",
+			Currency: currency,
+			Value: quantitativeValue{
+				Type:     "QuantitativeValue",
+				MinValue: job.SalaryMin,
+				MaxValue: job.SalaryMax,
+				UnitText: salaryUnit(job.SalaryPeriod),
+			},
+		}
+	}
+	return posting, nil
+}
+
+func (s *Service) jobURL(job *models.Job) string {
+	return s.baseURL + "/jobs/" + job.Slug
+}
+
+// validThrough returns the earlier of expiry and application deadline
+func validThrough(job *models.Job) *time.Time {
+	result := job.ExpiresAt
+	if job.ApplicationDeadline != nil && (result == nil || job.ApplicationDeadline.Before(*result)) {
+		result = job.ApplicationDeadline
+	}
+	return result
+}
+
+func indeedJobType(jobType models.JobType) string {
+	switch jobType {
+	case models.JobTypeFullTime:
+		return "fulltime"
+	case models.JobTypePartTime:
+		return "parttime"
+	case models.JobTypeInternship:
+		return "internship"
+	default:
+		return "contract"
+	}
+}
+
+func schemaEmploymentType(jobType models.JobType) string {
+	switch jobType {
+	case models.JobTypeFullTime:
+		return "FULL_TIME"
+	case models.JobTypePartTime:
+		return "PART_TIME"
+	case models.JobTypeInternship:
+		return "INTERN"
+	default:
+		return "CONTRACTOR"
+	}
+}
+
+func salaryUnit(period string) string {
+	switch period {
+	case "monthly":
+		return "MONTH"
+	case "hourly":
+		return "HOUR"
+	default:
+		return "YEAR"
+	}
+}
+
+// indeedSalary formats the salary like "50000 - 60000 EUR pro Jahr"
+func indeedSalary(job *models.Job) string {
+	salary := job.FormatSalary()
+	if salary == "" {
+		return ""
+	}
+	switch job.SalaryPeriod {
+	case "monthly":
+		return salary + " pro Monat"
+	case "hourly":
+		return salary + " pro Stunde"
+	default:
+		return salary + " pro Jahr"
+	}
+}
@@ -0,0 +1,127 @@
+package jobfeed
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"elterngeld-portal/config"
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/tests/testutils"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+func TestOpen(t *testing.T) {
+	service, db := setupTestService(t)
+	past := time.Now().Add(-time.Hour)
+
+	createJob(t, db, &models.Job{Slug: "offen", Status: models.JobStatusPublished, PublishedAt: &past})
+	createJob(t, db, &models.Job{Slug: "entwurf", Status: models.JobStatusDraft})
+	createJob(t, db, &models.Job{Slug: "abgelaufen", Status: models.JobStatusPublished, PublishedAt: &past, ExpiresAt: &past})
+	createJob(t, db, &models.Job{Slug: "bewerbungsschluss", Status: models.JobStatusPublished, PublishedAt: &past, ApplicationDeadline: &past})
+
+	jobs, err := service.Open()
+	require.NoError(t, err)
+	require.Len(t, jobs, 1)
+	assert.Equal(t, "offen", jobs[0].Slug)
+
+	_, err = service.JobPosting("abgelaufen")
+	assert.ErrorIs(t, err, ErrJobNotFound)
+}
+
+func TestIndeedXML(t *testing.T) {
+	service, db := setupTestService(t)
+	past := time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)
+	salaryMin, salaryMax := 3500.0, 4200.0
+	createJob(t, db, &models.Job{
+		Slug:         "elterngeldberater-muenchen",
+		Status:       models.JobStatusPublished,
+		PublishedAt:  &past,
+		Type:         models.JobTypePartTime,
+		Description:  "<p>Beratung von Eltern & Familien</p>",
+		SalaryMin:    &salaryMin,
+		SalaryMax:    &salaryMax,
+		SalaryPeriod: "monthly",
+		WorkLocation: models.WorkLocationHybrid,
+	})
+
+	feed, err := service.IndeedXML()
+	require.NoError(t, err)
+	assert.Contains(t, string(feed), "<publisher>Elterngeld Portal</publisher>")
+	assert.Contains(t, string(feed), "<url><![CDATA[https://elterngeld.example/jobs/elterngeldberater-muenchen]]></url>")
+	assert.Contains(t, string(feed), "<description><![CDATA[<p>Beratung von Eltern & Familien</p>]]></description>")
+	assert.Contains(t, string(feed), "<jobtype><![CDATA[parttime]]></jobtype>")
+	assert.Contains(t, string(feed), "<salary><![CDATA[3500 - 4200 EUR pro Monat]]></salary>")
+	assert.Contains(t, string(feed), "<remotetype><![CDATA[Hybrid remote]]></remotetype>")
+}
+
+func TestJobPosting(t *testing.T) {
+	service, db := setupTestService(t)
+	past := time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)
+	deadline := time.Now().Add(48 * time.Hour).UTC().Truncate(time.Second)
+	expires := deadline.Add(24 * time.Hour)
+	salaryMin := 52000.0
+	job := createJob(t, db, &models.Job{
+		Slug:                "remote-berater",
+		Status:              models.JobStatusPublished,
+		PublishedAt:         &past,
+		Type:                models.JobTypeFreelance,
+		Location:            "",
+		WorkLocation:        models.WorkLocationRemote,
+		SalaryMin:           &salaryMin,
+		ApplicationDeadline: &deadline,
+		ExpiresAt:           &expires,
+	})
+
+	posting, err := service.JobPosting("remote-berater")
+	require.NoError(t, err)
+
+	data, err := json.Marshal(posting)
+	require.NoError(t, err)
+	var document map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &document))
+
+	assert.Equal(t, "JobPosting", document["@type"])
+	assert.Equal(t, "2025-03-01", document["datePosted"])
+	assert.Equal(t, deadline.Format(time.RFC3339), document["validThrough"])
+	assert.Equal(t, "CONTRACTOR", document["employmentType"])
+	assert.Equal(t, "TELECOMMUTE", document["jobLocationType"])
+	assert.NotContains(t, document, "jobLocation")
+	assert.Equal(t, job.ID.String(), document["identifier"].(map[string]interface{})["value"])
+	assert.Equal(t, map[string]interface{}{
+		"@type":    "MonetaryAmount",
+		"currency": "EUR",
+		"value":    map[string]interface{}{"@type": "QuantitativeValue", "minValue": 52000.0, "unitText": "YEAR"},
+	}, document["baseSalary"])
+}
+
+func setupTestService(t *testing.T) (*Service, *gorm.DB) {
+	t.Helper()
+	db := testutils.NewSQLiteDB(t, &models.Job{})
+
+	cfg := &config.Config{App: config.AppConfig{BaseURL: "https://elterngeld.example/", CompanyName: "Elterngeld Portal"}}
+	return NewService(db, zap.NewNop(), cfg), db
+}
+
+func createJob(t *testing.T, db *gorm.DB, job *models.Job) *models.Job {
+	t.Helper()
+	job.Title = "Elterngeldberater (m/w/d)"
+	job.CreatedBy = uuid.New()
+	if job.Type == "" {
+		job.Type = models.JobTypeFullTime
+	}
+	job.Level = models.JobLevelMid
+	if job.Location == "" && job.WorkLocation != models.WorkLocationRemote {
+		job.Location = "München"
+	}
+	if job.Description == "" {
+		job.Description = "..."
+	}
+	require.NoError(t, db.Create(job).Error)
+	return job
+}
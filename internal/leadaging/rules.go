@@ -0,0 +1,158 @@
+package leadaging
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"elterngeld-portal/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrRuleNotFound is returned for unknown lead aging rules
+	ErrRuleNotFound = errors.New("lead aging rule not found")
+	// ErrInvalidStatus is returned for rules on closed or unknown lead statuses
+	ErrInvalidStatus = errors.New("invalid lead status")
+	// ErrRuleExists is returned when another rule already applies to the lead status
+	ErrRuleExists = errors.New("lead aging rule for status already exists")
+	// ErrInvalidSequence is returned when a rule would close leads before all win-back emails are sent
+	ErrInvalidSequence = errors.New("win-back emails do not fit before closing")
+)
+
+// defaultLostReason is recorded on closed leads when the rule names no reason
+const defaultLostReason = "Keine Rückmeldung nach Reaktivierungs-E-Mails"
+
+// openStatuses are the lead statuses rules can apply to
+var openStatuses = []models.LeadStatus{
+	models.LeadStatusNew,
+	models.LeadStatusInProgress,
+	models.LeadStatusQuestion,
+	models.LeadStatusPaymentPending,
+}
+
+// RuleInput holds the fields of a lead aging rule
+type RuleInput struct {
+	Name                string            `json:"name" binding:"required,max=100"`
+	Status              models.LeadStatus `json:"status" binding:"required"`
+	InactiveAfterDays   int               `json:"inactive_after_days" binding:"required,min=1,max=365"`
+	WinBackEmails       int               `json:"win_back_emails" binding:"min=0,max=5"`
+	WinBackIntervalDays int               `json:"win_back_interval_days" binding:"min=0,max=90"`
+	CloseAfterDays      int               `json:"close_after_days" binding:"min=0,max=365"`
+	LostReason          string            `json:"lost_reason" binding:"max=500"`
+	IsActive            *bool             `json:"is_active"`
+}
+
+// ListRules returns all lead aging rules
+func (s *Service) ListRules() ([]models.LeadAgingRule, error) {
+	var rules []models.LeadAgingRule
+	if err := s.db.Order("status ASC").Find(&rules).Error; err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// CreateRule adds a lead aging rule. New rules are active unless stated otherwise.
+func (s *Service) CreateRule(input RuleInput) (*models.LeadAgingRule, error) {
+	if err := s.validate(input, uuid.Nil); err != nil {
+		return nil, err
+	}
+
+	rule := &models.LeadAgingRule{IsActive: input.IsActive == nil || *input.IsActive}
+	apply(rule, input)
+	if err := s.db.Create(rule).Error; err != nil {
+		return nil, fmt.Errorf("failed to create lead aging rule: %w", err)
+	}
+	return rule, nil
+}
+
+// UpdateRule replaces the settings of a lead aging rule. Leads already marked
+// inactive continue with the new settings.
+func (s *Service) UpdateRule(id uuid.UUID, input RuleInput) (*models.LeadAgingRule, error) {
+	rule, err := s.getRule(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.validate(input, id); err != nil {
+		return nil, err
+	}
+
+	apply(rule, input)
+	if input.IsActive != nil {
+		rule.IsActive = *input.IsActive
+	}
+	err = s.db.Select("name", "status", "inactive_after_days", "win_back_emails", "win_back_interval_days",
+		"close_after_days", "lost_reason", "is_active").Save(rule).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to update lead aging rule: %w", err)
+	}
+	return rule, nil
+}
+
+// DeleteRule removes a lead aging rule. Leads it marked inactive stay marked
+// until they show new activity.
+func (s *Service) DeleteRule(id uuid.UUID) error {
+	rule, err := s.getRule(id)
+	if err != nil {
+		return err
+	}
+	return s.db.Delete(rule).Error
+}
+
+func (s *Service) getRule(id uuid.UUID) (*models.LeadAgingRule, error) {
+	var rule models.LeadAgingRule
+	if err := s.db.First(&rule, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrRuleNotFound
+		}
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// validate checks that the status is open and has no other rule, and that the
+// lead is closed only after the last win-back email
+func (s *Service) validate(input RuleInput, id uuid.UUID) error {
+	valid := false
+	for _, status := range openStatuses {
+		if input.Status == status {
+			valid = true
+		}
+	}
+	if !valid {
+		return ErrInvalidStatus
+	}
+
+	if input.WinBackEmails > 1 && input.WinBackIntervalDays < 1 {
+		return ErrInvalidSequence
+	}
+	if input.CloseAfterDays > 0 && input.WinBackEmails > 0 &&
+		input.CloseAfterDays <= (input.WinBackEmails-1)*input.WinBackIntervalDays {
+		return ErrInvalidSequence
+	}
+
+	var count int64
+	if err := s.db.Model(&models.LeadAgingRule{}).Where("status = ? AND id <> ?", input.Status, id).
+		Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrRuleExists
+	}
+	return nil
+}
+
+func apply(rule *models.LeadAgingRule, input RuleInput) {
+	rule.Name = strings.TrimSpace(input.Name)
+	rule.Status = input.Status
+	rule.InactiveAfterDays = input.InactiveAfterDays
+	rule.WinBackEmails = input.WinBackEmails
+	rule.WinBackIntervalDays = input.WinBackIntervalDays
+	rule.CloseAfterDays = input.CloseAfterDays
+	rule.LostReason = strings.TrimSpace(input.LostReason)
+	if rule.LostReason == "" {
+		rule.LostReason = defaultLostReason
+	}
+}
@@ -0,0 +1,295 @@
+// Package leadaging keeps the lead pipeline clean: admin-defined rules mark
+// open leads without activity as inactive, send their customers a sequence
+// of win-back emails and finally close them as lost.
+package leadaging
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"elterngeld-portal/internal/models"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// batchSize limits the leads a rule marks inactive per run
+const batchSize = 200
+
+// agingActivities are recorded by the rules themselves and do not count as activity
+var agingActivities = []models.ActivityType{
+	models.ActivityTypeLeadInactive,
+	models.ActivityTypeLeadWinBackSent,
+	models.ActivityTypeLeadReactivated,
+}
+
+// Summary counts the changes of one run of the lead aging rules
+type Summary struct {
+	Reactivated    int `json:"reactivated"`
+	MarkedInactive int `json:"marked_inactive"`
+	WinBackEmails  int `json:"win_back_emails"`
+	Closed         int `json:"closed"`
+}
+
+// Service manages the lead aging rules and applies them in the background
+type Service struct {
+	db     *gorm.DB
+	logger *zap.Logger
+	now    func() time.Time
+}
+
+func NewService(db *gorm.DB, logger *zap.Logger) *Service {
+	return &Service{
+		db:     db,
+		logger: logger,
+		now:    time.Now,
+	}
+}
+
+// activityAfter matches leads with activity after the given SQL expression:
+// changes to the lead, customer emails, activities, comments, documents and
+// bookings
+func activityAfter(since string) string {
+	return strings.ReplaceAll(`(leads.updated_at > @since
+		OR (leads.last_contact_at IS NOT NULL AND leads.last_contact_at > @since)
+		OR EXISTS (SELECT 1 FROM activities WHERE activities.lead_id = leads.id AND activities.created_at > @since AND activities.type NOT IN @aging)
+		OR EXISTS (SELECT 1 FROM comments WHERE comments.lead_id = leads.id AND comments.created_at > @since AND comments.deleted_at IS NULL)
+		OR EXISTS (SELECT 1 FROM documents WHERE documents.lead_id = leads.id AND documents.created_at > @since AND documents.deleted_at IS NULL)
+		OR EXISTS (SELECT 1 FROM bookings WHERE bookings.lead_id = leads.id AND bookings.created_at > @since))`, "@since", since)
+}
+
+// Apply runs the active rules: inactive leads with new activity are
+// reactivated, leads without activity are marked inactive, due win-back
+// emails are queued and leads whose sequence ended are closed as lost.
+// Rule changes to leads do not touch updated_at so they do not count as
+// activity themselves.
+func (s *Service) Apply() (*Summary, error) {
+	now := s.now()
+	summary := &Summary{}
+
+	reactivated, err := s.reactivate()
+	if err != nil {
+		return summary, err
+	}
+	summary.Reactivated = reactivated
+
+	var rules []models.LeadAgingRule
+	if err := s.db.Where("is_active = ?", true).Find(&rules).Error; err != nil {
+		return summary, fmt.Errorf("failed to load lead aging rules: %w", err)
+	}
+
+	for i := range rules {
+		rule := &rules[i]
+
+		marked, err := s.markInactive(rule, now)
+		summary.MarkedInactive += marked
+		if err != nil {
+			return summary, err
+		}
+		sent, err := s.sendWinBacks(rule, now)
+		summary.WinBackEmails += sent
+		if err != nil {
+			return summary, err
+		}
+		closed, err := s.closeLost(rule, now)
+		summary.Closed += closed
+		if err != nil {
+			return summary, err
+		}
+	}
+
+	if *summary != (Summary{}) {
+		s.logger.Info("Applied lead aging rules",
+			zap.Int("reactivated", summary.Reactivated),
+			zap.Int("marked_inactive", summary.MarkedInactive),
+			zap.Int("win_back_emails", summary.WinBackEmails),
+			zap.Int("closed", summary.Closed))
+	}
+	return summary, nil
+}
+
+// reactivate clears the aging state of open inactive leads with activity
+// since they were marked
+func (s *Service) reactivate() (int, error) {
+	var leads []models.Lead
+	err := s.db.Select("id").
+		Where("inactive_since IS NOT NULL AND status IN ?", openStatuses).
+		Where(activityAfter("leads.inactive_since"), map[string]interface{}{"aging": agingActivities}).
+		Find(&leads).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to find reactivated leads: %w", err)
+	}
+
+	for i, lead := range leads {
+		err := s.db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Model(&models.Lead{}).Where("id = ?", lead.ID).UpdateColumns(map[string]interface{}{
+				"inactive_since":       nil,
+				"win_back_emails_sent": 0,
+				"last_win_back_at":     nil,
+				"lost_reason":          "",
+			}).Error; err != nil {
+				return err
+			}
+			return tx.Create(models.CreateLeadReactivatedActivity(lead.ID)).Error
+		})
+		if err != nil {
+			return i, fmt.Errorf("failed to reactivate lead %s: %w", lead.ID, err)
+		}
+	}
+	return len(leads), nil
+}
+
+// markInactive marks the leads of the rule's status without activity for the
+// rule's number of days as inactive
+func (s *Service) markInactive(rule *models.LeadAgingRule, now time.Time) (int, error) {
+	cutoff := now.AddDate(0, 0, -rule.InactiveAfterDays)
+
+	var leads []models.Lead
+	err := s.db.Select("id").
+		Where("status = ? AND inactive_since IS NULL AND created_at <= ?", rule.Status, cutoff).
+		Where("NOT "+activityAfter("@since"), map[string]interface{}{"since": cutoff, "aging": agingActivities}).
+		Order("created_at ASC").Limit(batchSize).
+		Find(&leads).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to find inactive leads: %w", err)
+	}
+
+	for i, lead := range leads {
+		err := s.db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Model(&models.Lead{}).Where("id = ?", lead.ID).UpdateColumns(map[string]interface{}{
+				"inactive_since":       now,
+				"win_back_emails_sent": 0,
+				"last_win_back_at":     nil,
+			}).Error; err != nil {
+				return err
+			}
+			return tx.Create(models.CreateLeadInactiveActivity(lead.ID, rule.ID, rule.InactiveAfterDays)).Error
+		})
+		if err != nil {
+			return i, fmt.Errorf("failed to mark lead %s inactive: %w", lead.ID, err)
+		}
+	}
+	return len(leads), nil
+}
+
+// sendWinBacks queues the due win-back emails of the rule's inactive leads
+func (s *Service) sendWinBacks(rule *models.LeadAgingRule, now time.Time) (int, error) {
+	if rule.WinBackEmails <= 0 {
+		return 0, nil
+	}
+
+	var leads []models.Lead
+	err := s.db.Preload("User").
+		Where("status = ? AND inactive_since IS NOT NULL AND win_back_emails_sent < ?", rule.Status, rule.WinBackEmails).
+		Find(&leads).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to find leads due for win-back emails: %w", err)
+	}
+
+	sent := 0
+	for i := range leads {
+		lead := &leads[i]
+		if !rule.WinBackDue(*lead.InactiveSince, lead.WinBackEmailsSent, now) {
+			continue
+		}
+
+		number := lead.WinBackEmailsSent + 1
+		err := s.db.Transaction(func(tx *gorm.DB) error {
+			// The sent count guards against sending an email twice
+			result := tx.Model(&models.Lead{}).
+				Where("id = ? AND win_back_emails_sent = ?", lead.ID, lead.WinBackEmailsSent).
+				UpdateColumns(map[string]interface{}{
+					"win_back_emails_sent": number,
+					"last_win_back_at":     now,
+				})
+			if result.Error != nil || result.RowsAffected == 0 {
+				return result.Error
+			}
+			if err := tx.Create(winBackEmail(rule, lead, number)).Error; err != nil {
+				return err
+			}
+			return tx.Create(models.CreateLeadWinBackSentActivity(lead.ID, number, rule.WinBackEmails)).Error
+		})
+		if err != nil {
+			return sent, fmt.Errorf("failed to queue win-back email for lead %s: %w", lead.ID, err)
+		}
+		sent++
+	}
+	return sent, nil
+}
+
+// closeLost closes the rule's inactive leads as lost once all win-back emails
+// were sent and the closing delay has passed
+func (s *Service) closeLost(rule *models.LeadAgingRule, now time.Time) (int, error) {
+	if rule.CloseAfterDays <= 0 {
+		return 0, nil
+	}
+
+	var leads []models.Lead
+	err := s.db.Where("status = ? AND inactive_since IS NOT NULL AND win_back_emails_sent >= ?", rule.Status, rule.WinBackEmails).
+		Find(&leads).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to find leads to close: %w", err)
+	}
+
+	closed := 0
+	for i := range leads {
+		lead := &leads[i]
+		if !rule.CloseDue(*lead.InactiveSince, now) {
+			continue
+		}
+
+		err := s.db.Transaction(func(tx *gorm.DB) error {
+			result := tx.Model(&models.Lead{}).Where("id = ? AND status = ?", lead.ID, rule.Status).
+				Updates(map[string]interface{}{
+					"status":      models.LeadStatusCancelled,
+					"lost_reason": rule.LostReason,
+				})
+			if result.Error != nil || result.RowsAffected == 0 {
+				return result.Error
+			}
+			return tx.Create(models.CreateLeadLostActivity(lead.ID, rule.Status, rule.LostReason)).Error
+		})
+		if err != nil {
+			return closed, fmt.Errorf("failed to close lead %s: %w", lead.ID, err)
+		}
+		closed++
+	}
+	return closed, nil
+}
+
+// winBackEmail builds the queued email of the given win-back email of a
+// lead. The last email announces the closing date.
+func winBackEmail(rule *models.LeadAgingRule, lead *models.Lead, number int) *models.Notification {
+	title := "Wir sind weiterhin für Sie da"
+	message := fmt.Sprintf("Wir haben länger nichts von Ihnen gehört. Ihr Elterngeld-Antrag '%s' (%s) ist noch offen. "+
+		"Melden Sie sich gerne, wenn wir Sie unterstützen können.", lead.Title, lead.ApplicationNumber)
+	if number > 1 {
+		title = "Ihr Elterngeld-Antrag wartet auf Sie"
+		message = fmt.Sprintf("Ihr Elterngeld-Antrag '%s' (%s) ist weiterhin offen. "+
+			"Antworten Sie einfach auf diese E-Mail oder vereinbaren Sie einen Termin, damit wir gemeinsam weitermachen können.",
+			lead.Title, lead.ApplicationNumber)
+	}
+	if number == rule.WinBackEmails && rule.CloseAfterDays > 0 {
+		title = "Letzte Erinnerung zu Ihrem Elterngeld-Antrag"
+		closeAt := lead.InactiveSince.AddDate(0, 0, rule.CloseAfterDays).In(lead.User.TimeLocation())
+		message += fmt.Sprintf(" Ohne Rückmeldung schließen wir den Vorgang am %s.", closeAt.Format("02.01.2006"))
+	}
+
+	data, _ := json.Marshal(map[string]interface{}{
+		"lead_id": lead.ID,
+		"number":  number,
+	})
+	return &models.Notification{
+		UserID:    lead.UserID,
+		Type:      models.NotificationTypeEmail,
+		Status:    models.NotificationStatusPending,
+		Title:     title,
+		Message:   message,
+		Data:      string(data),
+		Template:  string(models.EmailTemplateLeadWinBack),
+		Recipient: lead.User.NotificationAddress(),
+	}
+}
@@ -0,0 +1,245 @@
+package leadaging
+
+import (
+	"testing"
+	"time"
+
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/tests/testutils"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+func TestApply(t *testing.T) {
+	db, service := setupTestService(t)
+	now := time.Date(2025, 3, 10, 9, 0, 0, 0, time.UTC)
+	service.now = func() time.Time { return now }
+
+	_, err := service.CreateRule(RuleInput{
+		Name:                "Neue Anfragen",
+		Status:              models.LeadStatusNew,
+		InactiveAfterDays:   14,
+		WinBackEmails:       2,
+		WinBackIntervalDays: 7,
+		CloseAfterDays:      21,
+	})
+	require.NoError(t, err)
+
+	customer := testutils.CreateTestUser(t, db, models.RoleUser)
+	stale := createLead(t, db, customer.ID, models.LeadStatusNew, now.AddDate(0, 0, -20))
+	fresh := createLead(t, db, customer.ID, models.LeadStatusNew, now.AddDate(0, 0, -2))
+	commented := createLead(t, db, customer.ID, models.LeadStatusNew, now.AddDate(0, 0, -30))
+	require.NoError(t, db.Create(&models.Comment{
+		LeadID:    commented.ID,
+		UserID:    customer.ID,
+		Content:   "Ich melde mich nächste Woche.",
+		CreatedAt: now.AddDate(0, 0, -3),
+		UpdatedAt: now.AddDate(0, 0, -3),
+	}).Error)
+	// No rule applies to leads in progress
+	inProgress := createLead(t, db, customer.ID, models.LeadStatusInProgress, now.AddDate(0, 0, -60))
+
+	summary, err := service.Apply()
+	require.NoError(t, err)
+	assert.Equal(t, Summary{MarkedInactive: 1, WinBackEmails: 1}, *summary)
+
+	lead := loadLead(t, db, stale.ID)
+	require.NotNil(t, lead.InactiveSince)
+	assert.Equal(t, 1, lead.WinBackEmailsSent)
+	for _, id := range []uuid.UUID{fresh.ID, commented.ID, inProgress.ID} {
+		assert.Nil(t, loadLead(t, db, id).InactiveSince)
+	}
+
+	// Nothing is due until the interval has passed
+	summary, err = service.Apply()
+	require.NoError(t, err)
+	assert.Equal(t, Summary{}, *summary)
+
+	now = now.AddDate(0, 0, 7)
+	summary, err = service.Apply()
+	require.NoError(t, err)
+	assert.Equal(t, 1, summary.WinBackEmails)
+
+	var emails []models.Notification
+	require.NoError(t, db.Where("template = ?", models.EmailTemplateLeadWinBack).Order("title ASC").Find(&emails).Error)
+	require.Len(t, emails, 2)
+	assert.Equal(t, "Letzte Erinnerung zu Ihrem Elterngeld-Antrag", emails[0].Title)
+	assert.Contains(t, emails[0].Message, "31.03.2025")
+	assert.Equal(t, customer.Email, emails[0].Recipient)
+	assert.Equal(t, models.NotificationStatusPending, emails[0].Status)
+
+	now = now.AddDate(0, 0, 14)
+	summary, err = service.Apply()
+	require.NoError(t, err)
+	assert.Equal(t, 1, summary.Closed)
+
+	lead = loadLead(t, db, stale.ID)
+	assert.Equal(t, models.LeadStatusCancelled, lead.Status)
+	assert.Equal(t, defaultLostReason, lead.LostReason)
+
+	var activities []models.Activity
+	require.NoError(t, db.Where("lead_id = ?", stale.ID).Order("created_at ASC").Find(&activities).Error)
+	types := make([]models.ActivityType, 0, len(activities))
+	for _, activity := range activities {
+		types = append(types, activity.Type)
+	}
+	assert.ElementsMatch(t, []models.ActivityType{
+		models.ActivityTypeLeadInactive,
+		models.ActivityTypeLeadWinBackSent,
+		models.ActivityTypeLeadWinBackSent,
+		models.ActivityTypeLeadStatusChanged,
+	}, types)
+}
+
+func TestApply_Reactivates(t *testing.T) {
+	db, service := setupTestService(t)
+	now := time.Date(2025, 3, 10, 9, 0, 0, 0, time.UTC)
+	service.now = func() time.Time { return now }
+
+	_, err := service.CreateRule(RuleInput{
+		Name:                "Rückfragen",
+		Status:              models.LeadStatusQuestion,
+		InactiveAfterDays:   10,
+		WinBackEmails:       3,
+		WinBackIntervalDays: 5,
+		CloseAfterDays:      20,
+		LostReason:          "Unterlagen nicht nachgereicht",
+	})
+	require.NoError(t, err)
+
+	customer := testutils.CreateTestUser(t, db, models.RoleUser)
+	lead := createLead(t, db, customer.ID, models.LeadStatusQuestion, now.AddDate(0, 0, -11))
+
+	_, err = service.Apply()
+	require.NoError(t, err)
+	require.NotNil(t, loadLead(t, db, lead.ID).InactiveSince)
+
+	// The customer answers by email
+	now = now.AddDate(0, 0, 2)
+	require.NoError(t, db.Model(&models.Lead{}).Where("id = ?", lead.ID).
+		UpdateColumn("last_contact_at", now.Add(-time.Hour)).Error)
+
+	summary, err := service.Apply()
+	require.NoError(t, err)
+	assert.Equal(t, 1, summary.Reactivated)
+
+	reactivated := loadLead(t, db, lead.ID)
+	assert.Nil(t, reactivated.InactiveSince)
+	assert.Equal(t, 0, reactivated.WinBackEmailsSent)
+	assert.Equal(t, models.LeadStatusQuestion, reactivated.Status)
+
+	// Deactivated rules are not applied
+	rules, err := service.ListRules()
+	require.NoError(t, err)
+	inactive := false
+	input := RuleInput{
+		Name:                rules[0].Name,
+		Status:              rules[0].Status,
+		InactiveAfterDays:   rules[0].InactiveAfterDays,
+		WinBackEmails:       rules[0].WinBackEmails,
+		WinBackIntervalDays: rules[0].WinBackIntervalDays,
+		CloseAfterDays:      rules[0].CloseAfterDays,
+		IsActive:            &inactive,
+	}
+	_, err = service.UpdateRule(rules[0].ID, input)
+	require.NoError(t, err)
+
+	now = now.AddDate(0, 0, 30)
+	summary, err = service.Apply()
+	require.NoError(t, err)
+	assert.Equal(t, Summary{}, *summary)
+}
+
+func TestRules(t *testing.T) {
+	_, service := setupTestService(t)
+
+	rule, err := service.CreateRule(RuleInput{
+		Name:              "  Neue Anfragen ",
+		Status:            models.LeadStatusNew,
+		InactiveAfterDays: 14,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Neue Anfragen", rule.Name)
+	assert.True(t, rule.IsActive)
+	assert.Equal(t, defaultLostReason, rule.LostReason)
+
+	tests := []struct {
+		name  string
+		input RuleInput
+		err   error
+	}{
+		{"closed status", RuleInput{Name: "Fertig", Status: models.LeadStatusCompleted, InactiveAfterDays: 5}, ErrInvalidStatus},
+		{"unknown status", RuleInput{Name: "Unbekannt", Status: "offen", InactiveAfterDays: 5}, ErrInvalidStatus},
+		{"second rule for status", RuleInput{Name: "Doppelt", Status: models.LeadStatusNew, InactiveAfterDays: 5}, ErrRuleExists},
+		{"emails without interval", RuleInput{Name: "Zahlung", Status: models.LeadStatusPaymentPending, InactiveAfterDays: 5, WinBackEmails: 2}, ErrInvalidSequence},
+		{"closed before last email", RuleInput{Name: "Zahlung", Status: models.LeadStatusPaymentPending, InactiveAfterDays: 5,
+			WinBackEmails: 3, WinBackIntervalDays: 7, CloseAfterDays: 14}, ErrInvalidSequence},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := service.CreateRule(tt.input)
+			assert.ErrorIs(t, err, tt.err)
+		})
+	}
+
+	// A rule can keep its own status
+	updated, err := service.UpdateRule(rule.ID, RuleInput{
+		Name:                "Neue Anfragen",
+		Status:              models.LeadStatusNew,
+		InactiveAfterDays:   21,
+		WinBackEmails:       1,
+		WinBackIntervalDays: 0,
+		CloseAfterDays:      7,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 21, updated.InactiveAfterDays)
+	assert.True(t, updated.IsActive)
+
+	_, err = service.UpdateRule(uuid.New(), RuleInput{Name: "x", Status: models.LeadStatusNew, InactiveAfterDays: 1})
+	assert.ErrorIs(t, err, ErrRuleNotFound)
+
+	require.NoError(t, service.DeleteRule(rule.ID))
+	assert.ErrorIs(t, service.DeleteRule(rule.ID), ErrRuleNotFound)
+}
+
+func setupTestService(t *testing.T) (*gorm.DB, *Service) {
+	t.Helper()
+	db := testutils.NewSQLiteDB(t,
+		&models.User{},
+		&models.Lead{},
+		&models.Comment{},
+		&models.Document{},
+		&models.Booking{},
+		&models.Activity{},
+		&models.Notification{},
+		&models.LeadAgingRule{},
+	)
+
+	return db, NewService(db, zap.NewNop())
+}
+
+func createLead(t *testing.T, db *gorm.DB, userID uuid.UUID, status models.LeadStatus, lastChange time.Time) *models.Lead {
+	t.Helper()
+	lead := &models.Lead{
+		UserID:    userID,
+		Title:     "Elterngeld für Emma",
+		Status:    status,
+		Priority:  models.PriorityMedium,
+		Source:    models.LeadSourceWebsite,
+		CreatedAt: lastChange,
+		UpdatedAt: lastChange,
+	}
+	require.NoError(t, db.Create(lead).Error)
+	return lead
+}
+
+func loadLead(t *testing.T, db *gorm.DB, id uuid.UUID) models.Lead {
+	t.Helper()
+	var lead models.Lead
+	require.NoError(t, db.First(&lead, "id = ?", id).Error)
+	return lead
+}
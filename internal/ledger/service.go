@@ -0,0 +1,376 @@
+// Package ledger puts together the payment history of a booking or a
+// customer: charges, refunds, account credit, vouchers and disputes as one
+// statement with running balances, so billing questions can be answered
+// without looking through Stripe.
+package ledger
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"elterngeld-portal/internal/models"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrBookingNotFound is returned for statements of unknown bookings
+	ErrBookingNotFound = errors.New("booking not found")
+	// ErrUserNotFound is returned for statements of unknown customers
+	ErrUserNotFound = errors.New("user not found")
+	// ErrPaymentNotFound is returned for disputes of payments we do not know
+	ErrPaymentNotFound = errors.New("payment not found")
+)
+
+// LineType is the kind of financial event of a statement line
+type LineType string
+
+const (
+	LineCharge         LineType = "charge"          // payment charged to the card
+	LineRefund         LineType = "refund"          // refund to the card
+	LineCreditRefund   LineType = "credit_refund"   // payment refunded as account credit
+	LineGoodwill       LineType = "goodwill"        // credit granted by an admin
+	LineVoucher        LineType = "voucher"         // voucher redeemed for credit
+	LineCreditApplied  LineType = "credit_applied"  // credit used to pay a booking
+	LineCreditRestored LineType = "credit_restored" // used credit returned because the booking was not paid
+	LineDispute        LineType = "dispute"         // chargeback or inquiry of the customer's bank
+	LineDisputeWon     LineType = "dispute_won"     // disputed amount returned after the dispute was won
+)
+
+// Line is one financial event. Amount is the change of what the customer
+// paid, CreditAmount the change of their account credit; the balances are
+// running totals up to and including the line.
+type Line struct {
+	Date          time.Time  `json:"date"`
+	Type          LineType   `json:"type"`
+	Description   string     `json:"description"`
+	Amount        float64    `json:"amount"`
+	CreditAmount  float64    `json:"credit_amount"`
+	Balance       float64    `json:"balance"`
+	CreditBalance float64    `json:"credit_balance"`
+	PaymentID     *uuid.UUID `json:"payment_id,omitempty"`
+	BookingID     *uuid.UUID `json:"booking_id,omitempty"`
+	Reference     string     `json:"reference,omitempty"` // Stripe ID of the charge, refund or dispute
+}
+
+// Statement lists the financial events of a booking or a customer, oldest first
+type Statement struct {
+	UserID    *uuid.UUID `json:"user_id,omitempty"`
+	BookingID *uuid.UUID `json:"booking_id,omitempty"`
+	Currency  string     `json:"currency"`
+	Lines     []Line     `json:"lines"`
+
+	Charged  float64 `json:"charged"`  // charged to the card
+	Refunded float64 `json:"refunded"` // refunded to the card or as credit
+	Disputed float64 `json:"disputed"` // withdrawn by disputes not won
+	Balance  float64 `json:"balance"`  // paid after refunds and disputes
+	Credit   float64 `json:"credit"`   // change of the account credit; the credit balance for customers
+}
+
+// DisputeUpdate is the state of a Stripe dispute from a webhook
+type DisputeUpdate struct {
+	StripeDisputeID string
+	PaymentIntentID string
+	ChargeID        string
+	Amount          float64
+	Currency        string
+	Reason          string
+	Status          string
+	OpenedAt        time.Time
+}
+
+type Service struct {
+	db     *gorm.DB
+	logger *zap.Logger
+	now    func() time.Time
+}
+
+func NewService(db *gorm.DB, logger *zap.Logger) *Service {
+	return &Service{
+		db:     db,
+		logger: logger,
+		now:    time.Now,
+	}
+}
+
+// ForBooking returns the statement of a booking: its payment with refunds
+// and disputes, and the credit used for it
+func (s *Service) ForBooking(bookingID uuid.UUID) (*Statement, error) {
+	var booking models.Booking
+	if err := s.db.First(&booking, "id = ?", bookingID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrBookingNotFound
+		}
+		return nil, err
+	}
+
+	var paymentIDs []uuid.UUID
+	if booking.PaymentID != nil {
+		paymentIDs = append(paymentIDs, *booking.PaymentID)
+	}
+	scope := func(db *gorm.DB) *gorm.DB { return db.Where("payment_id IN ?", paymentIDs) }
+	creditScope := func(db *gorm.DB) *gorm.DB {
+		return db.Where("booking_id = ? OR payment_id IN ?", booking.ID, paymentIDs)
+	}
+
+	statement := &Statement{BookingID: &booking.ID, Currency: "EUR"}
+	if err := s.build(statement, s.db.Where("id IN ?", paymentIDs), scope, creditScope); err != nil {
+		return nil, err
+	}
+	for i := range statement.Lines {
+		statement.Lines[i].BookingID = &booking.ID
+	}
+	return statement, nil
+}
+
+// ForUser returns the statement of a customer across all their bookings;
+// the credit balance is their current account credit
+func (s *Service) ForUser(userID uuid.UUID) (*Statement, error) {
+	var count int64
+	if err := s.db.Model(&models.User{}).Where("id = ?", userID).Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count == 0 {
+		return nil, ErrUserNotFound
+	}
+
+	paymentIDs := s.db.Model(&models.Payment{}).Select("id").Where("user_id = ?", userID)
+	scope := func(db *gorm.DB) *gorm.DB { return db.Where("payment_id IN (?)", paymentIDs) }
+	creditScope := func(db *gorm.DB) *gorm.DB { return db.Where("user_id = ?", userID) }
+
+	statement := &Statement{UserID: &userID, Currency: "EUR"}
+	if err := s.build(statement, s.db.Where("user_id = ?", userID), scope, creditScope); err != nil {
+		return nil, err
+	}
+
+	// Lines of a customer belong to the booking of their payment
+	var bookings []models.Booking
+	if err := s.db.Select("id", "payment_id").Where("user_id = ? AND payment_id IS NOT NULL", userID).Find(&bookings).Error; err != nil {
+		return nil, fmt.Errorf("failed to load bookings: %w", err)
+	}
+	bookingOf := make(map[uuid.UUID]uuid.UUID, len(bookings))
+	for _, booking := range bookings {
+		bookingOf[*booking.PaymentID] = booking.ID
+	}
+	for i, line := range statement.Lines {
+		if line.BookingID != nil || line.PaymentID == nil {
+			continue
+		}
+		if bookingID, ok := bookingOf[*line.PaymentID]; ok {
+			statement.Lines[i].BookingID = &bookingID
+		}
+	}
+	return statement, nil
+}
+
+// RecordDispute creates or updates a dispute from a Stripe webhook
+func (s *Service) RecordDispute(update DisputeUpdate) (*models.PaymentDispute, error) {
+	var query *gorm.DB
+	switch {
+	case update.PaymentIntentID != "":
+		query = s.db.Where("stripe_payment_intent = ?", update.PaymentIntentID)
+	case update.ChargeID != "":
+		query = s.db.Where("stripe_charge_id = ?", update.ChargeID)
+	default:
+		return nil, ErrPaymentNotFound
+	}
+
+	var payment models.Payment
+	if err := query.First(&payment).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPaymentNotFound
+		}
+		return nil, err
+	}
+
+	var dispute models.PaymentDispute
+	err := s.db.Where("stripe_dispute_id = ?", update.StripeDisputeID).First(&dispute).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	dispute.PaymentID = payment.ID
+	dispute.UserID = payment.UserID
+	dispute.StripeDisputeID = update.StripeDisputeID
+	dispute.Amount = round(update.Amount)
+	dispute.Currency = update.Currency
+	dispute.Reason = update.Reason
+	dispute.Status = update.Status
+	if dispute.OpenedAt.IsZero() {
+		dispute.OpenedAt = update.OpenedAt
+	}
+	if dispute.IsClosed() && dispute.ClosedAt == nil {
+		now := s.now()
+		dispute.ClosedAt = &now
+	}
+	if err := s.db.Save(&dispute).Error; err != nil {
+		return nil, fmt.Errorf("failed to save dispute: %w", err)
+	}
+
+	s.logger.Info("Payment dispute recorded",
+		zap.String("payment_id", payment.ID.String()),
+		zap.String("stripe_dispute_id", dispute.StripeDisputeID),
+		zap.String("status", dispute.Status),
+		zap.Float64("amount", dispute.Amount))
+	return &dispute, nil
+}
+
+// build collects the lines of the payments and credit entries in scope and
+// adds up the balances
+func (s *Service) build(statement *Statement, payments *gorm.DB, scope, creditScope func(*gorm.DB) *gorm.DB) error {
+	var paid []models.Payment
+	if err := payments.Where("status IN ?", []models.PaymentStatus{models.PaymentStatusSucceeded, models.PaymentStatusRefunded}).
+		Find(&paid).Error; err != nil {
+		return fmt.Errorf("failed to load payments: %w", err)
+	}
+	var refunds []models.RefundRequest
+	if err := s.db.Scopes(scope).Where("status = ?", models.RefundRequestStatusExecuted).Find(&refunds).Error; err != nil {
+		return fmt.Errorf("failed to load refunds: %w", err)
+	}
+	var disputes []models.PaymentDispute
+	if err := s.db.Scopes(scope).Find(&disputes).Error; err != nil {
+		return fmt.Errorf("failed to load disputes: %w", err)
+	}
+	var entries []models.CreditEntry
+	if err := s.db.Scopes(creditScope).Find(&entries).Error; err != nil {
+		return fmt.Errorf("failed to load credit entries: %w", err)
+	}
+
+	// Refunded amounts of a payment the lines do not explain were refunded
+	// before refunds were requested
+	unexplained := make(map[uuid.UUID]float64, len(paid))
+	for _, payment := range paid {
+		unexplained[payment.ID] = payment.RefundAmount
+	}
+
+	lines := []Line{}
+	for _, refund := range refunds {
+		if refund.ToCredit {
+			continue // the credit entry is the line
+		}
+		unexplained[refund.PaymentID] -= refund.Amount
+		lines = append(lines, Line{
+			Date:        timeOr(refund.ExecutedAt, refund.UpdatedAt),
+			Type:        LineRefund,
+			Description: refund.Reason,
+			Amount:      -refund.Amount,
+			PaymentID:   uuidPtr(refund.PaymentID),
+			Reference:   refund.StripeRefundID,
+		})
+	}
+	for _, entry := range entries {
+		line := Line{
+			Date:         entry.CreatedAt,
+			Description:  entry.Description,
+			CreditAmount: entry.Amount,
+			PaymentID:    entry.PaymentID,
+			BookingID:    entry.BookingID,
+		}
+		switch entry.Type {
+		case models.CreditEntryRefund:
+			line.Type = LineCreditRefund
+			line.Amount = -entry.Amount
+			if entry.PaymentID != nil {
+				unexplained[*entry.PaymentID] -= entry.Amount
+			}
+		case models.CreditEntryGoodwill:
+			line.Type = LineGoodwill
+		case models.CreditEntryVoucher:
+			line.Type = LineVoucher
+		case models.CreditEntryCheckout:
+			line.Type = LineCreditApplied
+		case models.CreditEntryRestore:
+			line.Type = LineCreditRestored
+		default:
+			line.Type = LineType(entry.Type)
+		}
+		lines = append(lines, line)
+	}
+	for _, payment := range paid {
+		if payment.Amount > 0 {
+			lines = append(lines, Line{
+				Date:        timeOr(payment.PaidAt, payment.CreatedAt),
+				Type:        LineCharge,
+				Description: payment.Description,
+				Amount:      payment.Amount,
+				PaymentID:   uuidPtr(payment.ID),
+				Reference:   payment.StripePaymentIntent,
+			})
+		}
+		if rest := round(unexplained[payment.ID]); rest > 0 {
+			lines = append(lines, Line{
+				Date:        timeOr(payment.RefundedAt, payment.UpdatedAt),
+				Type:        LineRefund,
+				Description: payment.RefundReason,
+				Amount:      -rest,
+				PaymentID:   uuidPtr(payment.ID),
+			})
+		}
+	}
+	for _, dispute := range disputes {
+		line := Line{
+			Date:        dispute.OpenedAt,
+			Type:        LineDispute,
+			Description: dispute.Reason,
+			PaymentID:   uuidPtr(dispute.PaymentID),
+			Reference:   dispute.StripeDisputeID,
+		}
+		if !dispute.IsInquiry() {
+			line.Amount = -dispute.Amount
+		}
+		lines = append(lines, line)
+		if dispute.Status == "won" {
+			lines = append(lines, Line{
+				Date:        timeOr(dispute.ClosedAt, dispute.UpdatedAt),
+				Type:        LineDisputeWon,
+				Description: dispute.Reason,
+				Amount:      dispute.Amount,
+				PaymentID:   uuidPtr(dispute.PaymentID),
+				Reference:   dispute.StripeDisputeID,
+			})
+		}
+	}
+
+	sort.SliceStable(lines, func(i, j int) bool { return lines[i].Date.Before(lines[j].Date) })
+	for i := range lines {
+		line := &lines[i]
+		line.Amount = round(line.Amount)
+		line.CreditAmount = round(line.CreditAmount)
+		switch line.Type {
+		case LineCharge:
+			statement.Charged += line.Amount
+		case LineRefund, LineCreditRefund:
+			statement.Refunded -= line.Amount
+		case LineDispute, LineDisputeWon:
+			statement.Disputed -= line.Amount
+		}
+		statement.Balance = round(statement.Balance + line.Amount)
+		statement.Credit = round(statement.Credit + line.CreditAmount)
+		line.Balance = statement.Balance
+		line.CreditBalance = statement.Credit
+	}
+	statement.Charged = round(statement.Charged)
+	statement.Refunded = round(statement.Refunded)
+	statement.Disputed = round(statement.Disputed)
+	statement.Lines = lines
+	return nil
+}
+
+func timeOr(at *time.Time, fallback time.Time) time.Time {
+	if at != nil {
+		return *at
+	}
+	return fallback
+}
+
+func uuidPtr(id uuid.UUID) *uuid.UUID {
+	return &id
+}
+
+func round(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
@@ -0,0 +1,188 @@
+package ledger
+
+import (
+	"testing"
+	"time"
+
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/tests/testutils"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+func TestForBooking(t *testing.T) {
+	db, service := setupTestService(t)
+	customer := testutils.CreateTestUser(t, db, models.RoleUser)
+	day := time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC)
+
+	// 249 € booking: 50 € voucher credit applied, 199 € charged to the card
+	createEntry(t, db, models.CreditEntry{UserID: customer.ID, Type: models.CreditEntryVoucher, Amount: 50, CreatedAt: day})
+	payment := createPayment(t, db, customer, 199, 50, day.Add(time.Hour))
+	booking := createBooking(t, db, customer, payment)
+	createEntry(t, db, models.CreditEntry{UserID: customer.ID, Type: models.CreditEntryCheckout, Amount: -50,
+		BookingID: &booking.ID, PaymentID: &payment.ID, CreatedAt: day.Add(time.Hour)})
+
+	// 40 € back to the card, 30 € as credit
+	executed := day.Add(48 * time.Hour)
+	require.NoError(t, db.Create(&models.RefundRequest{PaymentID: payment.ID, RequestedBy: customer.ID, Status: models.RefundRequestStatusExecuted,
+		Amount: 40, Reason: "Kulanz", ExecutedAt: &executed, StripeRefundID: "re_1"}).Error)
+	createEntry(t, db, models.CreditEntry{UserID: customer.ID, Type: models.CreditEntryRefund, Amount: 30,
+		PaymentID: &payment.ID, CreatedAt: day.Add(72 * time.Hour)})
+	require.NoError(t, db.Model(payment).Update("refund_amount", 70).Error)
+
+	statement, err := service.ForBooking(booking.ID)
+	require.NoError(t, err)
+	require.Len(t, statement.Lines, 4, "the voucher belongs to the customer, not the booking")
+
+	types := make([]LineType, len(statement.Lines))
+	for i, line := range statement.Lines {
+		types[i] = line.Type
+		assert.Equal(t, booking.ID, *line.BookingID)
+	}
+	assert.Equal(t, []LineType{LineCreditApplied, LineCharge, LineRefund, LineCreditRefund}, types)
+	assert.Equal(t, 199.0, statement.Lines[1].Balance)
+	assert.Equal(t, "re_1", statement.Lines[2].Reference)
+	assert.Equal(t, 159.0, statement.Lines[2].Balance)
+	assert.Equal(t, 129.0, statement.Lines[3].Balance)
+	assert.Equal(t, -20.0, statement.Lines[3].CreditBalance)
+
+	assert.Equal(t, 199.0, statement.Charged)
+	assert.Equal(t, 70.0, statement.Refunded)
+	assert.Equal(t, 129.0, statement.Balance)
+
+	_, err = service.ForBooking(uuid.New())
+	assert.ErrorIs(t, err, ErrBookingNotFound)
+}
+
+func TestForUser(t *testing.T) {
+	db, service := setupTestService(t)
+	customer := testutils.CreateTestUser(t, db, models.RoleUser)
+	other := testutils.CreateTestUser(t, db, models.RoleUser)
+	day := time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC)
+
+	first := createPayment(t, db, customer, 149, 0, day)
+	booking := createBooking(t, db, customer, first)
+	second := createPayment(t, db, customer, 99, 0, day.Add(24*time.Hour))
+	createPayment(t, db, other, 500, 0, day)
+	createEntry(t, db, models.CreditEntry{UserID: customer.ID, Type: models.CreditEntryGoodwill, Amount: 20, CreatedAt: day.Add(2 * time.Hour)})
+
+	// Refunded before refunds were requested
+	refundedAt := day.Add(36 * time.Hour)
+	require.NoError(t, db.Model(second).Updates(map[string]interface{}{
+		"refund_amount": 99, "refund_reason": "Doppelt bezahlt", "refunded_at": refundedAt, "status": models.PaymentStatusRefunded,
+	}).Error)
+
+	// A lost chargeback on the first payment
+	require.NoError(t, db.Create(&models.PaymentDispute{PaymentID: first.ID, UserID: customer.ID, StripeDisputeID: "dp_1",
+		Amount: 149, Reason: "fraudulent", Status: "lost", OpenedAt: day.Add(96 * time.Hour)}).Error)
+
+	statement, err := service.ForUser(customer.ID)
+	require.NoError(t, err)
+	require.Len(t, statement.Lines, 5)
+	assert.Equal(t, LineCharge, statement.Lines[0].Type)
+	assert.Equal(t, booking.ID, *statement.Lines[0].BookingID)
+	assert.Equal(t, LineGoodwill, statement.Lines[1].Type)
+	assert.Equal(t, LineCharge, statement.Lines[2].Type)
+	assert.Nil(t, statement.Lines[2].BookingID)
+	assert.Equal(t, LineRefund, statement.Lines[3].Type)
+	assert.Equal(t, "Doppelt bezahlt", statement.Lines[3].Description)
+	assert.Equal(t, LineDispute, statement.Lines[4].Type)
+	assert.Equal(t, 0.0, statement.Lines[4].Balance)
+
+	assert.Equal(t, 248.0, statement.Charged)
+	assert.Equal(t, 99.0, statement.Refunded)
+	assert.Equal(t, 149.0, statement.Disputed)
+	assert.Equal(t, 0.0, statement.Balance)
+	assert.Equal(t, 20.0, statement.Credit)
+
+	_, err = service.ForUser(uuid.New())
+	assert.ErrorIs(t, err, ErrUserNotFound)
+}
+
+func TestRecordDispute(t *testing.T) {
+	db, service := setupTestService(t)
+	customer := testutils.CreateTestUser(t, db, models.RoleUser)
+	day := time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC)
+	payment := createPayment(t, db, customer, 249, 0, day)
+	closed := day.Add(30 * 24 * time.Hour)
+	service.now = func() time.Time { return closed }
+
+	update := DisputeUpdate{StripeDisputeID: "dp_1", PaymentIntentID: "pi_" + payment.ID.String(), Amount: 249,
+		Currency: "EUR", Reason: "product_not_received", Status: "needs_response", OpenedAt: day.Add(time.Hour)}
+	dispute, err := service.RecordDispute(update)
+	require.NoError(t, err)
+	assert.Equal(t, payment.ID, dispute.PaymentID)
+	assert.Nil(t, dispute.ClosedAt)
+
+	statement, err := service.ForUser(customer.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 0.0, statement.Balance, "the disputed amount was withdrawn")
+
+	update.Status = "won"
+	dispute, err = service.RecordDispute(update)
+	require.NoError(t, err)
+	require.NotNil(t, dispute.ClosedAt)
+	assert.Equal(t, closed, *dispute.ClosedAt)
+	var count int64
+	require.NoError(t, db.Model(&models.PaymentDispute{}).Count(&count).Error)
+	assert.Equal(t, int64(1), count)
+
+	statement, err = service.ForUser(customer.ID)
+	require.NoError(t, err)
+	require.Len(t, statement.Lines, 3)
+	assert.Equal(t, LineDisputeWon, statement.Lines[2].Type)
+	assert.Equal(t, 249.0, statement.Balance)
+	assert.Equal(t, 0.0, statement.Disputed)
+
+	_, err = service.RecordDispute(DisputeUpdate{StripeDisputeID: "dp_2", PaymentIntentID: "pi_unknown"})
+	assert.ErrorIs(t, err, ErrPaymentNotFound)
+}
+
+func setupTestService(t *testing.T) (*gorm.DB, *Service) {
+	t.Helper()
+	db := testutils.NewSQLiteDB(t, &models.User{}, &models.Lead{}, &models.Booking{}, &models.Payment{},
+		&models.CreditEntry{}, &models.RefundRequest{}, &models.PaymentDispute{})
+	return db, NewService(db, zap.NewNop())
+}
+
+func createPayment(t *testing.T, db *gorm.DB, customer *models.User, amount, creditAmount float64, paidAt time.Time) *models.Payment {
+	t.Helper()
+	lead := &models.Lead{UserID: customer.ID, Title: "Elterngeld-Beratung"}
+	require.NoError(t, db.Create(lead).Error)
+	id := uuid.New()
+	payment := &models.Payment{
+		ID:                  id,
+		UserID:              customer.ID,
+		LeadID:              lead.ID,
+		Amount:              amount,
+		CreditAmount:        creditAmount,
+		Currency:            "EUR",
+		Status:              models.PaymentStatusSucceeded,
+		StripeSessionID:     "cs_" + id.String(),
+		StripePaymentIntent: "pi_" + id.String(),
+		PaidAt:              &paidAt,
+	}
+	require.NoError(t, db.Create(payment).Error)
+	return payment
+}
+
+func createBooking(t *testing.T, db *gorm.DB, customer *models.User, payment *models.Payment) *models.Booking {
+	t.Helper()
+	booking := &models.Booking{
+		UserID:      customer.ID,
+		PaymentID:   &payment.ID,
+		Status:      models.BookingStatusConfirmed,
+		TotalAmount: payment.Amount + payment.CreditAmount,
+	}
+	require.NoError(t, db.Create(booking).Error)
+	return booking
+}
+
+func createEntry(t *testing.T, db *gorm.DB, entry models.CreditEntry) {
+	t.Helper()
+	require.NoError(t, db.Create(&entry).Error)
+}
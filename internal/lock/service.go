@@ -0,0 +1,170 @@
+// Package lock keeps scheduled jobs from running on several instances at
+// once. A job is guarded by a lease in the job_locks table: the instance
+// holding the lease runs the job and renews the lease while it runs; when the
+// instance stops or fails to renew, another instance takes the lease over once
+// it expired. The table works with SQLite and PostgreSQL alike, so no lock
+// server is needed.
+package lock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"elterngeld-portal/internal/models"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrInvalidTTL is returned when a lease would expire immediately
+	ErrInvalidTTL = errors.New("lock ttl must be positive")
+)
+
+// Service acquires and renews job leases on behalf of this instance
+type Service struct {
+	db     *gorm.DB
+	logger *zap.Logger
+	now    func() time.Time
+	// owner identifies this instance in the leases it holds
+	owner string
+}
+
+func NewService(db *gorm.DB, logger *zap.Logger) *Service {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	return &Service{
+		db:     db,
+		logger: logger,
+		now:    time.Now,
+		owner:  host + "-" + uuid.NewString()[:8],
+	}
+}
+
+// TryAcquire takes the lease on name for ttl when it is free, expired or
+// already held by this instance, in which case it is renewed. It reports
+// whether this instance holds the lease.
+func (s *Service) TryAcquire(name string, ttl time.Duration) (bool, error) {
+	if ttl <= 0 {
+		return false, ErrInvalidTTL
+	}
+
+	now := s.now()
+	expires := now.Add(ttl)
+	create := s.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.JobLock{
+		Name:      name,
+		Owner:     s.owner,
+		ExpiresAt: expires,
+		UpdatedAt: now,
+	})
+	if create.Error != nil {
+		return false, fmt.Errorf("failed to create lock %s: %w", name, create.Error)
+	}
+	if create.RowsAffected == 1 {
+		return true, nil
+	}
+
+	// The update only matches when the lease is ours or expired, so of
+	// several instances trying at the same time only one gets it
+	update := s.db.Model(&models.JobLock{}).
+		Where("name = ? AND (owner = ? OR expires_at <= ?)", name, s.owner, now).
+		Updates(map[string]interface{}{
+			"owner":      s.owner,
+			"expires_at": expires,
+			"updated_at": now,
+		})
+	if update.Error != nil {
+		return false, fmt.Errorf("failed to acquire lock %s: %w", name, update.Error)
+	}
+	return update.RowsAffected == 1, nil
+}
+
+// Release gives up the lease on name if this instance holds it, so another
+// instance can take it over without waiting for it to expire
+func (s *Service) Release(name string) error {
+	if err := s.db.Where("name = ? AND owner = ?", name, s.owner).Delete(&models.JobLock{}).Error; err != nil {
+		return fmt.Errorf("failed to release lock %s: %w", name, err)
+	}
+	return nil
+}
+
+// Lead runs a job on one instance at a time. It waits until this instance
+// gets the lease on name and then calls run, renewing the lease every third
+// of ttl. When a renewal fails, the context passed to run is cancelled and
+// this instance competes for the lease again once run returned. Lead returns
+// when ctx is cancelled, releasing the lease, or when run returns by itself.
+func (s *Service) Lead(ctx context.Context, name string, ttl time.Duration, run func(ctx context.Context)) {
+	if ttl <= 0 {
+		s.logger.Error("Invalid lock ttl, job not started", zap.String("lock", name), zap.Duration("ttl", ttl))
+		return
+	}
+
+	ticker := time.NewTicker(ttl / 3)
+	defer ticker.Stop()
+
+	for {
+		held, err := s.TryAcquire(name, ttl)
+		if err != nil {
+			s.logger.Error("Failed to acquire job lock", zap.String("lock", name), zap.Error(err))
+		}
+		if held && s.hold(ctx, name, ttl, run) {
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// hold runs the job while this instance keeps the lease. It reports whether
+// the job is over, because ctx was cancelled or run returned by itself.
+func (s *Service) hold(ctx context.Context, name string, ttl time.Duration, run func(ctx context.Context)) bool {
+	s.logger.Info("Acquired job lock", zap.String("lock", name), zap.String("owner", s.owner))
+
+	jobCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		run(jobCtx)
+	}()
+
+	ticker := time.NewTicker(ttl / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			s.release(name)
+			return true
+		case <-ctx.Done():
+			<-done
+			s.release(name)
+			return true
+		case <-ticker.C:
+			held, err := s.TryAcquire(name, ttl)
+			if err != nil || !held {
+				s.logger.Warn("Lost job lock, stopping job", zap.String("lock", name), zap.Error(err))
+				cancel()
+				<-done
+				return false
+			}
+		}
+	}
+}
+
+func (s *Service) release(name string) {
+	if err := s.Release(name); err != nil {
+		s.logger.Error("Failed to release job lock", zap.String("lock", name), zap.Error(err))
+	}
+}
@@ -0,0 +1,121 @@
+package lock
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/tests/testutils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+func TestTryAcquire(t *testing.T) {
+	db := setupTestDB(t)
+	now := time.Date(2026, 5, 4, 10, 0, 0, 0, time.UTC)
+	first, second := NewService(db, zap.NewNop()), NewService(db, zap.NewNop())
+	first.now = func() time.Time { return now }
+	second.now = func() time.Time { return now }
+
+	_, err := first.TryAcquire("noshow", 0)
+	assert.ErrorIs(t, err, ErrInvalidTTL)
+
+	held, err := first.TryAcquire("noshow", time.Minute)
+	require.NoError(t, err)
+	assert.True(t, held)
+	held, err = second.TryAcquire("noshow", time.Minute)
+	require.NoError(t, err)
+	assert.False(t, held)
+
+	// Other jobs have their own lease
+	held, err = second.TryAcquire("digest", time.Minute)
+	require.NoError(t, err)
+	assert.True(t, held)
+
+	// The holder renews its lease, so it does not expire while it runs
+	now = now.Add(50 * time.Second)
+	held, err = first.TryAcquire("noshow", time.Minute)
+	require.NoError(t, err)
+	assert.True(t, held)
+	now = now.Add(50 * time.Second)
+	held, err = second.TryAcquire("noshow", time.Minute)
+	require.NoError(t, err)
+	assert.False(t, held)
+
+	// An expired lease is taken over
+	now = now.Add(time.Minute)
+	held, err = second.TryAcquire("noshow", time.Minute)
+	require.NoError(t, err)
+	assert.True(t, held)
+	held, err = first.TryAcquire("noshow", time.Minute)
+	require.NoError(t, err)
+	assert.False(t, held)
+
+	// Only the holder releases a lease
+	require.NoError(t, first.Release("noshow"))
+	held, err = first.TryAcquire("noshow", time.Minute)
+	require.NoError(t, err)
+	assert.False(t, held)
+	require.NoError(t, second.Release("noshow"))
+	held, err = first.TryAcquire("noshow", time.Minute)
+	require.NoError(t, err)
+	assert.True(t, held)
+}
+
+func TestLead(t *testing.T) {
+	db := setupTestDB(t)
+	first, second := NewService(db, zap.NewNop()), NewService(db, zap.NewNop())
+
+	var running, runs int32
+	job := func(ctx context.Context) {
+		atomic.AddInt32(&running, 1)
+		atomic.AddInt32(&runs, 1)
+		defer atomic.AddInt32(&running, -1)
+		<-ctx.Done()
+	}
+
+	firstCtx, stopFirst := context.WithCancel(context.Background())
+	firstDone := make(chan struct{})
+	go func() {
+		defer close(firstDone)
+		first.Lead(firstCtx, "digest", 300*time.Millisecond, job)
+	}()
+	require.Eventually(t, func() bool { return atomic.LoadInt32(&running) == 1 }, time.Second, 5*time.Millisecond)
+
+	secondCtx, stopSecond := context.WithCancel(context.Background())
+	secondDone := make(chan struct{})
+	go func() {
+		defer close(secondDone)
+		second.Lead(secondCtx, "digest", 300*time.Millisecond, job)
+	}()
+
+	// The second instance waits while the first renews its lease
+	time.Sleep(400 * time.Millisecond)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&running))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&runs))
+
+	// Stopping the first instance releases the lease to the second
+	stopFirst()
+	<-firstDone
+	require.Eventually(t, func() bool { return atomic.LoadInt32(&runs) == 2 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&running))
+
+	stopSecond()
+	<-secondDone
+	assert.Zero(t, atomic.LoadInt32(&running))
+	var count int64
+	require.NoError(t, db.Model(&models.JobLock{}).Count(&count).Error)
+	assert.Zero(t, count)
+}
+
+func setupTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db := testutils.NewSQLiteDB(t, &models.JobLock{})
+
+	return db
+}
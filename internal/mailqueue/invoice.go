@@ -0,0 +1,115 @@
+package mailqueue
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/pkg/i18n"
+	"elterngeld-portal/pkg/pdf"
+)
+
+// InvoiceNumber returns the invoice number of a payment; it is derived from
+// the booking reference so customers can match invoice and booking
+func InvoiceNumber(payment *models.Payment, booking *models.Booking) string {
+	if booking.BookingReference != "" {
+		return "RE-" + strings.TrimPrefix(booking.BookingReference, "BK-")
+	}
+	return "RE-" + strings.ToUpper(payment.ID.String()[:8])
+}
+
+// InvoiceFileName is the name of the invoice attachment
+func InvoiceFileName(payment *models.Payment, booking *models.Booking) string {
+	return fmt.Sprintf("Rechnung-%s.pdf", InvoiceNumber(payment, booking))
+}
+
+// RenderInvoice renders the invoice attached to a payment receipt, with dates
+// in the customer's timezone
+func RenderInvoice(payment *models.Payment, booking *models.Booking, customer *models.User) []byte {
+	lang := i18n.DefaultLanguage
+	loc := customer.TimeLocation()
+	price := func(amount float64) string {
+		return i18n.FormatCurrency(lang, amount, payment.Currency)
+	}
+
+	doc := pdf.New()
+	doc.SetFooter("Rechnung · Elterngeld-Portal")
+	doc.Title("Rechnung")
+	doc.Field("Rechnungsnummer", InvoiceNumber(payment, booking))
+	if payment.PaidAt != nil {
+		doc.Field("Rechnungsdatum", payment.PaidAt.In(loc).Format("02.01.2006"))
+	}
+	if booking.BookingReference != "" {
+		doc.Field("Buchungsnummer", booking.BookingReference)
+	}
+
+	// Business customers are billed to their company, for the attention of
+	// the customer
+	doc.Heading("Rechnungsempfänger")
+	name := payment.BillingName
+	if name == "" {
+		name = customer.FullName()
+	}
+	if booking.CompanyName != "" {
+		doc.Text(booking.CompanyName)
+		name = "z. Hd. " + name
+	}
+	doc.Text(name)
+	address := booking.BillingAddress
+	if address == "" {
+		address = payment.BillingAddress
+	}
+	for _, line := range strings.Split(address, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			doc.Text(line)
+		}
+	}
+	doc.Text(customer.Email)
+	vatID := payment.CustomerVATID
+	if vatID == "" {
+		vatID = booking.VATID
+	}
+	if vatID != "" {
+		doc.Text("USt-IdNr.: " + vatID)
+	}
+
+	doc.Heading("Leistung")
+	service := booking.Title
+	if booking.Package != nil && booking.Package.Name != "" {
+		service = booking.Package.Name
+	}
+	doc.Field(service, price(payment.Amount+payment.CreditAmount))
+	doc.Field("Termin", booking.StartTime.In(loc).Format("02.01.2006 15:04"))
+
+	// Payments from before tax was recorded have no treatment
+	if payment.TaxTreatment != "" {
+		doc.Heading("Umsatzsteuer")
+		doc.Field("Nettobetrag", price(payment.NetAmount))
+		rate := strconv.FormatFloat(math.Round(payment.TaxRate*10000)/100, 'f', -1, 64)
+		doc.Field(fmt.Sprintf("USt. %s %%", strings.Replace(rate, ".", ",", 1)), price(payment.TaxAmount))
+		doc.Field("Gesamtbetrag", price(payment.NetAmount+payment.TaxAmount))
+	}
+
+	doc.Heading("Zahlung")
+	if payment.CreditAmount > 0 {
+		doc.Field("Abzüglich Guthaben", price(-payment.CreditAmount))
+	}
+	doc.Field("Bezahlt", price(payment.Amount))
+	doc.Field("Zahlungsart", payment.Method.GetDisplayName())
+	if payment.PaidAt != nil {
+		doc.Field("Zahlungsdatum", payment.PaidAt.In(loc).Format("02.01.2006"))
+	}
+
+	doc.Space()
+	switch payment.TaxTreatment {
+	case models.TaxTreatmentReverseCharge:
+		doc.Text("Steuerschuldnerschaft des Leistungsempfängers (Reverse Charge).")
+	case models.TaxTreatmentNonEU:
+		doc.Text("Nicht im Inland steuerbare Leistung.")
+	}
+	doc.Text("Vielen Dank für Ihre Buchung.")
+
+	return doc.Bytes()
+}
@@ -0,0 +1,335 @@
+// Package mailqueue sends the email notifications stored in the database,
+// retrying failed emails with backoff so a short SMTP outage does not lose
+// payment receipts or booking confirmations.
+package mailqueue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"elterngeld-portal/internal/models"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	// maxRetries is how often a failed receipt or confirmation is sent again
+	maxRetries = 5
+	batchSize  = 50
+)
+
+// Mailer renders and sends queued emails
+type Mailer interface {
+	SendPaymentReceipt(payment *models.Payment, booking *models.Booking, customer *models.User, invoice []byte) error
+	SendBookingConfirmation(booking *models.Booking, customer *models.User) error
+	// SendNotification sends the title and message of a notification without a dedicated template
+	SendNotification(notification *models.Notification) error
+}
+
+// Service queues transactional emails as notifications and sends them in the background
+type Service struct {
+	db     *gorm.DB
+	logger *zap.Logger
+	mailer Mailer
+	now    func() time.Time
+
+	// mu guards inFlight, the emails this instance is sending
+	mu       sync.Mutex
+	inFlight map[uuid.UUID]struct{}
+}
+
+func NewService(db *gorm.DB, logger *zap.Logger, mailer Mailer) *Service {
+	return &Service{
+		db:       db,
+		logger:   logger,
+		mailer:   mailer,
+		now:      time.Now,
+		inFlight: make(map[uuid.UUID]struct{}),
+	}
+}
+
+// reference links a queued email to the records it is rendered from
+type reference struct {
+	BookingID uuid.UUID  `json:"booking_id"`
+	PaymentID *uuid.UUID `json:"payment_id,omitempty"`
+}
+
+// EnqueuePaymentReceipt queues the receipt for a completed payment of a
+// booking. The invoice is rendered and attached when the email is sent.
+func (s *Service) EnqueuePaymentReceipt(payment *models.Payment, booking *models.Booking) error {
+	return s.enqueue(booking, models.EmailTemplatePaymentReceived,
+		"Zahlungsbestätigung",
+		fmt.Sprintf("Ihre Zahlung für '%s' ist eingegangen. Die Rechnung finden Sie im Anhang.", booking.Title),
+		reference{BookingID: booking.ID, PaymentID: &payment.ID})
+}
+
+// EnqueueBookingConfirmation queues the confirmation for a booking that was confirmed
+func (s *Service) EnqueueBookingConfirmation(booking *models.Booking) error {
+	return s.enqueue(booking, models.EmailTemplateBookingConfirmation,
+		"Buchungsbestätigung",
+		fmt.Sprintf("Ihr Termin '%s' ist bestätigt.", booking.Title),
+		reference{BookingID: booking.ID})
+}
+
+func (s *Service) enqueue(booking *models.Booking, template models.EmailTemplate, title, message string, ref reference) error {
+	var customer models.User
+	if err := s.db.Select("id", "email").First(&customer, "id = ?", booking.UserID).Error; err != nil {
+		return fmt.Errorf("failed to load customer: %w", err)
+	}
+
+	data, err := json.Marshal(ref)
+	if err != nil {
+		return err
+	}
+
+	notification := models.Notification{
+		UserID:     customer.ID,
+		Type:       models.NotificationTypeEmail,
+		Status:     models.NotificationStatusPending,
+		Title:      title,
+		Message:    message,
+		Data:       string(data),
+		Template:   string(template),
+		Recipient:  customer.NotificationAddress(),
+		MaxRetries: maxRetries,
+	}
+	if err := s.db.Create(&notification).Error; err != nil {
+		return fmt.Errorf("failed to queue email: %w", err)
+	}
+	return nil
+}
+
+// Dispatch sends the queued emails that are due and returns the number of
+// sent emails. A failed email is retried with backoff until it used up its
+// retries.
+func (s *Service) Dispatch() (int, error) {
+	return s.dispatch(context.Background())
+}
+
+// dispatch sends the due emails until the context is cancelled; the emails
+// left are sent by the next dispatch
+func (s *Service) dispatch(ctx context.Context) (int, error) {
+	now := s.now()
+
+	var ids []uuid.UUID
+	err := s.db.Model(&models.Notification{}).
+		Where("type = ? AND (status = ? OR (status = ? AND next_retry_at <= ?)) AND (schedule_at IS NULL OR schedule_at <= ?)",
+			models.NotificationTypeEmail, models.NotificationStatusPending, models.NotificationStatusRetrying, now, now).
+		Order("priority DESC, created_at ASC").Limit(batchSize).Pluck("id", &ids).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to load queued emails: %w", err)
+	}
+
+	sent := 0
+	for _, id := range ids {
+		if ctx.Err() != nil {
+			break
+		}
+		if s.process(id) {
+			sent++
+		}
+	}
+	return sent, nil
+}
+
+// process sends a queued email. The status update claims the email so it is
+// not sent twice when several instances dispatch at the same time.
+func (s *Service) process(id uuid.UUID) bool {
+	claim := s.db.Model(&models.Notification{}).
+		Where("id = ? AND status IN ?", id, []models.NotificationStatus{models.NotificationStatusPending, models.NotificationStatusRetrying}).
+		Update("status", models.NotificationStatusSending)
+	if claim.Error != nil {
+		s.logger.Error("Failed to claim queued email", zap.String("notification_id", id.String()), zap.Error(claim.Error))
+		return false
+	}
+	if claim.RowsAffected == 0 {
+		return false
+	}
+	s.track(id, true)
+	defer s.track(id, false)
+
+	var notification models.Notification
+	if err := s.db.First(&notification, "id = ?", id).Error; err != nil {
+		s.logger.Error("Failed to load queued email", zap.String("notification_id", id.String()), zap.Error(err))
+		return false
+	}
+	if s.batch(&notification) {
+		return false
+	}
+
+	now := s.now()
+	sendErr := s.send(&notification)
+
+	updates := map[string]interface{}{}
+	if sendErr == nil {
+		updates["status"] = models.NotificationStatusSent
+		updates["sent_at"] = &now
+		updates["error_message"] = ""
+		updates["next_retry_at"] = nil
+	} else {
+		retries := notification.RetryCount + 1
+		updates["retry_count"] = retries
+		updates["error_message"] = sendErr.Error()
+		if retries <= notification.MaxRetries {
+			// Exponential backoff like webhook event retries
+			next := now.Add(time.Duration(retries*retries) * time.Minute)
+			updates["status"] = models.NotificationStatusRetrying
+			updates["next_retry_at"] = &next
+		} else {
+			updates["status"] = models.NotificationStatusFailed
+			updates["failed_at"] = &now
+			updates["next_retry_at"] = nil
+		}
+		s.logger.Error("Failed to send queued email",
+			zap.String("notification_id", id.String()),
+			zap.String("template", notification.Template),
+			zap.Int("retry_count", retries),
+			zap.Error(sendErr))
+	}
+
+	if err := s.db.Model(&notification).Updates(updates).Error; err != nil {
+		s.logger.Error("Failed to update queued email", zap.String("notification_id", id.String()), zap.Error(err))
+	}
+	return sendErr == nil
+}
+
+// batch holds a notification back for the digest when the user chose an
+// hourly or daily digest for its category. It reports whether the
+// notification was held back.
+func (s *Service) batch(notification *models.Notification) bool {
+	category, ok := models.EmailTemplate(notification.Template).DigestCategory()
+	if !ok {
+		return false
+	}
+
+	var preference models.NotificationPreference
+	if err := s.db.Where("user_id = ?", notification.UserID).First(&preference).Error; err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			s.logger.Warn("Failed to load notification preferences, sending immediately",
+				zap.String("notification_id", notification.ID.String()), zap.Error(err))
+		}
+		return false
+	}
+	if preference.DigestModeFor(category) == models.DigestModeImmediate {
+		return false
+	}
+
+	if err := s.db.Model(notification).Update("status", models.NotificationStatusBatched).Error; err != nil {
+		s.logger.Error("Failed to batch queued email", zap.String("notification_id", notification.ID.String()), zap.Error(err))
+		return false
+	}
+	return true
+}
+
+// send renders a queued email from the records it refers to, so it always
+// shows their current state
+func (s *Service) send(notification *models.Notification) error {
+	switch models.EmailTemplate(notification.Template) {
+	case models.EmailTemplatePaymentReceived:
+		ref, booking, customer, err := s.load(notification)
+		if err != nil {
+			return err
+		}
+		if ref.PaymentID == nil {
+			return errors.New("queued receipt has no payment")
+		}
+		var payment models.Payment
+		if err := s.db.First(&payment, "id = ?", *ref.PaymentID).Error; err != nil {
+			return fmt.Errorf("failed to load payment: %w", err)
+		}
+		invoice := RenderInvoice(&payment, booking, customer)
+		return s.mailer.SendPaymentReceipt(&payment, booking, customer, invoice)
+	case models.EmailTemplateBookingConfirmation:
+		_, booking, customer, err := s.load(notification)
+		if err != nil {
+			return err
+		}
+		return s.mailer.SendBookingConfirmation(booking, customer)
+	default:
+		return s.mailer.SendNotification(notification)
+	}
+}
+
+func (s *Service) load(notification *models.Notification) (*reference, *models.Booking, *models.User, error) {
+	var ref reference
+	if err := json.Unmarshal([]byte(notification.Data), &ref); err != nil {
+		return nil, nil, nil, fmt.Errorf("invalid queued email data: %w", err)
+	}
+
+	var booking models.Booking
+	if err := s.db.Preload("Package").Preload("Timeslot").First(&booking, "id = ?", ref.BookingID).Error; err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to load booking: %w", err)
+	}
+	var customer models.User
+	if err := s.db.First(&customer, "id = ?", booking.UserID).Error; err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to load customer: %w", err)
+	}
+	return &ref, &booking, &customer, nil
+}
+
+// Run sends queued emails every interval until the context is cancelled.
+// Emails interrupted by a shutdown are sent again.
+func (s *Service) Run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	if err := s.db.Model(&models.Notification{}).
+		Where("type = ? AND status = ?", models.NotificationTypeEmail, models.NotificationStatusSending).
+		Update("status", models.NotificationStatusPending).Error; err != nil {
+		s.logger.Error("Failed to reset interrupted emails", zap.Error(err))
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := s.dispatch(ctx); err != nil {
+			s.logger.Error("Failed to send queued emails", zap.Error(err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Requeue returns the emails this instance is still sending to pending, so
+// they are sent again when a shutdown cannot wait for them
+func (s *Service) Requeue() (int64, error) {
+	s.mu.Lock()
+	ids := make([]uuid.UUID, 0, len(s.inFlight))
+	for id := range s.inFlight {
+		ids = append(ids, id)
+	}
+	s.mu.Unlock()
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	result := s.db.Model(&models.Notification{}).
+		Where("id IN ? AND status = ?", ids, models.NotificationStatusSending).
+		Update("status", models.NotificationStatusPending)
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to requeue emails: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
+
+func (s *Service) track(id uuid.UUID, inFlight bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if inFlight {
+		s.inFlight[id] = struct{}{}
+	} else {
+		delete(s.inFlight, id)
+	}
+}
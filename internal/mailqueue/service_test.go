@@ -0,0 +1,286 @@
+package mailqueue
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/tests/testutils"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type fakeMailer struct {
+	fail          bool
+	receipts      []*models.Payment
+	invoices      [][]byte
+	confirmations []*models.Booking
+	notifications []*models.Notification
+}
+
+func (m *fakeMailer) SendPaymentReceipt(payment *models.Payment, booking *models.Booking, customer *models.User, invoice []byte) error {
+	if m.fail {
+		return errors.New("smtp unavailable")
+	}
+	m.receipts = append(m.receipts, payment)
+	m.invoices = append(m.invoices, invoice)
+	return nil
+}
+
+func (m *fakeMailer) SendBookingConfirmation(booking *models.Booking, customer *models.User) error {
+	if m.fail {
+		return errors.New("smtp unavailable")
+	}
+	m.confirmations = append(m.confirmations, booking)
+	return nil
+}
+
+func (m *fakeMailer) SendNotification(notification *models.Notification) error {
+	if m.fail {
+		return errors.New("smtp unavailable")
+	}
+	m.notifications = append(m.notifications, notification)
+	return nil
+}
+
+func TestDispatch(t *testing.T) {
+	db, service, mailer := setupTestService(t)
+	customer := testutils.CreateTestUser(t, db, models.RoleUser)
+	booking, payment := createPaidBooking(t, db, customer)
+
+	require.NoError(t, service.EnqueuePaymentReceipt(payment, booking))
+	require.NoError(t, service.EnqueueBookingConfirmation(booking))
+	// Notifications without a dedicated template are sent as plain emails
+	require.NoError(t, db.Create(&models.Notification{
+		UserID:    customer.ID,
+		Type:      models.NotificationTypeEmail,
+		Title:     "Neue Nachricht",
+		Message:   "Sie haben eine neue Nachricht erhalten.",
+		Template:  string(models.EmailTemplateLeadEmailReceived),
+		Recipient: customer.Email,
+	}).Error)
+	// In-app notifications are not emailed
+	require.NoError(t, db.Create(&models.Notification{
+		UserID:    customer.ID,
+		Type:      models.NotificationTypeInApp,
+		Title:     "Neue Nachricht",
+		Message:   "Sie haben eine neue Nachricht erhalten.",
+		Recipient: customer.Email,
+	}).Error)
+
+	sent, err := service.Dispatch()
+	require.NoError(t, err)
+	assert.Equal(t, 3, sent)
+
+	require.Len(t, mailer.receipts, 1)
+	assert.Equal(t, payment.ID, mailer.receipts[0].ID)
+	assert.True(t, bytes.HasPrefix(mailer.invoices[0], []byte("%PDF-")))
+	require.Len(t, mailer.confirmations, 1)
+	assert.Equal(t, booking.ID, mailer.confirmations[0].ID)
+	assert.Len(t, mailer.notifications, 1)
+
+	// Sent emails are not sent again
+	sent, err = service.Dispatch()
+	require.NoError(t, err)
+	assert.Equal(t, 0, sent)
+
+	var count int64
+	require.NoError(t, db.Model(&models.Notification{}).Where("status = ?", models.NotificationStatusSent).Count(&count).Error)
+	assert.Equal(t, int64(3), count)
+}
+
+func TestDispatchRetries(t *testing.T) {
+	db, service, mailer := setupTestService(t)
+	customer := testutils.CreateTestUser(t, db, models.RoleUser)
+	booking, _ := createPaidBooking(t, db, customer)
+	require.NoError(t, service.EnqueueBookingConfirmation(booking))
+
+	now := time.Now()
+	service.now = func() time.Time { return now }
+	mailer.fail = true
+
+	load := func() models.Notification {
+		var notification models.Notification
+		require.NoError(t, db.First(&notification, "user_id = ?", customer.ID).Error)
+		return notification
+	}
+
+	sent, err := service.Dispatch()
+	require.NoError(t, err)
+	assert.Equal(t, 0, sent)
+
+	notification := load()
+	assert.Equal(t, models.NotificationStatusRetrying, notification.Status)
+	assert.Equal(t, 1, notification.RetryCount)
+	assert.Equal(t, "smtp unavailable", notification.ErrorMessage)
+	require.NotNil(t, notification.NextRetryAt)
+
+	// Not due yet
+	sent, err = service.Dispatch()
+	require.NoError(t, err)
+	assert.Equal(t, 0, sent)
+	assert.Equal(t, 1, load().RetryCount)
+
+	// Every retry waits longer until the retries are used up
+	for retry := 2; retry <= maxRetries+1; retry++ {
+		now = now.Add(time.Duration(retry*retry) * time.Minute)
+		_, err := service.Dispatch()
+		require.NoError(t, err)
+		assert.Equal(t, retry, load().RetryCount)
+	}
+	notification = load()
+	assert.Equal(t, models.NotificationStatusFailed, notification.Status)
+	assert.NotNil(t, notification.FailedAt)
+	assert.Nil(t, notification.NextRetryAt)
+
+	// Failed emails stay failed
+	mailer.fail = false
+	now = now.Add(24 * time.Hour)
+	sent, err = service.Dispatch()
+	require.NoError(t, err)
+	assert.Equal(t, 0, sent)
+}
+
+func TestDispatchRecovers(t *testing.T) {
+	db, service, mailer := setupTestService(t)
+	customer := testutils.CreateTestUser(t, db, models.RoleUser)
+	booking, payment := createPaidBooking(t, db, customer)
+	require.NoError(t, service.EnqueuePaymentReceipt(payment, booking))
+
+	now := time.Now()
+	service.now = func() time.Time { return now }
+
+	mailer.fail = true
+	_, err := service.Dispatch()
+	require.NoError(t, err)
+
+	mailer.fail = false
+	now = now.Add(time.Minute)
+	sent, err := service.Dispatch()
+	require.NoError(t, err)
+	assert.Equal(t, 1, sent)
+
+	var notification models.Notification
+	require.NoError(t, db.First(&notification, "user_id = ?", customer.ID).Error)
+	assert.Equal(t, models.NotificationStatusSent, notification.Status)
+	assert.Empty(t, notification.ErrorMessage)
+	assert.NotNil(t, notification.SentAt)
+}
+
+func TestDispatchBatchesDigestNotifications(t *testing.T) {
+	db, service, mailer := setupTestService(t)
+	customer := testutils.CreateTestUser(t, db, models.RoleUser)
+	booking, _ := createPaidBooking(t, db, customer)
+	require.NoError(t, db.Create(&models.NotificationPreference{
+		UserID:     customer.ID,
+		LeadDigest: models.DigestModeDaily,
+	}).Error)
+
+	require.NoError(t, service.EnqueueBookingConfirmation(booking))
+	lead := &models.Notification{
+		UserID:    customer.ID,
+		Type:      models.NotificationTypeEmail,
+		Title:     "Neue Nachricht",
+		Message:   "Sie haben eine neue Nachricht erhalten.",
+		Template:  string(models.EmailTemplateLeadEmailReceived),
+		Recipient: customer.Email,
+	}
+	require.NoError(t, db.Create(lead).Error)
+
+	// Confirmations are always sent immediately, lead notifications wait for the digest
+	sent, err := service.Dispatch()
+	require.NoError(t, err)
+	assert.Equal(t, 1, sent)
+	assert.Len(t, mailer.confirmations, 1)
+	assert.Empty(t, mailer.notifications)
+
+	var notification models.Notification
+	require.NoError(t, db.First(&notification, "id = ?", lead.ID).Error)
+	assert.Equal(t, models.NotificationStatusBatched, notification.Status)
+}
+
+func TestInvoiceNumber(t *testing.T) {
+	payment := &models.Payment{ID: uuid.MustParse("3f2a9c1e-0000-0000-0000-000000000000")}
+
+	assert.Equal(t, "RE-2026-ab12cd34", InvoiceNumber(payment, &models.Booking{BookingReference: "BK-2026-ab12cd34"}))
+	assert.Equal(t, "RE-3F2A9C1E", InvoiceNumber(payment, &models.Booking{}))
+	assert.Equal(t, "Rechnung-RE-3F2A9C1E.pdf", InvoiceFileName(payment, &models.Booking{}))
+}
+
+func TestRenderInvoice_Business(t *testing.T) {
+	paidAt := time.Now()
+	payment := &models.Payment{
+		ID:            uuid.New(),
+		Amount:        167.23,
+		Currency:      "EUR",
+		PaidAt:        &paidAt,
+		NetAmount:     167.23,
+		TaxCountry:    "AT",
+		TaxTreatment:  models.TaxTreatmentReverseCharge,
+		CustomerVATID: "ATU12345678",
+	}
+	booking := &models.Booking{
+		Title:          "Beratung",
+		StartTime:      paidAt,
+		CompanyName:    "Beratung GmbH",
+		VATID:          "ATU12345678",
+		BillingAddress: "Ringstrasse 1\n1010 Wien",
+	}
+	customer := &models.User{FirstName: "Test", LastName: "Kunde", Email: "kunde@example.com"}
+
+	invoice := RenderInvoice(payment, booking, customer)
+	for _, text := range []string{"Beratung GmbH", "z. Hd. Test Kunde", "Ringstrasse 1", "1010 Wien", "USt-IdNr.: ATU12345678", "Reverse Charge"} {
+		assert.True(t, bytes.Contains(invoice, []byte(text)), text)
+	}
+}
+
+func createPaidBooking(t *testing.T, db *gorm.DB, customer *models.User) (*models.Booking, *models.Payment) {
+	t.Helper()
+	paidAt := time.Now()
+	payment := &models.Payment{
+		UserID:          customer.ID,
+		Amount:          70,
+		CreditAmount:    50,
+		Status:          models.PaymentStatusSucceeded,
+		StripeSessionID: "cs_" + uuid.New().String(),
+		PaidAt:          &paidAt,
+	}
+	require.NoError(t, db.Create(payment).Error)
+
+	start := time.Now().Add(48 * time.Hour)
+	booking := &models.Booking{
+		UserID:      customer.ID,
+		PaymentID:   &payment.ID,
+		Title:       "Erstberatung",
+		Status:      models.BookingStatusConfirmed,
+		ScheduledAt: start,
+		StartTime:   start,
+		EndTime:     start.Add(time.Hour),
+		TotalAmount: 120,
+		Currency:    "EUR",
+	}
+	require.NoError(t, db.Create(booking).Error)
+	return booking, payment
+}
+
+func setupTestService(t *testing.T) (*gorm.DB, *Service, *fakeMailer) {
+	t.Helper()
+	db := testutils.NewSQLiteDB(t,
+		&models.User{},
+		&models.Package{},
+		&models.Timeslot{},
+		&models.Payment{},
+		&models.Booking{},
+		&models.Notification{},
+		&models.NotificationPreference{},
+	)
+
+	mailer := &fakeMailer{}
+	return db, NewService(db, zap.NewNop(), mailer), mailer
+}
@@ -0,0 +1,356 @@
+package marketing
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/pkg/timeutil"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrSpendNotFound is returned for unknown marketing spend entries
+	ErrSpendNotFound = errors.New("marketing spend not found")
+	// ErrInvalidPeriod is returned for malformed dates or periods that end before they start
+	ErrInvalidPeriod = errors.New("invalid period")
+	// ErrInvalidCost is returned for negative costs
+	ErrInvalidCost = errors.New("invalid cost")
+)
+
+// Service records marketing spend and reports the return on it
+type Service struct {
+	db     *gorm.DB
+	logger *zap.Logger
+}
+
+func NewService(db *gorm.DB, logger *zap.Logger) *Service {
+	return &Service{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// SpendInput holds the fields of a marketing spend entry
+type SpendInput struct {
+	Channel     string  `json:"channel" binding:"required,max=100"` // utm_source, e.g. google
+	Campaign    string  `json:"campaign" binding:"max=200"`         // utm_campaign; empty for the whole channel
+	PeriodStart string  `json:"period_start" binding:"required,datetime=2006-01-02"`
+	PeriodEnd   string  `json:"period_end" binding:"required,datetime=2006-01-02"`
+	Cost        float64 `json:"cost"`
+	Note        string  `json:"note"`
+}
+
+// SpendFilter narrows the spend list down to entries overlapping a period and a channel
+type SpendFilter struct {
+	From    string
+	To      string
+	Channel string
+}
+
+// ListSpend returns the spend entries, latest period first
+func (s *Service) ListSpend(filter SpendFilter) ([]models.MarketingSpend, error) {
+	query := s.db.Model(&models.MarketingSpend{})
+	if filter.From != "" {
+		query = query.Where("period_end >= ?", filter.From)
+	}
+	if filter.To != "" {
+		query = query.Where("period_start <= ?", filter.To)
+	}
+	if filter.Channel != "" {
+		query = query.Where("channel = ?", normalize(filter.Channel))
+	}
+
+	var spend []models.MarketingSpend
+	if err := query.Order("period_start DESC, channel ASC, campaign ASC").Find(&spend).Error; err != nil {
+		return nil, err
+	}
+	return spend, nil
+}
+
+// CreateSpend records the cost of a channel or campaign
+func (s *Service) CreateSpend(input SpendInput, createdBy uuid.UUID) (*models.MarketingSpend, error) {
+	if err := validateSpend(input); err != nil {
+		return nil, err
+	}
+
+	spend := &models.MarketingSpend{CreatedBy: createdBy}
+	applySpend(spend, input)
+	if err := s.db.Create(spend).Error; err != nil {
+		return nil, fmt.Errorf("failed to create marketing spend: %w", err)
+	}
+	return spend, nil
+}
+
+// UpdateSpend replaces a spend entry
+func (s *Service) UpdateSpend(id uuid.UUID, input SpendInput) (*models.MarketingSpend, error) {
+	spend, err := s.getSpend(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateSpend(input); err != nil {
+		return nil, err
+	}
+
+	applySpend(spend, input)
+	if err := s.db.Select("channel", "campaign", "period_start", "period_end", "cost", "note").Save(spend).Error; err != nil {
+		return nil, fmt.Errorf("failed to update marketing spend: %w", err)
+	}
+	return spend, nil
+}
+
+// DeleteSpend removes a spend entry
+func (s *Service) DeleteSpend(id uuid.UUID) error {
+	spend, err := s.getSpend(id)
+	if err != nil {
+		return err
+	}
+	return s.db.Delete(spend).Error
+}
+
+func (s *Service) getSpend(id uuid.UUID) (*models.MarketingSpend, error) {
+	var spend models.MarketingSpend
+	err := s.db.First(&spend, "id = ?", id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrSpendNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &spend, nil
+}
+
+// ReportFilter is the period of an ROI report, as calendar days in a location
+type ReportFilter struct {
+	From     string
+	To       string
+	Location *time.Location
+}
+
+// ROIRow holds spend and attributed results of a campaign, a channel or all
+// channels. CAC is the spend per paying customer and ROAS the revenue per
+// euro spent; both are null when undefined.
+type ROIRow struct {
+	Channel   string   `json:"channel"`            // empty for leads without UTM source
+	Campaign  string   `json:"campaign,omitempty"` // empty for channel-wide spend
+	Spend     float64  `json:"spend"`
+	Leads     int64    `json:"leads"`
+	Customers int64    `json:"customers"`
+	Revenue   float64  `json:"revenue"`
+	CAC       *float64 `json:"cac"`
+	ROAS      *float64 `json:"roas"`
+}
+
+// ROIReport joins marketing spend with the revenue of the leads each campaign brought in
+type ROIReport struct {
+	From      string   `json:"from"`
+	To        string   `json:"to"`
+	Campaigns []ROIRow `json:"campaigns"`
+	Channels  []ROIRow `json:"channels"`
+	Total     ROIRow   `json:"total"`
+}
+
+// Report computes CAC and ROAS per campaign and channel. Spend is prorated to
+// the days of each entry within the period. Leads count by creation date;
+// revenue counts successful payments by payment date, net of refunds, and is
+// attributed to the UTM source and campaign of the paid lead.
+func (s *Service) Report(filter ReportFilter) (*ROIReport, error) {
+	from, err := time.Parse(timeutil.DateLayout, filter.From)
+	if err != nil {
+		return nil, ErrInvalidPeriod
+	}
+	to, err := time.Parse(timeutil.DateLayout, filter.To)
+	if err != nil || to.Before(from) {
+		return nil, ErrInvalidPeriod
+	}
+	loc := filter.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	start, _ := timeutil.ParseDate(filter.From, loc)
+	end, _ := timeutil.ParseDate(filter.To, loc)
+	end = timeutil.AddDays(end, 1, loc)
+
+	rows := make(map[[2]string]*ROIRow)
+	row := func(channel, campaign string) *ROIRow {
+		key := [2]string{normalize(channel), normalize(campaign)}
+		if rows[key] == nil {
+			rows[key] = &ROIRow{Channel: key[0], Campaign: key[1]}
+		}
+		return rows[key]
+	}
+
+	spend, err := s.ListSpend(SpendFilter{From: filter.From, To: filter.To})
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range spend {
+		row(entry.Channel, entry.Campaign).Spend += proratedCost(entry, from, to)
+	}
+
+	var leads []struct {
+		Source   string
+		Campaign string
+		Count    int64
+	}
+	if err := s.db.Model(&models.Lead{}).
+		Select("LOWER(utm_source) AS source, LOWER(utm_campaign) AS campaign, COUNT(*) AS count").
+		Where("created_at >= ? AND created_at < ?", start, end).
+		Group("LOWER(utm_source), LOWER(utm_campaign)").
+		Scan(&leads).Error; err != nil {
+		return nil, err
+	}
+	for _, lead := range leads {
+		row(lead.Source, lead.Campaign).Leads += lead.Count
+	}
+
+	var revenue []struct {
+		Source    string
+		Campaign  string
+		Customers int64
+		Revenue   float64
+	}
+	if err := s.db.Model(&models.Payment{}).
+		Select("LOWER(leads.utm_source) AS source, LOWER(leads.utm_campaign) AS campaign, "+
+			"COUNT(DISTINCT payments.user_id) AS customers, COALESCE(SUM(payments.amount - payments.refund_amount), 0) AS revenue").
+		Joins("JOIN leads ON leads.id = payments.lead_id").
+		Where("payments.status = ? AND payments.paid_at >= ? AND payments.paid_at < ?", models.PaymentStatusSucceeded, start, end).
+		Group("LOWER(leads.utm_source), LOWER(leads.utm_campaign)").
+		Scan(&revenue).Error; err != nil {
+		return nil, err
+	}
+	for _, result := range revenue {
+		r := row(result.Source, result.Campaign)
+		r.Customers += result.Customers
+		r.Revenue += result.Revenue
+	}
+
+	report := &ROIReport{From: filter.From, To: filter.To, Campaigns: []ROIRow{}, Channels: []ROIRow{}}
+	channels := make(map[string]*ROIRow)
+	for _, r := range rows {
+		report.Campaigns = append(report.Campaigns, *r)
+		if channels[r.Channel] == nil {
+			channels[r.Channel] = &ROIRow{Channel: r.Channel}
+		}
+		add(channels[r.Channel], r)
+		add(&report.Total, r)
+	}
+	for _, r := range channels {
+		report.Channels = append(report.Channels, *r)
+	}
+
+	for i := range report.Campaigns {
+		finish(&report.Campaigns[i])
+	}
+	for i := range report.Channels {
+		finish(&report.Channels[i])
+	}
+	finish(&report.Total)
+	sortRows(report.Campaigns)
+	sortRows(report.Channels)
+
+	return report, nil
+}
+
+// proratedCost returns the share of the cost on the days of the entry within [from, to]
+func proratedCost(spend models.MarketingSpend, from, to time.Time) float64 {
+	start, err := time.Parse(timeutil.DateLayout, spend.PeriodStart)
+	if err != nil {
+		return 0
+	}
+	end, err := time.Parse(timeutil.DateLayout, spend.PeriodEnd)
+	if err != nil {
+		return 0
+	}
+
+	days := end.Sub(start).Hours()/24 + 1
+	overlapStart, overlapEnd := start, end
+	if from.After(overlapStart) {
+		overlapStart = from
+	}
+	if to.Before(overlapEnd) {
+		overlapEnd = to
+	}
+	if overlapEnd.Before(overlapStart) {
+		return 0
+	}
+	overlap := overlapEnd.Sub(overlapStart).Hours()/24 + 1
+	return spend.Cost * overlap / days
+}
+
+func add(total, r *ROIRow) {
+	total.Spend += r.Spend
+	total.Leads += r.Leads
+	total.Customers += r.Customers
+	total.Revenue += r.Revenue
+}
+
+func finish(r *ROIRow) {
+	r.Spend = round(r.Spend)
+	r.Revenue = round(r.Revenue)
+	if r.Customers > 0 && r.Spend > 0 {
+		cac := round(r.Spend / float64(r.Customers))
+		r.CAC = &cac
+	}
+	if r.Spend > 0 {
+		roas := round(r.Revenue / r.Spend)
+		r.ROAS = &roas
+	}
+}
+
+// sortRows orders by revenue, then spend, so the most important campaigns come first
+func sortRows(rows []ROIRow) {
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].Revenue != rows[j].Revenue {
+			return rows[i].Revenue > rows[j].Revenue
+		}
+		if rows[i].Spend != rows[j].Spend {
+			return rows[i].Spend > rows[j].Spend
+		}
+		if rows[i].Channel != rows[j].Channel {
+			return rows[i].Channel < rows[j].Channel
+		}
+		return rows[i].Campaign < rows[j].Campaign
+	})
+}
+
+func validateSpend(input SpendInput) error {
+	start, err := time.Parse(timeutil.DateLayout, input.PeriodStart)
+	if err != nil {
+		return ErrInvalidPeriod
+	}
+	end, err := time.Parse(timeutil.DateLayout, input.PeriodEnd)
+	if err != nil || end.Before(start) {
+		return ErrInvalidPeriod
+	}
+	if input.Cost < 0 || math.IsNaN(input.Cost) || math.IsInf(input.Cost, 0) {
+		return ErrInvalidCost
+	}
+	return nil
+}
+
+func applySpend(spend *models.MarketingSpend, input SpendInput) {
+	spend.Channel = normalize(input.Channel)
+	spend.Campaign = normalize(input.Campaign)
+	spend.PeriodStart = input.PeriodStart
+	spend.PeriodEnd = input.PeriodEnd
+	spend.Cost = round(input.Cost)
+	spend.Note = strings.TrimSpace(input.Note)
+}
+
+// normalize matches UTM values case-insensitively, as ad platforms and
+// hand-written links mix cases
+func normalize(value string) string {
+	return strings.ToLower(strings.TrimSpace(value))
+}
+
+func round(value float64) float64 {
+	return math.Round(value*100) / 100
+}
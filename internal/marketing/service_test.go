@@ -0,0 +1,177 @@
+package marketing
+
+import (
+	"testing"
+	"time"
+
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/tests/testutils"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+func TestSpendValidation(t *testing.T) {
+	_, service := setupTestService(t)
+	admin := uuid.New()
+
+	tests := []struct {
+		name  string
+		input SpendInput
+		err   error
+	}{
+		{
+			name:  "malformed date",
+			input: SpendInput{Channel: "google", PeriodStart: "01.03.2026", PeriodEnd: "2026-03-31", Cost: 100},
+			err:   ErrInvalidPeriod,
+		},
+		{
+			name:  "end before start",
+			input: SpendInput{Channel: "google", PeriodStart: "2026-03-31", PeriodEnd: "2026-03-01", Cost: 100},
+			err:   ErrInvalidPeriod,
+		},
+		{
+			name:  "negative cost",
+			input: SpendInput{Channel: "google", PeriodStart: "2026-03-01", PeriodEnd: "2026-03-31", Cost: -1},
+			err:   ErrInvalidCost,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := service.CreateSpend(tt.input, admin)
+			assert.ErrorIs(t, err, tt.err)
+		})
+	}
+
+	spend, err := service.CreateSpend(SpendInput{Channel: " Google ", Campaign: "Elterngeld-Beratung", PeriodStart: "2026-03-01", PeriodEnd: "2026-03-31", Cost: 310}, admin)
+	require.NoError(t, err)
+	assert.Equal(t, "google", spend.Channel)
+	assert.Equal(t, "elterngeld-beratung", spend.Campaign)
+
+	updated, err := service.UpdateSpend(spend.ID, SpendInput{Channel: "google", PeriodStart: "2026-03-01", PeriodEnd: "2026-03-31", Cost: 400})
+	require.NoError(t, err)
+	assert.Equal(t, 400.0, updated.Cost)
+	assert.Empty(t, updated.Campaign)
+
+	require.NoError(t, service.DeleteSpend(spend.ID))
+	assert.ErrorIs(t, service.DeleteSpend(spend.ID), ErrSpendNotFound)
+}
+
+func TestReport(t *testing.T) {
+	db, service := setupTestService(t)
+	admin := uuid.New()
+	march := func(day int) time.Time { return time.Date(2026, 3, day, 12, 0, 0, 0, time.UTC) }
+
+	for _, input := range []SpendInput{
+		{Channel: "google", Campaign: "beratung", PeriodStart: "2026-03-01", PeriodEnd: "2026-03-31", Cost: 310},
+		// Half of February and half of the report period
+		{Channel: "facebook", Campaign: "herbst", PeriodStart: "2026-02-27", PeriodEnd: "2026-03-02", Cost: 100},
+		{Channel: "google", PeriodStart: "2026-03-01", PeriodEnd: "2026-03-31", Cost: 62},
+		{Channel: "newsletter", PeriodStart: "2026-04-01", PeriodEnd: "2026-04-30", Cost: 500},
+	} {
+		_, err := service.CreateSpend(input, admin)
+		require.NoError(t, err)
+	}
+
+	first := createLead(t, db, "Google", "Beratung", march(2))
+	second := createLead(t, db, "google", "beratung", march(3))
+	createLead(t, db, "google", "beratung", march(4))
+	facebook := createLead(t, db, "facebook", "herbst", march(1))
+	direct := createLead(t, db, "", "", march(5))
+	// Outside the period
+	createLead(t, db, "google", "beratung", time.Date(2026, 2, 10, 12, 0, 0, 0, time.UTC))
+
+	createPayment(t, db, first, 300, 0, models.PaymentStatusSucceeded, march(10))
+	createPayment(t, db, first, 100, 0, models.PaymentStatusSucceeded, march(11))
+	createPayment(t, db, second, 300, 50, models.PaymentStatusSucceeded, march(12))
+	createPayment(t, db, facebook, 200, 0, models.PaymentStatusFailed, march(12))
+	createPayment(t, db, direct, 150, 0, models.PaymentStatusSucceeded, march(13))
+
+	report, err := service.Report(ReportFilter{From: "2026-03-01", To: "2026-03-15"})
+	require.NoError(t, err)
+
+	campaigns := map[string]ROIRow{}
+	for _, row := range report.Campaigns {
+		campaigns[row.Channel+"/"+row.Campaign] = row
+	}
+
+	google := campaigns["google/beratung"]
+	assert.Equal(t, 150.0, google.Spend)
+	assert.Equal(t, int64(3), google.Leads)
+	assert.Equal(t, int64(2), google.Customers)
+	assert.Equal(t, 650.0, google.Revenue)
+	require.NotNil(t, google.CAC)
+	assert.Equal(t, 75.0, *google.CAC)
+	require.NotNil(t, google.ROAS)
+	assert.Equal(t, 4.33, *google.ROAS)
+
+	herbst := campaigns["facebook/herbst"]
+	assert.Equal(t, 50.0, herbst.Spend)
+	assert.Equal(t, int64(1), herbst.Leads)
+	assert.Zero(t, herbst.Customers)
+	assert.Nil(t, herbst.CAC)
+	require.NotNil(t, herbst.ROAS)
+	assert.Zero(t, *herbst.ROAS)
+
+	directRow := campaigns["/"]
+	assert.Equal(t, 150.0, directRow.Revenue)
+	assert.Nil(t, directRow.ROAS)
+	assert.NotContains(t, campaigns, "newsletter/")
+
+	require.NotEmpty(t, report.Channels)
+	assert.Equal(t, "google", report.Channels[0].Channel)
+	assert.Equal(t, 180.0, report.Channels[0].Spend)
+	assert.Equal(t, 650.0, report.Channels[0].Revenue)
+
+	assert.Equal(t, 230.0, report.Total.Spend)
+	assert.Equal(t, int64(5), report.Total.Leads)
+	assert.Equal(t, 800.0, report.Total.Revenue)
+
+	_, err = service.Report(ReportFilter{From: "2026-03-15", To: "2026-03-01"})
+	assert.ErrorIs(t, err, ErrInvalidPeriod)
+}
+
+func createLead(t *testing.T, db *gorm.DB, source, campaign string, createdAt time.Time) *models.Lead {
+	t.Helper()
+	user := testutils.CreateTestUser(t, db, models.RoleUser)
+
+	lead := &models.Lead{
+		UserID:      user.ID,
+		Title:       "Elterngeldantrag",
+		Status:      models.LeadStatusNew,
+		UtmSource:   source,
+		UtmCampaign: campaign,
+		CreatedAt:   createdAt,
+	}
+	require.NoError(t, db.Create(lead).Error)
+	return lead
+}
+
+func createPayment(t *testing.T, db *gorm.DB, lead *models.Lead, amount, refunded float64, status models.PaymentStatus, paidAt time.Time) {
+	t.Helper()
+	require.NoError(t, db.Create(&models.Payment{
+		LeadID:          lead.ID,
+		UserID:          lead.UserID,
+		Amount:          amount,
+		RefundAmount:    refunded,
+		Status:          status,
+		StripeSessionID: uuid.New().String(),
+		PaidAt:          &paidAt,
+	}).Error)
+}
+
+func setupTestService(t *testing.T) (*gorm.DB, *Service) {
+	t.Helper()
+	db := testutils.NewSQLiteDB(t,
+		&models.User{},
+		&models.Lead{},
+		&models.Payment{},
+		&models.MarketingSpend{},
+	)
+
+	return db, NewService(db, zap.NewNop())
+}
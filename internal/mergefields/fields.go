@@ -0,0 +1,203 @@
+package mergefields
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Field is a merge field a text may contain, written as {{name}}
+type Field struct {
+	Name        string `json:"name"`
+	Group       string `json:"group"`
+	Description string `json:"description"`
+	Example     string `json:"example"`
+}
+
+// Fields lists the merge fields by group
+var Fields = []Field{
+	{"customer.name", "customer", "Name des Kunden", "Anna Schmidt"},
+	{"customer.first_name", "customer", "Vorname des Kunden", "Anna"},
+	{"customer.email", "customer", "E-Mail-Adresse des Kunden", "anna@example.com"},
+	{"lead.application_number", "lead", "Aktenzeichen des Antrags", "EG-2026-000123"},
+	{"lead.title", "lead", "Titel des Antrags", "Elterngeld für Mia"},
+	{"booking.reference", "booking", "Buchungsnummer", "BK-20260504-AB12"},
+	{"booking.date", "booking", "Datum des Termins", "04.05.2026"},
+	{"booking.time", "booking", "Uhrzeit des Termins", "10:00"},
+	{"booking.package", "booking", "Gebuchtes Paket", "Elterngeld-Beratung Komplett"},
+	{"berater.name", "berater", "Name der Beraterin oder des Beraters", "Berta Berater"},
+	{"berater.email", "berater", "E-Mail-Adresse der Beraterin oder des Beraters", "berta@example.com"},
+	{"calculation.total", "calculation", "Berechnetes Elterngeld des gewählten Szenarios", "15.600,00 €"},
+	{"calculation.scenario", "calculation", "Name des gewählten Szenarios", "7+7 Plus mit Partnerbonus"},
+	{"today", "", "Heutiges Datum", "15.10.2026"},
+}
+
+// aliases are the placeholder names snippets used before merge fields were
+// shared; texts written with them keep working
+var aliases = map[string]string{
+	"customer_name":       "customer.name",
+	"customer_first_name": "customer.first_name",
+	"application_number":  "lead.application_number",
+	"booking_ref":         "booking.reference",
+	"berater_name":        "berater.name",
+}
+
+var pattern = regexp.MustCompile(`\{\{\s*([a-zA-Z_][a-zA-Z0-9_]*(?:\.[a-zA-Z_][a-zA-Z0-9_]*)*)\s*\}\}`)
+
+// Values are the values of the merge fields, by field name
+type Values map[string]string
+
+// Names returns the names of the merge fields
+func Names() []string {
+	names := make([]string, len(Fields))
+	for i, field := range Fields {
+		names[i] = field.Name
+	}
+	return names
+}
+
+// Known checks if a name is a merge field or the alias of one
+func Known(name string) bool {
+	return canonical(name) != ""
+}
+
+func canonical(name string) string {
+	if field, ok := aliases[name]; ok {
+		return field
+	}
+	for _, field := range Fields {
+		if field.Name == name {
+			return name
+		}
+	}
+	return ""
+}
+
+// Used returns the merge fields in the texts, sorted and without duplicates
+func Used(texts ...string) []string {
+	seen := map[string]bool{}
+	used := []string{}
+	for _, text := range texts {
+		for _, match := range pattern.FindAllStringSubmatch(text, -1) {
+			if !seen[match[1]] {
+				seen[match[1]] = true
+				used = append(used, match[1])
+			}
+		}
+	}
+	sort.Strings(used)
+	return used
+}
+
+// Unknown returns the merge fields in the texts that do not exist
+func Unknown(texts ...string) []string {
+	unknown := []string{}
+	for _, name := range Used(texts...) {
+		if !Known(name) {
+			unknown = append(unknown, name)
+		}
+	}
+	return unknown
+}
+
+// Merge fills in the merge fields of a text. Missing lists the fields the
+// values have nothing for, as written in the text; they are left empty.
+// Unknown fields are left as they are.
+func Merge(text string, values Values) (merged string, missing []string) {
+	seen := map[string]bool{}
+	missing = []string{}
+	merged = pattern.ReplaceAllStringFunc(text, func(match string) string {
+		name := pattern.FindStringSubmatch(match)[1]
+		field := canonical(name)
+		if field == "" {
+			return match
+		}
+		value := values[field]
+		if value == "" && !seen[name] {
+			seen[name] = true
+			missing = append(missing, name)
+		}
+		return value
+	})
+	sort.Strings(missing)
+	return merged, missing
+}
+
+// Problem is an unknown merge field in a part of a template
+type Problem struct {
+	Part       string `json:"part"`
+	Field      string `json:"field"`
+	Line       int    `json:"line"`
+	Suggestion string `json:"suggestion,omitempty"` // the merge field that was probably meant
+}
+
+// Validation reports the merge fields of a template
+type Validation struct {
+	Valid    bool      `json:"valid"`
+	Fields   []string  `json:"fields"`
+	Problems []Problem `json:"problems"`
+}
+
+// Validate checks the merge fields of the parts of a template, e.g. its
+// subject and body, before it is saved
+func Validate(parts map[string]string) Validation {
+	names := make([]string, 0, len(parts))
+	texts := make([]string, 0, len(parts))
+	for name, text := range parts {
+		names = append(names, name)
+		texts = append(texts, text)
+	}
+	sort.Strings(names)
+
+	result := Validation{Fields: Used(texts...), Problems: []Problem{}}
+	for _, part := range names {
+		for i, line := range strings.Split(parts[part], "\n") {
+			for _, match := range pattern.FindAllStringSubmatch(line, -1) {
+				if Known(match[1]) {
+					continue
+				}
+				result.Problems = append(result.Problems, Problem{
+					Part:       part,
+					Field:      match[1],
+					Line:       i + 1,
+					Suggestion: suggest(match[1]),
+				})
+			}
+		}
+	}
+	result.Valid = len(result.Problems) == 0
+	return result
+}
+
+// suggest returns the merge field closest to a misspelled name, if any is
+// close enough to have been meant
+func suggest(name string) string {
+	name = strings.ToLower(name)
+	best, bestDistance := "", 4
+	for _, field := range Fields {
+		if distance := levenshtein(name, field.Name); distance < bestDistance {
+			best, bestDistance = field.Name, distance
+		}
+	}
+	return best
+}
+
+func levenshtein(a, b string) int {
+	previous := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current := make([]int, len(b)+1)
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous = current
+	}
+	return previous[len(b)]
+}
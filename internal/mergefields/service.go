@@ -0,0 +1,174 @@
+// Package mergefields fills in merge fields like {{customer.name}},
+// {{booking.reference}} or {{calculation.total}} in the texts of snippets,
+// emails and PDF templates. The values come from the contact form, lead or
+// booking a text is used for and from the user using it; templates are
+// validated against the known fields before they are saved.
+package mergefields
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/internal/scopes"
+	"elterngeld-portal/pkg/i18n"
+	"elterngeld-portal/pkg/timeutil"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// ErrContextNotFound is returned when the contact form, lead or booking a text is merged for does not exist or the user cannot see it
+var ErrContextNotFound = errors.New("merge context not found")
+
+// Context names what a text is merged for. A booking or lead fills in more
+// fields than a contact form; a booking without a lead is completed with its
+// lead, a lead without a booking with its latest booking.
+type Context struct {
+	ContactFormID *uuid.UUID `json:"contact_form_id"`
+	LeadID        *uuid.UUID `json:"lead_id"`
+	BookingID     *uuid.UUID `json:"booking_id"`
+}
+
+// Service resolves the values of merge fields
+type Service struct {
+	db     *gorm.DB
+	logger *zap.Logger
+	now    func() time.Time
+}
+
+func NewService(db *gorm.DB, logger *zap.Logger) *Service {
+	return &Service{
+		db:     db,
+		logger: logger,
+		now:    time.Now,
+	}
+}
+
+// Resolve collects the merge field values of a context. The Berater fields
+// come from the viewer, or for customers from the Berater of their lead.
+func (s *Service) Resolve(viewer scopes.Viewer, context Context) (Values, error) {
+	values := Values{"today": s.now().In(timeutil.LoadLocation("")).Format("02.01.2006")}
+
+	var user models.User
+	if err := s.db.First(&user, "id = ?", viewer.ID).Error; err != nil {
+		return nil, fmt.Errorf("failed to load user: %w", err)
+	}
+	if viewer.Role != models.RoleUser {
+		values["berater.name"] = user.FullName()
+		values["berater.email"] = user.Email
+	}
+
+	if context.ContactFormID != nil {
+		var form models.ContactForm
+		if err := s.db.First(&form, "id = ?", *context.ContactFormID).Error; err != nil {
+			return nil, notFound(err)
+		}
+		setCustomer(values, form.Name, form.Email)
+	}
+
+	var booking *models.Booking
+	if context.BookingID != nil {
+		booking = &models.Booking{}
+		if err := s.db.Scopes(scopes.VisibleBookings(viewer)).Preload("User").Preload("Package").
+			First(booking, "bookings.id = ?", *context.BookingID).Error; err != nil {
+			return nil, notFound(err)
+		}
+		if context.LeadID == nil && booking.LeadID != nil {
+			context.LeadID = booking.LeadID
+		}
+	}
+
+	if context.LeadID != nil {
+		var lead models.Lead
+		if err := s.db.Scopes(scopes.VisibleLeads(viewer)).Preload("User").
+			First(&lead, "leads.id = ?", *context.LeadID).Error; err != nil {
+			return nil, notFound(err)
+		}
+		values["lead.application_number"] = lead.ApplicationNumber
+		values["lead.title"] = lead.Title
+		setCustomer(values, lead.User.FullName(), lead.User.Email)
+		if values["berater.name"] == "" && lead.BeraterID != nil {
+			var berater models.User
+			if err := s.db.First(&berater, "id = ?", *lead.BeraterID).Error; err != nil {
+				return nil, fmt.Errorf("failed to load Berater: %w", err)
+			}
+			values["berater.name"] = berater.FullName()
+			values["berater.email"] = berater.Email
+		}
+		if err := s.calculation(values, &lead); err != nil {
+			return nil, err
+		}
+
+		// Texts on a lead refer to its latest booking
+		if booking == nil {
+			var bookings []models.Booking
+			if err := s.db.Preload("User").Preload("Package").Where("lead_id = ?", lead.ID).
+				Order("scheduled_at DESC").Limit(1).Find(&bookings).Error; err != nil {
+				return nil, err
+			}
+			if len(bookings) > 0 {
+				booking = &bookings[0]
+			}
+		}
+	}
+
+	if booking != nil {
+		loc := timeutil.LoadLocation("")
+		if booking.User.ID != uuid.Nil {
+			loc = booking.User.TimeLocation()
+		}
+		values["booking.reference"] = booking.BookingReference
+		values["booking.date"] = booking.ScheduledAt.In(loc).Format("02.01.2006")
+		values["booking.time"] = booking.ScheduledAt.In(loc).Format("15:04")
+		if booking.Package != nil {
+			values["booking.package"] = booking.Package.Name
+		}
+		if booking.CustomerName != "" {
+			setCustomer(values, booking.CustomerName, booking.CustomerEmail)
+		} else if booking.User.ID != uuid.Nil {
+			setCustomer(values, booking.User.FullName(), booking.User.Email)
+		}
+	}
+	return values, nil
+}
+
+// calculation fills in the calculation fields from the chosen scenario of a
+// lead, whose calculated total is the lead's expected amount
+func (s *Service) calculation(values Values, lead *models.Lead) error {
+	var chosen []models.ElterngeldScenario
+	if err := s.db.Select("name").Where("lead_id = ? AND chosen_at IS NOT NULL", lead.ID).
+		Limit(1).Find(&chosen).Error; err != nil {
+		return fmt.Errorf("failed to load chosen scenario: %w", err)
+	}
+	if len(chosen) == 0 {
+		return nil
+	}
+	values["calculation.scenario"] = chosen[0].Name
+	if lead.ExpectedAmount > 0 {
+		values["calculation.total"] = i18n.FormatCurrency(i18n.DefaultLanguage, lead.ExpectedAmount, "EUR")
+	}
+	return nil
+}
+
+// setCustomer fills in the customer fields unless an earlier part of the
+// context already did
+func setCustomer(values Values, name, email string) {
+	name = strings.TrimSpace(name)
+	if name == "" || values["customer.name"] != "" {
+		return
+	}
+	values["customer.name"] = name
+	values["customer.first_name"] = strings.Fields(name)[0]
+	values["customer.email"] = email
+}
+
+func notFound(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrContextNotFound
+	}
+	return err
+}
@@ -0,0 +1,117 @@
+package mergefields
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/internal/scopes"
+	"elterngeld-portal/tests/testutils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+func TestMerge(t *testing.T) {
+	values := Values{"customer.name": "Anna Schmidt", "booking.reference": "BK-1"}
+
+	merged, missing := Merge("Hallo {{ customer.name }}, Termin {{booking_ref}}, Antrag {{lead.application_number}} {{kunde}}", values)
+	assert.Equal(t, "Hallo Anna Schmidt, Termin BK-1, Antrag  {{kunde}}", merged)
+	assert.Equal(t, []string{"lead.application_number"}, missing)
+
+	assert.Equal(t, []string{"booking_ref", "customer.name", "kunde"}, Used("{{customer.name}} {{booking_ref}}", "{{kunde}} {{customer.name}}"))
+	assert.Equal(t, []string{"kunde"}, Unknown("{{customer.name}} {{booking_ref}}", "{{kunde}}"))
+	assert.True(t, Known("berater_name"))
+}
+
+func TestValidate(t *testing.T) {
+	result := Validate(map[string]string{
+		"subject": "Ihr Termin {{booking.reference}}",
+		"body":    "Hallo {{customer.name}},\n\nIhr Elterngeld: {{calculation.totl}}\n{{foo}}",
+	})
+	assert.False(t, result.Valid)
+	assert.Equal(t, []string{"booking.reference", "calculation.totl", "customer.name", "foo"}, result.Fields)
+	require.Len(t, result.Problems, 2)
+	assert.Equal(t, Problem{Part: "body", Field: "calculation.totl", Line: 3, Suggestion: "calculation.total"}, result.Problems[0])
+	assert.Equal(t, Problem{Part: "body", Field: "foo", Line: 4}, result.Problems[1])
+
+	assert.True(t, Validate(map[string]string{"body": "{{today}} {{customer_first_name}}"}).Valid)
+}
+
+func TestResolve(t *testing.T) {
+	service, db := setupTestService(t)
+	service.now = func() time.Time { return time.Date(2026, 5, 4, 10, 0, 0, 0, time.UTC) }
+	berater := createTestUser(t, db, models.RoleBerater, "Berta Berater")
+	customer := createTestUser(t, db, models.RoleUser, "Anna Schmidt")
+
+	beraterID := berater.ID
+	lead := &models.Lead{UserID: customer.ID, BeraterID: &beraterID, Title: "Elterngeld für Mia", ExpectedAmount: 15600}
+	require.NoError(t, db.Create(lead).Error)
+	booking := &models.Booking{
+		UserID:      customer.ID,
+		BeraterID:   &beraterID,
+		LeadID:      &lead.ID,
+		Title:       "Beratung",
+		ScheduledAt: time.Date(2026, 5, 6, 8, 0, 0, 0, time.UTC),
+		Duration:    60,
+	}
+	require.NoError(t, db.Create(booking).Error)
+
+	// Without a chosen scenario there is no calculation yet
+	values, err := service.Resolve(viewerOf(customer), Context{BookingID: &booking.ID})
+	require.NoError(t, err)
+	assert.Equal(t, "04.05.2026", values["today"])
+	assert.Equal(t, "Anna", values["customer.first_name"])
+	assert.Equal(t, lead.ApplicationNumber, values["lead.application_number"])
+	assert.Equal(t, booking.BookingReference, values["booking.reference"])
+	assert.Equal(t, "06.05.2026", values["booking.date"])
+	assert.Equal(t, "Berta Berater", values["berater.name"])
+	assert.Empty(t, values["calculation.total"])
+
+	now := time.Now()
+	require.NoError(t, db.Create(&models.ElterngeldScenario{LeadID: lead.ID, CreatedByID: berater.ID, Name: "7+7 Plus", ChosenAt: &now}).Error)
+	values, err = service.Resolve(viewerOf(berater), Context{LeadID: &lead.ID})
+	require.NoError(t, err)
+	assert.Equal(t, "7+7 Plus", values["calculation.scenario"])
+	assert.Contains(t, values["calculation.total"], "15.600,00")
+	assert.Equal(t, booking.BookingReference, values["booking.reference"])
+
+	merged, missing := Merge("{{customer.name}}: {{calculation.total}} ({{calculation.scenario}})", values)
+	assert.Empty(t, missing)
+	assert.True(t, strings.HasPrefix(merged, "Anna Schmidt: 15.600,00"))
+
+	// Customers only see their own leads
+	other := createTestUser(t, db, models.RoleUser, "Otto Other")
+	_, err = service.Resolve(viewerOf(other), Context{LeadID: &lead.ID})
+	assert.ErrorIs(t, err, ErrContextNotFound)
+}
+
+func setupTestService(t *testing.T) (*Service, *gorm.DB) {
+	t.Helper()
+	db := testutils.NewSQLiteDB(t,
+		&models.User{},
+		&models.Lead{},
+		&models.FamilyMember{},
+		&models.Booking{},
+		&models.ContactForm{},
+		&models.Package{},
+		&models.ElterngeldScenario{},
+	)
+
+	return NewService(db, zap.NewNop()), db
+}
+
+func createTestUser(t *testing.T, db *gorm.DB, role models.UserRole, name string) *models.User {
+	t.Helper()
+	user := testutils.CreateTestUser(t, db, role)
+	user.FirstName, user.LastName, _ = strings.Cut(name, " ")
+	require.NoError(t, db.Model(user).Updates(map[string]interface{}{"first_name": user.FirstName, "last_name": user.LastName}).Error)
+	return user
+}
+
+func viewerOf(user *models.User) scopes.Viewer {
+	return scopes.Viewer{ID: user.ID, Role: user.Role}
+}
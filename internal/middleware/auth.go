@@ -1,23 +1,28 @@
 package middleware
 
 import (
+	"errors"
 	"net/http"
+	"strconv"
 
+	"elterngeld-portal/internal/accesstokens"
 	"elterngeld-portal/internal/models"
+	"elterngeld-portal/internal/servicekeys"
 	"elterngeld-portal/pkg/auth"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
 
-// AuthMiddleware validates JWT tokens
-func AuthMiddleware(jwtService *auth.JWTService) gin.HandlerFunc {
+// AuthMiddleware validates JWT tokens. With a token service, personal access
+// tokens are accepted as well for the routes their scopes cover.
+func AuthMiddleware(jwtService *auth.JWTService, tokens *accesstokens.Service) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Extract token from Authorization header
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
 			c.JSON(http.StatusUnauthorized, gin.H{
-				"error": "Authorization header is required",
+				"error": T(c, "Authorization header is required"),
 				"code":  "MISSING_AUTH_HEADER",
 			})
 			c.Abort()
@@ -28,13 +33,18 @@ func AuthMiddleware(jwtService *auth.JWTService) gin.HandlerFunc {
 		token := auth.ExtractTokenFromBearer(authHeader)
 		if token == "" {
 			c.JSON(http.StatusUnauthorized, gin.H{
-				"error": "Invalid authorization header format",
+				"error": T(c, "Invalid authorization header format"),
 				"code":  "INVALID_AUTH_FORMAT",
 			})
 			c.Abort()
 			return
 		}
 
+		if tokens != nil && accesstokens.IsAccessToken(token) {
+			authenticateAccessToken(c, tokens, token)
+			return
+		}
+
 		// Validate token
 		claims, err := jwtService.ValidateTokenWithBlacklist(token)
 		if err != nil {
@@ -54,7 +64,7 @@ func AuthMiddleware(jwtService *auth.JWTService) gin.HandlerFunc {
 			}
 
 			c.JSON(http.StatusUnauthorized, gin.H{
-				"error": message,
+				"error": T(c, message),
 				"code":  code,
 			})
 			c.Abort()
@@ -66,18 +76,77 @@ func AuthMiddleware(jwtService *auth.JWTService) gin.HandlerFunc {
 		c.Set("user_email", claims.Email)
 		c.Set("user_role", claims.Role)
 		c.Set("jwt_claims", claims)
+		applyUserLanguage(c, claims.Language)
+		applyUserTimezone(c, claims.Timezone)
 
 		c.Next()
 	}
 }
 
+// authenticateAccessToken sets the owner of a personal access token as the
+// current user, provided the token's scopes cover the requested route
+func authenticateAccessToken(c *gin.Context, tokens *accesstokens.Service, plain string) {
+	token, err := tokens.Authenticate(plain)
+	if err != nil {
+		if errors.Is(err, accesstokens.ErrInvalidToken) {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error": T(c, "Invalid token"),
+				"code":  "TOKEN_INVALID",
+			})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": T(c, "Internal server error")})
+		}
+		c.Abort()
+		return
+	}
+
+	scope, ok := accesstokens.RequiredScope(c.Request.Method, c.FullPath())
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": T(c, "This endpoint cannot be used with an access token"),
+			"code":  "TOKEN_NOT_ALLOWED",
+		})
+		c.Abort()
+		return
+	}
+	if !token.HasScope(scope) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": T(c, "Access token lacks the %s scope", scope),
+			"code":  "INSUFFICIENT_SCOPE",
+		})
+		c.Abort()
+		return
+	}
+
+	c.Set("access_token", token)
+	c.Set("user_id", token.User.ID)
+	c.Set("user_email", token.User.Email)
+	c.Set("user_role", token.User.Role)
+	applyUserLanguage(c, token.User.Language)
+	applyUserTimezone(c, token.User.Timezone)
+
+	c.Next()
+}
+
+// GetCurrentAccessToken extracts the personal access token of the request from
+// context; requests authenticated with a JWT have none
+func GetCurrentAccessToken(c *gin.Context) (*models.AccessToken, bool) {
+	value, exists := c.Get("access_token")
+	if !exists {
+		return nil, false
+	}
+
+	token, ok := value.(*models.AccessToken)
+	return token, ok
+}
+
 // RequireRole ensures the user has the specified role
 func RequireRole(roles ...models.UserRole) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		userRole, exists := c.Get("user_role")
 		if !exists {
 			c.JSON(http.StatusUnauthorized, gin.H{
-				"error": "User role not found in context",
+				"error": T(c, "User role not found in context"),
 				"code":  "MISSING_USER_ROLE",
 			})
 			c.Abort()
@@ -87,7 +156,7 @@ func RequireRole(roles ...models.UserRole) gin.HandlerFunc {
 		role, ok := userRole.(models.UserRole)
 		if !ok {
 			c.JSON(http.StatusInternalServerError, gin.H{
-				"error": "Invalid user role type",
+				"error": T(c, "Invalid user role type"),
 				"code":  "INVALID_ROLE_TYPE",
 			})
 			c.Abort()
@@ -103,7 +172,7 @@ func RequireRole(roles ...models.UserRole) gin.HandlerFunc {
 		}
 
 		c.JSON(http.StatusForbidden, gin.H{
-			"error": "Insufficient permissions",
+			"error": T(c, "Insufficient permissions"),
 			"code":  "INSUFFICIENT_PERMISSIONS",
 		})
 		c.Abort()
@@ -126,7 +195,7 @@ func RequireOwnershipOrRole(resourceUserIDKey string, roles ...models.UserRole)
 		userID, exists := c.Get("user_id")
 		if !exists {
 			c.JSON(http.StatusUnauthorized, gin.H{
-				"error": "User ID not found in context",
+				"error": T(c, "User ID not found in context"),
 				"code":  "MISSING_USER_ID",
 			})
 			c.Abort()
@@ -136,7 +205,7 @@ func RequireOwnershipOrRole(resourceUserIDKey string, roles ...models.UserRole)
 		currentUserID, ok := userID.(uuid.UUID)
 		if !ok {
 			c.JSON(http.StatusInternalServerError, gin.H{
-				"error": "Invalid user ID type",
+				"error": T(c, "Invalid user ID type"),
 				"code":  "INVALID_USER_ID_TYPE",
 			})
 			c.Abort()
@@ -146,7 +215,7 @@ func RequireOwnershipOrRole(resourceUserIDKey string, roles ...models.UserRole)
 		userRole, exists := c.Get("user_role")
 		if !exists {
 			c.JSON(http.StatusUnauthorized, gin.H{
-				"error": "User role not found in context",
+				"error": T(c, "User role not found in context"),
 				"code":  "MISSING_USER_ROLE",
 			})
 			c.Abort()
@@ -156,7 +225,7 @@ func RequireOwnershipOrRole(resourceUserIDKey string, roles ...models.UserRole)
 		role, ok := userRole.(models.UserRole)
 		if !ok {
 			c.JSON(http.StatusInternalServerError, gin.H{
-				"error": "Invalid user role type",
+				"error": T(c, "Invalid user role type"),
 				"code":  "INVALID_ROLE_TYPE",
 			})
 			c.Abort()
@@ -182,7 +251,7 @@ func RequireOwnershipOrRole(resourceUserIDKey string, roles ...models.UserRole)
 		}
 
 		c.JSON(http.StatusForbidden, gin.H{
-			"error": "Access denied",
+			"error": T(c, "Access denied"),
 			"code":  "ACCESS_DENIED",
 		})
 		c.Abort()
@@ -216,6 +285,8 @@ func OptionalAuth(jwtService *auth.JWTService) gin.HandlerFunc {
 		c.Set("user_email", claims.Email)
 		c.Set("user_role", claims.Role)
 		c.Set("jwt_claims", claims)
+		applyUserLanguage(c, claims.Language)
+		applyUserTimezone(c, claims.Timezone)
 
 		c.Next()
 	}
@@ -305,8 +376,12 @@ func CanAccessResource(c *gin.Context, resourceUserID uuid.UUID, allowedRoles ..
 	return false
 }
 
-// APIKeyMiddleware validates API keys (for webhook endpoints)
-func APIKeyMiddleware(validAPIKeys map[string]string) gin.HandlerFunc {
+// APIKeyMiddleware validates the API keys of machine integrations such as the
+// inbound email webhook, website forms and partner systems. Service keys are
+// checked against their IP allowlist and rate limit. The static keys from the
+// configuration are accepted as well; they are named after the service key
+// scope they grant.
+func APIKeyMiddleware(keys *servicekeys.Service, staticKeys map[string]string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		apiKey := c.GetHeader("X-API-Key")
 		if apiKey == "" {
@@ -316,30 +391,101 @@ func APIKeyMiddleware(validAPIKeys map[string]string) gin.HandlerFunc {
 
 		if apiKey == "" {
 			c.JSON(http.StatusUnauthorized, gin.H{
-				"error": "API key is required",
+				"error": T(c, "API key is required"),
 				"code":  "MISSING_API_KEY",
 			})
 			c.Abort()
 			return
 		}
 
-		// Validate API key
-		keyName, exists := validAPIKeys[apiKey]
-		if !exists {
+		if keyName, exists := staticKeys[apiKey]; exists {
+			c.Set("api_key_name", keyName)
+			c.Next()
+			return
+		}
+		if keys == nil {
 			c.JSON(http.StatusUnauthorized, gin.H{
-				"error": "Invalid API key",
+				"error": T(c, "Invalid API key"),
 				"code":  "INVALID_API_KEY",
 			})
 			c.Abort()
 			return
 		}
 
+		key, err := keys.Authenticate(apiKey, c.ClientIP())
+		if err != nil {
+			switch {
+			case errors.Is(err, servicekeys.ErrInvalidKey):
+				c.JSON(http.StatusUnauthorized, gin.H{
+					"error": T(c, "Invalid API key"),
+					"code":  "INVALID_API_KEY",
+				})
+			case errors.Is(err, servicekeys.ErrIPNotAllowed):
+				c.JSON(http.StatusForbidden, gin.H{
+					"error": T(c, "API key is not allowed from this IP address"),
+					"code":  "IP_NOT_ALLOWED",
+				})
+			default:
+				c.JSON(http.StatusInternalServerError, gin.H{"error": T(c, "Internal server error")})
+			}
+			c.Abort()
+			return
+		}
+
+		usage, ok := keys.Allow(key)
+		c.Header("X-RateLimit-Limit", strconv.Itoa(usage.Limit))
+		c.Header("X-RateLimit-Remaining", strconv.Itoa(usage.Remaining))
+		c.Header("X-RateLimit-Reset", strconv.FormatInt(usage.Reset.Unix(), 10))
+		if !ok {
+			c.JSON(http.StatusTooManyRequests, gin.H{
+				"error": T(c, "Rate limit exceeded"),
+				"code":  "RATE_LIMIT_EXCEEDED",
+			})
+			c.Abort()
+			return
+		}
+
 		// Set API key info in context
-		c.Set("api_key_name", keyName)
+		c.Set("service_key", key)
+		c.Set("api_key_name", key.Name)
 		c.Next()
 	}
 }
 
+// RequireServiceKeyScope ensures the service key was granted a scope. Static
+// keys from the configuration only grant the scope they are named after.
+func RequireServiceKeyScope(scope models.ServiceKeyScope) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if key, ok := GetCurrentServiceKey(c); ok {
+			if key.HasScope(scope) {
+				c.Next()
+				return
+			}
+		} else if c.GetString("api_key_name") == string(scope) {
+			c.Next()
+			return
+		}
+
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": T(c, "API key lacks the %s scope", scope),
+			"code":  "INSUFFICIENT_SCOPE",
+		})
+		c.Abort()
+	}
+}
+
+// GetCurrentServiceKey extracts the service key from context; requests made
+// with a static key have none
+func GetCurrentServiceKey(c *gin.Context) (*models.ServiceKey, bool) {
+	value, exists := c.Get("service_key")
+	if !exists {
+		return nil, false
+	}
+
+	key, ok := value.(*models.ServiceKey)
+	return key, ok
+}
+
 // CORSMiddleware handles CORS headers
 func CORSMiddleware(allowedOrigins []string, allowCredentials bool) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -363,7 +509,7 @@ func CORSMiddleware(allowedOrigins []string, allowCredentials bool) gin.HandlerF
 		}
 
 		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, HEAD, OPTIONS")
-		c.Header("Access-Control-Allow-Headers", "Origin, Content-Length, Content-Type, Authorization, X-Requested-With, X-API-Key")
+		c.Header("Access-Control-Allow-Headers", "Origin, Content-Length, Content-Type, Authorization, X-Requested-With, X-API-Key, X-Captcha-Token, X-CSRF-Token")
 		c.Header("Access-Control-Max-Age", "86400")
 
 		if c.Request.Method == "OPTIONS" {
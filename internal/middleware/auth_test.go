@@ -5,7 +5,9 @@ import (
 	"net/http/httptest"
 	"testing"
 
+	"elterngeld-portal/internal/accesstokens"
 	"elterngeld-portal/internal/models"
+	"elterngeld-portal/internal/servicekeys"
 	"elterngeld-portal/tests/testutils"
 
 	"github.com/gin-gonic/gin"
@@ -22,7 +24,7 @@ func TestAuthMiddleware(t *testing.T) {
 	user := testutils.CreateTestUser(t, ctx.DB, models.RoleUser)
 	token := testutils.GenerateAuthToken(t, ctx.JWTService, user)
 
-	middleware := AuthMiddleware(ctx.JWTService)
+	middleware := AuthMiddleware(ctx.JWTService, nil)
 
 	t.Run("valid_token", func(t *testing.T) {
 		w := httptest.NewRecorder()
@@ -95,6 +97,49 @@ func TestAuthMiddleware(t *testing.T) {
 	})
 }
 
+func TestAuthMiddlewareAccessToken(t *testing.T) {
+	testutils.SetupGinTestMode()
+	ctx := testutils.SetupTestContext(t)
+	defer testutils.CleanupTestContext(ctx)
+
+	user := testutils.CreateTestUser(t, ctx.DB, models.RoleUser)
+	tokens := accesstokens.NewService(ctx.DB, ctx.Logger)
+	_, plain, err := tokens.Create(user.ID, "CLI", []models.AccessTokenScope{models.AccessTokenScopeLeadsRead}, nil)
+	require.NoError(t, err)
+
+	router := gin.New()
+	protected := router.Group("/api/v1", AuthMiddleware(ctx.JWTService, tokens))
+	handler := func(c *gin.Context) {
+		userID, _ := GetCurrentUserID(c)
+		c.JSON(http.StatusOK, gin.H{"user_id": userID})
+	}
+	protected.GET("/leads/:id", handler)
+	protected.PUT("/leads/:id", handler)
+	protected.GET("/me/tokens", handler)
+
+	request := func(method, path, token string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := request("GET", "/api/v1/leads/1", plain)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), user.ID.String())
+
+	w = request("PUT", "/api/v1/leads/1", plain)
+	testutils.AssertErrorResponse(t, w, http.StatusForbidden, "Access token lacks the leads:write scope")
+
+	// Tokens cannot manage tokens
+	w = request("GET", "/api/v1/me/tokens", plain)
+	testutils.AssertErrorResponse(t, w, http.StatusForbidden, "This endpoint cannot be used with an access token")
+
+	w = request("GET", "/api/v1/leads/1", models.AccessTokenPrefix+"unknown")
+	testutils.AssertErrorResponse(t, w, http.StatusUnauthorized, "Invalid token")
+}
+
 func TestRequireRole(t *testing.T) {
 	testutils.SetupGinTestMode()
 
@@ -548,7 +593,7 @@ func TestAPIKeyMiddleware(t *testing.T) {
 		"admin-key-456":    "admin",
 	}
 
-	middleware := APIKeyMiddleware(validAPIKeys)
+	middleware := APIKeyMiddleware(nil, validAPIKeys)
 
 	t.Run("valid_api_key_header", func(t *testing.T) {
 		w := httptest.NewRecorder()
@@ -599,6 +644,51 @@ func TestAPIKeyMiddleware(t *testing.T) {
 	})
 }
 
+func TestAPIKeyMiddlewareServiceKeys(t *testing.T) {
+	testutils.SetupGinTestMode()
+	ctx := testutils.SetupTestContext(t)
+	defer testutils.CleanupTestContext(ctx)
+
+	keys := servicekeys.NewService(ctx.DB, ctx.Logger)
+	_, plain, err := keys.Create(servicekeys.Input{
+		Name:      "Website",
+		Scopes:    []models.ServiceKeyScope{models.ServiceKeyScopeContactForms},
+		RateLimit: 2,
+	}, uuid.New())
+	require.NoError(t, err)
+
+	router := gin.New()
+	webhooks := router.Group("/webhooks", APIKeyMiddleware(keys, map[string]string{"inbound-secret": string(models.ServiceKeyScopeInboundEmail)}))
+	ok := func(c *gin.Context) { c.Status(http.StatusNoContent) }
+	webhooks.POST("/inbound-email", RequireServiceKeyScope(models.ServiceKeyScopeInboundEmail), ok)
+	webhooks.POST("/contact-forms", RequireServiceKeyScope(models.ServiceKeyScopeContactForms), ok)
+
+	request := func(path, key string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", path, nil)
+		req.Header.Set("X-API-Key", key)
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := request("/webhooks/contact-forms", plain)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, "2", w.Header().Get("X-RateLimit-Limit"))
+	assert.Equal(t, "1", w.Header().Get("X-RateLimit-Remaining"))
+
+	w = request("/webhooks/inbound-email", plain)
+	testutils.AssertErrorResponse(t, w, http.StatusForbidden, "API key lacks the inbound_email scope")
+
+	w = request("/webhooks/contact-forms", plain)
+	testutils.AssertErrorResponse(t, w, http.StatusTooManyRequests, "Rate limit exceeded")
+
+	// The static key only grants the scope it is named after
+	w = request("/webhooks/inbound-email", "inbound-secret")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	w = request("/webhooks/contact-forms", "inbound-secret")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
 func TestCORSMiddleware(t *testing.T) {
 	testutils.SetupGinTestMode()
 
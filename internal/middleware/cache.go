@@ -0,0 +1,144 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ConditionalGetMiddleware answers conditional GET requests of cacheable
+// routes. Successful responses are buffered and get an ETag from a hash of
+// their body, unless the handler set one, and a public Cache-Control header
+// with maxAge, unless the handler set one. Requests whose If-None-Match
+// matches the ETag, or without If-None-Match whose If-Modified-Since is not
+// before the Last-Modified set by the handler, get 304 Not Modified without
+// body. Responses vary by Accept-Language, as messages are translated.
+func ConditionalGetMiddleware(maxAge time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Method != http.MethodGet {
+			c.Next()
+			return
+		}
+
+		original := c.Writer
+		writer := &bufferedWriter{ResponseWriter: original, status: http.StatusOK}
+		c.Writer = writer
+		c.Next()
+		c.Writer = original
+
+		header := original.Header()
+		if writer.status != http.StatusOK {
+			original.WriteHeader(writer.status)
+			_, _ = original.Write(writer.body.Bytes())
+			return
+		}
+
+		etag := header.Get("ETag")
+		if etag == "" {
+			sum := sha256.Sum256(writer.body.Bytes())
+			etag = `"` + hex.EncodeToString(sum[:16]) + `"`
+			header.Set("ETag", etag)
+		}
+		if header.Get("Cache-Control") == "" {
+			header.Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(maxAge.Seconds())))
+		}
+		header.Add("Vary", "Accept-Language")
+
+		if notModified(c.Request, etag, header.Get("Last-Modified")) {
+			header.Del("Content-Type")
+			header.Del("Content-Length")
+			original.WriteHeader(http.StatusNotModified)
+			return
+		}
+		original.WriteHeader(http.StatusOK)
+		_, _ = original.Write(writer.body.Bytes())
+	}
+}
+
+// notModified evaluates the preconditions of a request as in RFC 9110:
+// If-None-Match takes precedence over If-Modified-Since
+func notModified(r *http.Request, etag, lastModified string) bool {
+	if match := r.Header.Get("If-None-Match"); match != "" {
+		for _, candidate := range strings.Split(match, ",") {
+			candidate = strings.TrimSpace(candidate)
+			// Weak comparison, so ETags weakened by compressing proxies still match
+			if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+				return true
+			}
+		}
+		return false
+	}
+
+	since := r.Header.Get("If-Modified-Since")
+	if since == "" || lastModified == "" {
+		return false
+	}
+	sinceTime, err := http.ParseTime(since)
+	if err != nil {
+		return false
+	}
+	modified, err := http.ParseTime(lastModified)
+	if err != nil {
+		return false
+	}
+	return !modified.After(sinceTime)
+}
+
+// SetLastModified sets the Last-Modified header of a response, which
+// ConditionalGetMiddleware compares with If-Modified-Since
+func SetLastModified(c *gin.Context, modified time.Time) {
+	if modified.IsZero() {
+		return
+	}
+	c.Header("Last-Modified", modified.UTC().Format(http.TimeFormat))
+}
+
+// bufferedWriter holds back the response of a handler so its ETag can be
+// computed before anything is sent
+type bufferedWriter struct {
+	gin.ResponseWriter
+	body    bytes.Buffer
+	status  int
+	written bool
+}
+
+func (w *bufferedWriter) WriteHeader(code int) {
+	if code > 0 && !w.written {
+		w.status = code
+	}
+}
+
+func (w *bufferedWriter) WriteHeaderNow() {
+	w.written = true
+}
+
+func (w *bufferedWriter) Write(data []byte) (int, error) {
+	w.written = true
+	return w.body.Write(data)
+}
+
+func (w *bufferedWriter) WriteString(s string) (int, error) {
+	w.written = true
+	return w.body.WriteString(s)
+}
+
+func (w *bufferedWriter) Status() int {
+	return w.status
+}
+
+func (w *bufferedWriter) Size() int {
+	if !w.written {
+		return -1
+	}
+	return w.body.Len()
+}
+
+func (w *bufferedWriter) Written() bool {
+	return w.written
+}
@@ -0,0 +1,84 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"elterngeld-portal/tests/testutils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConditionalGetMiddleware(t *testing.T) {
+	testutils.SetupGinTestMode()
+
+	modified := time.Date(2026, 5, 4, 10, 0, 0, 0, time.UTC)
+	router := gin.New()
+	router.Use(ConditionalGetMiddleware(5 * time.Minute))
+	router.GET("/packages", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"packages": []string{"Basis", "Komplett"}})
+	})
+	router.GET("/faq", func(c *gin.Context) {
+		SetLastModified(c, modified)
+		c.Header("Cache-Control", "public, max-age=60")
+		c.JSON(http.StatusOK, gin.H{"entries": []string{}})
+	})
+	router.GET("/missing", func(c *gin.Context) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+	})
+
+	get := func(path string, headers map[string]string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		for name, value := range headers {
+			req.Header.Set(name, value)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("/packages", nil)
+	require.Equal(t, http.StatusOK, w.Code)
+	etag := w.Header().Get("ETag")
+	require.NotEmpty(t, etag)
+	assert.Equal(t, "public, max-age=300", w.Header().Get("Cache-Control"))
+	assert.Equal(t, "Accept-Language", w.Header().Get("Vary"))
+	assert.JSONEq(t, `{"packages":["Basis","Komplett"]}`, w.Body.String())
+
+	// The same body has the same ETag
+	assert.Equal(t, etag, get("/packages", nil).Header().Get("ETag"))
+
+	w = get("/packages", map[string]string{"If-None-Match": `"other", ` + etag})
+	assert.Equal(t, http.StatusNotModified, w.Code)
+	assert.Empty(t, w.Body.String())
+	assert.Equal(t, etag, w.Header().Get("ETag"))
+
+	w = get("/packages", map[string]string{"If-None-Match": "W/" + etag})
+	assert.Equal(t, http.StatusNotModified, w.Code)
+
+	w = get("/packages", map[string]string{"If-None-Match": `"other"`})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotEmpty(t, w.Body.String())
+
+	// Handlers may set Last-Modified and their own Cache-Control
+	w = get("/faq", map[string]string{"If-Modified-Since": modified.Format(http.TimeFormat)})
+	assert.Equal(t, http.StatusNotModified, w.Code)
+	assert.Equal(t, "public, max-age=60", w.Header().Get("Cache-Control"))
+	w = get("/faq", map[string]string{"If-Modified-Since": modified.Add(-time.Hour).Format(http.TimeFormat)})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, modified.Format(http.TimeFormat), w.Header().Get("Last-Modified"))
+
+	// If-None-Match takes precedence over If-Modified-Since
+	w = get("/faq", map[string]string{"If-None-Match": `"other"`, "If-Modified-Since": modified.Format(http.TimeFormat)})
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Errors are passed through without caching headers
+	w = get("/missing", map[string]string{"If-None-Match": "*"})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Empty(t, w.Header().Get("ETag"))
+	assert.JSONEq(t, `{"error":"not found"}`, w.Body.String())
+}
@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+
+	"elterngeld-portal/pkg/captcha"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// CaptchaHeader carries the captcha response token of the client
+const CaptchaHeader = "X-Captcha-Token"
+
+// CaptchaMiddleware requires a valid captcha response on unauthenticated endpoints
+// that create accounts or send emails. A nil verifier disables the check.
+func CaptchaMiddleware(verifier *captcha.Verifier, logger *zap.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if verifier == nil {
+			c.Next()
+			return
+		}
+
+		token := c.GetHeader(CaptchaHeader)
+		if token == "" {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": T(c, "Captcha verification is required"),
+				"code":  "CAPTCHA_REQUIRED",
+			})
+			c.Abort()
+			return
+		}
+
+		if err := verifier.Verify(c.Request.Context(), token, c.ClientIP()); err != nil {
+			if errors.Is(err, captcha.ErrInvalidToken) {
+				logger.Warn("Captcha verification failed",
+					zap.String("client_ip", c.ClientIP()),
+					zap.String("path", c.Request.URL.Path),
+					zap.Error(err),
+				)
+				c.JSON(http.StatusForbidden, gin.H{
+					"error": T(c, "Captcha verification failed"),
+					"code":  "CAPTCHA_FAILED",
+				})
+			} else {
+				// Fail closed so an outage does not open the endpoints to bots
+				logger.Error("Captcha provider unavailable", zap.String("provider", verifier.Provider()), zap.Error(err))
+				c.JSON(http.StatusServiceUnavailable, gin.H{
+					"error": T(c, "Captcha service is unavailable"),
+					"code":  "CAPTCHA_UNAVAILABLE",
+				})
+			}
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
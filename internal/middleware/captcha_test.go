@@ -0,0 +1,106 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"elterngeld-portal/config"
+	"elterngeld-portal/pkg/captcha"
+	"elterngeld-portal/tests/testutils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestCaptchaMiddleware(t *testing.T) {
+	testutils.SetupGinTestMode()
+
+	provider := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		assert.Equal(t, "test-secret", r.PostForm.Get("secret"))
+
+		switch r.PostForm.Get("response") {
+		case "valid":
+			json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "score": 0.9})
+		case "bot":
+			json.NewEncoder(w).Encode(map[string]interface{}{"success": true, "score": 0.1})
+		case "outage":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			json.NewEncoder(w).Encode(map[string]interface{}{"success": false, "error-codes": []string{"invalid-input-response"}})
+		}
+	}))
+	defer provider.Close()
+
+	verifier, err := captcha.NewVerifier(&config.Config{Captcha: config.CaptchaConfig{
+		Provider:  captcha.ProviderRecaptcha,
+		SecretKey: "test-secret",
+		MinScore:  0.5,
+		VerifyURL: provider.URL,
+		Timeout:   time.Second,
+	}})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		token  string
+		status int
+		code   string
+	}{
+		{"missing_token", "", http.StatusBadRequest, "CAPTCHA_REQUIRED"},
+		{"valid_token", "valid", http.StatusOK, ""},
+		{"low_score", "bot", http.StatusForbidden, "CAPTCHA_FAILED"},
+		{"rejected_token", "expired", http.StatusForbidden, "CAPTCHA_FAILED"},
+		{"provider_outage", "outage", http.StatusServiceUnavailable, "CAPTCHA_UNAVAILABLE"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := performCaptchaRequest(verifier, tt.token)
+
+			assert.Equal(t, tt.status, w.Code)
+			if tt.code != "" {
+				var body map[string]interface{}
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+				assert.Equal(t, tt.code, body["code"])
+			}
+		})
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		w := performCaptchaRequest(nil, "")
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+}
+
+func TestNewCaptchaVerifier_Config(t *testing.T) {
+	verifier, err := captcha.NewVerifier(&config.Config{})
+	assert.NoError(t, err)
+	assert.Nil(t, verifier)
+
+	_, err = captcha.NewVerifier(&config.Config{Captcha: config.CaptchaConfig{Provider: "hcaptcha", SecretKey: "secret"}})
+	assert.Error(t, err)
+
+	_, err = captcha.NewVerifier(&config.Config{Captcha: config.CaptchaConfig{Provider: "Turnstile"}})
+	assert.Error(t, err)
+}
+
+func performCaptchaRequest(verifier *captcha.Verifier, token string) *httptest.ResponseRecorder {
+	router := gin.New()
+	router.POST("/register", CaptchaMiddleware(verifier, zap.NewNop()), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"message": "ok"})
+	})
+
+	req := httptest.NewRequest("POST", "/register", nil)
+	if token != "" {
+		req.Header.Set(CaptchaHeader, token)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
@@ -0,0 +1,228 @@
+package middleware
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/gin-gonic/gin"
+)
+
+// encoder is the part of gzip.Writer and brotli.Writer the middleware uses
+type encoder interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+// encoders reuses the writers of each content coding between responses, as
+// each one allocates several hundred kilobytes of compression state
+var encoders = map[string]*sync.Pool{
+	"br": {New: func() interface{} {
+		return brotli.NewWriterLevel(nil, brotli.DefaultCompression)
+	}},
+	"gzip": {New: func() interface{} {
+		w, _ := gzip.NewWriterLevel(nil, gzip.DefaultCompression)
+		return w
+	}},
+}
+
+// CompressionMiddleware compresses responses with brotli or gzip, whichever
+// the client prefers; brotli wins a tie as it compresses better. The body
+// is held back until it reaches minSize; smaller responses are sent as they
+// are, since compressing them saves less than it costs. Responses that are
+// already encoded, partial content and types that are compressed already,
+// like images, PDFs and archives, are never compressed. Streamed responses
+// are compressed as soon as they are flushed.
+func CompressionMiddleware(minSize int) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Method == http.MethodHead || c.GetHeader("Upgrade") != "" {
+			c.Next()
+			return
+		}
+		encoding := negotiateEncoding(c.GetHeader("Accept-Encoding"))
+		if encoding == "" {
+			c.Next()
+			return
+		}
+
+		writer := &compressWriter{ResponseWriter: c.Writer, minSize: minSize, status: http.StatusOK, encoding: encoding}
+		c.Writer = writer
+		defer writer.finish()
+		c.Next()
+	}
+}
+
+// negotiateEncoding picks the content coding of an Accept-Encoding header:
+// "br" or "gzip" by their q-values, with "*" standing for the codings that
+// are not listed, or an empty string if the client accepts neither. q=0
+// refuses a coding.
+func negotiateEncoding(header string) string {
+	qualities := make(map[string]float64)
+	for _, part := range strings.Split(header, ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		coding = strings.ToLower(strings.TrimSpace(coding))
+		if coding != "br" && coding != "gzip" && coding != "*" {
+			continue
+		}
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.ReplaceAll(params, " ", ""), "q="); ok {
+			if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+				q = parsed
+			}
+		}
+		qualities[coding] = q
+	}
+
+	best, bestQ := "", 0.0
+	for _, coding := range []string{"br", "gzip"} {
+		q, ok := qualities[coding]
+		if !ok {
+			q = qualities["*"]
+		}
+		if q > bestQ {
+			best, bestQ = coding, q
+		}
+	}
+	return best
+}
+
+// compressible checks if a content type is worth compressing: text, JSON,
+// XML, JavaScript and SVG
+func compressible(contentType string) bool {
+	mediaType, _, _ := strings.Cut(strings.ToLower(contentType), ";")
+	mediaType = strings.TrimSpace(mediaType)
+	if mediaType == "text/event-stream" {
+		return false
+	}
+	return strings.HasPrefix(mediaType, "text/") ||
+		strings.Contains(mediaType, "json") ||
+		strings.Contains(mediaType, "xml") ||
+		strings.Contains(mediaType, "javascript")
+}
+
+// compressWriter holds back the status and the start of the body until it
+// is known whether the response is compressed
+type compressWriter struct {
+	gin.ResponseWriter
+	minSize  int
+	status   int
+	encoding string
+	buffer   bytes.Buffer
+	decided  bool
+	encoder  encoder
+}
+
+func (w *compressWriter) WriteHeader(code int) {
+	if code > 0 && !w.decided {
+		w.status = code
+	}
+}
+
+// WriteHeaderNow is deferred until the body decides about compression
+func (w *compressWriter) WriteHeaderNow() {}
+
+func (w *compressWriter) Write(data []byte) (int, error) {
+	if w.decided {
+		if w.encoder != nil {
+			return w.encoder.Write(data)
+		}
+		return w.ResponseWriter.Write(data)
+	}
+
+	n, _ := w.buffer.Write(data)
+	if w.buffer.Len() >= w.minSize {
+		if err := w.decide(true); err != nil {
+			return 0, err
+		}
+	}
+	return n, nil
+}
+
+func (w *compressWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *compressWriter) Status() int {
+	if !w.decided {
+		return w.status
+	}
+	return w.ResponseWriter.Status()
+}
+
+func (w *compressWriter) Written() bool {
+	return w.decided || w.buffer.Len() > 0
+}
+
+// Flush sends what was written so far; a streamed response is compressed
+// regardless of its size, which is not known yet
+func (w *compressWriter) Flush() {
+	if !w.decided {
+		if err := w.decide(true); err != nil {
+			return
+		}
+	}
+	if w.encoder != nil {
+		_ = w.encoder.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+// decide writes the held back status and body, compressed if large is set
+// and the response qualifies
+func (w *compressWriter) decide(large bool) error {
+	w.decided = true
+	header := w.Header()
+	contentType := header.Get("Content-Type")
+	if contentType == "" && w.buffer.Len() > 0 {
+		contentType = http.DetectContentType(w.buffer.Bytes())
+	}
+
+	if compressible(contentType) {
+		header.Add("Vary", "Accept-Encoding")
+		if large && header.Get("Content-Encoding") == "" &&
+			w.status >= 200 && w.status < 300 && w.status != http.StatusNoContent && w.status != http.StatusPartialContent {
+			header.Set("Content-Encoding", w.encoding)
+			header.Del("Content-Length")
+			// The compressed body differs byte by byte, so a strong ETag of
+			// the original body becomes weak
+			if etag := header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+				header.Set("ETag", "W/"+etag)
+			}
+			w.encoder = encoders[w.encoding].Get().(encoder)
+			w.encoder.Reset(w.ResponseWriter)
+		}
+	}
+
+	w.ResponseWriter.WriteHeader(w.status)
+	if w.buffer.Len() == 0 {
+		return nil
+	}
+	var err error
+	if w.encoder != nil {
+		_, err = w.encoder.Write(w.buffer.Bytes())
+	} else {
+		_, err = w.ResponseWriter.Write(w.buffer.Bytes())
+	}
+	w.buffer.Reset()
+	return err
+}
+
+// finish sends a response that stayed below the minimum size as it is and
+// completes a compressed one
+func (w *compressWriter) finish() {
+	if !w.decided {
+		_ = w.decide(false)
+		w.ResponseWriter.WriteHeaderNow()
+	}
+	if w.encoder != nil {
+		_ = w.encoder.Close()
+		encoders[w.encoding].Put(w.encoder)
+		w.encoder = nil
+	}
+}
@@ -0,0 +1,124 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"elterngeld-portal/tests/testutils"
+
+	"github.com/andybalholm/brotli"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompressionMiddleware(t *testing.T) {
+	testutils.SetupGinTestMode()
+
+	large := strings.Repeat("Elterngeld Plus ", 200)
+	router := gin.New()
+	router.Use(CompressionMiddleware(1024))
+	router.GET("/large", func(c *gin.Context) {
+		c.Header("ETag", `"abc"`)
+		c.JSON(http.StatusOK, gin.H{"text": large})
+	})
+	router.GET("/small", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"text": "kurz"})
+	})
+	router.GET("/pdf", func(c *gin.Context) {
+		c.Data(http.StatusOK, "application/pdf", []byte(large))
+	})
+	router.GET("/stream", func(c *gin.Context) {
+		c.Header("Content-Type", "text/csv")
+		c.Status(http.StatusOK)
+		_, _ = c.Writer.WriteString("id,name\n")
+		c.Writer.Flush()
+		_, _ = c.Writer.WriteString("1,Anna\n")
+	})
+	router.GET("/empty", func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	get := func(path, acceptEncoding string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if acceptEncoding != "" {
+			req.Header.Set("Accept-Encoding", acceptEncoding)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	gunzip := func(w *httptest.ResponseRecorder) string {
+		reader, err := gzip.NewReader(w.Body)
+		require.NoError(t, err)
+		body, err := io.ReadAll(reader)
+		require.NoError(t, err)
+		return string(body)
+	}
+
+	w := get("/large", "br, gzip;q=0.8")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "br", w.Header().Get("Content-Encoding"))
+	assert.Equal(t, "Accept-Encoding", w.Header().Get("Vary"))
+	assert.Equal(t, `W/"abc"`, w.Header().Get("ETag"))
+	assert.Less(t, w.Body.Len(), len(large))
+	body, err := io.ReadAll(brotli.NewReader(w.Body))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"text":"`+large+`"}`, string(body))
+
+	w = get("/large", "gzip, deflate")
+	assert.Equal(t, "gzip", w.Header().Get("Content-Encoding"))
+	assert.Equal(t, `W/"abc"`, w.Header().Get("ETag"))
+	assert.JSONEq(t, `{"text":"`+large+`"}`, gunzip(w))
+
+	// Clients that accept neither get the plain body
+	for _, acceptEncoding := range []string{"", "identity", "gzip;q=0", "*;q=0", "deflate"} {
+		w = get("/large", acceptEncoding)
+		assert.Empty(t, w.Header().Get("Content-Encoding"), acceptEncoding)
+		assert.Equal(t, `"abc"`, w.Header().Get("ETag"), acceptEncoding)
+	}
+	assert.Equal(t, "br", get("/large", "*").Header().Get("Content-Encoding"))
+
+	w = get("/small", "gzip")
+	assert.Empty(t, w.Header().Get("Content-Encoding"))
+	assert.JSONEq(t, `{"text":"kurz"}`, w.Body.String())
+
+	w = get("/pdf", "gzip")
+	assert.Empty(t, w.Header().Get("Content-Encoding"))
+	assert.Equal(t, large, w.Body.String())
+
+	// Flushed responses are compressed right away
+	w = get("/stream", "gzip")
+	assert.Equal(t, "gzip", w.Header().Get("Content-Encoding"))
+	assert.Equal(t, "id,name\n1,Anna\n", gunzip(w))
+
+	w = get("/empty", "gzip")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Empty(t, w.Header().Get("Content-Encoding"))
+}
+
+func TestNegotiateEncoding(t *testing.T) {
+	tests := map[string]string{
+		"":                       "",
+		"identity":               "",
+		"gzip":                   "gzip",
+		"br":                     "br",
+		"gzip, deflate, br":      "br",
+		"br;q=0.5, gzip":         "gzip",
+		"BR; q=0.9, gzip;q=0.8":  "br",
+		"br;q=0, gzip":           "gzip",
+		"br;q=0, gzip;q=0":       "",
+		"*":                      "br",
+		"br;q=0, *":              "gzip",
+		"gzip;q=0.5, *;q=0.8":    "br",
+		"gzip, *;q=0":            "gzip",
+		"deflate, compress;q=.5": "",
+	}
+	for header, expected := range tests {
+		assert.Equal(t, expected, negotiateEncoding(header), header)
+	}
+}
@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"elterngeld-portal/pkg/i18n"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LanguageMiddleware negotiates the response language. An explicit "lang" query
+// parameter wins, followed by the authenticated user's language (applied by
+// AuthMiddleware) and the Accept-Language header. Without any of them messages
+// stay untranslated, so API clients keep getting the English message IDs.
+func LanguageMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if queryLang, ok := i18n.Parse(c.Query("lang")); ok {
+			c.Set("language_explicit", true)
+			setLanguage(c, queryLang)
+		} else if headerLang, ok := i18n.FromAcceptLanguage(c.GetHeader("Accept-Language")); ok {
+			setLanguage(c, headerLang)
+		}
+
+		c.Next()
+	}
+}
+
+// applyUserLanguage switches to the user's preferred language unless one was requested explicitly
+func applyUserLanguage(c *gin.Context, value string) {
+	if c.GetBool("language_explicit") {
+		return
+	}
+	if lang, ok := i18n.Parse(value); ok {
+		setLanguage(c, lang)
+	}
+}
+
+func setLanguage(c *gin.Context, lang i18n.Language) {
+	c.Set("language", lang)
+	c.Header("Content-Language", string(lang))
+}
+
+// GetLanguage returns the negotiated language of the current request. The
+// empty language is returned when none was negotiated, which leaves messages untranslated.
+func GetLanguage(c *gin.Context) i18n.Language {
+	if lang, exists := c.Get("language"); exists {
+		if l, ok := lang.(i18n.Language); ok {
+			return l
+		}
+	}
+	return ""
+}
+
+// T translates a message into the language of the current request
+func T(c *gin.Context, message string, args ...interface{}) string {
+	return i18n.T(GetLanguage(c), message, args...)
+}
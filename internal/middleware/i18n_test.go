@@ -0,0 +1,105 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/pkg/i18n"
+	"elterngeld-portal/tests/testutils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLanguageMiddleware(t *testing.T) {
+	testutils.SetupGinTestMode()
+
+	tests := []struct {
+		name           string
+		url            string
+		acceptLanguage string
+		expected       i18n.Language
+	}{
+		{"not_negotiated", "/test", "", ""},
+		{"accept_language", "/test", "en-US,en;q=0.9", i18n.English},
+		{"german_accept_language", "/test", "de-DE,de;q=0.9", i18n.German},
+		{"unsupported_accept_language", "/test", "fr-FR", ""},
+		{"query_parameter", "/test?lang=en", "de-DE", i18n.English},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest("GET", tt.url, nil)
+			if tt.acceptLanguage != "" {
+				c.Request.Header.Set("Accept-Language", tt.acceptLanguage)
+			}
+
+			LanguageMiddleware()(c)
+
+			assert.Equal(t, tt.expected, GetLanguage(c))
+			assert.Equal(t, string(tt.expected), w.Header().Get("Content-Language"))
+		})
+	}
+}
+
+func TestLanguageMiddleware_UserLanguage(t *testing.T) {
+	testutils.SetupGinTestMode()
+	ctx := testutils.SetupTestContext(t)
+	defer testutils.CleanupTestContext(ctx)
+
+	user := testutils.CreateTestUser(t, ctx.DB, models.RoleUser)
+	user.Language = "en"
+	token := testutils.GenerateAuthToken(t, ctx.JWTService, user)
+
+	router := gin.New()
+	router.Use(LanguageMiddleware(), AuthMiddleware(ctx.JWTService, nil))
+	router.GET("/test", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"language": GetLanguage(c)})
+	})
+
+	t.Run("user_language_overrides_header", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/test", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Accept-Language", "de-DE")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		var body map[string]string
+		testutils.ParseJSONResponse(t, w, &body)
+		assert.Equal(t, "en", body["language"])
+	})
+
+	t.Run("query_parameter_overrides_user_language", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/test?lang=de", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		var body map[string]string
+		testutils.ParseJSONResponse(t, w, &body)
+		assert.Equal(t, "de", body["language"])
+	})
+
+	t.Run("localized_error", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/test", nil)
+		req.Header.Set("Accept-Language", "de")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		require.Equal(t, http.StatusUnauthorized, w.Code)
+		testutils.AssertErrorResponse(t, w, http.StatusUnauthorized, "Authorization-Header erforderlich")
+	})
+	t.Run("untranslated_without_language", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/test", nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		require.Equal(t, http.StatusUnauthorized, w.Code)
+		testutils.AssertErrorResponse(t, w, http.StatusUnauthorized, "Authorization header is required")
+	})
+}
@@ -0,0 +1,83 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+
+	"elterngeld-portal/internal/integrations"
+	"elterngeld-portal/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// IntegrationAPIKeyMiddleware authenticates integrations such as Zapier and Make
+// with a personal API key sent in the X-API-Key header. Requests act as the key
+// owner, so role-based visibility and PII masking apply as in the portal.
+func IntegrationAPIKeyMiddleware(keys *integrations.Service, logger *zap.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		plain := c.GetHeader("X-API-Key")
+		if plain == "" {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error": T(c, "API key is required"),
+				"code":  "MISSING_API_KEY",
+			})
+			c.Abort()
+			return
+		}
+
+		key, err := keys.Authenticate(plain)
+		if err != nil {
+			if errors.Is(err, integrations.ErrInvalidAPIKey) {
+				c.JSON(http.StatusUnauthorized, gin.H{
+					"error": T(c, "Invalid API key"),
+					"code":  "INVALID_API_KEY",
+				})
+			} else {
+				logger.Error("Failed to authenticate API key", zap.Error(err))
+				c.JSON(http.StatusInternalServerError, gin.H{"error": T(c, "Internal server error")})
+			}
+			c.Abort()
+			return
+		}
+
+		c.Set("api_key", key)
+		c.Set("api_key_name", key.Name)
+		c.Set("user_id", key.User.ID)
+		c.Set("user_email", key.User.Email)
+		c.Set("user_role", key.User.Role)
+		applyUserLanguage(c, key.User.Language)
+		applyUserTimezone(c, key.User.Timezone)
+
+		c.Next()
+	}
+}
+
+// RequireAPIKeyScope ensures the API key was granted a scope and its owner still
+// holds the permission behind it
+func RequireAPIKeyScope(keys *integrations.Service, scope models.APIKeyScope) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key, ok := GetCurrentAPIKey(c)
+		if !ok || !keys.Authorize(key, scope) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": T(c, "API key lacks the %s scope", scope),
+				"code":  "INSUFFICIENT_SCOPE",
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// GetCurrentAPIKey extracts the integration API key from context
+func GetCurrentAPIKey(c *gin.Context) (*models.APIKey, bool) {
+	value, exists := c.Get("api_key")
+	if !exists {
+		return nil, false
+	}
+
+	key, ok := value.(*models.APIKey)
+	return key, ok
+}
@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"elterngeld-portal/internal/integrations"
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/tests/testutils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestIntegrationAPIKeyMiddleware(t *testing.T) {
+	testutils.SetupGinTestMode()
+	ctx := testutils.SetupTestContext(t)
+	defer testutils.CleanupTestContext(ctx)
+
+	keys := integrations.NewService(ctx.DB, zap.NewNop())
+	berater := testutils.CreateTestUser(t, ctx.DB, models.RoleBerater)
+	_, plain, err := keys.CreateAPIKey(berater, "Zapier", []models.APIKeyScope{models.APIKeyScopeLeadsRead}, nil)
+	require.NoError(t, err)
+
+	router := gin.New()
+	router.Use(IntegrationAPIKeyMiddleware(keys, zap.NewNop()))
+	router.GET("/leads", RequireAPIKeyScope(keys, models.APIKeyScopeLeadsRead), func(c *gin.Context) {
+		userID, _ := GetCurrentUserID(c)
+		role, _ := GetCurrentUserRole(c)
+		assert.Equal(t, berater.ID, userID)
+		assert.Equal(t, models.RoleBerater, role)
+		c.Status(http.StatusOK)
+	})
+	router.GET("/bookings", RequireAPIKeyScope(keys, models.APIKeyScopeBookingsRead), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	tests := []struct {
+		name   string
+		path   string
+		key    string
+		status int
+		code   string
+	}{
+		{"missing_key", "/leads", "", http.StatusUnauthorized, "MISSING_API_KEY"},
+		{"invalid_key", "/leads", models.APIKeyPrefix + "invalid", http.StatusUnauthorized, "INVALID_API_KEY"},
+		{"granted_scope", "/leads", plain, http.StatusOK, ""},
+		{"missing_scope", "/bookings", plain, http.StatusForbidden, "INSUFFICIENT_SCOPE"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.key != "" {
+				req.Header.Set("X-API-Key", tt.key)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.status, w.Code)
+			if tt.code != "" {
+				assert.Contains(t, w.Body.String(), tt.code)
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BodyLimitMiddleware limits the size of request bodies. Routes are limited
+// to defaultLimit unless routeLimits has an entry for them, keyed by method
+// and route pattern like "POST /api/v1/documents". Bodies that announce a
+// larger size are rejected right away; bodies without a length are cut off
+// while reading, see BodyTooLarge.
+func BodyLimitMiddleware(defaultLimit int64, routeLimits map[string]int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		limit, ok := routeLimits[c.Request.Method+" "+c.FullPath()]
+		if !ok {
+			limit = defaultLimit
+		}
+		if limit <= 0 || c.Request.Body == nil {
+			c.Next()
+			return
+		}
+
+		if c.Request.ContentLength > limit {
+			abortBodyTooLarge(c, limit)
+			return
+		}
+
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limit)
+		c.Next()
+	}
+}
+
+// BodyTooLarge checks if reading the request body failed because it exceeded
+// the body limit and responds with 413 if so. Handlers call it before
+// reporting a binding error as a bad request.
+func BodyTooLarge(c *gin.Context, err error) bool {
+	var maxBytesErr *http.MaxBytesError
+	if !errors.As(err, &maxBytesErr) {
+		return false
+	}
+	abortBodyTooLarge(c, maxBytesErr.Limit)
+	return true
+}
+
+func abortBodyTooLarge(c *gin.Context, limit int64) {
+	c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+		"error":     T(c, "Request body too large"),
+		"code":      "BODY_TOO_LARGE",
+		"max_bytes": limit,
+	})
+	c.Abort()
+}
@@ -0,0 +1,103 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"elterngeld-portal/tests/testutils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBodyLimitMiddleware(t *testing.T) {
+	testutils.SetupGinTestMode()
+
+	router := gin.New()
+	router.Use(BodyLimitMiddleware(16, map[string]int64{"POST /upload": 1024}))
+	handler := func(c *gin.Context) {
+		var body map[string]interface{}
+		if err := c.ShouldBindJSON(&body); err != nil {
+			if BodyTooLarge(c, err) {
+				return
+			}
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.Status(http.StatusOK)
+	}
+	router.POST("/json", handler)
+	router.POST("/upload", func(c *gin.Context) {
+		if _, _, err := c.Request.FormFile("file"); err != nil {
+			if BodyTooLarge(c, err) {
+				return
+			}
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.Status(http.StatusOK)
+	})
+
+	large := `{"name":"` + strings.Repeat("a", 64) + `"}`
+
+	tests := []struct {
+		name     string
+		path     string
+		body     io.Reader
+		chunked  bool
+		expected int
+	}{
+		{"small body", "/json", strings.NewReader(`{"a":1}`), false, http.StatusOK},
+		{"announced size too large", "/json", strings.NewReader(large), false, http.StatusRequestEntityTooLarge},
+		{"unannounced size too large", "/json", strings.NewReader(large), true, http.StatusRequestEntityTooLarge},
+		{"route allows more", "/upload", nil, false, http.StatusOK},
+		{"upload too large", "/upload", nil, true, http.StatusRequestEntityTooLarge},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, contentType := tt.body, "application/json"
+			if tt.path == "/upload" {
+				size := 256
+				if tt.expected != http.StatusOK {
+					size = 4096
+				}
+				body, contentType = multipartBody(t, size)
+			}
+
+			req := httptest.NewRequest(http.MethodPost, tt.path, body)
+			req.Header.Set("Content-Type", contentType)
+			if tt.chunked {
+				req.ContentLength = -1
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expected, w.Code)
+			if tt.expected == http.StatusRequestEntityTooLarge {
+				var response map[string]interface{}
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+				assert.Equal(t, "BODY_TOO_LARGE", response["code"])
+				assert.NotZero(t, response["max_bytes"])
+			}
+		})
+	}
+}
+
+func multipartBody(t *testing.T, size int) (io.Reader, string) {
+	t.Helper()
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+	part, err := writer.CreateFormFile("file", "antrag.pdf")
+	require.NoError(t, err)
+	_, err = part.Write(bytes.Repeat([]byte("x"), size))
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+	return &buf, writer.FormDataContentType()
+}
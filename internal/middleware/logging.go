@@ -158,23 +158,6 @@ func (w *responseBodyWriter) Write(b []byte) (int, error) {
 	return w.ResponseWriter.Write(b)
 }
 
-// SecurityHeadersMiddleware adds security headers
-func SecurityHeadersMiddleware() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		c.Header("X-Content-Type-Options", "nosniff")
-		c.Header("X-Frame-Options", "DENY")
-		c.Header("X-XSS-Protection", "1; mode=block")
-		c.Header("Referrer-Policy", "strict-origin-when-cross-origin")
-
-		// Only add HSTS in production with HTTPS
-		if c.Request.TLS != nil {
-			c.Header("Strict-Transport-Security", "max-age=31536000; includeSubDomains")
-		}
-
-		c.Next()
-	}
-}
-
 // RecoveryMiddleware provides panic recovery with logging
 func RecoveryMiddleware(logger *zap.Logger) gin.HandlerFunc {
 	return gin.CustomRecovery(func(c *gin.Context, recovered interface{}) {
@@ -201,7 +184,7 @@ func RecoveryMiddleware(logger *zap.Logger) gin.HandlerFunc {
 		)
 
 		c.JSON(500, gin.H{
-			"error":      "Internal server error",
+			"error":      T(c, "Internal server error"),
 			"request_id": reqID,
 		})
 	})
@@ -255,7 +238,7 @@ func RateLimitMiddleware(rateLimiter *RateLimitInfo, logger *zap.Logger) gin.Han
 			c.Header("X-RateLimit-Reset", strconv.FormatInt(now.Add(rateLimiter.window).Unix(), 10))
 
 			c.JSON(429, gin.H{
-				"error": "Rate limit exceeded",
+				"error": T(c, "Rate limit exceeded"),
 				"code":  "RATE_LIMIT_EXCEEDED",
 			})
 			c.Abort()
@@ -0,0 +1,81 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/pkg/pii"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// PIIMaskingMiddleware masks emails, phone numbers and addresses in JSON responses
+// for staff without the pii.read permission. Customers only ever receive their own
+// records and public requests no personal data, so both are left unmasked.
+func PIIMaskingMiddleware(db *gorm.DB, logger *zap.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		writer := &piiMaskingWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+
+		c.Next()
+
+		body := writer.body.Bytes()
+		if !writer.buffering || len(body) == 0 {
+			return
+		}
+
+		if requiresPIIMasking(c, db) {
+			masked, err := pii.MaskJSON(body)
+			if err != nil {
+				// Never fall back to the unmasked body
+				logger.Error("Failed to mask personal data in response", zap.Error(err))
+				writer.ResponseWriter.WriteHeader(http.StatusInternalServerError)
+				masked, _ = json.Marshal(gin.H{"error": T(c, "Internal server error")})
+			}
+			body = masked
+		}
+
+		writer.ResponseWriter.Write(body)
+	}
+}
+
+func requiresPIIMasking(c *gin.Context, db *gorm.DB) bool {
+	role, ok := GetCurrentUserRole(c)
+	if !ok || role == models.RoleUser {
+		return false
+	}
+	userID, ok := GetCurrentUserID(c)
+	if !ok {
+		return true
+	}
+	return !models.HasPermission(db, userID, role, models.PermissionPIIRead)
+}
+
+// piiMaskingWriter holds back JSON bodies until the middleware decided whether to
+// mask them. Other content such as file downloads is written through.
+type piiMaskingWriter struct {
+	gin.ResponseWriter
+	body      bytes.Buffer
+	buffering bool
+	decided   bool
+}
+
+func (w *piiMaskingWriter) Write(data []byte) (int, error) {
+	if !w.decided {
+		w.decided = true
+		w.buffering = strings.HasPrefix(w.Header().Get("Content-Type"), "application/json")
+	}
+	if w.buffering {
+		return w.body.Write(data)
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *piiMaskingWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
@@ -0,0 +1,118 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/tests/testutils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+func TestPIIMaskingMiddleware(t *testing.T) {
+	testutils.SetupGinTestMode()
+	ctx := testutils.SetupTestContext(t)
+	defer testutils.CleanupTestContext(ctx)
+
+	tests := []struct {
+		name   string
+		role   models.UserRole
+		masked bool
+	}{
+		{"anonymous", "", false},
+		{"customer", models.RoleUser, false},
+		{"junior_berater", models.RoleJuniorBerater, true},
+		{"berater", models.RoleBerater, false},
+		{"admin", models.RoleAdmin, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := performPIIRequest(ctx.DB, uuid.New(), tt.role)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			if tt.masked {
+				assert.JSONEq(t, `{"email":"a***@example.com","phone":"***67","address":"***","first_name":"Anna"}`, w.Body.String())
+			} else {
+				assert.JSONEq(t, `{"email":"anna@example.com","phone":"0171 1234567","address":"Hauptstraße 5","first_name":"Anna"}`, w.Body.String())
+			}
+		})
+	}
+
+	t.Run("downloads_pass_through", func(t *testing.T) {
+		router := gin.New()
+		router.Use(PIIMaskingMiddleware(ctx.DB, zap.NewNop()), func(c *gin.Context) {
+			c.Set("user_id", uuid.New())
+			c.Set("user_role", models.RoleJuniorBerater)
+		})
+		router.GET("/file", func(c *gin.Context) {
+			c.Data(http.StatusOK, "text/csv", []byte("email\nanna@example.com\n"))
+		})
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/file", nil))
+		assert.Equal(t, "email\nanna@example.com\n", w.Body.String())
+	})
+}
+
+func TestPIIMaskingMiddleware_ExplicitPermission(t *testing.T) {
+	testutils.SetupGinTestMode()
+	ctx := testutils.SetupTestContext(t)
+	defer testutils.CleanupTestContext(ctx)
+
+	require.NoError(t, ctx.DB.AutoMigrate(&models.Permission{}, &models.UserPermission{}, &models.Role{}, &models.RolePermission{}))
+	admin := testutils.CreateTestUser(t, ctx.DB, models.RoleAdmin)
+	junior := testutils.CreateTestUser(t, ctx.DB, models.RoleJuniorBerater)
+	berater := testutils.CreateTestUser(t, ctx.DB, models.RoleBerater)
+
+	permission := models.Permission{Name: models.PermissionPIIRead, Resource: models.PermissionResourcePII, Action: models.PermissionActionRead, IsActive: true}
+	require.NoError(t, ctx.DB.Create(&permission).Error)
+
+	grant := func(userID uuid.UUID, granted bool) {
+		require.NoError(t, ctx.DB.Create(&models.UserPermission{
+			UserID: userID, PermissionID: permission.ID, IsGranted: granted,
+			GrantedAt: time.Now(), GrantedBy: admin.ID,
+		}).Error)
+		// GORM applies the column default to false on insert
+		require.NoError(t, ctx.DB.Model(&models.UserPermission{}).
+			Where("user_id = ?", userID).Update("is_granted", granted).Error)
+	}
+	grant(junior.ID, true)
+	grant(berater.ID, false)
+
+	w := performPIIRequest(ctx.DB, junior.ID, junior.Role)
+	assert.Contains(t, w.Body.String(), "anna@example.com")
+
+	w = performPIIRequest(ctx.DB, berater.ID, berater.Role)
+	assert.Contains(t, w.Body.String(), "a***@example.com")
+}
+
+func performPIIRequest(db *gorm.DB, userID uuid.UUID, role models.UserRole) *httptest.ResponseRecorder {
+	router := gin.New()
+	router.Use(PIIMaskingMiddleware(db, zap.NewNop()), func(c *gin.Context) {
+		if role != "" {
+			c.Set("user_id", userID)
+			c.Set("user_role", role)
+		}
+	})
+	router.GET("/test", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{
+			"email":      "anna@example.com",
+			"phone":      "0171 1234567",
+			"address":    "Hauptstraße 5",
+			"first_name": "Anna",
+		})
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/test", nil))
+	return w
+}
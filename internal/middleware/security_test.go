@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"elterngeld-portal/tests/testutils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSecurityHeadersMiddleware(t *testing.T) {
+	testutils.SetupGinTestMode()
+
+	router := gin.New()
+	router.Use(SecurityHeadersMiddleware(24*time.Hour, "default-src 'none'"))
+	router.GET("/", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, "nosniff", w.Header().Get("X-Content-Type-Options"))
+	assert.Equal(t, "strict-origin-when-cross-origin", w.Header().Get("Referrer-Policy"))
+	assert.Equal(t, "default-src 'none'", w.Header().Get("Content-Security-Policy"))
+	assert.Empty(t, w.Header().Get("Strict-Transport-Security"), "no HSTS over plain HTTP")
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Forwarded-Proto", "https")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, "max-age=86400; includeSubDomains", w.Header().Get("Strict-Transport-Security"))
+}
+
+func TestCSRFMiddleware(t *testing.T) {
+	testutils.SetupGinTestMode()
+
+	router := gin.New()
+	router.Use(CSRFMiddleware())
+	router.POST("/refresh", func(c *gin.Context) { c.Status(http.StatusOK) })
+	router.GET("/refresh", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	tests := []struct {
+		name     string
+		method   string
+		cookies  map[string]string
+		header   string
+		expected int
+	}{
+		{"bearer client without cookies", http.MethodPost, nil, "", http.StatusOK},
+		{"matching token", http.MethodPost, map[string]string{RefreshCookieName: "r", CSRFCookieName: "abc"}, "abc", http.StatusOK},
+		{"missing header", http.MethodPost, map[string]string{RefreshCookieName: "r", CSRFCookieName: "abc"}, "", http.StatusForbidden},
+		{"wrong header", http.MethodPost, map[string]string{RefreshCookieName: "r", CSRFCookieName: "abc"}, "abd", http.StatusForbidden},
+		{"missing csrf cookie", http.MethodPost, map[string]string{RefreshCookieName: "r"}, "", http.StatusForbidden},
+		{"safe method", http.MethodGet, map[string]string{RefreshCookieName: "r"}, "", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/refresh", nil)
+			for name, value := range tt.cookies {
+				req.AddCookie(&http.Cookie{Name: name, Value: value})
+			}
+			if tt.header != "" {
+				req.Header.Set(CSRFHeaderName, tt.header)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, tt.expected, w.Code)
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"time"
+
+	"elterngeld-portal/pkg/timeutil"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TimezoneMiddleware determines the timezone used to present times to the client.
+// An explicit "tz" query parameter wins, followed by the X-Timezone header, the
+// authenticated user's timezone (applied by AuthMiddleware) and defaultTimezone.
+func TimezoneMiddleware(defaultTimezone string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		name := defaultTimezone
+		if tz := c.Query("tz"); timeutil.IsValidTimezone(tz) {
+			name = tz
+			c.Set("timezone_explicit", true)
+		} else if tz := c.GetHeader("X-Timezone"); timeutil.IsValidTimezone(tz) {
+			name = tz
+			c.Set("timezone_explicit", true)
+		}
+
+		c.Set("timezone", timeutil.LoadLocation(name))
+		c.Next()
+	}
+}
+
+// applyUserTimezone switches to the user's timezone unless the client sent one explicitly
+func applyUserTimezone(c *gin.Context, name string) {
+	if c.GetBool("timezone_explicit") || !timeutil.IsValidTimezone(name) {
+		return
+	}
+	c.Set("timezone", timeutil.LoadLocation(name))
+}
+
+// GetTimezone returns the client timezone of the current request.
+// Without TimezoneMiddleware the default timezone is returned.
+func GetTimezone(c *gin.Context) *time.Location {
+	if loc, exists := c.Get("timezone"); exists {
+		if l, ok := loc.(*time.Location); ok {
+			return l
+		}
+	}
+	return timeutil.LoadLocation(timeutil.DefaultTimezone)
+}
@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"elterngeld-portal/tests/testutils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimezoneMiddleware(t *testing.T) {
+	testutils.SetupGinTestMode()
+
+	tests := []struct {
+		name     string
+		url      string
+		header   string
+		expected string
+	}{
+		{"default", "/test", "", "Europe/Berlin"},
+		{"header", "/test", "America/New_York", "America/New_York"},
+		{"invalid_header", "/test", "Mars/Olympus", "Europe/Berlin"},
+		{"server_local_rejected", "/test?tz=Local", "", "Europe/Berlin"},
+		{"query_parameter", "/test?tz=Asia/Tokyo", "America/New_York", "Asia/Tokyo"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest("GET", tt.url, nil)
+			if tt.header != "" {
+				c.Request.Header.Set("X-Timezone", tt.header)
+			}
+
+			TimezoneMiddleware("Europe/Berlin")(c)
+
+			assert.Equal(t, tt.expected, GetTimezone(c).String())
+		})
+	}
+}
+
+func TestApplyUserTimezone(t *testing.T) {
+	testutils.SetupGinTestMode()
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("GET", "/test", nil)
+	TimezoneMiddleware("Europe/Berlin")(c)
+	applyUserTimezone(c, "Europe/Lisbon")
+	assert.Equal(t, "Europe/Lisbon", GetTimezone(c).String())
+
+	c, _ = gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("GET", "/test?tz=UTC", nil)
+	TimezoneMiddleware("Europe/Berlin")(c)
+	applyUserTimezone(c, "Europe/Lisbon")
+	assert.Equal(t, "UTC", GetTimezone(c).String())
+}
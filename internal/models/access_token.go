@@ -0,0 +1,111 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// AccessTokenScope limits the part of the API a personal access token may use
+type AccessTokenScope string
+
+const (
+	AccessTokenScopeProfileRead    AccessTokenScope = "profile:read"
+	AccessTokenScopeLeadsRead      AccessTokenScope = "leads:read"
+	AccessTokenScopeLeadsWrite     AccessTokenScope = "leads:write"
+	AccessTokenScopeBookingsRead   AccessTokenScope = "bookings:read"
+	AccessTokenScopeBookingsWrite  AccessTokenScope = "bookings:write"
+	AccessTokenScopeDocumentsRead  AccessTokenScope = "documents:read"
+	AccessTokenScopeDocumentsWrite AccessTokenScope = "documents:write"
+	AccessTokenScopeTodosRead      AccessTokenScope = "todos:read"
+	AccessTokenScopeTodosWrite     AccessTokenScope = "todos:write"
+)
+
+// AccessTokenScopes lists every scope a token can be granted
+var AccessTokenScopes = []AccessTokenScope{
+	AccessTokenScopeProfileRead,
+	AccessTokenScopeLeadsRead,
+	AccessTokenScopeLeadsWrite,
+	AccessTokenScopeBookingsRead,
+	AccessTokenScopeBookingsWrite,
+	AccessTokenScopeDocumentsRead,
+	AccessTokenScopeDocumentsWrite,
+	AccessTokenScopeTodosRead,
+	AccessTokenScopeTodosWrite,
+}
+
+// IsValid checks if the scope exists
+func (s AccessTokenScope) IsValid() bool {
+	for _, scope := range AccessTokenScopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// AccessTokenPrefix marks personal access tokens so AuthMiddleware can tell
+// them apart from JWTs and leaked tokens are easy to recognize
+const AccessTokenPrefix = "egpat_"
+
+// AccessToken is a personal access token. Requests authenticated with it act
+// as its owner, limited to the granted scopes. Unlike other keys, tokens
+// always expire.
+type AccessToken struct {
+	ID     uuid.UUID `json:"id" gorm:"type:char(36);primary_key"`
+	UserID uuid.UUID `json:"user_id" gorm:"type:char(36);not null;index"`
+	Name   string    `json:"name" gorm:"size:100;not null"`
+	HashedKey[AccessTokenScope]
+
+	CreatedAt time.Time `json:"created_at" gorm:"not null"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"not null"`
+
+	// Relationships
+	User User `json:"-" gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+}
+
+// AccessTokenResponse is returned when listing tokens; the token itself is only shown once
+type AccessTokenResponse struct {
+	ID          uuid.UUID          `json:"id"`
+	Name        string             `json:"name"`
+	TokenPrefix string             `json:"token_prefix"`
+	Scopes      []AccessTokenScope `json:"scopes"`
+	LastUsedAt  *time.Time         `json:"last_used_at"`
+	ExpiresAt   *time.Time         `json:"expires_at"`
+	RevokedAt   *time.Time         `json:"revoked_at"`
+	CreatedAt   time.Time          `json:"created_at"`
+}
+
+func (t *AccessToken) BeforeCreate(tx *gorm.DB) error {
+	if t.ID == uuid.Nil {
+		t.ID = uuid.New()
+	}
+	return nil
+}
+
+// HasScope checks if the token was granted a scope. Write scopes include
+// reading the same resource.
+func (t *AccessToken) HasScope(scope AccessTokenScope) bool {
+	resource, _, _ := strings.Cut(string(scope), ":")
+	for _, granted := range t.GetScopes() {
+		if granted == scope || granted == AccessTokenScope(resource+":write") {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *AccessToken) ToResponse() AccessTokenResponse {
+	return AccessTokenResponse{
+		ID:          t.ID,
+		Name:        t.Name,
+		TokenPrefix: t.KeyPrefix,
+		Scopes:      t.GetScopes(),
+		LastUsedAt:  t.LastUsedAt,
+		ExpiresAt:   t.ExpiresAt,
+		RevokedAt:   t.RevokedAt,
+		CreatedAt:   t.CreatedAt,
+	}
+}
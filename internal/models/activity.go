@@ -3,6 +3,7 @@ package models
 import (
 	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -16,9 +17,11 @@ const (
 	ActivityTypeLeadUpdated       ActivityType = "lead_updated"
 	ActivityTypeLeadStatusChanged ActivityType = "lead_status_changed"
 	ActivityTypeLeadAssigned      ActivityType = "lead_assigned"
+	ActivityTypeLeadReleased      ActivityType = "lead_released"
 	ActivityTypeCommentAdded      ActivityType = "comment_added"
 	ActivityTypeDocumentUploaded  ActivityType = "document_uploaded"
 	ActivityTypeDocumentDeleted   ActivityType = "document_deleted"
+	ActivityTypeDocumentMatched   ActivityType = "document_matched"
 	ActivityTypePaymentCreated    ActivityType = "payment_created"
 	ActivityTypePaymentCompleted  ActivityType = "payment_completed"
 	ActivityTypePaymentFailed     ActivityType = "payment_failed"
@@ -27,14 +30,44 @@ const (
 	ActivityTypeUserLogout        ActivityType = "user_logout"
 	ActivityTypePasswordChanged   ActivityType = "password_changed"
 	ActivityTypeEmailSent         ActivityType = "email_sent"
+	ActivityTypeEmailReceived     ActivityType = "email_received"
+	ActivityTypeWhatsAppSent      ActivityType = "whatsapp_sent"
+	ActivityTypeWhatsAppReceived  ActivityType = "whatsapp_received"
+	ActivityTypeLinkClicked       ActivityType = "link_clicked"
+	ActivityTypeLeadExported      ActivityType = "lead_exported"
+	ActivityTypeBookingReassigned ActivityType = "booking_reassigned"
+	ActivityTypeLeadInactive      ActivityType = "lead_inactive"
+	ActivityTypeLeadWinBackSent   ActivityType = "lead_win_back_sent"
+	ActivityTypeLeadReactivated   ActivityType = "lead_reactivated"
+	ActivityTypeLeadRestored      ActivityType = "lead_restored"
+	ActivityTypeLeadDeleted       ActivityType = "lead_deleted"
+	ActivityTypeTodoCreated       ActivityType = "todo_created"
+	ActivityTypeTodoUpdated       ActivityType = "todo_updated"
+	ActivityTypeTodoCompleted     ActivityType = "todo_completed"
+	ActivityTypeBookingCreated    ActivityType = "booking_created"
+	ActivityTypeBookingStatus     ActivityType = "booking_status_changed"
 	ActivityTypeSystem            ActivityType = "system"
+
+	ActivityTypeApplicationSubmitted ActivityType = "application_submitted"
+	ActivityTypeDocumentsRequested   ActivityType = "documents_requested"
+	ActivityTypeBescheidReceived     ActivityType = "bescheid_received"
+
+	ActivityTypeRefundRequested ActivityType = "refund_requested"
+	ActivityTypeRefundApproved  ActivityType = "refund_approved"
+	ActivityTypeRefundRejected  ActivityType = "refund_rejected"
+	ActivityTypeRefundExecuted  ActivityType = "refund_executed"
 )
 
+// IsValid checks if the activity type is known
+func (at ActivityType) IsValid() bool {
+	return at.GetDisplayName() != "Unbekannte Aktivität"
+}
+
 // Activity represents an activity/event in the system
 type Activity struct {
 	ID     uuid.UUID  `json:"id" gorm:"type:char(36);primary_key"`
 	UserID *uuid.UUID `json:"user_id" gorm:"type:char(36);index"`
-	LeadID *uuid.UUID `json:"lead_id" gorm:"type:char(36);index"`
+	LeadID *uuid.UUID `json:"lead_id" gorm:"type:char(36);index;index:idx_activities_lead_created,priority:1"`
 
 	// Activity information
 	Type        ActivityType `json:"type" gorm:"not null;index" validate:"required"`
@@ -49,7 +82,7 @@ type Activity struct {
 	UserAgent string `json:"user_agent" gorm:""`
 
 	// Timestamps
-	CreatedAt time.Time `json:"created_at" gorm:"not null;index"`
+	CreatedAt time.Time `json:"created_at" gorm:"not null;index;index:idx_activities_lead_created,priority:2"`
 
 	// Relationships
 	User *User `json:"user,omitempty" gorm:"constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
@@ -213,12 +246,16 @@ func (at ActivityType) GetDisplayName() string {
 		return "Lead-Status geändert"
 	case ActivityTypeLeadAssigned:
 		return "Lead zugewiesen"
+	case ActivityTypeLeadReleased:
+		return "Lead freigegeben"
 	case ActivityTypeCommentAdded:
 		return "Kommentar hinzugefügt"
 	case ActivityTypeDocumentUploaded:
 		return "Dokument hochgeladen"
 	case ActivityTypeDocumentDeleted:
 		return "Dokument gelöscht"
+	case ActivityTypeDocumentMatched:
+		return "Dokument zugeordnet"
 	case ActivityTypePaymentCreated:
 		return "Zahlung erstellt"
 	case ActivityTypePaymentCompleted:
@@ -235,6 +272,52 @@ func (at ActivityType) GetDisplayName() string {
 		return "Passwort geändert"
 	case ActivityTypeEmailSent:
 		return "E-Mail gesendet"
+	case ActivityTypeEmailReceived:
+		return "E-Mail empfangen"
+	case ActivityTypeWhatsAppSent:
+		return "WhatsApp-Nachricht gesendet"
+	case ActivityTypeWhatsAppReceived:
+		return "WhatsApp-Nachricht empfangen"
+	case ActivityTypeLinkClicked:
+		return "Link geklickt"
+	case ActivityTypeLeadExported:
+		return "Fallakte exportiert"
+	case ActivityTypeBookingReassigned:
+		return "Termin übertragen"
+	case ActivityTypeLeadInactive:
+		return "Als inaktiv markiert"
+	case ActivityTypeLeadWinBackSent:
+		return "Reaktivierungs-E-Mail gesendet"
+	case ActivityTypeLeadReactivated:
+		return "Reaktiviert"
+	case ActivityTypeLeadRestored:
+		return "Wiederhergestellt"
+	case ActivityTypeLeadDeleted:
+		return "Lead gelöscht"
+	case ActivityTypeTodoCreated:
+		return "Aufgabe erstellt"
+	case ActivityTypeTodoUpdated:
+		return "Aufgabe aktualisiert"
+	case ActivityTypeTodoCompleted:
+		return "Aufgabe erledigt"
+	case ActivityTypeBookingCreated:
+		return "Termin gebucht"
+	case ActivityTypeBookingStatus:
+		return "Terminstatus geändert"
+	case ActivityTypeApplicationSubmitted:
+		return "Antrag eingereicht"
+	case ActivityTypeDocumentsRequested:
+		return "Unterlagen nachgefordert"
+	case ActivityTypeBescheidReceived:
+		return "Bescheid erhalten"
+	case ActivityTypeRefundRequested:
+		return "Erstattung beantragt"
+	case ActivityTypeRefundApproved:
+		return "Erstattung genehmigt"
+	case ActivityTypeRefundRejected:
+		return "Erstattung abgelehnt"
+	case ActivityTypeRefundExecuted:
+		return "Erstattung ausgeführt"
 	case ActivityTypeSystem:
 		return "System-Aktivität"
 	default:
@@ -253,12 +336,16 @@ func (at ActivityType) GetIconName() string {
 		return "refresh"
 	case ActivityTypeLeadAssigned:
 		return "user-plus"
+	case ActivityTypeLeadReleased:
+		return "user-minus"
 	case ActivityTypeCommentAdded:
 		return "message-circle"
 	case ActivityTypeDocumentUploaded:
 		return "upload"
 	case ActivityTypeDocumentDeleted:
 		return "trash-2"
+	case ActivityTypeDocumentMatched:
+		return "file-check"
 	case ActivityTypePaymentCreated:
 		return "credit-card"
 	case ActivityTypePaymentCompleted:
@@ -275,6 +362,44 @@ func (at ActivityType) GetIconName() string {
 		return "lock"
 	case ActivityTypeEmailSent:
 		return "mail"
+	case ActivityTypeEmailReceived:
+		return "inbox"
+	case ActivityTypeWhatsAppSent, ActivityTypeWhatsAppReceived:
+		return "message-circle"
+	case ActivityTypeLinkClicked:
+		return "mouse-pointer"
+	case ActivityTypeLeadExported:
+		return "download"
+	case ActivityTypeBookingReassigned:
+		return "repeat"
+	case ActivityTypeLeadInactive:
+		return "clock"
+	case ActivityTypeLeadWinBackSent:
+		return "send"
+	case ActivityTypeLeadReactivated:
+		return "rotate-ccw"
+	case ActivityTypeLeadRestored:
+		return "archive-restore"
+	case ActivityTypeLeadDeleted:
+		return "trash"
+	case ActivityTypeTodoCreated:
+		return "list-plus"
+	case ActivityTypeTodoUpdated:
+		return "list"
+	case ActivityTypeTodoCompleted:
+		return "check-square"
+	case ActivityTypeBookingCreated:
+		return "calendar-plus"
+	case ActivityTypeBookingStatus:
+		return "calendar-check"
+	case ActivityTypeApplicationSubmitted:
+		return "send"
+	case ActivityTypeDocumentsRequested:
+		return "file-question"
+	case ActivityTypeBescheidReceived:
+		return "file-check"
+	case ActivityTypeRefundRequested, ActivityTypeRefundApproved, ActivityTypeRefundRejected, ActivityTypeRefundExecuted:
+		return "rotate-ccw"
 	case ActivityTypeSystem:
 		return "settings"
 	default:
@@ -295,6 +420,23 @@ func CreateLeadCreatedActivity(userID, leadID uuid.UUID, leadTitle string) *Acti
 		Build()
 }
 
+// CreateCommentAddedActivity creates an activity for a comment on a lead
+func CreateCommentAddedActivity(userID, leadID, commentID uuid.UUID) *Activity {
+	metadata := ActivityMetadata{
+		EntityType: "comment",
+		EntityID:   commentID.String(),
+	}
+
+	return NewActivityBuilder().
+		WithType(ActivityTypeCommentAdded).
+		WithTitle("Kommentar hinzugefügt").
+		WithDescription("Ein Kommentar wurde zum Lead hinzugefügt").
+		WithUser(userID).
+		WithLead(leadID).
+		WithMetadata(metadata).
+		Build()
+}
+
 // CreateLeadStatusChangedActivity creates an activity for lead status change
 func CreateLeadStatusChangedActivity(userID, leadID uuid.UUID, oldStatus, newStatus LeadStatus) *Activity {
 	metadata := ActivityMetadata{
@@ -351,3 +493,168 @@ func CreatePaymentCompletedActivity(userID, leadID uuid.UUID, amount float64, cu
 		WithMetadata(metadata).
 		Build()
 }
+
+// CreateDocumentMatchedActivity creates an activity for a classified document that
+// fulfilled an open todo
+func CreateDocumentMatchedActivity(leadID, documentID uuid.UUID, fileName string, documentType DocumentType, todoTitle string) *Activity {
+	metadata := ActivityMetadata{
+		EntityType: "document",
+		EntityID:   documentID.String(),
+		ExtraData: map[string]interface{}{
+			"file_name":     fileName,
+			"document_type": documentType,
+			"todo_title":    todoTitle,
+		},
+	}
+
+	return NewActivityBuilder().
+		WithType(ActivityTypeDocumentMatched).
+		WithTitle("Dokument zugeordnet").
+		WithDescription(fmt.Sprintf("%s '%s' wurde automatisch erkannt und der Aufgabe '%s' zugeordnet",
+			documentType.DisplayName(), fileName, todoTitle)).
+		WithLead(leadID).
+		WithMetadata(metadata).
+		Build()
+}
+
+// CreateEmailReceivedActivity creates an activity for an inbound email attached to a lead
+func CreateEmailReceivedActivity(leadID uuid.UUID, fromEmail, subject string, attachmentCount int) *Activity {
+	metadata := ActivityMetadata{
+		EntityType: "email",
+		ExtraData: map[string]interface{}{
+			"from_email":       fromEmail,
+			"subject":          subject,
+			"attachment_count": attachmentCount,
+		},
+	}
+
+	return NewActivityBuilder().
+		WithType(ActivityTypeEmailReceived).
+		WithTitle("E-Mail empfangen").
+		WithDescription(fmt.Sprintf("E-Mail von %s empfangen: %s", fromEmail, subject)).
+		WithLead(leadID).
+		WithMetadata(metadata).
+		Build()
+}
+
+// CreateLinkClickedActivity creates an activity for the first click on a tracked email link
+func CreateLinkClickedActivity(leadID uuid.UUID, code string, purpose ShortLinkPurpose, scoreDelta int) *Activity {
+	metadata := ActivityMetadata{
+		EntityType: "short_link",
+		EntityID:   code,
+		ExtraData: map[string]interface{}{
+			"purpose":     purpose,
+			"score_delta": scoreDelta,
+		},
+	}
+
+	return NewActivityBuilder().
+		WithType(ActivityTypeLinkClicked).
+		WithTitle("Link geklickt").
+		WithDescription(fmt.Sprintf("Link aus E-Mail (%s) wurde geöffnet", purpose.GetDisplayName())).
+		WithLead(leadID).
+		WithMetadata(metadata).
+		Build()
+}
+
+// CreateLeadExportedActivity records who exported the case file of a lead, in
+// which format, whether internal comments were included and from where
+func CreateLeadExportedActivity(userID, leadID uuid.UUID, format string, includeInternal bool, ipAddress, userAgent string) *Activity {
+	metadata := ActivityMetadata{
+		EntityType: "case_file",
+		ExtraData: map[string]interface{}{
+			"format":           format,
+			"include_internal": includeInternal,
+		},
+	}
+
+	return NewActivityBuilder().
+		WithType(ActivityTypeLeadExported).
+		WithTitle("Fallakte exportiert").
+		WithDescription(fmt.Sprintf("Die Fallakte wurde als %s exportiert", strings.ToUpper(format))).
+		WithUser(userID).
+		WithLead(leadID).
+		WithMetadata(metadata).
+		WithIPAddress(ipAddress).
+		WithUserAgent(userAgent).
+		Build()
+}
+
+// CreateLeadInactiveActivity creates an activity for a lead marked inactive by a lead aging rule
+func CreateLeadInactiveActivity(leadID, ruleID uuid.UUID, inactiveDays int) *Activity {
+	metadata := ActivityMetadata{
+		EntityType: "lead_aging_rule",
+		EntityID:   ruleID.String(),
+		ExtraData: map[string]interface{}{
+			"inactive_days": inactiveDays,
+		},
+	}
+
+	return NewActivityBuilder().
+		WithType(ActivityTypeLeadInactive).
+		WithTitle("Als inaktiv markiert").
+		WithDescription(fmt.Sprintf("Seit %d Tagen keine Aktivität", inactiveDays)).
+		WithLead(leadID).
+		WithMetadata(metadata).
+		Build()
+}
+
+// CreateLeadWinBackSentActivity creates an activity for a win-back email sent to the customer of an inactive lead
+func CreateLeadWinBackSentActivity(leadID uuid.UUID, number, total int) *Activity {
+	metadata := ActivityMetadata{
+		EntityType: "email",
+		ExtraData: map[string]interface{}{
+			"number": number,
+			"total":  total,
+		},
+	}
+
+	return NewActivityBuilder().
+		WithType(ActivityTypeLeadWinBackSent).
+		WithTitle("Reaktivierungs-E-Mail gesendet").
+		WithDescription(fmt.Sprintf("Reaktivierungs-E-Mail %d von %d wurde versendet", number, total)).
+		WithLead(leadID).
+		WithMetadata(metadata).
+		Build()
+}
+
+// CreateLeadReactivatedActivity creates an activity for an inactive lead that showed new activity
+func CreateLeadReactivatedActivity(leadID uuid.UUID) *Activity {
+	return NewActivityBuilder().
+		WithType(ActivityTypeLeadReactivated).
+		WithTitle("Reaktiviert").
+		WithDescription("Der inaktive Lead zeigt wieder Aktivität").
+		WithLead(leadID).
+		Build()
+}
+
+// CreateLeadRestoredActivity creates an activity for a deleted lead an admin restored from the trash
+func CreateLeadRestoredActivity(userID, leadID uuid.UUID) *Activity {
+	return NewActivityBuilder().
+		WithType(ActivityTypeLeadRestored).
+		WithTitle("Wiederhergestellt").
+		WithDescription("Der gelöschte Lead wurde aus dem Papierkorb wiederhergestellt").
+		WithUser(userID).
+		WithLead(leadID).
+		Build()
+}
+
+// CreateLeadLostActivity creates an activity for a lead closed as lost by a lead aging rule
+func CreateLeadLostActivity(leadID uuid.UUID, oldStatus LeadStatus, reason string) *Activity {
+	metadata := ActivityMetadata{
+		OldValue: string(oldStatus),
+		NewValue: string(LeadStatusCancelled),
+		Field:    "status",
+		ExtraData: map[string]interface{}{
+			"lost_reason": reason,
+		},
+	}
+
+	return NewActivityBuilder().
+		WithType(ActivityTypeLeadStatusChanged).
+		WithTitle("Als verloren geschlossen").
+		WithDescription(reason).
+		WithLead(leadID).
+		WithMetadata(metadata).
+		Build()
+}
@@ -0,0 +1,10 @@
+package models
+
+// AddressStatus is the result of validating a postal address with the address provider
+type AddressStatus string
+
+const (
+	AddressStatusUnchecked     AddressStatus = ""              // no provider configured, address outside Germany or lookup failed
+	AddressStatusVerified      AddressStatus = "verified"      // the provider knows the address; it was normalized
+	AddressStatusUndeliverable AddressStatus = "undeliverable" // the provider does not know the address
+)
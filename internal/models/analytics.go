@@ -0,0 +1,88 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// AnalyticsEventType is a product interaction tracked for the marketing funnel
+type AnalyticsEventType string
+
+const (
+	AnalyticsEventPageView       AnalyticsEventType = "page_view"
+	AnalyticsEventCalculatorUsed AnalyticsEventType = "calculator_used"
+	AnalyticsEventCheckoutStep   AnalyticsEventType = "checkout_step"
+	AnalyticsEventPreTalkBooked  AnalyticsEventType = "pre_talk_booked"
+)
+
+// IsValid checks if the event type can be collected
+func (t AnalyticsEventType) IsValid() bool {
+	switch t {
+	case AnalyticsEventPageView, AnalyticsEventCalculatorUsed, AnalyticsEventCheckoutStep, AnalyticsEventPreTalkBooked:
+		return true
+	}
+	return false
+}
+
+// Checkout steps, in order
+const (
+	CheckoutStepStarted   = "started"
+	CheckoutStepDetails   = "details"
+	CheckoutStepPayment   = "payment"
+	CheckoutStepCompleted = "completed"
+)
+
+// AnalyticsSession groups the events of an anonymous visitor until a period of
+// inactivity. Visitors are identified by a random ID the browser keeps after
+// analytics consent; no IP address, user agent or account is stored.
+type AnalyticsSession struct {
+	ID        uuid.UUID `json:"id" gorm:"type:char(36);primary_key"`
+	VisitorID uuid.UUID `json:"visitor_id" gorm:"type:char(36);not null;index"`
+
+	// Attribution of the first event
+	LandingPath  string `json:"landing_path" gorm:"size:255"`
+	ReferrerHost string `json:"referrer_host" gorm:"size:255"`
+	UTMSource    string `json:"utm_source" gorm:"size:100;index"`
+	UTMMedium    string `json:"utm_medium" gorm:"size:100"`
+	UTMCampaign  string `json:"utm_campaign" gorm:"size:100"`
+
+	EventCount int       `json:"event_count" gorm:"not null"`
+	StartedAt  time.Time `json:"started_at" gorm:"not null;index"`
+	LastSeenAt time.Time `json:"last_seen_at" gorm:"not null"`
+
+	CreatedAt time.Time `json:"created_at" gorm:"not null"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"not null"`
+}
+
+// AnalyticsEvent is a single tracked interaction
+type AnalyticsEvent struct {
+	ID        uuid.UUID          `json:"id" gorm:"type:char(36);primary_key"`
+	SessionID uuid.UUID          `json:"session_id" gorm:"type:char(36);not null;index"`
+	VisitorID uuid.UUID          `json:"visitor_id" gorm:"type:char(36);not null;index"`
+	Type      AnalyticsEventType `json:"type" gorm:"size:30;not null;index"`
+	Path      string             `json:"path" gorm:"size:255"`
+	Name      string             `json:"name,omitempty" gorm:"size:100"` // e.g. the calculator variant
+	Step      string             `json:"step,omitempty" gorm:"size:30"`  // checkout steps only
+
+	OccurredAt time.Time `json:"occurred_at" gorm:"not null;index"`
+	CreatedAt  time.Time `json:"created_at" gorm:"not null"`
+
+	// Relationships
+	Session AnalyticsSession `json:"-" gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+}
+
+func (s *AnalyticsSession) BeforeCreate(tx *gorm.DB) error {
+	if s.ID == uuid.Nil {
+		s.ID = uuid.New()
+	}
+	return nil
+}
+
+func (e *AnalyticsEvent) BeforeCreate(tx *gorm.DB) error {
+	if e.ID == uuid.Nil {
+		e.ID = uuid.New()
+	}
+	return nil
+}
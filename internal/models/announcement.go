@@ -0,0 +1,77 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// AnnouncementAudience is the group of users an announcement is sent to
+type AnnouncementAudience string
+
+const (
+	AnnouncementAudienceAll       AnnouncementAudience = "all"       // every active user
+	AnnouncementAudienceCustomers AnnouncementAudience = "customers" // customers with a pending or confirmed upcoming booking
+	AnnouncementAudienceBeraters  AnnouncementAudience = "beraters"  // Berater and Junior-Berater
+)
+
+// IsValid checks if the audience is known
+func (a AnnouncementAudience) IsValid() bool {
+	return a == AnnouncementAudienceAll || a == AnnouncementAudienceCustomers || a == AnnouncementAudienceBeraters
+}
+
+// Announcement is a notice admins broadcast to a group of users, e.g. changed
+// opening hours or new terms. It is published at PublishAt as an in-app
+// notification and optionally as email; recipients of compliance notices
+// have to confirm they read them.
+type Announcement struct {
+	ID          uuid.UUID            `json:"id" gorm:"type:char(36);primary_key"`
+	Title       string               `json:"title" gorm:"size:200;not null"`
+	Message     string               `json:"message" gorm:"type:text;not null"`
+	Audience    AnnouncementAudience `json:"audience" gorm:"size:20;not null"`
+	SendEmail   bool                 `json:"send_email" gorm:"not null"`
+	Compliance  bool                 `json:"compliance" gorm:"not null"` // recipients have to confirm they read it
+	PublishAt   time.Time            `json:"publish_at" gorm:"not null;index"`
+	PublishedAt *time.Time           `json:"published_at" gorm:""`
+	Recipients  int                  `json:"recipients" gorm:"not null"` // users it was published to
+	CreatedByID uuid.UUID            `json:"created_by_id" gorm:"type:char(36);not null"`
+
+	CreatedAt time.Time      `json:"created_at" gorm:"not null"`
+	UpdatedAt time.Time      `json:"updated_at" gorm:"not null"`
+	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+func (a *Announcement) BeforeCreate(tx *gorm.DB) error {
+	if a.ID == uuid.Nil {
+		a.ID = uuid.New()
+	}
+	return nil
+}
+
+// IsPublished checks if the announcement was sent to its audience
+func (a *Announcement) IsPublished() bool {
+	return a.PublishedAt != nil
+}
+
+// AnnouncementReceipt records that an announcement was published to a user
+// and when the user read it
+type AnnouncementReceipt struct {
+	ID             uuid.UUID  `json:"id" gorm:"type:char(36);primary_key"`
+	AnnouncementID uuid.UUID  `json:"announcement_id" gorm:"type:char(36);not null;uniqueIndex:idx_announcement_receipts_user"`
+	UserID         uuid.UUID  `json:"user_id" gorm:"type:char(36);not null;uniqueIndex:idx_announcement_receipts_user;index"`
+	ReadAt         *time.Time `json:"read_at" gorm:""`
+
+	CreatedAt time.Time `json:"created_at" gorm:"not null"`
+
+	// Relationships
+	Announcement Announcement `json:"-" gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	User         User         `json:"-" gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+}
+
+func (r *AnnouncementReceipt) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == uuid.Nil {
+		r.ID = uuid.New()
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// APIKeyScope limits what an integration such as Zapier or Make may do with a key
+type APIKeyScope string
+
+const (
+	APIKeyScopeLeadsRead     APIKeyScope = "leads:read"
+	APIKeyScopeLeadsWrite    APIKeyScope = "leads:write"
+	APIKeyScopeBookingsRead  APIKeyScope = "bookings:read"
+	APIKeyScopeCommentsWrite APIKeyScope = "comments:write"
+)
+
+// APIKeyScopePermissions maps each scope to the permission its owner must hold.
+// A key never grants more than its owner is allowed to do.
+var APIKeyScopePermissions = map[APIKeyScope]string{
+	APIKeyScopeLeadsRead:     "leads.read",
+	APIKeyScopeLeadsWrite:    "leads.create",
+	APIKeyScopeBookingsRead:  "bookings.read",
+	APIKeyScopeCommentsWrite: "leads.update",
+}
+
+// APIKeyPrefix marks portal API keys so leaked keys are easy to recognize
+const APIKeyPrefix = "egp_"
+
+// APIKey authenticates an integration on behalf of the staff member who created it
+type APIKey struct {
+	ID     uuid.UUID `json:"id" gorm:"type:char(36);primary_key"`
+	UserID uuid.UUID `json:"user_id" gorm:"type:char(36);not null;index"`
+	Name   string    `json:"name" gorm:"size:100;not null"`
+	HashedKey[APIKeyScope]
+
+	CreatedAt time.Time `json:"created_at" gorm:"not null"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"not null"`
+
+	// Relationships
+	User User `json:"-" gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+}
+
+// APIKeyResponse is returned when listing keys; the key itself is only shown once
+type APIKeyResponse struct {
+	ID         uuid.UUID     `json:"id"`
+	UserID     uuid.UUID     `json:"user_id"`
+	Name       string        `json:"name"`
+	KeyPrefix  string        `json:"key_prefix"`
+	Scopes     []APIKeyScope `json:"scopes"`
+	LastUsedAt *time.Time    `json:"last_used_at"`
+	ExpiresAt  *time.Time    `json:"expires_at"`
+	RevokedAt  *time.Time    `json:"revoked_at"`
+	CreatedAt  time.Time     `json:"created_at"`
+}
+
+func (k *APIKey) BeforeCreate(tx *gorm.DB) error {
+	if k.ID == uuid.Nil {
+		k.ID = uuid.New()
+	}
+	return nil
+}
+
+func (k *APIKey) ToResponse() APIKeyResponse {
+	return APIKeyResponse{
+		ID:         k.ID,
+		UserID:     k.UserID,
+		Name:       k.Name,
+		KeyPrefix:  k.KeyPrefix,
+		Scopes:     k.GetScopes(),
+		LastUsedAt: k.LastUsedAt,
+		ExpiresAt:  k.ExpiresAt,
+		RevokedAt:  k.RevokedAt,
+		CreatedAt:  k.CreatedAt,
+	}
+}
@@ -0,0 +1,129 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// SubmissionStatus is where an application stands at the Elterngeldstelle
+type SubmissionStatus string
+
+const (
+	SubmissionStatusSubmitted          SubmissionStatus = "eingereicht"
+	SubmissionStatusDocumentsRequested SubmissionStatus = "unterlagen_nachgefordert"
+	SubmissionStatusDecided            SubmissionStatus = "beschieden"
+)
+
+// IsValid checks if the submission status exists
+func (s SubmissionStatus) IsValid() bool {
+	switch s {
+	case SubmissionStatusSubmitted, SubmissionStatusDocumentsRequested, SubmissionStatusDecided:
+		return true
+	}
+	return false
+}
+
+// GrantedAmount is a monthly amount the Bescheid grants a parent for a range
+// of Lebensmonate
+type GrantedAmount struct {
+	Parent          string  `json:"parent"`
+	FromLebensmonat int     `json:"from_lebensmonat"`
+	ToLebensmonat   int     `json:"to_lebensmonat"`
+	MonthlyAmount   float64 `json:"monthly_amount"`
+}
+
+// ApplicationSubmission tracks the Elterngeld application of a lead after it
+// was submitted to the Elterngeldstelle, until the Bescheid arrives and the
+// Widerspruchsfrist ends
+type ApplicationSubmission struct {
+	ID              uuid.UUID        `json:"id" gorm:"type:char(36);primary_key"`
+	LeadID          uuid.UUID        `json:"lead_id" gorm:"type:char(36);not null;uniqueIndex"`
+	SubmittedByID   uuid.UUID        `json:"submitted_by_id" gorm:"type:char(36);not null"`
+	SubmittedAt     time.Time        `json:"submitted_at" gorm:"not null"`
+	Office          string           `json:"office" gorm:"size:200"`                 // Elterngeldstelle
+	OfficeReference string           `json:"office_reference" gorm:"size:100;index"` // Aktenzeichen of the Elterngeldstelle
+	Status          SubmissionStatus `json:"status" gorm:"size:30;not null;index"`
+
+	// Bescheid
+	BescheidDate       *time.Time      `json:"bescheid_date" gorm:""`
+	BescheidReceivedAt *time.Time      `json:"bescheid_received_at" gorm:""`
+	GrantedAmounts     json.RawMessage `json:"granted_amounts" gorm:"type:jsonb"`
+	GrantedTotal       float64         `json:"granted_total" gorm:""`
+	ObjectionDeadline  *time.Time      `json:"objection_deadline" gorm:"index"` // end of the Widerspruchsfrist
+	// VarianceFlaggedAt is set when the granted total falls significantly
+	// short of the calculated expectation
+	VarianceFlaggedAt *time.Time `json:"variance_flagged_at" gorm:"index"`
+
+	// Reminders of the Berater to ask the Elterngeldstelle for the Bescheid
+	// and to check it before the Widerspruchsfrist ends
+	FollowUpReminderID  *uuid.UUID `json:"follow_up_reminder_id" gorm:"type:char(36)"`
+	ObjectionReminderID *uuid.UUID `json:"objection_reminder_id" gorm:"type:char(36)"`
+
+	CreatedAt time.Time `json:"created_at" gorm:"not null"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"not null"`
+
+	// Relationships
+	Lead             *Lead                        `json:"lead,omitempty" gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	DocumentRequests []ApplicationDocumentRequest `json:"document_requests,omitempty" gorm:"foreignKey:SubmissionID"`
+}
+
+// BeforeCreate is a GORM hook that runs before creating a submission
+func (s *ApplicationSubmission) BeforeCreate(tx *gorm.DB) error {
+	if s.ID == uuid.Nil {
+		s.ID = uuid.New()
+	}
+	return nil
+}
+
+// GetGrantedAmounts decodes the amounts granted by the Bescheid
+func (s *ApplicationSubmission) GetGrantedAmounts() ([]GrantedAmount, error) {
+	amounts := []GrantedAmount{}
+	if len(s.GrantedAmounts) == 0 {
+		return amounts, nil
+	}
+	err := json.Unmarshal(s.GrantedAmounts, &amounts)
+	return amounts, err
+}
+
+// SetGrantedAmounts encodes the amounts granted by the Bescheid
+func (s *ApplicationSubmission) SetGrantedAmounts(amounts []GrantedAmount) error {
+	data, err := json.Marshal(amounts)
+	if err != nil {
+		return err
+	}
+	s.GrantedAmounts = data
+	return nil
+}
+
+// ApplicationDocumentRequest is a request of the Elterngeldstelle for
+// additional documents
+type ApplicationDocumentRequest struct {
+	ID           uuid.UUID  `json:"id" gorm:"type:char(36);primary_key"`
+	SubmissionID uuid.UUID  `json:"submission_id" gorm:"type:char(36);not null;index"`
+	LeadID       uuid.UUID  `json:"lead_id" gorm:"type:char(36);not null;index"`
+	CreatedByID  uuid.UUID  `json:"created_by_id" gorm:"type:char(36);not null"`
+	Description  string     `json:"description" gorm:"type:text;not null"`
+	ReceivedAt   time.Time  `json:"received_at" gorm:"not null"` // when the letter of the Elterngeldstelle arrived
+	DueDate      *time.Time `json:"due_date" gorm:"index"`
+	FulfilledAt  *time.Time `json:"fulfilled_at" gorm:""`
+	ReminderID   *uuid.UUID `json:"reminder_id" gorm:"type:char(36)"`
+
+	CreatedAt time.Time `json:"created_at" gorm:"not null"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"not null"`
+}
+
+// BeforeCreate is a GORM hook that runs before creating a document request
+func (r *ApplicationDocumentRequest) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == uuid.Nil {
+		r.ID = uuid.New()
+	}
+	return nil
+}
+
+// IsFulfilled checks if the requested documents were sent
+func (r *ApplicationDocumentRequest) IsFulfilled() bool {
+	return r.FulfilledAt != nil
+}
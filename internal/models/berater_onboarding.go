@@ -0,0 +1,257 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type OnboardingStatus string
+
+const (
+	OnboardingStatusProfilePending  OnboardingStatus = "profile_pending"
+	OnboardingStatusApprovalPending OnboardingStatus = "approval_pending"
+	OnboardingStatusApproved        OnboardingStatus = "approved"
+	OnboardingStatusRejected        OnboardingStatus = "rejected"
+)
+
+// BeraterInvitation represents an admin invitation for a new Berater
+type BeraterInvitation struct {
+	ID        uuid.UUID `json:"id" gorm:"type:char(36);primary_key"`
+	Email     string    `json:"email" gorm:"not null;index" validate:"required,email"`
+	FirstName string    `json:"first_name" gorm:"not null"`
+	LastName  string    `json:"last_name" gorm:"not null"`
+	Role      UserRole  `json:"role" gorm:"not null;default:'berater'" validate:"oneof=berater junior_berater"`
+
+	// Only the SHA-256 hash of the invitation token is stored
+	TokenHash string    `json:"-" gorm:"not null;uniqueIndex"`
+	ExpiresAt time.Time `json:"expires_at" gorm:"not null"`
+
+	InvitedBy  uuid.UUID  `json:"invited_by" gorm:"type:char(36);not null;index"`
+	AcceptedAt *time.Time `json:"accepted_at" gorm:""`
+	RevokedAt  *time.Time `json:"revoked_at" gorm:""`
+	UserID     *uuid.UUID `json:"user_id" gorm:"type:char(36);index"` // Berater account created on acceptance
+
+	CreatedAt time.Time      `json:"created_at" gorm:"not null"`
+	UpdatedAt time.Time      `json:"updated_at" gorm:"not null"`
+	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+
+	// Relationships
+	Inviter User  `json:"inviter,omitempty" gorm:"foreignKey:InvitedBy;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	User    *User `json:"user,omitempty" gorm:"constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
+}
+
+// AvailabilityRule represents a weekly recurring working window of a Berater
+// from which timeslots are generated. Times are wall clock times in the
+// Berater's timezone.
+type AvailabilityRule struct {
+	ID           uuid.UUID `json:"id" gorm:"type:char(36);primary_key"`
+	BeraterID    uuid.UUID `json:"berater_id" gorm:"type:char(36);not null;index"`
+	Weekday      int       `json:"weekday" gorm:"not null" validate:"gte=0,lte=6"`        // 0 = Sunday
+	StartTime    string    `json:"start_time" gorm:"size:5;not null" validate:"required"` // HH:MM
+	EndTime      string    `json:"end_time" gorm:"size:5;not null" validate:"required"`   // HH:MM
+	SlotDuration int       `json:"slot_duration" gorm:"not null;default:60"`              // in minutes
+	IsOnline     bool      `json:"is_online" gorm:"not null"`
+
+	CreatedAt time.Time      `json:"created_at" gorm:"not null"`
+	UpdatedAt time.Time      `json:"updated_at" gorm:"not null"`
+	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+
+	// Relationships
+	Berater User `json:"-" gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+}
+
+// BeraterRating aggregates the customer ratings of a Berater's completed bookings
+type BeraterRating struct {
+	BeraterID uuid.UUID `json:"-"`
+	Average   float64   `json:"average"`
+	Count     int64     `json:"count"`
+}
+
+// BeraterProfile is the public profile of a bookable Berater shown to customers
+type BeraterProfile struct {
+	ID                   uuid.UUID        `json:"id"`
+	FirstName            string           `json:"first_name"`
+	LastName             string           `json:"last_name"`
+	Name                 string           `json:"name"`
+	PhotoURL             string           `json:"photo_url,omitempty"`
+	Bio                  string           `json:"bio,omitempty"`
+	Specializations      []Specialization `json:"specializations"`
+	ServiceBundeslaender []Bundesland     `json:"service_bundeslaender"`
+	WorkingLanguages     []string         `json:"working_languages"`
+	Rating               *BeraterRating   `json:"rating,omitempty"` // nil until the first rating
+}
+
+// BeforeCreate hooks
+func (bi *BeraterInvitation) BeforeCreate(tx *gorm.DB) error {
+	if bi.ID == uuid.Nil {
+		bi.ID = uuid.New()
+	}
+	return nil
+}
+
+func (ar *AvailabilityRule) BeforeCreate(tx *gorm.DB) error {
+	if ar.ID == uuid.Nil {
+		ar.ID = uuid.New()
+	}
+	if ar.SlotDuration == 0 {
+		ar.SlotDuration = 60
+	}
+	return nil
+}
+
+// HashInvitationToken returns the stored representation of an invitation token
+func HashInvitationToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// IsExpired checks if the invitation is past its expiry date
+func (bi *BeraterInvitation) IsExpired() bool {
+	return time.Now().After(bi.ExpiresAt)
+}
+
+// IsPending checks if the invitation can still be accepted
+func (bi *BeraterInvitation) IsPending() bool {
+	return bi.AcceptedAt == nil && bi.RevokedAt == nil && !bi.IsExpired()
+}
+
+// Minutes parses the rule's start and end time into minutes after midnight
+func (ar *AvailabilityRule) Minutes() (int, int, error) {
+	start, err := parseClock(ar.StartTime)
+	if err != nil {
+		return 0, 0, err
+	}
+	end, err := parseClock(ar.EndTime)
+	if err != nil {
+		return 0, 0, err
+	}
+	return start, end, nil
+}
+
+// Validate checks that the rule describes a non-empty window on a weekday
+func (ar *AvailabilityRule) Validate() error {
+	if ar.Weekday < 0 || ar.Weekday > 6 {
+		return fmt.Errorf("invalid weekday %d", ar.Weekday)
+	}
+	start, end, err := ar.Minutes()
+	if err != nil {
+		return err
+	}
+	if end <= start {
+		return fmt.Errorf("end time %s must be after start time %s", ar.EndTime, ar.StartTime)
+	}
+	if ar.SlotDuration < 0 || (ar.SlotDuration > 0 && ar.SlotDuration > end-start) {
+		return fmt.Errorf("slot duration %d does not fit into %s-%s", ar.SlotDuration, ar.StartTime, ar.EndTime)
+	}
+	return nil
+}
+
+func parseClock(value string) (int, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, use HH:MM", value)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// Berater profile lists are stored as JSON arrays
+
+func (u *User) GetSpecializations() []Specialization {
+	return toSpecializations(decodeStringList(u.Specializations))
+}
+
+func (u *User) SetSpecializations(values []Specialization) {
+	u.Specializations = encodeStringList(fromSpecializations(values))
+}
+
+func (u *User) GetServiceBundeslaender() []Bundesland {
+	values := decodeStringList(u.ServiceBundeslaender)
+	result := make([]Bundesland, 0, len(values))
+	for _, value := range values {
+		result = append(result, Bundesland(value))
+	}
+	return result
+}
+
+func (u *User) SetServiceBundeslaender(values []Bundesland) {
+	list := make([]string, 0, len(values))
+	for _, value := range values {
+		list = append(list, string(value))
+	}
+	u.ServiceBundeslaender = encodeStringList(list)
+}
+
+func (u *User) GetWorkingLanguages() []string {
+	return decodeStringList(u.WorkingLanguages)
+}
+
+func (u *User) SetWorkingLanguages(values []string) {
+	u.WorkingLanguages = encodeStringList(values)
+}
+
+// ToPublicProfile returns the customer facing profile of a Berater
+func (u *User) ToPublicProfile(rating *BeraterRating) BeraterProfile {
+	if rating != nil && rating.Count == 0 {
+		rating = nil
+	}
+	return BeraterProfile{
+		ID:                   u.ID,
+		FirstName:            u.FirstName,
+		LastName:             u.LastName,
+		Name:                 u.FullName(),
+		PhotoURL:             u.PhotoURL,
+		Bio:                  u.Bio,
+		Specializations:      u.GetSpecializations(),
+		ServiceBundeslaender: u.GetServiceBundeslaender(),
+		WorkingLanguages:     u.GetWorkingLanguages(),
+		Rating:               rating,
+	}
+}
+
+// IsBookable checks if customers can book appointments with the user
+func (u *User) IsBookable() bool {
+	return (u.IsBerater() || u.IsJuniorBerater()) && u.IsActive && u.OnboardingStatus == OnboardingStatusApproved
+}
+
+func decodeStringList(value string) []string {
+	result := []string{}
+	if value == "" {
+		return result
+	}
+	if err := json.Unmarshal([]byte(value), &result); err != nil {
+		return []string{}
+	}
+	return result
+}
+
+func encodeStringList(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	data, err := json.Marshal(values)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+func (os OnboardingStatus) GetDisplayName() string {
+	switch os {
+	case OnboardingStatusProfilePending:
+		return "Profil unvollständig"
+	case OnboardingStatusApprovalPending:
+		return "Freigabe ausstehend"
+	case OnboardingStatusApproved:
+		return "Freigegeben"
+	case OnboardingStatusRejected:
+		return "Abgelehnt"
+	default:
+		return "Unbekannt"
+	}
+}
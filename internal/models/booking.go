@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"time"
 
+	"elterngeld-portal/pkg/timeutil"
+
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
@@ -26,6 +28,13 @@ const (
 	BookingTypeFollowUp     BookingType = "follow_up"
 )
 
+type TimeslotPurpose string
+
+const (
+	TimeslotPurposeConsultation TimeslotPurpose = "consultation" // bookable by customers
+	TimeslotPurposeInterview    TimeslotPurpose = "interview"    // job interview, picked by applicants
+)
+
 // Booking represents a booked appointment
 type Booking struct {
 	ID        uuid.UUID     `json:"id" gorm:"type:char(36);primary_key"`
@@ -33,6 +42,7 @@ type Booking struct {
 	PackageID *uuid.UUID    `json:"package_id" gorm:"type:char(36);index"`
 	BeraterID *uuid.UUID    `json:"berater_id" gorm:"type:char(36);index"`
 	LeadID    *uuid.UUID    `json:"lead_id" gorm:"type:char(36);index"`
+	FamilyID  *uuid.UUID    `json:"family_id" gorm:"type:char(36);index"` // copied from the lead, the family's parents see the booking
 	PaymentID *uuid.UUID    `json:"payment_id" gorm:"type:char(36);index"`
 	TimeslotID *uuid.UUID    `json:"timeslot_id" gorm:"type:char(36);index"`
 	
@@ -52,23 +62,49 @@ type Booking struct {
 	CustomerName    string `json:"customer_name" gorm:""`
 	CustomerEmail   string `json:"customer_email" gorm:""`
 	CustomerPhone   string `json:"customer_phone" gorm:""`
-	CustomerAddress string `json:"customer_address" gorm:"type:text"`
+	CustomerAddress string `json:"customer_address" gorm:"type:text;serializer:encrypted"`
+	AddressStatus   AddressStatus `json:"address_status" gorm:"size:20"` // set when the contact-info step validates the address
 	CustomerNotes   string `json:"customer_notes" gorm:"type:text"`
 	
+	// Company billing details of business customers, captured at checkout and printed on the invoice
+	CompanyName    string `json:"company_name,omitempty" gorm:"size:200"`
+	VATID          string `json:"vat_id,omitempty" gorm:"size:20"` // with country prefix, checked via VIES for EU businesses outside Germany
+	BillingAddress string `json:"billing_address,omitempty" gorm:"type:text;serializer:encrypted"`
+	BillingCountry string `json:"billing_country,omitempty" gorm:"size:2"`
+	
 	// Meeting details
 	MeetingLink     string `json:"meeting_link" gorm:""`
 	MeetingPassword string `json:"meeting_password" gorm:""`
 	Location        string `json:"location" gorm:""`
 	IsOnline        bool   `json:"is_online" gorm:"not null;default:true"`
 	
+	// Language of the consultation; without a language shared by customer and Berater an interpreter for InterpreterLanguage is needed
+	ConsultationLanguage string `json:"consultation_language,omitempty" gorm:"size:5"`
+	InterpreterRequired  bool   `json:"interpreter_required" gorm:"not null;index"`
+	InterpreterLanguage  string `json:"interpreter_language,omitempty" gorm:"size:5"`
+	
 	// Booking metadata
 	BookingReference string `json:"booking_reference" gorm:"uniqueIndex"`
 	InternalNotes    string `json:"internal_notes" gorm:"type:text"`
 	CancellationNote string `json:"cancellation_note" gorm:"type:text"`
 	
-	// Pricing (for display purposes)
-	TotalAmount float64 `json:"total_amount" gorm:"default:0"`
-	Currency    string  `json:"currency" gorm:"default:'EUR'"`
+	// Customer rating of the consultation (1-5), shown on the Berater's public profile
+	Rating        *int       `json:"rating" gorm:"" validate:"omitempty,min=1,max=5"`
+	RatingComment string     `json:"rating_comment" gorm:"type:text"`
+	RatedAt       *time.Time `json:"rated_at" gorm:""`
+	
+	// No-show handling: flagged by the scheduled check, then confirmed or corrected by the Berater
+	NoShowFlaggedAt   *time.Time `json:"no_show_flagged_at" gorm:"index"`
+	NoShowConfirmedAt *time.Time `json:"no_show_confirmed_at" gorm:""`
+	FollowUpSentAt    *time.Time `json:"follow_up_sent_at" gorm:""` // rescheduling offer to the customer
+	
+	WhatsAppReminderSentAt *time.Time `json:"whatsapp_reminder_sent_at" gorm:""`
+	
+	// Pricing: the total after the coupon discount, including VAT
+	TotalAmount    float64    `json:"total_amount" gorm:"default:0"`
+	DiscountAmount float64    `json:"discount_amount" gorm:"not null;default:0"`
+	CouponID       *uuid.UUID `json:"coupon_id" gorm:"type:char(36);index"`
+	Currency       string     `json:"currency" gorm:"default:'EUR'"`
 	
 	// Timestamps
 	BookedAt     time.Time      `json:"booked_at" gorm:"not null"`
@@ -90,11 +126,14 @@ type Booking struct {
 	Todos    []Todo    `json:"todos,omitempty" gorm:"foreignKey:BookingID"`
 }
 
-// BookingAddon represents the junction table for booking-addon relationships
+// BookingAddon represents the junction table for booking-addon relationships.
+// Price is the addon price when it was booked; addons included in the
+// package are booked at 0.
 type BookingAddon struct {
 	BookingID uuid.UUID `json:"booking_id" gorm:"type:char(36);primary_key"`
-	AddonID   uuid.UUID `json:"addon_id" gorm:"type:char(36);primary_key"`
+	AddonID   uuid.UUID `json:"addon_id" gorm:"type:char(36);primary_key;index"`
 	Price     float64   `json:"price" gorm:"not null"`
+	Included  bool      `json:"included" gorm:"not null"`
 	
 	CreatedAt time.Time `json:"created_at" gorm:"not null"`
 	
@@ -113,6 +152,8 @@ type Timeslot struct {
 	StartTime time.Time `json:"start_time" gorm:"not null"`
 	EndTime   time.Time `json:"end_time" gorm:"not null"`
 	Duration  int       `json:"duration" gorm:"not null"` // in minutes
+	Timezone  string    `json:"timezone" gorm:"size:64;not null;default:'Europe/Berlin'"` // Berater timezone the slot was planned in
+	Purpose   TimeslotPurpose `json:"purpose" gorm:"size:20;not null;default:'consultation';index"` // for interviews BeraterID is the interviewer
 	
 	// Availability
 	IsAvailable bool `json:"is_available" gorm:"not null;default:true"`
@@ -176,6 +217,7 @@ type BookingResponse struct {
 	PackageID        *uuid.UUID      `json:"package_id"`
 	BeraterID        *uuid.UUID      `json:"berater_id"`
 	LeadID           *uuid.UUID      `json:"lead_id"`
+	FamilyID         *uuid.UUID      `json:"family_id,omitempty"`
 	Title            string          `json:"title"`
 	Description      string          `json:"description"`
 	Type             BookingType     `json:"type"`
@@ -190,7 +232,13 @@ type BookingResponse struct {
 	MeetingLink      string          `json:"meeting_link"`
 	Location         string          `json:"location"`
 	IsOnline         bool            `json:"is_online"`
+	ConsultationLanguage string      `json:"consultation_language,omitempty"`
+	InterpreterRequired  bool        `json:"interpreter_required"`
+	InterpreterLanguage  string      `json:"interpreter_language,omitempty"`
 	BookingReference string          `json:"booking_reference"`
+	Rating           *int            `json:"rating,omitempty"`
+	RatingComment    string          `json:"rating_comment,omitempty"`
+	RatedAt          *time.Time      `json:"rated_at,omitempty"`
 	TotalAmount      float64         `json:"total_amount"`
 	FormattedAmount  string          `json:"formatted_amount"`
 	Currency         string          `json:"currency"`
@@ -206,6 +254,7 @@ type BookingResponse struct {
 	SelectedAddons   []AddonResponse `json:"selected_addons,omitempty"`
 	CanCancel        bool            `json:"can_cancel"`
 	CanReschedule    bool            `json:"can_reschedule"`
+	CanRate          bool            `json:"can_rate"`
 }
 
 // TimeslotResponse represents the timeslot data returned in API responses
@@ -216,10 +265,14 @@ type TimeslotResponse struct {
 	StartTime       time.Time    `json:"start_time"`
 	EndTime         time.Time    `json:"end_time"`
 	Duration        int          `json:"duration"`
+	Timezone        string       `json:"timezone"`
+	LocalDate       string       `json:"local_date"`
 	IsAvailable     bool         `json:"is_available"`
 	MaxBookings     int          `json:"max_bookings"`
 	CurrentBookings int          `json:"current_bookings"`
 	AvailableSlots  int          `json:"available_slots"`
+	HeldSlots       int          `json:"held_slots"`           // places reserved by checkouts in progress
+	HeldUntil       *time.Time   `json:"held_until,omitempty"` // when the last of these holds ends
 	Title           string       `json:"title"`
 	Location        string       `json:"location"`
 	IsOnline        bool         `json:"is_online"`
@@ -303,7 +356,7 @@ func (b *Booking) BeforeCreate(tx *gorm.DB) error {
 		b.EndTime = b.StartTime.Add(time.Duration(b.Duration) * time.Minute)
 	}
 	if b.BookedAt.IsZero() {
-		b.BookedAt = time.Now()
+		b.BookedAt = time.Now().UTC()
 	}
 	
 	return nil
@@ -316,6 +369,30 @@ func (t *Timeslot) BeforeCreate(tx *gorm.DB) error {
 	return nil
 }
 
+// BeforeSave hooks store all points in time in UTC
+func (b *Booking) BeforeSave(tx *gorm.DB) error {
+	b.ScheduledAt = b.ScheduledAt.UTC()
+	b.StartTime = b.StartTime.UTC()
+	b.EndTime = b.EndTime.UTC()
+	b.BookedAt = b.BookedAt.UTC()
+	return nil
+}
+
+func (t *Timeslot) BeforeSave(tx *gorm.DB) error {
+	if t.Timezone == "" {
+		t.Timezone = timeutil.DefaultTimezone
+	}
+	t.StartTime = t.StartTime.UTC()
+	t.EndTime = t.EndTime.UTC()
+	if !t.StartTime.IsZero() {
+		// Date is the local midnight of the slot in the Berater's timezone
+		t.Date = timeutil.StartOfDay(t.StartTime, t.TimeLocation())
+	} else {
+		t.Date = t.Date.UTC()
+	}
+	return nil
+}
+
 func (td *Todo) BeforeCreate(tx *gorm.DB) error {
 	if td.ID == uuid.Nil {
 		td.ID = uuid.New()
@@ -336,6 +413,7 @@ func (b *Booking) ToResponse() BookingResponse {
 		PackageID:        b.PackageID,
 		BeraterID:        b.BeraterID,
 		LeadID:           b.LeadID,
+		FamilyID:         b.FamilyID,
 		Title:            b.Title,
 		Description:      b.Description,
 		Type:             b.Type,
@@ -350,7 +428,13 @@ func (b *Booking) ToResponse() BookingResponse {
 		MeetingLink:      b.MeetingLink,
 		Location:         b.Location,
 		IsOnline:         b.IsOnline,
+		ConsultationLanguage: b.ConsultationLanguage,
+		InterpreterRequired:  b.InterpreterRequired,
+		InterpreterLanguage:  b.InterpreterLanguage,
 		BookingReference: b.BookingReference,
+		Rating:           b.Rating,
+		RatingComment:    b.RatingComment,
+		RatedAt:          b.RatedAt,
 		TotalAmount:      b.TotalAmount,
 		FormattedAmount:  b.FormatAmount(),
 		Currency:         b.Currency,
@@ -362,6 +446,7 @@ func (b *Booking) ToResponse() BookingResponse {
 		UpdatedAt:        b.UpdatedAt,
 		CanCancel:        b.CanCancel(),
 		CanReschedule:    b.CanReschedule(),
+		CanRate:          b.CanRate(),
 	}
 	
 	// Add relationships
@@ -389,13 +474,20 @@ func (b *Booking) ToResponse() BookingResponse {
 }
 
 func (t *Timeslot) ToResponse() TimeslotResponse {
+	return t.ToResponseIn(t.TimeLocation())
+}
+
+// ToResponseIn converts the timeslot to a response with all times in loc
+func (t *Timeslot) ToResponseIn(loc *time.Location) TimeslotResponse {
 	response := TimeslotResponse{
 		ID:              t.ID,
 		BeraterID:       t.BeraterID,
-		Date:            t.Date,
-		StartTime:       t.StartTime,
-		EndTime:         t.EndTime,
+		Date:            t.Date.In(loc),
+		StartTime:       t.StartTime.In(loc),
+		EndTime:         t.EndTime.In(loc),
 		Duration:        t.Duration,
+		Timezone:        loc.String(),
+		LocalDate:       timeutil.FormatDate(t.StartTime, loc),
 		IsAvailable:     t.IsAvailable,
 		MaxBookings:     t.MaxBookings,
 		CurrentBookings: t.CurrentBookings,
@@ -440,20 +532,46 @@ func (b *Booking) FormatAmount() string {
 	return formatCurrency(b.TotalAmount, b.Currency)
 }
 
+// DefaultChangeWindow is how long before the appointment customers can still
+// cancel or reschedule, unless admins configured other windows
+const DefaultChangeWindow = 24 * time.Hour
+
 func (b *Booking) CanCancel() bool {
-	// Can cancel if booking is pending or confirmed and not in the past
+	return b.CanCancelBefore(DefaultChangeWindow)
+}
+
+// CanCancelBefore checks if the booking is pending or confirmed and the
+// appointment is more than window away
+func (b *Booking) CanCancelBefore(window time.Duration) bool {
 	if b.Status != BookingStatusPending && b.Status != BookingStatusConfirmed {
 		return false
 	}
-	return time.Now().Before(b.StartTime.Add(-24 * time.Hour)) // 24h before appointment
+	return time.Now().Before(b.StartTime.Add(-window))
 }
 
 func (b *Booking) CanReschedule() bool {
-	// Can reschedule if booking is pending or confirmed and not in the past
+	return b.CanRescheduleBefore(DefaultChangeWindow)
+}
+
+// CanRescheduleBefore checks if the booking is pending or confirmed and the
+// appointment is more than window away
+func (b *Booking) CanRescheduleBefore(window time.Duration) bool {
 	if b.Status != BookingStatusPending && b.Status != BookingStatusConfirmed {
 		return false
 	}
-	return time.Now().Before(b.StartTime.Add(-24 * time.Hour)) // 24h before appointment
+	return time.Now().Before(b.StartTime.Add(-window))
+}
+
+// ReminderAt returns when the reminder for the booking is due: the day before the
+// appointment at the same wall clock time in loc, which is not always 24 hours
+// earlier when a DST transition lies in between
+func (b *Booking) ReminderAt(loc *time.Location) time.Time {
+	return timeutil.AddDays(b.StartTime, -1, loc)
+}
+
+// CanRate checks if the customer can still rate the consultation
+func (b *Booking) CanRate() bool {
+	return b.Status == BookingStatusCompleted && b.Rating == nil
 }
 
 func (b *Booking) IsUpcoming() bool {
@@ -472,6 +590,11 @@ func (t *Timeslot) IsInPast() bool {
 	return time.Now().After(t.EndTime)
 }
 
+// TimeLocation returns the timezone the timeslot was planned in
+func (t *Timeslot) TimeLocation() *time.Location {
+	return timeutil.LoadLocation(t.Timezone)
+}
+
 func (td *Todo) MarkCompleted() {
 	td.IsCompleted = true
 	now := time.Now()
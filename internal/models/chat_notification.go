@@ -0,0 +1,91 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type ChatProvider string
+
+const (
+	ChatProviderSlack ChatProvider = "slack"
+	ChatProviderTeams ChatProvider = "teams"
+)
+
+// ChatEvent is a portal event that can be posted to team chat channels
+type ChatEvent string
+
+const (
+	ChatEventLeadCreated     ChatEvent = "lead.created"
+	ChatEventBookingPaid     ChatEvent = "booking.paid"
+	ChatEventLeadSLABreached ChatEvent = "lead.sla_breached"
+	ChatEventInboxUnclaimed  ChatEvent = "inbox.unclaimed"
+)
+
+// ChatChannel is a Slack or Microsoft Teams incoming webhook that receives
+// notifications according to its routing rules
+type ChatChannel struct {
+	ID         uuid.UUID    `json:"id" gorm:"type:char(36);primary_key"`
+	Name       string       `json:"name" gorm:"size:100;not null"`
+	Provider   ChatProvider `json:"provider" gorm:"size:20;not null"`
+	WebhookURL string       `json:"-" gorm:"type:text;not null;serializer:encrypted"` // the URL is the credential
+	IsActive   bool         `json:"is_active" gorm:"not null"`
+
+	// Delivery status
+	LastDeliveredAt *time.Time `json:"last_delivered_at" gorm:""`
+	LastError       string     `json:"last_error,omitempty" gorm:"type:text"`
+
+	CreatedAt time.Time      `json:"created_at" gorm:"not null"`
+	UpdatedAt time.Time      `json:"updated_at" gorm:"not null"`
+	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+
+	// Relationships
+	Rules []ChatRoutingRule `json:"rules,omitempty" gorm:"foreignKey:ChannelID"`
+}
+
+// ChatRoutingRule sends an event to a channel. Optional filters narrow the rule
+// down to important leads or the cases of a single Berater.
+type ChatRoutingRule struct {
+	ID          uuid.UUID  `json:"id" gorm:"type:char(36);primary_key"`
+	ChannelID   uuid.UUID  `json:"channel_id" gorm:"type:char(36);not null;index"`
+	Event       ChatEvent  `json:"event" gorm:"size:50;not null;index"`
+	MinPriority Priority   `json:"min_priority,omitempty" gorm:"size:20"` // lead events only
+	BeraterID   *uuid.UUID `json:"berater_id,omitempty" gorm:"type:char(36)"`
+
+	CreatedAt time.Time `json:"created_at" gorm:"not null"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"not null"`
+
+	// Relationships
+	Channel ChatChannel `json:"-" gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+}
+
+func (c *ChatChannel) BeforeCreate(tx *gorm.DB) error {
+	if c.ID == uuid.Nil {
+		c.ID = uuid.New()
+	}
+	return nil
+}
+
+func (r *ChatRoutingRule) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == uuid.Nil {
+		r.ID = uuid.New()
+	}
+	return nil
+}
+
+// Matches checks if the rule applies to an event about a case with the given
+// priority and Berater
+func (r *ChatRoutingRule) Matches(event ChatEvent, priority Priority, beraterID *uuid.UUID) bool {
+	if r.Event != event {
+		return false
+	}
+	if r.MinPriority != "" && priority.Rank() < r.MinPriority.Rank() {
+		return false
+	}
+	if r.BeraterID != nil && (beraterID == nil || *beraterID != *r.BeraterID) {
+		return false
+	}
+	return true
+}
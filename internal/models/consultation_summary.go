@@ -0,0 +1,116 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// SummarySection is a part of a consultation summary
+type SummarySection string
+
+const (
+	SummarySectionTopic          SummarySection = "topic"
+	SummarySectionRecommendation SummarySection = "recommendation"
+	SummarySectionNextStep       SummarySection = "next_step"
+)
+
+// ConsultationSummary is the protocol a Berater writes after a consultation:
+// the topics covered, recommendations and next steps. Customers only see it
+// once it is shared with them.
+type ConsultationSummary struct {
+	ID        uuid.UUID  `json:"id" gorm:"type:char(36);primary_key"`
+	BookingID uuid.UUID  `json:"booking_id" gorm:"type:char(36);not null;uniqueIndex"`
+	LeadID    *uuid.UUID `json:"lead_id,omitempty" gorm:"type:char(36);index"`
+	UserID    uuid.UUID  `json:"user_id" gorm:"type:char(36);not null;index"` // customer
+	AuthorID  uuid.UUID  `json:"author_id" gorm:"type:char(36);not null;index"`
+
+	SharedAt *time.Time `json:"shared_at,omitempty" gorm:""`
+
+	CreatedAt time.Time `json:"created_at" gorm:"not null"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"not null"`
+
+	// Relationships
+	Items   []ConsultationSummaryItem `json:"items,omitempty" gorm:"foreignKey:SummaryID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	Booking *Booking                  `json:"booking,omitempty" gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	Author  *User                     `json:"author,omitempty" gorm:"foreignKey:AuthorID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+}
+
+// ConsultationSummaryItem is an entry of a summary section
+type ConsultationSummaryItem struct {
+	ID        uuid.UUID      `json:"id" gorm:"type:char(36);primary_key"`
+	SummaryID uuid.UUID      `json:"summary_id" gorm:"type:char(36);not null;index"`
+	Section   SummarySection `json:"section" gorm:"size:20;not null"`
+	Position  int            `json:"position" gorm:"not null"`
+	Text      string         `json:"text" gorm:"type:text;not null"`
+}
+
+// ConsultationSummaryResponse is a consultation summary as shown to Beraters and customers
+type ConsultationSummaryResponse struct {
+	ID              uuid.UUID  `json:"id"`
+	BookingID       uuid.UUID  `json:"booking_id"`
+	LeadID          *uuid.UUID `json:"lead_id,omitempty"`
+	Title           string     `json:"title"`
+	ConsultationAt  time.Time  `json:"consultation_at"`
+	AuthorName      string     `json:"author_name"`
+	Topics          []string   `json:"topics"`
+	Recommendations []string   `json:"recommendations"`
+	NextSteps       []string   `json:"next_steps"`
+	Shared          bool       `json:"shared"`
+	SharedAt        *time.Time `json:"shared_at,omitempty"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+func (s *ConsultationSummary) BeforeCreate(tx *gorm.DB) error {
+	if s.ID == uuid.Nil {
+		s.ID = uuid.New()
+	}
+	return nil
+}
+
+func (i *ConsultationSummaryItem) BeforeCreate(tx *gorm.DB) error {
+	if i.ID == uuid.Nil {
+		i.ID = uuid.New()
+	}
+	return nil
+}
+
+// IsShared checks if the customer can see the summary
+func (s *ConsultationSummary) IsShared() bool {
+	return s.SharedAt != nil
+}
+
+// Section returns the entries of a section in order
+func (s *ConsultationSummary) Section(section SummarySection) []string {
+	entries := []string{}
+	for _, item := range s.Items {
+		if item.Section == section {
+			entries = append(entries, item.Text)
+		}
+	}
+	return entries
+}
+
+// ToResponse converts the summary to a response; Booking and Author should be loaded
+func (s *ConsultationSummary) ToResponse() ConsultationSummaryResponse {
+	response := ConsultationSummaryResponse{
+		ID:              s.ID,
+		BookingID:       s.BookingID,
+		LeadID:          s.LeadID,
+		Topics:          s.Section(SummarySectionTopic),
+		Recommendations: s.Section(SummarySectionRecommendation),
+		NextSteps:       s.Section(SummarySectionNextStep),
+		Shared:          s.IsShared(),
+		SharedAt:        s.SharedAt,
+		UpdatedAt:       s.UpdatedAt,
+	}
+	if s.Booking != nil {
+		response.Title = s.Booking.Title
+		response.ConsultationAt = s.Booking.StartTime
+	}
+	if s.Author != nil {
+		response.AuthorName = s.Author.FullName()
+	}
+	return response
+}
@@ -0,0 +1,63 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ContentKind is the kind of a marketing content entry
+type ContentKind string
+
+const (
+	ContentKindFAQ   ContentKind = "faq"   // question in Title, answer in Body
+	ContentKindPage  ContentKind = "page"  // info page about Elterngeld
+	ContentKindGuide ContentKind = "guide" // guide for a Bundesland
+)
+
+// IsValid checks if the content kind is known
+func (k ContentKind) IsValid() bool {
+	switch k {
+	case ContentKindFAQ, ContentKindPage, ContentKindGuide:
+		return true
+	}
+	return false
+}
+
+type ContentStatus string
+
+const (
+	ContentStatusDraft     ContentStatus = "draft"
+	ContentStatusPublished ContentStatus = "published"
+)
+
+// ContentEntry is an FAQ entry, info page or Bundesland guide shown on the
+// website. Only published entries are public; their slug is unique per kind.
+type ContentEntry struct {
+	ID         uuid.UUID     `json:"id" gorm:"type:char(36);primary_key"`
+	Kind       ContentKind   `json:"kind" gorm:"size:20;not null;uniqueIndex:idx_content_kind_slug"`
+	Slug       string        `json:"slug" gorm:"size:150;not null;uniqueIndex:idx_content_kind_slug"`
+	Title      string        `json:"title" gorm:"size:200;not null"`
+	Summary    string        `json:"summary,omitempty" gorm:"type:text"`
+	Body       string        `json:"body" gorm:"type:text;not null"` // Markdown
+	Category   string        `json:"category,omitempty" gorm:"size:100;index"`
+	Bundesland Bundesland    `json:"bundesland,omitempty" gorm:"size:2;index"` // set for guides only
+	Position   int           `json:"position" gorm:"not null"`                 // order within the kind and category
+	Status     ContentStatus `json:"status" gorm:"size:20;not null;index"`
+
+	PublishedAt *time.Time `json:"published_at,omitempty"`
+	CreatedBy   uuid.UUID  `json:"created_by" gorm:"type:char(36);not null"`
+	UpdatedBy   uuid.UUID  `json:"updated_by" gorm:"type:char(36);not null"`
+
+	CreatedAt time.Time `json:"created_at" gorm:"not null"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"not null"`
+}
+
+// BeforeCreate is a GORM hook that runs before creating a content entry
+func (ce *ContentEntry) BeforeCreate(tx *gorm.DB) error {
+	if ce.ID == uuid.Nil {
+		ce.ID = uuid.New()
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Coupon is a code that lowers the price of a booking, either by a percentage
+// or by a fixed amount. Unlike a Voucher it is not turned into credit.
+type Coupon struct {
+	ID              uuid.UUID  `json:"id" gorm:"type:char(36);primary_key"`
+	Code            string     `json:"code" gorm:"size:32;not null;uniqueIndex"`
+	Description     string     `json:"description" gorm:"type:text"`
+	PercentOff      float64    `json:"percent_off" gorm:"not null;default:0"` // set either PercentOff or AmountOff
+	AmountOff       float64    `json:"amount_off" gorm:"not null;default:0"`
+	Currency        string     `json:"currency" gorm:"size:3;not null;default:'EUR'"`
+	PackageID       *uuid.UUID `json:"package_id,omitempty" gorm:"type:char(36);index"` // only valid for this package when set
+	MaxRedemptions  int        `json:"max_redemptions" gorm:"not null;default:0"`       // 0 = unlimited
+	RedemptionCount int        `json:"redemption_count" gorm:"not null;default:0"`
+	ExpiresAt       *time.Time `json:"expires_at,omitempty" gorm:""`
+	CreatedBy       uuid.UUID  `json:"created_by" gorm:"type:char(36);not null"`
+
+	CreatedAt time.Time      `json:"created_at" gorm:"not null"`
+	UpdatedAt time.Time      `json:"updated_at" gorm:"not null"`
+	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+func (c *Coupon) BeforeCreate(tx *gorm.DB) error {
+	if c.ID == uuid.Nil {
+		c.ID = uuid.New()
+	}
+	if c.Currency == "" {
+		c.Currency = "EUR"
+	}
+	return nil
+}
+
+// IsRedeemable checks if the coupon has not expired and has redemptions left
+func (c *Coupon) IsRedeemable(at time.Time) bool {
+	if c.ExpiresAt != nil && !at.Before(*c.ExpiresAt) {
+		return false
+	}
+	return c.MaxRedemptions == 0 || c.RedemptionCount < c.MaxRedemptions
+}
+
+// Discount returns how much the coupon takes off a price, never more than the price
+func (c *Coupon) Discount(price float64) float64 {
+	discount := c.AmountOff
+	if c.PercentOff > 0 {
+		discount = price * c.PercentOff / 100
+	}
+	if discount > price {
+		return price
+	}
+	return discount
+}
@@ -0,0 +1,82 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// CreditEntryType is the reason for a change of a customer's credit balance
+type CreditEntryType string
+
+const (
+	CreditEntryRefund   CreditEntryType = "refund"   // payment refunded as credit instead of to the card
+	CreditEntryGoodwill CreditEntryType = "goodwill" // credit granted by an admin
+	CreditEntryVoucher  CreditEntryType = "voucher"  // voucher code redeemed by the customer
+	CreditEntryCheckout CreditEntryType = "checkout" // credit applied to the price of a booking
+	CreditEntryRestore  CreditEntryType = "restore"  // applied credit returned because the booking was not paid
+)
+
+// CreditEntry is a line of a customer's credit ledger. Positive amounts add
+// credit, negative amounts use it; the balance is the sum of all entries.
+type CreditEntry struct {
+	ID          uuid.UUID       `json:"id" gorm:"type:char(36);primary_key"`
+	UserID      uuid.UUID       `json:"user_id" gorm:"type:char(36);not null;index"`
+	Type        CreditEntryType `json:"type" gorm:"size:20;not null"`
+	Amount      float64         `json:"amount" gorm:"not null"`
+	Currency    string          `json:"currency" gorm:"size:3;not null;default:'EUR'"`
+	Description string          `json:"description" gorm:"type:text"`
+
+	BookingID *uuid.UUID `json:"booking_id,omitempty" gorm:"type:char(36);index"`
+	PaymentID *uuid.UUID `json:"payment_id,omitempty" gorm:"type:char(36);index"`
+	VoucherID *uuid.UUID `json:"voucher_id,omitempty" gorm:"type:char(36);index"`
+	CreatedBy *uuid.UUID `json:"created_by,omitempty" gorm:"type:char(36)"` // admin or Berater, empty for customer actions
+
+	CreatedAt time.Time `json:"created_at" gorm:"not null;index"`
+}
+
+// Voucher is a code customers redeem for account credit
+type Voucher struct {
+	ID              uuid.UUID  `json:"id" gorm:"type:char(36);primary_key"`
+	Code            string     `json:"code" gorm:"size:32;not null;uniqueIndex"`
+	Amount          float64    `json:"amount" gorm:"not null"`
+	Currency        string     `json:"currency" gorm:"size:3;not null;default:'EUR'"`
+	Description     string     `json:"description" gorm:"type:text"`
+	MaxRedemptions  int        `json:"max_redemptions" gorm:"not null;default:0"` // 0 = unlimited
+	RedemptionCount int        `json:"redemption_count" gorm:"not null;default:0"`
+	ExpiresAt       *time.Time `json:"expires_at,omitempty" gorm:""`
+	CreatedBy       uuid.UUID  `json:"created_by" gorm:"type:char(36);not null"`
+
+	CreatedAt time.Time      `json:"created_at" gorm:"not null"`
+	UpdatedAt time.Time      `json:"updated_at" gorm:"not null"`
+	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+func (e *CreditEntry) BeforeCreate(tx *gorm.DB) error {
+	if e.ID == uuid.Nil {
+		e.ID = uuid.New()
+	}
+	if e.Currency == "" {
+		e.Currency = "EUR"
+	}
+	return nil
+}
+
+func (v *Voucher) BeforeCreate(tx *gorm.DB) error {
+	if v.ID == uuid.Nil {
+		v.ID = uuid.New()
+	}
+	if v.Currency == "" {
+		v.Currency = "EUR"
+	}
+	return nil
+}
+
+// IsRedeemable checks if the voucher has not expired and has redemptions left
+func (v *Voucher) IsRedeemable(at time.Time) bool {
+	if v.ExpiresAt != nil && !at.Before(*v.ExpiresAt) {
+		return false
+	}
+	return v.MaxRedemptions == 0 || v.RedemptionCount < v.MaxRedemptions
+}
@@ -17,9 +17,59 @@ const (
 	DocumentTypeIncomeProof      DocumentType = "einkommensnachweis"
 	DocumentTypeEmploymentCert   DocumentType = "arbeitsbescheinigung"
 	DocumentTypeApplication      DocumentType = "antrag"
+	DocumentTypeBescheid         DocumentType = "bescheid"
+	DocumentTypePayslip          DocumentType = "gehaltsabrechnung"
+	DocumentTypeTaxAssessment    DocumentType = "steuerbescheid"
+	DocumentTypeHealthInsurance  DocumentType = "krankenkassenbescheinigung"
 	DocumentTypeOther            DocumentType = "sonstiges"
 )
 
+// OCRStatus describes the state of text recognition and classification of a document
+type OCRStatus string
+
+const (
+	OCRStatusPending     OCRStatus = "pending"
+	OCRStatusCompleted   OCRStatus = "completed"
+	OCRStatusFailed      OCRStatus = "failed"
+	OCRStatusUnavailable OCRStatus = "unavailable"
+)
+
+// DocumentVisibility controls who sees a document listed on its lead
+type DocumentVisibility string
+
+const (
+	DocumentVisibilityCustomer DocumentVisibility = "kunde"   // only the customers of the lead, not shown to the Berater
+	DocumentVisibilityBerater  DocumentVisibility = "berater" // internal working document of the Berater
+	DocumentVisibilityShared   DocumentVisibility = "geteilt" // customers and Berater
+)
+
+// IsValid checks if the document visibility exists
+func (v DocumentVisibility) IsValid() bool {
+	return v == DocumentVisibilityCustomer || v == DocumentVisibilityBerater || v == DocumentVisibilityShared
+}
+
+func (v DocumentVisibility) GetDisplayName() string {
+	switch v {
+	case DocumentVisibilityCustomer:
+		return "Nur Kunde"
+	case DocumentVisibilityBerater:
+		return "Nur Berater"
+	case DocumentVisibilityShared:
+		return "Geteilt"
+	default:
+		return "Unbekannt"
+	}
+}
+
+// DefaultDocumentVisibility returns the visibility of a new upload. Customers
+// upload for their Berater, uploads of staff stay internal until they are shared.
+func DefaultDocumentVisibility(uploader UserRole) DocumentVisibility {
+	if uploader == RoleUser {
+		return DocumentVisibilityShared
+	}
+	return DocumentVisibilityBerater
+}
+
 // Document represents an uploaded file/document
 type Document struct {
 	ID     uuid.UUID `json:"id" gorm:"type:char(36);primary_key"`
@@ -39,6 +89,22 @@ type Document struct {
 	Description  string       `json:"description" gorm:"type:text"`
 	IsProcessed  bool         `json:"is_processed" gorm:"not null;default:false"`
 
+	// Sensitive documents are watermarked with the viewer's name on every download
+	IsSensitive   bool   `json:"is_sensitive" gorm:"not null"`
+	ThumbnailPath string `json:"-" gorm:""`
+
+	// Sharing between the customers and the Berater of the lead
+	Visibility DocumentVisibility `json:"visibility" gorm:"size:20;not null;default:'geteilt';index"`
+	SharedAt   *time.Time         `json:"shared_at"`
+	SharedByID *uuid.UUID         `json:"shared_by_id" gorm:"type:char(36)"`
+
+	// OCR and automatic classification
+	OCRStatus                OCRStatus    `json:"ocr_status" gorm:"size:20;index"`
+	OCRText                  string       `json:"-" gorm:"type:text"`
+	ClassifiedType           DocumentType `json:"classified_type" gorm:"size:50"`
+	ClassificationConfidence float64      `json:"classification_confidence"`
+	TodoID                   *uuid.UUID   `json:"todo_id" gorm:"type:char(36);index"`
+
 	// S3 information (if using S3)
 	S3Bucket string `json:"s3_bucket" gorm:""`
 	S3Key    string `json:"s3_key" gorm:""`
@@ -67,22 +133,35 @@ type DocumentResponse struct {
 	DocumentType  DocumentType `json:"document_type"`
 	Description   string       `json:"description"`
 	IsProcessed   bool         `json:"is_processed"`
+	IsSensitive   bool         `json:"is_sensitive"`
+	Watermarked   bool         `json:"watermarked"`
 	DownloadURL   string       `json:"download_url"`
-	CreatedAt     time.Time    `json:"created_at"`
-	UpdatedAt     time.Time    `json:"updated_at"`
+
+	Visibility DocumentVisibility `json:"visibility"`
+	SharedAt   *time.Time         `json:"shared_at,omitempty"`
+
+	OCRStatus                OCRStatus    `json:"ocr_status"`
+	ClassifiedType           DocumentType `json:"classified_type,omitempty"`
+	ClassificationConfidence float64      `json:"classification_confidence"`
+	TodoID                   *uuid.UUID   `json:"todo_id,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
 }
 
 // UploadDocumentRequest represents the request for uploading a document
 type UploadDocumentRequest struct {
-	DocumentType DocumentType `form:"document_type" validate:"required,oneof=geburtsurkunde einkommensnachweis arbeitsbescheinigung antrag sonstiges"`
+	DocumentType DocumentType `form:"document_type" validate:"required,oneof=geburtsurkunde einkommensnachweis arbeitsbescheinigung antrag bescheid gehaltsabrechnung steuerbescheid krankenkassenbescheinigung sonstiges"`
 	Description  string       `form:"description"`
+	IsSensitive  bool         `form:"is_sensitive"`
 }
 
 // UpdateDocumentRequest represents the request for updating document metadata
 type UpdateDocumentRequest struct {
-	DocumentType *DocumentType `json:"document_type" validate:"omitempty,oneof=geburtsurkunde einkommensnachweis arbeitsbescheinigung antrag sonstiges"`
+	DocumentType *DocumentType `json:"document_type" validate:"omitempty,oneof=geburtsurkunde einkommensnachweis arbeitsbescheinigung antrag bescheid gehaltsabrechnung steuerbescheid krankenkassenbescheinigung sonstiges"`
 	Description  *string       `json:"description"`
 	IsProcessed  *bool         `json:"is_processed"`
+	IsSensitive  *bool         `json:"is_sensitive"`
 }
 
 // BeforeCreate is a GORM hook that runs before creating a document
@@ -120,9 +199,32 @@ func (d *Document) ToResponse(baseURL string) DocumentResponse {
 		DocumentType:  d.DocumentType,
 		Description:   d.Description,
 		IsProcessed:   d.IsProcessed,
+		IsSensitive:   d.IsSensitive,
+		Watermarked:   d.RequiresWatermark(),
 		DownloadURL:   downloadURL,
-		CreatedAt:     d.CreatedAt,
-		UpdatedAt:     d.UpdatedAt,
+
+		Visibility: d.Visibility,
+		SharedAt:   d.SharedAt,
+
+		OCRStatus:                d.OCRStatus,
+		ClassifiedType:           d.ClassifiedType,
+		ClassificationConfidence: d.ClassificationConfidence,
+		TodoID:                   d.TodoID,
+		CreatedAt:                d.CreatedAt,
+		UpdatedAt:                d.UpdatedAt,
+	}
+}
+
+// VisibleTo checks if the visibility of the document lets users of the role see
+// it. Admins see every document.
+func (d *Document) VisibleTo(role UserRole) bool {
+	switch role {
+	case RoleAdmin:
+		return true
+	case RoleUser:
+		return d.Visibility != DocumentVisibilityBerater
+	default:
+		return d.Visibility != DocumentVisibilityCustomer
 	}
 }
 
@@ -142,6 +244,12 @@ func (d *Document) IsPDF() bool {
 	return d.ContentType == "application/pdf"
 }
 
+// RequiresWatermark checks if downloads of the document are stamped with the viewer's
+// name. Bescheide are always treated as sensitive.
+func (d *Document) RequiresWatermark() bool {
+	return d.IsSensitive || d.DocumentType == DocumentTypeBescheid || d.DocumentType == DocumentTypeTaxAssessment
+}
+
 // IsValid checks if the document has a valid file type
 func (d *Document) IsValid() bool {
 	validTypes := []string{
@@ -188,6 +296,14 @@ func (dt DocumentType) DisplayName() string {
 		return "Arbeitsbescheinigung"
 	case DocumentTypeApplication:
 		return "Antrag"
+	case DocumentTypeBescheid:
+		return "Bescheid"
+	case DocumentTypePayslip:
+		return "Gehaltsabrechnung"
+	case DocumentTypeTaxAssessment:
+		return "Steuerbescheid"
+	case DocumentTypeHealthInsurance:
+		return "Krankenkassenbescheinigung"
 	case DocumentTypeOther:
 		return "Sonstiges"
 	default:
@@ -0,0 +1,99 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ScenarioMonthType is the kind of Elterngeld a parent takes in a Lebensmonat
+type ScenarioMonthType string
+
+const (
+	ScenarioMonthBasis        ScenarioMonthType = "basis"
+	ScenarioMonthPlus         ScenarioMonthType = "plus"
+	ScenarioMonthPartnerBonus ScenarioMonthType = "partnerbonus" // Partnerschaftsbonus, paid as ElterngeldPlus
+)
+
+// IsValid checks if the month type exists
+func (t ScenarioMonthType) IsValid() bool {
+	switch t {
+	case ScenarioMonthBasis, ScenarioMonthPlus, ScenarioMonthPartnerBonus:
+		return true
+	}
+	return false
+}
+
+// ScenarioPlan is how the parents split the Lebensmonate of the child
+type ScenarioPlan struct {
+	SingleParent bool             `json:"single_parent"`
+	Parents      []ScenarioParent `json:"parents"`
+}
+
+// ScenarioParent is a parent's income and the Lebensmonate they take Elterngeld in
+type ScenarioParent struct {
+	Name         string          `json:"name"`          // e.g. Mutter
+	IncomeBefore float64         `json:"income_before"` // monthly Elterngeld-Netto before the birth
+	Months       []ScenarioMonth `json:"months"`
+}
+
+// ScenarioMonth is a Lebensmonat in which a parent takes Elterngeld, with their
+// income from part-time work in that month
+type ScenarioMonth struct {
+	Lebensmonat int               `json:"lebensmonat"`
+	Type        ScenarioMonthType `json:"type"`
+	IncomeAfter float64           `json:"income_after,omitempty"`
+	WeeklyHours float64           `json:"weekly_hours,omitempty"`
+}
+
+// ElterngeldScenario is a named plan for splitting the Elterngeld of a lead,
+// e.g. "Mutter 12 Monate Basis" or "7+7 Plus mit Partnerbonus". The chosen
+// scenario of a lead is the one its application is prepared with.
+type ElterngeldScenario struct {
+	ID          uuid.UUID       `json:"id" gorm:"type:char(36);primary_key"`
+	LeadID      uuid.UUID       `json:"lead_id" gorm:"type:char(36);not null;index"`
+	CreatedByID uuid.UUID       `json:"created_by_id" gorm:"type:char(36);not null"`
+	Name        string          `json:"name" gorm:"size:100;not null"`
+	Plan        json.RawMessage `json:"plan" gorm:"type:jsonb"`
+	ChosenAt    *time.Time      `json:"chosen_at" gorm:""`
+
+	CreatedAt time.Time `json:"created_at" gorm:"not null"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"not null"`
+
+	// Relationships
+	Lead Lead `json:"-" gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+}
+
+func (s *ElterngeldScenario) BeforeCreate(tx *gorm.DB) error {
+	if s.ID == uuid.Nil {
+		s.ID = uuid.New()
+	}
+	return nil
+}
+
+// IsChosen reports whether the scenario was chosen for the application
+func (s *ElterngeldScenario) IsChosen() bool {
+	return s.ChosenAt != nil
+}
+
+// GetPlan decodes the plan
+func (s *ElterngeldScenario) GetPlan() (ScenarioPlan, error) {
+	var plan ScenarioPlan
+	if len(s.Plan) == 0 {
+		return plan, nil
+	}
+	err := json.Unmarshal(s.Plan, &plan)
+	return plan, err
+}
+
+// SetPlan encodes the plan
+func (s *ElterngeldScenario) SetPlan(plan ScenarioPlan) error {
+	data, err := json.Marshal(plan)
+	if err != nil {
+		return err
+	}
+	s.Plan = data
+	return nil
+}
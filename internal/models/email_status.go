@@ -0,0 +1,25 @@
+package models
+
+// EmailStatus is the result of checking whether an email address can receive mail
+type EmailStatus string
+
+const (
+	EmailStatusUnchecked     EmailStatus = ""
+	EmailStatusValid         EmailStatus = "valid"
+	EmailStatusDisposable    EmailStatus = "disposable"    // the domain belongs to a disposable email provider
+	EmailStatusUndeliverable EmailStatus = "undeliverable" // the domain does not exist or accepts no mail
+)
+
+// IsValid checks if the status exists; unchecked is not a status to filter by
+func (s EmailStatus) IsValid() bool {
+	switch s {
+	case EmailStatusValid, EmailStatusDisposable, EmailStatusUndeliverable:
+		return true
+	}
+	return false
+}
+
+// IsInvalid reports whether mail to the address is unlikely to reach anyone
+func (s EmailStatus) IsInvalid() bool {
+	return s == EmailStatusDisposable || s == EmailStatusUndeliverable
+}
@@ -0,0 +1,60 @@
+package models
+
+// EmploymentType is how a parent earned their income before the birth. It
+// decides the Bemessungszeitraum of the Elterngeld calculation (§ 2b BEEG).
+type EmploymentType string
+
+const (
+	EmploymentTypeEmployed      EmploymentType = "angestellt"
+	EmploymentTypeSelfEmployed  EmploymentType = "selbststaendig"
+	EmploymentTypeCivilServant  EmploymentType = "beamtet"
+	EmploymentTypeMixed         EmploymentType = "mischeinkuenfte"
+	EmploymentTypeMiniJob       EmploymentType = "minijob"
+	EmploymentTypeWithoutIncome EmploymentType = "ohne_einkommen"
+)
+
+// EmploymentTypes lists all known employment types
+var EmploymentTypes = []EmploymentType{
+	EmploymentTypeEmployed,
+	EmploymentTypeSelfEmployed,
+	EmploymentTypeCivilServant,
+	EmploymentTypeMixed,
+	EmploymentTypeMiniJob,
+	EmploymentTypeWithoutIncome,
+}
+
+// IsValid checks if the employment type is known
+func (e EmploymentType) IsValid() bool {
+	for _, employmentType := range EmploymentTypes {
+		if e == employmentType {
+			return true
+		}
+	}
+	return false
+}
+
+// AssessedByTaxYear checks if the income is assessed over the last tax year
+// before the birth instead of the twelve calendar months before it, which
+// applies as soon as a parent has profit income
+func (e EmploymentType) AssessedByTaxYear() bool {
+	return e == EmploymentTypeSelfEmployed || e == EmploymentTypeMixed
+}
+
+func (e EmploymentType) GetDisplayName() string {
+	switch e {
+	case EmploymentTypeEmployed:
+		return "Angestellt"
+	case EmploymentTypeSelfEmployed:
+		return "Selbstständig"
+	case EmploymentTypeCivilServant:
+		return "Beamtet"
+	case EmploymentTypeMixed:
+		return "Angestellt und selbstständig"
+	case EmploymentTypeMiniJob:
+		return "Minijob"
+	case EmploymentTypeWithoutIncome:
+		return "Ohne Einkommen"
+	default:
+		return "Unbekannt"
+	}
+}
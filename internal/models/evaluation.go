@@ -0,0 +1,95 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Ratings are given on a scale from MinRating to MaxRating
+const (
+	MinRating = 1
+	MaxRating = 5
+)
+
+// EvaluationCriterion is a criterion reviewers rate job applications on, such
+// as professional experience or communication. Inactive criteria are kept for
+// existing ratings but cannot be rated anymore.
+type EvaluationCriterion struct {
+	ID          uuid.UUID `json:"id" gorm:"type:char(36);primary_key"`
+	Name        string    `json:"name" gorm:"size:100;not null;uniqueIndex"`
+	Description string    `json:"description" gorm:"type:text"`
+	Weight      int       `json:"weight" gorm:"not null"` // share of the criterion in the weighted score
+	Position    int       `json:"position" gorm:"not null"`
+	IsActive    bool      `json:"is_active" gorm:"not null;index"`
+
+	CreatedAt time.Time `json:"created_at" gorm:"not null"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"not null"`
+}
+
+// BeforeCreate is a GORM hook that runs before creating an evaluation criterion
+func (ec *EvaluationCriterion) BeforeCreate(tx *gorm.DB) error {
+	if ec.ID == uuid.Nil {
+		ec.ID = uuid.New()
+	}
+	return nil
+}
+
+// Recommendation is the overall verdict of a reviewer
+type Recommendation string
+
+const (
+	RecommendationStrongYes Recommendation = "strong_yes"
+	RecommendationYes       Recommendation = "yes"
+	RecommendationNo        Recommendation = "no"
+	RecommendationStrongNo  Recommendation = "strong_no"
+)
+
+// ApplicationEvaluation is the structured feedback of one reviewer on an
+// application in one stage of the recruiting process, e.g. after the interview
+type ApplicationEvaluation struct {
+	ID            uuid.UUID         `json:"id" gorm:"type:char(36);primary_key"`
+	ApplicationID uuid.UUID         `json:"application_id" gorm:"type:char(36);not null;uniqueIndex:idx_application_evaluations_reviewer"`
+	ReviewerID    uuid.UUID         `json:"reviewer_id" gorm:"type:char(36);not null;uniqueIndex:idx_application_evaluations_reviewer"`
+	Stage         ApplicationStatus `json:"stage" gorm:"size:20;not null;uniqueIndex:idx_application_evaluations_reviewer"`
+
+	Recommendation Recommendation `json:"recommendation,omitempty" gorm:"size:20"`
+	Notes          string         `json:"notes" gorm:"type:text"`
+	Score          *float64       `json:"score"` // weighted average of the ratings, nil without ratings
+
+	CreatedAt time.Time `json:"created_at" gorm:"not null"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"not null"`
+
+	// Relationships
+	Reviewer *User               `json:"reviewer,omitempty" gorm:"foreignKey:ReviewerID"`
+	Ratings  []ApplicationRating `json:"ratings,omitempty" gorm:"foreignKey:EvaluationID;constraint:OnDelete:CASCADE"`
+}
+
+// BeforeCreate is a GORM hook that runs before creating an application evaluation
+func (ae *ApplicationEvaluation) BeforeCreate(tx *gorm.DB) error {
+	if ae.ID == uuid.Nil {
+		ae.ID = uuid.New()
+	}
+	return nil
+}
+
+// ApplicationRating is the rating of one criterion in an evaluation
+type ApplicationRating struct {
+	ID           uuid.UUID `json:"id" gorm:"type:char(36);primary_key"`
+	EvaluationID uuid.UUID `json:"evaluation_id" gorm:"type:char(36);not null;index"`
+	CriterionID  uuid.UUID `json:"criterion_id" gorm:"type:char(36);not null;index"`
+	Rating       int       `json:"rating" gorm:"not null"`
+	Comment      string    `json:"comment,omitempty" gorm:"type:text"`
+
+	// Relationships
+	Criterion *EvaluationCriterion `json:"criterion,omitempty" gorm:"foreignKey:CriterionID"`
+}
+
+// BeforeCreate is a GORM hook that runs before creating an application rating
+func (ar *ApplicationRating) BeforeCreate(tx *gorm.DB) error {
+	if ar.ID == uuid.Nil {
+		ar.ID = uuid.New()
+	}
+	return nil
+}
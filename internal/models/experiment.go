@@ -0,0 +1,140 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type ExperimentStatus string
+
+const (
+	ExperimentStatusDraft   ExperimentStatus = "draft"
+	ExperimentStatusRunning ExperimentStatus = "running"
+	ExperimentStatusStopped ExperimentStatus = "stopped"
+)
+
+// ExperimentConversionKind is the goal a conversion is attributed for
+type ExperimentConversionKind string
+
+const (
+	ExperimentConversionBooking ExperimentConversionKind = "booking"
+	ExperimentConversionPayment ExperimentConversionKind = "payment"
+)
+
+// Experiment is an A/B test of the package and pricing display. Visitors are
+// assigned to a variant by a stable hash, so they see the same variant on
+// every visit.
+type Experiment struct {
+	ID          uuid.UUID        `json:"id" gorm:"type:char(36);primary_key"`
+	Key         string           `json:"key" gorm:"size:100;uniqueIndex;not null"` // used by the frontend
+	Name        string           `json:"name" gorm:"size:200;not null"`
+	Description string           `json:"description,omitempty" gorm:"type:text"`
+	Status      ExperimentStatus `json:"status" gorm:"size:20;not null;index"`
+
+	StartedAt *time.Time `json:"started_at,omitempty" gorm:""`
+	StoppedAt *time.Time `json:"stopped_at,omitempty" gorm:""`
+
+	CreatedAt time.Time `json:"created_at" gorm:"not null"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"not null"`
+
+	// Relationships
+	Variants []ExperimentVariant `json:"variants,omitempty" gorm:"foreignKey:ExperimentID"`
+}
+
+// ExperimentVariant is a variant of an experiment. The share of visitors is
+// its weight relative to the weights of all variants.
+type ExperimentVariant struct {
+	ID           uuid.UUID `json:"id" gorm:"type:char(36);primary_key"`
+	ExperimentID uuid.UUID `json:"experiment_id" gorm:"type:char(36);not null;uniqueIndex:idx_experiment_variant_key"`
+	Key          string    `json:"key" gorm:"size:50;not null;uniqueIndex:idx_experiment_variant_key"`
+	Name         string    `json:"name" gorm:"size:100;not null"`
+	Weight       int       `json:"weight" gorm:"not null"`
+	Position     int       `json:"position" gorm:"not null"`
+
+	// Display settings for the frontend, e.g. which packages to highlight
+	Config json.RawMessage `json:"config,omitempty" gorm:"type:jsonb"`
+
+	CreatedAt time.Time `json:"created_at" gorm:"not null"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"not null"`
+
+	// Relationships
+	Experiment Experiment `json:"-" gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+}
+
+// ExperimentExposure records that a visitor was shown a variant. The subject
+// is the anonymous visitor ID or, without one, the user ID. The user is linked
+// once the visitor signs in, so bookings and payments can be attributed.
+type ExperimentExposure struct {
+	ID           uuid.UUID  `json:"id" gorm:"type:char(36);primary_key"`
+	ExperimentID uuid.UUID  `json:"experiment_id" gorm:"type:char(36);not null;uniqueIndex:idx_experiment_exposure_subject"`
+	VariantID    uuid.UUID  `json:"variant_id" gorm:"type:char(36);not null;index"`
+	SubjectID    uuid.UUID  `json:"subject_id" gorm:"type:char(36);not null;uniqueIndex:idx_experiment_exposure_subject"`
+	UserID       *uuid.UUID `json:"user_id,omitempty" gorm:"type:char(36);index"`
+
+	ExposureCount  int       `json:"exposure_count" gorm:"not null"`
+	FirstExposedAt time.Time `json:"first_exposed_at" gorm:"not null"`
+	LastExposedAt  time.Time `json:"last_exposed_at" gorm:"not null"`
+
+	CreatedAt time.Time `json:"created_at" gorm:"not null"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"not null"`
+
+	// Relationships
+	Experiment Experiment        `json:"-" gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	Variant    ExperimentVariant `json:"-" gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+}
+
+// ExperimentConversion attributes a booking or payment of an exposed user to
+// the variant they saw
+type ExperimentConversion struct {
+	ID           uuid.UUID                `json:"id" gorm:"type:char(36);primary_key"`
+	ExperimentID uuid.UUID                `json:"experiment_id" gorm:"type:char(36);not null;uniqueIndex:idx_experiment_conversion_reference"`
+	VariantID    uuid.UUID                `json:"variant_id" gorm:"type:char(36);not null;index"`
+	ExposureID   uuid.UUID                `json:"exposure_id" gorm:"type:char(36);not null;index"`
+	UserID       uuid.UUID                `json:"user_id" gorm:"type:char(36);not null;index"`
+	Kind         ExperimentConversionKind `json:"kind" gorm:"size:20;not null;uniqueIndex:idx_experiment_conversion_reference"`
+	ReferenceID  uuid.UUID                `json:"reference_id" gorm:"type:char(36);not null;uniqueIndex:idx_experiment_conversion_reference"` // booking or payment
+	Amount       float64                  `json:"amount" gorm:"type:decimal(10,2);not null"`
+	Currency     string                   `json:"currency" gorm:"size:3;not null"`
+	ConvertedAt  time.Time                `json:"converted_at" gorm:"not null"`
+
+	CreatedAt time.Time `json:"created_at" gorm:"not null"`
+
+	// Relationships
+	Experiment Experiment `json:"-" gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+}
+
+func (e *Experiment) BeforeCreate(tx *gorm.DB) error {
+	if e.ID == uuid.Nil {
+		e.ID = uuid.New()
+	}
+	return nil
+}
+
+func (v *ExperimentVariant) BeforeCreate(tx *gorm.DB) error {
+	if v.ID == uuid.Nil {
+		v.ID = uuid.New()
+	}
+	return nil
+}
+
+func (e *ExperimentExposure) BeforeCreate(tx *gorm.DB) error {
+	if e.ID == uuid.Nil {
+		e.ID = uuid.New()
+	}
+	return nil
+}
+
+func (c *ExperimentConversion) BeforeCreate(tx *gorm.DB) error {
+	if c.ID == uuid.Nil {
+		c.ID = uuid.New()
+	}
+	return nil
+}
+
+// IsRunning checks if visitors are assigned to variants
+func (e *Experiment) IsRunning() bool {
+	return e.Status == ExperimentStatusRunning
+}
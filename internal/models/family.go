@@ -0,0 +1,122 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// FamilyRole is the part a member plays in a family's case
+type FamilyRole string
+
+const (
+	FamilyRoleApplicant FamilyRole = "antragsteller" // parent who opened the case, contact of the Berater
+	FamilyRoleParent    FamilyRole = "elternteil"    // other parent
+)
+
+// IsValid checks if the family role exists
+func (r FamilyRole) IsValid() bool {
+	return r == FamilyRoleApplicant || r == FamilyRoleParent
+}
+
+func (r FamilyRole) GetDisplayName() string {
+	switch r {
+	case FamilyRoleApplicant:
+		return "Antragsteller"
+	case FamilyRoleParent:
+		return "Elternteil"
+	default:
+		return "Unbekannt"
+	}
+}
+
+// Family groups the parents and children of a case with its leads, bookings
+// and the application. Parents with an account see the leads and bookings of
+// their families, not only those they created themselves.
+type Family struct {
+	ID        uuid.UUID `json:"id" gorm:"type:char(36);primary_key"`
+	Name      string    `json:"name" gorm:"size:200;not null"` // e.g. "Familie Müller"
+	CreatedBy uuid.UUID `json:"created_by" gorm:"type:char(36);not null;index"`
+
+	CreatedAt time.Time      `json:"created_at" gorm:"not null"`
+	UpdatedAt time.Time      `json:"updated_at" gorm:"not null"`
+	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+
+	// Relationships
+	Members  []FamilyMember `json:"members,omitempty" gorm:"foreignKey:FamilyID"`
+	Children []FamilyChild  `json:"children,omitempty" gorm:"foreignKey:FamilyID"`
+	Leads    []Lead         `json:"leads,omitempty" gorm:"foreignKey:FamilyID"`
+	Bookings []Booking      `json:"bookings,omitempty" gorm:"foreignKey:FamilyID"`
+}
+
+// FamilyMember is a parent of a family. Parents without an account in the
+// portal are recorded with their name and email only.
+type FamilyMember struct {
+	ID        uuid.UUID  `json:"id" gorm:"type:char(36);primary_key"`
+	FamilyID  uuid.UUID  `json:"family_id" gorm:"type:char(36);not null;uniqueIndex:idx_family_members_user"`
+	UserID    *uuid.UUID `json:"user_id" gorm:"type:char(36);uniqueIndex:idx_family_members_user;index"`
+	Role      FamilyRole `json:"role" gorm:"size:20;not null"`
+	FirstName string     `json:"first_name" gorm:"size:100;not null"`
+	LastName  string     `json:"last_name" gorm:"size:100;not null"`
+	Email     string     `json:"email" gorm:"size:255"`
+
+	CreatedAt time.Time `json:"created_at" gorm:"not null"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"not null"`
+
+	// Relationships
+	Family *Family `json:"family,omitempty" gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	User   *User   `json:"user,omitempty" gorm:"foreignKey:UserID"`
+}
+
+// FamilyChild is a child of a family, born or expected
+type FamilyChild struct {
+	ID                uuid.UUID  `json:"id" gorm:"type:char(36);primary_key"`
+	FamilyID          uuid.UUID  `json:"family_id" gorm:"type:char(36);not null;index"`
+	FirstName         string     `json:"first_name" gorm:"size:100"`
+	BirthDate         *time.Time `json:"birth_date"`
+	ExpectedBirthDate *time.Time `json:"expected_birth_date"`            // due date before the birth
+	MultipleBirth     bool       `json:"multiple_birth" gorm:"not null"` // twins or more, raises the Elterngeld by the Mehrlingszuschlag
+
+	CreatedAt time.Time `json:"created_at" gorm:"not null"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"not null"`
+
+	// Relationships
+	Family *Family `json:"family,omitempty" gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+}
+
+// BeforeCreate is a GORM hook that runs before creating a family
+func (f *Family) BeforeCreate(tx *gorm.DB) error {
+	if f.ID == uuid.Nil {
+		f.ID = uuid.New()
+	}
+	return nil
+}
+
+// BeforeCreate is a GORM hook that runs before creating a family member
+func (m *FamilyMember) BeforeCreate(tx *gorm.DB) error {
+	if m.ID == uuid.Nil {
+		m.ID = uuid.New()
+	}
+	m.Email = strings.ToLower(strings.TrimSpace(m.Email))
+	return nil
+}
+
+// BeforeCreate is a GORM hook that runs before creating a family child
+func (c *FamilyChild) BeforeCreate(tx *gorm.DB) error {
+	if c.ID == uuid.Nil {
+		c.ID = uuid.New()
+	}
+	return nil
+}
+
+// FullName returns the member's full name
+func (m *FamilyMember) FullName() string {
+	return strings.TrimSpace(m.FirstName + " " + m.LastName)
+}
+
+// HasAccount checks if the parent can sign in to the portal
+func (m *FamilyMember) HasAccount() bool {
+	return m.UserID != nil
+}
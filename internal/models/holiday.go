@@ -0,0 +1,114 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Bundesland is a German federal state identified by its ISO 3166-2:DE subdivision code
+type Bundesland string
+
+const (
+	BundeslandBadenWuerttemberg     Bundesland = "BW"
+	BundeslandBayern                Bundesland = "BY"
+	BundeslandBerlin                Bundesland = "BE"
+	BundeslandBrandenburg           Bundesland = "BB"
+	BundeslandBremen                Bundesland = "HB"
+	BundeslandHamburg               Bundesland = "HH"
+	BundeslandHessen                Bundesland = "HE"
+	BundeslandMecklenburgVorpommern Bundesland = "MV"
+	BundeslandNiedersachsen         Bundesland = "NI"
+	BundeslandNordrheinWestfalen    Bundesland = "NW"
+	BundeslandRheinlandPfalz        Bundesland = "RP"
+	BundeslandSaarland              Bundesland = "SL"
+	BundeslandSachsen               Bundesland = "SN"
+	BundeslandSachsenAnhalt         Bundesland = "ST"
+	BundeslandSchleswigHolstein     Bundesland = "SH"
+	BundeslandThueringen            Bundesland = "TH"
+)
+
+// Bundeslaender lists all German federal states
+var Bundeslaender = []Bundesland{
+	BundeslandBadenWuerttemberg, BundeslandBayern, BundeslandBerlin, BundeslandBrandenburg,
+	BundeslandBremen, BundeslandHamburg, BundeslandHessen, BundeslandMecklenburgVorpommern,
+	BundeslandNiedersachsen, BundeslandNordrheinWestfalen, BundeslandRheinlandPfalz, BundeslandSaarland,
+	BundeslandSachsen, BundeslandSachsenAnhalt, BundeslandSchleswigHolstein, BundeslandThueringen,
+}
+
+// HolidayOverride adds or removes a public holiday on a calendar date. Overrides
+// without a Bundesland apply to all federal states.
+type HolidayOverride struct {
+	ID         uuid.UUID  `json:"id" gorm:"type:char(36);primary_key"`
+	Date       string     `json:"date" gorm:"size:10;not null;index" validate:"required,datetime=2006-01-02"`
+	Bundesland Bundesland `json:"bundesland" gorm:"size:2;index"`
+	Name       string     `json:"name" gorm:""`
+	IsHoliday  bool       `json:"is_holiday" gorm:"not null"` // false turns a statutory holiday into a working day
+	Note       string     `json:"note" gorm:"type:text"`
+	CreatedBy  uuid.UUID  `json:"created_by" gorm:"type:char(36);not null;index"`
+
+	CreatedAt time.Time      `json:"created_at" gorm:"not null"`
+	UpdatedAt time.Time      `json:"updated_at" gorm:"not null"`
+	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+
+	// Relationships
+	Creator User `json:"creator,omitempty" gorm:"foreignKey:CreatedBy;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+}
+
+// BeforeCreate is a GORM hook that runs before creating a holiday override
+func (ho *HolidayOverride) BeforeCreate(tx *gorm.DB) error {
+	if ho.ID == uuid.Nil {
+		ho.ID = uuid.New()
+	}
+	return nil
+}
+
+// IsValid checks if the Bundesland is a known federal state
+func (b Bundesland) IsValid() bool {
+	for _, state := range Bundeslaender {
+		if b == state {
+			return true
+		}
+	}
+	return false
+}
+
+func (b Bundesland) GetDisplayName() string {
+	switch b {
+	case BundeslandBadenWuerttemberg:
+		return "Baden-Württemberg"
+	case BundeslandBayern:
+		return "Bayern"
+	case BundeslandBerlin:
+		return "Berlin"
+	case BundeslandBrandenburg:
+		return "Brandenburg"
+	case BundeslandBremen:
+		return "Bremen"
+	case BundeslandHamburg:
+		return "Hamburg"
+	case BundeslandHessen:
+		return "Hessen"
+	case BundeslandMecklenburgVorpommern:
+		return "Mecklenburg-Vorpommern"
+	case BundeslandNiedersachsen:
+		return "Niedersachsen"
+	case BundeslandNordrheinWestfalen:
+		return "Nordrhein-Westfalen"
+	case BundeslandRheinlandPfalz:
+		return "Rheinland-Pfalz"
+	case BundeslandSaarland:
+		return "Saarland"
+	case BundeslandSachsen:
+		return "Sachsen"
+	case BundeslandSachsenAnhalt:
+		return "Sachsen-Anhalt"
+	case BundeslandSchleswigHolstein:
+		return "Schleswig-Holstein"
+	case BundeslandThueringen:
+		return "Thüringen"
+	default:
+		return "Unbekannt"
+	}
+}
@@ -0,0 +1,82 @@
+package models
+
+import "elterngeld-portal/pkg/i18n"
+
+// Localized display names. The German names are defined by GetDisplayName,
+// other languages are looked up in the i18n catalogs.
+
+func (ls LeadStatus) GetLocalizedName(lang i18n.Language) string {
+	return i18n.DisplayName(lang, "lead_status."+string(ls), ls.GetDisplayName())
+}
+
+func (p Priority) GetLocalizedName(lang i18n.Language) string {
+	return i18n.DisplayName(lang, "priority."+string(p), p.GetDisplayName())
+}
+
+func (at ActivityType) GetLocalizedName(lang i18n.Language) string {
+	return i18n.DisplayName(lang, "activity_type."+string(at), at.GetDisplayName())
+}
+
+func (bs BookingStatus) GetLocalizedName(lang i18n.Language) string {
+	return i18n.DisplayName(lang, "booking_status."+string(bs), bs.GetDisplayName())
+}
+
+func (bt BookingType) GetLocalizedName(lang i18n.Language) string {
+	return i18n.DisplayName(lang, "booking_type."+string(bt), bt.GetDisplayName())
+}
+
+func (pt PackageType) GetLocalizedName(lang i18n.Language) string {
+	return i18n.DisplayName(lang, "package_type."+string(pt), pt.GetDisplayName())
+}
+
+func (ps PaymentStatus) GetLocalizedName(lang i18n.Language) string {
+	return i18n.DisplayName(lang, "payment_status."+string(ps), ps.GetDisplayName())
+}
+
+func (pm PaymentMethod) GetLocalizedName(lang i18n.Language) string {
+	return i18n.DisplayName(lang, "payment_method."+string(pm), pm.GetDisplayName())
+}
+
+func (s InboundEmailStatus) GetLocalizedName(lang i18n.Language) string {
+	return i18n.DisplayName(lang, "inbound_email_status."+string(s), s.GetDisplayName())
+}
+
+func (p ShortLinkPurpose) GetLocalizedName(lang i18n.Language) string {
+	return i18n.DisplayName(lang, "short_link_purpose."+string(p), p.GetDisplayName())
+}
+
+func (js JobStatus) GetLocalizedName(lang i18n.Language) string {
+	return i18n.DisplayName(lang, "job_status."+string(js), js.GetDisplayName())
+}
+
+func (jt JobType) GetLocalizedName(lang i18n.Language) string {
+	return i18n.DisplayName(lang, "job_type."+string(jt), jt.GetDisplayName())
+}
+
+func (jl JobLevel) GetLocalizedName(lang i18n.Language) string {
+	return i18n.DisplayName(lang, "job_level."+string(jl), jl.GetDisplayName())
+}
+
+func (wl WorkLocation) GetLocalizedName(lang i18n.Language) string {
+	return i18n.DisplayName(lang, "work_location."+string(wl), wl.GetDisplayName())
+}
+
+func (as ApplicationStatus) GetLocalizedName(lang i18n.Language) string {
+	return i18n.DisplayName(lang, "application_status."+string(as), as.GetDisplayName())
+}
+
+func (e EmploymentType) GetLocalizedName(lang i18n.Language) string {
+	return i18n.DisplayName(lang, "employment_type."+string(e), e.GetDisplayName())
+}
+
+func (r FamilyRole) GetLocalizedName(lang i18n.Language) string {
+	return i18n.DisplayName(lang, "family_role."+string(r), r.GetDisplayName())
+}
+
+func (v DocumentVisibility) GetLocalizedName(lang i18n.Language) string {
+	return i18n.DisplayName(lang, "document_visibility."+string(v), v.GetDisplayName())
+}
+
+func (t QuestionType) GetLocalizedName(lang i18n.Language) string {
+	return i18n.DisplayName(lang, "question_type."+string(t), t.GetDisplayName())
+}
@@ -0,0 +1,226 @@
+package models
+
+import (
+	"net/mail"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type InboundEmailStatus string
+
+const (
+	InboundEmailStatusMatched   InboundEmailStatus = "matched"
+	InboundEmailStatusUnmatched InboundEmailStatus = "unmatched"
+	InboundEmailStatusFailed    InboundEmailStatus = "failed"
+)
+
+type InboundEmailMatch string
+
+const (
+	InboundEmailMatchThread    InboundEmailMatch = "thread"
+	InboundEmailMatchReference InboundEmailMatch = "reference"
+	InboundEmailMatchSender    InboundEmailMatch = "sender"
+	InboundEmailMatchManual    InboundEmailMatch = "manual"
+)
+
+// ReferenceKind describes which kind of reference number was found in an email
+type ReferenceKind string
+
+const (
+	ReferenceKindLead        ReferenceKind = "lead"
+	ReferenceKindBooking     ReferenceKind = "booking"
+	ReferenceKindContactForm ReferenceKind = "contact_form"
+)
+
+var (
+	leadReferencePattern        = regexp.MustCompile(`(?i)\bEG-\d{4}-[0-9A-F]{6}\b`)
+	bookingReferencePattern     = regexp.MustCompile(`(?i)\bBK-\d{4}-[0-9a-f]{8}\b`)
+	contactFormReferencePattern = regexp.MustCompile(`(?i)\bCF-[0-9a-f]{8}\b`)
+	replyPrefixPattern          = regexp.MustCompile(`(?i)^\s*((re|aw|fw|fwd|wg)\s*:\s*)+`)
+)
+
+// InboundEmail represents a received email from the support mailbox or an inbound webhook
+type InboundEmail struct {
+	ID        uuid.UUID `json:"id" gorm:"type:char(36);primary_key"`
+	MessageID string    `json:"message_id" gorm:"not null;uniqueIndex"` // External message ID
+	InReplyTo string    `json:"in_reply_to" gorm:"index"`
+
+	FromEmail string `json:"from_email" gorm:"not null;index"`
+	FromName  string `json:"from_name" gorm:""`
+	ToEmail   string `json:"to_email" gorm:""`
+	Subject   string `json:"subject" gorm:"not null"`
+	Body      string `json:"body" gorm:"type:text"`
+	IsHTML    bool   `json:"is_html" gorm:"not null;default:false"`
+
+	// Matching result
+	Status         InboundEmailStatus `json:"status" gorm:"not null;default:'unmatched';index"`
+	MatchedBy      InboundEmailMatch  `json:"matched_by" gorm:""`
+	Reference      string             `json:"reference" gorm:""`
+	LeadID         *uuid.UUID         `json:"lead_id" gorm:"type:char(36);index"`
+	ContactFormID  *uuid.UUID         `json:"contact_form_id" gorm:"type:char(36);index"`
+	EmailMessageID *uuid.UUID         `json:"email_message_id" gorm:"type:char(36);index"`
+	ErrorMessage   string             `json:"error_message" gorm:"type:text"`
+
+	ReceivedAt  time.Time  `json:"received_at" gorm:"not null;index"`
+	ProcessedAt *time.Time `json:"processed_at" gorm:""`
+
+	CreatedAt time.Time      `json:"created_at" gorm:"not null"`
+	UpdatedAt time.Time      `json:"updated_at" gorm:"not null"`
+	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+
+	// Relationships
+	Lead        *Lead             `json:"lead,omitempty" gorm:"constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
+	ContactForm *ContactForm      `json:"contact_form,omitempty" gorm:"constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
+	Attachments []EmailAttachment `json:"attachments,omitempty" gorm:"foreignKey:InboundEmailID"`
+}
+
+// EmailAttachment represents a file attached to a received email
+type EmailAttachment struct {
+	ID             uuid.UUID  `json:"id" gorm:"type:char(36);primary_key"`
+	InboundEmailID uuid.UUID  `json:"inbound_email_id" gorm:"type:char(36);not null;index"`
+	EmailMessageID *uuid.UUID `json:"email_message_id" gorm:"type:char(36);index"`
+
+	FileName     string `json:"file_name" gorm:"not null"`
+	OriginalName string `json:"original_name" gorm:"not null"`
+	FilePath     string `json:"-" gorm:"not null"`
+	FileSize     int64  `json:"file_size" gorm:"not null"`
+	ContentType  string `json:"content_type" gorm:""`
+
+	CreatedAt time.Time `json:"created_at" gorm:"not null"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"not null"`
+}
+
+// InboundEmailResponse represents the inbound email data returned in API responses
+type InboundEmailResponse struct {
+	ID            uuid.UUID          `json:"id"`
+	MessageID     string             `json:"message_id"`
+	FromEmail     string             `json:"from_email"`
+	FromName      string             `json:"from_name"`
+	Subject       string             `json:"subject"`
+	Status        InboundEmailStatus `json:"status"`
+	MatchedBy     InboundEmailMatch  `json:"matched_by"`
+	Reference     string             `json:"reference"`
+	LeadID        *uuid.UUID         `json:"lead_id"`
+	ContactFormID *uuid.UUID         `json:"contact_form_id"`
+	Attachments   []EmailAttachment  `json:"attachments"`
+	ReceivedAt    time.Time          `json:"received_at"`
+	ProcessedAt   *time.Time         `json:"processed_at"`
+}
+
+// AssignInboundEmailRequest represents the request body for manually assigning an email to a lead
+type AssignInboundEmailRequest struct {
+	LeadID uuid.UUID `json:"lead_id" validate:"required"`
+}
+
+// BeforeCreate is a GORM hook that runs before creating an inbound email
+func (ie *InboundEmail) BeforeCreate(tx *gorm.DB) error {
+	if ie.ID == uuid.Nil {
+		ie.ID = uuid.New()
+	}
+	if ie.ReceivedAt.IsZero() {
+		ie.ReceivedAt = time.Now()
+	}
+	return nil
+}
+
+// BeforeCreate is a GORM hook that runs before creating an email attachment
+func (ea *EmailAttachment) BeforeCreate(tx *gorm.DB) error {
+	if ea.ID == uuid.Nil {
+		ea.ID = uuid.New()
+	}
+	return nil
+}
+
+// BeforeCreate is a GORM hook that runs before creating an email thread
+func (et *EmailThread) BeforeCreate(tx *gorm.DB) error {
+	if et.ID == uuid.Nil {
+		et.ID = uuid.New()
+	}
+	return nil
+}
+
+// BeforeCreate is a GORM hook that runs before creating an email message
+func (em *EmailMessage) BeforeCreate(tx *gorm.DB) error {
+	if em.ID == uuid.Nil {
+		em.ID = uuid.New()
+	}
+	return nil
+}
+
+// ToResponse converts an InboundEmail to InboundEmailResponse
+func (ie *InboundEmail) ToResponse() InboundEmailResponse {
+	return InboundEmailResponse{
+		ID:            ie.ID,
+		MessageID:     ie.MessageID,
+		FromEmail:     ie.FromEmail,
+		FromName:      ie.FromName,
+		Subject:       ie.Subject,
+		Status:        ie.Status,
+		MatchedBy:     ie.MatchedBy,
+		Reference:     ie.Reference,
+		LeadID:        ie.LeadID,
+		ContactFormID: ie.ContactFormID,
+		Attachments:   ie.Attachments,
+		ReceivedAt:    ie.ReceivedAt,
+		ProcessedAt:   ie.ProcessedAt,
+	}
+}
+
+// IsMatched checks if the email has been attached to a lead or contact form
+func (ie *InboundEmail) IsMatched() bool {
+	return ie.Status == InboundEmailStatusMatched
+}
+
+// GetDisplayName returns a human-readable display name for the inbound email status
+func (s InboundEmailStatus) GetDisplayName() string {
+	switch s {
+	case InboundEmailStatusMatched:
+		return "Zugeordnet"
+	case InboundEmailStatusUnmatched:
+		return "Nicht zugeordnet"
+	case InboundEmailStatusFailed:
+		return "Fehlgeschlagen"
+	default:
+		return string(s)
+	}
+}
+
+// FindEmailReference searches the given texts for a known reference number.
+// Lead application numbers take precedence over booking and contact form references.
+func FindEmailReference(texts ...string) (ReferenceKind, string) {
+	patterns := []struct {
+		kind    ReferenceKind
+		pattern *regexp.Regexp
+	}{
+		{ReferenceKindLead, leadReferencePattern},
+		{ReferenceKindBooking, bookingReferencePattern},
+		{ReferenceKindContactForm, contactFormReferencePattern},
+	}
+
+	for _, p := range patterns {
+		for _, text := range texts {
+			if match := p.pattern.FindString(text); match != "" {
+				return p.kind, match
+			}
+		}
+	}
+
+	return "", ""
+}
+
+// NormalizeEmailAddress extracts and lowercases the address part of a mail header value
+func NormalizeEmailAddress(value string) string {
+	if addr, err := mail.ParseAddress(value); err == nil {
+		return strings.ToLower(addr.Address)
+	}
+	return strings.ToLower(strings.Trim(strings.TrimSpace(value), "<>"))
+}
+
+// NormalizeEmailSubject strips reply and forward prefixes (Re:, AW:, Fwd:, WG:) from a subject
+func NormalizeEmailSubject(subject string) string {
+	return strings.TrimSpace(replyPrefixPattern.ReplaceAllString(subject, ""))
+}
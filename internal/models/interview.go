@@ -0,0 +1,54 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// InterviewInvitation is the link HR sends an applicant to pick one of the
+// published interview slots. Choosing a slot uses up the invitation.
+type InterviewInvitation struct {
+	ID            uuid.UUID `json:"id" gorm:"type:char(36);primary_key"`
+	ApplicationID uuid.UUID `json:"application_id" gorm:"type:char(36);not null;index"`
+
+	// Only the SHA-256 hash of the invitation token is stored
+	TokenHash string    `json:"-" gorm:"size:64;not null;uniqueIndex"`
+	ExpiresAt time.Time `json:"expires_at" gorm:"not null"`
+	InvitedBy uuid.UUID `json:"invited_by" gorm:"type:char(36);not null"`
+
+	TimeslotID  *uuid.UUID `json:"timeslot_id,omitempty" gorm:"type:char(36);index"` // chosen interview slot
+	ScheduledAt *time.Time `json:"scheduled_at,omitempty"`
+	RevokedAt   *time.Time `json:"revoked_at,omitempty"` // replaced by a newer invitation
+
+	CreatedAt time.Time `json:"created_at" gorm:"not null"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"not null"`
+
+	// Relationships
+	Application *JobApplication `json:"-" gorm:"foreignKey:ApplicationID"`
+	Timeslot    *Timeslot       `json:"timeslot,omitempty" gorm:"foreignKey:TimeslotID"`
+}
+
+// BeforeCreate is a GORM hook that runs before creating an interview invitation
+func (ii *InterviewInvitation) BeforeCreate(tx *gorm.DB) error {
+	if ii.ID == uuid.Nil {
+		ii.ID = uuid.New()
+	}
+	return nil
+}
+
+// IsOpen checks if the applicant can still pick a slot with the invitation
+func (ii *InterviewInvitation) IsOpen(now time.Time) bool {
+	return ii.ScheduledAt == nil && ii.RevokedAt == nil && now.Before(ii.ExpiresAt)
+}
+
+// CanBeInvited checks if an applicant in the status can be invited to an interview
+func (as ApplicationStatus) CanBeInvited() bool {
+	switch as {
+	case ApplicationStatusSubmitted, ApplicationStatusReviewing, ApplicationStatusScreening:
+		return true
+	default:
+		return false
+	}
+}
@@ -179,6 +179,21 @@ type JobApplication struct {
 	InterviewScheduled bool      `json:"interview_scheduled" gorm:"not null;default:false"`
 	InterviewDate     *time.Time `json:"interview_date" gorm:""`
 	
+	// Talent pool, only with the explicit consent of the applicant
+	TalentPool              bool       `json:"talent_pool" gorm:"not null;default:false;index"`
+	TalentPoolConsentAt     *time.Time `json:"talent_pool_consent_at,omitempty" gorm:""`
+	TalentPoolConsentText   string     `json:"talent_pool_consent_text,omitempty" gorm:"type:text"` // wording the applicant agreed to
+	TalentPoolConsentSource string     `json:"talent_pool_consent_source,omitempty" gorm:"size:20"`  // application_form, email or phone
+	
+	// Set when the applicant confirmed their email address with the link from the confirmation email
+	EmailVerifiedAt *time.Time `json:"email_verified_at" gorm:"index"`
+	
+	// Average of the reviewers' evaluation scores, nil before the first rating
+	Score *float64 `json:"score" gorm:"index"`
+	
+	// Set when the personal data was removed after the retention period
+	AnonymizedAt *time.Time `json:"anonymized_at,omitempty" gorm:"index"`
+	
 	CreatedAt time.Time      `json:"created_at" gorm:"not null"`
 	UpdatedAt time.Time      `json:"updated_at" gorm:"not null"`
 	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// JobLock is a lease on a scheduled job held by one instance, so a job runs
+// on one instance at a time when several are deployed. The lease is renewed
+// while the job runs and taken over by another instance once it expired.
+type JobLock struct {
+	Name      string    `json:"name" gorm:"primaryKey;size:100"`
+	Owner     string    `json:"owner" gorm:"size:100;not null"`
+	ExpiresAt time.Time `json:"expires_at" gorm:"not null"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"not null"`
+}
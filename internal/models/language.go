@@ -0,0 +1,115 @@
+package models
+
+import "strings"
+
+// ConsultationLanguages lists the languages consultations can be held in.
+// Customers state which of them they speak, Beraters which they work in.
+var ConsultationLanguages = []string{"de", "en", "tr", "ar", "ru", "uk", "pl", "fa", "ro"}
+
+// IsConsultationLanguage checks if the code is a known consultation language
+func IsConsultationLanguage(code string) bool {
+	for _, language := range ConsultationLanguages {
+		if strings.EqualFold(code, language) {
+			return true
+		}
+	}
+	return false
+}
+
+// LanguageDisplayName returns the German name of a consultation language
+func LanguageDisplayName(code string) string {
+	switch strings.ToLower(code) {
+	case "de":
+		return "Deutsch"
+	case "en":
+		return "Englisch"
+	case "tr":
+		return "Türkisch"
+	case "ar":
+		return "Arabisch"
+	case "ru":
+		return "Russisch"
+	case "uk":
+		return "Ukrainisch"
+	case "pl":
+		return "Polnisch"
+	case "fa":
+		return "Persisch"
+	case "ro":
+		return "Rumänisch"
+	default:
+		return "Unbekannt"
+	}
+}
+
+// CleanLanguages lower-cases language codes and drops duplicates and blanks
+func CleanLanguages(values []string) []string {
+	result := make([]string, 0, len(values))
+	seen := make(map[string]bool, len(values))
+	for _, value := range values {
+		value = strings.ToLower(strings.TrimSpace(value))
+		if value == "" || seen[value] {
+			continue
+		}
+		seen[value] = true
+		result = append(result, value)
+	}
+	return result
+}
+
+// SharedLanguage returns the first of the customer's languages the Berater
+// works in, or an empty string if they have none in common
+func SharedLanguage(customer, berater []string) string {
+	for _, language := range customer {
+		for _, working := range berater {
+			if strings.EqualFold(language, working) {
+				return strings.ToLower(language)
+			}
+		}
+	}
+	return ""
+}
+
+// GetSpokenLanguages returns the languages the customer can hold a
+// consultation in, preferred first
+func (u *User) GetSpokenLanguages() []string {
+	return decodeStringList(u.SpokenLanguages)
+}
+
+func (u *User) SetSpokenLanguages(values []string) {
+	u.SpokenLanguages = encodeStringList(values)
+}
+
+// PreferredLanguages returns the languages to match a Berater against.
+// Customers who did not state their languages speak their portal language.
+func (u *User) PreferredLanguages() []string {
+	if languages := u.GetSpokenLanguages(); len(languages) > 0 {
+		return languages
+	}
+	if u.Language != "" {
+		return []string{u.Language}
+	}
+	return []string{"de"}
+}
+
+// MatchLanguages sets the consultation language of a booking from the
+// languages of its customer and Berater. Without a shared language the
+// consultation is held in the Berater's first working language and the
+// booking needs an interpreter for the customer's preferred language.
+func (b *Booking) MatchLanguages(customer, berater *User) {
+	preferred := customer.PreferredLanguages()
+	working := berater.GetWorkingLanguages()
+	if len(working) == 0 {
+		working = []string{"de"}
+	}
+
+	if shared := SharedLanguage(preferred, working); shared != "" {
+		b.ConsultationLanguage = shared
+		b.InterpreterRequired = false
+		b.InterpreterLanguage = ""
+		return
+	}
+	b.ConsultationLanguage = strings.ToLower(working[0])
+	b.InterpreterRequired = true
+	b.InterpreterLanguage = strings.ToLower(preferred[0])
+}
@@ -40,6 +40,7 @@ const (
 	LeadSourceEmail       LeadSource = "email"
 	LeadSourceSocial      LeadSource = "social_media"
 	LeadSourceManual      LeadSource = "manual"
+	LeadSourceIntegration LeadSource = "integration" // created by Zapier, Make or another API key integration
 )
 
 // Lead represents an Elterngeld application/case
@@ -47,6 +48,7 @@ type Lead struct {
 	ID        uuid.UUID  `json:"id" gorm:"type:char(36);primary_key"`
 	UserID    uuid.UUID  `json:"user_id" gorm:"type:char(36);not null;index"`
 	BeraterID *uuid.UUID `json:"berater_id" gorm:"type:char(36);index"`
+	FamilyID  *uuid.UUID `json:"family_id" gorm:"type:char(36);index"` // case of several parents, its members see the lead
 
 	// Lead information
 	Title       string     `json:"title" gorm:"not null" validate:"required"`
@@ -67,6 +69,18 @@ type Lead struct {
 	LastContactAt       *time.Time `json:"last_contact_at" gorm:""`
 	NextFollowUpAt      *time.Time `json:"next_follow_up_at" gorm:""`
 	NextFollowUpNote    string     `json:"next_follow_up_note" gorm:"type:text"`
+	SLABreachNotifiedAt *time.Time `json:"sla_breach_notified_at" gorm:""` // first response SLA breach was posted to chat
+	UnclaimedNotifiedAt *time.Time `json:"unclaimed_notified_at" gorm:""`  // claim SLA breach of the unassigned lead was posted to chat
+
+	// Lead aging
+	InactiveSince     *time.Time `json:"inactive_since" gorm:"index"` // marked inactive by a lead aging rule
+	WinBackEmailsSent int        `json:"win_back_emails_sent" gorm:"not null;default:0"`
+	LastWinBackAt     *time.Time `json:"last_win_back_at" gorm:""`
+	LostReason        string     `json:"lost_reason" gorm:"type:text"` // why the lead was closed as lost
+
+	// Status of the client's email address, copied from the user so leads
+	// with unreachable contacts can be filtered
+	EmailStatus EmailStatus `json:"email_status" gorm:"size:20;index"`
 	
 	// Qualification
 	IsQualified         bool   `json:"is_qualified" gorm:"not null;default:false"`
@@ -91,6 +105,7 @@ type Lead struct {
 	ConversionValue    float64    `json:"conversion_value" gorm:"default:0"`
 	
 	// Elterngeld specific fields
+	Topics            string     `json:"-" gorm:"type:text"` // JSON array of Specialization tags the case requires
 	ChildName         string     `json:"child_name" gorm:""`
 	ChildBirthDate    *time.Time `json:"child_birth_date" gorm:""`
 	ExpectedAmount    float64    `json:"expected_amount" gorm:""`
@@ -185,7 +200,8 @@ type EmailMessage struct {
 	UpdatedAt time.Time `json:"updated_at" gorm:"not null"`
 	
 	// Relationships
-	Thread EmailThread `json:"thread,omitempty" gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	Thread      EmailThread       `json:"thread,omitempty" gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	Attachments []EmailAttachment `json:"attachments,omitempty" gorm:"foreignKey:EmailMessageID"`
 }
 
 // Comment represents comments on a lead
@@ -196,13 +212,126 @@ type Comment struct {
 	Content    string    `json:"content" gorm:"type:text;not null" validate:"required"`
 	IsInternal bool      `json:"is_internal" gorm:"not null;default:false"`
 
+	// EditedAt is set when the content was changed, earlier versions are kept as revisions
+	EditedAt *time.Time `json:"edited_at,omitempty"`
+
 	CreatedAt time.Time      `json:"created_at" gorm:"not null"`
 	UpdatedAt time.Time      `json:"updated_at" gorm:"not null"`
 	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
 
 	// Relationships
-	Lead Lead `json:"lead,omitempty" gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
-	User User `json:"user,omitempty" gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	Lead        Lead                `json:"lead,omitempty" gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	User        User                `json:"user,omitempty" gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	Attachments []CommentAttachment `json:"-" gorm:"foreignKey:CommentID"`
+	Reactions   []CommentReaction   `json:"-" gorm:"foreignKey:CommentID"`
+}
+
+// CommentAttachment links a document of the lead to a comment, e.g. a screenshot
+// or a Bescheid excerpt a Berater refers to in a note
+type CommentAttachment struct {
+	ID         uuid.UUID `json:"id" gorm:"type:char(36);primary_key"`
+	CommentID  uuid.UUID `json:"comment_id" gorm:"type:char(36);not null;uniqueIndex:idx_comment_attachments_comment_document"`
+	DocumentID uuid.UUID `json:"document_id" gorm:"type:char(36);not null;uniqueIndex:idx_comment_attachments_comment_document;index"`
+	Position   int       `json:"position" gorm:"not null"`
+
+	CreatedAt time.Time `json:"created_at" gorm:"not null"`
+
+	// Relationships
+	Document Document `json:"document,omitempty" gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+}
+
+// BeforeCreate hook for CommentAttachment
+func (a *CommentAttachment) BeforeCreate(tx *gorm.DB) error {
+	if a.ID == uuid.Nil {
+		a.ID = uuid.New()
+	}
+	return nil
+}
+
+// CommentRevision is an earlier version of an edited comment
+type CommentRevision struct {
+	ID         uuid.UUID `json:"id" gorm:"type:char(36);primary_key"`
+	CommentID  uuid.UUID `json:"comment_id" gorm:"type:char(36);not null;index"`
+	Content    string    `json:"content" gorm:"type:text;not null"`
+	EditedByID uuid.UUID `json:"edited_by_id" gorm:"type:char(36);not null"`
+
+	// CreatedAt is when the content was replaced
+	CreatedAt time.Time `json:"created_at" gorm:"not null"`
+
+	// Relationships
+	EditedBy User `json:"edited_by,omitempty" gorm:"foreignKey:EditedByID"`
+}
+
+// BeforeCreate hook for CommentRevision
+func (r *CommentRevision) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == uuid.Nil {
+		r.ID = uuid.New()
+	}
+	return nil
+}
+
+// Reaction is an emoji reaction on a comment
+type Reaction string
+
+const (
+	ReactionThumbsUp Reaction = "thumbs_up"
+	ReactionHeart    Reaction = "heart"
+	ReactionCheck    Reaction = "check"
+	ReactionEyes     Reaction = "eyes"
+	ReactionParty    Reaction = "party"
+	ReactionQuestion Reaction = "question"
+)
+
+// Reactions are all reactions in the order they are shown
+var Reactions = []Reaction{ReactionThumbsUp, ReactionHeart, ReactionCheck, ReactionEyes, ReactionParty, ReactionQuestion}
+
+// IsValid checks if the reaction is known
+func (r Reaction) IsValid() bool {
+	for _, reaction := range Reactions {
+		if r == reaction {
+			return true
+		}
+	}
+	return false
+}
+
+// Emoji returns the emoji shown for the reaction
+func (r Reaction) Emoji() string {
+	switch r {
+	case ReactionThumbsUp:
+		return "👍"
+	case ReactionHeart:
+		return "❤️"
+	case ReactionCheck:
+		return "✅"
+	case ReactionEyes:
+		return "👀"
+	case ReactionParty:
+		return "🎉"
+	case ReactionQuestion:
+		return "❓"
+	default:
+		return ""
+	}
+}
+
+// CommentReaction is a user's reaction on a comment, each user reacts with
+// each emoji at most once
+type CommentReaction struct {
+	ID        uuid.UUID `json:"id" gorm:"type:char(36);primary_key"`
+	CommentID uuid.UUID `json:"comment_id" gorm:"type:char(36);not null;uniqueIndex:idx_comment_reactions_comment_user_reaction"`
+	UserID    uuid.UUID `json:"user_id" gorm:"type:char(36);not null;uniqueIndex:idx_comment_reactions_comment_user_reaction"`
+	Reaction  Reaction  `json:"reaction" gorm:"size:20;not null;uniqueIndex:idx_comment_reactions_comment_user_reaction"`
+
+	CreatedAt time.Time `json:"created_at" gorm:"not null"`
+}
+
+// BeforeCreate hook for CommentReaction
+func (r *CommentReaction) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == uuid.Nil {
+		r.ID = uuid.New()
+	}
+	return nil
 }
 
 // CreateLeadRequest represents the request body for creating a lead
@@ -212,6 +341,7 @@ type CreateLeadRequest struct {
 	ChildName        string     `json:"child_name"`
 	ChildBirthDate   *time.Time `json:"child_birth_date"`
 	ExpectedAmount   float64    `json:"expected_amount"`
+	Topics           []Specialization `json:"topics" validate:"omitempty,dive,oneof=selbststaendige beamte zwillinge widerspruch"`
 	PreferredContact string     `json:"preferred_contact" validate:"omitempty,oneof=email phone both"`
 	DueDate          *time.Time `json:"due_date"`
 }
@@ -224,6 +354,7 @@ type UpdateLeadRequest struct {
 	ChildName        *string    `json:"child_name"`
 	ChildBirthDate   *time.Time `json:"child_birth_date"`
 	ExpectedAmount   *float64   `json:"expected_amount"`
+	Topics           []Specialization `json:"topics" validate:"omitempty,dive,oneof=selbststaendige beamte zwillinge widerspruch"`
 	PreferredContact *string    `json:"preferred_contact" validate:"omitempty,oneof=email phone both"`
 	DueDate          *time.Time `json:"due_date"`
 	InternalNotes    *string    `json:"internal_notes"`
@@ -251,6 +382,7 @@ type LeadResponse struct {
 	ID                uuid.UUID     `json:"id"`
 	UserID            uuid.UUID     `json:"user_id"`
 	BeraterID         *uuid.UUID    `json:"berater_id"`
+	FamilyID          *uuid.UUID    `json:"family_id,omitempty"`
 	Title             string        `json:"title"`
 	Description       string        `json:"description"`
 	Status            LeadStatus    `json:"status"`
@@ -258,10 +390,14 @@ type LeadResponse struct {
 	ChildName         string        `json:"child_name"`
 	ChildBirthDate    *time.Time    `json:"child_birth_date"`
 	ExpectedAmount    float64       `json:"expected_amount"`
+	Topics            []Specialization `json:"topics"`
 	ApplicationNumber string        `json:"application_number"`
 	PreferredContact  string        `json:"preferred_contact"`
 	DueDate           *time.Time    `json:"due_date"`
 	CompletedAt       *time.Time    `json:"completed_at"`
+	InactiveSince     *time.Time    `json:"inactive_since"`
+	LostReason        string        `json:"lost_reason,omitempty"`
+	EmailStatus       EmailStatus   `json:"email_status"`
 	CreatedAt         time.Time     `json:"created_at"`
 	UpdatedAt         time.Time     `json:"updated_at"`
 	User              *UserResponse `json:"user,omitempty"`
@@ -270,6 +406,20 @@ type LeadResponse struct {
 	CommentCount      int           `json:"comment_count"`
 }
 
+// BeforeSave stores the reminder time in UTC
+func (r *Reminder) BeforeSave(tx *gorm.DB) error {
+	r.RemindAt = r.RemindAt.UTC()
+	return nil
+}
+
+// BeforeCreate is a GORM hook that runs before creating a reminder
+func (r *Reminder) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == uuid.Nil {
+		r.ID = uuid.New()
+	}
+	return nil
+}
+
 // BeforeCreate is a GORM hook that runs before creating a lead
 func (l *Lead) BeforeCreate(tx *gorm.DB) error {
 	if l.ID == uuid.Nil {
@@ -298,6 +448,7 @@ func (l *Lead) ToResponse() LeadResponse {
 		ID:                l.ID,
 		UserID:            l.UserID,
 		BeraterID:         l.BeraterID,
+		FamilyID:          l.FamilyID,
 		Title:             l.Title,
 		Description:       l.Description,
 		Status:            l.Status,
@@ -305,10 +456,14 @@ func (l *Lead) ToResponse() LeadResponse {
 		ChildName:         l.ChildName,
 		ChildBirthDate:    l.ChildBirthDate,
 		ExpectedAmount:    l.ExpectedAmount,
+		Topics:            l.GetTopics(),
 		ApplicationNumber: l.ApplicationNumber,
 		PreferredContact:  l.PreferredContact,
 		DueDate:           l.DueDate,
 		CompletedAt:       l.CompletedAt,
+		InactiveSince:     l.InactiveSince,
+		LostReason:        l.LostReason,
+		EmailStatus:       l.EmailStatus,
 		CreatedAt:         l.CreatedAt,
 		UpdatedAt:         l.UpdatedAt,
 		DocumentCount:     len(l.Documents),
@@ -400,3 +555,55 @@ func (l *Lead) IsOverdue() bool {
 	}
 	return time.Now().After(*l.DueDate) && l.IsActive()
 }
+
+// GetDisplayName returns a human-readable display name for the lead status
+func (ls LeadStatus) GetDisplayName() string {
+	switch ls {
+	case LeadStatusNew:
+		return "Neu"
+	case LeadStatusInProgress:
+		return "In Bearbeitung"
+	case LeadStatusQuestion:
+		return "Rückfrage"
+	case LeadStatusCompleted:
+		return "Abgeschlossen"
+	case LeadStatusCancelled:
+		return "Storniert"
+	case LeadStatusPaymentPending:
+		return "Zahlung ausstehend"
+	default:
+		return "Unbekannt"
+	}
+}
+
+// GetDisplayName returns a human-readable display name for the priority
+func (p Priority) GetDisplayName() string {
+	switch p {
+	case PriorityLow:
+		return "Niedrig"
+	case PriorityMedium:
+		return "Mittel"
+	case PriorityHigh:
+		return "Hoch"
+	case PriorityUrgent:
+		return "Dringend"
+	default:
+		return "Unbekannt"
+	}
+}
+
+// Rank orders priorities from low (1) to urgent (4); unknown priorities rank 0
+func (p Priority) Rank() int {
+	switch p {
+	case PriorityLow:
+		return 1
+	case PriorityMedium:
+		return 2
+	case PriorityHigh:
+		return 3
+	case PriorityUrgent:
+		return 4
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,52 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// LeadAgingRule keeps the pipeline clean: open leads of a status without
+// activity for a number of days are marked inactive, the customer receives a
+// sequence of win-back emails and the lead is eventually closed as lost
+type LeadAgingRule struct {
+	ID                  uuid.UUID  `json:"id" gorm:"type:char(36);primary_key"`
+	Name                string     `json:"name" gorm:"size:100;not null"`
+	Status              LeadStatus `json:"status" gorm:"size:30;not null;index"`   // open lead status the rule applies to
+	InactiveAfterDays   int        `json:"inactive_after_days" gorm:"not null"`    // days without activity until the lead is marked inactive
+	WinBackEmails       int        `json:"win_back_emails" gorm:"not null"`        // emails sent to the customer of an inactive lead
+	WinBackIntervalDays int        `json:"win_back_interval_days" gorm:"not null"` // days between two win-back emails
+	CloseAfterDays      int        `json:"close_after_days" gorm:"not null"`       // days after being marked inactive until the lead is closed; 0 keeps it open
+	LostReason          string     `json:"lost_reason" gorm:"type:text"`           // recorded on the leads the rule closes
+	IsActive            bool       `json:"is_active" gorm:"not null"`
+
+	CreatedAt time.Time      `json:"created_at" gorm:"not null"`
+	UpdatedAt time.Time      `json:"updated_at" gorm:"not null"`
+	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+func (r *LeadAgingRule) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == uuid.Nil {
+		r.ID = uuid.New()
+	}
+	return nil
+}
+
+// WinBackDue checks if the next win-back email of a lead marked inactive at
+// inactiveSince is due
+func (r *LeadAgingRule) WinBackDue(inactiveSince time.Time, sent int, now time.Time) bool {
+	if sent >= r.WinBackEmails {
+		return false
+	}
+	due := inactiveSince.AddDate(0, 0, sent*r.WinBackIntervalDays)
+	return !now.Before(due)
+}
+
+// CloseDue checks if a lead marked inactive at inactiveSince is closed as lost
+func (r *LeadAgingRule) CloseDue(inactiveSince time.Time, now time.Time) bool {
+	if r.CloseAfterDays <= 0 {
+		return false
+	}
+	return !now.Before(inactiveSince.AddDate(0, 0, r.CloseAfterDays))
+}
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// MarketingSpend is the cost of a marketing channel or campaign within a
+// period. Channel and campaign match the utm_source and utm_campaign captured
+// on leads; spend without a campaign applies to the whole channel.
+type MarketingSpend struct {
+	ID          uuid.UUID `json:"id" gorm:"type:char(36);primary_key"`
+	Channel     string    `json:"channel" gorm:"size:100;not null;index"`
+	Campaign    string    `json:"campaign" gorm:"size:200;index"`
+	PeriodStart string    `json:"period_start" gorm:"size:10;not null;index"` // first day, YYYY-MM-DD
+	PeriodEnd   string    `json:"period_end" gorm:"size:10;not null;index"`   // last day, YYYY-MM-DD
+	Cost        float64   `json:"cost" gorm:"type:decimal(10,2);not null"`    // in EUR
+	Note        string    `json:"note,omitempty" gorm:"type:text"`
+	CreatedBy   uuid.UUID `json:"created_by" gorm:"type:char(36);not null;index"`
+
+	CreatedAt time.Time      `json:"created_at" gorm:"not null"`
+	UpdatedAt time.Time      `json:"updated_at" gorm:"not null"`
+	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+
+	// Relationships
+	Creator User `json:"-" gorm:"foreignKey:CreatedBy;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+}
+
+func (s *MarketingSpend) BeforeCreate(tx *gorm.DB) error {
+	if s.ID == uuid.Nil {
+		s.ID = uuid.New()
+	}
+	return nil
+}
@@ -1,9 +1,12 @@
 package models
 
 import (
+	"strings"
 	"testing"
 	"time"
 
+	"elterngeld-portal/pkg/timeutil"
+
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"golang.org/x/crypto/bcrypt"
@@ -107,6 +110,27 @@ func TestUserModel_FullName(t *testing.T) {
 	assert.Equal(t, "John Doe", user.FullName())
 }
 
+func TestUserModel_NotificationAddress(t *testing.T) {
+	user := &User{Email: "anna@example.com"}
+	assert.Equal(t, "anna@example.com", user.NotificationAddress())
+
+	user.NotificationEmail = "familie@example.com"
+	assert.Equal(t, "familie@example.com", user.NotificationAddress())
+}
+
+func TestEmploymentType_AssessedByTaxYear(t *testing.T) {
+	for _, employmentType := range EmploymentTypes {
+		assert.True(t, employmentType.IsValid())
+		assert.NotEqual(t, "Unbekannt", employmentType.GetDisplayName())
+	}
+	assert.False(t, EmploymentType("freiberuflich").IsValid())
+
+	assert.True(t, EmploymentTypeSelfEmployed.AssessedByTaxYear())
+	assert.True(t, EmploymentTypeMixed.AssessedByTaxYear())
+	assert.False(t, EmploymentTypeEmployed.AssessedByTaxYear())
+	assert.False(t, EmploymentTypeCivilServant.AssessedByTaxYear())
+}
+
 func TestUserModel_RoleChecks(t *testing.T) {
 	tests := []struct {
 		name      string
@@ -210,6 +234,22 @@ func TestLeadModel_IsOverdue(t *testing.T) {
 	}
 }
 
+func TestLeadAgingRule_Schedule(t *testing.T) {
+	inactiveSince := time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)
+	rule := &LeadAgingRule{WinBackEmails: 2, WinBackIntervalDays: 7, CloseAfterDays: 14}
+
+	assert.True(t, rule.WinBackDue(inactiveSince, 0, inactiveSince))
+	assert.False(t, rule.WinBackDue(inactiveSince, 1, inactiveSince.AddDate(0, 0, 6)))
+	assert.True(t, rule.WinBackDue(inactiveSince, 1, inactiveSince.AddDate(0, 0, 7)))
+	assert.False(t, rule.WinBackDue(inactiveSince, 2, inactiveSince.AddDate(0, 0, 30)))
+
+	assert.False(t, rule.CloseDue(inactiveSince, inactiveSince.AddDate(0, 0, 13)))
+	assert.True(t, rule.CloseDue(inactiveSince, inactiveSince.AddDate(0, 0, 14)))
+
+	rule.CloseAfterDays = 0
+	assert.False(t, rule.CloseDue(inactiveSince, inactiveSince.AddDate(1, 0, 0)))
+}
+
 func TestPaymentModel_StatusChecks(t *testing.T) {
 	tests := []struct {
 		name         string
@@ -264,6 +304,17 @@ func TestPaymentModel_GetRemainingRefundAmount(t *testing.T) {
 	assert.Equal(t, 70.0, payment.GetRemainingRefundAmount())
 }
 
+func TestPackageModel_GetFeaturesArray(t *testing.T) {
+	pkg := &Package{Features: `["Erstberatung (30 Min)", "E-Mail Support"]`}
+	assert.Equal(t, []string{"Erstberatung (30 Min)", "E-Mail Support"}, pkg.GetFeaturesArray())
+
+	pkg.Features = "Erstberatung (30 Min)\n\n  E-Mail Support "
+	assert.Equal(t, []string{"Erstberatung (30 Min)", "E-Mail Support"}, pkg.GetFeaturesArray())
+
+	pkg.Features = ""
+	assert.Equal(t, []string{}, pkg.GetFeaturesArray())
+}
+
 func TestDocumentModel_FileTypeChecks(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -317,6 +368,10 @@ func TestDocumentType_DisplayName(t *testing.T) {
 		{DocumentTypeIncomeProof, "Einkommensnachweis"},
 		{DocumentTypeEmploymentCert, "Arbeitsbescheinigung"},
 		{DocumentTypeApplication, "Antrag"},
+		{DocumentTypeBescheid, "Bescheid"},
+		{DocumentTypePayslip, "Gehaltsabrechnung"},
+		{DocumentTypeTaxAssessment, "Steuerbescheid"},
+		{DocumentTypeHealthInsurance, "Krankenkassenbescheinigung"},
 		{DocumentTypeOther, "Sonstiges"},
 		{DocumentType("unknown"), "Unbekannt"},
 	}
@@ -409,4 +464,120 @@ func TestPaymentMethod_GetDisplayName(t *testing.T) {
 			assert.Equal(t, tt.expected, tt.method.GetDisplayName())
 		})
 	}
-}
\ No newline at end of file
+}
+func TestTimeslotModel_BeforeSaveNormalizesToUTC(t *testing.T) {
+	berlin := timeutil.LoadLocation("Europe/Berlin")
+
+	slot := &Timeslot{
+		StartTime: time.Date(2024, time.October, 27, 9, 0, 0, 0, berlin),
+		EndTime:   time.Date(2024, time.October, 27, 10, 0, 0, 0, berlin),
+		Duration:  60,
+	}
+
+	assert.NoError(t, slot.BeforeSave(nil))
+	assert.Equal(t, "Europe/Berlin", slot.Timezone)
+	assert.Equal(t, time.UTC, slot.StartTime.Location())
+	assert.Equal(t, time.Date(2024, time.October, 27, 8, 0, 0, 0, time.UTC), slot.StartTime)
+	assert.Equal(t, time.Date(2024, time.October, 26, 22, 0, 0, 0, time.UTC), slot.Date)
+}
+
+func TestTimeslotModel_ToResponseIn(t *testing.T) {
+	slot := &Timeslot{
+		ID:          uuid.New(),
+		Timezone:    "Europe/Berlin",
+		StartTime:   time.Date(2024, time.June, 30, 22, 30, 0, 0, time.UTC),
+		EndTime:     time.Date(2024, time.June, 30, 23, 30, 0, 0, time.UTC),
+		MaxBookings: 1,
+	}
+
+	response := slot.ToResponse()
+	assert.Equal(t, "Europe/Berlin", response.Timezone)
+	assert.Equal(t, "2024-07-01", response.LocalDate)
+	assert.Equal(t, 0, response.StartTime.Hour())
+
+	response = slot.ToResponseIn(timeutil.LoadLocation("America/New_York"))
+	assert.Equal(t, "America/New_York", response.Timezone)
+	assert.Equal(t, "2024-06-30", response.LocalDate)
+	assert.Equal(t, 18, response.StartTime.Hour())
+	assert.True(t, response.StartTime.Equal(slot.StartTime))
+}
+
+func TestBookingModel_ReminderAtAcrossDST(t *testing.T) {
+	berlin := timeutil.LoadLocation("Europe/Berlin")
+
+	tests := []struct {
+		name     string
+		start    time.Time
+		expected time.Time
+	}{
+		{"regular_day", time.Date(2024, time.May, 14, 10, 0, 0, 0, berlin), time.Date(2024, time.May, 13, 8, 0, 0, 0, time.UTC)},
+		{"after_spring_forward", time.Date(2024, time.March, 31, 10, 0, 0, 0, berlin), time.Date(2024, time.March, 30, 9, 0, 0, 0, time.UTC)},
+		{"after_fall_back", time.Date(2024, time.October, 27, 10, 0, 0, 0, berlin), time.Date(2024, time.October, 26, 8, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			booking := &Booking{StartTime: tt.start, ScheduledAt: tt.start}
+			assert.NoError(t, booking.BeforeSave(nil))
+			assert.Equal(t, time.UTC, booking.StartTime.Location())
+
+			reminder := booking.ReminderAt(berlin)
+			assert.Equal(t, tt.expected, reminder)
+			assert.Equal(t, tt.start.Hour(), reminder.In(berlin).Hour())
+		})
+	}
+}
+
+func TestBookingModel_MatchLanguages(t *testing.T) {
+	berater := &User{}
+	berater.SetWorkingLanguages([]string{"de", "en"})
+
+	tests := []struct {
+		name        string
+		customer    func(u *User)
+		language    string
+		interpreter string
+	}{
+		{"portal_language", func(u *User) { u.Language = "en" }, "en", ""},
+		{"second_language", func(u *User) { u.SetSpokenLanguages([]string{"tr", "EN"}) }, "en", ""},
+		{"no_shared_language", func(u *User) { u.SetSpokenLanguages([]string{"ar"}) }, "de", "ar"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			customer := &User{Language: "de"}
+			tt.customer(customer)
+
+			booking := &Booking{}
+			booking.MatchLanguages(customer, berater)
+			assert.Equal(t, tt.language, booking.ConsultationLanguage)
+			assert.Equal(t, tt.interpreter != "", booking.InterpreterRequired)
+			assert.Equal(t, tt.interpreter, booking.InterpreterLanguage)
+		})
+	}
+}
+
+func TestHashedKey(t *testing.T) {
+	key := &APIKey{}
+	plain, err := key.Generate(APIKeyPrefix)
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(plain, key.KeyPrefix))
+	assert.Equal(t, HashKey(plain), key.KeyHash)
+	assert.NotContains(t, key.KeyHash, plain)
+
+	key.SetScopes([]APIKeyScope{APIKeyScopeLeadsRead, APIKeyScopeCommentsWrite})
+	assert.Equal(t, "leads:read,comments:write", key.Scopes)
+	assert.Equal(t, []APIKeyScope{APIKeyScopeLeadsRead, APIKeyScopeCommentsWrite}, key.GetScopes())
+	assert.True(t, key.HasScope(APIKeyScopeLeadsRead))
+	assert.False(t, key.HasScope(APIKeyScopeLeadsWrite))
+
+	now := time.Now()
+	assert.True(t, key.IsActive(now))
+	expiresAt := now.Add(time.Hour)
+	key.ExpiresAt = &expiresAt
+	assert.True(t, key.IsActive(now))
+	assert.False(t, key.IsActive(expiresAt))
+	key.ExpiresAt = nil
+	key.RevokedAt = &now
+	assert.False(t, key.IsActive(now))
+}
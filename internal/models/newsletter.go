@@ -0,0 +1,74 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// NewsletterSegment is a tag on the newsletter contact describing where the
+// customer is in the funnel
+type NewsletterSegment string
+
+const (
+	NewsletterSegmentPreTalkDone NewsletterSegment = "pre_talk_done"
+	NewsletterSegmentCustomer    NewsletterSegment = "customer"
+	NewsletterSegmentLostLead    NewsletterSegment = "lost_lead"
+)
+
+type NewsletterContactStatus string
+
+const (
+	NewsletterContactStatusSubscribed   NewsletterContactStatus = "subscribed"
+	NewsletterContactStatusUnsubscribed NewsletterContactStatus = "unsubscribed"
+)
+
+// NewsletterContact tracks a user synced to the newsletter provider list, so
+// changed segments and withdrawn consent can be propagated
+type NewsletterContact struct {
+	ID       uuid.UUID               `json:"id" gorm:"type:char(36);primary_key"`
+	UserID   uuid.UUID               `json:"user_id" gorm:"type:char(36);not null;uniqueIndex"`
+	Email    string                  `json:"email" gorm:"not null"` // address known to the provider
+	Provider string                  `json:"provider" gorm:"size:20;not null"`
+	Status   NewsletterContactStatus `json:"status" gorm:"size:20;not null;index"`
+	Segments string                  `json:"-" gorm:"type:text"` // comma-separated NewsletterSegment values
+
+	SyncedAt       *time.Time `json:"synced_at" gorm:""`
+	UnsubscribedAt *time.Time `json:"unsubscribed_at" gorm:""`
+	LastError      string     `json:"last_error,omitempty" gorm:"type:text"`
+
+	CreatedAt time.Time `json:"created_at" gorm:"not null"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"not null"`
+
+	// Relationships
+	User User `json:"-" gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+}
+
+func (c *NewsletterContact) BeforeCreate(tx *gorm.DB) error {
+	if c.ID == uuid.Nil {
+		c.ID = uuid.New()
+	}
+	return nil
+}
+
+// GetSegments returns the segments the contact is tagged with at the provider
+func (c *NewsletterContact) GetSegments() []NewsletterSegment {
+	var segments []NewsletterSegment
+	for _, segment := range strings.Split(c.Segments, ",") {
+		if segment = strings.TrimSpace(segment); segment != "" {
+			segments = append(segments, NewsletterSegment(segment))
+		}
+	}
+	return segments
+}
+
+// SetSegments stores the segments the contact is tagged with at the provider
+func (c *NewsletterContact) SetSegments(segments []NewsletterSegment) {
+	values := make([]string, len(segments))
+	for i, segment := range segments {
+		values[i] = string(segment)
+	}
+	c.Segments = strings.Join(values, ",")
+}
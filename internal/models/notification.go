@@ -1,6 +1,8 @@
 package models
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"time"
 
 	"github.com/google/uuid"
@@ -20,10 +22,12 @@ type NotificationStatus string
 
 const (
 	NotificationStatusPending    NotificationStatus = "pending"
+	NotificationStatusSending    NotificationStatus = "sending" // claimed by the email queue
 	NotificationStatusSent       NotificationStatus = "sent"
 	NotificationStatusDelivered  NotificationStatus = "delivered"
 	NotificationStatusFailed     NotificationStatus = "failed"
 	NotificationStatusRetrying   NotificationStatus = "retrying"
+	NotificationStatusBatched    NotificationStatus = "batched" // held back for the user's digest
 )
 
 type EmailTemplate string
@@ -42,8 +46,67 @@ const (
 	EmailTemplateLeadAssigned         EmailTemplate = "lead_assigned"
 	EmailTemplateReminderDue          EmailTemplate = "reminder_due"
 	EmailTemplateContactForm          EmailTemplate = "contact_form"
+	EmailTemplateLeadEmailReceived    EmailTemplate = "lead_email_received"
+	EmailTemplateBookingNoShow        EmailTemplate = "booking_no_show"
+	EmailTemplateBookingReassigned    EmailTemplate = "booking_reassigned"
+	EmailTemplateLeadWinBack          EmailTemplate = "lead_win_back"
+	EmailTemplateAnnouncement         EmailTemplate = "announcement"
 )
 
+// NotificationCategory groups email notifications for the digest settings
+type NotificationCategory string
+
+const (
+	NotificationCategoryBooking  NotificationCategory = "booking"
+	NotificationCategoryPayment  NotificationCategory = "payment"
+	NotificationCategoryTodo     NotificationCategory = "todo"
+	NotificationCategoryReminder NotificationCategory = "reminder"
+	NotificationCategoryLead     NotificationCategory = "lead"
+)
+
+// NotificationCategories lists the categories that can be sent as digest
+var NotificationCategories = []NotificationCategory{
+	NotificationCategoryBooking,
+	NotificationCategoryPayment,
+	NotificationCategoryTodo,
+	NotificationCategoryReminder,
+	NotificationCategoryLead,
+}
+
+// DigestCategory returns the digest category of an email template. Account
+// emails, win-back emails, payment receipts with their invoice and booking
+// confirmations are always sent right away and have no category.
+func (t EmailTemplate) DigestCategory() (NotificationCategory, bool) {
+	switch t {
+	case EmailTemplateBookingReminder, EmailTemplateBookingCancellation, EmailTemplateBookingNoShow, EmailTemplateBookingReassigned:
+		return NotificationCategoryBooking, true
+	case EmailTemplateOrderConfirmation, EmailTemplatePaymentFailed:
+		return NotificationCategoryPayment, true
+	case EmailTemplateTodoAssigned:
+		return NotificationCategoryTodo, true
+	case EmailTemplateReminderDue:
+		return NotificationCategoryReminder, true
+	case EmailTemplateLeadAssigned, EmailTemplateContactForm, EmailTemplateLeadEmailReceived:
+		return NotificationCategoryLead, true
+	default:
+		return "", false
+	}
+}
+
+// DigestMode is how often the email notifications of a category are sent
+type DigestMode string
+
+const (
+	DigestModeImmediate DigestMode = "immediate"
+	DigestModeHourly    DigestMode = "hourly"
+	DigestModeDaily     DigestMode = "daily"
+)
+
+// IsValid checks if the digest mode is known
+func (m DigestMode) IsValid() bool {
+	return m == DigestModeImmediate || m == DigestModeHourly || m == DigestModeDaily
+}
+
 // Notification represents a notification to be sent to a user
 type Notification struct {
 	ID       uuid.UUID        `json:"id" gorm:"type:char(36);primary_key"`
@@ -100,7 +163,7 @@ type EmailVerification struct {
 	UserID uuid.UUID `json:"user_id" gorm:"type:char(36);not null;index"`
 	Email  string    `json:"email" gorm:"not null;index"`
 	
-	Token     string    `json:"token" gorm:"not null;uniqueIndex"`
+	Token     string    `json:"-" gorm:"not null;uniqueIndex"` // SHA-256 hash of the emailed token
 	ExpiresAt time.Time `json:"expires_at" gorm:"not null"`
 	IsUsed    bool      `json:"is_used" gorm:"not null;default:false"`
 	UsedAt    *time.Time `json:"used_at" gorm:""`
@@ -170,6 +233,18 @@ type NotificationPreference struct {
 	QuietHoursEnd     time.Time `json:"quiet_hours_end" gorm:""`
 	Timezone          string    `json:"timezone" gorm:"default:'Europe/Berlin'"`
 	
+	// Daily digest of the activity feed, for Beraters and admins
+	EmailDailyDigest bool       `json:"email_daily_digest" gorm:"not null"`
+	DigestSentAt     *time.Time `json:"digest_sent_at" gorm:""`
+	
+	// Email digest mode per notification category
+	BookingDigest            DigestMode `json:"booking_digest" gorm:"size:10;not null;default:'immediate'"`
+	PaymentDigest            DigestMode `json:"payment_digest" gorm:"size:10;not null;default:'immediate'"`
+	TodoDigest               DigestMode `json:"todo_digest" gorm:"size:10;not null;default:'immediate'"`
+	ReminderDigest           DigestMode `json:"reminder_digest" gorm:"size:10;not null;default:'immediate'"`
+	LeadDigest               DigestMode `json:"lead_digest" gorm:"size:10;not null;default:'immediate'"`
+	NotificationDigestSentAt *time.Time `json:"notification_digest_sent_at" gorm:""` // last daily notification digest
+	
 	CreatedAt time.Time      `json:"created_at" gorm:"not null"`
 	UpdatedAt time.Time      `json:"updated_at" gorm:"not null"`
 	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
@@ -178,16 +253,41 @@ type NotificationPreference struct {
 	User User `json:"user,omitempty" gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
 }
 
+// DigestModeFor returns the digest mode of a category; unset modes are immediate
+func (p *NotificationPreference) DigestModeFor(category NotificationCategory) DigestMode {
+	var mode DigestMode
+	switch category {
+	case NotificationCategoryBooking:
+		mode = p.BookingDigest
+	case NotificationCategoryPayment:
+		mode = p.PaymentDigest
+	case NotificationCategoryTodo:
+		mode = p.TodoDigest
+	case NotificationCategoryReminder:
+		mode = p.ReminderDigest
+	case NotificationCategoryLead:
+		mode = p.LeadDigest
+	}
+	if !mode.IsValid() {
+		return DigestModeImmediate
+	}
+	return mode
+}
+
 // ContactForm represents contact form submissions
 type ContactForm struct {
-	ID uuid.UUID `json:"id" gorm:"type:char(36);primary_key"`
+	ID     uuid.UUID  `json:"id" gorm:"type:char(36);primary_key"`
+	UserID *uuid.UUID `json:"user_id" gorm:"type:char(36);index"` // account of the submitter, set once the lead is created
 	
 	// Contact information
 	Name    string `json:"name" gorm:"not null" validate:"required"`
 	Email   string `json:"email" gorm:"not null" validate:"required,email"`
 	Phone   string `json:"phone" gorm:""`
+	Company string `json:"company" gorm:""`
 	Subject string `json:"subject" gorm:"not null" validate:"required"`
 	Message string `json:"message" gorm:"type:text;not null" validate:"required"`
+
+	PreferredDate *time.Time `json:"preferred_date" gorm:""` // when the submitter would like to be contacted
 	
 	// Additional context
 	Source         string `json:"source" gorm:"default:'website'"` // website, landing_page, etc.
@@ -214,12 +314,18 @@ type ContactForm struct {
 	RepliedAt   *time.Time `json:"replied_at" gorm:""`
 	RepliedBy   *uuid.UUID `json:"replied_by" gorm:"type:char(36);index"`
 	
+	// Team inbox: the Berater who claimed the form replies to it
+	ClaimedBy           *uuid.UUID `json:"claimed_by" gorm:"type:char(36);index"`
+	ClaimedAt           *time.Time `json:"claimed_at" gorm:""`
+	UnclaimedNotifiedAt *time.Time `json:"unclaimed_notified_at" gorm:""` // claim SLA breach was posted to chat
+	
 	CreatedAt time.Time      `json:"created_at" gorm:"not null"`
 	UpdatedAt time.Time      `json:"updated_at" gorm:"not null"`
 	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
 	
 	// Relationships
 	Processor *User `json:"processor,omitempty" gorm:"foreignKey:ProcessedBy;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
+	Claimer   *User `json:"claimer,omitempty" gorm:"foreignKey:ClaimedBy;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
 	Responder *User `json:"responder,omitempty" gorm:"foreignKey:RepliedBy;constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
 	Lead      *Lead `json:"lead,omitempty" gorm:"constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
 }
@@ -398,6 +504,12 @@ func (n *Notification) ShouldRetryNow() bool {
 	return time.Now().After(*n.NextRetryAt)
 }
 
+// HashVerificationToken returns the stored representation of an email verification token
+func HashVerificationToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
 func (ev *EmailVerification) IsExpired() bool {
 	return time.Now().After(ev.ExpiresAt)
 }
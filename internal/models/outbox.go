@@ -0,0 +1,47 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type OutboxStatus string
+
+const (
+	OutboxStatusPending    OutboxStatus = "pending"
+	OutboxStatusProcessing OutboxStatus = "processing"
+	OutboxStatusDelivered  OutboxStatus = "delivered"
+	OutboxStatusFailed     OutboxStatus = "failed" // retried at next_attempt_at
+	OutboxStatusDead       OutboxStatus = "dead"   // gave up, waits for an admin to retry it
+)
+
+// OutboxMessage is a side effect (email, chat webhook, notification) of a
+// business change. It is written in the transaction of the change and
+// delivered by the outbox relay afterwards, so side effects are neither lost
+// on a crash nor sent for changes that were rolled back.
+type OutboxMessage struct {
+	ID       uuid.UUID `json:"id" gorm:"type:char(36);primary_key"`
+	Topic    string    `json:"topic" gorm:"size:100;not null;index"`
+	DedupKey string    `json:"dedup_key" gorm:"size:255;not null;uniqueIndex"` // drops a second message for the same side effect
+	Payload  string    `json:"payload" gorm:"type:text;not null"`
+
+	// Delivery
+	Status        OutboxStatus `json:"status" gorm:"size:20;not null;index"`
+	Attempts      int          `json:"attempts" gorm:"not null"`
+	LastError     string       `json:"last_error,omitempty" gorm:"type:text"`
+	NextAttemptAt *time.Time   `json:"next_attempt_at,omitempty" gorm:"index"`
+	DeliveredAt   *time.Time   `json:"delivered_at,omitempty"`
+	DeadAt        *time.Time   `json:"dead_at,omitempty"`
+
+	CreatedAt time.Time `json:"created_at" gorm:"not null;index"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"not null"`
+}
+
+func (m *OutboxMessage) BeforeCreate(tx *gorm.DB) error {
+	if m.ID == uuid.Nil {
+		m.ID = uuid.New()
+	}
+	return nil
+}
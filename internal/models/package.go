@@ -1,7 +1,9 @@
 package models
 
 import (
+	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -85,7 +87,7 @@ type Addon struct {
 // PackageAddon represents the junction table for package-addon relationships
 type PackageAddon struct {
 	PackageID uuid.UUID `json:"package_id" gorm:"type:char(36);primary_key"`
-	AddonID   uuid.UUID `json:"addon_id" gorm:"type:char(36);primary_key"`
+	AddonID   uuid.UUID `json:"addon_id" gorm:"type:char(36);primary_key;index"`
 	IsDefault bool      `json:"is_default" gorm:"not null;default:false"`
 	
 	CreatedAt time.Time `json:"created_at" gorm:"not null"`
@@ -269,13 +271,27 @@ func (a *Addon) FormatPrice() string {
 	return formatCurrency(a.Price, a.Currency)
 }
 
-// GetFeaturesArray parses the features JSON string into a slice
+// GetFeaturesArray parses the features JSON string into a slice. Features
+// entered before they were stored as JSON are read one per line.
 func (p *Package) GetFeaturesArray() []string {
-	// This would normally parse JSON, but for simplicity we'll return empty slice
-	// In real implementation, you'd unmarshal the JSON string
-	return []string{}
+	features := []string{}
+	if strings.TrimSpace(p.Features) == "" {
+		return features
+	}
+	if err := json.Unmarshal([]byte(p.Features), &features); err == nil {
+		return features
+	}
+
+	features = []string{}
+	for _, line := range strings.Split(p.Features, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			features = append(features, line)
+		}
+	}
+	return features
 }
 
+
 // Helper function to format currency (could be moved to utils)
 func formatCurrency(amount float64, currency string) string {
 	switch currency {
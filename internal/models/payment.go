@@ -25,6 +25,17 @@ const (
 	PaymentMethodStripe PaymentMethod = "stripe"
 	PaymentMethodBank   PaymentMethod = "bank_transfer"
 	PaymentMethodCash   PaymentMethod = "cash"
+	PaymentMethodCredit PaymentMethod = "credit" // paid entirely with account credit
+)
+
+// TaxTreatment is how Umsatzsteuer applies to a payment
+type TaxTreatment string
+
+const (
+	TaxTreatmentDomestic      TaxTreatment = "inland"         // German VAT is charged
+	TaxTreatmentReverseCharge TaxTreatment = "reverse_charge" // business in another EU country owes the VAT
+	TaxTreatmentNonEU         TaxTreatment = "drittland"      // customer outside the EU, not taxable in Germany
+	TaxTreatmentStripe        TaxTreatment = "stripe_tax"     // calculated by Stripe Tax
 )
 
 // Payment represents a payment transaction
@@ -40,6 +51,18 @@ type Payment struct {
 	Method      PaymentMethod `json:"method" gorm:"not null;default:'stripe'"`
 	Description string        `json:"description" gorm:"type:text"`
 
+	// Account credit applied at checkout; Amount is only the remainder charged
+	CreditAmount float64 `json:"credit_amount" gorm:"not null;default:0"`
+
+	// Tax of the whole price (Amount + CreditAmount = NetAmount + TaxAmount)
+	NetAmount      float64      `json:"net_amount" gorm:"not null;default:0"`
+	TaxAmount      float64      `json:"tax_amount" gorm:"not null;default:0"`
+	TaxRate        float64      `json:"tax_rate" gorm:"not null;default:0"`
+	TaxCountry     string       `json:"tax_country" gorm:"size:2"`
+	TaxTreatment   TaxTreatment `json:"tax_treatment" gorm:"size:20"`
+	CustomerVATID  string       `json:"customer_vat_id" gorm:"size:20"`
+	VATIDCheckedAt *time.Time   `json:"vat_id_checked_at" gorm:""` // when VIES confirmed the USt-ID
+
 	// Stripe specific fields
 	StripeSessionID     string `json:"stripe_session_id" gorm:"uniqueIndex"`
 	StripePaymentIntent string `json:"stripe_payment_intent" gorm:""`
@@ -53,7 +76,7 @@ type Payment struct {
 	// Billing information
 	BillingName    string `json:"billing_name" gorm:""`
 	BillingEmail   string `json:"billing_email" gorm:""`
-	BillingAddress string `json:"billing_address" gorm:"type:text"`
+	BillingAddress string `json:"billing_address" gorm:"type:text;serializer:encrypted"`
 
 	// Timestamps
 	PaidAt     *time.Time `json:"paid_at" gorm:""`
@@ -87,6 +110,13 @@ type PaymentResponse struct {
 	Status                PaymentStatus `json:"status"`
 	Method                PaymentMethod `json:"method"`
 	Description           string        `json:"description"`
+	CreditAmount          float64       `json:"credit_amount"`
+	NetAmount             float64       `json:"net_amount"`
+	TaxAmount             float64       `json:"tax_amount"`
+	TaxRate               float64       `json:"tax_rate"`
+	TaxCountry            string        `json:"tax_country"`
+	TaxTreatment          TaxTreatment  `json:"tax_treatment"`
+	CustomerVATID         string        `json:"customer_vat_id"`
 	BillingName           string        `json:"billing_name"`
 	BillingEmail          string        `json:"billing_email"`
 	ReceiptURL            string        `json:"receipt_url"`
@@ -156,6 +186,13 @@ func (p *Payment) ToResponse() PaymentResponse {
 		Status:                p.Status,
 		Method:                p.Method,
 		Description:           p.Description,
+		CreditAmount:          p.CreditAmount,
+		NetAmount:             p.NetAmount,
+		TaxAmount:             p.TaxAmount,
+		TaxRate:               p.TaxRate,
+		TaxCountry:            p.TaxCountry,
+		TaxTreatment:          p.TaxTreatment,
+		CustomerVATID:         p.CustomerVATID,
 		BillingName:           p.BillingName,
 		BillingEmail:          p.BillingEmail,
 		ReceiptURL:            p.ReceiptURL,
@@ -266,6 +303,22 @@ func (ps PaymentStatus) GetDisplayName() string {
 	}
 }
 
+// GetDisplayName returns a human-readable display name for the tax treatment
+func (t TaxTreatment) GetDisplayName() string {
+	switch t {
+	case TaxTreatmentDomestic:
+		return "Umsatzsteuer Deutschland"
+	case TaxTreatmentReverseCharge:
+		return "Steuerschuldnerschaft des Leistungsempfängers"
+	case TaxTreatmentNonEU:
+		return "Nicht im Inland steuerbare Leistung"
+	case TaxTreatmentStripe:
+		return "Stripe Tax"
+	default:
+		return "Unbekannt"
+	}
+}
+
 // GetDisplayName returns a human-readable display name for the payment method
 func (pm PaymentMethod) GetDisplayName() string {
 	switch pm {
@@ -275,6 +328,8 @@ func (pm PaymentMethod) GetDisplayName() string {
 		return "Banküberweisung"
 	case PaymentMethodCash:
 		return "Bar"
+	case PaymentMethodCredit:
+		return "Guthaben"
 	default:
 		return "Unbekannt"
 	}
@@ -0,0 +1,53 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// PaymentDispute is a chargeback or inquiry the customer's bank opened for a
+// payment, kept in sync from the Stripe dispute webhooks
+type PaymentDispute struct {
+	ID              uuid.UUID `json:"id" gorm:"type:char(36);primary_key"`
+	PaymentID       uuid.UUID `json:"payment_id" gorm:"type:char(36);not null;index"`
+	UserID          uuid.UUID `json:"user_id" gorm:"type:char(36);not null;index"`
+	StripeDisputeID string    `json:"stripe_dispute_id" gorm:"size:255;not null;uniqueIndex"`
+
+	Amount   float64 `json:"amount" gorm:"not null"`
+	Currency string  `json:"currency" gorm:"size:3;not null;default:'EUR'"`
+	Reason   string  `json:"reason" gorm:"size:50"`
+	Status   string  `json:"status" gorm:"size:30;not null;index"` // Stripe dispute status, e.g. needs_response, won, lost
+
+	OpenedAt time.Time  `json:"opened_at" gorm:"not null"`
+	ClosedAt *time.Time `json:"closed_at,omitempty" gorm:""`
+
+	CreatedAt time.Time `json:"created_at" gorm:"not null"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"not null"`
+
+	// Relationships
+	Payment *Payment `json:"payment,omitempty" gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+}
+
+func (d *PaymentDispute) BeforeCreate(tx *gorm.DB) error {
+	if d.ID == uuid.Nil {
+		d.ID = uuid.New()
+	}
+	if d.Currency == "" {
+		d.Currency = "EUR"
+	}
+	return nil
+}
+
+// IsInquiry checks if the dispute is only an inquiry of the bank; no money
+// is withdrawn for inquiries
+func (d *PaymentDispute) IsInquiry() bool {
+	return strings.HasPrefix(d.Status, "warning_")
+}
+
+// IsClosed checks if the dispute was decided
+func (d *PaymentDispute) IsClosed() bool {
+	return d.Status == "won" || d.Status == "lost" || d.Status == "warning_closed"
+}
@@ -0,0 +1,98 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type PaymentLinkStatus string
+
+const (
+	PaymentLinkStatusOpen      PaymentLinkStatus = "offen"
+	PaymentLinkStatusPaid      PaymentLinkStatus = "bezahlt"
+	PaymentLinkStatusCancelled PaymentLinkStatus = "storniert"
+)
+
+// PaymentLinkTokenLength is the length of the token in the public payment URL
+const PaymentLinkTokenLength = 24
+
+// PaymentLink is a Stripe payment a Berater sends to a lead, e.g. after a
+// phone call, for an offer or a custom amount. The booking is only created
+// when the link is paid.
+type PaymentLink struct {
+	ID        uuid.UUID         `json:"id" gorm:"type:char(36);primary_key"`
+	Token     string            `json:"-" gorm:"size:32;not null;uniqueIndex"`
+	LeadID    uuid.UUID         `json:"lead_id" gorm:"type:char(36);not null;index"`
+	UserID    uuid.UUID         `json:"user_id" gorm:"type:char(36);not null;index"` // customer of the lead
+	PackageID *uuid.UUID        `json:"package_id,omitempty" gorm:"type:char(36);index"`
+	CreatedBy uuid.UUID         `json:"created_by" gorm:"type:char(36);not null"`
+	Status    PaymentLinkStatus `json:"status" gorm:"size:20;not null;default:'offen';index"`
+
+	Title       string  `json:"title" gorm:"size:200;not null"`
+	Description string  `json:"description" gorm:"type:text"`
+	Amount      float64 `json:"amount" gorm:"not null"` // gross
+	Currency    string  `json:"currency" gorm:"size:3;not null;default:'EUR'"`
+
+	// Opening is tracked by the short link the customer receives
+	ShortLinkID *uuid.UUID `json:"short_link_id,omitempty" gorm:"type:char(36);index"`
+
+	StripeSessionID string     `json:"stripe_session_id,omitempty" gorm:"index"` // latest checkout of the link
+	ExpiresAt       *time.Time `json:"expires_at,omitempty" gorm:""`
+	PaidAt          *time.Time `json:"paid_at,omitempty" gorm:""`
+	BookingID       *uuid.UUID `json:"booking_id,omitempty" gorm:"type:char(36);index"` // created on payment
+	PaymentID       *uuid.UUID `json:"payment_id,omitempty" gorm:"type:char(36);index"`
+
+	CreatedAt time.Time      `json:"created_at" gorm:"not null"`
+	UpdatedAt time.Time      `json:"updated_at" gorm:"not null"`
+	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+
+	// Relationships
+	Lead      *Lead      `json:"-" gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	User      *User      `json:"-" gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	Package   *Package   `json:"package,omitempty" gorm:"constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
+	ShortLink *ShortLink `json:"-" gorm:"constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
+}
+
+func (pl *PaymentLink) BeforeCreate(tx *gorm.DB) error {
+	if pl.ID == uuid.Nil {
+		pl.ID = uuid.New()
+	}
+	if pl.Token == "" {
+		token, err := GenerateShortLinkCode(PaymentLinkTokenLength)
+		if err != nil {
+			return err
+		}
+		pl.Token = token
+	}
+	if pl.Status == "" {
+		pl.Status = PaymentLinkStatusOpen
+	}
+	if pl.Currency == "" {
+		pl.Currency = "EUR"
+	}
+	return nil
+}
+
+// IsPayable checks if the link is open and has not expired
+func (pl *PaymentLink) IsPayable(at time.Time) bool {
+	if pl.Status != PaymentLinkStatusOpen {
+		return false
+	}
+	return pl.ExpiresAt == nil || at.Before(*pl.ExpiresAt)
+}
+
+// GetDisplayName returns the German display name of the status
+func (s PaymentLinkStatus) GetDisplayName() string {
+	switch s {
+	case PaymentLinkStatusOpen:
+		return "Offen"
+	case PaymentLinkStatusPaid:
+		return "Bezahlt"
+	case PaymentLinkStatusCancelled:
+		return "Storniert"
+	default:
+		return string(s)
+	}
+}
@@ -0,0 +1,63 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type PDFJobStatus string
+
+const (
+	PDFJobStatusPending   PDFJobStatus = "pending"
+	PDFJobStatusCompleted PDFJobStatus = "completed"
+	PDFJobStatusFailed    PDFJobStatus = "failed" // the template could not be rendered, retrying does not help
+)
+
+// PDFJob is a PDF generated in the background from an HTML template. The
+// template data is kept so a failed conversion can be retried.
+type PDFJob struct {
+	ID            uuid.UUID    `json:"id" gorm:"type:char(36);primary_key"`
+	Template      string       `json:"template" gorm:"size:50;not null"`
+	Tenant        string       `json:"tenant" gorm:"size:50;not null"`
+	FileName      string       `json:"file_name" gorm:"size:255;not null"`
+	Data          string       `json:"-" gorm:"type:text;not null"`
+	Status        PDFJobStatus `json:"status" gorm:"size:20;not null;index"`
+	Error         string       `json:"error,omitempty" gorm:"type:text"`
+	Size          int64        `json:"size" gorm:""`
+	RequestedByID uuid.UUID    `json:"requested_by_id" gorm:"type:char(36);not null;index"`
+	CompletedAt   *time.Time   `json:"completed_at,omitempty"`
+
+	CreatedAt time.Time `json:"created_at" gorm:"not null"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"not null"`
+}
+
+func (j *PDFJob) BeforeCreate(tx *gorm.DB) error {
+	if j.ID == uuid.Nil {
+		j.ID = uuid.New()
+	}
+	return nil
+}
+
+// PDFBranding is the letterhead of the PDFs of a tenant. Tenants without a
+// branding use the company name and logo of the configuration.
+type PDFBranding struct {
+	ID           uuid.UUID  `json:"id" gorm:"type:char(36);primary_key"`
+	Tenant       string     `json:"tenant" gorm:"size:50;not null;uniqueIndex"`
+	CompanyName  string     `json:"company_name" gorm:"size:200;not null"`
+	Logo         string     `json:"logo" gorm:"type:text"` // data URI, embedded so conversion needs no network access
+	PrimaryColor string     `json:"primary_color" gorm:"size:7"`
+	Footer       string     `json:"footer" gorm:"size:500"`
+	UpdatedByID  *uuid.UUID `json:"updated_by_id" gorm:"type:char(36)"`
+
+	CreatedAt time.Time `json:"created_at" gorm:"not null"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"not null"`
+}
+
+func (b *PDFBranding) BeforeCreate(tx *gorm.DB) error {
+	if b.ID == uuid.Nil {
+		b.ID = uuid.New()
+	}
+	return nil
+}
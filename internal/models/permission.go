@@ -94,8 +94,14 @@ const (
 	PermissionResourceEmail         PermissionResource = "email"
 	PermissionResourceNotification  PermissionResource = "notification"
 	PermissionResourceNotifications PermissionResource = "notifications"
+
+	// Personal data
+	PermissionResourcePII PermissionResource = "pii"
 )
 
+// PermissionPIIRead allows reading unmasked emails, phone numbers and addresses of other people
+const PermissionPIIRead = "pii.read"
+
 // Permission represents a specific permission in the system
 type Permission struct {
 	ID          uuid.UUID          `json:"id" gorm:"type:char(36);primary_key"`
@@ -495,6 +501,7 @@ var DefaultPermissions = map[string][]string{
 		"packages.read",
 		"profile.read",
 		"profile.update",
+		"pii.read",
 	},
 	"junior_berater": {
 		"dashboard.read",
@@ -515,4 +522,53 @@ var DefaultPermissions = map[string][]string{
 		// Admin gets all permissions - this would be populated dynamically
 		"*.manage",
 	},
-}
\ No newline at end of file
+}
+
+// HasPermission checks if a user holds a permission. A grant or denial for the user
+// wins over the permissions of their role; roles not configured in the database
+// fall back to DefaultPermissions. Admins hold every permission.
+func HasPermission(db *gorm.DB, userID uuid.UUID, role UserRole, name string) bool {
+	if role == RoleAdmin {
+		return true
+	}
+
+	// Deployments without role management tables only use the defaults
+	if !db.Migrator().HasTable(&Permission{}) {
+		return hasDefaultPermission(role, name)
+	}
+
+	var overrides []UserPermission
+	err := db.Joins("JOIN permissions ON permissions.id = user_permissions.permission_id").
+		Where("user_permissions.user_id = ? AND permissions.name = ? AND permissions.is_active = ?", userID, name, true).
+		Where("(user_permissions.expires_at IS NULL OR user_permissions.expires_at > ?)", time.Now()).
+		Find(&overrides).Error
+	if err == nil && len(overrides) > 0 {
+		for _, override := range overrides {
+			if !override.IsGranted {
+				return false
+			}
+		}
+		return true
+	}
+
+	var roleRecord Role
+	if err := db.Where("name = ? AND is_active = ?", string(role), true).Limit(1).Find(&roleRecord).Error; err == nil && roleRecord.ID != uuid.Nil {
+		var count int64
+		db.Table("role_permissions").
+			Joins("JOIN permissions ON permissions.id = role_permissions.permission_id").
+			Where("role_permissions.role_id = ? AND permissions.name = ? AND permissions.is_active = ?", roleRecord.ID, name, true).
+			Count(&count)
+		return count > 0
+	}
+
+	return hasDefaultPermission(role, name)
+}
+
+func hasDefaultPermission(role UserRole, name string) bool {
+	for _, permission := range DefaultPermissions[string(role)] {
+		if permission == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,52 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type PostStatus string
+
+const (
+	PostStatusDraft     PostStatus = "draft"
+	PostStatusPublished PostStatus = "published" // public from PublishedAt on
+)
+
+// Post is a blog or news article of the content-marketing site, written by a
+// Berater. Published posts with a PublishedAt in the future are scheduled and
+// become public at that time.
+type Post struct {
+	ID            uuid.UUID  `json:"id" gorm:"type:char(36);primary_key"`
+	Slug          string     `json:"slug" gorm:"size:150;not null;uniqueIndex"`
+	Title         string     `json:"title" gorm:"size:200;not null"`
+	Excerpt       string     `json:"excerpt,omitempty" gorm:"type:text"` // teaser for lists and feeds
+	Body          string     `json:"body" gorm:"type:text;not null"`     // Markdown
+	Category      string     `json:"category,omitempty" gorm:"size:100;index"`
+	CoverImageURL string     `json:"cover_image_url,omitempty" gorm:"size:500"`
+	AuthorID      uuid.UUID  `json:"author_id" gorm:"type:char(36);not null;index"`
+	Status        PostStatus `json:"status" gorm:"size:20;not null;index"`
+	PublishedAt   *time.Time `json:"published_at,omitempty" gorm:"index"`
+
+	CreatedBy uuid.UUID `json:"created_by" gorm:"type:char(36);not null"`
+	UpdatedBy uuid.UUID `json:"updated_by" gorm:"type:char(36);not null"`
+	CreatedAt time.Time `json:"created_at" gorm:"not null"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"not null"`
+
+	// Relationships
+	Author *User `json:"-" gorm:"foreignKey:AuthorID"`
+}
+
+// BeforeCreate is a GORM hook that runs before creating a post
+func (p *Post) BeforeCreate(tx *gorm.DB) error {
+	if p.ID == uuid.Nil {
+		p.ID = uuid.New()
+	}
+	return nil
+}
+
+// IsScheduled checks if the post is published but not public yet
+func (p *Post) IsScheduled(now time.Time) bool {
+	return p.Status == PostStatusPublished && p.PublishedAt != nil && p.PublishedAt.After(now)
+}
@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// PostalCodeArea assigns a place with a German postal code (PLZ) to its Kreis and
+// Bundesland. A postal code can span several places, Kreise and, in rare cases,
+// Bundesländer. The table is replaced as a whole by importing the reference dataset.
+type PostalCodeArea struct {
+	ID         uuid.UUID  `json:"-" gorm:"type:char(36);primary_key"`
+	PostalCode string     `json:"postal_code" gorm:"size:5;not null;index"`
+	Place      string     `json:"place" gorm:"size:255"`
+	Kreis      string     `json:"kreis" gorm:"size:255"`
+	KreisCode  string     `json:"kreis_code" gorm:"size:5;index"` // Amtlicher Gemeindeschlüssel of the Kreis
+	Bundesland Bundesland `json:"bundesland" gorm:"size:2;not null;index"`
+
+	CreatedAt time.Time `json:"-" gorm:"not null"`
+}
+
+func (a *PostalCodeArea) BeforeCreate(tx *gorm.DB) error {
+	if a.ID == uuid.Nil {
+		a.ID = uuid.New()
+	}
+	return nil
+}
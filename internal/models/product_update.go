@@ -0,0 +1,71 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ProductUpdateKind is the kind of an entry of the product update feed
+type ProductUpdateKind string
+
+const (
+	ProductUpdateKindFeature     ProductUpdateKind = "feature"
+	ProductUpdateKindImprovement ProductUpdateKind = "improvement"
+	ProductUpdateKindFix         ProductUpdateKind = "fix"
+	ProductUpdateKindMaintenance ProductUpdateKind = "maintenance" // announces a maintenance window
+)
+
+// IsValid checks if the product update kind is known
+func (k ProductUpdateKind) IsValid() bool {
+	switch k {
+	case ProductUpdateKindFeature, ProductUpdateKindImprovement, ProductUpdateKindFix, ProductUpdateKindMaintenance:
+		return true
+	}
+	return false
+}
+
+// ProductUpdateAudience is who sees a product update
+type ProductUpdateAudience string
+
+const (
+	ProductUpdateAudienceAll       ProductUpdateAudience = "all"
+	ProductUpdateAudienceCustomers ProductUpdateAudience = "customers"
+	ProductUpdateAudienceStaff     ProductUpdateAudience = "staff" // Berater and admins
+)
+
+// IsValid checks if the product update audience is known
+func (a ProductUpdateAudience) IsValid() bool {
+	return a == ProductUpdateAudienceAll || a == ProductUpdateAudienceCustomers || a == ProductUpdateAudienceStaff
+}
+
+// ProductUpdate is an entry of the "What's new" feed: a new feature, an
+// improvement, a fix or a maintenance window. Entries without a publish
+// time are drafts; entries published in the future are scheduled.
+type ProductUpdate struct {
+	ID       uuid.UUID             `json:"id" gorm:"type:char(36);primary_key"`
+	Kind     ProductUpdateKind     `json:"kind" gorm:"size:20;not null"`
+	Audience ProductUpdateAudience `json:"audience" gorm:"size:20;not null"`
+	Title    string                `json:"title" gorm:"size:200;not null"`
+	Body     string                `json:"body" gorm:"type:text;not null"` // Markdown
+	Link     string                `json:"link,omitempty" gorm:"size:500"` // e.g. to the help article of a feature
+
+	// Maintenance window, set for maintenance entries only
+	StartsAt *time.Time `json:"starts_at,omitempty"`
+	EndsAt   *time.Time `json:"ends_at,omitempty"`
+
+	PublishedAt *time.Time `json:"published_at" gorm:"index"`
+	CreatedBy   uuid.UUID  `json:"created_by" gorm:"type:char(36);not null"`
+	UpdatedBy   uuid.UUID  `json:"updated_by" gorm:"type:char(36);not null"`
+
+	CreatedAt time.Time `json:"created_at" gorm:"not null"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"not null"`
+}
+
+func (u *ProductUpdate) BeforeCreate(tx *gorm.DB) error {
+	if u.ID == uuid.Nil {
+		u.ID = uuid.New()
+	}
+	return nil
+}
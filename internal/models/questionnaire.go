@@ -0,0 +1,252 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// QuestionType is the kind of answer a question of an intake questionnaire takes
+type QuestionType string
+
+const (
+	QuestionTypeText           QuestionType = "text"
+	QuestionTypeNumber         QuestionType = "zahl"
+	QuestionTypeDate           QuestionType = "datum" // YYYY-MM-DD
+	QuestionTypeChoice         QuestionType = "auswahl"
+	QuestionTypeMultipleChoice QuestionType = "mehrfachauswahl"
+	QuestionTypeYesNo          QuestionType = "ja_nein"
+)
+
+// IsValid checks if the question type exists
+func (t QuestionType) IsValid() bool {
+	switch t {
+	case QuestionTypeText, QuestionTypeNumber, QuestionTypeDate, QuestionTypeChoice, QuestionTypeMultipleChoice, QuestionTypeYesNo:
+		return true
+	}
+	return false
+}
+
+// HasOptions checks if answers are picked from the options of the question
+func (t QuestionType) HasOptions() bool {
+	return t == QuestionTypeChoice || t == QuestionTypeMultipleChoice
+}
+
+func (t QuestionType) GetDisplayName() string {
+	switch t {
+	case QuestionTypeText:
+		return "Text"
+	case QuestionTypeNumber:
+		return "Zahl"
+	case QuestionTypeDate:
+		return "Datum"
+	case QuestionTypeChoice:
+		return "Auswahl"
+	case QuestionTypeMultipleChoice:
+		return "Mehrfachauswahl"
+	case QuestionTypeYesNo:
+		return "Ja/Nein"
+	default:
+		return "Unbekannt"
+	}
+}
+
+// PrefillField is an input of the calculators and the application an answer
+// is copied into, named like the field of the calculator requests
+type PrefillField string
+
+const (
+	PrefillChildBirthDate           PrefillField = "child_birth_date"            // birth or due date of the child
+	PrefillSingleParent             PrefillField = "single_parent"               // Alleinerziehend
+	PrefillIncomeBefore             PrefillField = "income_before"               // Elterngeld-Netto of the applicant before the birth
+	PrefillPartnerIncomeBefore      PrefillField = "partner_income_before"       // Elterngeld-Netto of the other parent before the birth
+	PrefillIncomeAfter              PrefillField = "income_after"                // part-time income of the applicant after the birth
+	PrefillWeeklyHours              PrefillField = "weekly_hours"                // part-time hours of the applicant after the birth
+	PrefillEmployerSupplementPerDay PrefillField = "employer_supplement_per_day" // Arbeitgeberzuschuss zum Mutterschaftsgeld
+)
+
+// IsValid checks if the prefill field exists
+func (f PrefillField) IsValid() bool {
+	_, ok := prefillTypes[f]
+	return ok
+}
+
+// QuestionType is the type of the questions that can fill the field
+func (f PrefillField) QuestionType() QuestionType {
+	return prefillTypes[f]
+}
+
+var prefillTypes = map[PrefillField]QuestionType{
+	PrefillChildBirthDate:           QuestionTypeDate,
+	PrefillSingleParent:             QuestionTypeYesNo,
+	PrefillIncomeBefore:             QuestionTypeNumber,
+	PrefillPartnerIncomeBefore:      QuestionTypeNumber,
+	PrefillIncomeAfter:              QuestionTypeNumber,
+	PrefillWeeklyHours:              QuestionTypeNumber,
+	PrefillEmployerSupplementPerDay: QuestionTypeNumber,
+}
+
+// QuestionCondition shows a question only when an earlier question was
+// answered with one of the values. Yes/no answers compare as "true" and
+// "false", multiple choice answers match when one of their options does.
+type QuestionCondition struct {
+	QuestionKey string   `json:"question_key"`
+	Values      []string `json:"values"`
+}
+
+// AnswerPoints are the qualification points the answers to a question earn:
+// by option for choice and yes/no questions ("true" and "false"), by range
+// for number questions such as the income before the birth
+type AnswerPoints struct {
+	Options map[string]int `json:"options,omitempty"`
+	Ranges  []PointsRange  `json:"ranges,omitempty"`
+}
+
+// PointsRange gives points to numbers from From up to, but not including, To
+type PointsRange struct {
+	From   float64  `json:"from"`
+	To     *float64 `json:"to,omitempty"` // open-ended without
+	Points int      `json:"points"`
+}
+
+// Questionnaire is a version of the intake questionnaire of a package that
+// customers fill in after booking. Admins edit a draft and publish it; a
+// published version no longer changes, so the answers given to it stay
+// readable. Changes go into a new draft version.
+type Questionnaire struct {
+	ID          uuid.UUID  `json:"id" gorm:"type:char(36);primary_key"`
+	PackageID   uuid.UUID  `json:"package_id" gorm:"type:char(36);not null;uniqueIndex:idx_questionnaires_version"`
+	Version     int        `json:"version" gorm:"not null;uniqueIndex:idx_questionnaires_version"`
+	Title       string     `json:"title" gorm:"size:200;not null"`
+	Description string     `json:"description" gorm:"type:text"`
+	PublishedAt *time.Time `json:"published_at" gorm:"index"`
+	CreatedByID uuid.UUID  `json:"created_by_id" gorm:"type:char(36);not null"`
+
+	CreatedAt time.Time `json:"created_at" gorm:"not null"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"not null"`
+
+	// Relationships
+	Questions []QuestionnaireQuestion `json:"questions,omitempty" gorm:"foreignKey:QuestionnaireID;constraint:OnDelete:CASCADE"`
+}
+
+// BeforeCreate is a GORM hook that runs before creating a questionnaire
+func (q *Questionnaire) BeforeCreate(tx *gorm.DB) error {
+	if q.ID == uuid.Nil {
+		q.ID = uuid.New()
+	}
+	return nil
+}
+
+// IsPublished checks if customers can answer the questionnaire
+func (q *Questionnaire) IsPublished() bool {
+	return q.PublishedAt != nil
+}
+
+// QuestionnaireQuestion is a question of a questionnaire version. The key
+// names the answer in the responses and stays the same across versions.
+type QuestionnaireQuestion struct {
+	ID              uuid.UUID       `json:"id" gorm:"type:char(36);primary_key"`
+	QuestionnaireID uuid.UUID       `json:"questionnaire_id" gorm:"type:char(36);not null;uniqueIndex:idx_questionnaire_questions_key"`
+	Key             string          `json:"key" gorm:"size:50;not null;uniqueIndex:idx_questionnaire_questions_key"`
+	Label           string          `json:"label" gorm:"size:300;not null"`
+	HelpText        string          `json:"help_text" gorm:"type:text"`
+	Type            QuestionType    `json:"type" gorm:"size:20;not null"`
+	Required        bool            `json:"required" gorm:"not null"`
+	Options         json.RawMessage `json:"options" gorm:"type:jsonb"`
+	ShowIf          json.RawMessage `json:"show_if" gorm:"type:jsonb"`
+	Prefill         PrefillField    `json:"prefill,omitempty" gorm:"size:50"`
+	Points          json.RawMessage `json:"points" gorm:"type:jsonb"` // AnswerPoints for the qualification score
+	Position        int             `json:"position" gorm:"not null"`
+
+	CreatedAt time.Time `json:"created_at" gorm:"not null"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"not null"`
+}
+
+// BeforeCreate is a GORM hook that runs before creating a question
+func (q *QuestionnaireQuestion) BeforeCreate(tx *gorm.DB) error {
+	if q.ID == uuid.Nil {
+		q.ID = uuid.New()
+	}
+	return nil
+}
+
+// GetOptions decodes the options of a choice question
+func (q *QuestionnaireQuestion) GetOptions() ([]string, error) {
+	options := []string{}
+	if len(q.Options) == 0 || string(q.Options) == "null" {
+		return options, nil
+	}
+	err := json.Unmarshal(q.Options, &options)
+	return options, err
+}
+
+// GetPoints decodes the qualification points of the answers, nil if the
+// question does not count towards the score
+func (q *QuestionnaireQuestion) GetPoints() (*AnswerPoints, error) {
+	if len(q.Points) == 0 || string(q.Points) == "null" {
+		return nil, nil
+	}
+	var points AnswerPoints
+	if err := json.Unmarshal(q.Points, &points); err != nil {
+		return nil, err
+	}
+	return &points, nil
+}
+
+// GetShowIf decodes the condition of the question, nil if it is always shown
+func (q *QuestionnaireQuestion) GetShowIf() (*QuestionCondition, error) {
+	if len(q.ShowIf) == 0 || string(q.ShowIf) == "null" {
+		return nil, nil
+	}
+	var condition QuestionCondition
+	if err := json.Unmarshal(q.ShowIf, &condition); err != nil {
+		return nil, err
+	}
+	return &condition, nil
+}
+
+// QuestionnaireResponse holds the answers of a lead to a questionnaire
+// version by question key. Answering the same version again replaces them.
+// Questionnaires with points also qualify the lead: the score sets its
+// priority and recommends a package to the Berater.
+type QuestionnaireResponse struct {
+	ID              uuid.UUID       `json:"id" gorm:"type:char(36);primary_key"`
+	QuestionnaireID uuid.UUID       `json:"questionnaire_id" gorm:"type:char(36);not null;uniqueIndex:idx_questionnaire_responses_lead"`
+	LeadID          uuid.UUID       `json:"lead_id" gorm:"type:char(36);not null;uniqueIndex:idx_questionnaire_responses_lead;index"`
+	UserID          *uuid.UUID      `json:"user_id" gorm:"type:char(36)"` // who answered, nil for anonymous pre-talk bookings
+	Answers         json.RawMessage `json:"answers" gorm:"type:jsonb"`
+	SubmittedAt     time.Time       `json:"submitted_at" gorm:"not null"`
+
+	// Qualification
+	Score                *int       `json:"score"`                          // nil when no question has points
+	ScoreReasons         string     `json:"score_reasons" gorm:"type:text"` // answers that earned points, one per line
+	RecommendedPackageID *uuid.UUID `json:"recommended_package_id" gorm:"type:char(36)"`
+
+	CreatedAt time.Time `json:"created_at" gorm:"not null"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"not null"`
+
+	// Relationships
+	Questionnaire      *Questionnaire `json:"questionnaire,omitempty" gorm:"foreignKey:QuestionnaireID"`
+	RecommendedPackage *Package       `json:"recommended_package,omitempty" gorm:"foreignKey:RecommendedPackageID"`
+	Lead               Lead           `json:"-" gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+}
+
+// BeforeCreate is a GORM hook that runs before creating a questionnaire response
+func (r *QuestionnaireResponse) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == uuid.Nil {
+		r.ID = uuid.New()
+	}
+	return nil
+}
+
+// GetAnswers decodes the answers by question key
+func (r *QuestionnaireResponse) GetAnswers() (map[string]interface{}, error) {
+	answers := map[string]interface{}{}
+	if len(r.Answers) == 0 {
+		return answers, nil
+	}
+	err := json.Unmarshal(r.Answers, &answers)
+	return answers, err
+}
@@ -0,0 +1,75 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type RefundRequestStatus string
+
+const (
+	RefundRequestStatusPending  RefundRequestStatus = "beantragt"
+	RefundRequestStatusApproved RefundRequestStatus = "genehmigt" // being executed
+	RefundRequestStatusRejected RefundRequestStatus = "abgelehnt"
+	RefundRequestStatusExecuted RefundRequestStatus = "ausgefuehrt"
+	RefundRequestStatusFailed   RefundRequestStatus = "fehlgeschlagen"
+)
+
+// RefundRequest is a refund of a payment with its justification. Refunds
+// above the approval threshold are only executed after a second person
+// approved them; the request keeps who asked, who decided and the result.
+type RefundRequest struct {
+	ID          uuid.UUID           `json:"id" gorm:"type:char(36);primary_key"`
+	PaymentID   uuid.UUID           `json:"payment_id" gorm:"type:char(36);not null;index"`
+	RequestedBy uuid.UUID           `json:"requested_by" gorm:"type:char(36);not null;index"`
+	Status      RefundRequestStatus `json:"status" gorm:"size:20;not null;default:'beantragt';index"`
+
+	Amount           float64 `json:"amount" gorm:"not null"`
+	Currency         string  `json:"currency" gorm:"size:3;not null;default:'EUR'"`
+	ToCredit         bool    `json:"to_credit" gorm:"not null"` // as account credit instead of to the card
+	Reason           string  `json:"reason" gorm:"type:text;not null"`
+	RequiresApproval bool    `json:"requires_approval" gorm:"not null"`
+
+	// Decision of the second approver
+	DecidedBy    *uuid.UUID `json:"decided_by,omitempty" gorm:"type:char(36);index"`
+	DecidedAt    *time.Time `json:"decided_at,omitempty" gorm:""`
+	DecisionNote string     `json:"decision_note,omitempty" gorm:"type:text"`
+
+	// Result of the refund
+	ExecutedAt     *time.Time `json:"executed_at,omitempty" gorm:""`
+	StripeRefundID string     `json:"stripe_refund_id,omitempty" gorm:""`
+	CreditEntryID  *uuid.UUID `json:"credit_entry_id,omitempty" gorm:"type:char(36)"`
+	FailureMessage string     `json:"failure_message,omitempty" gorm:"type:text"`
+
+	CreatedAt time.Time `json:"created_at" gorm:"not null"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"not null"`
+
+	// Relationships
+	Payment *Payment `json:"payment,omitempty" gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+}
+
+func (r *RefundRequest) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == uuid.Nil {
+		r.ID = uuid.New()
+	}
+	if r.Status == "" {
+		r.Status = RefundRequestStatusPending
+	}
+	if r.Currency == "" {
+		r.Currency = "EUR"
+	}
+	return nil
+}
+
+// IsValid checks if the status is known
+func (s RefundRequestStatus) IsValid() bool {
+	switch s {
+	case RefundRequestStatusPending, RefundRequestStatusApproved, RefundRequestStatusRejected,
+		RefundRequestStatusExecuted, RefundRequestStatusFailed:
+		return true
+	default:
+		return false
+	}
+}
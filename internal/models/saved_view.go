@@ -0,0 +1,55 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// SavedViewResource is a list endpoint views can be saved for
+type SavedViewResource string
+
+const (
+	SavedViewResourceLeads    SavedViewResource = "leads"
+	SavedViewResourceBookings SavedViewResource = "bookings"
+)
+
+// SavedView is a named filter, sort and column configuration of a list.
+// Shared views are offered to the whole team.
+type SavedView struct {
+	ID        uuid.UUID         `json:"id" gorm:"type:char(36);primary_key"`
+	UserID    uuid.UUID         `json:"user_id" gorm:"type:char(36);not null;index"`
+	Resource  SavedViewResource `json:"resource" gorm:"size:20;not null;index"`
+	Name      string            `json:"name" gorm:"size:100;not null"`
+	Filters   json.RawMessage   `json:"filters" gorm:"type:jsonb"` // query parameters of the list endpoint
+	SortBy    string            `json:"sort_by" gorm:"size:50"`
+	SortOrder string            `json:"sort_order" gorm:"size:4"`
+	Columns   json.RawMessage   `json:"columns" gorm:"type:jsonb"` // visible columns in display order
+	IsShared  bool              `json:"is_shared" gorm:"not null;index"`
+
+	CreatedAt time.Time      `json:"created_at" gorm:"not null"`
+	UpdatedAt time.Time      `json:"updated_at" gorm:"not null"`
+	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+
+	// Relationships
+	User User `json:"user,omitempty" gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+}
+
+func (v *SavedView) BeforeCreate(tx *gorm.DB) error {
+	if v.ID == uuid.Nil {
+		v.ID = uuid.New()
+	}
+	return nil
+}
+
+// SavedViewDefault is the view a user's list opens with
+type SavedViewDefault struct {
+	UserID      uuid.UUID         `json:"user_id" gorm:"type:char(36);primaryKey"`
+	Resource    SavedViewResource `json:"resource" gorm:"size:20;primaryKey"`
+	SavedViewID uuid.UUID         `json:"saved_view_id" gorm:"type:char(36);not null;index"`
+
+	CreatedAt time.Time `json:"created_at" gorm:"not null"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"not null"`
+}
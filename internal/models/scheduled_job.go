@@ -0,0 +1,70 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type JobRunStatus string
+
+const (
+	JobRunStatusRunning   JobRunStatus = "running"
+	JobRunStatusSucceeded JobRunStatus = "succeeded"
+	JobRunStatusFailed    JobRunStatus = "failed"
+)
+
+type JobRunTrigger string
+
+const (
+	JobRunTriggerSchedule JobRunTrigger = "schedule"
+	JobRunTriggerManual   JobRunTrigger = "manual" // started by an admin
+)
+
+// ScheduledJob is the state of a recurring job registered with the
+// scheduler: when it runs next, whether an admin paused or triggered it and
+// the outcome of its last run
+type ScheduledJob struct {
+	Name     string     `json:"name" gorm:"primaryKey;size:100"`
+	Schedule string     `json:"schedule" gorm:"size:100;not null"` // cron expression or "@every <duration>"
+	Paused   bool       `json:"paused" gorm:"not null"`
+	PausedBy *uuid.UUID `json:"paused_by,omitempty" gorm:"type:char(36)"`
+
+	NextRunAt   *time.Time `json:"next_run_at,omitempty" gorm:"index"`
+	TriggeredAt *time.Time `json:"triggered_at,omitempty"` // an admin asked for a run outside the schedule
+	TriggeredBy *uuid.UUID `json:"triggered_by,omitempty" gorm:"type:char(36)"`
+
+	// RunCount is incremented when a run starts, which claims the run
+	RunCount   int64        `json:"run_count" gorm:"not null"`
+	LastRunAt  *time.Time   `json:"last_run_at,omitempty"`
+	LastStatus JobRunStatus `json:"last_status,omitempty" gorm:"size:20"`
+	LastError  string       `json:"last_error,omitempty" gorm:"type:text"`
+
+	CreatedAt time.Time `json:"created_at" gorm:"not null"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"not null"`
+}
+
+// ScheduledJobRun is one run of a scheduled job
+type ScheduledJobRun struct {
+	ID          uuid.UUID     `json:"id" gorm:"type:char(36);primary_key"`
+	JobName     string        `json:"job_name" gorm:"size:100;not null;index"`
+	Trigger     JobRunTrigger `json:"trigger" gorm:"size:20;not null"`
+	TriggeredBy *uuid.UUID    `json:"triggered_by,omitempty" gorm:"type:char(36)"`
+
+	Status     JobRunStatus `json:"status" gorm:"size:20;not null"`
+	StartedAt  time.Time    `json:"started_at" gorm:"not null;index"`
+	FinishedAt *time.Time   `json:"finished_at,omitempty"`
+	DurationMs int64        `json:"duration_ms"`
+	Result     string       `json:"result,omitempty" gorm:"type:text"` // summary of what the run did
+	Error      string       `json:"error,omitempty" gorm:"type:text"`
+
+	CreatedAt time.Time `json:"created_at" gorm:"not null"`
+}
+
+func (r *ScheduledJobRun) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == uuid.Nil {
+		r.ID = uuid.New()
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ServiceKeyScope limits which machine endpoints a service key may call
+type ServiceKeyScope string
+
+const (
+	ServiceKeyScopeInboundEmail ServiceKeyScope = "inbound_email"
+	ServiceKeyScopeContactForms ServiceKeyScope = "contact_forms"
+)
+
+// ServiceKeyScopes lists every scope a service key can be granted
+var ServiceKeyScopes = []ServiceKeyScope{
+	ServiceKeyScopeInboundEmail,
+	ServiceKeyScopeContactForms,
+}
+
+// IsValid checks if the scope exists
+func (s ServiceKeyScope) IsValid() bool {
+	for _, scope := range ServiceKeyScopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// ServiceKeyPrefix marks service keys so leaked keys are easy to recognize
+const ServiceKeyPrefix = "egsk_"
+
+// ServiceKey authenticates a machine integration such as the website forms or
+// a partner system. Unlike integration API keys it does not act as a user.
+type ServiceKey struct {
+	ID          uuid.UUID `json:"id" gorm:"type:char(36);primary_key"`
+	Name        string    `json:"name" gorm:"size:100;not null"`
+	Description string    `json:"description" gorm:"type:text"`
+	HashedKey[ServiceKeyScope]
+	AllowedIPs string `json:"-" gorm:"type:text"`         // comma-separated IP addresses and CIDR ranges; empty allows any address
+	RateLimit  int    `json:"rate_limit" gorm:"not null"` // requests per minute
+
+	// The replaced key stays valid for a grace period after a rotation, so the
+	// integration can be switched over without downtime
+	PreviousKeyHash      string     `json:"-" gorm:"size:64;index"`
+	PreviousKeyExpiresAt *time.Time `json:"previous_key_expires_at" gorm:""`
+	RotatedAt            *time.Time `json:"rotated_at" gorm:""`
+
+	LastUsedIP string `json:"last_used_ip" gorm:"size:45"`
+
+	CreatedByID uuid.UUID `json:"created_by_id" gorm:"type:char(36);not null"`
+	CreatedAt   time.Time `json:"created_at" gorm:"not null"`
+	UpdatedAt   time.Time `json:"updated_at" gorm:"not null"`
+}
+
+// ServiceKeyResponse is returned when listing keys; the key itself is only shown once
+type ServiceKeyResponse struct {
+	ID                   uuid.UUID         `json:"id"`
+	Name                 string            `json:"name"`
+	Description          string            `json:"description"`
+	KeyPrefix            string            `json:"key_prefix"`
+	Scopes               []ServiceKeyScope `json:"scopes"`
+	AllowedIPs           []string          `json:"allowed_ips"`
+	RateLimit            int               `json:"rate_limit"`
+	PreviousKeyExpiresAt *time.Time        `json:"previous_key_expires_at"`
+	RotatedAt            *time.Time        `json:"rotated_at"`
+	LastUsedAt           *time.Time        `json:"last_used_at"`
+	LastUsedIP           string            `json:"last_used_ip"`
+	ExpiresAt            *time.Time        `json:"expires_at"`
+	RevokedAt            *time.Time        `json:"revoked_at"`
+	CreatedByID          uuid.UUID         `json:"created_by_id"`
+	CreatedAt            time.Time         `json:"created_at"`
+}
+
+func (k *ServiceKey) BeforeCreate(tx *gorm.DB) error {
+	if k.ID == uuid.Nil {
+		k.ID = uuid.New()
+	}
+	return nil
+}
+
+// GetAllowedIPs returns the IP addresses and CIDR ranges the key may be used from
+func (k *ServiceKey) GetAllowedIPs() []string {
+	return splitList(k.AllowedIPs)
+}
+
+func (k *ServiceKey) ToResponse() ServiceKeyResponse {
+	allowedIPs := k.GetAllowedIPs()
+	if allowedIPs == nil {
+		allowedIPs = []string{}
+	}
+	return ServiceKeyResponse{
+		ID:                   k.ID,
+		Name:                 k.Name,
+		Description:          k.Description,
+		KeyPrefix:            k.KeyPrefix,
+		Scopes:               k.GetScopes(),
+		AllowedIPs:           allowedIPs,
+		RateLimit:            k.RateLimit,
+		PreviousKeyExpiresAt: k.PreviousKeyExpiresAt,
+		RotatedAt:            k.RotatedAt,
+		LastUsedAt:           k.LastUsedAt,
+		LastUsedIP:           k.LastUsedIP,
+		ExpiresAt:            k.ExpiresAt,
+		RevokedAt:            k.RevokedAt,
+		CreatedByID:          k.CreatedByID,
+		CreatedAt:            k.CreatedAt,
+	}
+}
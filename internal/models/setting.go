@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type SettingType string
+
+const (
+	SettingTypeString   SettingType = "string"
+	SettingTypeInt      SettingType = "int"
+	SettingTypeBool     SettingType = "bool"
+	SettingTypeDuration SettingType = "duration" // Go duration like "24h" or "90m"
+	SettingTypeEmail    SettingType = "email"
+)
+
+// SystemSetting is a value an admin changed at runtime. Settings without a row
+// use the default from the configuration; see the settings package for the
+// known keys and their types.
+type SystemSetting struct {
+	Key       string     `json:"key" gorm:"primaryKey;size:100"`
+	Value     string     `json:"value" gorm:"type:text;not null"`
+	UpdatedBy *uuid.UUID `json:"updated_by,omitempty" gorm:"type:char(36)"`
+	CreatedAt time.Time  `json:"created_at" gorm:"not null"`
+	UpdatedAt time.Time  `json:"updated_at" gorm:"not null"`
+}
@@ -0,0 +1,151 @@
+package models
+
+import (
+	"crypto/rand"
+	"math/big"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type ShortLinkPurpose string
+
+const (
+	ShortLinkPurposeVerification ShortLinkPurpose = "verification"
+	ShortLinkPurposeOffer        ShortLinkPurpose = "offer"
+	ShortLinkPurposeReminder     ShortLinkPurpose = "reminder"
+	ShortLinkPurposePaymentLink  ShortLinkPurpose = "payment_link"
+	ShortLinkPurposeOther        ShortLinkPurpose = "other"
+)
+
+const shortLinkAlphabet = "abcdefghijkmnpqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// ShortLinkCodeLength is the length of generated short link codes
+const ShortLinkCodeLength = 8
+
+// ShortLink represents a tracked short link used in outgoing emails
+type ShortLink struct {
+	ID        uuid.UUID        `json:"id" gorm:"type:char(36);primary_key"`
+	Code      string           `json:"code" gorm:"not null;uniqueIndex"`
+	TargetURL string           `json:"target_url" gorm:"type:text;not null" validate:"required,url"`
+	Purpose   ShortLinkPurpose `json:"purpose" gorm:"not null;default:'other';index"`
+
+	// Recipient information
+	RecipientEmail string     `json:"recipient_email" gorm:"index"`
+	UserID         *uuid.UUID `json:"user_id" gorm:"type:char(36);index"`
+	LeadID         *uuid.UUID `json:"lead_id" gorm:"type:char(36);index"`
+
+	// Click tracking
+	ClickCount     int        `json:"click_count" gorm:"default:0"`
+	FirstClickedAt *time.Time `json:"first_clicked_at" gorm:""`
+	LastClickedAt  *time.Time `json:"last_clicked_at" gorm:""`
+
+	ExpiresAt *time.Time `json:"expires_at" gorm:""`
+
+	CreatedAt time.Time      `json:"created_at" gorm:"not null"`
+	UpdatedAt time.Time      `json:"updated_at" gorm:"not null"`
+	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+
+	// Relationships
+	User   *User            `json:"user,omitempty" gorm:"constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
+	Lead   *Lead            `json:"lead,omitempty" gorm:"constraint:OnUpdate:CASCADE,OnDelete:SET NULL;"`
+	Clicks []ShortLinkClick `json:"clicks,omitempty" gorm:"foreignKey:ShortLinkID"`
+}
+
+// ShortLinkClick represents a single click on a short link
+type ShortLinkClick struct {
+	ID          uuid.UUID `json:"id" gorm:"type:char(36);primary_key"`
+	ShortLinkID uuid.UUID `json:"short_link_id" gorm:"type:char(36);not null;index"`
+
+	IPAddress string `json:"ip_address" gorm:""`
+	UserAgent string `json:"user_agent" gorm:"type:text"`
+	Referer   string `json:"referer" gorm:""`
+
+	ClickedAt time.Time `json:"clicked_at" gorm:"not null;index"`
+
+	// Relationships
+	ShortLink ShortLink `json:"-" gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+}
+
+// BeforeCreate is a GORM hook that runs before creating a short link
+func (sl *ShortLink) BeforeCreate(tx *gorm.DB) error {
+	if sl.ID == uuid.Nil {
+		sl.ID = uuid.New()
+	}
+	if sl.Code == "" {
+		code, err := GenerateShortLinkCode(ShortLinkCodeLength)
+		if err != nil {
+			return err
+		}
+		sl.Code = code
+	}
+	if sl.Purpose == "" {
+		sl.Purpose = ShortLinkPurposeOther
+	}
+	return nil
+}
+
+// BeforeCreate is a GORM hook that runs before creating a short link click
+func (slc *ShortLinkClick) BeforeCreate(tx *gorm.DB) error {
+	if slc.ID == uuid.Nil {
+		slc.ID = uuid.New()
+	}
+	if slc.ClickedAt.IsZero() {
+		slc.ClickedAt = time.Now()
+	}
+	return nil
+}
+
+// IsExpired checks if the short link can no longer be used
+func (sl *ShortLink) IsExpired() bool {
+	return sl.ExpiresAt != nil && time.Now().After(*sl.ExpiresAt)
+}
+
+// GetEngagementScore returns the lead score points awarded for the first click on a link
+func (p ShortLinkPurpose) GetEngagementScore() int {
+	switch p {
+	case ShortLinkPurposePaymentLink:
+		return 15
+	case ShortLinkPurposeOffer:
+		return 10
+	case ShortLinkPurposeReminder:
+		return 5
+	case ShortLinkPurposeVerification:
+		return 3
+	default:
+		return 1
+	}
+}
+
+// GetDisplayName returns a human-readable display name for the short link purpose
+func (p ShortLinkPurpose) GetDisplayName() string {
+	switch p {
+	case ShortLinkPurposeVerification:
+		return "E-Mail-Bestätigung"
+	case ShortLinkPurposeOffer:
+		return "Angebot"
+	case ShortLinkPurposeReminder:
+		return "Erinnerung"
+	case ShortLinkPurposePaymentLink:
+		return "Zahlungslink"
+	case ShortLinkPurposeOther:
+		return "Sonstiges"
+	default:
+		return string(p)
+	}
+}
+
+// GenerateShortLinkCode generates a random code without ambiguous characters (0/O, 1/l/I)
+func GenerateShortLinkCode(length int) (string, error) {
+	code := make([]byte, length)
+	max := big.NewInt(int64(len(shortLinkAlphabet)))
+	for i := range code {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		code[i] = shortLinkAlphabet[n.Int64()]
+	}
+	return string(code), nil
+}
@@ -0,0 +1,42 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// SnippetScope decides who can use a snippet
+type SnippetScope string
+
+const (
+	SnippetScopePersonal SnippetScope = "personal"
+	SnippetScopeTeam     SnippetScope = "team"
+)
+
+// Snippet is a canned response Berater insert when replying to contact forms
+// and lead emails. The body may contain placeholders like {{customer_name}}
+// that are filled in when the snippet is used.
+type Snippet struct {
+	ID       uuid.UUID    `json:"id" gorm:"type:char(36);primary_key"`
+	OwnerID  uuid.UUID    `json:"owner_id" gorm:"type:char(36);not null;index"`
+	Title    string       `json:"title" gorm:"size:100;not null"`
+	Body     string       `json:"body" gorm:"type:text;not null"`
+	Category string       `json:"category" gorm:"size:50;index"`
+	Scope    SnippetScope `json:"scope" gorm:"size:10;not null;index"`
+
+	CreatedAt time.Time      `json:"created_at" gorm:"not null"`
+	UpdatedAt time.Time      `json:"updated_at" gorm:"not null"`
+	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+
+	// Relationships
+	Owner User `json:"owner,omitempty" gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+}
+
+func (s *Snippet) BeforeCreate(tx *gorm.DB) error {
+	if s.ID == uuid.Nil {
+		s.ID = uuid.New()
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package models
+
+// Specialization is a topic a Berater is experienced in and a lead can require
+type Specialization string
+
+const (
+	SpecializationSelbststaendige Specialization = "selbststaendige"
+	SpecializationBeamte          Specialization = "beamte"
+	SpecializationZwillinge       Specialization = "zwillinge"
+	SpecializationWiderspruch     Specialization = "widerspruch"
+)
+
+// Specializations lists all known specialization tags
+var Specializations = []Specialization{
+	SpecializationSelbststaendige,
+	SpecializationBeamte,
+	SpecializationZwillinge,
+	SpecializationWiderspruch,
+}
+
+// IsValid checks if the specialization is a known tag
+func (s Specialization) IsValid() bool {
+	for _, specialization := range Specializations {
+		if s == specialization {
+			return true
+		}
+	}
+	return false
+}
+
+func (s Specialization) GetDisplayName() string {
+	switch s {
+	case SpecializationSelbststaendige:
+		return "Selbstständige"
+	case SpecializationBeamte:
+		return "Beamte"
+	case SpecializationZwillinge:
+		return "Zwillinge & Mehrlinge"
+	case SpecializationWiderspruch:
+		return "Widerspruch"
+	default:
+		return "Unbekannt"
+	}
+}
+
+// GetTopics returns the specializations a lead's case requires
+func (l *Lead) GetTopics() []Specialization {
+	return toSpecializations(decodeStringList(l.Topics))
+}
+
+// SetTopics stores the specializations a lead's case requires
+func (l *Lead) SetTopics(topics []Specialization) {
+	l.Topics = encodeStringList(fromSpecializations(topics))
+}
+
+func toSpecializations(values []string) []Specialization {
+	result := make([]Specialization, 0, len(values))
+	for _, value := range values {
+		result = append(result, Specialization(value))
+	}
+	return result
+}
+
+func fromSpecializations(values []Specialization) []string {
+	result := make([]string, 0, len(values))
+	for _, value := range values {
+		result = append(result, string(value))
+	}
+	return result
+}
@@ -0,0 +1,131 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// SubmissionEndpoint names the public endpoint a checked submission was sent to
+type SubmissionEndpoint string
+
+const (
+	SubmissionEndpointContact  SubmissionEndpoint = "contact"
+	SubmissionEndpointRegister SubmissionEndpoint = "register"
+)
+
+// SubmissionReason explains why a submission scored as suspicious
+type SubmissionReason string
+
+const (
+	SubmissionReasonIPBurst         SubmissionReason = "ip_burst"         // several submissions to the same endpoint from one IP within minutes
+	SubmissionReasonIPVelocity      SubmissionReason = "ip_velocity"      // many submissions to any public endpoint from one IP within an hour
+	SubmissionReasonEmailRepeat     SubmissionReason = "email_repeat"     // the same email address was submitted repeatedly
+	SubmissionReasonDisposableEmail SubmissionReason = "disposable_email" // the email address belongs to a disposable email provider
+)
+
+// SubmissionReviewStatus tracks flagged submissions through the review queue
+type SubmissionReviewStatus string
+
+const (
+	SubmissionReviewPending  SubmissionReviewStatus = "pending"
+	SubmissionReviewApproved SubmissionReviewStatus = "approved"
+	SubmissionReviewRejected SubmissionReviewStatus = "rejected"
+)
+
+// IsValid checks if the review status exists
+func (s SubmissionReviewStatus) IsValid() bool {
+	switch s {
+	case SubmissionReviewPending, SubmissionReviewApproved, SubmissionReviewRejected:
+		return true
+	}
+	return false
+}
+
+// SubmissionCheck records the anomaly score of a submission to a public
+// endpoint. Passed submissions are kept for a while to measure bursts and
+// velocity; flagged ones wait in the review queue until an admin approves or
+// rejects them.
+type SubmissionCheck struct {
+	ID        uuid.UUID          `json:"id" gorm:"type:char(36);primary_key"`
+	Endpoint  SubmissionEndpoint `json:"endpoint" gorm:"size:20;not null;index"`
+	IPAddress string             `json:"ip_address" gorm:"size:45;index"`
+	Email     string             `json:"email" gorm:"size:255;index"`
+	Score     int                `json:"score" gorm:"not null"`
+	Reasons   string             `json:"-" gorm:"type:text"` // comma-separated SubmissionReason values
+
+	// Empty for submissions that passed the check
+	ReviewStatus  SubmissionReviewStatus `json:"review_status" gorm:"size:20;index"`
+	ContactFormID *uuid.UUID             `json:"contact_form_id" gorm:"type:char(36);index"`
+	UserID        *uuid.UUID             `json:"user_id" gorm:"type:char(36);index"`
+	ReviewedByID  *uuid.UUID             `json:"reviewed_by_id" gorm:"type:char(36)"`
+	ReviewedAt    *time.Time             `json:"reviewed_at" gorm:""`
+
+	CreatedAt time.Time `json:"created_at" gorm:"not null;index"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"not null"`
+}
+
+// SubmissionCheckResponse is returned in the review queue
+type SubmissionCheckResponse struct {
+	ID            uuid.UUID              `json:"id"`
+	Endpoint      SubmissionEndpoint     `json:"endpoint"`
+	IPAddress     string                 `json:"ip_address"`
+	Email         string                 `json:"email"`
+	Score         int                    `json:"score"`
+	Reasons       []SubmissionReason     `json:"reasons"`
+	ReviewStatus  SubmissionReviewStatus `json:"review_status"`
+	ContactFormID *uuid.UUID             `json:"contact_form_id"`
+	UserID        *uuid.UUID             `json:"user_id"`
+	ReviewedByID  *uuid.UUID             `json:"reviewed_by_id"`
+	ReviewedAt    *time.Time             `json:"reviewed_at"`
+	CreatedAt     time.Time              `json:"created_at"`
+}
+
+func (s *SubmissionCheck) BeforeCreate(tx *gorm.DB) error {
+	if s.ID == uuid.Nil {
+		s.ID = uuid.New()
+	}
+	return nil
+}
+
+// GetReasons returns why the submission scored as suspicious
+func (s *SubmissionCheck) GetReasons() []SubmissionReason {
+	reasons := []SubmissionReason{}
+	for _, reason := range splitList(s.Reasons) {
+		reasons = append(reasons, SubmissionReason(reason))
+	}
+	return reasons
+}
+
+// SetReasons stores why the submission scored as suspicious
+func (s *SubmissionCheck) SetReasons(reasons []SubmissionReason) {
+	values := make([]string, len(reasons))
+	for i, reason := range reasons {
+		values[i] = string(reason)
+	}
+	s.Reasons = strings.Join(values, ",")
+}
+
+// IsFlagged reports whether the submission was routed into the review queue
+func (s *SubmissionCheck) IsFlagged() bool {
+	return s.ReviewStatus != ""
+}
+
+func (s *SubmissionCheck) ToResponse() SubmissionCheckResponse {
+	return SubmissionCheckResponse{
+		ID:            s.ID,
+		Endpoint:      s.Endpoint,
+		IPAddress:     s.IPAddress,
+		Email:         s.Email,
+		Score:         s.Score,
+		Reasons:       s.GetReasons(),
+		ReviewStatus:  s.ReviewStatus,
+		ContactFormID: s.ContactFormID,
+		UserID:        s.UserID,
+		ReviewedByID:  s.ReviewedByID,
+		ReviewedAt:    s.ReviewedAt,
+		CreatedAt:     s.CreatedAt,
+	}
+}
@@ -0,0 +1,46 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// HoldReleaseReason records why a timeslot hold ended
+type HoldReleaseReason string
+
+const (
+	HoldReleasePaid      HoldReleaseReason = "paid"
+	HoldReleaseExpired   HoldReleaseReason = "expired"
+	HoldReleaseCancelled HoldReleaseReason = "cancelled"
+)
+
+// TimeslotHold reserves a place on a timeslot for a pending booking during
+// checkout. The place counts as taken until the hold is released or expires;
+// expired holds cancel their booking if it is still unpaid.
+type TimeslotHold struct {
+	ID         uuid.UUID `json:"id" gorm:"type:char(36);primary_key"`
+	TimeslotID uuid.UUID `json:"timeslot_id" gorm:"type:char(36);not null;index"`
+	BookingID  uuid.UUID `json:"booking_id" gorm:"type:char(36);not null;uniqueIndex"`
+	UserID     uuid.UUID `json:"user_id" gorm:"type:char(36);not null"`
+
+	ExpiresAt     time.Time         `json:"expires_at" gorm:"not null;index"`
+	ReleasedAt    *time.Time        `json:"released_at,omitempty" gorm:"index"`
+	ReleaseReason HoldReleaseReason `json:"release_reason,omitempty" gorm:"size:20"`
+
+	CreatedAt time.Time `json:"created_at" gorm:"not null"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"not null"`
+}
+
+func (h *TimeslotHold) BeforeCreate(tx *gorm.DB) error {
+	if h.ID == uuid.Nil {
+		h.ID = uuid.New()
+	}
+	return nil
+}
+
+// IsActive checks if the hold still reserves its place at the given time
+func (h *TimeslotHold) IsActive(at time.Time) bool {
+	return h.ReleasedAt == nil && at.Before(h.ExpiresAt)
+}
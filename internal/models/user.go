@@ -1,8 +1,14 @@
 package models
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"time"
 
+	// registers the encrypted GORM serializer
+	_ "elterngeld-portal/pkg/encryption"
+	"elterngeld-portal/pkg/timeutil"
+
 	"github.com/google/uuid"
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
@@ -29,10 +35,46 @@ type User struct {
 	IsActive  bool      `json:"is_active" gorm:"not null;default:true"`
 
 	// Profile information
-	DateOfBirth *time.Time `json:"date_of_birth" gorm:""`
-	Address     string     `json:"address" gorm:""`
+	DateOfBirth *time.Time `json:"date_of_birth" gorm:"type:text;serializer:encrypted"`
+	Address     string     `json:"address" gorm:"type:text;serializer:encrypted"`
 	PostalCode  string     `json:"postal_code" gorm:""`
 	City        string     `json:"city" gorm:""`
+	Bundesland  Bundesland `json:"bundesland" gorm:"size:2" validate:"omitempty,oneof=BW BY BE BB HB HH HE MV NI NW RP SL SN ST SH TH"` // decides which public holidays apply to a Berater
+	Language    string     `json:"language" gorm:"size:5;not null;default:'de'" validate:"omitempty,oneof=de en"`
+	Timezone    string     `json:"timezone" gorm:"size:64;not null;default:'Europe/Berlin'" validate:"omitempty,timezone"`
+	SpokenLanguages string `json:"-" gorm:"type:text"` // JSON array of languages the customer can hold a consultation in, preferred first
+
+	// Notifications go to NotificationEmail when set, account emails always to Email
+	NotificationEmail string `json:"notification_email" gorm:"size:255" validate:"omitempty,email"`
+
+	// Occupation before the birth, needed by the Elterngeld calculator
+	Profession     string         `json:"profession" gorm:"size:100" validate:"omitempty,max=100"`
+	EmploymentType EmploymentType `json:"employment_type" gorm:"size:20" validate:"omitempty,oneof=angestellt selbststaendig beamtet mischeinkuenfte minijob ohne_einkommen"`
+
+	// Berater profile (used to personalize customer emails and on the public profile)
+	EmailSignature string `json:"email_signature" gorm:"type:text"`
+	PhotoURL       string `json:"photo_url" gorm:""`
+	AvatarPath     string `json:"-" gorm:""` // uploaded avatar, PhotoURL points at it
+	CalendarURL    string `json:"calendar_url" gorm:""`
+	MeetingURL     string `json:"meeting_url" gorm:""` // personal online meeting room, used for bookings reassigned to the Berater
+	Bio            string `json:"bio" gorm:"type:text"`
+
+	// Booking limits of a Berater, enforced when offering and booking timeslots
+	BookingBufferMinutes int `json:"booking_buffer_minutes" gorm:"not null;default:0"` // free minutes required between two appointments
+	MaxBookingsPerDay    int `json:"max_bookings_per_day" gorm:"not null;default:0"`   // 0 = no limit
+
+	// Berater onboarding; only approved Beraters can be booked
+	OnboardingStatus     OnboardingStatus `json:"onboarding_status" gorm:"size:20;not null;default:'approved'"`
+	Specializations      string           `json:"-" gorm:"type:text"` // JSON array of Specialization tags
+	ServiceBundeslaender string           `json:"-" gorm:"type:text"` // JSON array of Bundesländer the Berater serves
+	WorkingLanguages     string           `json:"-" gorm:"type:text"` // JSON array of language codes
+	ApprovedAt           *time.Time       `json:"approved_at" gorm:""`
+	ApprovedBy           *uuid.UUID       `json:"approved_by" gorm:"type:char(36)"`
+
+	// Offboarding; deactivated users can no longer log in
+	DeactivatedAt      *time.Time `json:"deactivated_at" gorm:"index"`
+	DeactivatedBy      *uuid.UUID `json:"deactivated_by" gorm:"type:char(36)"`
+	DeactivationReason string     `json:"deactivation_reason" gorm:"type:text"`
 
 	// Timestamps
 	CreatedAt time.Time      `json:"created_at" gorm:"not null"`
@@ -42,6 +84,8 @@ type User struct {
 	// Email verification
 	EmailVerified   bool       `json:"email_verified" gorm:"not null;default:false"`
 	EmailVerifiedAt *time.Time `json:"email_verified_at" gorm:""`
+	EmailStatus     EmailStatus `json:"email_status" gorm:"size:20;index"` // checked in the background for disposable and undeliverable domains
+	EmailCheckedAt  *time.Time  `json:"email_checked_at" gorm:""`
 
 	// Password reset
 	ResetToken    string     `json:"-" gorm:""`
@@ -103,6 +147,32 @@ type UserResponse struct {
 	PostalCode    string     `json:"postal_code"`
 	City          string     `json:"city"`
 	EmailVerified bool       `json:"email_verified"`
+	EmailStatus   EmailStatus `json:"email_status"`
+	Bundesland    Bundesland `json:"bundesland,omitempty"`
+	Language      string     `json:"language"`
+	Timezone      string     `json:"timezone"`
+	SpokenLanguages []string `json:"spoken_languages,omitempty"`
+
+	NotificationEmail string         `json:"notification_email,omitempty"`
+	Profession        string         `json:"profession,omitempty"`
+	EmploymentType    EmploymentType `json:"employment_type,omitempty"`
+
+	EmailSignature string `json:"email_signature,omitempty"`
+	PhotoURL       string `json:"photo_url,omitempty"`
+	CalendarURL    string `json:"calendar_url,omitempty"`
+	MeetingURL     string `json:"meeting_url,omitempty"`
+	Bio            string `json:"bio,omitempty"`
+
+	BookingBufferMinutes int `json:"booking_buffer_minutes,omitempty"`
+	MaxBookingsPerDay    int `json:"max_bookings_per_day,omitempty"`
+
+	OnboardingStatus     OnboardingStatus `json:"onboarding_status,omitempty"`
+	Specializations      []Specialization `json:"specializations,omitempty"`
+	ServiceBundeslaender []Bundesland     `json:"service_bundeslaender,omitempty"`
+	WorkingLanguages     []string         `json:"working_languages,omitempty"`
+
+	DeactivatedAt *time.Time `json:"deactivated_at,omitempty"`
+
 	CreatedAt     time.Time  `json:"created_at"`
 	UpdatedAt     time.Time  `json:"updated_at"`
 }
@@ -126,6 +196,19 @@ type UpdateUserRequest struct {
 	Address     *string    `json:"address"`
 	PostalCode  *string    `json:"postal_code"`
 	City        *string    `json:"city"`
+	Bundesland  *Bundesland `json:"bundesland" validate:"omitempty,oneof=BW BY BE BB HB HH HE MV NI NW RP SL SN ST SH TH"`
+	Language    *string    `json:"language" validate:"omitempty,oneof=de en"`
+	Timezone    *string    `json:"timezone" validate:"omitempty,timezone"`
+
+	NotificationEmail *string         `json:"notification_email" validate:"omitempty,email"`
+	Profession        *string         `json:"profession" validate:"omitempty,max=100"`
+	EmploymentType    *EmploymentType `json:"employment_type" validate:"omitempty,oneof=angestellt selbststaendig beamtet mischeinkuenfte minijob ohne_einkommen"`
+
+	EmailSignature *string `json:"email_signature"`
+	PhotoURL       *string `json:"photo_url" validate:"omitempty,url"`
+	CalendarURL    *string `json:"calendar_url" validate:"omitempty,url"`
+	MeetingURL     *string `json:"meeting_url" validate:"omitempty,url"`
+	Bio            *string `json:"bio" validate:"omitempty,max=2000"`
 }
 
 // ChangePasswordRequest represents the request body for changing password
@@ -142,6 +225,20 @@ func (u *User) BeforeCreate(tx *gorm.DB) error {
 	return u.HashPassword()
 }
 
+// BeforeCreate is a GORM hook that runs before creating a refresh token
+func (rt *RefreshToken) BeforeCreate(tx *gorm.DB) error {
+	if rt.ID == uuid.Nil {
+		rt.ID = uuid.New()
+	}
+	return nil
+}
+
+// HashRefreshToken returns the stored representation of a refresh token
+func HashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
 // HashPassword hashes the user's password
 func (u *User) HashPassword() error {
 	if u.Password == "" {
@@ -178,9 +275,47 @@ func (u *User) ToResponse() UserResponse {
 		PostalCode:    u.PostalCode,
 		City:          u.City,
 		EmailVerified: u.EmailVerified,
+		EmailStatus:   u.EmailStatus,
+		Bundesland:    u.Bundesland,
+		Language:      u.Language,
+		Timezone:      u.Timezone,
+		SpokenLanguages: u.GetSpokenLanguages(),
 		CreatedAt:     u.CreatedAt,
 		UpdatedAt:     u.UpdatedAt,
+
+		NotificationEmail: u.NotificationEmail,
+		Profession:        u.Profession,
+		EmploymentType:    u.EmploymentType,
+
+		EmailSignature: u.EmailSignature,
+		PhotoURL:       u.PhotoURL,
+		CalendarURL:    u.CalendarURL,
+		MeetingURL:     u.MeetingURL,
+		Bio:            u.Bio,
+
+		BookingBufferMinutes: u.BookingBufferMinutes,
+		MaxBookingsPerDay:    u.MaxBookingsPerDay,
+
+		OnboardingStatus:     u.OnboardingStatus,
+		Specializations:      u.GetSpecializations(),
+		ServiceBundeslaender: u.GetServiceBundeslaender(),
+		WorkingLanguages:     u.GetWorkingLanguages(),
+
+		DeactivatedAt: u.DeactivatedAt,
+	}
+}
+
+// NotificationAddress returns the address notifications are sent to
+func (u *User) NotificationAddress() string {
+	if u.NotificationEmail != "" {
+		return u.NotificationEmail
 	}
+	return u.Email
+}
+
+// IsDeactivated checks if the user was offboarded
+func (u *User) IsDeactivated() bool {
+	return u.DeactivatedAt != nil
 }
 
 // FullName returns the user's full name
@@ -188,6 +323,11 @@ func (u *User) FullName() string {
 	return u.FirstName + " " + u.LastName
 }
 
+// TimeLocation returns the user's timezone, falling back to the default timezone
+func (u *User) TimeLocation() *time.Location {
+	return timeutil.LoadLocation(u.Timezone)
+}
+
 // IsAdmin checks if the user has admin role
 func (u *User) IsAdmin() bool {
 	return u.Role == RoleAdmin
@@ -0,0 +1,45 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type WebhookEventStatus string
+
+const (
+	WebhookEventStatusPending    WebhookEventStatus = "pending"
+	WebhookEventStatusProcessing WebhookEventStatus = "processing"
+	WebhookEventStatusProcessed  WebhookEventStatus = "processed"
+	WebhookEventStatusFailed     WebhookEventStatus = "failed"
+)
+
+// WebhookEvent is a verified delivery from a third-party integration. The raw
+// payload is stored before processing so events can be retried and replayed.
+type WebhookEvent struct {
+	ID        uuid.UUID `json:"id" gorm:"type:char(36);primary_key"`
+	Provider  string    `json:"provider" gorm:"size:50;not null;uniqueIndex:idx_webhook_events_provider_event"`
+	EventID   string    `json:"event_id" gorm:"size:255;not null;uniqueIndex:idx_webhook_events_provider_event"` // provider's ID, used to drop redeliveries
+	EventType string    `json:"event_type" gorm:"size:100;index"`
+	Payload   string    `json:"-" gorm:"type:text;not null"`
+
+	// Processing
+	Status        WebhookEventStatus `json:"status" gorm:"size:20;not null;index"`
+	Attempts      int                `json:"attempts" gorm:"not null"`
+	LastError     string             `json:"last_error,omitempty" gorm:"type:text"`
+	NextAttemptAt *time.Time         `json:"next_attempt_at,omitempty" gorm:"index"`
+	ProcessedAt   *time.Time         `json:"processed_at,omitempty"`
+
+	ReceivedAt time.Time `json:"received_at" gorm:"not null"`
+	CreatedAt  time.Time `json:"created_at" gorm:"not null"`
+	UpdatedAt  time.Time `json:"updated_at" gorm:"not null"`
+}
+
+func (e *WebhookEvent) BeforeCreate(tx *gorm.DB) error {
+	if e.ID == uuid.Nil {
+		e.ID = uuid.New()
+	}
+	return nil
+}
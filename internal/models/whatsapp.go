@@ -0,0 +1,91 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type WhatsAppConsentSource string
+
+const (
+	WhatsAppConsentSourcePortal   WhatsAppConsentSource = "portal"   // set by the customer in their settings
+	WhatsAppConsentSourceWhatsApp WhatsAppConsentSource = "whatsapp" // customer replied STOP
+)
+
+// WhatsAppConsent records whether a customer agreed to be contacted on WhatsApp
+// and with which number. The timestamps and wording are kept as proof of the
+// opt-in after it was withdrawn.
+type WhatsAppConsent struct {
+	ID          uuid.UUID             `json:"id" gorm:"type:char(36);primary_key"`
+	UserID      uuid.UUID             `json:"user_id" gorm:"type:char(36);not null;uniqueIndex"`
+	Phone       string                `json:"phone" gorm:"size:32;not null;index"` // E.164, e.g. +4917612345678
+	OptedIn     bool                  `json:"opted_in" gorm:"not null"`
+	Source      WhatsAppConsentSource `json:"source" gorm:"size:20;not null"`
+	ConsentText string                `json:"consent_text" gorm:"type:text"` // wording the customer agreed to
+
+	OptedInAt  *time.Time `json:"opted_in_at" gorm:""`
+	OptedOutAt *time.Time `json:"opted_out_at" gorm:""`
+
+	CreatedAt time.Time `json:"created_at" gorm:"not null"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"not null"`
+
+	// Relationships
+	User User `json:"-" gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+}
+
+func (c *WhatsAppConsent) BeforeCreate(tx *gorm.DB) error {
+	if c.ID == uuid.Nil {
+		c.ID = uuid.New()
+	}
+	return nil
+}
+
+type WhatsAppDirection string
+
+const (
+	WhatsAppDirectionInbound  WhatsAppDirection = "inbound"
+	WhatsAppDirectionOutbound WhatsAppDirection = "outbound"
+)
+
+type WhatsAppMessageStatus string
+
+const (
+	WhatsAppMessageStatusReceived  WhatsAppMessageStatus = "received"
+	WhatsAppMessageStatusSent      WhatsAppMessageStatus = "sent"
+	WhatsAppMessageStatusDelivered WhatsAppMessageStatus = "delivered"
+	WhatsAppMessageStatusRead      WhatsAppMessageStatus = "read"
+	WhatsAppMessageStatusFailed    WhatsAppMessageStatus = "failed"
+)
+
+// WhatsAppMessage is a message of the WhatsApp conversation with a customer,
+// shown on the timeline of their lead
+type WhatsAppMessage struct {
+	ID        uuid.UUID             `json:"id" gorm:"type:char(36);primary_key"`
+	UserID    uuid.UUID             `json:"user_id" gorm:"type:char(36);not null;index"`
+	LeadID    *uuid.UUID            `json:"lead_id" gorm:"type:char(36);index"`
+	BookingID *uuid.UUID            `json:"booking_id" gorm:"type:char(36);index"` // reminders only
+	SentBy    *uuid.UUID            `json:"sent_by" gorm:"type:char(36)"`          // Berater of outbound replies, nil for reminders
+	Direction WhatsAppDirection     `json:"direction" gorm:"size:10;not null"`
+	Phone     string                `json:"phone" gorm:"size:32;not null"`
+	Body      string                `json:"body" gorm:"type:text"`
+	Template  string                `json:"template,omitempty" gorm:"size:100"`
+	Status    WhatsAppMessageStatus `json:"status" gorm:"size:20;not null"`
+	Error     string                `json:"error,omitempty" gorm:"type:text"`
+
+	ProviderMessageID string `json:"-" gorm:"size:100;index"`
+
+	CreatedAt time.Time `json:"created_at" gorm:"not null;index"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"not null"`
+
+	// Relationships
+	User User `json:"-" gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+}
+
+func (m *WhatsAppMessage) BeforeCreate(tx *gorm.DB) error {
+	if m.ID == uuid.Nil {
+		m.ID = uuid.New()
+	}
+	return nil
+}
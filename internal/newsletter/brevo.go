@@ -0,0 +1,83 @@
+package newsletter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const brevoAPIURL = "https://api.brevo.com/v3"
+
+// brevoSegmentsAttribute is the text contact attribute holding the segments,
+// since Brevo has no contact tags. It has to be created in the Brevo account.
+const brevoSegmentsAttribute = "SEGMENTS"
+
+// Brevo syncs contacts to a Brevo (formerly Sendinblue) list
+type Brevo struct {
+	apiKey  string
+	listID  int64
+	baseURL string
+	client  *http.Client
+}
+
+func NewBrevo(apiKey, listID, baseURL string, client *http.Client) (*Brevo, error) {
+	id, err := strconv.ParseInt(listID, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("brevo list ID must be numeric: %w", err)
+	}
+	if baseURL == "" {
+		baseURL = brevoAPIURL
+	}
+
+	return &Brevo{
+		apiKey:  apiKey,
+		listID:  id,
+		baseURL: strings.TrimRight(baseURL, "/"),
+		client:  client,
+	}, nil
+}
+
+func (b *Brevo) Name() string {
+	return ProviderBrevo
+}
+
+func (b *Brevo) Subscribe(ctx context.Context, contact Contact) error {
+	segments := make([]string, len(contact.Segments))
+	for i, segment := range contact.Segments {
+		segments[i] = string(segment)
+	}
+
+	return b.request(ctx, http.MethodPost, "/contacts", map[string]interface{}{
+		"email": contact.Email,
+		"attributes": map[string]string{
+			"FIRSTNAME":            contact.FirstName,
+			"LASTNAME":             contact.LastName,
+			brevoSegmentsAttribute: strings.Join(segments, ","),
+		},
+		"listIds":          []int64{b.listID},
+		"updateEnabled":    true,
+		"emailBlacklisted": false,
+	})
+}
+
+func (b *Brevo) Unsubscribe(ctx context.Context, email string) error {
+	err := b.request(ctx, http.MethodPost, fmt.Sprintf("/contacts/lists/%d/contacts/remove", b.listID), map[string]interface{}{
+		"emails": []string{email},
+	})
+	if errors.Is(err, errContactNotFound) {
+		return nil
+	}
+	return err
+}
+
+func (b *Brevo) request(ctx context.Context, method, path string, body interface{}) error {
+	req, err := http.NewRequest(method, b.baseURL+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("api-key", b.apiKey)
+	return doJSON(ctx, b.client, req, body)
+}
@@ -0,0 +1,93 @@
+package newsletter
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Mailchimp syncs contacts to a Mailchimp audience. Segments are member tags.
+type Mailchimp struct {
+	apiKey  string
+	listID  string
+	baseURL string
+	client  *http.Client
+}
+
+// NewMailchimp creates a Mailchimp client. The API URL is derived from the data
+// center suffix of the API key unless baseURL is set.
+func NewMailchimp(apiKey, listID, baseURL string, client *http.Client) (*Mailchimp, error) {
+	if baseURL == "" {
+		i := strings.LastIndex(apiKey, "-")
+		if i < 0 || i == len(apiKey)-1 {
+			return nil, fmt.Errorf("mailchimp API key has no data center suffix")
+		}
+		baseURL = fmt.Sprintf("https://%s.api.mailchimp.com/3.0", apiKey[i+1:])
+	}
+
+	return &Mailchimp{
+		apiKey:  apiKey,
+		listID:  listID,
+		baseURL: strings.TrimRight(baseURL, "/"),
+		client:  client,
+	}, nil
+}
+
+func (m *Mailchimp) Name() string {
+	return ProviderMailchimp
+}
+
+func (m *Mailchimp) Subscribe(ctx context.Context, contact Contact) error {
+	member := map[string]interface{}{
+		"email_address": contact.Email,
+		"status_if_new": "subscribed",
+		"status":        "subscribed",
+		"language":      contact.Language,
+		"merge_fields": map[string]string{
+			"FNAME": contact.FirstName,
+			"LNAME": contact.LastName,
+		},
+	}
+	if err := m.request(ctx, http.MethodPut, m.memberURL(contact.Email), member); err != nil {
+		return err
+	}
+
+	tags := make([]map[string]string, 0, len(contact.Segments)+len(contact.Removed))
+	for _, segment := range contact.Segments {
+		tags = append(tags, map[string]string{"name": string(segment), "status": "active"})
+	}
+	for _, segment := range contact.Removed {
+		tags = append(tags, map[string]string{"name": string(segment), "status": "inactive"})
+	}
+	if len(tags) == 0 {
+		return nil
+	}
+	return m.request(ctx, http.MethodPost, m.memberURL(contact.Email)+"/tags", map[string]interface{}{"tags": tags})
+}
+
+func (m *Mailchimp) Unsubscribe(ctx context.Context, email string) error {
+	err := m.request(ctx, http.MethodPatch, m.memberURL(email), map[string]string{"status": "unsubscribed"})
+	if errors.Is(err, errContactNotFound) {
+		return nil
+	}
+	return err
+}
+
+// memberURL addresses a list member by the MD5 hash of the lowercase address
+func (m *Mailchimp) memberURL(email string) string {
+	hash := md5.Sum([]byte(strings.ToLower(strings.TrimSpace(email))))
+	return fmt.Sprintf("%s/lists/%s/members/%s", m.baseURL, m.listID, hex.EncodeToString(hash[:]))
+}
+
+func (m *Mailchimp) request(ctx context.Context, method, url string, body interface{}) error {
+	req, err := http.NewRequest(method, url, nil)
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth("elterngeld-portal", m.apiKey)
+	return doJSON(ctx, m.client, req, body)
+}
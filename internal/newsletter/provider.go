@@ -0,0 +1,95 @@
+package newsletter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"elterngeld-portal/config"
+	"elterngeld-portal/internal/models"
+)
+
+// Supported newsletter providers
+const (
+	ProviderMailchimp = "mailchimp"
+	ProviderBrevo     = "brevo"
+)
+
+// errContactNotFound is returned by clients when the provider does not know a contact
+var errContactNotFound = errors.New("contact not found")
+
+// Contact is a newsletter subscriber
+type Contact struct {
+	Email     string
+	FirstName string
+	LastName  string
+	Language  string
+	Segments  []models.NewsletterSegment
+	// Removed are segments the contact was tagged with before and no longer has
+	Removed []models.NewsletterSegment
+}
+
+// Provider subscribes and unsubscribes contacts on the newsletter list
+type Provider interface {
+	Name() string
+	// Subscribe adds or updates a contact on the list, including its segments
+	Subscribe(ctx context.Context, contact Contact) error
+	// Unsubscribe removes an address from the list; unknown addresses are no error
+	Unsubscribe(ctx context.Context, email string) error
+}
+
+// NewProvider creates the client for the configured provider. It returns nil when
+// no provider is configured, which disables the newsletter sync.
+func NewProvider(cfg *config.Config) (Provider, error) {
+	provider := strings.ToLower(strings.TrimSpace(cfg.Newsletter.Provider))
+	if provider == "" {
+		return nil, nil
+	}
+	if cfg.Newsletter.APIKey == "" || cfg.Newsletter.ListID == "" {
+		return nil, fmt.Errorf("NEWSLETTER_API_KEY and NEWSLETTER_LIST_ID are required for provider %q", provider)
+	}
+
+	client := &http.Client{Timeout: cfg.Newsletter.Timeout}
+	switch provider {
+	case ProviderMailchimp:
+		return NewMailchimp(cfg.Newsletter.APIKey, cfg.Newsletter.ListID, cfg.Newsletter.APIURL, client)
+	case ProviderBrevo:
+		return NewBrevo(cfg.Newsletter.APIKey, cfg.Newsletter.ListID, cfg.Newsletter.APIURL, client)
+	default:
+		return nil, fmt.Errorf("unknown newsletter provider %q", cfg.Newsletter.Provider)
+	}
+}
+
+// doJSON sends a JSON request and fails for unsuccessful status codes
+func doJSON(ctx context.Context, client *http.Client, req *http.Request, body interface{}) error {
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(payload))
+		req.ContentLength = int64(len(payload))
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("newsletter request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return errContactNotFound
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("newsletter provider returned %d: %s", resp.StatusCode, strings.TrimSpace(string(detail)))
+	}
+	return nil
+}
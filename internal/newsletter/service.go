@@ -0,0 +1,289 @@
+package newsletter
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"elterngeld-portal/internal/models"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// ErrUnknownContact is returned for unsubscribe webhooks of addresses without an account
+var ErrUnknownContact = errors.New("newsletter contact not found")
+
+// Service syncs users who opted into marketing emails to the newsletter list of
+// the provider and propagates withdrawn consent in both directions
+type Service struct {
+	db       *gorm.DB
+	logger   *zap.Logger
+	provider Provider
+	now      func() time.Time
+}
+
+// NewService creates the newsletter sync; a nil provider disables syncing, while
+// unsubscribe webhooks are still applied
+func NewService(db *gorm.DB, logger *zap.Logger, provider Provider) *Service {
+	return &Service{
+		db:       db,
+		logger:   logger,
+		provider: provider,
+		now:      time.Now,
+	}
+}
+
+// SyncResult summarizes a sync run
+type SyncResult struct {
+	Subscribed   int `json:"subscribed"`
+	Unsubscribed int `json:"unsubscribed"`
+	Failed       int `json:"failed"`
+}
+
+// Sync subscribes consenting customers with their current segments and
+// unsubscribes contacts whose consent was withdrawn. Contacts that are up to
+// date are not sent again.
+func (s *Service) Sync(ctx context.Context) (*SyncResult, error) {
+	result := &SyncResult{}
+	if s.provider == nil {
+		return result, nil
+	}
+
+	var users []models.User
+	if err := s.db.Joins("JOIN notification_preferences ON notification_preferences.user_id = users.id AND notification_preferences.deleted_at IS NULL").
+		Where("notification_preferences.email_marketing_notifications = ?", true).
+		Where("users.role = ? AND users.is_active = ? AND users.email_verified = ?", models.RoleUser, true, true).
+		Find(&users).Error; err != nil {
+		return nil, err
+	}
+
+	userIDs := make([]uuid.UUID, len(users))
+	for i, user := range users {
+		userIDs[i] = user.ID
+	}
+	segments, err := s.segments(userIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	var contacts []models.NewsletterContact
+	if err := s.db.Find(&contacts).Error; err != nil {
+		return nil, err
+	}
+	existing := make(map[uuid.UUID]*models.NewsletterContact, len(contacts))
+	for i := range contacts {
+		existing[contacts[i].UserID] = &contacts[i]
+	}
+
+	consenting := make(map[uuid.UUID]bool, len(users))
+	for i := range users {
+		user := &users[i]
+		consenting[user.ID] = true
+
+		contact := existing[user.ID]
+		if contact == nil {
+			contact = &models.NewsletterContact{UserID: user.ID}
+		}
+		synced, err := s.subscribe(ctx, user, contact, segments[user.ID])
+		if err != nil {
+			result.Failed++
+		} else if synced {
+			result.Subscribed++
+		}
+	}
+
+	// Consent was withdrawn in the portal or the account was closed
+	for i := range contacts {
+		contact := &contacts[i]
+		if consenting[contact.UserID] || contact.Status != models.NewsletterContactStatusSubscribed {
+			continue
+		}
+		if err := s.unsubscribe(ctx, contact); err != nil {
+			result.Failed++
+		} else {
+			result.Unsubscribed++
+		}
+	}
+
+	return result, nil
+}
+
+// subscribe sends a contact to the provider unless it is already up to date
+func (s *Service) subscribe(ctx context.Context, user *models.User, contact *models.NewsletterContact, segments []models.NewsletterSegment) (bool, error) {
+	previous := contact.GetSegments()
+	if contact.Status == models.NewsletterContactStatusSubscribed && contact.SyncedAt != nil &&
+		contact.Provider == s.provider.Name() && strings.EqualFold(contact.Email, user.Email) &&
+		sameSegments(previous, segments) {
+		return false, nil
+	}
+
+	// The customer changed their address since the last sync
+	if contact.Email != "" && !strings.EqualFold(contact.Email, user.Email) && contact.Status == models.NewsletterContactStatusSubscribed {
+		if err := s.provider.Unsubscribe(ctx, contact.Email); err != nil {
+			return false, s.recordError(contact, err)
+		}
+	}
+
+	err := s.provider.Subscribe(ctx, Contact{
+		Email:     user.Email,
+		FirstName: user.FirstName,
+		LastName:  user.LastName,
+		Language:  user.Language,
+		Segments:  segments,
+		Removed:   removedSegments(previous, segments),
+	})
+	if err != nil {
+		return false, s.recordError(contact, err)
+	}
+
+	now := s.now()
+	contact.Email = user.Email
+	contact.Provider = s.provider.Name()
+	contact.Status = models.NewsletterContactStatusSubscribed
+	contact.SetSegments(segments)
+	contact.SyncedAt = &now
+	contact.UnsubscribedAt = nil
+	contact.LastError = ""
+	if err := s.db.Save(contact).Error; err != nil {
+		s.logger.Error("Failed to save newsletter contact", zap.String("user_id", user.ID.String()), zap.Error(err))
+		return false, err
+	}
+	return true, nil
+}
+
+func (s *Service) unsubscribe(ctx context.Context, contact *models.NewsletterContact) error {
+	if err := s.provider.Unsubscribe(ctx, contact.Email); err != nil {
+		return s.recordError(contact, err)
+	}
+
+	now := s.now()
+	return s.db.Model(contact).Updates(map[string]interface{}{
+		"status":          models.NewsletterContactStatusUnsubscribed,
+		"unsubscribed_at": now,
+		"synced_at":       now,
+		"last_error":      "",
+	}).Error
+}
+
+// recordError keeps the provider error on the contact; new contacts are not stored
+func (s *Service) recordError(contact *models.NewsletterContact, err error) error {
+	s.logger.Warn("Failed to sync newsletter contact",
+		zap.String("user_id", contact.UserID.String()),
+		zap.String("provider", s.provider.Name()),
+		zap.Error(err))
+	if contact.ID != uuid.Nil {
+		if dbErr := s.db.Model(contact).UpdateColumn("last_error", err.Error()).Error; dbErr != nil {
+			s.logger.Warn("Failed to record newsletter sync error", zap.Error(dbErr))
+		}
+	}
+	return err
+}
+
+// segments tags the users by funnel stage: customers have paid, lost leads had
+// their case cancelled without paying, and pre-talk done had a free consultation
+func (s *Service) segments(userIDs []uuid.UUID) (map[uuid.UUID][]models.NewsletterSegment, error) {
+	result := make(map[uuid.UUID][]models.NewsletterSegment, len(userIDs))
+	if len(userIDs) == 0 {
+		return result, nil
+	}
+
+	var preTalk, customers, lost []uuid.UUID
+	if err := s.db.Model(&models.Booking{}).Distinct("user_id").
+		Where("user_id IN ? AND type = ? AND status = ?", userIDs, models.BookingTypePreTalk, models.BookingStatusCompleted).
+		Pluck("user_id", &preTalk).Error; err != nil {
+		return nil, err
+	}
+	if err := s.db.Model(&models.Payment{}).Distinct("user_id").
+		Where("user_id IN ? AND status = ?", userIDs, models.PaymentStatusSucceeded).
+		Pluck("user_id", &customers).Error; err != nil {
+		return nil, err
+	}
+	if err := s.db.Model(&models.Lead{}).Distinct("user_id").
+		Where("user_id IN ? AND status = ?", userIDs, models.LeadStatusCancelled).
+		Pluck("user_id", &lost).Error; err != nil {
+		return nil, err
+	}
+
+	isCustomer := make(map[uuid.UUID]bool, len(customers))
+	for _, id := range preTalk {
+		result[id] = append(result[id], models.NewsletterSegmentPreTalkDone)
+	}
+	for _, id := range customers {
+		isCustomer[id] = true
+		result[id] = append(result[id], models.NewsletterSegmentCustomer)
+	}
+	for _, id := range lost {
+		if !isCustomer[id] {
+			result[id] = append(result[id], models.NewsletterSegmentLostLead)
+		}
+	}
+	return result, nil
+}
+
+// Unsubscribe withdraws the marketing consent of the user with the address,
+// e.g. after they used the unsubscribe link of a newsletter
+func (s *Service) Unsubscribe(email string) error {
+	var user models.User
+	if err := s.db.Where("LOWER(email) = ?", strings.ToLower(strings.TrimSpace(email))).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrUnknownContact
+		}
+		return err
+	}
+
+	now := s.now()
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var preference models.NotificationPreference
+		err := tx.Where("user_id = ?", user.ID).First(&preference).Error
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			preference = models.NotificationPreference{UserID: user.ID, EmailMarketingNotifications: false}
+			if err := tx.Create(&preference).Error; err != nil {
+				return err
+			}
+		case err != nil:
+			return err
+		default:
+			if err := tx.Model(&preference).Update("email_marketing_notifications", false).Error; err != nil {
+				return err
+			}
+		}
+
+		return tx.Model(&models.NewsletterContact{}).Where("user_id = ?", user.ID).Updates(map[string]interface{}{
+			"status":          models.NewsletterContactStatusUnsubscribed,
+			"unsubscribed_at": now,
+		}).Error
+	})
+}
+
+func sameSegments(a, b []models.NewsletterSegment) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func removedSegments(previous, current []models.NewsletterSegment) []models.NewsletterSegment {
+	var removed []models.NewsletterSegment
+	for _, segment := range previous {
+		found := false
+		for _, c := range current {
+			if c == segment {
+				found = true
+				break
+			}
+		}
+		if !found {
+			removed = append(removed, segment)
+		}
+	}
+	return removed
+}
@@ -0,0 +1,352 @@
+package newsletter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"elterngeld-portal/config"
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/tests/testutils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// fakeProvider records the calls of the sync
+type fakeProvider struct {
+	subscribed   []Contact
+	unsubscribed []string
+	err          error
+}
+
+func (p *fakeProvider) Name() string { return ProviderMailchimp }
+
+func (p *fakeProvider) Subscribe(ctx context.Context, contact Contact) error {
+	if p.err != nil {
+		return p.err
+	}
+	p.subscribed = append(p.subscribed, contact)
+	return nil
+}
+
+func (p *fakeProvider) Unsubscribe(ctx context.Context, email string) error {
+	if p.err != nil {
+		return p.err
+	}
+	p.unsubscribed = append(p.unsubscribed, email)
+	return nil
+}
+
+func (p *fakeProvider) reset() {
+	p.subscribed = nil
+	p.unsubscribed = nil
+}
+
+func TestSync(t *testing.T) {
+	provider := &fakeProvider{}
+	db, service := setupTestService(t, provider)
+	ctx := context.Background()
+
+	customer := createTestUser(t, db, "kunde@example.com", true, true)
+	lost := createTestUser(t, db, "verloren@example.com", true, true)
+	createTestUser(t, db, "ohne-einwilligung@example.com", true, false)
+	createTestUser(t, db, "unbestaetigt@example.com", false, true)
+
+	require.NoError(t, db.Create(&models.Booking{
+		UserID: customer.ID, Title: "Vorgespräch", Type: models.BookingTypePreTalk, Status: models.BookingStatusCompleted,
+	}).Error)
+	require.NoError(t, db.Create(&models.Payment{UserID: customer.ID, Amount: 149, Status: models.PaymentStatusSucceeded, StripeSessionID: "cs_1"}).Error)
+	require.NoError(t, db.Create(&models.Lead{UserID: lost.ID, Title: "Antrag", Status: models.LeadStatusCancelled}).Error)
+
+	result, err := service.Sync(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Subscribed)
+	require.Len(t, provider.subscribed, 2)
+
+	segments := map[string][]models.NewsletterSegment{}
+	for _, contact := range provider.subscribed {
+		segments[contact.Email] = contact.Segments
+	}
+	assert.Equal(t, []models.NewsletterSegment{models.NewsletterSegmentPreTalkDone, models.NewsletterSegmentCustomer}, segments[customer.Email])
+	assert.Equal(t, []models.NewsletterSegment{models.NewsletterSegmentLostLead}, segments[lost.Email])
+
+	// Contacts that are up to date are not sent again
+	provider.reset()
+	result, err = service.Sync(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, SyncResult{}, *result)
+	assert.Empty(t, provider.subscribed)
+
+	// A lost lead who becomes a customer loses the lost lead tag
+	require.NoError(t, db.Create(&models.Payment{UserID: lost.ID, Amount: 149, Status: models.PaymentStatusSucceeded, StripeSessionID: "cs_2"}).Error)
+	_, err = service.Sync(ctx)
+	require.NoError(t, err)
+	require.Len(t, provider.subscribed, 1)
+	assert.Equal(t, []models.NewsletterSegment{models.NewsletterSegmentCustomer}, provider.subscribed[0].Segments)
+	assert.Equal(t, []models.NewsletterSegment{models.NewsletterSegmentLostLead}, provider.subscribed[0].Removed)
+
+	// Consent withdrawn in the portal
+	provider.reset()
+	require.NoError(t, db.Model(&models.NotificationPreference{}).Where("user_id = ?", customer.ID).
+		Update("email_marketing_notifications", false).Error)
+	result, err = service.Sync(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Unsubscribed)
+	assert.Equal(t, []string{customer.Email}, provider.unsubscribed)
+
+	var contact models.NewsletterContact
+	require.NoError(t, db.First(&contact, "user_id = ?", customer.ID).Error)
+	assert.Equal(t, models.NewsletterContactStatusUnsubscribed, contact.Status)
+	assert.NotNil(t, contact.UnsubscribedAt)
+
+	// Provider errors are recorded and retried on the next run
+	provider.reset()
+	provider.err = errors.New("provider unavailable")
+	require.NoError(t, db.Model(lost).Update("first_name", "Lea").Error)
+	require.NoError(t, db.Model(&models.NotificationPreference{}).Where("user_id = ?", customer.ID).
+		Update("email_marketing_notifications", true).Error)
+	result, err = service.Sync(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Failed)
+	require.NoError(t, db.First(&contact, "user_id = ?", customer.ID).Error)
+	assert.Equal(t, "provider unavailable", contact.LastError)
+}
+
+func TestSync_Disabled(t *testing.T) {
+	_, service := setupTestService(t, nil)
+
+	result, err := service.Sync(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, SyncResult{}, *result)
+}
+
+func TestHandleWebhook(t *testing.T) {
+	provider := &fakeProvider{}
+	db, service := setupTestService(t, provider)
+	mailchimpUser := createTestUser(t, db, "kunde@example.com", true, true)
+	brevoUser := createTestUser(t, db, "brevo@example.com", true, false)
+	_, err := service.Sync(context.Background())
+	require.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		event   models.WebhookEvent
+		userID  string
+		wantErr bool
+	}{
+		{
+			name: "mailchimp_unsubscribe",
+			event: models.WebhookEvent{
+				Provider: ProviderMailchimp,
+				Payload:  "type=unsubscribe&fired_at=2025-03-10+09%3A00%3A00&data%5Baction%5D=unsub&data%5Bemail%5D=Kunde%40Example.com",
+			},
+			userID: mailchimpUser.ID.String(),
+		},
+		{
+			name: "brevo_unsubscribe_without_preferences",
+			event: models.WebhookEvent{
+				Provider: ProviderBrevo,
+				Payload:  `{"id": 123, "event": "unsubscribed", "email": "brevo@example.com"}`,
+			},
+			userID: brevoUser.ID.String(),
+		},
+		{
+			name:  "unknown_address",
+			event: models.WebhookEvent{Provider: ProviderBrevo, Payload: `{"event": "unsubscribed", "email": "fremd@example.com"}`},
+		},
+		{
+			name:  "other_event",
+			event: models.WebhookEvent{Provider: ProviderMailchimp, Payload: "type=profile&data%5Bemail%5D=kunde%40example.com"},
+		},
+		{
+			name:    "invalid_payload",
+			event:   models.WebhookEvent{Provider: ProviderBrevo, Payload: "not json"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := service.HandleWebhook(&tt.event)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			if tt.userID == "" {
+				return
+			}
+
+			var preference models.NotificationPreference
+			require.NoError(t, db.First(&preference, "user_id = ?", tt.userID).Error)
+			assert.False(t, preference.EmailMarketingNotifications)
+			assert.True(t, preference.EmailEnabled)
+		})
+	}
+
+	var contact models.NewsletterContact
+	require.NoError(t, db.First(&contact, "user_id = ?", mailchimpUser.ID).Error)
+	assert.Equal(t, models.NewsletterContactStatusUnsubscribed, contact.Status)
+
+	// The provider already removed the contact, so the sync does not call it again
+	provider.reset()
+	_, err = service.Sync(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, provider.subscribed)
+	assert.Empty(t, provider.unsubscribed)
+}
+
+func TestMailchimp(t *testing.T) {
+	type request struct {
+		method string
+		path   string
+		body   map[string]interface{}
+	}
+	var requests []request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, key, ok := r.BasicAuth()
+		if !ok || key != "secret-us21" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var body map[string]interface{}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		requests = append(requests, request{r.Method, r.URL.Path, body})
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client, err := NewMailchimp("secret-us21", "list1", server.URL, server.Client())
+	require.NoError(t, err)
+
+	err = client.Subscribe(context.Background(), Contact{
+		Email:     "Kunde@Example.com",
+		FirstName: "Anna",
+		Segments:  []models.NewsletterSegment{models.NewsletterSegmentCustomer},
+		Removed:   []models.NewsletterSegment{models.NewsletterSegmentLostLead},
+	})
+	require.NoError(t, err)
+	require.Len(t, requests, 2)
+
+	// MD5 of the lowercase address
+	memberPath := "/lists/list1/members/12dcd58f8db5895e17897e5fdb80c152"
+	assert.Equal(t, http.MethodPut, requests[0].method)
+	assert.Equal(t, memberPath, requests[0].path)
+	assert.Equal(t, "subscribed", requests[0].body["status_if_new"])
+	assert.Equal(t, memberPath+"/tags", requests[1].path)
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"name": "customer", "status": "active"},
+		map[string]interface{}{"name": "lost_lead", "status": "inactive"},
+	}, requests[1].body["tags"])
+
+	require.NoError(t, client.Unsubscribe(context.Background(), "kunde@example.com"))
+	assert.Equal(t, http.MethodPatch, requests[2].method)
+	assert.Equal(t, "unsubscribed", requests[2].body["status"])
+
+	// The API URL is derived from the data center of the key
+	derived, err := NewMailchimp("secret-us21", "list1", "", http.DefaultClient)
+	require.NoError(t, err)
+	assert.Equal(t, "https://us21.api.mailchimp.com/3.0", derived.baseURL)
+	_, err = NewMailchimp("secret", "list1", "", http.DefaultClient)
+	assert.Error(t, err)
+}
+
+func TestBrevo(t *testing.T) {
+	var paths []string
+	var bodies []map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("api-key") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var body map[string]interface{}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		paths = append(paths, r.URL.Path)
+		bodies = append(bodies, body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	client, err := NewBrevo("secret", "7", server.URL, server.Client())
+	require.NoError(t, err)
+
+	require.NoError(t, client.Subscribe(context.Background(), Contact{
+		Email:    "kunde@example.com",
+		Segments: []models.NewsletterSegment{models.NewsletterSegmentPreTalkDone, models.NewsletterSegmentCustomer},
+	}))
+	require.NoError(t, client.Unsubscribe(context.Background(), "kunde@example.com"))
+
+	assert.Equal(t, []string{"/contacts", "/contacts/lists/7/contacts/remove"}, paths)
+	assert.Equal(t, []interface{}{float64(7)}, bodies[0]["listIds"])
+	assert.Equal(t, "pre_talk_done,customer", bodies[0]["attributes"].(map[string]interface{})["SEGMENTS"])
+	assert.Equal(t, []interface{}{"kunde@example.com"}, bodies[1]["emails"])
+
+	_, err = NewBrevo("secret", "newsletter", "", http.DefaultClient)
+	assert.Error(t, err)
+}
+
+func TestNewProvider(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      config.NewsletterConfig
+		expected string
+		wantErr  bool
+	}{
+		{"disabled", config.NewsletterConfig{}, "", false},
+		{"mailchimp", config.NewsletterConfig{Provider: "Mailchimp", APIKey: "key-us1", ListID: "abc"}, ProviderMailchimp, false},
+		{"brevo", config.NewsletterConfig{Provider: "brevo", APIKey: "key", ListID: "3"}, ProviderBrevo, false},
+		{"missing_list", config.NewsletterConfig{Provider: "brevo", APIKey: "key"}, "", true},
+		{"unknown", config.NewsletterConfig{Provider: "rapidmail", APIKey: "key", ListID: "3"}, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider, err := NewProvider(&config.Config{Newsletter: tt.cfg})
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			if tt.expected == "" {
+				assert.Nil(t, provider)
+				return
+			}
+			assert.Equal(t, tt.expected, provider.Name())
+		})
+	}
+}
+
+func setupTestService(t *testing.T, provider Provider) (*gorm.DB, *Service) {
+	t.Helper()
+	db := testutils.NewSQLiteDB(t,
+		&models.User{},
+		&models.Lead{},
+		&models.Booking{},
+		&models.Payment{},
+		&models.NotificationPreference{},
+		&models.NewsletterContact{},
+	)
+
+	return db, NewService(db, zap.NewNop(), provider)
+}
+
+func createTestUser(t *testing.T, db *gorm.DB, email string, verified, marketing bool) *models.User {
+	t.Helper()
+	user := testutils.CreateTestUser(t, db, models.RoleUser)
+	require.NoError(t, db.Model(user).Updates(map[string]interface{}{"email": email, "email_verified": verified}).Error)
+	user.Email = email
+	user.EmailVerified = verified
+	if marketing {
+		require.NoError(t, db.Create(&models.NotificationPreference{
+			UserID:                      user.ID,
+			EmailMarketingNotifications: true,
+		}).Error)
+	}
+	return user
+}
@@ -0,0 +1,102 @@
+package newsletter
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/internal/webhooks"
+
+	"go.uber.org/zap"
+)
+
+// Unsubscribe events of the providers
+const (
+	mailchimpUnsubscribe = "unsubscribe"
+	brevoUnsubscribe     = "unsubscribed"
+)
+
+// providerEvent is the part of a newsletter webhook the portal acts on
+type providerEvent struct {
+	Type  string
+	Email string
+}
+
+// MailchimpEventInfo parses the form encoded webhooks of Mailchimp. They carry
+// no delivery ID, so redeliveries are detected by the payload hash.
+func MailchimpEventInfo(payload []byte) (webhooks.EventInfo, error) {
+	event, err := parseMailchimp(payload)
+	return webhooks.EventInfo{Type: event.Type}, err
+}
+
+// BrevoEventInfo parses the JSON webhooks of Brevo. Their "id" is the ID of the
+// webhook, not of the delivery, so the payload hash is used instead.
+func BrevoEventInfo(payload []byte) (webhooks.EventInfo, error) {
+	event, err := parseBrevo(payload)
+	return webhooks.EventInfo{Type: event.Type}, err
+}
+
+// HandleWebhook applies unsubscribes from the newsletter provider to the
+// marketing consent of the user; other events are ignored
+func (s *Service) HandleWebhook(event *models.WebhookEvent) error {
+	var parsed providerEvent
+	var err error
+	switch event.Provider {
+	case ProviderMailchimp:
+		parsed, err = parseMailchimp([]byte(event.Payload))
+	case ProviderBrevo:
+		parsed, err = parseBrevo([]byte(event.Payload))
+	default:
+		return fmt.Errorf("unsupported newsletter provider %q", event.Provider)
+	}
+	if err != nil {
+		return err
+	}
+
+	if parsed.Type != mailchimpUnsubscribe && parsed.Type != brevoUnsubscribe {
+		return nil
+	}
+	if parsed.Email == "" {
+		return fmt.Errorf("%s unsubscribe without email", event.Provider)
+	}
+
+	if err := s.Unsubscribe(parsed.Email); err != nil {
+		if errors.Is(err, ErrUnknownContact) {
+			// Subscribers who signed up outside the portal have no consent to update
+			return nil
+		}
+		return err
+	}
+
+	s.logger.Info("Marketing consent withdrawn via newsletter unsubscribe",
+		zap.String("provider", event.Provider),
+		zap.String("webhook_event_id", event.ID.String()))
+	return nil
+}
+
+func parseMailchimp(payload []byte) (providerEvent, error) {
+	values, err := url.ParseQuery(string(payload))
+	if err != nil {
+		return providerEvent{}, err
+	}
+	if values.Get("type") == "" {
+		return providerEvent{}, errors.New("mailchimp webhook without type")
+	}
+	return providerEvent{Type: values.Get("type"), Email: values.Get("data[email]")}, nil
+}
+
+func parseBrevo(payload []byte) (providerEvent, error) {
+	var body struct {
+		Event string `json:"event"`
+		Email string `json:"email"`
+	}
+	if err := json.Unmarshal(payload, &body); err != nil {
+		return providerEvent{}, err
+	}
+	if body.Event == "" {
+		return providerEvent{}, errors.New("brevo webhook without event")
+	}
+	return providerEvent{Type: body.Event, Email: body.Email}, nil
+}
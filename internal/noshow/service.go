@@ -0,0 +1,220 @@
+package noshow
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"elterngeld-portal/config"
+	"elterngeld-portal/internal/bookingstate"
+	"elterngeld-portal/internal/models"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrBookingNotFound is returned when the booking does not exist
+	ErrBookingNotFound = errors.New("booking not found")
+	// ErrAccessDenied is returned when the user neither conducts the booking nor is an admin
+	ErrAccessDenied = errors.New("access denied")
+	// ErrNotEnded is returned when attendance is recorded for a booking that has not ended yet
+	ErrNotEnded = errors.New("booking has not ended yet")
+	// ErrInvalidStatus is returned when attendance is recorded for a booking that was not confirmed
+	ErrInvalidStatus = errors.New("booking is neither confirmed nor flagged as no-show")
+)
+
+// FollowUpSender emails a customer who missed their appointment an offer to reschedule
+type FollowUpSender interface {
+	SendNoShowFollowUp(booking *models.Booking, customer *models.User) error
+}
+
+// Service flags confirmed bookings that were not completed after their end
+// time as no-shows, asks the Berater to confirm and offers the customer to
+// reschedule
+type Service struct {
+	db     *gorm.DB
+	logger *zap.Logger
+	sender FollowUpSender
+	states *bookingstate.Service
+	config config.NoShowConfig
+	now    func() time.Time
+}
+
+func NewService(db *gorm.DB, logger *zap.Logger, cfg *config.Config, sender FollowUpSender, bookingStates *bookingstate.Service) *Service {
+	return &Service{
+		db:     db,
+		logger: logger,
+		sender: sender,
+		states: bookingStates,
+		config: cfg.NoShow,
+		now:    time.Now,
+	}
+}
+
+// DetectNoShows flags confirmed bookings that ended more than the grace
+// period ago as no-shows and notifies their Berater. It returns the number of
+// flagged bookings.
+func (s *Service) DetectNoShows() (int, error) {
+	now := s.now()
+
+	var bookings []models.Booking
+	err := s.db.Where("status = ? AND end_time <= ?", models.BookingStatusConfirmed, now.Add(-s.config.GracePeriod)).
+		Find(&bookings).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to find overdue bookings: %w", err)
+	}
+
+	flagged := 0
+	for i := range bookings {
+		booking := &bookings[i]
+
+		err := s.db.Transaction(func(tx *gorm.DB) error {
+			return s.states.Transition(tx, booking, models.BookingStatusNoShow, bookingstate.Change{At: now})
+		})
+		if err != nil {
+			// The Berater may have completed the booking in the meantime
+			if errors.Is(err, bookingstate.ErrStale) {
+				continue
+			}
+			return flagged, fmt.Errorf("failed to flag booking %s: %w", booking.ID, err)
+		}
+		flagged++
+
+		s.notifyBerater(booking)
+	}
+
+	if flagged > 0 {
+		s.logger.Info("Flagged bookings as no-show", zap.Int("count", flagged))
+	}
+	return flagged, nil
+}
+
+// notifyBerater asks the Berater of a flagged booking to confirm the no-show
+func (s *Service) notifyBerater(booking *models.Booking) {
+	if booking.BeraterID == nil {
+		return
+	}
+
+	var berater models.User
+	if err := s.db.First(&berater, "id = ?", *booking.BeraterID).Error; err != nil {
+		s.logger.Warn("Failed to load Berater of no-show booking", zap.String("booking_id", booking.ID.String()), zap.Error(err))
+		return
+	}
+
+	title := fmt.Sprintf("Nichterscheinen bestätigen: %s", booking.BookingReference)
+	message := fmt.Sprintf("Der Termin '%s' am %s wurde nicht als abgeschlossen markiert und daher als nicht wahrgenommen vermerkt. "+
+		"Bitte bestätigen Sie das Nichterscheinen oder markieren Sie den Termin als wahrgenommen.",
+		booking.Title, booking.StartTime.In(berater.TimeLocation()).Format("02.01.2006 15:04"))
+	data, _ := json.Marshal(map[string]interface{}{
+		"booking_id": booking.ID,
+		"lead_id":    booking.LeadID,
+	})
+
+	notifications := []models.Notification{
+		{
+			UserID:    berater.ID,
+			Type:      models.NotificationTypeInApp,
+			Title:     title,
+			Message:   message,
+			Data:      string(data),
+			Recipient: berater.NotificationAddress(),
+		},
+		{
+			UserID:    berater.ID,
+			Type:      models.NotificationTypeEmail,
+			Title:     title,
+			Message:   message,
+			Data:      string(data),
+			Template:  string(models.EmailTemplateBookingNoShow),
+			Recipient: berater.NotificationAddress(),
+		},
+	}
+	if err := s.db.Create(&notifications).Error; err != nil {
+		s.logger.Error("Failed to create no-show notification", zap.String("booking_id", booking.ID.String()), zap.Error(err))
+	}
+}
+
+// SendFollowUps emails the customers of no-show bookings an offer to
+// reschedule. Flagged bookings wait for the follow-up delay so the Berater
+// can correct the flag first; bookings confirmed by the Berater are sent right
+// away. It returns the number of sent emails.
+func (s *Service) SendFollowUps() (int, error) {
+	if !s.config.FollowUpEnabled {
+		return 0, nil
+	}
+
+	var bookings []models.Booking
+	err := s.db.Preload("User").
+		Where("status = ? AND follow_up_sent_at IS NULL", models.BookingStatusNoShow).
+		Where("no_show_confirmed_at IS NOT NULL OR no_show_flagged_at <= ?", s.now().Add(-s.config.FollowUpDelay)).
+		Find(&bookings).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to find due follow-ups: %w", err)
+	}
+
+	sent := 0
+	for i := range bookings {
+		booking := &bookings[i]
+		if err := s.sender.SendNoShowFollowUp(booking, &booking.User); err != nil {
+			s.logger.Error("Failed to send no-show follow-up", zap.String("booking_id", booking.ID.String()), zap.Error(err))
+			continue
+		}
+		if err := s.db.Model(booking).Update("follow_up_sent_at", s.now()).Error; err != nil {
+			return sent, fmt.Errorf("failed to record follow-up of booking %s: %w", booking.ID, err)
+		}
+		sent++
+	}
+	return sent, nil
+}
+
+// RecordAttendance lets the Berater of a booking, or an admin, confirm a
+// no-show or mark the booking as completed after it has ended
+func (s *Service) RecordAttendance(bookingID uuid.UUID, user *models.User, attended bool) (*models.Booking, error) {
+	var booking models.Booking
+	if err := s.db.First(&booking, "id = ?", bookingID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrBookingNotFound
+		}
+		return nil, fmt.Errorf("failed to load booking: %w", err)
+	}
+	if !user.IsAdmin() && (booking.BeraterID == nil || *booking.BeraterID != user.ID) {
+		return nil, ErrAccessDenied
+	}
+	if booking.Status != models.BookingStatusConfirmed && booking.Status != models.BookingStatusNoShow {
+		return nil, ErrInvalidStatus
+	}
+
+	now := s.now()
+	if booking.EndTime.After(now) {
+		return nil, ErrNotEnded
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		change := bookingstate.Change{ActorID: &user.ID, At: now}
+		switch {
+		case attended:
+			change.Updates = map[string]interface{}{"no_show_confirmed_at": nil}
+			return s.states.Transition(tx, &booking, models.BookingStatusCompleted, change)
+		case booking.Status == models.BookingStatusConfirmed:
+			change.Updates = map[string]interface{}{"no_show_confirmed_at": now}
+			return s.states.Transition(tx, &booking, models.BookingStatusNoShow, change)
+		default:
+			// Confirming a flagged no-show keeps the status
+			booking.NoShowConfirmedAt = &now
+			booking.UpdatedAt = now
+			return tx.Model(&booking).Select("no_show_confirmed_at", "updated_at").Updates(&booking).Error
+		}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to record attendance: %w", err)
+	}
+
+	s.logger.Info("Recorded booking attendance",
+		zap.String("booking_id", booking.ID.String()),
+		zap.String("user_id", user.ID.String()),
+		zap.Bool("attended", attended))
+	return &booking, nil
+}
@@ -0,0 +1,180 @@
+package noshow
+
+import (
+	"testing"
+	"time"
+
+	"elterngeld-portal/config"
+	"elterngeld-portal/internal/bookingstate"
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/tests/testutils"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type recordingSender struct {
+	sent []uuid.UUID
+}
+
+func (r *recordingSender) SendNoShowFollowUp(booking *models.Booking, customer *models.User) error {
+	r.sent = append(r.sent, booking.ID)
+	return nil
+}
+
+func TestDetectNoShows(t *testing.T) {
+	db, service, sender, now := setupTestService(t, true)
+	customer := testutils.CreateTestUser(t, db, models.RoleUser)
+	berater := testutils.CreateTestUser(t, db, models.RoleBerater)
+
+	overdue := createBooking(t, db, customer, berater, models.BookingStatusConfirmed, now.Add(-3*time.Hour))
+	withinGrace := createBooking(t, db, customer, berater, models.BookingStatusConfirmed, now.Add(-time.Hour))
+	completed := createBooking(t, db, customer, berater, models.BookingStatusCompleted, now.Add(-5*time.Hour))
+
+	flagged, err := service.DetectNoShows()
+	require.NoError(t, err)
+	assert.Equal(t, 1, flagged)
+
+	for _, tt := range []struct {
+		booking *models.Booking
+		status  models.BookingStatus
+	}{
+		{overdue, models.BookingStatusNoShow},
+		{withinGrace, models.BookingStatusConfirmed},
+		{completed, models.BookingStatusCompleted},
+	} {
+		var booking models.Booking
+		require.NoError(t, db.First(&booking, "id = ?", tt.booking.ID).Error)
+		assert.Equal(t, tt.status, booking.Status)
+	}
+
+	var notifications []models.Notification
+	require.NoError(t, db.Where("user_id = ?", berater.ID).Find(&notifications).Error)
+	assert.Len(t, notifications, 2)
+
+	// Flagged bookings are not flagged again
+	flagged, err = service.DetectNoShows()
+	require.NoError(t, err)
+	assert.Equal(t, 0, flagged)
+
+	t.Run("follow-up after delay", func(t *testing.T) {
+		sent, err := service.SendFollowUps()
+		require.NoError(t, err)
+		assert.Equal(t, 0, sent)
+
+		*now = now.Add(25 * time.Hour)
+		sent, err = service.SendFollowUps()
+		require.NoError(t, err)
+		assert.Equal(t, 1, sent)
+		assert.Equal(t, []uuid.UUID{overdue.ID}, sender.sent)
+
+		sent, err = service.SendFollowUps()
+		require.NoError(t, err)
+		assert.Equal(t, 0, sent)
+	})
+}
+
+func TestFollowUpDisabled(t *testing.T) {
+	db, service, sender, now := setupTestService(t, false)
+	customer := testutils.CreateTestUser(t, db, models.RoleUser)
+	berater := testutils.CreateTestUser(t, db, models.RoleBerater)
+	createBooking(t, db, customer, berater, models.BookingStatusConfirmed, now.Add(-3*time.Hour))
+
+	flagged, err := service.DetectNoShows()
+	require.NoError(t, err)
+	assert.Equal(t, 1, flagged)
+
+	*now = now.Add(48 * time.Hour)
+	sent, err := service.SendFollowUps()
+	require.NoError(t, err)
+	assert.Equal(t, 0, sent)
+	assert.Empty(t, sender.sent)
+}
+
+func TestRecordAttendance(t *testing.T) {
+	db, service, sender, now := setupTestService(t, true)
+	customer := testutils.CreateTestUser(t, db, models.RoleUser)
+	berater := testutils.CreateTestUser(t, db, models.RoleBerater)
+	otherBerater := testutils.CreateTestUser(t, db, models.RoleBerater)
+	admin := testutils.CreateTestUser(t, db, models.RoleAdmin)
+
+	flagged := createBooking(t, db, customer, berater, models.BookingStatusConfirmed, now.Add(-3*time.Hour))
+	_, err := service.DetectNoShows()
+	require.NoError(t, err)
+	missed := createBooking(t, db, customer, berater, models.BookingStatusConfirmed, now.Add(-time.Hour))
+	upcoming := createBooking(t, db, customer, berater, models.BookingStatusConfirmed, now.Add(2*time.Hour))
+	cancelled := createBooking(t, db, customer, berater, models.BookingStatusCancelled, now.Add(-time.Hour))
+
+	tests := []struct {
+		name     string
+		booking  uuid.UUID
+		user     *models.User
+		attended bool
+		status   models.BookingStatus
+		err      error
+	}{
+		{"other Berater", flagged.ID, otherBerater, true, "", ErrAccessDenied},
+		{"unknown booking", uuid.New(), admin, true, "", ErrBookingNotFound},
+		{"not ended", upcoming.ID, berater, false, "", ErrNotEnded},
+		{"cancelled", cancelled.ID, berater, false, "", ErrInvalidStatus},
+		{"correct flag", flagged.ID, berater, true, models.BookingStatusCompleted, nil},
+		{"confirm no-show", missed.ID, admin, false, models.BookingStatusNoShow, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			booking, err := service.RecordAttendance(tt.booking, tt.user, tt.attended)
+			if tt.err != nil {
+				assert.ErrorIs(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.status, booking.Status)
+
+			var stored models.Booking
+			require.NoError(t, db.First(&stored, "id = ?", tt.booking).Error)
+			assert.Equal(t, tt.status, stored.Status)
+		})
+	}
+
+	// A confirmed no-show is followed up without waiting for the delay
+	sent, err := service.SendFollowUps()
+	require.NoError(t, err)
+	assert.Equal(t, 1, sent)
+	assert.Equal(t, []uuid.UUID{missed.ID}, sender.sent)
+}
+
+func createBooking(t *testing.T, db *gorm.DB, customer, berater *models.User, status models.BookingStatus, end time.Time) *models.Booking {
+	t.Helper()
+	booking := &models.Booking{
+		UserID:      customer.ID,
+		BeraterID:   &berater.ID,
+		Title:       "Erstberatung",
+		Status:      status,
+		ScheduledAt: end.Add(-time.Hour),
+		StartTime:   end.Add(-time.Hour),
+		EndTime:     end,
+	}
+	require.NoError(t, db.Create(booking).Error)
+	return booking
+}
+
+func setupTestService(t *testing.T, followUp bool) (*gorm.DB, *Service, *recordingSender, *time.Time) {
+	t.Helper()
+	db := testutils.NewSQLiteDB(t,
+		&models.User{},
+		&models.Booking{},
+		&models.Notification{},
+	)
+
+	sender := &recordingSender{}
+	service := NewService(db, zap.NewNop(), &config.Config{
+		NoShow: config.NoShowConfig{GracePeriod: 2 * time.Hour, FollowUpEnabled: followUp, FollowUpDelay: 24 * time.Hour},
+	}, sender, bookingstate.NewService(db, zap.NewNop()))
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	service.now = func() time.Time { return now }
+	return db, service, sender, &now
+}
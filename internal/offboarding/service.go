@@ -0,0 +1,183 @@
+package offboarding
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"elterngeld-portal/internal/models"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrBeraterNotFound is returned when no Berater or Junior Berater has the given ID
+	ErrBeraterNotFound = errors.New("berater not found")
+	// ErrAlreadyDeactivated is returned when a Berater was offboarded before
+	ErrAlreadyDeactivated = errors.New("berater is already deactivated")
+)
+
+// openLeadStatuses are the lead statuses that still need a Berater
+var openLeadStatuses = []models.LeadStatus{
+	models.LeadStatusNew,
+	models.LeadStatusInProgress,
+	models.LeadStatusQuestion,
+	models.LeadStatusPaymentPending,
+}
+
+// upcomingBookingStatuses are the booking statuses of appointments that still take place
+var upcomingBookingStatuses = []models.BookingStatus{
+	models.BookingStatusPending,
+	models.BookingStatusConfirmed,
+}
+
+// Worklist lists the leads and upcoming bookings of a Berater that admins have to reassign
+type Worklist struct {
+	Berater  models.UserResponse      `json:"berater"`
+	Leads    []models.LeadResponse    `json:"leads"`
+	Bookings []models.BookingResponse `json:"bookings"`
+}
+
+// Result describes a completed deactivation
+type Result struct {
+	Worklist
+	CancelledTimeslots int `json:"cancelled_timeslots"`
+	RevokedSessions    int `json:"revoked_sessions"`
+}
+
+// Service implements the offboarding of Beraters who leave
+type Service struct {
+	db     *gorm.DB
+	logger *zap.Logger
+	now    func() time.Time
+}
+
+func NewService(db *gorm.DB, logger *zap.Logger) *Service {
+	return &Service{
+		db:     db,
+		logger: logger,
+		now:    time.Now,
+	}
+}
+
+// Deactivate blocks the login of a Berater, revokes their sessions and cancels their
+// future timeslots. Unbooked timeslots are removed; booked ones are closed for new
+// bookings and stay with their booking until an admin reassigns it. The returned
+// worklist lists the leads and bookings that need a new Berater.
+func (s *Service) Deactivate(beraterID, deactivatedBy uuid.UUID, reason string) (*Result, error) {
+	berater, err := s.findBerater(beraterID)
+	if err != nil {
+		return nil, err
+	}
+	if berater.IsDeactivated() {
+		return nil, ErrAlreadyDeactivated
+	}
+
+	now := s.now()
+	result := &Result{}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		berater.IsActive = false
+		berater.DeactivatedAt = &now
+		berater.DeactivatedBy = &deactivatedBy
+		berater.DeactivationReason = strings.TrimSpace(reason)
+		if err := tx.Model(berater).Select("is_active", "deactivated_at", "deactivated_by", "deactivation_reason").
+			Updates(berater).Error; err != nil {
+			return fmt.Errorf("failed to deactivate user: %w", err)
+		}
+
+		revoked := tx.Model(&models.RefreshToken{}).
+			Where("user_id = ? AND is_revoked = ?", berater.ID, false).
+			Update("is_revoked", true)
+		if revoked.Error != nil {
+			return fmt.Errorf("failed to revoke sessions: %w", revoked.Error)
+		}
+		result.RevokedSessions = int(revoked.RowsAffected)
+
+		if err := tx.Model(&models.Timeslot{}).Where("berater_id = ? AND start_time >= ?", berater.ID, now).
+			Update("is_available", false).Error; err != nil {
+			return fmt.Errorf("failed to close timeslots: %w", err)
+		}
+
+		bookedSlots := tx.Model(&models.Booking{}).Select("timeslot_id").
+			Where("timeslot_id IS NOT NULL AND status IN ?", upcomingBookingStatuses)
+		cancelled := tx.Where("berater_id = ? AND start_time >= ? AND id NOT IN (?)", berater.ID, now, bookedSlots).
+			Delete(&models.Timeslot{})
+		if cancelled.Error != nil {
+			return fmt.Errorf("failed to cancel timeslots: %w", cancelled.Error)
+		}
+		result.CancelledTimeslots = int(cancelled.RowsAffected)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	worklist, err := s.worklist(berater)
+	if err != nil {
+		return nil, err
+	}
+	result.Worklist = *worklist
+
+	s.logger.Info("Berater deactivated",
+		zap.String("berater_id", berater.ID.String()),
+		zap.String("deactivated_by", deactivatedBy.String()),
+		zap.Int("cancelled_timeslots", result.CancelledTimeslots),
+		zap.Int("open_leads", len(result.Leads)),
+		zap.Int("upcoming_bookings", len(result.Bookings)))
+
+	return result, nil
+}
+
+// Worklist returns the leads and upcoming bookings still assigned to a Berater.
+// Items disappear from it as soon as they are reassigned, completed or cancelled.
+func (s *Service) Worklist(beraterID uuid.UUID) (*Worklist, error) {
+	berater, err := s.findBerater(beraterID)
+	if err != nil {
+		return nil, err
+	}
+	return s.worklist(berater)
+}
+
+func (s *Service) worklist(berater *models.User) (*Worklist, error) {
+	var leads []models.Lead
+	if err := s.db.Preload("User").
+		Where("berater_id = ? AND status IN ?", berater.ID, openLeadStatuses).
+		Order("created_at ASC").Find(&leads).Error; err != nil {
+		return nil, fmt.Errorf("failed to load leads: %w", err)
+	}
+
+	var bookings []models.Booking
+	if err := s.db.Where("berater_id = ? AND status IN ? AND start_time >= ?", berater.ID, upcomingBookingStatuses, s.now()).
+		Order("start_time ASC").Find(&bookings).Error; err != nil {
+		return nil, fmt.Errorf("failed to load bookings: %w", err)
+	}
+
+	worklist := &Worklist{
+		Berater:  berater.ToResponse(),
+		Leads:    make([]models.LeadResponse, 0, len(leads)),
+		Bookings: make([]models.BookingResponse, 0, len(bookings)),
+	}
+	for i := range leads {
+		worklist.Leads = append(worklist.Leads, leads[i].ToResponse())
+	}
+	for i := range bookings {
+		worklist.Bookings = append(worklist.Bookings, bookings[i].ToResponse())
+	}
+	return worklist, nil
+}
+
+func (s *Service) findBerater(beraterID uuid.UUID) (*models.User, error) {
+	var berater models.User
+	if err := s.db.Where("id = ? AND role IN ?", beraterID,
+		[]models.UserRole{models.RoleBerater, models.RoleJuniorBerater}).First(&berater).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrBeraterNotFound
+		}
+		return nil, err
+	}
+	return &berater, nil
+}
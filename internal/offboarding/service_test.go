@@ -0,0 +1,160 @@
+package offboarding
+
+import (
+	"testing"
+	"time"
+
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/tests/testutils"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+func TestDeactivate(t *testing.T) {
+	db, service := setupTestService(t)
+	now := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+	service.now = func() time.Time { return now }
+
+	admin := createUser(t, db, "admin@example.com", models.RoleAdmin)
+	berater := createUser(t, db, "berater@example.com", models.RoleBerater)
+	colleague := createUser(t, db, "kollegin@example.com", models.RoleBerater)
+	customer := createUser(t, db, "kunde@example.com", models.RoleUser)
+
+	require.NoError(t, db.Create(&models.RefreshToken{UserID: berater.ID, Token: "session", ExpiresAt: now.Add(time.Hour)}).Error)
+
+	pastSlot := createTimeslot(t, db, berater.ID, now.Add(-24*time.Hour))
+	freeSlot := createTimeslot(t, db, berater.ID, now.Add(24*time.Hour))
+	bookedSlot := createTimeslot(t, db, berater.ID, now.Add(48*time.Hour))
+	colleagueSlot := createTimeslot(t, db, colleague.ID, now.Add(24*time.Hour))
+
+	openLead := createLead(t, db, customer.ID, berater.ID, models.LeadStatusInProgress)
+	createLead(t, db, customer.ID, berater.ID, models.LeadStatusCompleted)
+	createLead(t, db, customer.ID, colleague.ID, models.LeadStatusNew)
+
+	upcoming := createBooking(t, db, customer.ID, berater.ID, &bookedSlot.ID, now.Add(48*time.Hour), models.BookingStatusConfirmed)
+	createBooking(t, db, customer.ID, berater.ID, &pastSlot.ID, now.Add(-24*time.Hour), models.BookingStatusCompleted)
+	createBooking(t, db, customer.ID, berater.ID, nil, now.Add(72*time.Hour), models.BookingStatusCancelled)
+
+	result, err := service.Deactivate(berater.ID, admin.ID, "  Wechsel zu anderem Arbeitgeber ")
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.CancelledTimeslots)
+	assert.Equal(t, 1, result.RevokedSessions)
+	require.Len(t, result.Leads, 1)
+	assert.Equal(t, openLead.ID, result.Leads[0].ID)
+	require.Len(t, result.Bookings, 1)
+	assert.Equal(t, upcoming.ID, result.Bookings[0].ID)
+
+	var stored models.User
+	require.NoError(t, db.First(&stored, "id = ?", berater.ID).Error)
+	assert.False(t, stored.IsActive)
+	assert.True(t, stored.IsDeactivated())
+	assert.Equal(t, admin.ID, *stored.DeactivatedBy)
+	assert.Equal(t, "Wechsel zu anderem Arbeitgeber", stored.DeactivationReason)
+	assert.False(t, stored.IsBookable())
+
+	var token models.RefreshToken
+	require.NoError(t, db.First(&token, "user_id = ?", berater.ID).Error)
+	assert.True(t, token.IsRevoked)
+
+	// The free slot is gone, the booked one stays with its booking but takes no new bookings
+	assert.ErrorIs(t, db.First(&models.Timeslot{}, "id = ?", freeSlot.ID).Error, gorm.ErrRecordNotFound)
+	var booked models.Timeslot
+	require.NoError(t, db.First(&booked, "id = ?", bookedSlot.ID).Error)
+	assert.False(t, booked.IsAvailable)
+	var past models.Timeslot
+	require.NoError(t, db.First(&past, "id = ?", pastSlot.ID).Error)
+	assert.True(t, past.IsAvailable)
+	var other models.Timeslot
+	require.NoError(t, db.First(&other, "id = ?", colleagueSlot.ID).Error)
+	assert.True(t, other.IsAvailable)
+
+	_, err = service.Deactivate(berater.ID, admin.ID, "")
+	assert.ErrorIs(t, err, ErrAlreadyDeactivated)
+
+	// Reassigned items leave the worklist
+	require.NoError(t, db.Model(&models.Lead{}).Where("id = ?", openLead.ID).Update("berater_id", colleague.ID).Error)
+	worklist, err := service.Worklist(berater.ID)
+	require.NoError(t, err)
+	assert.Empty(t, worklist.Leads)
+	assert.Len(t, worklist.Bookings, 1)
+}
+
+func TestDeactivate_OnlyBeraters(t *testing.T) {
+	db, service := setupTestService(t)
+	admin := createUser(t, db, "admin@example.com", models.RoleAdmin)
+	customer := createUser(t, db, "kunde@example.com", models.RoleUser)
+
+	_, err := service.Deactivate(customer.ID, admin.ID, "")
+	assert.ErrorIs(t, err, ErrBeraterNotFound)
+
+	_, err = service.Worklist(uuid.New())
+	assert.ErrorIs(t, err, ErrBeraterNotFound)
+}
+
+func setupTestService(t *testing.T) (*gorm.DB, *Service) {
+	t.Helper()
+	db := testutils.NewSQLiteDB(t,
+		&models.User{},
+		&models.RefreshToken{},
+		&models.Lead{},
+		&models.Timeslot{},
+		&models.Booking{},
+	)
+
+	return db, NewService(db, zap.NewNop())
+}
+
+func createUser(t *testing.T, db *gorm.DB, email string, role models.UserRole) *models.User {
+	t.Helper()
+	user := testutils.CreateTestUser(t, db, role)
+	require.NoError(t, db.Model(user).Update("email", email).Error)
+	user.Email = email
+	return user
+}
+
+func createTimeslot(t *testing.T, db *gorm.DB, beraterID uuid.UUID, start time.Time) *models.Timeslot {
+	t.Helper()
+	slot := &models.Timeslot{
+		BeraterID:   beraterID,
+		Date:        start,
+		StartTime:   start,
+		EndTime:     start.Add(time.Hour),
+		Duration:    60,
+		IsAvailable: true,
+	}
+	require.NoError(t, db.Create(slot).Error)
+	return slot
+}
+
+func createLead(t *testing.T, db *gorm.DB, userID, beraterID uuid.UUID, status models.LeadStatus) *models.Lead {
+	t.Helper()
+	lead := &models.Lead{
+		UserID:    userID,
+		BeraterID: &beraterID,
+		Title:     "Elterngeldantrag",
+		Status:    status,
+	}
+	require.NoError(t, db.Create(lead).Error)
+	return lead
+}
+
+func createBooking(t *testing.T, db *gorm.DB, userID, beraterID uuid.UUID, timeslotID *uuid.UUID, start time.Time, status models.BookingStatus) *models.Booking {
+	t.Helper()
+	booking := &models.Booking{
+		UserID:      userID,
+		BeraterID:   &beraterID,
+		TimeslotID:  timeslotID,
+		Title:       "Beratung",
+		Status:      status,
+		ScheduledAt: start,
+		StartTime:   start,
+		EndTime:     start.Add(time.Hour),
+		BookedAt:    start.Add(-72 * time.Hour),
+	}
+	require.NoError(t, db.Create(booking).Error)
+	return booking
+}
@@ -0,0 +1,381 @@
+package onboarding
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"elterngeld-portal/internal/availability"
+	"elterngeld-portal/internal/models"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// InvitationTTL is how long an invitation link stays valid
+const InvitationTTL = 7 * 24 * time.Hour
+
+var (
+	// ErrInvitationNotFound is returned when no invitation matches a token or ID
+	ErrInvitationNotFound = errors.New("invitation not found")
+	// ErrInvitationNotPending is returned for accepted, revoked or expired invitations
+	ErrInvitationNotPending = errors.New("invitation is no longer valid")
+	// ErrEmailTaken is returned when a user with the invited email already exists
+	ErrEmailTaken = errors.New("user with this email already exists")
+	// ErrNotBerater is returned when the user is not a Berater in onboarding
+	ErrNotBerater = errors.New("user is not a berater")
+	// ErrInvalidStatus is returned when a step is not allowed in the current onboarding status
+	ErrInvalidStatus = errors.New("invalid onboarding status for this step")
+	// ErrIncomplete is returned when a Berater submits an incomplete onboarding
+	ErrIncomplete = errors.New("onboarding is incomplete")
+)
+
+// Onboarding steps a Berater completes before submitting for approval
+const (
+	StepProfile      = "profile"
+	StepCalendar     = "calendar"
+	StepAvailability = "availability"
+)
+
+// InviteInput describes a new Berater invitation
+type InviteInput struct {
+	Email     string
+	FirstName string
+	LastName  string
+	Role      models.UserRole
+}
+
+// ProfileInput holds the onboarding profile fields. Nil fields are left unchanged.
+type ProfileInput struct {
+	Phone                *string
+	Specializations      []models.Specialization
+	ServiceBundeslaender []models.Bundesland
+	WorkingLanguages     []string
+	Bundesland           *models.Bundesland
+	Timezone             *string
+	EmailSignature       *string
+	PhotoURL             *string
+	CalendarURL          *string
+	Bio                  *string
+}
+
+// Step is a single onboarding step and whether it is done
+type Step struct {
+	Name      string `json:"name"`
+	Completed bool   `json:"completed"`
+}
+
+// Progress summarizes the onboarding of a Berater
+type Progress struct {
+	Status models.OnboardingStatus `json:"status"`
+	Steps  []Step                  `json:"steps"`
+}
+
+// Service implements the invitation based Berater onboarding
+type Service struct {
+	db           *gorm.DB
+	logger       *zap.Logger
+	availability *availability.Service
+}
+
+func NewService(db *gorm.DB, logger *zap.Logger, availabilityService *availability.Service) *Service {
+	return &Service{
+		db:           db,
+		logger:       logger,
+		availability: availabilityService,
+	}
+}
+
+// Invite creates an invitation and returns it together with the plain token for
+// the invitation link. Earlier pending invitations for the same email are revoked.
+func (s *Service) Invite(input InviteInput, invitedBy uuid.UUID) (*models.BeraterInvitation, string, error) {
+	email := strings.ToLower(strings.TrimSpace(input.Email))
+
+	var count int64
+	if err := s.db.Model(&models.User{}).Where("LOWER(email) = ?", email).Count(&count).Error; err != nil {
+		return nil, "", fmt.Errorf("failed to check existing users: %w", err)
+	}
+	if count > 0 {
+		return nil, "", ErrEmailTaken
+	}
+
+	token, err := generateToken()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to generate invitation token: %w", err)
+	}
+
+	role := input.Role
+	if role == "" {
+		role = models.RoleBerater
+	}
+
+	invitation := &models.BeraterInvitation{
+		Email:     email,
+		FirstName: input.FirstName,
+		LastName:  input.LastName,
+		Role:      role,
+		TokenHash: models.HashInvitationToken(token),
+		ExpiresAt: time.Now().Add(InvitationTTL),
+		InvitedBy: invitedBy,
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		if err := tx.Model(&models.BeraterInvitation{}).
+			Where("email = ? AND accepted_at IS NULL AND revoked_at IS NULL", email).
+			Update("revoked_at", &now).Error; err != nil {
+			return err
+		}
+		return tx.Create(invitation).Error
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create invitation: %w", err)
+	}
+
+	return invitation, token, nil
+}
+
+// FindPending returns the pending invitation for a token
+func (s *Service) FindPending(token string) (*models.BeraterInvitation, error) {
+	var invitation models.BeraterInvitation
+	if err := s.db.Where("token_hash = ?", models.HashInvitationToken(token)).First(&invitation).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvitationNotFound
+		}
+		return nil, err
+	}
+	if !invitation.IsPending() {
+		return nil, ErrInvitationNotPending
+	}
+	return &invitation, nil
+}
+
+// Accept creates the Berater account for an invitation with the chosen password.
+// The email address counts as verified since the invitation link was sent to it.
+func (s *Service) Accept(token, password string) (*models.User, error) {
+	invitation, err := s.FindPending(token)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	user := &models.User{
+		Email:            invitation.Email,
+		Password:         password,
+		FirstName:        invitation.FirstName,
+		LastName:         invitation.LastName,
+		Role:             invitation.Role,
+		IsActive:         true,
+		EmailVerified:    true,
+		EmailVerifiedAt:  &now,
+		OnboardingStatus: models.OnboardingStatusProfilePending,
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&models.User{}).Where("LOWER(email) = ?", invitation.Email).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrEmailTaken
+		}
+		if err := tx.Create(user).Error; err != nil {
+			return err
+		}
+		return tx.Model(invitation).Updates(map[string]interface{}{
+			"accepted_at": &now,
+			"user_id":     user.ID,
+		}).Error
+	})
+	if err != nil {
+		if errors.Is(err, ErrEmailTaken) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to accept invitation: %w", err)
+	}
+
+	s.logger.Info("Berater invitation accepted",
+		zap.String("invitation_id", invitation.ID.String()),
+		zap.String("user_id", user.ID.String()))
+
+	return user, nil
+}
+
+// Revoke invalidates a pending invitation
+func (s *Service) Revoke(invitationID uuid.UUID) error {
+	var invitation models.BeraterInvitation
+	if err := s.db.First(&invitation, "id = ?", invitationID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrInvitationNotFound
+		}
+		return err
+	}
+	if invitation.AcceptedAt != nil || invitation.RevokedAt != nil {
+		return ErrInvitationNotPending
+	}
+
+	now := time.Now()
+	return s.db.Model(&invitation).Update("revoked_at", &now).Error
+}
+
+// UpdateProfile stores the onboarding profile of a Berater
+func (s *Service) UpdateProfile(berater *models.User, input ProfileInput) error {
+	if err := requireOnboarding(berater); err != nil {
+		return err
+	}
+
+	if input.Phone != nil {
+		berater.Phone = *input.Phone
+	}
+	if input.Specializations != nil {
+		berater.SetSpecializations(uniqueSpecializations(input.Specializations))
+	}
+	if input.ServiceBundeslaender != nil {
+		berater.SetServiceBundeslaender(input.ServiceBundeslaender)
+	}
+	if input.WorkingLanguages != nil {
+		berater.SetWorkingLanguages(models.CleanLanguages(input.WorkingLanguages))
+	}
+	if input.Bundesland != nil {
+		berater.Bundesland = *input.Bundesland
+	}
+	if input.Timezone != nil {
+		berater.Timezone = *input.Timezone
+	}
+	if input.EmailSignature != nil {
+		berater.EmailSignature = *input.EmailSignature
+	}
+	if input.PhotoURL != nil {
+		berater.PhotoURL = *input.PhotoURL
+	}
+	if input.CalendarURL != nil {
+		berater.CalendarURL = *input.CalendarURL
+	}
+	if input.Bio != nil {
+		berater.Bio = *input.Bio
+	}
+
+	return s.db.Model(berater).Select(
+		"phone", "specializations", "service_bundeslaender", "working_languages",
+		"bundesland", "timezone", "email_signature", "photo_url", "calendar_url", "bio",
+	).Updates(berater).Error
+}
+
+// SetAvailability replaces the weekly availability of a Berater
+func (s *Service) SetAvailability(berater *models.User, rules []models.AvailabilityRule) ([]models.AvailabilityRule, error) {
+	if err := requireOnboarding(berater); err != nil {
+		return nil, err
+	}
+	return s.availability.ReplaceRules(berater.ID, rules)
+}
+
+// Progress returns the onboarding status and steps of a Berater
+func (s *Service) Progress(berater *models.User) (*Progress, error) {
+	var ruleCount int64
+	if err := s.db.Model(&models.AvailabilityRule{}).Where("berater_id = ?", berater.ID).Count(&ruleCount).Error; err != nil {
+		return nil, fmt.Errorf("failed to count availability rules: %w", err)
+	}
+
+	return &Progress{
+		Status: berater.OnboardingStatus,
+		Steps: []Step{
+			{Name: StepProfile, Completed: len(berater.GetSpecializations()) > 0 &&
+				len(berater.GetServiceBundeslaender()) > 0 && len(berater.GetWorkingLanguages()) > 0},
+			{Name: StepCalendar, Completed: berater.CalendarURL != ""},
+			{Name: StepAvailability, Completed: ruleCount > 0},
+		},
+	}, nil
+}
+
+// Submit hands a completed onboarding over to the admins for approval
+func (s *Service) Submit(berater *models.User) error {
+	if berater.OnboardingStatus != models.OnboardingStatusProfilePending &&
+		berater.OnboardingStatus != models.OnboardingStatusRejected {
+		return ErrInvalidStatus
+	}
+
+	progress, err := s.Progress(berater)
+	if err != nil {
+		return err
+	}
+	for _, step := range progress.Steps {
+		if !step.Completed {
+			return fmt.Errorf("%w: %s", ErrIncomplete, step.Name)
+		}
+	}
+
+	berater.OnboardingStatus = models.OnboardingStatusApprovalPending
+	return s.db.Model(berater).Update("onboarding_status", berater.OnboardingStatus).Error
+}
+
+// Approve makes a Berater bookable and generates the first timeslots from their availability
+func (s *Service) Approve(berater *models.User, approvedBy uuid.UUID) (int, error) {
+	if berater.OnboardingStatus != models.OnboardingStatusApprovalPending {
+		return 0, ErrInvalidStatus
+	}
+
+	now := time.Now()
+	berater.OnboardingStatus = models.OnboardingStatusApproved
+	berater.ApprovedAt = &now
+	berater.ApprovedBy = &approvedBy
+	if err := s.db.Model(berater).Select("onboarding_status", "approved_at", "approved_by").Updates(berater).Error; err != nil {
+		return 0, fmt.Errorf("failed to approve berater: %w", err)
+	}
+
+	created, err := s.availability.GenerateTimeslots(berater, now, availability.DefaultHorizonDays)
+	if err != nil {
+		return 0, err
+	}
+
+	s.logger.Info("Berater approved",
+		zap.String("berater_id", berater.ID.String()),
+		zap.String("approved_by", approvedBy.String()),
+		zap.Int("timeslots", created))
+
+	return created, nil
+}
+
+// Reject sends a submitted onboarding back to the Berater
+func (s *Service) Reject(berater *models.User) error {
+	if berater.OnboardingStatus != models.OnboardingStatusApprovalPending {
+		return ErrInvalidStatus
+	}
+
+	berater.OnboardingStatus = models.OnboardingStatusRejected
+	return s.db.Model(berater).Update("onboarding_status", berater.OnboardingStatus).Error
+}
+
+func requireOnboarding(user *models.User) error {
+	if !user.IsBerater() && !user.IsJuniorBerater() {
+		return ErrNotBerater
+	}
+	if user.OnboardingStatus == models.OnboardingStatusApprovalPending {
+		return ErrInvalidStatus
+	}
+	return nil
+}
+
+func uniqueSpecializations(values []models.Specialization) []models.Specialization {
+	result := make([]models.Specialization, 0, len(values))
+	seen := make(map[models.Specialization]bool, len(values))
+	for _, value := range values {
+		if seen[value] {
+			continue
+		}
+		seen[value] = true
+		result = append(result, value)
+	}
+	return result
+}
+
+func generateToken() (string, error) {
+	bytes := make([]byte, 32)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(bytes), nil
+}
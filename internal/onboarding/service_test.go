@@ -0,0 +1,173 @@
+package onboarding
+
+import (
+	"testing"
+	"time"
+
+	"elterngeld-portal/internal/availability"
+	"elterngeld-portal/internal/holidays"
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/tests/testutils"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+func TestOnboardingFlow(t *testing.T) {
+	db, service := setupTestService(t)
+	admin := createAdmin(t, db)
+
+	invitation, token, err := service.Invite(InviteInput{
+		Email:     "Neu.Berater@example.com",
+		FirstName: "Nina",
+		LastName:  "Neu",
+	}, admin.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "neu.berater@example.com", invitation.Email)
+	assert.Equal(t, models.RoleBerater, invitation.Role)
+	assert.NotEqual(t, token, invitation.TokenHash)
+
+	berater, err := service.Accept(token, "sicheres-passwort")
+	require.NoError(t, err)
+	assert.True(t, berater.CheckPassword("sicheres-passwort"))
+	assert.True(t, berater.EmailVerified)
+	assert.Equal(t, models.OnboardingStatusProfilePending, berater.OnboardingStatus)
+	assert.False(t, berater.IsBookable())
+
+	// The invitation can only be used once
+	_, err = service.Accept(token, "anderes-passwort")
+	assert.ErrorIs(t, err, ErrInvitationNotPending)
+
+	err = service.Submit(berater)
+	assert.ErrorIs(t, err, ErrIncomplete)
+
+	bundesland := models.BundeslandBerlin
+	calendarURL := "https://cal.example.com/nina"
+	require.NoError(t, service.UpdateProfile(berater, ProfileInput{
+		Specializations:      []models.Specialization{models.SpecializationBeamte, models.SpecializationZwillinge, models.SpecializationBeamte},
+		ServiceBundeslaender: []models.Bundesland{models.BundeslandBerlin, models.BundeslandBrandenburg},
+		WorkingLanguages:     []string{"de", "en"},
+		Bundesland:           &bundesland,
+		CalendarURL:          &calendarURL,
+	}))
+	assert.Equal(t, []models.Specialization{models.SpecializationBeamte, models.SpecializationZwillinge}, berater.GetSpecializations())
+
+	rules := make([]models.AvailabilityRule, 0, 7)
+	for weekday := 0; weekday < 7; weekday++ {
+		rules = append(rules, models.AvailabilityRule{Weekday: weekday, StartTime: "09:00", EndTime: "11:00", IsOnline: true})
+	}
+	_, err = service.SetAvailability(berater, rules)
+	require.NoError(t, err)
+
+	progress, err := service.Progress(berater)
+	require.NoError(t, err)
+	for _, step := range progress.Steps {
+		assert.True(t, step.Completed, step.Name)
+	}
+
+	require.NoError(t, service.Submit(berater))
+	assert.Equal(t, models.OnboardingStatusApprovalPending, berater.OnboardingStatus)
+
+	// Submitted profiles are locked until an admin decides
+	_, err = service.SetAvailability(berater, rules)
+	assert.ErrorIs(t, err, ErrInvalidStatus)
+
+	require.NoError(t, service.Reject(berater))
+	require.NoError(t, service.Submit(berater))
+
+	created, err := service.Approve(berater, admin.ID)
+	require.NoError(t, err)
+	assert.Greater(t, created, 0)
+
+	var stored models.User
+	require.NoError(t, db.First(&stored, "id = ?", berater.ID).Error)
+	assert.True(t, stored.IsBookable())
+	assert.Equal(t, admin.ID, *stored.ApprovedBy)
+
+	var timeslotCount int64
+	require.NoError(t, db.Model(&models.Timeslot{}).Where("berater_id = ?", berater.ID).Count(&timeslotCount).Error)
+	assert.Equal(t, int64(created), timeslotCount)
+
+	_, err = service.Approve(berater, admin.ID)
+	assert.ErrorIs(t, err, ErrInvalidStatus)
+}
+
+func TestInvite_RejectsExistingEmail(t *testing.T) {
+	db, service := setupTestService(t)
+	admin := createAdmin(t, db)
+
+	_, _, err := service.Invite(InviteInput{Email: "ADMIN@example.com", FirstName: "A", LastName: "B"}, admin.ID)
+	assert.ErrorIs(t, err, ErrEmailTaken)
+}
+
+func TestInvite_RevokesEarlierInvitations(t *testing.T) {
+	db, service := setupTestService(t)
+	admin := createAdmin(t, db)
+
+	input := InviteInput{Email: "berater@example.com", FirstName: "Ben", LastName: "Berater"}
+	_, firstToken, err := service.Invite(input, admin.ID)
+	require.NoError(t, err)
+	_, secondToken, err := service.Invite(input, admin.ID)
+	require.NoError(t, err)
+
+	_, err = service.FindPending(firstToken)
+	assert.ErrorIs(t, err, ErrInvitationNotPending)
+	_, err = service.FindPending(secondToken)
+	assert.NoError(t, err)
+}
+
+func TestAccept_ExpiredOrRevokedInvitation(t *testing.T) {
+	db, service := setupTestService(t)
+	admin := createAdmin(t, db)
+
+	expired, expiredToken, err := service.Invite(InviteInput{Email: "alt@example.com", FirstName: "A", LastName: "Alt"}, admin.ID)
+	require.NoError(t, err)
+	require.NoError(t, db.Model(expired).Update("expires_at", time.Now().Add(-time.Hour)).Error)
+
+	_, err = service.Accept(expiredToken, "sicheres-passwort")
+	assert.ErrorIs(t, err, ErrInvitationNotPending)
+
+	revoked, revokedToken, err := service.Invite(InviteInput{Email: "weg@example.com", FirstName: "W", LastName: "Weg"}, admin.ID)
+	require.NoError(t, err)
+	require.NoError(t, service.Revoke(revoked.ID))
+	assert.ErrorIs(t, service.Revoke(revoked.ID), ErrInvitationNotPending)
+
+	_, err = service.Accept(revokedToken, "sicheres-passwort")
+	assert.ErrorIs(t, err, ErrInvitationNotPending)
+
+	_, err = service.Accept("unknown", "sicheres-passwort")
+	assert.ErrorIs(t, err, ErrInvitationNotFound)
+	assert.ErrorIs(t, service.Revoke(uuid.New()), ErrInvitationNotFound)
+}
+
+func createAdmin(t *testing.T, db *gorm.DB) *models.User {
+	t.Helper()
+	admin := &models.User{
+		Email:     "admin@example.com",
+		Password:  "admin-passwort",
+		FirstName: "Ada",
+		LastName:  "Admin",
+		Role:      models.RoleAdmin,
+		IsActive:  true,
+	}
+	require.NoError(t, db.Create(admin).Error)
+	return admin
+}
+
+func setupTestService(t *testing.T) (*gorm.DB, *Service) {
+	t.Helper()
+	db := testutils.NewSQLiteDB(t,
+		&models.User{},
+		&models.Timeslot{},
+		&models.HolidayOverride{},
+		&models.BeraterInvitation{},
+		&models.AvailabilityRule{},
+	)
+
+	holidayService := holidays.NewService(db, zap.NewNop())
+	availabilityService := availability.NewService(db, zap.NewNop(), holidayService)
+	return db, NewService(db, zap.NewNop(), availabilityService)
+}
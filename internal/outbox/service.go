@@ -0,0 +1,361 @@
+// Package outbox delivers the side effects of business changes. Messages are
+// written in the transaction of the change, so they exist exactly when the
+// change was committed, and a relay delivers them afterwards with retries.
+// Messages that keep failing are dead-lettered until an admin retries them.
+//
+// Delivery is at least once: a message whose handler succeeded may be
+// delivered again when the relay stops before recording the success, so
+// handlers should tolerate repeats.
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"elterngeld-portal/internal/models"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// maxAttempts is how often a message is delivered before it is dead-lettered
+	maxAttempts = 8
+	batchSize   = 50
+
+	// DefaultLimit is the number of dead letters per page when no limit is given
+	DefaultLimit = 20
+	// MaxLimit caps the number of dead letters per page
+	MaxLimit = 100
+)
+
+var (
+	// ErrUnknownTopic is returned when enqueuing or delivering a message without a registered handler
+	ErrUnknownTopic = errors.New("unknown outbox topic")
+	// ErrMessageNotFound is returned when retrying an unknown message
+	ErrMessageNotFound = errors.New("outbox message not found")
+	// ErrNotDead is returned when retrying a message that was not dead-lettered
+	ErrNotDead = errors.New("outbox message is not dead-lettered")
+)
+
+// Handler delivers a message. Returned errors are retried with backoff.
+type Handler func(message *models.OutboxMessage) error
+
+// Service stores outbox messages and relays them to the handlers of their topics
+type Service struct {
+	db     *gorm.DB
+	logger *zap.Logger
+	now    func() time.Time
+
+	mu       sync.RWMutex
+	handlers map[string]Handler
+	// inFlight holds the messages this instance is delivering
+	inFlight map[uuid.UUID]struct{}
+}
+
+func NewService(db *gorm.DB, logger *zap.Logger) *Service {
+	return &Service{
+		db:       db,
+		logger:   logger,
+		now:      time.Now,
+		handlers: make(map[string]Handler),
+		inFlight: make(map[uuid.UUID]struct{}),
+	}
+}
+
+// Register sets the handler of a topic; registering a topic again replaces it
+func (s *Service) Register(topic string, handler Handler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers[topic] = handler
+}
+
+func (s *Service) handler(topic string) (Handler, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	handler, ok := s.handlers[topic]
+	return handler, ok
+}
+
+// Enqueue stores a message in tx, the transaction of the change it belongs
+// to. If enqueuing fails the error must roll the change back. The key
+// identifies the side effect within the topic; a message whose key was
+// enqueued before is dropped, so replayed changes do not repeat side effects.
+// Callers without a transaction pass nil.
+func (s *Service) Enqueue(tx *gorm.DB, topic, key string, payload interface{}) error {
+	if _, ok := s.handler(topic); !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownTopic, topic)
+	}
+	if tx == nil {
+		tx = s.db
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s outbox message: %w", topic, err)
+	}
+
+	message := &models.OutboxMessage{
+		Topic:    topic,
+		DedupKey: topic + ":" + key,
+		Payload:  string(data),
+		Status:   models.OutboxStatusPending,
+	}
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(message).Error; err != nil {
+		return fmt.Errorf("failed to enqueue %s outbox message: %w", topic, err)
+	}
+	return nil
+}
+
+// Decode unmarshals the payload of a message
+func Decode(message *models.OutboxMessage, v interface{}) error {
+	if err := json.Unmarshal([]byte(message.Payload), v); err != nil {
+		return fmt.Errorf("invalid %s outbox payload: %w", message.Topic, err)
+	}
+	return nil
+}
+
+// Relay delivers the messages that are due, oldest first, and returns the
+// number of delivered messages
+func (s *Service) Relay() (int, error) {
+	return s.relay(context.Background())
+}
+
+// relay delivers the due messages until the context is cancelled; the
+// messages left are delivered by the next relay
+func (s *Service) relay(ctx context.Context) (int, error) {
+	var ids []uuid.UUID
+	err := s.db.Model(&models.OutboxMessage{}).
+		Where("status = ? OR (status = ? AND next_attempt_at <= ?)",
+			models.OutboxStatusPending, models.OutboxStatusFailed, s.now()).
+		Order("created_at ASC").Limit(batchSize).Pluck("id", &ids).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to load outbox messages: %w", err)
+	}
+
+	delivered := 0
+	for _, id := range ids {
+		if ctx.Err() != nil {
+			break
+		}
+		if s.process(id) {
+			delivered++
+		}
+	}
+	return delivered, nil
+}
+
+// process delivers a message. The status update claims the message so it is
+// not delivered twice when several instances relay at the same time.
+func (s *Service) process(id uuid.UUID) bool {
+	claim := s.db.Model(&models.OutboxMessage{}).
+		Where("id = ? AND status IN ?", id, []models.OutboxStatus{models.OutboxStatusPending, models.OutboxStatusFailed}).
+		Updates(map[string]interface{}{
+			"status":   models.OutboxStatusProcessing,
+			"attempts": gorm.Expr("attempts + 1"),
+		})
+	if claim.Error != nil {
+		s.logger.Error("Failed to claim outbox message", zap.String("outbox_message_id", id.String()), zap.Error(claim.Error))
+		return false
+	}
+	if claim.RowsAffected == 0 {
+		return false
+	}
+	s.track(id, true)
+	defer s.track(id, false)
+
+	var message models.OutboxMessage
+	if err := s.db.First(&message, "id = ?", id).Error; err != nil {
+		s.logger.Error("Failed to load outbox message", zap.String("outbox_message_id", id.String()), zap.Error(err))
+		return false
+	}
+
+	var deliverErr error
+	if handler, ok := s.handler(message.Topic); ok {
+		deliverErr = s.deliver(handler, &message)
+	} else {
+		deliverErr = fmt.Errorf("%w: %s", ErrUnknownTopic, message.Topic)
+	}
+
+	now := s.now()
+	updates := map[string]interface{}{"next_attempt_at": nil}
+	if deliverErr == nil {
+		updates["status"] = models.OutboxStatusDelivered
+		updates["delivered_at"] = &now
+		updates["last_error"] = ""
+	} else {
+		updates["last_error"] = deliverErr.Error()
+		if message.Attempts < maxAttempts {
+			// Exponential backoff like webhook and email retries
+			next := now.Add(time.Duration(message.Attempts*message.Attempts) * time.Minute)
+			updates["status"] = models.OutboxStatusFailed
+			updates["next_attempt_at"] = &next
+			s.logger.Warn("Failed to deliver outbox message",
+				zap.String("outbox_message_id", id.String()),
+				zap.String("topic", message.Topic),
+				zap.Int("attempts", message.Attempts),
+				zap.Error(deliverErr))
+		} else {
+			updates["status"] = models.OutboxStatusDead
+			updates["dead_at"] = &now
+			s.logger.Error("Outbox message dead-lettered",
+				zap.String("outbox_message_id", id.String()),
+				zap.String("topic", message.Topic),
+				zap.Int("attempts", message.Attempts),
+				zap.Error(deliverErr))
+		}
+	}
+
+	if err := s.db.Model(&message).Updates(updates).Error; err != nil {
+		s.logger.Error("Failed to update outbox message", zap.String("outbox_message_id", id.String()), zap.Error(err))
+	}
+	return deliverErr == nil
+}
+
+// deliver calls the topic's handler and turns panics into errors so a broken
+// handler cannot stop the relay
+func (s *Service) deliver(handler Handler, message *models.OutboxMessage) (err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("outbox handler panicked: %v", recovered)
+		}
+	}()
+	return handler(message)
+}
+
+// DeadLetters returns a page of the dead-lettered messages, latest first,
+// and their number
+func (s *Service) DeadLetters(topic string, page, limit int) ([]models.OutboxMessage, int64, error) {
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 {
+		limit = DefaultLimit
+	}
+	if limit > MaxLimit {
+		limit = MaxLimit
+	}
+
+	query := s.db.Model(&models.OutboxMessage{}).Where("status = ?", models.OutboxStatusDead)
+	if topic != "" {
+		query = query.Where("topic = ?", topic)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var messages []models.OutboxMessage
+	err := query.Order("dead_at DESC").Order("id DESC").
+		Offset((page - 1) * limit).Limit(limit).Find(&messages).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return messages, total, nil
+}
+
+// Retry delivers a dead-lettered message again with a fresh set of attempts,
+// e.g. after a broken chat webhook URL was fixed
+func (s *Service) Retry(id uuid.UUID) (*models.OutboxMessage, error) {
+	var message models.OutboxMessage
+	if err := s.db.First(&message, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrMessageNotFound
+		}
+		return nil, err
+	}
+
+	result := s.db.Model(&models.OutboxMessage{}).
+		Where("id = ? AND status = ?", message.ID, models.OutboxStatusDead).
+		Updates(map[string]interface{}{
+			"status":          models.OutboxStatusPending,
+			"attempts":        0,
+			"next_attempt_at": nil,
+			"dead_at":         nil,
+		})
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to retry outbox message: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrNotDead
+	}
+	message.Status = models.OutboxStatusPending
+	message.Attempts = 0
+	message.NextAttemptAt = nil
+	message.DeadAt = nil
+
+	s.logger.Info("Outbox message retried", zap.String("outbox_message_id", id.String()), zap.String("topic", message.Topic))
+	return &message, nil
+}
+
+// Run relays due messages every interval until the context is cancelled.
+// Messages interrupted by a shutdown are delivered again.
+func (s *Service) Run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	if err := s.db.Model(&models.OutboxMessage{}).
+		Where("status = ?", models.OutboxStatusProcessing).
+		Update("status", models.OutboxStatusPending).Error; err != nil {
+		s.logger.Error("Failed to reset interrupted outbox messages", zap.Error(err))
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := s.relay(ctx); err != nil {
+			s.logger.Error("Failed to relay outbox messages", zap.Error(err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Requeue returns the messages this instance is still delivering to pending,
+// so they are delivered again when a shutdown cannot wait for them. The
+// interrupted attempt does not count towards dead-lettering.
+func (s *Service) Requeue() (int64, error) {
+	s.mu.RLock()
+	ids := make([]uuid.UUID, 0, len(s.inFlight))
+	for id := range s.inFlight {
+		ids = append(ids, id)
+	}
+	s.mu.RUnlock()
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	result := s.db.Model(&models.OutboxMessage{}).
+		Where("id IN ? AND status = ?", ids, models.OutboxStatusProcessing).
+		Updates(map[string]interface{}{
+			"status":   models.OutboxStatusPending,
+			"attempts": gorm.Expr("attempts - 1"),
+		})
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to requeue outbox messages: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
+
+func (s *Service) track(id uuid.UUID, inFlight bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if inFlight {
+		s.inFlight[id] = struct{}{}
+	} else {
+		delete(s.inFlight, id)
+	}
+}
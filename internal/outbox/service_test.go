@@ -0,0 +1,191 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/tests/testutils"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+func TestEnqueue_SameTransaction(t *testing.T) {
+	db, service := setupTestService(t)
+	var delivered []LeadMessage
+	service.Register(TopicChatLeadCreated, func(message *models.OutboxMessage) error {
+		var payload LeadMessage
+		require.NoError(t, Decode(message, &payload))
+		delivered = append(delivered, payload)
+		return nil
+	})
+
+	rolledBack, committed := uuid.New(), uuid.New()
+	err := db.Transaction(func(tx *gorm.DB) error {
+		require.NoError(t, service.Enqueue(tx, TopicChatLeadCreated, rolledBack.String(), LeadMessage{LeadID: rolledBack}))
+		return errors.New("change failed")
+	})
+	require.Error(t, err)
+
+	require.NoError(t, db.Transaction(func(tx *gorm.DB) error {
+		return service.Enqueue(tx, TopicChatLeadCreated, committed.String(), LeadMessage{LeadID: committed})
+	}))
+	// A replayed change does not repeat the side effect
+	require.NoError(t, service.Enqueue(nil, TopicChatLeadCreated, committed.String(), LeadMessage{LeadID: committed}))
+
+	sent, err := service.Relay()
+	require.NoError(t, err)
+	assert.Equal(t, 1, sent)
+	assert.Equal(t, []LeadMessage{{LeadID: committed}}, delivered)
+
+	var message models.OutboxMessage
+	require.NoError(t, db.First(&message).Error)
+	assert.Equal(t, models.OutboxStatusDelivered, message.Status)
+	assert.NotNil(t, message.DeliveredAt)
+
+	// Delivered messages are not relayed again
+	sent, err = service.Relay()
+	require.NoError(t, err)
+	assert.Zero(t, sent)
+
+	assert.ErrorIs(t, service.Enqueue(nil, "unknown", "1", nil), ErrUnknownTopic)
+}
+
+func TestRelay_RetriesAndDeadLetters(t *testing.T) {
+	db, service := setupTestService(t)
+	now := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+	service.now = func() time.Time { return now }
+
+	calls := 0
+	broken := true
+	service.Register(TopicChatBookingPaid, func(message *models.OutboxMessage) error {
+		calls++
+		if broken {
+			return errors.New("webhook returned 500")
+		}
+		return nil
+	})
+	service.Register(TopicChatLeadCreated, func(message *models.OutboxMessage) error {
+		panic("nil lead")
+	})
+
+	require.NoError(t, service.Enqueue(nil, TopicChatBookingPaid, "payment-1", PaymentMessage{}))
+	require.NoError(t, service.Enqueue(nil, TopicChatLeadCreated, "lead-1", LeadMessage{}))
+
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		sent, err := service.Relay()
+		require.NoError(t, err)
+		assert.Zero(t, sent)
+
+		// Not due before the backoff passed
+		sent, err = service.Relay()
+		require.NoError(t, err)
+		assert.Zero(t, sent)
+		now = now.Add(time.Duration(attempt*attempt) * time.Minute)
+	}
+	assert.Equal(t, maxAttempts, calls)
+
+	dead, total, err := service.DeadLetters("", 1, 10)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), total)
+	require.Len(t, dead, 2)
+
+	dead, total, err = service.DeadLetters(TopicChatLeadCreated, 1, 10)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+	assert.Contains(t, dead[0].LastError, "panicked")
+	assert.NotNil(t, dead[0].DeadAt)
+
+	// Dead letters stay until an admin retries them
+	_, err = service.Relay()
+	require.NoError(t, err)
+	assert.Equal(t, maxAttempts, calls)
+
+	var payment models.OutboxMessage
+	require.NoError(t, db.First(&payment, "topic = ?", TopicChatBookingPaid).Error)
+	broken = false
+	retried, err := service.Retry(payment.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.OutboxStatusPending, retried.Status)
+	assert.Zero(t, retried.Attempts)
+
+	sent, err := service.Relay()
+	require.NoError(t, err)
+	assert.Equal(t, 1, sent)
+
+	_, err = service.Retry(payment.ID)
+	assert.ErrorIs(t, err, ErrNotDead)
+	_, err = service.Retry(uuid.New())
+	assert.ErrorIs(t, err, ErrMessageNotFound)
+}
+
+func TestRelay_ClaimsOnce(t *testing.T) {
+	db, service := setupTestService(t)
+	calls := 0
+	service.Register(TopicEmailPaymentReceipt, func(message *models.OutboxMessage) error {
+		calls++
+		return nil
+	})
+	require.NoError(t, service.Enqueue(nil, TopicEmailPaymentReceipt, "payment-1", PaymentMessage{}))
+
+	var message models.OutboxMessage
+	require.NoError(t, db.First(&message).Error)
+
+	// A second relay that loaded the same message does not deliver it again
+	assert.True(t, service.process(message.ID))
+	assert.False(t, service.process(message.ID))
+	assert.Equal(t, 1, calls)
+}
+
+func TestRequeue(t *testing.T) {
+	db, service := setupTestService(t)
+	started, release := make(chan struct{}), make(chan struct{})
+	service.Register(TopicEmailPaymentReceipt, func(message *models.OutboxMessage) error {
+		close(started)
+		<-release
+		return nil
+	})
+	require.NoError(t, service.Enqueue(nil, TopicEmailPaymentReceipt, "payment-1", PaymentMessage{}))
+
+	// A cancelled relay does not start new deliveries
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	delivered, err := service.relay(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, delivered)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, _ = service.Relay()
+	}()
+	<-started
+
+	requeued, err := service.Requeue()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), requeued)
+
+	var message models.OutboxMessage
+	require.NoError(t, db.First(&message).Error)
+	assert.Equal(t, models.OutboxStatusPending, message.Status)
+	assert.Zero(t, message.Attempts)
+
+	close(release)
+	<-done
+	requeued, err = service.Requeue()
+	require.NoError(t, err)
+	assert.Zero(t, requeued)
+}
+
+func setupTestService(t *testing.T) (*gorm.DB, *Service) {
+	t.Helper()
+	db := testutils.NewSQLiteDB(t, &models.OutboxMessage{})
+
+	return db, NewService(db, zap.NewNop())
+}
@@ -0,0 +1,59 @@
+package outbox
+
+import "github.com/google/uuid"
+
+// Topics of the side effects written by the handlers. Handlers are
+// registered when the server starts.
+const (
+	// TopicChatLeadCreated posts a new lead to the matching chat channels
+	TopicChatLeadCreated = "chat.lead_created"
+	// TopicChatBookingPaid posts a paid booking to the matching chat channels
+	TopicChatBookingPaid = "chat.booking_paid"
+	// TopicEmailLeadAssigned notifies the Berater of a lead assigned to them
+	TopicEmailLeadAssigned = "email.lead_assigned"
+	// TopicEmailLeadIntroduction introduces the assigned Berater to the customer
+	TopicEmailLeadIntroduction = "email.lead_introduction"
+	// TopicEmailPaymentReceipt queues the receipt of a completed payment
+	TopicEmailPaymentReceipt = "email.payment_receipt"
+	// TopicEmailBookingConfirmation queues the confirmation of a confirmed booking
+	TopicEmailBookingConfirmation = "email.booking_confirmation"
+	// TopicEmailOffer sends a payment link to the customer as an offer of its creator
+	TopicEmailOffer = "email.offer"
+	// TopicPDFGenerate generates the PDF of a queued PDF job
+	TopicPDFGenerate = "pdf.generate"
+	// TopicBackupRestore restores a backup set into the staging environment
+	TopicBackupRestore = "backup.restore"
+)
+
+// LeadMessage refers to a lead
+type LeadMessage struct {
+	LeadID uuid.UUID `json:"lead_id"`
+}
+
+// AssignmentMessage refers to the assignment of a lead to a Berater
+type AssignmentMessage struct {
+	LeadID    uuid.UUID `json:"lead_id"`
+	BeraterID uuid.UUID `json:"berater_id"`
+}
+
+// PaymentMessage refers to a payment of a booking
+type PaymentMessage struct {
+	BookingID uuid.UUID `json:"booking_id"`
+	PaymentID uuid.UUID `json:"payment_id"`
+}
+
+// PaymentLinkMessage refers to a payment link
+type PaymentLinkMessage struct {
+	PaymentLinkID uuid.UUID `json:"payment_link_id"`
+}
+
+// PDFJobMessage refers to a queued PDF job
+type PDFJobMessage struct {
+	JobID uuid.UUID `json:"job_id"`
+}
+
+// BackupRestoreMessage refers to a backup set an admin asked to restore
+type BackupRestoreMessage struct {
+	BackupID    string    `json:"backup_id"`
+	RequestedBy uuid.UUID `json:"requested_by"`
+}
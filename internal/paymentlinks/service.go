@@ -0,0 +1,373 @@
+// Package paymentlinks lets Beraters sell outside the online booking flow,
+// e.g. on the phone: a payment link for a package offer or a custom amount is
+// sent to a lead, opening it is tracked through a short link and paying it
+// creates the booking.
+package paymentlinks
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"elterngeld-portal/config"
+	"elterngeld-portal/internal/catalog"
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/internal/outbox"
+	"elterngeld-portal/internal/shortlink"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrNotFound is returned for unknown payment links
+	ErrNotFound = errors.New("payment link not found")
+	// ErrLeadNotFound is returned when the lead of a new payment link does not exist
+	ErrLeadNotFound = errors.New("lead not found")
+	// ErrInvalidLink is returned for payment links without a positive amount or a title
+	ErrInvalidLink = errors.New("payment link needs an amount and a title")
+	// ErrNotPayable is returned for payment links that are paid, cancelled or expired
+	ErrNotPayable = errors.New("payment link is no longer payable")
+)
+
+// DefaultTTL is how long a payment link can be paid when no validity is given
+const DefaultTTL = 14 * 24 * time.Hour
+
+// Input describes a new payment link. With a package the link is an offer of
+// the package at its price; an amount overrides the price.
+type Input struct {
+	LeadID      uuid.UUID
+	PackageID   *uuid.UUID
+	Amount      float64 // gross
+	Title       string  // the package name when empty
+	Description string
+	TTL         time.Duration
+	SendEmail   bool // emails the link to the customer as an offer signed by its creator
+}
+
+// Link is a payment link with its public URL and how often it was opened
+type Link struct {
+	*models.PaymentLink
+	URL           string     `json:"url"`
+	Opens         int        `json:"opens"`
+	FirstOpenedAt *time.Time `json:"first_opened_at,omitempty"`
+	LastOpenedAt  *time.Time `json:"last_opened_at,omitempty"`
+}
+
+// Checkout is a completed Stripe checkout of a payment link
+type Checkout struct {
+	SessionID       string
+	PaymentIntentID string
+	Amount          float64
+}
+
+type Service struct {
+	db         *gorm.DB
+	logger     *zap.Logger
+	catalog    *catalog.Service
+	shortLinks *shortlink.Service
+	relay      *outbox.Service
+	baseURL    string
+	now        func() time.Time
+}
+
+func NewService(db *gorm.DB, logger *zap.Logger, cfg *config.Config, catalogService *catalog.Service, shortLinkService *shortlink.Service, relay *outbox.Service) *Service {
+	return &Service{
+		db:         db,
+		logger:     logger,
+		catalog:    catalogService,
+		shortLinks: shortLinkService,
+		relay:      relay,
+		baseURL:    strings.TrimRight(cfg.App.BaseURL, "/"),
+		now:        time.Now,
+	}
+}
+
+// Create creates a payment link for a lead. The customer receives a short
+// link, so opening the link is tracked and raises the lead score. With
+// SendEmail the link is queued as an offer email to the customer.
+func (s *Service) Create(input Input, creator *models.User) (*Link, error) {
+	var lead models.Lead
+	if err := s.db.Select("id", "user_id").First(&lead, "id = ?", input.LeadID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrLeadNotFound
+		}
+		return nil, err
+	}
+
+	link := &models.PaymentLink{
+		LeadID:      lead.ID,
+		UserID:      lead.UserID,
+		PackageID:   input.PackageID,
+		CreatedBy:   creator.ID,
+		Title:       strings.TrimSpace(input.Title),
+		Description: strings.TrimSpace(input.Description),
+		Amount:      roundCents(input.Amount),
+		Currency:    "EUR",
+	}
+	if input.PackageID != nil {
+		quote, err := s.catalog.Quote(catalog.QuoteInput{PackageID: *input.PackageID})
+		if err != nil {
+			return nil, err
+		}
+		if link.Amount == 0 {
+			link.Amount = quote.Total.Gross
+		}
+		if link.Title == "" {
+			link.Title = quote.LineItems[0].Name
+		}
+		link.Currency = quote.Currency
+	}
+	if link.Amount <= 0 || link.Title == "" {
+		return nil, ErrInvalidLink
+	}
+
+	ttl := input.TTL
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	expiresAt := s.now().Add(ttl)
+	link.ExpiresAt = &expiresAt
+
+	token, err := models.GenerateShortLinkCode(models.PaymentLinkTokenLength)
+	if err != nil {
+		return nil, err
+	}
+	link.Token = token
+
+	shortLink, err := s.shortLinks.Create(s.PayURL(link), shortlink.Options{
+		Purpose: models.ShortLinkPurposePaymentLink,
+		UserID:  &lead.UserID,
+		LeadID:  &lead.ID,
+		TTL:     ttl,
+	})
+	if err != nil {
+		return nil, err
+	}
+	link.ShortLinkID = &shortLink.ID
+	link.ShortLink = shortLink
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit(clause.Associations).Create(link).Error; err != nil {
+			return err
+		}
+		if !input.SendEmail {
+			return nil
+		}
+		return s.relay.Enqueue(tx, outbox.TopicEmailOffer, link.ID.String(), outbox.PaymentLinkMessage{PaymentLinkID: link.ID})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create payment link: %w", err)
+	}
+
+	s.logger.Info("Payment link created",
+		zap.String("payment_link_id", link.ID.String()),
+		zap.String("lead_id", lead.ID.String()),
+		zap.Float64("amount", link.Amount))
+
+	return s.toLink(link), nil
+}
+
+// Get returns a payment link with its URL
+func (s *Service) Get(id uuid.UUID) (*Link, error) {
+	var link models.PaymentLink
+	if err := s.db.Preload("ShortLink").First(&link, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return s.toLink(&link), nil
+}
+
+// List returns the payment links of a lead, newest first
+func (s *Service) List(leadID uuid.UUID) ([]Link, error) {
+	var links []models.PaymentLink
+	if err := s.db.Preload("ShortLink").Preload("Package").
+		Where("lead_id = ?", leadID).Order("created_at DESC").Find(&links).Error; err != nil {
+		return nil, err
+	}
+
+	result := make([]Link, 0, len(links))
+	for i := range links {
+		result = append(result, *s.toLink(&links[i]))
+	}
+	return result, nil
+}
+
+// Cancel cancels an open payment link of a lead
+func (s *Service) Cancel(leadID, id uuid.UUID) (*Link, error) {
+	var link models.PaymentLink
+	if err := s.db.Preload("ShortLink").First(&link, "id = ? AND lead_id = ?", id, leadID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	result := s.db.Model(&models.PaymentLink{}).
+		Where("id = ? AND status = ?", link.ID, models.PaymentLinkStatusOpen).
+		Update("status", models.PaymentLinkStatusCancelled)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrNotPayable
+	}
+	link.Status = models.PaymentLinkStatusCancelled
+	return s.toLink(&link), nil
+}
+
+// Open returns the payment link of a token with its customer, to start a
+// checkout
+func (s *Service) Open(token string) (*models.PaymentLink, error) {
+	var link models.PaymentLink
+	if err := s.db.Preload("User").First(&link, "token = ?", token).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	if !link.IsPayable(s.now()) {
+		return &link, ErrNotPayable
+	}
+	return &link, nil
+}
+
+// SetSession remembers the latest Stripe checkout session of a payment link
+func (s *Service) SetSession(link *models.PaymentLink, sessionID string) error {
+	link.StripeSessionID = sessionID
+	return s.db.Model(link).Update("stripe_session_id", sessionID).Error
+}
+
+// Complete creates the booking and the payment of a paid payment link and
+// marks the link as paid. The booking has no appointment yet; the Berater
+// schedules it with the customer, so it stays pending until then. paid is
+// called within the transaction, e.g. to queue the receipt. Replayed
+// checkouts return no booking.
+func (s *Service) Complete(id uuid.UUID, checkout Checkout, paid func(tx *gorm.DB, booking *models.Booking, payment *models.Payment) error) (*models.Booking, *models.Payment, error) {
+	var booking *models.Booking
+	var payment *models.Payment
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var link models.PaymentLink
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Lead").
+			First(&link, "id = ?", id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrNotFound
+			}
+			return err
+		}
+
+		var replayed int64
+		if err := tx.Model(&models.Payment{}).Where("stripe_session_id = ?", checkout.SessionID).
+			Count(&replayed).Error; err != nil {
+			return err
+		}
+		if replayed > 0 {
+			return nil
+		}
+		// A link can be paid after it expired as long as its checkout was
+		// started in time, but not twice or after it was cancelled
+		if link.Status != models.PaymentLinkStatusOpen {
+			return ErrNotPayable
+		}
+
+		now := s.now()
+		payment = &models.Payment{
+			LeadID:              link.LeadID,
+			UserID:              link.UserID,
+			Amount:              roundCents(checkout.Amount),
+			Currency:            link.Currency,
+			Status:              models.PaymentStatusSucceeded,
+			Method:              models.PaymentMethodStripe,
+			Description:         link.Title,
+			StripeSessionID:     checkout.SessionID,
+			StripePaymentIntent: checkout.PaymentIntentID,
+			PaidAt:              &now,
+		}
+		if err := tx.Create(payment).Error; err != nil {
+			return err
+		}
+
+		booking = &models.Booking{
+			UserID:      link.UserID,
+			PackageID:   link.PackageID,
+			LeadID:      &link.LeadID,
+			PaymentID:   &payment.ID,
+			Title:       link.Title,
+			Description: link.Description,
+			Type:        models.BookingTypeConsultation,
+			Status:      models.BookingStatusPending,
+			ScheduledAt: now,
+			TotalAmount: link.Amount,
+			Currency:    link.Currency,
+			BookedAt:    now,
+		}
+		if link.Lead != nil {
+			booking.BeraterID = link.Lead.BeraterID
+			booking.FamilyID = link.Lead.FamilyID
+		}
+		if booking.BeraterID != nil {
+			var customer, berater models.User
+			if err := tx.Select("id", "language", "spoken_languages").First(&customer, "id = ?", link.UserID).Error; err != nil {
+				return err
+			}
+			if err := tx.Select("id", "working_languages").First(&berater, "id = ?", *booking.BeraterID).Error; err != nil {
+				return err
+			}
+			booking.MatchLanguages(&customer, &berater)
+		}
+		if err := tx.Create(booking).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Model(&link).Updates(map[string]interface{}{
+			"status":            models.PaymentLinkStatusPaid,
+			"paid_at":           now,
+			"booking_id":        booking.ID,
+			"payment_id":        payment.ID,
+			"stripe_session_id": checkout.SessionID,
+		}).Error; err != nil {
+			return err
+		}
+
+		if paid != nil {
+			return paid(tx, booking, payment)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to complete payment link: %w", err)
+	}
+	if booking != nil {
+		s.logger.Info("Payment link paid",
+			zap.String("payment_link_id", id.String()),
+			zap.String("booking_id", booking.ID.String()))
+	}
+	return booking, payment, nil
+}
+
+// PayURL returns the public URL that starts the checkout of a payment link
+func (s *Service) PayURL(link *models.PaymentLink) string {
+	return s.baseURL + "/pay/" + link.Token
+}
+
+// toLink adds the URL sent to the customer and the opening stats of the short link
+func (s *Service) toLink(link *models.PaymentLink) *Link {
+	result := &Link{PaymentLink: link, URL: s.PayURL(link)}
+	if link.ShortLink != nil {
+		result.URL = s.shortLinks.URL(link.ShortLink)
+		result.Opens = link.ShortLink.ClickCount
+		result.FirstOpenedAt = link.ShortLink.FirstClickedAt
+		result.LastOpenedAt = link.ShortLink.LastClickedAt
+	}
+	return result
+}
+
+func roundCents(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
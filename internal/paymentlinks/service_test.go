@@ -0,0 +1,198 @@
+package paymentlinks
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"elterngeld-portal/config"
+	"elterngeld-portal/internal/catalog"
+	"elterngeld-portal/internal/credit"
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/internal/outbox"
+	"elterngeld-portal/internal/shortlink"
+	"elterngeld-portal/tests/testutils"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+func TestCreate(t *testing.T) {
+	db, service := setupTestService(t)
+	berater := testutils.CreateTestUser(t, db, models.RoleBerater)
+	lead := createLead(t, db, berater)
+	pkg := &models.Package{Name: "Premium", Type: models.PackageTypePremium, Price: 249, IsActive: true}
+	require.NoError(t, db.Create(pkg).Error)
+
+	// An offer of a package is priced by the catalog
+	offer, err := service.Create(Input{LeadID: lead.ID, PackageID: &pkg.ID}, berater)
+	require.NoError(t, err)
+	assert.Equal(t, 249.0, offer.Amount)
+	assert.Equal(t, "Premium", offer.Title)
+	assert.Equal(t, lead.UserID, offer.UserID)
+	assert.Equal(t, models.PaymentLinkStatusOpen, offer.Status)
+	assert.True(t, strings.HasPrefix(offer.URL, "https://portal.example.com/r/"))
+	require.NotNil(t, offer.ExpiresAt)
+	assert.WithinDuration(t, time.Now().Add(DefaultTTL), *offer.ExpiresAt, time.Minute)
+
+	// A custom amount overrides the package price
+	custom, err := service.Create(Input{LeadID: lead.ID, PackageID: &pkg.ID, Amount: 199.999, Title: "Premium (Telefonangebot)", TTL: time.Hour}, berater)
+	require.NoError(t, err)
+	assert.Equal(t, 200.0, custom.Amount)
+	assert.Equal(t, "Premium (Telefonangebot)", custom.Title)
+
+	_, err = service.Create(Input{LeadID: lead.ID, Amount: 50}, berater)
+	assert.ErrorIs(t, err, ErrInvalidLink, "title missing")
+	_, err = service.Create(Input{LeadID: lead.ID, Title: "Beratung"}, berater)
+	assert.ErrorIs(t, err, ErrInvalidLink, "amount missing")
+	_, err = service.Create(Input{LeadID: uuid.New(), Amount: 50, Title: "Beratung"}, berater)
+	assert.ErrorIs(t, err, ErrLeadNotFound)
+	missing := uuid.New()
+	_, err = service.Create(Input{LeadID: lead.ID, PackageID: &missing}, berater)
+	assert.ErrorIs(t, err, catalog.ErrPackageNotFound)
+
+	// Opens are the clicks on the short link
+	var shortLink models.ShortLink
+	require.NoError(t, db.First(&shortLink, "id = ?", *offer.ShortLinkID).Error)
+	assert.Equal(t, service.PayURL(offer.PaymentLink), shortLink.TargetURL)
+	_, err = service.shortLinks.Resolve(shortLink.Code, shortlink.Click{})
+	require.NoError(t, err)
+
+	links, err := service.List(lead.ID)
+	require.NoError(t, err)
+	require.Len(t, links, 2)
+	assert.Equal(t, offer.ID, links[1].ID)
+	assert.Equal(t, 1, links[1].Opens)
+	assert.NotNil(t, links[1].FirstOpenedAt)
+	assert.Equal(t, 0, links[0].Opens)
+
+	// Only links sent by email queue the offer
+	var queued int64
+	require.NoError(t, db.Model(&models.OutboxMessage{}).Count(&queued).Error)
+	assert.Zero(t, queued)
+	sent, err := service.Create(Input{LeadID: lead.ID, PackageID: &pkg.ID, SendEmail: true}, berater)
+	require.NoError(t, err)
+	var message models.OutboxMessage
+	require.NoError(t, db.First(&message).Error)
+	assert.Equal(t, outbox.TopicEmailOffer, message.Topic)
+	var payload outbox.PaymentLinkMessage
+	require.NoError(t, outbox.Decode(&message, &payload))
+	assert.Equal(t, sent.ID, payload.PaymentLinkID)
+
+	stored, err := service.Get(sent.ID)
+	require.NoError(t, err)
+	assert.Equal(t, sent.URL, stored.URL)
+	_, err = service.Get(uuid.New())
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestComplete(t *testing.T) {
+	db, service := setupTestService(t)
+	berater := testutils.CreateTestUser(t, db, models.RoleBerater)
+	lead := createLead(t, db, berater)
+	link, err := service.Create(Input{LeadID: lead.ID, Amount: 149, Title: "Beratung am Telefon"}, berater)
+	require.NoError(t, err)
+
+	opened, err := service.Open(link.Token)
+	require.NoError(t, err)
+	require.NotNil(t, opened.User)
+	assert.Equal(t, lead.UserID, opened.User.ID)
+	require.NoError(t, service.SetSession(opened, "cs_test_1"))
+
+	calls := 0
+	checkout := Checkout{SessionID: "cs_test_1", PaymentIntentID: "pi_test_1", Amount: 149}
+	booking, payment, err := service.Complete(link.ID, checkout, func(tx *gorm.DB, booking *models.Booking, payment *models.Payment) error {
+		calls++
+		return nil
+	})
+	require.NoError(t, err)
+	require.NotNil(t, booking)
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, models.BookingStatusPending, booking.Status, "scheduled later by the Berater")
+	assert.Equal(t, lead.ID, *booking.LeadID)
+	assert.Equal(t, berater.ID, *booking.BeraterID)
+	assert.Equal(t, payment.ID, *booking.PaymentID)
+	assert.Equal(t, 149.0, booking.TotalAmount)
+	assert.True(t, payment.IsPaid())
+	assert.Equal(t, lead.ID, payment.LeadID)
+
+	var paid models.PaymentLink
+	require.NoError(t, db.First(&paid, "id = ?", link.ID).Error)
+	assert.Equal(t, models.PaymentLinkStatusPaid, paid.Status)
+	assert.Equal(t, booking.ID, *paid.BookingID)
+	assert.NotNil(t, paid.PaidAt)
+
+	// Replayed webhooks do not book twice
+	booking, _, err = service.Complete(link.ID, checkout, nil)
+	require.NoError(t, err)
+	assert.Nil(t, booking)
+	var count int64
+	require.NoError(t, db.Model(&models.Booking{}).Count(&count).Error)
+	assert.Equal(t, int64(1), count)
+
+	// Paid links cannot be paid again
+	_, err = service.Open(link.Token)
+	assert.ErrorIs(t, err, ErrNotPayable)
+	_, _, err = service.Complete(link.ID, Checkout{SessionID: "cs_test_2", Amount: 149}, nil)
+	assert.ErrorIs(t, err, ErrNotPayable)
+	_, err = service.Open("unknown")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestCancel(t *testing.T) {
+	db, service := setupTestService(t)
+	berater := testutils.CreateTestUser(t, db, models.RoleBerater)
+	lead := createLead(t, db, berater)
+	link, err := service.Create(Input{LeadID: lead.ID, Amount: 99, Title: "Beratung"}, berater)
+	require.NoError(t, err)
+
+	_, err = service.Cancel(uuid.New(), link.ID)
+	assert.ErrorIs(t, err, ErrNotFound, "link of another lead")
+
+	cancelled, err := service.Cancel(lead.ID, link.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.PaymentLinkStatusCancelled, cancelled.Status)
+
+	_, err = service.Cancel(lead.ID, link.ID)
+	assert.ErrorIs(t, err, ErrNotPayable)
+	_, err = service.Open(link.Token)
+	assert.ErrorIs(t, err, ErrNotPayable)
+
+	// Expired links cannot be opened
+	expired, err := service.Create(Input{LeadID: lead.ID, Amount: 99, Title: "Beratung", TTL: time.Hour}, berater)
+	require.NoError(t, err)
+	service.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	_, err = service.Open(expired.Token)
+	assert.ErrorIs(t, err, ErrNotPayable)
+}
+
+func setupTestService(t *testing.T) (*gorm.DB, *Service) {
+	t.Helper()
+	db := testutils.NewSQLiteDB(t, &models.User{}, &models.Lead{}, &models.Package{}, &models.Addon{}, &models.PackageAddon{},
+		&models.Booking{}, &models.BookingAddon{}, &models.Payment{}, &models.Coupon{}, &models.CreditEntry{},
+		&models.ShortLink{}, &models.ShortLinkClick{}, &models.Activity{}, &models.PaymentLink{}, &models.OutboxMessage{})
+
+	cfg := &config.Config{
+		App:     config.AppConfig{BaseURL: "https://portal.example.com"},
+		Booking: config.BookingConfig{VATRate: 0.19},
+	}
+	catalogService := catalog.NewService(db, zap.NewNop(), cfg, credit.NewService(db, zap.NewNop()))
+	relay := outbox.NewService(db, zap.NewNop())
+	relay.Register(outbox.TopicEmailOffer, func(message *models.OutboxMessage) error { return nil })
+	return db, NewService(db, zap.NewNop(), cfg, catalogService, shortlink.NewService(db, zap.NewNop(), cfg), relay)
+}
+
+func createLead(t *testing.T, db *gorm.DB, berater *models.User) *models.Lead {
+	t.Helper()
+	customer := testutils.CreateTestUser(t, db, models.RoleUser)
+	lead := &models.Lead{
+		UserID:    customer.ID,
+		BeraterID: &berater.ID,
+		Title:     "Elterngeld-Beratung",
+	}
+	require.NoError(t, db.Create(lead).Error)
+	return lead
+}
@@ -0,0 +1,186 @@
+package pdf
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"elterngeld-portal/internal/models"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// DefaultTenant is the tenant of PDFs requested without one
+	DefaultTenant = "default"
+
+	defaultColor = "#1f6f8b"
+	// maxLogoSize limits the decoded logo, which is embedded into every PDF
+	maxLogoSize = 256 << 10
+)
+
+var (
+	tenantPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,49}$`)
+	colorPattern  = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+	logoTypes     = []string{"data:image/png;base64,", "data:image/jpeg;base64,", "data:image/svg+xml;base64,"}
+)
+
+// Branding is the letterhead a tenant's PDFs are rendered with
+type Branding struct {
+	Tenant       string `json:"tenant"`
+	CompanyName  string `json:"company_name"`
+	Logo         string `json:"logo"`
+	PrimaryColor string `json:"primary_color"`
+	Footer       string `json:"footer"`
+	IsDefault    bool   `json:"is_default"` // no branding was stored for the tenant
+}
+
+// BrandingInput changes the branding of a tenant. Empty optional fields fall
+// back to the defaults.
+type BrandingInput struct {
+	CompanyName  string `json:"company_name" binding:"required"`
+	Logo         string `json:"logo"` // data URI of a PNG, JPEG or SVG image
+	PrimaryColor string `json:"primary_color"`
+	Footer       string `json:"footer"`
+}
+
+// Branding returns the branding of a tenant, or the default branding from
+// the configuration when none was stored
+func (s *Service) Branding(tenant string) (Branding, error) {
+	tenant, err := normalizeTenant(tenant)
+	if err != nil {
+		return Branding{}, err
+	}
+
+	var record models.PDFBranding
+	err = s.db.Where("tenant = ?", tenant).First(&record).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return s.defaultBranding(tenant), nil
+	}
+	if err != nil {
+		return Branding{}, fmt.Errorf("failed to load branding: %w", err)
+	}
+	return s.branding(&record), nil
+}
+
+// Brandings lists the stored brandings by tenant
+func (s *Service) Brandings() ([]Branding, error) {
+	var records []models.PDFBranding
+	if err := s.db.Order("tenant ASC").Find(&records).Error; err != nil {
+		return nil, fmt.Errorf("failed to load brandings: %w", err)
+	}
+	result := make([]Branding, len(records))
+	for i := range records {
+		result[i] = s.branding(&records[i])
+	}
+	return result, nil
+}
+
+// SetBranding stores the branding of a tenant
+func (s *Service) SetBranding(tenant string, input BrandingInput, userID uuid.UUID) (Branding, error) {
+	tenant, err := normalizeTenant(tenant)
+	if err != nil {
+		return Branding{}, err
+	}
+	record := models.PDFBranding{
+		Tenant:       tenant,
+		CompanyName:  strings.TrimSpace(input.CompanyName),
+		Logo:         strings.TrimSpace(input.Logo),
+		PrimaryColor: strings.TrimSpace(input.PrimaryColor),
+		Footer:       strings.TrimSpace(input.Footer),
+		UpdatedByID:  &userID,
+	}
+	switch {
+	case record.CompanyName == "" || len(record.CompanyName) > 200:
+		return Branding{}, fmt.Errorf("%w: company name must have 1 to 200 characters", ErrInvalidInput)
+	case record.PrimaryColor != "" && !colorPattern.MatchString(record.PrimaryColor):
+		return Branding{}, fmt.Errorf("%w: primary color must be a hex color like #1f6f8b", ErrInvalidInput)
+	case len(record.Footer) > 500:
+		return Branding{}, fmt.Errorf("%w: footer must not exceed 500 characters", ErrInvalidInput)
+	}
+	if record.Logo != "" {
+		if err := validLogo(record.Logo); err != nil {
+			return Branding{}, err
+		}
+	}
+
+	err = s.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "tenant"}},
+		DoUpdates: clause.AssignmentColumns([]string{"company_name", "logo", "primary_color", "footer", "updated_by_id", "updated_at"}),
+	}).Create(&record).Error
+	if err != nil {
+		return Branding{}, fmt.Errorf("failed to save branding: %w", err)
+	}
+
+	s.logger.Info("PDF branding updated",
+		zap.String("tenant", tenant),
+		zap.String("updated_by", userID.String()))
+	return s.Branding(tenant)
+}
+
+func (s *Service) defaultBranding(tenant string) Branding {
+	return Branding{
+		Tenant:       tenant,
+		CompanyName:  s.companyName,
+		Logo:         defaultLogoURI,
+		PrimaryColor: defaultColor,
+		Footer:       s.companyName,
+		IsDefault:    true,
+	}
+}
+
+// branding fills the optional fields of a stored branding with the defaults
+func (s *Service) branding(record *models.PDFBranding) Branding {
+	brand := Branding{
+		Tenant:       record.Tenant,
+		CompanyName:  record.CompanyName,
+		Logo:         record.Logo,
+		PrimaryColor: record.PrimaryColor,
+		Footer:       record.Footer,
+	}
+	if brand.Logo == "" {
+		brand.Logo = defaultLogoURI
+	}
+	if brand.PrimaryColor == "" {
+		brand.PrimaryColor = defaultColor
+	}
+	if brand.Footer == "" {
+		brand.Footer = brand.CompanyName
+	}
+	return brand
+}
+
+func normalizeTenant(tenant string) (string, error) {
+	tenant = strings.ToLower(strings.TrimSpace(tenant))
+	if tenant == "" {
+		return DefaultTenant, nil
+	}
+	if !tenantPattern.MatchString(tenant) {
+		return "", fmt.Errorf("%w: tenant must consist of up to 50 lowercase letters, digits, dashes and underscores", ErrInvalidInput)
+	}
+	return tenant, nil
+}
+
+// validLogo checks that a logo is a base64 data URI of a supported image type
+// within maxLogoSize
+func validLogo(logo string) error {
+	for _, prefix := range logoTypes {
+		if !strings.HasPrefix(logo, prefix) {
+			continue
+		}
+		data, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(logo, prefix))
+		if err != nil {
+			return fmt.Errorf("%w: logo is not valid base64", ErrInvalidInput)
+		}
+		if len(data) > maxLogoSize {
+			return fmt.Errorf("%w: logo must not exceed %d KB", ErrInvalidInput, maxLogoSize>>10)
+		}
+		return nil
+	}
+	return fmt.Errorf("%w: logo must be a data URI of a PNG, JPEG or SVG image", ErrInvalidInput)
+}
@@ -0,0 +1,52 @@
+package pdf
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+)
+
+// wkhtmltopdf converts HTML to an A4 PDF with the configured wkhtmltopdf
+// binary. The HTML is passed on stdin and the PDF read from stdout, so no
+// temporary files are written; the footer carries the page numbers.
+func (s *Service) wkhtmltopdf(html []byte, footer string) ([]byte, error) {
+	if s.converter == "" {
+		return nil, ErrConverterUnavailable
+	}
+	path, err := exec.LookPath(s.converter)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s is not installed", ErrConverterUnavailable, s.converter)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+
+	args := []string{
+		"--quiet",
+		"--encoding", "utf-8",
+		"--page-size", "A4",
+		"--margin-top", "20mm",
+		"--margin-bottom", "18mm",
+		"--margin-left", "20mm",
+		"--margin-right", "20mm",
+		"--disable-local-file-access",
+		"--footer-font-size", "8",
+		"--footer-right", "[page] / [topage]",
+	}
+	if footer != "" {
+		args = append(args, "--footer-left", footer)
+	}
+	args = append(args, "-", "-")
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, path, args...)
+	cmd.Stdin = bytes.NewReader(html)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("%s failed: %w: %s", filepath.Base(path), err, bytes.TrimSpace(stderr.Bytes()))
+	}
+	return stdout.Bytes(), nil
+}
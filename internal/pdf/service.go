@@ -0,0 +1,326 @@
+// Package pdf renders invoices, proposals, checklists and Bezugspläne from
+// embedded HTML templates to PDF with wkhtmltopdf. Every tenant's PDFs carry
+// its own letterhead. PDFs can be rendered on request or generated in the
+// background through the outbox, which retries failed conversions.
+//
+// Simple text documents that must render without external tools are written
+// with pkg/pdf instead.
+package pdf
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"elterngeld-portal/config"
+	"elterngeld-portal/internal/mergefields"
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/internal/outbox"
+	"elterngeld-portal/internal/scopes"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrUnknownTemplate is returned for template names without a template
+	ErrUnknownTemplate = errors.New("unknown PDF template")
+	// ErrRenderFailed is returned when the template data does not fit the template
+	ErrRenderFailed = errors.New("PDF template could not be rendered")
+	// ErrConverterUnavailable is returned when wkhtmltopdf is not configured or installed
+	ErrConverterUnavailable = errors.New("PDF converter unavailable")
+	// ErrInvalidInput is returned for invalid template data, tenants or brandings
+	ErrInvalidInput = errors.New("invalid PDF input")
+	// ErrJobNotFound is returned for unknown jobs and jobs of other users
+	ErrJobNotFound = errors.New("PDF job not found")
+	// ErrNotReady is returned when downloading a PDF that was not generated yet
+	ErrNotReady = errors.New("PDF not generated yet")
+)
+
+// Request describes a PDF to render. Data is the template data; it is
+// encoded as JSON, so templates see the field names of its JSON encoding.
+// Merge fields like {{customer.name}} in its texts are filled in from Fields.
+type Request struct {
+	Template string             `json:"template" binding:"required"`
+	Tenant   string             `json:"tenant"`
+	FileName string             `json:"file_name"`
+	Data     interface{}        `json:"data" binding:"required"`
+	Fields   mergefields.Values `json:"-"`
+}
+
+// Service renders PDFs from templates and generates them in the background
+type Service struct {
+	db          *gorm.DB
+	logger      *zap.Logger
+	relay       *outbox.Service
+	converter   string
+	timeout     time.Duration
+	path        string
+	companyName string
+	convert     func(html []byte, footer string) ([]byte, error)
+	now         func() time.Time
+}
+
+func NewService(db *gorm.DB, logger *zap.Logger, cfg *config.Config, relay *outbox.Service) *Service {
+	timeout := cfg.PDF.Timeout
+	if timeout <= 0 {
+		timeout = time.Minute
+	}
+	companyName := cfg.App.CompanyName
+	if companyName == "" {
+		companyName = "Elterngeld-Portal"
+	}
+
+	s := &Service{
+		db:          db,
+		logger:      logger,
+		relay:       relay,
+		converter:   cfg.PDF.Converter,
+		timeout:     timeout,
+		path:        cfg.PDF.Path,
+		companyName: companyName,
+		now:         time.Now,
+	}
+	s.convert = s.wkhtmltopdf
+	return s
+}
+
+// Render renders a PDF right away
+func (s *Service) Render(req Request) ([]byte, error) {
+	_, brand, data, err := s.prepare(req)
+	if err != nil {
+		return nil, err
+	}
+	return s.render(req.Template, brand, data)
+}
+
+// Enqueue stores a PDF job and queues its generation in tx, so the PDF is
+// generated exactly when the change it belongs to is committed. Callers
+// without a transaction pass nil.
+func (s *Service) Enqueue(tx *gorm.DB, req Request, requestedBy uuid.UUID) (*models.PDFJob, error) {
+	encoded, brand, _, err := s.prepare(req)
+	if err != nil {
+		return nil, err
+	}
+	if tx == nil {
+		tx = s.db
+	}
+
+	job := &models.PDFJob{
+		Template:      req.Template,
+		Tenant:        brand.Tenant,
+		FileName:      fileName(req.FileName, req.Template),
+		Data:          string(encoded),
+		Status:        models.PDFJobStatusPending,
+		RequestedByID: requestedBy,
+	}
+	err = tx.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(job).Error; err != nil {
+			return fmt.Errorf("failed to create PDF job: %w", err)
+		}
+		return s.relay.Enqueue(tx, outbox.TopicPDFGenerate, job.ID.String(), outbox.PDFJobMessage{JobID: job.ID})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// Generate renders the PDF of a job and stores it. Conversion errors are
+// returned so the outbox retries them; data that does not fit the template
+// fails the job for good.
+func (s *Service) Generate(jobID uuid.UUID) error {
+	var job models.PDFJob
+	if err := s.db.First(&job, "id = ?", jobID).Error; err != nil {
+		return fmt.Errorf("failed to load PDF job: %w", err)
+	}
+	if job.Status != models.PDFJobStatusPending {
+		return nil
+	}
+
+	var data interface{}
+	if err := json.Unmarshal([]byte(job.Data), &data); err != nil {
+		return s.fail(&job, fmt.Errorf("%w: %v", ErrInvalidInput, err))
+	}
+	brand, err := s.Branding(job.Tenant)
+	if err != nil {
+		return err
+	}
+
+	content, err := s.render(job.Template, brand, data)
+	if errors.Is(err, ErrUnknownTemplate) || errors.Is(err, ErrRenderFailed) {
+		return s.fail(&job, err)
+	}
+	if err != nil {
+		if updateErr := s.db.Model(&job).Update("error", err.Error()).Error; updateErr != nil {
+			s.logger.Error("Failed to record PDF job error", zap.String("pdf_job_id", job.ID.String()), zap.Error(updateErr))
+		}
+		return err
+	}
+
+	if err := os.MkdirAll(s.path, 0o750); err != nil {
+		return fmt.Errorf("failed to create PDF directory: %w", err)
+	}
+	if err := os.WriteFile(s.file(job.ID), content, 0o640); err != nil {
+		return fmt.Errorf("failed to store PDF: %w", err)
+	}
+
+	now := s.now()
+	err = s.db.Model(&job).Updates(map[string]interface{}{
+		"status":       models.PDFJobStatusCompleted,
+		"size":         len(content),
+		"error":        "",
+		"completed_at": &now,
+	}).Error
+	if err != nil {
+		return fmt.Errorf("failed to complete PDF job: %w", err)
+	}
+
+	s.logger.Info("PDF generated",
+		zap.String("pdf_job_id", job.ID.String()),
+		zap.String("template", job.Template),
+		zap.String("tenant", job.Tenant),
+		zap.Int("size", len(content)))
+	return nil
+}
+
+// Job returns a job of the viewer; admins see every job
+func (s *Service) Job(jobID uuid.UUID, viewer scopes.Viewer) (*models.PDFJob, error) {
+	query := s.db.Where("id = ?", jobID)
+	if viewer.Role != models.RoleAdmin {
+		query = query.Where("requested_by_id = ?", viewer.ID)
+	}
+
+	var job models.PDFJob
+	if err := query.First(&job).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrJobNotFound
+		}
+		return nil, fmt.Errorf("failed to load PDF job: %w", err)
+	}
+	return &job, nil
+}
+
+// Content returns the generated PDF of a job
+func (s *Service) Content(job *models.PDFJob) ([]byte, error) {
+	if job.Status != models.PDFJobStatusCompleted {
+		return nil, ErrNotReady
+	}
+	content, err := os.ReadFile(s.file(job.ID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read PDF: %w", err)
+	}
+	return content, nil
+}
+
+// prepare checks a request, fills in the merge fields of its data and encodes
+// it. The data is decoded again so templates see the same values whether a
+// PDF is rendered right away or in the background.
+func (s *Service) prepare(req Request) ([]byte, Branding, interface{}, error) {
+	if _, ok := templates[req.Template]; !ok {
+		return nil, Branding{}, nil, fmt.Errorf("%w: %s", ErrUnknownTemplate, req.Template)
+	}
+	brand, err := s.Branding(req.Tenant)
+	if err != nil {
+		return nil, Branding{}, nil, err
+	}
+
+	encoded, err := json.Marshal(req.Data)
+	if err != nil {
+		return nil, Branding{}, nil, fmt.Errorf("%w: %v", ErrInvalidInput, err)
+	}
+	var data interface{}
+	if err := json.Unmarshal(encoded, &data); err != nil {
+		return nil, Branding{}, nil, fmt.Errorf("%w: %v", ErrInvalidInput, err)
+	}
+	if _, ok := data.(map[string]interface{}); !ok {
+		return nil, Branding{}, nil, fmt.Errorf("%w: data must be an object", ErrInvalidInput)
+	}
+
+	var unknown []string
+	data = merge(data, req.Fields, &unknown)
+	if len(unknown) > 0 {
+		slices.Sort(unknown)
+		return nil, Branding{}, nil, fmt.Errorf("%w: unknown merge fields %s", ErrInvalidInput, strings.Join(slices.Compact(unknown), ", "))
+	}
+	if encoded, err = json.Marshal(data); err != nil {
+		return nil, Branding{}, nil, fmt.Errorf("%w: %v", ErrInvalidInput, err)
+	}
+	return encoded, brand, data, nil
+}
+
+// merge fills in the merge fields of the texts in decoded template data and
+// collects the unknown ones
+func merge(data interface{}, fields mergefields.Values, unknown *[]string) interface{} {
+	switch value := data.(type) {
+	case string:
+		*unknown = append(*unknown, mergefields.Unknown(value)...)
+		merged, _ := mergefields.Merge(value, fields)
+		return merged
+	case map[string]interface{}:
+		for key, item := range value {
+			value[key] = merge(item, fields, unknown)
+		}
+	case []interface{}:
+		for i, item := range value {
+			value[i] = merge(item, fields, unknown)
+		}
+	}
+	return data
+}
+
+func (s *Service) render(name string, brand Branding, data interface{}) ([]byte, error) {
+	html, err := renderHTML(name, brand, data)
+	if err != nil {
+		return nil, err
+	}
+	return s.convert(html, brand.Footer)
+}
+
+// fail marks a job as failed. The error is only logged, as retrying it
+// would not help.
+func (s *Service) fail(job *models.PDFJob, cause error) error {
+	err := s.db.Model(job).Updates(map[string]interface{}{
+		"status": models.PDFJobStatusFailed,
+		"error":  cause.Error(),
+	}).Error
+	if err != nil {
+		return fmt.Errorf("failed to record failed PDF job: %w", err)
+	}
+	s.logger.Warn("PDF job failed",
+		zap.String("pdf_job_id", job.ID.String()),
+		zap.String("template", job.Template),
+		zap.Error(cause))
+	return nil
+}
+
+func (s *Service) file(jobID uuid.UUID) string {
+	return filepath.Join(s.path, jobID.String()+".pdf")
+}
+
+// fileName sanitizes the requested download name; it defaults to the
+// template name
+func fileName(name, template string) string {
+	name = strings.Map(func(r rune) rune {
+		if r < 0x20 || r == '"' || r == '/' || r == '\\' {
+			return -1
+		}
+		return r
+	}, strings.TrimSpace(name))
+	if name == "" || strings.Trim(name, ".") == "" {
+		name = template
+	}
+	if !strings.HasSuffix(strings.ToLower(name), ".pdf") {
+		name += ".pdf"
+	}
+	if len(name) > 255 {
+		name = strings.ToValidUTF8(name[:251], "") + ".pdf"
+	}
+	return name
+}
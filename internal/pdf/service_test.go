@@ -0,0 +1,252 @@
+package pdf
+
+import (
+	"encoding/base64"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"elterngeld-portal/config"
+	"elterngeld-portal/internal/mergefields"
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/internal/outbox"
+	"elterngeld-portal/internal/scopes"
+	"elterngeld-portal/tests/testutils"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+func TestRenderHTML(t *testing.T) {
+	brand := Branding{CompanyName: "Familienberatung Nord", Logo: defaultLogoURI, PrimaryColor: "#aa3300"}
+	samples := map[string]map[string]interface{}{
+		TemplateInvoice: {
+			"number":    "RE-2026-0042",
+			"date":      "2026-05-04T10:00:00Z",
+			"recipient": map[string]interface{}{"name": "Anna Muster", "address": "Hauptstraße 1\n50667 Köln"},
+			"items":     []interface{}{map[string]interface{}{"description": "Elterngeld-Beratung", "amount": 149.0}},
+			"total":     149.0,
+		},
+		TemplateProposal: {
+			"number": "AN-7",
+			"items":  []interface{}{map[string]interface{}{"description": "Antragsprüfung", "amount": 89.0}},
+			"total":  89.0,
+		},
+		TemplateChecklist: {
+			"title": "Unterlagen für den Antrag",
+			"sections": []interface{}{map[string]interface{}{
+				"title": "Einkommen",
+				"items": []interface{}{map[string]interface{}{"label": "Gehaltsnachweise", "done": true}},
+			}},
+		},
+		TemplatePayoutPlan: {
+			"scenario_name":    "Partnerschaftsbonus",
+			"child_birth_date": "2026-03-15",
+			"total":            15600.0,
+			"duration":         14,
+			"parents": []interface{}{map[string]interface{}{
+				"name":   "Mutter",
+				"total":  1300.0,
+				"months": []interface{}{map[string]interface{}{"lebensmonat": 1, "type": "basis", "amount": 1300.0}},
+			}},
+		},
+	}
+	require.ElementsMatch(t, Templates(), []string{TemplateInvoice, TemplateProposal, TemplateChecklist, TemplatePayoutPlan})
+
+	for name, data := range samples {
+		html, err := renderHTML(name, brand, data)
+		require.NoError(t, err, name)
+		content := string(html)
+		assert.Contains(t, content, "Familienberatung Nord", name)
+		assert.Contains(t, content, "#aa3300", name)
+		assert.Contains(t, content, `src="data:image/svg`, name)
+		assert.NotContains(t, content, "<no value>", name)
+	}
+
+	html, err := renderHTML(TemplateInvoice, brand, samples[TemplateInvoice])
+	require.NoError(t, err)
+	assert.Contains(t, string(html), "149,00 €")
+	assert.Contains(t, string(html), "04.05.2026")
+
+	// Data is escaped, logos that are not image data URIs are dropped
+	brand.Logo = "javascript:alert(1)"
+	html, err = renderHTML(TemplateChecklist, brand, map[string]interface{}{"title": "<script>"})
+	require.NoError(t, err)
+	assert.NotContains(t, string(html), "<script>")
+	assert.NotContains(t, string(html), "<img")
+
+	_, err = renderHTML("brief", brand, map[string]interface{}{})
+	assert.ErrorIs(t, err, ErrUnknownTemplate)
+}
+
+func TestEnqueueAndGenerate(t *testing.T) {
+	db, service, relay := setupTestService(t)
+	requester := uuid.New()
+	var footers []string
+	service.convert = func(html []byte, footer string) ([]byte, error) {
+		footers = append(footers, footer)
+		return append([]byte("%PDF-"), html[:10]...), nil
+	}
+
+	_, err := service.Enqueue(nil, Request{Template: "brief", Data: map[string]interface{}{}}, requester)
+	assert.ErrorIs(t, err, ErrUnknownTemplate)
+	_, err = service.Enqueue(nil, Request{Template: TemplateChecklist, Data: []string{"a"}}, requester)
+	assert.ErrorIs(t, err, ErrInvalidInput)
+	_, err = service.Enqueue(nil, Request{Template: TemplateChecklist, Tenant: "Nicht gültig!", Data: map[string]interface{}{}}, requester)
+	assert.ErrorIs(t, err, ErrInvalidInput)
+
+	job, err := service.Enqueue(nil, Request{
+		Template: TemplateChecklist,
+		FileName: "../Checkliste",
+		Data:     map[string]interface{}{"title": "Unterlagen"},
+	}, requester)
+	require.NoError(t, err)
+	assert.Equal(t, models.PDFJobStatusPending, job.Status)
+	assert.Equal(t, DefaultTenant, job.Tenant)
+	assert.Equal(t, "..Checkliste.pdf", job.FileName)
+
+	owner := scopes.Viewer{ID: requester, Role: models.RoleBerater}
+	_, err = service.Content(job)
+	assert.ErrorIs(t, err, ErrNotReady)
+
+	delivered, err := relay.Relay()
+	require.NoError(t, err)
+	assert.Equal(t, 1, delivered)
+	assert.Equal(t, []string{"Elterngeld-Portal"}, footers)
+
+	job, err = service.Job(job.ID, owner)
+	require.NoError(t, err)
+	assert.Equal(t, models.PDFJobStatusCompleted, job.Status)
+	assert.NotNil(t, job.CompletedAt)
+	content, err := service.Content(job)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(content), "%PDF-"))
+	assert.Equal(t, int64(len(content)), job.Size)
+
+	// Generating a job again is a no-op, as the outbox may deliver twice
+	require.NoError(t, service.Generate(job.ID))
+	assert.Len(t, footers, 1)
+
+	_, err = service.Job(job.ID, scopes.Viewer{ID: uuid.New(), Role: models.RoleBerater})
+	assert.ErrorIs(t, err, ErrJobNotFound)
+	_, err = service.Job(job.ID, scopes.Viewer{ID: uuid.New(), Role: models.RoleAdmin})
+	assert.NoError(t, err)
+
+	// Conversion errors are retried, the job stays pending
+	service.convert = func(html []byte, footer string) ([]byte, error) {
+		return nil, ErrConverterUnavailable
+	}
+	job, err = service.Enqueue(nil, Request{Template: TemplateChecklist, Data: map[string]interface{}{}}, requester)
+	require.NoError(t, err)
+	assert.ErrorIs(t, service.Generate(job.ID), ErrConverterUnavailable)
+	require.NoError(t, db.First(job, "id = ?", job.ID).Error)
+	assert.Equal(t, models.PDFJobStatusPending, job.Status)
+	assert.Contains(t, job.Error, "converter unavailable")
+
+	// Data that does not fit the template fails the job for good
+	require.NoError(t, db.Model(job).Update("template", "brief").Error)
+	require.NoError(t, service.Generate(job.ID))
+	require.NoError(t, db.First(job, "id = ?", job.ID).Error)
+	assert.Equal(t, models.PDFJobStatusFailed, job.Status)
+}
+
+func TestRender_UsesTenantBranding(t *testing.T) {
+	_, service, _ := setupTestService(t)
+	var rendered string
+	var footer string
+	service.convert = func(html []byte, f string) ([]byte, error) {
+		rendered, footer = string(html), f
+		return []byte("%PDF-"), nil
+	}
+
+	brand, err := service.Branding("")
+	require.NoError(t, err)
+	assert.True(t, brand.IsDefault)
+	assert.Equal(t, DefaultTenant, brand.Tenant)
+
+	admin := uuid.New()
+	_, err = service.SetBranding("nord", BrandingInput{CompanyName: "Nord", PrimaryColor: "rot"}, admin)
+	assert.ErrorIs(t, err, ErrInvalidInput)
+	_, err = service.SetBranding("nord", BrandingInput{CompanyName: "Nord", Logo: "https://example.com/logo.png"}, admin)
+	assert.ErrorIs(t, err, ErrInvalidInput)
+	_, err = service.SetBranding("nord", BrandingInput{CompanyName: "Nord", Logo: "data:image/png;base64,###"}, admin)
+	assert.ErrorIs(t, err, ErrInvalidInput)
+
+	logo := "data:image/png;base64," + base64.StdEncoding.EncodeToString([]byte("png"))
+	brand, err = service.SetBranding("Nord", BrandingInput{CompanyName: "Familienberatung Nord", Logo: logo, PrimaryColor: "#aa3300"}, admin)
+	require.NoError(t, err)
+	assert.False(t, brand.IsDefault)
+	assert.Equal(t, "nord", brand.Tenant)
+	assert.Equal(t, "Familienberatung Nord", brand.Footer)
+
+	brand, err = service.SetBranding("nord", BrandingInput{CompanyName: "Familienberatung Nord", Footer: "Nord GmbH · Köln"}, admin)
+	require.NoError(t, err)
+	assert.Equal(t, defaultLogoURI, brand.Logo)
+	assert.Equal(t, defaultColor, brand.PrimaryColor)
+	brandings, err := service.Brandings()
+	require.NoError(t, err)
+	require.Len(t, brandings, 1)
+
+	_, err = service.Render(Request{Template: TemplateChecklist, Tenant: "nord", Data: map[string]interface{}{"title": "Unterlagen"}})
+	require.NoError(t, err)
+	assert.Contains(t, rendered, "Familienberatung Nord")
+	assert.Equal(t, "Nord GmbH · Köln", footer)
+}
+
+func TestRender_MergeFields(t *testing.T) {
+	_, service, _ := setupTestService(t)
+	var rendered string
+	service.convert = func(html []byte, footer string) ([]byte, error) {
+		rendered = string(html)
+		return []byte("%PDF-"), nil
+	}
+
+	fields := mergefields.Values{"customer.name": "Anna Schmidt", "lead.application_number": "EG-2026-000123"}
+	_, err := service.Render(Request{
+		Template: TemplateChecklist,
+		Data: map[string]interface{}{
+			"title":      "Unterlagen für {{customer.name}}",
+			"lead_title": "Antrag {{ lead.application_number }}",
+		},
+		Fields: fields,
+	})
+	require.NoError(t, err)
+	assert.Contains(t, rendered, "Unterlagen für Anna Schmidt")
+	assert.Contains(t, rendered, "Antrag EG-2026-000123")
+
+	_, err = service.Render(Request{
+		Template: TemplateChecklist,
+		Data:     map[string]interface{}{"sections": []interface{}{map[string]interface{}{"title": "{{kunde.name}}"}}},
+		Fields:   fields,
+	})
+	assert.ErrorIs(t, err, ErrInvalidInput)
+	assert.Contains(t, err.Error(), "kunde.name")
+}
+
+func TestWkhtmltopdf_Unavailable(t *testing.T) {
+	_, service, _ := setupTestService(t)
+	service.converter = "wkhtmltopdf-not-installed"
+	_, err := service.Render(Request{Template: TemplateChecklist, Data: map[string]interface{}{}})
+	assert.True(t, errors.Is(err, ErrConverterUnavailable))
+}
+
+func setupTestService(t *testing.T) (*gorm.DB, *Service, *outbox.Service) {
+	t.Helper()
+	db := testutils.NewSQLiteDB(t, &models.PDFJob{}, &models.PDFBranding{}, &models.OutboxMessage{})
+
+	relay := outbox.NewService(db, zap.NewNop())
+	cfg := &config.Config{PDF: config.PDFConfig{Path: filepath.Join(t.TempDir(), "generated")}}
+	service := NewService(db, zap.NewNop(), cfg, relay)
+	relay.Register(outbox.TopicPDFGenerate, func(message *models.OutboxMessage) error {
+		var payload outbox.PDFJobMessage
+		if err := outbox.Decode(message, &payload); err != nil {
+			return err
+		}
+		return service.Generate(payload.JobID)
+	})
+	return db, service, relay
+}
@@ -0,0 +1,122 @@
+package pdf
+
+import (
+	"bytes"
+	"embed"
+	"encoding/base64"
+	"fmt"
+	"html/template"
+	"sort"
+	"strings"
+	"time"
+
+	"elterngeld-portal/pkg/i18n"
+)
+
+// Templates of the generated PDFs
+const (
+	TemplateInvoice    = "invoice"
+	TemplateProposal   = "proposal"
+	TemplateChecklist  = "checklist"
+	TemplatePayoutPlan = "payout_plan"
+)
+
+// The templates and their assets are embedded so the binary renders PDFs
+// without files next to it, and the stylesheet and logo are inlined into the
+// HTML so the converter needs neither local file nor network access
+var (
+	//go:embed templates/*.html
+	templateFiles embed.FS
+	//go:embed assets/style.css
+	stylesheet string
+	//go:embed assets/logo.svg
+	defaultLogo []byte
+)
+
+var defaultLogoURI = "data:image/svg+xml;base64," + base64.StdEncoding.EncodeToString(defaultLogo)
+
+// view is what the templates render: the branding of the tenant and the
+// template data as decoded from JSON
+type view struct {
+	Brand Branding
+	Logo  template.URL
+	Style template.CSS
+	Data  interface{}
+}
+
+var templates = parseTemplates(TemplateInvoice, TemplateProposal, TemplateChecklist, TemplatePayoutPlan)
+
+// parseTemplates parses every template together with the shared layout
+func parseTemplates(names ...string) map[string]*template.Template {
+	parsed := make(map[string]*template.Template, len(names))
+	for _, name := range names {
+		parsed[name] = template.Must(template.New(name).Funcs(templateFuncs).
+			ParseFS(templateFiles, "templates/layout.html", "templates/"+name+".html"))
+	}
+	return parsed
+}
+
+// Templates returns the names of the available templates
+func Templates() []string {
+	names := make([]string, 0, len(templates))
+	for name := range templates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+var monthTypeNames = map[string]string{
+	"basis":        "Basiselterngeld",
+	"plus":         "ElterngeldPlus",
+	"partnerbonus": "Partnerschaftsbonus",
+}
+
+var templateFuncs = template.FuncMap{
+	// currency formats an amount like "1.234,56 €"; the currency defaults to EUR
+	"currency": func(amount interface{}, currency ...interface{}) string {
+		value, _ := amount.(float64)
+		code := ""
+		if len(currency) > 0 && currency[0] != nil {
+			code = fmt.Sprint(currency[0])
+		}
+		return i18n.FormatCurrency(i18n.DefaultLanguage, value, code)
+	},
+	// date formats RFC 3339 timestamps and ISO dates like "02.01.2006"
+	"date": func(value interface{}) string {
+		text := fmt.Sprint(value)
+		for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+			if parsed, err := time.Parse(layout, text); err == nil {
+				return parsed.Format("02.01.2006")
+			}
+		}
+		return text
+	},
+	"monthType": func(value interface{}) string {
+		text := fmt.Sprint(value)
+		if name, ok := monthTypeNames[text]; ok {
+			return name
+		}
+		return text
+	},
+}
+
+// renderHTML executes a template with the branding of the tenant
+func renderHTML(name string, brand Branding, data interface{}) ([]byte, error) {
+	tmpl, ok := templates[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownTemplate, name)
+	}
+
+	v := view{Brand: brand, Style: template.CSS(stylesheet), Data: data}
+	// Only data URIs of images are stored as logos, see validLogo
+	if strings.HasPrefix(brand.Logo, "data:image/") {
+		v.Logo = template.URL(brand.Logo)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&buf, "layout", v); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrRenderFailed, err)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,368 @@
+package pipeline
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"elterngeld-portal/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Horizons are the forecast periods in days
+var Horizons = []int{30, 60, 90}
+
+const (
+	// historyDays is the period of leads the stage conversion rates are learned from
+	historyDays = 365
+	// maturityDays leaves recent leads out of the history, they had no time to convert yet
+	maturityDays = 90
+)
+
+// openStatuses are the lead statuses that can still turn into revenue
+var openStatuses = []models.LeadStatus{
+	models.LeadStatusNew,
+	models.LeadStatusInProgress,
+	models.LeadStatusQuestion,
+	models.LeadStatusPaymentPending,
+}
+
+// ForecastFilter narrows the forecast down to the leads of one Berater
+type ForecastFilter struct {
+	BeraterID *uuid.UUID
+}
+
+// StageRate is the historical chance of a lead in a stage to pay and the
+// median time it took; MedianDaysToPaid is null without paid leads
+type StageRate struct {
+	Stage            Stage    `json:"stage"`
+	Leads            int      `json:"leads"`
+	ConversionToPaid float64  `json:"conversion_to_paid"`
+	MedianDaysToPaid *float64 `json:"median_days_to_paid"`
+}
+
+// HorizonForecast is the revenue expected within the given number of days.
+// Horizons are cumulative, the 60 days include the first 30.
+type HorizonForecast struct {
+	Days            int     `json:"days"`
+	Leads           int     `json:"leads"`
+	ExpectedRevenue float64 `json:"expected_revenue"`
+}
+
+// BeraterForecast is the expected revenue of the leads assigned to a Berater;
+// unassigned leads have no Berater ID
+type BeraterForecast struct {
+	BeraterID *uuid.UUID        `json:"berater_id"`
+	Name      string            `json:"name"`
+	Horizons  []HorizonForecast `json:"horizons"`
+}
+
+// PackageForecast is the expected revenue of the leads that booked a package;
+// leads without a booked package have no package ID
+type PackageForecast struct {
+	PackageID *uuid.UUID        `json:"package_id"`
+	Name      string            `json:"name"`
+	Horizons  []HorizonForecast `json:"horizons"`
+}
+
+// ForecastReport is the expected revenue of the open leads
+type ForecastReport struct {
+	GeneratedAt    time.Time         `json:"generated_at"`
+	BeraterID      *uuid.UUID        `json:"berater_id,omitempty"`
+	AverageRevenue float64           `json:"average_revenue"`
+	StageRates     []StageRate       `json:"stage_rates"`
+	Horizons       []HorizonForecast `json:"horizons"`
+	Beraters       []BeraterForecast `json:"beraters"`
+	Packages       []PackageForecast `json:"packages"`
+}
+
+// expectation is the forecast of one open lead
+type expectation struct {
+	beraterID *uuid.UUID
+	packageID *uuid.UUID
+	revenue   float64
+	closeAt   time.Time
+}
+
+// Forecast estimates the revenue of the open leads for the next 30, 60 and
+// 90 days. A lead's chance to pay is the historical conversion rate from the
+// stage it reached to paid, averaged with its lead score when it has one. The
+// revenue is its estimated value, or the price of its booked package, or the
+// average revenue of a paid lead. It is expected at its estimated close date,
+// or after the historical median time from its stage to paid; leads without
+// either are expected at the end of the forecast.
+func (s *Service) Forecast(filter ForecastFilter) (*ForecastReport, error) {
+	now := s.now()
+	rates, err := s.stageRates(now)
+	if err != nil {
+		return nil, err
+	}
+
+	var average struct{ Revenue float64 }
+	if err := s.db.Model(&models.Payment{}).
+		Select("COALESCE(SUM(amount) / NULLIF(COUNT(DISTINCT lead_id), 0), 0) AS revenue").
+		Where("status = ?", models.PaymentStatusSucceeded).
+		Scan(&average).Error; err != nil {
+		return nil, err
+	}
+
+	selected := func() *gorm.DB {
+		// Leads with a successful payment are revenue already
+		query := s.db.Model(&models.Lead{}).
+			Where("leads.status IN ?", openStatuses).
+			Where("NOT EXISTS (SELECT 1 FROM payments WHERE payments.lead_id = leads.id AND payments.status = ?)", models.PaymentStatusSucceeded)
+		if filter.BeraterID != nil {
+			query = query.Where("leads.berater_id = ?", *filter.BeraterID)
+		}
+		return query
+	}
+	byLead, err := s.progress(selected)
+	if err != nil {
+		return nil, err
+	}
+
+	var leads []struct {
+		ID                 uuid.UUID
+		BeraterID          *uuid.UUID
+		EstimatedValue     float64
+		EstimatedCloseDate *time.Time
+		LeadScore          int
+	}
+	if err := selected().Select("leads.id, leads.berater_id, leads.estimated_value, leads.estimated_close_date, leads.lead_score").
+		Scan(&leads).Error; err != nil {
+		return nil, err
+	}
+
+	// The latest booking that was not cancelled names the package
+	var bookings []struct {
+		LeadID    uuid.UUID
+		PackageID uuid.UUID
+		Price     float64
+	}
+	if err := s.db.Model(&models.Booking{}).
+		Select("bookings.lead_id, bookings.package_id, packages.price").
+		Joins("JOIN packages ON packages.id = bookings.package_id").
+		Where("bookings.lead_id IN (?) AND bookings.status <> ?", selected().Select("leads.id"), models.BookingStatusCancelled).
+		Order("bookings.created_at ASC").
+		Scan(&bookings).Error; err != nil {
+		return nil, err
+	}
+	type booked struct {
+		packageID uuid.UUID
+		price     float64
+	}
+	packages := make(map[uuid.UUID]booked, len(bookings))
+	for _, booking := range bookings {
+		packages[booking.LeadID] = booked{packageID: booking.PackageID, price: booking.Price}
+	}
+
+	end := now.AddDate(0, 0, Horizons[len(Horizons)-1])
+	expectations := make([]expectation, 0, len(leads))
+	for _, lead := range leads {
+		p := byLead[lead.ID]
+		if p == nil {
+			continue
+		}
+		rate := rates[p.reached]
+
+		probability := rate.ConversionToPaid / 100
+		if lead.LeadScore > 0 {
+			probability = (probability + math.Min(float64(lead.LeadScore), 100)/100) / 2
+		}
+
+		e := expectation{beraterID: lead.BeraterID, closeAt: end}
+		value := average.Revenue
+		if pkg, ok := packages[lead.ID]; ok {
+			packageID := pkg.packageID
+			e.packageID = &packageID
+			value = pkg.price
+		}
+		if lead.EstimatedValue > 0 {
+			value = lead.EstimatedValue
+		}
+		e.revenue = value * probability
+
+		switch {
+		case lead.EstimatedCloseDate != nil:
+			e.closeAt = *lead.EstimatedCloseDate
+		case rate.MedianDaysToPaid != nil:
+			e.closeAt = p.reachedAt().Add(time.Duration(*rate.MedianDaysToPaid * float64(24*time.Hour)))
+		}
+		// Overdue leads are expected right away
+		if e.closeAt.Before(now) {
+			e.closeAt = now
+		}
+		expectations = append(expectations, e)
+	}
+
+	report := &ForecastReport{
+		GeneratedAt:    now,
+		BeraterID:      filter.BeraterID,
+		AverageRevenue: roundCents(average.Revenue),
+		StageRates:     rates,
+		Horizons:       horizons(expectations, now),
+		Beraters:       []BeraterForecast{},
+		Packages:       []PackageForecast{},
+	}
+
+	byBerater := make(map[uuid.UUID][]expectation)
+	byPackage := make(map[uuid.UUID][]expectation)
+	for _, e := range expectations {
+		var beraterID, packageID uuid.UUID
+		if e.beraterID != nil {
+			beraterID = *e.beraterID
+		}
+		if e.packageID != nil {
+			packageID = *e.packageID
+		}
+		byBerater[beraterID] = append(byBerater[beraterID], e)
+		byPackage[packageID] = append(byPackage[packageID], e)
+	}
+
+	names, err := s.beraterNames(byBerater)
+	if err != nil {
+		return nil, err
+	}
+	for beraterID, group := range byBerater {
+		forecast := BeraterForecast{Name: "Nicht zugewiesen", Horizons: horizons(group, now)}
+		if beraterID != uuid.Nil {
+			id := beraterID
+			forecast.BeraterID = &id
+			forecast.Name = names[beraterID]
+		}
+		report.Beraters = append(report.Beraters, forecast)
+	}
+
+	packageNames, err := s.packageNames(byPackage)
+	if err != nil {
+		return nil, err
+	}
+	for packageID, group := range byPackage {
+		forecast := PackageForecast{Name: "Ohne Paket", Horizons: horizons(group, now)}
+		if packageID != uuid.Nil {
+			id := packageID
+			forecast.PackageID = &id
+			forecast.Name = packageNames[packageID]
+		}
+		report.Packages = append(report.Packages, forecast)
+	}
+
+	// Most expected revenue first
+	last := len(Horizons) - 1
+	sort.Slice(report.Beraters, func(i, j int) bool {
+		a, b := report.Beraters[i].Horizons[last].ExpectedRevenue, report.Beraters[j].Horizons[last].ExpectedRevenue
+		if a != b {
+			return a > b
+		}
+		return report.Beraters[i].Name < report.Beraters[j].Name
+	})
+	sort.Slice(report.Packages, func(i, j int) bool {
+		a, b := report.Packages[i].Horizons[last].ExpectedRevenue, report.Packages[j].Horizons[last].ExpectedRevenue
+		if a != b {
+			return a > b
+		}
+		return report.Packages[i].Name < report.Packages[j].Name
+	})
+
+	return report, nil
+}
+
+// stageRates learns per stage how many leads went on to pay and how long it
+// took, from the leads created in the year before the last maturityDays
+func (s *Service) stageRates(now time.Time) ([]StageRate, error) {
+	until := now.AddDate(0, 0, -maturityDays)
+	since := until.AddDate(0, 0, -historyDays)
+	byLead, err := s.progress(func() *gorm.DB {
+		return s.db.Model(&models.Lead{}).Where("leads.created_at >= ? AND leads.created_at < ?", since, until)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	paid := len(stages) - 1
+	rates := make([]StageRate, len(stages))
+	for i, stage := range stages {
+		rate := StageRate{Stage: stage}
+		converted := 0
+		var durations []time.Duration
+		for _, p := range byLead {
+			if p.reached < i {
+				continue
+			}
+			rate.Leads++
+			if p.reached < paid {
+				continue
+			}
+			converted++
+			from, okFrom := p.at[stage]
+			to, okTo := p.at[StagePaid]
+			if okFrom && okTo && !to.Before(from) {
+				durations = append(durations, to.Sub(from))
+			}
+		}
+		rate.ConversionToPaid = percent(converted, rate.Leads)
+		if hours := medianHours(durations); hours != nil {
+			days := math.Round(*hours/24*10) / 10
+			rate.MedianDaysToPaid = &days
+		}
+		rates[i] = rate
+	}
+	return rates, nil
+}
+
+func (s *Service) beraterNames(byBerater map[uuid.UUID][]expectation) (map[uuid.UUID]string, error) {
+	ids := make([]uuid.UUID, 0, len(byBerater))
+	for id := range byBerater {
+		ids = append(ids, id)
+	}
+	var users []models.User
+	if err := s.db.Unscoped().Select("id, first_name, last_name").Where("id IN ?", ids).Find(&users).Error; err != nil {
+		return nil, err
+	}
+	names := make(map[uuid.UUID]string, len(users))
+	for _, user := range users {
+		names[user.ID] = user.FullName()
+	}
+	return names, nil
+}
+
+func (s *Service) packageNames(byPackage map[uuid.UUID][]expectation) (map[uuid.UUID]string, error) {
+	ids := make([]uuid.UUID, 0, len(byPackage))
+	for id := range byPackage {
+		ids = append(ids, id)
+	}
+	var packages []models.Package
+	if err := s.db.Unscoped().Select("id, name").Where("id IN ?", ids).Find(&packages).Error; err != nil {
+		return nil, err
+	}
+	names := make(map[uuid.UUID]string, len(packages))
+	for _, pkg := range packages {
+		names[pkg.ID] = pkg.Name
+	}
+	return names, nil
+}
+
+// horizons sums the expected revenue of the leads closing within each horizon
+func horizons(expectations []expectation, now time.Time) []HorizonForecast {
+	forecasts := make([]HorizonForecast, len(Horizons))
+	for i, days := range Horizons {
+		forecast := HorizonForecast{Days: days}
+		until := now.AddDate(0, 0, days)
+		for _, e := range expectations {
+			if e.closeAt.After(until) {
+				continue
+			}
+			forecast.Leads++
+			forecast.ExpectedRevenue += e.revenue
+		}
+		forecast.ExpectedRevenue = roundCents(forecast.ExpectedRevenue)
+		forecasts[i] = forecast
+	}
+	return forecasts
+}
+
+func roundCents(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
@@ -0,0 +1,345 @@
+// Package pipeline reports how leads move through the sales pipeline from
+// the first request to a paid booking.
+package pipeline
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/pkg/timeutil"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrInvalidPeriod is returned for unparsable, reversed or too long report periods
+	ErrInvalidPeriod = errors.New("invalid period")
+)
+
+// maxDays limits the report to about a year of leads
+const maxDays = 366
+
+// Stage is a step of the lead pipeline
+type Stage string
+
+const (
+	StageNew       Stage = "new"
+	StageContacted Stage = "contacted"
+	StageQualified Stage = "qualified"
+	StageBooked    Stage = "booked"
+	StagePaid      Stage = "paid"
+)
+
+// stages are the pipeline steps in order
+var stages = []Stage{StageNew, StageContacted, StageQualified, StageBooked, StagePaid}
+
+// Service builds the conversion funnel of the lead pipeline
+type Service struct {
+	db     *gorm.DB
+	logger *zap.Logger
+	now    func() time.Time
+}
+
+func NewService(db *gorm.DB, logger *zap.Logger) *Service {
+	return &Service{
+		db:     db,
+		logger: logger,
+		now:    time.Now,
+	}
+}
+
+// FunnelFilter selects the leads created in a period, as calendar days in a
+// location. Source, campaign and Berater narrow the leads down further.
+type FunnelFilter struct {
+	From      string
+	To        string
+	Source    models.LeadSource
+	Campaign  string // UTM campaign, case-insensitive
+	BeraterID *uuid.UUID
+	Location  *time.Location
+}
+
+// StageReport counts the leads that reached a stage. DropOff is the share of
+// the previous stage's leads that did not reach the stage and MedianHours the
+// median time leads took from the previous stage; it is null without data.
+type StageReport struct {
+	Stage             Stage    `json:"stage"`
+	Leads             int      `json:"leads"`
+	StepConversion    float64  `json:"step_conversion"`
+	OverallConversion float64  `json:"overall_conversion"`
+	DropOff           float64  `json:"drop_off"`
+	MedianHours       *float64 `json:"median_hours"`
+}
+
+// SourceReport is the funnel of the leads of one source
+type SourceReport struct {
+	Source models.LeadSource `json:"source"`
+	Stages []StageReport     `json:"stages"`
+}
+
+// FunnelReport is the funnel of all selected leads with a breakdown per lead source
+type FunnelReport struct {
+	From      string         `json:"from"`
+	To        string         `json:"to"`
+	Source    string         `json:"source,omitempty"`
+	Campaign  string         `json:"campaign,omitempty"`
+	BeraterID *uuid.UUID     `json:"berater_id,omitempty"`
+	Stages    []StageReport  `json:"stages"`
+	Sources   []SourceReport `json:"sources"`
+}
+
+// progress holds when a lead reached each stage; a stage can be reached
+// without a known time, e.g. leads qualified before the time was recorded
+type progress struct {
+	source  models.LeadSource
+	reached int // index of the furthest stage
+	at      map[Stage]time.Time
+}
+
+func (p *progress) reach(stage Stage, at *time.Time) {
+	for i, s := range stages {
+		if s == stage && i > p.reached {
+			p.reached = i
+		}
+	}
+	if at == nil {
+		return
+	}
+	if current, ok := p.at[stage]; !ok || at.Before(current) {
+		p.at[stage] = *at
+	}
+}
+
+// reachedAt is the latest known time the lead reached a stage up to its
+// furthest one
+func (p *progress) reachedAt() time.Time {
+	var latest time.Time
+	for _, stage := range stages[:p.reached+1] {
+		if at, ok := p.at[stage]; ok && at.After(latest) {
+			latest = at
+		}
+	}
+	return latest
+}
+
+// Funnel reports how many of the leads created in the period were contacted,
+// qualified, booked and paid. A lead is contacted with the first contact
+// recorded on it or the first activity of a team member, qualified when
+// marked as qualified, booked with its first booking that was not cancelled
+// and paid with its first successful payment. Leads count for every stage up
+// to the furthest stage they reached, so a paid lead that was never marked
+// as qualified still counts as qualified.
+func (s *Service) Funnel(filter FunnelFilter) (*FunnelReport, error) {
+	loc := filter.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	start, err := timeutil.ParseDate(filter.From, loc)
+	if err != nil {
+		return nil, ErrInvalidPeriod
+	}
+	last, err := timeutil.ParseDate(filter.To, loc)
+	if err != nil || last.Before(start) || last.After(timeutil.AddDays(start, maxDays-1, loc)) {
+		return nil, ErrInvalidPeriod
+	}
+	end := timeutil.AddDays(last, 1, loc)
+
+	selected := func() *gorm.DB {
+		query := s.db.Model(&models.Lead{}).Where("leads.created_at >= ? AND leads.created_at < ?", start, end)
+		if filter.Source != "" {
+			query = query.Where("leads.source = ?", filter.Source)
+		}
+		if filter.Campaign != "" {
+			query = query.Where("LOWER(leads.utm_campaign) = ?", strings.ToLower(strings.TrimSpace(filter.Campaign)))
+		}
+		if filter.BeraterID != nil {
+			query = query.Where("leads.berater_id = ?", *filter.BeraterID)
+		}
+		return query
+	}
+
+	byLead, err := s.progress(selected)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &FunnelReport{
+		From:      timeutil.FormatDate(start, loc),
+		To:        timeutil.FormatDate(last, loc),
+		Source:    string(filter.Source),
+		Campaign:  filter.Campaign,
+		BeraterID: filter.BeraterID,
+		Sources:   []SourceReport{},
+	}
+
+	all := make([]*progress, 0, len(byLead))
+	bySource := make(map[models.LeadSource][]*progress)
+	for _, p := range byLead {
+		all = append(all, p)
+		bySource[p.source] = append(bySource[p.source], p)
+	}
+	report.Stages = funnel(all)
+	for source, group := range bySource {
+		report.Sources = append(report.Sources, SourceReport{Source: source, Stages: funnel(group)})
+	}
+	// Sources with the most leads first
+	sort.Slice(report.Sources, func(i, j int) bool {
+		a, b := report.Sources[i].Stages[0].Leads, report.Sources[j].Stages[0].Leads
+		if a != b {
+			return a > b
+		}
+		return report.Sources[i].Source < report.Sources[j].Source
+	})
+
+	return report, nil
+}
+
+// progress loads how far the selected leads got in the pipeline
+func (s *Service) progress(selected func() *gorm.DB) (map[uuid.UUID]*progress, error) {
+	var leads []struct {
+		ID            uuid.UUID
+		Source        models.LeadSource
+		CreatedAt     time.Time
+		LastContactAt *time.Time
+		IsQualified   bool
+		QualifiedAt   *time.Time
+	}
+	if err := selected().Select("leads.id, leads.source, leads.created_at, leads.last_contact_at, leads.is_qualified, leads.qualified_at").
+		Scan(&leads).Error; err != nil {
+		return nil, err
+	}
+
+	byLead := make(map[uuid.UUID]*progress, len(leads))
+	for _, lead := range leads {
+		createdAt := lead.CreatedAt
+		p := &progress{source: lead.Source, at: make(map[Stage]time.Time)}
+		p.reach(StageNew, &createdAt)
+		if lead.LastContactAt != nil {
+			p.reach(StageContacted, lead.LastContactAt)
+		}
+		if lead.IsQualified || lead.QualifiedAt != nil {
+			p.reach(StageQualified, lead.QualifiedAt)
+		}
+		byLead[lead.ID] = p
+	}
+	// Leads created while the report is built are not in the report
+	reach := func(leadID uuid.UUID, stage Stage, at *time.Time) {
+		if p := byLead[leadID]; p != nil {
+			p.reach(stage, at)
+		}
+	}
+
+	var events []struct {
+		LeadID    uuid.UUID
+		CreatedAt time.Time
+	}
+	// Activities of anyone but the customer are the team working on the lead
+	if err := s.db.Model(&models.Activity{}).
+		Select("activities.lead_id, activities.created_at").
+		Joins("JOIN leads ON leads.id = activities.lead_id").
+		Where("activities.lead_id IN (?)", selected().Select("leads.id")).
+		Where("activities.user_id IS NOT NULL AND activities.user_id <> leads.user_id").
+		Scan(&events).Error; err != nil {
+		return nil, err
+	}
+	for _, event := range events {
+		reach(event.LeadID, StageContacted, &event.CreatedAt)
+	}
+
+	events = nil
+	if err := s.db.Model(&models.Booking{}).
+		Select("lead_id, created_at").
+		Where("lead_id IN (?) AND status <> ?", selected().Select("leads.id"), models.BookingStatusCancelled).
+		Scan(&events).Error; err != nil {
+		return nil, err
+	}
+	for _, event := range events {
+		reach(event.LeadID, StageBooked, &event.CreatedAt)
+	}
+
+	var payments []struct {
+		LeadID    uuid.UUID
+		PaidAt    *time.Time
+		CreatedAt time.Time
+	}
+	if err := s.db.Model(&models.Payment{}).
+		Select("lead_id, paid_at, created_at").
+		Where("lead_id IN (?) AND status = ?", selected().Select("leads.id"), models.PaymentStatusSucceeded).
+		Scan(&payments).Error; err != nil {
+		return nil, err
+	}
+	for _, payment := range payments {
+		paidAt := payment.PaidAt
+		if paidAt == nil {
+			paidAt = &payment.CreatedAt
+		}
+		reach(payment.LeadID, StagePaid, paidAt)
+	}
+
+	return byLead, nil
+}
+
+// funnel counts the leads per stage with conversion, drop-off and the median
+// time from the previous stage
+func funnel(leads []*progress) []StageReport {
+	reports := make([]StageReport, len(stages))
+	for i, stage := range stages {
+		report := StageReport{Stage: stage}
+
+		var durations []time.Duration
+		for _, p := range leads {
+			if p.reached < i {
+				continue
+			}
+			report.Leads++
+			if i == 0 {
+				continue
+			}
+			from, okFrom := p.at[stages[i-1]]
+			to, okTo := p.at[stage]
+			if okFrom && okTo && !to.Before(from) {
+				durations = append(durations, to.Sub(from))
+			}
+		}
+
+		if i > 0 {
+			report.StepConversion = percent(report.Leads, reports[i-1].Leads)
+			report.OverallConversion = percent(report.Leads, reports[0].Leads)
+			if reports[i-1].Leads > 0 {
+				report.DropOff = math.Round((100-report.StepConversion)*10) / 10
+			}
+			report.MedianHours = medianHours(durations)
+		} else if report.Leads > 0 {
+			report.StepConversion = 100
+			report.OverallConversion = 100
+		}
+		reports[i] = report
+	}
+	return reports
+}
+
+func medianHours(durations []time.Duration) *float64 {
+	if len(durations) == 0 {
+		return nil
+	}
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+	median := durations[len(durations)/2]
+	if len(durations)%2 == 0 {
+		median = (durations[len(durations)/2-1] + median) / 2
+	}
+	hours := math.Round(median.Hours()*10) / 10
+	return &hours
+}
+
+func percent(part, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return math.Round(float64(part)/float64(total)*1000) / 10
+}
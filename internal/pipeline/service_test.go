@@ -0,0 +1,292 @@
+package pipeline
+
+import (
+	"testing"
+	"time"
+
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/tests/testutils"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+func TestFunnel(t *testing.T) {
+	db, service := setupTestService(t)
+	customer := testutils.CreateTestUser(t, db, models.RoleUser)
+	berater := testutils.CreateTestUser(t, db, models.RoleBerater)
+	created := time.Date(2025, 3, 3, 9, 0, 0, 0, time.UTC)
+
+	// Paid: contacted after 2h by the Berater, booked after a day, paid an hour later
+	paid := createLead(t, db, customer.ID, models.LeadSourceWebsite, "Fruehling", created)
+	createActivity(t, db, berater.ID, paid.ID, created.Add(2*time.Hour))
+	createBooking(t, db, customer.ID, paid.ID, models.BookingStatusConfirmed, created.Add(26*time.Hour))
+	createPayment(t, db, customer.ID, paid.ID, models.PaymentStatusSucceeded, created.Add(27*time.Hour))
+
+	// Qualified: first contact by email after 4h
+	qualified := createLead(t, db, customer.ID, models.LeadSourceWebsite, "", created)
+	contactedAt := created.Add(4 * time.Hour)
+	qualifiedAt := created.Add(48 * time.Hour)
+	require.NoError(t, db.Model(qualified).Updates(map[string]interface{}{
+		"last_contact_at": contactedAt,
+		"is_qualified":    true,
+		"qualified_at":    qualifiedAt,
+	}).Error)
+
+	// New: the customer's own activity and a cancelled booking do not count
+	untouched := createLead(t, db, customer.ID, models.LeadSourceReferral, "", created)
+	createActivity(t, db, customer.ID, untouched.ID, created.Add(time.Hour))
+	createBooking(t, db, customer.ID, untouched.ID, models.BookingStatusCancelled, created.Add(time.Hour))
+	createPayment(t, db, customer.ID, untouched.ID, models.PaymentStatusFailed, created.Add(time.Hour))
+
+	// Outside the period
+	createLead(t, db, customer.ID, models.LeadSourceWebsite, "", created.AddDate(0, -1, 0))
+
+	report, err := service.Funnel(FunnelFilter{From: "2025-03-01", To: "2025-03-31"})
+	require.NoError(t, err)
+
+	leads := make(map[Stage]int)
+	for _, stage := range report.Stages {
+		leads[stage.Stage] = stage.Leads
+	}
+	assert.Equal(t, map[Stage]int{StageNew: 3, StageContacted: 2, StageQualified: 2, StageBooked: 1, StagePaid: 1}, leads)
+
+	contacted := report.Stages[1]
+	assert.Equal(t, 66.7, contacted.StepConversion)
+	assert.Equal(t, 33.3, contacted.DropOff)
+	require.NotNil(t, contacted.MedianHours)
+	assert.Equal(t, 3.0, *contacted.MedianHours)
+
+	booked := report.Stages[3]
+	assert.Equal(t, 50.0, booked.StepConversion)
+	assert.Equal(t, 33.3, booked.OverallConversion)
+	// The paid lead was never marked as qualified, so no duration is known
+	assert.Nil(t, booked.MedianHours)
+	require.NotNil(t, report.Stages[4].MedianHours)
+	assert.Equal(t, 1.0, *report.Stages[4].MedianHours)
+
+	require.Len(t, report.Sources, 2)
+	assert.Equal(t, models.LeadSourceWebsite, report.Sources[0].Source)
+	assert.Equal(t, 2, report.Sources[0].Stages[0].Leads)
+	assert.Equal(t, 1, report.Sources[0].Stages[4].Leads)
+	assert.Equal(t, models.LeadSourceReferral, report.Sources[1].Source)
+	assert.Equal(t, 0, report.Sources[1].Stages[1].Leads)
+	assert.Equal(t, 100.0, report.Sources[1].Stages[1].DropOff)
+}
+
+func TestFunnel_Filters(t *testing.T) {
+	db, service := setupTestService(t)
+	customer := testutils.CreateTestUser(t, db, models.RoleUser)
+	berater := testutils.CreateTestUser(t, db, models.RoleBerater)
+	created := time.Date(2025, 3, 3, 9, 0, 0, 0, time.UTC)
+
+	campaign := createLead(t, db, customer.ID, models.LeadSourceWebsite, "Fruehling", created)
+	require.NoError(t, db.Model(campaign).Update("berater_id", berater.ID).Error)
+	createLead(t, db, customer.ID, models.LeadSourceWebsite, "Sommer", created)
+	createLead(t, db, customer.ID, models.LeadSourcePhone, "", created)
+
+	tests := []struct {
+		name   string
+		filter FunnelFilter
+		leads  int
+	}{
+		{"source", FunnelFilter{Source: models.LeadSourceWebsite}, 2},
+		{"campaign", FunnelFilter{Campaign: "fruehling"}, 1},
+		{"berater", FunnelFilter{BeraterID: &berater.ID}, 1},
+		{"period", FunnelFilter{From: "2025-03-04", To: "2025-03-10"}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter := tt.filter
+			if filter.From == "" {
+				filter.From, filter.To = "2025-03-01", "2025-03-03"
+			}
+			report, err := service.Funnel(filter)
+			require.NoError(t, err)
+			assert.Equal(t, tt.leads, report.Stages[0].Leads)
+		})
+	}
+
+	for _, period := range [][2]string{{"2025-03-31", "2025-03-01"}, {"März", "2025-03-01"}, {"2024-01-01", "2025-03-01"}} {
+		_, err := service.Funnel(FunnelFilter{From: period[0], To: period[1]})
+		assert.ErrorIs(t, err, ErrInvalidPeriod)
+	}
+}
+
+func TestForecast(t *testing.T) {
+	db, service := setupTestService(t)
+	now := time.Date(2025, 6, 1, 9, 0, 0, 0, time.UTC)
+	service.now = func() time.Time { return now }
+	customer := testutils.CreateTestUser(t, db, models.RoleUser)
+	berater := testutils.CreateTestUser(t, db, models.RoleBerater)
+
+	// History: half of the new leads and two thirds of the contacted leads paid
+	created := time.Date(2024, 12, 1, 9, 0, 0, 0, time.UTC)
+	for days, amount := range map[int]float64{11: 120, 21: 180} {
+		lead := createLead(t, db, customer.ID, models.LeadSourceWebsite, "", created)
+		contact(t, db, lead, created.AddDate(0, 0, 1))
+		createPayment(t, db, customer.ID, lead.ID, models.PaymentStatusSucceeded, created.AddDate(0, 0, days))
+		require.NoError(t, db.Model(&models.Payment{}).Where("lead_id = ?", lead.ID).Update("amount", amount).Error)
+	}
+	lost := createLead(t, db, customer.ID, models.LeadSourceWebsite, "", created)
+	assign(t, db, lost, "status", models.LeadStatusCancelled)
+	lost = createLead(t, db, customer.ID, models.LeadSourceWebsite, "", created)
+	contact(t, db, lost, created.AddDate(0, 0, 1))
+	assign(t, db, lost, "status", models.LeadStatusCancelled)
+
+	// New lead with the average revenue of 150, expected 16 days after creation
+	fresh := createLead(t, db, customer.ID, models.LeadSourceWebsite, "", now.AddDate(0, 0, -2))
+	assign(t, db, fresh, "berater_id", berater.ID)
+
+	// Booked lead with a high score, expected at the end as no booked lead paid before
+	premium := &models.Package{Name: "Premium", Type: models.PackageTypePremium, Price: 300, Currency: "EUR"}
+	require.NoError(t, db.Create(premium).Error)
+	booked := createLead(t, db, customer.ID, models.LeadSourceWebsite, "", now.AddDate(0, 0, -10))
+	assign(t, db, booked, "berater_id", berater.ID)
+	assign(t, db, booked, "lead_score", 80)
+	contact(t, db, booked, now.AddDate(0, 0, -5))
+	start := now.AddDate(0, 0, 3)
+	require.NoError(t, db.Create(&models.Booking{
+		UserID:      customer.ID,
+		LeadID:      &booked.ID,
+		PackageID:   &premium.ID,
+		Title:       "Beratung",
+		Status:      models.BookingStatusConfirmed,
+		ScheduledAt: start,
+		StartTime:   start,
+		EndTime:     start.Add(time.Hour),
+	}).Error)
+
+	// Qualified leads close with certainty, one on its estimated close date
+	estimated := createLead(t, db, customer.ID, models.LeadSourceWebsite, "", now.AddDate(0, 0, -20))
+	assign(t, db, estimated, "is_qualified", true)
+	assign(t, db, estimated, "estimated_value", 500)
+	assign(t, db, estimated, "estimated_close_date", now.AddDate(0, 0, 45))
+	qualified := createLead(t, db, customer.ID, models.LeadSourceWebsite, "", now.AddDate(0, 0, -20))
+	assign(t, db, qualified, "is_qualified", true)
+
+	// Paid and cancelled leads are not forecast
+	paid := createLead(t, db, customer.ID, models.LeadSourceWebsite, "", now.AddDate(0, 0, -3))
+	createPayment(t, db, customer.ID, paid.ID, models.PaymentStatusSucceeded, now.AddDate(0, 0, -1))
+	require.NoError(t, db.Model(&models.Payment{}).Where("lead_id = ?", paid.ID).Update("amount", 150).Error)
+	cancelled := createLead(t, db, customer.ID, models.LeadSourceWebsite, "", now.AddDate(0, 0, -3))
+	assign(t, db, cancelled, "status", models.LeadStatusCancelled)
+
+	report, err := service.Forecast(ForecastFilter{})
+	require.NoError(t, err)
+
+	assert.Equal(t, 150.0, report.AverageRevenue)
+	assert.Equal(t, 50.0, report.StageRates[0].ConversionToPaid)
+	require.NotNil(t, report.StageRates[0].MedianDaysToPaid)
+	assert.Equal(t, 16.0, *report.StageRates[0].MedianDaysToPaid)
+	assert.Equal(t, 66.7, report.StageRates[1].ConversionToPaid)
+	assert.Equal(t, 100.0, report.StageRates[2].ConversionToPaid)
+	assert.Nil(t, report.StageRates[2].MedianDaysToPaid)
+
+	assert.Equal(t, []HorizonForecast{
+		{Days: 30, Leads: 1, ExpectedRevenue: 75},
+		{Days: 60, Leads: 2, ExpectedRevenue: 575},
+		{Days: 90, Leads: 4, ExpectedRevenue: 995},
+	}, report.Horizons)
+
+	require.Len(t, report.Beraters, 2)
+	assert.Nil(t, report.Beraters[0].BeraterID)
+	assert.Equal(t, 650.0, report.Beraters[0].Horizons[2].ExpectedRevenue)
+	assert.Equal(t, &berater.ID, report.Beraters[1].BeraterID)
+	assert.Equal(t, berater.FullName(), report.Beraters[1].Name)
+	assert.Equal(t, 75.0, report.Beraters[1].Horizons[0].ExpectedRevenue)
+	assert.Equal(t, 345.0, report.Beraters[1].Horizons[2].ExpectedRevenue)
+
+	require.Len(t, report.Packages, 2)
+	assert.Nil(t, report.Packages[0].PackageID)
+	assert.Equal(t, 725.0, report.Packages[0].Horizons[2].ExpectedRevenue)
+	assert.Equal(t, "Premium", report.Packages[1].Name)
+	assert.Equal(t, 270.0, report.Packages[1].Horizons[2].ExpectedRevenue)
+
+	report, err = service.Forecast(ForecastFilter{BeraterID: &berater.ID})
+	require.NoError(t, err)
+	assert.Equal(t, HorizonForecast{Days: 90, Leads: 2, ExpectedRevenue: 345}, report.Horizons[2])
+	require.Len(t, report.Beraters, 1)
+}
+
+func setupTestService(t *testing.T) (*gorm.DB, *Service) {
+	t.Helper()
+	db := testutils.NewSQLiteDB(t,
+		&models.User{},
+		&models.Package{},
+		&models.Lead{},
+		&models.Activity{},
+		&models.Booking{},
+		&models.Payment{},
+	)
+
+	return db, NewService(db, zap.NewNop())
+}
+
+func createLead(t *testing.T, db *gorm.DB, userID uuid.UUID, source models.LeadSource, campaign string, createdAt time.Time) *models.Lead {
+	t.Helper()
+	lead := &models.Lead{
+		UserID:      userID,
+		Title:       "Elterngeld",
+		Status:      models.LeadStatusNew,
+		Priority:    models.PriorityMedium,
+		Source:      source,
+		UtmCampaign: campaign,
+		CreatedAt:   createdAt,
+		UpdatedAt:   createdAt,
+	}
+	require.NoError(t, db.Create(lead).Error)
+	return lead
+}
+
+func createActivity(t *testing.T, db *gorm.DB, userID, leadID uuid.UUID, at time.Time) {
+	t.Helper()
+	require.NoError(t, db.Create(&models.Activity{
+		UserID:    &userID,
+		LeadID:    &leadID,
+		Type:      models.ActivityTypeCommentAdded,
+		Title:     "Kommentar hinzugefügt",
+		CreatedAt: at,
+	}).Error)
+}
+
+func createBooking(t *testing.T, db *gorm.DB, userID, leadID uuid.UUID, status models.BookingStatus, at time.Time) {
+	t.Helper()
+	start := at.Add(72 * time.Hour)
+	require.NoError(t, db.Create(&models.Booking{
+		UserID:      userID,
+		LeadID:      &leadID,
+		Title:       "Beratung",
+		Status:      status,
+		ScheduledAt: start,
+		StartTime:   start,
+		EndTime:     start.Add(time.Hour),
+		CreatedAt:   at,
+		UpdatedAt:   at,
+	}).Error)
+}
+
+func createPayment(t *testing.T, db *gorm.DB, userID, leadID uuid.UUID, status models.PaymentStatus, at time.Time) {
+	t.Helper()
+	require.NoError(t, db.Create(&models.Payment{
+		UserID:          userID,
+		LeadID:          leadID,
+		Amount:          120,
+		Status:          status,
+		StripeSessionID: "cs_" + uuid.New().String(),
+		PaidAt:          &at,
+	}).Error)
+}
+
+func contact(t *testing.T, db *gorm.DB, lead *models.Lead, at time.Time) {
+	t.Helper()
+	assign(t, db, lead, "last_contact_at", at)
+}
+
+func assign(t *testing.T, db *gorm.DB, lead *models.Lead, column string, value interface{}) {
+	t.Helper()
+	require.NoError(t, db.Model(&models.Lead{}).Where("id = ?", lead.ID).UpdateColumn(column, value).Error)
+}
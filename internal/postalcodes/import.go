@@ -0,0 +1,190 @@
+package postalcodes
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+
+	"elterngeld-portal/internal/models"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// importBatchSize is the number of areas inserted per statement
+const importBatchSize = 500
+
+// columnNames maps the accepted header names to the fields of an area, so
+// common PLZ datasets such as the OpenStreetMap based zuordnung_plz_ort.csv can
+// be imported as downloaded
+var columnNames = map[string]string{
+	"plz":             "postal_code",
+	"postal_code":     "postal_code",
+	"postleitzahl":    "postal_code",
+	"ort":             "place",
+	"place":           "place",
+	"kreis":           "kreis",
+	"landkreis":       "kreis",
+	"kreis_code":      "kreis_code",
+	"kreisschluessel": "kreis_code",
+	"ags":             "kreis_code",
+	"bundesland":      "bundesland",
+}
+
+// statePrefixes maps the first two digits of an Amtlicher Gemeindeschlüssel
+// to the Bundesland
+var statePrefixes = map[string]models.Bundesland{
+	"01": models.BundeslandSchleswigHolstein,
+	"02": models.BundeslandHamburg,
+	"03": models.BundeslandNiedersachsen,
+	"04": models.BundeslandBremen,
+	"05": models.BundeslandNordrheinWestfalen,
+	"06": models.BundeslandHessen,
+	"07": models.BundeslandRheinlandPfalz,
+	"08": models.BundeslandBadenWuerttemberg,
+	"09": models.BundeslandBayern,
+	"10": models.BundeslandSaarland,
+	"11": models.BundeslandBerlin,
+	"12": models.BundeslandBrandenburg,
+	"13": models.BundeslandMecklenburgVorpommern,
+	"14": models.BundeslandSachsen,
+	"15": models.BundeslandSachsenAnhalt,
+	"16": models.BundeslandThueringen,
+}
+
+// Import replaces the reference dataset with the areas of a CSV file and returns
+// their number. The file needs a header with a plz column and a bundesland or
+// ags column; ort, kreis or landkreis, and kreis_code are optional. Bundesländer
+// may be given by code or name, and ags may be the full Gemeindeschlüssel.
+func (s *Service) Import(r io.Reader) (int, error) {
+	areas, err := parseDataset(r)
+	if err != nil {
+		return 0, err
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(&models.PostalCodeArea{}).Error; err != nil {
+			return fmt.Errorf("failed to remove postal code areas: %w", err)
+		}
+		if err := tx.CreateInBatches(areas, importBatchSize).Error; err != nil {
+			return fmt.Errorf("failed to import postal code areas: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	s.logger.Info("Imported postal code areas", zap.Int("areas", len(areas)))
+	return len(areas), nil
+}
+
+func parseDataset(r io.Reader) ([]models.PostalCodeArea, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read postal code dataset: %w", err)
+	}
+	data = bytes.TrimPrefix(data, []byte("\ufeff"))
+
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	// Datasets exported from spreadsheets are separated by semicolons
+	header, _, _ := bytes.Cut(data, []byte("\n"))
+	if bytes.Count(header, []byte(";")) > bytes.Count(header, []byte(",")) {
+		reader.Comma = ';'
+	}
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidDataset, err)
+	}
+	if len(records) < 2 {
+		return nil, fmt.Errorf("%w: no postal codes", ErrInvalidDataset)
+	}
+
+	columns := map[string]int{}
+	for i, name := range records[0] {
+		if field, ok := columnNames[strings.ToLower(strings.TrimSpace(name))]; ok {
+			if _, seen := columns[field]; !seen {
+				columns[field] = i
+			}
+		}
+	}
+	if _, ok := columns["postal_code"]; !ok {
+		return nil, fmt.Errorf("%w: missing plz column", ErrInvalidDataset)
+	}
+	_, hasState := columns["bundesland"]
+	_, hasCode := columns["kreis_code"]
+	if !hasState && !hasCode {
+		return nil, fmt.Errorf("%w: missing bundesland or ags column", ErrInvalidDataset)
+	}
+
+	value := func(record []string, field string) string {
+		i, ok := columns[field]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	areas := make([]models.PostalCodeArea, 0, len(records)-1)
+	seen := map[string]bool{}
+	for n, record := range records[1:] {
+		line := n + 2
+		area := models.PostalCodeArea{
+			PostalCode: value(record, "postal_code"),
+			Place:      value(record, "place"),
+			Kreis:      value(record, "kreis"),
+			KreisCode:  value(record, "kreis_code"),
+		}
+		if area.PostalCode == "" {
+			continue
+		}
+		// Leading zeros get lost in spreadsheets
+		if len(area.PostalCode) == 4 {
+			area.PostalCode = "0" + area.PostalCode
+		}
+		if !postalCodePattern.MatchString(area.PostalCode) {
+			return nil, fmt.Errorf("%w: line %d: invalid postal code %q", ErrInvalidDataset, line, area.PostalCode)
+		}
+		if len(area.KreisCode) > 5 {
+			area.KreisCode = area.KreisCode[:5]
+		}
+
+		area.Bundesland = parseBundesland(value(record, "bundesland"))
+		if area.Bundesland == "" && len(area.KreisCode) >= 2 {
+			area.Bundesland = statePrefixes[area.KreisCode[:2]]
+		}
+		if area.Bundesland == "" {
+			return nil, fmt.Errorf("%w: line %d: unknown Bundesland for postal code %s", ErrInvalidDataset, line, area.PostalCode)
+		}
+
+		key := strings.Join([]string{area.PostalCode, area.Place, area.KreisCode, area.Kreis, string(area.Bundesland)}, "|")
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		areas = append(areas, area)
+	}
+	if len(areas) == 0 {
+		return nil, fmt.Errorf("%w: no postal codes", ErrInvalidDataset)
+	}
+
+	return areas, nil
+}
+
+// parseBundesland accepts a Bundesland code or name
+func parseBundesland(value string) models.Bundesland {
+	if state := models.Bundesland(strings.ToUpper(value)); state.IsValid() {
+		return state
+	}
+	for _, state := range models.Bundeslaender {
+		if strings.EqualFold(state.GetDisplayName(), value) {
+			return state
+		}
+	}
+	return ""
+}
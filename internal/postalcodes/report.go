@@ -0,0 +1,125 @@
+package postalcodes
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/pkg/timeutil"
+)
+
+// maxReportDays limits the region report to about a year
+const maxReportDays = 366
+
+// RegionFilter is the period of a region report, as calendar days in a location
+type RegionFilter struct {
+	From     string
+	To       string
+	Location *time.Location
+}
+
+// Region counts the leads created in a Kreis. Leads whose customer has no known
+// postal code are counted in a region without Kreis, under the customer's
+// Bundesland if they have one.
+type Region struct {
+	Bundesland models.Bundesland `json:"bundesland,omitempty"`
+	Kreis      string            `json:"kreis,omitempty"`
+	KreisCode  string            `json:"kreis_code,omitempty"`
+	Leads      int64             `json:"leads"`
+}
+
+// RegionReport breaks down the leads of a period by region
+type RegionReport struct {
+	From    string   `json:"from"`
+	To      string   `json:"to"`
+	Total   int64    `json:"total"`
+	Regions []Region `json:"regions"` // most leads first
+}
+
+// Regions counts the leads created in a period per Kreis of the customer's
+// postal code
+func (s *Service) Regions(filter RegionFilter) (*RegionReport, error) {
+	loc := filter.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	from, err := timeutil.ParseDate(filter.From, loc)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPeriod, err)
+	}
+	to, err := timeutil.ParseDate(filter.To, loc)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPeriod, err)
+	}
+	end := timeutil.AddDays(to, 1, loc)
+	if !end.After(from) || end.Sub(from) > maxReportDays*24*time.Hour {
+		return nil, ErrInvalidPeriod
+	}
+
+	var rows []struct {
+		PostalCode string
+		Bundesland models.Bundesland
+		Leads      int64
+	}
+	if err := s.db.Model(&models.Lead{}).
+		Select("users.postal_code AS postal_code, users.bundesland AS bundesland, COUNT(*) AS leads").
+		Joins("JOIN users ON users.id = leads.user_id").
+		Where("leads.created_at >= ? AND leads.created_at < ?", from, end).
+		Group("users.postal_code, users.bundesland").
+		Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to count leads: %w", err)
+	}
+
+	postalCodes := make([]string, 0, len(rows))
+	for _, row := range rows {
+		if postalCodePattern.MatchString(row.PostalCode) {
+			postalCodes = append(postalCodes, row.PostalCode)
+		}
+	}
+	areasByCode := map[string][]models.PostalCodeArea{}
+	if len(postalCodes) > 0 {
+		var areas []models.PostalCodeArea
+		if err := s.db.Where("postal_code IN ?", postalCodes).Find(&areas).Error; err != nil {
+			return nil, fmt.Errorf("failed to load postal code areas: %w", err)
+		}
+		for _, area := range areas {
+			areasByCode[area.PostalCode] = append(areasByCode[area.PostalCode], area)
+		}
+	}
+
+	report := &RegionReport{
+		From:    timeutil.FormatDate(from, loc),
+		To:      timeutil.FormatDate(to, loc),
+		Regions: []Region{},
+	}
+	byRegion := map[Region]int64{}
+	for _, row := range rows {
+		region := Region{Bundesland: row.Bundesland}
+		if areas, ok := areasByCode[row.PostalCode]; ok {
+			known := jurisdiction(row.PostalCode, areas)
+			if known.Bundesland != "" {
+				region = Region{Bundesland: known.Bundesland, Kreis: known.Kreis, KreisCode: known.KreisCode}
+			}
+		}
+		byRegion[region] += row.Leads
+		report.Total += row.Leads
+	}
+
+	for region, leads := range byRegion {
+		region.Leads = leads
+		report.Regions = append(report.Regions, region)
+	}
+	sort.Slice(report.Regions, func(i, j int) bool {
+		a, b := report.Regions[i], report.Regions[j]
+		if a.Leads != b.Leads {
+			return a.Leads > b.Leads
+		}
+		if a.Bundesland != b.Bundesland {
+			return a.Bundesland < b.Bundesland
+		}
+		return a.KreisCode < b.KreisCode
+	})
+
+	return report, nil
+}
@@ -0,0 +1,138 @@
+// Package postalcodes derives the Bundesland and Kreis of a German postal code
+// (PLZ) from an imported reference dataset. The Bundesland decides the
+// responsible Elterngeldstelle and the public holidays, the Kreis is used to
+// break reports down by region.
+package postalcodes
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"elterngeld-portal/internal/models"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrInvalidPostalCode is returned for postal codes that are not five digits
+	ErrInvalidPostalCode = errors.New("invalid postal code")
+	// ErrNotFound is returned for postal codes missing from the reference dataset
+	ErrNotFound = errors.New("postal code not found")
+	// ErrInvalidDataset is returned when the reference dataset cannot be imported
+	ErrInvalidDataset = errors.New("invalid postal code dataset")
+	// ErrInvalidPeriod is returned for unparsable, reversed or too long report periods
+	ErrInvalidPeriod = errors.New("invalid period")
+)
+
+var postalCodePattern = regexp.MustCompile(`^[0-9]{5}$`)
+
+// Jurisdiction is what a postal code tells about where a customer lives.
+// Bundesland and Kreis are only set when all areas of the postal code agree.
+type Jurisdiction struct {
+	PostalCode string                  `json:"postal_code"`
+	Bundesland models.Bundesland       `json:"bundesland,omitempty"`
+	Kreis      string                  `json:"kreis,omitempty"`
+	KreisCode  string                  `json:"kreis_code,omitempty"`
+	Places     []string                `json:"places"`
+	Areas      []models.PostalCodeArea `json:"areas"`
+}
+
+// IsAmbiguous reports whether the postal code spans several Bundesländer
+func (j *Jurisdiction) IsAmbiguous() bool {
+	return j.Bundesland == ""
+}
+
+// Service looks up postal codes in the reference dataset
+type Service struct {
+	db     *gorm.DB
+	logger *zap.Logger
+}
+
+func NewService(db *gorm.DB, logger *zap.Logger) *Service {
+	return &Service{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// Lookup returns the areas of a postal code and the Bundesland and Kreis they share
+func (s *Service) Lookup(postalCode string) (*Jurisdiction, error) {
+	postalCode = strings.TrimSpace(postalCode)
+	if !postalCodePattern.MatchString(postalCode) {
+		return nil, ErrInvalidPostalCode
+	}
+
+	var areas []models.PostalCodeArea
+	if err := s.db.Where("postal_code = ?", postalCode).Order("place ASC, kreis_code ASC").Find(&areas).Error; err != nil {
+		return nil, fmt.Errorf("failed to load postal code areas: %w", err)
+	}
+	if len(areas) == 0 {
+		return nil, ErrNotFound
+	}
+
+	return jurisdiction(postalCode, areas), nil
+}
+
+// Bundesland returns the Bundesland of a postal code. It is empty for invalid
+// and unknown postal codes and for postal codes spanning several Bundesländer.
+func (s *Service) Bundesland(postalCode string) (models.Bundesland, error) {
+	result, err := s.Lookup(postalCode)
+	if errors.Is(err, ErrInvalidPostalCode) || errors.Is(err, ErrNotFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return result.Bundesland, nil
+}
+
+// BackfillBundesland sets the Bundesland of users without one from their postal
+// code, where the postal code lies in a single Bundesland
+func (s *Service) BackfillBundesland() (int64, error) {
+	result := s.db.Model(&models.User{}).
+		Where("COALESCE(bundesland, '') = ''").
+		Where("postal_code IN (?)", s.db.Model(&models.PostalCodeArea{}).
+			Select("postal_code").Group("postal_code").Having("COUNT(DISTINCT bundesland) = 1")).
+		Update("bundesland", gorm.Expr("(SELECT MIN(postal_code_areas.bundesland) FROM postal_code_areas WHERE postal_code_areas.postal_code = users.postal_code)"))
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to backfill Bundesland: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
+
+// jurisdiction combines the areas of a postal code
+func jurisdiction(postalCode string, areas []models.PostalCodeArea) *Jurisdiction {
+	result := &Jurisdiction{
+		PostalCode: postalCode,
+		Bundesland: areas[0].Bundesland,
+		Kreis:      areas[0].Kreis,
+		KreisCode:  areas[0].KreisCode,
+		Places:     []string{},
+		Areas:      areas,
+	}
+
+	seen := map[string]bool{}
+	for _, area := range areas {
+		if area.Bundesland != result.Bundesland {
+			result.Bundesland = ""
+		}
+		if area.KreisCode != result.KreisCode || area.Kreis != result.Kreis {
+			result.Kreis = ""
+			result.KreisCode = ""
+		}
+		if area.Place != "" && !seen[area.Place] {
+			seen[area.Place] = true
+			result.Places = append(result.Places, area.Place)
+		}
+	}
+	// A Kreis lies in a single Bundesland
+	if result.Bundesland == "" {
+		result.Kreis = ""
+		result.KreisCode = ""
+	}
+
+	return result
+}
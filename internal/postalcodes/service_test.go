@@ -0,0 +1,189 @@
+package postalcodes
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/tests/testutils"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// dataset has the columns of zuordnung_plz_ort.csv; 07919 spans Sachsen and Thüringen
+const dataset = `osm_id,ags,ort,plz,landkreis,bundesland
+62422,09162000,München,80331,,Bayern
+62422,09162000,München,80331,,Bayern
+1234,09184119,Unterhaching,82008,Landkreis München,Bayern
+5678,14523310,Mühltroff,07919,Vogtlandkreis,Sachsen
+5679,16075055,Kirschkau,07919,Saale-Orla-Kreis,Thüringen
+62428,11000000,Berlin,10115,,Berlin
+`
+
+func TestImportAndLookup(t *testing.T) {
+	db, service := setupTestService(t)
+
+	count, err := service.Import(strings.NewReader(dataset))
+	require.NoError(t, err)
+	assert.Equal(t, 5, count)
+
+	result, err := service.Lookup("80331")
+	require.NoError(t, err)
+	assert.Equal(t, models.BundeslandBayern, result.Bundesland)
+	assert.Equal(t, "09162", result.KreisCode)
+	assert.Equal(t, []string{"München"}, result.Places)
+	assert.False(t, result.IsAmbiguous())
+
+	result, err = service.Lookup("07919")
+	require.NoError(t, err)
+	assert.True(t, result.IsAmbiguous())
+	assert.Empty(t, result.KreisCode)
+	assert.Equal(t, []string{"Kirschkau", "Mühltroff"}, result.Places)
+	assert.Len(t, result.Areas, 2)
+
+	_, err = service.Lookup("99999")
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = service.Lookup("8033")
+	assert.ErrorIs(t, err, ErrInvalidPostalCode)
+
+	state, err := service.Bundesland(" 10115 ")
+	require.NoError(t, err)
+	assert.Equal(t, models.BundeslandBerlin, state)
+	state, err = service.Bundesland("07919")
+	require.NoError(t, err)
+	assert.Empty(t, state)
+
+	// Importing replaces the dataset; semicolons, codes and lost leading zeros are accepted
+	count, err = service.Import(strings.NewReader("PLZ;Ort;Kreis;Kreis_Code;Bundesland\n1067;Dresden;Dresden;14612;SN\n"))
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	var total int64
+	require.NoError(t, db.Model(&models.PostalCodeArea{}).Count(&total).Error)
+	assert.Equal(t, int64(1), total)
+	state, err = service.Bundesland("01067")
+	require.NoError(t, err)
+	assert.Equal(t, models.BundeslandSachsen, state)
+}
+
+func TestImportInvalidDataset(t *testing.T) {
+	db, service := setupTestService(t)
+	_, err := service.Import(strings.NewReader(dataset))
+	require.NoError(t, err)
+
+	for name, data := range map[string]string{
+		"empty":             "",
+		"no postal codes":   "plz,bundesland\n",
+		"missing plz":       "ort,bundesland\nMünchen,Bayern\n",
+		"missing state":     "plz,ort\n80331,München\n",
+		"invalid plz":       "plz,bundesland\n8033X,Bayern\n",
+		"unknown state":     "plz,bundesland\n80331,Bavaria\n",
+		"unknown ags state": "plz,ags\n80331,99162000\n",
+	} {
+		_, err := service.Import(strings.NewReader(data))
+		assert.ErrorIs(t, err, ErrInvalidDataset, name)
+	}
+
+	// Failed imports keep the previous dataset
+	var total int64
+	require.NoError(t, db.Model(&models.PostalCodeArea{}).Count(&total).Error)
+	assert.Equal(t, int64(5), total)
+}
+
+func TestBackfillBundesland(t *testing.T) {
+	db, service := setupTestService(t)
+	_, err := service.Import(strings.NewReader(dataset))
+	require.NoError(t, err)
+
+	munich := createTestUser(t, db, "80331", "")
+	border := createTestUser(t, db, "07919", "")
+	moved := createTestUser(t, db, "10115", models.BundeslandHamburg)
+	unknown := createTestUser(t, db, "", "")
+
+	updated, err := service.BackfillBundesland()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), updated)
+
+	for user, expected := range map[uuid.UUID]models.Bundesland{
+		munich.ID:  models.BundeslandBayern,
+		border.ID:  "",
+		moved.ID:   models.BundeslandHamburg,
+		unknown.ID: "",
+	} {
+		var reloaded models.User
+		require.NoError(t, db.First(&reloaded, "id = ?", user).Error)
+		assert.Equal(t, expected, reloaded.Bundesland)
+	}
+}
+
+func TestRegions(t *testing.T) {
+	db, service := setupTestService(t)
+	_, err := service.Import(strings.NewReader(dataset))
+	require.NoError(t, err)
+
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	for _, customer := range []struct {
+		postalCode string
+		bundesland models.Bundesland
+		leads      int
+	}{
+		{"80331", "", 2},
+		{"82008", models.BundeslandBayern, 1},
+		{"07919", models.BundeslandSachsen, 1},
+		{"", models.BundeslandHessen, 1},
+	} {
+		user := createTestUser(t, db, customer.postalCode, customer.bundesland)
+		for i := 0; i < customer.leads; i++ {
+			createTestLead(t, db, user.ID, now)
+		}
+	}
+	// Outside the period
+	createTestLead(t, db, createTestUser(t, db, "10115", "").ID, now.AddDate(0, -2, 0))
+
+	report, err := service.Regions(RegionFilter{From: "2026-03-01", To: "2026-03-31"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), report.Total)
+	assert.Equal(t, []Region{
+		{Bundesland: models.BundeslandBayern, KreisCode: "09162", Leads: 2},
+		{Bundesland: models.BundeslandBayern, Kreis: "Landkreis München", KreisCode: "09184", Leads: 1},
+		{Bundesland: models.BundeslandHessen, Leads: 1},
+		{Bundesland: models.BundeslandSachsen, Leads: 1},
+	}, report.Regions)
+
+	_, err = service.Regions(RegionFilter{From: "2026-03-31", To: "2026-03-01"})
+	assert.ErrorIs(t, err, ErrInvalidPeriod)
+	_, err = service.Regions(RegionFilter{From: "2025-01-01", To: "2026-03-01"})
+	assert.ErrorIs(t, err, ErrInvalidPeriod)
+}
+
+func createTestUser(t *testing.T, db *gorm.DB, postalCode string, bundesland models.Bundesland) *models.User {
+	t.Helper()
+	user := testutils.CreateTestUser(t, db, models.RoleUser)
+	require.NoError(t, db.Model(user).Updates(map[string]interface{}{"postal_code": postalCode, "bundesland": bundesland}).Error)
+	user.PostalCode = postalCode
+	user.Bundesland = bundesland
+	return user
+}
+
+func createTestLead(t *testing.T, db *gorm.DB, userID uuid.UUID, createdAt time.Time) {
+	t.Helper()
+	lead := &models.Lead{
+		UserID:    userID,
+		Title:     "Elterngeld-Beratung",
+		Status:    models.LeadStatusNew,
+		CreatedAt: createdAt,
+	}
+	require.NoError(t, db.Create(lead).Error)
+}
+
+func setupTestService(t *testing.T) (*gorm.DB, *Service) {
+	t.Helper()
+	db := testutils.NewSQLiteDB(t, &models.User{}, &models.Lead{}, &models.PostalCodeArea{})
+
+	return db, NewService(db, zap.NewNop())
+}
@@ -0,0 +1,247 @@
+package questionnaires
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"elterngeld-portal/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// MaxScore is the highest qualification score; points above it are capped
+const MaxScore = 100
+
+// maxPoints bounds the points of a single answer
+const maxPoints = MaxScore
+
+// priorityScores are the scores from which a lead gets a priority, highest first
+var priorityScores = []struct {
+	score    int
+	priority models.Priority
+}{
+	{75, models.PriorityUrgent},
+	{50, models.PriorityHigh},
+	{25, models.PriorityMedium},
+	{0, models.PriorityLow},
+}
+
+// packageScores are the scores from which a package type is recommended,
+// highest first: complex cases need the complete service
+var packageScores = []struct {
+	score       int
+	packageType models.PackageType
+}{
+	{65, models.PackageTypeComplete},
+	{35, models.PackageTypePremium},
+	{0, models.PackageTypeBasic},
+}
+
+// Qualification is the score of a lead from its answers with the package
+// recommended to the Berater for the pre-talk
+type Qualification struct {
+	Score              int             `json:"score"`
+	Reasons            []string        `json:"reasons"`
+	Priority           models.Priority `json:"priority"`
+	RecommendedPackage *PackageSummary `json:"recommended_package,omitempty"`
+}
+
+// PackageSummary names a recommended package
+type PackageSummary struct {
+	ID   uuid.UUID          `json:"id"`
+	Name string             `json:"name"`
+	Type models.PackageType `json:"type"`
+}
+
+// PriorityForScore returns the lead priority of a qualification score
+func PriorityForScore(score int) models.Priority {
+	for _, band := range priorityScores {
+		if score >= band.score {
+			return band.priority
+		}
+	}
+	return models.PriorityLow
+}
+
+// PackageTypeForScore returns the package type recommended for a qualification score
+func PackageTypeForScore(score int) models.PackageType {
+	for _, band := range packageScores {
+		if score >= band.score {
+			return band.packageType
+		}
+	}
+	return models.PackageTypeBasic
+}
+
+// score adds up the points of the answers. It returns false when no question
+// of the questionnaire has points, so the lead is not qualified by it.
+func score(questions []models.QuestionnaireQuestion, answers map[string]interface{}) (int, []string, bool, error) {
+	total, reasons, scored := 0, []string{}, false
+	for _, question := range questions {
+		points, err := question.GetPoints()
+		if err != nil {
+			return 0, nil, false, fmt.Errorf("failed to decode points: %w", err)
+		}
+		if points == nil {
+			continue
+		}
+		scored = true
+
+		answer, ok := answers[question.Key]
+		if !ok {
+			continue
+		}
+		earned := answerPoints(points, answer)
+		if earned == 0 {
+			continue
+		}
+		total += earned
+		reasons = append(reasons, fmt.Sprintf("%s: %s (%+d)", question.Label, formatAnswer(answer), earned))
+	}
+
+	if total < 0 {
+		total = 0
+	}
+	if total > MaxScore {
+		total = MaxScore
+	}
+	return total, reasons, scored, nil
+}
+
+// answerPoints returns the points of an answer. Each selected option of a
+// multiple choice answer counts.
+func answerPoints(points *models.AnswerPoints, answer interface{}) int {
+	if value, ok := answer.(float64); ok {
+		for _, r := range points.Ranges {
+			if value >= r.From && (r.To == nil || value < *r.To) {
+				return r.Points
+			}
+		}
+		return 0
+	}
+
+	earned := 0
+	for _, value := range conditionValues(answer) {
+		earned += points.Options[value]
+	}
+	return earned
+}
+
+func formatAnswer(answer interface{}) string {
+	switch value := answer.(type) {
+	case bool:
+		if value {
+			return "Ja"
+		}
+		return "Nein"
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	}
+	return strings.Join(conditionValues(answer), ", ")
+}
+
+// checkPoints makes sure points are only given to answers the question can have
+func checkPoints(points *models.AnswerPoints, questionType models.QuestionType, options []string) error {
+	if points == nil {
+		return nil
+	}
+
+	switch {
+	case questionType == models.QuestionTypeNumber:
+		if len(points.Options) > 0 || len(points.Ranges) == 0 {
+			return fmt.Errorf("%w: number questions give points by range", ErrInvalidQuestion)
+		}
+		for _, r := range points.Ranges {
+			if r.To != nil && *r.To <= r.From {
+				return fmt.Errorf("%w: points range must end after it starts", ErrInvalidQuestion)
+			}
+			if r.Points < -maxPoints || r.Points > maxPoints {
+				return fmt.Errorf("%w: points must be between %d and %d", ErrInvalidQuestion, -maxPoints, maxPoints)
+			}
+		}
+		return nil
+	case questionType == models.QuestionTypeYesNo:
+		options = []string{"true", "false"}
+	case !questionType.HasOptions():
+		return fmt.Errorf("%w: only choice, yes/no and number questions give points", ErrInvalidQuestion)
+	}
+
+	if len(points.Ranges) > 0 || len(points.Options) == 0 {
+		return fmt.Errorf("%w: choice questions give points by option", ErrInvalidQuestion)
+	}
+	for option, p := range points.Options {
+		if !contains(options, option) {
+			return fmt.Errorf("%w: points for unknown option %q", ErrInvalidQuestion, option)
+		}
+		if p < -maxPoints || p > maxPoints {
+			return fmt.Errorf("%w: points must be between %d and %d", ErrInvalidQuestion, -maxPoints, maxPoints)
+		}
+	}
+	return nil
+}
+
+// recommend returns the active package of the type recommended for the
+// score, nil if there is none
+func (s *Service) recommend(tx *gorm.DB, score int) (*models.Package, error) {
+	var pkg models.Package
+	err := tx.Where("type = ? AND is_active = ?", PackageTypeForScore(score), true).
+		Order("sort_order ASC, price ASC").First(&pkg).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to load package: %w", err)
+	}
+	return &pkg, nil
+}
+
+// qualify scores the answers of a response and sets the lead's priority
+func (s *Service) qualify(tx *gorm.DB, response *models.QuestionnaireResponse, questions []models.QuestionnaireQuestion, answers map[string]interface{}) error {
+	total, reasons, scored, err := score(questions, answers)
+	if err != nil || !scored {
+		return err
+	}
+
+	pkg, err := s.recommend(tx, total)
+	if err != nil {
+		return err
+	}
+	response.Score = &total
+	response.ScoreReasons = strings.Join(reasons, "\n")
+	response.RecommendedPackageID = nil
+	response.RecommendedPackage = pkg
+	if pkg != nil {
+		response.RecommendedPackageID = &pkg.ID
+	}
+
+	if err := tx.Model(&models.Lead{}).Where("id = ?", response.LeadID).Update("priority", PriorityForScore(total)).Error; err != nil {
+		return fmt.Errorf("failed to update lead priority: %w", err)
+	}
+	return nil
+}
+
+// qualification presents the score of a response, nil if it was not scored
+func qualification(response *models.QuestionnaireResponse) *Qualification {
+	if response.Score == nil {
+		return nil
+	}
+	result := &Qualification{
+		Score:    *response.Score,
+		Reasons:  []string{},
+		Priority: PriorityForScore(*response.Score),
+	}
+	if response.ScoreReasons != "" {
+		result.Reasons = strings.Split(response.ScoreReasons, "\n")
+	}
+	if response.RecommendedPackage != nil {
+		result.RecommendedPackage = &PackageSummary{
+			ID:   response.RecommendedPackage.ID,
+			Name: response.RecommendedPackage.Name,
+			Type: response.RecommendedPackage.Type,
+		}
+	}
+	return result
+}
@@ -0,0 +1,430 @@
+package questionnaires
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/internal/scopes"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// maxTextAnswer is the longest answer to a text question
+const maxTextAnswer = 2000
+
+// SubmitInput are the answers of a lead to a published questionnaire by
+// question key. Answers to questions hidden by their condition are dropped.
+type SubmitInput struct {
+	QuestionnaireID uuid.UUID                  `json:"questionnaire_id" binding:"required"`
+	Answers         map[string]json.RawMessage `json:"answers" binding:"required"`
+}
+
+// Answer is the answer to one question, as asked
+type Answer struct {
+	Key   string              `json:"key"`
+	Label string              `json:"label"`
+	Type  models.QuestionType `json:"type"`
+	Value interface{}         `json:"value"`
+}
+
+// Response is the latest answered questionnaire of a lead. Only staff see
+// the qualification.
+type Response struct {
+	ID              uuid.UUID      `json:"id"`
+	LeadID          uuid.UUID      `json:"lead_id"`
+	QuestionnaireID uuid.UUID      `json:"questionnaire_id"`
+	Title           string         `json:"title"`
+	Version         int            `json:"version"`
+	UserID          *uuid.UUID     `json:"user_id"`
+	SubmittedAt     time.Time      `json:"submitted_at"`
+	Answers         []Answer       `json:"answers"`
+	Qualification   *Qualification `json:"qualification,omitempty"`
+}
+
+// Prefill are the answers of a lead that fill the calculators and the
+// application. Fields nobody answered are left out.
+type Prefill struct {
+	ResponseID               uuid.UUID           `json:"response_id"`
+	ChildBirthDate           *string             `json:"child_birth_date,omitempty"` // YYYY-MM-DD
+	SingleParent             *bool               `json:"single_parent,omitempty"`
+	IncomeBefore             *float64            `json:"income_before,omitempty"`
+	PartnerIncomeBefore      *float64            `json:"partner_income_before,omitempty"`
+	IncomeAfter              *float64            `json:"income_after,omitempty"`
+	WeeklyHours              *float64            `json:"weekly_hours,omitempty"`
+	EmployerSupplementPerDay *float64            `json:"employer_supplement_per_day,omitempty"`
+	Plan                     models.ScenarioPlan `json:"plan"` // parents and incomes of a first scenario, without months
+}
+
+// Submit stores the answers of a lead to a published questionnaire, replacing
+// earlier answers to the same version. A birth date answered for the
+// calculators is copied to the lead if it has none yet, and questionnaires
+// with points set the lead's priority from the qualification score.
+func (s *Service) Submit(leadID uuid.UUID, viewer scopes.Viewer, input SubmitInput) (*Response, error) {
+	questionnaire, err := s.published(s.db, input.QuestionnaireID)
+	if err != nil {
+		return nil, err
+	}
+
+	var response *models.QuestionnaireResponse
+	var answers map[string]interface{}
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		lead, err := s.lead(tx, leadID, scopes.EditableLeads(viewer))
+		if err != nil {
+			return err
+		}
+		response, answers, err = s.save(tx, questionnaire, lead, &viewer.ID, input.Answers)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Questionnaire answered",
+		zap.String("lead_id", leadID.String()),
+		zap.String("questionnaire_id", questionnaire.ID.String()),
+		zap.String("user_id", viewer.ID.String()))
+	return present(questionnaire, response, answers, viewer), nil
+}
+
+// SubmitPreTalk stores the answers given with a pre-talk booking in the
+// transaction that creates its lead. Anonymous bookings have no user. The
+// qualification is nil for questionnaires without points.
+func (s *Service) SubmitPreTalk(tx *gorm.DB, leadID uuid.UUID, userID *uuid.UUID, input SubmitInput) (*Qualification, error) {
+	questionnaire, err := s.published(tx, input.QuestionnaireID)
+	if err != nil {
+		return nil, err
+	}
+	lead, err := s.lead(tx, leadID, func(db *gorm.DB) *gorm.DB { return db })
+	if err != nil {
+		return nil, err
+	}
+	response, _, err := s.save(tx, questionnaire, lead, userID, input.Answers)
+	if err != nil {
+		return nil, err
+	}
+	return qualification(response), nil
+}
+
+// save validates, scores and stores the answers of a lead
+func (s *Service) save(tx *gorm.DB, questionnaire *models.Questionnaire, lead *models.Lead, userID *uuid.UUID, raw map[string]json.RawMessage) (*models.QuestionnaireResponse, map[string]interface{}, error) {
+	answers, err := validateAnswers(questionnaire.Questions, raw)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	response := &models.QuestionnaireResponse{
+		QuestionnaireID: questionnaire.ID,
+		LeadID:          lead.ID,
+		UserID:          userID,
+		SubmittedAt:     s.now(),
+	}
+	if response.Answers, err = json.Marshal(answers); err != nil {
+		return nil, nil, fmt.Errorf("failed to encode answers: %w", err)
+	}
+	if err := s.qualify(tx, response, questionnaire.Questions, answers); err != nil {
+		return nil, nil, err
+	}
+
+	if err := tx.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "questionnaire_id"}, {Name: "lead_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"user_id", "answers", "submitted_at",
+			"score", "score_reasons", "recommended_package_id", "updated_at"}),
+	}).Create(response).Error; err != nil {
+		return nil, nil, fmt.Errorf("failed to save answers: %w", err)
+	}
+	// Answering again keeps the ID of the first response
+	var stored models.QuestionnaireResponse
+	if err := tx.Where("questionnaire_id = ? AND lead_id = ?", questionnaire.ID, lead.ID).First(&stored).Error; err != nil {
+		return nil, nil, fmt.Errorf("failed to load answers: %w", err)
+	}
+	stored.RecommendedPackage = response.RecommendedPackage
+
+	prefill := buildPrefill(questionnaire.Questions, answers)
+	if lead.ChildBirthDate == nil && prefill.ChildBirthDate != nil {
+		birthDate, _ := time.Parse("2006-01-02", *prefill.ChildBirthDate)
+		if err := tx.Model(lead).Update("child_birth_date", birthDate).Error; err != nil {
+			return nil, nil, fmt.Errorf("failed to update lead: %w", err)
+		}
+	}
+	return &stored, answers, nil
+}
+
+// Response returns the latest answered questionnaire of a lead
+func (s *Service) Response(leadID uuid.UUID, viewer scopes.Viewer) (*Response, error) {
+	response, answers, err := s.latest(leadID, viewer)
+	if err != nil {
+		return nil, err
+	}
+	return present(response.Questionnaire, response, answers, viewer), nil
+}
+
+// Prefill returns the inputs of the calculators and the application the
+// latest answered questionnaire of a lead fills
+func (s *Service) Prefill(leadID uuid.UUID, viewer scopes.Viewer) (*Prefill, error) {
+	response, answers, err := s.latest(leadID, viewer)
+	if err != nil {
+		return nil, err
+	}
+	prefill := buildPrefill(response.Questionnaire.Questions, answers)
+	prefill.ResponseID = response.ID
+	return prefill, nil
+}
+
+func (s *Service) latest(leadID uuid.UUID, viewer scopes.Viewer) (*models.QuestionnaireResponse, map[string]interface{}, error) {
+	if _, err := s.lead(s.db, leadID, scopes.VisibleLeads(viewer)); err != nil {
+		return nil, nil, err
+	}
+
+	var response models.QuestionnaireResponse
+	err := s.db.Preload("Questionnaire.Questions", orderQuestions).Preload("RecommendedPackage").
+		Where("lead_id = ?", leadID).Order("submitted_at DESC").First(&response).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrNoResponse
+		}
+		return nil, nil, fmt.Errorf("failed to load answers: %w", err)
+	}
+	answers, err := response.GetAnswers()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to decode answers: %w", err)
+	}
+	return &response, answers, nil
+}
+
+func (s *Service) lead(tx *gorm.DB, leadID uuid.UUID, scope func(*gorm.DB) *gorm.DB) (*models.Lead, error) {
+	var lead models.Lead
+	if err := tx.Scopes(scope).Select("leads.id", "leads.child_birth_date").First(&lead, "leads.id = ?", leadID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrLeadNotFound
+		}
+		return nil, fmt.Errorf("failed to load lead: %w", err)
+	}
+	return &lead, nil
+}
+
+// validateAnswers checks the answers question by question in their order and
+// returns those of the questions that are shown
+func validateAnswers(questions []models.QuestionnaireQuestion, raw map[string]json.RawMessage) (map[string]interface{}, error) {
+	known := make(map[string]bool, len(questions))
+	for _, question := range questions {
+		known[question.Key] = true
+	}
+	for key := range raw {
+		if !known[key] {
+			return nil, fmt.Errorf("%w: unknown question %q", ErrInvalidAnswers, key)
+		}
+	}
+
+	answers := map[string]interface{}{}
+	for _, question := range questions {
+		shown, err := isShown(&question, answers)
+		if err != nil {
+			return nil, err
+		}
+		if !shown {
+			continue
+		}
+
+		value, err := decodeAnswer(&question, raw[question.Key])
+		if err != nil {
+			return nil, err
+		}
+		if value == nil {
+			if question.Required {
+				return nil, fmt.Errorf("%w: %q is required", ErrInvalidAnswers, question.Key)
+			}
+			continue
+		}
+		answers[question.Key] = value
+	}
+	return answers, nil
+}
+
+// isShown checks the condition of a question against the answers so far
+func isShown(question *models.QuestionnaireQuestion, answers map[string]interface{}) (bool, error) {
+	condition, err := question.GetShowIf()
+	if err != nil {
+		return false, fmt.Errorf("failed to decode condition: %w", err)
+	}
+	if condition == nil {
+		return true, nil
+	}
+	answer, ok := answers[condition.QuestionKey]
+	if !ok {
+		return false, nil
+	}
+	for _, value := range conditionValues(answer) {
+		if contains(condition.Values, value) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// conditionValues returns an answer as the values conditions compare
+func conditionValues(answer interface{}) []string {
+	switch value := answer.(type) {
+	case bool:
+		return []string{strconv.FormatBool(value)}
+	case float64:
+		return []string{strconv.FormatFloat(value, 'f', -1, 64)}
+	case string:
+		return []string{value}
+	case []string:
+		return value
+	case []interface{}:
+		values := make([]string, 0, len(value))
+		for _, v := range value {
+			values = append(values, fmt.Sprint(v))
+		}
+		return values
+	}
+	return nil
+}
+
+// decodeAnswer decodes the answer to a question, nil if it was not answered
+func decodeAnswer(question *models.QuestionnaireQuestion, raw json.RawMessage) (interface{}, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+	invalid := fmt.Errorf("%w: %q needs a %s answer", ErrInvalidAnswers, question.Key, question.Type)
+
+	switch question.Type {
+	case models.QuestionTypeYesNo:
+		var value bool
+		if err := json.Unmarshal(raw, &value); err != nil {
+			return nil, invalid
+		}
+		return value, nil
+	case models.QuestionTypeNumber:
+		var value float64
+		if err := json.Unmarshal(raw, &value); err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+			return nil, invalid
+		}
+		return value, nil
+	case models.QuestionTypeMultipleChoice:
+		var values []string
+		if err := json.Unmarshal(raw, &values); err != nil {
+			return nil, invalid
+		}
+		if len(values) == 0 {
+			return nil, nil
+		}
+		options, err := question.GetOptions()
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode options: %w", err)
+		}
+		seen := make(map[string]bool, len(values))
+		for _, value := range values {
+			if !contains(options, value) || seen[value] {
+				return nil, fmt.Errorf("%w: %q has no option %q", ErrInvalidAnswers, question.Key, value)
+			}
+			seen[value] = true
+		}
+		return values, nil
+	}
+
+	var value string
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return nil, invalid
+	}
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, nil
+	}
+	switch question.Type {
+	case models.QuestionTypeDate:
+		if _, err := time.Parse("2006-01-02", value); err != nil {
+			return nil, invalid
+		}
+	case models.QuestionTypeChoice:
+		options, err := question.GetOptions()
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode options: %w", err)
+		}
+		if !contains(options, value) {
+			return nil, fmt.Errorf("%w: %q has no option %q", ErrInvalidAnswers, question.Key, value)
+		}
+	default:
+		if len([]rune(value)) > maxTextAnswer {
+			return nil, fmt.Errorf("%w: %q is longer than %d characters", ErrInvalidAnswers, question.Key, maxTextAnswer)
+		}
+	}
+	return value, nil
+}
+
+// buildPrefill copies the answers of the questions with a prefill field
+func buildPrefill(questions []models.QuestionnaireQuestion, answers map[string]interface{}) *Prefill {
+	prefill := &Prefill{}
+	for _, question := range questions {
+		answer, ok := answers[question.Key]
+		if !ok || question.Prefill == "" {
+			continue
+		}
+		switch value := answer.(type) {
+		case string:
+			if question.Prefill == models.PrefillChildBirthDate {
+				prefill.ChildBirthDate = &value
+			}
+		case bool:
+			if question.Prefill == models.PrefillSingleParent {
+				prefill.SingleParent = &value
+			}
+		case float64:
+			switch question.Prefill {
+			case models.PrefillIncomeBefore:
+				prefill.IncomeBefore = &value
+			case models.PrefillPartnerIncomeBefore:
+				prefill.PartnerIncomeBefore = &value
+			case models.PrefillIncomeAfter:
+				prefill.IncomeAfter = &value
+			case models.PrefillWeeklyHours:
+				prefill.WeeklyHours = &value
+			case models.PrefillEmployerSupplementPerDay:
+				prefill.EmployerSupplementPerDay = &value
+			}
+		}
+	}
+
+	prefill.Plan.SingleParent = prefill.SingleParent != nil && *prefill.SingleParent
+	prefill.Plan.Parents = []models.ScenarioParent{{Name: "Antragsteller"}}
+	if prefill.IncomeBefore != nil {
+		prefill.Plan.Parents[0].IncomeBefore = *prefill.IncomeBefore
+	}
+	if !prefill.Plan.SingleParent && prefill.PartnerIncomeBefore != nil {
+		prefill.Plan.Parents = append(prefill.Plan.Parents, models.ScenarioParent{Name: "Partner", IncomeBefore: *prefill.PartnerIncomeBefore})
+	}
+	return prefill
+}
+
+// present lists the answers in the order of the questions
+func present(questionnaire *models.Questionnaire, response *models.QuestionnaireResponse, answers map[string]interface{}, viewer scopes.Viewer) *Response {
+	result := &Response{
+		ID:              response.ID,
+		LeadID:          response.LeadID,
+		QuestionnaireID: questionnaire.ID,
+		Title:           questionnaire.Title,
+		Version:         questionnaire.Version,
+		UserID:          response.UserID,
+		SubmittedAt:     response.SubmittedAt,
+		Answers:         []Answer{},
+	}
+	for _, question := range questionnaire.Questions {
+		if value, ok := answers[question.Key]; ok {
+			result.Answers = append(result.Answers, Answer{Key: question.Key, Label: question.Label, Type: question.Type, Value: value})
+		}
+	}
+	if viewer.Role != models.RoleUser {
+		result.Qualification = qualification(response)
+	}
+	return result
+}
@@ -0,0 +1,570 @@
+// Package questionnaires lets admins build the intake questionnaire of each
+// package: questions of several types, shown only when earlier answers call
+// for them, in versions that no longer change once published. Customers
+// answer the current version for their lead; the answers are stored by
+// question key and pre-fill the calculators and the application.
+package questionnaires
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"elterngeld-portal/internal/models"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrNotFound is returned for unknown questionnaires, and for drafts when customers ask
+	ErrNotFound = errors.New("questionnaire not found")
+	// ErrPackageNotFound is returned when creating a questionnaire for an unknown package
+	ErrPackageNotFound = errors.New("package not found")
+	// ErrQuestionNotFound is returned when the question does not exist in the questionnaire
+	ErrQuestionNotFound = errors.New("question not found")
+	// ErrPublished is returned when changing a published version; revise it instead
+	ErrPublished = errors.New("questionnaire already published")
+	// ErrDraftExists is returned when the package already has a draft version
+	ErrDraftExists = errors.New("package already has a draft questionnaire")
+	// ErrNoQuestions is returned when publishing a questionnaire without questions
+	ErrNoQuestions = errors.New("questionnaire has no questions")
+	// ErrInvalidQuestion is returned for malformed keys, options, conditions or prefill fields
+	ErrInvalidQuestion = errors.New("invalid question")
+	// ErrDuplicateKey is returned when another question of the questionnaire has the key
+	ErrDuplicateKey = errors.New("question key already used")
+	// ErrLeadNotFound is returned when the lead does not exist or the viewer may not access it
+	ErrLeadNotFound = errors.New("lead not found")
+	// ErrInvalidAnswers is returned for missing required answers and answers of the wrong type
+	ErrInvalidAnswers = errors.New("invalid answers")
+	// ErrNoResponse is returned when the lead has not answered a questionnaire yet
+	ErrNoResponse = errors.New("questionnaire not answered")
+)
+
+// keyPattern restricts question keys to lower snake case, e.g. "income_mother"
+var keyPattern = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// CreateInput starts the next version of the questionnaire of a package
+type CreateInput struct {
+	PackageID   uuid.UUID `json:"package_id" binding:"required"`
+	Title       string    `json:"title" binding:"required,max=200"`
+	Description string    `json:"description" binding:"max=2000"`
+}
+
+// UpdateInput changes the title and introduction of a draft
+type UpdateInput struct {
+	Title       string `json:"title" binding:"required,max=200"`
+	Description string `json:"description" binding:"max=2000"`
+}
+
+// QuestionInput describes a question. Choice questions need at least two
+// options; conditions refer to questions placed before the question. Points
+// count the answers towards the qualification score of the lead.
+type QuestionInput struct {
+	Key      string                    `json:"key" binding:"required,max=50"`
+	Label    string                    `json:"label" binding:"required,max=300"`
+	HelpText string                    `json:"help_text" binding:"max=2000"`
+	Type     models.QuestionType       `json:"type" binding:"required"`
+	Required bool                      `json:"required"`
+	Options  []string                  `json:"options" binding:"max=50,dive,required,max=200"`
+	ShowIf   *models.QuestionCondition `json:"show_if"`
+	Prefill  models.PrefillField       `json:"prefill"`
+	Points   *models.AnswerPoints      `json:"points"`
+	Position int                       `json:"position"`
+}
+
+// Service manages questionnaires and the answers of leads
+type Service struct {
+	db     *gorm.DB
+	logger *zap.Logger
+	now    func() time.Time
+}
+
+func NewService(db *gorm.DB, logger *zap.Logger) *Service {
+	return &Service{
+		db:     db,
+		logger: logger,
+		now:    time.Now,
+	}
+}
+
+// List returns the questionnaire versions, newest first, optionally of one package
+func (s *Service) List(packageID *uuid.UUID) ([]models.Questionnaire, error) {
+	query := s.db.Order("package_id ASC, version DESC")
+	if packageID != nil {
+		query = query.Where("package_id = ?", *packageID)
+	}
+
+	questionnaires := []models.Questionnaire{}
+	if err := query.Find(&questionnaires).Error; err != nil {
+		return nil, fmt.Errorf("failed to load questionnaires: %w", err)
+	}
+	return questionnaires, nil
+}
+
+// Get returns a questionnaire version with its questions
+func (s *Service) Get(id uuid.UUID) (*models.Questionnaire, error) {
+	return s.find(s.db, id)
+}
+
+// Current returns the latest published version of the questionnaire of a
+// package, the one customers answer
+func (s *Service) Current(packageID uuid.UUID) (*models.Questionnaire, error) {
+	var questionnaire models.Questionnaire
+	err := s.db.Where("package_id = ? AND published_at IS NOT NULL", packageID).
+		Order("version DESC").Preload("Questions", orderQuestions).First(&questionnaire).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to load questionnaire: %w", err)
+	}
+	return &questionnaire, nil
+}
+
+// Create starts a draft as the next version of the questionnaire of a package
+func (s *Service) Create(userID uuid.UUID, input CreateInput) (*models.Questionnaire, error) {
+	var questionnaire *models.Questionnaire
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var packages int64
+		if err := tx.Model(&models.Package{}).Where("id = ?", input.PackageID).Count(&packages).Error; err != nil {
+			return fmt.Errorf("failed to check package: %w", err)
+		}
+		if packages == 0 {
+			return ErrPackageNotFound
+		}
+
+		version, err := s.nextVersion(tx, input.PackageID)
+		if err != nil {
+			return err
+		}
+		questionnaire = &models.Questionnaire{
+			PackageID:   input.PackageID,
+			Version:     version,
+			Title:       strings.TrimSpace(input.Title),
+			Description: strings.TrimSpace(input.Description),
+			CreatedByID: userID,
+		}
+		if err := tx.Create(questionnaire).Error; err != nil {
+			return fmt.Errorf("failed to create questionnaire: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return questionnaire, nil
+}
+
+// Update changes the title and introduction of a draft
+func (s *Service) Update(id uuid.UUID, input UpdateInput) (*models.Questionnaire, error) {
+	questionnaire, err := s.draft(s.db, id)
+	if err != nil {
+		return nil, err
+	}
+
+	questionnaire.Title = strings.TrimSpace(input.Title)
+	questionnaire.Description = strings.TrimSpace(input.Description)
+	if err := s.db.Model(questionnaire).Select("title", "description").Updates(questionnaire).Error; err != nil {
+		return nil, fmt.Errorf("failed to update questionnaire: %w", err)
+	}
+	return questionnaire, nil
+}
+
+// Delete removes a draft. Published versions are kept for their answers.
+func (s *Service) Delete(id uuid.UUID) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		questionnaire, err := s.draft(tx, id)
+		if err != nil {
+			return err
+		}
+		if err := tx.Where("questionnaire_id = ?", questionnaire.ID).Delete(&models.QuestionnaireQuestion{}).Error; err != nil {
+			return fmt.Errorf("failed to delete questions: %w", err)
+		}
+		if err := tx.Delete(questionnaire).Error; err != nil {
+			return fmt.Errorf("failed to delete questionnaire: %w", err)
+		}
+		return nil
+	})
+}
+
+// Publish makes a draft the version customers answer from now on
+func (s *Service) Publish(id uuid.UUID) (*models.Questionnaire, error) {
+	questionnaire, err := s.draft(s.db, id)
+	if err != nil {
+		return nil, err
+	}
+	if len(questionnaire.Questions) == 0 {
+		return nil, ErrNoQuestions
+	}
+
+	now := s.now()
+	if err := s.db.Model(questionnaire).Update("published_at", now).Error; err != nil {
+		return nil, fmt.Errorf("failed to publish questionnaire: %w", err)
+	}
+	questionnaire.PublishedAt = &now
+
+	s.logger.Info("Questionnaire published",
+		zap.String("questionnaire_id", questionnaire.ID.String()),
+		zap.String("package_id", questionnaire.PackageID.String()),
+		zap.Int("version", questionnaire.Version))
+	return questionnaire, nil
+}
+
+// Revise copies a version with its questions into a new draft of the package
+func (s *Service) Revise(id, userID uuid.UUID) (*models.Questionnaire, error) {
+	var revision *models.Questionnaire
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		source, err := s.find(tx, id)
+		if err != nil {
+			return err
+		}
+		version, err := s.nextVersion(tx, source.PackageID)
+		if err != nil {
+			return err
+		}
+
+		revision = &models.Questionnaire{
+			PackageID:   source.PackageID,
+			Version:     version,
+			Title:       source.Title,
+			Description: source.Description,
+			CreatedByID: userID,
+		}
+		for _, question := range source.Questions {
+			question.ID = uuid.Nil
+			question.QuestionnaireID = uuid.Nil
+			revision.Questions = append(revision.Questions, question)
+		}
+		if err := tx.Create(revision).Error; err != nil {
+			return fmt.Errorf("failed to create questionnaire: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return revision, nil
+}
+
+// AddQuestion adds a question to a draft
+func (s *Service) AddQuestion(id uuid.UUID, input QuestionInput) (*models.QuestionnaireQuestion, error) {
+	question := &models.QuestionnaireQuestion{QuestionnaireID: id}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		questionnaire, err := s.draft(tx, id)
+		if err != nil {
+			return err
+		}
+		if err := s.apply(question, questionnaire.Questions, input); err != nil {
+			return err
+		}
+		if err := tx.Create(question).Error; err != nil {
+			return fmt.Errorf("failed to create question: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return question, nil
+}
+
+// UpdateQuestion changes a question of a draft. Conditions of later questions
+// on it must still hold.
+func (s *Service) UpdateQuestion(id, questionID uuid.UUID, input QuestionInput) (*models.QuestionnaireQuestion, error) {
+	var question models.QuestionnaireQuestion
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		questionnaire, err := s.draft(tx, id)
+		if err != nil {
+			return err
+		}
+
+		others := make([]models.QuestionnaireQuestion, 0, len(questionnaire.Questions))
+		found := false
+		for _, q := range questionnaire.Questions {
+			if q.ID == questionID {
+				question, found = q, true
+				continue
+			}
+			others = append(others, q)
+		}
+		if !found {
+			return ErrQuestionNotFound
+		}
+		previousKey := question.Key
+
+		if err := s.apply(&question, others, input); err != nil {
+			return err
+		}
+		if err := checkDependents(previousKey, &question, others); err != nil {
+			return err
+		}
+		if err := tx.Save(&question).Error; err != nil {
+			return fmt.Errorf("failed to update question: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &question, nil
+}
+
+// DeleteQuestion removes a question from a draft unless other questions depend on it
+func (s *Service) DeleteQuestion(id, questionID uuid.UUID) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		questionnaire, err := s.draft(tx, id)
+		if err != nil {
+			return err
+		}
+
+		var question *models.QuestionnaireQuestion
+		for i := range questionnaire.Questions {
+			if questionnaire.Questions[i].ID == questionID {
+				question = &questionnaire.Questions[i]
+			}
+		}
+		if question == nil {
+			return ErrQuestionNotFound
+		}
+		for _, q := range questionnaire.Questions {
+			condition, err := q.GetShowIf()
+			if err != nil {
+				return fmt.Errorf("failed to decode condition: %w", err)
+			}
+			if condition != nil && condition.QuestionKey == question.Key {
+				return fmt.Errorf("%w: question %q depends on %q", ErrInvalidQuestion, q.Key, question.Key)
+			}
+		}
+
+		if err := tx.Delete(question).Error; err != nil {
+			return fmt.Errorf("failed to delete question: %w", err)
+		}
+		return nil
+	})
+}
+
+// apply validates the input against the other questions of the questionnaire
+// and copies it into the question
+func (s *Service) apply(question *models.QuestionnaireQuestion, others []models.QuestionnaireQuestion, input QuestionInput) error {
+	key := strings.TrimSpace(input.Key)
+	if !keyPattern.MatchString(key) {
+		return fmt.Errorf("%w: key %q must be lower snake case", ErrInvalidQuestion, key)
+	}
+	if !input.Type.IsValid() {
+		return fmt.Errorf("%w: unknown type %q", ErrInvalidQuestion, input.Type)
+	}
+
+	options, err := questionOptions(input)
+	if err != nil {
+		return err
+	}
+
+	if input.Prefill != "" {
+		if !input.Prefill.IsValid() {
+			return fmt.Errorf("%w: unknown prefill field %q", ErrInvalidQuestion, input.Prefill)
+		}
+		if input.Prefill.QuestionType() != input.Type {
+			return fmt.Errorf("%w: %s is filled by %s questions", ErrInvalidQuestion, input.Prefill, input.Prefill.QuestionType())
+		}
+	}
+
+	byKey := make(map[string]*models.QuestionnaireQuestion, len(others))
+	for i, other := range others {
+		if other.Key == key {
+			return ErrDuplicateKey
+		}
+		if input.Prefill != "" && other.Prefill == input.Prefill {
+			return fmt.Errorf("%w: question %q already fills %s", ErrInvalidQuestion, other.Key, input.Prefill)
+		}
+		byKey[other.Key] = &others[i]
+	}
+
+	var showIf json.RawMessage
+	if input.ShowIf != nil {
+		if err := checkCondition(input.ShowIf, input.Position, byKey); err != nil {
+			return err
+		}
+		if showIf, err = json.Marshal(input.ShowIf); err != nil {
+			return fmt.Errorf("failed to encode condition: %w", err)
+		}
+	}
+	encodedOptions, err := json.Marshal(options)
+	if err != nil {
+		return fmt.Errorf("failed to encode options: %w", err)
+	}
+	if err := checkPoints(input.Points, input.Type, options); err != nil {
+		return err
+	}
+	var points json.RawMessage
+	if input.Points != nil {
+		if points, err = json.Marshal(input.Points); err != nil {
+			return fmt.Errorf("failed to encode points: %w", err)
+		}
+	}
+
+	question.Key = key
+	question.Label = strings.TrimSpace(input.Label)
+	question.HelpText = strings.TrimSpace(input.HelpText)
+	question.Type = input.Type
+	question.Required = input.Required
+	question.Options = encodedOptions
+	question.ShowIf = showIf
+	question.Prefill = input.Prefill
+	question.Points = points
+	question.Position = input.Position
+	return nil
+}
+
+// questionOptions returns the trimmed options of a choice question
+func questionOptions(input QuestionInput) ([]string, error) {
+	if !input.Type.HasOptions() {
+		if len(input.Options) > 0 {
+			return nil, fmt.Errorf("%w: only choice questions have options", ErrInvalidQuestion)
+		}
+		return []string{}, nil
+	}
+
+	options := make([]string, 0, len(input.Options))
+	seen := make(map[string]bool, len(input.Options))
+	for _, option := range input.Options {
+		option = strings.TrimSpace(option)
+		if option == "" || seen[option] {
+			return nil, fmt.Errorf("%w: options must be distinct and not empty", ErrInvalidQuestion)
+		}
+		seen[option] = true
+		options = append(options, option)
+	}
+	if len(options) < 2 {
+		return nil, fmt.Errorf("%w: choice questions need at least two options", ErrInvalidQuestion)
+	}
+	return options, nil
+}
+
+// checkCondition makes sure a condition refers to a question before the
+// position and only to answers that question can have
+func checkCondition(condition *models.QuestionCondition, position int, byKey map[string]*models.QuestionnaireQuestion) error {
+	target, ok := byKey[condition.QuestionKey]
+	if !ok {
+		return fmt.Errorf("%w: condition refers to unknown question %q", ErrInvalidQuestion, condition.QuestionKey)
+	}
+	if target.Position >= position {
+		return fmt.Errorf("%w: condition refers to question %q that is not asked before", ErrInvalidQuestion, target.Key)
+	}
+	if len(condition.Values) == 0 {
+		return fmt.Errorf("%w: condition needs values", ErrInvalidQuestion)
+	}
+
+	var allowed []string
+	switch {
+	case target.Type.HasOptions():
+		options, err := target.GetOptions()
+		if err != nil {
+			return fmt.Errorf("failed to decode options: %w", err)
+		}
+		allowed = options
+	case target.Type == models.QuestionTypeYesNo:
+		allowed = []string{"true", "false"}
+	default:
+		return nil
+	}
+	for _, value := range condition.Values {
+		if !contains(allowed, value) {
+			return fmt.Errorf("%w: question %q cannot be answered with %q", ErrInvalidQuestion, target.Key, value)
+		}
+	}
+	return nil
+}
+
+// checkDependents makes sure the questions with a condition on a changed
+// question can still rely on it
+func checkDependents(previousKey string, question *models.QuestionnaireQuestion, others []models.QuestionnaireQuestion) error {
+	byKey := map[string]*models.QuestionnaireQuestion{question.Key: question}
+	for _, other := range others {
+		condition, err := other.GetShowIf()
+		if err != nil {
+			return fmt.Errorf("failed to decode condition: %w", err)
+		}
+		if condition == nil || condition.QuestionKey != previousKey {
+			continue
+		}
+		if question.Key != previousKey {
+			return fmt.Errorf("%w: question %q depends on key %q", ErrInvalidQuestion, other.Key, previousKey)
+		}
+		if err := checkCondition(condition, other.Position, byKey); err != nil {
+			return fmt.Errorf("%w (condition of question %q)", err, other.Key)
+		}
+	}
+	return nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// nextVersion returns the version of a new draft of the package. A package
+// has at most one draft at a time.
+func (s *Service) nextVersion(tx *gorm.DB, packageID uuid.UUID) (int, error) {
+	var drafts int64
+	if err := tx.Model(&models.Questionnaire{}).Where("package_id = ? AND published_at IS NULL", packageID).Count(&drafts).Error; err != nil {
+		return 0, fmt.Errorf("failed to check drafts: %w", err)
+	}
+	if drafts > 0 {
+		return 0, ErrDraftExists
+	}
+
+	var latest int
+	if err := tx.Model(&models.Questionnaire{}).Where("package_id = ?", packageID).
+		Select("COALESCE(MAX(version), 0)").Scan(&latest).Error; err != nil {
+		return 0, fmt.Errorf("failed to load latest version: %w", err)
+	}
+	return latest + 1, nil
+}
+
+func (s *Service) find(tx *gorm.DB, id uuid.UUID) (*models.Questionnaire, error) {
+	var questionnaire models.Questionnaire
+	if err := tx.Preload("Questions", orderQuestions).First(&questionnaire, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to load questionnaire: %w", err)
+	}
+	return &questionnaire, nil
+}
+
+// published loads a questionnaire customers can answer
+func (s *Service) published(tx *gorm.DB, id uuid.UUID) (*models.Questionnaire, error) {
+	questionnaire, err := s.find(tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !questionnaire.IsPublished() {
+		return nil, ErrNotFound
+	}
+	return questionnaire, nil
+}
+
+// draft loads a questionnaire that can still be changed
+func (s *Service) draft(tx *gorm.DB, id uuid.UUID) (*models.Questionnaire, error) {
+	questionnaire, err := s.find(tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if questionnaire.IsPublished() {
+		return nil, ErrPublished
+	}
+	return questionnaire, nil
+}
+
+func orderQuestions(db *gorm.DB) *gorm.DB {
+	return db.Order("position ASC, created_at ASC")
+}
@@ -0,0 +1,368 @@
+package questionnaires
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/internal/scopes"
+	"elterngeld-portal/tests/testutils"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+var now = time.Date(2025, 3, 10, 9, 0, 0, 0, time.UTC)
+
+func TestVersions_PublishedAreFrozen(t *testing.T) {
+	db, service := setupTestService(t)
+	admin := testutils.CreateTestUser(t, db, models.RoleAdmin)
+	pkg := createPackage(t, db)
+
+	_, err := service.Create(admin.ID, CreateInput{PackageID: uuid.New(), Title: "Aufnahme"})
+	assert.ErrorIs(t, err, ErrPackageNotFound)
+
+	draft, err := service.Create(admin.ID, CreateInput{PackageID: pkg.ID, Title: "Aufnahme"})
+	require.NoError(t, err)
+	assert.Equal(t, 1, draft.Version)
+	_, err = service.Create(admin.ID, CreateInput{PackageID: pkg.ID, Title: "Zweiter Entwurf"})
+	assert.ErrorIs(t, err, ErrDraftExists)
+
+	_, err = service.Publish(draft.ID)
+	assert.ErrorIs(t, err, ErrNoQuestions)
+	_, err = service.AddQuestion(draft.ID, QuestionInput{Key: "income_mother", Label: "Netto der Mutter", Type: models.QuestionTypeNumber, Position: 1})
+	require.NoError(t, err)
+	_, err = service.AddQuestion(draft.ID, QuestionInput{Key: "income_mother", Label: "Doppelt", Type: models.QuestionTypeText, Position: 2})
+	assert.ErrorIs(t, err, ErrDuplicateKey)
+
+	published, err := service.Publish(draft.ID)
+	require.NoError(t, err)
+	assert.True(t, published.IsPublished())
+	_, err = service.AddQuestion(draft.ID, QuestionInput{Key: "notes", Label: "Anmerkungen", Type: models.QuestionTypeText, Position: 2})
+	assert.ErrorIs(t, err, ErrPublished)
+	assert.ErrorIs(t, service.Delete(draft.ID), ErrPublished)
+
+	// Changes go into the next version, which customers only see once published
+	revision, err := service.Revise(draft.ID, admin.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, revision.Version)
+	require.Len(t, revision.Questions, 1)
+	assert.NotEqual(t, published.Questions[0].ID, revision.Questions[0].ID)
+	_, err = service.AddQuestion(revision.ID, QuestionInput{Key: "notes", Label: "Anmerkungen", Type: models.QuestionTypeText, Position: 2})
+	require.NoError(t, err)
+
+	current, err := service.Current(pkg.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, current.Version)
+	assert.Len(t, current.Questions, 1)
+
+	_, err = service.Publish(revision.ID)
+	require.NoError(t, err)
+	current, err = service.Current(pkg.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, current.Version)
+	assert.Len(t, current.Questions, 2)
+}
+
+func TestQuestions_Validation(t *testing.T) {
+	db, service := setupTestService(t)
+	admin := testutils.CreateTestUser(t, db, models.RoleAdmin)
+	draft, err := service.Create(admin.ID, CreateInput{PackageID: createPackage(t, db).ID, Title: "Aufnahme"})
+	require.NoError(t, err)
+
+	invalid := []QuestionInput{
+		{Key: "Einkommen", Label: "Einkommen", Type: models.QuestionTypeNumber},
+		{Key: "employment", Label: "Beschäftigung", Type: models.QuestionTypeChoice, Options: []string{"angestellt"}},
+		{Key: "employment", Label: "Beschäftigung", Type: models.QuestionTypeChoice, Options: []string{"angestellt", "angestellt"}},
+		{Key: "notes", Label: "Anmerkungen", Type: models.QuestionTypeText, Options: []string{"a", "b"}},
+		{Key: "birth", Label: "Geburt", Type: models.QuestionTypeText, Prefill: models.PrefillChildBirthDate},
+		{Key: "birth", Label: "Geburt", Type: models.QuestionTypeDate, Prefill: "unknown"},
+		{Key: "partner", Label: "Partner", Type: models.QuestionTypeText, ShowIf: &models.QuestionCondition{QuestionKey: "missing", Values: []string{"true"}}},
+	}
+	for _, input := range invalid {
+		_, err := service.AddQuestion(draft.ID, input)
+		assert.ErrorIs(t, err, ErrInvalidQuestion, input.Key)
+	}
+
+	single, err := service.AddQuestion(draft.ID, QuestionInput{Key: "single_parent", Label: "Alleinerziehend?", Type: models.QuestionTypeYesNo, Position: 1})
+	require.NoError(t, err)
+
+	// Conditions refer to earlier questions and to answers they can have
+	_, err = service.AddQuestion(draft.ID, QuestionInput{Key: "partner_income", Label: "Netto des Partners", Type: models.QuestionTypeNumber, Position: 1,
+		ShowIf: &models.QuestionCondition{QuestionKey: "single_parent", Values: []string{"false"}}})
+	assert.ErrorIs(t, err, ErrInvalidQuestion)
+	_, err = service.AddQuestion(draft.ID, QuestionInput{Key: "partner_income", Label: "Netto des Partners", Type: models.QuestionTypeNumber, Position: 2,
+		ShowIf: &models.QuestionCondition{QuestionKey: "single_parent", Values: []string{"nein"}}})
+	assert.ErrorIs(t, err, ErrInvalidQuestion)
+	_, err = service.AddQuestion(draft.ID, QuestionInput{Key: "partner_income", Label: "Netto des Partners", Type: models.QuestionTypeNumber, Position: 2,
+		ShowIf: &models.QuestionCondition{QuestionKey: "single_parent", Values: []string{"false"}}})
+	require.NoError(t, err)
+
+	// The question others depend on can neither move behind them nor go away
+	_, err = service.UpdateQuestion(draft.ID, single.ID, QuestionInput{Key: "single_parent", Label: "Alleinerziehend?", Type: models.QuestionTypeYesNo, Position: 3})
+	assert.ErrorIs(t, err, ErrInvalidQuestion)
+	_, err = service.UpdateQuestion(draft.ID, single.ID, QuestionInput{Key: "alone", Label: "Alleinerziehend?", Type: models.QuestionTypeYesNo, Position: 1})
+	assert.ErrorIs(t, err, ErrInvalidQuestion)
+	assert.ErrorIs(t, service.DeleteQuestion(draft.ID, single.ID), ErrInvalidQuestion)
+
+	updated, err := service.UpdateQuestion(draft.ID, single.ID, QuestionInput{Key: "single_parent", Label: "Sind Sie alleinerziehend?", Type: models.QuestionTypeYesNo, Required: true, Position: 1})
+	require.NoError(t, err)
+	assert.Equal(t, "Sind Sie alleinerziehend?", updated.Label)
+	assert.ErrorIs(t, service.DeleteQuestion(draft.ID, uuid.New()), ErrQuestionNotFound)
+}
+
+func TestSubmit_ConditionalAnswersAndPrefill(t *testing.T) {
+	db, service := setupTestService(t)
+	admin := testutils.CreateTestUser(t, db, models.RoleAdmin)
+	customer := testutils.CreateTestUser(t, db, models.RoleUser)
+	stranger := testutils.CreateTestUser(t, db, models.RoleUser)
+	lead := createLead(t, db, customer.ID)
+	questionnaire := publishIntake(t, service, admin, createPackage(t, db))
+
+	submit := func(answers map[string]interface{}) (*Response, error) {
+		raw := map[string]json.RawMessage{}
+		for key, value := range answers {
+			encoded, err := json.Marshal(value)
+			require.NoError(t, err)
+			raw[key] = encoded
+		}
+		return service.Submit(lead.ID, viewer(customer), SubmitInput{QuestionnaireID: questionnaire.ID, Answers: raw})
+	}
+
+	_, err := submit(map[string]interface{}{"single_parent": false})
+	assert.ErrorIs(t, err, ErrInvalidAnswers, "income is required")
+	_, err = submit(map[string]interface{}{"single_parent": "nein", "income_before": 2000})
+	assert.ErrorIs(t, err, ErrInvalidAnswers)
+	_, err = submit(map[string]interface{}{"single_parent": false, "income_before": 2000, "employment": "verbeamtet"})
+	assert.ErrorIs(t, err, ErrInvalidAnswers)
+	_, err = submit(map[string]interface{}{"single_parent": false, "income_before": 2000, "birth_date": "10.03.2025"})
+	assert.ErrorIs(t, err, ErrInvalidAnswers)
+	_, err = submit(map[string]interface{}{"single_parent": false, "income_before": 2000, "hobby": "Lesen"})
+	assert.ErrorIs(t, err, ErrInvalidAnswers)
+	_, err = service.Submit(lead.ID, viewer(stranger), SubmitInput{QuestionnaireID: questionnaire.ID})
+	assert.ErrorIs(t, err, ErrLeadNotFound)
+
+	// The partner's income is only asked without single parent
+	first, err := submit(map[string]interface{}{
+		"single_parent":  true,
+		"income_before":  2000,
+		"partner_income": 1800,
+		"birth_date":     "2025-05-20",
+		"employment":     "angestellt",
+	})
+	require.NoError(t, err)
+	keys := []string{}
+	for _, answer := range first.Answers {
+		keys = append(keys, answer.Key)
+	}
+	assert.Equal(t, []string{"single_parent", "income_before", "employment", "birth_date"}, keys)
+
+	var stored models.Lead
+	require.NoError(t, db.First(&stored, "id = ?", lead.ID).Error)
+	require.NotNil(t, stored.ChildBirthDate)
+	assert.Equal(t, "2025-05-20", stored.ChildBirthDate.Format("2006-01-02"))
+
+	second, err := submit(map[string]interface{}{
+		"single_parent":  false,
+		"income_before":  2000,
+		"partner_income": 1800,
+		"birth_date":     "2025-05-22",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, first.ID, second.ID)
+
+	// The Berater reads the answers and the calculators start from them
+	berater := testutils.CreateTestUser(t, db, models.RoleBerater)
+	response, err := service.Response(lead.ID, viewer(berater))
+	require.NoError(t, err)
+	assert.Len(t, response.Answers, 4)
+	assert.Equal(t, 1, response.Version)
+
+	prefill, err := service.Prefill(lead.ID, viewer(customer))
+	require.NoError(t, err)
+	assert.Equal(t, second.ID, prefill.ResponseID)
+	assert.Equal(t, "2025-05-22", *prefill.ChildBirthDate)
+	assert.False(t, *prefill.SingleParent)
+	assert.Equal(t, 2000.0, *prefill.IncomeBefore)
+	assert.Equal(t, 1800.0, *prefill.PartnerIncomeBefore)
+	assert.Nil(t, prefill.WeeklyHours)
+	require.Len(t, prefill.Plan.Parents, 2)
+	assert.Equal(t, 1800.0, prefill.Plan.Parents[1].IncomeBefore)
+
+	// A birth date the lead already has is kept
+	require.NoError(t, db.First(&stored, "id = ?", lead.ID).Error)
+	assert.Equal(t, "2025-05-20", stored.ChildBirthDate.Format("2006-01-02"))
+
+	_, err = service.Prefill(createLead(t, db, customer.ID).ID, viewer(customer))
+	assert.ErrorIs(t, err, ErrNoResponse)
+	_, err = service.Response(lead.ID, viewer(stranger))
+	assert.ErrorIs(t, err, ErrLeadNotFound)
+}
+
+func TestSubmit_DraftsCannotBeAnswered(t *testing.T) {
+	db, service := setupTestService(t)
+	admin := testutils.CreateTestUser(t, db, models.RoleAdmin)
+	customer := testutils.CreateTestUser(t, db, models.RoleUser)
+	lead := createLead(t, db, customer.ID)
+
+	draft, err := service.Create(admin.ID, CreateInput{PackageID: createPackage(t, db).ID, Title: "Aufnahme"})
+	require.NoError(t, err)
+	_, err = service.AddQuestion(draft.ID, QuestionInput{Key: "notes", Label: "Anmerkungen", Type: models.QuestionTypeText})
+	require.NoError(t, err)
+
+	_, err = service.Submit(lead.ID, viewer(customer), SubmitInput{QuestionnaireID: draft.ID, Answers: map[string]json.RawMessage{"notes": json.RawMessage(`"Hallo"`)}})
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestQualification_ScoresAnswers(t *testing.T) {
+	db, service := setupTestService(t)
+	admin := testutils.CreateTestUser(t, db, models.RoleAdmin)
+	customer := testutils.CreateTestUser(t, db, models.RoleUser)
+	premium := createPackage(t, db)
+	complete := createPackage(t, db)
+	require.NoError(t, db.Model(complete).Updates(map[string]interface{}{"name": "Elterngeld Komplett", "type": models.PackageTypeComplete}).Error)
+
+	draft, err := service.Create(admin.ID, CreateInput{PackageID: premium.ID, Title: "Vorgespräch"})
+	require.NoError(t, err)
+
+	// Points only go to answers the question can have
+	_, err = service.AddQuestion(draft.ID, QuestionInput{Key: "notes", Label: "Anmerkungen", Type: models.QuestionTypeText,
+		Points: &models.AnswerPoints{Options: map[string]int{"x": 5}}})
+	assert.ErrorIs(t, err, ErrInvalidQuestion)
+	_, err = service.AddQuestion(draft.ID, QuestionInput{Key: "employment", Label: "Beschäftigung", Type: models.QuestionTypeChoice,
+		Options: []string{"angestellt"}, Points: &models.AnswerPoints{Options: map[string]int{"verbeamtet": 5}}})
+	assert.ErrorIs(t, err, ErrInvalidQuestion)
+	_, err = service.AddQuestion(draft.ID, QuestionInput{Key: "income", Label: "Netto", Type: models.QuestionTypeNumber,
+		Points: &models.AnswerPoints{Options: map[string]int{"true": 5}}})
+	assert.ErrorIs(t, err, ErrInvalidQuestion)
+
+	upper := 3000.0
+	questions := []QuestionInput{
+		{Key: "employment", Label: "Beschäftigung", Type: models.QuestionTypeChoice, Required: true, Position: 1,
+			Options: []string{"angestellt", "selbststaendig"}, Points: &models.AnswerPoints{Options: map[string]int{"selbststaendig": 40}}},
+		{Key: "income", Label: "Netto vor der Geburt", Type: models.QuestionTypeNumber, Required: true, Position: 2,
+			Points: &models.AnswerPoints{Ranges: []models.PointsRange{{From: 0, To: &upper, Points: 5}, {From: upper, Points: 30}}}},
+		{Key: "abroad", Label: "Einkommen im Ausland?", Type: models.QuestionTypeYesNo, Position: 3,
+			Points: &models.AnswerPoints{Options: map[string]int{"true": 20}}},
+	}
+	for _, input := range questions {
+		_, err := service.AddQuestion(draft.ID, input)
+		require.NoError(t, err, input.Key)
+	}
+	questionnaire, err := service.Publish(draft.ID)
+	require.NoError(t, err)
+
+	// A simple case stays low and gets the basic package, of which there is none
+	lead := createLead(t, db, customer.ID)
+	response, err := service.Submit(lead.ID, viewer(customer), SubmitInput{QuestionnaireID: questionnaire.ID, Answers: map[string]json.RawMessage{
+		"employment": json.RawMessage(`"angestellt"`), "income": json.RawMessage(`1800`), "abroad": json.RawMessage(`false`),
+	}})
+	require.NoError(t, err)
+	assert.Nil(t, response.Qualification, "customers do not see the qualification")
+
+	berater := testutils.CreateTestUser(t, db, models.RoleBerater)
+	simple, err := service.Response(lead.ID, viewer(berater))
+	require.NoError(t, err)
+	require.NotNil(t, simple.Qualification)
+	assert.Equal(t, 5, simple.Qualification.Score)
+	assert.Equal(t, models.PriorityLow, simple.Qualification.Priority)
+	assert.Nil(t, simple.Qualification.RecommendedPackage)
+
+	var stored models.Lead
+	require.NoError(t, db.First(&stored, "id = ?", lead.ID).Error)
+	assert.Equal(t, models.PriorityLow, stored.Priority)
+
+	// An anonymous pre-talk of a self-employed applicant with a high income
+	preTalk := createLead(t, db, customer.ID)
+	qualification, err := service.SubmitPreTalk(db, preTalk.ID, nil, SubmitInput{QuestionnaireID: questionnaire.ID, Answers: map[string]json.RawMessage{
+		"employment": json.RawMessage(`"selbststaendig"`), "income": json.RawMessage(`4200`), "abroad": json.RawMessage(`true`),
+	}})
+	require.NoError(t, err)
+	assert.Equal(t, 90, qualification.Score)
+	assert.Equal(t, models.PriorityUrgent, qualification.Priority)
+	assert.Equal(t, []string{"Beschäftigung: selbststaendig (+40)", "Netto vor der Geburt: 4200 (+30)", "Einkommen im Ausland?: Ja (+20)"}, qualification.Reasons)
+	require.NotNil(t, qualification.RecommendedPackage)
+	assert.Equal(t, complete.ID, qualification.RecommendedPackage.ID)
+
+	var qualified models.Lead
+	require.NoError(t, db.First(&qualified, "id = ?", preTalk.ID).Error)
+	assert.Equal(t, models.PriorityUrgent, qualified.Priority)
+	var answered models.QuestionnaireResponse
+	require.NoError(t, db.First(&answered, "lead_id = ?", preTalk.ID).Error)
+	assert.Nil(t, answered.UserID)
+	assert.Equal(t, complete.ID, *answered.RecommendedPackageID)
+
+	assert.Equal(t, models.PackageTypePremium, PackageTypeForScore(50))
+	assert.Equal(t, models.PriorityMedium, PriorityForScore(25))
+}
+
+// publishIntake publishes a questionnaire asking for the incomes of the
+// parents, the partner's only when the applicant is not a single parent
+func publishIntake(t *testing.T, service *Service, admin *models.User, pkg *models.Package) *models.Questionnaire {
+	t.Helper()
+	questionnaire, err := service.Create(admin.ID, CreateInput{PackageID: pkg.ID, Title: "Aufnahme"})
+	require.NoError(t, err)
+
+	questions := []QuestionInput{
+		{Key: "single_parent", Label: "Alleinerziehend?", Type: models.QuestionTypeYesNo, Required: true, Prefill: models.PrefillSingleParent, Position: 1},
+		{Key: "income_before", Label: "Ihr Netto vor der Geburt", Type: models.QuestionTypeNumber, Required: true, Prefill: models.PrefillIncomeBefore, Position: 2},
+		{Key: "partner_income", Label: "Netto des Partners", Type: models.QuestionTypeNumber, Required: true, Prefill: models.PrefillPartnerIncomeBefore, Position: 3,
+			ShowIf: &models.QuestionCondition{QuestionKey: "single_parent", Values: []string{"false"}}},
+		{Key: "employment", Label: "Beschäftigung", Type: models.QuestionTypeChoice, Options: []string{"angestellt", "selbststaendig"}, Position: 4},
+		{Key: "birth_date", Label: "Geburtstermin", Type: models.QuestionTypeDate, Prefill: models.PrefillChildBirthDate, Position: 5},
+	}
+	for _, input := range questions {
+		_, err := service.AddQuestion(questionnaire.ID, input)
+		require.NoError(t, err, input.Key)
+	}
+	questionnaire, err = service.Publish(questionnaire.ID)
+	require.NoError(t, err)
+	return questionnaire
+}
+
+func setupTestService(t *testing.T) (*gorm.DB, *Service) {
+	t.Helper()
+	db := testutils.NewSQLiteDB(t, &models.User{}, &models.Lead{}, &models.FamilyMember{}, &models.Package{},
+		&models.Questionnaire{}, &models.QuestionnaireQuestion{}, &models.QuestionnaireResponse{})
+
+	service := NewService(db, zap.NewNop())
+	service.now = func() time.Time { return now }
+	return db, service
+}
+
+func viewer(user *models.User) scopes.Viewer {
+	return scopes.Viewer{ID: user.ID, Role: user.Role}
+}
+
+func createLead(t *testing.T, db *gorm.DB, userID uuid.UUID) *models.Lead {
+	t.Helper()
+	lead := &models.Lead{
+		UserID:   userID,
+		Title:    "Elterngeld",
+		Status:   models.LeadStatusNew,
+		Priority: models.PriorityMedium,
+		Source:   models.LeadSourceWebsite,
+	}
+	require.NoError(t, db.Create(lead).Error)
+	return lead
+}
+
+func createPackage(t *testing.T, db *gorm.DB) *models.Package {
+	t.Helper()
+	id := uuid.New()
+	pkg := &models.Package{
+		ID:              id,
+		Name:            "Elterngeld Premium",
+		Type:            models.PackageTypePremium,
+		Price:           149,
+		Currency:        "EUR",
+		StripeProductID: "prod_" + id.String(),
+		StripePriceID:   "price_" + id.String(),
+	}
+	require.NoError(t, db.Create(pkg).Error)
+	return pkg
+}
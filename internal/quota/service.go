@@ -0,0 +1,97 @@
+// Package quota limits the storage customers use for their documents, so a
+// single customer cannot fill the upload volume. Staff uploads are not
+// limited.
+package quota
+
+import (
+	"errors"
+	"fmt"
+
+	"elterngeld-portal/config"
+	"elterngeld-portal/internal/models"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrQuotaExceeded is returned when an upload does not fit into the user's remaining storage
+var ErrQuotaExceeded = errors.New("storage quota exceeded")
+
+// Usage is the storage a user's documents take up. Limit is 0 for users
+// without a quota.
+type Usage struct {
+	Used      int64 `json:"used"`
+	Limit     int64 `json:"limit"`
+	Remaining int64 `json:"remaining"`
+}
+
+// Service checks uploads against the storage quota
+type Service struct {
+	db     *gorm.DB
+	logger *zap.Logger
+	limit  int64
+}
+
+func NewService(db *gorm.DB, logger *zap.Logger, cfg *config.Config) *Service {
+	return &Service{
+		db:     db,
+		logger: logger,
+		limit:  cfg.Upload.UserQuota,
+	}
+}
+
+// Usage returns the storage used by the user's documents. Documents in the
+// trash do not count.
+func (s *Service) Usage(userID uuid.UUID) (*Usage, error) {
+	var user models.User
+	if err := s.db.Select("id", "role").First(&user, "id = ?", userID).Error; err != nil {
+		return nil, err
+	}
+	return s.usage(s.db, &user)
+}
+
+// Check verifies that size more bytes fit into the user's quota. Called in
+// the transaction that creates the documents, the user is locked so
+// concurrent uploads cannot exceed the quota together. Callers without a
+// transaction pass nil.
+func (s *Service) Check(tx *gorm.DB, userID uuid.UUID, size int64) error {
+	if tx == nil {
+		tx = s.db
+	}
+
+	var user models.User
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id", "role").
+		First(&user, "id = ?", userID).Error; err != nil {
+		return err
+	}
+
+	usage, err := s.usage(tx, &user)
+	if err != nil {
+		return err
+	}
+	if usage.Limit > 0 && size > usage.Remaining {
+		s.logger.Info("Upload rejected by storage quota",
+			zap.String("user_id", userID.String()),
+			zap.Int64("used", usage.Used),
+			zap.Int64("requested", size))
+		return ErrQuotaExceeded
+	}
+	return nil
+}
+
+func (s *Service) usage(db *gorm.DB, user *models.User) (*Usage, error) {
+	var used int64
+	if err := db.Model(&models.Document{}).Where("user_id = ?", user.ID).
+		Select("COALESCE(SUM(file_size), 0)").Scan(&used).Error; err != nil {
+		return nil, fmt.Errorf("failed to sum document sizes: %w", err)
+	}
+
+	usage := &Usage{Used: used}
+	if user.Role == models.RoleUser && s.limit > 0 {
+		usage.Limit = s.limit
+		usage.Remaining = max(s.limit-used, 0)
+	}
+	return usage, nil
+}
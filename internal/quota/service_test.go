@@ -0,0 +1,84 @@
+package quota
+
+import (
+	"testing"
+
+	"elterngeld-portal/config"
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/tests/testutils"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+func TestCheck_CustomerQuota(t *testing.T) {
+	db, service := setupTestService(t, 1000)
+	customer := testutils.CreateTestUser(t, db, models.RoleUser)
+	createDocument(t, db, customer.ID, 600)
+	trashed := createDocument(t, db, customer.ID, 300)
+	require.NoError(t, db.Delete(trashed).Error)
+
+	usage, err := service.Usage(customer.ID)
+	require.NoError(t, err)
+	assert.Equal(t, Usage{Used: 600, Limit: 1000, Remaining: 400}, *usage)
+
+	assert.NoError(t, service.Check(nil, customer.ID, 400))
+	assert.ErrorIs(t, service.Check(nil, customer.ID, 401), ErrQuotaExceeded)
+
+	// Uploads in the same transaction count once they are created
+	err = db.Transaction(func(tx *gorm.DB) error {
+		require.NoError(t, service.Check(tx, customer.ID, 300))
+		require.NoError(t, tx.Create(document(customer.ID, 300)).Error)
+		return service.Check(tx, customer.ID, 300)
+	})
+	assert.ErrorIs(t, err, ErrQuotaExceeded)
+}
+
+func TestCheck_StaffAndDisabledQuota(t *testing.T) {
+	db, service := setupTestService(t, 1000)
+	berater := testutils.CreateTestUser(t, db, models.RoleBerater)
+	createDocument(t, db, berater.ID, 5000)
+
+	usage, err := service.Usage(berater.ID)
+	require.NoError(t, err)
+	assert.Equal(t, Usage{Used: 5000}, *usage)
+	assert.NoError(t, service.Check(nil, berater.ID, 5000))
+
+	_, unlimited := setupTestService(t, 0)
+	customer := testutils.CreateTestUser(t, unlimited.db, models.RoleUser)
+	assert.NoError(t, unlimited.Check(nil, customer.ID, 1<<40))
+
+	_, err = service.Usage(uuid.New())
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
+
+func setupTestService(t *testing.T, limit int64) (*gorm.DB, *Service) {
+	t.Helper()
+	db := testutils.NewSQLiteDB(t, &models.User{}, &models.Document{})
+
+	cfg := &config.Config{Upload: config.UploadConfig{UserQuota: limit}}
+	return db, NewService(db, zap.NewNop(), cfg)
+}
+
+func document(userID uuid.UUID, size int64) *models.Document {
+	return &models.Document{
+		LeadID:       uuid.New(),
+		UserID:       userID,
+		FileName:     "antrag.pdf",
+		OriginalName: "antrag.pdf",
+		FilePath:     "/tmp/antrag.pdf",
+		FileSize:     size,
+		ContentType:  "application/pdf",
+		DocumentType: models.DocumentTypeOther,
+	}
+}
+
+func createDocument(t *testing.T, db *gorm.DB, userID uuid.UUID, size int64) *models.Document {
+	t.Helper()
+	doc := document(userID, size)
+	require.NoError(t, db.Create(doc).Error)
+	return doc
+}
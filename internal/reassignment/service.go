@@ -0,0 +1,422 @@
+// Package reassignment moves upcoming bookings from one Berater to another,
+// e.g. during a vacation or sickness, together with their timeslots.
+package reassignment
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"elterngeld-portal/internal/availability"
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/pkg/timeutil"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrBeraterNotFound is returned when no Berater or Junior Berater has the given ID
+	ErrBeraterNotFound = errors.New("berater not found")
+	// ErrSameBerater is returned when bookings are reassigned to the Berater they belong to
+	ErrSameBerater = errors.New("bookings cannot be reassigned to the same berater")
+	// ErrBeraterNotBookable is returned when the new Berater is deactivated or not approved
+	ErrBeraterNotBookable = errors.New("berater cannot take bookings")
+	// ErrInvalidPeriod is returned when neither bookings nor a valid period are given
+	ErrInvalidPeriod = errors.New("invalid period")
+	// ErrBookingNotFound is returned when a selected booking is not an upcoming booking of the Berater
+	ErrBookingNotFound = errors.New("booking not found")
+)
+
+// upcomingBookingStatuses are the booking statuses of appointments that still take place
+var upcomingBookingStatuses = []models.BookingStatus{
+	models.BookingStatusPending,
+	models.BookingStatusConfirmed,
+}
+
+// Reasons for bookings that could not be reassigned
+const (
+	SkipTimeConflict = "time_conflict" // overlaps or is too close to an appointment of the new Berater
+	SkipDailyLimit   = "daily_limit"   // the new Berater has no more appointments available that day
+)
+
+// Input selects the bookings to reassign: the given bookings, or all upcoming
+// bookings of the Berater in the period [From, To] when none are given
+type Input struct {
+	FromBeraterID uuid.UUID
+	ToBeraterID   uuid.UUID
+	BookingIDs    []uuid.UUID
+	From          string // YYYY-MM-DD
+	To            string // YYYY-MM-DD, inclusive
+	Location      *time.Location
+	Reason        string // internal note, not shown to customers
+}
+
+// Skipped is a booking that stays with its Berater
+type Skipped struct {
+	Booking models.BookingResponse `json:"booking"`
+	Reason  string                 `json:"reason"`
+}
+
+// Result describes a completed reassignment
+type Result struct {
+	FromBerater    models.UserResponse      `json:"from_berater"`
+	ToBerater      models.UserResponse      `json:"to_berater"`
+	Moved          []models.BookingResponse `json:"moved"`
+	Skipped        []Skipped                `json:"skipped"`
+	MovedTimeslots int                      `json:"moved_timeslots"`
+}
+
+// Service reassigns bookings between Beraters
+type Service struct {
+	db           *gorm.DB
+	logger       *zap.Logger
+	availability *availability.Service
+	now          func() time.Time
+}
+
+func NewService(db *gorm.DB, logger *zap.Logger, availabilityService *availability.Service) *Service {
+	return &Service{
+		db:           db,
+		logger:       logger,
+		availability: availabilityService,
+		now:          time.Now,
+	}
+}
+
+// Reassign moves the selected bookings to another Berater. Bookings of a
+// timeslot move together with the timeslot, so group appointments are never
+// split. Bookings that would break the new Berater's booking limits are
+// skipped. Online bookings get the new Berater's meeting room, customers are
+// notified and every move is recorded as an activity. Leads stay with their
+// Berater.
+func (s *Service) Reassign(input Input, adminID uuid.UUID) (*Result, error) {
+	if input.FromBeraterID == input.ToBeraterID {
+		return nil, ErrSameBerater
+	}
+	from, err := findBerater(s.db, input.FromBeraterID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &Result{
+		FromBerater: from.ToResponse(),
+		Moved:       []models.BookingResponse{},
+		Skipped:     []Skipped{},
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		// The new Berater is locked so concurrent bookings cannot both pass the booking limits
+		to, err := findBerater(tx.Clauses(clause.Locking{Strength: "UPDATE"}), input.ToBeraterID)
+		if err != nil {
+			return err
+		}
+		if !to.IsBookable() {
+			return ErrBeraterNotBookable
+		}
+		result.ToBerater = to.ToResponse()
+
+		bookings, err := s.selectBookings(tx, input, from)
+		if err != nil {
+			return err
+		}
+		if len(bookings) == 0 {
+			return nil
+		}
+
+		schedule, err := s.availability.LoadSchedule(tx, []uuid.UUID{to.ID},
+			bookings[0].StartTime, bookings[len(bookings)-1].EndTime, uuid.Nil)
+		if err != nil {
+			return err
+		}
+
+		for _, group := range groupByTimeslot(bookings) {
+			if err := check(schedule, to, group); err != nil {
+				reason := SkipTimeConflict
+				if errors.Is(err, availability.ErrDailyLimitReached) {
+					reason = SkipDailyLimit
+				}
+				for i := range group {
+					result.Skipped = append(result.Skipped, Skipped{Booking: group[i].ToResponse(), Reason: reason})
+				}
+				continue
+			}
+
+			if err := s.move(tx, group, from, to, adminID, input.Reason); err != nil {
+				return err
+			}
+			for i := range group {
+				schedule.Add(to.ID, group[i])
+				result.Moved = append(result.Moved, group[i].ToResponse())
+			}
+			if group[0].TimeslotID != nil {
+				result.MovedTimeslots++
+			}
+		}
+
+		if len(result.Moved) > 0 {
+			return notifyBerater(tx, to, from, len(result.Moved))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Bookings reassigned",
+		zap.String("from_berater_id", from.ID.String()),
+		zap.String("to_berater_id", input.ToBeraterID.String()),
+		zap.String("admin_id", adminID.String()),
+		zap.Int("moved", len(result.Moved)),
+		zap.Int("skipped", len(result.Skipped)))
+
+	return result, nil
+}
+
+// selectBookings loads the selected upcoming bookings of the Berater, together
+// with the other bookings of their timeslots, ordered by start time
+func (s *Service) selectBookings(tx *gorm.DB, input Input, from *models.User) ([]models.Booking, error) {
+	query := tx.Preload("User").
+		Where("berater_id = ? AND status IN ? AND start_time >= ?", from.ID, upcomingBookingStatuses, s.now())
+
+	if len(input.BookingIDs) > 0 {
+		query = query.Where("id IN ?", input.BookingIDs)
+	} else {
+		loc := input.Location
+		if loc == nil {
+			loc = time.UTC
+		}
+		start, err := timeutil.ParseDate(input.From, loc)
+		if err != nil {
+			return nil, ErrInvalidPeriod
+		}
+		last, err := timeutil.ParseDate(input.To, loc)
+		if err != nil || last.Before(start) {
+			return nil, ErrInvalidPeriod
+		}
+		query = query.Where("start_time >= ? AND start_time < ?", start, timeutil.AddDays(last, 1, loc))
+	}
+
+	var bookings []models.Booking
+	if err := query.Find(&bookings).Error; err != nil {
+		return nil, fmt.Errorf("failed to load bookings: %w", err)
+	}
+
+	selected := make(map[uuid.UUID]bool, len(bookings))
+	var slotIDs []uuid.UUID
+	for _, booking := range bookings {
+		selected[booking.ID] = true
+		if booking.TimeslotID != nil {
+			slotIDs = append(slotIDs, *booking.TimeslotID)
+		}
+	}
+	for _, id := range input.BookingIDs {
+		if !selected[id] {
+			return nil, ErrBookingNotFound
+		}
+	}
+
+	if len(slotIDs) > 0 {
+		var others []models.Booking
+		if err := tx.Preload("User").
+			Where("timeslot_id IN ? AND berater_id = ? AND status IN ?", slotIDs, from.ID, upcomingBookingStatuses).
+			Find(&others).Error; err != nil {
+			return nil, fmt.Errorf("failed to load timeslot bookings: %w", err)
+		}
+		for _, booking := range others {
+			if !selected[booking.ID] {
+				selected[booking.ID] = true
+				bookings = append(bookings, booking)
+			}
+		}
+	}
+
+	sort.SliceStable(bookings, func(i, j int) bool {
+		return bookings[i].StartTime.Before(bookings[j].StartTime)
+	})
+	return bookings, nil
+}
+
+// move assigns a group of bookings and their timeslot to the new Berater
+func (s *Service) move(tx *gorm.DB, group []models.Booking, from, to *models.User, adminID uuid.UUID, reason string) error {
+	now := s.now()
+	ids := make([]uuid.UUID, 0, len(group))
+	for _, booking := range group {
+		ids = append(ids, booking.ID)
+	}
+
+	if err := tx.Model(&models.Booking{}).Where("id IN ?", ids).
+		Updates(map[string]interface{}{"berater_id": to.ID, "updated_at": now}).Error; err != nil {
+		return fmt.Errorf("failed to reassign bookings: %w", err)
+	}
+	// The meeting room of the previous Berater cannot be used any more
+	if err := tx.Model(&models.Booking{}).Where("id IN ? AND is_online = ?", ids, true).
+		Updates(map[string]interface{}{"meeting_link": to.MeetingURL, "meeting_password": ""}).Error; err != nil {
+		return fmt.Errorf("failed to update meeting links: %w", err)
+	}
+	if group[0].TimeslotID != nil {
+		if err := tx.Model(&models.Timeslot{}).Where("id = ?", *group[0].TimeslotID).
+			Updates(map[string]interface{}{"berater_id": to.ID, "updated_at": now}).Error; err != nil {
+			return fmt.Errorf("failed to reassign timeslot: %w", err)
+		}
+	}
+
+	for i := range group {
+		booking := &group[i]
+		booking.BeraterID = &to.ID
+		booking.UpdatedAt = now
+		if booking.IsOnline {
+			booking.MeetingLink = to.MeetingURL
+			booking.MeetingPassword = ""
+		}
+
+		// The new Berater may not speak the customer's language
+		var customer models.User
+		if err := tx.Select("id", "language", "spoken_languages").First(&customer, "id = ?", booking.UserID).Error; err != nil {
+			return fmt.Errorf("failed to load customer: %w", err)
+		}
+		booking.MatchLanguages(&customer, to)
+		if err := tx.Model(booking).Updates(map[string]interface{}{
+			"consultation_language": booking.ConsultationLanguage,
+			"interpreter_required":  booking.InterpreterRequired,
+			"interpreter_language":  booking.InterpreterLanguage,
+		}).Error; err != nil {
+			return fmt.Errorf("failed to update booking language: %w", err)
+		}
+
+		metadata, _ := json.Marshal(map[string]interface{}{
+			"booking_id":      booking.ID,
+			"from_berater_id": from.ID,
+			"to_berater_id":   to.ID,
+			"reason":          reason,
+		})
+		activity := models.Activity{
+			UserID:      &adminID,
+			LeadID:      booking.LeadID,
+			Type:        models.ActivityTypeBookingReassigned,
+			Title:       fmt.Sprintf("Termin '%s' von %s an %s übertragen", booking.Title, from.FullName(), to.FullName()),
+			Description: reason,
+			Metadata:    metadata,
+		}
+		if err := tx.Create(&activity).Error; err != nil {
+			return fmt.Errorf("failed to record activity: %w", err)
+		}
+
+		if err := notifyCustomer(tx, booking, from, to); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// notifyCustomer tells the customer who conducts their appointment now. The
+// reason for the change is internal and not shared.
+func notifyCustomer(tx *gorm.DB, booking *models.Booking, from, to *models.User) error {
+	customer := &booking.User
+	title := "Ihr Termin findet mit einem anderen Berater statt"
+	message := fmt.Sprintf("Ihr Termin '%s' am %s findet mit %s statt, da %s verhindert ist. Datum und Uhrzeit bleiben unverändert.",
+		booking.Title, booking.StartTime.In(customer.TimeLocation()).Format("02.01.2006 15:04"), to.FullName(), from.FullName())
+	if booking.IsOnline {
+		if booking.MeetingLink != "" {
+			message += " Den neuen Link zum Online-Termin finden Sie in Ihrer Buchung."
+		} else {
+			message += " Den neuen Link zum Online-Termin erhalten Sie in Kürze."
+		}
+	}
+	data, _ := json.Marshal(map[string]interface{}{
+		"booking_id": booking.ID,
+		"berater_id": to.ID,
+	})
+
+	notifications := []models.Notification{
+		{
+			UserID:    customer.ID,
+			Type:      models.NotificationTypeInApp,
+			Title:     title,
+			Message:   message,
+			Data:      string(data),
+			Recipient: customer.NotificationAddress(),
+		},
+		{
+			UserID:    customer.ID,
+			Type:      models.NotificationTypeEmail,
+			Status:    models.NotificationStatusPending,
+			Title:     title,
+			Message:   message,
+			Data:      string(data),
+			Template:  string(models.EmailTemplateBookingReassigned),
+			Recipient: customer.NotificationAddress(),
+		},
+	}
+	if err := tx.Create(&notifications).Error; err != nil {
+		return fmt.Errorf("failed to notify customer: %w", err)
+	}
+	return nil
+}
+
+// notifyBerater tells the new Berater about the appointments they took over
+func notifyBerater(tx *gorm.DB, to, from *models.User, moved int) error {
+	notification := models.Notification{
+		UserID:    to.ID,
+		Type:      models.NotificationTypeInApp,
+		Title:     "Termine übernommen",
+		Message:   fmt.Sprintf("Ihnen wurden %d Termin(e) von %s übertragen.", moved, from.FullName()),
+		Recipient: to.NotificationAddress(),
+	}
+	if err := tx.Create(&notification).Error; err != nil {
+		return fmt.Errorf("failed to notify berater: %w", err)
+	}
+	return nil
+}
+
+// check verifies that the bookings of a group fit the new Berater's booking limits
+func check(schedule *availability.Schedule, berater *models.User, group []models.Booking) error {
+	slot := models.Timeslot{StartTime: group[0].StartTime, EndTime: group[0].EndTime}
+	if group[0].TimeslotID != nil {
+		slot.ID = *group[0].TimeslotID
+	}
+
+	// Every booking of the group takes a place on that day, Check counts one
+	limited := *berater
+	if limited.MaxBookingsPerDay > 0 {
+		limited.MaxBookingsPerDay -= len(group) - 1
+		if limited.MaxBookingsPerDay <= 0 {
+			return availability.ErrDailyLimitReached
+		}
+	}
+	return schedule.Check(&limited, &slot)
+}
+
+// groupByTimeslot groups bookings of the same timeslot; bookings without a
+// timeslot form a group of their own. The order of the bookings is kept.
+func groupByTimeslot(bookings []models.Booking) [][]models.Booking {
+	var groups [][]models.Booking
+	index := make(map[uuid.UUID]int)
+	for _, booking := range bookings {
+		if booking.TimeslotID != nil {
+			if i, ok := index[*booking.TimeslotID]; ok {
+				groups[i] = append(groups[i], booking)
+				continue
+			}
+			index[*booking.TimeslotID] = len(groups)
+		}
+		groups = append(groups, []models.Booking{booking})
+	}
+	return groups
+}
+
+func findBerater(db *gorm.DB, beraterID uuid.UUID) (*models.User, error) {
+	var berater models.User
+	if err := db.Where("id = ? AND role IN ?", beraterID,
+		[]models.UserRole{models.RoleBerater, models.RoleJuniorBerater}).First(&berater).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrBeraterNotFound
+		}
+		return nil, err
+	}
+	return &berater, nil
+}
@@ -0,0 +1,230 @@
+package reassignment
+
+import (
+	"testing"
+	"time"
+
+	"elterngeld-portal/internal/availability"
+	"elterngeld-portal/internal/holidays"
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/tests/testutils"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+func TestReassign(t *testing.T) {
+	db, service := setupTestService(t)
+	now := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+	service.now = func() time.Time { return now }
+
+	admin := createUser(t, db, "admin@example.com", models.RoleAdmin)
+	berater := createUser(t, db, "berater@example.com", models.RoleBerater)
+	colleague := createUser(t, db, "kollegin@example.com", models.RoleBerater)
+	require.NoError(t, db.Model(colleague).Updates(map[string]interface{}{
+		"meeting_url":          "https://meet.example.com/kollegin",
+		"max_bookings_per_day": 3,
+		"working_languages":    `["de"]`,
+	}).Error)
+	customer := createUser(t, db, "kunde@example.com", models.RoleUser)
+	otherCustomer := createUser(t, db, "kundin@example.com", models.RoleUser)
+	require.NoError(t, db.Model(otherCustomer).Update("spoken_languages", `["ar"]`).Error)
+
+	tomorrow := now.Add(22 * time.Hour) // 10:00
+	group := createTimeslot(t, db, berater.ID, tomorrow)
+	first := createBooking(t, db, customer.ID, berater.ID, &group.ID, tomorrow, models.BookingStatusConfirmed)
+	second := createBooking(t, db, otherCustomer.ID, berater.ID, &group.ID, tomorrow, models.BookingStatusPending)
+	require.NoError(t, db.Model(first).Update("meeting_link", "https://meet.example.com/berater").Error)
+
+	// The colleague is busy in the afternoon
+	afternoon := createTimeslot(t, db, berater.ID, tomorrow.Add(4*time.Hour))
+	conflicting := createBooking(t, db, customer.ID, berater.ID, &afternoon.ID, afternoon.StartTime, models.BookingStatusConfirmed)
+	createBooking(t, db, otherCustomer.ID, colleague.ID, nil, afternoon.StartTime.Add(30*time.Minute), models.BookingStatusConfirmed)
+
+	withoutSlot := createBooking(t, db, customer.ID, berater.ID, nil, tomorrow.Add(24*time.Hour), models.BookingStatusConfirmed)
+	createBooking(t, db, customer.ID, berater.ID, nil, tomorrow.Add(26*time.Hour), models.BookingStatusCancelled)
+	createBooking(t, db, customer.ID, berater.ID, nil, tomorrow.Add(10*24*time.Hour), models.BookingStatusConfirmed)
+
+	result, err := service.Reassign(Input{
+		FromBeraterID: berater.ID,
+		ToBeraterID:   colleague.ID,
+		From:          "2025-03-11",
+		To:            "2025-03-12",
+		Location:      time.UTC,
+		Reason:        "Urlaub",
+	}, admin.ID)
+	require.NoError(t, err)
+
+	moved := make([]uuid.UUID, 0, len(result.Moved))
+	for _, booking := range result.Moved {
+		moved = append(moved, booking.ID)
+	}
+	assert.ElementsMatch(t, []uuid.UUID{first.ID, second.ID, withoutSlot.ID}, moved)
+	require.Len(t, result.Skipped, 1)
+	assert.Equal(t, conflicting.ID, result.Skipped[0].Booking.ID)
+	assert.Equal(t, SkipTimeConflict, result.Skipped[0].Reason)
+	assert.Equal(t, 1, result.MovedTimeslots)
+
+	var stored models.Booking
+	require.NoError(t, db.First(&stored, "id = ?", first.ID).Error)
+	assert.Equal(t, colleague.ID, *stored.BeraterID)
+	assert.Equal(t, "https://meet.example.com/kollegin", stored.MeetingLink)
+	assert.False(t, stored.InterpreterRequired)
+	// The colleague does not speak the other customer's language
+	var interpreted models.Booking
+	require.NoError(t, db.First(&interpreted, "id = ?", second.ID).Error)
+	assert.True(t, interpreted.InterpreterRequired)
+	assert.Equal(t, "ar", interpreted.InterpreterLanguage)
+	assert.Equal(t, "de", interpreted.ConsultationLanguage)
+	var skipped models.Booking
+	require.NoError(t, db.First(&skipped, "id = ?", conflicting.ID).Error)
+	assert.Equal(t, berater.ID, *skipped.BeraterID)
+
+	var movedSlot, keptSlot models.Timeslot
+	require.NoError(t, db.First(&movedSlot, "id = ?", group.ID).Error)
+	assert.Equal(t, colleague.ID, movedSlot.BeraterID)
+	require.NoError(t, db.First(&keptSlot, "id = ?", afternoon.ID).Error)
+	assert.Equal(t, berater.ID, keptSlot.BeraterID)
+
+	var activities int64
+	require.NoError(t, db.Model(&models.Activity{}).Where("type = ?", models.ActivityTypeBookingReassigned).Count(&activities).Error)
+	assert.Equal(t, int64(3), activities)
+
+	var emails []models.Notification
+	require.NoError(t, db.Where("type = ? AND template = ?", models.NotificationTypeEmail, models.EmailTemplateBookingReassigned).
+		Find(&emails).Error)
+	require.Len(t, emails, 3)
+	assert.Contains(t, emails[0].Message, colleague.FullName())
+	assert.NotContains(t, emails[0].Message, "Urlaub")
+
+	var beraterNotifications int64
+	require.NoError(t, db.Model(&models.Notification{}).Where("user_id = ?", colleague.ID).Count(&beraterNotifications).Error)
+	assert.Equal(t, int64(1), beraterNotifications)
+}
+
+func TestReassign_GroupsAndLimits(t *testing.T) {
+	db, service := setupTestService(t)
+	now := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+	service.now = func() time.Time { return now }
+
+	admin := createUser(t, db, "admin@example.com", models.RoleAdmin)
+	berater := createUser(t, db, "berater@example.com", models.RoleBerater)
+	colleague := createUser(t, db, "kollegin@example.com", models.RoleBerater)
+	require.NoError(t, db.Model(colleague).Update("max_bookings_per_day", 1).Error)
+	customer := createUser(t, db, "kunde@example.com", models.RoleUser)
+
+	start := now.Add(24 * time.Hour)
+	group := createTimeslot(t, db, berater.ID, start)
+	selected := createBooking(t, db, customer.ID, berater.ID, &group.ID, start, models.BookingStatusConfirmed)
+	createBooking(t, db, customer.ID, berater.ID, &group.ID, start, models.BookingStatusConfirmed)
+
+	// Selecting one booking of a group selects the whole group, which does not fit the daily limit
+	result, err := service.Reassign(Input{FromBeraterID: berater.ID, ToBeraterID: colleague.ID, BookingIDs: []uuid.UUID{selected.ID}}, admin.ID)
+	require.NoError(t, err)
+	assert.Empty(t, result.Moved)
+	require.Len(t, result.Skipped, 2)
+	assert.Equal(t, SkipDailyLimit, result.Skipped[0].Reason)
+
+	require.NoError(t, db.Model(colleague).Update("max_bookings_per_day", 2).Error)
+	result, err = service.Reassign(Input{FromBeraterID: berater.ID, ToBeraterID: colleague.ID, BookingIDs: []uuid.UUID{selected.ID}}, admin.ID)
+	require.NoError(t, err)
+	assert.Len(t, result.Moved, 2)
+	assert.Empty(t, result.Skipped)
+}
+
+func TestReassign_Errors(t *testing.T) {
+	db, service := setupTestService(t)
+	now := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+	service.now = func() time.Time { return now }
+
+	admin := createUser(t, db, "admin@example.com", models.RoleAdmin)
+	berater := createUser(t, db, "berater@example.com", models.RoleBerater)
+	colleague := createUser(t, db, "kollegin@example.com", models.RoleBerater)
+	deactivated := createUser(t, db, "ehemalig@example.com", models.RoleBerater)
+	require.NoError(t, db.Model(deactivated).Update("is_active", false).Error)
+	customer := createUser(t, db, "kunde@example.com", models.RoleUser)
+
+	past := createBooking(t, db, customer.ID, berater.ID, nil, now.Add(-time.Hour), models.BookingStatusConfirmed)
+	ofColleague := createBooking(t, db, customer.ID, colleague.ID, nil, now.Add(time.Hour), models.BookingStatusConfirmed)
+
+	tests := []struct {
+		name  string
+		input Input
+		err   error
+	}{
+		{"same berater", Input{FromBeraterID: berater.ID, ToBeraterID: berater.ID}, ErrSameBerater},
+		{"unknown berater", Input{FromBeraterID: berater.ID, ToBeraterID: uuid.New()}, ErrBeraterNotFound},
+		{"customer as berater", Input{FromBeraterID: customer.ID, ToBeraterID: colleague.ID}, ErrBeraterNotFound},
+		{"deactivated berater", Input{FromBeraterID: berater.ID, ToBeraterID: deactivated.ID}, ErrBeraterNotBookable},
+		{"missing period", Input{FromBeraterID: berater.ID, ToBeraterID: colleague.ID}, ErrInvalidPeriod},
+		{"reversed period", Input{FromBeraterID: berater.ID, ToBeraterID: colleague.ID, From: "2025-03-12", To: "2025-03-11"}, ErrInvalidPeriod},
+		{"past booking", Input{FromBeraterID: berater.ID, ToBeraterID: colleague.ID, BookingIDs: []uuid.UUID{past.ID}}, ErrBookingNotFound},
+		{"booking of another berater", Input{FromBeraterID: berater.ID, ToBeraterID: colleague.ID, BookingIDs: []uuid.UUID{ofColleague.ID}}, ErrBookingNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := service.Reassign(tt.input, admin.ID)
+			assert.ErrorIs(t, err, tt.err)
+		})
+	}
+}
+
+func setupTestService(t *testing.T) (*gorm.DB, *Service) {
+	t.Helper()
+	db := testutils.NewSQLiteDB(t,
+		&models.User{},
+		&models.Lead{},
+		&models.Timeslot{},
+		&models.Booking{},
+		&models.Activity{},
+		&models.Notification{},
+		&models.HolidayOverride{},
+	)
+
+	holidayService := holidays.NewService(db, zap.NewNop())
+	availabilityService := availability.NewService(db, zap.NewNop(), holidayService)
+	return db, NewService(db, zap.NewNop(), availabilityService)
+}
+
+func createUser(t *testing.T, db *gorm.DB, email string, role models.UserRole) *models.User {
+	t.Helper()
+	user := testutils.CreateTestUser(t, db, role)
+	require.NoError(t, db.Model(user).Update("email", email).Error)
+	user.Email = email
+	return user
+}
+
+func createTimeslot(t *testing.T, db *gorm.DB, beraterID uuid.UUID, start time.Time) *models.Timeslot {
+	t.Helper()
+	slot := &models.Timeslot{
+		BeraterID:   beraterID,
+		Date:        start,
+		StartTime:   start,
+		EndTime:     start.Add(time.Hour),
+		Duration:    60,
+		IsAvailable: true,
+		MaxBookings: 4,
+	}
+	require.NoError(t, db.Create(slot).Error)
+	return slot
+}
+
+func createBooking(t *testing.T, db *gorm.DB, userID, beraterID uuid.UUID, timeslotID *uuid.UUID, start time.Time, status models.BookingStatus) *models.Booking {
+	t.Helper()
+	booking := &models.Booking{
+		UserID:      userID,
+		BeraterID:   &beraterID,
+		TimeslotID:  timeslotID,
+		Title:       "Beratung",
+		Status:      status,
+		ScheduledAt: start,
+		StartTime:   start,
+		EndTime:     start.Add(time.Hour),
+		BookedAt:    start.Add(-72 * time.Hour),
+	}
+	require.NoError(t, db.Create(booking).Error)
+	return booking
+}
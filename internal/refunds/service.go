@@ -0,0 +1,469 @@
+// Package refunds runs refunds of payments through a request and approval
+// workflow. Every refund is requested with a justification; refunds above
+// the approval threshold are only executed after a second admin approved
+// them (four-eyes principle). Refunds go back to the card via Stripe or are
+// granted as account credit, and every step is recorded in the activity
+// history of the lead.
+package refunds
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"elterngeld-portal/config"
+	"elterngeld-portal/internal/activitylog"
+	"elterngeld-portal/internal/credit"
+	"elterngeld-portal/internal/models"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrPaymentNotFound is returned when the refunded payment does not exist
+	ErrPaymentNotFound = errors.New("payment not found")
+	// ErrNotFound is returned for unknown refund requests
+	ErrNotFound = errors.New("refund request not found")
+	// ErrNotRefundable is returned when the payment is not paid or nothing is left to refund
+	ErrNotRefundable = errors.New("payment cannot be refunded")
+	// ErrNoPaymentIntent is returned for card refunds of payments not paid via Stripe
+	ErrNoPaymentIntent = errors.New("payment has no Stripe payment intent")
+	// ErrInvalidAmount is returned for refund amounts that are not positive
+	ErrInvalidAmount = errors.New("invalid refund amount")
+	// ErrRefundTooHigh is returned when a refund exceeds the amount left to refund
+	ErrRefundTooHigh = errors.New("refund exceeds the refundable amount")
+	// ErrReasonRequired is returned for refunds and rejections without a justification
+	ErrReasonRequired = errors.New("a justification is required")
+	// ErrNotPending is returned when a refund request was already decided
+	ErrNotPending = errors.New("refund request was already decided")
+	// ErrSameApprover is returned when the requester tries to approve their own refund
+	ErrSameApprover = errors.New("refund must be approved by a second person")
+	// ErrRefundFailed is returned when Stripe or the credit ledger refused the refund
+	ErrRefundFailed = errors.New("refund failed")
+)
+
+// RequestInput describes a refund. Without an amount everything left to
+// refund is refunded.
+type RequestInput struct {
+	PaymentID uuid.UUID
+	Amount    *float64
+	ToCredit  bool
+	Reason    string
+}
+
+// Filter narrows down the listed refund requests
+type Filter struct {
+	Status    models.RefundRequestStatus
+	PaymentID *uuid.UUID
+}
+
+type Service struct {
+	db        *gorm.DB
+	logger    *zap.Logger
+	credit    *credit.Service
+	activity  *activitylog.Service
+	refunder  Refunder
+	threshold float64
+	now       func() time.Time
+}
+
+func NewService(db *gorm.DB, logger *zap.Logger, cfg *config.Config, creditService *credit.Service, activityLog *activitylog.Service, refunder Refunder) *Service {
+	return &Service{
+		db:        db,
+		logger:    logger,
+		credit:    creditService,
+		activity:  activityLog,
+		refunder:  refunder,
+		threshold: cfg.Refund.ApprovalThreshold,
+		now:       time.Now,
+	}
+}
+
+// Request requests a refund of a payment. Refunds up to the approval
+// threshold are executed right away; larger ones wait for a second admin,
+// who is notified.
+func (s *Service) Request(input RequestInput, actor *models.User) (*models.RefundRequest, error) {
+	reason := strings.TrimSpace(input.Reason)
+	if reason == "" {
+		return nil, ErrReasonRequired
+	}
+
+	var request models.RefundRequest
+	var payment models.Payment
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&payment, "id = ?", input.PaymentID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrPaymentNotFound
+			}
+			return err
+		}
+
+		// Requests not decided yet are reserved, so they cannot add up to
+		// more than the payment
+		var reserved float64
+		if err := tx.Model(&models.RefundRequest{}).
+			Where("payment_id = ? AND status IN ?", payment.ID,
+				[]models.RefundRequestStatus{models.RefundRequestStatusPending, models.RefundRequestStatusApproved}).
+			Select("COALESCE(SUM(amount), 0)").Scan(&reserved).Error; err != nil {
+			return err
+		}
+		refundable := round(payment.Amount + payment.CreditAmount - payment.RefundAmount - reserved)
+		if !input.ToCredit {
+			if payment.StripePaymentIntent == "" {
+				return ErrNoPaymentIntent
+			}
+			// Only the part charged to the card can go back to it
+			refundable = math.Min(refundable, round(payment.Amount-payment.RefundAmount-reserved))
+		}
+		if !payment.IsPaid() || refundable <= 0 {
+			return ErrNotRefundable
+		}
+
+		amount := refundable
+		if input.Amount != nil {
+			amount = round(*input.Amount)
+			if amount <= 0 {
+				return ErrInvalidAmount
+			}
+			if amount > refundable {
+				return ErrRefundTooHigh
+			}
+		}
+
+		request = models.RefundRequest{
+			PaymentID:        payment.ID,
+			RequestedBy:      actor.ID,
+			Status:           models.RefundRequestStatusPending,
+			Amount:           amount,
+			Currency:         payment.Currency,
+			ToCredit:         input.ToCredit,
+			Reason:           reason,
+			RequiresApproval: amount > s.threshold,
+		}
+		if err := tx.Create(&request).Error; err != nil {
+			return err
+		}
+		if err := s.record(tx, &payment, activitylog.RefundRequested{
+			ActorID:          actor.ID,
+			RequestID:        request.ID,
+			Amount:           amount,
+			ToCredit:         input.ToCredit,
+			Reason:           reason,
+			RequiresApproval: request.RequiresApproval,
+		}); err != nil {
+			return err
+		}
+		if request.RequiresApproval {
+			return s.notifyApprovers(tx, &request, actor)
+		}
+		return nil
+	})
+	if err != nil {
+		if isServiceError(err) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to request refund: %w", err)
+	}
+
+	s.logger.Info("Refund requested",
+		zap.String("refund_request_id", request.ID.String()),
+		zap.String("payment_id", payment.ID.String()),
+		zap.Float64("amount", request.Amount),
+		zap.Bool("requires_approval", request.RequiresApproval))
+
+	if request.RequiresApproval {
+		return &request, nil
+	}
+	request.Status = models.RefundRequestStatusApproved
+	if err := s.db.Model(&request).Update("status", request.Status).Error; err != nil {
+		return nil, fmt.Errorf("failed to request refund: %w", err)
+	}
+	return s.execute(&request, actor)
+}
+
+// Approve approves a refund request of someone else and executes it
+func (s *Service) Approve(id uuid.UUID, approver *models.User, note string) (*models.RefundRequest, error) {
+	request, err := s.decide(id, approver, strings.TrimSpace(note), true)
+	if err != nil {
+		return nil, err
+	}
+	return s.execute(request, approver)
+}
+
+// Reject rejects a refund request; the requester is told why
+func (s *Service) Reject(id uuid.UUID, approver *models.User, note string) (*models.RefundRequest, error) {
+	note = strings.TrimSpace(note)
+	if note == "" {
+		return nil, ErrReasonRequired
+	}
+	return s.decide(id, approver, note, false)
+}
+
+// List returns refund requests, newest first
+func (s *Service) List(filter Filter) ([]models.RefundRequest, error) {
+	query := s.db.Preload("Payment").Order("created_at DESC")
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.PaymentID != nil {
+		query = query.Where("payment_id = ?", *filter.PaymentID)
+	}
+
+	var requests []models.RefundRequest
+	if err := query.Find(&requests).Error; err != nil {
+		return nil, err
+	}
+	return requests, nil
+}
+
+// decide records the decision on a pending request. Approved requests move
+// on to be executed, so no second approver can execute them again.
+func (s *Service) decide(id uuid.UUID, approver *models.User, note string, approved bool) (*models.RefundRequest, error) {
+	var request models.RefundRequest
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("Payment").First(&request, "id = ?", id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrNotFound
+			}
+			return err
+		}
+		if request.Status != models.RefundRequestStatusPending {
+			return ErrNotPending
+		}
+		if request.RequestedBy == approver.ID {
+			return ErrSameApprover
+		}
+
+		now := s.now()
+		request.DecidedBy = &approver.ID
+		request.DecidedAt = &now
+		request.DecisionNote = note
+		request.Status = models.RefundRequestStatusRejected
+		if approved {
+			request.Status = models.RefundRequestStatusApproved
+		}
+		if err := tx.Model(&request).Updates(map[string]interface{}{
+			"status":        request.Status,
+			"decided_by":    approver.ID,
+			"decided_at":    now,
+			"decision_note": note,
+		}).Error; err != nil {
+			return err
+		}
+
+		if err := s.record(tx, request.Payment, activitylog.RefundDecided{
+			ActorID:   approver.ID,
+			RequestID: request.ID,
+			Amount:    request.Amount,
+			Approved:  approved,
+			Note:      note,
+		}); err != nil {
+			return err
+		}
+		if !approved {
+			return s.notifyRequester(tx, &request)
+		}
+		return nil
+	})
+	if err != nil {
+		if isServiceError(err) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to decide refund request: %w", err)
+	}
+
+	s.logger.Info("Refund request decided",
+		zap.String("refund_request_id", request.ID.String()),
+		zap.String("approver_id", approver.ID.String()),
+		zap.String("status", string(request.Status)))
+	return &request, nil
+}
+
+// execute pays back an approved request. A refused refund marks the request
+// as failed and returns ErrRefundFailed.
+func (s *Service) execute(request *models.RefundRequest, actor *models.User) (*models.RefundRequest, error) {
+	if request.ToCredit {
+		_, entry, err := s.credit.RefundToCredit(request.PaymentID, &request.Amount, request.Reason, actor)
+		if err != nil {
+			return s.fail(request, err)
+		}
+		request.CreditEntryID = &entry.ID
+	} else {
+		var payment models.Payment
+		if err := s.db.First(&payment, "id = ?", request.PaymentID).Error; err != nil {
+			return s.fail(request, err)
+		}
+		refundID, err := s.refunder.Refund(payment.StripePaymentIntent, int64(math.Round(request.Amount*100)), request.Reason)
+		if err != nil {
+			return s.fail(request, err)
+		}
+		request.StripeRefundID = refundID
+	}
+
+	now := s.now()
+	request.Status = models.RefundRequestStatusExecuted
+	request.ExecutedAt = &now
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(request).Updates(map[string]interface{}{
+			"status":           request.Status,
+			"executed_at":      now,
+			"stripe_refund_id": request.StripeRefundID,
+			"credit_entry_id":  request.CreditEntryID,
+		}).Error; err != nil {
+			return err
+		}
+
+		var payment models.Payment
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&payment, "id = ?", request.PaymentID).Error; err != nil {
+			return err
+		}
+		// Credit refunds are booked on the payment by the credit ledger
+		if !request.ToCredit {
+			payment.RefundAmount = round(payment.RefundAmount + request.Amount)
+			payment.RefundReason = request.Reason
+			payment.RefundedAt = &now
+			if payment.RefundAmount >= round(payment.Amount+payment.CreditAmount) {
+				payment.Status = models.PaymentStatusRefunded
+			}
+			if err := tx.Save(&payment).Error; err != nil {
+				return err
+			}
+		}
+
+		return s.record(tx, &payment, activitylog.RefundExecuted{
+			ActorID:        actor.ID,
+			RequestID:      request.ID,
+			Amount:         request.Amount,
+			ToCredit:       request.ToCredit,
+			StripeRefundID: request.StripeRefundID,
+		})
+	})
+	if err != nil {
+		// The money was paid back, only our records are behind
+		s.logger.Error("Refund executed but failed to update records",
+			zap.String("refund_request_id", request.ID.String()),
+			zap.String("stripe_refund_id", request.StripeRefundID),
+			zap.Error(err))
+		return nil, fmt.Errorf("failed to record executed refund: %w", err)
+	}
+
+	s.logger.Info("Refund executed",
+		zap.String("refund_request_id", request.ID.String()),
+		zap.String("payment_id", request.PaymentID.String()),
+		zap.Float64("amount", request.Amount),
+		zap.Bool("to_credit", request.ToCredit))
+	return request, nil
+}
+
+// fail marks a request whose refund was refused as failed
+func (s *Service) fail(request *models.RefundRequest, cause error) (*models.RefundRequest, error) {
+	request.Status = models.RefundRequestStatusFailed
+	request.FailureMessage = cause.Error()
+	if err := s.db.Model(request).Updates(map[string]interface{}{
+		"status":          request.Status,
+		"failure_message": request.FailureMessage,
+	}).Error; err != nil {
+		s.logger.Error("Failed to mark refund request as failed", zap.String("refund_request_id", request.ID.String()), zap.Error(err))
+	}
+
+	s.logger.Error("Refund failed",
+		zap.String("refund_request_id", request.ID.String()),
+		zap.String("payment_id", request.PaymentID.String()),
+		zap.Error(cause))
+	return request, fmt.Errorf("%w: %v", ErrRefundFailed, cause)
+}
+
+// record adds a refund event to the activity history of the payment's lead;
+// payments without a lead have no history
+func (s *Service) record(tx *gorm.DB, payment *models.Payment, event activitylog.Event) error {
+	if payment == nil || payment.LeadID == uuid.Nil {
+		return nil
+	}
+	switch e := event.(type) {
+	case activitylog.RefundRequested:
+		e.LeadID = payment.LeadID
+		event = e
+	case activitylog.RefundDecided:
+		e.LeadID = payment.LeadID
+		event = e
+	case activitylog.RefundExecuted:
+		e.LeadID = payment.LeadID
+		event = e
+	}
+	return s.activity.Record(tx, event)
+}
+
+// notifyApprovers tells the other active admins that a refund waits for approval
+func (s *Service) notifyApprovers(tx *gorm.DB, request *models.RefundRequest, requester *models.User) error {
+	var admins []models.User
+	if err := tx.Where("role = ? AND is_active = ? AND id <> ?", models.RoleAdmin, true, requester.ID).
+		Find(&admins).Error; err != nil {
+		return err
+	}
+
+	data, _ := json.Marshal(map[string]interface{}{
+		"refund_request_id": request.ID,
+		"payment_id":        request.PaymentID,
+		"amount":            request.Amount,
+	})
+	for _, admin := range admins {
+		if err := tx.Create(&models.Notification{
+			UserID:    admin.ID,
+			Type:      models.NotificationTypeInApp,
+			Title:     "Erstattung wartet auf Freigabe",
+			Message:   fmt.Sprintf("%s beantragt eine Erstattung über %.2f €: %s", requester.FullName(), request.Amount, request.Reason),
+			Data:      string(data),
+			Recipient: admin.NotificationAddress(),
+		}).Error; err != nil {
+			return fmt.Errorf("failed to notify approver: %w", err)
+		}
+	}
+	return nil
+}
+
+// notifyRequester tells the requester that their refund was rejected
+func (s *Service) notifyRequester(tx *gorm.DB, request *models.RefundRequest) error {
+	var requester models.User
+	if err := tx.First(&requester, "id = ?", request.RequestedBy).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	data, _ := json.Marshal(map[string]interface{}{
+		"refund_request_id": request.ID,
+		"payment_id":        request.PaymentID,
+	})
+	if err := tx.Create(&models.Notification{
+		UserID:    requester.ID,
+		Type:      models.NotificationTypeInApp,
+		Title:     "Erstattung abgelehnt",
+		Message:   fmt.Sprintf("Die Erstattung über %.2f € wurde abgelehnt: %s", request.Amount, request.DecisionNote),
+		Data:      string(data),
+		Recipient: requester.NotificationAddress(),
+	}).Error; err != nil {
+		return fmt.Errorf("failed to notify requester: %w", err)
+	}
+	return nil
+}
+
+func isServiceError(err error) bool {
+	for _, target := range []error{ErrPaymentNotFound, ErrNotFound, ErrNotRefundable, ErrNoPaymentIntent,
+		ErrInvalidAmount, ErrRefundTooHigh, ErrNotPending, ErrSameApprover} {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+func round(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
@@ -0,0 +1,210 @@
+package refunds
+
+import (
+	"errors"
+	"testing"
+
+	"elterngeld-portal/config"
+	"elterngeld-portal/internal/activitylog"
+	"elterngeld-portal/internal/credit"
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/tests/testutils"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type fakeRefunder struct {
+	calls  []int64
+	intent string
+	err    error
+}
+
+func (f *fakeRefunder) Refund(paymentIntentID string, amount int64, reason string) (string, error) {
+	if f.err != nil {
+		return "", f.err
+	}
+	f.intent = paymentIntentID
+	f.calls = append(f.calls, amount)
+	return "re_test_1", nil
+}
+
+func TestRequest_BelowThreshold(t *testing.T) {
+	db, service, refunder := setupTestService(t)
+	berater := testutils.CreateTestUser(t, db, models.RoleBerater)
+	payment := createPayment(t, db, 249)
+
+	_, err := service.Request(RequestInput{PaymentID: payment.ID, Amount: float64Ptr(50)}, berater)
+	assert.ErrorIs(t, err, ErrReasonRequired)
+
+	amount := 80.0
+	request, err := service.Request(RequestInput{PaymentID: payment.ID, Amount: &amount, Reason: "Termin ausgefallen"}, berater)
+	require.NoError(t, err)
+	assert.False(t, request.RequiresApproval)
+	assert.Equal(t, models.RefundRequestStatusExecuted, request.Status)
+	assert.Equal(t, "re_test_1", request.StripeRefundID)
+	assert.Equal(t, []int64{8000}, refunder.calls)
+	assert.Equal(t, "pi_test_1", refunder.intent)
+
+	var refunded models.Payment
+	require.NoError(t, db.First(&refunded, "id = ?", payment.ID).Error)
+	assert.Equal(t, 80.0, refunded.RefundAmount)
+	assert.Equal(t, models.PaymentStatusSucceeded, refunded.Status, "partially refunded")
+
+	var activities []models.Activity
+	require.NoError(t, db.Where("lead_id = ?", payment.LeadID).Find(&activities).Error)
+	assert.Len(t, activities, 2, "requested and executed")
+}
+
+func TestRequest_FourEyes(t *testing.T) {
+	db, service, refunder := setupTestService(t)
+	requester := testutils.CreateTestUser(t, db, models.RoleAdmin)
+	approver := testutils.CreateTestUser(t, db, models.RoleAdmin)
+	payment := createPayment(t, db, 249)
+
+	request, err := service.Request(RequestInput{PaymentID: payment.ID, Reason: "Kunde unzufrieden"}, requester)
+	require.NoError(t, err)
+	assert.True(t, request.RequiresApproval)
+	assert.Equal(t, models.RefundRequestStatusPending, request.Status)
+	assert.Equal(t, 249.0, request.Amount, "everything left to refund")
+	assert.Empty(t, refunder.calls, "waits for approval")
+
+	// Only the other admin is asked for approval
+	var notifications []models.Notification
+	require.NoError(t, db.Find(&notifications).Error)
+	require.Len(t, notifications, 1)
+	assert.Equal(t, approver.ID, notifications[0].UserID)
+
+	// The pending request reserves the amount
+	_, err = service.Request(RequestInput{PaymentID: payment.ID, Amount: float64Ptr(10), Reason: "Doppelt"}, requester)
+	assert.ErrorIs(t, err, ErrNotRefundable)
+
+	_, err = service.Approve(request.ID, requester, "")
+	assert.ErrorIs(t, err, ErrSameApprover)
+
+	approved, err := service.Approve(request.ID, approver, "Passt")
+	require.NoError(t, err)
+	assert.Equal(t, models.RefundRequestStatusExecuted, approved.Status)
+	assert.Equal(t, approver.ID, *approved.DecidedBy)
+	assert.Equal(t, []int64{24900}, refunder.calls)
+
+	var refunded models.Payment
+	require.NoError(t, db.First(&refunded, "id = ?", payment.ID).Error)
+	assert.Equal(t, models.PaymentStatusRefunded, refunded.Status)
+
+	_, err = service.Approve(request.ID, approver, "")
+	assert.ErrorIs(t, err, ErrNotPending)
+}
+
+func TestReject(t *testing.T) {
+	db, service, refunder := setupTestService(t)
+	requester := testutils.CreateTestUser(t, db, models.RoleBerater)
+	approver := testutils.CreateTestUser(t, db, models.RoleAdmin)
+	payment := createPayment(t, db, 249)
+
+	request, err := service.Request(RequestInput{PaymentID: payment.ID, Amount: float64Ptr(200), Reason: "Kulanz"}, requester)
+	require.NoError(t, err)
+
+	_, err = service.Reject(request.ID, approver, " ")
+	assert.ErrorIs(t, err, ErrReasonRequired)
+
+	rejected, err := service.Reject(request.ID, approver, "Leistung wurde erbracht")
+	require.NoError(t, err)
+	assert.Equal(t, models.RefundRequestStatusRejected, rejected.Status)
+	assert.Empty(t, refunder.calls)
+
+	// The requester learns why
+	var notification models.Notification
+	require.NoError(t, db.Where("user_id = ?", requester.ID).First(&notification).Error)
+	assert.Contains(t, notification.Message, "Leistung wurde erbracht")
+
+	// The rejected amount can be requested again
+	_, err = service.Request(RequestInput{PaymentID: payment.ID, Amount: float64Ptr(50), Reason: "Teilerstattung"}, requester)
+	require.NoError(t, err)
+
+	pending, err := service.List(Filter{Status: models.RefundRequestStatusPending})
+	require.NoError(t, err)
+	assert.Empty(t, pending)
+	all, err := service.List(Filter{PaymentID: &payment.ID})
+	require.NoError(t, err)
+	assert.Len(t, all, 2)
+}
+
+func TestRequest_ToCredit(t *testing.T) {
+	db, service, refunder := setupTestService(t)
+	berater := testutils.CreateTestUser(t, db, models.RoleBerater)
+	payment := createPayment(t, db, 99)
+	payment.StripePaymentIntent = ""
+	require.NoError(t, db.Save(payment).Error)
+
+	_, err := service.Request(RequestInput{PaymentID: payment.ID, Reason: "Storno"}, berater)
+	assert.ErrorIs(t, err, ErrNoPaymentIntent)
+
+	request, err := service.Request(RequestInput{PaymentID: payment.ID, ToCredit: true, Reason: "Storno"}, berater)
+	require.NoError(t, err)
+	assert.Equal(t, models.RefundRequestStatusExecuted, request.Status)
+	require.NotNil(t, request.CreditEntryID)
+	assert.Empty(t, refunder.calls)
+
+	var entry models.CreditEntry
+	require.NoError(t, db.First(&entry, "id = ?", *request.CreditEntryID).Error)
+	assert.Equal(t, 99.0, entry.Amount)
+}
+
+func TestRequest_Failed(t *testing.T) {
+	db, service, refunder := setupTestService(t)
+	berater := testutils.CreateTestUser(t, db, models.RoleBerater)
+	payment := createPayment(t, db, 249)
+	refunder.err = errors.New("charge already refunded")
+
+	request, err := service.Request(RequestInput{PaymentID: payment.ID, Amount: float64Ptr(20), Reason: "Kulanz"}, berater)
+	assert.ErrorIs(t, err, ErrRefundFailed)
+	require.NotNil(t, request)
+	assert.Equal(t, models.RefundRequestStatusFailed, request.Status)
+	assert.Equal(t, "charge already refunded", request.FailureMessage)
+
+	var unchanged models.Payment
+	require.NoError(t, db.First(&unchanged, "id = ?", payment.ID).Error)
+	assert.Zero(t, unchanged.RefundAmount)
+
+	_, err = service.Request(RequestInput{PaymentID: payment.ID, Amount: float64Ptr(300), Reason: "Kulanz"}, berater)
+	assert.ErrorIs(t, err, ErrRefundTooHigh, "failed requests do not reserve the amount")
+	_, err = service.Request(RequestInput{PaymentID: uuid.New(), Reason: "Kulanz"}, berater)
+	assert.ErrorIs(t, err, ErrPaymentNotFound)
+}
+
+func setupTestService(t *testing.T) (*gorm.DB, *Service, *fakeRefunder) {
+	t.Helper()
+	db := testutils.NewSQLiteDB(t, &models.User{}, &models.Lead{}, &models.Payment{}, &models.CreditEntry{},
+		&models.Activity{}, &models.Notification{}, &models.RefundRequest{})
+
+	cfg := &config.Config{Refund: config.RefundConfig{ApprovalThreshold: 100}}
+	refunder := &fakeRefunder{}
+	service := NewService(db, zap.NewNop(), cfg, credit.NewService(db, zap.NewNop()), activitylog.NewService(db, zap.NewNop()), refunder)
+	return db, service, refunder
+}
+
+func createPayment(t *testing.T, db *gorm.DB, amount float64) *models.Payment {
+	t.Helper()
+	customer := testutils.CreateTestUser(t, db, models.RoleUser)
+	lead := &models.Lead{UserID: customer.ID, Title: "Elterngeld-Beratung"}
+	require.NoError(t, db.Create(lead).Error)
+	payment := &models.Payment{
+		UserID:              customer.ID,
+		LeadID:              lead.ID,
+		Amount:              amount,
+		Currency:            "EUR",
+		Status:              models.PaymentStatusSucceeded,
+		StripeSessionID:     "cs_" + uuid.New().String(),
+		StripePaymentIntent: "pi_test_1",
+	}
+	require.NoError(t, db.Create(payment).Error)
+	return payment
+}
+
+func float64Ptr(value float64) *float64 {
+	return &value
+}
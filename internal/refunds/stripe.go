@@ -0,0 +1,32 @@
+package refunds
+
+import (
+	"github.com/stripe/stripe-go/v76"
+	"github.com/stripe/stripe-go/v76/refund"
+)
+
+// Refunder refunds card payments
+type Refunder interface {
+	// Refund refunds an amount in cents of a payment intent and returns the
+	// ID of the refund
+	Refund(paymentIntentID string, amount int64, reason string) (string, error)
+}
+
+// StripeRefunder refunds payments via the Stripe API configured for the
+// payment handler
+type StripeRefunder struct{}
+
+func (StripeRefunder) Refund(paymentIntentID string, amount int64, reason string) (string, error) {
+	params := &stripe.RefundParams{
+		PaymentIntent: stripe.String(paymentIntentID),
+		Amount:        stripe.Int64(amount),
+		Reason:        stripe.String(string(stripe.RefundReasonRequestedByCustomer)),
+	}
+	params.AddMetadata("reason", reason)
+
+	result, err := refund.New(params)
+	if err != nil {
+		return "", err
+	}
+	return result.ID, nil
+}
@@ -0,0 +1,408 @@
+// Package replies sends email replies to contact forms and leads from the
+// portal. A reply goes out in the name of the Berater, is recorded on the
+// activity timeline and, for leads, on the lead's email thread. Lead replies
+// carry a Message-ID, so the customer's answer is matched back to the thread
+// by the inbound email processor.
+package replies
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"elterngeld-portal/config"
+	"elterngeld-portal/internal/activitylog"
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/internal/scopes"
+	"elterngeld-portal/internal/snippets"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// MaxAttachments is the maximum number of files sent with a single reply
+const MaxAttachments = 10
+
+var (
+	// ErrContactFormNotFound is returned for unknown contact forms
+	ErrContactFormNotFound = errors.New("contact form not found")
+	// ErrLeadNotFound is returned when the lead does not exist or the user cannot reply to it
+	ErrLeadNotFound = errors.New("lead not found")
+	// ErrClaimedByOther is returned when replying to a contact form someone else claimed
+	ErrClaimedByOther = errors.New("contact form is claimed by someone else")
+	// ErrEmptyReply is returned when a reply has no subject or body
+	ErrEmptyReply = errors.New("reply subject and body are required")
+	// ErrTooManyAttachments is returned when a reply exceeds MaxAttachments
+	ErrTooManyAttachments = errors.New("too many attachments")
+	// ErrFileTooLarge is returned when an attachment exceeds the upload size limit
+	ErrFileTooLarge = errors.New("file exceeds maximum upload size")
+	// ErrFileTypeNotAllowed is returned when an attachment has a disallowed extension
+	ErrFileTypeNotAllowed = errors.New("file type not allowed")
+)
+
+// Upload is a file uploaded to be sent with a reply
+type Upload struct {
+	FileName    string
+	ContentType string
+	Size        int64
+	Content     io.Reader
+}
+
+// Input is a reply written by a Berater. Without a body the snippet is sent,
+// with its placeholders filled in for the contact form or lead replied to.
+type Input struct {
+	Subject   string
+	Body      string
+	SnippetID *uuid.UUID
+	Uploads   []Upload
+}
+
+// Attachment is a file sent with a reply
+type Attachment struct {
+	FileName    string
+	ContentType string
+	Content     []byte
+}
+
+// Email is a reply as handed to the mailer
+type Email struct {
+	To        string
+	Name      string
+	Language  string
+	Subject   string
+	Body      string
+	Reference string // application number or contact form reference, shown below the reply
+	MessageID string
+	InReplyTo string
+
+	Berater     *models.User
+	Attachments []Attachment
+}
+
+// Mailer sends replies
+type Mailer interface {
+	SendReply(reply *Email) error
+}
+
+// Reply is a sent reply
+type Reply struct {
+	To             string     `json:"to"`
+	Subject        string     `json:"subject"`
+	Body           string     `json:"body"`
+	Attachments    []string   `json:"attachments"`
+	MessageID      string     `json:"message_id"`
+	EmailMessageID *uuid.UUID `json:"email_message_id,omitempty"` // the reply on the lead's email thread
+	SentAt         time.Time  `json:"sent_at"`
+}
+
+// Service sends and records replies
+type Service struct {
+	db                *gorm.DB
+	logger            *zap.Logger
+	mailer            Mailer
+	snippets          *snippets.Service
+	activities        *activitylog.Service
+	maxSize           int64
+	allowedExtensions []string
+	now               func() time.Time
+}
+
+func NewService(db *gorm.DB, logger *zap.Logger, cfg *config.Config, mailer Mailer, snippetService *snippets.Service, activities *activitylog.Service) *Service {
+	return &Service{
+		db:                db,
+		logger:            logger,
+		mailer:            mailer,
+		snippets:          snippetService,
+		activities:        activities,
+		maxSize:           cfg.Upload.MaxSize,
+		allowedExtensions: cfg.Upload.AllowedExtensions,
+		now:               time.Now,
+	}
+}
+
+// ReplyToContactForm emails a reply to the sender of a contact form and marks
+// the form as replied. A form nobody claimed is claimed by the replier, a form
+// claimed by someone else can only be answered by admins. Replies to forms
+// that became a lead are recorded on the lead's email thread.
+func (s *Service) ReplyToContactForm(id uuid.UUID, viewer scopes.Viewer, input Input) (*Reply, error) {
+	var form models.ContactForm
+	if err := s.db.First(&form, "id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrContactFormNotFound
+		}
+		return nil, err
+	}
+	if form.ClaimedBy != nil && *form.ClaimedBy != viewer.ID && viewer.Role != models.RoleAdmin {
+		return nil, ErrClaimedByOther
+	}
+
+	if strings.TrimSpace(input.Subject) == "" {
+		input.Subject = "Re: " + form.Subject
+	}
+	email, err := s.compose(viewer, input, snippets.RenderContext{ContactFormID: &form.ID})
+	if err != nil {
+		return nil, err
+	}
+	email.To = form.Email
+	email.Name = form.Name
+	email.Reference = "CF-" + form.ID.String()[:8]
+
+	var lead *models.Lead
+	if form.LeadID != nil {
+		lead = &models.Lead{}
+		if err := s.db.First(lead, "id = ?", *form.LeadID).Error; err != nil {
+			if !errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, err
+			}
+			lead = nil
+		}
+	}
+
+	return s.send(email, lead, &form)
+}
+
+// ReplyToLead emails a reply to the customer of a lead. Junior-Berater only
+// reply to the leads assigned to them.
+func (s *Service) ReplyToLead(id uuid.UUID, viewer scopes.Viewer, input Input) (*Reply, error) {
+	var lead models.Lead
+	if err := s.db.Scopes(scopes.EditableLeads(viewer)).Preload("User").
+		First(&lead, "leads.id = ?", id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrLeadNotFound
+		}
+		return nil, err
+	}
+
+	email, err := s.compose(viewer, input, snippets.RenderContext{LeadID: &lead.ID})
+	if err != nil {
+		return nil, err
+	}
+	email.To = lead.User.NotificationAddress()
+	email.Name = lead.User.FullName()
+	email.Language = lead.User.Language
+	email.Reference = lead.ApplicationNumber
+
+	return s.send(email, &lead, nil)
+}
+
+// compose builds the email of a reply from the input, the snippet and the
+// uploaded attachments
+func (s *Service) compose(viewer scopes.Viewer, input Input, context snippets.RenderContext) (*Email, error) {
+	if input.SnippetID != nil && strings.TrimSpace(input.Body) == "" {
+		rendered, err := s.snippets.Render(*input.SnippetID, viewer, context)
+		if err != nil {
+			return nil, err
+		}
+		input.Body = rendered.Body
+	}
+
+	email := &Email{
+		Subject: strings.TrimSpace(input.Subject),
+		Body:    strings.TrimSpace(input.Body),
+	}
+	if email.Subject == "" || email.Body == "" {
+		return nil, ErrEmptyReply
+	}
+
+	if len(input.Uploads) > MaxAttachments {
+		return nil, ErrTooManyAttachments
+	}
+	for _, upload := range input.Uploads {
+		attachment, err := s.read(upload)
+		if err != nil {
+			return nil, err
+		}
+		email.Attachments = append(email.Attachments, *attachment)
+	}
+
+	var berater models.User
+	if err := s.db.First(&berater, "id = ?", viewer.ID).Error; err != nil {
+		return nil, fmt.Errorf("failed to load user: %w", err)
+	}
+	email.Berater = &berater
+	return email, nil
+}
+
+// send emails the reply and records it. Nothing is recorded when sending fails.
+func (s *Service) send(email *Email, lead *models.Lead, form *models.ContactForm) (*Reply, error) {
+	now := s.now()
+	reply := &Reply{
+		To:          email.To,
+		Subject:     email.Subject,
+		Body:        email.Body,
+		Attachments: []string{},
+		MessageID:   fmt.Sprintf("<%s@outbound.elterngeld-portal>", uuid.New()),
+		SentAt:      now,
+	}
+	for _, attachment := range email.Attachments {
+		reply.Attachments = append(reply.Attachments, attachment.FileName)
+	}
+	email.MessageID = reply.MessageID
+
+	var thread *models.EmailThread
+	if lead != nil {
+		var err error
+		if thread, err = s.thread(lead, email.Subject); err != nil {
+			return nil, err
+		}
+		if thread != nil {
+			email.InReplyTo = s.lastInbound(thread)
+		}
+	}
+
+	if err := s.mailer.SendReply(email); err != nil {
+		return nil, fmt.Errorf("failed to send reply: %w", err)
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		event := activitylog.ReplySent{
+			ActorID:     email.Berater.ID,
+			To:          email.To,
+			Subject:     email.Subject,
+			Body:        email.Body,
+			Attachments: reply.Attachments,
+		}
+
+		if lead != nil {
+			message, err := s.recordOnThread(tx, lead, thread, email, now)
+			if err != nil {
+				return err
+			}
+			reply.EmailMessageID = &message.ID
+			event.LeadID = &lead.ID
+			event.EmailMessageID = &message.ID
+		}
+
+		if form != nil {
+			updates := map[string]interface{}{
+				"is_replied": true,
+				"replied_at": now,
+				"replied_by": email.Berater.ID,
+			}
+			if form.ClaimedBy == nil {
+				updates["claimed_by"] = email.Berater.ID
+				updates["claimed_at"] = now
+			}
+			if err := tx.Model(form).Updates(updates).Error; err != nil {
+				return fmt.Errorf("failed to update contact form: %w", err)
+			}
+			event.ContactFormID = &form.ID
+		}
+
+		return s.activities.Record(tx, event)
+	})
+	if err != nil {
+		// The email is out, so the reply is reported as sent
+		s.logger.Error("Failed to record reply", zap.String("message_id", reply.MessageID), zap.Error(err))
+	}
+	return reply, nil
+}
+
+// thread returns the active thread of the lead with the reply's subject, if any
+func (s *Service) thread(lead *models.Lead, subject string) (*models.EmailThread, error) {
+	var threads []models.EmailThread
+	if err := s.db.Where("lead_id = ? AND subject = ? AND is_active = ?", lead.ID, models.NormalizeEmailSubject(subject), true).
+		Order("last_message_at DESC").Limit(1).Find(&threads).Error; err != nil {
+		return nil, fmt.Errorf("failed to load email thread: %w", err)
+	}
+	if len(threads) == 0 {
+		return nil, nil
+	}
+	return &threads[0], nil
+}
+
+// lastInbound returns the Message-ID of the customer's latest message on the thread
+func (s *Service) lastInbound(thread *models.EmailThread) string {
+	var messages []models.EmailMessage
+	if err := s.db.Where("thread_id = ? AND is_inbound = ?", thread.ID, true).
+		Order("sent_at DESC").Limit(1).Find(&messages).Error; err != nil || len(messages) == 0 {
+		return ""
+	}
+	return messages[0].MessageID
+}
+
+// recordOnThread adds the reply to the lead's email thread, starting a new
+// thread for a new subject
+func (s *Service) recordOnThread(tx *gorm.DB, lead *models.Lead, thread *models.EmailThread, email *Email, sentAt time.Time) (*models.EmailMessage, error) {
+	if thread == nil {
+		thread = &models.EmailThread{
+			LeadID:        lead.ID,
+			Subject:       models.NormalizeEmailSubject(email.Subject),
+			ThreadID:      email.MessageID,
+			LastMessageAt: sentAt,
+			IsActive:      true,
+		}
+		if err := tx.Create(thread).Error; err != nil {
+			return nil, fmt.Errorf("failed to create email thread: %w", err)
+		}
+	}
+
+	message := &models.EmailMessage{
+		ThreadID:  thread.ID,
+		MessageID: email.MessageID,
+		FromEmail: email.Berater.Email,
+		ToEmail:   email.To,
+		Subject:   email.Subject,
+		Body:      email.Body,
+		SentAt:    sentAt,
+	}
+	if err := tx.Create(message).Error; err != nil {
+		return nil, fmt.Errorf("failed to create email message: %w", err)
+	}
+	// GORM would replace a false IsInbound with the column default on create
+	if err := tx.Model(message).Updates(map[string]interface{}{"is_inbound": false, "is_read": true}).Error; err != nil {
+		return nil, fmt.Errorf("failed to update email message: %w", err)
+	}
+
+	if err := tx.Model(thread).Updates(map[string]interface{}{
+		"message_count":   gorm.Expr("message_count + ?", 1),
+		"last_message_at": sentAt,
+	}).Error; err != nil {
+		return nil, fmt.Errorf("failed to update email thread: %w", err)
+	}
+	if err := tx.Model(lead).Updates(map[string]interface{}{
+		"last_contact_at":  sentAt,
+		"contact_attempts": gorm.Expr("contact_attempts + ?", 1),
+	}).Error; err != nil {
+		return nil, fmt.Errorf("failed to update lead: %w", err)
+	}
+	return message, nil
+}
+
+// read validates an upload and reads it into memory
+func (s *Service) read(upload Upload) (*Attachment, error) {
+	if s.maxSize > 0 && upload.Size > s.maxSize {
+		return nil, ErrFileTooLarge
+	}
+	if len(s.allowedExtensions) > 0 {
+		ext := strings.ToLower(filepath.Ext(upload.FileName))
+		allowed := false
+		for _, candidate := range s.allowedExtensions {
+			if ext == strings.ToLower(strings.TrimSpace(candidate)) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return nil, ErrFileTypeNotAllowed
+		}
+	}
+
+	content, err := io.ReadAll(upload.Content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read attachment: %w", err)
+	}
+	if s.maxSize > 0 && int64(len(content)) > s.maxSize {
+		return nil, ErrFileTooLarge
+	}
+
+	fileName := filepath.Base(upload.FileName)
+	if fileName == "." || fileName == string(filepath.Separator) {
+		fileName = "anhang"
+	}
+	return &Attachment{FileName: fileName, ContentType: upload.ContentType, Content: content}, nil
+}
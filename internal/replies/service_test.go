@@ -0,0 +1,176 @@
+package replies
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"elterngeld-portal/config"
+	"elterngeld-portal/internal/activitylog"
+	"elterngeld-portal/internal/mergefields"
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/internal/scopes"
+	"elterngeld-portal/internal/snippets"
+	"elterngeld-portal/tests/testutils"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type fakeMailer struct {
+	sent []*Email
+	err  error
+}
+
+func (m *fakeMailer) SendReply(reply *Email) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.sent = append(m.sent, reply)
+	return nil
+}
+
+func TestReplyToContactForm(t *testing.T) {
+	service, db, mailer := setupTestService(t)
+	berater := testutils.CreateTestUser(t, db, models.RoleBerater)
+	other := testutils.CreateTestUser(t, db, models.RoleBerater)
+	admin := testutils.CreateTestUser(t, db, models.RoleAdmin)
+
+	form := &models.ContactForm{Name: "Anna Schmidt", Email: "anna@example.com", Subject: "Elterngeld Plus", Message: "Wie beantrage ich Elterngeld Plus?"}
+	require.NoError(t, db.Create(form).Error)
+
+	reply, err := service.ReplyToContactForm(form.ID, viewerOf(berater), Input{Body: "Gerne helfe ich Ihnen weiter."})
+	require.NoError(t, err)
+	assert.Equal(t, "Re: Elterngeld Plus", reply.Subject)
+	assert.Nil(t, reply.EmailMessageID)
+
+	require.Len(t, mailer.sent, 1)
+	assert.Equal(t, "anna@example.com", mailer.sent[0].To)
+	assert.Equal(t, "CF-"+form.ID.String()[:8], mailer.sent[0].Reference)
+	assert.Equal(t, berater.ID, mailer.sent[0].Berater.ID)
+
+	var saved models.ContactForm
+	require.NoError(t, db.First(&saved, "id = ?", form.ID).Error)
+	assert.True(t, saved.IsReplied)
+	require.NotNil(t, saved.RepliedBy)
+	assert.Equal(t, berater.ID, *saved.RepliedBy)
+	require.NotNil(t, saved.ClaimedBy, "replying claims the form")
+	assert.Equal(t, berater.ID, *saved.ClaimedBy)
+
+	var activity models.Activity
+	require.NoError(t, db.Where("type = ?", models.ActivityTypeEmailSent).First(&activity).Error)
+	assert.Nil(t, activity.LeadID)
+	assert.Contains(t, string(activity.Metadata), "Gerne helfe ich Ihnen weiter.")
+
+	_, err = service.ReplyToContactForm(form.ID, viewerOf(other), Input{Body: "Hallo"})
+	assert.ErrorIs(t, err, ErrClaimedByOther)
+	_, err = service.ReplyToContactForm(form.ID, viewerOf(admin), Input{Body: "Hallo"})
+	require.NoError(t, err)
+
+	_, err = service.ReplyToContactForm(form.ID, viewerOf(berater), Input{Body: "  "})
+	assert.ErrorIs(t, err, ErrEmptyReply)
+	_, err = service.ReplyToContactForm(uuid.New(), viewerOf(berater), Input{Body: "Hallo"})
+	assert.ErrorIs(t, err, ErrContactFormNotFound)
+}
+
+func TestReplyToLead(t *testing.T) {
+	service, db, mailer := setupTestService(t)
+	berater := testutils.CreateTestUser(t, db, models.RoleBerater)
+	junior := testutils.CreateTestUser(t, db, models.RoleJuniorBerater)
+	customer := testutils.CreateTestUser(t, db, models.RoleUser)
+
+	lead := &models.Lead{UserID: customer.ID, Title: "Elterngeld für Zwillinge"}
+	require.NoError(t, db.Create(lead).Error)
+
+	// The customer wrote first, the reply continues the thread
+	thread := &models.EmailThread{LeadID: lead.ID, Subject: "Unterlagen", ThreadID: "<inbound-1@example.com>", LastMessageAt: time.Now(), IsActive: true}
+	require.NoError(t, db.Create(thread).Error)
+	inbound := &models.EmailMessage{ThreadID: thread.ID, MessageID: "<inbound-1@example.com>", FromEmail: customer.Email, ToEmail: "support@example.com", Subject: "Unterlagen", IsInbound: true, SentAt: time.Now()}
+	require.NoError(t, db.Create(inbound).Error)
+
+	snippet, err := service.snippets.Create(viewerOf(berater), snippets.Input{
+		Title: "Unterlagen",
+		Body:  "Hallo {{customer_first_name}}, zu {{application_number}} fehlt noch die Geburtsurkunde.",
+		Scope: models.SnippetScopeTeam,
+	})
+	require.NoError(t, err)
+
+	_, err = service.ReplyToLead(lead.ID, viewerOf(junior), Input{Subject: "Re: Unterlagen", SnippetID: &snippet.ID})
+	assert.ErrorIs(t, err, ErrLeadNotFound, "Junior-Berater only reply to their own leads")
+
+	_, err = service.ReplyToLead(lead.ID, viewerOf(berater), Input{Subject: "Re: Unterlagen", Body: "Hallo", Uploads: []Upload{upload("skript.exe", "x")}})
+	assert.ErrorIs(t, err, ErrFileTypeNotAllowed)
+
+	reply, err := service.ReplyToLead(lead.ID, viewerOf(berater), Input{
+		Subject:   "Re: Unterlagen",
+		SnippetID: &snippet.ID,
+		Uploads:   []Upload{upload("checkliste.pdf", "%PDF-1.4")},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, fmt.Sprintf("Hallo Test, zu %s fehlt noch die Geburtsurkunde.", lead.ApplicationNumber), reply.Body)
+	assert.Equal(t, []string{"checkliste.pdf"}, reply.Attachments)
+	require.NotNil(t, reply.EmailMessageID)
+
+	require.Len(t, mailer.sent, 1)
+	assert.Equal(t, customer.Email, mailer.sent[0].To)
+	assert.Equal(t, "<inbound-1@example.com>", mailer.sent[0].InReplyTo)
+	assert.Equal(t, reply.MessageID, mailer.sent[0].MessageID)
+	require.Len(t, mailer.sent[0].Attachments, 1)
+	assert.Equal(t, []byte("%PDF-1.4"), mailer.sent[0].Attachments[0].Content)
+
+	var message models.EmailMessage
+	require.NoError(t, db.First(&message, "id = ?", *reply.EmailMessageID).Error)
+	assert.Equal(t, thread.ID, message.ThreadID)
+	assert.False(t, message.IsInbound)
+
+	var saved models.Lead
+	require.NoError(t, db.First(&saved, "id = ?", lead.ID).Error)
+	assert.NotNil(t, saved.LastContactAt)
+
+	var activity models.Activity
+	require.NoError(t, db.Where("type = ? AND lead_id = ?", models.ActivityTypeEmailSent, lead.ID).First(&activity).Error)
+	assert.Equal(t, berater.ID, *activity.UserID)
+
+	// Nothing is recorded when the email cannot be sent
+	mailer.err = errors.New("smtp unavailable")
+	_, err = service.ReplyToLead(lead.ID, viewerOf(berater), Input{Subject: "Neues Thema", Body: "Hallo"})
+	require.Error(t, err)
+	var count int64
+	require.NoError(t, db.Model(&models.EmailThread{}).Where("lead_id = ?", lead.ID).Count(&count).Error)
+	assert.Equal(t, int64(1), count)
+}
+
+func setupTestService(t *testing.T) (*Service, *gorm.DB, *fakeMailer) {
+	t.Helper()
+	db := testutils.NewSQLiteDB(t,
+		&models.User{},
+		&models.Lead{},
+		&models.Booking{},
+		&models.Activity{},
+		&models.ContactForm{},
+		&models.EmailThread{},
+		&models.EmailMessage{},
+		&models.Snippet{},
+		&models.Package{},
+		&models.ElterngeldScenario{},
+	)
+
+	cfg := &config.Config{Upload: config.UploadConfig{MaxSize: 1024, AllowedExtensions: []string{".pdf"}}}
+	mailer := &fakeMailer{}
+	service := NewService(db, zap.NewNop(), cfg, mailer,
+		snippets.NewService(db, zap.NewNop(), mergefields.NewService(db, zap.NewNop())), activitylog.NewService(db, zap.NewNop()))
+	return service, db, mailer
+}
+
+func viewerOf(user *models.User) scopes.Viewer {
+	return scopes.Viewer{ID: user.ID, Role: user.Role}
+}
+
+func upload(name, content string) Upload {
+	return Upload{FileName: name, ContentType: "application/pdf", Size: int64(len(content)), Content: strings.NewReader(content)}
+}
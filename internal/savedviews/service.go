@@ -0,0 +1,355 @@
+// Package savedviews stores named filter, sort and column configurations of
+// the lead and booking lists. Users share their views with the team and pick
+// the view their list opens with.
+package savedviews
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/internal/scopes"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrNotFound is returned when the view does not exist or the user cannot see it
+	ErrNotFound = errors.New("saved view not found")
+	// ErrNotOwner is returned when someone other than the owner changes a view
+	ErrNotOwner = errors.New("only the owner can change the saved view")
+	// ErrUnknownResource is returned for lists views cannot be saved for
+	ErrUnknownResource = errors.New("unknown saved view resource")
+	// ErrInvalidConfiguration is returned for filters, sort fields or columns the list does not support
+	ErrInvalidConfiguration = errors.New("invalid saved view configuration")
+	// ErrNameTaken is returned when the user already has a view of the same name for the list
+	ErrNameTaken = errors.New("saved view name already taken")
+	// ErrShareNotAllowed is returned when a customer shares a view, only staff share with the team
+	ErrShareNotAllowed = errors.New("saved view cannot be shared")
+)
+
+// definition lists what a view of a list may configure
+type definition struct {
+	filters []string
+	sorts   []string
+	columns []string
+}
+
+var definitions = map[models.SavedViewResource]definition{
+	models.SavedViewResourceLeads: {
+		filters: []string{"status", "priority", "source", "assigned_to", "search", "my_leads"},
+		sorts:   []string{"created_at", "updated_at", "status", "priority", "title", "lead_score", "estimated_value"},
+		columns: []string{"application_number", "title", "status", "priority", "source", "user", "assigned_to", "lead_score", "estimated_value", "created_at", "updated_at"},
+	},
+	models.SavedViewResourceBookings: {
+		filters: []string{"status"},
+		sorts:   []string{"created_at", "scheduled_at", "status"},
+		columns: []string{"title", "status", "scheduled_at", "package", "lead", "berater", "created_at"},
+	},
+}
+
+// Input holds the configuration of a saved view
+type Input struct {
+	Name      string            `json:"name" binding:"required,max=100"`
+	Filters   map[string]string `json:"filters"`
+	SortBy    string            `json:"sort_by"`
+	SortOrder string            `json:"sort_order" binding:"omitempty,oneof=asc desc"`
+	Columns   []string          `json:"columns"`
+}
+
+// View is a saved view as seen by a user
+type View struct {
+	models.SavedView
+	IsOwner   bool `json:"is_owner"`
+	IsDefault bool `json:"is_default"`
+}
+
+// Service manages saved list views
+type Service struct {
+	db     *gorm.DB
+	logger *zap.Logger
+}
+
+func NewService(db *gorm.DB, logger *zap.Logger) *Service {
+	return &Service{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// List returns the viewer's own views of a list and the views shared with
+// the team, by name
+func (s *Service) List(viewer scopes.Viewer, resource models.SavedViewResource) ([]View, error) {
+	if _, ok := definitions[resource]; !ok {
+		return nil, ErrUnknownResource
+	}
+
+	var saved []models.SavedView
+	if err := s.visible(viewer).Preload("User").Where("resource = ?", resource).
+		Order("name ASC").Find(&saved).Error; err != nil {
+		return nil, err
+	}
+
+	defaultID, err := s.defaultID(viewer.ID, resource)
+	if err != nil {
+		return nil, err
+	}
+
+	views := make([]View, 0, len(saved))
+	for _, view := range saved {
+		views = append(views, present(view, viewer.ID, defaultID))
+	}
+	return views, nil
+}
+
+// Create saves a view of a list for the viewer
+func (s *Service) Create(viewer scopes.Viewer, resource models.SavedViewResource, input Input) (*View, error) {
+	view := &models.SavedView{UserID: viewer.ID, Resource: resource}
+	if err := s.apply(view, input); err != nil {
+		return nil, err
+	}
+	if err := s.db.Create(view).Error; err != nil {
+		return nil, fmt.Errorf("failed to create saved view: %w", err)
+	}
+
+	result := present(*view, viewer.ID, uuid.Nil)
+	return &result, nil
+}
+
+// Update replaces the configuration of one of the viewer's views
+func (s *Service) Update(id uuid.UUID, viewer scopes.Viewer, input Input) (*View, error) {
+	view, err := s.owned(id, viewer)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.apply(view, input); err != nil {
+		return nil, err
+	}
+	if err := s.db.Save(view).Error; err != nil {
+		return nil, fmt.Errorf("failed to update saved view: %w", err)
+	}
+	return s.get(view.ID, viewer)
+}
+
+// Share offers one of the viewer's views to the team or takes it back.
+// Taking a view back removes it as the default of other users.
+func (s *Service) Share(id uuid.UUID, viewer scopes.Viewer, shared bool) (*View, error) {
+	if shared && viewer.Role == models.RoleUser {
+		return nil, ErrShareNotAllowed
+	}
+	view, err := s.owned(id, viewer)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(view).Update("is_shared", shared).Error; err != nil {
+			return err
+		}
+		if shared {
+			return nil
+		}
+		return tx.Where("saved_view_id = ? AND user_id <> ?", view.ID, viewer.ID).
+			Delete(&models.SavedViewDefault{}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.get(view.ID, viewer)
+}
+
+// Delete removes one of the viewer's views and every default pointing to it
+func (s *Service) Delete(id uuid.UUID, viewer scopes.Viewer) error {
+	view, err := s.owned(id, viewer)
+	if err != nil {
+		return err
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("saved_view_id = ?", view.ID).Delete(&models.SavedViewDefault{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(view).Error
+	})
+}
+
+// SetDefault makes a view the one the viewer's list opens with. Views shared
+// by others can be the default as well.
+func (s *Service) SetDefault(id uuid.UUID, viewer scopes.Viewer) (*View, error) {
+	view, err := s.get(id, viewer)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "resource"}},
+		DoUpdates: clause.AssignmentColumns([]string{"saved_view_id", "updated_at"}),
+	}).Create(&models.SavedViewDefault{
+		UserID:      viewer.ID,
+		Resource:    view.Resource,
+		SavedViewID: view.ID,
+	}).Error
+	if err != nil {
+		return nil, err
+	}
+
+	view.IsDefault = true
+	return view, nil
+}
+
+// Default returns the view the viewer's list opens with
+func (s *Service) Default(viewer scopes.Viewer, resource models.SavedViewResource) (*View, error) {
+	if _, ok := definitions[resource]; !ok {
+		return nil, ErrUnknownResource
+	}
+	id, err := s.defaultID(viewer.ID, resource)
+	if err != nil {
+		return nil, err
+	}
+	if id == uuid.Nil {
+		return nil, ErrNotFound
+	}
+	return s.get(id, viewer)
+}
+
+// ClearDefault lets the viewer's list open without a saved view again
+func (s *Service) ClearDefault(viewer scopes.Viewer, resource models.SavedViewResource) error {
+	if _, ok := definitions[resource]; !ok {
+		return ErrUnknownResource
+	}
+	return s.db.Where("user_id = ? AND resource = ?", viewer.ID, resource).
+		Delete(&models.SavedViewDefault{}).Error
+}
+
+// visible selects the viewer's own views and, for staff, the views shared with the team
+func (s *Service) visible(viewer scopes.Viewer) *gorm.DB {
+	if viewer.Role == models.RoleUser {
+		return s.db.Where("saved_views.user_id = ?", viewer.ID)
+	}
+	return s.db.Where("saved_views.user_id = ? OR saved_views.is_shared = ?", viewer.ID, true)
+}
+
+// get loads a view the viewer can see
+func (s *Service) get(id uuid.UUID, viewer scopes.Viewer) (*View, error) {
+	var view models.SavedView
+	if err := s.visible(viewer).Preload("User").Where("saved_views.id = ?", id).First(&view).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	defaultID, err := s.defaultID(viewer.ID, view.Resource)
+	if err != nil {
+		return nil, err
+	}
+	result := present(view, viewer.ID, defaultID)
+	return &result, nil
+}
+
+// owned loads a view the viewer can see and change
+func (s *Service) owned(id uuid.UUID, viewer scopes.Viewer) (*models.SavedView, error) {
+	view, err := s.get(id, viewer)
+	if err != nil {
+		return nil, err
+	}
+	if !view.IsOwner {
+		return nil, ErrNotOwner
+	}
+	saved := view.SavedView
+	saved.User = models.User{}
+	return &saved, nil
+}
+
+func (s *Service) defaultID(userID uuid.UUID, resource models.SavedViewResource) (uuid.UUID, error) {
+	var defaults []models.SavedViewDefault
+	if err := s.db.Where("user_id = ? AND resource = ?", userID, resource).
+		Limit(1).Find(&defaults).Error; err != nil {
+		return uuid.Nil, err
+	}
+	if len(defaults) == 0 {
+		return uuid.Nil, nil
+	}
+	return defaults[0].SavedViewID, nil
+}
+
+// apply validates the input against the list's definition and copies it to the view
+func (s *Service) apply(view *models.SavedView, input Input) error {
+	def, ok := definitions[view.Resource]
+	if !ok {
+		return ErrUnknownResource
+	}
+
+	name := strings.TrimSpace(input.Name)
+	if name == "" {
+		return ErrInvalidConfiguration
+	}
+	for key := range input.Filters {
+		if !contains(def.filters, key) {
+			return fmt.Errorf("%w: unknown filter %q", ErrInvalidConfiguration, key)
+		}
+	}
+	if input.SortBy != "" && !contains(def.sorts, input.SortBy) {
+		return fmt.Errorf("%w: unknown sort field %q", ErrInvalidConfiguration, input.SortBy)
+	}
+	if input.SortOrder != "" && input.SortOrder != "asc" && input.SortOrder != "desc" {
+		return fmt.Errorf("%w: unknown sort order %q", ErrInvalidConfiguration, input.SortOrder)
+	}
+	seen := make(map[string]bool, len(input.Columns))
+	for _, column := range input.Columns {
+		if !contains(def.columns, column) || seen[column] {
+			return fmt.Errorf("%w: unknown or repeated column %q", ErrInvalidConfiguration, column)
+		}
+		seen[column] = true
+	}
+
+	var count int64
+	if err := s.db.Model(&models.SavedView{}).
+		Where("user_id = ? AND resource = ? AND LOWER(name) = LOWER(?) AND id <> ?", view.UserID, view.Resource, name, view.ID).
+		Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrNameTaken
+	}
+
+	filters := input.Filters
+	if filters == nil {
+		filters = map[string]string{}
+	}
+	columns := input.Columns
+	if columns == nil {
+		columns = []string{}
+	}
+	view.Filters, _ = json.Marshal(filters)
+	view.Columns, _ = json.Marshal(columns)
+	view.Name = name
+	view.SortBy = input.SortBy
+	view.SortOrder = input.SortOrder
+	if view.SortBy != "" && view.SortOrder == "" {
+		view.SortOrder = "desc"
+	}
+	return nil
+}
+
+func present(view models.SavedView, viewerID, defaultID uuid.UUID) View {
+	return View{
+		SavedView: view,
+		IsOwner:   view.UserID == viewerID,
+		IsDefault: defaultID != uuid.Nil && view.ID == defaultID,
+	}
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,173 @@
+package savedviews
+
+import (
+	"testing"
+
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/internal/scopes"
+	"elterngeld-portal/tests/testutils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+func TestCreateAndUpdate(t *testing.T) {
+	db, service := setupTestService(t)
+	berater := createViewer(t, db, models.RoleBerater)
+
+	view, err := service.Create(berater, models.SavedViewResourceLeads, Input{
+		Name:    " Neue Leads ",
+		Filters: map[string]string{"status": "neu", "my_leads": "true"},
+		SortBy:  "lead_score",
+		Columns: []string{"title", "status", "lead_score"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Neue Leads", view.Name)
+	assert.Equal(t, "desc", view.SortOrder)
+	assert.JSONEq(t, `{"status":"neu","my_leads":"true"}`, string(view.Filters))
+	assert.JSONEq(t, `["title","status","lead_score"]`, string(view.Columns))
+	assert.True(t, view.IsOwner)
+	assert.False(t, view.IsShared)
+
+	updated, err := service.Update(view.ID, berater, Input{Name: "Neue Leads", SortBy: "created_at", SortOrder: "asc"})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{}`, string(updated.Filters))
+	assert.Equal(t, "asc", updated.SortOrder)
+
+	tests := []struct {
+		name  string
+		input Input
+		err   error
+	}{
+		{"unknown filter", Input{Name: "A", Filters: map[string]string{"password": "x"}}, ErrInvalidConfiguration},
+		{"unknown sort field", Input{Name: "A", SortBy: "deleted_at"}, ErrInvalidConfiguration},
+		{"repeated column", Input{Name: "A", Columns: []string{"title", "title"}}, ErrInvalidConfiguration},
+		{"booking column on leads", Input{Name: "A", Columns: []string{"scheduled_at"}}, ErrInvalidConfiguration},
+		{"name taken", Input{Name: "neue leads"}, ErrNameTaken},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := service.Create(berater, models.SavedViewResourceLeads, tt.input)
+			assert.ErrorIs(t, err, tt.err)
+		})
+	}
+
+	// The same name is fine for another list
+	_, err = service.Create(berater, models.SavedViewResourceBookings, Input{Name: "Neue Leads", SortBy: "scheduled_at"})
+	require.NoError(t, err)
+	_, err = service.Create(berater, "todos", Input{Name: "Offen"})
+	assert.ErrorIs(t, err, ErrUnknownResource)
+}
+
+func TestShare(t *testing.T) {
+	db, service := setupTestService(t)
+	berater, junior, customer := createViewer(t, db, models.RoleBerater), createViewer(t, db, models.RoleJuniorBerater), createViewer(t, db, models.RoleUser)
+
+	view, err := service.Create(berater, models.SavedViewResourceLeads, Input{Name: "Heiße Leads", SortBy: "lead_score"})
+	require.NoError(t, err)
+	_, err = service.Create(junior, models.SavedViewResourceLeads, Input{Name: "Meine Leads"})
+	require.NoError(t, err)
+
+	views, err := service.List(junior, models.SavedViewResourceLeads)
+	require.NoError(t, err)
+	assert.Len(t, views, 1)
+
+	_, err = service.Share(view.ID, junior, true)
+	assert.ErrorIs(t, err, ErrNotFound)
+	shared, err := service.Share(view.ID, berater, true)
+	require.NoError(t, err)
+	assert.True(t, shared.IsShared)
+
+	views, err = service.List(junior, models.SavedViewResourceLeads)
+	require.NoError(t, err)
+	require.Len(t, views, 2)
+	assert.Equal(t, "Heiße Leads", views[0].Name)
+	assert.False(t, views[0].IsOwner)
+	assert.Equal(t, berater.ID, views[0].User.ID)
+
+	// Only the owner changes a shared view, customers never see it
+	_, err = service.Update(view.ID, junior, Input{Name: "Übernommen"})
+	assert.ErrorIs(t, err, ErrNotOwner)
+	assert.ErrorIs(t, service.Delete(view.ID, junior), ErrNotOwner)
+	views, err = service.List(customer, models.SavedViewResourceLeads)
+	require.NoError(t, err)
+	assert.Empty(t, views)
+
+	own, err := service.Create(customer, models.SavedViewResourceBookings, Input{Name: "Anstehend"})
+	require.NoError(t, err)
+	_, err = service.Share(own.ID, customer, true)
+	assert.ErrorIs(t, err, ErrShareNotAllowed)
+}
+
+func TestDefaults(t *testing.T) {
+	db, service := setupTestService(t)
+	berater, junior := createViewer(t, db, models.RoleBerater), createViewer(t, db, models.RoleJuniorBerater)
+
+	shared, err := service.Create(berater, models.SavedViewResourceLeads, Input{Name: "Team"})
+	require.NoError(t, err)
+	_, err = service.Share(shared.ID, berater, true)
+	require.NoError(t, err)
+	own, err := service.Create(junior, models.SavedViewResourceLeads, Input{Name: "Eigene"})
+	require.NoError(t, err)
+
+	_, err = service.Default(junior, models.SavedViewResourceLeads)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	view, err := service.SetDefault(own.ID, junior)
+	require.NoError(t, err)
+	assert.True(t, view.IsDefault)
+
+	// Setting another default replaces the first one
+	_, err = service.SetDefault(shared.ID, junior)
+	require.NoError(t, err)
+	view, err = service.Default(junior, models.SavedViewResourceLeads)
+	require.NoError(t, err)
+	assert.Equal(t, shared.ID, view.ID)
+
+	views, err := service.List(junior, models.SavedViewResourceLeads)
+	require.NoError(t, err)
+	for _, v := range views {
+		assert.Equal(t, v.ID == shared.ID, v.IsDefault, v.Name)
+	}
+
+	// Taking the view back removes it as the default of others
+	_, err = service.SetDefault(shared.ID, berater)
+	require.NoError(t, err)
+	_, err = service.Share(shared.ID, berater, false)
+	require.NoError(t, err)
+	_, err = service.Default(junior, models.SavedViewResourceLeads)
+	assert.ErrorIs(t, err, ErrNotFound)
+	view, err = service.Default(berater, models.SavedViewResourceLeads)
+	require.NoError(t, err)
+	assert.Equal(t, shared.ID, view.ID)
+
+	// Deleting a view removes it as a default
+	require.NoError(t, service.Delete(shared.ID, berater))
+	_, err = service.Default(berater, models.SavedViewResourceLeads)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	_, err = service.SetDefault(own.ID, junior)
+	require.NoError(t, err)
+	require.NoError(t, service.ClearDefault(junior, models.SavedViewResourceLeads))
+	_, err = service.Default(junior, models.SavedViewResourceLeads)
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func setupTestService(t *testing.T) (*gorm.DB, *Service) {
+	t.Helper()
+	db := testutils.NewSQLiteDB(t,
+		&models.User{},
+		&models.SavedView{},
+		&models.SavedViewDefault{},
+	)
+
+	return db, NewService(db, zap.NewNop())
+}
+
+func createViewer(t *testing.T, db *gorm.DB, role models.UserRole) scopes.Viewer {
+	t.Helper()
+	user := testutils.CreateTestUser(t, db, role)
+	return scopes.Viewer{ID: user.ID, Role: user.Role}
+}
@@ -0,0 +1,97 @@
+package scenarios
+
+import (
+	"fmt"
+
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/internal/scopes"
+
+	"github.com/google/uuid"
+)
+
+// ComparisonMonth holds the family's Elterngeld of a Lebensmonat in each
+// scenario, in the order of the comparison
+type ComparisonMonth struct {
+	Lebensmonat int       `json:"lebensmonat"`
+	Totals      []float64 `json:"totals"`
+}
+
+// Comparison lines up scenarios side by side
+type Comparison struct {
+	Scenarios []Scenario        `json:"scenarios"`
+	Months    []ComparisonMonth `json:"months"`
+	// Scenarios with the highest total and the longest duration; ties go to
+	// the earlier scenario
+	HighestTotalID    *uuid.UUID `json:"highest_total_id,omitempty"`
+	LongestDurationID *uuid.UUID `json:"longest_duration_id,omitempty"`
+}
+
+// Compare calculates scenarios of a lead side by side, in the given order. Without
+// IDs all scenarios of the lead are compared.
+func (s *Service) Compare(leadID uuid.UUID, viewer scopes.Viewer, scenarioIDs []uuid.UUID) (*Comparison, error) {
+	if _, err := s.lead(s.db, leadID, scopes.VisibleLeads(viewer)); err != nil {
+		return nil, err
+	}
+
+	var records []models.ElterngeldScenario
+	query := s.db.Where("lead_id = ?", leadID).Order("created_at ASC")
+	if len(scenarioIDs) > 0 {
+		query = query.Where("id IN ?", scenarioIDs)
+	}
+	if err := query.Find(&records).Error; err != nil {
+		return nil, fmt.Errorf("failed to load scenarios: %w", err)
+	}
+
+	if len(scenarioIDs) > 0 {
+		byID := make(map[uuid.UUID]models.ElterngeldScenario, len(records))
+		for _, record := range records {
+			byID[record.ID] = record
+		}
+		ordered := make([]models.ElterngeldScenario, 0, len(scenarioIDs))
+		seen := map[uuid.UUID]bool{}
+		for _, id := range scenarioIDs {
+			record, ok := byID[id]
+			if !ok {
+				return nil, ErrNotFound
+			}
+			if !seen[id] {
+				seen[id] = true
+				ordered = append(ordered, record)
+			}
+		}
+		records = ordered
+	}
+
+	scenarios, err := s.calculate(records)
+	if err != nil {
+		return nil, err
+	}
+
+	comparison := &Comparison{Scenarios: scenarios, Months: []ComparisonMonth{}}
+	highest, longest, duration := -1, -1, 0
+	for i, scenario := range scenarios {
+		if highest < 0 || scenario.Result.Total > scenarios[highest].Result.Total {
+			highest = i
+		}
+		if longest < 0 || scenario.Result.Duration > scenarios[longest].Result.Duration {
+			longest = i
+		}
+		duration = max(duration, scenario.Result.Duration)
+	}
+	if len(scenarios) > 0 {
+		comparison.HighestTotalID = &scenarios[highest].ID
+		comparison.LongestDurationID = &scenarios[longest].ID
+	}
+
+	for lebensmonat := 1; lebensmonat <= duration; lebensmonat++ {
+		month := ComparisonMonth{Lebensmonat: lebensmonat, Totals: make([]float64, len(scenarios))}
+		for i, scenario := range scenarios {
+			if lebensmonat <= len(scenario.Result.Months) {
+				month.Totals[i] = scenario.Result.Months[lebensmonat-1].Total
+			}
+		}
+		comparison.Months = append(comparison.Months, month)
+	}
+
+	return comparison, nil
+}
@@ -0,0 +1,304 @@
+package scenarios
+
+import (
+	"fmt"
+	"sort"
+
+	"elterngeld-portal/internal/calculator"
+	"elterngeld-portal/internal/models"
+)
+
+// Limits of the Elterngeld months (§ 4 BEEG)
+const (
+	maxLebensmonat       = 32 // ElterngeldPlus and Partnerschaftsbonus end with the 32nd Lebensmonat
+	maxBasisLebensmonat  = 14 // Basiselterngeld ends with the 14th Lebensmonat
+	maxSimultaneousBasis = 12 // parents may take Basiselterngeld together once, within the first 12 Lebensmonate
+	parentMonths         = 12 // Basiselterngeld months of one parent
+	familyMonths         = 14 // including the two Partnermonate when both parents take Elterngeld
+	minParentMonths      = 2
+	minPartnerBonus      = 2
+	maxPartnerBonus      = 4
+	minPartnerBonusHours = 24
+)
+
+// Warning names a rule of the BEEG a plan breaks. Plans with warnings are
+// still calculated, so parents can compare them, but cannot be applied for as is.
+type Warning string
+
+const (
+	WarningBasisAfterLebensmonat14 Warning = "basis_after_lebensmonat_14"
+	WarningTooManyMonths           Warning = "too_many_months"
+	WarningMinimumMonths           Warning = "minimum_two_months"
+	WarningSimultaneousBasis       Warning = "simultaneous_basis"
+	WarningPartnerBonus            Warning = "partner_bonus_rules"
+	WarningInterrupted             Warning = "interrupted_after_lebensmonat_14"
+	WarningHourLimit               Warning = "hour_limit"
+)
+
+// ParentResult sums up the Elterngeld of a parent
+type ParentResult struct {
+	Name               string  `json:"name"`
+	BasisMonths        int     `json:"basis_months"`
+	PlusMonths         int     `json:"plus_months"`
+	PartnerBonusMonths int     `json:"partner_bonus_months"`
+	Total              float64 `json:"total"`
+}
+
+// MonthAmount is the Elterngeld of a parent in a Lebensmonat; Type is empty
+// when the parent takes none
+type MonthAmount struct {
+	Type   models.ScenarioMonthType `json:"type,omitempty"`
+	Amount float64                  `json:"amount"`
+}
+
+// MonthResult is the Elterngeld of the family in a Lebensmonat, with the
+// amounts of the parents in the order of the plan
+type MonthResult struct {
+	Lebensmonat int           `json:"lebensmonat"`
+	Parents     []MonthAmount `json:"parents"`
+	Total       float64       `json:"total"`
+}
+
+// Result is the calculated Elterngeld of a plan
+type Result struct {
+	Total    float64        `json:"total"`
+	Duration int            `json:"duration"` // last Lebensmonat with Elterngeld
+	Parents  []ParentResult `json:"parents"`
+	Months   []MonthResult  `json:"months"`
+	Warnings []Warning      `json:"warnings"`
+}
+
+// Evaluate calculates the Elterngeld of every Lebensmonat of a plan and checks it
+// against the rules for splitting the months. Mutterschaftsgeld, Geschwisterbonus
+// and Mehrlingszuschlag are not included. Plans that cannot be calculated, e.g.
+// with unknown month types, return ErrInvalidPlan.
+func (s *Service) Evaluate(plan models.ScenarioPlan) (*Result, error) {
+	if err := validatePlan(plan); err != nil {
+		return nil, err
+	}
+
+	result := &Result{
+		Parents:  make([]ParentResult, len(plan.Parents)),
+		Months:   []MonthResult{},
+		Warnings: []Warning{},
+	}
+	// byMonth[lebensmonat][parent] is the month a parent takes in a Lebensmonat
+	byMonth := map[int][]*models.ScenarioMonth{}
+	warned := map[Warning]bool{}
+	warn := func(warning Warning) {
+		if !warned[warning] {
+			warned[warning] = true
+			result.Warnings = append(result.Warnings, warning)
+		}
+	}
+
+	for i, parent := range plan.Parents {
+		result.Parents[i].Name = parent.Name
+		for j := range parent.Months {
+			month := &parent.Months[j]
+			if byMonth[month.Lebensmonat] == nil {
+				byMonth[month.Lebensmonat] = make([]*models.ScenarioMonth, len(plan.Parents))
+			}
+			byMonth[month.Lebensmonat][i] = month
+			if month.Lebensmonat > result.Duration {
+				result.Duration = month.Lebensmonat
+			}
+
+			switch month.Type {
+			case models.ScenarioMonthBasis:
+				result.Parents[i].BasisMonths++
+				if month.Lebensmonat > maxBasisLebensmonat {
+					warn(WarningBasisAfterLebensmonat14)
+				}
+			case models.ScenarioMonthPlus:
+				result.Parents[i].PlusMonths++
+			case models.ScenarioMonthPartnerBonus:
+				result.Parents[i].PartnerBonusMonths++
+			}
+			if month.WeeklyHours > calculator.MaxWeeklyHours {
+				warn(WarningHourLimit)
+			}
+		}
+	}
+
+	for lebensmonat := 1; lebensmonat <= result.Duration; lebensmonat++ {
+		row := MonthResult{Lebensmonat: lebensmonat, Parents: make([]MonthAmount, len(plan.Parents))}
+		for i, month := range byMonth[lebensmonat] {
+			if month == nil {
+				continue
+			}
+			amounts, err := s.calculator.PartTime(calculator.PartTimeInput{
+				IncomeBefore: plan.Parents[i].IncomeBefore,
+				IncomeAfter:  month.IncomeAfter,
+				WeeklyHours:  month.WeeklyHours,
+			})
+			if err != nil {
+				return nil, fmt.Errorf("%w: %v", ErrInvalidPlan, err)
+			}
+			amount := amounts.ElterngeldPlus
+			if month.Type == models.ScenarioMonthBasis {
+				amount = amounts.Basis
+			}
+			row.Parents[i] = MonthAmount{Type: month.Type, Amount: amount}
+			row.Total += amount
+			result.Parents[i].Total += amount
+		}
+		row.Total = calculator.RoundCents(row.Total)
+		result.Total += row.Total
+		result.Months = append(result.Months, row)
+	}
+	result.Total = calculator.RoundCents(result.Total)
+	for i := range result.Parents {
+		result.Parents[i].Total = calculator.RoundCents(result.Parents[i].Total)
+	}
+
+	for _, warning := range checkMonths(plan, result, byMonth) {
+		warn(warning)
+	}
+	return result, nil
+}
+
+// checkMonths checks how the parents split the months
+func checkMonths(plan models.ScenarioPlan, result *Result, byMonth map[int][]*models.ScenarioMonth) []Warning {
+	var warnings []Warning
+
+	// Each ElterngeldPlus month uses half a Basiselterngeld month; the
+	// Partnerschaftsbonus comes on top
+	claiming := 0
+	familyUsed := 0.0
+	tooMany := false
+	for _, parent := range result.Parents {
+		used := float64(parent.BasisMonths) + float64(parent.PlusMonths)/2
+		familyUsed += used
+		months := parent.BasisMonths + parent.PlusMonths + parent.PartnerBonusMonths
+		if months > 0 {
+			claiming++
+		}
+		if months > 0 && months < minParentMonths {
+			warnings = append(warnings, WarningMinimumMonths)
+		}
+		limit := float64(parentMonths)
+		if plan.SingleParent {
+			limit = familyMonths
+		}
+		if used > limit {
+			tooMany = true
+		}
+	}
+	limit := float64(parentMonths)
+	if plan.SingleParent || claiming == 2 {
+		limit = familyMonths
+	}
+	if tooMany || familyUsed > limit {
+		warnings = append(warnings, WarningTooManyMonths)
+	}
+
+	// Parents may take Basiselterngeld together for one month within the first 12
+	simultaneous := 0
+	lebensmonate := make([]int, 0, len(byMonth))
+	for lebensmonat := range byMonth {
+		lebensmonate = append(lebensmonate, lebensmonat)
+	}
+	sort.Ints(lebensmonate)
+	for _, lebensmonat := range lebensmonate {
+		months := byMonth[lebensmonat]
+		if len(months) == 2 && months[0] != nil && months[1] != nil &&
+			months[0].Type == models.ScenarioMonthBasis && months[1].Type == models.ScenarioMonthBasis {
+			simultaneous++
+			if simultaneous > 1 || lebensmonat > maxSimultaneousBasis {
+				warnings = append(warnings, WarningSimultaneousBasis)
+				break
+			}
+		}
+	}
+
+	if !validPartnerBonus(plan, result, byMonth) {
+		warnings = append(warnings, WarningPartnerBonus)
+	}
+
+	// After the 14th Lebensmonat Elterngeld is only paid without interruption
+	for lebensmonat := maxBasisLebensmonat + 1; lebensmonat <= result.Duration; lebensmonat++ {
+		if !claimed(byMonth[lebensmonat]) {
+			warnings = append(warnings, WarningInterrupted)
+			break
+		}
+	}
+
+	return warnings
+}
+
+// validPartnerBonus checks that the Partnerschaftsbonus is taken for 2 to 4
+// consecutive Lebensmonate, by both parents at the same time (single parents
+// alone), working 24 to 32 hours a week
+func validPartnerBonus(plan models.ScenarioPlan, result *Result, byMonth map[int][]*models.ScenarioMonth) bool {
+	var bonusMonths []int
+	for lebensmonat := 1; lebensmonat <= result.Duration; lebensmonat++ {
+		takers := 0
+		for _, month := range byMonth[lebensmonat] {
+			if month == nil || month.Type != models.ScenarioMonthPartnerBonus {
+				continue
+			}
+			takers++
+			if month.WeeklyHours < minPartnerBonusHours || month.WeeklyHours > calculator.MaxWeeklyHours {
+				return false
+			}
+		}
+		if takers == 0 {
+			continue
+		}
+		if !plan.SingleParent && takers != 2 {
+			return false
+		}
+		bonusMonths = append(bonusMonths, lebensmonat)
+	}
+
+	if len(bonusMonths) == 0 {
+		return true
+	}
+	if len(bonusMonths) < minPartnerBonus || len(bonusMonths) > maxPartnerBonus {
+		return false
+	}
+	return bonusMonths[len(bonusMonths)-1]-bonusMonths[0] == len(bonusMonths)-1
+}
+
+func claimed(months []*models.ScenarioMonth) bool {
+	for _, month := range months {
+		if month != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// validatePlan rejects plans that cannot be calculated
+func validatePlan(plan models.ScenarioPlan) error {
+	if len(plan.Parents) == 0 || len(plan.Parents) > 2 {
+		return fmt.Errorf("%w: a plan needs one or two parents", ErrInvalidPlan)
+	}
+	if plan.SingleParent && len(plan.Parents) != 1 {
+		return fmt.Errorf("%w: single parents plan alone", ErrInvalidPlan)
+	}
+
+	for _, parent := range plan.Parents {
+		if parent.IncomeBefore < 0 {
+			return fmt.Errorf("%w: negative income", ErrInvalidPlan)
+		}
+		seen := map[int]bool{}
+		for _, month := range parent.Months {
+			if month.Lebensmonat < 1 || month.Lebensmonat > maxLebensmonat {
+				return fmt.Errorf("%w: Lebensmonat %d is out of range", ErrInvalidPlan, month.Lebensmonat)
+			}
+			if seen[month.Lebensmonat] {
+				return fmt.Errorf("%w: Lebensmonat %d is planned twice", ErrInvalidPlan, month.Lebensmonat)
+			}
+			seen[month.Lebensmonat] = true
+			if !month.Type.IsValid() {
+				return fmt.Errorf("%w: unknown month type %q", ErrInvalidPlan, month.Type)
+			}
+			if month.IncomeAfter < 0 || month.WeeklyHours < 0 || month.WeeklyHours > 168 {
+				return fmt.Errorf("%w: invalid income or working hours in Lebensmonat %d", ErrInvalidPlan, month.Lebensmonat)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,227 @@
+package scenarios
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"elterngeld-portal/internal/calculator"
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/internal/scopes"
+	"elterngeld-portal/pkg/i18n"
+	"elterngeld-portal/pkg/pdf"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// PayoutMonth is a Lebensmonat in which a parent is paid Elterngeld. From and
+// To are only set once the child's birth date is known; Elterngeld is paid at
+// the end of the Lebensmonat.
+type PayoutMonth struct {
+	Lebensmonat int                      `json:"lebensmonat"`
+	From        string                   `json:"from,omitempty"`
+	To          string                   `json:"to,omitempty"`
+	Type        models.ScenarioMonthType `json:"type"`
+	Amount      float64                  `json:"amount"`
+}
+
+// PayoutParent lists the payouts of a parent
+type PayoutParent struct {
+	Name   string        `json:"name"`
+	Total  float64       `json:"total"`
+	Months []PayoutMonth `json:"months"`
+}
+
+// PayoutPlan is the Bezugsplan of the chosen scenario: which parent is paid
+// how much in which Lebensmonat
+type PayoutPlan struct {
+	LeadID         uuid.UUID      `json:"lead_id"`
+	ScenarioID     uuid.UUID      `json:"scenario_id"`
+	ScenarioName   string         `json:"scenario_name"`
+	ChildBirthDate string         `json:"child_birth_date,omitempty"`
+	Total          float64        `json:"total"`
+	Duration       int            `json:"duration"`
+	PartnerMonths  float64        `json:"partner_months"` // of the two Partnermonate, counted in Basiselterngeld months
+	Parents        []PayoutParent `json:"parents"`
+	Warnings       []Warning      `json:"warnings"`
+}
+
+// PayoutPlan returns the Bezugsplan of the lead's chosen scenario
+func (s *Service) PayoutPlan(leadID uuid.UUID, viewer scopes.Viewer) (*PayoutPlan, error) {
+	lead, err := s.lead(s.db, leadID, scopes.VisibleLeads(viewer))
+	if err != nil {
+		return nil, err
+	}
+	scenario, err := s.chosen(leadID)
+	if err != nil {
+		return nil, err
+	}
+	return payoutPlan(lead, scenario), nil
+}
+
+// SharePayoutPlan tells the customer that the Bezugsplan of the chosen scenario
+// is available in their dashboard
+func (s *Service) SharePayoutPlan(leadID uuid.UUID, viewer scopes.Viewer) (*PayoutPlan, error) {
+	lead, err := s.lead(s.db, leadID, scopes.EditableLeads(viewer))
+	if err != nil {
+		return nil, err
+	}
+	scenario, err := s.chosen(leadID)
+	if err != nil {
+		return nil, err
+	}
+
+	var customer models.User
+	if err := s.db.First(&customer, "id = ?", lead.UserID).Error; err != nil {
+		return nil, fmt.Errorf("failed to load customer: %w", err)
+	}
+
+	data, _ := json.Marshal(map[string]interface{}{
+		"lead_id":     lead.ID,
+		"scenario_id": scenario.ID,
+	})
+	notification := models.Notification{
+		UserID:    customer.ID,
+		Type:      models.NotificationTypeInApp,
+		Title:     "Ihr Elterngeld-Bezugsplan ist verfügbar",
+		Message:   fmt.Sprintf("Der Bezugsplan für '%s' steht in Ihrem Dashboard als PDF bereit.", scenario.Name),
+		Data:      string(data),
+		Recipient: customer.NotificationAddress(),
+	}
+	if err := s.db.Create(&notification).Error; err != nil {
+		return nil, fmt.Errorf("failed to create payout plan notification: %w", err)
+	}
+
+	s.logger.Info("Payout plan shared",
+		zap.String("lead_id", lead.ID.String()),
+		zap.String("scenario_id", scenario.ID.String()),
+		zap.String("shared_by", viewer.ID.String()))
+
+	return payoutPlan(lead, scenario), nil
+}
+
+// chosen loads and calculates the chosen scenario of a lead
+func (s *Service) chosen(leadID uuid.UUID) (*Scenario, error) {
+	var record models.ElterngeldScenario
+	if err := s.db.Where("lead_id = ? AND chosen_at IS NOT NULL", leadID).First(&record).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNoneChosen
+		}
+		return nil, fmt.Errorf("failed to load chosen scenario: %w", err)
+	}
+	return s.scenario(&record)
+}
+
+func payoutPlan(lead *models.Lead, scenario *Scenario) *PayoutPlan {
+	result := scenario.Result
+	plan := &PayoutPlan{
+		LeadID:       lead.ID,
+		ScenarioID:   scenario.ID,
+		ScenarioName: scenario.Name,
+		Total:        result.Total,
+		Duration:     result.Duration,
+		Parents:      make([]PayoutParent, len(result.Parents)),
+		Warnings:     result.Warnings,
+	}
+
+	var birth time.Time
+	if lead.ChildBirthDate != nil {
+		birth = time.Date(lead.ChildBirthDate.Year(), lead.ChildBirthDate.Month(), lead.ChildBirthDate.Day(), 0, 0, 0, 0, time.UTC)
+		plan.ChildBirthDate = birth.Format("2006-01-02")
+	}
+
+	for i, parent := range result.Parents {
+		plan.Parents[i] = PayoutParent{Name: parent.Name, Total: parent.Total, Months: []PayoutMonth{}}
+	}
+	for _, row := range result.Months {
+		for i, amount := range row.Parents {
+			if amount.Type == "" {
+				continue
+			}
+			month := PayoutMonth{Lebensmonat: row.Lebensmonat, Type: amount.Type, Amount: amount.Amount}
+			if !birth.IsZero() {
+				from, to := calculator.LebensmonatRange(birth, row.Lebensmonat)
+				month.From = from.Format("2006-01-02")
+				month.To = to.Format("2006-01-02")
+			}
+			plan.Parents[i].Months = append(plan.Parents[i].Months, month)
+		}
+	}
+
+	// The Partnermonate are the months beyond the 12 of one parent when both
+	// parents take Elterngeld
+	if len(result.Parents) == 2 && !scenario.Plan.SingleParent {
+		used := 0.0
+		for _, parent := range result.Parents {
+			used += float64(parent.BasisMonths) + float64(parent.PlusMonths)/2
+		}
+		plan.PartnerMonths = math.Min(math.Max(used-parentMonths, 0), familyMonths-parentMonths)
+	}
+
+	return plan
+}
+
+var monthTypeNames = map[models.ScenarioMonthType]string{
+	models.ScenarioMonthBasis:        "Basiselterngeld",
+	models.ScenarioMonthPlus:         "ElterngeldPlus",
+	models.ScenarioMonthPartnerBonus: "Partnerschaftsbonus",
+}
+
+// RenderPDF renders the Bezugsplan for the customer
+func RenderPDF(plan *PayoutPlan) []byte {
+	price := func(amount float64) string {
+		return i18n.FormatCurrency(i18n.DefaultLanguage, amount, "EUR")
+	}
+	day := func(value string) string {
+		parsed, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			return value
+		}
+		return parsed.Format("02.01.2006")
+	}
+
+	doc := pdf.New()
+	doc.SetFooter("Elterngeld-Bezugsplan · Elterngeld-Portal")
+	doc.Title("Elterngeld-Bezugsplan")
+	doc.Field("Szenario", plan.ScenarioName)
+	if plan.ChildBirthDate != "" {
+		doc.Field("Geburt des Kindes", day(plan.ChildBirthDate))
+	}
+	doc.Field("Elterngeld gesamt", price(plan.Total))
+	doc.Field("Bezugsdauer", fmt.Sprintf("%d Lebensmonate", plan.Duration))
+	if plan.PartnerMonths > 0 {
+		doc.Field("Partnermonate", fmt.Sprintf("%g von 2", plan.PartnerMonths))
+	}
+
+	for _, parent := range plan.Parents {
+		heading := parent.Name
+		if heading == "" {
+			heading = "Elternteil"
+		}
+		doc.Heading(heading)
+		if len(parent.Months) == 0 {
+			doc.Text("Kein Elterngeld geplant.")
+			continue
+		}
+		for _, month := range parent.Months {
+			label := fmt.Sprintf("%d. Lebensmonat", month.Lebensmonat)
+			if month.From != "" {
+				label += fmt.Sprintf(" (%s – %s)", day(month.From), day(month.To))
+			}
+			doc.Field(label, fmt.Sprintf("%s, %s", monthTypeNames[month.Type], price(month.Amount)))
+		}
+		doc.Field("Summe", price(parent.Total))
+	}
+
+	doc.Space()
+	doc.Text("Das Elterngeld wird jeweils am Ende des Lebensmonats ausgezahlt. Die Beträge sind eine Berechnung auf Grundlage Ihrer Angaben; maßgeblich ist der Bescheid der Elterngeldstelle.")
+	if len(plan.Warnings) > 0 {
+		doc.Text("Hinweis: Die Aufteilung der Monate entspricht noch nicht allen Regeln des BEEG und sollte vor dem Antrag mit Ihrer Beraterin oder Ihrem Berater abgestimmt werden.")
+	}
+
+	return doc.Bytes()
+}
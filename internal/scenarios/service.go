@@ -0,0 +1,292 @@
+// Package scenarios stores Elterngeld plans of a lead, e.g. "Mutter 12 Monate
+// Basis" against "7+7 Plus mit Partnerbonus", calculates them with the
+// calculator package and compares them side by side. The scenario the family
+// chooses is the one their application is prepared with; its Bezugsplan lists
+// the payouts per Lebensmonat and is shared with the customer as PDF.
+package scenarios
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"elterngeld-portal/internal/calculator"
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/internal/scopes"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrLeadNotFound is returned when the lead does not exist or the viewer may not access it
+	ErrLeadNotFound = errors.New("lead not found")
+	// ErrNotFound is returned when the scenario does not exist on the lead
+	ErrNotFound = errors.New("scenario not found")
+	// ErrNoneChosen is returned when no scenario of the lead was chosen yet
+	ErrNoneChosen = errors.New("no scenario chosen")
+	// ErrInvalidPlan is returned for plans that cannot be calculated
+	ErrInvalidPlan = errors.New("invalid scenario plan")
+	// ErrTooManyScenarios is returned when a lead already has MaxScenarios scenarios
+	ErrTooManyScenarios = errors.New("too many scenarios")
+)
+
+// MaxScenarios is the number of scenarios a lead can have
+const MaxScenarios = 10
+
+// Input is a named plan
+type Input struct {
+	Name string              `json:"name" binding:"required,max=100"`
+	Plan models.ScenarioPlan `json:"plan"`
+}
+
+// Scenario is a stored plan with its calculation
+type Scenario struct {
+	ID          uuid.UUID           `json:"id"`
+	LeadID      uuid.UUID           `json:"lead_id"`
+	Name        string              `json:"name"`
+	Plan        models.ScenarioPlan `json:"plan"`
+	Chosen      bool                `json:"chosen"`
+	ChosenAt    *time.Time          `json:"chosen_at,omitempty"`
+	CreatedByID uuid.UUID           `json:"created_by_id"`
+	CreatedAt   time.Time           `json:"created_at"`
+	UpdatedAt   time.Time           `json:"updated_at"`
+	Result      *Result             `json:"result"`
+}
+
+// Service manages the scenarios of leads
+type Service struct {
+	db         *gorm.DB
+	logger     *zap.Logger
+	calculator *calculator.Service
+	now        func() time.Time
+}
+
+func NewService(db *gorm.DB, logger *zap.Logger, calculatorService *calculator.Service) *Service {
+	return &Service{
+		db:         db,
+		logger:     logger,
+		calculator: calculatorService,
+		now:        time.Now,
+	}
+}
+
+// List returns the scenarios of a lead, oldest first
+func (s *Service) List(leadID uuid.UUID, viewer scopes.Viewer) ([]Scenario, error) {
+	if _, err := s.lead(s.db, leadID, scopes.VisibleLeads(viewer)); err != nil {
+		return nil, err
+	}
+
+	var records []models.ElterngeldScenario
+	if err := s.db.Where("lead_id = ?", leadID).Order("created_at ASC").Find(&records).Error; err != nil {
+		return nil, fmt.Errorf("failed to load scenarios: %w", err)
+	}
+	return s.calculate(records)
+}
+
+// Create adds a scenario to a lead
+func (s *Service) Create(leadID uuid.UUID, viewer scopes.Viewer, input Input) (*Scenario, error) {
+	if _, err := s.Evaluate(input.Plan); err != nil {
+		return nil, err
+	}
+
+	record := &models.ElterngeldScenario{
+		LeadID:      leadID,
+		CreatedByID: viewer.ID,
+		Name:        strings.TrimSpace(input.Name),
+	}
+	if err := record.SetPlan(input.Plan); err != nil {
+		return nil, fmt.Errorf("failed to encode plan: %w", err)
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if _, err := s.lead(tx, leadID, scopes.EditableLeads(viewer)); err != nil {
+			return err
+		}
+		var count int64
+		if err := tx.Model(&models.ElterngeldScenario{}).Where("lead_id = ?", leadID).Count(&count).Error; err != nil {
+			return fmt.Errorf("failed to count scenarios: %w", err)
+		}
+		if count >= MaxScenarios {
+			return ErrTooManyScenarios
+		}
+		if err := tx.Create(record).Error; err != nil {
+			return fmt.Errorf("failed to create scenario: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.scenario(record)
+}
+
+// Update renames a scenario and replaces its plan. The lead's expected amount
+// follows the plan of the chosen scenario.
+func (s *Service) Update(leadID, scenarioID uuid.UUID, viewer scopes.Viewer, input Input) (*Scenario, error) {
+	result, err := s.Evaluate(input.Plan)
+	if err != nil {
+		return nil, err
+	}
+
+	var record models.ElterngeldScenario
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if _, err := s.lead(tx, leadID, scopes.EditableLeads(viewer)); err != nil {
+			return err
+		}
+		if err := s.find(tx, leadID, scenarioID, &record); err != nil {
+			return err
+		}
+
+		record.Name = strings.TrimSpace(input.Name)
+		if err := record.SetPlan(input.Plan); err != nil {
+			return fmt.Errorf("failed to encode plan: %w", err)
+		}
+		if err := tx.Save(&record).Error; err != nil {
+			return fmt.Errorf("failed to update scenario: %w", err)
+		}
+		if record.IsChosen() {
+			return s.setExpectedAmount(tx, leadID, result.Total)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.scenario(&record)
+}
+
+// Delete removes a scenario. Deleting the chosen scenario leaves the lead
+// without one; its expected amount is kept.
+func (s *Service) Delete(leadID, scenarioID uuid.UUID, viewer scopes.Viewer) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if _, err := s.lead(tx, leadID, scopes.EditableLeads(viewer)); err != nil {
+			return err
+		}
+		var record models.ElterngeldScenario
+		if err := s.find(tx, leadID, scenarioID, &record); err != nil {
+			return err
+		}
+		if err := tx.Delete(&record).Error; err != nil {
+			return fmt.Errorf("failed to delete scenario: %w", err)
+		}
+		return nil
+	})
+}
+
+// Choose marks a scenario as the one to apply with, replacing an earlier
+// choice, and sets the lead's expected amount to its total
+func (s *Service) Choose(leadID, scenarioID uuid.UUID, viewer scopes.Viewer) (*Scenario, error) {
+	var record models.ElterngeldScenario
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if _, err := s.lead(tx, leadID, scopes.EditableLeads(viewer)); err != nil {
+			return err
+		}
+		if err := s.find(tx, leadID, scenarioID, &record); err != nil {
+			return err
+		}
+		plan, err := record.GetPlan()
+		if err != nil {
+			return fmt.Errorf("failed to decode plan: %w", err)
+		}
+		result, err := s.Evaluate(plan)
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Model(&models.ElterngeldScenario{}).
+			Where("lead_id = ? AND id <> ? AND chosen_at IS NOT NULL", leadID, scenarioID).
+			Update("chosen_at", nil).Error; err != nil {
+			return fmt.Errorf("failed to reset chosen scenario: %w", err)
+		}
+		now := s.now()
+		record.ChosenAt = &now
+		if err := tx.Model(&record).Update("chosen_at", now).Error; err != nil {
+			return fmt.Errorf("failed to choose scenario: %w", err)
+		}
+		return s.setExpectedAmount(tx, leadID, result.Total)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.scenario(&record)
+}
+
+// Chosen returns the scenario the lead's application is prepared with
+func (s *Service) Chosen(leadID uuid.UUID, viewer scopes.Viewer) (*Scenario, error) {
+	if _, err := s.lead(s.db, leadID, scopes.VisibleLeads(viewer)); err != nil {
+		return nil, err
+	}
+	return s.chosen(leadID)
+}
+
+// lead loads a lead the scope allows
+func (s *Service) lead(tx *gorm.DB, leadID uuid.UUID, scope func(*gorm.DB) *gorm.DB) (*models.Lead, error) {
+	var lead models.Lead
+	if err := tx.Scopes(scope).Select("leads.id", "leads.user_id", "leads.child_birth_date").First(&lead, "leads.id = ?", leadID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrLeadNotFound
+		}
+		return nil, fmt.Errorf("failed to load lead: %w", err)
+	}
+	return &lead, nil
+}
+
+func (s *Service) find(tx *gorm.DB, leadID, scenarioID uuid.UUID, record *models.ElterngeldScenario) error {
+	if err := tx.Where("id = ? AND lead_id = ?", scenarioID, leadID).First(record).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrNotFound
+		}
+		return fmt.Errorf("failed to load scenario: %w", err)
+	}
+	return nil
+}
+
+func (s *Service) setExpectedAmount(tx *gorm.DB, leadID uuid.UUID, amount float64) error {
+	if err := tx.Model(&models.Lead{}).Where("id = ?", leadID).Update("expected_amount", amount).Error; err != nil {
+		return fmt.Errorf("failed to update expected amount: %w", err)
+	}
+	return nil
+}
+
+func (s *Service) calculate(records []models.ElterngeldScenario) ([]Scenario, error) {
+	result := make([]Scenario, 0, len(records))
+	for i := range records {
+		scenario, err := s.scenario(&records[i])
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, *scenario)
+	}
+	return result, nil
+}
+
+// scenario decodes and calculates a stored scenario
+func (s *Service) scenario(record *models.ElterngeldScenario) (*Scenario, error) {
+	plan, err := record.GetPlan()
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode plan of scenario %s: %w", record.ID, err)
+	}
+	result, err := s.Evaluate(plan)
+	if err != nil {
+		return nil, fmt.Errorf("failed to calculate scenario %s: %w", record.ID, err)
+	}
+
+	return &Scenario{
+		ID:          record.ID,
+		LeadID:      record.LeadID,
+		Name:        record.Name,
+		Plan:        plan,
+		Chosen:      record.IsChosen(),
+		ChosenAt:    record.ChosenAt,
+		CreatedByID: record.CreatedByID,
+		CreatedAt:   record.CreatedAt,
+		UpdatedAt:   record.UpdatedAt,
+		Result:      result,
+	}, nil
+}
@@ -0,0 +1,322 @@
+package scenarios
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+
+	"elterngeld-portal/internal/calculator"
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/internal/scopes"
+	"elterngeld-portal/tests/testutils"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// months plans consecutive Lebensmonate of one type
+func months(from, to int, monthType models.ScenarioMonthType, incomeAfter, weeklyHours float64) []models.ScenarioMonth {
+	var result []models.ScenarioMonth
+	for lebensmonat := from; lebensmonat <= to; lebensmonat++ {
+		result = append(result, models.ScenarioMonth{Lebensmonat: lebensmonat, Type: monthType, IncomeAfter: incomeAfter, WeeklyHours: weeklyHours})
+	}
+	return result
+}
+
+func motherOnly() models.ScenarioPlan {
+	return models.ScenarioPlan{Parents: []models.ScenarioParent{
+		{Name: "Mutter", IncomeBefore: 2000, Months: months(1, 12, models.ScenarioMonthBasis, 0, 0)},
+	}}
+}
+
+func sevenSevenWithBonus() models.ScenarioPlan {
+	return models.ScenarioPlan{Parents: []models.ScenarioParent{
+		{Name: "Mutter", IncomeBefore: 2000, Months: append(
+			months(1, 7, models.ScenarioMonthBasis, 0, 0),
+			months(15, 18, models.ScenarioMonthPartnerBonus, 1400, 28)...,
+		)},
+		{Name: "Vater", IncomeBefore: 3000, Months: append(
+			months(8, 14, models.ScenarioMonthBasis, 0, 0),
+			months(15, 18, models.ScenarioMonthPartnerBonus, 2000, 30)...,
+		)},
+	}}
+}
+
+func TestEvaluate(t *testing.T) {
+	_, service := setupTestService(t)
+
+	result, err := service.Evaluate(motherOnly())
+	require.NoError(t, err)
+	assert.Equal(t, 15600.0, result.Total)
+	assert.Equal(t, 12, result.Duration)
+	assert.Empty(t, result.Warnings)
+	assert.Equal(t, ParentResult{Name: "Mutter", BasisMonths: 12, Total: 15600}, result.Parents[0])
+
+	result, err = service.Evaluate(sevenSevenWithBonus())
+	require.NoError(t, err)
+	assert.Empty(t, result.Warnings)
+	assert.Equal(t, 18, result.Duration)
+	assert.Equal(t, 7*1300+7*1800+4*(390+500.5), result.Total)
+	assert.Equal(t, MonthResult{Lebensmonat: 15, Parents: []MonthAmount{
+		{Type: models.ScenarioMonthPartnerBonus, Amount: 390},
+		{Type: models.ScenarioMonthPartnerBonus, Amount: 500.5},
+	}, Total: 890.5}, result.Months[14])
+	assert.Equal(t, ParentResult{Name: "Vater", BasisMonths: 7, PartnerBonusMonths: 4, Total: 14602}, result.Parents[1])
+}
+
+func TestEvaluate_Warnings(t *testing.T) {
+	_, service := setupTestService(t)
+
+	for name, test := range map[string]struct {
+		plan     models.ScenarioPlan
+		expected []Warning
+	}{
+		"one parent takes more than 12 months": {
+			plan: models.ScenarioPlan{Parents: []models.ScenarioParent{
+				{IncomeBefore: 2000, Months: months(1, 13, models.ScenarioMonthBasis, 0, 0)},
+			}},
+			expected: []Warning{WarningTooManyMonths},
+		},
+		"single parents take 14 months": {
+			plan: models.ScenarioPlan{SingleParent: true, Parents: []models.ScenarioParent{
+				{IncomeBefore: 2000, Months: append(months(1, 12, models.ScenarioMonthBasis, 0, 0), months(13, 16, models.ScenarioMonthPlus, 0, 0)...)},
+			}},
+			expected: []Warning{},
+		},
+		"Basiselterngeld after the 14th Lebensmonat": {
+			plan: models.ScenarioPlan{Parents: []models.ScenarioParent{
+				{IncomeBefore: 2000, Months: months(14, 15, models.ScenarioMonthBasis, 0, 0)},
+			}},
+			expected: []Warning{WarningBasisAfterLebensmonat14},
+		},
+		"Basiselterngeld together twice": {
+			plan: models.ScenarioPlan{Parents: []models.ScenarioParent{
+				{IncomeBefore: 2000, Months: months(1, 10, models.ScenarioMonthBasis, 0, 0)},
+				{IncomeBefore: 2000, Months: months(1, 2, models.ScenarioMonthBasis, 0, 0)},
+			}},
+			expected: []Warning{WarningSimultaneousBasis},
+		},
+		"a single month": {
+			plan: models.ScenarioPlan{Parents: []models.ScenarioParent{
+				{IncomeBefore: 2000, Months: months(1, 12, models.ScenarioMonthBasis, 0, 0)},
+				{IncomeBefore: 2000, Months: months(13, 13, models.ScenarioMonthBasis, 0, 0)},
+			}},
+			expected: []Warning{WarningMinimumMonths},
+		},
+		"Partnerschaftsbonus alone": {
+			plan: models.ScenarioPlan{Parents: []models.ScenarioParent{
+				{IncomeBefore: 2000, Months: append(months(1, 10, models.ScenarioMonthBasis, 0, 0), months(11, 12, models.ScenarioMonthPartnerBonus, 1000, 25)...)},
+				{IncomeBefore: 2000, Months: months(11, 12, models.ScenarioMonthBasis, 0, 0)},
+			}},
+			expected: []Warning{WarningPartnerBonus},
+		},
+		"gap after the 14th Lebensmonat": {
+			plan: models.ScenarioPlan{Parents: []models.ScenarioParent{
+				{IncomeBefore: 2000, Months: append(months(1, 10, models.ScenarioMonthBasis, 0, 0), months(16, 17, models.ScenarioMonthPlus, 0, 0)...)},
+			}},
+			expected: []Warning{WarningInterrupted},
+		},
+		"working full-time": {
+			plan: models.ScenarioPlan{Parents: []models.ScenarioParent{
+				{IncomeBefore: 2000, Months: months(1, 2, models.ScenarioMonthPlus, 1800, 35)},
+			}},
+			expected: []Warning{WarningHourLimit},
+		},
+	} {
+		result, err := service.Evaluate(test.plan)
+		require.NoError(t, err, name)
+		assert.ElementsMatch(t, test.expected, result.Warnings, name)
+	}
+
+	// The hour limit leaves no Elterngeld
+	result, err := service.Evaluate(models.ScenarioPlan{Parents: []models.ScenarioParent{
+		{IncomeBefore: 2000, Months: months(1, 2, models.ScenarioMonthPlus, 1800, 35)},
+	}})
+	require.NoError(t, err)
+	assert.Equal(t, 0.0, result.Total)
+}
+
+func TestEvaluate_InvalidPlan(t *testing.T) {
+	_, service := setupTestService(t)
+	parent := models.ScenarioParent{IncomeBefore: 2000, Months: months(1, 2, models.ScenarioMonthBasis, 0, 0)}
+
+	for name, plan := range map[string]models.ScenarioPlan{
+		"no parents":       {},
+		"three parents":    {Parents: []models.ScenarioParent{parent, parent, parent}},
+		"two single":       {SingleParent: true, Parents: []models.ScenarioParent{parent, parent}},
+		"out of range":     {Parents: []models.ScenarioParent{{Months: months(33, 33, models.ScenarioMonthPlus, 0, 0)}}},
+		"planned twice":    {Parents: []models.ScenarioParent{{Months: append(months(1, 1, models.ScenarioMonthPlus, 0, 0), months(1, 1, models.ScenarioMonthBasis, 0, 0)...)}}},
+		"unknown type":     {Parents: []models.ScenarioParent{{Months: months(1, 1, "elternzeit", 0, 0)}}},
+		"negative income":  {Parents: []models.ScenarioParent{{IncomeBefore: -1, Months: parent.Months}}},
+		"impossible hours": {Parents: []models.ScenarioParent{{Months: months(1, 1, models.ScenarioMonthPlus, 0, 200)}}},
+	} {
+		_, err := service.Evaluate(plan)
+		assert.ErrorIs(t, err, ErrInvalidPlan, name)
+	}
+}
+
+func TestScenarios(t *testing.T) {
+	db, service := setupTestService(t)
+	customer := testutils.CreateTestUser(t, db, models.RoleUser)
+	berater := testutils.CreateTestUser(t, db, models.RoleBerater)
+	stranger := testutils.CreateTestUser(t, db, models.RoleUser)
+	lead := createTestLead(t, db, customer.ID)
+
+	owner := scopes.Viewer{ID: customer.ID, Role: customer.Role}
+	basis, err := service.Create(lead.ID, owner, Input{Name: " Mutter 12 Monate Basis ", Plan: motherOnly()})
+	require.NoError(t, err)
+	assert.Equal(t, "Mutter 12 Monate Basis", basis.Name)
+	assert.Equal(t, 15600.0, basis.Result.Total)
+
+	staff := scopes.Viewer{ID: berater.ID, Role: berater.Role}
+	bonus, err := service.Create(lead.ID, staff, Input{Name: "7+7 mit Partnerbonus", Plan: sevenSevenWithBonus()})
+	require.NoError(t, err)
+	assert.Equal(t, berater.ID, bonus.CreatedByID)
+
+	_, err = service.Create(lead.ID, scopes.Viewer{ID: stranger.ID, Role: stranger.Role}, Input{Name: "Fremd", Plan: motherOnly()})
+	assert.ErrorIs(t, err, ErrLeadNotFound)
+	_, err = service.List(lead.ID, scopes.Viewer{ID: stranger.ID, Role: stranger.Role})
+	assert.ErrorIs(t, err, ErrLeadNotFound)
+	_, err = service.Create(lead.ID, owner, Input{Name: "Kaputt", Plan: models.ScenarioPlan{}})
+	assert.ErrorIs(t, err, ErrInvalidPlan)
+
+	list, err := service.List(lead.ID, owner)
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+	assert.Equal(t, basis.ID, list[0].ID)
+
+	// Side by side in the requested order
+	comparison, err := service.Compare(lead.ID, owner, []uuid.UUID{bonus.ID, basis.ID})
+	require.NoError(t, err)
+	require.Len(t, comparison.Scenarios, 2)
+	assert.Equal(t, bonus.ID, comparison.Scenarios[0].ID)
+	assert.Equal(t, bonus.ID, *comparison.HighestTotalID)
+	assert.Equal(t, bonus.ID, *comparison.LongestDurationID)
+	require.Len(t, comparison.Months, 18)
+	assert.Equal(t, []float64{1300, 1300}, comparison.Months[0].Totals)
+	assert.Equal(t, []float64{1800, 0}, comparison.Months[12].Totals)
+
+	_, err = service.Compare(lead.ID, owner, []uuid.UUID{uuid.New()})
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	// Choosing sets the expected amount and replaces an earlier choice
+	_, err = service.Chosen(lead.ID, owner)
+	assert.ErrorIs(t, err, ErrNoneChosen)
+
+	_, err = service.Choose(lead.ID, basis.ID, owner)
+	require.NoError(t, err)
+	chosen, err := service.Choose(lead.ID, bonus.ID, owner)
+	require.NoError(t, err)
+	assert.True(t, chosen.Chosen)
+
+	chosen, err = service.Chosen(lead.ID, staff)
+	require.NoError(t, err)
+	assert.Equal(t, bonus.ID, chosen.ID)
+	assertExpectedAmount(t, db, lead.ID, 25262)
+
+	// Updating the chosen scenario updates the expected amount
+	_, err = service.Update(lead.ID, bonus.ID, owner, Input{Name: "Nur Mutter", Plan: motherOnly()})
+	require.NoError(t, err)
+	assertExpectedAmount(t, db, lead.ID, 15600)
+
+	require.NoError(t, service.Delete(lead.ID, bonus.ID, owner))
+	_, err = service.Chosen(lead.ID, owner)
+	assert.ErrorIs(t, err, ErrNoneChosen)
+	assert.ErrorIs(t, service.Delete(lead.ID, bonus.ID, owner), ErrNotFound)
+
+	list, err = service.List(lead.ID, owner)
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	assert.False(t, list[0].Chosen)
+}
+
+func TestCreate_Limit(t *testing.T) {
+	db, service := setupTestService(t)
+	customer := testutils.CreateTestUser(t, db, models.RoleUser)
+	lead := createTestLead(t, db, customer.ID)
+	viewer := scopes.Viewer{ID: customer.ID, Role: customer.Role}
+
+	for i := 0; i < MaxScenarios; i++ {
+		_, err := service.Create(lead.ID, viewer, Input{Name: fmt.Sprintf("Variante %d", i), Plan: motherOnly()})
+		require.NoError(t, err)
+	}
+	_, err := service.Create(lead.ID, viewer, Input{Name: "Eine zu viel", Plan: motherOnly()})
+	assert.ErrorIs(t, err, ErrTooManyScenarios)
+}
+
+func TestPayoutPlan(t *testing.T) {
+	db, service := setupTestService(t)
+	customer := testutils.CreateTestUser(t, db, models.RoleUser)
+	berater := testutils.CreateTestUser(t, db, models.RoleBerater)
+	lead := createTestLead(t, db, customer.ID)
+	birth := time.Date(2026, 3, 15, 0, 0, 0, 0, time.UTC)
+	require.NoError(t, db.Model(lead).Update("child_birth_date", birth).Error)
+
+	owner := scopes.Viewer{ID: customer.ID, Role: customer.Role}
+	staff := scopes.Viewer{ID: berater.ID, Role: berater.Role}
+	_, err := service.PayoutPlan(lead.ID, owner)
+	assert.ErrorIs(t, err, ErrNoneChosen)
+
+	scenario, err := service.Create(lead.ID, owner, Input{Name: "7+7 mit Partnerbonus", Plan: sevenSevenWithBonus()})
+	require.NoError(t, err)
+	_, err = service.Choose(lead.ID, scenario.ID, owner)
+	require.NoError(t, err)
+
+	plan, err := service.PayoutPlan(lead.ID, owner)
+	require.NoError(t, err)
+	assert.Equal(t, "2026-03-15", plan.ChildBirthDate)
+	assert.Equal(t, 25262.0, plan.Total)
+	assert.Equal(t, 18, plan.Duration)
+	assert.Equal(t, 2.0, plan.PartnerMonths)
+	require.Len(t, plan.Parents, 2)
+	assert.Equal(t, "Vater", plan.Parents[1].Name)
+	require.Len(t, plan.Parents[1].Months, 11)
+	assert.Equal(t, PayoutMonth{
+		Lebensmonat: 8,
+		From:        "2026-10-15",
+		To:          "2026-11-14",
+		Type:        models.ScenarioMonthBasis,
+		Amount:      1800,
+	}, plan.Parents[1].Months[0])
+	assert.Equal(t, models.ScenarioMonthPartnerBonus, plan.Parents[0].Months[7].Type)
+
+	document := RenderPDF(plan)
+	assert.True(t, bytes.HasPrefix(document, []byte("%PDF-")))
+
+	// Sharing notifies the customer
+	_, err = service.SharePayoutPlan(lead.ID, staff)
+	require.NoError(t, err)
+	var notifications []models.Notification
+	require.NoError(t, db.Where("user_id = ?", customer.ID).Find(&notifications).Error)
+	require.Len(t, notifications, 1)
+	assert.Contains(t, notifications[0].Message, "7+7 mit Partnerbonus")
+}
+
+func assertExpectedAmount(t *testing.T, db *gorm.DB, leadID uuid.UUID, expected float64) {
+	t.Helper()
+	var lead models.Lead
+	require.NoError(t, db.First(&lead, "id = ?", leadID).Error)
+	assert.Equal(t, expected, lead.ExpectedAmount)
+}
+
+func createTestLead(t *testing.T, db *gorm.DB, userID uuid.UUID) *models.Lead {
+	t.Helper()
+	lead := &models.Lead{
+		UserID: userID,
+		Title:  "Elterngeldantrag",
+		Status: models.LeadStatusNew,
+	}
+	require.NoError(t, db.Create(lead).Error)
+	return lead
+}
+
+func setupTestService(t *testing.T) (*gorm.DB, *Service) {
+	t.Helper()
+	db := testutils.NewSQLiteDB(t, &models.User{}, &models.Lead{}, &models.FamilyMember{}, &models.ElterngeldScenario{}, &models.Notification{})
+
+	return db, NewService(db, zap.NewNop(), calculator.NewService(zap.NewNop()))
+}
@@ -0,0 +1,406 @@
+// Package scheduler runs the recurring jobs: reminders, purges,
+// reconciliation and digests. Jobs are registered in code with a cron
+// expression; their state and the history of their runs are stored in the
+// database, so admins can see what ran when and with which outcome, pause a
+// job or trigger a run outside its schedule.
+//
+// A run claims its job by incrementing the job's run count, so it starts once
+// even when several instances find the job due at the same time.
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"elterngeld-portal/internal/models"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// DefaultLimit is the number of runs per page when no limit is given
+	DefaultLimit = 20
+	// MaxLimit caps the number of runs per page
+	MaxLimit = 100
+
+	// runRetention is how long the run history is kept
+	runRetention = 90 * 24 * time.Hour
+)
+
+var (
+	// ErrJobNotFound is returned for names no job was registered with
+	ErrJobNotFound = errors.New("scheduled job not found")
+	// ErrInvalidSchedule is returned for cron expressions that cannot be parsed
+	ErrInvalidSchedule = errors.New("invalid schedule")
+)
+
+// Func runs a job once. The returned summary, like "3 reminders sent", is
+// stored with the run.
+type Func func(ctx context.Context) (string, error)
+
+// Job is a registered job with its stored state
+type Job struct {
+	models.ScheduledJob
+	Description string `json:"description"`
+}
+
+type job struct {
+	name        string
+	description string
+	expr        string
+	schedule    Schedule
+	run         Func
+}
+
+// Service runs the registered jobs when they are due
+type Service struct {
+	db       *gorm.DB
+	logger   *zap.Logger
+	now      func() time.Time
+	location *time.Location
+
+	mu   sync.RWMutex
+	jobs map[string]*job
+	// runs tracks the runs started by this instance so Run can wait for them
+	runs sync.WaitGroup
+}
+
+// NewService creates a scheduler evaluating cron expressions in location
+func NewService(db *gorm.DB, logger *zap.Logger, location *time.Location) *Service {
+	return &Service{
+		db:       db,
+		logger:   logger,
+		now:      time.Now,
+		location: location,
+		jobs:     make(map[string]*job),
+	}
+}
+
+// Register adds a job running on the schedule expr, see Parse. Registering a
+// name again replaces the job; a changed schedule applies from the next run.
+func (s *Service) Register(name, description, expr string, run Func) error {
+	schedule, err := Parse(expr, s.location)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.jobs[name] = &job{name: name, description: description, expr: expr, schedule: schedule, run: run}
+	return nil
+}
+
+func (s *Service) job(name string) (*job, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	j, ok := s.jobs[name]
+	return j, ok
+}
+
+func (s *Service) registered() []*job {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	jobs := make([]*job, 0, len(s.jobs))
+	for _, j := range s.jobs {
+		jobs = append(jobs, j)
+	}
+	sort.Slice(jobs, func(a, b int) bool { return jobs[a].name < jobs[b].name })
+	return jobs
+}
+
+// sync stores the registered jobs that have no state yet and updates the
+// schedule of jobs registered with a new one
+func (s *Service) sync() error {
+	now := s.now()
+	for _, j := range s.registered() {
+		next := j.schedule.Next(now)
+		state := models.ScheduledJob{Name: j.name, Schedule: j.expr, NextRunAt: &next}
+		if err := s.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&state).Error; err != nil {
+			return fmt.Errorf("failed to store scheduled job %s: %w", j.name, err)
+		}
+		if err := s.db.Model(&models.ScheduledJob{}).
+			Where("name = ? AND schedule <> ?", j.name, j.expr).
+			Updates(map[string]interface{}{"schedule": j.expr, "next_run_at": &next}).Error; err != nil {
+			return fmt.Errorf("failed to update scheduled job %s: %w", j.name, err)
+		}
+	}
+	return nil
+}
+
+// List returns the registered jobs with their state
+func (s *Service) List() ([]Job, error) {
+	if err := s.sync(); err != nil {
+		return nil, err
+	}
+
+	var states []models.ScheduledJob
+	if err := s.db.Order("name ASC").Find(&states).Error; err != nil {
+		return nil, fmt.Errorf("failed to load scheduled jobs: %w", err)
+	}
+	jobs := make([]Job, 0, len(states))
+	for _, state := range states {
+		// Jobs no longer registered keep their history but are not listed
+		if j, ok := s.job(state.Name); ok {
+			jobs = append(jobs, Job{ScheduledJob: state, Description: j.description})
+		}
+	}
+	return jobs, nil
+}
+
+// Get returns a registered job with its state
+func (s *Service) Get(name string) (*Job, error) {
+	j, ok := s.job(name)
+	if !ok {
+		return nil, ErrJobNotFound
+	}
+	if err := s.sync(); err != nil {
+		return nil, err
+	}
+
+	var state models.ScheduledJob
+	if err := s.db.First(&state, "name = ?", name).Error; err != nil {
+		return nil, fmt.Errorf("failed to load scheduled job: %w", err)
+	}
+	return &Job{ScheduledJob: state, Description: j.description}, nil
+}
+
+// Runs returns the run history of a job, latest first
+func (s *Service) Runs(name string, page, limit int) ([]models.ScheduledJobRun, int64, error) {
+	if _, ok := s.job(name); !ok {
+		return nil, 0, ErrJobNotFound
+	}
+	if limit <= 0 || limit > MaxLimit {
+		limit = DefaultLimit
+	}
+	if page < 1 {
+		page = 1
+	}
+
+	query := s.db.Model(&models.ScheduledJobRun{}).Where("job_name = ?", name)
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count job runs: %w", err)
+	}
+	var runs []models.ScheduledJobRun
+	if err := query.Order("started_at DESC").Offset((page - 1) * limit).Limit(limit).Find(&runs).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to load job runs: %w", err)
+	}
+	return runs, total, nil
+}
+
+// Pause stops a job from running on its schedule until it is resumed.
+// Triggered runs still start.
+func (s *Service) Pause(name string, userID uuid.UUID) (*Job, error) {
+	return s.update(name, map[string]interface{}{"paused": true, "paused_by": &userID})
+}
+
+// Resume lets a paused job run on its schedule again, starting with the next
+// time due from now, so runs missed while paused are not made up
+func (s *Service) Resume(name string) (*Job, error) {
+	j, ok := s.job(name)
+	if !ok {
+		return nil, ErrJobNotFound
+	}
+	next := j.schedule.Next(s.now())
+	return s.update(name, map[string]interface{}{"paused": false, "paused_by": nil, "next_run_at": &next})
+}
+
+// Trigger asks for a run of a job outside its schedule; it starts with the
+// scheduler's next check
+func (s *Service) Trigger(name string, userID uuid.UUID) (*Job, error) {
+	now := s.now()
+	return s.update(name, map[string]interface{}{"triggered_at": &now, "triggered_by": &userID})
+}
+
+func (s *Service) update(name string, updates map[string]interface{}) (*Job, error) {
+	if _, err := s.Get(name); err != nil {
+		return nil, err
+	}
+	if err := s.db.Model(&models.ScheduledJob{}).Where("name = ?", name).Updates(updates).Error; err != nil {
+		return nil, fmt.Errorf("failed to update scheduled job: %w", err)
+	}
+	return s.Get(name)
+}
+
+// RunDue starts the runs of the jobs that are due or were triggered and
+// returns the number of started runs. Runs execute in the background; a job
+// whose previous run has not finished is not started again.
+func (s *Service) RunDue(ctx context.Context) (int, error) {
+	now := s.now()
+	var due []models.ScheduledJob
+	if err := s.db.Where("(paused = ? AND next_run_at <= ?) OR triggered_at IS NOT NULL", false, now).
+		Order("next_run_at ASC").Find(&due).Error; err != nil {
+		return 0, fmt.Errorf("failed to load due jobs: %w", err)
+	}
+
+	started := 0
+	for _, state := range due {
+		j, ok := s.job(state.Name)
+		if !ok || state.LastStatus == models.JobRunStatusRunning {
+			continue
+		}
+		run, err := s.claim(j, &state, now)
+		if err != nil {
+			s.logger.Error("Failed to start scheduled job", zap.String("job", j.name), zap.Error(err))
+			continue
+		}
+		if run == nil {
+			continue
+		}
+
+		s.runs.Add(1)
+		go s.execute(ctx, j, run)
+		started++
+	}
+	return started, nil
+}
+
+// claim records the start of a run. It returns nil when another instance
+// started the run first.
+func (s *Service) claim(j *job, state *models.ScheduledJob, now time.Time) (*models.ScheduledJobRun, error) {
+	run := &models.ScheduledJobRun{
+		JobName:   j.name,
+		Trigger:   models.JobRunTriggerSchedule,
+		Status:    models.JobRunStatusRunning,
+		StartedAt: now,
+	}
+	if state.TriggeredAt != nil {
+		run.Trigger = models.JobRunTriggerManual
+		run.TriggeredBy = state.TriggeredBy
+	}
+
+	claimed := false
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		next := j.schedule.Next(now)
+		result := tx.Model(&models.ScheduledJob{}).
+			Where("name = ? AND run_count = ?", j.name, state.RunCount).
+			Updates(map[string]interface{}{
+				"run_count":    gorm.Expr("run_count + 1"),
+				"next_run_at":  &next,
+				"triggered_at": nil,
+				"triggered_by": nil,
+				"last_run_at":  &now,
+				"last_status":  models.JobRunStatusRunning,
+				"last_error":   "",
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		claimed = true
+		return tx.Create(run).Error
+	})
+	if err != nil || !claimed {
+		return nil, err
+	}
+	return run, nil
+}
+
+// execute runs a job and records its outcome
+func (s *Service) execute(ctx context.Context, j *job, run *models.ScheduledJobRun) {
+	defer s.runs.Done()
+
+	result, err := s.call(ctx, j)
+	finished := s.now()
+	status := models.JobRunStatusSucceeded
+	message := ""
+	if err != nil {
+		status = models.JobRunStatusFailed
+		message = err.Error()
+		s.logger.Error("Scheduled job failed", zap.String("job", j.name), zap.Error(err))
+	} else if result != "" {
+		s.logger.Info("Scheduled job finished", zap.String("job", j.name), zap.String("result", result))
+	}
+
+	if err := s.db.Model(run).Updates(map[string]interface{}{
+		"status":      status,
+		"finished_at": &finished,
+		"duration_ms": finished.Sub(run.StartedAt).Milliseconds(),
+		"result":      result,
+		"error":       message,
+	}).Error; err != nil {
+		s.logger.Error("Failed to record job run", zap.String("job", j.name), zap.Error(err))
+	}
+	if err := s.db.Model(&models.ScheduledJob{}).Where("name = ?", j.name).
+		Updates(map[string]interface{}{"last_status": status, "last_error": message}).Error; err != nil {
+		s.logger.Error("Failed to update scheduled job", zap.String("job", j.name), zap.Error(err))
+	}
+}
+
+// call runs a job and turns panics into errors so a broken job cannot stop
+// the scheduler
+func (s *Service) call(ctx context.Context, j *job) (result string, err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("scheduled job panicked: %v", recovered)
+		}
+	}()
+	return j.run(ctx)
+}
+
+// resetInterrupted marks runs that were still running when the scheduler
+// stopped as failed, so their jobs are started again
+func (s *Service) resetInterrupted() error {
+	now := s.now()
+	if err := s.db.Model(&models.ScheduledJobRun{}).
+		Where("status = ?", models.JobRunStatusRunning).
+		Updates(map[string]interface{}{
+			"status":      models.JobRunStatusFailed,
+			"finished_at": &now,
+			"error":       "interrupted",
+		}).Error; err != nil {
+		return err
+	}
+	return s.db.Model(&models.ScheduledJob{}).
+		Where("last_status = ?", models.JobRunStatusRunning).
+		Updates(map[string]interface{}{"last_status": models.JobRunStatusFailed, "last_error": "interrupted"}).Error
+}
+
+// prune removes runs older than the retention period
+func (s *Service) prune() error {
+	return s.db.Where("started_at < ?", s.now().Add(-runRetention)).Delete(&models.ScheduledJobRun{}).Error
+}
+
+// Run starts due jobs every interval until the context is cancelled and then
+// waits for the runs it started. It is meant to run on one instance at a
+// time; runs interrupted by a previous stop are recorded as failed.
+func (s *Service) Run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	if err := s.sync(); err != nil {
+		s.logger.Error("Failed to store scheduled jobs", zap.Error(err))
+	}
+	if err := s.resetInterrupted(); err != nil {
+		s.logger.Error("Failed to reset interrupted job runs", zap.Error(err))
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := s.RunDue(ctx); err != nil {
+			s.logger.Error("Failed to run scheduled jobs", zap.Error(err))
+		}
+		if err := s.prune(); err != nil {
+			s.logger.Error("Failed to prune job runs", zap.Error(err))
+		}
+
+		select {
+		case <-ctx.Done():
+			s.runs.Wait()
+			return
+		case <-ticker.C:
+		}
+	}
+}
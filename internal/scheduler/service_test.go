@@ -0,0 +1,209 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/tests/testutils"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+func TestParse(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+	from := time.Date(2026, 5, 4, 10, 7, 30, 0, berlin) // a Monday
+
+	tests := []struct {
+		expr string
+		next time.Time
+	}{
+		{"*/15 * * * *", time.Date(2026, 5, 4, 10, 15, 0, 0, berlin)},
+		{"0 3 * * *", time.Date(2026, 5, 5, 3, 0, 0, 0, berlin)},
+		{"30 8-18/2 * * 1-5", time.Date(2026, 5, 4, 10, 30, 0, 0, berlin)},
+		{"0 9 * * 0", time.Date(2026, 5, 10, 9, 0, 0, 0, berlin)},
+		{"0 9 * * 7", time.Date(2026, 5, 10, 9, 0, 0, 0, berlin)},
+		{"0 0 1,15 * *", time.Date(2026, 5, 15, 0, 0, 0, 0, berlin)},
+		// Either day field matches when both are restricted
+		{"0 0 15 * 2", time.Date(2026, 5, 5, 0, 0, 0, 0, berlin)},
+		{"@monthly", time.Date(2026, 6, 1, 0, 0, 0, 0, berlin)},
+		{"@every 90m", from.Add(90 * time.Minute)},
+	}
+	for _, tt := range tests {
+		schedule, err := Parse(tt.expr, berlin)
+		require.NoError(t, err, tt.expr)
+		assert.True(t, tt.next.Equal(schedule.Next(from)), "%s: got %s", tt.expr, schedule.Next(from))
+	}
+
+	// 2:30 does not exist on the day clocks go forward, so that day is skipped
+	dst, err := Parse("30 2 * * *", berlin)
+	require.NoError(t, err)
+	assert.True(t, time.Date(2026, 3, 30, 2, 30, 0, 0, berlin).Equal(dst.Next(time.Date(2026, 3, 29, 1, 0, 0, 0, berlin))))
+
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* * 0 * *", "*/0 * * * *", "5-1 * * * *", "a * * * *", "@every 0s", "@every soon", "@often"} {
+		_, err := Parse(expr, berlin)
+		assert.ErrorIs(t, err, ErrInvalidSchedule, expr)
+	}
+}
+
+func TestRunDue(t *testing.T) {
+	_, service := setupTestService(t)
+	now := time.Date(2026, 5, 4, 10, 0, 0, 0, time.UTC)
+	service.now = func() time.Time { return now }
+
+	runs := 0
+	require.NoError(t, service.Register("holds", "Release expired timeslot holds", "*/5 * * * *", func(ctx context.Context) (string, error) {
+		runs++
+		if runs == 2 {
+			return "", errors.New("database is locked")
+		}
+		return "2 holds released", nil
+	}))
+	assert.ErrorIs(t, service.Register("broken", "", "every minute", nil), ErrInvalidSchedule)
+
+	jobs, err := service.List()
+	require.NoError(t, err)
+	require.Len(t, jobs, 1)
+	assert.Equal(t, "Release expired timeslot holds", jobs[0].Description)
+	assert.Equal(t, now.Add(5*time.Minute), jobs[0].NextRunAt.UTC())
+
+	runDue := func() int {
+		started, err := service.RunDue(context.Background())
+		require.NoError(t, err)
+		service.runs.Wait()
+		return started
+	}
+
+	assert.Zero(t, runDue())
+	now = now.Add(5 * time.Minute)
+	assert.Equal(t, 1, runDue())
+	assert.Zero(t, runDue())
+
+	job, err := service.Get("holds")
+	require.NoError(t, err)
+	assert.Equal(t, models.JobRunStatusSucceeded, job.LastStatus)
+	assert.Equal(t, int64(1), job.RunCount)
+	assert.Equal(t, now.Add(5*time.Minute), job.NextRunAt.UTC())
+
+	// Failures are recorded with the run
+	now = now.Add(5 * time.Minute)
+	assert.Equal(t, 1, runDue())
+	job, err = service.Get("holds")
+	require.NoError(t, err)
+	assert.Equal(t, models.JobRunStatusFailed, job.LastStatus)
+	assert.Equal(t, "database is locked", job.LastError)
+
+	history, total, err := service.Runs("holds", 1, DefaultLimit)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), total)
+	assert.Equal(t, models.JobRunStatusFailed, history[0].Status)
+	assert.Equal(t, models.JobRunTriggerSchedule, history[0].Trigger)
+	assert.Equal(t, "2 holds released", history[1].Result)
+
+	_, _, err = service.Runs("unknown", 1, DefaultLimit)
+	assert.ErrorIs(t, err, ErrJobNotFound)
+}
+
+func TestPauseAndTrigger(t *testing.T) {
+	_, service := setupTestService(t)
+	now := time.Date(2026, 5, 4, 10, 0, 0, 0, time.UTC)
+	service.now = func() time.Time { return now }
+	admin := uuid.New()
+
+	runs := 0
+	require.NoError(t, service.Register("digest", "Send notification digests", "@every 15m", func(ctx context.Context) (string, error) {
+		runs++
+		return "", nil
+	}))
+
+	job, err := service.Pause("digest", admin)
+	require.NoError(t, err)
+	assert.True(t, job.Paused)
+	now = now.Add(time.Hour)
+	started, err := service.RunDue(context.Background())
+	require.NoError(t, err)
+	assert.Zero(t, started)
+
+	// Admins can run a paused job
+	_, err = service.Trigger("digest", admin)
+	require.NoError(t, err)
+	started, err = service.RunDue(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, started)
+	service.runs.Wait()
+	assert.Equal(t, 1, runs)
+
+	history, _, err := service.Runs("digest", 1, DefaultLimit)
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	assert.Equal(t, models.JobRunTriggerManual, history[0].Trigger)
+	assert.Equal(t, &admin, history[0].TriggeredBy)
+
+	// Resuming does not make up the runs missed while paused
+	job, err = service.Resume("digest")
+	require.NoError(t, err)
+	assert.False(t, job.Paused)
+	assert.Equal(t, now.Add(15*time.Minute), job.NextRunAt.UTC())
+
+	_, err = service.Pause("unknown", admin)
+	assert.ErrorIs(t, err, ErrJobNotFound)
+	_, err = service.Trigger("unknown", admin)
+	assert.ErrorIs(t, err, ErrJobNotFound)
+}
+
+func TestRunDue_ClaimsOnce(t *testing.T) {
+	db, first := setupTestService(t)
+	second := NewService(db, zap.NewNop(), time.UTC)
+	now := time.Date(2026, 5, 4, 10, 0, 0, 0, time.UTC)
+	first.now = func() time.Time { return now }
+	second.now = first.now
+
+	runs := 0
+	run := func(ctx context.Context) (string, error) {
+		runs++
+		panic("nil pointer")
+	}
+	require.NoError(t, first.Register("noshow", "", "@hourly", run))
+	require.NoError(t, second.Register("noshow", "", "@hourly", run))
+	require.NoError(t, first.sync())
+
+	var state models.ScheduledJob
+	require.NoError(t, db.First(&state, "name = ?", "noshow").Error)
+	now = now.Add(time.Hour)
+
+	// Both instances loaded the job as due; only the first claim starts it
+	claimed, err := first.claim(first.jobs["noshow"], &state, now)
+	require.NoError(t, err)
+	require.NotNil(t, claimed)
+	unclaimed, err := second.claim(second.jobs["noshow"], &state, now)
+	require.NoError(t, err)
+	assert.Nil(t, unclaimed)
+
+	first.runs.Add(1)
+	first.execute(context.Background(), first.jobs["noshow"], claimed)
+	assert.Equal(t, 1, runs)
+	var recorded models.ScheduledJobRun
+	require.NoError(t, db.First(&recorded, "id = ?", claimed.ID).Error)
+	assert.Equal(t, models.JobRunStatusFailed, recorded.Status)
+	assert.Contains(t, recorded.Error, "panicked")
+
+	// Runs cut off by a stop are recorded as failed
+	require.NoError(t, db.Model(&recorded).Update("status", models.JobRunStatusRunning).Error)
+	require.NoError(t, second.resetInterrupted())
+	require.NoError(t, db.First(&recorded, "id = ?", claimed.ID).Error)
+	assert.Equal(t, "interrupted", recorded.Error)
+}
+
+func setupTestService(t *testing.T) (*gorm.DB, *Service) {
+	t.Helper()
+	db := testutils.NewSQLiteDB(t, &models.ScheduledJob{}, &models.ScheduledJobRun{})
+
+	return db, NewService(db, zap.NewNop(), time.UTC)
+}
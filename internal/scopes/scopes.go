@@ -0,0 +1,189 @@
+// Package scopes holds the GORM query scopes that limit leads, families,
+// comments, documents, bookings, payments and activities to what the
+// requesting user may access. Handlers apply them with db.Scopes instead of
+// filtering by role themselves.
+package scopes
+
+import (
+	"elterngeld-portal/internal/middleware"
+	"elterngeld-portal/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Viewer is the user a query is scoped to
+type Viewer struct {
+	ID   uuid.UUID
+	Role models.UserRole
+}
+
+// FromContext returns the authenticated user of the request
+func FromContext(c *gin.Context) (Viewer, bool) {
+	id, ok := middleware.GetCurrentUserID(c)
+	if !ok {
+		return Viewer{}, false
+	}
+	role, ok := middleware.GetCurrentUserRole(c)
+	if !ok {
+		return Viewer{}, false
+	}
+	return Viewer{ID: id, Role: role}, true
+}
+
+// none matches no rows, unknown roles see nothing
+func none(db *gorm.DB) *gorm.DB {
+	return db.Where("1 = 0")
+}
+
+// familiesOf selects the families the user is a parent in
+func familiesOf(db *gorm.DB, userID uuid.UUID) *gorm.DB {
+	return db.Session(&gorm.Session{NewDB: true}).Model(&models.FamilyMember{}).
+		Select("family_id").Where("user_id = ?", userID)
+}
+
+// VisibleLeads limits leads to those the viewer may see: customers their own
+// leads and those of their families, Junior-Berater the leads assigned to them and unassigned leads,
+// Berater and admins all leads
+func VisibleLeads(viewer Viewer) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		switch viewer.Role {
+		case models.RoleUser:
+			return db.Where("(leads.user_id = ? OR leads.family_id IN (?))", viewer.ID, familiesOf(db, viewer.ID))
+		case models.RoleJuniorBerater:
+			return db.Where("(leads.berater_id = ? OR leads.berater_id IS NULL)", viewer.ID)
+		case models.RoleBerater, models.RoleAdmin:
+			return db
+		default:
+			return none(db)
+		}
+	}
+}
+
+// EditableLeads limits leads to those the viewer may change. Junior-Berater
+// only change the leads assigned to them.
+func EditableLeads(viewer Viewer) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if viewer.Role == models.RoleJuniorBerater {
+			return db.Where("leads.berater_id = ?", viewer.ID)
+		}
+		return VisibleLeads(viewer)(db)
+	}
+}
+
+// VisibleFamilies limits families to those the viewer may see: customers the
+// families they are a parent in, Junior-Berater the families of the leads they
+// see, Berater and admins all families
+func VisibleFamilies(viewer Viewer) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		switch viewer.Role {
+		case models.RoleUser:
+			return db.Where("families.id IN (?)", familiesOf(db, viewer.ID))
+		case models.RoleJuniorBerater:
+			return db.Where("families.id IN (?)", familiesOfLeads(db, VisibleLeads(viewer)))
+		case models.RoleBerater, models.RoleAdmin:
+			return db
+		default:
+			return none(db)
+		}
+	}
+}
+
+// EditableFamilies limits families to those the viewer may change.
+// Junior-Berater only change the families of the leads assigned to them.
+func EditableFamilies(viewer Viewer) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if viewer.Role == models.RoleJuniorBerater {
+			return db.Where("families.id IN (?)", familiesOfLeads(db, EditableLeads(viewer)))
+		}
+		return VisibleFamilies(viewer)(db)
+	}
+}
+
+// familiesOfLeads selects the families of the leads the scope allows
+func familiesOfLeads(db *gorm.DB, scope func(*gorm.DB) *gorm.DB) *gorm.DB {
+	return db.Session(&gorm.Session{NewDB: true}).Model(&models.Lead{}).
+		Scopes(scope).Where("leads.family_id IS NOT NULL").Select("leads.family_id")
+}
+
+// VisibleComments limits comments to those on leads the viewer may see.
+// Customers do not see internal comments.
+func VisibleComments(viewer Viewer) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		leads := db.Session(&gorm.Session{NewDB: true}).Model(&models.Lead{}).
+			Scopes(VisibleLeads(viewer)).Select("leads.id")
+		db = db.Where("comments.lead_id IN (?)", leads)
+		if viewer.Role == models.RoleUser {
+			db = db.Where("comments.is_internal = ?", false)
+		}
+		return db
+	}
+}
+
+// VisibleDocuments limits documents to those on leads the viewer may see and
+// the viewer's own uploads. Customers do not see the internal documents of the
+// Berater, staff do not see documents the customers keep to themselves.
+func VisibleDocuments(viewer Viewer) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if viewer.Role == models.RoleAdmin {
+			return db
+		}
+		hidden := models.DocumentVisibilityCustomer
+		if viewer.Role == models.RoleUser {
+			hidden = models.DocumentVisibilityBerater
+		}
+		leads := db.Session(&gorm.Session{NewDB: true}).Model(&models.Lead{}).
+			Scopes(VisibleLeads(viewer)).Select("leads.id")
+		return db.Where("(documents.user_id = ? OR (documents.lead_id IN (?) AND documents.visibility <> ?))",
+			viewer.ID, leads, hidden)
+	}
+}
+
+// VisibleBookings limits bookings to those the viewer may see: Berater and
+// admins all bookings, everyone else the bookings they made or conduct.
+// Customers also see the bookings of their families.
+func VisibleBookings(viewer Viewer) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		switch viewer.Role {
+		case models.RoleBerater, models.RoleAdmin:
+			return db
+		case models.RoleJuniorBerater:
+			return db.Where("(bookings.user_id = ? OR bookings.berater_id = ?)", viewer.ID, viewer.ID)
+		case models.RoleUser:
+			return db.Where("(bookings.user_id = ? OR bookings.family_id IN (?))", viewer.ID, familiesOf(db, viewer.ID))
+		default:
+			return none(db)
+		}
+	}
+}
+
+// VisiblePayments limits payments to those the viewer may see: Berater and
+// admins all payments, everyone else their own
+func VisiblePayments(viewer Viewer) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		switch viewer.Role {
+		case models.RoleBerater, models.RoleAdmin:
+			return db
+		case models.RoleUser, models.RoleJuniorBerater:
+			return db.Where("payments.user_id = ?", viewer.ID)
+		default:
+			return none(db)
+		}
+	}
+}
+
+// VisibleActivities limits activities to those on leads the viewer may see
+// and the viewer's own activities without a lead. Only admins see the
+// activities of other users without a lead, like their logins.
+func VisibleActivities(viewer Viewer) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if viewer.Role == models.RoleAdmin {
+			return db
+		}
+		leads := db.Session(&gorm.Session{NewDB: true}).Model(&models.Lead{}).
+			Scopes(VisibleLeads(viewer)).Select("leads.id")
+		return db.Where("(activities.lead_id IN (?) OR (activities.lead_id IS NULL AND activities.user_id = ?))",
+			leads, viewer.ID)
+	}
+}
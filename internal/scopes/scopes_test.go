@@ -0,0 +1,354 @@
+package scopes
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/tests/testutils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+type fixture struct {
+	customer, otherCustomer, junior, otherJunior, berater, admin models.User
+
+	assignedLead, unassignedLead, otherLead models.Lead
+	publicComment, internalComment          models.Comment
+	booking, otherBooking                   models.Booking
+	payment, otherPayment                   models.Payment
+}
+
+func TestVisibleLeads(t *testing.T) {
+	db, f := setupFixture(t)
+
+	tests := []struct {
+		name   string
+		viewer models.User
+		leads  []uuid.UUID
+	}{
+		{"customer sees own leads", f.customer, []uuid.UUID{f.assignedLead.ID, f.unassignedLead.ID}},
+		{"other customer sees only theirs", f.otherCustomer, []uuid.UUID{f.otherLead.ID}},
+		{"junior sees assigned and unassigned leads", f.junior, []uuid.UUID{f.assignedLead.ID, f.unassignedLead.ID}},
+		{"other junior does not see leads assigned to someone else", f.otherJunior, []uuid.UUID{f.unassignedLead.ID, f.otherLead.ID}},
+		{"berater sees all leads", f.berater, []uuid.UUID{f.assignedLead.ID, f.unassignedLead.ID, f.otherLead.ID}},
+		{"admin sees all leads", f.admin, []uuid.UUID{f.assignedLead.ID, f.unassignedLead.ID, f.otherLead.ID}},
+		{"unknown role sees nothing", models.User{ID: uuid.New(), Role: "guest"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var leads []models.Lead
+			require.NoError(t, db.Scopes(VisibleLeads(viewer(tt.viewer))).Find(&leads).Error)
+			assert.ElementsMatch(t, tt.leads, leadIDs(leads))
+		})
+	}
+
+	// A scope combines with the lookup of a single lead
+	var lead models.Lead
+	err := db.Scopes(VisibleLeads(viewer(f.otherCustomer))).Where("id = ?", f.assignedLead.ID).First(&lead).Error
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
+
+func TestEditableLeads(t *testing.T) {
+	db, f := setupFixture(t)
+
+	var leads []models.Lead
+	require.NoError(t, db.Scopes(EditableLeads(viewer(f.junior))).Find(&leads).Error)
+	assert.ElementsMatch(t, []uuid.UUID{f.assignedLead.ID}, leadIDs(leads))
+
+	leads = nil
+	require.NoError(t, db.Scopes(EditableLeads(viewer(f.otherJunior))).Find(&leads).Error)
+	assert.ElementsMatch(t, []uuid.UUID{f.otherLead.ID}, leadIDs(leads))
+
+	leads = nil
+	require.NoError(t, db.Scopes(EditableLeads(viewer(f.customer))).Find(&leads).Error)
+	assert.ElementsMatch(t, []uuid.UUID{f.assignedLead.ID, f.unassignedLead.ID}, leadIDs(leads))
+}
+
+func TestVisibleComments(t *testing.T) {
+	db, f := setupFixture(t)
+
+	tests := []struct {
+		name     string
+		viewer   models.User
+		comments []uuid.UUID
+	}{
+		{"customer does not see internal comments", f.customer, []uuid.UUID{f.publicComment.ID}},
+		{"other customer sees no comments on foreign leads", f.otherCustomer, nil},
+		{"junior sees comments on assigned leads", f.junior, []uuid.UUID{f.publicComment.ID, f.internalComment.ID}},
+		{"other junior sees no comments on leads assigned to someone else", f.otherJunior, nil},
+		{"berater sees all comments", f.berater, []uuid.UUID{f.publicComment.ID, f.internalComment.ID}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var comments []models.Comment
+			require.NoError(t, db.Scopes(VisibleComments(viewer(tt.viewer))).Find(&comments).Error)
+			ids := make([]uuid.UUID, 0, len(comments))
+			for _, comment := range comments {
+				ids = append(ids, comment.ID)
+			}
+			assert.ElementsMatch(t, tt.comments, ids)
+		})
+	}
+}
+
+func TestVisibleDocuments(t *testing.T) {
+	db, f := setupFixture(t)
+
+	document := func(uploader models.User, visibility models.DocumentVisibility) uuid.UUID {
+		doc := models.Document{
+			LeadID:       f.assignedLead.ID,
+			UserID:       uploader.ID,
+			FileName:     uuid.NewString() + ".pdf",
+			OriginalName: "nachweis.pdf",
+			FilePath:     "/tmp/nachweis.pdf",
+			ContentType:  "application/pdf",
+			DocumentType: models.DocumentTypeIncomeProof,
+			Visibility:   visibility,
+		}
+		require.NoError(t, db.Create(&doc).Error)
+		return doc.ID
+	}
+	shared := document(f.customer, models.DocumentVisibilityShared)
+	private := document(f.customer, models.DocumentVisibilityCustomer)
+	internal := document(f.junior, models.DocumentVisibilityBerater)
+
+	tests := []struct {
+		name      string
+		viewer    models.User
+		documents []uuid.UUID
+	}{
+		{"customer does not see internal documents", f.customer, []uuid.UUID{shared, private}},
+		{"other customer sees no documents on foreign leads", f.otherCustomer, nil},
+		{"junior does not see documents the customer keeps private", f.junior, []uuid.UUID{shared, internal}},
+		{"other junior sees no documents on leads assigned to someone else", f.otherJunior, nil},
+		{"berater sees shared and internal documents", f.berater, []uuid.UUID{shared, internal}},
+		{"admin sees all documents", f.admin, []uuid.UUID{shared, private, internal}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var documents []models.Document
+			require.NoError(t, db.Scopes(VisibleDocuments(viewer(tt.viewer))).Find(&documents).Error)
+			ids := make([]uuid.UUID, 0, len(documents))
+			for _, document := range documents {
+				ids = append(ids, document.ID)
+			}
+			assert.ElementsMatch(t, tt.documents, ids)
+		})
+	}
+}
+
+func TestVisibleBookingsAndPayments(t *testing.T) {
+	db, f := setupFixture(t)
+
+	tests := []struct {
+		name     string
+		viewer   models.User
+		bookings []uuid.UUID
+		payments []uuid.UUID
+	}{
+		{"customer", f.customer, []uuid.UUID{f.booking.ID}, []uuid.UUID{f.payment.ID}},
+		{"other customer", f.otherCustomer, []uuid.UUID{f.otherBooking.ID}, []uuid.UUID{f.otherPayment.ID}},
+		{"junior sees conducted bookings only", f.junior, []uuid.UUID{f.booking.ID}, nil},
+		{"other junior", f.otherJunior, nil, nil},
+		{"berater", f.berater, []uuid.UUID{f.booking.ID, f.otherBooking.ID}, []uuid.UUID{f.payment.ID, f.otherPayment.ID}},
+		{"admin", f.admin, []uuid.UUID{f.booking.ID, f.otherBooking.ID}, []uuid.UUID{f.payment.ID, f.otherPayment.ID}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var bookings []models.Booking
+			require.NoError(t, db.Scopes(VisibleBookings(viewer(tt.viewer))).Find(&bookings).Error)
+			bookingIDs := make([]uuid.UUID, 0, len(bookings))
+			for _, booking := range bookings {
+				bookingIDs = append(bookingIDs, booking.ID)
+			}
+			assert.ElementsMatch(t, tt.bookings, bookingIDs)
+
+			var payments []models.Payment
+			require.NoError(t, db.Scopes(VisiblePayments(viewer(tt.viewer))).Find(&payments).Error)
+			paymentIDs := make([]uuid.UUID, 0, len(payments))
+			for _, payment := range payments {
+				paymentIDs = append(paymentIDs, payment.ID)
+			}
+			assert.ElementsMatch(t, tt.payments, paymentIDs)
+		})
+	}
+}
+
+func TestFamilies(t *testing.T) {
+	db, f := setupFixture(t)
+
+	// The other customer is the second parent of the customer's case
+	family := models.Family{Name: "Familie Test", CreatedBy: f.customer.ID}
+	require.NoError(t, db.Create(&family).Error)
+	for user, role := range map[*models.User]models.FamilyRole{
+		&f.customer:      models.FamilyRoleApplicant,
+		&f.otherCustomer: models.FamilyRoleParent,
+	} {
+		member := models.FamilyMember{FamilyID: family.ID, UserID: &user.ID, Role: role, FirstName: user.FirstName, LastName: user.LastName}
+		require.NoError(t, db.Create(&member).Error)
+	}
+	require.NoError(t, db.Model(&f.assignedLead).Update("family_id", family.ID).Error)
+	require.NoError(t, db.Model(&f.booking).Update("family_id", family.ID).Error)
+
+	var leads []models.Lead
+	require.NoError(t, db.Scopes(VisibleLeads(viewer(f.otherCustomer))).Find(&leads).Error)
+	assert.ElementsMatch(t, []uuid.UUID{f.assignedLead.ID, f.otherLead.ID}, leadIDs(leads))
+
+	leads = nil
+	require.NoError(t, db.Scopes(VisibleLeads(viewer(f.customer))).Find(&leads).Error)
+	assert.ElementsMatch(t, []uuid.UUID{f.assignedLead.ID, f.unassignedLead.ID}, leadIDs(leads))
+
+	var bookings []models.Booking
+	require.NoError(t, db.Scopes(VisibleBookings(viewer(f.otherCustomer))).Find(&bookings).Error)
+	assert.Len(t, bookings, 2)
+
+	tests := []struct {
+		name     string
+		viewer   models.User
+		visible  bool
+		editable bool
+	}{
+		{"parent", f.otherCustomer, true, true},
+		{"junior of a family lead", f.junior, true, true},
+		{"other junior", f.otherJunior, false, false},
+		{"berater", f.berater, true, true},
+		{"unknown role", models.User{ID: uuid.New(), Role: "guest"}, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var visible, editable int64
+			require.NoError(t, db.Model(&models.Family{}).Scopes(VisibleFamilies(viewer(tt.viewer))).Count(&visible).Error)
+			require.NoError(t, db.Model(&models.Family{}).Scopes(EditableFamilies(viewer(tt.viewer))).Count(&editable).Error)
+			assert.Equal(t, tt.visible, visible == 1)
+			assert.Equal(t, tt.editable, editable == 1)
+		})
+	}
+}
+
+func TestFromContext(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	_, ok := FromContext(c)
+	assert.False(t, ok)
+
+	id := uuid.New()
+	c.Set("user_id", id)
+	c.Set("user_role", models.RoleJuniorBerater)
+	v, ok := FromContext(c)
+	require.True(t, ok)
+	assert.Equal(t, Viewer{ID: id, Role: models.RoleJuniorBerater}, v)
+}
+
+func setupFixture(t *testing.T) (*gorm.DB, *fixture) {
+	t.Helper()
+	db := testutils.NewSQLiteDB(t,
+		&models.User{},
+		&models.Lead{},
+		&models.Family{},
+		&models.FamilyMember{},
+		&models.Comment{},
+		&models.Document{},
+		&models.Booking{},
+		&models.Payment{},
+	)
+
+	f := &fixture{}
+	for user, role := range map[*models.User]models.UserRole{
+		&f.customer:      models.RoleUser,
+		&f.otherCustomer: models.RoleUser,
+		&f.junior:        models.RoleJuniorBerater,
+		&f.otherJunior:   models.RoleJuniorBerater,
+		&f.berater:       models.RoleBerater,
+		&f.admin:         models.RoleAdmin,
+	} {
+		*user = models.User{
+			Email:     fmt.Sprintf("%s@example.com", uuid.New()),
+			Password:  "passwort123",
+			FirstName: "Test",
+			LastName:  string(role),
+			Role:      role,
+			IsActive:  true,
+		}
+		require.NoError(t, db.Create(user).Error)
+	}
+
+	f.assignedLead = createLead(t, db, f.customer.ID, &f.junior.ID)
+	f.unassignedLead = createLead(t, db, f.customer.ID, nil)
+	f.otherLead = createLead(t, db, f.otherCustomer.ID, &f.otherJunior.ID)
+
+	f.publicComment = models.Comment{ID: uuid.New(), LeadID: f.assignedLead.ID, UserID: f.junior.ID, Content: "Bitte Lohnabrechnungen hochladen"}
+	f.internalComment = models.Comment{ID: uuid.New(), LeadID: f.assignedLead.ID, UserID: f.junior.ID, Content: "Rückruf einplanen", IsInternal: true}
+	for _, comment := range []*models.Comment{&f.publicComment, &f.internalComment} {
+		require.NoError(t, db.Create(comment).Error)
+	}
+
+	f.booking = createBooking(t, db, f.customer.ID, &f.junior.ID)
+	f.otherBooking = createBooking(t, db, f.otherCustomer.ID, nil)
+	f.payment = createPayment(t, db, f.customer.ID, f.assignedLead.ID)
+	f.otherPayment = createPayment(t, db, f.otherCustomer.ID, f.otherLead.ID)
+
+	return db, f
+}
+
+func viewer(user models.User) Viewer {
+	return Viewer{ID: user.ID, Role: user.Role}
+}
+
+func leadIDs(leads []models.Lead) []uuid.UUID {
+	ids := make([]uuid.UUID, 0, len(leads))
+	for _, lead := range leads {
+		ids = append(ids, lead.ID)
+	}
+	return ids
+}
+
+func createLead(t *testing.T, db *gorm.DB, userID uuid.UUID, beraterID *uuid.UUID) models.Lead {
+	t.Helper()
+	lead := models.Lead{
+		UserID:    userID,
+		BeraterID: beraterID,
+		Title:     "Elterngeld",
+		Status:    models.LeadStatusNew,
+		Priority:  models.PriorityMedium,
+		Source:    models.LeadSourceWebsite,
+	}
+	require.NoError(t, db.Create(&lead).Error)
+	return lead
+}
+
+func createBooking(t *testing.T, db *gorm.DB, userID uuid.UUID, beraterID *uuid.UUID) models.Booking {
+	t.Helper()
+	start := time.Now().Add(48 * time.Hour)
+	booking := models.Booking{
+		UserID:      userID,
+		BeraterID:   beraterID,
+		Title:       "Beratung",
+		Status:      models.BookingStatusConfirmed,
+		ScheduledAt: start,
+		StartTime:   start,
+		EndTime:     start.Add(time.Hour),
+	}
+	require.NoError(t, db.Create(&booking).Error)
+	return booking
+}
+
+func createPayment(t *testing.T, db *gorm.DB, userID, leadID uuid.UUID) models.Payment {
+	t.Helper()
+	payment := models.Payment{
+		UserID:          userID,
+		LeadID:          leadID,
+		Amount:          120,
+		Status:          models.PaymentStatusSucceeded,
+		StripeSessionID: "cs_" + uuid.New().String(),
+	}
+	require.NoError(t, db.Create(&payment).Error)
+	return payment
+}
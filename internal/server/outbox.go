@@ -0,0 +1,170 @@
+package server
+
+import (
+	"context"
+	"fmt"
+
+	"elterngeld-portal/internal/backup"
+	"elterngeld-portal/internal/chatnotify"
+	"elterngeld-portal/internal/email"
+	"elterngeld-portal/internal/mailqueue"
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/internal/outbox"
+	"elterngeld-portal/internal/paymentlinks"
+	"elterngeld-portal/internal/pdf"
+	"elterngeld-portal/pkg/i18n"
+
+	"gorm.io/gorm"
+)
+
+// registerOutboxTopics delivers the side effects queued by the handlers.
+// Messages only carry IDs; records are loaded on delivery so they show their
+// current state.
+func registerOutboxTopics(relay *outbox.Service, db *gorm.DB, notifier *chatnotify.Notifier, emailService *email.EmailService, mailQueue *mailqueue.Service, paymentLinks *paymentlinks.Service, pdfService *pdf.Service, backups *backup.Service) {
+	relay.Register(outbox.TopicChatLeadCreated, func(message *models.OutboxMessage) error {
+		var payload outbox.LeadMessage
+		if err := outbox.Decode(message, &payload); err != nil {
+			return err
+		}
+		var lead models.Lead
+		if err := db.First(&lead, "id = ?", payload.LeadID).Error; err != nil {
+			return fmt.Errorf("failed to load lead: %w", err)
+		}
+		return notifier.LeadCreated(&lead)
+	})
+
+	relay.Register(outbox.TopicChatBookingPaid, func(message *models.OutboxMessage) error {
+		booking, payment, err := loadPaidBooking(db, message)
+		if err != nil {
+			return err
+		}
+		return notifier.BookingPaid(booking, payment)
+	})
+
+	relay.Register(outbox.TopicEmailLeadAssigned, func(message *models.OutboxMessage) error {
+		lead, berater, _, err := loadAssignment(db, message)
+		if err != nil {
+			return err
+		}
+		return emailService.SendLeadAssignment(lead, berater)
+	})
+
+	relay.Register(outbox.TopicEmailLeadIntroduction, func(message *models.OutboxMessage) error {
+		lead, berater, customer, err := loadAssignment(db, message)
+		if err != nil {
+			return err
+		}
+		return emailService.SendLeadAssignmentConfirmation(lead, customer, berater)
+	})
+
+	// Receipts and confirmations go through the email queue, which renders
+	// the invoice and retries SMTP failures on its own
+	relay.Register(outbox.TopicEmailPaymentReceipt, func(message *models.OutboxMessage) error {
+		booking, payment, err := loadPaidBooking(db, message)
+		if err != nil {
+			return err
+		}
+		return mailQueue.EnqueuePaymentReceipt(payment, booking)
+	})
+
+	// Free bookings are confirmed without a payment, so only the booking is needed
+	relay.Register(outbox.TopicEmailBookingConfirmation, func(message *models.OutboxMessage) error {
+		var payload outbox.PaymentMessage
+		if err := outbox.Decode(message, &payload); err != nil {
+			return err
+		}
+		var booking models.Booking
+		if err := db.First(&booking, "id = ?", payload.BookingID).Error; err != nil {
+			return fmt.Errorf("failed to load booking: %w", err)
+		}
+		return mailQueue.EnqueueBookingConfirmation(&booking)
+	})
+
+	// Offers are signed by the Berater who created the payment link
+	relay.Register(outbox.TopicEmailOffer, func(message *models.OutboxMessage) error {
+		var payload outbox.PaymentLinkMessage
+		if err := outbox.Decode(message, &payload); err != nil {
+			return err
+		}
+		link, err := paymentLinks.Get(payload.PaymentLinkID)
+		if err != nil {
+			return fmt.Errorf("failed to load payment link: %w", err)
+		}
+		var lead models.Lead
+		if err := db.First(&lead, "id = ?", link.LeadID).Error; err != nil {
+			return fmt.Errorf("failed to load lead: %w", err)
+		}
+		var customer, berater models.User
+		if err := db.First(&customer, "id = ?", link.UserID).Error; err != nil {
+			return fmt.Errorf("failed to load customer: %w", err)
+		}
+		if err := db.First(&berater, "id = ?", link.CreatedBy).Error; err != nil {
+			return fmt.Errorf("failed to load Berater: %w", err)
+		}
+
+		offer := email.OfferData{
+			PackageName: link.Title,
+			Price:       link.Amount,
+			Currency:    link.Currency,
+			Message:     link.Description,
+			OfferURL:    link.URL,
+		}
+		if link.ExpiresAt != nil {
+			offer.ValidUntil = i18n.FormatDate(i18n.ParseOrDefault(customer.Language), *link.ExpiresAt)
+		}
+		return emailService.SendOffer(&lead, &customer, &berater, offer)
+	})
+
+	relay.Register(outbox.TopicPDFGenerate, func(message *models.OutboxMessage) error {
+		var payload outbox.PDFJobMessage
+		if err := outbox.Decode(message, &payload); err != nil {
+			return err
+		}
+		return pdfService.Generate(payload.JobID)
+	})
+
+	relay.Register(outbox.TopicBackupRestore, func(message *models.OutboxMessage) error {
+		var payload outbox.BackupRestoreMessage
+		if err := outbox.Decode(message, &payload); err != nil {
+			return err
+		}
+		_, err := backups.Restore(context.Background(), payload.BackupID)
+		return err
+	})
+}
+
+func loadPaidBooking(db *gorm.DB, message *models.OutboxMessage) (*models.Booking, *models.Payment, error) {
+	var payload outbox.PaymentMessage
+	if err := outbox.Decode(message, &payload); err != nil {
+		return nil, nil, err
+	}
+	var booking models.Booking
+	if err := db.First(&booking, "id = ?", payload.BookingID).Error; err != nil {
+		return nil, nil, fmt.Errorf("failed to load booking: %w", err)
+	}
+	var payment models.Payment
+	if err := db.First(&payment, "id = ?", payload.PaymentID).Error; err != nil {
+		return nil, nil, fmt.Errorf("failed to load payment: %w", err)
+	}
+	return &booking, &payment, nil
+}
+
+func loadAssignment(db *gorm.DB, message *models.OutboxMessage) (*models.Lead, *models.User, *models.User, error) {
+	var payload outbox.AssignmentMessage
+	if err := outbox.Decode(message, &payload); err != nil {
+		return nil, nil, nil, err
+	}
+	var lead models.Lead
+	if err := db.First(&lead, "id = ?", payload.LeadID).Error; err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to load lead: %w", err)
+	}
+	var berater models.User
+	if err := db.First(&berater, "id = ?", payload.BeraterID).Error; err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to load Berater: %w", err)
+	}
+	var customer models.User
+	if err := db.First(&customer, "id = ?", lead.UserID).Error; err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to load customer: %w", err)
+	}
+	return &lead, &berater, &customer, nil
+}
@@ -0,0 +1,163 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"elterngeld-portal/config"
+	"elterngeld-portal/internal/abuse"
+	"elterngeld-portal/internal/activity"
+	"elterngeld-portal/internal/announcements"
+	"elterngeld-portal/internal/applicants"
+	"elterngeld-portal/internal/backup"
+	"elterngeld-portal/internal/chatnotify"
+	"elterngeld-portal/internal/credit"
+	"elterngeld-portal/internal/digest"
+	"elterngeld-portal/internal/emailcheck"
+	"elterngeld-portal/internal/holds"
+	"elterngeld-portal/internal/leadaging"
+	"elterngeld-portal/internal/newsletter"
+	"elterngeld-portal/internal/noshow"
+	"elterngeld-portal/internal/scheduler"
+	"elterngeld-portal/internal/verification"
+	"elterngeld-portal/internal/whatsapp"
+
+	"go.uber.org/zap"
+)
+
+// scheduledJobs are the services whose recurring work runs on the scheduler
+type scheduledJobs struct {
+	verification  *verification.Service
+	abuse         *abuse.Service
+	emailCheck    *emailcheck.Service
+	chatNotifier  *chatnotify.Notifier
+	newsletter    *newsletter.Service
+	whatsApp      *whatsapp.Service
+	activity      *activity.Service
+	digest        *digest.Service
+	holds         *holds.Service
+	noShow        *noshow.Service
+	credit        *credit.Service
+	leadAging     *leadaging.Service
+	applicants    *applicants.Service
+	announcements *announcements.Service
+	backups       *backup.Service
+}
+
+// registerScheduledJobs registers the recurring jobs. Their schedules follow
+// the check intervals of the configuration; a job whose interval is not
+// positive is disabled, as before the scheduler. Backups run at the times of
+// their cron expression instead.
+func registerScheduledJobs(jobs *scheduler.Service, cfg *config.Config, logger *zap.Logger, services scheduledJobs) {
+	register := func(name, description string, interval time.Duration, run scheduler.Func) {
+		if interval <= 0 {
+			return
+		}
+		if err := jobs.Register(name, description, "@every "+interval.String(), run); err != nil {
+			logger.Error("Failed to register scheduled job", zap.String("job", name), zap.Error(err))
+		}
+	}
+	count := func(format string, run func() (int, error)) scheduler.Func {
+		return func(ctx context.Context) (string, error) {
+			n, err := run()
+			return fmt.Sprintf(format, n), err
+		}
+	}
+
+	register("pending_accounts_cleanup", "Remove accounts whose email address was never verified",
+		cfg.Auth.PendingCleanupInterval, count("%d pending accounts removed", services.verification.CleanupPending))
+
+	if cfg.Abuse.Retention > 0 {
+		register("submission_checks_purge", "Remove abuse checks of passed submissions after the retention period",
+			cfg.Abuse.CleanupInterval, func(ctx context.Context) (string, error) {
+				pruned, err := services.abuse.Prune()
+				return fmt.Sprintf("%d submission checks removed", pruned), err
+			})
+	}
+
+	register("email_checks", "Check unchecked email addresses for deliverability",
+		cfg.Abuse.EmailCheckInterval, func(ctx context.Context) (string, error) {
+			checked, err := services.emailCheck.CheckPending(ctx)
+			return fmt.Sprintf("%d email addresses checked", checked), err
+		})
+
+	register("chat_sla", "Alert chat channels about leads and inbox items waiting beyond their SLA",
+		cfg.Chat.SLACheckInterval, func(ctx context.Context) (string, error) {
+			services.chatNotifier.CheckSLA()
+			services.chatNotifier.CheckUnclaimed()
+			return "", nil
+		})
+
+	register("newsletter_sync", "Sync consenting customers and their segments with the newsletter provider",
+		cfg.Newsletter.SyncInterval, func(ctx context.Context) (string, error) {
+			result, err := services.newsletter.Sync(ctx)
+			if err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("%d subscribed, %d unsubscribed, %d failed", result.Subscribed, result.Unsubscribed, result.Failed), nil
+		})
+
+	if services.whatsApp.Enabled() {
+		register("whatsapp_reminders", "Send WhatsApp reminders of upcoming bookings",
+			cfg.WhatsApp.CheckInterval, func(ctx context.Context) (string, error) {
+				sent, err := services.whatsApp.SendReminders(ctx)
+				return fmt.Sprintf("%d reminders sent", sent), err
+			})
+	}
+
+	register("activity_digests", "Send the activity digests of followed leads",
+		cfg.Digest.CheckInterval, func(ctx context.Context) (string, error) {
+			services.activity.SendDigests()
+			return "", nil
+		})
+
+	register("notification_digests", "Send hourly and daily notification digests",
+		cfg.Digest.CheckInterval, count("%d digests sent", services.digest.SendDigests))
+
+	register("timeslot_holds", "Release expired timeslot holds",
+		cfg.Booking.HoldCheckInterval, count("%d holds released", services.holds.ExpireHolds))
+
+	register("no_shows", "Detect no-shows and send their follow-ups",
+		cfg.NoShow.CheckInterval, func(ctx context.Context) (string, error) {
+			detected, err := services.noShow.DetectNoShows()
+			if err != nil {
+				return "", err
+			}
+			followUps, err := services.noShow.SendFollowUps()
+			return fmt.Sprintf("%d no-shows detected, %d follow-ups sent", detected, followUps), err
+		})
+
+	// Credit of bookings cancelled after checkout is returned on the hold schedule
+	register("credit_reconciliation", "Return the credit of bookings cancelled after checkout",
+		cfg.Booking.HoldCheckInterval, count("%d credits restored", services.credit.RestoreCancelled))
+
+	register("lead_aging", "Apply the lead aging rules",
+		cfg.LeadAging.CheckInterval, func(ctx context.Context) (string, error) {
+			summary, err := services.leadAging.Apply()
+			if err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("%d reactivated, %d marked inactive, %d win-back emails, %d closed",
+				summary.Reactivated, summary.MarkedInactive, summary.WinBackEmails, summary.Closed), nil
+		})
+
+	register("applicant_retention", "Anonymize applications past the retention period",
+		cfg.Recruiting.RetentionCheckInterval, count("%d applications anonymized", services.applicants.AnonymizeExpired))
+
+	register("announcements", "Publish scheduled announcements",
+		cfg.Announcement.CheckInterval, count("%d announcements published", services.announcements.PublishDue))
+
+	if cfg.Backup.Schedule != "" {
+		err := jobs.Register(backup.JobName, "Back up the database and document storage", cfg.Backup.Schedule, func(ctx context.Context) (string, error) {
+			manifest, err := services.backups.Create(ctx)
+			if err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("backup %s with %d files", manifest.ID, manifest.Files), nil
+		})
+		if err != nil {
+			logger.Error("Failed to register scheduled job", zap.String("job", backup.JobName), zap.Error(err))
+		}
+	}
+}
@@ -1,13 +1,92 @@
 package server
 
 import (
+	"context"
+	"net"
+	"sync"
 	"time"
 
 	"elterngeld-portal/config"
+	"elterngeld-portal/internal/abuse"
+	"elterngeld-portal/internal/accesstokens"
+	"elterngeld-portal/internal/addresses"
+	"elterngeld-portal/internal/activity"
+	"elterngeld-portal/internal/activitylog"
+	"elterngeld-portal/internal/analytics"
+	"elterngeld-portal/internal/announcements"
+	"elterngeld-portal/internal/applicants"
+	"elterngeld-portal/internal/availability"
+	"elterngeld-portal/internal/backup"
+	"elterngeld-portal/internal/beraters"
+	"elterngeld-portal/internal/blog"
+	"elterngeld-portal/internal/bookingstate"
+	"elterngeld-portal/internal/calculator"
+	"elterngeld-portal/internal/capacity"
+	"elterngeld-portal/internal/catalog"
+	"elterngeld-portal/internal/changelog"
+	"elterngeld-portal/internal/casefile"
+	"elterngeld-portal/internal/chatnotify"
+	"elterngeld-portal/internal/comments"
+	"elterngeld-portal/internal/content"
+	"elterngeld-portal/internal/credit"
 	"elterngeld-portal/internal/database"
+	"elterngeld-portal/internal/digest"
+	"elterngeld-portal/internal/documents"
+	"elterngeld-portal/internal/experiments"
+	"elterngeld-portal/internal/exports"
+	"elterngeld-portal/internal/email"
+	"elterngeld-portal/internal/emailcheck"
+	"elterngeld-portal/internal/evaluations"
+	"elterngeld-portal/internal/families"
 	"elterngeld-portal/internal/handlers"
+	"elterngeld-portal/internal/holidays"
+	"elterngeld-portal/internal/holds"
+	"elterngeld-portal/internal/inbound"
+	"elterngeld-portal/internal/inbox"
+	"elterngeld-portal/internal/integrations"
+	"elterngeld-portal/internal/interviews"
+	"elterngeld-portal/internal/jobfeed"
+	"elterngeld-portal/internal/leadaging"
+	"elterngeld-portal/internal/ledger"
+	"elterngeld-portal/internal/lock"
+	"elterngeld-portal/internal/mailqueue"
+	"elterngeld-portal/internal/marketing"
+	"elterngeld-portal/internal/mergefields"
 	"elterngeld-portal/internal/middleware"
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/internal/newsletter"
+	"elterngeld-portal/internal/noshow"
+	"elterngeld-portal/internal/offboarding"
+	"elterngeld-portal/internal/outbox"
+	"elterngeld-portal/internal/paymentlinks"
+	"elterngeld-portal/internal/onboarding"
+	"elterngeld-portal/internal/pdf"
+	"elterngeld-portal/internal/pipeline"
+	"elterngeld-portal/internal/postalcodes"
+	"elterngeld-portal/internal/questionnaires"
+	"elterngeld-portal/internal/quota"
+	"elterngeld-portal/internal/reassignment"
+	"elterngeld-portal/internal/refunds"
+	"elterngeld-portal/internal/replies"
+	"elterngeld-portal/internal/savedviews"
+	"elterngeld-portal/internal/scheduler"
+	"elterngeld-portal/internal/scenarios"
+	"elterngeld-portal/internal/servicekeys"
+	"elterngeld-portal/internal/sessions"
+	"elterngeld-portal/internal/settings"
+	"elterngeld-portal/internal/shortlink"
+	"elterngeld-portal/internal/sitemap"
+	"elterngeld-portal/internal/snippets"
+	"elterngeld-portal/internal/submissions"
+	"elterngeld-portal/internal/summaries"
+	"elterngeld-portal/internal/tax"
+	"elterngeld-portal/internal/trash"
+	"elterngeld-portal/internal/verification"
+	"elterngeld-portal/internal/webhooks"
+	"elterngeld-portal/internal/whatsapp"
 	"elterngeld-portal/pkg/auth"
+	"elterngeld-portal/pkg/captcha"
+	"elterngeld-portal/pkg/timeutil"
 
 	"github.com/gin-gonic/gin"
 	swaggerFiles "github.com/swaggo/files"
@@ -23,6 +102,9 @@ type Server struct {
 	logger     *zap.Logger
 	jwtService *auth.JWTService
 	db         *gorm.DB
+
+	// Captcha verification for unauthenticated auth endpoints; nil when disabled
+	captchaVerifier *captcha.Verifier
 	
 	// Handlers
 	authHandler     *handlers.AuthHandler
@@ -33,6 +115,81 @@ type Server struct {
 	documentHandler *handlers.DocumentHandler
 	todoHandler     *handlers.TodoHandler
 	contactHandler  *handlers.ContactHandler
+	inboxHandler    *handlers.InboxHandler
+	replyHandler    *handlers.ReplyHandler
+
+	inboundEmailHandler *handlers.InboundEmailHandler
+	shortLinkHandler    *handlers.ShortLinkHandler
+	holidayHandler      *handlers.HolidayHandler
+	onboardingHandler   *handlers.BeraterOnboardingHandler
+	beraterHandler      *handlers.BeraterHandler
+	offboardingHandler  *handlers.BeraterOffboardingHandler
+	reassignmentHandler *handlers.BookingReassignmentHandler
+	webhookHandler      *handlers.WebhookHandler
+	integrationHandler  *handlers.IntegrationHandler
+	accessTokenHandler  *handlers.AccessTokenHandler
+	serviceKeyHandler   *handlers.ServiceKeyHandler
+	submissionHandler   *handlers.SubmissionReviewHandler
+	chatHandler         *handlers.ChatNotificationHandler
+	analyticsHandler    *handlers.AnalyticsHandler
+	experimentHandler   *handlers.ExperimentHandler
+	marketingHandler    *handlers.MarketingHandler
+	activityHandler     *handlers.ActivityHandler
+	caseFileHandler     *handlers.CaseFileHandler
+	noShowHandler       *handlers.NoShowHandler
+	summaryHandler      *handlers.ConsultationSummaryHandler
+	capacityHandler     *handlers.CapacityHandler
+	pipelineHandler     *handlers.PipelineHandler
+	trashHandler        *handlers.TrashHandler
+	savedViewHandler    *handlers.SavedViewHandler
+	snippetHandler      *handlers.SnippetHandler
+	whatsAppHandler     *handlers.WhatsAppHandler
+	addressHandler      *handlers.AddressHandler
+	postalCodeHandler   *handlers.PostalCodeHandler
+	calculatorHandler   *handlers.CalculatorHandler
+	scenarioHandler     *handlers.ScenarioHandler
+	bescheidHandler     *handlers.ApplicationSubmissionHandler
+	familyHandler       *handlers.FamilyHandler
+	intakeHandler       *handlers.QuestionnaireHandler
+	digestHandler       *handlers.DigestHandler
+	exportHandler       *handlers.ExportHandler
+	outboxHandler       *handlers.OutboxHandler
+	schedulerHandler    *handlers.SchedulerHandler
+	backupHandler       *handlers.BackupHandler
+	pdfHandler          *handlers.PDFHandler
+	mergeFieldHandler   *handlers.MergeFieldHandler
+	settingHandler      *handlers.SettingHandler
+	contentHandler      *handlers.ContentHandler
+	blogHandler         *handlers.BlogHandler
+	sitemapHandler      *handlers.SitemapHandler
+	jobFeedHandler      *handlers.JobFeedHandler
+	interviewHandler    *handlers.InterviewHandler
+	applicationHandler  *handlers.JobApplicationHandler
+	evaluationHandler   *handlers.EvaluationHandler
+	creditHandler       *handlers.CreditHandler
+	couponHandler       *handlers.CouponHandler
+	paymentLinkHandler  *handlers.PaymentLinkHandler
+	refundHandler       *handlers.RefundHandler
+	statementHandler    *handlers.StatementHandler
+	bookingStateHandler *handlers.BookingStateHandler
+	leadAgingHandler    *handlers.LeadAgingHandler
+	announcementHandler *handlers.AnnouncementHandler
+	changelogHandler    *handlers.ChangelogHandler
+
+	// Integration API keys for Zapier and Make
+	integrationService *integrations.Service
+	accessTokenService *accesstokens.Service
+	serviceKeyService  *servicekeys.Service
+
+	// Background jobs
+	webhookReceiver *webhooks.Receiver
+	mailQueue       *mailqueue.Service
+	outboxService   *outbox.Service
+	scheduler       *scheduler.Service
+	// jobLocks keeps scheduled jobs to one instance at a time
+	jobLocks *lock.Service
+	// jobs tracks the running background jobs so a shutdown can wait for them
+	jobs sync.WaitGroup
 }
 
 // New creates a new server instance
@@ -56,15 +213,218 @@ func New(cfg *config.Config, logger *zap.Logger) *Server {
 		logger.Fatal("Failed to connect to database", zap.Error(err))
 	}
 
+	// Initialize captcha verification
+	captchaVerifier, err := captcha.NewVerifier(cfg)
+	if err != nil {
+		logger.Fatal("Invalid captcha configuration", zap.Error(err))
+	}
+
+	// Initialize services
+	shortLinkService := shortlink.NewService(db, logger, cfg)
+	emailService := email.NewEmailService(cfg, logger).WithShortLinks(shortLinkService)
+	mailQueue := mailqueue.NewService(db, logger, emailService)
+	holidayService := holidays.NewService(db, logger)
+	availabilityService := availability.NewService(db, logger, holidayService)
+	onboardingService := onboarding.NewService(db, logger, availabilityService)
+	postalCodeService := postalcodes.NewService(db, logger)
+	calculatorService := calculator.NewService(logger)
+	beraterService := beraters.NewService(db, logger, postalCodeService)
+	documentService := documents.NewService(db, logger, cfg)
+	quotaService := quota.NewService(db, logger, cfg)
+	commentService := comments.NewService(db, logger, cfg, documentService, quotaService)
+	activityLog := activitylog.NewService(db, logger)
+	offboardingService := offboarding.NewService(db, logger)
+	reassignmentService := reassignment.NewService(db, logger, availabilityService)
+	verificationService := verification.NewService(db, logger, cfg, emailService)
+	passwordPolicy := auth.NewPasswordPolicy(cfg)
+	webhookReceiver := webhooks.NewReceiver(db, logger)
+	integrationService := integrations.NewService(db, logger)
+	accessTokenService := accesstokens.NewService(db, logger)
+	serviceKeyService := servicekeys.NewService(db, logger)
+	abuseService := abuse.NewService(db, logger, cfg)
+	emailCheckService := emailcheck.NewService(db, logger, cfg, net.DefaultResolver, abuseService)
+	settingsService := settings.NewService(db, logger, cfg)
+	chatNotifier := chatnotify.NewNotifier(db, logger, cfg, settingsService)
+	outboxService := outbox.NewService(db, logger)
+
+	// Initialize newsletter sync
+	newsletterProvider, err := newsletter.NewProvider(cfg)
+	if err != nil {
+		logger.Fatal("Invalid newsletter configuration", zap.Error(err))
+	}
+	newsletterService := newsletter.NewService(db, logger, newsletterProvider)
+
+	// Initialize WhatsApp messaging
+	whatsAppProvider, err := whatsapp.NewProvider(cfg)
+	if err != nil {
+		logger.Fatal("Invalid WhatsApp configuration", zap.Error(err))
+	}
+	whatsAppService := whatsapp.NewService(db, logger, cfg, whatsAppProvider, activityLog)
+
+	// Initialize address validation
+	addressProvider, err := addresses.NewProvider(cfg)
+	if err != nil {
+		logger.Fatal("Invalid address provider configuration", zap.Error(err))
+	}
+	addressService := addresses.NewService(logger, addressProvider)
+
+	digestService := digest.NewService(db, logger, cfg, emailService)
+	analyticsService := analytics.NewService(db, logger, cfg.Analytics.SessionTimeout)
+	experimentService := experiments.NewService(db, logger)
+	marketingService := marketing.NewService(db, logger)
+	activityService := activity.NewService(db, logger, cfg, emailService)
+	caseFileService := casefile.NewService(db, logger)
+	// Every status change of a booking goes through the state machine, which
+	// records it on the lead and sends the confirmation of paid bookings
+	bookingStates := bookingstate.NewService(db, logger)
+	bookingStates.OnChange(bookingstate.RecordActivity(activityLog))
+	bookingStates.OnEnter(models.BookingStatusConfirmed, bookingstate.SendConfirmation(outboxService))
+	holdService := holds.NewService(db, logger, cfg, bookingStates)
+	creditService := credit.NewService(db, logger)
+	noShowService := noshow.NewService(db, logger, cfg, emailService, bookingStates)
+	summaryService := summaries.NewService(db, logger)
+	capacityService := capacity.NewService(db, logger)
+	leadAgingService := leadaging.NewService(db, logger)
+	announcementService := announcements.NewService(db, logger)
+	jobScheduler := scheduler.NewService(db, logger, timeutil.LoadLocation(cfg.App.Timezone))
+	applicantService := applicants.NewService(db, logger, cfg)
+	pipelineService := pipeline.NewService(db, logger)
+	trashService := trash.NewService(db, logger)
+	savedViewService := savedviews.NewService(db, logger)
+	mergeFieldService := mergefields.NewService(db, logger)
+	snippetService := snippets.NewService(db, logger, mergeFieldService)
+	exportService := exports.NewService(db, logger, cfg)
+
 	// Initialize handlers
-	authHandler := handlers.NewAuthHandler(db, logger, jwtService, cfg)
+	authHandler := handlers.NewAuthHandler(db, logger, jwtService, cfg, verificationService, passwordPolicy, sessions.NewService(db, logger, jwtService), abuseService, documentService)
 	userHandler := handlers.NewUserHandler(db, logger)
-	leadHandler := handlers.NewLeadHandler(db, logger)
-	bookingHandler := handlers.NewBookingHandler(db, logger)
-	paymentHandler := handlers.NewPaymentHandler(db, logger, cfg)
-	documentHandler := handlers.NewDocumentHandler(db, logger, cfg)
-	todoHandler := handlers.NewTodoHandler(db, logger)
-	contactHandler := handlers.NewContactHandler(db, logger)
+	leadHandler := handlers.NewLeadHandler(db, logger, beraterService, commentService, activityLog, outboxService)
+	catalogService := catalog.NewService(db, logger, cfg, creditService)
+	taxService := tax.NewService(logger, cfg, tax.NewValidator(cfg))
+	paymentLinkService := paymentlinks.NewService(db, logger, cfg, catalogService, shortLinkService, outboxService)
+	ledgerService := ledger.NewService(db, logger)
+	bookingHandler := handlers.NewBookingHandler(db, logger, holidayService, experimentService, holdService, availabilityService, settingsService, addressService, postalCodeService, catalogService)
+	paymentHandler := handlers.NewPaymentHandler(db, logger, cfg, experimentService, holdService, creditService, outboxService, catalogService, taxService, paymentLinkService, ledgerService, bookingStates)
+	documentHandler := handlers.NewDocumentHandler(db, logger, cfg, documentService, quotaService)
+	todoHandler := handlers.NewTodoHandler(db, logger, activityLog)
+	questionnaireService := questionnaires.NewService(db, logger)
+	inboxService := inbox.NewService(db, logger, activityLog, outboxService)
+	contactHandler := handlers.NewContactHandler(db, logger, activityLog, outboxService, abuseService, questionnaireService, inboxService)
+	inboxHandler := handlers.NewInboxHandler(db, logger, inboxService)
+	replyHandler := handlers.NewReplyHandler(db, logger, replies.NewService(db, logger, cfg, emailService, snippetService, activityLog))
+	inboundEmailHandler := handlers.NewInboundEmailHandler(db, logger, inbound.NewProcessor(db, logger, cfg))
+	shortLinkHandler := handlers.NewShortLinkHandler(db, logger, shortLinkService)
+	holidayHandler := handlers.NewHolidayHandler(db, logger, holidayService, postalCodeService)
+	onboardingHandler := handlers.NewBeraterOnboardingHandler(db, logger, onboardingService, availabilityService, emailService, passwordPolicy)
+	beraterHandler := handlers.NewBeraterHandler(db, logger, beraterService)
+	offboardingHandler := handlers.NewBeraterOffboardingHandler(db, logger, offboardingService)
+	reassignmentHandler := handlers.NewBookingReassignmentHandler(db, logger, reassignmentService)
+	webhookHandler := handlers.NewWebhookHandler(db, logger, webhookReceiver)
+	integrationHandler := handlers.NewIntegrationHandler(db, logger, integrationService, outboxService)
+	accessTokenHandler := handlers.NewAccessTokenHandler(db, logger, accessTokenService)
+	serviceKeyHandler := handlers.NewServiceKeyHandler(db, logger, serviceKeyService)
+	submissionHandler := handlers.NewSubmissionReviewHandler(db, logger, abuseService, inboxService, verificationService)
+	chatHandler := handlers.NewChatNotificationHandler(db, logger, chatNotifier)
+	analyticsHandler := handlers.NewAnalyticsHandler(db, logger, analyticsService)
+	experimentHandler := handlers.NewExperimentHandler(db, logger, experimentService)
+	marketingHandler := handlers.NewMarketingHandler(db, logger, marketingService)
+	activityHandler := handlers.NewActivityHandler(db, logger, activityService, activityLog)
+	caseFileHandler := handlers.NewCaseFileHandler(db, logger, caseFileService)
+	noShowHandler := handlers.NewNoShowHandler(db, logger, noShowService)
+	summaryHandler := handlers.NewConsultationSummaryHandler(db, logger, summaryService)
+	creditHandler := handlers.NewCreditHandler(db, logger, creditService)
+	couponHandler := handlers.NewCouponHandler(db, logger, catalogService)
+	paymentLinkHandler := handlers.NewPaymentLinkHandler(db, logger, cfg, paymentLinkService, taxService)
+	statementHandler := handlers.NewStatementHandler(db, logger, ledgerService)
+	bookingStateHandler := handlers.NewBookingStateHandler(db, logger, bookingStates)
+	refundHandler := handlers.NewRefundHandler(db, logger, refunds.NewService(db, logger, cfg, creditService, activityLog, refunds.StripeRefunder{}))
+	capacityHandler := handlers.NewCapacityHandler(db, logger, capacityService)
+	leadAgingHandler := handlers.NewLeadAgingHandler(db, logger, leadAgingService)
+	announcementHandler := handlers.NewAnnouncementHandler(db, logger, announcementService)
+	changelogHandler := handlers.NewChangelogHandler(db, logger, changelog.NewService(db, logger))
+	pipelineHandler := handlers.NewPipelineHandler(db, logger, pipelineService)
+	trashHandler := handlers.NewTrashHandler(db, logger, trashService)
+	savedViewHandler := handlers.NewSavedViewHandler(db, logger, savedViewService)
+	snippetHandler := handlers.NewSnippetHandler(db, logger, snippetService)
+	whatsAppHandler := handlers.NewWhatsAppHandler(db, logger, whatsAppService)
+	addressHandler := handlers.NewAddressHandler(db, logger, addressService)
+	postalCodeHandler := handlers.NewPostalCodeHandler(db, logger, postalCodeService)
+	calculatorHandler := handlers.NewCalculatorHandler(db, logger, calculatorService)
+	scenarioHandler := handlers.NewScenarioHandler(db, logger, scenarios.NewService(db, logger, calculatorService))
+	bescheidHandler := handlers.NewApplicationSubmissionHandler(db, logger, submissions.NewService(db, logger, activityLog))
+	familyHandler := handlers.NewFamilyHandler(db, logger, families.NewService(db, logger))
+	intakeHandler := handlers.NewQuestionnaireHandler(db, logger, questionnaireService)
+	digestHandler := handlers.NewDigestHandler(db, logger, digestService)
+	exportHandler := handlers.NewExportHandler(db, logger, exportService)
+	outboxHandler := handlers.NewOutboxHandler(db, logger, outboxService)
+	schedulerHandler := handlers.NewSchedulerHandler(db, logger, jobScheduler)
+	pdfService := pdf.NewService(db, logger, cfg, outboxService)
+	backupService := backup.NewService(db, logger, cfg, outboxService)
+	backupHandler := handlers.NewBackupHandler(db, logger, backupService, jobScheduler)
+	pdfHandler := handlers.NewPDFHandler(db, logger, pdfService, mergeFieldService)
+	mergeFieldHandler := handlers.NewMergeFieldHandler(db, logger)
+	settingHandler := handlers.NewSettingHandler(db, logger, settingsService)
+	contentHandler := handlers.NewContentHandler(db, logger, content.NewService(db, logger))
+	blogHandler := handlers.NewBlogHandler(db, logger, blog.NewService(db, logger, cfg))
+	sitemapHandler := handlers.NewSitemapHandler(db, logger, sitemap.NewService(db, logger, cfg))
+	jobFeedService := jobfeed.NewService(db, logger, cfg)
+	jobFeedHandler := handlers.NewJobFeedHandler(db, logger, jobFeedService)
+	interviewHandler := handlers.NewInterviewHandler(db, logger, interviews.NewService(db, logger, cfg), emailService)
+	applicationHandler := handlers.NewJobApplicationHandler(db, logger, applicantService, jobFeedService, emailService)
+	evaluationHandler := handlers.NewEvaluationHandler(db, logger, evaluations.NewService(db, logger))
+
+	// Register webhook providers
+	webhookReceiver.Register(webhooks.Provider{
+		Name:     "stripe",
+		Verifier: webhooks.StripeSignature{Secret: cfg.Stripe.WebhookSecret},
+		Parse:    webhooks.JSONEventInfo,
+		Handle:   paymentHandler.HandleStripeEvent,
+	})
+	newsletterVerifier := webhooks.SharedSecret{Header: webhooks.TokenHeader, Secret: cfg.Newsletter.WebhookSecret}
+	webhookReceiver.Register(webhooks.Provider{
+		Name:     newsletter.ProviderMailchimp,
+		Verifier: newsletterVerifier,
+		Parse:    newsletter.MailchimpEventInfo,
+		Handle:   newsletterService.HandleWebhook,
+	})
+	webhookReceiver.Register(webhooks.Provider{
+		Name:     newsletter.ProviderBrevo,
+		Verifier: newsletterVerifier,
+		Parse:    newsletter.BrevoEventInfo,
+		Handle:   newsletterService.HandleWebhook,
+	})
+	whatsAppVerifier := webhooks.SharedSecret{Header: webhooks.TokenHeader, Secret: cfg.WhatsApp.WebhookSecret}
+	webhookReceiver.Register(webhooks.Provider{
+		Name:     whatsapp.Provider360dialog,
+		Verifier: whatsAppVerifier,
+		Parse:    whatsapp.Dialog360EventInfo,
+		Handle:   whatsAppService.HandleWebhook,
+	})
+	webhookReceiver.Register(webhooks.Provider{
+		Name:     whatsapp.ProviderTwilio,
+		Verifier: whatsAppVerifier,
+		Parse:    whatsapp.TwilioEventInfo,
+		Handle:   whatsAppService.HandleWebhook,
+	})
+
+	registerOutboxTopics(outboxService, db, chatNotifier, emailService, mailQueue, paymentLinkService, pdfService, backupService)
+	registerScheduledJobs(jobScheduler, cfg, logger, scheduledJobs{
+		verification:  verificationService,
+		abuse:         abuseService,
+		emailCheck:    emailCheckService,
+		chatNotifier:  chatNotifier,
+		newsletter:    newsletterService,
+		whatsApp:      whatsAppService,
+		activity:      activityService,
+		digest:        digestService,
+		holds:         holdService,
+		noShow:        noShowService,
+		credit:        creditService,
+		leadAging:     leadAgingService,
+		applicants:    applicantService,
+		announcements: announcementService,
+		backups:       backupService,
+	})
 
 	server := &Server{
 		Router:          router,
@@ -72,6 +432,9 @@ func New(cfg *config.Config, logger *zap.Logger) *Server {
 		logger:          logger,
 		jwtService:      jwtService,
 		db:              db,
+
+		captchaVerifier: captchaVerifier,
+
 		authHandler:     authHandler,
 		userHandler:     userHandler,
 		leadHandler:     leadHandler,
@@ -80,6 +443,76 @@ func New(cfg *config.Config, logger *zap.Logger) *Server {
 		documentHandler: documentHandler,
 		todoHandler:     todoHandler,
 		contactHandler:  contactHandler,
+		inboxHandler:    inboxHandler,
+		replyHandler:    replyHandler,
+
+		inboundEmailHandler: inboundEmailHandler,
+		shortLinkHandler:    shortLinkHandler,
+		holidayHandler:      holidayHandler,
+		onboardingHandler:   onboardingHandler,
+		beraterHandler:      beraterHandler,
+		offboardingHandler:  offboardingHandler,
+		reassignmentHandler: reassignmentHandler,
+		webhookHandler:      webhookHandler,
+		integrationHandler:  integrationHandler,
+		accessTokenHandler:  accessTokenHandler,
+		serviceKeyHandler:   serviceKeyHandler,
+		submissionHandler:   submissionHandler,
+		chatHandler:         chatHandler,
+		analyticsHandler:    analyticsHandler,
+		experimentHandler:   experimentHandler,
+		marketingHandler:    marketingHandler,
+		activityHandler:     activityHandler,
+		caseFileHandler:     caseFileHandler,
+		noShowHandler:       noShowHandler,
+		summaryHandler:      summaryHandler,
+		capacityHandler:     capacityHandler,
+		pipelineHandler:     pipelineHandler,
+		trashHandler:        trashHandler,
+		savedViewHandler:    savedViewHandler,
+		snippetHandler:      snippetHandler,
+		whatsAppHandler:     whatsAppHandler,
+		addressHandler:      addressHandler,
+		postalCodeHandler:   postalCodeHandler,
+		calculatorHandler:   calculatorHandler,
+		scenarioHandler:     scenarioHandler,
+		bescheidHandler:     bescheidHandler,
+		familyHandler:       familyHandler,
+		intakeHandler:       intakeHandler,
+		digestHandler:       digestHandler,
+		exportHandler:       exportHandler,
+		outboxHandler:       outboxHandler,
+		schedulerHandler:    schedulerHandler,
+		backupHandler:       backupHandler,
+		pdfHandler:          pdfHandler,
+		mergeFieldHandler:   mergeFieldHandler,
+		settingHandler:      settingHandler,
+		contentHandler:      contentHandler,
+		blogHandler:         blogHandler,
+		sitemapHandler:      sitemapHandler,
+		jobFeedHandler:      jobFeedHandler,
+		interviewHandler:    interviewHandler,
+		applicationHandler:  applicationHandler,
+		evaluationHandler:   evaluationHandler,
+		creditHandler:       creditHandler,
+		couponHandler:       couponHandler,
+		paymentLinkHandler:  paymentLinkHandler,
+		refundHandler:       refundHandler,
+		statementHandler:    statementHandler,
+		bookingStateHandler: bookingStateHandler,
+		leadAgingHandler:    leadAgingHandler,
+		announcementHandler: announcementHandler,
+		changelogHandler:    changelogHandler,
+
+		integrationService: integrationService,
+		accessTokenService: accessTokenService,
+		serviceKeyService:  serviceKeyService,
+
+		webhookReceiver: webhookReceiver,
+		mailQueue:       mailQueue,
+		outboxService:   outboxService,
+		scheduler:       jobScheduler,
+		jobLocks:        lock.NewService(db, logger),
 	}
 
 	// Setup middleware
@@ -91,12 +524,82 @@ func New(cfg *config.Config, logger *zap.Logger) *Server {
 	return server
 }
 
+const (
+	// webhookWorkers is the number of goroutines processing received webhooks
+	webhookWorkers = 2
+	// schedulerLockTTL is how long an instance holds the lease on a scheduled
+	// job without renewing it; another instance takes the job over after that
+	schedulerLockTTL = 30 * time.Second
+)
+
+// StartBackgroundJobs starts the queue workers and the scheduler of the
+// recurring jobs; they stop when the context is cancelled, see
+// DrainBackgroundJobs. The queue workers claim each item and run on every
+// instance; the scheduler runs on one instance at a time.
+func (s *Server) StartBackgroundJobs(ctx context.Context) {
+	s.runJob(func() { s.webhookReceiver.Run(ctx, webhookWorkers) })
+	s.runJob(func() { s.mailQueue.Run(ctx, s.config.Email.QueueInterval) })
+	s.runJob(func() { s.outboxService.Run(ctx, s.config.Outbox.RelayInterval) })
+	s.runScheduled(ctx, "scheduler", func(ctx context.Context) { s.scheduler.Run(ctx, s.config.Scheduler.TickInterval) })
+}
+
+// runJob runs a background job in its own goroutine and tracks it until it returns
+func (s *Server) runJob(job func()) {
+	s.jobs.Add(1)
+	go func() {
+		defer s.jobs.Done()
+		job()
+	}()
+}
+
+// runScheduled runs a periodic job on the instance that holds its lock
+func (s *Server) runScheduled(ctx context.Context, name string, job func(ctx context.Context)) {
+	s.runJob(func() { s.jobLocks.Lead(ctx, name, schedulerLockTTL, job) })
+}
+
+// DrainBackgroundJobs waits for the background jobs to return after their
+// context was cancelled, so emails, outbox messages and webhook events being
+// handled are finished. When ctx expires first, the ones still in flight are
+// handed back to their queues to be picked up again and false is returned.
+func (s *Server) DrainBackgroundJobs(ctx context.Context) bool {
+	drained := make(chan struct{})
+	go func() {
+		s.jobs.Wait()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+		return true
+	case <-ctx.Done():
+	}
+
+	if requeued, err := s.mailQueue.Requeue(); err != nil {
+		s.logger.Error("Failed to requeue emails", zap.Error(err))
+	} else if requeued > 0 {
+		s.logger.Warn("Requeued emails interrupted by shutdown", zap.Int64("count", requeued))
+	}
+	if requeued, err := s.outboxService.Requeue(); err != nil {
+		s.logger.Error("Failed to requeue outbox messages", zap.Error(err))
+	} else if requeued > 0 {
+		s.logger.Warn("Requeued outbox messages interrupted by shutdown", zap.Int64("count", requeued))
+	}
+	if requeued, err := s.webhookReceiver.Requeue(); err != nil {
+		s.logger.Error("Failed to requeue webhook events", zap.Error(err))
+	} else if requeued > 0 {
+		s.logger.Warn("Requeued webhook events interrupted by shutdown", zap.Int64("count", requeued))
+	}
+	return false
+}
+
 // setupMiddleware configures middleware
 func (s *Server) setupMiddleware() {
 	// Basic middleware
 	s.Router.Use(middleware.RequestIDMiddleware())
 	s.Router.Use(middleware.RecoveryMiddleware(s.logger))
-	s.Router.Use(middleware.SecurityHeadersMiddleware())
+	s.Router.Use(middleware.SecurityHeadersMiddleware(s.config.Security.HSTSMaxAge, s.config.Security.ContentSecurityPolicy))
+	s.Router.Use(middleware.LanguageMiddleware())
+	s.Router.Use(middleware.TimezoneMiddleware(s.config.App.Timezone))
 
 	// CORS middleware
 	s.Router.Use(middleware.CORSMiddleware(
@@ -111,12 +614,29 @@ func (s *Server) setupMiddleware() {
 	)
 	s.Router.Use(middleware.RateLimitMiddleware(rateLimiter, s.logger))
 
+	// Request body limits; routes with uploads or attachments get a larger one
+	s.Router.Use(middleware.BodyLimitMiddleware(s.config.Server.MaxBodySize, map[string]int64{
+		"POST /api/v1/documents":               s.config.Upload.MaxRequestSize,
+		"POST /api/v1/auth/me/avatar":          s.config.Upload.MaxRequestSize,
+		"POST /api/v1/leads/:id/comments":      s.config.Upload.MaxRequestSize,
+		"POST /api/v1/leads/:id/reply":         s.config.Upload.MaxRequestSize,
+		"POST /api/v1/contact/forms/:id/reply": s.config.Upload.MaxRequestSize,
+		"POST /api/v1/webhooks/inbound-email":  s.config.Upload.MaxRequestSize,
+	}))
+
+	// Compress text responses; registered before logging and masking so
+	// those see the uncompressed body
+	s.Router.Use(middleware.CompressionMiddleware(s.config.Server.CompressionMinSize))
+
 	// Logging middleware
 	if s.config.IsDevelopment() {
 		s.Router.Use(middleware.DetailedLoggingMiddleware(s.logger, false, false))
 	} else {
 		s.Router.Use(middleware.LoggingMiddleware(s.logger))
 	}
+
+	// Mask personal data for staff without the pii.read permission
+	s.Router.Use(middleware.PIIMaskingMiddleware(s.db, s.logger))
 }
 
 // setupRoutes configures API routes
@@ -129,7 +649,10 @@ func (s *Server) setupRoutes() {
 
 	// Swagger documentation
 	if s.config.IsDevelopment() {
-		s.Router.GET("/docs/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
+		// Swagger UI needs scripts and styles the API policy does not allow
+		s.Router.GET("/docs/*any", func(c *gin.Context) {
+			c.Header("Content-Security-Policy", "default-src 'self'; script-src 'self' 'unsafe-inline'; style-src 'self' 'unsafe-inline'; img-src 'self' data:")
+		}, ginSwagger.WrapHandler(swaggerFiles.Handler))
 	}
 
 	// API v1 routes
@@ -141,36 +664,134 @@ func (s *Server) setupRoutes() {
 			// Authentication routes
 			auth := public.Group("/auth")
 			{
-				auth.POST("/register", s.authHandler.Register)
+				// Endpoints that create accounts or send emails require a captcha when enabled
+				requireCaptcha := middleware.CaptchaMiddleware(s.captchaVerifier, s.logger)
+
+				auth.POST("/register", requireCaptcha, s.authHandler.Register)
 				auth.POST("/login", s.authHandler.Login)
-				auth.POST("/refresh", s.authHandler.RefreshToken)
-				auth.POST("/forgot-password", s.authHandler.ForgotPassword)
-				auth.POST("/reset-password", s.authHandler.ResetPassword)
+				auth.POST("/refresh", middleware.CSRFMiddleware(), s.authHandler.RefreshToken)
+				auth.GET("/csrf", s.authHandler.IssueCSRFToken)
+				auth.POST("/forgot-password", requireCaptcha, s.authHandler.ForgotPassword)
+				auth.POST("/reset-password", requireCaptcha, s.authHandler.ResetPassword)
 				auth.GET("/verify-email", s.authHandler.VerifyEmail)
+				auth.POST("/resend-verification", middleware.RateLimitMiddleware(
+					middleware.NewRateLimit(5, time.Hour), s.logger,
+				), requireCaptcha, s.authHandler.ResendVerification)
 			}
 
+			// Responses of the public website's GET routes get ETags, so
+			// unchanged data is answered with 304 Not Modified
+			cached := middleware.ConditionalGetMiddleware(s.config.Cache.MaxAge)
+
 			// Public package and timeslot routes
-			public.GET("/packages", s.bookingHandler.ListPackages)
-			public.GET("/packages/:id/addons", s.bookingHandler.GetPackageAddOns)
+			public.GET("/packages", cached, s.bookingHandler.ListPackages)
+			public.GET("/pricing", cached, s.bookingHandler.GetPricing)
+			public.GET("/packages/:id/addons", cached, s.bookingHandler.GetPackageAddOns)
+			public.GET("/packages/:id/questionnaire", cached, s.intakeHandler.GetPackageQuestionnaire)
 			public.GET("/timeslots/available", s.bookingHandler.GetAvailableTimeslots)
+			public.GET("/holidays", cached, s.holidayHandler.ListHolidays)
+			public.GET("/postal-codes/:postal_code", cached, s.postalCodeHandler.LookupPostalCode)
+
+			// Calculators for benefits related to Elterngeld
+			public.POST("/calculator/kindergeld", s.calculatorHandler.CalculateKindergeld)
+			public.POST("/calculator/maternity-offset", s.calculatorHandler.CalculateMaternityOffset)
+			public.POST("/calculator/part-time", s.calculatorHandler.CalculatePartTime)
+			public.GET("/settings/public", cached, s.settingHandler.GetPublicSettings)
+
+			// Published FAQ entries, info pages and Bundesland guides
+			public.GET("/content/:kind", cached, s.contentHandler.ListPublishedContent)
+			public.GET("/content/:kind/:slug", cached, s.contentHandler.GetPublishedContent)
+
+			// Blog and news posts with feeds
+			public.GET("/blog/posts", cached, s.blogHandler.ListPublishedPosts)
+			public.GET("/blog/posts/:slug", cached, s.blogHandler.GetPublishedPost)
+			public.GET("/blog/categories", cached, s.blogHandler.ListCategories)
+			public.GET("/blog/rss.xml", cached, s.blogHandler.RSSFeed)
+			public.GET("/blog/atom.xml", cached, s.blogHandler.AtomFeed)
+
+			// Job syndication for Indeed and Google for Jobs
+			public.GET("/jobs/indeed.xml", cached, s.jobFeedHandler.IndeedFeed)
+			public.GET("/jobs/:slug/jsonld", cached, s.jobFeedHandler.JobPosting)
+
+			// Applications from the website; applicants confirm and track them with the signed link from the confirmation email
+			public.POST("/jobs/:slug/applications", middleware.RateLimitMiddleware(
+				middleware.NewRateLimit(5, time.Hour), s.logger,
+			), middleware.CaptchaMiddleware(s.captchaVerifier, s.logger), s.applicationHandler.Apply)
+			public.GET("/applications/verify", s.applicationHandler.VerifyApplicationEmail)
+			public.GET("/applications/track", s.applicationHandler.TrackApplication)
+
+			// Applicants picking an interview slot with their invitation link
+			public.GET("/interviews/:token", s.interviewHandler.GetInvitation)
+			public.POST("/interviews/:token/schedule", s.interviewHandler.Schedule)
+
+			// Public Berater profiles
+			public.GET("/beraters", cached, s.beraterHandler.ListBeraters)
+			public.GET("/beraters/suggestions", cached, s.beraterHandler.SuggestBeraters)
+			public.GET("/beraters/:id", cached, s.beraterHandler.GetBeraterProfile)
+			public.GET("/specializations", cached, s.beraterHandler.ListSpecializations)
+			public.GET("/languages", cached, s.beraterHandler.ListLanguages)
+
+			// Berater onboarding invitations
+			public.GET("/onboarding/invitations/:token", s.onboardingHandler.GetInvitation)
+			public.POST("/onboarding/accept", s.onboardingHandler.AcceptInvitation)
 
 			// Public contact routes
 			public.POST("/contact", s.contactHandler.SubmitContactForm)
 			public.POST("/contact/pre-talk", s.contactHandler.BookPreTalk)
 
-			// Webhook routes (with API key authentication)
+			// Anonymous funnel analytics, only stored with analytics consent
+			public.POST("/events", middleware.RateLimitMiddleware(
+				middleware.NewRateLimit(120, time.Minute), s.logger,
+			), s.analyticsHandler.TrackEvents)
+
+			// A/B test variants; signed-in visitors are linked for conversion attribution
+			public.POST("/experiments/:key/assign", middleware.OptionalAuth(s.jwtService), s.experimentHandler.AssignVariant)
+
+			// Signed webhook routes, verified and stored by the webhook receiver
+			signedWebhooks := public.Group("/webhooks")
+			{
+				signedWebhooks.POST("/stripe", s.webhookHandler.Receive("stripe"))
+
+				// Newsletter unsubscribes, authenticated with ?token=NEWSLETTER_WEBHOOK_SECRET
+				signedWebhooks.GET("/mailchimp", s.webhookHandler.VerifyEndpoint)
+				signedWebhooks.POST("/mailchimp", s.webhookHandler.Receive(newsletter.ProviderMailchimp))
+				signedWebhooks.POST("/brevo", s.webhookHandler.Receive(newsletter.ProviderBrevo))
+
+				// WhatsApp messages and delivery statuses, authenticated with ?token=WHATSAPP_WEBHOOK_SECRET
+				signedWebhooks.POST("/whatsapp/360dialog", s.webhookHandler.Receive(whatsapp.Provider360dialog))
+				signedWebhooks.POST("/whatsapp/twilio", s.webhookHandler.Receive(whatsapp.ProviderTwilio))
+			}
+
+			// Webhook routes for machine integrations (with service keys; the inbound
+			// email secret from the configuration keeps working)
 			webhooks := public.Group("/webhooks")
-			webhooks.Use(middleware.APIKeyMiddleware(map[string]string{
-				s.config.Stripe.WebhookSecret: "stripe",
+			webhooks.Use(middleware.APIKeyMiddleware(s.serviceKeyService, map[string]string{
+				s.config.Email.InboundWebhookSecret: string(models.ServiceKeyScopeInboundEmail),
 			}))
 			{
-				webhooks.POST("/stripe", s.paymentHandler.StripeWebhook)
+				webhooks.POST("/inbound-email", middleware.RequireServiceKeyScope(models.ServiceKeyScopeInboundEmail), s.inboundEmailHandler.ReceiveInboundEmail)
+				webhooks.POST("/contact-forms", middleware.RequireServiceKeyScope(models.ServiceKeyScopeContactForms), s.contactHandler.SubmitContactForm)
 			}
 		}
 
+		// Integration routes for Zapier and Make (with personal API keys)
+		integrationAPI := v1.Group("/integrations")
+		integrationAPI.Use(middleware.IntegrationAPIKeyMiddleware(s.integrationService, s.logger))
+		{
+			integrationAPI.GET("/me", s.integrationHandler.Me)
+
+			// Polling triggers
+			integrationAPI.GET("/triggers/leads", middleware.RequireAPIKeyScope(s.integrationService, models.APIKeyScopeLeadsRead), s.integrationHandler.NewLeadsTrigger)
+			integrationAPI.GET("/triggers/bookings", middleware.RequireAPIKeyScope(s.integrationService, models.APIKeyScopeBookingsRead), s.integrationHandler.NewBookingsTrigger)
+
+			// Actions
+			integrationAPI.POST("/actions/leads", middleware.RequireAPIKeyScope(s.integrationService, models.APIKeyScopeLeadsWrite), s.integrationHandler.CreateLeadAction)
+			integrationAPI.POST("/actions/leads/:id/comments", middleware.RequireAPIKeyScope(s.integrationService, models.APIKeyScopeCommentsWrite), s.integrationHandler.AddCommentAction)
+		}
+
 		// Protected routes (authentication required)
 		protected := v1.Group("")
-		protected.Use(middleware.AuthMiddleware(s.jwtService))
+		protected.Use(middleware.AuthMiddleware(s.jwtService, s.accessTokenService))
 		{
 			// Authentication routes for authenticated users
 			auth := protected.Group("/auth")
@@ -178,6 +799,8 @@ func (s *Server) setupRoutes() {
 				auth.POST("/logout", s.authHandler.Logout)
 				auth.GET("/me", s.authHandler.GetMe)
 				auth.PUT("/me", s.authHandler.UpdateMe)
+				auth.POST("/me/avatar", s.authHandler.UploadAvatar)
+				auth.DELETE("/me/avatar", s.authHandler.DeleteAvatar)
 				auth.POST("/change-password", s.authHandler.ChangePassword)
 			}
 
@@ -186,6 +809,7 @@ func (s *Server) setupRoutes() {
 			{
 				users.GET("", middleware.RequireBeraterOrAdmin(), s.userHandler.ListUsers)
 				users.GET("/:id", middleware.RequireOwnershipOrRole("user_id", "berater", "admin"), s.userHandler.GetUser)
+				users.GET("/:id/statement", middleware.RequireBeraterOrAdmin(), s.statementHandler.GetUserStatement)
 				users.PUT("/:id", middleware.RequireOwnershipOrRole("user_id", "admin"), s.userHandler.UpdateUser)
 				users.DELETE("/:id", middleware.RequireAdmin(), s.userHandler.DeleteUser)
 			}
@@ -195,17 +819,108 @@ func (s *Server) setupRoutes() {
 			{
 				leads.GET("", s.leadHandler.ListLeads)
 				leads.POST("", s.leadHandler.CreateLead)
+				leads.GET("/export", middleware.RequireBeraterOrAdmin(), s.exportHandler.ExportLeads)
 				leads.GET("/:id", s.leadHandler.GetLead)
 				leads.PUT("/:id", s.leadHandler.UpdateLead)
 				leads.DELETE("/:id", s.leadHandler.DeleteLead)
 				leads.PATCH("/:id/status", s.leadHandler.UpdateLeadStatus)
 				leads.POST("/:id/assign", middleware.RequireBeraterOrAdmin(), s.leadHandler.AssignLead)
+				leads.POST("/:id/auto-assign", middleware.RequireBeraterOrAdmin(), s.leadHandler.AutoAssignLead)
+				leads.POST("/:id/claim", middleware.RequireBeraterOrAdmin(), s.inboxHandler.ClaimLead)
+				leads.POST("/:id/release", middleware.RequireBeraterOrAdmin(), s.inboxHandler.ReleaseLead)
+				leads.POST("/:id/reply", middleware.RequireBeraterOrAdmin(), s.replyHandler.ReplyToLead)
+				leads.GET("/:id/whatsapp", middleware.RequireBeraterOrAdmin(), s.whatsAppHandler.ListLeadMessages)
+				leads.POST("/:id/whatsapp", middleware.RequireBeraterOrAdmin(), s.whatsAppHandler.SendLeadMessage)
+				leads.GET("/:id/berater-suggestions", middleware.RequireBeraterOrAdmin(), s.leadHandler.GetLeadBeraterSuggestions)
+				leads.GET("/:id/link-stats", middleware.RequireBeraterOrAdmin(), s.shortLinkHandler.GetLeadLinkStats)
+				leads.GET("/:id/payment-links", middleware.RequireBeraterOrAdmin(), s.paymentLinkHandler.ListPaymentLinks)
+				leads.POST("/:id/payment-links", middleware.RequireBeraterOrAdmin(), s.paymentLinkHandler.CreatePaymentLink)
+				leads.POST("/:id/payment-links/:linkId/cancel", middleware.RequireBeraterOrAdmin(), s.paymentLinkHandler.CancelPaymentLink)
+				leads.GET("/:id/export", middleware.RequireBeraterOrAdmin(), s.caseFileHandler.ExportLead)
+
+				// Elterngeld scenarios, compared side by side
+				leads.GET("/:id/scenarios", s.scenarioHandler.ListScenarios)
+				leads.POST("/:id/scenarios", s.scenarioHandler.CreateScenario)
+				leads.GET("/:id/scenarios/compare", s.scenarioHandler.CompareScenarios)
+				leads.GET("/:id/scenarios/chosen", s.scenarioHandler.GetChosenScenario)
+				leads.PUT("/:id/scenarios/:scenarioId", s.scenarioHandler.UpdateScenario)
+				leads.DELETE("/:id/scenarios/:scenarioId", s.scenarioHandler.DeleteScenario)
+				leads.POST("/:id/scenarios/:scenarioId/choose", s.scenarioHandler.ChooseScenario)
+				leads.GET("/:id/payout-plan", s.scenarioHandler.GetPayoutPlan)
+				leads.GET("/:id/payout-plan/pdf", s.scenarioHandler.DownloadPayoutPlan)
+				leads.POST("/:id/payout-plan/share", middleware.RequireBeraterOrAdmin(), s.scenarioHandler.SharePayoutPlan)
+
+				// Answers to the intake questionnaire, pre-filling calculators and application
+				leads.GET("/:id/questionnaire", s.intakeHandler.GetLeadQuestionnaire)
+				leads.PUT("/:id/questionnaire", s.intakeHandler.SubmitLeadQuestionnaire)
+				leads.GET("/:id/questionnaire/prefill", s.intakeHandler.GetLeadPrefill)
+
+				// Submission to the Elterngeldstelle and the Bescheid
+				leads.GET("/:id/submission", s.bescheidHandler.GetSubmission)
+				leads.PUT("/:id/submission", middleware.RequireBeraterOrAdmin(), s.bescheidHandler.SubmitApplication)
+				leads.POST("/:id/submission/document-requests", middleware.RequireBeraterOrAdmin(), s.bescheidHandler.RequestDocuments)
+				leads.POST("/:id/submission/document-requests/:requestId/fulfill", middleware.RequireBeraterOrAdmin(), s.bescheidHandler.FulfillDocumentRequest)
+				leads.PUT("/:id/submission/bescheid", middleware.RequireBeraterOrAdmin(), s.bescheidHandler.RecordBescheid)
 
 				// Lead comments
 				leads.GET("/:id/comments", s.leadHandler.ListLeadComments)
 				leads.POST("/:id/comments", s.leadHandler.CreateLeadComment)
-				leads.PUT("/comments/:commentId", s.placeholder("Update Lead Comment"))
-				leads.DELETE("/comments/:commentId", s.placeholder("Delete Lead Comment"))
+				leads.PATCH("/comments/:commentId", s.leadHandler.UpdateLeadComment)
+				leads.DELETE("/comments/:commentId", s.leadHandler.DeleteLeadComment)
+				leads.GET("/comments/:commentId/history", s.leadHandler.GetLeadCommentHistory)
+				leads.PUT("/comments/:commentId/reactions/:reaction", s.leadHandler.AddLeadCommentReaction)
+				leads.DELETE("/comments/:commentId/reactions/:reaction", s.leadHandler.RemoveLeadCommentReaction)
+			}
+
+			// Submitted applications awaiting a response of the Elterngeldstelle
+			protected.GET("/application-submissions", middleware.RequireBeraterOrAdmin(), s.bescheidHandler.ListSubmissions)
+
+			// Families grouping the parents, children, leads and bookings of a case
+			familyRoutes := protected.Group("/families")
+			{
+				familyRoutes.GET("", s.familyHandler.ListFamilies)
+				familyRoutes.POST("", s.familyHandler.CreateFamily)
+				familyRoutes.GET("/:id", s.familyHandler.GetFamily)
+				familyRoutes.PUT("/:id", s.familyHandler.UpdateFamily)
+				familyRoutes.DELETE("/:id", s.familyHandler.DeleteFamily)
+				familyRoutes.POST("/:id/members", s.familyHandler.AddFamilyMember)
+				familyRoutes.DELETE("/:id/members/:memberId", s.familyHandler.RemoveFamilyMember)
+				familyRoutes.POST("/:id/children", s.familyHandler.AddFamilyChild)
+				familyRoutes.PUT("/:id/children/:childId", s.familyHandler.UpdateFamilyChild)
+				familyRoutes.DELETE("/:id/children/:childId", s.familyHandler.RemoveFamilyChild)
+				familyRoutes.POST("/:id/leads/:leadId", s.familyHandler.AttachFamilyLead)
+				familyRoutes.DELETE("/:id/leads/:leadId", s.familyHandler.DetachFamilyLead)
+			}
+
+			// PDFs generated from templates in the background
+			pdfRoutes := protected.Group("/pdf")
+			{
+				pdfRoutes.GET("/templates", middleware.RequireBeraterOrAdmin(), s.pdfHandler.ListTemplates)
+				pdfRoutes.POST("/jobs", middleware.RequireBeraterOrAdmin(), s.pdfHandler.CreateJob)
+				pdfRoutes.GET("/jobs/:id", s.pdfHandler.GetJob)
+				pdfRoutes.GET("/jobs/:id/download", s.pdfHandler.DownloadJob)
+			}
+
+			// Announcements of the admins published to the current user
+			protected.GET("/announcements", s.announcementHandler.ListMyAnnouncements)
+			protected.POST("/announcements/:id/read", s.announcementHandler.MarkAnnouncementRead)
+
+			// "What's new" feed of product updates and maintenance windows
+			protected.GET("/product-updates", s.changelogHandler.GetProductUpdateFeed)
+
+			// Merge fields of snippets and PDF templates
+			mergeFieldRoutes := protected.Group("/merge-fields")
+			mergeFieldRoutes.Use(middleware.RequireBeraterOrAdmin())
+			{
+				mergeFieldRoutes.GET("", s.mergeFieldHandler.ListMergeFields)
+				mergeFieldRoutes.POST("/validate", s.mergeFieldHandler.ValidateTemplate)
+			}
+
+			// Address validation and autocomplete for the contact-info step
+			addressRoutes := protected.Group("/addresses")
+			{
+				addressRoutes.POST("/validate", s.addressHandler.ValidateAddress)
+				addressRoutes.GET("/autocomplete", s.addressHandler.AutocompleteAddress)
 			}
 
 			// Booking routes
@@ -213,8 +928,23 @@ func (s *Server) setupRoutes() {
 			{
 				bookings.GET("", s.bookingHandler.GetUserBookings)
 				bookings.POST("", s.bookingHandler.CreateBooking)
+				bookings.POST("/quote", s.bookingHandler.QuoteBooking)
 				bookings.GET("/:id", s.bookingHandler.GetBooking)
+				bookings.GET("/:id/statement", middleware.RequireBeraterOrAdmin(), s.statementHandler.GetBookingStatement)
+				bookings.GET("/:id/transitions", s.bookingStateHandler.GetBookingTransitions)
+				bookings.PUT("/:id/interpreter", middleware.RequireBeraterOrAdmin(), s.bookingHandler.SetInterpreter)
 				bookings.PUT("/:id/contact-info", s.bookingHandler.UpdateBookingContactInfo)
+				bookings.POST("/:id/rating", s.bookingHandler.RateBooking)
+				bookings.POST("/:id/attendance", middleware.RequireBeraterOrAdmin(), s.noShowHandler.RecordAttendance)
+				bookings.PUT("/:id/summary", middleware.RequireBeraterOrAdmin(), s.summaryHandler.SaveSummary)
+				bookings.GET("/:id/summary", s.summaryHandler.GetSummary)
+				bookings.GET("/:id/summary/pdf", s.summaryHandler.DownloadSummary)
+			}
+
+			// Consultation summaries shared with the customer (dashboard)
+			consultationSummaries := protected.Group("/consultation-summaries")
+			{
+				consultationSummaries.GET("", s.summaryHandler.ListMySummaries)
 			}
 
 			// Document routes
@@ -222,10 +952,15 @@ func (s *Server) setupRoutes() {
 			{
 				documents.GET("", s.documentHandler.ListDocuments)
 				documents.POST("", s.documentHandler.UploadDocument)
+				documents.GET("/quota", s.documentHandler.GetStorageQuota)
 				documents.GET("/:id", s.documentHandler.GetDocument)
 				documents.PUT("/:id", s.documentHandler.UpdateDocument)
 				documents.DELETE("/:id", s.documentHandler.DeleteDocument)
 				documents.GET("/:id/download", s.documentHandler.DownloadDocument)
+				documents.POST("/:id/links", s.documentHandler.CreateDocumentLinks)
+				documents.PUT("/:id/visibility", s.documentHandler.UpdateDocumentVisibility)
+				documents.POST("/:id/share", s.documentHandler.ShareDocument)
+				documents.POST("/:id/classify", middleware.RequireBeraterOrAdmin(), s.documentHandler.ClassifyDocument)
 			}
 
 			// Payment routes
@@ -234,7 +969,14 @@ func (s *Server) setupRoutes() {
 				payments.GET("", s.paymentHandler.ListPayments)
 				payments.POST("/checkout", s.paymentHandler.CreateCheckout)
 				payments.GET("/:id", s.paymentHandler.GetPayment)
-				payments.POST("/:id/refund", middleware.RequireBeraterOrAdmin(), s.paymentHandler.RefundPayment)
+				payments.POST("/:id/refund", middleware.RequireBeraterOrAdmin(), s.refundHandler.RequestRefund)
+			}
+
+			// Account credit routes
+			credit := protected.Group("/credit")
+			{
+				credit.GET("", s.creditHandler.GetMyCredit)
+				credit.POST("/vouchers", s.creditHandler.RedeemVoucher)
 			}
 
 			// Todo routes
@@ -253,13 +995,83 @@ func (s *Server) setupRoutes() {
 			{
 				contacts.GET("/forms", middleware.RequireBeraterOrAdmin(), s.contactHandler.GetContactForms)
 				contacts.PATCH("/forms/:id/status", middleware.RequireBeraterOrAdmin(), s.contactHandler.UpdateContactFormStatus)
+
+				// Team inbox: claim an inquiry before replying so nobody answers it twice
+				contacts.GET("/inbox", middleware.RequireBeraterOrAdmin(), s.inboxHandler.GetInbox)
+				contacts.POST("/forms/:id/claim", middleware.RequireBeraterOrAdmin(), s.inboxHandler.ClaimContactForm)
+				contacts.POST("/forms/:id/release", middleware.RequireBeraterOrAdmin(), s.inboxHandler.ReleaseContactForm)
+				contacts.POST("/forms/:id/reply", middleware.RequireBeraterOrAdmin(), s.replyHandler.ReplyToContactForm)
+			}
+
+			// Integration API key management (staff only)
+			apiKeys := protected.Group("/integrations/api-keys")
+			apiKeys.Use(middleware.RequireRole(models.RoleBerater, models.RoleJuniorBerater, models.RoleAdmin))
+			{
+				apiKeys.GET("", s.integrationHandler.ListAPIKeys)
+				apiKeys.POST("", s.integrationHandler.CreateAPIKey)
+				apiKeys.DELETE("/:id", s.integrationHandler.RevokeAPIKey)
+			}
+
+			// Personal access tokens; AuthMiddleware rejects tokens on these routes
+			me := protected.Group("/me")
+			{
+				me.GET("/tokens", s.accessTokenHandler.ListAccessTokens)
+				me.POST("/tokens", s.accessTokenHandler.CreateAccessToken)
+				me.DELETE("/tokens/:id", s.accessTokenHandler.RevokeAccessToken)
 			}
 
 			// Activity routes
 			activities := protected.Group("/activities")
 			{
-				activities.GET("", s.placeholder("List Activities"))
-				activities.GET("/:id", s.placeholder("Get Activity"))
+				activities.GET("", s.activityHandler.ListActivities)
+				activities.GET("/:id", s.activityHandler.GetActivity)
+			}
+
+			// Activity feed and daily digest (staff only)
+			activityFeed := protected.Group("/activity-feed")
+			activityFeed.Use(middleware.RequireRole(models.RoleBerater, models.RoleJuniorBerater, models.RoleAdmin))
+			{
+				activityFeed.GET("", s.activityHandler.GetActivityFeed)
+				activityFeed.GET("/digest", s.activityHandler.GetDigestPreference)
+				activityFeed.PUT("/digest", s.activityHandler.UpdateDigestPreference)
+			}
+
+			// Saved filter, sort and column configurations of the lead and booking lists
+			savedViews := protected.Group("/saved-views")
+			{
+				savedViews.GET("", s.savedViewHandler.ListSavedViews)
+				savedViews.POST("", s.savedViewHandler.CreateSavedView)
+				savedViews.GET("/defaults/:resource", s.savedViewHandler.GetDefaultSavedView)
+				savedViews.DELETE("/defaults/:resource", s.savedViewHandler.ClearDefaultSavedView)
+				savedViews.PUT("/:id", s.savedViewHandler.UpdateSavedView)
+				savedViews.DELETE("/:id", s.savedViewHandler.DeleteSavedView)
+				savedViews.PUT("/:id/share", s.savedViewHandler.ShareSavedView)
+				savedViews.PUT("/:id/default", s.savedViewHandler.SetDefaultSavedView)
+			}
+
+			// Canned responses for replies to contact forms and lead emails
+			snippetRoutes := protected.Group("/snippets")
+			snippetRoutes.Use(middleware.RequireBeraterOrAdmin())
+			{
+				snippetRoutes.GET("", s.snippetHandler.ListSnippets)
+				snippetRoutes.POST("", s.snippetHandler.CreateSnippet)
+				snippetRoutes.PUT("/:id", s.snippetHandler.UpdateSnippet)
+				snippetRoutes.DELETE("/:id", s.snippetHandler.DeleteSnippet)
+				snippetRoutes.POST("/:id/render", s.snippetHandler.RenderSnippet)
+			}
+
+			// WhatsApp opt-in of the current user for booking reminders and messages
+			whatsAppRoutes := protected.Group("/whatsapp")
+			{
+				whatsAppRoutes.GET("/consent", s.whatsAppHandler.GetConsent)
+				whatsAppRoutes.PUT("/consent", s.whatsAppHandler.UpdateConsent)
+			}
+
+			// Immediate, hourly or daily emails per notification category
+			notifications := protected.Group("/notifications")
+			{
+				notifications.GET("/digest", s.digestHandler.GetDigestModes)
+				notifications.PUT("/digest", s.digestHandler.UpdateDigestModes)
 			}
 
 			// Admin routes
@@ -274,7 +1086,194 @@ func (s *Server) setupRoutes() {
 
 				admin.GET("/leads", s.leadHandler.ListLeads)
 				admin.GET("/payments", s.paymentHandler.ListPayments)
-				admin.GET("/activities", s.placeholder("Admin List Activities"))
+				admin.GET("/activities", s.activityHandler.ListActivities)
+				admin.GET("/activity-feed", s.activityHandler.GetActivityFeed)
+				admin.GET("/inbound-emails", s.inboundEmailHandler.ListInboundEmails)
+				admin.POST("/inbound-emails/:id/assign", s.inboundEmailHandler.AssignInboundEmail)
+
+				// Webhook events
+				admin.GET("/webhooks", s.webhookHandler.ListWebhookEvents)
+				admin.POST("/webhooks/:id/replay", s.webhookHandler.ReplayWebhookEvent)
+				admin.GET("/outbox", s.outboxHandler.ListDeadLetters)
+				admin.POST("/outbox/:id/retry", s.outboxHandler.RetryMessage)
+
+				// Recurring jobs and their run history
+				admin.GET("/scheduler/jobs", s.schedulerHandler.ListScheduledJobs)
+				admin.GET("/scheduler/jobs/:name/runs", s.schedulerHandler.ListJobRuns)
+				admin.POST("/scheduler/jobs/:name/run", s.schedulerHandler.TriggerScheduledJob)
+				admin.POST("/scheduler/jobs/:name/pause", s.schedulerHandler.PauseScheduledJob)
+				admin.POST("/scheduler/jobs/:name/resume", s.schedulerHandler.ResumeScheduledJob)
+
+				// Backups
+				admin.GET("/backups", s.backupHandler.ListBackups)
+				admin.POST("/backups", s.backupHandler.CreateBackup)
+				admin.POST("/backups/:id/restore", s.backupHandler.RestoreBackup)
+
+				// Letterheads of the generated PDFs
+				admin.GET("/pdf-brandings", s.pdfHandler.ListBrandings)
+				admin.PUT("/pdf-brandings/:tenant", s.pdfHandler.UpdateBranding)
+
+				// API keys of machine integrations
+				admin.GET("/service-keys", s.serviceKeyHandler.ListServiceKeys)
+				admin.POST("/service-keys", s.serviceKeyHandler.CreateServiceKey)
+				admin.PUT("/service-keys/:id", s.serviceKeyHandler.UpdateServiceKey)
+				admin.POST("/service-keys/:id/rotate", s.serviceKeyHandler.RotateServiceKey)
+				admin.DELETE("/service-keys/:id", s.serviceKeyHandler.RevokeServiceKey)
+
+				// Review queue of suspicious contact forms and registrations
+				admin.GET("/submission-reviews", s.submissionHandler.ListSubmissionReviews)
+				admin.POST("/submission-reviews/:id/approve", s.submissionHandler.ApproveSubmission)
+				admin.POST("/submission-reviews/:id/reject", s.submissionHandler.RejectSubmission)
+
+				// System settings
+				admin.GET("/settings", s.settingHandler.ListSettings)
+				admin.GET("/settings/:key", s.settingHandler.GetSetting)
+				admin.PUT("/settings/:key", s.settingHandler.UpdateSetting)
+				admin.DELETE("/settings/:key", s.settingHandler.ResetSetting)
+
+				// Website content
+				admin.GET("/content", s.contentHandler.ListContent)
+				admin.POST("/content", s.contentHandler.CreateContent)
+				admin.GET("/content/:id", s.contentHandler.GetContent)
+				admin.PUT("/content/:id", s.contentHandler.UpdateContent)
+				admin.DELETE("/content/:id", s.contentHandler.DeleteContent)
+				admin.POST("/content/:id/publish", s.contentHandler.PublishContent)
+				admin.POST("/content/:id/unpublish", s.contentHandler.UnpublishContent)
+
+				// Blog posts
+				admin.GET("/blog/posts", s.blogHandler.ListPosts)
+				admin.POST("/blog/posts", s.blogHandler.CreatePost)
+				admin.GET("/blog/posts/:id", s.blogHandler.GetPost)
+				admin.PUT("/blog/posts/:id", s.blogHandler.UpdatePost)
+				admin.DELETE("/blog/posts/:id", s.blogHandler.DeletePost)
+				admin.POST("/blog/posts/:id/publish", s.blogHandler.PublishPost)
+				admin.POST("/blog/posts/:id/unpublish", s.blogHandler.UnpublishPost)
+
+				// Job applications with scoring and structured interview feedback
+				admin.GET("/job-applications", s.applicationHandler.ListApplications)
+				admin.GET("/job-applications/:id/evaluations", s.evaluationHandler.ListEvaluations)
+				admin.PUT("/job-applications/:id/evaluations", s.evaluationHandler.Evaluate)
+				admin.GET("/evaluation-criteria", s.evaluationHandler.ListCriteria)
+				admin.POST("/evaluation-criteria", s.evaluationHandler.CreateCriterion)
+				admin.PUT("/evaluation-criteria/:id", s.evaluationHandler.UpdateCriterion)
+				admin.DELETE("/evaluation-criteria/:id", s.evaluationHandler.DeleteCriterion)
+
+				// Intake questionnaires of the packages, in versions
+				admin.GET("/questionnaires", s.intakeHandler.ListQuestionnaires)
+				admin.POST("/questionnaires", s.intakeHandler.CreateQuestionnaire)
+				admin.GET("/questionnaires/:id", s.intakeHandler.GetQuestionnaire)
+				admin.PUT("/questionnaires/:id", s.intakeHandler.UpdateQuestionnaire)
+				admin.DELETE("/questionnaires/:id", s.intakeHandler.DeleteQuestionnaire)
+				admin.POST("/questionnaires/:id/publish", s.intakeHandler.PublishQuestionnaire)
+				admin.POST("/questionnaires/:id/revise", s.intakeHandler.ReviseQuestionnaire)
+				admin.POST("/questionnaires/:id/questions", s.intakeHandler.AddQuestion)
+				admin.PUT("/questionnaires/:id/questions/:questionId", s.intakeHandler.UpdateQuestion)
+				admin.DELETE("/questionnaires/:id/questions/:questionId", s.intakeHandler.DeleteQuestion)
+
+				// Interview scheduling for job applications
+				admin.GET("/interview-slots", s.interviewHandler.ListSlots)
+				admin.POST("/interview-slots", s.interviewHandler.CreateSlot)
+				admin.DELETE("/interview-slots/:id", s.interviewHandler.DeleteSlot)
+				admin.POST("/job-applications/:id/interview-invitation", s.interviewHandler.Invite)
+
+				// Talent pool with the consent of the applicant
+				admin.GET("/talent-pool", s.applicationHandler.ListTalentPool)
+				admin.PUT("/job-applications/:id/talent-pool", s.applicationHandler.AddToTalentPool)
+				admin.DELETE("/job-applications/:id/talent-pool", s.applicationHandler.RemoveFromTalentPool)
+
+				// Slack and Teams notifications
+				admin.GET("/chat-channels", s.chatHandler.ListChatChannels)
+				admin.POST("/chat-channels", s.chatHandler.CreateChatChannel)
+				admin.PUT("/chat-channels/:id", s.chatHandler.UpdateChatChannel)
+				admin.DELETE("/chat-channels/:id", s.chatHandler.DeleteChatChannel)
+				admin.POST("/chat-channels/:id/rules", s.chatHandler.AddChatRoutingRule)
+				admin.DELETE("/chat-channels/:id/rules/:rule_id", s.chatHandler.DeleteChatRoutingRule)
+				admin.POST("/chat-channels/:id/test", s.chatHandler.TestChatChannel)
+
+				// Lead aging rules
+				admin.GET("/lead-aging-rules", s.leadAgingHandler.ListLeadAgingRules)
+				admin.POST("/lead-aging-rules", s.leadAgingHandler.CreateLeadAgingRule)
+				admin.POST("/lead-aging-rules/apply", s.leadAgingHandler.ApplyLeadAgingRules)
+				admin.PUT("/lead-aging-rules/:id", s.leadAgingHandler.UpdateLeadAgingRule)
+				admin.DELETE("/lead-aging-rules/:id", s.leadAgingHandler.DeleteLeadAgingRule)
+
+				// Announcements broadcast to users, with read receipts
+				admin.GET("/announcements", s.announcementHandler.ListAnnouncements)
+				admin.POST("/announcements", s.announcementHandler.CreateAnnouncement)
+				admin.PUT("/announcements/:id", s.announcementHandler.UpdateAnnouncement)
+				admin.DELETE("/announcements/:id", s.announcementHandler.DeleteAnnouncement)
+				admin.GET("/announcements/:id/report", s.announcementHandler.GetAnnouncementReport)
+
+				// Product update feed of the "What's new" widget
+				admin.GET("/product-updates", s.changelogHandler.ListProductUpdates)
+				admin.POST("/product-updates", s.changelogHandler.CreateProductUpdate)
+				admin.GET("/product-updates/:id", s.changelogHandler.GetProductUpdate)
+				admin.PUT("/product-updates/:id", s.changelogHandler.UpdateProductUpdate)
+				admin.DELETE("/product-updates/:id", s.changelogHandler.DeleteProductUpdate)
+
+				// Funnel analytics
+				admin.GET("/analytics/funnel", s.analyticsHandler.GetFunnelReport)
+
+				// A/B tests
+				admin.GET("/experiments", s.experimentHandler.ListExperiments)
+				admin.POST("/experiments", s.experimentHandler.CreateExperiment)
+				admin.GET("/experiments/:id", s.experimentHandler.GetExperiment)
+				admin.PUT("/experiments/:id", s.experimentHandler.UpdateExperiment)
+				admin.POST("/experiments/:id/start", s.experimentHandler.StartExperiment)
+				admin.POST("/experiments/:id/stop", s.experimentHandler.StopExperiment)
+				admin.GET("/experiments/:id/results", s.experimentHandler.GetExperimentResults)
+
+				// Marketing spend and ROI
+				admin.GET("/marketing/spend", s.marketingHandler.ListMarketingSpend)
+				admin.POST("/marketing/spend", s.marketingHandler.CreateMarketingSpend)
+				admin.PUT("/marketing/spend/:id", s.marketingHandler.UpdateMarketingSpend)
+				admin.DELETE("/marketing/spend/:id", s.marketingHandler.DeleteMarketingSpend)
+				admin.GET("/marketing/roi", s.marketingHandler.GetROIReport)
+
+				// Capacity planning
+				admin.GET("/capacity", s.capacityHandler.GetCapacityReport)
+
+				// Leads per Kreis and Bundesland
+				admin.GET("/regions", s.postalCodeHandler.GetRegionReport)
+
+				// Lead pipeline conversion and revenue forecast
+				admin.GET("/pipeline/funnel", s.pipelineHandler.GetLeadFunnel)
+				admin.GET("/pipeline/forecast", s.pipelineHandler.GetRevenueForecast)
+
+				// Trash: restore or permanently delete soft-deleted records
+				for _, recordType := range trash.Types {
+					path := "/" + string(recordType)
+					admin.GET(path+"/trash", s.trashHandler.ListTrash(recordType))
+					admin.POST(path+"/:id/restore", s.trashHandler.RestoreFromTrash(recordType))
+					admin.DELETE(path+"/:id/permanent", s.trashHandler.DeletePermanently(recordType))
+				}
+
+				// Customer credit and vouchers
+				admin.GET("/users/:id/credit", s.creditHandler.GetUserCredit)
+				admin.POST("/users/:id/credit", s.creditHandler.GrantCredit)
+				admin.GET("/vouchers", s.creditHandler.ListVouchers)
+				admin.POST("/vouchers", s.creditHandler.CreateVoucher)
+
+				// Refunds above the approval threshold (four-eyes principle)
+				admin.GET("/refund-requests", s.refundHandler.ListRefundRequests)
+				admin.POST("/refund-requests/:id/approve", s.refundHandler.ApproveRefund)
+				admin.POST("/refund-requests/:id/reject", s.refundHandler.RejectRefund)
+
+				admin.GET("/coupons", s.couponHandler.ListCoupons)
+				admin.POST("/coupons", s.couponHandler.CreateCoupon)
+
+				admin.GET("/holiday-overrides", s.holidayHandler.ListHolidayOverrides)
+				admin.POST("/holiday-overrides", s.holidayHandler.CreateHolidayOverride)
+				admin.DELETE("/holiday-overrides/:id", s.holidayHandler.DeleteHolidayOverride)
+				admin.GET("/berater-invitations", s.onboardingHandler.ListInvitations)
+				admin.POST("/berater-invitations", s.onboardingHandler.InviteBerater)
+				admin.DELETE("/berater-invitations/:id", s.onboardingHandler.RevokeInvitation)
+				admin.GET("/beraters/onboarding", s.onboardingHandler.ListOnboardingBeraters)
+				admin.POST("/beraters/:id/approve", s.onboardingHandler.ApproveBerater)
+				admin.POST("/beraters/:id/reject", s.onboardingHandler.RejectBerater)
+				admin.POST("/beraters/:id/deactivate", s.offboardingHandler.DeactivateBerater)
+				admin.GET("/beraters/:id/offboarding", s.offboardingHandler.GetOffboardingWorklist)
+				admin.POST("/beraters/:id/reassign-bookings", s.reassignmentHandler.ReassignBookings)
 				admin.GET("/system", s.placeholder("System Information"))
 			}
 
@@ -285,12 +1284,35 @@ func (s *Server) setupRoutes() {
 				berater.GET("/leads", s.leadHandler.ListLeads)
 				berater.GET("/stats", s.placeholder("Berater Stats"))
 			}
+
+			// Berater onboarding routes (also for junior Beraters)
+			beraterOnboarding := protected.Group("/berater/onboarding")
+			beraterOnboarding.Use(middleware.RequireRole(models.RoleBerater, models.RoleJuniorBerater))
+			{
+				beraterOnboarding.GET("", s.onboardingHandler.GetOnboarding)
+				beraterOnboarding.PUT("/profile", s.onboardingHandler.UpdateOnboardingProfile)
+				beraterOnboarding.PUT("/availability", s.onboardingHandler.SetAvailability)
+				beraterOnboarding.POST("/submit", s.onboardingHandler.SubmitOnboarding)
+			}
 		}
 	}
 
 	// Payment result pages (public, for Stripe redirects)
 	s.Router.GET("/payment/success", s.paymentHandler.PaymentSuccessPage)
 	s.Router.GET("/payment/cancel", s.paymentHandler.PaymentCancelPage)
+	s.Router.GET("/pay/:token", s.paymentLinkHandler.Pay)
+
+	// Sitemap of the public website for search engines
+	s.Router.GET("/sitemap.xml", s.sitemapHandler.Sitemap)
+
+	// Tracked short links used in emails (public)
+	s.Router.GET("/r/:code", s.shortLinkHandler.Redirect)
+
+	// Signed, short-lived document previews and downloads (public, verified by signature)
+	s.Router.GET("/files/documents/:id/:variant", s.documentHandler.ServeSignedDocument)
+
+	// Avatars of users, linked as their photo URL (public)
+	s.Router.GET("/files/avatars/:id", s.authHandler.ServeAvatar)
 
 	// Static file serving (for uploaded documents, only in development)
 	if s.config.IsDevelopment() && !s.config.S3.UseS3 {
@@ -3,6 +3,7 @@ package server
 import (
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
@@ -204,6 +205,7 @@ func TestProtectedEndpointsRequireAuth(t *testing.T) {
 		{"POST", "/api/v1/auth/logout"},
 		{"GET", "/api/v1/auth/me"},
 		{"PUT", "/api/v1/auth/me"},
+		{"POST", "/api/v1/auth/me/avatar"},
 		{"POST", "/api/v1/auth/change-password"},
 		{"GET", "/api/v1/users"},
 		{"GET", "/api/v1/leads"},
@@ -213,6 +215,8 @@ func TestProtectedEndpointsRequireAuth(t *testing.T) {
 		{"GET", "/api/v1/payments"},
 		{"POST", "/api/v1/payments/checkout"},
 		{"GET", "/api/v1/activities"},
+		{"GET", "/api/v1/families"},
+		{"GET", "/api/v1/leads/00000000-0000-0000-0000-000000000000/questionnaire"},
 		{"GET", "/api/v1/admin/stats"},
 		{"GET", "/api/v1/berater/leads"},
 	}
@@ -232,14 +236,14 @@ func TestProtectedEndpointsRequireAuth(t *testing.T) {
 func TestWebhookEndpointsRequireAPIKey(t *testing.T) {
 	testutils.SetupGinTestMode()
 	cfg := createTestConfig()
-	cfg.Stripe.WebhookSecret = "test-webhook-secret"
+	cfg.Email.InboundWebhookSecret = "test-webhook-secret"
 	logger := zap.NewNop()
 
 	server := New(cfg, logger)
 
 	t.Run("without_api_key", func(t *testing.T) {
 		w := httptest.NewRecorder()
-		req := httptest.NewRequest("POST", "/api/v1/webhooks/stripe", nil)
+		req := httptest.NewRequest("POST", "/api/v1/webhooks/inbound-email", nil)
 		server.Router.ServeHTTP(w, req)
 
 		assert.Equal(t, http.StatusUnauthorized, w.Code)
@@ -248,7 +252,7 @@ func TestWebhookEndpointsRequireAPIKey(t *testing.T) {
 
 	t.Run("with_invalid_api_key", func(t *testing.T) {
 		w := httptest.NewRecorder()
-		req := httptest.NewRequest("POST", "/api/v1/webhooks/stripe", nil)
+		req := httptest.NewRequest("POST", "/api/v1/webhooks/inbound-email", nil)
 		req.Header.Set("X-API-Key", "invalid-key")
 		server.Router.ServeHTTP(w, req)
 
@@ -258,11 +262,38 @@ func TestWebhookEndpointsRequireAPIKey(t *testing.T) {
 
 	t.Run("with_valid_api_key", func(t *testing.T) {
 		w := httptest.NewRecorder()
-		req := httptest.NewRequest("POST", "/api/v1/webhooks/stripe", nil)
+		req := httptest.NewRequest("POST", "/api/v1/webhooks/inbound-email", nil)
+		req.Header.Set("X-API-Key", cfg.Email.InboundWebhookSecret)
+		server.Router.ServeHTTP(w, req)
+
+		assert.NotEqual(t, http.StatusUnauthorized, w.Code) // Reaches the handler
+	})
+}
+
+func TestStripeWebhookRequiresSignature(t *testing.T) {
+	testutils.SetupGinTestMode()
+	cfg := createTestConfig()
+	cfg.Stripe.WebhookSecret = "test-webhook-secret"
+	logger := zap.NewNop()
+
+	server := New(cfg, logger)
+
+	t.Run("without_signature", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", "/api/v1/webhooks/stripe", strings.NewReader(`{"id": "evt_1"}`))
+		server.Router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		testutils.AssertErrorResponse(t, w, http.StatusUnauthorized, "Invalid signature")
+	})
+
+	t.Run("api_key_is_not_enough", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", "/api/v1/webhooks/stripe", strings.NewReader(`{"id": "evt_1"}`))
 		req.Header.Set("X-API-Key", cfg.Stripe.WebhookSecret)
 		server.Router.ServeHTTP(w, req)
 
-		assert.Equal(t, http.StatusOK, w.Code) // Should reach placeholder handler
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
 	})
 }
 
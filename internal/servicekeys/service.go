@@ -0,0 +1,361 @@
+// Package servicekeys manages the API keys of machine integrations such as
+// the website forms and partner systems. Keys are stored hashed, limited to
+// scopes and optionally to IP addresses, and rate limited per key.
+package servicekeys
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"strings"
+	"sync"
+	"time"
+
+	"elterngeld-portal/internal/models"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	// DefaultRateLimit is the number of requests per minute of keys without a limit
+	DefaultRateLimit = 60
+	// MaxRateLimit caps the requests per minute a key can be granted
+	MaxRateLimit = 6000
+	// DefaultRotationGrace is how long the replaced key keeps working after a rotation
+	DefaultRotationGrace = 24 * time.Hour
+	// MaxRotationGrace caps the grace period of a rotation
+	MaxRotationGrace = 7 * 24 * time.Hour
+
+	rateWindow = time.Minute
+)
+
+var (
+	// ErrInvalidKey is returned for unknown, revoked or expired keys
+	ErrInvalidKey = errors.New("invalid service key")
+	// ErrIPNotAllowed is returned when a key is used from an address outside its allowlist
+	ErrIPNotAllowed = errors.New("IP address not allowed for service key")
+	// ErrNotFound is returned when a key does not exist or was revoked
+	ErrNotFound = errors.New("service key not found")
+	// ErrInvalidScope is returned for scopes that do not exist
+	ErrInvalidScope = errors.New("invalid service key scope")
+	// ErrInvalidAllowedIP is returned for allowlist entries that are neither an IP address nor a CIDR range
+	ErrInvalidAllowedIP = errors.New("invalid IP address or range")
+	// ErrInvalidRateLimit is returned for negative rate limits and limits above MaxRateLimit
+	ErrInvalidRateLimit = errors.New("invalid rate limit")
+	// ErrInvalidExpiry is returned for expiry dates in the past
+	ErrInvalidExpiry = errors.New("invalid expiry")
+	// ErrInvalidGrace is returned for rotation grace periods that are negative or above MaxRotationGrace
+	ErrInvalidGrace = errors.New("invalid rotation grace period")
+)
+
+// Input describes a service key to create or the new settings of a key.
+// A rate limit of 0 uses DefaultRateLimit.
+type Input struct {
+	Name        string                   `json:"name" binding:"required,max=100"`
+	Description string                   `json:"description"`
+	Scopes      []models.ServiceKeyScope `json:"scopes" binding:"required,min=1"`
+	AllowedIPs  []string                 `json:"allowed_ips"`
+	RateLimit   int                      `json:"rate_limit"`
+	ExpiresAt   *time.Time               `json:"expires_at"`
+}
+
+// Usage is the rate limit state of a key after a request
+type Usage struct {
+	Limit     int
+	Remaining int
+	Reset     time.Time
+}
+
+// window counts the requests of a key in the current rate limit window
+type window struct {
+	start time.Time
+	count int
+}
+
+// Service manages service keys and authenticates the requests made with them
+type Service struct {
+	db     *gorm.DB
+	logger *zap.Logger
+	now    func() time.Time
+
+	mu      sync.Mutex
+	windows map[uuid.UUID]*window
+}
+
+func NewService(db *gorm.DB, logger *zap.Logger) *Service {
+	return &Service{
+		db:      db,
+		logger:  logger,
+		now:     time.Now,
+		windows: make(map[uuid.UUID]*window),
+	}
+}
+
+// List returns all service keys, newest first
+func (s *Service) List() ([]models.ServiceKey, error) {
+	var keys []models.ServiceKey
+	if err := s.db.Order("created_at DESC").Find(&keys).Error; err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// Create creates a key and returns it together with the plain key, which is
+// only available at this point
+func (s *Service) Create(input Input, createdByID uuid.UUID) (*models.ServiceKey, string, error) {
+	key := &models.ServiceKey{CreatedByID: createdByID}
+	if err := s.apply(key, input); err != nil {
+		return nil, "", err
+	}
+	plain, err := key.Generate(models.ServiceKeyPrefix)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if err := s.db.Create(key).Error; err != nil {
+		return nil, "", fmt.Errorf("failed to create service key: %w", err)
+	}
+
+	s.logger.Info("Service key created",
+		zap.String("service_key_id", key.ID.String()),
+		zap.String("created_by", createdByID.String()),
+		zap.String("scopes", key.Scopes))
+
+	return key, plain, nil
+}
+
+// Update changes the name, scopes, allowlist, rate limit and expiry of a key
+func (s *Service) Update(id uuid.UUID, input Input) (*models.ServiceKey, error) {
+	key, err := s.find(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.apply(key, input); err != nil {
+		return nil, err
+	}
+
+	if err := s.db.Model(key).Select("name", "description", "scopes", "allowed_ips", "rate_limit", "expires_at").
+		Updates(key).Error; err != nil {
+		return nil, fmt.Errorf("failed to update service key: %w", err)
+	}
+	return key, nil
+}
+
+// Rotate replaces the secret of a key and returns the new plain key. The
+// replaced key keeps working for the grace period; a grace period of 0
+// invalidates it right away.
+func (s *Service) Rotate(id uuid.UUID, grace time.Duration) (*models.ServiceKey, string, error) {
+	if grace < 0 || grace > MaxRotationGrace {
+		return nil, "", ErrInvalidGrace
+	}
+	key, err := s.find(id)
+	if err != nil {
+		return nil, "", err
+	}
+
+	now := s.now()
+	previousHash := key.KeyHash
+	plain, err := key.Generate(models.ServiceKeyPrefix)
+	if err != nil {
+		return nil, "", err
+	}
+	updates := map[string]interface{}{
+		"key_prefix":              key.KeyPrefix,
+		"key_hash":                key.KeyHash,
+		"previous_key_hash":       "",
+		"previous_key_expires_at": nil,
+		"rotated_at":              now,
+	}
+	key.PreviousKeyHash = ""
+	key.PreviousKeyExpiresAt = nil
+	if grace > 0 {
+		expiresAt := now.Add(grace)
+		updates["previous_key_hash"] = previousHash
+		updates["previous_key_expires_at"] = expiresAt
+		key.PreviousKeyHash = previousHash
+		key.PreviousKeyExpiresAt = &expiresAt
+	}
+	key.RotatedAt = &now
+
+	if err := s.db.Model(key).Updates(updates).Error; err != nil {
+		return nil, "", fmt.Errorf("failed to rotate service key: %w", err)
+	}
+
+	s.logger.Info("Service key rotated", zap.String("service_key_id", key.ID.String()), zap.Duration("grace", grace))
+	return key, plain, nil
+}
+
+// Revoke revokes a key, including a replaced key that is still in its grace period
+func (s *Service) Revoke(id uuid.UUID) error {
+	revoked, err := models.RevokeKeys(s.db.Model(&models.ServiceKey{}).Where("id = ?", id), s.now())
+	if err != nil {
+		return fmt.Errorf("failed to revoke service key: %w", err)
+	}
+	if revoked == 0 {
+		return ErrNotFound
+	}
+
+	s.logger.Info("Service key revoked", zap.String("service_key_id", id.String()))
+	return nil
+}
+
+// Authenticate resolves a plain key used from ip to its stored key. Replaced
+// keys are accepted until their grace period ends.
+func (s *Service) Authenticate(plain, ip string) (*models.ServiceKey, error) {
+	if !strings.HasPrefix(plain, models.ServiceKeyPrefix) {
+		return nil, ErrInvalidKey
+	}
+
+	now := s.now()
+	hash := models.HashKey(plain)
+	var key models.ServiceKey
+	err := s.db.Where("key_hash = ? OR (previous_key_hash = ? AND previous_key_expires_at > ?)", hash, hash, now).
+		First(&key).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrInvalidKey
+	}
+	if err != nil {
+		s.logger.Error("Failed to load service key", zap.Error(err))
+		return nil, err
+	}
+	if !key.IsActive(now) {
+		return nil, ErrInvalidKey
+	}
+	if !ipAllowed(key.GetAllowedIPs(), ip) {
+		s.logger.Warn("Service key used from address outside its allowlist",
+			zap.String("service_key_id", key.ID.String()), zap.String("ip", ip))
+		return nil, ErrIPNotAllowed
+	}
+
+	// Only record usage once a minute; form submissions can come in bursts
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) > time.Minute || key.LastUsedIP != ip {
+		if err := s.db.Model(&key).UpdateColumns(map[string]interface{}{
+			"last_used_at": now,
+			"last_used_ip": ip,
+		}).Error; err != nil {
+			s.logger.Warn("Failed to record service key usage", zap.String("service_key_id", key.ID.String()), zap.Error(err))
+		}
+		key.LastUsedAt = &now
+		key.LastUsedIP = ip
+	}
+
+	return &key, nil
+}
+
+// Allow counts a request of a key against its rate limit and reports whether
+// it is within the limit. Windows are kept in memory per instance.
+func (s *Service) Allow(key *models.ServiceKey) (Usage, bool) {
+	limit := key.RateLimit
+	if limit <= 0 {
+		limit = DefaultRateLimit
+	}
+	now := s.now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, ok := s.windows[key.ID]
+	if !ok || now.Sub(current.start) >= rateWindow {
+		current = &window{start: now}
+		s.windows[key.ID] = current
+	}
+	usage := Usage{Limit: limit, Reset: current.start.Add(rateWindow)}
+	if current.count >= limit {
+		return usage, false
+	}
+	current.count++
+	usage.Remaining = limit - current.count
+	return usage, true
+}
+
+func (s *Service) find(id uuid.UUID) (*models.ServiceKey, error) {
+	var key models.ServiceKey
+	if err := s.db.Where("id = ? AND revoked_at IS NULL", id).First(&key).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &key, nil
+}
+
+// apply validates input and sets it on key
+func (s *Service) apply(key *models.ServiceKey, input Input) error {
+	for _, scope := range input.Scopes {
+		if !scope.IsValid() {
+			return fmt.Errorf("%w: %s", ErrInvalidScope, scope)
+		}
+	}
+	allowed := make([]string, 0, len(input.AllowedIPs))
+	for _, entry := range input.AllowedIPs {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		normalized, err := normalizeAllowedIP(entry)
+		if err != nil {
+			return err
+		}
+		allowed = append(allowed, normalized)
+	}
+	if input.RateLimit < 0 || input.RateLimit > MaxRateLimit {
+		return ErrInvalidRateLimit
+	}
+	if input.ExpiresAt != nil && !input.ExpiresAt.After(s.now()) {
+		return ErrInvalidExpiry
+	}
+
+	key.Name = strings.TrimSpace(input.Name)
+	key.Description = input.Description
+	key.SetScopes(input.Scopes)
+	key.AllowedIPs = strings.Join(allowed, ",")
+	key.RateLimit = input.RateLimit
+	if key.RateLimit == 0 {
+		key.RateLimit = DefaultRateLimit
+	}
+	key.ExpiresAt = input.ExpiresAt
+	return nil
+}
+
+// normalizeAllowedIP validates an allowlist entry and returns it as an address
+// or a masked CIDR range
+func normalizeAllowedIP(entry string) (string, error) {
+	if strings.Contains(entry, "/") {
+		prefix, err := netip.ParsePrefix(entry)
+		if err != nil {
+			return "", fmt.Errorf("%w: %s", ErrInvalidAllowedIP, entry)
+		}
+		return prefix.Masked().String(), nil
+	}
+	addr, err := netip.ParseAddr(entry)
+	if err != nil {
+		return "", fmt.Errorf("%w: %s", ErrInvalidAllowedIP, entry)
+	}
+	return addr.Unmap().String(), nil
+}
+
+// ipAllowed checks ip against an allowlist; an empty allowlist allows any address
+func ipAllowed(allowlist []string, ip string) bool {
+	if len(allowlist) == 0 {
+		return true
+	}
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, entry := range allowlist {
+		if prefix, err := netip.ParsePrefix(entry); err == nil {
+			if prefix.Contains(addr) {
+				return true
+			}
+			continue
+		}
+		if allowed, err := netip.ParseAddr(entry); err == nil && allowed == addr {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,139 @@
+package servicekeys
+
+import (
+	"testing"
+	"time"
+
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/tests/testutils"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+func TestServiceKeys(t *testing.T) {
+	_, service := setupTestService(t)
+	adminID := uuid.New()
+
+	key, plain, err := service.Create(Input{
+		Name:       " Website ",
+		Scopes:     []models.ServiceKeyScope{models.ServiceKeyScopeContactForms},
+		AllowedIPs: []string{"203.0.113.7", "198.51.100.0/24", " "},
+	}, adminID)
+	require.NoError(t, err)
+	assert.Equal(t, "Website", key.Name)
+	assert.Contains(t, plain, models.ServiceKeyPrefix)
+	assert.Equal(t, DefaultRateLimit, key.RateLimit)
+	assert.Equal(t, []string{"203.0.113.7", "198.51.100.0/24"}, key.GetAllowedIPs())
+
+	authenticated, err := service.Authenticate(plain, "198.51.100.42")
+	require.NoError(t, err)
+	assert.Equal(t, key.ID, authenticated.ID)
+	assert.Equal(t, "198.51.100.42", authenticated.LastUsedIP)
+	assert.True(t, authenticated.HasScope(models.ServiceKeyScopeContactForms))
+	assert.False(t, authenticated.HasScope(models.ServiceKeyScopeInboundEmail))
+
+	_, err = service.Authenticate(plain, "192.0.2.1")
+	assert.ErrorIs(t, err, ErrIPNotAllowed)
+	_, err = service.Authenticate(models.ServiceKeyPrefix+"unknown", "203.0.113.7")
+	assert.ErrorIs(t, err, ErrInvalidKey)
+
+	// An empty allowlist allows any address
+	key, err = service.Update(key.ID, Input{Name: "Website", Scopes: key.GetScopes(), RateLimit: 10})
+	require.NoError(t, err)
+	assert.Equal(t, 10, key.RateLimit)
+	_, err = service.Authenticate(plain, "192.0.2.1")
+	require.NoError(t, err)
+
+	require.NoError(t, service.Revoke(key.ID))
+	assert.ErrorIs(t, service.Revoke(key.ID), ErrNotFound)
+	_, err = service.Authenticate(plain, "192.0.2.1")
+	assert.ErrorIs(t, err, ErrInvalidKey)
+}
+
+func TestCreateValidation(t *testing.T) {
+	_, service := setupTestService(t)
+	scopes := []models.ServiceKeyScope{models.ServiceKeyScopeInboundEmail}
+	past := time.Now().Add(-time.Hour)
+
+	_, _, err := service.Create(Input{Name: "Partner", Scopes: []models.ServiceKeyScope{"leads"}}, uuid.New())
+	assert.ErrorIs(t, err, ErrInvalidScope)
+	_, _, err = service.Create(Input{Name: "Partner", Scopes: scopes, AllowedIPs: []string{"10.0.0.0/33"}}, uuid.New())
+	assert.ErrorIs(t, err, ErrInvalidAllowedIP)
+	_, _, err = service.Create(Input{Name: "Partner", Scopes: scopes, AllowedIPs: []string{"example.com"}}, uuid.New())
+	assert.ErrorIs(t, err, ErrInvalidAllowedIP)
+	_, _, err = service.Create(Input{Name: "Partner", Scopes: scopes, RateLimit: MaxRateLimit + 1}, uuid.New())
+	assert.ErrorIs(t, err, ErrInvalidRateLimit)
+	_, _, err = service.Create(Input{Name: "Partner", Scopes: scopes, ExpiresAt: &past}, uuid.New())
+	assert.ErrorIs(t, err, ErrInvalidExpiry)
+}
+
+func TestRotate(t *testing.T) {
+	_, service := setupTestService(t)
+	now := time.Now()
+	service.now = func() time.Time { return now }
+
+	key, oldPlain, err := service.Create(Input{Name: "Partner", Scopes: []models.ServiceKeyScope{models.ServiceKeyScopeInboundEmail}}, uuid.New())
+	require.NoError(t, err)
+
+	_, _, err = service.Rotate(key.ID, MaxRotationGrace+time.Hour)
+	assert.ErrorIs(t, err, ErrInvalidGrace)
+
+	rotated, newPlain, err := service.Rotate(key.ID, time.Hour)
+	require.NoError(t, err)
+	assert.NotEqual(t, oldPlain, newPlain)
+	assert.NotNil(t, rotated.RotatedAt)
+
+	// Both keys work during the grace period, only the new one afterwards
+	_, err = service.Authenticate(oldPlain, "203.0.113.7")
+	require.NoError(t, err)
+	_, err = service.Authenticate(newPlain, "203.0.113.7")
+	require.NoError(t, err)
+
+	now = now.Add(time.Hour)
+	_, err = service.Authenticate(oldPlain, "203.0.113.7")
+	assert.ErrorIs(t, err, ErrInvalidKey)
+	_, err = service.Authenticate(newPlain, "203.0.113.7")
+	require.NoError(t, err)
+
+	// Without a grace period the replaced key stops working right away
+	_, latestPlain, err := service.Rotate(key.ID, 0)
+	require.NoError(t, err)
+	_, err = service.Authenticate(newPlain, "203.0.113.7")
+	assert.ErrorIs(t, err, ErrInvalidKey)
+	_, err = service.Authenticate(latestPlain, "203.0.113.7")
+	require.NoError(t, err)
+}
+
+func TestAllow(t *testing.T) {
+	_, service := setupTestService(t)
+	now := time.Now()
+	service.now = func() time.Time { return now }
+	key := &models.ServiceKey{ID: uuid.New(), RateLimit: 2}
+
+	usage, ok := service.Allow(key)
+	assert.True(t, ok)
+	assert.Equal(t, 1, usage.Remaining)
+	_, ok = service.Allow(key)
+	assert.True(t, ok)
+	usage, ok = service.Allow(key)
+	assert.False(t, ok)
+	assert.Equal(t, 0, usage.Remaining)
+
+	// Limits are counted per key
+	_, ok = service.Allow(&models.ServiceKey{ID: uuid.New(), RateLimit: 2})
+	assert.True(t, ok)
+
+	now = now.Add(rateWindow)
+	_, ok = service.Allow(key)
+	assert.True(t, ok)
+}
+
+func setupTestService(t *testing.T) (*gorm.DB, *Service) {
+	t.Helper()
+	db := testutils.NewSQLiteDB(t, &models.ServiceKey{})
+	return db, NewService(db, zap.NewNop())
+}
@@ -0,0 +1,155 @@
+// Package sessions issues access tokens together with refresh tokens that are
+// stored in the database, so sessions can be refreshed, rotated and revoked.
+package sessions
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/pkg/auth"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrInvalidRefreshToken is returned for unknown, revoked and expired refresh tokens
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	// ErrAccountDeactivated is returned when the user of a session was deactivated
+	ErrAccountDeactivated = errors.New("account deactivated")
+)
+
+// Session is a new access token and the refresh token to renew it
+type Session struct {
+	AccessToken      string
+	RefreshToken     string
+	ExpiresAt        time.Time
+	RefreshExpiresAt time.Time
+}
+
+// Service creates and refreshes sessions
+type Service struct {
+	db     *gorm.DB
+	logger *zap.Logger
+	jwt    *auth.JWTService
+	now    func() time.Time
+}
+
+func NewService(db *gorm.DB, logger *zap.Logger, jwtService *auth.JWTService) *Service {
+	return &Service{
+		db:     db,
+		logger: logger,
+		jwt:    jwtService,
+		now:    time.Now,
+	}
+}
+
+// Create starts a session for the user. Only a hash of the refresh token is stored.
+func (s *Service) Create(user *models.User) (*Session, error) {
+	return s.create(s.db, user)
+}
+
+// Refresh exchanges a refresh token for a new session. The token is rotated:
+// it stops working once used. Presenting a token that was already rotated
+// means it leaked, so all sessions of the user are revoked.
+func (s *Service) Refresh(token string) (*models.User, *Session, error) {
+	var user models.User
+	var session *Session
+	reused := false
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var stored models.RefreshToken
+		if err := tx.Where("token = ?", models.HashRefreshToken(token)).First(&stored).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrInvalidRefreshToken
+			}
+			return err
+		}
+		if stored.IsRevoked {
+			reused = true
+			return ErrInvalidRefreshToken
+		}
+		if !stored.ExpiresAt.After(s.now()) {
+			return ErrInvalidRefreshToken
+		}
+
+		// Concurrent refreshes with the same token: only one rotates it
+		rotated := tx.Model(&models.RefreshToken{}).Where("id = ? AND is_revoked = ?", stored.ID, false).
+			Update("is_revoked", true)
+		if rotated.Error != nil {
+			return fmt.Errorf("failed to rotate refresh token: %w", rotated.Error)
+		}
+		if rotated.RowsAffected == 0 {
+			return ErrInvalidRefreshToken
+		}
+
+		if err := tx.First(&user, "id = ?", stored.UserID).Error; err != nil {
+			return fmt.Errorf("failed to load user: %w", err)
+		}
+		if user.IsDeactivated() {
+			return ErrAccountDeactivated
+		}
+
+		var err error
+		session, err = s.create(tx, &user)
+		return err
+	})
+
+	if reused {
+		s.revokeAll(token)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return &user, session, nil
+}
+
+// Revoke ends the session of the refresh token. Unknown tokens are ignored.
+func (s *Service) Revoke(token string) error {
+	if err := s.db.Model(&models.RefreshToken{}).Where("token = ?", models.HashRefreshToken(token)).
+		Update("is_revoked", true).Error; err != nil {
+		return fmt.Errorf("failed to revoke refresh token: %w", err)
+	}
+	return nil
+}
+
+func (s *Service) create(tx *gorm.DB, user *models.User) (*Session, error) {
+	pair, err := s.jwt.GenerateTokenPair(user)
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	session := &Session{
+		AccessToken:      pair.AccessToken,
+		RefreshToken:     pair.RefreshToken,
+		ExpiresAt:        now.Add(time.Duration(pair.ExpiresIn) * time.Second),
+		RefreshExpiresAt: now.Add(s.jwt.RefreshTTL()),
+	}
+	if err := tx.Create(&models.RefreshToken{
+		UserID:    user.ID,
+		Token:     models.HashRefreshToken(pair.RefreshToken),
+		ExpiresAt: session.RefreshExpiresAt,
+	}).Error; err != nil {
+		return nil, fmt.Errorf("failed to store refresh token: %w", err)
+	}
+	return session, nil
+}
+
+// revokeAll revokes every session of the user owning the reused token
+func (s *Service) revokeAll(token string) {
+	var stored models.RefreshToken
+	if err := s.db.Select("user_id").Where("token = ?", models.HashRefreshToken(token)).
+		First(&stored).Error; err != nil {
+		s.logger.Error("Failed to load reused refresh token", zap.Error(err))
+		return
+	}
+
+	s.logger.Warn("Rotated refresh token was reused, revoking all sessions",
+		zap.String("user_id", stored.UserID.String()))
+	if err := s.db.Model(&models.RefreshToken{}).Where("user_id = ? AND is_revoked = ?", stored.UserID, false).
+		Update("is_revoked", true).Error; err != nil {
+		s.logger.Error("Failed to revoke sessions", zap.String("user_id", stored.UserID.String()), zap.Error(err))
+	}
+}
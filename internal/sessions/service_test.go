@@ -0,0 +1,97 @@
+package sessions
+
+import (
+	"testing"
+	"time"
+
+	"elterngeld-portal/config"
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/pkg/auth"
+	"elterngeld-portal/tests/testutils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+func TestRefresh_RotatesToken(t *testing.T) {
+	db, service := setupTestService(t)
+	user := testutils.CreateTestUser(t, db, models.RoleUser)
+
+	session, err := service.Create(user)
+	require.NoError(t, err)
+	assert.NotEmpty(t, session.AccessToken)
+
+	var stored models.RefreshToken
+	require.NoError(t, db.First(&stored, "user_id = ?", user.ID).Error)
+	assert.NotEqual(t, session.RefreshToken, stored.Token, "only the hash is stored")
+
+	refreshedUser, refreshed, err := service.Refresh(session.RefreshToken)
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, refreshedUser.ID)
+	assert.NotEqual(t, session.RefreshToken, refreshed.RefreshToken)
+
+	// The new token works once, the old one no longer
+	_, _, err = service.Refresh(refreshed.RefreshToken)
+	require.NoError(t, err)
+	_, _, err = service.Refresh("unknown")
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+}
+
+func TestRefresh_ReuseRevokesAllSessions(t *testing.T) {
+	db, service := setupTestService(t)
+	user := testutils.CreateTestUser(t, db, models.RoleUser)
+
+	session, err := service.Create(user)
+	require.NoError(t, err)
+	other, err := service.Create(user)
+	require.NoError(t, err)
+
+	_, rotated, err := service.Refresh(session.RefreshToken)
+	require.NoError(t, err)
+
+	_, _, err = service.Refresh(session.RefreshToken)
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+
+	for _, token := range []string{rotated.RefreshToken, other.RefreshToken} {
+		_, _, err = service.Refresh(token)
+		assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+	}
+}
+
+func TestRefresh_ExpiredRevokedAndDeactivated(t *testing.T) {
+	db, service := setupTestService(t)
+	user := testutils.CreateTestUser(t, db, models.RoleUser)
+
+	expired, err := service.Create(user)
+	require.NoError(t, err)
+	service.now = func() time.Time { return time.Now().Add(8 * 24 * time.Hour) }
+	_, _, err = service.Refresh(expired.RefreshToken)
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+	service.now = time.Now
+
+	loggedOut, err := service.Create(user)
+	require.NoError(t, err)
+	require.NoError(t, service.Revoke(loggedOut.RefreshToken))
+	_, _, err = service.Refresh(loggedOut.RefreshToken)
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+
+	session, err := service.Create(user)
+	require.NoError(t, err)
+	require.NoError(t, db.Model(user).Update("deactivated_at", time.Now()).Error)
+	_, _, err = service.Refresh(session.RefreshToken)
+	assert.ErrorIs(t, err, ErrAccountDeactivated)
+}
+
+func setupTestService(t *testing.T) (*gorm.DB, *Service) {
+	t.Helper()
+	db := testutils.NewSQLiteDB(t, &models.User{}, &models.RefreshToken{})
+
+	jwtService := auth.NewJWTService(&config.Config{JWT: config.JWTConfig{
+		Secret:        "test-secret",
+		AccessExpiry:  15 * time.Minute,
+		RefreshExpiry: 7 * 24 * time.Hour,
+	}})
+	return db, NewService(db, zap.NewNop(), jwtService)
+}
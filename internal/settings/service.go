@@ -0,0 +1,331 @@
+// Package settings holds system settings admins change at runtime, such as
+// cancellation windows, the lead response SLA and support contact details.
+// Every setting is defined in code with a type, a group and a default from the
+// configuration; the database only stores changed values.
+package settings
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"elterngeld-portal/config"
+	"elterngeld-portal/internal/models"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	GroupBooking = "booking"
+	GroupLeads   = "leads"
+	GroupSupport = "support"
+)
+
+const (
+	KeyCancellationWindow = "booking.cancellation_window"
+	KeyRescheduleWindow   = "booking.reschedule_window"
+	KeyLeadResponseSLA    = "leads.response_sla"
+	KeyInboxClaimSLA      = "leads.inbox_claim_sla"
+	KeySupportEmail       = "support.email"
+	KeySupportPhone       = "support.phone"
+	KeySupportHours       = "support.hours"
+)
+
+// cacheTTL bounds how long an instance keeps serving a value another instance changed
+const cacheTTL = 30 * time.Second
+
+// maxStringLength limits free text settings
+const maxStringLength = 500
+
+var (
+	// ErrUnknownSetting is returned for keys without a definition
+	ErrUnknownSetting = errors.New("unknown setting")
+	// ErrInvalidValue is returned when a value does not match the type or range of the setting
+	ErrInvalidValue = errors.New("invalid setting value")
+)
+
+// Definition describes a setting. Max limits durations; 0 means no limit.
+type Definition struct {
+	Key         string
+	Group       string
+	Type        models.SettingType
+	Default     string
+	Description string
+	Public      bool // shown to customers and the website, e.g. support contact details
+	Max         time.Duration
+}
+
+// Entry is a setting with its current value
+type Entry struct {
+	Key         string             `json:"key"`
+	Group       string             `json:"group"`
+	Type        models.SettingType `json:"type"`
+	Value       string             `json:"value"`
+	Default     string             `json:"default"`
+	IsDefault   bool               `json:"is_default"`
+	Description string             `json:"description"`
+	Public      bool               `json:"public"`
+	UpdatedBy   *uuid.UUID         `json:"updated_by,omitempty"`
+	UpdatedAt   *time.Time         `json:"updated_at,omitempty"`
+}
+
+// Service reads and changes settings. Reads are served from a cache that is
+// refreshed every cacheTTL, so hot paths do not query the database.
+type Service struct {
+	db          *gorm.DB
+	logger      *zap.Logger
+	definitions map[string]Definition
+	now         func() time.Time
+
+	mu       sync.RWMutex
+	cache    map[string]string
+	loadedAt time.Time
+}
+
+func NewService(db *gorm.DB, logger *zap.Logger, cfg *config.Config) *Service {
+	definitions := []Definition{
+		{
+			Key: KeyCancellationWindow, Group: GroupBooking, Type: models.SettingTypeDuration, Default: "24h",
+			Description: "Customers can cancel bookings up to this long before the appointment", Public: true, Max: 30 * 24 * time.Hour,
+		},
+		{
+			Key: KeyRescheduleWindow, Group: GroupBooking, Type: models.SettingTypeDuration, Default: "24h",
+			Description: "Customers can reschedule bookings up to this long before the appointment", Public: true, Max: 30 * 24 * time.Hour,
+		},
+		{
+			Key: KeyLeadResponseSLA, Group: GroupLeads, Type: models.SettingTypeDuration, Default: cfg.Chat.LeadResponseSLA.String(),
+			Description: "New leads without a first contact after this period breach the SLA; 0 disables the check", Max: 14 * 24 * time.Hour,
+		},
+		{
+			Key: KeyInboxClaimSLA, Group: GroupLeads, Type: models.SettingTypeDuration, Default: cfg.Chat.InboxClaimSLA.String(),
+			Description: "Contact forms and unassigned leads nobody claimed after this period are posted to chat; 0 disables the check", Max: 14 * 24 * time.Hour,
+		},
+		{
+			Key: KeySupportEmail, Group: GroupSupport, Type: models.SettingTypeEmail, Default: cfg.Email.From,
+			Description: "Support email address shown to customers", Public: true,
+		},
+		{
+			Key: KeySupportPhone, Group: GroupSupport, Type: models.SettingTypeString,
+			Description: "Support phone number shown to customers", Public: true,
+		},
+		{
+			Key: KeySupportHours, Group: GroupSupport, Type: models.SettingTypeString,
+			Description: "Support opening hours shown to customers, e.g. Mo-Fr 9-17 Uhr", Public: true,
+		},
+	}
+
+	s := &Service{
+		db:          db,
+		logger:      logger,
+		definitions: make(map[string]Definition, len(definitions)),
+		now:         time.Now,
+	}
+	for _, definition := range definitions {
+		s.definitions[definition.Key] = definition
+	}
+	return s
+}
+
+// List returns the settings of a group, or all settings for an empty group,
+// ordered by key
+func (s *Service) List(group string) ([]Entry, error) {
+	var stored []models.SystemSetting
+	if err := s.db.Find(&stored).Error; err != nil {
+		return nil, fmt.Errorf("failed to load settings: %w", err)
+	}
+	byKey := make(map[string]*models.SystemSetting, len(stored))
+	for i := range stored {
+		byKey[stored[i].Key] = &stored[i]
+	}
+
+	entries := []Entry{}
+	for _, definition := range s.definitions {
+		if group != "" && definition.Group != group {
+			continue
+		}
+		entries = append(entries, entry(definition, byKey[definition.Key]))
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Key < entries[j].Key })
+	return entries, nil
+}
+
+// Get returns a setting with its current value
+func (s *Service) Get(key string) (*Entry, error) {
+	definition, ok := s.definitions[key]
+	if !ok {
+		return nil, ErrUnknownSetting
+	}
+
+	var stored models.SystemSetting
+	if err := s.db.Where("key = ?", key).Limit(1).Find(&stored).Error; err != nil {
+		return nil, fmt.Errorf("failed to load setting: %w", err)
+	}
+	if stored.Key == "" {
+		result := entry(definition, nil)
+		return &result, nil
+	}
+	result := entry(definition, &stored)
+	return &result, nil
+}
+
+// Update validates and stores a new value for a setting
+func (s *Service) Update(key, value string, updatedBy uuid.UUID) (*Entry, error) {
+	definition, ok := s.definitions[key]
+	if !ok {
+		return nil, ErrUnknownSetting
+	}
+	value, err := normalize(definition, value)
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	setting := &models.SystemSetting{Key: key, Value: value, UpdatedBy: &updatedBy, CreatedAt: now, UpdatedAt: now}
+	if err := s.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "key"}},
+		DoUpdates: clause.AssignmentColumns([]string{"value", "updated_by", "updated_at"}),
+	}).Create(setting).Error; err != nil {
+		return nil, fmt.Errorf("failed to store setting: %w", err)
+	}
+	s.invalidate()
+
+	s.logger.Info("System setting changed",
+		zap.String("key", key),
+		zap.String("value", value),
+		zap.String("updated_by", updatedBy.String()))
+	return s.Get(key)
+}
+
+// Reset removes the stored value, so the setting uses its default again
+func (s *Service) Reset(key string) (*Entry, error) {
+	if _, ok := s.definitions[key]; !ok {
+		return nil, ErrUnknownSetting
+	}
+	if err := s.db.Where("key = ?", key).Delete(&models.SystemSetting{}).Error; err != nil {
+		return nil, fmt.Errorf("failed to reset setting: %w", err)
+	}
+	s.invalidate()
+	return s.Get(key)
+}
+
+// Public returns the current values of the public settings by key
+func (s *Service) Public() map[string]string {
+	public := map[string]string{}
+	for key, definition := range s.definitions {
+		if definition.Public {
+			public[key] = s.String(key)
+		}
+	}
+	return public
+}
+
+// String returns the current value of a setting. Unknown keys return "".
+func (s *Service) String(key string) string {
+	definition, ok := s.definitions[key]
+	if !ok {
+		s.logger.Error("Unknown setting read", zap.String("key", key))
+		return ""
+	}
+	if value, ok := s.values()[key]; ok {
+		return value
+	}
+	return definition.Default
+}
+
+// Duration returns the current value of a duration setting
+func (s *Service) Duration(key string) time.Duration {
+	value, err := time.ParseDuration(s.String(key))
+	if err != nil {
+		return 0
+	}
+	return value
+}
+
+// values returns the stored values, reloading them when the cache expired.
+// If the database is unavailable the previous values are served.
+func (s *Service) values() map[string]string {
+	now := s.now()
+	s.mu.RLock()
+	cache, loadedAt := s.cache, s.loadedAt
+	s.mu.RUnlock()
+	if cache != nil && now.Sub(loadedAt) < cacheTTL {
+		return cache
+	}
+
+	var stored []models.SystemSetting
+	if err := s.db.Find(&stored).Error; err != nil {
+		s.logger.Error("Failed to load settings", zap.Error(err))
+		if cache == nil {
+			return map[string]string{}
+		}
+		return cache
+	}
+
+	cache = make(map[string]string, len(stored))
+	for _, setting := range stored {
+		cache[setting.Key] = setting.Value
+	}
+	s.mu.Lock()
+	s.cache, s.loadedAt = cache, now
+	s.mu.Unlock()
+	return cache
+}
+
+func (s *Service) invalidate() {
+	s.mu.Lock()
+	s.cache = nil
+	s.mu.Unlock()
+}
+
+func entry(definition Definition, stored *models.SystemSetting) Entry {
+	result := Entry{
+		Key:         definition.Key,
+		Group:       definition.Group,
+		Type:        definition.Type,
+		Value:       definition.Default,
+		Default:     definition.Default,
+		IsDefault:   true,
+		Description: definition.Description,
+		Public:      definition.Public,
+	}
+	if stored != nil {
+		result.Value = stored.Value
+		result.IsDefault = false
+		result.UpdatedBy = stored.UpdatedBy
+		result.UpdatedAt = &stored.UpdatedAt
+	}
+	return result
+}
+
+// normalize checks a value against the type and range of the setting
+func normalize(definition Definition, value string) (string, error) {
+	value = strings.TrimSpace(value)
+	switch definition.Type {
+	case models.SettingTypeDuration:
+		duration, err := time.ParseDuration(value)
+		if err != nil {
+			return "", fmt.Errorf("%w: %s is not a duration like 24h or 90m", ErrInvalidValue, definition.Key)
+		}
+		if duration < 0 || (definition.Max > 0 && duration > definition.Max) {
+			return "", fmt.Errorf("%w: %s must be between 0 and %s", ErrInvalidValue, definition.Key, definition.Max)
+		}
+	case models.SettingTypeEmail:
+		address, err := mail.ParseAddress(value)
+		if err != nil || address.Name != "" {
+			return "", fmt.Errorf("%w: %s is not an email address", ErrInvalidValue, definition.Key)
+		}
+		value = strings.ToLower(address.Address)
+	default:
+		if len(value) > maxStringLength {
+			return "", fmt.Errorf("%w: %s is longer than %d characters", ErrInvalidValue, definition.Key, maxStringLength)
+		}
+	}
+	return value, nil
+}
@@ -0,0 +1,119 @@
+package settings
+
+import (
+	"testing"
+	"time"
+
+	"elterngeld-portal/config"
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/tests/testutils"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+func TestUpdate_ValidatesAndStores(t *testing.T) {
+	_, service := setupTestService(t)
+	admin := uuid.New()
+
+	entry, err := service.Get(KeyLeadResponseSLA)
+	require.NoError(t, err)
+	assert.True(t, entry.IsDefault)
+	assert.Equal(t, 24*time.Hour, service.Duration(KeyLeadResponseSLA), "default from the configuration")
+
+	entry, err = service.Update(KeyLeadResponseSLA, " 4h ", admin)
+	require.NoError(t, err)
+	assert.Equal(t, "4h", entry.Value)
+	assert.False(t, entry.IsDefault)
+	assert.Equal(t, admin, *entry.UpdatedBy)
+	assert.Equal(t, 4*time.Hour, service.Duration(KeyLeadResponseSLA))
+
+	// A second update replaces the value
+	_, err = service.Update(KeyLeadResponseSLA, "2h", admin)
+	require.NoError(t, err)
+	assert.Equal(t, 2*time.Hour, service.Duration(KeyLeadResponseSLA))
+
+	entry, err = service.Update(KeySupportEmail, "Hilfe@Example.com", admin)
+	require.NoError(t, err)
+	assert.Equal(t, "hilfe@example.com", entry.Value)
+
+	tests := []struct {
+		key   string
+		value string
+	}{
+		{KeyCancellationWindow, "one day"},
+		{KeyCancellationWindow, "-1h"},
+		{KeyCancellationWindow, "1000h"},
+		{KeySupportEmail, "no address"},
+		{KeySupportEmail, "Support <support@example.com>"},
+	}
+	for _, tt := range tests {
+		_, err := service.Update(tt.key, tt.value, admin)
+		assert.ErrorIs(t, err, ErrInvalidValue, "%s=%s", tt.key, tt.value)
+	}
+
+	_, err = service.Update("booking.unknown", "1h", admin)
+	assert.ErrorIs(t, err, ErrUnknownSetting)
+}
+
+func TestReset_RestoresDefault(t *testing.T) {
+	_, service := setupTestService(t)
+
+	_, err := service.Update(KeyCancellationWindow, "48h", uuid.New())
+	require.NoError(t, err)
+	assert.Equal(t, 48*time.Hour, service.Duration(KeyCancellationWindow))
+
+	entry, err := service.Reset(KeyCancellationWindow)
+	require.NoError(t, err)
+	assert.True(t, entry.IsDefault)
+	assert.Equal(t, models.DefaultChangeWindow, service.Duration(KeyCancellationWindow))
+}
+
+func TestList_GroupsAndPublic(t *testing.T) {
+	_, service := setupTestService(t)
+
+	entries, err := service.List(GroupSupport)
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+	assert.Equal(t, KeySupportEmail, entries[0].Key)
+	assert.Equal(t, "support@example.com", entries[0].Value)
+
+	all, err := service.List("")
+	require.NoError(t, err)
+	assert.Len(t, all, 7)
+
+	public := service.Public()
+	assert.Contains(t, public, KeySupportPhone)
+	assert.Contains(t, public, KeyCancellationWindow)
+	assert.NotContains(t, public, KeyLeadResponseSLA)
+	assert.NotContains(t, public, KeyInboxClaimSLA)
+}
+
+func TestRuntimeReads_CacheExpires(t *testing.T) {
+	db, service := setupTestService(t)
+	now := time.Now()
+	service.now = func() time.Time { return now }
+
+	assert.Equal(t, models.DefaultChangeWindow, service.Duration(KeyRescheduleWindow))
+
+	// A change by another instance shows up once the cache expired
+	require.NoError(t, db.Create(&models.SystemSetting{Key: KeyRescheduleWindow, Value: "12h"}).Error)
+	assert.Equal(t, models.DefaultChangeWindow, service.Duration(KeyRescheduleWindow))
+
+	now = now.Add(cacheTTL)
+	assert.Equal(t, 12*time.Hour, service.Duration(KeyRescheduleWindow))
+}
+
+func setupTestService(t *testing.T) (*gorm.DB, *Service) {
+	t.Helper()
+	db := testutils.NewSQLiteDB(t, &models.SystemSetting{})
+
+	cfg := &config.Config{
+		Chat:  config.ChatConfig{LeadResponseSLA: 24 * time.Hour, InboxClaimSLA: 4 * time.Hour},
+		Email: config.EmailConfig{From: "support@example.com"},
+	}
+	return db, NewService(db, zap.NewNop(), cfg)
+}
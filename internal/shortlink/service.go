@@ -0,0 +1,213 @@
+package shortlink
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"elterngeld-portal/config"
+	"elterngeld-portal/internal/models"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// MaxLeadScore is the upper bound for lead scores raised by link engagement
+const MaxLeadScore = 100
+
+var (
+	// ErrNotFound is returned when no short link exists for a code
+	ErrNotFound = errors.New("short link not found")
+	// ErrExpired is returned when a short link is past its expiry date
+	ErrExpired = errors.New("short link expired")
+)
+
+// Options describes the recipient and purpose of a tracked link
+type Options struct {
+	Purpose        models.ShortLinkPurpose
+	RecipientEmail string
+	UserID         *uuid.UUID
+	LeadID         *uuid.UUID
+	TTL            time.Duration
+}
+
+// Click holds request information recorded for a click
+type Click struct {
+	IPAddress string
+	UserAgent string
+	Referer   string
+}
+
+// Service creates tracked short links and records clicks on them
+type Service struct {
+	db      *gorm.DB
+	logger  *zap.Logger
+	baseURL string
+}
+
+func NewService(db *gorm.DB, logger *zap.Logger, cfg *config.Config) *Service {
+	return &Service{
+		db:      db,
+		logger:  logger,
+		baseURL: strings.TrimRight(cfg.App.BaseURL, "/"),
+	}
+}
+
+// Create stores a new short link pointing to targetURL
+func (s *Service) Create(targetURL string, opts Options) (*models.ShortLink, error) {
+	link := &models.ShortLink{
+		TargetURL:      targetURL,
+		Purpose:        opts.Purpose,
+		RecipientEmail: models.NormalizeEmailAddress(opts.RecipientEmail),
+		UserID:         opts.UserID,
+		LeadID:         opts.LeadID,
+	}
+	if opts.TTL > 0 {
+		expiresAt := time.Now().Add(opts.TTL)
+		link.ExpiresAt = &expiresAt
+	}
+
+	if err := s.db.Create(link).Error; err != nil {
+		return nil, fmt.Errorf("failed to create short link: %w", err)
+	}
+
+	return link, nil
+}
+
+// Shorten creates a short link and returns its public URL
+func (s *Service) Shorten(targetURL string, opts Options) (string, error) {
+	link, err := s.Create(targetURL, opts)
+	if err != nil {
+		return "", err
+	}
+	return s.URL(link), nil
+}
+
+// URL returns the public redirect URL of a short link
+func (s *Service) URL(link *models.ShortLink) string {
+	return s.baseURL + "/r/" + link.Code
+}
+
+// Resolve records a click on the link with the given code and returns the link.
+// The first click on a link attached to a lead raises the lead score.
+func (s *Service) Resolve(code string, click Click) (*models.ShortLink, error) {
+	var link models.ShortLink
+	if err := s.db.Where("code = ?", code).First(&link).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	if link.IsExpired() {
+		return &link, ErrExpired
+	}
+
+	now := time.Now()
+	firstClick := link.FirstClickedAt == nil
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&models.ShortLinkClick{
+			ShortLinkID: link.ID,
+			IPAddress:   click.IPAddress,
+			UserAgent:   click.UserAgent,
+			Referer:     click.Referer,
+			ClickedAt:   now,
+		}).Error; err != nil {
+			return err
+		}
+
+		updates := map[string]interface{}{
+			"click_count":     gorm.Expr("click_count + 1"),
+			"last_clicked_at": now,
+		}
+		if firstClick {
+			updates["first_clicked_at"] = now
+		}
+		if err := tx.Model(&link).Updates(updates).Error; err != nil {
+			return err
+		}
+
+		if firstClick && link.LeadID != nil {
+			return s.recordEngagement(tx, &link)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to record click: %w", err)
+	}
+
+	link.ClickCount++
+	link.LastClickedAt = &now
+	if firstClick {
+		link.FirstClickedAt = &now
+	}
+
+	return &link, nil
+}
+
+// recordEngagement raises the lead score for the first click on a link and logs an activity
+func (s *Service) recordEngagement(tx *gorm.DB, link *models.ShortLink) error {
+	var lead models.Lead
+	if err := tx.Select("id", "lead_score").First(&lead, "id = ?", *link.LeadID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	delta := link.Purpose.GetEngagementScore()
+	score := lead.LeadScore + delta
+	if score > MaxLeadScore {
+		score = MaxLeadScore
+	}
+
+	if err := tx.Model(&lead).Updates(map[string]interface{}{
+		"lead_score":        score,
+		"lead_score_reason": fmt.Sprintf("E-Mail-Interaktion: %s geöffnet", link.Purpose.GetDisplayName()),
+	}).Error; err != nil {
+		return err
+	}
+
+	if err := tx.Create(models.CreateLinkClickedActivity(lead.ID, link.Code, link.Purpose, score-lead.LeadScore)).Error; err != nil {
+		return err
+	}
+
+	s.logger.Debug("Lead score raised by link click",
+		zap.String("lead_id", lead.ID.String()),
+		zap.String("code", link.Code),
+		zap.Int("lead_score", score))
+
+	return nil
+}
+
+// Stats summarizes the clicks of all links sent to a lead
+type Stats struct {
+	Links         int64      `json:"links"`
+	ClickedLinks  int64      `json:"clicked_links"`
+	TotalClicks   int64      `json:"total_clicks"`
+	LastClickedAt *time.Time `json:"last_clicked_at"`
+}
+
+// LeadStats returns click statistics for the links sent to a lead
+func (s *Service) LeadStats(leadID uuid.UUID) (*Stats, error) {
+	var links []models.ShortLink
+	if err := s.db.Where("lead_id = ?", leadID).Find(&links).Error; err != nil {
+		return nil, err
+	}
+
+	stats := &Stats{Links: int64(len(links))}
+	for _, link := range links {
+		if link.ClickCount > 0 {
+			stats.ClickedLinks++
+		}
+		stats.TotalClicks += int64(link.ClickCount)
+		if link.LastClickedAt != nil && (stats.LastClickedAt == nil || link.LastClickedAt.After(*stats.LastClickedAt)) {
+			stats.LastClickedAt = link.LastClickedAt
+		}
+	}
+
+	return stats, nil
+}
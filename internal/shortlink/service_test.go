@@ -0,0 +1,109 @@
+package shortlink
+
+import (
+	"testing"
+	"time"
+
+	"elterngeld-portal/config"
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/tests/testutils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+func TestShorten(t *testing.T) {
+	_, service := setupTestService(t)
+
+	url, err := service.Shorten("https://example.com/auth/verify-email?token=abc", Options{
+		Purpose:        models.ShortLinkPurposeVerification,
+		RecipientEmail: "Anna <ANNA@example.com>",
+	})
+	require.NoError(t, err)
+	assert.Regexp(t, `^https://portal\.example\.com/r/[a-zA-Z2-9]{8}$`, url)
+}
+
+func TestResolve_RecordsClicksAndRaisesLeadScore(t *testing.T) {
+	db, service := setupTestService(t)
+	lead := createTestLead(t, db, 95)
+
+	link, err := service.Create("https://example.com/offers/1", Options{
+		Purpose:        models.ShortLinkPurposeOffer,
+		RecipientEmail: "kunde@example.com",
+		LeadID:         &lead.ID,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "kunde@example.com", link.RecipientEmail)
+
+	for i := 0; i < 2; i++ {
+		resolved, err := service.Resolve(link.Code, Click{IPAddress: "127.0.0.1", UserAgent: "test"})
+		require.NoError(t, err)
+		assert.Equal(t, "https://example.com/offers/1", resolved.TargetURL)
+	}
+
+	var stored models.ShortLink
+	require.NoError(t, db.First(&stored, "id = ?", link.ID).Error)
+	assert.Equal(t, 2, stored.ClickCount)
+	assert.NotNil(t, stored.FirstClickedAt)
+
+	var clickCount int64
+	db.Model(&models.ShortLinkClick{}).Where("short_link_id = ?", link.ID).Count(&clickCount)
+	assert.Equal(t, int64(2), clickCount)
+
+	// Only the first click counts towards the lead score, capped at MaxLeadScore
+	require.NoError(t, db.First(lead, "id = ?", lead.ID).Error)
+	assert.Equal(t, MaxLeadScore, lead.LeadScore)
+
+	var activityCount int64
+	db.Model(&models.Activity{}).Where("lead_id = ? AND type = ?", lead.ID, models.ActivityTypeLinkClicked).Count(&activityCount)
+	assert.Equal(t, int64(1), activityCount)
+
+	stats, err := service.LeadStats(lead.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), stats.Links)
+	assert.Equal(t, int64(1), stats.ClickedLinks)
+	assert.Equal(t, int64(2), stats.TotalClicks)
+}
+
+func TestResolve_Errors(t *testing.T) {
+	_, service := setupTestService(t)
+
+	_, err := service.Resolve("missing", Click{})
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	link, err := service.Create("https://example.com", Options{TTL: time.Millisecond})
+	require.NoError(t, err)
+	time.Sleep(5 * time.Millisecond)
+
+	_, err = service.Resolve(link.Code, Click{})
+	assert.ErrorIs(t, err, ErrExpired)
+}
+
+func setupTestService(t *testing.T) (*gorm.DB, *Service) {
+	db := testutils.NewSQLiteDB(t,
+		&models.User{},
+		&models.Lead{},
+		&models.Activity{},
+		&models.ShortLink{},
+		&models.ShortLinkClick{},
+	)
+
+	cfg := &config.Config{App: config.AppConfig{BaseURL: "https://portal.example.com/"}}
+	return db, NewService(db, zap.NewNop(), cfg)
+}
+
+func createTestLead(t *testing.T, db *gorm.DB, score int) *models.Lead {
+	user := testutils.CreateTestUser(t, db, models.RoleUser)
+
+	lead := &models.Lead{
+		UserID:    user.ID,
+		Title:     "Elterngeld Antrag",
+		Status:    models.LeadStatusNew,
+		Priority:  models.PriorityMedium,
+		LeadScore: score,
+	}
+	require.NoError(t, db.Create(lead).Error)
+	return lead
+}
@@ -0,0 +1,149 @@
+// Package sitemap generates the sitemap.xml of the public website from the
+// published jobs, blog posts, content pages and active packages, so search
+// engines find new pages without anyone maintaining the sitemap by hand.
+package sitemap
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"elterngeld-portal/config"
+	"elterngeld-portal/internal/models"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// cacheTTL bounds how long a generated sitemap is served before it is rebuilt
+const cacheTTL = time.Hour
+
+// staticPages are the fixed pages of the website
+var staticPages = []string{"/", "/pakete", "/blog", "/faq", "/jobs", "/kontakt"}
+
+// contentPaths are the website paths of the content kinds
+var contentPaths = map[models.ContentKind]string{
+	models.ContentKindFAQ:   "/faq",
+	models.ContentKindPage:  "/ratgeber",
+	models.ContentKindGuide: "/bundeslaender",
+}
+
+type urlSet struct {
+	XMLName xml.Name `xml:"http://www.sitemaps.org/schemas/sitemap/0.9 urlset"`
+	URLs    []URL    `xml:"url"`
+}
+
+// URL is an entry of the sitemap
+type URL struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+// Service builds and caches the sitemap
+type Service struct {
+	db      *gorm.DB
+	logger  *zap.Logger
+	baseURL string
+	now     func() time.Time
+
+	mu      sync.Mutex
+	cached  []byte
+	builtAt time.Time
+}
+
+func NewService(db *gorm.DB, logger *zap.Logger, cfg *config.Config) *Service {
+	return &Service{
+		db:      db,
+		logger:  logger,
+		baseURL: strings.TrimRight(cfg.App.BaseURL, "/"),
+		now:     time.Now,
+	}
+}
+
+// XML returns the sitemap document. It is rebuilt at most once per cacheTTL;
+// if rebuilding fails the previous sitemap is served.
+func (s *Service) XML() ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if s.cached != nil && now.Sub(s.builtAt) < cacheTTL {
+		return s.cached, nil
+	}
+
+	urls, err := s.URLs()
+	if err == nil {
+		var body []byte
+		body, err = xml.MarshalIndent(urlSet{URLs: urls}, "", "  ")
+		if err == nil {
+			s.cached = append([]byte(xml.Header), body...)
+			s.builtAt = now
+			return s.cached, nil
+		}
+	}
+
+	if s.cached == nil {
+		return nil, fmt.Errorf("failed to build sitemap: %w", err)
+	}
+	s.logger.Error("Failed to rebuild sitemap, serving previous one", zap.Error(err))
+	return s.cached, nil
+}
+
+// URLs returns all public pages of the website
+func (s *Service) URLs() ([]URL, error) {
+	now := s.now()
+	urls := make([]URL, 0, len(staticPages))
+	for _, path := range staticPages {
+		urls = append(urls, URL{Loc: s.baseURL + path})
+	}
+
+	var packages []models.Package
+	if err := s.db.Select("id", "updated_at").Where("is_active = ?", true).
+		Order("sort_order ASC").Find(&packages).Error; err != nil {
+		return nil, fmt.Errorf("failed to load packages: %w", err)
+	}
+	for _, pkg := range packages {
+		urls = append(urls, s.url("/pakete/"+pkg.ID.String(), pkg.UpdatedAt))
+	}
+
+	var posts []models.Post
+	if err := s.db.Select("slug", "updated_at").
+		Where("status = ? AND published_at <= ?", models.PostStatusPublished, now).
+		Order("published_at DESC").Find(&posts).Error; err != nil {
+		return nil, fmt.Errorf("failed to load posts: %w", err)
+	}
+	for _, post := range posts {
+		urls = append(urls, s.url("/blog/"+post.Slug, post.UpdatedAt))
+	}
+
+	var entries []models.ContentEntry
+	if err := s.db.Select("kind", "slug", "updated_at").Where("status = ?", models.ContentStatusPublished).
+		Order("kind ASC, slug ASC").Find(&entries).Error; err != nil {
+		return nil, fmt.Errorf("failed to load content entries: %w", err)
+	}
+	for _, entry := range entries {
+		if path, ok := contentPaths[entry.Kind]; ok {
+			urls = append(urls, s.url(path+"/"+entry.Slug, entry.UpdatedAt))
+		}
+	}
+
+	var jobs []models.Job
+	if err := s.db.Select("slug", "updated_at").
+		Where("status = ? AND published_at IS NOT NULL", models.JobStatusPublished).
+		Where("expires_at IS NULL OR expires_at > ?", now).
+		Where("application_deadline IS NULL OR application_deadline > ?", now).
+		Order("published_at DESC").Find(&jobs).Error; err != nil {
+		return nil, fmt.Errorf("failed to load jobs: %w", err)
+	}
+	for _, job := range jobs {
+		urls = append(urls, s.url("/jobs/"+job.Slug, job.UpdatedAt))
+	}
+
+	return urls, nil
+}
+
+func (s *Service) url(path string, lastMod time.Time) URL {
+	return URL{Loc: s.baseURL + path, LastMod: lastMod.UTC().Format(time.RFC3339)}
+}
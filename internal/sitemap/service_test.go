@@ -0,0 +1,111 @@
+package sitemap
+
+import (
+	"testing"
+	"time"
+
+	"elterngeld-portal/config"
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/tests/testutils"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+func TestURLs(t *testing.T) {
+	service, db := setupTestService(t)
+	now := time.Now()
+	past := now.Add(-time.Hour)
+	future := now.Add(time.Hour)
+
+	active := &models.Package{Name: "Premium", Type: models.PackageTypePremium, Price: 199, StripeProductID: "prod_1", StripePriceID: "price_1"}
+	inactive := &models.Package{Name: "Alt", Type: models.PackageTypePremium, Price: 99, StripeProductID: "prod_2", StripePriceID: "price_2"}
+	require.NoError(t, db.Create(active).Error)
+	require.NoError(t, db.Create(inactive).Error)
+	require.NoError(t, db.Model(inactive).Update("is_active", false).Error)
+
+	for _, post := range []*models.Post{
+		{Slug: "neu-ab-2025", Status: models.PostStatusPublished, PublishedAt: &past},
+		{Slug: "geplant", Status: models.PostStatusPublished, PublishedAt: &future},
+		{Slug: "entwurf", Status: models.PostStatusDraft},
+	} {
+		post.Title, post.Body, post.AuthorID, post.CreatedBy, post.UpdatedBy = post.Slug, "...", uuid.New(), uuid.New(), uuid.New()
+		require.NoError(t, db.Create(post).Error)
+	}
+
+	for _, entry := range []*models.ContentEntry{
+		{Kind: models.ContentKindFAQ, Slug: "wie-lange", Status: models.ContentStatusPublished},
+		{Kind: models.ContentKindGuide, Slug: "elterngeld-in-bayern", Status: models.ContentStatusPublished, Bundesland: models.BundeslandBayern},
+		{Kind: models.ContentKindPage, Slug: "entwurf-seite", Status: models.ContentStatusDraft},
+	} {
+		entry.Title, entry.Body, entry.CreatedBy, entry.UpdatedBy = entry.Slug, "...", uuid.New(), uuid.New()
+		require.NoError(t, db.Create(entry).Error)
+	}
+
+	for _, job := range []*models.Job{
+		{Slug: "elterngeldberater-muenchen", Status: models.JobStatusPublished, PublishedAt: &past},
+		{Slug: "abgelaufen", Status: models.JobStatusPublished, PublishedAt: &past, ExpiresAt: &past},
+		{Slug: "pausiert", Status: models.JobStatusPaused, PublishedAt: &past},
+	} {
+		job.Title, job.Description, job.Type, job.Level, job.Location, job.CreatedBy =
+			job.Slug, "...", models.JobTypeFullTime, models.JobLevelMid, "München", uuid.New()
+		require.NoError(t, db.Create(job).Error)
+	}
+
+	urls, err := service.URLs()
+	require.NoError(t, err)
+
+	locs := make([]string, len(urls))
+	for i, url := range urls {
+		locs[i] = url.Loc
+	}
+	assert.Equal(t, []string{
+		"https://elterngeld.example/",
+		"https://elterngeld.example/pakete",
+		"https://elterngeld.example/blog",
+		"https://elterngeld.example/faq",
+		"https://elterngeld.example/jobs",
+		"https://elterngeld.example/kontakt",
+		"https://elterngeld.example/pakete/" + active.ID.String(),
+		"https://elterngeld.example/blog/neu-ab-2025",
+		"https://elterngeld.example/faq/wie-lange",
+		"https://elterngeld.example/bundeslaender/elterngeld-in-bayern",
+		"https://elterngeld.example/jobs/elterngeldberater-muenchen",
+	}, locs)
+	assert.Empty(t, urls[0].LastMod)
+	assert.NotEmpty(t, urls[len(urls)-1].LastMod)
+}
+
+func TestXML_Cached(t *testing.T) {
+	service, db := setupTestService(t)
+	now := time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)
+	service.now = func() time.Time { return now }
+
+	first, err := service.XML()
+	require.NoError(t, err)
+	assert.Contains(t, string(first), `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">`)
+	assert.Contains(t, string(first), "<loc>https://elterngeld.example/faq</loc>")
+
+	entry := &models.ContentEntry{Kind: models.ContentKindFAQ, Slug: "neu", Title: "Neu", Body: "...", Status: models.ContentStatusPublished}
+	require.NoError(t, db.Create(entry).Error)
+
+	cached, err := service.XML()
+	require.NoError(t, err)
+	assert.NotContains(t, string(cached), "/faq/neu")
+
+	now = now.Add(cacheTTL)
+	rebuilt, err := service.XML()
+	require.NoError(t, err)
+	assert.Contains(t, string(rebuilt), "<loc>https://elterngeld.example/faq/neu</loc>")
+}
+
+func setupTestService(t *testing.T) (*Service, *gorm.DB) {
+	t.Helper()
+	db := testutils.NewSQLiteDB(t, &models.Package{}, &models.Post{}, &models.ContentEntry{}, &models.Job{})
+
+	cfg := &config.Config{App: config.AppConfig{BaseURL: "https://elterngeld.example/"}}
+	return NewService(db, zap.NewNop(), cfg), db
+}
@@ -0,0 +1,199 @@
+// Package snippets is the library of canned responses Berater use when
+// replying to contact forms and lead emails. Snippets are personal or shared
+// with the team, and their placeholders are filled in from the contact form,
+// lead or booking being answered, like all merge fields.
+package snippets
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"elterngeld-portal/internal/mergefields"
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/internal/scopes"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrNotFound is returned when the snippet does not exist or the user cannot see it
+	ErrNotFound = errors.New("snippet not found")
+	// ErrNotOwner is returned when someone other than the owner or an admin changes a snippet
+	ErrNotOwner = errors.New("only the owner can change the snippet")
+	// ErrUnknownPlaceholder is returned for snippet bodies using placeholders that cannot be filled in
+	ErrUnknownPlaceholder = errors.New("unknown snippet placeholder")
+	// ErrContextNotFound is returned when the contact form, lead or booking a snippet is rendered for does not exist or the user cannot see it
+	ErrContextNotFound = mergefields.ErrContextNotFound
+)
+
+// Placeholders lists the merge fields a snippet may contain, each written as
+// {{name}}. The former names like {{customer_name}} keep working.
+var Placeholders = mergefields.Names()
+
+// Input holds the content of a snippet
+type Input struct {
+	Title    string              `json:"title" binding:"required,max=100"`
+	Body     string              `json:"body" binding:"required"`
+	Category string              `json:"category" binding:"max=50"`
+	Scope    models.SnippetScope `json:"scope" binding:"required,oneof=personal team"`
+}
+
+// RenderContext names what a snippet is used to reply to. A booking or lead
+// fills in more placeholders than a contact form.
+type RenderContext = mergefields.Context
+
+// Rendered is a snippet with its placeholders filled in. Missing lists the
+// placeholders the reply context had no value for; they are left empty.
+type Rendered struct {
+	Title   string   `json:"title"`
+	Body    string   `json:"body"`
+	Missing []string `json:"missing"`
+}
+
+// Service manages snippets
+type Service struct {
+	db     *gorm.DB
+	logger *zap.Logger
+	fields *mergefields.Service
+}
+
+func NewService(db *gorm.DB, logger *zap.Logger, fieldService *mergefields.Service) *Service {
+	return &Service{
+		db:     db,
+		logger: logger,
+		fields: fieldService,
+	}
+}
+
+// List returns the viewer's personal snippets and the team snippets by
+// category and title, optionally limited to one category
+func (s *Service) List(viewer scopes.Viewer, category string) ([]models.Snippet, error) {
+	query := s.visible(viewer).Preload("Owner")
+	if category != "" {
+		query = query.Where("category = ?", category)
+	}
+
+	snippets := []models.Snippet{}
+	if err := query.Order("category ASC, title ASC").Find(&snippets).Error; err != nil {
+		return nil, err
+	}
+	return snippets, nil
+}
+
+// Create adds a snippet owned by the viewer
+func (s *Service) Create(viewer scopes.Viewer, input Input) (*models.Snippet, error) {
+	snippet := &models.Snippet{OwnerID: viewer.ID}
+	if err := apply(snippet, input); err != nil {
+		return nil, err
+	}
+	if err := s.db.Create(snippet).Error; err != nil {
+		return nil, fmt.Errorf("failed to create snippet: %w", err)
+	}
+	return s.get(snippet.ID, viewer)
+}
+
+// Update replaces the content of a snippet. Only its owner and admins can
+// change it.
+func (s *Service) Update(id uuid.UUID, viewer scopes.Viewer, input Input) (*models.Snippet, error) {
+	snippet, err := s.owned(id, viewer)
+	if err != nil {
+		return nil, err
+	}
+	if err := apply(snippet, input); err != nil {
+		return nil, err
+	}
+	if err := s.db.Save(snippet).Error; err != nil {
+		return nil, fmt.Errorf("failed to update snippet: %w", err)
+	}
+	return s.get(snippet.ID, viewer)
+}
+
+// Delete removes a snippet. Only its owner and admins can delete it.
+func (s *Service) Delete(id uuid.UUID, viewer scopes.Viewer) error {
+	snippet, err := s.owned(id, viewer)
+	if err != nil {
+		return err
+	}
+	return s.db.Delete(snippet).Error
+}
+
+// Render fills in the placeholders of a snippet for a reply to a contact
+// form, lead or booking. The Berater placeholders come from the viewer.
+func (s *Service) Render(id uuid.UUID, viewer scopes.Viewer, context RenderContext) (*Rendered, error) {
+	snippet, err := s.get(id, viewer)
+	if err != nil {
+		return nil, err
+	}
+
+	values, err := s.fields.Resolve(viewer, context)
+	if err != nil {
+		return nil, err
+	}
+
+	missing := map[string]bool{}
+	expand := func(text string) string {
+		merged, empty := mergefields.Merge(text, values)
+		for _, name := range empty {
+			missing[name] = true
+		}
+		return merged
+	}
+
+	rendered := &Rendered{
+		Title:   expand(snippet.Title),
+		Body:    expand(snippet.Body),
+		Missing: []string{},
+	}
+	for name := range missing {
+		rendered.Missing = append(rendered.Missing, name)
+	}
+	sort.Strings(rendered.Missing)
+	return rendered, nil
+}
+
+// visible selects the viewer's personal snippets and the team snippets
+func (s *Service) visible(viewer scopes.Viewer) *gorm.DB {
+	return s.db.Where("snippets.owner_id = ? OR snippets.scope = ?", viewer.ID, models.SnippetScopeTeam)
+}
+
+// get loads a snippet the viewer can see
+func (s *Service) get(id uuid.UUID, viewer scopes.Viewer) (*models.Snippet, error) {
+	var snippet models.Snippet
+	if err := s.visible(viewer).Preload("Owner").Where("snippets.id = ?", id).First(&snippet).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &snippet, nil
+}
+
+// owned loads a snippet the viewer can see and change
+func (s *Service) owned(id uuid.UUID, viewer scopes.Viewer) (*models.Snippet, error) {
+	snippet, err := s.get(id, viewer)
+	if err != nil {
+		return nil, err
+	}
+	if snippet.OwnerID != viewer.ID && viewer.Role != models.RoleAdmin {
+		return nil, ErrNotOwner
+	}
+	snippet.Owner = models.User{}
+	return snippet, nil
+}
+
+// apply validates the placeholders of the input and copies it to the snippet
+func apply(snippet *models.Snippet, input Input) error {
+	if unknown := mergefields.Unknown(input.Title, input.Body); len(unknown) > 0 {
+		return fmt.Errorf("%w: %q", ErrUnknownPlaceholder, unknown[0])
+	}
+
+	snippet.Title = strings.TrimSpace(input.Title)
+	snippet.Body = input.Body
+	snippet.Category = strings.TrimSpace(input.Category)
+	snippet.Scope = input.Scope
+	return nil
+}
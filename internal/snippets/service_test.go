@@ -0,0 +1,141 @@
+package snippets
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"elterngeld-portal/internal/mergefields"
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/internal/scopes"
+	"elterngeld-portal/tests/testutils"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+func TestScopes(t *testing.T) {
+	service, db := setupTestService(t)
+	owner := viewerOf(createTestUser(t, db, models.RoleBerater, "Berta Berater"))
+	colleague := viewerOf(createTestUser(t, db, models.RoleJuniorBerater, "Jana Junior"))
+	admin := viewerOf(createTestUser(t, db, models.RoleAdmin, "Ada Admin"))
+
+	personal, err := service.Create(owner, Input{Title: "Unterlagen", Body: "Bitte senden Sie uns ...", Category: "Unterlagen", Scope: models.SnippetScopePersonal})
+	require.NoError(t, err)
+	team, err := service.Create(owner, Input{Title: "Begrüßung", Body: "Hallo {{customer_first_name}}", Category: "Allgemein", Scope: models.SnippetScopeTeam})
+	require.NoError(t, err)
+
+	list, err := service.List(owner, "")
+	require.NoError(t, err)
+	assert.Len(t, list, 2)
+
+	list, err = service.List(colleague, "")
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	assert.Equal(t, team.ID, list[0].ID)
+
+	list, err = service.List(owner, "Unterlagen")
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	assert.Equal(t, personal.ID, list[0].ID)
+
+	_, err = service.Update(personal.ID, colleague, Input{Title: "x", Body: "x", Scope: models.SnippetScopeTeam})
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = service.Update(team.ID, colleague, Input{Title: "x", Body: "x", Scope: models.SnippetScopeTeam})
+	assert.ErrorIs(t, err, ErrNotOwner)
+
+	updated, err := service.Update(team.ID, admin, Input{Title: "Begrüßung", Body: "Guten Tag {{customer_name}}", Category: "Allgemein", Scope: models.SnippetScopeTeam})
+	require.NoError(t, err)
+	assert.Equal(t, "Guten Tag {{customer_name}}", updated.Body)
+	assert.Equal(t, owner.ID, updated.OwnerID)
+
+	_, err = service.Create(owner, Input{Title: "Kaputt", Body: "Hallo {{kunde}}", Scope: models.SnippetScopePersonal})
+	assert.ErrorIs(t, err, ErrUnknownPlaceholder)
+
+	require.ErrorIs(t, service.Delete(team.ID, colleague), ErrNotOwner)
+	require.NoError(t, service.Delete(team.ID, owner))
+	list, err = service.List(colleague, "")
+	require.NoError(t, err)
+	assert.Empty(t, list)
+}
+
+func TestRender(t *testing.T) {
+	service, db := setupTestService(t)
+	berater := createTestUser(t, db, models.RoleBerater, "Berta Berater")
+	junior := createTestUser(t, db, models.RoleJuniorBerater, "Jana Junior")
+	customer := createTestUser(t, db, models.RoleUser, "Anna Schmidt")
+
+	snippet, err := service.Create(viewerOf(berater), Input{
+		Title: "Termin {{booking_ref}}",
+		Body:  "Hallo {{customer_first_name}}, zu Antrag {{application_number}} ... Viele Grüße, {{berater_name}}",
+		Scope: models.SnippetScopeTeam,
+	})
+	require.NoError(t, err)
+
+	form := &models.ContactForm{Name: "Max Mustermann", Email: "max@example.com", Subject: "Frage", Message: "Hallo"}
+	require.NoError(t, db.Create(form).Error)
+
+	rendered, err := service.Render(snippet.ID, viewerOf(berater), RenderContext{ContactFormID: &form.ID})
+	require.NoError(t, err)
+	assert.Equal(t, "Termin ", rendered.Title)
+	assert.Equal(t, "Hallo Max, zu Antrag  ... Viele Grüße, Berta Berater", rendered.Body)
+	assert.Equal(t, []string{"application_number", "booking_ref"}, rendered.Missing)
+
+	beraterID := berater.ID
+	lead := &models.Lead{UserID: customer.ID, BeraterID: &beraterID, Title: "Elterngeld"}
+	require.NoError(t, db.Create(lead).Error)
+	booking := &models.Booking{
+		UserID:      customer.ID,
+		BeraterID:   &beraterID,
+		LeadID:      &lead.ID,
+		Title:       "Beratung",
+		ScheduledAt: time.Now().Add(48 * time.Hour),
+		Duration:    60,
+	}
+	require.NoError(t, db.Create(booking).Error)
+
+	rendered, err = service.Render(snippet.ID, viewerOf(berater), RenderContext{LeadID: &lead.ID})
+	require.NoError(t, err)
+	assert.Equal(t, "Termin "+booking.BookingReference, rendered.Title)
+	assert.Equal(t, fmt.Sprintf("Hallo Anna, zu Antrag %s ... Viele Grüße, Berta Berater", lead.ApplicationNumber), rendered.Body)
+	assert.Empty(t, rendered.Missing)
+
+	// Junior-Berater only reply on their own leads and bookings
+	_, err = service.Render(snippet.ID, viewerOf(junior), RenderContext{BookingID: &booking.ID})
+	assert.ErrorIs(t, err, ErrContextNotFound)
+
+	missing := uuid.New()
+	_, err = service.Render(snippet.ID, viewerOf(berater), RenderContext{ContactFormID: &missing})
+	assert.ErrorIs(t, err, ErrContextNotFound)
+}
+
+func setupTestService(t *testing.T) (*Service, *gorm.DB) {
+	t.Helper()
+	db := testutils.NewSQLiteDB(t,
+		&models.User{},
+		&models.Lead{},
+		&models.Booking{},
+		&models.ContactForm{},
+		&models.Snippet{},
+		&models.Package{},
+		&models.ElterngeldScenario{},
+	)
+
+	return NewService(db, zap.NewNop(), mergefields.NewService(db, zap.NewNop())), db
+}
+
+func createTestUser(t *testing.T, db *gorm.DB, role models.UserRole, name string) *models.User {
+	t.Helper()
+	user := testutils.CreateTestUser(t, db, role)
+	user.FirstName, user.LastName, _ = strings.Cut(name, " ")
+	require.NoError(t, db.Model(user).Updates(map[string]interface{}{"first_name": user.FirstName, "last_name": user.LastName}).Error)
+	return user
+}
+
+func viewerOf(user *models.User) scopes.Viewer {
+	return scopes.Viewer{ID: user.ID, Role: user.Role}
+}
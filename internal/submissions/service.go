@@ -0,0 +1,614 @@
+// Package submissions tracks Elterngeld applications after they were submitted
+// to the Elterngeldstelle: requests for additional documents, the Bescheid with
+// the granted amounts and the Widerspruchsfrist. Each step creates a reminder
+// for the lead's Berater, so no answer of the office goes unnoticed.
+package submissions
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"elterngeld-portal/internal/activitylog"
+	"elterngeld-portal/internal/calculator"
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/internal/scopes"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	// FollowUpWeeks is how long after the submission the Berater is reminded to
+	// ask the Elterngeldstelle for the Bescheid
+	FollowUpWeeks = 6
+	// DocumentReminderDays is how many days before the due date of a document
+	// request the Berater is reminded
+	DocumentReminderDays = 3
+	// ObjectionReminderDays is how many days before the end of the
+	// Widerspruchsfrist the Berater is reminded to check the Bescheid
+	ObjectionReminderDays = 7
+	// maxLebensmonat is the last Lebensmonat Elterngeld can be granted for
+	maxLebensmonat = 32
+)
+
+var (
+	// ErrLeadNotFound is returned when the lead does not exist or the viewer may not access it
+	ErrLeadNotFound = errors.New("lead not found")
+	// ErrNotSubmitted is returned when the application of the lead was not submitted yet
+	ErrNotSubmitted = errors.New("application not submitted")
+	// ErrRequestNotFound is returned when the document request does not exist on the lead
+	ErrRequestNotFound = errors.New("document request not found")
+	// ErrAlreadyDecided is returned for document requests after the Bescheid arrived
+	ErrAlreadyDecided = errors.New("application already decided")
+	// ErrInvalidInput is returned for dates in the future or invalid granted amounts
+	ErrInvalidInput = errors.New("invalid input")
+)
+
+// DeadlineType names what a deadline is about
+type DeadlineType string
+
+const (
+	DeadlineFollowUp  DeadlineType = "follow_up" // ask the Elterngeldstelle for the Bescheid
+	DeadlineDocuments DeadlineType = "documents" // send the requested documents
+	DeadlineObjection DeadlineType = "objection" // end of the Widerspruchsfrist
+)
+
+// Deadline is an upcoming or passed date of a submission
+type Deadline struct {
+	Type      DeadlineType `json:"type"`
+	Date      time.Time    `json:"date"`
+	RequestID *uuid.UUID   `json:"request_id,omitempty"`
+	Overdue   bool         `json:"overdue"`
+}
+
+// Tracking is the state of a submitted application with its deadlines, the
+// earliest first
+type Tracking struct {
+	Submission     *models.ApplicationSubmission `json:"submission"`
+	ExpectedAmount float64                       `json:"expected_amount"`
+	// Variance compares the Bescheid with the expected amount once it arrived
+	Variance  *Variance  `json:"variance,omitempty"`
+	Deadlines []Deadline `json:"deadlines"`
+}
+
+// Overview is a submission in the list Beraters track office responses with
+type Overview struct {
+	LeadID            uuid.UUID               `json:"lead_id"`
+	LeadTitle         string                  `json:"lead_title"`
+	ApplicationNumber string                  `json:"application_number"`
+	BeraterID         *uuid.UUID              `json:"berater_id"`
+	SubmissionID      uuid.UUID               `json:"submission_id"`
+	Status            models.SubmissionStatus `json:"status"`
+	SubmittedAt       time.Time               `json:"submitted_at"`
+	Office            string                  `json:"office"`
+	OfficeReference   string                  `json:"office_reference"`
+	OpenRequests      int                     `json:"open_requests"`
+	NextDeadline      *Deadline               `json:"next_deadline"`
+	VarianceFlaggedAt *time.Time              `json:"variance_flagged_at"`
+}
+
+// ListFilter narrows down the list of submissions
+type ListFilter struct {
+	Status  models.SubmissionStatus
+	Flagged bool // only Bescheide that fall significantly short of the calculation
+}
+
+// SubmitInput records the submission of an application
+type SubmitInput struct {
+	SubmittedAt     time.Time `json:"submitted_at" binding:"required"`
+	Office          string    `json:"office" binding:"max=200"`
+	OfficeReference string    `json:"office_reference" binding:"max=100"`
+}
+
+// DocumentRequestInput records a request for additional documents
+type DocumentRequestInput struct {
+	Description string     `json:"description" binding:"required,max=2000"`
+	ReceivedAt  *time.Time `json:"received_at"` // defaults to now
+	DueDate     *time.Time `json:"due_date"`
+}
+
+// BescheidInput records the Bescheid of the Elterngeldstelle
+type BescheidInput struct {
+	BescheidDate *time.Time             `json:"bescheid_date"`
+	ReceivedAt   time.Time              `json:"received_at" binding:"required"`
+	Amounts      []models.GrantedAmount `json:"amounts" binding:"required,min=1"`
+}
+
+// Service tracks submitted applications
+type Service struct {
+	db          *gorm.DB
+	logger      *zap.Logger
+	activityLog *activitylog.Service
+	now         func() time.Time
+}
+
+func NewService(db *gorm.DB, logger *zap.Logger, activityLog *activitylog.Service) *Service {
+	return &Service{
+		db:          db,
+		logger:      logger,
+		activityLog: activityLog,
+		now:         time.Now,
+	}
+}
+
+// Get returns the tracking of the lead's submitted application
+func (s *Service) Get(leadID uuid.UUID, viewer scopes.Viewer) (*Tracking, error) {
+	lead, err := s.lead(s.db, leadID, scopes.VisibleLeads(viewer))
+	if err != nil {
+		return nil, err
+	}
+	submission, err := s.load(s.db, leadID)
+	if err != nil {
+		return nil, err
+	}
+	return s.tracking(lead, submission)
+}
+
+// Submit records that the application of a lead was submitted. Recording it
+// again corrects the date and the Elterngeldstelle. The Berater is reminded
+// to follow up FollowUpWeeks after the submission.
+func (s *Service) Submit(leadID uuid.UUID, viewer scopes.Viewer, input SubmitInput) (*Tracking, error) {
+	if input.SubmittedAt.After(s.now()) {
+		return nil, fmt.Errorf("%w: the submission date is in the future", ErrInvalidInput)
+	}
+
+	var lead *models.Lead
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if lead, err = s.lead(tx, leadID, scopes.EditableLeads(viewer)); err != nil {
+			return err
+		}
+
+		var submission models.ApplicationSubmission
+		err = tx.Where("lead_id = ?", leadID).First(&submission).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("failed to load submission: %w", err)
+		}
+		isNew := errors.Is(err, gorm.ErrRecordNotFound)
+
+		submission.LeadID = leadID
+		submission.SubmittedAt = input.SubmittedAt
+		submission.Office = strings.TrimSpace(input.Office)
+		submission.OfficeReference = strings.TrimSpace(input.OfficeReference)
+		followUp := input.SubmittedAt.AddDate(0, 0, FollowUpWeeks*7)
+		if isNew {
+			submission.SubmittedByID = viewer.ID
+			submission.Status = models.SubmissionStatusSubmitted
+			reminder, err := s.remind(tx, lead, viewer, followUp, "Bei der Elterngeldstelle nachfragen",
+				fmt.Sprintf("Der Antrag '%s' wurde vor %d Wochen eingereicht und ist noch nicht beschieden.", lead.Title, FollowUpWeeks))
+			if err != nil {
+				return err
+			}
+			submission.FollowUpReminderID = &reminder.ID
+		} else if err := s.reschedule(tx, submission.FollowUpReminderID, followUp); err != nil {
+			return err
+		}
+
+		if err := tx.Save(&submission).Error; err != nil {
+			return fmt.Errorf("failed to save submission: %w", err)
+		}
+		if !isNew {
+			return nil
+		}
+		return s.activityLog.Record(tx, activitylog.ApplicationSubmitted{
+			ActorID:         viewer.ID,
+			LeadID:          leadID,
+			SubmissionID:    submission.ID,
+			Office:          submission.Office,
+			OfficeReference: submission.OfficeReference,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	submission, err := s.load(s.db, leadID)
+	if err != nil {
+		return nil, err
+	}
+	return s.tracking(lead, submission)
+}
+
+// RequestDocuments records a request of the Elterngeldstelle for additional
+// documents. With a due date the Berater is reminded DocumentReminderDays before.
+func (s *Service) RequestDocuments(leadID uuid.UUID, viewer scopes.Viewer, input DocumentRequestInput) (*models.ApplicationDocumentRequest, error) {
+	receivedAt := s.now()
+	if input.ReceivedAt != nil {
+		if input.ReceivedAt.After(receivedAt) {
+			return nil, fmt.Errorf("%w: the request was received in the future", ErrInvalidInput)
+		}
+		receivedAt = *input.ReceivedAt
+	}
+	if input.DueDate != nil && input.DueDate.Before(receivedAt) {
+		return nil, fmt.Errorf("%w: the due date is before the request was received", ErrInvalidInput)
+	}
+
+	request := &models.ApplicationDocumentRequest{
+		LeadID:      leadID,
+		CreatedByID: viewer.ID,
+		Description: strings.TrimSpace(input.Description),
+		ReceivedAt:  receivedAt,
+		DueDate:     input.DueDate,
+	}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		lead, err := s.lead(tx, leadID, scopes.EditableLeads(viewer))
+		if err != nil {
+			return err
+		}
+		submission, err := s.load(tx, leadID)
+		if err != nil {
+			return err
+		}
+		if submission.Status == models.SubmissionStatusDecided {
+			return ErrAlreadyDecided
+		}
+
+		request.SubmissionID = submission.ID
+		if input.DueDate != nil {
+			remindAt := input.DueDate.AddDate(0, 0, -DocumentReminderDays)
+			if now := s.now(); remindAt.Before(now) {
+				remindAt = now
+			}
+			reminder, err := s.remind(tx, lead, viewer, remindAt, "Unterlagen an die Elterngeldstelle senden",
+				fmt.Sprintf("Die Elterngeldstelle fordert für '%s' bis zum %s an: %s", lead.Title, input.DueDate.Format("02.01.2006"), request.Description))
+			if err != nil {
+				return err
+			}
+			request.ReminderID = &reminder.ID
+		}
+		if err := tx.Create(request).Error; err != nil {
+			return fmt.Errorf("failed to create document request: %w", err)
+		}
+		if err := tx.Model(submission).Update("status", models.SubmissionStatusDocumentsRequested).Error; err != nil {
+			return fmt.Errorf("failed to update submission status: %w", err)
+		}
+		return s.activityLog.Record(tx, activitylog.DocumentsRequested{
+			ActorID:     viewer.ID,
+			LeadID:      leadID,
+			RequestID:   request.ID,
+			Description: request.Description,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return request, nil
+}
+
+// FulfillDocumentRequest marks requested documents as sent and completes
+// their reminder. Once all requests are fulfilled the application is back to
+// waiting for the Bescheid.
+func (s *Service) FulfillDocumentRequest(leadID, requestID uuid.UUID, viewer scopes.Viewer) (*models.ApplicationDocumentRequest, error) {
+	var request models.ApplicationDocumentRequest
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if _, err := s.lead(tx, leadID, scopes.EditableLeads(viewer)); err != nil {
+			return err
+		}
+		if err := tx.Where("id = ? AND lead_id = ?", requestID, leadID).First(&request).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrRequestNotFound
+			}
+			return fmt.Errorf("failed to load document request: %w", err)
+		}
+		if request.IsFulfilled() {
+			return nil
+		}
+
+		now := s.now()
+		request.FulfilledAt = &now
+		if err := tx.Model(&request).Update("fulfilled_at", now).Error; err != nil {
+			return fmt.Errorf("failed to fulfill document request: %w", err)
+		}
+		if err := s.complete(tx, request.ReminderID); err != nil {
+			return err
+		}
+
+		var open int64
+		if err := tx.Model(&models.ApplicationDocumentRequest{}).
+			Where("submission_id = ? AND fulfilled_at IS NULL", request.SubmissionID).
+			Count(&open).Error; err != nil {
+			return fmt.Errorf("failed to count open document requests: %w", err)
+		}
+		if open > 0 {
+			return nil
+		}
+		err := tx.Model(&models.ApplicationSubmission{}).
+			Where("id = ? AND status = ?", request.SubmissionID, models.SubmissionStatusDocumentsRequested).
+			Update("status", models.SubmissionStatusSubmitted).Error
+		if err != nil {
+			return fmt.Errorf("failed to update submission status: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &request, nil
+}
+
+// RecordBescheid records the Bescheid with the granted amounts. The
+// Widerspruchsfrist ends a month after the Bescheid was received; the Berater
+// is reminded ObjectionReminderDays before. Recording it again corrects it.
+func (s *Service) RecordBescheid(leadID uuid.UUID, viewer scopes.Viewer, input BescheidInput) (*Tracking, error) {
+	if input.ReceivedAt.After(s.now()) {
+		return nil, fmt.Errorf("%w: the Bescheid was received in the future", ErrInvalidInput)
+	}
+	if input.BescheidDate != nil && input.BescheidDate.After(input.ReceivedAt) {
+		return nil, fmt.Errorf("%w: the Bescheid is dated after it was received", ErrInvalidInput)
+	}
+	total, err := grantedTotal(input.Amounts)
+	if err != nil {
+		return nil, err
+	}
+	deadline := input.ReceivedAt.AddDate(0, 1, 0)
+
+	var lead *models.Lead
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if lead, err = s.lead(tx, leadID, scopes.EditableLeads(viewer)); err != nil {
+			return err
+		}
+		submission, err := s.load(tx, leadID)
+		if err != nil {
+			return err
+		}
+		isNew := submission.Status != models.SubmissionStatusDecided
+
+		receivedAt := input.ReceivedAt
+		submission.BescheidDate = input.BescheidDate
+		submission.BescheidReceivedAt = &receivedAt
+		submission.GrantedTotal = total
+		submission.ObjectionDeadline = &deadline
+		submission.Status = models.SubmissionStatusDecided
+		if err := submission.SetGrantedAmounts(input.Amounts); err != nil {
+			return fmt.Errorf("failed to encode granted amounts: %w", err)
+		}
+
+		remindAt := deadline.AddDate(0, 0, -ObjectionReminderDays)
+		if submission.ObjectionReminderID == nil {
+			reminder, err := s.remind(tx, lead, viewer, remindAt, "Widerspruchsfrist prüfen",
+				fmt.Sprintf("Die Widerspruchsfrist für den Bescheid zu '%s' endet am %s.", lead.Title, deadline.Format("02.01.2006")))
+			if err != nil {
+				return err
+			}
+			submission.ObjectionReminderID = &reminder.ID
+		} else if err := s.reschedule(tx, submission.ObjectionReminderID, remindAt); err != nil {
+			return err
+		}
+
+		// The Bescheid answers the application, nothing is left to follow up
+		if err := s.complete(tx, submission.FollowUpReminderID); err != nil {
+			return err
+		}
+		for _, request := range submission.DocumentRequests {
+			if !request.IsFulfilled() {
+				if err := s.complete(tx, request.ReminderID); err != nil {
+					return err
+				}
+			}
+		}
+
+		if err := tx.Omit("DocumentRequests").Save(submission).Error; err != nil {
+			return fmt.Errorf("failed to save Bescheid: %w", err)
+		}
+		if result := variance(submission, lead.ExpectedAmount, s.now()); result != nil {
+			if err := s.flagVariance(tx, lead, submission, result); err != nil {
+				return err
+			}
+		}
+		if !isNew {
+			return nil
+		}
+		return s.activityLog.Record(tx, activitylog.BescheidReceived{
+			ActorID:           viewer.ID,
+			LeadID:            leadID,
+			SubmissionID:      submission.ID,
+			GrantedTotal:      total,
+			ObjectionDeadline: deadline.Format("02.01.2006"),
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	submission, err := s.load(s.db, leadID)
+	if err != nil {
+		return nil, err
+	}
+	return s.tracking(lead, submission)
+}
+
+// List returns the submissions on leads the viewer may see, the earliest next
+// deadline first
+func (s *Service) List(viewer scopes.Viewer, filter ListFilter) ([]Overview, error) {
+	if filter.Status != "" && !filter.Status.IsValid() {
+		return nil, fmt.Errorf("%w: unknown status %q", ErrInvalidInput, filter.Status)
+	}
+
+	var submissions []models.ApplicationSubmission
+	query := s.db.Preload("DocumentRequests").Preload("Lead").
+		Joins("JOIN leads ON leads.id = application_submissions.lead_id AND leads.deleted_at IS NULL").
+		Scopes(scopes.VisibleLeads(viewer))
+	if filter.Status != "" {
+		query = query.Where("application_submissions.status = ?", filter.Status)
+	}
+	if filter.Flagged {
+		query = query.Where("application_submissions.variance_flagged_at IS NOT NULL")
+	}
+	if err := query.Find(&submissions).Error; err != nil {
+		return nil, fmt.Errorf("failed to load submissions: %w", err)
+	}
+
+	now := s.now()
+	result := make([]Overview, 0, len(submissions))
+	for i := range submissions {
+		submission := &submissions[i]
+		overview := Overview{
+			LeadID:            submission.LeadID,
+			SubmissionID:      submission.ID,
+			Status:            submission.Status,
+			SubmittedAt:       submission.SubmittedAt,
+			Office:            submission.Office,
+			OfficeReference:   submission.OfficeReference,
+			VarianceFlaggedAt: submission.VarianceFlaggedAt,
+		}
+		if submission.Lead != nil {
+			overview.LeadTitle = submission.Lead.Title
+			overview.ApplicationNumber = submission.Lead.ApplicationNumber
+			overview.BeraterID = submission.Lead.BeraterID
+		}
+		for _, request := range submission.DocumentRequests {
+			if !request.IsFulfilled() {
+				overview.OpenRequests++
+			}
+		}
+		if deadlines := deadlines(submission, now); len(deadlines) > 0 {
+			overview.NextDeadline = &deadlines[0]
+		}
+		result = append(result, overview)
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		a, b := result[i].NextDeadline, result[j].NextDeadline
+		if a == nil || b == nil {
+			return a != nil
+		}
+		return a.Date.Before(b.Date)
+	})
+	return result, nil
+}
+
+// lead loads a lead the scope allows
+func (s *Service) lead(tx *gorm.DB, leadID uuid.UUID, scope func(*gorm.DB) *gorm.DB) (*models.Lead, error) {
+	var lead models.Lead
+	if err := tx.Scopes(scope).First(&lead, "leads.id = ?", leadID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrLeadNotFound
+		}
+		return nil, fmt.Errorf("failed to load lead: %w", err)
+	}
+	return &lead, nil
+}
+
+func (s *Service) load(tx *gorm.DB, leadID uuid.UUID) (*models.ApplicationSubmission, error) {
+	var submission models.ApplicationSubmission
+	err := tx.Preload("DocumentRequests", func(db *gorm.DB) *gorm.DB {
+		return db.Order("received_at ASC")
+	}).Where("lead_id = ?", leadID).First(&submission).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotSubmitted
+		}
+		return nil, fmt.Errorf("failed to load submission: %w", err)
+	}
+	return &submission, nil
+}
+
+// remind creates a reminder for the lead's Berater, or for the viewer when
+// nobody is assigned
+func (s *Service) remind(tx *gorm.DB, lead *models.Lead, viewer scopes.Viewer, remindAt time.Time, title, description string) (*models.Reminder, error) {
+	userID := viewer.ID
+	if lead.BeraterID != nil {
+		userID = *lead.BeraterID
+	}
+	reminder := &models.Reminder{
+		LeadID:      lead.ID,
+		UserID:      userID,
+		CreatedBy:   viewer.ID,
+		Title:       title,
+		Description: description,
+		RemindAt:    remindAt,
+	}
+	if err := tx.Create(reminder).Error; err != nil {
+		return nil, fmt.Errorf("failed to create reminder: %w", err)
+	}
+	return reminder, nil
+}
+
+// reschedule moves an open reminder
+func (s *Service) reschedule(tx *gorm.DB, reminderID *uuid.UUID, remindAt time.Time) error {
+	if reminderID == nil {
+		return nil
+	}
+	err := tx.Model(&models.Reminder{}).Where("id = ? AND is_completed = ?", *reminderID, false).
+		Update("remind_at", remindAt.UTC()).Error
+	if err != nil {
+		return fmt.Errorf("failed to reschedule reminder: %w", err)
+	}
+	return nil
+}
+
+// complete marks a reminder as done
+func (s *Service) complete(tx *gorm.DB, reminderID *uuid.UUID) error {
+	if reminderID == nil {
+		return nil
+	}
+	if err := tx.Model(&models.Reminder{}).Where("id = ?", *reminderID).Update("is_completed", true).Error; err != nil {
+		return fmt.Errorf("failed to complete reminder: %w", err)
+	}
+	return nil
+}
+
+func (s *Service) tracking(lead *models.Lead, submission *models.ApplicationSubmission) (*Tracking, error) {
+	now := s.now()
+	tracking := &Tracking{
+		Submission:     submission,
+		ExpectedAmount: lead.ExpectedAmount,
+		Variance:       variance(submission, lead.ExpectedAmount, now),
+		Deadlines:      deadlines(submission, now),
+	}
+	if tracking.Variance != nil && tracking.Variance.SuggestObjection {
+		addon, err := s.appealAddon(s.db, lead.ID)
+		if err != nil {
+			return nil, err
+		}
+		tracking.Variance.AppealAddon = addon
+	}
+	return tracking, nil
+}
+
+// deadlines lists the deadlines of a submission, the earliest first. Following
+// up ends with the Bescheid, fulfilled document requests have no deadline.
+func deadlines(submission *models.ApplicationSubmission, now time.Time) []Deadline {
+	result := []Deadline{}
+	add := func(deadlineType DeadlineType, date time.Time, requestID *uuid.UUID) {
+		result = append(result, Deadline{Type: deadlineType, Date: date, RequestID: requestID, Overdue: date.Before(now)})
+	}
+
+	if submission.Status != models.SubmissionStatusDecided {
+		add(DeadlineFollowUp, submission.SubmittedAt.AddDate(0, 0, FollowUpWeeks*7), nil)
+		for _, request := range submission.DocumentRequests {
+			if request.DueDate != nil && !request.IsFulfilled() {
+				requestID := request.ID
+				add(DeadlineDocuments, *request.DueDate, &requestID)
+			}
+		}
+	}
+	if submission.ObjectionDeadline != nil {
+		add(DeadlineObjection, *submission.ObjectionDeadline, nil)
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Date.Before(result[j].Date)
+	})
+	return result
+}
+
+// grantedTotal sums up the granted amounts over their Lebensmonate
+func grantedTotal(amounts []models.GrantedAmount) (float64, error) {
+	total := 0.0
+	for _, amount := range amounts {
+		if amount.FromLebensmonat < 1 || amount.ToLebensmonat < amount.FromLebensmonat || amount.ToLebensmonat > maxLebensmonat {
+			return 0, fmt.Errorf("%w: invalid Lebensmonate %d to %d", ErrInvalidInput, amount.FromLebensmonat, amount.ToLebensmonat)
+		}
+		if amount.MonthlyAmount < 0 {
+			return 0, fmt.Errorf("%w: negative monthly amount", ErrInvalidInput)
+		}
+		total += amount.MonthlyAmount * float64(amount.ToLebensmonat-amount.FromLebensmonat+1)
+	}
+	return calculator.RoundCents(total), nil
+}
@@ -0,0 +1,238 @@
+package submissions
+
+import (
+	"testing"
+	"time"
+
+	"elterngeld-portal/internal/activitylog"
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/internal/scopes"
+	"elterngeld-portal/tests/testutils"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+var now = time.Date(2026, 5, 4, 10, 0, 0, 0, time.UTC)
+
+func TestSubmissionWorkflow(t *testing.T) {
+	db, service := setupTestService(t)
+	customer := testutils.CreateTestUser(t, db, models.RoleUser)
+	berater := testutils.CreateTestUser(t, db, models.RoleBerater)
+	lead := createTestLead(t, db, customer.ID, &berater.ID)
+	require.NoError(t, db.Model(lead).Update("expected_amount", 15600).Error)
+	staff := scopes.Viewer{ID: berater.ID, Role: berater.Role}
+	owner := scopes.Viewer{ID: customer.ID, Role: customer.Role}
+
+	_, err := service.Get(lead.ID, owner)
+	assert.ErrorIs(t, err, ErrNotSubmitted)
+	_, err = service.Submit(lead.ID, staff, SubmitInput{SubmittedAt: now.AddDate(0, 0, 1)})
+	assert.ErrorIs(t, err, ErrInvalidInput)
+
+	submittedAt := now.AddDate(0, 0, -10)
+	tracking, err := service.Submit(lead.ID, staff, SubmitInput{SubmittedAt: submittedAt, Office: " Elterngeldstelle Köln ", OfficeReference: "EG-2026-123"})
+	require.NoError(t, err)
+	assert.Equal(t, models.SubmissionStatusSubmitted, tracking.Submission.Status)
+	assert.Equal(t, "Elterngeldstelle Köln", tracking.Submission.Office)
+	require.Len(t, tracking.Deadlines, 1)
+	assert.Equal(t, DeadlineFollowUp, tracking.Deadlines[0].Type)
+	followUp := findReminder(t, db, *tracking.Submission.FollowUpReminderID)
+	assert.Equal(t, berater.ID, followUp.UserID)
+	assert.True(t, submittedAt.AddDate(0, 0, 42).Equal(followUp.RemindAt))
+
+	// Documents requested with a due date remind the Berater before it
+	dueDate := now.AddDate(0, 0, 14)
+	request, err := service.RequestDocuments(lead.ID, staff, DocumentRequestInput{Description: "Gehaltsnachweise", DueDate: &dueDate})
+	require.NoError(t, err)
+	require.NotNil(t, request.ReminderID)
+	assert.True(t, dueDate.AddDate(0, 0, -3).Equal(findReminder(t, db, *request.ReminderID).RemindAt))
+
+	tracking, err = service.Get(lead.ID, owner)
+	require.NoError(t, err)
+	assert.Equal(t, models.SubmissionStatusDocumentsRequested, tracking.Submission.Status)
+	require.Len(t, tracking.Deadlines, 2)
+	assert.Equal(t, DeadlineDocuments, tracking.Deadlines[0].Type)
+
+	_, err = service.FulfillDocumentRequest(lead.ID, uuid.New(), staff)
+	assert.ErrorIs(t, err, ErrRequestNotFound)
+	fulfilled, err := service.FulfillDocumentRequest(lead.ID, request.ID, staff)
+	require.NoError(t, err)
+	assert.True(t, fulfilled.IsFulfilled())
+	assert.True(t, findReminder(t, db, *request.ReminderID).IsCompleted)
+
+	tracking, err = service.Get(lead.ID, staff)
+	require.NoError(t, err)
+	assert.Equal(t, models.SubmissionStatusSubmitted, tracking.Submission.Status)
+
+	// The Bescheid starts the Widerspruchsfrist and ends following up
+	receivedAt := now.AddDate(0, 0, -1)
+	tracking, err = service.RecordBescheid(lead.ID, staff, BescheidInput{
+		ReceivedAt: receivedAt,
+		Amounts: []models.GrantedAmount{
+			{Parent: "Mutter", FromLebensmonat: 1, ToLebensmonat: 12, MonthlyAmount: 1250.5},
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, models.SubmissionStatusDecided, tracking.Submission.Status)
+	assert.Equal(t, 15006.0, tracking.Submission.GrantedTotal)
+	require.NotNil(t, tracking.Variance)
+	assert.Equal(t, -594.0, tracking.Variance.Difference)
+	assert.False(t, tracking.Variance.Significant)
+	assert.Nil(t, tracking.Submission.VarianceFlaggedAt)
+	require.Len(t, tracking.Deadlines, 1)
+	assert.Equal(t, DeadlineObjection, tracking.Deadlines[0].Type)
+	assert.True(t, receivedAt.AddDate(0, 1, 0).Equal(tracking.Deadlines[0].Date))
+	assert.True(t, findReminder(t, db, *tracking.Submission.FollowUpReminderID).IsCompleted)
+	assert.False(t, findReminder(t, db, *tracking.Submission.ObjectionReminderID).IsCompleted)
+
+	_, err = service.RequestDocuments(lead.ID, staff, DocumentRequestInput{Description: "Zu spät"})
+	assert.ErrorIs(t, err, ErrAlreadyDecided)
+
+	var activities int64
+	require.NoError(t, db.Model(&models.Activity{}).Where("lead_id = ?", lead.ID).Count(&activities).Error)
+	assert.Equal(t, int64(3), activities)
+}
+
+func TestRecordBescheid_Variance(t *testing.T) {
+	db, service := setupTestService(t)
+	customer := testutils.CreateTestUser(t, db, models.RoleUser)
+	berater := testutils.CreateTestUser(t, db, models.RoleBerater)
+	lead := createTestLead(t, db, customer.ID, &berater.ID)
+	require.NoError(t, db.Model(lead).Update("expected_amount", 15600).Error)
+	staff := scopes.Viewer{ID: berater.ID, Role: berater.Role}
+	addon := &models.Addon{ID: uuid.New(), Name: "Einspruchsverfahren", Price: 89, Currency: "EUR", IsActive: true, Category: AppealAddonCategory}
+	require.NoError(t, db.Create(addon).Error)
+
+	_, err := service.Submit(lead.ID, staff, SubmitInput{SubmittedAt: now.AddDate(0, 0, -30)})
+	require.NoError(t, err)
+	bescheid := BescheidInput{
+		ReceivedAt: now.AddDate(0, 0, -2),
+		Amounts:    []models.GrantedAmount{{Parent: "Mutter", FromLebensmonat: 1, ToLebensmonat: 12, MonthlyAmount: 1000}},
+	}
+	tracking, err := service.RecordBescheid(lead.ID, staff, bescheid)
+	require.NoError(t, err)
+	require.NotNil(t, tracking.Variance)
+	assert.Equal(t, -3600.0, tracking.Variance.Difference)
+	assert.Equal(t, -23.1, tracking.Variance.Percent)
+	assert.True(t, tracking.Variance.Significant)
+	assert.True(t, tracking.Variance.SuggestObjection)
+	require.NotNil(t, tracking.Variance.AppealAddon)
+	assert.Equal(t, addon.ID, tracking.Variance.AppealAddon.ID)
+	assert.NotNil(t, tracking.Submission.VarianceFlaggedAt)
+
+	// The Berater is told once, even when the Bescheid is corrected
+	_, err = service.RecordBescheid(lead.ID, staff, bescheid)
+	require.NoError(t, err)
+	var notifications []models.Notification
+	require.NoError(t, db.Where("user_id = ?", berater.ID).Find(&notifications).Error)
+	require.Len(t, notifications, 1)
+	assert.Contains(t, notifications[0].Message, "3600.00 €")
+
+	list, err := service.List(staff, ListFilter{Flagged: true})
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	assert.Equal(t, lead.ID, list[0].LeadID)
+
+	// After the Widerspruchsfrist the objection is no longer suggested
+	service.now = func() time.Time { return now.AddDate(0, 2, 0) }
+	tracking, err = service.Get(lead.ID, staff)
+	require.NoError(t, err)
+	assert.True(t, tracking.Variance.Significant)
+	assert.False(t, tracking.Variance.SuggestObjection)
+	assert.Nil(t, tracking.Variance.AppealAddon)
+
+	// A corrected Bescheid without shortfall clears the flag
+	service.now = func() time.Time { return now }
+	bescheid.Amounts[0].MonthlyAmount = 1300
+	tracking, err = service.RecordBescheid(lead.ID, staff, bescheid)
+	require.NoError(t, err)
+	assert.False(t, tracking.Variance.Significant)
+	assert.Nil(t, tracking.Submission.VarianceFlaggedAt)
+}
+
+func TestRecordBescheid_InvalidAmounts(t *testing.T) {
+	db, service := setupTestService(t)
+	berater := testutils.CreateTestUser(t, db, models.RoleBerater)
+	lead := createTestLead(t, db, testutils.CreateTestUser(t, db, models.RoleUser).ID, nil)
+	staff := scopes.Viewer{ID: berater.ID, Role: berater.Role}
+
+	_, err := service.RecordBescheid(lead.ID, staff, BescheidInput{ReceivedAt: now, Amounts: []models.GrantedAmount{{FromLebensmonat: 1, ToLebensmonat: 2, MonthlyAmount: 300}}})
+	assert.ErrorIs(t, err, ErrNotSubmitted)
+
+	_, err = service.Submit(lead.ID, staff, SubmitInput{SubmittedAt: now})
+	require.NoError(t, err)
+	for _, amount := range []models.GrantedAmount{
+		{FromLebensmonat: 0, ToLebensmonat: 2, MonthlyAmount: 300},
+		{FromLebensmonat: 5, ToLebensmonat: 4, MonthlyAmount: 300},
+		{FromLebensmonat: 1, ToLebensmonat: 33, MonthlyAmount: 300},
+		{FromLebensmonat: 1, ToLebensmonat: 2, MonthlyAmount: -1},
+	} {
+		_, err = service.RecordBescheid(lead.ID, staff, BescheidInput{ReceivedAt: now, Amounts: []models.GrantedAmount{amount}})
+		assert.ErrorIs(t, err, ErrInvalidInput)
+	}
+}
+
+func TestList(t *testing.T) {
+	db, service := setupTestService(t)
+	customer := testutils.CreateTestUser(t, db, models.RoleUser)
+	berater := testutils.CreateTestUser(t, db, models.RoleBerater)
+	staff := scopes.Viewer{ID: berater.ID, Role: berater.Role}
+	older := createTestLead(t, db, customer.ID, nil)
+	newer := createTestLead(t, db, customer.ID, nil)
+	createTestLead(t, db, customer.ID, nil)
+
+	_, err := service.Submit(newer.ID, staff, SubmitInput{SubmittedAt: now.AddDate(0, 0, -5)})
+	require.NoError(t, err)
+	_, err = service.Submit(older.ID, staff, SubmitInput{SubmittedAt: now.AddDate(0, 0, -50)})
+	require.NoError(t, err)
+
+	list, err := service.List(staff, ListFilter{})
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+	assert.Equal(t, older.ID, list[0].LeadID)
+	assert.True(t, list[0].NextDeadline.Overdue)
+	assert.False(t, list[1].NextDeadline.Overdue)
+
+	list, err = service.List(staff, ListFilter{Status: models.SubmissionStatusDecided})
+	require.NoError(t, err)
+	assert.Empty(t, list)
+	_, err = service.List(staff, ListFilter{Status: "unbekannt"})
+	assert.ErrorIs(t, err, ErrInvalidInput)
+
+	stranger := testutils.CreateTestUser(t, db, models.RoleUser)
+	list, err = service.List(scopes.Viewer{ID: stranger.ID, Role: stranger.Role}, ListFilter{})
+	require.NoError(t, err)
+	assert.Empty(t, list)
+}
+
+func findReminder(t *testing.T, db *gorm.DB, id uuid.UUID) models.Reminder {
+	t.Helper()
+	var reminder models.Reminder
+	require.NoError(t, db.First(&reminder, "id = ?", id).Error)
+	return reminder
+}
+
+func createTestLead(t *testing.T, db *gorm.DB, userID uuid.UUID, beraterID *uuid.UUID) *models.Lead {
+	t.Helper()
+	lead := &models.Lead{
+		UserID:    userID,
+		BeraterID: beraterID,
+		Title:     "Elterngeldantrag",
+		Status:    models.LeadStatusInProgress,
+	}
+	require.NoError(t, db.Create(lead).Error)
+	return lead
+}
+
+func setupTestService(t *testing.T) (*gorm.DB, *Service) {
+	t.Helper()
+	db := testutils.NewSQLiteDB(t, &models.User{}, &models.Lead{}, &models.FamilyMember{}, &models.Reminder{}, &models.Activity{},
+		&models.ApplicationSubmission{}, &models.ApplicationDocumentRequest{}, &models.Notification{}, &models.Booking{})
+
+	service := NewService(db, zap.NewNop(), activitylog.NewService(db, zap.NewNop()))
+	service.now = func() time.Time { return now }
+	return db, service
+}
@@ -0,0 +1,147 @@
+package submissions
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"elterngeld-portal/internal/calculator"
+	"elterngeld-portal/internal/models"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	// VarianceThresholdPercent and VarianceThresholdAmount decide when a
+	// Bescheid falls significantly short of the calculation: by more than
+	// both the share of the expected amount and the amount in euros
+	VarianceThresholdPercent = 5.0
+	VarianceThresholdAmount  = 100.0
+	// AppealAddonCategory is the addon category of the support with a Widerspruch
+	AppealAddonCategory = "legal"
+)
+
+// Variance compares the granted total of a Bescheid with the amount the
+// chosen scenario calculated
+type Variance struct {
+	Expected   float64 `json:"expected"`
+	Granted    float64 `json:"granted"`
+	Difference float64 `json:"difference"` // granted minus expected
+	Percent    float64 `json:"percent"`    // difference relative to the expected amount
+	// Significant is set when the Bescheid falls short by more than the thresholds
+	Significant bool `json:"significant"`
+	// SuggestObjection is set for significant shortfalls while the
+	// Widerspruchsfrist runs
+	SuggestObjection bool `json:"suggest_objection"`
+	// AppealAddon is the addon offered for the Widerspruch, unless the lead
+	// has already booked it
+	AppealAddon *models.AddonResponse `json:"appeal_addon,omitempty"`
+}
+
+// variance compares the Bescheid of a submission with the expected amount.
+// Without an expectation or a Bescheid there is nothing to compare.
+func variance(submission *models.ApplicationSubmission, expected float64, now time.Time) *Variance {
+	if submission.Status != models.SubmissionStatusDecided || expected <= 0 {
+		return nil
+	}
+
+	result := &Variance{
+		Expected:   expected,
+		Granted:    submission.GrantedTotal,
+		Difference: calculator.RoundCents(submission.GrantedTotal - expected),
+	}
+	result.Percent = math.Round(result.Difference/expected*1000) / 10
+	result.Significant = -result.Difference > VarianceThresholdAmount && -result.Percent > VarianceThresholdPercent
+	result.SuggestObjection = result.Significant &&
+		submission.ObjectionDeadline != nil && !submission.ObjectionDeadline.Before(now)
+	return result
+}
+
+// appealAddon returns the active addon for the Widerspruch, or nil when there
+// is none or the lead has already booked it
+func (s *Service) appealAddon(tx *gorm.DB, leadID uuid.UUID) (*models.AddonResponse, error) {
+	var addon models.Addon
+	err := tx.Where("category = ? AND is_active = ?", AppealAddonCategory, true).
+		Order("sort_order ASC").First(&addon).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to load appeal addon: %w", err)
+	}
+
+	var booked int64
+	err = tx.Table("booking_addons").
+		Joins("JOIN bookings ON bookings.id = booking_addons.booking_id").
+		Where("bookings.lead_id = ? AND booking_addons.addon_id = ? AND bookings.status <> ?", leadID, addon.ID, models.BookingStatusCancelled).
+		Count(&booked).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to check booked appeal addon: %w", err)
+	}
+	if booked > 0 {
+		return nil, nil
+	}
+
+	response := addon.ToResponse()
+	return &response, nil
+}
+
+// flagVariance marks a submission whose Bescheid falls significantly short and
+// tells the lead's Berater, once per submission, so they can offer the
+// customer support with the Widerspruch
+func (s *Service) flagVariance(tx *gorm.DB, lead *models.Lead, submission *models.ApplicationSubmission, result *Variance) error {
+	if !result.Significant {
+		if submission.VarianceFlaggedAt == nil {
+			return nil
+		}
+		submission.VarianceFlaggedAt = nil
+		return tx.Model(submission).Update("variance_flagged_at", nil).Error
+	}
+	if submission.VarianceFlaggedAt != nil {
+		return nil
+	}
+
+	now := s.now()
+	submission.VarianceFlaggedAt = &now
+	if err := tx.Model(submission).Update("variance_flagged_at", now).Error; err != nil {
+		return fmt.Errorf("failed to flag variance: %w", err)
+	}
+	if lead.BeraterID == nil {
+		return nil
+	}
+
+	var berater models.User
+	if err := tx.First(&berater, "id = ?", *lead.BeraterID).Error; err != nil {
+		return fmt.Errorf("failed to load Berater: %w", err)
+	}
+	message := fmt.Sprintf("Der Bescheid zu '%s' liegt %.2f € (%.1f %%) unter der Berechnung.", lead.Title, -result.Difference, -result.Percent)
+	if result.SuggestObjection {
+		message += fmt.Sprintf(" Ein Widerspruch ist bis zum %s möglich.", submission.ObjectionDeadline.Format("02.01.2006"))
+	}
+	data, _ := json.Marshal(map[string]interface{}{
+		"lead_id":       lead.ID,
+		"submission_id": submission.ID,
+		"difference":    result.Difference,
+	})
+	notification := models.Notification{
+		UserID:    berater.ID,
+		Type:      models.NotificationTypeInApp,
+		Title:     "Bescheid weicht von der Berechnung ab",
+		Message:   message,
+		Data:      string(data),
+		Recipient: berater.NotificationAddress(),
+	}
+	if err := tx.Create(&notification).Error; err != nil {
+		return fmt.Errorf("failed to create variance notification: %w", err)
+	}
+
+	s.logger.Info("Bescheid falls short of the calculation",
+		zap.String("lead_id", lead.ID.String()),
+		zap.Float64("difference", result.Difference),
+		zap.Float64("percent", result.Percent))
+	return nil
+}
@@ -0,0 +1,270 @@
+package summaries
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/pkg/pdf"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrBookingNotFound is returned when the booking does not exist
+	ErrBookingNotFound = errors.New("booking not found")
+	// ErrSummaryNotFound is returned when the booking has no summary the user may see
+	ErrSummaryNotFound = errors.New("consultation summary not found")
+	// ErrAccessDenied is returned when the user neither conducts the booking nor is an admin
+	ErrAccessDenied = errors.New("access denied")
+	// ErrNotHeld is returned when the consultation was cancelled, missed or has not started yet
+	ErrNotHeld = errors.New("consultation has not taken place")
+	// ErrEmptySummary is returned when a summary has no entries
+	ErrEmptySummary = errors.New("consultation summary is empty")
+)
+
+// Input is the content of a summary written by the Berater
+type Input struct {
+	Topics          []string
+	Recommendations []string
+	NextSteps       []string
+	Shared          bool // visible to the customer in their dashboard and as PDF
+}
+
+// Service manages the consultation summaries Beraters write after a booking
+type Service struct {
+	db     *gorm.DB
+	logger *zap.Logger
+	now    func() time.Time
+}
+
+func NewService(db *gorm.DB, logger *zap.Logger) *Service {
+	return &Service{
+		db:     db,
+		logger: logger,
+		now:    time.Now,
+	}
+}
+
+// Save creates or replaces the summary of a booking. Only the Berater of the
+// booking and admins may write it, once the consultation has taken place.
+// The customer is notified when the summary is shared for the first time.
+func (s *Service) Save(bookingID uuid.UUID, author *models.User, input Input) (*models.ConsultationSummary, error) {
+	booking, err := s.loadBooking(bookingID)
+	if err != nil {
+		return nil, err
+	}
+	if !canWrite(booking, author) {
+		return nil, ErrAccessDenied
+	}
+	if booking.Status != models.BookingStatusConfirmed && booking.Status != models.BookingStatusCompleted {
+		return nil, ErrNotHeld
+	}
+	if booking.StartTime.After(s.now()) {
+		return nil, ErrNotHeld
+	}
+
+	var items []models.ConsultationSummaryItem
+	for _, section := range []struct {
+		section models.SummarySection
+		entries []string
+	}{
+		{models.SummarySectionTopic, input.Topics},
+		{models.SummarySectionRecommendation, input.Recommendations},
+		{models.SummarySectionNextStep, input.NextSteps},
+	} {
+		position := 0
+		for _, entry := range section.entries {
+			if entry = strings.TrimSpace(entry); entry == "" {
+				continue
+			}
+			items = append(items, models.ConsultationSummaryItem{Section: section.section, Position: position, Text: entry})
+			position++
+		}
+	}
+	if len(items) == 0 {
+		return nil, ErrEmptySummary
+	}
+
+	var summary models.ConsultationSummary
+	newlyShared := false
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("booking_id = ?", booking.ID).First(&summary).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		summary.BookingID = booking.ID
+		summary.LeadID = booking.LeadID
+		summary.UserID = booking.UserID
+		summary.AuthorID = author.ID
+		switch {
+		case input.Shared && summary.SharedAt == nil:
+			now := s.now()
+			summary.SharedAt = &now
+			newlyShared = true
+		case !input.Shared:
+			summary.SharedAt = nil
+		}
+		if err := tx.Save(&summary).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("summary_id = ?", summary.ID).Delete(&models.ConsultationSummaryItem{}).Error; err != nil {
+			return err
+		}
+		for i := range items {
+			items[i].SummaryID = summary.ID
+		}
+		return tx.Create(&items).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to save consultation summary: %w", err)
+	}
+
+	if newlyShared {
+		s.notifyCustomer(booking, &summary)
+	}
+
+	s.logger.Info("Consultation summary saved",
+		zap.String("booking_id", booking.ID.String()),
+		zap.String("author_id", author.ID.String()),
+		zap.Bool("shared", summary.IsShared()))
+
+	return s.load(booking.ID)
+}
+
+// Get returns the summary of a booking. Customers only see shared summaries
+// of their own bookings.
+func (s *Service) Get(bookingID uuid.UUID, viewer *models.User) (*models.ConsultationSummary, error) {
+	booking, err := s.loadBooking(bookingID)
+	if err != nil {
+		return nil, err
+	}
+	isCustomer := booking.UserID == viewer.ID
+	if !isCustomer && !canWrite(booking, viewer) {
+		return nil, ErrAccessDenied
+	}
+
+	summary, err := s.load(bookingID)
+	if err != nil {
+		return nil, err
+	}
+	if !canWrite(booking, viewer) && !summary.IsShared() {
+		return nil, ErrSummaryNotFound
+	}
+	return summary, nil
+}
+
+// ListShared returns the summaries shared with a customer, latest consultation first
+func (s *Service) ListShared(customerID uuid.UUID) ([]models.ConsultationSummary, error) {
+	var summaries []models.ConsultationSummary
+	err := s.db.Preload("Booking").Preload("Author").Preload("Items", orderItems).
+		Joins("JOIN bookings ON bookings.id = consultation_summaries.booking_id").
+		Where("consultation_summaries.user_id = ? AND consultation_summaries.shared_at IS NOT NULL", customerID).
+		Order("bookings.start_time DESC").
+		Find(&summaries).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to load consultation summaries: %w", err)
+	}
+	return summaries, nil
+}
+
+// RenderPDF renders the summary as a PDF for the customer, with times in loc
+func RenderPDF(summary *models.ConsultationSummary, loc *time.Location) []byte {
+	response := summary.ToResponse()
+
+	doc := pdf.New()
+	doc.SetFooter("Beratungsprotokoll · Elterngeld-Portal")
+	doc.Title("Beratungsprotokoll")
+	doc.Field("Termin", response.Title)
+	doc.Field("Datum", response.ConsultationAt.In(loc).Format("02.01.2006 15:04"))
+	if response.AuthorName != "" {
+		doc.Field("Berater/in", response.AuthorName)
+	}
+	if summary.Booking != nil && summary.Booking.BookingReference != "" {
+		doc.Field("Buchungsnummer", summary.Booking.BookingReference)
+	}
+
+	for _, section := range []struct {
+		heading string
+		entries []string
+	}{
+		{"Besprochene Themen", response.Topics},
+		{"Empfehlungen", response.Recommendations},
+		{"Nächste Schritte", response.NextSteps},
+	} {
+		if len(section.entries) == 0 {
+			continue
+		}
+		doc.Heading(section.heading)
+		for i, entry := range section.entries {
+			doc.Text(fmt.Sprintf("%d. %s", i+1, entry))
+		}
+	}
+
+	return doc.Bytes()
+}
+
+// notifyCustomer tells the customer that the summary is available in their dashboard
+func (s *Service) notifyCustomer(booking *models.Booking, summary *models.ConsultationSummary) {
+	var customer models.User
+	if err := s.db.First(&customer, "id = ?", booking.UserID).Error; err != nil {
+		s.logger.Warn("Failed to load customer of consultation summary", zap.String("booking_id", booking.ID.String()), zap.Error(err))
+		return
+	}
+
+	data, _ := json.Marshal(map[string]interface{}{
+		"booking_id": booking.ID,
+		"summary_id": summary.ID,
+	})
+	notification := models.Notification{
+		UserID:    customer.ID,
+		Type:      models.NotificationTypeInApp,
+		Title:     "Ihr Beratungsprotokoll ist verfügbar",
+		Message:   fmt.Sprintf("Das Protokoll zu Ihrem Termin '%s' steht in Ihrem Dashboard bereit.", booking.Title),
+		Data:      string(data),
+		Recipient: customer.NotificationAddress(),
+	}
+	if err := s.db.Create(&notification).Error; err != nil {
+		s.logger.Error("Failed to create consultation summary notification", zap.String("booking_id", booking.ID.String()), zap.Error(err))
+	}
+}
+
+func (s *Service) loadBooking(bookingID uuid.UUID) (*models.Booking, error) {
+	var booking models.Booking
+	if err := s.db.First(&booking, "id = ?", bookingID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrBookingNotFound
+		}
+		return nil, fmt.Errorf("failed to load booking: %w", err)
+	}
+	return &booking, nil
+}
+
+func (s *Service) load(bookingID uuid.UUID) (*models.ConsultationSummary, error) {
+	var summary models.ConsultationSummary
+	err := s.db.Preload("Booking").Preload("Author").Preload("Items", orderItems).
+		Where("booking_id = ?", bookingID).First(&summary).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrSummaryNotFound
+		}
+		return nil, fmt.Errorf("failed to load consultation summary: %w", err)
+	}
+	return &summary, nil
+}
+
+func orderItems(db *gorm.DB) *gorm.DB {
+	return db.Order("position ASC")
+}
+
+// canWrite checks if the user conducts the booking or is an admin
+func canWrite(booking *models.Booking, user *models.User) bool {
+	return user.IsAdmin() || (booking.BeraterID != nil && *booking.BeraterID == user.ID)
+}
@@ -0,0 +1,157 @@
+package summaries
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/tests/testutils"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+func TestSave(t *testing.T) {
+	db, service := setupTestService(t)
+	customer := testutils.CreateTestUser(t, db, models.RoleUser)
+	berater := testutils.CreateTestUser(t, db, models.RoleBerater)
+	otherBerater := testutils.CreateTestUser(t, db, models.RoleBerater)
+	admin := testutils.CreateTestUser(t, db, models.RoleAdmin)
+
+	held := createBooking(t, db, customer, berater, models.BookingStatusCompleted, -2*time.Hour)
+	upcoming := createBooking(t, db, customer, berater, models.BookingStatusConfirmed, 2*time.Hour)
+	missed := createBooking(t, db, customer, berater, models.BookingStatusNoShow, -2*time.Hour)
+
+	input := Input{
+		Topics:          []string{"Basiselterngeld oder ElterngeldPlus", " "},
+		Recommendations: []string{"Partnerschaftsbonus nutzen"},
+		NextSteps:       []string{"Einkommensnachweise hochladen", "Antrag bis 30.04. einreichen"},
+	}
+
+	tests := []struct {
+		name    string
+		booking uuid.UUID
+		author  *models.User
+		input   Input
+		err     error
+	}{
+		{"other Berater", held.ID, otherBerater, input, ErrAccessDenied},
+		{"customer", held.ID, customer, input, ErrAccessDenied},
+		{"unknown booking", uuid.New(), admin, input, ErrBookingNotFound},
+		{"upcoming booking", upcoming.ID, berater, input, ErrNotHeld},
+		{"missed booking", missed.ID, berater, input, ErrNotHeld},
+		{"empty summary", held.ID, berater, Input{Topics: []string{""}}, ErrEmptySummary},
+		{"assigned Berater", held.ID, berater, input, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			summary, err := service.Save(tt.booking, tt.author, tt.input)
+			if tt.err != nil {
+				assert.ErrorIs(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+
+			response := summary.ToResponse()
+			assert.Equal(t, []string{"Basiselterngeld oder ElterngeldPlus"}, response.Topics)
+			assert.Equal(t, []string{"Partnerschaftsbonus nutzen"}, response.Recommendations)
+			assert.Equal(t, input.NextSteps, response.NextSteps)
+			assert.Equal(t, berater.FullName(), response.AuthorName)
+			assert.False(t, response.Shared)
+		})
+	}
+
+	t.Run("update replaces entries", func(t *testing.T) {
+		summary, err := service.Save(held.ID, admin, Input{Topics: []string{"Steuerklassenwechsel"}})
+		require.NoError(t, err)
+		response := summary.ToResponse()
+		assert.Equal(t, []string{"Steuerklassenwechsel"}, response.Topics)
+		assert.Empty(t, response.NextSteps)
+
+		var summaries, items int64
+		db.Model(&models.ConsultationSummary{}).Count(&summaries)
+		db.Model(&models.ConsultationSummaryItem{}).Count(&items)
+		assert.Equal(t, int64(1), summaries)
+		assert.Equal(t, int64(1), items)
+	})
+}
+
+func TestSharing(t *testing.T) {
+	db, service := setupTestService(t)
+	customer := testutils.CreateTestUser(t, db, models.RoleUser)
+	otherCustomer := testutils.CreateTestUser(t, db, models.RoleUser)
+	berater := testutils.CreateTestUser(t, db, models.RoleBerater)
+	earlier := createBooking(t, db, customer, berater, models.BookingStatusCompleted, -48*time.Hour)
+	later := createBooking(t, db, customer, berater, models.BookingStatusConfirmed, -2*time.Hour)
+
+	_, err := service.Save(later.ID, berater, Input{Topics: []string{"Antragstellung"}})
+	require.NoError(t, err)
+
+	// Unshared summaries are hidden from the customer
+	_, err = service.Get(later.ID, customer)
+	assert.ErrorIs(t, err, ErrSummaryNotFound)
+	_, err = service.Get(later.ID, otherCustomer)
+	assert.ErrorIs(t, err, ErrAccessDenied)
+	_, err = service.Get(later.ID, berater)
+	assert.NoError(t, err)
+
+	for _, booking := range []*models.Booking{later, earlier} {
+		_, err = service.Save(booking.ID, berater, Input{Topics: []string{"Antragstellung"}, Shared: true})
+		require.NoError(t, err)
+	}
+	// Saving again does not notify twice
+	_, err = service.Save(later.ID, berater, Input{Topics: []string{"Antragstellung", "Fristen"}, Shared: true})
+	require.NoError(t, err)
+
+	summary, err := service.Get(later.ID, customer)
+	require.NoError(t, err)
+	assert.True(t, summary.IsShared())
+
+	var notifications int64
+	db.Model(&models.Notification{}).Where("user_id = ?", customer.ID).Count(&notifications)
+	assert.Equal(t, int64(2), notifications)
+
+	shared, err := service.ListShared(customer.ID)
+	require.NoError(t, err)
+	require.Len(t, shared, 2)
+	assert.Equal(t, later.ID, shared[0].BookingID)
+	assert.Equal(t, []string{"Antragstellung", "Fristen"}, shared[0].ToResponse().Topics)
+
+	document := RenderPDF(summary, time.UTC)
+	assert.True(t, bytes.HasPrefix(document, []byte("%PDF-")))
+	assert.Contains(t, string(document), "(1. Antragstellung) Tj")
+}
+
+func createBooking(t *testing.T, db *gorm.DB, customer, berater *models.User, status models.BookingStatus, startsIn time.Duration) *models.Booking {
+	t.Helper()
+	start := time.Now().Add(startsIn)
+	booking := &models.Booking{
+		UserID:      customer.ID,
+		BeraterID:   &berater.ID,
+		Title:       "Erstberatung",
+		Status:      status,
+		ScheduledAt: start,
+		StartTime:   start,
+		EndTime:     start.Add(time.Hour),
+	}
+	require.NoError(t, db.Create(booking).Error)
+	return booking
+}
+
+func setupTestService(t *testing.T) (*gorm.DB, *Service) {
+	t.Helper()
+	db := testutils.NewSQLiteDB(t,
+		&models.User{},
+		&models.Booking{},
+		&models.Notification{},
+		&models.ConsultationSummary{},
+		&models.ConsultationSummaryItem{},
+	)
+
+	return db, NewService(db, zap.NewNop())
+}
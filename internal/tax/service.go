@@ -0,0 +1,250 @@
+// Package tax determines the Umsatzsteuer of a booking from the customer's
+// country and, for businesses, their USt-ID checked via VIES. Customers in
+// Germany and consumers in the EU pay German VAT, businesses elsewhere in the
+// EU are charged the net price under reverse charge and customers outside the
+// EU are not taxable in Germany. In Stripe mode Stripe Tax calculates the tax
+// in checkout instead and it is recorded from the completed session.
+package tax
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"elterngeld-portal/config"
+	"elterngeld-portal/internal/models"
+
+	"github.com/stripe/stripe-go/v76"
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrInvalidCountry is returned for countries that are not an ISO 3166-1 alpha-2 code
+	ErrInvalidCountry = errors.New("invalid country")
+	// ErrInvalidVATID is returned for USt-IDs that are malformed or of another country
+	ErrInvalidVATID = errors.New("invalid VAT ID")
+	// ErrVATIDRejected is returned when VIES does not know the USt-ID
+	ErrVATIDRejected = errors.New("VAT ID is not valid")
+	// ErrVIESUnavailable is returned when VIES or the member state does not answer
+	ErrVIESUnavailable = errors.New("VIES is unavailable")
+)
+
+// Supported tax modes
+const (
+	ModeInternal = "internal"
+	ModeStripe   = "stripe"
+)
+
+// euCountries maps the EU member states to their VAT prefix; Greece uses EL
+// and Northern Ireland XI
+var euCountries = map[string]string{
+	"AT": "AT", "BE": "BE", "BG": "BG", "CY": "CY", "CZ": "CZ", "DE": "DE", "DK": "DK",
+	"EE": "EE", "ES": "ES", "FI": "FI", "FR": "FR", "GR": "EL", "HR": "HR", "HU": "HU",
+	"IE": "IE", "IT": "IT", "LT": "LT", "LU": "LU", "LV": "LV", "MT": "MT", "NL": "NL",
+	"PL": "PL", "PT": "PT", "RO": "RO", "SE": "SE", "SI": "SI", "SK": "SK", "XI": "XI",
+}
+
+var (
+	countryPattern = regexp.MustCompile(`^[A-Z]{2}$`)
+	// vatNumberPattern is the common shape of VAT numbers after the prefix
+	vatNumberPattern = regexp.MustCompile(`^[0-9A-Z+*]{2,12}$`)
+)
+
+// Customer is who a booking is billed to
+type Customer struct {
+	Country string // ISO 3166-1 alpha-2 code, the home country when empty
+	VATID   string // USt-ID of a business customer, with or without country prefix
+}
+
+// Determination is how VAT applies to a customer
+type Determination struct {
+	Country        string              `json:"country"`
+	VATID          string              `json:"vat_id,omitempty"` // with country prefix
+	VATIDCheckedAt *time.Time          `json:"vat_id_checked_at,omitempty"`
+	BusinessName   string              `json:"business_name,omitempty"` // as registered in VIES
+	Treatment      models.TaxTreatment `json:"treatment"`
+	Rate           float64             `json:"rate"`
+}
+
+// Amounts is a price split into net amount and tax
+type Amounts struct {
+	Net   float64 `json:"net"`
+	Tax   float64 `json:"tax"`
+	Gross float64 `json:"gross"`
+}
+
+// Service determines the VAT of checkouts. Package and addon prices are gross
+// prices including the VAT rate of the home country.
+type Service struct {
+	logger      *zap.Logger
+	validator   Validator
+	mode        string
+	homeCountry string
+	rate        float64
+	now         func() time.Time
+}
+
+func NewService(logger *zap.Logger, cfg *config.Config, validator Validator) *Service {
+	mode := strings.ToLower(strings.TrimSpace(cfg.Tax.Mode))
+	if mode == "" {
+		mode = ModeInternal
+	}
+	return &Service{
+		logger:      logger,
+		validator:   validator,
+		mode:        mode,
+		homeCountry: strings.ToUpper(cfg.Tax.HomeCountry),
+		rate:        cfg.Booking.VATRate,
+		now:         time.Now,
+	}
+}
+
+// NewValidator creates the VIES client of the configuration
+func NewValidator(cfg *config.Config) Validator {
+	return NewVIES(cfg.Tax.VIESURL, &http.Client{Timeout: cfg.Tax.VIESTimeout})
+}
+
+// StripeTax reports whether Stripe Tax calculates the VAT in checkout
+func (s *Service) StripeTax() bool {
+	return s.mode == ModeStripe
+}
+
+// ConfigureCheckout lets Stripe Tax calculate the VAT of a checkout session
+// in Stripe mode. Prices include VAT; Stripe Tax asks for the address and
+// USt-ID it needs.
+func (s *Service) ConfigureCheckout(params *stripe.CheckoutSessionParams) {
+	if !s.StripeTax() {
+		return
+	}
+	for _, item := range params.LineItems {
+		item.PriceData.TaxBehavior = stripe.String(string(stripe.PriceTaxBehaviorInclusive))
+	}
+	params.AutomaticTax = &stripe.CheckoutSessionAutomaticTaxParams{Enabled: stripe.Bool(true)}
+	params.TaxIDCollection = &stripe.CheckoutSessionTaxIDCollectionParams{Enabled: stripe.Bool(true)}
+	params.BillingAddressCollection = stripe.String(string(stripe.CheckoutSessionBillingAddressCollectionRequired))
+	params.CustomerCreation = stripe.String(string(stripe.CheckoutSessionCustomerCreationAlways))
+}
+
+// Determine returns how VAT applies to a customer. A USt-ID of a business in
+// another EU country is checked via VIES; when VIES does not answer German
+// VAT is charged, which the business can reclaim.
+func (s *Service) Determine(ctx context.Context, customer Customer) (*Determination, error) {
+	country := strings.ToUpper(strings.TrimSpace(customer.Country))
+	if country == "" {
+		country = s.homeCountry
+	}
+	if !countryPattern.MatchString(country) {
+		return nil, ErrInvalidCountry
+	}
+
+	determination := &Determination{Country: country, Treatment: models.TaxTreatmentDomestic, Rate: s.rate}
+	prefix, inEU := euCountries[country]
+	if !inEU {
+		determination.Treatment = models.TaxTreatmentNonEU
+		determination.Rate = 0
+	}
+
+	vatID := strings.ToUpper(strings.NewReplacer(" ", "", ".", "", "-", "").Replace(customer.VATID))
+	if vatID == "" {
+		return determination, nil
+	}
+	if !inEU {
+		// Outside the EU the USt-ID is only printed on the invoice
+		determination.VATID = vatID
+		return determination, nil
+	}
+
+	number := strings.TrimPrefix(vatID, prefix)
+	if number == vatID && len(vatID) > 2 && countryPattern.MatchString(vatID[:2]) {
+		return nil, ErrInvalidVATID // prefix of another country
+	}
+	if !vatNumberPattern.MatchString(number) {
+		return nil, ErrInvalidVATID
+	}
+	determination.VATID = prefix + number
+	if country == s.homeCountry {
+		return determination, nil
+	}
+
+	check, err := s.validator.Check(ctx, prefix, number)
+	if err != nil {
+		if errors.Is(err, ErrVIESUnavailable) {
+			s.logger.Warn("VIES unavailable, charging German VAT",
+				zap.String("vat_id", determination.VATID), zap.Error(err))
+			return determination, nil
+		}
+		return nil, err
+	}
+	if !check.Valid {
+		return nil, ErrVATIDRejected
+	}
+
+	checkedAt := s.now()
+	determination.VATIDCheckedAt = &checkedAt
+	determination.BusinessName = check.Name
+	determination.Treatment = models.TaxTreatmentReverseCharge
+	determination.Rate = 0
+	return determination, nil
+}
+
+// Amounts splits a gross list price for a customer. Customers who pay no
+// German VAT are charged the net price. Without a determination the price is
+// split at the home rate.
+func (s *Service) Amounts(determination *Determination, gross float64) Amounts {
+	net := roundCents(gross / (1 + s.rate))
+	if determination != nil && determination.Treatment != models.TaxTreatmentDomestic {
+		return Amounts{Net: net, Gross: net}
+	}
+	return Amounts{Net: net, Tax: roundCents(gross - net), Gross: roundCents(gross)}
+}
+
+// Record stores the tax of a checkout on its payment. Nothing is recorded
+// without a determination, e.g. when Stripe Tax calculates it.
+func (s *Service) Record(payment *models.Payment, determination *Determination, amounts Amounts) {
+	if determination == nil {
+		return
+	}
+	payment.NetAmount = amounts.Net
+	payment.TaxAmount = amounts.Tax
+	payment.TaxRate = determination.Rate
+	payment.TaxCountry = determination.Country
+	payment.TaxTreatment = determination.Treatment
+	payment.CustomerVATID = determination.VATID
+	payment.VATIDCheckedAt = determination.VATIDCheckedAt
+}
+
+// RecordStripe stores the tax Stripe Tax calculated for a completed checkout
+// session on its payment
+func (s *Service) RecordStripe(payment *models.Payment, session *stripe.CheckoutSession) {
+	if session.TotalDetails == nil {
+		return
+	}
+	tax := float64(session.TotalDetails.AmountTax) / 100
+	payment.TaxAmount = roundCents(tax)
+	payment.NetAmount = roundCents(float64(session.AmountTotal)/100 + payment.CreditAmount - tax)
+	payment.TaxRate = 0
+	if net := float64(session.AmountTotal)/100 - tax; net > 0 {
+		payment.TaxRate = math.Round(tax/net*10000) / 10000
+	}
+	payment.TaxTreatment = models.TaxTreatmentStripe
+
+	if details := session.CustomerDetails; details != nil {
+		if details.Address != nil {
+			payment.TaxCountry = strings.ToUpper(details.Address.Country)
+		}
+		for _, id := range details.TaxIDs {
+			if id.Type == stripe.CheckoutSessionCustomerDetailsTaxIDTypeEUVAT {
+				payment.CustomerVATID = strings.ToUpper(id.Value)
+				break
+			}
+		}
+	}
+}
+
+func roundCents(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
@@ -0,0 +1,154 @@
+package tax
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"elterngeld-portal/config"
+	"elterngeld-portal/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stripe/stripe-go/v76"
+	"go.uber.org/zap"
+)
+
+// fakeValidator knows a single valid Austrian USt-ID
+type fakeValidator struct {
+	checks      []string
+	unavailable bool
+}
+
+func (v *fakeValidator) Check(ctx context.Context, country, number string) (*VATCheck, error) {
+	v.checks = append(v.checks, country+number)
+	if v.unavailable {
+		return nil, ErrVIESUnavailable
+	}
+	if country+number != "ATU12345678" {
+		return &VATCheck{}, nil
+	}
+	return &VATCheck{Valid: true, Name: "Beratung GmbH"}, nil
+}
+
+func TestDetermine(t *testing.T) {
+	validator := &fakeValidator{}
+	service := setupTestService(validator)
+	ctx := context.Background()
+
+	consumer, err := service.Determine(ctx, Customer{})
+	require.NoError(t, err)
+	assert.Equal(t, &Determination{Country: "DE", Treatment: models.TaxTreatmentDomestic, Rate: 0.19}, consumer)
+	assert.Equal(t, Amounts{Net: 167.23, Tax: 31.77, Gross: 199}, service.Amounts(consumer, 199))
+
+	// Consumers in the EU pay German VAT
+	french, err := service.Determine(ctx, Customer{Country: "fr"})
+	require.NoError(t, err)
+	assert.Equal(t, models.TaxTreatmentDomestic, french.Treatment)
+
+	swiss, err := service.Determine(ctx, Customer{Country: "CH", VATID: "CHE-123.456.789 MWST"})
+	require.NoError(t, err)
+	assert.Equal(t, models.TaxTreatmentNonEU, swiss.Treatment)
+	assert.Equal(t, "CHE123456789MWST", swiss.VATID)
+	assert.Equal(t, Amounts{Net: 167.23, Gross: 167.23}, service.Amounts(swiss, 199))
+
+	// German businesses are not checked and pay German VAT
+	german, err := service.Determine(ctx, Customer{Country: "DE", VATID: "123456789"})
+	require.NoError(t, err)
+	assert.Equal(t, "DE123456789", german.VATID)
+	assert.Equal(t, models.TaxTreatmentDomestic, german.Treatment)
+	assert.Empty(t, validator.checks)
+
+	business, err := service.Determine(ctx, Customer{Country: "AT", VATID: "atu 1234 5678"})
+	require.NoError(t, err)
+	assert.Equal(t, models.TaxTreatmentReverseCharge, business.Treatment)
+	assert.Equal(t, 0.0, business.Rate)
+	assert.Equal(t, "ATU12345678", business.VATID)
+	assert.Equal(t, "Beratung GmbH", business.BusinessName)
+	require.NotNil(t, business.VATIDCheckedAt)
+
+	_, err = service.Determine(ctx, Customer{Country: "AT", VATID: "ATU99999999"})
+	assert.ErrorIs(t, err, ErrVATIDRejected)
+	_, err = service.Determine(ctx, Customer{Country: "AT", VATID: "FR12345678901"})
+	assert.ErrorIs(t, err, ErrInvalidVATID, "prefix of another country")
+	_, err = service.Determine(ctx, Customer{Country: "AT", VATID: "ATU?"})
+	assert.ErrorIs(t, err, ErrInvalidVATID)
+	_, err = service.Determine(ctx, Customer{Country: "Österreich"})
+	assert.ErrorIs(t, err, ErrInvalidCountry)
+
+	// Without an answer from VIES German VAT is charged
+	validator.unavailable = true
+	fallback, err := service.Determine(ctx, Customer{Country: "AT", VATID: "ATU12345678"})
+	require.NoError(t, err)
+	assert.Equal(t, models.TaxTreatmentDomestic, fallback.Treatment)
+	assert.Nil(t, fallback.VATIDCheckedAt)
+
+	payment := &models.Payment{Amount: 117.23, CreditAmount: 50}
+	service.Record(payment, business, service.Amounts(business, 199))
+	assert.Equal(t, 167.23, payment.NetAmount)
+	assert.Equal(t, 0.0, payment.TaxAmount)
+	assert.Equal(t, "AT", payment.TaxCountry)
+	assert.Equal(t, models.TaxTreatmentReverseCharge, payment.TaxTreatment)
+	assert.Equal(t, "ATU12345678", payment.CustomerVATID)
+}
+
+func TestRecordStripe(t *testing.T) {
+	service := setupTestService(&fakeValidator{})
+	payment := &models.Payment{Amount: 149, CreditAmount: 50}
+	service.RecordStripe(payment, &stripe.CheckoutSession{
+		AmountTotal:  14900,
+		TotalDetails: &stripe.CheckoutSessionTotalDetails{AmountTax: 2379},
+		CustomerDetails: &stripe.CheckoutSessionCustomerDetails{
+			Address: &stripe.Address{Country: "de"},
+			TaxIDs:  []*stripe.CheckoutSessionCustomerDetailsTaxID{{Type: stripe.CheckoutSessionCustomerDetailsTaxIDTypeEUVAT, Value: "de123456789"}},
+		},
+	})
+	assert.Equal(t, 23.79, payment.TaxAmount)
+	assert.Equal(t, 175.21, payment.NetAmount)
+	assert.Equal(t, 0.19, payment.TaxRate)
+	assert.Equal(t, "DE", payment.TaxCountry)
+	assert.Equal(t, "DE123456789", payment.CustomerVATID)
+	assert.Equal(t, models.TaxTreatmentStripe, payment.TaxTreatment)
+}
+
+func TestVIES(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/ms/AT/vat/U12345678":
+			w.Write([]byte(`{"isValid": true, "userError": "VALID", "name": "Beratung GmbH", "address": "---"}`))
+		case "/ms/AT/vat/U99999999":
+			w.Write([]byte(`{"isValid": false, "userError": "INVALID", "name": "---"}`))
+		case "/ms/EL/vat/123456789":
+			w.Write([]byte(`{"isValid": false, "userError": "MS_UNAVAILABLE"}`))
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+	vies := NewVIES(server.URL+"/", &http.Client{Timeout: time.Second})
+	ctx := context.Background()
+
+	check, err := vies.Check(ctx, "AT", "U12345678")
+	require.NoError(t, err)
+	assert.Equal(t, &VATCheck{Valid: true, Name: "Beratung GmbH"}, check)
+
+	check, err = vies.Check(ctx, "AT", "U99999999")
+	require.NoError(t, err)
+	assert.False(t, check.Valid)
+
+	_, err = vies.Check(ctx, "EL", "123456789")
+	assert.ErrorIs(t, err, ErrVIESUnavailable)
+	_, err = vies.Check(ctx, "FR", "12345678901")
+	assert.ErrorIs(t, err, ErrVIESUnavailable)
+}
+
+func setupTestService(validator Validator) *Service {
+	cfg := &config.Config{
+		Booking: config.BookingConfig{VATRate: 0.19},
+		Tax:     config.TaxConfig{Mode: ModeInternal, HomeCountry: "DE"},
+	}
+	return NewService(zap.NewNop(), cfg, validator)
+}
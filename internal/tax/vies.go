@@ -0,0 +1,95 @@
+package tax
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const viesAPIURL = "https://ec.europa.eu/taxation_customs/vies/rest-api"
+
+// VATCheck is the answer of VIES for a USt-ID
+type VATCheck struct {
+	Valid   bool
+	Name    string // registered name of the business, if the member state shares it
+	Address string
+}
+
+// Validator checks USt-IDs of businesses in the EU
+type Validator interface {
+	// Check looks up a VAT number of a member state, given without the country
+	// prefix. It returns ErrVIESUnavailable when VIES cannot answer.
+	Check(ctx context.Context, country, number string) (*VATCheck, error)
+}
+
+// VIES checks USt-IDs with the VAT Information Exchange System of the EU Commission
+type VIES struct {
+	baseURL string
+	client  *http.Client
+}
+
+func NewVIES(baseURL string, client *http.Client) *VIES {
+	if baseURL == "" {
+		baseURL = viesAPIURL
+	}
+	return &VIES{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		client:  client,
+	}
+}
+
+// viesResponse is the answer of the check-vat endpoint; userError tells
+// whether the member state could be asked at all
+type viesResponse struct {
+	IsValid   bool   `json:"isValid"`
+	UserError string `json:"userError"`
+	Name      string `json:"name"`
+	Address   string `json:"address"`
+}
+
+func (v *VIES) Check(ctx context.Context, country, number string) (*VATCheck, error) {
+	endpoint := fmt.Sprintf("%s/ms/%s/vat/%s", v.baseURL, url.PathEscape(country), url.PathEscape(number))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrVIESUnavailable, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("%w: VIES returned %d: %s", ErrVIESUnavailable, resp.StatusCode, strings.TrimSpace(string(detail)))
+	}
+
+	var result viesResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("%w: failed to decode VIES response: %v", ErrVIESUnavailable, err)
+	}
+	switch result.UserError {
+	case "", "VALID", "INVALID":
+	default:
+		// e.g. MS_UNAVAILABLE or TIMEOUT: the member state did not answer
+		return nil, fmt.Errorf("%w: %s", ErrVIESUnavailable, result.UserError)
+	}
+
+	check := &VATCheck{Valid: result.IsValid}
+	if result.IsValid {
+		// Member states that do not share the details answer "---"
+		if name := strings.TrimSpace(result.Name); name != "---" {
+			check.Name = name
+		}
+		if address := strings.TrimSpace(result.Address); address != "---" {
+			check.Address = address
+		}
+	}
+	return check, nil
+}
@@ -0,0 +1,197 @@
+// Package trash lists soft-deleted records, restores them and deletes them
+// permanently. Leads, bookings, jobs and contact forms are only soft-deleted
+// by the API, this is the only way back.
+package trash
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"elterngeld-portal/internal/models"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrUnknownType is returned for record types without a trash
+	ErrUnknownType = errors.New("unknown record type")
+	// ErrNotFound is returned when the record is not in the trash
+	ErrNotFound = errors.New("record not found in trash")
+	// ErrConfirmationMismatch is returned when a permanent delete is not confirmed with the record ID
+	ErrConfirmationMismatch = errors.New("confirmation does not match the record ID")
+	// ErrHasPayments is returned when records with payments or documents would be deleted permanently
+	ErrHasPayments = errors.New("record has payments or documents")
+)
+
+// Type is a kind of record that can be restored from the trash
+type Type string
+
+const (
+	TypeLeads        Type = "leads"
+	TypeBookings     Type = "bookings"
+	TypeJobs         Type = "jobs"
+	TypeContactForms Type = "contact-forms"
+)
+
+// Types are all record types with a trash
+var Types = []Type{TypeLeads, TypeBookings, TypeJobs, TypeContactForms}
+
+// resource describes how the records of a type are stored
+type resource struct {
+	model  func() interface{}
+	title  string // column shown as the record's title
+	detail string // column that tells records with the same title apart
+	// keep is a condition on the record that must not hold for a permanent
+	// delete, e.g. because accounting still needs it
+	keep string
+}
+
+var resources = map[Type]resource{
+	TypeLeads: {
+		model:  func() interface{} { return &models.Lead{} },
+		title:  "title",
+		detail: "application_number",
+		keep: `EXISTS (SELECT 1 FROM payments WHERE payments.lead_id = leads.id)
+			OR EXISTS (SELECT 1 FROM documents WHERE documents.lead_id = leads.id)`,
+	},
+	TypeBookings: {
+		model:  func() interface{} { return &models.Booking{} },
+		title:  "title",
+		detail: "status",
+		keep:   "bookings.payment_id IS NOT NULL",
+	},
+	TypeJobs: {
+		model:  func() interface{} { return &models.Job{} },
+		title:  "title",
+		detail: "slug",
+	},
+	TypeContactForms: {
+		model:  func() interface{} { return &models.ContactForm{} },
+		title:  "subject",
+		detail: "name",
+	},
+}
+
+// Item is a record in the trash
+type Item struct {
+	ID        uuid.UUID `json:"id"`
+	Title     string    `json:"title"`
+	Detail    string    `json:"detail"`
+	CreatedAt time.Time `json:"created_at"`
+	DeletedAt time.Time `json:"deleted_at"`
+}
+
+// Service manages the trash of soft-deleted records
+type Service struct {
+	db     *gorm.DB
+	logger *zap.Logger
+}
+
+func NewService(db *gorm.DB, logger *zap.Logger) *Service {
+	return &Service{
+		db:     db,
+		logger: logger,
+	}
+}
+
+func lookup(recordType Type) (resource, error) {
+	r, ok := resources[recordType]
+	if !ok {
+		return resource{}, ErrUnknownType
+	}
+	return r, nil
+}
+
+// trashed selects the soft-deleted records of a type
+func (s *Service) trashed(r resource) *gorm.DB {
+	return s.db.Unscoped().Model(r.model()).Where("deleted_at IS NOT NULL")
+}
+
+// List returns a page of the soft-deleted records of a type, most recently
+// deleted first
+func (s *Service) List(recordType Type, page, limit int) ([]Item, int64, error) {
+	r, err := lookup(recordType)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var total int64
+	if err := s.trashed(r).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	items := []Item{}
+	err = s.trashed(r).
+		Select(fmt.Sprintf("id, %s AS title, %s AS detail, created_at, deleted_at", r.title, r.detail)).
+		Order("deleted_at DESC").
+		Offset((page - 1) * limit).Limit(limit).
+		Scan(&items).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return items, total, nil
+}
+
+// Restore brings a soft-deleted record back. Restored leads record who
+// restored them in their activity history.
+func (s *Service) Restore(recordType Type, id, adminID uuid.UUID) error {
+	r, err := lookup(recordType)
+	if err != nil {
+		return err
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Unscoped().Model(r.model()).
+			Where("id = ? AND deleted_at IS NOT NULL", id).
+			Update("deleted_at", nil)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrNotFound
+		}
+		if recordType == TypeLeads {
+			return tx.Create(models.CreateLeadRestoredActivity(adminID, id)).Error
+		}
+		return nil
+	})
+}
+
+// DeletePermanently removes a soft-deleted record for good. The confirmation
+// must repeat the record ID. Leads with payments or documents and paid
+// bookings are kept for accounting and retention.
+func (s *Service) DeletePermanently(recordType Type, id uuid.UUID, confirmation string) error {
+	r, err := lookup(recordType)
+	if err != nil {
+		return err
+	}
+	if confirmation != id.String() {
+		return ErrConfirmationMismatch
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Unscoped().Model(r.model()).Where("id = ? AND deleted_at IS NOT NULL", id).
+			Count(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 {
+			return ErrNotFound
+		}
+
+		if r.keep != "" {
+			if err := tx.Unscoped().Model(r.model()).Where("id = ?", id).Where(r.keep).
+				Count(&count).Error; err != nil {
+				return err
+			}
+			if count > 0 {
+				return ErrHasPayments
+			}
+		}
+
+		return tx.Unscoped().Where("id = ?", id).Delete(r.model()).Error
+	})
+}
@@ -0,0 +1,146 @@
+package trash
+
+import (
+	"testing"
+	"time"
+
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/tests/testutils"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+func TestListAndRestore(t *testing.T) {
+	db, service := setupTestService(t)
+	customer := testutils.CreateTestUser(t, db, models.RoleUser)
+	admin := testutils.CreateTestUser(t, db, models.RoleAdmin)
+
+	kept := createLead(t, db, customer.ID, "Elterngeld für Emma")
+	first := createLead(t, db, customer.ID, "Elterngeld für Paul")
+	second := createLead(t, db, customer.ID, "Elterngeld für Mia")
+	require.NoError(t, db.Delete(first).Error)
+	require.NoError(t, db.Delete(second).Error)
+	require.NoError(t, db.Model(&models.Lead{}).Unscoped().Where("id = ?", first.ID).
+		Update("deleted_at", time.Now().Add(-time.Hour)).Error)
+
+	items, total, err := service.List(TypeLeads, 1, 20)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), total)
+	require.Len(t, items, 2)
+	assert.Equal(t, second.ID, items[0].ID)
+	assert.Equal(t, "Elterngeld für Mia", items[0].Title)
+	assert.Equal(t, second.ApplicationNumber, items[0].Detail)
+	assert.False(t, items[0].DeletedAt.IsZero())
+
+	items, _, err = service.List(TypeLeads, 2, 1)
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	assert.Equal(t, first.ID, items[0].ID)
+
+	require.NoError(t, service.Restore(TypeLeads, first.ID, admin.ID))
+	var restored models.Lead
+	require.NoError(t, db.First(&restored, "id = ?", first.ID).Error)
+
+	var activity models.Activity
+	require.NoError(t, db.Where("lead_id = ? AND type = ?", first.ID, models.ActivityTypeLeadRestored).First(&activity).Error)
+	assert.Equal(t, admin.ID, *activity.UserID)
+
+	// Records that are not in the trash cannot be restored
+	assert.ErrorIs(t, service.Restore(TypeLeads, first.ID, admin.ID), ErrNotFound)
+	assert.ErrorIs(t, service.Restore(TypeLeads, kept.ID, admin.ID), ErrNotFound)
+	assert.ErrorIs(t, service.Restore(TypeLeads, uuid.New(), admin.ID), ErrNotFound)
+	assert.ErrorIs(t, service.Restore("todos", kept.ID, admin.ID), ErrUnknownType)
+
+	form := &models.ContactForm{ID: uuid.New(), Name: "Anna Schmidt", Email: "anna@example.com", Subject: "Rückruf", Message: "Bitte rufen Sie mich an"}
+	require.NoError(t, db.Create(form).Error)
+	require.NoError(t, db.Delete(form).Error)
+	items, _, err = service.List(TypeContactForms, 1, 20)
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	assert.Equal(t, "Rückruf", items[0].Title)
+	assert.Equal(t, "Anna Schmidt", items[0].Detail)
+	require.NoError(t, service.Restore(TypeContactForms, form.ID, admin.ID))
+}
+
+func TestDeletePermanently(t *testing.T) {
+	db, service := setupTestService(t)
+	customer := testutils.CreateTestUser(t, db, models.RoleUser)
+
+	lead := createLead(t, db, customer.ID, "Elterngeld für Emma")
+	paid := createLead(t, db, customer.ID, "Elterngeld für Paul")
+	require.NoError(t, db.Create(&models.Payment{
+		UserID:          customer.ID,
+		LeadID:          paid.ID,
+		Amount:          120,
+		Status:          models.PaymentStatusSucceeded,
+		StripeSessionID: "cs_test",
+	}).Error)
+	active := createLead(t, db, customer.ID, "Elterngeld für Mia")
+	require.NoError(t, db.Delete(lead).Error)
+	require.NoError(t, db.Delete(paid).Error)
+
+	assert.ErrorIs(t, service.DeletePermanently(TypeLeads, lead.ID, "ja"), ErrConfirmationMismatch)
+	assert.ErrorIs(t, service.DeletePermanently(TypeLeads, active.ID, active.ID.String()), ErrNotFound)
+	assert.ErrorIs(t, service.DeletePermanently(TypeLeads, paid.ID, paid.ID.String()), ErrHasPayments)
+
+	require.NoError(t, service.DeletePermanently(TypeLeads, lead.ID, lead.ID.String()))
+	var count int64
+	require.NoError(t, db.Unscoped().Model(&models.Lead{}).Where("id = ?", lead.ID).Count(&count).Error)
+	assert.Zero(t, count)
+
+	// Paid bookings are kept, unpaid ones can be deleted
+	start := time.Now().Add(48 * time.Hour)
+	paymentID := uuid.New()
+	for _, payment := range []*uuid.UUID{nil, &paymentID} {
+		booking := &models.Booking{
+			UserID:      customer.ID,
+			PaymentID:   payment,
+			Title:       "Beratung",
+			Status:      models.BookingStatusCancelled,
+			ScheduledAt: start,
+			StartTime:   start,
+			EndTime:     start.Add(time.Hour),
+		}
+		require.NoError(t, db.Create(booking).Error)
+		require.NoError(t, db.Delete(booking).Error)
+
+		err := service.DeletePermanently(TypeBookings, booking.ID, booking.ID.String())
+		if payment == nil {
+			assert.NoError(t, err)
+		} else {
+			assert.ErrorIs(t, err, ErrHasPayments)
+		}
+	}
+}
+
+func setupTestService(t *testing.T) (*gorm.DB, *Service) {
+	t.Helper()
+	db := testutils.NewSQLiteDB(t,
+		&models.User{},
+		&models.Lead{},
+		&models.Document{},
+		&models.Activity{},
+		&models.Payment{},
+		&models.Booking{},
+		&models.ContactForm{},
+	)
+
+	return db, NewService(db, zap.NewNop())
+}
+
+func createLead(t *testing.T, db *gorm.DB, userID uuid.UUID, title string) *models.Lead {
+	t.Helper()
+	lead := &models.Lead{
+		UserID:   userID,
+		Title:    title,
+		Status:   models.LeadStatusNew,
+		Priority: models.PriorityMedium,
+		Source:   models.LeadSourceWebsite,
+	}
+	require.NoError(t, db.Create(lead).Error)
+	return lead
+}
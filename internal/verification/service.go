@@ -0,0 +1,213 @@
+package verification
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"elterngeld-portal/config"
+	"elterngeld-portal/internal/models"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// resendWindow is the period in which at most VerificationMaxResends emails are sent per account
+const resendWindow = 24 * time.Hour
+
+var (
+	// ErrInvalidToken is returned for unknown, used or superseded verification tokens
+	ErrInvalidToken = errors.New("invalid verification token")
+	// ErrTokenExpired is returned for expired tokens; a new verification email has been sent
+	ErrTokenExpired = errors.New("verification token expired")
+	// ErrResendTooSoon is returned when a verification email was sent within the cooldown
+	ErrResendTooSoon = errors.New("verification email was sent recently")
+	// ErrResendLimitReached is returned when the daily number of verification emails is used up
+	ErrResendLimitReached = errors.New("too many verification emails requested")
+)
+
+// Mailer sends the email containing the verification link
+type Mailer interface {
+	SendWelcomeEmail(user *models.User, verificationToken string) error
+}
+
+// Service issues and checks email verification tokens and removes
+// self-registered accounts that were never verified
+type Service struct {
+	db     *gorm.DB
+	logger *zap.Logger
+	mailer Mailer
+	config config.AuthConfig
+	now    func() time.Time
+}
+
+func NewService(db *gorm.DB, logger *zap.Logger, cfg *config.Config, mailer Mailer) *Service {
+	return &Service{
+		db:     db,
+		logger: logger,
+		mailer: mailer,
+		config: cfg.Auth,
+		now:    time.Now,
+	}
+}
+
+// Issue creates a new verification token for the user and emails the link.
+// Earlier unused tokens of the user stop working.
+func (s *Service) Issue(user *models.User) error {
+	token, err := generateToken()
+	if err != nil {
+		return fmt.Errorf("failed to generate verification token: %w", err)
+	}
+
+	now := s.now()
+	verification := &models.EmailVerification{
+		UserID:    user.ID,
+		Email:     user.Email,
+		Token:     models.HashVerificationToken(token),
+		ExpiresAt: now.Add(s.config.VerificationTokenTTL),
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ? AND is_used = ?", user.ID, false).
+			Delete(&models.EmailVerification{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(verification).Error
+	})
+	if err != nil {
+		return fmt.Errorf("failed to store verification token: %w", err)
+	}
+
+	if err := s.mailer.SendWelcomeEmail(user, token); err != nil {
+		return fmt.Errorf("failed to send verification email: %w", err)
+	}
+	return nil
+}
+
+// Resend emails a new verification link to an unverified account. Unknown and
+// already verified addresses are ignored so the endpoint does not reveal accounts.
+func (s *Service) Resend(email string) error {
+	var user models.User
+	if err := s.db.Where("LOWER(email) = ?", strings.ToLower(strings.TrimSpace(email))).
+		First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+	if user.EmailVerified {
+		return nil
+	}
+
+	// Superseded tokens are soft deleted and still count towards the limits
+	var recent []models.EmailVerification
+	if err := s.db.Unscoped().Where("user_id = ? AND created_at > ?", user.ID, s.now().Add(-resendWindow)).
+		Order("created_at DESC").Find(&recent).Error; err != nil {
+		return fmt.Errorf("failed to load verification tokens: %w", err)
+	}
+	if len(recent) > 0 && s.now().Sub(recent[0].CreatedAt) < s.config.VerificationResendCooldown {
+		return ErrResendTooSoon
+	}
+	if len(recent) >= s.config.VerificationMaxResends {
+		return ErrResendLimitReached
+	}
+
+	return s.Issue(&user)
+}
+
+// Verify marks the email address of the token's user as verified and activates
+// the account. An expired token is replaced by a new one that is emailed to the
+// user, and ErrTokenExpired is returned.
+func (s *Service) Verify(token string) (*models.User, error) {
+	var verification models.EmailVerification
+	if err := s.db.Preload("User").Where("token = ?", models.HashVerificationToken(token)).
+		First(&verification).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidToken
+		}
+		return nil, err
+	}
+	if verification.IsUsed || verification.User.Email != verification.Email {
+		return nil, ErrInvalidToken
+	}
+
+	user := verification.User
+	if verification.ExpiresAt.Before(s.now()) {
+		if err := s.Issue(&user); err != nil {
+			return nil, err
+		}
+		s.logger.Info("Expired verification token replaced", zap.String("user_id", user.ID.String()))
+		return nil, ErrTokenExpired
+	}
+
+	now := s.now()
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&verification).Updates(map[string]interface{}{
+			"is_used": true,
+			"used_at": now,
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&user).Updates(map[string]interface{}{
+			"email_verified":    true,
+			"email_verified_at": now,
+			"is_active":         true,
+		}).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify email: %w", err)
+	}
+
+	return &user, nil
+}
+
+// CleanupPending deletes self-registered accounts whose email address was not
+// verified within PendingAccountTTL and which have no leads, bookings or payments.
+// It returns the number of deleted accounts.
+func (s *Service) CleanupPending() (int, error) {
+	query := s.db.Model(&models.User{}).
+		Where("role = ? AND email_verified = ? AND created_at < ?",
+			models.RoleUser, false, s.now().Add(-s.config.PendingAccountTTL)).
+		Where("EXISTS (SELECT 1 FROM email_verifications WHERE email_verifications.user_id = users.id)")
+	for _, table := range []string{"leads", "bookings", "payments"} {
+		if s.db.Migrator().HasTable(table) {
+			query = query.Where(fmt.Sprintf("NOT EXISTS (SELECT 1 FROM %s WHERE %s.user_id = users.id)", table, table))
+		}
+	}
+
+	var userIDs []uuid.UUID
+	if err := query.Pluck("id", &userIDs).Error; err != nil {
+		return 0, fmt.Errorf("failed to find pending accounts: %w", err)
+	}
+	if len(userIDs) == 0 {
+		return 0, nil
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		for _, model := range []interface{}{&models.EmailVerification{}, &models.RefreshToken{}} {
+			if err := tx.Unscoped().Where("user_id IN ?", userIDs).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		// Hard delete so the email address can be registered again
+		return tx.Unscoped().Where("id IN ?", userIDs).Delete(&models.User{}).Error
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete pending accounts: %w", err)
+	}
+
+	s.logger.Info("Removed unverified accounts", zap.Int("count", len(userIDs)))
+	return len(userIDs), nil
+}
+
+func generateToken() (string, error) {
+	bytes := make([]byte, 32)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(bytes), nil
+}
@@ -0,0 +1,182 @@
+package verification
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"elterngeld-portal/config"
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/tests/testutils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type fakeMailer struct {
+	tokens []string
+	err    error
+}
+
+func (m *fakeMailer) SendWelcomeEmail(user *models.User, verificationToken string) error {
+	m.tokens = append(m.tokens, verificationToken)
+	return m.err
+}
+
+func (m *fakeMailer) last() string {
+	return m.tokens[len(m.tokens)-1]
+}
+
+func TestVerify(t *testing.T) {
+	db, service, mailer := setupTestService(t)
+	user := createUser(t, db, "anna@example.com", false)
+
+	require.NoError(t, service.Issue(user))
+	require.Len(t, mailer.tokens, 1)
+	token := mailer.last()
+
+	_, err := service.Verify("unknown")
+	assert.ErrorIs(t, err, ErrInvalidToken)
+
+	verified, err := service.Verify(token)
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, verified.ID)
+
+	var stored models.User
+	require.NoError(t, db.First(&stored, "id = ?", user.ID).Error)
+	assert.True(t, stored.EmailVerified)
+	assert.NotNil(t, stored.EmailVerifiedAt)
+	assert.True(t, stored.IsActive)
+
+	// Tokens work only once
+	_, err = service.Verify(token)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestVerify_ExpiredTokenIsRegenerated(t *testing.T) {
+	db, service, mailer := setupTestService(t)
+	user := createUser(t, db, "anna@example.com", false)
+
+	require.NoError(t, service.Issue(user))
+	expired := mailer.last()
+	service.now = func() time.Time { return time.Now().Add(25 * time.Hour) }
+
+	_, err := service.Verify(expired)
+	assert.ErrorIs(t, err, ErrTokenExpired)
+	require.Len(t, mailer.tokens, 2)
+
+	// The expired token is superseded, the new one works
+	_, err = service.Verify(expired)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+	_, err = service.Verify(mailer.last())
+	assert.NoError(t, err)
+}
+
+func TestIssue_SupersedesEarlierTokens(t *testing.T) {
+	db, service, mailer := setupTestService(t)
+	user := createUser(t, db, "anna@example.com", false)
+
+	require.NoError(t, service.Issue(user))
+	first := mailer.last()
+	require.NoError(t, service.Issue(user))
+
+	_, err := service.Verify(first)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+	_, err = service.Verify(mailer.last())
+	assert.NoError(t, err)
+
+	mailer.err = errors.New("smtp down")
+	assert.Error(t, service.Issue(user))
+}
+
+func TestResend(t *testing.T) {
+	db, service, mailer := setupTestService(t)
+	user := createUser(t, db, "anna@example.com", false)
+	createUser(t, db, "berta@example.com", true)
+
+	// Unknown and verified addresses are silently ignored
+	require.NoError(t, service.Resend("nobody@example.com"))
+	require.NoError(t, service.Resend("berta@example.com"))
+	assert.Empty(t, mailer.tokens)
+
+	require.NoError(t, service.Resend(" Anna@Example.com "))
+	assert.Len(t, mailer.tokens, 1)
+
+	assert.ErrorIs(t, service.Resend("anna@example.com"), ErrResendTooSoon)
+
+	// Once the cooldown passed, resends count towards the daily limit
+	ageTokens := func() {
+		require.NoError(t, db.Unscoped().Model(&models.EmailVerification{}).Where("user_id = ?", user.ID).
+			Update("created_at", gorm.Expr("datetime(created_at, '-5 minutes')")).Error)
+	}
+	for i := 0; i < 2; i++ {
+		ageTokens()
+		require.NoError(t, service.Resend("anna@example.com"))
+	}
+	ageTokens()
+	assert.ErrorIs(t, service.Resend("anna@example.com"), ErrResendLimitReached)
+	assert.Len(t, mailer.tokens, 3)
+}
+
+func TestCleanupPending(t *testing.T) {
+	db, service, _ := setupTestService(t)
+	stale := createUser(t, db, "stale@example.com", false)
+	fresh := createUser(t, db, "fresh@example.com", false)
+	withLead := createUser(t, db, "lead@example.com", false)
+	verified := createUser(t, db, "verified@example.com", true)
+	seeded := createUser(t, db, "seeded@example.com", false)
+
+	for _, user := range []*models.User{stale, fresh, withLead, verified} {
+		require.NoError(t, service.Issue(user))
+	}
+	require.NoError(t, db.Create(&models.Lead{UserID: withLead.ID, Title: "Elterngeldantrag"}).Error)
+
+	old := time.Now().Add(-31 * 24 * time.Hour)
+	require.NoError(t, db.Model(&models.User{}).Where("id <> ?", fresh.ID).Update("created_at", old).Error)
+
+	removed, err := service.CleanupPending()
+	require.NoError(t, err)
+	assert.Equal(t, 1, removed)
+
+	assert.ErrorIs(t, db.Unscoped().First(&models.User{}, "id = ?", stale.ID).Error, gorm.ErrRecordNotFound)
+	var count int64
+	require.NoError(t, db.Unscoped().Model(&models.EmailVerification{}).Where("user_id = ?", stale.ID).Count(&count).Error)
+	assert.Zero(t, count)
+
+	for _, kept := range []*models.User{fresh, withLead, verified, seeded} {
+		assert.NoError(t, db.First(&models.User{}, "id = ?", kept.ID).Error, kept.Email)
+	}
+
+	// The address can be registered again
+	createUser(t, db, "stale@example.com", false)
+}
+
+func setupTestService(t *testing.T) (*gorm.DB, *Service, *fakeMailer) {
+	t.Helper()
+	db := testutils.NewSQLiteDB(t,
+		&models.User{},
+		&models.RefreshToken{},
+		&models.Lead{},
+		&models.EmailVerification{},
+	)
+
+	cfg := &config.Config{Auth: config.AuthConfig{
+		VerificationTokenTTL:       24 * time.Hour,
+		VerificationResendCooldown: 2 * time.Minute,
+		VerificationMaxResends:     3,
+		PendingAccountTTL:          30 * 24 * time.Hour,
+	}}
+	mailer := &fakeMailer{}
+	return db, NewService(db, zap.NewNop(), cfg, mailer), mailer
+}
+
+func createUser(t *testing.T, db *gorm.DB, email string, verified bool) *models.User {
+	t.Helper()
+	user := testutils.CreateTestUser(t, db, models.RoleUser)
+	require.NoError(t, db.Model(user).Updates(map[string]interface{}{"email": email, "email_verified": verified}).Error)
+	user.Email = email
+	user.EmailVerified = verified
+	return user
+}
@@ -0,0 +1,364 @@
+package webhooks
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"elterngeld-portal/internal/models"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	// maxAttempts is how often an event is processed automatically before it
+	// stays failed until an admin replays it
+	maxAttempts = 5
+	// sweepInterval is how often stored events are picked up for (re)processing
+	sweepInterval = time.Minute
+	queueSize     = 100
+)
+
+var (
+	// ErrUnknownProvider is returned for webhooks of providers that are not registered
+	ErrUnknownProvider = errors.New("unknown webhook provider")
+	// ErrInvalidPayload is returned when a verified payload cannot be parsed
+	ErrInvalidPayload = errors.New("invalid webhook payload")
+	// ErrEventNotFound is returned when replaying an unknown event
+	ErrEventNotFound = errors.New("webhook event not found")
+	// ErrEventInProgress is returned when replaying an event that is being processed
+	ErrEventInProgress = errors.New("webhook event is being processed")
+)
+
+// EventInfo identifies a delivery within a provider
+type EventInfo struct {
+	ID   string
+	Type string
+}
+
+// Provider describes an inbound webhook integration
+type Provider struct {
+	Name     string
+	Verifier Verifier
+	// Parse extracts the event ID and type from a verified payload. Without an
+	// ID the payload hash is used to detect redeliveries.
+	Parse func(payload []byte) (EventInfo, error)
+	// Handle processes a stored event. Returned errors are retried with backoff.
+	Handle func(event *models.WebhookEvent) error
+}
+
+// JSONEventInfo parses the top-level "id" and "type" fields used by Stripe and
+// most other providers
+func JSONEventInfo(payload []byte) (EventInfo, error) {
+	var body struct {
+		ID   string `json:"id"`
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(payload, &body); err != nil {
+		return EventInfo{}, err
+	}
+	return EventInfo{ID: body.ID, Type: body.Type}, nil
+}
+
+// Receiver verifies, stores and asynchronously processes webhooks of all
+// registered providers
+type Receiver struct {
+	db     *gorm.DB
+	logger *zap.Logger
+	now    func() time.Time
+
+	mu        sync.RWMutex
+	providers map[string]Provider
+	queue     chan uuid.UUID
+	// inFlight holds the events this instance is processing
+	inFlight map[uuid.UUID]struct{}
+}
+
+func NewReceiver(db *gorm.DB, logger *zap.Logger) *Receiver {
+	return &Receiver{
+		db:        db,
+		logger:    logger,
+		now:       time.Now,
+		providers: make(map[string]Provider),
+		queue:     make(chan uuid.UUID, queueSize),
+		inFlight:  make(map[uuid.UUID]struct{}),
+	}
+}
+
+// Register adds a provider; registering a name again replaces it
+func (r *Receiver) Register(provider Provider) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.providers[provider.Name] = provider
+}
+
+func (r *Receiver) provider(name string) (Provider, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	provider, ok := r.providers[name]
+	return provider, ok
+}
+
+// Receive verifies a webhook request and stores its raw payload for processing.
+// Redeliveries of a stored event return the existing event without processing it again.
+func (r *Receiver) Receive(providerName string, header http.Header, payload []byte) (*models.WebhookEvent, error) {
+	provider, ok := r.provider(providerName)
+	if !ok {
+		return nil, ErrUnknownProvider
+	}
+	if err := provider.Verifier.Verify(header, payload); err != nil {
+		return nil, err
+	}
+
+	info, err := provider.Parse(payload)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPayload, err)
+	}
+	if info.ID == "" {
+		sum := sha256.Sum256(payload)
+		info.ID = "sha256:" + hex.EncodeToString(sum[:])
+	}
+
+	var existing models.WebhookEvent
+	err = r.db.Where("provider = ? AND event_id = ?", provider.Name, info.ID).First(&existing).Error
+	if err == nil {
+		r.logger.Info("Duplicate webhook delivery ignored",
+			zap.String("provider", provider.Name),
+			zap.String("event_id", info.ID))
+		return &existing, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	event := &models.WebhookEvent{
+		Provider:   provider.Name,
+		EventID:    info.ID,
+		EventType:  info.Type,
+		Payload:    string(payload),
+		Status:     models.WebhookEventStatusPending,
+		ReceivedAt: r.now(),
+	}
+	if err := r.db.Create(event).Error; err != nil {
+		return nil, fmt.Errorf("failed to store webhook event: %w", err)
+	}
+
+	r.logger.Info("Webhook received",
+		zap.String("provider", provider.Name),
+		zap.String("event_id", info.ID),
+		zap.String("type", info.Type))
+
+	r.enqueue(event.ID)
+	return event, nil
+}
+
+// Replay processes a stored event again, e.g. after a bug in its handler was fixed
+func (r *Receiver) Replay(eventID uuid.UUID) (*models.WebhookEvent, error) {
+	var event models.WebhookEvent
+	if err := r.db.First(&event, "id = ?", eventID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrEventNotFound
+		}
+		return nil, err
+	}
+
+	result := r.db.Model(&models.WebhookEvent{}).
+		Where("id = ? AND status <> ?", event.ID, models.WebhookEventStatusProcessing).
+		Updates(map[string]interface{}{
+			"status":          models.WebhookEventStatusPending,
+			"next_attempt_at": nil,
+		})
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to replay webhook event: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrEventInProgress
+	}
+	event.Status = models.WebhookEventStatusPending
+	event.NextAttemptAt = nil
+
+	r.enqueue(event.ID)
+	return &event, nil
+}
+
+// Run processes queued events with the given number of workers until the
+// context is cancelled and returns when the workers finished their current
+// events. Events left over from a previous run and failed events that are due
+// for a retry are picked up periodically.
+func (r *Receiver) Run(ctx context.Context, workers int) {
+	// Events interrupted by a shutdown are processed again
+	if err := r.db.Model(&models.WebhookEvent{}).
+		Where("status = ?", models.WebhookEventStatusProcessing).
+		Update("status", models.WebhookEventStatusPending).Error; err != nil {
+		r.logger.Error("Failed to reset interrupted webhook events", zap.Error(err))
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case id := <-r.queue:
+					r.process(id)
+				}
+			}
+		}()
+	}
+
+	ticker := time.NewTicker(sweepInterval)
+	defer ticker.Stop()
+
+	for {
+		r.sweep()
+
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// sweep queues pending events and failed events that are due for a retry
+func (r *Receiver) sweep() {
+	var ids []uuid.UUID
+	if err := r.db.Model(&models.WebhookEvent{}).
+		Where("status = ? OR (status = ? AND next_attempt_at <= ?)",
+			models.WebhookEventStatusPending, models.WebhookEventStatusFailed, r.now()).
+		Order("received_at ASC").Limit(queueSize).Pluck("id", &ids).Error; err != nil {
+		r.logger.Error("Failed to load webhook events", zap.Error(err))
+		return
+	}
+	for _, id := range ids {
+		r.enqueue(id)
+	}
+}
+
+// enqueue schedules an event for processing. When the queue is full the event
+// stays pending and the next sweep picks it up.
+func (r *Receiver) enqueue(id uuid.UUID) {
+	select {
+	case r.queue <- id:
+	default:
+	}
+}
+
+// process runs the provider's handler for an event. The status update claims
+// the event so it is not processed twice when it was queued more than once.
+func (r *Receiver) process(id uuid.UUID) {
+	claim := r.db.Model(&models.WebhookEvent{}).
+		Where("id = ? AND status IN ?", id, []models.WebhookEventStatus{models.WebhookEventStatusPending, models.WebhookEventStatusFailed}).
+		Updates(map[string]interface{}{
+			"status":   models.WebhookEventStatusProcessing,
+			"attempts": gorm.Expr("attempts + 1"),
+		})
+	if claim.Error != nil {
+		r.logger.Error("Failed to claim webhook event", zap.String("webhook_event_id", id.String()), zap.Error(claim.Error))
+		return
+	}
+	if claim.RowsAffected == 0 {
+		return
+	}
+	r.track(id, true)
+	defer r.track(id, false)
+
+	var event models.WebhookEvent
+	if err := r.db.First(&event, "id = ?", id).Error; err != nil {
+		r.logger.Error("Failed to load webhook event", zap.String("webhook_event_id", id.String()), zap.Error(err))
+		return
+	}
+
+	var handleErr error
+	if provider, ok := r.provider(event.Provider); ok {
+		handleErr = r.handle(provider, &event)
+	} else {
+		handleErr = ErrUnknownProvider
+	}
+
+	updates := map[string]interface{}{}
+	if handleErr == nil {
+		now := r.now()
+		updates["status"] = models.WebhookEventStatusProcessed
+		updates["processed_at"] = &now
+		updates["last_error"] = ""
+		updates["next_attempt_at"] = nil
+	} else {
+		updates["status"] = models.WebhookEventStatusFailed
+		updates["last_error"] = handleErr.Error()
+		updates["next_attempt_at"] = nil
+		if event.Attempts < maxAttempts {
+			// Exponential backoff like notification retries
+			next := r.now().Add(time.Duration(event.Attempts*event.Attempts) * time.Minute)
+			updates["next_attempt_at"] = &next
+		}
+		r.logger.Error("Failed to process webhook event",
+			zap.String("provider", event.Provider),
+			zap.String("event_id", event.EventID),
+			zap.Int("attempts", event.Attempts),
+			zap.Error(handleErr))
+	}
+
+	if err := r.db.Model(&event).Updates(updates).Error; err != nil {
+		r.logger.Error("Failed to update webhook event", zap.String("webhook_event_id", id.String()), zap.Error(err))
+	}
+}
+
+// handle calls the provider's handler and turns panics into errors so a broken
+// handler cannot stop the worker
+func (r *Receiver) handle(provider Provider, event *models.WebhookEvent) (err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("webhook handler panicked: %v", recovered)
+		}
+	}()
+	return provider.Handle(event)
+}
+
+// Requeue returns the events this instance is still processing to pending,
+// so they are processed again when a shutdown cannot wait for them. The
+// interrupted attempt does not count.
+func (r *Receiver) Requeue() (int64, error) {
+	r.mu.RLock()
+	ids := make([]uuid.UUID, 0, len(r.inFlight))
+	for id := range r.inFlight {
+		ids = append(ids, id)
+	}
+	r.mu.RUnlock()
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	result := r.db.Model(&models.WebhookEvent{}).
+		Where("id IN ? AND status = ?", ids, models.WebhookEventStatusProcessing).
+		Updates(map[string]interface{}{
+			"status":   models.WebhookEventStatusPending,
+			"attempts": gorm.Expr("attempts - 1"),
+		})
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to requeue webhook events: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
+
+func (r *Receiver) track(id uuid.UUID, inFlight bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if inFlight {
+		r.inFlight[id] = struct{}{}
+	} else {
+		delete(r.inFlight, id)
+	}
+}
@@ -0,0 +1,258 @@
+package webhooks
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/tests/testutils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const testSecret = "whsec_test"
+
+func TestReceive(t *testing.T) {
+	db, receiver := setupTestReceiver(t)
+	var handled []string
+	receiver.Register(testProvider(func(event *models.WebhookEvent) error {
+		handled = append(handled, event.EventID)
+		return nil
+	}))
+
+	payload := []byte(`{"id": "evt_1", "type": "booking.updated"}`)
+
+	_, err := receiver.Receive("unknown", signedHeader(payload), payload)
+	assert.ErrorIs(t, err, ErrUnknownProvider)
+
+	_, err = receiver.Receive("calendar", http.Header{}, payload)
+	assert.ErrorIs(t, err, ErrInvalidSignature)
+
+	_, err = receiver.Receive("calendar", signedHeader([]byte("not json")), []byte("not json"))
+	assert.ErrorIs(t, err, ErrInvalidPayload)
+
+	event, err := receiver.Receive("calendar", signedHeader(payload), payload)
+	require.NoError(t, err)
+	assert.Equal(t, "evt_1", event.EventID)
+	assert.Equal(t, "booking.updated", event.EventType)
+	assert.Equal(t, models.WebhookEventStatusPending, event.Status)
+	assert.Equal(t, string(payload), event.Payload)
+
+	// Redeliveries return the stored event
+	again, err := receiver.Receive("calendar", signedHeader(payload), payload)
+	require.NoError(t, err)
+	assert.Equal(t, event.ID, again.ID)
+
+	var count int64
+	require.NoError(t, db.Model(&models.WebhookEvent{}).Count(&count).Error)
+	assert.Equal(t, int64(1), count)
+
+	receiver.process(<-receiver.queue)
+	assert.Equal(t, []string{"evt_1"}, handled)
+
+	var stored models.WebhookEvent
+	require.NoError(t, db.First(&stored, "id = ?", event.ID).Error)
+	assert.Equal(t, models.WebhookEventStatusProcessed, stored.Status)
+	assert.Equal(t, 1, stored.Attempts)
+	assert.NotNil(t, stored.ProcessedAt)
+
+	// Already processed events are not claimed again
+	receiver.process(event.ID)
+	assert.Len(t, handled, 1)
+}
+
+func TestReceive_PayloadWithoutID(t *testing.T) {
+	_, receiver := setupTestReceiver(t)
+	receiver.Register(testProvider(func(*models.WebhookEvent) error { return nil }))
+
+	payload := []byte(`{"type": "ping"}`)
+	first, err := receiver.Receive("calendar", signedHeader(payload), payload)
+	require.NoError(t, err)
+	assert.Contains(t, first.EventID, "sha256:")
+
+	second, err := receiver.Receive("calendar", signedHeader(payload), payload)
+	require.NoError(t, err)
+	assert.Equal(t, first.ID, second.ID)
+}
+
+func TestProcess_RetriesAndReplay(t *testing.T) {
+	db, receiver := setupTestReceiver(t)
+	now := time.Date(2025, 3, 10, 12, 0, 0, 0, time.UTC)
+	receiver.now = func() time.Time { return now }
+
+	failing := true
+	receiver.Register(testProvider(func(*models.WebhookEvent) error {
+		if failing {
+			return errors.New("calendar not found")
+		}
+		return nil
+	}))
+
+	payload := []byte(`{"id": "evt_2", "type": "calendar.deleted"}`)
+	event, err := receiver.Receive("calendar", signedHeader(payload), payload)
+	require.NoError(t, err)
+	receiver.process(<-receiver.queue)
+
+	var stored models.WebhookEvent
+	require.NoError(t, db.First(&stored, "id = ?", event.ID).Error)
+	assert.Equal(t, models.WebhookEventStatusFailed, stored.Status)
+	assert.Equal(t, "calendar not found", stored.LastError)
+	require.NotNil(t, stored.NextAttemptAt)
+	assert.WithinDuration(t, now.Add(time.Minute), *stored.NextAttemptAt, time.Second)
+
+	// Retries are picked up once they are due
+	receiver.sweep()
+	assert.Empty(t, receiver.queue)
+	now = now.Add(2 * time.Minute)
+	receiver.sweep()
+	require.Len(t, receiver.queue, 1)
+
+	// After the last automatic attempt the event waits for a replay
+	require.NoError(t, db.Model(&stored).Update("attempts", maxAttempts-1).Error)
+	receiver.process(<-receiver.queue)
+	var exhausted models.WebhookEvent
+	require.NoError(t, db.First(&exhausted, "id = ?", event.ID).Error)
+	assert.Equal(t, maxAttempts, exhausted.Attempts)
+	assert.Nil(t, exhausted.NextAttemptAt)
+	receiver.sweep()
+	assert.Empty(t, receiver.queue)
+
+	failing = false
+	replayed, err := receiver.Replay(event.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.WebhookEventStatusPending, replayed.Status)
+	receiver.process(<-receiver.queue)
+
+	require.NoError(t, db.First(&stored, "id = ?", event.ID).Error)
+	assert.Equal(t, models.WebhookEventStatusProcessed, stored.Status)
+	assert.Empty(t, stored.LastError)
+
+	_, err = receiver.Replay(stored.ID)
+	require.NoError(t, err)
+	<-receiver.queue
+	require.NoError(t, db.Model(&stored).Update("status", models.WebhookEventStatusProcessing).Error)
+	_, err = receiver.Replay(stored.ID)
+	assert.ErrorIs(t, err, ErrEventInProgress)
+}
+
+func TestProcess_RecoversFromPanics(t *testing.T) {
+	db, receiver := setupTestReceiver(t)
+	receiver.Register(testProvider(func(*models.WebhookEvent) error {
+		panic("nil map")
+	}))
+
+	payload := []byte(`{"id": "evt_3"}`)
+	event, err := receiver.Receive("calendar", signedHeader(payload), payload)
+	require.NoError(t, err)
+	receiver.process(<-receiver.queue)
+
+	var stored models.WebhookEvent
+	require.NoError(t, db.First(&stored, "id = ?", event.ID).Error)
+	assert.Equal(t, models.WebhookEventStatusFailed, stored.Status)
+	assert.Contains(t, stored.LastError, "nil map")
+}
+
+func TestRun_WaitsForWorkers(t *testing.T) {
+	db, receiver := setupTestReceiver(t)
+	started, release := make(chan struct{}), make(chan struct{})
+	receiver.Register(testProvider(func(*models.WebhookEvent) error {
+		close(started)
+		<-release
+		return nil
+	}))
+
+	payload := []byte(`{"id": "evt_4", "type": "calendar.updated"}`)
+	event, err := receiver.Receive("calendar", signedHeader(payload), payload)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		receiver.Run(ctx, 1)
+	}()
+	<-started
+	cancel()
+
+	// A shutdown that cannot wait hands the event back
+	requeued, err := receiver.Requeue()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), requeued)
+	var stored models.WebhookEvent
+	require.NoError(t, db.First(&stored, "id = ?", event.ID).Error)
+	assert.Equal(t, models.WebhookEventStatusPending, stored.Status)
+	assert.Zero(t, stored.Attempts)
+
+	select {
+	case <-stopped:
+		t.Fatal("Run returned before its worker finished")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(release)
+	<-stopped
+}
+
+func TestVerifiers(t *testing.T) {
+	payload := []byte(`{"id": "evt_1"}`)
+
+	t.Run("hmac", func(t *testing.T) {
+		verifier := HMACSignature{Header: "X-Signature", Secret: testSecret, Prefix: "sha256="}
+		assert.NoError(t, verifier.Verify(signedHeader(payload), payload))
+		assert.ErrorIs(t, verifier.Verify(signedHeader(payload), []byte(`{"id": "evt_2"}`)), ErrInvalidSignature)
+		assert.ErrorIs(t, verifier.Verify(http.Header{"X-Signature": {"sha256=zz"}}, payload), ErrInvalidSignature)
+
+		unconfigured := HMACSignature{Header: "X-Signature"}
+		assert.ErrorIs(t, unconfigured.Verify(signedHeader(payload), payload), ErrInvalidSignature)
+	})
+
+	t.Run("shared_secret", func(t *testing.T) {
+		verifier := SharedSecret{Header: "X-Webhook-Token", Secret: testSecret}
+		assert.NoError(t, verifier.Verify(http.Header{"X-Webhook-Token": {testSecret}}, payload))
+		assert.ErrorIs(t, verifier.Verify(http.Header{"X-Webhook-Token": {"guess"}}, payload), ErrInvalidSignature)
+		assert.ErrorIs(t, SharedSecret{Header: "X-Webhook-Token"}.Verify(http.Header{}, payload), ErrInvalidSignature)
+	})
+
+	t.Run("stripe", func(t *testing.T) {
+		verifier := StripeSignature{Secret: testSecret}
+		timestamp := time.Now().Unix()
+		mac := hmac.New(sha256.New, []byte(testSecret))
+		fmt.Fprintf(mac, "%d.%s", timestamp, payload)
+		header := http.Header{"Stripe-Signature": {fmt.Sprintf("t=%d,v1=%s", timestamp, hex.EncodeToString(mac.Sum(nil)))}}
+
+		assert.NoError(t, verifier.Verify(header, payload))
+		assert.ErrorIs(t, verifier.Verify(header, []byte(`{"id": "evt_2"}`)), ErrInvalidSignature)
+		assert.ErrorIs(t, verifier.Verify(http.Header{}, payload), ErrInvalidSignature)
+	})
+}
+
+func setupTestReceiver(t *testing.T) (*gorm.DB, *Receiver) {
+	t.Helper()
+	db := testutils.NewSQLiteDB(t, &models.WebhookEvent{})
+
+	return db, NewReceiver(db, zap.NewNop())
+}
+
+func testProvider(handle func(*models.WebhookEvent) error) Provider {
+	return Provider{
+		Name:     "calendar",
+		Verifier: HMACSignature{Header: "X-Signature", Secret: testSecret, Prefix: "sha256="},
+		Parse:    JSONEventInfo,
+		Handle:   handle,
+	}
+}
+
+func signedHeader(payload []byte) http.Header {
+	mac := hmac.New(sha256.New, []byte(testSecret))
+	mac.Write(payload)
+	return http.Header{"X-Signature": {"sha256=" + hex.EncodeToString(mac.Sum(nil))}}
+}
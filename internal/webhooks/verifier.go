@@ -0,0 +1,99 @@
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/stripe/stripe-go/v76/webhook"
+)
+
+// ErrInvalidSignature is returned when a webhook request fails verification
+var ErrInvalidSignature = errors.New("invalid webhook signature")
+
+// Verifier checks that a webhook request was sent by the provider. Verifiers
+// reject every request when no secret is configured.
+type Verifier interface {
+	Verify(header http.Header, payload []byte) error
+}
+
+// StripeSignature verifies the timestamped Stripe-Signature header
+type StripeSignature struct {
+	Secret    string
+	Tolerance time.Duration // defaults to Stripe's five minutes
+}
+
+func (v StripeSignature) Verify(header http.Header, payload []byte) error {
+	if v.Secret == "" {
+		return fmt.Errorf("%w: no secret configured", ErrInvalidSignature)
+	}
+	tolerance := v.Tolerance
+	if tolerance == 0 {
+		tolerance = webhook.DefaultTolerance
+	}
+	if err := webhook.ValidatePayloadWithTolerance(payload, header.Get("Stripe-Signature"), v.Secret, tolerance); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+	}
+	return nil
+}
+
+// HMACSignature verifies an HMAC-SHA256 of the raw payload sent in a header,
+// the scheme most calendar and email providers use
+type HMACSignature struct {
+	Header string
+	Secret string
+	Prefix string // e.g. "sha256=", stripped before decoding
+	Base64 bool   // signature is base64 instead of hex encoded
+}
+
+func (v HMACSignature) Verify(header http.Header, payload []byte) error {
+	if v.Secret == "" {
+		return fmt.Errorf("%w: no secret configured", ErrInvalidSignature)
+	}
+
+	value := strings.TrimPrefix(strings.TrimSpace(header.Get(v.Header)), v.Prefix)
+	var signature []byte
+	var err error
+	if v.Base64 {
+		signature, err = base64.StdEncoding.DecodeString(value)
+	} else {
+		signature, err = hex.DecodeString(value)
+	}
+	if err != nil || len(signature) == 0 {
+		return fmt.Errorf("%w: malformed %s header", ErrInvalidSignature, v.Header)
+	}
+
+	mac := hmac.New(sha256.New, []byte(v.Secret))
+	mac.Write(payload)
+	if !hmac.Equal(signature, mac.Sum(nil)) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// TokenHeader carries the shared secret of providers that can only be given a
+// webhook URL; the handler copies the "token" query parameter into it
+const TokenHeader = "X-Webhook-Token"
+
+// SharedSecret compares a static token header, for providers that cannot sign payloads
+type SharedSecret struct {
+	Header string
+	Secret string
+}
+
+func (v SharedSecret) Verify(header http.Header, payload []byte) error {
+	if v.Secret == "" {
+		return fmt.Errorf("%w: no secret configured", ErrInvalidSignature)
+	}
+	if subtle.ConstantTimeCompare([]byte(header.Get(v.Header)), []byte(v.Secret)) != 1 {
+		return ErrInvalidSignature
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package whatsapp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+const dialog360APIURL = "https://waba-v2.360dialog.io"
+
+// Dialog360 sends messages through 360dialog, which exposes the WhatsApp Cloud API
+type Dialog360 struct {
+	apiKey  string
+	baseURL string
+	client  *http.Client
+}
+
+func NewDialog360(apiKey, baseURL string, client *http.Client) *Dialog360 {
+	if baseURL == "" {
+		baseURL = dialog360APIURL
+	}
+
+	return &Dialog360{
+		apiKey:  apiKey,
+		baseURL: strings.TrimRight(baseURL, "/"),
+		client:  client,
+	}
+}
+
+func (d *Dialog360) Name() string {
+	return Provider360dialog
+}
+
+func (d *Dialog360) SendTemplate(ctx context.Context, to string, template Template) (string, error) {
+	parameters := make([]map[string]string, len(template.Parameters))
+	for i, value := range template.Parameters {
+		parameters[i] = map[string]string{"type": "text", "text": value}
+	}
+
+	return d.send(ctx, map[string]interface{}{
+		"messaging_product": "whatsapp",
+		"recipient_type":    "individual",
+		"to":                strings.TrimPrefix(to, "+"),
+		"type":              "template",
+		"template": map[string]interface{}{
+			"name":     template.Name,
+			"language": map[string]string{"code": template.Language},
+			"components": []map[string]interface{}{
+				{"type": "body", "parameters": parameters},
+			},
+		},
+	})
+}
+
+func (d *Dialog360) SendText(ctx context.Context, to, body string) (string, error) {
+	return d.send(ctx, map[string]interface{}{
+		"messaging_product": "whatsapp",
+		"recipient_type":    "individual",
+		"to":                strings.TrimPrefix(to, "+"),
+		"type":              "text",
+		"text":              map[string]string{"body": body},
+	})
+}
+
+func (d *Dialog360) send(ctx context.Context, message map[string]interface{}) (string, error) {
+	payload, err := json.Marshal(message)
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequest(http.MethodPost, d.baseURL+"/messages", bytes.NewReader(payload))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("D360-API-KEY", d.apiKey)
+
+	var response struct {
+		Messages []struct {
+			ID string `json:"id"`
+		} `json:"messages"`
+	}
+	if err := do(ctx, d.client, req, &response); err != nil {
+		return "", err
+	}
+	if len(response.Messages) == 0 {
+		return "", errors.New("360dialog response without message ID")
+	}
+	return response.Messages[0].ID, nil
+}
@@ -0,0 +1,84 @@
+package whatsapp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"elterngeld-portal/config"
+)
+
+// Supported WhatsApp Business providers
+const (
+	Provider360dialog = "360dialog"
+	ProviderTwilio    = "twilio"
+)
+
+// Template is an approved message template with its body parameters in order
+type Template struct {
+	Name       string
+	Language   string
+	Parameters []string
+}
+
+// Provider sends WhatsApp messages through a WhatsApp Business API provider.
+// Numbers are in E.164 format, e.g. +4917612345678.
+type Provider interface {
+	Name() string
+	// SendTemplate sends an approved template, the only kind of message allowed
+	// outside the customer service window. It returns the provider's message ID.
+	SendTemplate(ctx context.Context, to string, template Template) (string, error)
+	// SendText sends a free-form message within the customer service window
+	SendText(ctx context.Context, to, body string) (string, error)
+}
+
+// NewProvider creates the client for the configured provider. It returns nil when
+// no provider is configured, which disables WhatsApp messaging.
+func NewProvider(cfg *config.Config) (Provider, error) {
+	provider := strings.ToLower(strings.TrimSpace(cfg.WhatsApp.Provider))
+	if provider == "" {
+		return nil, nil
+	}
+	if cfg.WhatsApp.APIKey == "" {
+		return nil, fmt.Errorf("WHATSAPP_API_KEY is required for provider %q", provider)
+	}
+
+	client := &http.Client{Timeout: cfg.WhatsApp.Timeout}
+	switch provider {
+	case Provider360dialog:
+		return NewDialog360(cfg.WhatsApp.APIKey, cfg.WhatsApp.APIURL, client), nil
+	case ProviderTwilio:
+		if cfg.WhatsApp.AccountSID == "" || cfg.WhatsApp.From == "" {
+			return nil, errors.New("WHATSAPP_ACCOUNT_SID and WHATSAPP_FROM are required for provider \"twilio\"")
+		}
+		from, err := NormalizePhone(cfg.WhatsApp.From)
+		if err != nil {
+			return nil, fmt.Errorf("invalid WHATSAPP_FROM: %w", err)
+		}
+		return NewTwilio(cfg.WhatsApp.AccountSID, cfg.WhatsApp.APIKey, from, cfg.WhatsApp.APIURL, client), nil
+	default:
+		return nil, fmt.Errorf("unknown WhatsApp provider %q", cfg.WhatsApp.Provider)
+	}
+}
+
+// do sends a request, fails for unsuccessful status codes and decodes the JSON
+// response into out
+func do(ctx context.Context, client *http.Client, req *http.Request, out interface{}) error {
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("whatsapp request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("whatsapp provider returned %d: %s", resp.StatusCode, strings.TrimSpace(string(detail)))
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,357 @@
+// Package whatsapp sends booking reminders and conversation messages to
+// customers on WhatsApp through a WhatsApp Business API provider. Customers
+// have to opt in with their number first. Messages in both directions are
+// stored and recorded on the timeline of the customer's lead.
+package whatsapp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"elterngeld-portal/config"
+	"elterngeld-portal/internal/activitylog"
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/internal/scopes"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrNotConfigured is returned when messages are sent without a configured provider
+	ErrNotConfigured = errors.New("whatsapp messaging is not configured")
+	// ErrInvalidPhone is returned for numbers that cannot be converted to E.164
+	ErrInvalidPhone = errors.New("invalid phone number")
+	// ErrNoConsent is returned when the customer has not opted in to WhatsApp messages
+	ErrNoConsent = errors.New("customer has not opted in to whatsapp messages")
+	// ErrSessionExpired is returned for free-form messages outside the customer service window
+	ErrSessionExpired = errors.New("customer service window has expired")
+	// ErrLeadNotFound is returned when the lead does not exist or the user cannot access it
+	ErrLeadNotFound = errors.New("lead not found")
+	// ErrEmptyMessage is returned when a message has no text
+	ErrEmptyMessage = errors.New("message is empty")
+)
+
+// ConsentText is the opt-in wording shown to the customer, stored with their consent
+const ConsentText = "Ich bin damit einverstanden, Terminerinnerungen und Nachrichten meines Beraters per WhatsApp zu erhalten. " +
+	"Die Einwilligung kann ich jederzeit in meinen Einstellungen oder mit der Antwort STOP widerrufen."
+
+// SessionWindow is how long after the customer's last message WhatsApp allows
+// free-form messages; outside of it only approved templates can be sent
+const SessionWindow = 24 * time.Hour
+
+// optOutKeywords withdraw the consent when a customer sends them as message
+var optOutKeywords = map[string]bool{"STOP": true, "STOPP": true, "ABMELDEN": true}
+
+// reminderLookahead bounds the bookings checked for due reminders; reminders
+// are due the day before, which is at most 25 hours with a DST transition
+const reminderLookahead = 26 * time.Hour
+
+// Service manages the WhatsApp consent of customers, sends booking reminders
+// and stores the conversation with them
+type Service struct {
+	db         *gorm.DB
+	logger     *zap.Logger
+	provider   Provider
+	activities *activitylog.Service
+	config     config.WhatsAppConfig
+	now        func() time.Time
+}
+
+// NewService creates the WhatsApp service; a nil provider disables sending,
+// while consent can still be managed
+func NewService(db *gorm.DB, logger *zap.Logger, cfg *config.Config, provider Provider, activities *activitylog.Service) *Service {
+	return &Service{
+		db:         db,
+		logger:     logger,
+		provider:   provider,
+		activities: activities,
+		config:     cfg.WhatsApp,
+		now:        time.Now,
+	}
+}
+
+// Enabled reports whether a provider is configured
+func (s *Service) Enabled() bool {
+	return s.provider != nil
+}
+
+// ConsentInput opts a customer in or out of WhatsApp messages
+type ConsentInput struct {
+	Phone   string `json:"phone" binding:"max=32"`
+	OptedIn bool   `json:"opted_in"`
+}
+
+// Consent returns the WhatsApp consent of a user; users who never decided get
+// an unsaved consent that is not opted in
+func (s *Service) Consent(userID uuid.UUID) (*models.WhatsAppConsent, error) {
+	var consent models.WhatsAppConsent
+	err := s.db.Where("user_id = ?", userID).First(&consent).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &models.WhatsAppConsent{UserID: userID, ConsentText: ConsentText}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &consent, nil
+}
+
+// UpdateConsent opts a customer in with their number or withdraws the consent
+func (s *Service) UpdateConsent(userID uuid.UUID, input ConsentInput) (*models.WhatsAppConsent, error) {
+	consent, err := s.Consent(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := s.now()
+	if input.OptedIn {
+		phone, err := NormalizePhone(input.Phone)
+		if err != nil {
+			return nil, err
+		}
+		consent.Phone = phone
+		consent.OptedIn = true
+		consent.Source = models.WhatsAppConsentSourcePortal
+		consent.ConsentText = ConsentText
+		consent.OptedInAt = &now
+		consent.OptedOutAt = nil
+	} else {
+		if consent.ID == uuid.Nil || !consent.OptedIn {
+			return consent, nil
+		}
+		consent.OptedIn = false
+		consent.Source = models.WhatsAppConsentSourcePortal
+		consent.OptedOutAt = &now
+	}
+
+	if err := s.db.Save(consent).Error; err != nil {
+		return nil, fmt.Errorf("failed to save whatsapp consent: %w", err)
+	}
+	return consent, nil
+}
+
+// Messages returns the WhatsApp conversation with the customer of a lead, oldest first
+func (s *Service) Messages(leadID uuid.UUID, viewer scopes.Viewer) ([]models.WhatsAppMessage, error) {
+	var lead models.Lead
+	if err := s.db.Scopes(scopes.VisibleLeads(viewer)).First(&lead, "leads.id = ?", leadID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrLeadNotFound
+		}
+		return nil, err
+	}
+
+	var messages []models.WhatsAppMessage
+	if err := s.db.Where("lead_id = ?", lead.ID).Order("created_at ASC").Find(&messages).Error; err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
+// Send messages the customer of a lead in the name of the viewer. Free-form
+// messages are only allowed within SessionWindow of the customer's last message.
+func (s *Service) Send(ctx context.Context, leadID uuid.UUID, viewer scopes.Viewer, body string) (*models.WhatsAppMessage, error) {
+	if s.provider == nil {
+		return nil, ErrNotConfigured
+	}
+	body = strings.TrimSpace(body)
+	if body == "" {
+		return nil, ErrEmptyMessage
+	}
+
+	var lead models.Lead
+	if err := s.db.Scopes(scopes.EditableLeads(viewer)).First(&lead, "leads.id = ?", leadID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrLeadNotFound
+		}
+		return nil, err
+	}
+
+	consent, err := s.Consent(lead.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if !consent.OptedIn {
+		return nil, ErrNoConsent
+	}
+
+	now := s.now()
+	var inbound int64
+	if err := s.db.Model(&models.WhatsAppMessage{}).
+		Where("user_id = ? AND direction = ? AND created_at > ?", lead.UserID, models.WhatsAppDirectionInbound, now.Add(-SessionWindow)).
+		Count(&inbound).Error; err != nil {
+		return nil, err
+	}
+	if inbound == 0 {
+		return nil, ErrSessionExpired
+	}
+
+	providerID, err := s.provider.SendText(ctx, consent.Phone, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send whatsapp message: %w", err)
+	}
+
+	message := &models.WhatsAppMessage{
+		UserID:            lead.UserID,
+		LeadID:            &lead.ID,
+		SentBy:            &viewer.ID,
+		Direction:         models.WhatsAppDirectionOutbound,
+		Phone:             consent.Phone,
+		Body:              body,
+		Status:            models.WhatsAppMessageStatusSent,
+		ProviderMessageID: providerID,
+	}
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(message).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&lead).Updates(map[string]interface{}{
+			"last_contact_at":  now,
+			"contact_attempts": gorm.Expr("contact_attempts + ?", 1),
+		}).Error; err != nil {
+			return err
+		}
+		return s.activities.Record(tx, activitylog.WhatsAppSent{
+			ActorID:   &viewer.ID,
+			LeadID:    lead.ID,
+			MessageID: message.ID,
+			Body:      body,
+		})
+	})
+	if err != nil {
+		// The message was delivered to the provider, so this is not reported as a send failure
+		s.logger.Error("Failed to record whatsapp message", zap.String("lead_id", lead.ID.String()), zap.Error(err))
+	}
+	return message, nil
+}
+
+// SendReminders sends the reminder template for confirmed bookings of opted-in
+// customers once the reminder is due. It returns the number of reminders sent.
+func (s *Service) SendReminders(ctx context.Context) (int, error) {
+	if s.provider == nil {
+		return 0, nil
+	}
+	now := s.now()
+
+	var bookings []models.Booking
+	err := s.db.Preload("User").
+		Joins("JOIN whats_app_consents ON whats_app_consents.user_id = bookings.user_id").
+		Where("whats_app_consents.opted_in = ?", true).
+		Where("bookings.status = ? AND bookings.whats_app_reminder_sent_at IS NULL", models.BookingStatusConfirmed).
+		Where("bookings.start_time > ? AND bookings.start_time <= ?", now, now.Add(reminderLookahead)).
+		Find(&bookings).Error
+	if err != nil {
+		return 0, fmt.Errorf("failed to find bookings due for a reminder: %w", err)
+	}
+
+	sent := 0
+	for i := range bookings {
+		booking := &bookings[i]
+		if now.Before(booking.ReminderAt(booking.User.TimeLocation())) {
+			continue
+		}
+		if err := s.sendReminder(ctx, booking, now); err != nil {
+			s.logger.Warn("Failed to send whatsapp booking reminder", zap.String("booking_id", booking.ID.String()), zap.Error(err))
+			continue
+		}
+		sent++
+	}
+
+	if sent > 0 {
+		s.logger.Info("Sent whatsapp booking reminders", zap.Int("count", sent))
+	}
+	return sent, nil
+}
+
+// sendReminder claims the reminder of a booking, so it is sent once even with
+// several instances, and releases the claim again when sending fails
+func (s *Service) sendReminder(ctx context.Context, booking *models.Booking, now time.Time) error {
+	result := s.db.Model(&models.Booking{}).
+		Where("id = ? AND whats_app_reminder_sent_at IS NULL", booking.ID).
+		Update("whats_app_reminder_sent_at", now)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil
+	}
+
+	consent, err := s.Consent(booking.UserID)
+	if err != nil {
+		return err
+	}
+
+	start := booking.StartTime.In(booking.User.TimeLocation())
+	template := Template{
+		Name:     s.config.ReminderTemplate,
+		Language: s.config.TemplateLanguage,
+		Parameters: []string{
+			booking.User.FirstName,
+			start.Format("02.01.2006"),
+			start.Format("15:04"),
+			booking.BookingReference,
+		},
+	}
+	providerID, err := s.provider.SendTemplate(ctx, consent.Phone, template)
+	if err != nil {
+		if releaseErr := s.db.Model(&models.Booking{}).Where("id = ?", booking.ID).
+			Update("whats_app_reminder_sent_at", nil).Error; releaseErr != nil {
+			s.logger.Error("Failed to release whatsapp reminder claim", zap.String("booking_id", booking.ID.String()), zap.Error(releaseErr))
+		}
+		return err
+	}
+
+	message := &models.WhatsAppMessage{
+		UserID:            booking.UserID,
+		LeadID:            booking.LeadID,
+		BookingID:         &booking.ID,
+		Direction:         models.WhatsAppDirectionOutbound,
+		Phone:             consent.Phone,
+		Body:              fmt.Sprintf("Terminerinnerung: %s am %s um %s Uhr", booking.BookingReference, template.Parameters[1], template.Parameters[2]),
+		Template:          template.Name,
+		Status:            models.WhatsAppMessageStatusSent,
+		ProviderMessageID: providerID,
+	}
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(message).Error; err != nil {
+			return err
+		}
+		if booking.LeadID == nil {
+			return nil
+		}
+		return s.activities.Record(tx, activitylog.WhatsAppSent{
+			LeadID:    *booking.LeadID,
+			MessageID: message.ID,
+			Template:  template.Name,
+			Body:      message.Body,
+		})
+	})
+	if err != nil {
+		s.logger.Error("Failed to record whatsapp reminder", zap.String("booking_id", booking.ID.String()), zap.Error(err))
+	}
+	return nil
+}
+
+var phoneSeparators = regexp.MustCompile(`[\s\-/().]`)
+
+var e164 = regexp.MustCompile(`^\+[1-9][0-9]{7,14}$`)
+
+// NormalizePhone converts a phone number to E.164. Numbers without country
+// code are taken as German numbers.
+func NormalizePhone(phone string) (string, error) {
+	phone = phoneSeparators.ReplaceAllString(strings.TrimSpace(phone), "")
+	switch {
+	case strings.HasPrefix(phone, "00"):
+		phone = "+" + phone[2:]
+	case strings.HasPrefix(phone, "0"):
+		phone = "+49" + phone[1:]
+	}
+	if !e164.MatchString(phone) {
+		return "", ErrInvalidPhone
+	}
+	return phone, nil
+}
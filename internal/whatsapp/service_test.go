@@ -0,0 +1,243 @@
+package whatsapp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"elterngeld-portal/config"
+	"elterngeld-portal/internal/activitylog"
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/internal/scopes"
+	"elterngeld-portal/tests/testutils"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type sentMessage struct {
+	To       string
+	Body     string
+	Template Template
+}
+
+type fakeProvider struct {
+	sent []sentMessage
+	err  error
+}
+
+func (p *fakeProvider) Name() string {
+	return Provider360dialog
+}
+
+func (p *fakeProvider) SendTemplate(ctx context.Context, to string, template Template) (string, error) {
+	if p.err != nil {
+		return "", p.err
+	}
+	p.sent = append(p.sent, sentMessage{To: to, Template: template})
+	return fmt.Sprintf("wamid.%d", len(p.sent)), nil
+}
+
+func (p *fakeProvider) SendText(ctx context.Context, to, body string) (string, error) {
+	if p.err != nil {
+		return "", p.err
+	}
+	p.sent = append(p.sent, sentMessage{To: to, Body: body})
+	return fmt.Sprintf("wamid.%d", len(p.sent)), nil
+}
+
+func TestNormalizePhone(t *testing.T) {
+	tests := map[string]string{
+		"+49 176 1234567":   "+491761234567",
+		"0176/123 45 67":    "+491761234567",
+		"0043 (1) 234-5678": "+4312345678",
+	}
+	for input, want := range tests {
+		got, err := NormalizePhone(input)
+		require.NoError(t, err, input)
+		assert.Equal(t, want, got)
+	}
+
+	for _, input := range []string{"", "12345", "+49abc", "+0123456789"} {
+		_, err := NormalizePhone(input)
+		assert.ErrorIs(t, err, ErrInvalidPhone, input)
+	}
+}
+
+func TestUpdateConsent(t *testing.T) {
+	service, db, _ := setupTestService(t)
+	customer := testutils.CreateTestUser(t, db, models.RoleUser)
+
+	consent, err := service.Consent(customer.ID)
+	require.NoError(t, err)
+	assert.False(t, consent.OptedIn)
+
+	_, err = service.UpdateConsent(customer.ID, ConsentInput{Phone: "123", OptedIn: true})
+	assert.ErrorIs(t, err, ErrInvalidPhone)
+
+	consent, err = service.UpdateConsent(customer.ID, ConsentInput{Phone: "0176 1234567", OptedIn: true})
+	require.NoError(t, err)
+	assert.True(t, consent.OptedIn)
+	assert.Equal(t, "+491761234567", consent.Phone)
+	assert.Equal(t, ConsentText, consent.ConsentText)
+	require.NotNil(t, consent.OptedInAt)
+
+	consent, err = service.UpdateConsent(customer.ID, ConsentInput{OptedIn: false})
+	require.NoError(t, err)
+	assert.False(t, consent.OptedIn)
+	require.NotNil(t, consent.OptedOutAt)
+
+	// The opt-in is kept as proof after it was withdrawn
+	var saved models.WhatsAppConsent
+	require.NoError(t, db.First(&saved, "user_id = ?", customer.ID).Error)
+	assert.False(t, saved.OptedIn)
+	assert.NotNil(t, saved.OptedInAt)
+	assert.Equal(t, "+491761234567", saved.Phone)
+}
+
+func TestConversation(t *testing.T) {
+	service, db, provider := setupTestService(t)
+	berater := testutils.CreateTestUser(t, db, models.RoleBerater)
+	customer := testutils.CreateTestUser(t, db, models.RoleUser)
+	lead := &models.Lead{UserID: customer.ID, BeraterID: &berater.ID, Title: "Elterngeld Plus"}
+	require.NoError(t, db.Create(lead).Error)
+
+	_, err := service.Send(context.Background(), lead.ID, viewerOf(berater), "Hallo")
+	assert.ErrorIs(t, err, ErrNoConsent)
+
+	_, err = service.UpdateConsent(customer.ID, ConsentInput{Phone: "+49 176 1234567", OptedIn: true})
+	require.NoError(t, err)
+
+	// Free-form messages need a message of the customer within the last 24 hours
+	_, err = service.Send(context.Background(), lead.ID, viewerOf(berater), "Hallo")
+	assert.ErrorIs(t, err, ErrSessionExpired)
+
+	inbound := `{"entry":[{"changes":[{"value":{"messages":[{"id":"wamid.in1","from":"491761234567","type":"text","text":{"body":"Welche Unterlagen brauche ich?"}}]}}]}]}`
+	require.NoError(t, service.HandleWebhook(&models.WebhookEvent{Provider: Provider360dialog, Payload: inbound}))
+	// Redeliveries are stored once
+	require.NoError(t, service.HandleWebhook(&models.WebhookEvent{Provider: Provider360dialog, Payload: inbound}))
+
+	message, err := service.Send(context.Background(), lead.ID, viewerOf(berater), "Die Geburtsurkunde und Ihre Gehaltsnachweise.")
+	require.NoError(t, err)
+	assert.Equal(t, models.WhatsAppMessageStatusSent, message.Status)
+	require.Len(t, provider.sent, 1)
+	assert.Equal(t, "+491761234567", provider.sent[0].To)
+
+	status := fmt.Sprintf(`{"entry":[{"changes":[{"value":{"statuses":[{"id":"%s","status":"read"}]}}]}]}`, message.ProviderMessageID)
+	require.NoError(t, service.HandleWebhook(&models.WebhookEvent{Provider: Provider360dialog, Payload: status}))
+	// A late delivered status does not overwrite read
+	status = fmt.Sprintf(`{"entry":[{"changes":[{"value":{"statuses":[{"id":"%s","status":"delivered"}]}}]}]}`, message.ProviderMessageID)
+	require.NoError(t, service.HandleWebhook(&models.WebhookEvent{Provider: Provider360dialog, Payload: status}))
+
+	messages, err := service.Messages(lead.ID, viewerOf(berater))
+	require.NoError(t, err)
+	require.Len(t, messages, 2)
+	assert.Equal(t, models.WhatsAppDirectionInbound, messages[0].Direction)
+	assert.Equal(t, "Welche Unterlagen brauche ich?", messages[0].Body)
+	assert.Equal(t, models.WhatsAppMessageStatusRead, messages[1].Status)
+
+	_, err = service.Messages(lead.ID, viewerOf(testutils.CreateTestUser(t, db, models.RoleUser)))
+	assert.ErrorIs(t, err, ErrLeadNotFound)
+
+	var activities []models.Activity
+	require.NoError(t, db.Where("lead_id = ?", lead.ID).Order("created_at ASC").Find(&activities).Error)
+	require.Len(t, activities, 2)
+	assert.Equal(t, models.ActivityTypeWhatsAppReceived, activities[0].Type)
+	assert.Equal(t, models.ActivityTypeWhatsAppSent, activities[1].Type)
+
+	// STOP withdraws the consent
+	stop := "MessageSid=SM2&From=whatsapp%3A%2B491761234567&Body=Stop&SmsStatus=received"
+	require.NoError(t, service.HandleWebhook(&models.WebhookEvent{Provider: ProviderTwilio, Payload: stop}))
+	consent, err := service.Consent(customer.ID)
+	require.NoError(t, err)
+	assert.False(t, consent.OptedIn)
+	assert.Equal(t, models.WhatsAppConsentSourceWhatsApp, consent.Source)
+
+	_, err = service.Send(context.Background(), lead.ID, viewerOf(berater), "Hallo")
+	assert.ErrorIs(t, err, ErrNoConsent)
+}
+
+func TestSendReminders(t *testing.T) {
+	service, db, provider := setupTestService(t)
+	now := time.Date(2026, 3, 10, 9, 0, 0, 0, time.UTC)
+	service.now = func() time.Time { return now }
+
+	customer := testutils.CreateTestUser(t, db, models.RoleUser)
+	other := testutils.CreateTestUser(t, db, models.RoleUser)
+	_, err := service.UpdateConsent(customer.ID, ConsentInput{Phone: "+491761234567", OptedIn: true})
+	require.NoError(t, err)
+	lead := &models.Lead{UserID: customer.ID, Title: "Elterngeld"}
+	require.NoError(t, db.Create(lead).Error)
+
+	due := createBooking(t, db, customer.ID, &lead.ID, now.Add(20*time.Hour))
+	createBooking(t, db, customer.ID, nil, now.Add(30*time.Hour)) // reminder due tomorrow
+	createBooking(t, db, other.ID, nil, now.Add(20*time.Hour))    // no consent
+
+	provider.err = errors.New("provider unavailable")
+	sent, err := service.SendReminders(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 0, sent)
+
+	// The claim is released when sending failed, so the reminder is retried
+	provider.err = nil
+	sent, err = service.SendReminders(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, sent)
+	require.Len(t, provider.sent, 1)
+	assert.Equal(t, "booking_reminder", provider.sent[0].Template.Name)
+	assert.Equal(t, []string{"Test", "11.03.2026", "06:00", due.BookingReference}, provider.sent[0].Template.Parameters)
+
+	sent, err = service.SendReminders(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 0, sent)
+
+	var message models.WhatsAppMessage
+	require.NoError(t, db.First(&message, "booking_id = ?", due.ID).Error)
+	assert.Equal(t, lead.ID, *message.LeadID)
+	var count int64
+	require.NoError(t, db.Model(&models.Activity{}).Where("lead_id = ? AND type = ?", lead.ID, models.ActivityTypeWhatsAppSent).Count(&count).Error)
+	assert.Equal(t, int64(1), count)
+}
+
+func setupTestService(t *testing.T) (*Service, *gorm.DB, *fakeProvider) {
+	t.Helper()
+	db := testutils.NewSQLiteDB(t,
+		&models.User{},
+		&models.Lead{},
+		&models.FamilyMember{},
+		&models.Booking{},
+		&models.Activity{},
+		&models.WhatsAppConsent{},
+		&models.WhatsAppMessage{},
+	)
+
+	cfg := &config.Config{WhatsApp: config.WhatsAppConfig{ReminderTemplate: "booking_reminder", TemplateLanguage: "de"}}
+	provider := &fakeProvider{}
+	service := NewService(db, zap.NewNop(), cfg, provider, activitylog.NewService(db, zap.NewNop()))
+	return service, db, provider
+}
+
+func createBooking(t *testing.T, db *gorm.DB, userID uuid.UUID, leadID *uuid.UUID, start time.Time) *models.Booking {
+	t.Helper()
+	booking := &models.Booking{
+		UserID:      userID,
+		LeadID:      leadID,
+		Title:       "Beratung",
+		Status:      models.BookingStatusConfirmed,
+		ScheduledAt: start,
+		StartTime:   start,
+		EndTime:     start.Add(time.Hour),
+		BookedAt:    start.Add(-72 * time.Hour),
+	}
+	require.NoError(t, db.Create(booking).Error)
+	return booking
+}
+
+func viewerOf(user *models.User) scopes.Viewer {
+	return scopes.Viewer{ID: user.ID, Role: user.Role}
+}
@@ -0,0 +1,84 @@
+package whatsapp
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const twilioAPIURL = "https://api.twilio.com/2010-04-01"
+
+// twilioPrefix marks WhatsApp numbers in Twilio's messaging API
+const twilioPrefix = "whatsapp:"
+
+// Twilio sends messages through the Twilio WhatsApp API. Templates are Twilio
+// content templates, referenced by their content SID.
+type Twilio struct {
+	accountSID string
+	authToken  string
+	from       string
+	baseURL    string
+	client     *http.Client
+}
+
+func NewTwilio(accountSID, authToken, from, baseURL string, client *http.Client) *Twilio {
+	if baseURL == "" {
+		baseURL = twilioAPIURL
+	}
+
+	return &Twilio{
+		accountSID: accountSID,
+		authToken:  authToken,
+		from:       from,
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		client:     client,
+	}
+}
+
+func (t *Twilio) Name() string {
+	return ProviderTwilio
+}
+
+func (t *Twilio) SendTemplate(ctx context.Context, to string, template Template) (string, error) {
+	variables := make(map[string]string, len(template.Parameters))
+	for i, value := range template.Parameters {
+		variables[strconv.Itoa(i+1)] = value
+	}
+	encoded, err := json.Marshal(variables)
+	if err != nil {
+		return "", err
+	}
+
+	return t.send(ctx, to, url.Values{
+		"ContentSid":       {template.Name},
+		"ContentVariables": {string(encoded)},
+	})
+}
+
+func (t *Twilio) SendText(ctx context.Context, to, body string) (string, error) {
+	return t.send(ctx, to, url.Values{"Body": {body}})
+}
+
+func (t *Twilio) send(ctx context.Context, to string, form url.Values) (string, error) {
+	form.Set("To", twilioPrefix+to)
+	form.Set("From", twilioPrefix+t.from)
+
+	req, err := http.NewRequest(http.MethodPost, t.baseURL+"/Accounts/"+url.PathEscape(t.accountSID)+"/Messages.json",
+		strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(t.accountSID, t.authToken)
+
+	var response struct {
+		SID string `json:"sid"`
+	}
+	if err := do(ctx, t.client, req, &response); err != nil {
+		return "", err
+	}
+	return response.SID, nil
+}
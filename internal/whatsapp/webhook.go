@@ -0,0 +1,309 @@
+package whatsapp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"elterngeld-portal/internal/activitylog"
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/internal/webhooks"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// Webhook event types
+const (
+	eventMessage = "message"
+	eventStatus  = "status"
+)
+
+// providerEvent is an inbound message or a delivery status update of a sent message
+type providerEvent struct {
+	Type      string
+	MessageID string
+	From      string // inbound messages only
+	Body      string // inbound messages only
+	Status    models.WhatsAppMessageStatus
+	Error     string
+}
+
+// statusRank orders the delivery statuses, so late status updates do not
+// overwrite a later status
+var statusRank = map[models.WhatsAppMessageStatus]int{
+	models.WhatsAppMessageStatusSent:      1,
+	models.WhatsAppMessageStatusDelivered: 2,
+	models.WhatsAppMessageStatusRead:      3,
+	models.WhatsAppMessageStatusFailed:    4,
+}
+
+// Dialog360EventInfo parses the Cloud API webhooks forwarded by 360dialog. A
+// delivery can batch several messages and statuses, so the payload hash is used
+// to detect redeliveries.
+func Dialog360EventInfo(payload []byte) (webhooks.EventInfo, error) {
+	events, err := parseDialog360(payload)
+	if err != nil {
+		return webhooks.EventInfo{}, err
+	}
+	info := webhooks.EventInfo{}
+	if len(events) > 0 {
+		info.Type = events[0].Type
+	}
+	return info, nil
+}
+
+// TwilioEventInfo parses the form encoded message and status callbacks of Twilio
+func TwilioEventInfo(payload []byte) (webhooks.EventInfo, error) {
+	event, err := parseTwilio(payload)
+	if err != nil {
+		return webhooks.EventInfo{}, err
+	}
+	id := event.MessageID
+	if event.Type == eventStatus {
+		id += ":" + string(event.Status)
+	}
+	return webhooks.EventInfo{ID: id, Type: event.Type}, nil
+}
+
+// HandleWebhook stores inbound messages on the conversation of the customer
+// and applies delivery status updates to sent messages
+func (s *Service) HandleWebhook(event *models.WebhookEvent) error {
+	var events []providerEvent
+	switch event.Provider {
+	case Provider360dialog:
+		parsed, err := parseDialog360([]byte(event.Payload))
+		if err != nil {
+			return err
+		}
+		events = parsed
+	case ProviderTwilio:
+		parsed, err := parseTwilio([]byte(event.Payload))
+		if err != nil {
+			return err
+		}
+		events = []providerEvent{parsed}
+	default:
+		return fmt.Errorf("unsupported whatsapp provider %q", event.Provider)
+	}
+
+	for _, parsed := range events {
+		var err error
+		switch parsed.Type {
+		case eventMessage:
+			err = s.receive(parsed)
+		case eventStatus:
+			err = s.updateStatus(parsed)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// receive stores a message of a customer and records it on their latest lead.
+// Opt-out keywords withdraw the consent. Messages from numbers without a
+// consent cannot be matched to a customer and are dropped.
+func (s *Service) receive(event providerEvent) error {
+	phone, err := NormalizePhone(event.From)
+	if err != nil {
+		return fmt.Errorf("whatsapp message from invalid number: %w", err)
+	}
+
+	var existing int64
+	if err := s.db.Model(&models.WhatsAppMessage{}).Where("provider_message_id = ?", event.MessageID).Count(&existing).Error; err != nil {
+		return err
+	}
+	if existing > 0 {
+		return nil
+	}
+
+	var consent models.WhatsAppConsent
+	if err := s.db.Where("phone = ?", phone).Order("updated_at DESC").First(&consent).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			s.logger.Info("Dropped whatsapp message from unknown number", zap.String("provider_message_id", event.MessageID))
+			return nil
+		}
+		return err
+	}
+
+	var leadID *uuid.UUID
+	var lead models.Lead
+	err = s.db.Where("user_id = ?", consent.UserID).Order("created_at DESC").First(&lead).Error
+	switch {
+	case err == nil:
+		leadID = &lead.ID
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		return err
+	}
+
+	now := s.now()
+	message := &models.WhatsAppMessage{
+		UserID:            consent.UserID,
+		LeadID:            leadID,
+		Direction:         models.WhatsAppDirectionInbound,
+		Phone:             phone,
+		Body:              event.Body,
+		Status:            models.WhatsAppMessageStatusReceived,
+		ProviderMessageID: event.MessageID,
+	}
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(message).Error; err != nil {
+			return err
+		}
+		if optOutKeywords[strings.ToUpper(strings.TrimSpace(event.Body))] && consent.OptedIn {
+			if err := tx.Model(&consent).Updates(map[string]interface{}{
+				"opted_in":     false,
+				"source":       models.WhatsAppConsentSourceWhatsApp,
+				"opted_out_at": now,
+			}).Error; err != nil {
+				return err
+			}
+		}
+		if leadID == nil {
+			return nil
+		}
+		return s.activities.Record(tx, activitylog.WhatsAppReceived{
+			LeadID:    *leadID,
+			MessageID: message.ID,
+			Body:      event.Body,
+		})
+	})
+}
+
+// updateStatus applies a delivery status to a sent message
+func (s *Service) updateStatus(event providerEvent) error {
+	var message models.WhatsAppMessage
+	err := s.db.Where("provider_message_id = ? AND direction = ?", event.MessageID, models.WhatsAppDirectionOutbound).
+		First(&message).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if statusRank[event.Status] <= statusRank[message.Status] {
+		return nil
+	}
+
+	return s.db.Model(&message).Updates(map[string]interface{}{
+		"status": event.Status,
+		"error":  event.Error,
+	}).Error
+}
+
+// providerStatus maps the delivery statuses of both providers; Twilio's
+// intermediate statuses like queued are ignored
+func providerStatus(status string) (models.WhatsAppMessageStatus, bool) {
+	switch status {
+	case "sent":
+		return models.WhatsAppMessageStatusSent, true
+	case "delivered":
+		return models.WhatsAppMessageStatusDelivered, true
+	case "read":
+		return models.WhatsAppMessageStatusRead, true
+	case "failed", "undelivered":
+		return models.WhatsAppMessageStatusFailed, true
+	default:
+		return "", false
+	}
+}
+
+func parseDialog360(payload []byte) ([]providerEvent, error) {
+	var body struct {
+		Entry []struct {
+			Changes []struct {
+				Value struct {
+					Messages []struct {
+						ID   string `json:"id"`
+						From string `json:"from"`
+						Type string `json:"type"`
+						Text struct {
+							Body string `json:"body"`
+						} `json:"text"`
+						Button struct {
+							Text string `json:"text"`
+						} `json:"button"`
+					} `json:"messages"`
+					Statuses []struct {
+						ID     string `json:"id"`
+						Status string `json:"status"`
+						Errors []struct {
+							Title string `json:"title"`
+						} `json:"errors"`
+					} `json:"statuses"`
+				} `json:"value"`
+			} `json:"changes"`
+		} `json:"entry"`
+	}
+	if err := json.Unmarshal(payload, &body); err != nil {
+		return nil, err
+	}
+	if len(body.Entry) == 0 {
+		return nil, errors.New("360dialog webhook without entries")
+	}
+
+	var events []providerEvent
+	for _, entry := range body.Entry {
+		for _, change := range entry.Changes {
+			for _, message := range change.Value.Messages {
+				text := message.Text.Body
+				if message.Type == "button" {
+					text = message.Button.Text
+				} else if message.Type != "text" {
+					text = fmt.Sprintf("[%s]", message.Type)
+				}
+				events = append(events, providerEvent{
+					Type:      eventMessage,
+					MessageID: message.ID,
+					From:      "+" + strings.TrimPrefix(message.From, "+"),
+					Body:      text,
+				})
+			}
+			for _, update := range change.Value.Statuses {
+				status, ok := providerStatus(update.Status)
+				if !ok {
+					continue
+				}
+				event := providerEvent{Type: eventStatus, MessageID: update.ID, Status: status}
+				if len(update.Errors) > 0 {
+					event.Error = update.Errors[0].Title
+				}
+				events = append(events, event)
+			}
+		}
+	}
+	return events, nil
+}
+
+func parseTwilio(payload []byte) (providerEvent, error) {
+	values, err := url.ParseQuery(string(payload))
+	if err != nil {
+		return providerEvent{}, err
+	}
+	id := values.Get("MessageSid")
+	if id == "" {
+		return providerEvent{}, errors.New("twilio webhook without MessageSid")
+	}
+
+	// Status callbacks carry the status of a sent message, inbound messages have "received"
+	if status := values.Get("MessageStatus"); status != "" && status != "received" {
+		event := providerEvent{Type: eventStatus, MessageID: id}
+		if parsed, ok := providerStatus(status); ok {
+			event.Status = parsed
+		}
+		event.Error = values.Get("ErrorMessage")
+		return event, nil
+	}
+
+	return providerEvent{
+		Type:      eventMessage,
+		MessageID: id,
+		From:      strings.TrimPrefix(values.Get("From"), twilioPrefix),
+		Body:      values.Get("Body"),
+	}, nil
+}
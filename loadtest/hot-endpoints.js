@@ -0,0 +1,132 @@
+// Load test of the hot endpoints with their performance budget.
+//
+// Runs against an instance seeded with `main.go -seed-loadtest`, see
+// `make load-test`. The thresholds below are the budget: k6 exits with a
+// non-zero status when one of them is exceeded, which fails the
+// performance-budget CI job.
+//
+// The instance needs a rate limit above the request rates below, e.g.
+// RATE_LIMIT_REQUESTS=100000, and STRIPE_API_URL pointing at stripe-mock so
+// checkouts do not reach Stripe.
+//
+// Environment:
+//   BASE_URL           instance to test, default http://localhost:8080
+//   DURATION           length of each scenario, default 1m
+//   LOADTEST_PASSWORD  password of the seeded accounts
+
+import http from 'k6/http';
+import { check, fail } from 'k6';
+
+const BASE_URL = (__ENV.BASE_URL || 'http://localhost:8080').replace(/\/$/, '');
+const API = `${BASE_URL}/api/v1`;
+const DURATION = __ENV.DURATION || '1m';
+const PASSWORD = __ENV.LOADTEST_PASSWORD || 'Loadtest-2024';
+
+const CUSTOMER_EMAIL = 'loadtest.customer@example.com';
+const BERATER_EMAIL = 'loadtest.berater@example.com';
+const PACKAGE_NAME = 'Lasttest Beratung';
+
+function scenario(exec, rate) {
+  return {
+    executor: 'constant-arrival-rate',
+    exec,
+    rate,
+    timeUnit: '1s',
+    duration: DURATION,
+    preAllocatedVUs: Math.max(5, rate),
+    maxVUs: rate * 4,
+  };
+}
+
+export const options = {
+  scenarios: {
+    availability: scenario('availability', 50),
+    lead_list: scenario('leadList', 20),
+    checkout: scenario('checkout', 5),
+  },
+  thresholds: {
+    // Public timeslot availability, shown on every booking page
+    'http_req_duration{scenario:availability}': ['p(95)<300', 'p(99)<800'],
+    'http_req_failed{scenario:availability}': ['rate<0.01'],
+    // Lead list of a Berater with a thousand leads
+    'http_req_duration{scenario:lead_list}': ['p(95)<400', 'p(99)<1000'],
+    'http_req_failed{scenario:lead_list}': ['rate<0.01'],
+    // Checkout including the Stripe session request
+    'http_req_duration{scenario:checkout}': ['p(95)<800', 'p(99)<2000'],
+    'http_req_failed{scenario:checkout}': ['rate<0.01'],
+    checks: ['rate>0.99'],
+  },
+};
+
+function auth(token) {
+  return { headers: { Authorization: `Bearer ${token}`, 'Content-Type': 'application/json' } };
+}
+
+function login(email) {
+  const res = http.post(`${API}/auth/login`, JSON.stringify({ email, password: PASSWORD }), {
+    headers: { 'Content-Type': 'application/json' },
+  });
+  if (res.status !== 200) {
+    fail(`login of ${email} failed with ${res.status}: ${res.body}`);
+  }
+  return res.json('access_token');
+}
+
+// setup signs in the seeded accounts and books a timeslot for the checkout
+// scenario; it runs once and is not part of the budget
+export function setup() {
+  const customerToken = login(CUSTOMER_EMAIL);
+  const beraterToken = login(BERATER_EMAIL);
+
+  const packages = http.get(`${API}/packages`).json('packages') || [];
+  const servicePackage = packages.find((p) => p.name === PACKAGE_NAME);
+  if (!servicePackage) {
+    fail(`package "${PACKAGE_NAME}" not found, seed the instance with -seed-loadtest`);
+  }
+
+  const available = http.get(`${API}/timeslots/available?package_id=${servicePackage.id}&days=30`);
+  const timeslots = available.json('timeslots') || [];
+  if (timeslots.length === 0) {
+    fail('no timeslots available, seed the instance again');
+  }
+
+  const booking = http.post(
+    `${API}/bookings`,
+    JSON.stringify({ package_id: servicePackage.id, timeslot_id: timeslots[0].id }),
+    auth(customerToken),
+  );
+  if (booking.status !== 201) {
+    fail(`booking failed with ${booking.status}: ${booking.body}`);
+  }
+
+  return {
+    customerToken,
+    beraterToken,
+    packageId: servicePackage.id,
+    bookingId: booking.json('id'),
+  };
+}
+
+export function availability(data) {
+  const res = http.get(`${API}/timeslots/available?package_id=${data.packageId}&days=14`, {
+    tags: { name: 'GET /timeslots/available' },
+  });
+  check(res, { 'availability 200': (r) => r.status === 200 });
+}
+
+export function leadList(data) {
+  const page = Math.floor(Math.random() * 10) + 1;
+  const res = http.get(`${API}/leads?page=${page}&limit=20`, {
+    headers: auth(data.beraterToken).headers,
+    tags: { name: 'GET /leads' },
+  });
+  check(res, { 'lead list 200': (r) => r.status === 200 });
+}
+
+export function checkout(data) {
+  const res = http.post(`${API}/payments/checkout`, JSON.stringify({ booking_id: data.bookingId }), {
+    headers: auth(data.customerToken).headers,
+    tags: { name: 'POST /payments/checkout' },
+  });
+  check(res, { 'checkout 200': (r) => r.status === 200 });
+}
@@ -0,0 +1,64 @@
+-- Inbound email processing
+-- Stores received support mailbox emails and their attachments
+
+-- Inbound emails
+CREATE TABLE IF NOT EXISTS inbound_emails (
+    id CHAR(36) PRIMARY KEY,
+    message_id VARCHAR(255) NOT NULL UNIQUE,
+    in_reply_to VARCHAR(255),
+
+    from_email VARCHAR(255) NOT NULL,
+    from_name VARCHAR(255),
+    to_email VARCHAR(255),
+    subject VARCHAR(255) NOT NULL,
+    body TEXT,
+    is_html BOOLEAN NOT NULL DEFAULT FALSE,
+
+    -- Matching result
+    status VARCHAR(50) NOT NULL DEFAULT 'unmatched',
+    matched_by VARCHAR(50),
+    reference VARCHAR(100),
+    lead_id CHAR(36),
+    contact_form_id CHAR(36),
+    email_message_id CHAR(36),
+    error_message TEXT,
+
+    received_at DATETIME NOT NULL,
+    processed_at DATETIME,
+
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL,
+    deleted_at DATETIME,
+
+    FOREIGN KEY (lead_id) REFERENCES leads(id) ON DELETE SET NULL,
+    FOREIGN KEY (contact_form_id) REFERENCES contact_forms(id) ON DELETE SET NULL,
+    FOREIGN KEY (email_message_id) REFERENCES email_messages(id) ON DELETE SET NULL,
+    INDEX idx_inbound_emails_in_reply_to (in_reply_to),
+    INDEX idx_inbound_emails_from_email (from_email),
+    INDEX idx_inbound_emails_status (status),
+    INDEX idx_inbound_emails_lead_id (lead_id),
+    INDEX idx_inbound_emails_contact_form_id (contact_form_id),
+    INDEX idx_inbound_emails_received_at (received_at),
+    INDEX idx_inbound_emails_deleted_at (deleted_at)
+);
+
+-- Email attachments
+CREATE TABLE IF NOT EXISTS email_attachments (
+    id CHAR(36) PRIMARY KEY,
+    inbound_email_id CHAR(36) NOT NULL,
+    email_message_id CHAR(36),
+
+    file_name VARCHAR(255) NOT NULL,
+    original_name VARCHAR(255) NOT NULL,
+    file_path VARCHAR(500) NOT NULL,
+    file_size BIGINT NOT NULL,
+    content_type VARCHAR(100),
+
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL,
+
+    FOREIGN KEY (inbound_email_id) REFERENCES inbound_emails(id) ON DELETE CASCADE,
+    FOREIGN KEY (email_message_id) REFERENCES email_messages(id) ON DELETE SET NULL,
+    INDEX idx_email_attachments_inbound_email_id (inbound_email_id),
+    INDEX idx_email_attachments_email_message_id (email_message_id)
+);
@@ -0,0 +1,5 @@
+-- Berater profile fields used to personalize outgoing customer emails
+
+ALTER TABLE users ADD COLUMN email_signature TEXT;
+ALTER TABLE users ADD COLUMN photo_url VARCHAR(500);
+ALTER TABLE users ADD COLUMN calendar_url VARCHAR(500);
@@ -0,0 +1,50 @@
+-- Tracked short links for email call-to-actions
+-- Clicks are recorded per recipient and feed into the lead score
+
+-- Short links
+CREATE TABLE IF NOT EXISTS short_links (
+    id CHAR(36) PRIMARY KEY,
+    code VARCHAR(32) NOT NULL UNIQUE,
+    target_url TEXT NOT NULL,
+    purpose VARCHAR(50) NOT NULL DEFAULT 'other',
+
+    -- Recipient information
+    recipient_email VARCHAR(255),
+    user_id CHAR(36),
+    lead_id CHAR(36),
+
+    -- Click tracking
+    click_count INTEGER DEFAULT 0,
+    first_clicked_at DATETIME,
+    last_clicked_at DATETIME,
+
+    expires_at DATETIME,
+
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL,
+    deleted_at DATETIME,
+
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE SET NULL,
+    FOREIGN KEY (lead_id) REFERENCES leads(id) ON DELETE SET NULL,
+    INDEX idx_short_links_purpose (purpose),
+    INDEX idx_short_links_recipient_email (recipient_email),
+    INDEX idx_short_links_user_id (user_id),
+    INDEX idx_short_links_lead_id (lead_id),
+    INDEX idx_short_links_deleted_at (deleted_at)
+);
+
+-- Short link clicks
+CREATE TABLE IF NOT EXISTS short_link_clicks (
+    id CHAR(36) PRIMARY KEY,
+    short_link_id CHAR(36) NOT NULL,
+
+    ip_address VARCHAR(45),
+    user_agent TEXT,
+    referer VARCHAR(500),
+
+    clicked_at DATETIME NOT NULL,
+
+    FOREIGN KEY (short_link_id) REFERENCES short_links(id) ON DELETE CASCADE,
+    INDEX idx_short_link_clicks_short_link_id (short_link_id),
+    INDEX idx_short_link_clicks_clicked_at (clicked_at)
+);
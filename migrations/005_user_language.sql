@@ -0,0 +1,3 @@
+-- Preferred language for localized API responses and emails
+
+ALTER TABLE users ADD COLUMN language VARCHAR(5) NOT NULL DEFAULT 'de';
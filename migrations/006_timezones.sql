@@ -0,0 +1,5 @@
+-- Explicit timezones for users and timeslots. Times are stored in UTC; the
+-- timezone records the wall clock a Berater planned a timeslot in.
+
+ALTER TABLE users ADD COLUMN timezone VARCHAR(64) NOT NULL DEFAULT 'Europe/Berlin';
+ALTER TABLE timeslots ADD COLUMN timezone VARCHAR(64) NOT NULL DEFAULT 'Europe/Berlin';
@@ -0,0 +1,25 @@
+-- Public holidays per Bundesland
+-- Statutory holidays are computed in code; admins can add or remove holidays here
+
+ALTER TABLE users ADD COLUMN bundesland VARCHAR(2);
+
+-- Holiday overrides (an empty bundesland applies to all federal states)
+CREATE TABLE IF NOT EXISTS holiday_overrides (
+    id CHAR(36) PRIMARY KEY,
+    date VARCHAR(10) NOT NULL,
+    bundesland VARCHAR(2),
+    name VARCHAR(255),
+    is_holiday BOOLEAN NOT NULL,
+    note TEXT,
+    created_by CHAR(36) NOT NULL,
+
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL,
+    deleted_at DATETIME,
+
+    FOREIGN KEY (created_by) REFERENCES users(id) ON DELETE CASCADE,
+    INDEX idx_holiday_overrides_date (date),
+    INDEX idx_holiday_overrides_bundesland (bundesland),
+    INDEX idx_holiday_overrides_created_by (created_by),
+    INDEX idx_holiday_overrides_deleted_at (deleted_at)
+);
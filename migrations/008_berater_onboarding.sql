@@ -0,0 +1,54 @@
+-- Invitation based Berater onboarding
+-- Existing Beraters are treated as approved; invited Beraters start with profile_pending
+
+ALTER TABLE users ADD COLUMN onboarding_status VARCHAR(20) DEFAULT 'approved';
+ALTER TABLE users ADD COLUMN specializations TEXT;
+ALTER TABLE users ADD COLUMN service_bundeslaender TEXT;
+ALTER TABLE users ADD COLUMN working_languages TEXT;
+ALTER TABLE users ADD COLUMN approved_at DATETIME;
+ALTER TABLE users ADD COLUMN approved_by CHAR(36);
+
+-- Berater invitations (only the SHA-256 hash of the token is stored)
+CREATE TABLE IF NOT EXISTS berater_invitations (
+    id CHAR(36) PRIMARY KEY,
+    email VARCHAR(255) NOT NULL,
+    first_name VARCHAR(255) NOT NULL,
+    last_name VARCHAR(255) NOT NULL,
+    role VARCHAR(20) NOT NULL DEFAULT 'berater',
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    expires_at DATETIME NOT NULL,
+    invited_by CHAR(36) NOT NULL,
+    accepted_at DATETIME,
+    revoked_at DATETIME,
+    user_id CHAR(36),
+
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL,
+    deleted_at DATETIME,
+
+    FOREIGN KEY (invited_by) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE SET NULL,
+    INDEX idx_berater_invitations_email (email),
+    INDEX idx_berater_invitations_invited_by (invited_by),
+    INDEX idx_berater_invitations_user_id (user_id),
+    INDEX idx_berater_invitations_deleted_at (deleted_at)
+);
+
+-- Weekly availability windows timeslots are generated from
+CREATE TABLE IF NOT EXISTS availability_rules (
+    id CHAR(36) PRIMARY KEY,
+    berater_id CHAR(36) NOT NULL,
+    weekday INTEGER NOT NULL,
+    start_time VARCHAR(5) NOT NULL,
+    end_time VARCHAR(5) NOT NULL,
+    slot_duration INTEGER NOT NULL DEFAULT 60,
+    is_online BOOLEAN NOT NULL,
+
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL,
+    deleted_at DATETIME,
+
+    FOREIGN KEY (berater_id) REFERENCES users(id) ON DELETE CASCADE,
+    INDEX idx_availability_rules_berater_id (berater_id),
+    INDEX idx_availability_rules_deleted_at (deleted_at)
+);
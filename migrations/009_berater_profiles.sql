@@ -0,0 +1,7 @@
+-- Public Berater profiles and customer ratings of completed consultations
+
+ALTER TABLE users ADD COLUMN bio TEXT;
+
+ALTER TABLE bookings ADD COLUMN rating INTEGER;
+ALTER TABLE bookings ADD COLUMN rating_comment TEXT;
+ALTER TABLE bookings ADD COLUMN rated_at DATETIME;
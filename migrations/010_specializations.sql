@@ -0,0 +1,3 @@
+-- Specialization topics of leads, matched against the specializations of Beraters
+
+ALTER TABLE leads ADD COLUMN topics TEXT;
@@ -0,0 +1,4 @@
+-- Sensitive documents (e.g. Bescheide) are watermarked on download; rendered thumbnails are cached
+
+ALTER TABLE documents ADD COLUMN is_sensitive BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE documents ADD COLUMN thumbnail_path VARCHAR(255);
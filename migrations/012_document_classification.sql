@@ -0,0 +1,10 @@
+-- Text recognition and automatic classification of uploaded documents
+
+ALTER TABLE documents ADD COLUMN ocr_status VARCHAR(20);
+ALTER TABLE documents ADD COLUMN ocr_text TEXT;
+ALTER TABLE documents ADD COLUMN classified_type VARCHAR(50);
+ALTER TABLE documents ADD COLUMN classification_confidence DOUBLE PRECISION DEFAULT 0;
+ALTER TABLE documents ADD COLUMN todo_id CHAR(36);
+
+CREATE INDEX idx_documents_ocr_status ON documents(ocr_status);
+CREATE INDEX idx_documents_todo_id ON documents(todo_id);
@@ -0,0 +1,6 @@
+-- Sensitive personal fields are stored encrypted (enc:v1:...) and need text columns.
+-- users.address and bookings.customer_address are TEXT already. SQLite accepts text
+-- in DATETIME columns, so the type change is only needed on PostgreSQL.
+-- Existing values stay readable as plaintext until `server -rotate-keys` encrypts them.
+
+ALTER TABLE users ALTER COLUMN date_of_birth TYPE TEXT USING date_of_birth::TEXT;
@@ -0,0 +1,7 @@
+-- Offboarding of Beraters who leave; deactivated users can no longer log in
+
+ALTER TABLE users ADD COLUMN deactivated_at DATETIME;
+ALTER TABLE users ADD COLUMN deactivated_by CHAR(36);
+ALTER TABLE users ADD COLUMN deactivation_reason TEXT;
+
+CREATE INDEX idx_users_deactivated_at ON users(deactivated_at);
@@ -0,0 +1,7 @@
+-- Email verification tokens are stored as SHA-256 hashes and expire; unverified
+-- self-registered accounts are removed after PENDING_ACCOUNT_TTL
+
+-- Earlier plain text tokens can no longer be matched, users request a new link
+UPDATE email_verifications SET deleted_at = CURRENT_TIMESTAMP WHERE is_used = FALSE AND deleted_at IS NULL;
+
+CREATE INDEX idx_users_email_verified_created_at ON users(email_verified, created_at);
@@ -0,0 +1,25 @@
+-- Verified inbound webhooks of third-party integrations (Stripe, calendar and
+-- email providers). Raw payloads are kept for asynchronous processing and replay.
+
+CREATE TABLE IF NOT EXISTS webhook_events (
+    id CHAR(36) PRIMARY KEY,
+    provider VARCHAR(50) NOT NULL,
+    event_id VARCHAR(255) NOT NULL,
+    event_type VARCHAR(100),
+    payload TEXT NOT NULL,
+
+    status VARCHAR(20) NOT NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    last_error TEXT,
+    next_attempt_at DATETIME,
+    processed_at DATETIME,
+
+    received_at DATETIME NOT NULL,
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL
+);
+
+CREATE UNIQUE INDEX idx_webhook_events_provider_event ON webhook_events(provider, event_id);
+CREATE INDEX idx_webhook_events_event_type ON webhook_events(event_type);
+CREATE INDEX idx_webhook_events_status ON webhook_events(status);
+CREATE INDEX idx_webhook_events_next_attempt_at ON webhook_events(next_attempt_at);
@@ -0,0 +1,22 @@
+-- Personal API keys for no-code integrations such as Zapier and Make. Only a
+-- SHA-256 hash of the key is stored; scopes are limited by the owner's permissions.
+
+CREATE TABLE IF NOT EXISTS api_keys (
+    id CHAR(36) PRIMARY KEY,
+    user_id CHAR(36) NOT NULL REFERENCES users(id) ON UPDATE CASCADE ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    key_prefix VARCHAR(16) NOT NULL,
+    key_hash VARCHAR(64) NOT NULL,
+    scopes TEXT NOT NULL,
+
+    last_used_at DATETIME,
+    expires_at DATETIME,
+    revoked_at DATETIME,
+
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL
+);
+
+CREATE INDEX idx_api_keys_user_id ON api_keys(user_id);
+CREATE UNIQUE INDEX idx_api_keys_key_hash ON api_keys(key_hash);
+CREATE INDEX idx_api_keys_revoked_at ON api_keys(revoked_at);
@@ -0,0 +1,36 @@
+-- Slack and Microsoft Teams notifications. Each channel is an incoming webhook
+-- (stored encrypted) that receives the events matching its routing rules.
+
+CREATE TABLE IF NOT EXISTS chat_channels (
+    id CHAR(36) PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    provider VARCHAR(20) NOT NULL,
+    webhook_url TEXT NOT NULL,
+    is_active BOOLEAN NOT NULL,
+
+    last_delivered_at DATETIME,
+    last_error TEXT,
+
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL,
+    deleted_at DATETIME
+);
+
+CREATE INDEX idx_chat_channels_deleted_at ON chat_channels(deleted_at);
+
+CREATE TABLE IF NOT EXISTS chat_routing_rules (
+    id CHAR(36) PRIMARY KEY,
+    channel_id CHAR(36) NOT NULL REFERENCES chat_channels(id) ON UPDATE CASCADE ON DELETE CASCADE,
+    event VARCHAR(50) NOT NULL,
+    min_priority VARCHAR(20),
+    berater_id CHAR(36),
+
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL
+);
+
+CREATE INDEX idx_chat_routing_rules_channel_id ON chat_routing_rules(channel_id);
+CREATE INDEX idx_chat_routing_rules_event ON chat_routing_rules(event);
+
+-- New leads without a first contact are posted once when the response SLA is breached
+ALTER TABLE leads ADD COLUMN sla_breach_notified_at DATETIME;
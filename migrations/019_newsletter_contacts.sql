@@ -0,0 +1,21 @@
+-- Users with marketing consent synced to the newsletter provider (Mailchimp or
+-- Brevo). Segments are the tags on the provider's contact.
+
+CREATE TABLE IF NOT EXISTS newsletter_contacts (
+    id CHAR(36) PRIMARY KEY,
+    user_id CHAR(36) NOT NULL REFERENCES users(id) ON UPDATE CASCADE ON DELETE CASCADE,
+    email VARCHAR(255) NOT NULL,
+    provider VARCHAR(20) NOT NULL,
+    status VARCHAR(20) NOT NULL,
+    segments TEXT,
+
+    synced_at DATETIME,
+    unsubscribed_at DATETIME,
+    last_error TEXT,
+
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL
+);
+
+CREATE UNIQUE INDEX idx_newsletter_contacts_user_id ON newsletter_contacts(user_id);
+CREATE INDEX idx_newsletter_contacts_status ON newsletter_contacts(status);
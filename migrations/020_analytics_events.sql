@@ -0,0 +1,41 @@
+-- Anonymous product analytics for the marketing funnel. Only collected with
+-- analytics consent; visitors are random IDs without IP address or account.
+
+CREATE TABLE IF NOT EXISTS analytics_sessions (
+    id CHAR(36) PRIMARY KEY,
+    visitor_id CHAR(36) NOT NULL,
+    landing_path VARCHAR(255),
+    referrer_host VARCHAR(255),
+    utm_source VARCHAR(100),
+    utm_medium VARCHAR(100),
+    utm_campaign VARCHAR(100),
+
+    event_count INTEGER NOT NULL,
+    started_at DATETIME NOT NULL,
+    last_seen_at DATETIME NOT NULL,
+
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL
+);
+
+CREATE INDEX idx_analytics_sessions_visitor_id ON analytics_sessions(visitor_id);
+CREATE INDEX idx_analytics_sessions_utm_source ON analytics_sessions(utm_source);
+CREATE INDEX idx_analytics_sessions_started_at ON analytics_sessions(started_at);
+
+CREATE TABLE IF NOT EXISTS analytics_events (
+    id CHAR(36) PRIMARY KEY,
+    session_id CHAR(36) NOT NULL REFERENCES analytics_sessions(id) ON UPDATE CASCADE ON DELETE CASCADE,
+    visitor_id CHAR(36) NOT NULL,
+    type VARCHAR(30) NOT NULL,
+    path VARCHAR(255),
+    name VARCHAR(100),
+    step VARCHAR(30),
+
+    occurred_at DATETIME NOT NULL,
+    created_at DATETIME NOT NULL
+);
+
+CREATE INDEX idx_analytics_events_session_id ON analytics_events(session_id);
+CREATE INDEX idx_analytics_events_visitor_id ON analytics_events(visitor_id);
+CREATE INDEX idx_analytics_events_type ON analytics_events(type);
+CREATE INDEX idx_analytics_events_occurred_at ON analytics_events(occurred_at);
@@ -0,0 +1,74 @@
+-- A/B tests of the package and pricing display. Visitors are assigned to a
+-- variant by a stable hash; bookings and payments of exposed users are
+-- attributed to the variant they saw.
+
+CREATE TABLE IF NOT EXISTS experiments (
+    id CHAR(36) PRIMARY KEY,
+    key VARCHAR(100) NOT NULL,
+    name VARCHAR(200) NOT NULL,
+    description TEXT,
+    status VARCHAR(20) NOT NULL,
+
+    started_at DATETIME,
+    stopped_at DATETIME,
+
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL
+);
+
+CREATE UNIQUE INDEX idx_experiments_key ON experiments(key);
+CREATE INDEX idx_experiments_status ON experiments(status);
+
+CREATE TABLE IF NOT EXISTS experiment_variants (
+    id CHAR(36) PRIMARY KEY,
+    experiment_id CHAR(36) NOT NULL REFERENCES experiments(id) ON UPDATE CASCADE ON DELETE CASCADE,
+    key VARCHAR(50) NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    weight INTEGER NOT NULL,
+    position INTEGER NOT NULL,
+    config JSONB,
+
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL
+);
+
+CREATE UNIQUE INDEX idx_experiment_variant_key ON experiment_variants(experiment_id, key);
+
+CREATE TABLE IF NOT EXISTS experiment_exposures (
+    id CHAR(36) PRIMARY KEY,
+    experiment_id CHAR(36) NOT NULL REFERENCES experiments(id) ON UPDATE CASCADE ON DELETE CASCADE,
+    variant_id CHAR(36) NOT NULL REFERENCES experiment_variants(id) ON UPDATE CASCADE ON DELETE CASCADE,
+    subject_id CHAR(36) NOT NULL,
+    user_id CHAR(36),
+
+    exposure_count INTEGER NOT NULL,
+    first_exposed_at DATETIME NOT NULL,
+    last_exposed_at DATETIME NOT NULL,
+
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL
+);
+
+CREATE UNIQUE INDEX idx_experiment_exposure_subject ON experiment_exposures(experiment_id, subject_id);
+CREATE INDEX idx_experiment_exposures_variant_id ON experiment_exposures(variant_id);
+CREATE INDEX idx_experiment_exposures_user_id ON experiment_exposures(user_id);
+
+CREATE TABLE IF NOT EXISTS experiment_conversions (
+    id CHAR(36) PRIMARY KEY,
+    experiment_id CHAR(36) NOT NULL REFERENCES experiments(id) ON UPDATE CASCADE ON DELETE CASCADE,
+    variant_id CHAR(36) NOT NULL,
+    exposure_id CHAR(36) NOT NULL,
+    user_id CHAR(36) NOT NULL,
+    kind VARCHAR(20) NOT NULL,
+    reference_id CHAR(36) NOT NULL,
+    amount DECIMAL(10,2) NOT NULL,
+    currency VARCHAR(3) NOT NULL,
+    converted_at DATETIME NOT NULL,
+
+    created_at DATETIME NOT NULL
+);
+
+CREATE UNIQUE INDEX idx_experiment_conversion_reference ON experiment_conversions(experiment_id, kind, reference_id);
+CREATE INDEX idx_experiment_conversions_variant_id ON experiment_conversions(variant_id);
+CREATE INDEX idx_experiment_conversions_exposure_id ON experiment_conversions(exposure_id);
+CREATE INDEX idx_experiment_conversions_user_id ON experiment_conversions(user_id);
@@ -0,0 +1,24 @@
+-- Costs of marketing channels (utm_source) and campaigns (utm_campaign) for
+-- the ROI report. Spend without a campaign applies to the whole channel.
+
+CREATE TABLE IF NOT EXISTS marketing_spends (
+    id CHAR(36) PRIMARY KEY,
+    channel VARCHAR(100) NOT NULL,
+    campaign VARCHAR(200),
+    period_start VARCHAR(10) NOT NULL,
+    period_end VARCHAR(10) NOT NULL,
+    cost DECIMAL(10,2) NOT NULL,
+    note TEXT,
+    created_by CHAR(36) NOT NULL REFERENCES users(id) ON UPDATE CASCADE ON DELETE CASCADE,
+
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL,
+    deleted_at DATETIME
+);
+
+CREATE INDEX idx_marketing_spends_channel ON marketing_spends(channel);
+CREATE INDEX idx_marketing_spends_campaign ON marketing_spends(campaign);
+CREATE INDEX idx_marketing_spends_period_start ON marketing_spends(period_start);
+CREATE INDEX idx_marketing_spends_period_end ON marketing_spends(period_end);
+CREATE INDEX idx_marketing_spends_created_by ON marketing_spends(created_by);
+CREATE INDEX idx_marketing_spends_deleted_at ON marketing_spends(deleted_at);
@@ -0,0 +1,6 @@
+-- Opt-in daily digest of the activity feed (new leads, large payments,
+-- cancelled bookings and SLA breaches) for Beraters and admins.
+
+ALTER TABLE notification_preferences ADD COLUMN email_daily_digest BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE notification_preferences ADD COLUMN digest_sent_at DATETIME;
+
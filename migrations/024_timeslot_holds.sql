@@ -0,0 +1,21 @@
+-- Timeslot holds reserve a place for a pending booking during checkout. Unpaid
+-- bookings are cancelled when their hold expires, freeing the timeslot again.
+
+CREATE TABLE IF NOT EXISTS timeslot_holds (
+    id CHAR(36) PRIMARY KEY,
+    timeslot_id CHAR(36) NOT NULL,
+    booking_id CHAR(36) NOT NULL,
+    user_id CHAR(36) NOT NULL,
+
+    expires_at DATETIME NOT NULL,
+    released_at DATETIME,
+    release_reason VARCHAR(20),
+
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL
+);
+
+CREATE INDEX idx_timeslot_holds_timeslot_id ON timeslot_holds(timeslot_id);
+CREATE UNIQUE INDEX idx_timeslot_holds_booking_id ON timeslot_holds(booking_id);
+CREATE INDEX idx_timeslot_holds_expires_at ON timeslot_holds(expires_at);
+CREATE INDEX idx_timeslot_holds_released_at ON timeslot_holds(released_at);
@@ -0,0 +1,9 @@
+-- Automatic no-show detection: confirmed bookings not completed within the
+-- grace period after their end are flagged, confirmed or corrected by the
+-- Berater, and followed up with a rescheduling offer.
+
+ALTER TABLE bookings ADD COLUMN no_show_flagged_at DATETIME;
+ALTER TABLE bookings ADD COLUMN no_show_confirmed_at DATETIME;
+ALTER TABLE bookings ADD COLUMN follow_up_sent_at DATETIME;
+
+CREATE INDEX idx_bookings_no_show_flagged_at ON bookings(no_show_flagged_at);
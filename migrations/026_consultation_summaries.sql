@@ -0,0 +1,30 @@
+-- Consultation summaries written by the Berater after a booking: topics
+-- covered, recommendations and next steps, optionally shared with the customer.
+
+CREATE TABLE IF NOT EXISTS consultation_summaries (
+    id CHAR(36) PRIMARY KEY,
+    booking_id CHAR(36) NOT NULL REFERENCES bookings(id) ON UPDATE CASCADE ON DELETE CASCADE,
+    lead_id CHAR(36),
+    user_id CHAR(36) NOT NULL,
+    author_id CHAR(36) NOT NULL REFERENCES users(id) ON UPDATE CASCADE ON DELETE CASCADE,
+
+    shared_at DATETIME,
+
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL
+);
+
+CREATE UNIQUE INDEX idx_consultation_summaries_booking_id ON consultation_summaries(booking_id);
+CREATE INDEX idx_consultation_summaries_lead_id ON consultation_summaries(lead_id);
+CREATE INDEX idx_consultation_summaries_user_id ON consultation_summaries(user_id);
+CREATE INDEX idx_consultation_summaries_author_id ON consultation_summaries(author_id);
+
+CREATE TABLE IF NOT EXISTS consultation_summary_items (
+    id CHAR(36) PRIMARY KEY,
+    summary_id CHAR(36) NOT NULL REFERENCES consultation_summaries(id) ON UPDATE CASCADE ON DELETE CASCADE,
+    section VARCHAR(20) NOT NULL,
+    position INTEGER NOT NULL,
+    text TEXT NOT NULL
+);
+
+CREATE INDEX idx_consultation_summary_items_summary_id ON consultation_summary_items(summary_id);
@@ -0,0 +1,6 @@
+-- Per-Berater booking limits: free minutes required between two appointments
+-- and the maximum number of bookings per day (0 = no limit). Timeslots that
+-- break a limit are no longer offered and cannot be booked.
+
+ALTER TABLE users ADD COLUMN booking_buffer_minutes INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE users ADD COLUMN max_bookings_per_day INTEGER NOT NULL DEFAULT 0;
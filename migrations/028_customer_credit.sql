@@ -0,0 +1,47 @@
+-- Customer account credit: a ledger of refunds to credit, goodwill credits,
+-- voucher redemptions and credit applied at checkout. The balance is the sum
+-- of all entries; only the remainder of a booking is charged via Stripe.
+
+CREATE TABLE IF NOT EXISTS credit_entries (
+    id CHAR(36) PRIMARY KEY,
+    user_id CHAR(36) NOT NULL REFERENCES users(id) ON UPDATE CASCADE ON DELETE CASCADE,
+    type VARCHAR(20) NOT NULL,
+    amount REAL NOT NULL,
+    currency VARCHAR(3) NOT NULL DEFAULT 'EUR',
+    description TEXT,
+
+    booking_id CHAR(36),
+    payment_id CHAR(36),
+    voucher_id CHAR(36),
+    created_by CHAR(36),
+
+    created_at DATETIME NOT NULL
+);
+
+CREATE INDEX idx_credit_entries_user_id ON credit_entries(user_id);
+CREATE INDEX idx_credit_entries_booking_id ON credit_entries(booking_id);
+CREATE INDEX idx_credit_entries_payment_id ON credit_entries(payment_id);
+CREATE INDEX idx_credit_entries_voucher_id ON credit_entries(voucher_id);
+CREATE INDEX idx_credit_entries_created_at ON credit_entries(created_at);
+
+CREATE TABLE IF NOT EXISTS vouchers (
+    id CHAR(36) PRIMARY KEY,
+    code VARCHAR(32) NOT NULL,
+    amount REAL NOT NULL,
+    currency VARCHAR(3) NOT NULL DEFAULT 'EUR',
+    description TEXT,
+    max_redemptions INTEGER NOT NULL DEFAULT 0,
+    redemption_count INTEGER NOT NULL DEFAULT 0,
+    expires_at DATETIME,
+    created_by CHAR(36) NOT NULL REFERENCES users(id) ON UPDATE CASCADE ON DELETE CASCADE,
+
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL,
+    deleted_at DATETIME
+);
+
+CREATE UNIQUE INDEX idx_vouchers_code ON vouchers(code);
+CREATE INDEX idx_vouchers_deleted_at ON vouchers(deleted_at);
+
+-- Credit applied to a payment; amount is what was charged via Stripe
+ALTER TABLE payments ADD COLUMN credit_amount REAL NOT NULL DEFAULT 0;
@@ -0,0 +1,5 @@
+-- Booking reassignment: Beraters keep a personal online meeting room. Online
+-- bookings moved to another Berater (vacation, sickness) get the new
+-- Berater's room as meeting link.
+
+ALTER TABLE users ADD COLUMN meeting_url VARCHAR(255);
@@ -0,0 +1,29 @@
+-- Lead aging: admin-defined rules per open lead status mark leads without
+-- activity as inactive, queue a sequence of win-back emails to the customer
+-- and finally close the lead as lost (storniert) with a reason.
+
+CREATE TABLE IF NOT EXISTS lead_aging_rules (
+    id CHAR(36) PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    status VARCHAR(30) NOT NULL,
+    inactive_after_days INTEGER NOT NULL,
+    win_back_emails INTEGER NOT NULL,
+    win_back_interval_days INTEGER NOT NULL,
+    close_after_days INTEGER NOT NULL,
+    lost_reason TEXT,
+    is_active BOOLEAN NOT NULL,
+
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL,
+    deleted_at DATETIME
+);
+
+CREATE INDEX idx_lead_aging_rules_status ON lead_aging_rules(status);
+CREATE INDEX idx_lead_aging_rules_deleted_at ON lead_aging_rules(deleted_at);
+
+ALTER TABLE leads ADD COLUMN inactive_since DATETIME;
+ALTER TABLE leads ADD COLUMN win_back_emails_sent INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE leads ADD COLUMN last_win_back_at DATETIME;
+ALTER TABLE leads ADD COLUMN lost_reason TEXT;
+
+CREATE INDEX idx_leads_inactive_since ON leads(inactive_since);
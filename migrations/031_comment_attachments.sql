@@ -0,0 +1,15 @@
+-- Comment attachments: documents of a lead attached to a comment, either
+-- referenced by ID or uploaded together with the comment. Lists of comments
+-- return the attachments with short-lived signed links.
+
+CREATE TABLE IF NOT EXISTS comment_attachments (
+    id CHAR(36) PRIMARY KEY,
+    comment_id CHAR(36) NOT NULL REFERENCES comments(id) ON UPDATE CASCADE ON DELETE CASCADE,
+    document_id CHAR(36) NOT NULL REFERENCES documents(id) ON UPDATE CASCADE ON DELETE CASCADE,
+    position INTEGER NOT NULL,
+
+    created_at DATETIME NOT NULL
+);
+
+CREATE UNIQUE INDEX idx_comment_attachments_comment_document ON comment_attachments(comment_id, document_id);
+CREATE INDEX idx_comment_attachments_document_id ON comment_attachments(document_id);
@@ -0,0 +1,26 @@
+-- Comment editing and reactions: edited comments keep their earlier
+-- versions as revisions, users react to comments with a fixed set of emoji.
+
+ALTER TABLE comments ADD COLUMN edited_at DATETIME;
+
+CREATE TABLE IF NOT EXISTS comment_revisions (
+    id CHAR(36) PRIMARY KEY,
+    comment_id CHAR(36) NOT NULL REFERENCES comments(id) ON UPDATE CASCADE ON DELETE CASCADE,
+    content TEXT NOT NULL,
+    edited_by_id CHAR(36) NOT NULL REFERENCES users(id),
+
+    created_at DATETIME NOT NULL
+);
+
+CREATE INDEX idx_comment_revisions_comment_id ON comment_revisions(comment_id);
+
+CREATE TABLE IF NOT EXISTS comment_reactions (
+    id CHAR(36) PRIMARY KEY,
+    comment_id CHAR(36) NOT NULL REFERENCES comments(id) ON UPDATE CASCADE ON DELETE CASCADE,
+    user_id CHAR(36) NOT NULL REFERENCES users(id) ON UPDATE CASCADE ON DELETE CASCADE,
+    reaction VARCHAR(20) NOT NULL,
+
+    created_at DATETIME NOT NULL
+);
+
+CREATE UNIQUE INDEX idx_comment_reactions_comment_user_reaction ON comment_reactions(comment_id, user_id, reaction);
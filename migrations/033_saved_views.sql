@@ -0,0 +1,37 @@
+-- Saved list views: named filter, sort and column configurations of the
+-- lead and booking lists. Views can be shared with the team, every user
+-- picks the view each list opens with.
+
+CREATE TABLE IF NOT EXISTS saved_views (
+    id CHAR(36) PRIMARY KEY,
+    user_id CHAR(36) NOT NULL REFERENCES users(id) ON UPDATE CASCADE ON DELETE CASCADE,
+    resource VARCHAR(20) NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    filters JSONB,
+    sort_by VARCHAR(50),
+    sort_order VARCHAR(4),
+    columns JSONB,
+    is_shared BOOLEAN NOT NULL,
+
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL,
+    deleted_at DATETIME
+);
+
+CREATE INDEX idx_saved_views_user_id ON saved_views(user_id);
+CREATE INDEX idx_saved_views_resource ON saved_views(resource);
+CREATE INDEX idx_saved_views_is_shared ON saved_views(is_shared);
+CREATE INDEX idx_saved_views_deleted_at ON saved_views(deleted_at);
+
+CREATE TABLE IF NOT EXISTS saved_view_defaults (
+    user_id CHAR(36) NOT NULL REFERENCES users(id) ON UPDATE CASCADE ON DELETE CASCADE,
+    resource VARCHAR(20) NOT NULL,
+    saved_view_id CHAR(36) NOT NULL REFERENCES saved_views(id) ON DELETE CASCADE,
+
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL,
+
+    PRIMARY KEY (user_id, resource)
+);
+
+CREATE INDEX idx_saved_view_defaults_saved_view_id ON saved_view_defaults(saved_view_id);
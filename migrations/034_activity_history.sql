@@ -0,0 +1,8 @@
+-- Activity history: activities are recorded as typed events in the
+-- transaction of the change they describe and listed per lead, latest first.
+-- Activities of anonymous contact forms and bookings have no user.
+
+ALTER TABLE activities ALTER COLUMN user_id DROP NOT NULL;
+
+CREATE INDEX idx_activities_lead_created ON activities(lead_id, created_at);
+CREATE INDEX idx_activities_created_at ON activities(created_at);
@@ -0,0 +1,27 @@
+-- Transactional outbox: side effects of business changes (emails, chat
+-- webhooks, notifications) are written in the transaction of the change and
+-- delivered by a relay with retries. Messages that used up their attempts
+-- are dead-lettered until an admin retries them.
+
+CREATE TABLE IF NOT EXISTS outbox_messages (
+    id CHAR(36) PRIMARY KEY,
+    topic VARCHAR(100) NOT NULL,
+    dedup_key VARCHAR(255) NOT NULL,
+    payload TEXT NOT NULL,
+
+    status VARCHAR(20) NOT NULL,
+    attempts INTEGER NOT NULL,
+    last_error TEXT,
+    next_attempt_at DATETIME,
+    delivered_at DATETIME,
+    dead_at DATETIME,
+
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL
+);
+
+CREATE UNIQUE INDEX idx_outbox_messages_dedup_key ON outbox_messages(dedup_key);
+CREATE INDEX idx_outbox_messages_topic ON outbox_messages(topic);
+CREATE INDEX idx_outbox_messages_status ON outbox_messages(status);
+CREATE INDEX idx_outbox_messages_next_attempt_at ON outbox_messages(next_attempt_at);
+CREATE INDEX idx_outbox_messages_created_at ON outbox_messages(created_at);
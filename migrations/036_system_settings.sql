@@ -0,0 +1,12 @@
+-- System settings changed by admins at runtime, e.g. cancellation windows,
+-- the lead response SLA and support contact details. Keys, types and
+-- defaults are defined in code; only changed values are stored.
+
+CREATE TABLE IF NOT EXISTS system_settings (
+    key VARCHAR(100) PRIMARY KEY,
+    value TEXT NOT NULL,
+    updated_by CHAR(36),
+
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL
+);
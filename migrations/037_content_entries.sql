@@ -0,0 +1,28 @@
+-- Marketing content managed in the portal: FAQ entries, info pages about
+-- Elterngeld and guides per Bundesland. Entries are edited as drafts and
+-- only published ones are served by the public endpoints.
+
+CREATE TABLE IF NOT EXISTS content_entries (
+    id CHAR(36) PRIMARY KEY,
+    kind VARCHAR(20) NOT NULL,
+    slug VARCHAR(150) NOT NULL,
+    title VARCHAR(200) NOT NULL,
+    summary TEXT,
+    body TEXT NOT NULL,
+    category VARCHAR(100),
+    bundesland VARCHAR(2),
+    position INTEGER NOT NULL,
+    status VARCHAR(20) NOT NULL,
+
+    published_at DATETIME,
+    created_by CHAR(36) NOT NULL,
+    updated_by CHAR(36) NOT NULL,
+
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL
+);
+
+CREATE UNIQUE INDEX idx_content_kind_slug ON content_entries(kind, slug);
+CREATE INDEX idx_content_entries_category ON content_entries(category);
+CREATE INDEX idx_content_entries_bundesland ON content_entries(bundesland);
+CREATE INDEX idx_content_entries_status ON content_entries(status);
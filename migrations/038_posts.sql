@@ -0,0 +1,27 @@
+-- Blog and news posts of the content-marketing site. Posts are written in
+-- Markdown by Beraters; published posts with a publication date in the
+-- future are scheduled and appear in the public list and feeds from then on.
+
+CREATE TABLE IF NOT EXISTS posts (
+    id CHAR(36) PRIMARY KEY,
+    slug VARCHAR(150) NOT NULL,
+    title VARCHAR(200) NOT NULL,
+    excerpt TEXT,
+    body TEXT NOT NULL,
+    category VARCHAR(100),
+    cover_image_url VARCHAR(500),
+    author_id CHAR(36) NOT NULL REFERENCES users(id),
+    status VARCHAR(20) NOT NULL,
+    published_at DATETIME,
+
+    created_by CHAR(36) NOT NULL,
+    updated_by CHAR(36) NOT NULL,
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL
+);
+
+CREATE UNIQUE INDEX idx_posts_slug ON posts(slug);
+CREATE INDEX idx_posts_category ON posts(category);
+CREATE INDEX idx_posts_author_id ON posts(author_id);
+CREATE INDEX idx_posts_status ON posts(status);
+CREATE INDEX idx_posts_published_at ON posts(published_at);
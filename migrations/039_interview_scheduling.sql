@@ -0,0 +1,26 @@
+-- Interview scheduling for job applications. Interview slots are timeslots
+-- with the purpose 'interview' whose Berater is the interviewer; consultation
+-- bookings only see timeslots with the purpose 'consultation'. Applicants pick
+-- a slot with a tokenized invitation link.
+
+ALTER TABLE timeslots ADD COLUMN purpose VARCHAR(20) NOT NULL DEFAULT 'consultation';
+CREATE INDEX idx_timeslots_purpose ON timeslots(purpose);
+
+CREATE TABLE IF NOT EXISTS interview_invitations (
+    id CHAR(36) PRIMARY KEY,
+    application_id CHAR(36) NOT NULL REFERENCES job_applications(id) ON DELETE CASCADE,
+    token_hash VARCHAR(64) NOT NULL,
+    expires_at DATETIME NOT NULL,
+    invited_by CHAR(36) NOT NULL REFERENCES users(id),
+
+    timeslot_id CHAR(36) REFERENCES timeslots(id) ON DELETE SET NULL,
+    scheduled_at DATETIME,
+    revoked_at DATETIME,
+
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL
+);
+
+CREATE UNIQUE INDEX idx_interview_invitations_token_hash ON interview_invitations(token_hash);
+CREATE INDEX idx_interview_invitations_application_id ON interview_invitations(application_id);
+CREATE INDEX idx_interview_invitations_timeslot_id ON interview_invitations(timeslot_id);
@@ -0,0 +1,13 @@
+-- Talent pool and GDPR retention of job applications. Applicants join the
+-- talent pool only with their explicit consent, whose wording and source are
+-- recorded. Rejected applications outside the talent pool are anonymized after
+-- the retention period (RECRUITING_RETENTION_MONTHS).
+
+ALTER TABLE job_applications ADD COLUMN talent_pool BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE job_applications ADD COLUMN talent_pool_consent_at DATETIME;
+ALTER TABLE job_applications ADD COLUMN talent_pool_consent_text TEXT;
+ALTER TABLE job_applications ADD COLUMN talent_pool_consent_source VARCHAR(20);
+ALTER TABLE job_applications ADD COLUMN anonymized_at DATETIME;
+
+CREATE INDEX idx_job_applications_talent_pool ON job_applications(talent_pool);
+CREATE INDEX idx_job_applications_anonymized_at ON job_applications(anonymized_at);
@@ -0,0 +1,49 @@
+-- Application scoring and structured interview feedback. Reviewers rate
+-- applications on configurable, weighted criteria and record a recommendation
+-- and notes per stage. The average of the evaluation scores is kept on the
+-- application so the application list can be sorted and filtered by it.
+
+ALTER TABLE job_applications ADD COLUMN score REAL;
+CREATE INDEX idx_job_applications_score ON job_applications(score);
+
+CREATE TABLE IF NOT EXISTS evaluation_criteria (
+    id CHAR(36) PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    description TEXT,
+    weight INTEGER NOT NULL,
+    position INTEGER NOT NULL,
+    is_active BOOLEAN NOT NULL,
+
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL
+);
+
+CREATE UNIQUE INDEX idx_evaluation_criteria_name ON evaluation_criteria(name);
+CREATE INDEX idx_evaluation_criteria_is_active ON evaluation_criteria(is_active);
+
+CREATE TABLE IF NOT EXISTS application_evaluations (
+    id CHAR(36) PRIMARY KEY,
+    application_id CHAR(36) NOT NULL REFERENCES job_applications(id) ON DELETE CASCADE,
+    reviewer_id CHAR(36) NOT NULL REFERENCES users(id),
+    stage VARCHAR(20) NOT NULL,
+
+    recommendation VARCHAR(20),
+    notes TEXT,
+    score REAL,
+
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL
+);
+
+CREATE UNIQUE INDEX idx_application_evaluations_reviewer ON application_evaluations(application_id, reviewer_id, stage);
+
+CREATE TABLE IF NOT EXISTS application_ratings (
+    id CHAR(36) PRIMARY KEY,
+    evaluation_id CHAR(36) NOT NULL REFERENCES application_evaluations(id) ON DELETE CASCADE,
+    criterion_id CHAR(36) NOT NULL REFERENCES evaluation_criteria(id),
+    rating INTEGER NOT NULL,
+    comment TEXT
+);
+
+CREATE INDEX idx_application_ratings_evaluation_id ON application_ratings(evaluation_id);
+CREATE INDEX idx_application_ratings_criterion_id ON application_ratings(criterion_id);
@@ -0,0 +1,8 @@
+-- Email-verified job applications. Applicants confirm their email address
+-- with the signed link from the confirmation email, which also opens the
+-- status page of the application. Unconfirmed applications are not listed for
+-- HR by default; existing applications count as confirmed.
+
+ALTER TABLE job_applications ADD COLUMN email_verified_at DATETIME;
+UPDATE job_applications SET email_verified_at = created_at;
+CREATE INDEX idx_job_applications_email_verified_at ON job_applications(email_verified_at);
@@ -0,0 +1,11 @@
+-- Team inbox for contact forms and unassigned leads. A Berater claims an
+-- inquiry before replying to it; contact forms and new leads nobody claimed
+-- within the claim SLA (setting leads.inbox_claim_sla) are posted once to the
+-- chat channels with an inbox.unclaimed rule.
+
+ALTER TABLE contact_forms ADD COLUMN claimed_by CHAR(36) REFERENCES users(id) ON DELETE SET NULL;
+ALTER TABLE contact_forms ADD COLUMN claimed_at DATETIME;
+ALTER TABLE contact_forms ADD COLUMN unclaimed_notified_at DATETIME;
+CREATE INDEX idx_contact_forms_claimed_by ON contact_forms(claimed_by);
+
+ALTER TABLE leads ADD COLUMN unclaimed_notified_at DATETIME;
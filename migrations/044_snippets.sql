@@ -0,0 +1,22 @@
+-- Canned responses for replies to contact forms and lead emails. Personal
+-- snippets are only offered to their owner, team snippets to every Berater.
+-- Placeholders like {{customer_name}} in the title and body are filled in
+-- from the contact form, lead or booking being answered.
+
+CREATE TABLE IF NOT EXISTS snippets (
+    id CHAR(36) PRIMARY KEY,
+    owner_id CHAR(36) NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    title VARCHAR(100) NOT NULL,
+    body TEXT NOT NULL,
+    category VARCHAR(50),
+    scope VARCHAR(10) NOT NULL,
+
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL,
+    deleted_at DATETIME
+);
+
+CREATE INDEX idx_snippets_owner_id ON snippets(owner_id);
+CREATE INDEX idx_snippets_category ON snippets(category);
+CREATE INDEX idx_snippets_scope ON snippets(scope);
+CREATE INDEX idx_snippets_deleted_at ON snippets(deleted_at);
@@ -0,0 +1,47 @@
+-- WhatsApp Business messaging. Customers opt in with their mobile number; the
+-- consent keeps the wording and timestamps as proof after it was withdrawn.
+-- Booking reminders and messages in both directions are stored and shown on
+-- the timeline of the customer's lead.
+
+CREATE TABLE IF NOT EXISTS whats_app_consents (
+    id CHAR(36) PRIMARY KEY,
+    user_id CHAR(36) NOT NULL UNIQUE REFERENCES users(id) ON DELETE CASCADE,
+    phone VARCHAR(32) NOT NULL,
+    opted_in BOOLEAN NOT NULL,
+    source VARCHAR(20) NOT NULL,
+    consent_text TEXT,
+    opted_in_at DATETIME,
+    opted_out_at DATETIME,
+
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL
+);
+
+CREATE INDEX idx_whats_app_consents_phone ON whats_app_consents(phone);
+
+CREATE TABLE IF NOT EXISTS whats_app_messages (
+    id CHAR(36) PRIMARY KEY,
+    user_id CHAR(36) NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    lead_id CHAR(36),
+    booking_id CHAR(36),
+    sent_by CHAR(36),
+    direction VARCHAR(10) NOT NULL,
+    phone VARCHAR(32) NOT NULL,
+    body TEXT,
+    template VARCHAR(100),
+    status VARCHAR(20) NOT NULL,
+    error TEXT,
+    provider_message_id VARCHAR(100),
+
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL
+);
+
+CREATE INDEX idx_whats_app_messages_user_id ON whats_app_messages(user_id);
+CREATE INDEX idx_whats_app_messages_lead_id ON whats_app_messages(lead_id);
+CREATE INDEX idx_whats_app_messages_booking_id ON whats_app_messages(booking_id);
+CREATE INDEX idx_whats_app_messages_provider_message_id ON whats_app_messages(provider_message_id);
+CREATE INDEX idx_whats_app_messages_created_at ON whats_app_messages(created_at);
+
+-- Set once the reminder template was sent for a confirmed booking
+ALTER TABLE bookings ADD COLUMN whats_app_reminder_sent_at DATETIME;
@@ -0,0 +1,12 @@
+-- Notification digests. Users choose per category whether email notifications
+-- are sent immediately or collected into an hourly or daily summary; the email
+-- queue holds those notifications back as batched until the digest is due.
+
+ALTER TABLE notification_preferences ADD COLUMN booking_digest VARCHAR(10) NOT NULL DEFAULT 'immediate';
+ALTER TABLE notification_preferences ADD COLUMN payment_digest VARCHAR(10) NOT NULL DEFAULT 'immediate';
+ALTER TABLE notification_preferences ADD COLUMN todo_digest VARCHAR(10) NOT NULL DEFAULT 'immediate';
+ALTER TABLE notification_preferences ADD COLUMN reminder_digest VARCHAR(10) NOT NULL DEFAULT 'immediate';
+ALTER TABLE notification_preferences ADD COLUMN lead_digest VARCHAR(10) NOT NULL DEFAULT 'immediate';
+ALTER TABLE notification_preferences ADD COLUMN notification_digest_sent_at DATETIME;
+
+CREATE INDEX idx_notifications_batched ON notifications(user_id, status, created_at);
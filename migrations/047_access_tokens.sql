@@ -0,0 +1,23 @@
+-- Personal access tokens that power users and their scripts send as bearer
+-- token instead of a JWT. Only a SHA-256 hash of the token is stored; every
+-- token is limited to its scopes and expires after at most a year.
+
+CREATE TABLE IF NOT EXISTS access_tokens (
+    id CHAR(36) PRIMARY KEY,
+    user_id CHAR(36) NOT NULL REFERENCES users(id) ON UPDATE CASCADE ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    token_prefix VARCHAR(16) NOT NULL,
+    token_hash VARCHAR(64) NOT NULL,
+    scopes TEXT NOT NULL,
+
+    last_used_at DATETIME,
+    expires_at DATETIME NOT NULL,
+    revoked_at DATETIME,
+
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL
+);
+
+CREATE INDEX idx_access_tokens_user_id ON access_tokens(user_id);
+CREATE UNIQUE INDEX idx_access_tokens_token_hash ON access_tokens(token_hash);
+CREATE INDEX idx_access_tokens_revoked_at ON access_tokens(revoked_at);
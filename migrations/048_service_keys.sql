@@ -0,0 +1,32 @@
+-- API keys of machine integrations such as the website forms and partner
+-- systems. Only SHA-256 hashes are stored; keys can be limited to IP addresses
+-- and CIDR ranges and are rate limited per minute. After a rotation the
+-- replaced key stays valid until previous_key_expires_at.
+
+CREATE TABLE IF NOT EXISTS service_keys (
+    id CHAR(36) PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    description TEXT,
+    key_prefix VARCHAR(16) NOT NULL,
+    key_hash VARCHAR(64) NOT NULL,
+    scopes TEXT NOT NULL,
+    allowed_ips TEXT,
+    rate_limit INTEGER NOT NULL,
+
+    previous_key_hash VARCHAR(64),
+    previous_key_expires_at DATETIME,
+    rotated_at DATETIME,
+
+    last_used_at DATETIME,
+    last_used_ip VARCHAR(45),
+    expires_at DATETIME,
+    revoked_at DATETIME,
+
+    created_by_id CHAR(36) NOT NULL,
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL
+);
+
+CREATE UNIQUE INDEX idx_service_keys_key_hash ON service_keys(key_hash);
+CREATE INDEX idx_service_keys_previous_key_hash ON service_keys(previous_key_hash);
+CREATE INDEX idx_service_keys_revoked_at ON service_keys(revoked_at);
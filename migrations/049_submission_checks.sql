@@ -0,0 +1,31 @@
+-- Anomaly scores of submissions to the public contact and registration
+-- endpoints. Checks of passed submissions are kept for ABUSE_RETENTION to
+-- detect bursts and velocity per IP and email address. Flagged submissions
+-- have a review_status and wait for an admin before their lead is created or
+-- their verification email is sent.
+
+CREATE TABLE IF NOT EXISTS submission_checks (
+    id CHAR(36) PRIMARY KEY,
+    endpoint VARCHAR(20) NOT NULL,
+    ip_address VARCHAR(45),
+    email VARCHAR(255),
+    score INTEGER NOT NULL,
+    reasons TEXT,
+
+    review_status VARCHAR(20),
+    contact_form_id CHAR(36),
+    user_id CHAR(36),
+    reviewed_by_id CHAR(36),
+    reviewed_at DATETIME,
+
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL
+);
+
+CREATE INDEX idx_submission_checks_endpoint ON submission_checks(endpoint);
+CREATE INDEX idx_submission_checks_ip_address ON submission_checks(ip_address);
+CREATE INDEX idx_submission_checks_email ON submission_checks(email);
+CREATE INDEX idx_submission_checks_review_status ON submission_checks(review_status);
+CREATE INDEX idx_submission_checks_contact_form_id ON submission_checks(contact_form_id);
+CREATE INDEX idx_submission_checks_user_id ON submission_checks(user_id);
+CREATE INDEX idx_submission_checks_created_at ON submission_checks(created_at);
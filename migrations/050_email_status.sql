@@ -0,0 +1,11 @@
+-- Status of client email addresses, checked in the background against the
+-- disposable-domain list and the MX records of the domain. The status is
+-- copied to the leads of the user so leads with unreachable contacts can be
+-- filtered. Empty means not checked yet.
+
+ALTER TABLE users ADD COLUMN email_status VARCHAR(20);
+ALTER TABLE users ADD COLUMN email_checked_at DATETIME;
+ALTER TABLE leads ADD COLUMN email_status VARCHAR(20);
+
+CREATE INDEX idx_users_email_status ON users(email_status);
+CREATE INDEX idx_leads_email_status ON leads(email_status);
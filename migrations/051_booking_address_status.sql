@@ -0,0 +1,5 @@
+-- Result of validating the address in the booking contact-info step with the
+-- address provider: verified addresses were normalized, undeliverable ones
+-- are unknown to the provider. Empty means the address was not checked.
+
+ALTER TABLE bookings ADD COLUMN address_status VARCHAR(20);
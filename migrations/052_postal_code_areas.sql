@@ -0,0 +1,19 @@
+-- Reference dataset of German postal codes (PLZ) with the place, Kreis and
+-- Bundesland they belong to. A postal code can span several places, Kreise and
+-- Bundesländer. The table is replaced as a whole with
+-- `--import-postal-codes <file>`.
+
+CREATE TABLE IF NOT EXISTS postal_code_areas (
+    id CHAR(36) PRIMARY KEY,
+    postal_code VARCHAR(5) NOT NULL,
+    place VARCHAR(255),
+    kreis VARCHAR(255),
+    kreis_code VARCHAR(5),
+    bundesland VARCHAR(2) NOT NULL,
+
+    created_at DATETIME NOT NULL
+);
+
+CREATE INDEX idx_postal_code_areas_postal_code ON postal_code_areas(postal_code);
+CREATE INDEX idx_postal_code_areas_kreis_code ON postal_code_areas(kreis_code);
+CREATE INDEX idx_postal_code_areas_bundesland ON postal_code_areas(bundesland);
@@ -0,0 +1,17 @@
+-- Elterngeld plans of a lead, compared side by side. The plan holds the
+-- parents' income and the type of Elterngeld each takes per Lebensmonat. The
+-- scenario with chosen_at is the one the application is prepared with.
+
+CREATE TABLE IF NOT EXISTS elterngeld_scenarios (
+    id CHAR(36) PRIMARY KEY,
+    lead_id CHAR(36) NOT NULL REFERENCES leads(id) ON UPDATE CASCADE ON DELETE CASCADE,
+    created_by_id CHAR(36) NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    plan JSONB,
+    chosen_at DATETIME,
+
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL
+);
+
+CREATE INDEX idx_elterngeld_scenarios_lead_id ON elterngeld_scenarios(lead_id);
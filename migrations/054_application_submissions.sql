@@ -0,0 +1,49 @@
+-- Tracking of Elterngeld applications after their submission to the
+-- Elterngeldstelle: requests for additional documents, the Bescheid with the
+-- granted amounts and the Widerspruchsfrist. The reminder columns point to the
+-- reminders created for the lead's Berater.
+
+CREATE TABLE IF NOT EXISTS application_submissions (
+    id CHAR(36) PRIMARY KEY,
+    lead_id CHAR(36) NOT NULL UNIQUE REFERENCES leads(id) ON UPDATE CASCADE ON DELETE CASCADE,
+    submitted_by_id CHAR(36) NOT NULL,
+    submitted_at DATETIME NOT NULL,
+    office VARCHAR(200),
+    office_reference VARCHAR(100),
+    status VARCHAR(30) NOT NULL,
+
+    bescheid_date DATETIME,
+    bescheid_received_at DATETIME,
+    granted_amounts JSONB,
+    granted_total DECIMAL(10,2),
+    objection_deadline DATETIME,
+
+    follow_up_reminder_id CHAR(36),
+    objection_reminder_id CHAR(36),
+
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL
+);
+
+CREATE INDEX idx_application_submissions_office_reference ON application_submissions(office_reference);
+CREATE INDEX idx_application_submissions_status ON application_submissions(status);
+CREATE INDEX idx_application_submissions_objection_deadline ON application_submissions(objection_deadline);
+
+CREATE TABLE IF NOT EXISTS application_document_requests (
+    id CHAR(36) PRIMARY KEY,
+    submission_id CHAR(36) NOT NULL REFERENCES application_submissions(id) ON DELETE CASCADE,
+    lead_id CHAR(36) NOT NULL,
+    created_by_id CHAR(36) NOT NULL,
+    description TEXT NOT NULL,
+    received_at DATETIME NOT NULL,
+    due_date DATETIME,
+    fulfilled_at DATETIME,
+    reminder_id CHAR(36),
+
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL
+);
+
+CREATE INDEX idx_application_document_requests_submission_id ON application_document_requests(submission_id);
+CREATE INDEX idx_application_document_requests_lead_id ON application_document_requests(lead_id);
+CREATE INDEX idx_application_document_requests_due_date ON application_document_requests(due_date);
@@ -0,0 +1,6 @@
+-- Bescheide whose granted total falls significantly short of the calculated
+-- Elterngeld are flagged so the Berater can suggest a Widerspruch.
+
+ALTER TABLE application_submissions ADD COLUMN variance_flagged_at DATETIME;
+
+CREATE INDEX idx_application_submissions_variance_flagged_at ON application_submissions(variance_flagged_at);
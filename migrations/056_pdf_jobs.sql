@@ -0,0 +1,37 @@
+-- PDFs generated from HTML templates in the background, and the letterheads
+-- of the tenants they are rendered with. Generation is queued through the
+-- outbox (topic pdf.generate); the PDFs are stored under PDF_PATH.
+
+CREATE TABLE IF NOT EXISTS pdf_jobs (
+    id CHAR(36) PRIMARY KEY,
+    template VARCHAR(50) NOT NULL,
+    tenant VARCHAR(50) NOT NULL,
+    file_name VARCHAR(255) NOT NULL,
+    data TEXT NOT NULL,
+    status VARCHAR(20) NOT NULL,
+    error TEXT,
+    size BIGINT,
+    requested_by_id CHAR(36) NOT NULL,
+    completed_at DATETIME,
+
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL
+);
+
+CREATE INDEX idx_pdf_jobs_status ON pdf_jobs(status);
+CREATE INDEX idx_pdf_jobs_requested_by_id ON pdf_jobs(requested_by_id);
+
+CREATE TABLE IF NOT EXISTS pdf_brandings (
+    id CHAR(36) PRIMARY KEY,
+    tenant VARCHAR(50) NOT NULL,
+    company_name VARCHAR(200) NOT NULL,
+    logo TEXT,
+    primary_color VARCHAR(7),
+    footer VARCHAR(500),
+    updated_by_id CHAR(36),
+
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL
+);
+
+CREATE UNIQUE INDEX idx_pdf_brandings_tenant ON pdf_brandings(tenant);
@@ -0,0 +1,36 @@
+-- Announcements the admins broadcast to all users, to customers with upcoming
+-- bookings or to the Beraters. They are published at publish_at as in-app
+-- notifications and optionally as emails; the receipts record who received
+-- and who read them.
+
+CREATE TABLE IF NOT EXISTS announcements (
+    id CHAR(36) PRIMARY KEY,
+    title VARCHAR(200) NOT NULL,
+    message TEXT NOT NULL,
+    audience VARCHAR(20) NOT NULL,
+    send_email BOOLEAN NOT NULL,
+    compliance BOOLEAN NOT NULL,
+    publish_at DATETIME NOT NULL,
+    published_at DATETIME,
+    recipients INTEGER NOT NULL,
+    created_by_id CHAR(36) NOT NULL,
+
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL,
+    deleted_at DATETIME
+);
+
+CREATE INDEX idx_announcements_publish_at ON announcements(publish_at);
+CREATE INDEX idx_announcements_deleted_at ON announcements(deleted_at);
+
+CREATE TABLE IF NOT EXISTS announcement_receipts (
+    id CHAR(36) PRIMARY KEY,
+    announcement_id CHAR(36) NOT NULL REFERENCES announcements(id) ON UPDATE CASCADE ON DELETE CASCADE,
+    user_id CHAR(36) NOT NULL REFERENCES users(id) ON UPDATE CASCADE ON DELETE CASCADE,
+    read_at DATETIME,
+
+    created_at DATETIME NOT NULL
+);
+
+CREATE UNIQUE INDEX idx_announcement_receipts_user ON announcement_receipts(announcement_id, user_id);
+CREATE INDEX idx_announcement_receipts_user_id ON announcement_receipts(user_id);
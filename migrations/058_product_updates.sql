@@ -0,0 +1,21 @@
+-- Product updates of the "What's new" feed: new features, improvements,
+-- fixes and maintenance windows. Entries without published_at are drafts.
+
+CREATE TABLE IF NOT EXISTS product_updates (
+    id CHAR(36) PRIMARY KEY,
+    kind VARCHAR(20) NOT NULL,
+    audience VARCHAR(20) NOT NULL,
+    title VARCHAR(200) NOT NULL,
+    body TEXT NOT NULL,
+    link VARCHAR(500),
+    starts_at DATETIME,
+    ends_at DATETIME,
+    published_at DATETIME,
+    created_by CHAR(36) NOT NULL,
+    updated_by CHAR(36) NOT NULL,
+
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL
+);
+
+CREATE INDEX idx_product_updates_published_at ON product_updates(published_at);
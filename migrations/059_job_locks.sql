@@ -0,0 +1,9 @@
+-- Leases on scheduled jobs, so each job runs on one instance at a time when
+-- several instances are deployed. An expired lease may be taken over.
+
+CREATE TABLE IF NOT EXISTS job_locks (
+    name VARCHAR(100) PRIMARY KEY,
+    owner VARCHAR(100) NOT NULL,
+    expires_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL
+);
@@ -0,0 +1,40 @@
+-- Recurring jobs of the scheduler and their run history. Each run claims
+-- its job by incrementing run_count, so a run starts once even when several
+-- instances see the job due.
+
+CREATE TABLE IF NOT EXISTS scheduled_jobs (
+    name VARCHAR(100) PRIMARY KEY,
+    schedule VARCHAR(100) NOT NULL,
+    paused BOOLEAN NOT NULL,
+    paused_by CHAR(36),
+    next_run_at DATETIME,
+    triggered_at DATETIME,
+    triggered_by CHAR(36),
+    run_count BIGINT NOT NULL,
+    last_run_at DATETIME,
+    last_status VARCHAR(20),
+    last_error TEXT,
+
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL
+);
+
+CREATE INDEX idx_scheduled_jobs_next_run_at ON scheduled_jobs(next_run_at);
+
+CREATE TABLE IF NOT EXISTS scheduled_job_runs (
+    id CHAR(36) PRIMARY KEY,
+    job_name VARCHAR(100) NOT NULL,
+    trigger VARCHAR(20) NOT NULL,
+    triggered_by CHAR(36),
+    status VARCHAR(20) NOT NULL,
+    started_at DATETIME NOT NULL,
+    finished_at DATETIME,
+    duration_ms BIGINT,
+    result TEXT,
+    error TEXT,
+
+    created_at DATETIME NOT NULL
+);
+
+CREATE INDEX idx_scheduled_job_runs_job_name ON scheduled_job_runs(job_name);
+CREATE INDEX idx_scheduled_job_runs_started_at ON scheduled_job_runs(started_at);
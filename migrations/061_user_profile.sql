@@ -0,0 +1,8 @@
+-- Profile fields of the user: an alternative address for notifications,
+-- the occupation before the birth used by the Elterngeld calculator and an
+-- uploaded avatar, served through photo_url.
+
+ALTER TABLE users ADD COLUMN notification_email VARCHAR(255);
+ALTER TABLE users ADD COLUMN profession VARCHAR(100);
+ALTER TABLE users ADD COLUMN employment_type ENUM('angestellt', 'selbststaendig', 'beamtet', 'mischeinkuenfte', 'minijob', 'ohne_einkommen');
+ALTER TABLE users ADD COLUMN avatar_path VARCHAR(255);
@@ -0,0 +1,56 @@
+-- Families group both parents, the children, the leads and bookings of a
+-- case. Parents with an account see the leads and bookings of their
+-- families, not only those they created themselves.
+
+CREATE TABLE IF NOT EXISTS families (
+    id CHAR(36) PRIMARY KEY,
+    name VARCHAR(200) NOT NULL,
+    created_by CHAR(36) NOT NULL,
+
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL,
+    deleted_at DATETIME
+);
+
+CREATE INDEX idx_families_created_by ON families(created_by);
+CREATE INDEX idx_families_deleted_at ON families(deleted_at);
+
+CREATE TABLE IF NOT EXISTS family_members (
+    id CHAR(36) PRIMARY KEY,
+    family_id CHAR(36) NOT NULL,
+    user_id CHAR(36),
+    role ENUM('antragsteller', 'elternteil') NOT NULL,
+    first_name VARCHAR(100) NOT NULL,
+    last_name VARCHAR(100) NOT NULL,
+    email VARCHAR(255),
+
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL,
+
+    FOREIGN KEY (family_id) REFERENCES families(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX idx_family_members_user ON family_members(family_id, user_id);
+CREATE INDEX idx_family_members_user_id ON family_members(user_id);
+
+CREATE TABLE IF NOT EXISTS family_children (
+    id CHAR(36) PRIMARY KEY,
+    family_id CHAR(36) NOT NULL,
+    first_name VARCHAR(100),
+    birth_date DATETIME,
+    expected_birth_date DATETIME,
+    multiple_birth BOOLEAN NOT NULL,
+
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL,
+
+    FOREIGN KEY (family_id) REFERENCES families(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_family_children_family_id ON family_children(family_id);
+
+ALTER TABLE leads ADD COLUMN family_id CHAR(36);
+ALTER TABLE bookings ADD COLUMN family_id CHAR(36);
+
+CREATE INDEX idx_leads_family_id ON leads(family_id);
+CREATE INDEX idx_bookings_family_id ON bookings(family_id);
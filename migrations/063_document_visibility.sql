@@ -0,0 +1,16 @@
+-- Who sees a document on its lead: only the customers (kunde), only the
+-- Berater (berater) or both (geteilt). New uploads of staff start internal;
+-- existing documents stay shared as before, except uploads to internal comments.
+
+ALTER TABLE documents ADD COLUMN visibility ENUM('kunde', 'berater', 'geteilt') NOT NULL DEFAULT 'geteilt';
+ALTER TABLE documents ADD COLUMN shared_at DATETIME;
+ALTER TABLE documents ADD COLUMN shared_by_id CHAR(36);
+
+CREATE INDEX idx_documents_visibility ON documents(visibility);
+
+UPDATE documents SET visibility = 'berater'
+WHERE id IN (
+    SELECT comment_attachments.document_id FROM comment_attachments
+    JOIN comments ON comments.id = comment_attachments.comment_id
+    WHERE comments.is_internal = TRUE AND comments.user_id = documents.user_id
+);
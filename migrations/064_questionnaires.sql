@@ -0,0 +1,58 @@
+-- Intake questionnaires of the packages. Admins edit a draft version and
+-- publish it; published versions no longer change, so the answers of a lead
+-- always refer to the questions they were given to.
+
+CREATE TABLE IF NOT EXISTS questionnaires (
+    id CHAR(36) PRIMARY KEY,
+    package_id CHAR(36) NOT NULL,
+    version INT NOT NULL,
+    title VARCHAR(200) NOT NULL,
+    description TEXT,
+    published_at DATETIME,
+    created_by_id CHAR(36) NOT NULL,
+
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL
+);
+
+CREATE UNIQUE INDEX idx_questionnaires_version ON questionnaires(package_id, version);
+CREATE INDEX idx_questionnaires_published_at ON questionnaires(published_at);
+
+CREATE TABLE IF NOT EXISTS questionnaire_questions (
+    id CHAR(36) PRIMARY KEY,
+    questionnaire_id CHAR(36) NOT NULL,
+    `key` VARCHAR(50) NOT NULL,
+    label VARCHAR(300) NOT NULL,
+    help_text TEXT,
+    type ENUM('text', 'zahl', 'datum', 'auswahl', 'mehrfachauswahl', 'ja_nein') NOT NULL,
+    required BOOLEAN NOT NULL,
+    options JSON,
+    show_if JSON,
+    prefill VARCHAR(50),
+    position INT NOT NULL,
+
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL,
+
+    FOREIGN KEY (questionnaire_id) REFERENCES questionnaires(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX idx_questionnaire_questions_key ON questionnaire_questions(questionnaire_id, `key`);
+
+CREATE TABLE IF NOT EXISTS questionnaire_responses (
+    id CHAR(36) PRIMARY KEY,
+    questionnaire_id CHAR(36) NOT NULL,
+    lead_id CHAR(36) NOT NULL,
+    user_id CHAR(36) NOT NULL,
+    answers JSON,
+    submitted_at DATETIME NOT NULL,
+
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL,
+
+    FOREIGN KEY (questionnaire_id) REFERENCES questionnaires(id),
+    FOREIGN KEY (lead_id) REFERENCES leads(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX idx_questionnaire_responses_lead ON questionnaire_responses(questionnaire_id, lead_id);
+CREATE INDEX idx_questionnaire_responses_lead_id ON questionnaire_responses(lead_id);
@@ -0,0 +1,10 @@
+-- Qualification points of intake answers. Questionnaires with points score
+-- the answers of a lead, set its priority and recommend a package; answers
+-- given with an anonymous pre-talk booking have no user.
+
+ALTER TABLE questionnaire_questions ADD COLUMN points JSON;
+
+ALTER TABLE questionnaire_responses MODIFY COLUMN user_id CHAR(36) NULL;
+ALTER TABLE questionnaire_responses ADD COLUMN score INT;
+ALTER TABLE questionnaire_responses ADD COLUMN score_reasons TEXT;
+ALTER TABLE questionnaire_responses ADD COLUMN recommended_package_id CHAR(36);
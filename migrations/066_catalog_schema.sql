@@ -0,0 +1,8 @@
+-- Bookings and checkout use the catalog schema only: packages, addons,
+-- package_addons and booking_addons. Addons included in a package are
+-- stored with the booking at a price of 0 and marked as included.
+
+ALTER TABLE booking_addons ADD COLUMN included BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE INDEX idx_booking_addons_addon_id ON booking_addons(addon_id);
+CREATE INDEX idx_package_addons_addon_id ON package_addons(addon_id);
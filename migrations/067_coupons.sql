@@ -0,0 +1,29 @@
+-- Coupon codes lower the price of a booking by a percentage or a fixed
+-- amount. Bookings store the discount next to the discounted total; quotes,
+-- bookings and checkout are all priced by the same catalog rules.
+
+CREATE TABLE IF NOT EXISTS coupons (
+    id CHAR(36) PRIMARY KEY,
+    code VARCHAR(32) NOT NULL,
+    description TEXT,
+    percent_off REAL NOT NULL DEFAULT 0,
+    amount_off REAL NOT NULL DEFAULT 0,
+    currency VARCHAR(3) NOT NULL DEFAULT 'EUR',
+    package_id CHAR(36) REFERENCES packages(id) ON UPDATE CASCADE ON DELETE CASCADE,
+    max_redemptions INTEGER NOT NULL DEFAULT 0,
+    redemption_count INTEGER NOT NULL DEFAULT 0,
+    expires_at DATETIME,
+    created_by CHAR(36) NOT NULL REFERENCES users(id) ON UPDATE CASCADE ON DELETE CASCADE,
+
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL,
+    deleted_at DATETIME
+);
+
+CREATE UNIQUE INDEX idx_coupons_code ON coupons(code);
+CREATE INDEX idx_coupons_package_id ON coupons(package_id);
+CREATE INDEX idx_coupons_deleted_at ON coupons(deleted_at);
+
+ALTER TABLE bookings ADD COLUMN discount_amount REAL NOT NULL DEFAULT 0;
+ALTER TABLE bookings ADD COLUMN coupon_id CHAR(36);
+CREATE INDEX idx_bookings_coupon_id ON bookings(coupon_id);
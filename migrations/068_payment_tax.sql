@@ -0,0 +1,11 @@
+-- Payments record the Umsatzsteuer of a checkout: net amount, tax, rate and
+-- country, the treatment (inland, reverse charge, Drittland or Stripe Tax)
+-- and the USt-ID of business customers with the time VIES confirmed it.
+
+ALTER TABLE payments ADD COLUMN net_amount REAL NOT NULL DEFAULT 0;
+ALTER TABLE payments ADD COLUMN tax_amount REAL NOT NULL DEFAULT 0;
+ALTER TABLE payments ADD COLUMN tax_rate REAL NOT NULL DEFAULT 0;
+ALTER TABLE payments ADD COLUMN tax_country VARCHAR(2);
+ALTER TABLE payments ADD COLUMN tax_treatment VARCHAR(20);
+ALTER TABLE payments ADD COLUMN customer_vat_id VARCHAR(20);
+ALTER TABLE payments ADD COLUMN vat_id_checked_at DATETIME;
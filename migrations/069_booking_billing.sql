@@ -0,0 +1,7 @@
+-- Business customers enter their company, USt-ID and billing address at
+-- checkout. They are kept on the booking and printed on the invoice.
+
+ALTER TABLE bookings ADD COLUMN company_name VARCHAR(200);
+ALTER TABLE bookings ADD COLUMN vat_id VARCHAR(20);
+ALTER TABLE bookings ADD COLUMN billing_address TEXT;
+ALTER TABLE bookings ADD COLUMN billing_country VARCHAR(2);
@@ -0,0 +1,38 @@
+-- Payment links let Beraters sell outside the online booking flow: a link for
+-- a package offer or a custom amount is sent to a lead, opening it is tracked
+-- by its short link and paying it creates the booking.
+
+CREATE TABLE IF NOT EXISTS payment_links (
+    id CHAR(36) PRIMARY KEY,
+    token VARCHAR(32) NOT NULL,
+    lead_id CHAR(36) NOT NULL REFERENCES leads(id) ON UPDATE CASCADE ON DELETE CASCADE,
+    user_id CHAR(36) NOT NULL REFERENCES users(id) ON UPDATE CASCADE ON DELETE CASCADE,
+    package_id CHAR(36) REFERENCES packages(id) ON UPDATE CASCADE ON DELETE SET NULL,
+    created_by CHAR(36) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'offen',
+    title VARCHAR(200) NOT NULL,
+    description TEXT,
+    amount REAL NOT NULL,
+    currency VARCHAR(3) NOT NULL DEFAULT 'EUR',
+    short_link_id CHAR(36) REFERENCES short_links(id) ON UPDATE CASCADE ON DELETE SET NULL,
+    stripe_session_id VARCHAR(255),
+    expires_at DATETIME,
+    paid_at DATETIME,
+    booking_id CHAR(36),
+    payment_id CHAR(36),
+
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL,
+    deleted_at DATETIME
+);
+
+CREATE UNIQUE INDEX idx_payment_links_token ON payment_links(token);
+CREATE INDEX idx_payment_links_lead_id ON payment_links(lead_id);
+CREATE INDEX idx_payment_links_user_id ON payment_links(user_id);
+CREATE INDEX idx_payment_links_package_id ON payment_links(package_id);
+CREATE INDEX idx_payment_links_status ON payment_links(status);
+CREATE INDEX idx_payment_links_short_link_id ON payment_links(short_link_id);
+CREATE INDEX idx_payment_links_stripe_session_id ON payment_links(stripe_session_id);
+CREATE INDEX idx_payment_links_booking_id ON payment_links(booking_id);
+CREATE INDEX idx_payment_links_payment_id ON payment_links(payment_id);
+CREATE INDEX idx_payment_links_deleted_at ON payment_links(deleted_at);
@@ -0,0 +1,32 @@
+-- Refunds are requested with a justification; refunds above the approval
+-- threshold are executed only after a second admin approved them. The request
+-- keeps who asked, who decided and the Stripe refund or credit entry.
+
+CREATE TABLE IF NOT EXISTS refund_requests (
+    id CHAR(36) PRIMARY KEY,
+    payment_id CHAR(36) NOT NULL REFERENCES payments(id) ON UPDATE CASCADE ON DELETE CASCADE,
+    requested_by CHAR(36) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'beantragt',
+    amount REAL NOT NULL,
+    currency VARCHAR(3) NOT NULL DEFAULT 'EUR',
+    to_credit BOOLEAN NOT NULL,
+    reason TEXT NOT NULL,
+    requires_approval BOOLEAN NOT NULL,
+
+    decided_by CHAR(36),
+    decided_at DATETIME,
+    decision_note TEXT,
+
+    executed_at DATETIME,
+    stripe_refund_id VARCHAR(255),
+    credit_entry_id CHAR(36),
+    failure_message TEXT,
+
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL
+);
+
+CREATE INDEX idx_refund_requests_payment_id ON refund_requests(payment_id);
+CREATE INDEX idx_refund_requests_requested_by ON refund_requests(requested_by);
+CREATE INDEX idx_refund_requests_status ON refund_requests(status);
+CREATE INDEX idx_refund_requests_decided_by ON refund_requests(decided_by);
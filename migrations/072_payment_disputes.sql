@@ -0,0 +1,23 @@
+-- Chargebacks and inquiries of the customer's bank, kept in sync from the
+-- Stripe dispute webhooks; they are part of the payment statements.
+
+CREATE TABLE IF NOT EXISTS payment_disputes (
+    id CHAR(36) PRIMARY KEY,
+    payment_id CHAR(36) NOT NULL REFERENCES payments(id) ON UPDATE CASCADE ON DELETE CASCADE,
+    user_id CHAR(36) NOT NULL,
+    stripe_dispute_id VARCHAR(255) NOT NULL,
+    amount REAL NOT NULL,
+    currency VARCHAR(3) NOT NULL DEFAULT 'EUR',
+    reason VARCHAR(50),
+    status VARCHAR(30) NOT NULL,
+    opened_at DATETIME NOT NULL,
+    closed_at DATETIME,
+
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL
+);
+
+CREATE UNIQUE INDEX idx_payment_disputes_stripe_dispute_id ON payment_disputes(stripe_dispute_id);
+CREATE INDEX idx_payment_disputes_payment_id ON payment_disputes(payment_id);
+CREATE INDEX idx_payment_disputes_user_id ON payment_disputes(user_id);
+CREATE INDEX idx_payment_disputes_status ON payment_disputes(status);
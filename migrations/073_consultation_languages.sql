@@ -0,0 +1,11 @@
+-- Languages customers can hold a consultation in, matched against the
+-- working languages of the Beraters; bookings without a shared language
+-- are flagged for an interpreter
+
+ALTER TABLE users ADD COLUMN spoken_languages TEXT;
+
+ALTER TABLE bookings ADD COLUMN consultation_language VARCHAR(5);
+ALTER TABLE bookings ADD COLUMN interpreter_required BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE bookings ADD COLUMN interpreter_language VARCHAR(5);
+
+CREATE INDEX idx_bookings_interpreter_required ON bookings(interpreter_required);
@@ -0,0 +1,9 @@
+-- Submitter details of contact forms that are carried over to the lead:
+-- the account of the submitter, their company and the date they would
+-- like to be contacted
+
+ALTER TABLE contact_forms ADD COLUMN user_id CHAR(36);
+ALTER TABLE contact_forms ADD COLUMN company VARCHAR(255);
+ALTER TABLE contact_forms ADD COLUMN preferred_date DATETIME;
+
+CREATE INDEX idx_contact_forms_user_id ON contact_forms(user_id);
@@ -0,0 +1,8 @@
+-- Personal access tokens store their secret like API keys and service keys,
+-- so the columns get the same names
+
+ALTER TABLE access_tokens RENAME COLUMN token_prefix TO key_prefix;
+ALTER TABLE access_tokens RENAME COLUMN token_hash TO key_hash;
+
+DROP INDEX idx_access_tokens_token_hash ON access_tokens;
+CREATE UNIQUE INDEX idx_access_tokens_key_hash ON access_tokens(key_hash);
@@ -15,9 +15,11 @@ import (
 
 // Claims represents JWT claims
 type Claims struct {
-	UserID uuid.UUID       `json:"user_id"`
-	Email  string          `json:"email"`
-	Role   models.UserRole `json:"role"`
+	UserID   uuid.UUID       `json:"user_id"`
+	Email    string          `json:"email"`
+	Role     models.UserRole `json:"role"`
+	Language string          `json:"lang,omitempty"`
+	Timezone string          `json:"tz,omitempty"`
 	jwt.RegisteredClaims
 }
 
@@ -73,9 +75,11 @@ func (js *JWTService) GenerateTokenPair(user *models.User) (*TokenPair, error) {
 func (js *JWTService) generateAccessToken(user *models.User) (string, error) {
 	now := time.Now()
 	claims := &Claims{
-		UserID: user.ID,
-		Email:  user.Email,
-		Role:   user.Role,
+		UserID:   user.ID,
+		Email:    user.Email,
+		Role:     user.Role,
+		Language: user.Language,
+		Timezone: user.Timezone,
 		RegisteredClaims: jwt.RegisteredClaims{
 			Issuer:    js.issuer,
 			Subject:   user.ID.String(),
@@ -158,6 +162,11 @@ func (js *JWTService) GetTokenClaims(tokenString string) (*Claims, error) {
 	return claims, nil
 }
 
+// RefreshTTL returns how long refresh tokens are valid
+func (js *JWTService) RefreshTTL() time.Duration {
+	return js.refreshTTL
+}
+
 // RefreshTokenExpiry returns the refresh token expiry time
 func (js *JWTService) RefreshTokenExpiry() time.Time {
 	return time.Now().Add(js.refreshTTL)
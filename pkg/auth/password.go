@@ -0,0 +1,157 @@
+package auth
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"elterngeld-portal/config"
+)
+
+// ErrBreachCheckUnavailable is returned when the breached password lookup fails.
+// Callers decide whether to accept the password anyway.
+var ErrBreachCheckUnavailable = errors.New("breached password check unavailable")
+
+// PasswordViolation is a rule a password does not satisfy. Message is the English
+// catalog key of the guidance shown to the user, formatted with Args.
+type PasswordViolation struct {
+	Message string
+	Args    []interface{}
+}
+
+// PasswordError lists all rules a rejected password violates
+type PasswordError struct {
+	Violations []PasswordViolation
+}
+
+func (e *PasswordError) Error() string {
+	messages := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		messages[i] = fmt.Sprintf(v.Message, v.Args...)
+	}
+	return "password rejected: " + strings.Join(messages, "; ")
+}
+
+// PasswordPolicy checks new passwords against the configured rules and optionally
+// against the HaveIBeenPwned range API. Only the first five characters of the
+// SHA-1 hash leave the server (k-anonymity).
+type PasswordPolicy struct {
+	config config.PasswordConfig
+	client *http.Client
+}
+
+// NewPasswordPolicy creates a password policy from the configuration
+func NewPasswordPolicy(cfg *config.Config) *PasswordPolicy {
+	return &PasswordPolicy{
+		config: cfg.Password,
+		client: &http.Client{Timeout: cfg.Password.BreachCheckTimeout},
+	}
+}
+
+// Validate returns a *PasswordError when the password violates a rule or is known
+// from a data breach, and an error wrapping ErrBreachCheckUnavailable when the
+// breach lookup fails
+func (p *PasswordPolicy) Validate(ctx context.Context, password string) error {
+	if violations := p.checkRules(password); len(violations) > 0 {
+		return &PasswordError{Violations: violations}
+	}
+
+	if !p.config.BreachCheck {
+		return nil
+	}
+	count, err := p.breachCount(ctx, password)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrBreachCheckUnavailable, err)
+	}
+	if count > 0 {
+		return &PasswordError{Violations: []PasswordViolation{{
+			Message: "This password appeared in a data breach, please choose a different one",
+		}}}
+	}
+	return nil
+}
+
+func (p *PasswordPolicy) checkRules(password string) []PasswordViolation {
+	var hasUpper, hasLower, hasDigit, hasSymbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r):
+			hasSymbol = true
+		}
+	}
+
+	var violations []PasswordViolation
+	if utf8.RuneCountInString(password) < p.config.MinLength {
+		violations = append(violations, PasswordViolation{
+			Message: "Password must be at least %d characters long",
+			Args:    []interface{}{p.config.MinLength},
+		})
+	}
+	if p.config.RequireUpper && !hasUpper {
+		violations = append(violations, PasswordViolation{Message: "Password must contain an uppercase letter"})
+	}
+	if p.config.RequireLower && !hasLower {
+		violations = append(violations, PasswordViolation{Message: "Password must contain a lowercase letter"})
+	}
+	if p.config.RequireDigit && !hasDigit {
+		violations = append(violations, PasswordViolation{Message: "Password must contain a digit"})
+	}
+	if p.config.RequireSymbol && !hasSymbol {
+		violations = append(violations, PasswordViolation{Message: "Password must contain a special character"})
+	}
+	return violations
+}
+
+// breachCount returns how often the password appears in the breach corpus
+func (p *PasswordPolicy) breachCount(ctx context.Context, password string) (int, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := hash[:5], hash[5:]
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet,
+		strings.TrimRight(p.config.BreachAPIURL, "/")+"/range/"+prefix, nil)
+	if err != nil {
+		return 0, err
+	}
+	// Padded responses hide the number of matching suffixes
+	req.Header.Set("Add-Padding", "true")
+	req.Header.Set("User-Agent", "elterngeld-portal")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		candidate, countText, ok := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+		if !ok || !strings.EqualFold(candidate, suffix) {
+			continue
+		}
+		// Padding entries have a count of 0
+		count, err := strconv.Atoi(countText)
+		if err != nil {
+			return 0, fmt.Errorf("invalid count for hash suffix: %w", err)
+		}
+		return count, nil
+	}
+	return 0, scanner.Err()
+}
@@ -0,0 +1,113 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"elterngeld-portal/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPasswordPolicy_Rules(t *testing.T) {
+	policy := NewPasswordPolicy(&config.Config{Password: config.PasswordConfig{
+		MinLength:     10,
+		RequireUpper:  true,
+		RequireLower:  true,
+		RequireDigit:  true,
+		RequireSymbol: true,
+	}})
+
+	tests := []struct {
+		name       string
+		password   string
+		violations []string
+	}{
+		{"valid", "Elterngeld-2025", nil},
+		{"umlauts_count_as_letters", "Überweisung#1", nil},
+		{"too_short", "Ab1!", []string{"Password must be at least %d characters long"}},
+		{"missing_classes", "elterngeldantrag", []string{
+			"Password must contain an uppercase letter",
+			"Password must contain a digit",
+			"Password must contain a special character",
+		}},
+		{"missing_lowercase", "ELTERNGELD-2025", []string{"Password must contain a lowercase letter"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := policy.Validate(context.Background(), tt.password)
+			if tt.violations == nil {
+				assert.NoError(t, err)
+				return
+			}
+
+			var passwordErr *PasswordError
+			require.ErrorAs(t, err, &passwordErr)
+			messages := make([]string, len(passwordErr.Violations))
+			for i, v := range passwordErr.Violations {
+				messages[i] = v.Message
+			}
+			assert.Equal(t, tt.violations, messages)
+		})
+	}
+}
+
+func TestPasswordPolicy_BreachCheck(t *testing.T) {
+	sum := sha1.Sum([]byte("Passwort123"))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+
+	var requestedPath string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestedPath = r.URL.Path
+		assert.Equal(t, "true", r.Header.Get("Add-Padding"))
+		fmt.Fprintf(w, "0018A45C4D1DEF81644B54AB7F969B88D65:1\r\n%s:3861\r\n00D4F6E8FA6EECAD2A3AA415EEC418D38EC:0\r\n", hash[5:])
+	}))
+	defer server.Close()
+
+	policy := NewPasswordPolicy(&config.Config{Password: config.PasswordConfig{
+		MinLength:          8,
+		BreachCheck:        true,
+		BreachAPIURL:       server.URL + "/",
+		BreachCheckTimeout: time.Second,
+	}})
+
+	err := policy.Validate(context.Background(), "Passwort123")
+	var passwordErr *PasswordError
+	require.ErrorAs(t, err, &passwordErr)
+	assert.Equal(t, "This password appeared in a data breach, please choose a different one", passwordErr.Violations[0].Message)
+
+	// Only the hash prefix is sent
+	assert.Equal(t, "/range/"+hash[:5], requestedPath)
+
+	assert.NoError(t, policy.Validate(context.Background(), "Ein sicheres Passwort"))
+
+	// Rule violations are reported without asking the API
+	requestedPath = ""
+	assert.Error(t, policy.Validate(context.Background(), "kurz"))
+	assert.Empty(t, requestedPath)
+}
+
+func TestPasswordPolicy_BreachCheckUnavailable(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	policy := NewPasswordPolicy(&config.Config{Password: config.PasswordConfig{
+		BreachCheck:        true,
+		BreachAPIURL:       server.URL,
+		BreachCheckTimeout: time.Second,
+	}})
+
+	err := policy.Validate(context.Background(), "Passwort123")
+	assert.ErrorIs(t, err, ErrBreachCheckUnavailable)
+}
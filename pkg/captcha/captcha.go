@@ -0,0 +1,119 @@
+package captcha
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"elterngeld-portal/config"
+)
+
+// Supported captcha providers
+const (
+	ProviderRecaptcha = "recaptcha"
+	ProviderTurnstile = "turnstile"
+)
+
+var verifyURLs = map[string]string{
+	ProviderRecaptcha: "https://www.google.com/recaptcha/api/siteverify",
+	ProviderTurnstile: "https://challenges.cloudflare.com/turnstile/v0/siteverify",
+}
+
+var (
+	// ErrInvalidToken is returned when the provider rejects the captcha response
+	ErrInvalidToken = errors.New("captcha verification failed")
+	// ErrUnavailable is returned when the provider cannot be reached
+	ErrUnavailable = errors.New("captcha verification unavailable")
+)
+
+// siteverifyResponse is the answer of the reCAPTCHA and Turnstile siteverify endpoints
+type siteverifyResponse struct {
+	Success    bool     `json:"success"`
+	Score      *float64 `json:"score,omitempty"` // reCAPTCHA v3 only
+	ErrorCodes []string `json:"error-codes"`
+}
+
+// Verifier checks captcha responses with reCAPTCHA or Cloudflare Turnstile
+type Verifier struct {
+	provider  string
+	secret    string
+	verifyURL string
+	minScore  float64
+	client    *http.Client
+}
+
+// NewVerifier creates a verifier for the configured provider. It returns nil when
+// no provider is configured, which disables captcha checks.
+func NewVerifier(cfg *config.Config) (*Verifier, error) {
+	provider := strings.ToLower(strings.TrimSpace(cfg.Captcha.Provider))
+	if provider == "" {
+		return nil, nil
+	}
+
+	verifyURL, ok := verifyURLs[provider]
+	if !ok {
+		return nil, fmt.Errorf("unknown captcha provider %q", cfg.Captcha.Provider)
+	}
+	if cfg.Captcha.SecretKey == "" {
+		return nil, fmt.Errorf("CAPTCHA_SECRET_KEY is required for provider %q", provider)
+	}
+	if cfg.Captcha.VerifyURL != "" {
+		verifyURL = cfg.Captcha.VerifyURL
+	}
+
+	return &Verifier{
+		provider:  provider,
+		secret:    cfg.Captcha.SecretKey,
+		verifyURL: verifyURL,
+		minScore:  cfg.Captcha.MinScore,
+		client:    &http.Client{Timeout: cfg.Captcha.Timeout},
+	}, nil
+}
+
+// Provider returns the name of the configured provider
+func (v *Verifier) Provider() string {
+	return v.provider
+}
+
+// Verify checks a captcha response token. Scored reCAPTCHA v3 responses must
+// reach the configured minimum score.
+func (v *Verifier) Verify(ctx context.Context, token, remoteIP string) error {
+	form := url.Values{
+		"secret":   {v.secret},
+		"response": {token},
+	}
+	if remoteIP != "" {
+		form.Set("remoteip", remoteIP)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, v.verifyURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%w: unexpected status %d", ErrUnavailable, resp.StatusCode)
+	}
+
+	var result siteverifyResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("%w: invalid response: %v", ErrUnavailable, err)
+	}
+	if !result.Success {
+		return fmt.Errorf("%w: %s", ErrInvalidToken, strings.Join(result.ErrorCodes, ", "))
+	}
+	if result.Score != nil && *result.Score < v.minScore {
+		return fmt.Errorf("%w: score %.1f below %.1f", ErrInvalidToken, *result.Score, v.minScore)
+	}
+	return nil
+}
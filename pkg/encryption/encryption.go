@@ -0,0 +1,262 @@
+// Package encryption implements application-level envelope encryption for sensitive
+// personal data stored in the database.
+//
+// Every value is encrypted with its own random data key (AES-256-GCM). The data key
+// is wrapped with a master key from the keyring and stored next to the ciphertext:
+//
+//	enc:v1:<key id>:<wrapped data key>:<ciphertext>
+//
+// Rotating the master key therefore only rewraps the small data keys; the values
+// themselves are never decrypted during a rotation.
+package encryption
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync/atomic"
+)
+
+const (
+	prefix  = "enc:v1:"
+	keySize = 32
+)
+
+var (
+	// ErrUnknownKey is returned when a value was encrypted with a key missing from the keyring
+	ErrUnknownKey = errors.New("unknown encryption key")
+	// ErrMalformed is returned when a value is not a valid envelope
+	ErrMalformed = errors.New("malformed encrypted value")
+	// ErrDisabled is returned when encrypted data is read without a configured keyring
+	ErrDisabled = errors.New("encryption is not configured")
+)
+
+// Keyring holds the master keys. New values are always wrapped with the primary
+// key; the other keys are kept to read values until they are rotated.
+type Keyring struct {
+	keys    map[string][]byte
+	primary string
+}
+
+// NewKeyring creates a keyring from 32 byte AES keys. The primary key must be part of keys.
+func NewKeyring(keys map[string][]byte, primary string) (*Keyring, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("no encryption keys configured")
+	}
+	for id, key := range keys {
+		if id == "" || strings.Contains(id, ":") {
+			return nil, fmt.Errorf("invalid encryption key id %q", id)
+		}
+		if len(key) != keySize {
+			return nil, fmt.Errorf("encryption key %q must be %d bytes, got %d", id, keySize, len(key))
+		}
+	}
+	if _, ok := keys[primary]; !ok {
+		return nil, fmt.Errorf("primary encryption key %q is not configured", primary)
+	}
+
+	return &Keyring{keys: keys, primary: primary}, nil
+}
+
+// LoadKeyring creates a keyring from a comma separated list of "<id>:<base64 key>"
+// pairs. The first key is primary unless another id is given. An empty spec
+// returns nil, which disables encryption.
+func LoadKeyring(spec, primary string) (*Keyring, error) {
+	keys := make(map[string][]byte)
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		id, encoded, ok := strings.Cut(entry, ":")
+		if !ok {
+			return nil, fmt.Errorf("encryption key %q must have the form <id>:<base64 key>", entry)
+		}
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("encryption key %q is not valid base64: %w", id, err)
+		}
+		if _, exists := keys[id]; exists {
+			return nil, fmt.Errorf("encryption key %q is configured twice", id)
+		}
+		keys[id] = key
+
+		if primary == "" {
+			primary = id
+		}
+	}
+	if len(keys) == 0 {
+		return nil, nil
+	}
+
+	return NewKeyring(keys, primary)
+}
+
+// PrimaryKeyID returns the id of the key new values are encrypted with
+func (k *Keyring) PrimaryKeyID() string {
+	return k.primary
+}
+
+// KeyIDs returns the ids of all keys in the keyring, sorted
+func (k *Keyring) KeyIDs() []string {
+	ids := make([]string, 0, len(k.keys))
+	for id := range k.keys {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Encrypt encrypts plaintext with a new data key wrapped by the primary key
+func (k *Keyring) Encrypt(plaintext []byte) (string, error) {
+	dataKey := make([]byte, keySize)
+	if _, err := io.ReadFull(rand.Reader, dataKey); err != nil {
+		return "", fmt.Errorf("failed to generate data key: %w", err)
+	}
+
+	ciphertext, err := seal(dataKey, plaintext)
+	if err != nil {
+		return "", err
+	}
+	wrapped, err := seal(k.keys[k.primary], dataKey)
+	if err != nil {
+		return "", err
+	}
+
+	return format(k.primary, wrapped, ciphertext), nil
+}
+
+// Decrypt decrypts a value created by Encrypt
+func (k *Keyring) Decrypt(value string) ([]byte, error) {
+	keyID, wrapped, ciphertext, err := parse(value)
+	if err != nil {
+		return nil, err
+	}
+
+	dataKey, err := k.unwrap(keyID, wrapped)
+	if err != nil {
+		return nil, err
+	}
+	return open(dataKey, ciphertext)
+}
+
+// Rewrap re-encrypts the data key of a value with the primary key. It reports
+// false if the value already uses the primary key.
+func (k *Keyring) Rewrap(value string) (string, bool, error) {
+	keyID, wrapped, ciphertext, err := parse(value)
+	if err != nil {
+		return "", false, err
+	}
+	if keyID == k.primary {
+		return value, false, nil
+	}
+
+	dataKey, err := k.unwrap(keyID, wrapped)
+	if err != nil {
+		return "", false, err
+	}
+	rewrapped, err := seal(k.keys[k.primary], dataKey)
+	if err != nil {
+		return "", false, err
+	}
+
+	return format(k.primary, rewrapped, ciphertext), true, nil
+}
+
+// IsEncrypted checks if a stored value is an encryption envelope
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, prefix)
+}
+
+var defaultKeyring atomic.Pointer[Keyring]
+
+// SetDefault sets the keyring used by the GORM serializer. A nil keyring disables
+// encryption of new values.
+func SetDefault(k *Keyring) {
+	defaultKeyring.Store(k)
+}
+
+// Default returns the keyring used by the GORM serializer, or nil if encryption is disabled
+func Default() *Keyring {
+	return defaultKeyring.Load()
+}
+
+func (k *Keyring) unwrap(keyID string, wrapped []byte) ([]byte, error) {
+	masterKey, ok := k.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownKey, keyID)
+	}
+	return open(masterKey, wrapped)
+}
+
+func seal(key, plaintext []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	return gcm.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+func open(key, sealed []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return nil, ErrMalformed
+	}
+
+	nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt value: %w", err)
+	}
+	return plaintext, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func format(keyID string, wrapped, ciphertext []byte) string {
+	return prefix + keyID + ":" +
+		base64.RawStdEncoding.EncodeToString(wrapped) + ":" +
+		base64.RawStdEncoding.EncodeToString(ciphertext)
+}
+
+func parse(value string) (string, []byte, []byte, error) {
+	if !IsEncrypted(value) {
+		return "", nil, nil, ErrMalformed
+	}
+
+	parts := strings.Split(strings.TrimPrefix(value, prefix), ":")
+	if len(parts) != 3 {
+		return "", nil, nil, ErrMalformed
+	}
+	wrapped, err := base64.RawStdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", nil, nil, ErrMalformed
+	}
+	ciphertext, err := base64.RawStdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", nil, nil, ErrMalformed
+	}
+
+	return parts[0], wrapped, ciphertext, nil
+}
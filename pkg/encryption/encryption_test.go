@@ -0,0 +1,216 @@
+package encryption
+
+import (
+	"bytes"
+	"encoding/base64"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+type testPerson struct {
+	ID          string `gorm:"primaryKey"`
+	Name        string
+	Address     string     `gorm:"type:text;serializer:encrypted"`
+	DateOfBirth *time.Time `gorm:"type:text;serializer:encrypted"`
+}
+
+func testKey(b byte) []byte {
+	return bytes.Repeat([]byte{b}, keySize)
+}
+
+func testKeyring(t *testing.T, primary string) *Keyring {
+	keyring, err := NewKeyring(map[string][]byte{"k1": testKey(1), "k2": testKey(2)}, primary)
+	require.NoError(t, err)
+	return keyring
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	keyring := testKeyring(t, "k1")
+
+	value, err := keyring.Encrypt([]byte("Musterstraße 1, 10115 Berlin"))
+	require.NoError(t, err)
+	assert.True(t, IsEncrypted(value))
+	assert.True(t, strings.HasPrefix(value, "enc:v1:k1:"))
+	assert.NotContains(t, value, "Musterstraße")
+
+	again, err := keyring.Encrypt([]byte("Musterstraße 1, 10115 Berlin"))
+	require.NoError(t, err)
+	assert.NotEqual(t, value, again, "every value gets its own data key and nonce")
+
+	plaintext, err := keyring.Decrypt(value)
+	require.NoError(t, err)
+	assert.Equal(t, "Musterstraße 1, 10115 Berlin", string(plaintext))
+}
+
+func TestDecrypt_Errors(t *testing.T) {
+	keyring := testKeyring(t, "k1")
+	value, err := keyring.Encrypt([]byte("secret"))
+	require.NoError(t, err)
+
+	other, err := NewKeyring(map[string][]byte{"k3": testKey(3)}, "k3")
+	require.NoError(t, err)
+	_, err = other.Decrypt(value)
+	assert.ErrorIs(t, err, ErrUnknownKey)
+
+	_, err = keyring.Decrypt("plaintext")
+	assert.ErrorIs(t, err, ErrMalformed)
+
+	tampered := value[:len(value)-2] + "AA"
+	_, err = keyring.Decrypt(tampered)
+	assert.Error(t, err)
+}
+
+func TestRewrap(t *testing.T) {
+	old := testKeyring(t, "k1")
+	value, err := old.Encrypt([]byte("secret"))
+	require.NoError(t, err)
+
+	rotated := testKeyring(t, "k2")
+	rewrapped, changed, err := rotated.Rewrap(value)
+	require.NoError(t, err)
+	assert.True(t, changed)
+	assert.True(t, strings.HasPrefix(rewrapped, "enc:v1:k2:"))
+	// Only the data key changes, the ciphertext stays the same
+	assert.Equal(t, value[strings.LastIndex(value, ":"):], rewrapped[strings.LastIndex(rewrapped, ":"):])
+
+	plaintext, err := rotated.Decrypt(rewrapped)
+	require.NoError(t, err)
+	assert.Equal(t, "secret", string(plaintext))
+
+	_, changed, err = rotated.Rewrap(rewrapped)
+	require.NoError(t, err)
+	assert.False(t, changed)
+}
+
+func TestLoadKeyring(t *testing.T) {
+	k1 := base64.StdEncoding.EncodeToString(testKey(1))
+	k2 := base64.StdEncoding.EncodeToString(testKey(2))
+
+	keyring, err := LoadKeyring("", "")
+	require.NoError(t, err)
+	assert.Nil(t, keyring)
+
+	keyring, err = LoadKeyring("old:"+k1+", new:"+k2, "")
+	require.NoError(t, err)
+	assert.Equal(t, "old", keyring.PrimaryKeyID())
+	assert.Equal(t, []string{"new", "old"}, keyring.KeyIDs())
+
+	keyring, err = LoadKeyring("old:"+k1+",new:"+k2, "new")
+	require.NoError(t, err)
+	assert.Equal(t, "new", keyring.PrimaryKeyID())
+
+	for _, spec := range []string{
+		"missing-separator",
+		"short:" + base64.StdEncoding.EncodeToString([]byte("too short")),
+		"bad:not-base64!",
+		"dup:" + k1 + ",dup:" + k2,
+	} {
+		_, err := LoadKeyring(spec, "")
+		assert.Error(t, err, spec)
+	}
+
+	_, err = LoadKeyring("old:"+k1, "unknown")
+	assert.Error(t, err)
+}
+
+func TestSerializer(t *testing.T) {
+	db := setupTestDB(t)
+	birthday := time.Date(1990, 5, 15, 0, 0, 0, 0, time.UTC)
+
+	SetDefault(testKeyring(t, "k1"))
+	require.NoError(t, db.Create(&testPerson{ID: "1", Name: "Anna", Address: "Hauptstraße 5", DateOfBirth: &birthday}).Error)
+	require.NoError(t, db.Create(&testPerson{ID: "2", Name: "Ben"}).Error)
+
+	var stored struct {
+		Address     *string
+		DateOfBirth *string
+	}
+	require.NoError(t, db.Table("test_people").Select("address, date_of_birth").Where("id = ?", "1").Scan(&stored).Error)
+	require.NotNil(t, stored.Address)
+	assert.True(t, IsEncrypted(*stored.Address))
+	assert.True(t, IsEncrypted(*stored.DateOfBirth))
+
+	var person testPerson
+	require.NoError(t, db.First(&person, "id = ?", "1").Error)
+	assert.Equal(t, "Hauptstraße 5", person.Address)
+	require.NotNil(t, person.DateOfBirth)
+	assert.True(t, birthday.Equal(*person.DateOfBirth))
+
+	var empty testPerson
+	require.NoError(t, db.First(&empty, "id = ?", "2").Error)
+	assert.Empty(t, empty.Address)
+	assert.Nil(t, empty.DateOfBirth)
+
+	SetDefault(nil)
+	err := db.First(&person, "id = ?", "1").Error
+	assert.ErrorIs(t, err, ErrDisabled)
+}
+
+func TestSerializer_ReadsLegacyPlaintext(t *testing.T) {
+	db := setupTestDB(t)
+	SetDefault(testKeyring(t, "k1"))
+
+	require.NoError(t, db.Exec("INSERT INTO test_people (id, name, address, date_of_birth) VALUES (?, ?, ?, ?)",
+		"1", "Anna", "Hauptstraße 5", "1990-05-15 00:00:00+00:00").Error)
+
+	var person testPerson
+	require.NoError(t, db.First(&person, "id = ?", "1").Error)
+	assert.Equal(t, "Hauptstraße 5", person.Address)
+	require.NotNil(t, person.DateOfBirth)
+	assert.Equal(t, "1990-05-15", person.DateOfBirth.Format("2006-01-02"))
+}
+
+func TestRotate(t *testing.T) {
+	db := setupTestDB(t)
+
+	// Written before encryption was enabled and with the old key
+	SetDefault(nil)
+	require.NoError(t, db.Create(&testPerson{ID: "1", Name: "Anna", Address: "Hauptstraße 5"}).Error)
+	SetDefault(testKeyring(t, "k1"))
+	require.NoError(t, db.Create(&testPerson{ID: "2", Name: "Ben", Address: "Nebenweg 2"}).Error)
+
+	rotated := testKeyring(t, "k2")
+	stats, err := rotated.Rotate(db, &testPerson{})
+	require.NoError(t, err)
+	assert.Equal(t, RotationStats{Encrypted: 1, Rewrapped: 1}, stats)
+
+	var addresses []string
+	require.NoError(t, db.Table("test_people").Order("id").Pluck("address", &addresses).Error)
+	for _, address := range addresses {
+		assert.True(t, strings.HasPrefix(address, "enc:v1:k2:"), address)
+	}
+
+	// The old key is no longer needed
+	newOnly, err := NewKeyring(map[string][]byte{"k2": testKey(2)}, "k2")
+	require.NoError(t, err)
+	SetDefault(newOnly)
+
+	var people []testPerson
+	require.NoError(t, db.Order("id").Find(&people).Error)
+	require.Len(t, people, 2)
+	assert.Equal(t, "Hauptstraße 5", people[0].Address)
+	assert.Equal(t, "Nebenweg 2", people[1].Address)
+
+	stats, err = newOnly.Rotate(db, &testPerson{})
+	require.NoError(t, err)
+	assert.Equal(t, RotationStats{Unchanged: 2}, stats)
+}
+
+func setupTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&testPerson{}))
+
+	t.Cleanup(func() { SetDefault(nil) })
+	return db
+}
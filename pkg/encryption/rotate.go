@@ -0,0 +1,89 @@
+package encryption
+
+import (
+	"fmt"
+	"sync"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// RotationStats counts the values visited by a key rotation
+type RotationStats struct {
+	Encrypted int `json:"encrypted"`
+	Rewrapped int `json:"rewrapped"`
+	Unchanged int `json:"unchanged"`
+}
+
+type storedValue struct {
+	ID    string
+	Value *string
+}
+
+// Rotate rewraps all encrypted fields of the given models with the primary key and
+// encrypts values still stored as plaintext. Old keys can be removed from the
+// keyring once a rotation finished without errors.
+func (k *Keyring) Rotate(db *gorm.DB, models ...interface{}) (RotationStats, error) {
+	var stats RotationStats
+
+	for _, model := range models {
+		s, err := schema.Parse(model, &sync.Map{}, db.NamingStrategy)
+		if err != nil {
+			return stats, fmt.Errorf("failed to parse model: %w", err)
+		}
+		if s.PrioritizedPrimaryField == nil {
+			return stats, fmt.Errorf("model %s has no primary key", s.Name)
+		}
+		pk := s.PrioritizedPrimaryField.DBName
+
+		for _, field := range s.Fields {
+			if field.TagSettings["SERIALIZER"] != SerializerName {
+				continue
+			}
+			if err := k.rotateColumn(db, s.Table, pk, field.DBName, &stats); err != nil {
+				return stats, fmt.Errorf("failed to rotate %s.%s: %w", s.Table, field.DBName, err)
+			}
+		}
+	}
+
+	return stats, nil
+}
+
+func (k *Keyring) rotateColumn(db *gorm.DB, table, pk, column string, stats *RotationStats) error {
+	var rows []storedValue
+	if err := db.Table(table).
+		Select(fmt.Sprintf("%s AS id, %s AS value", pk, column)).
+		Where(column+" IS NOT NULL AND "+column+" <> ?", "").
+		Scan(&rows).Error; err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		value := *row.Value
+		var updated string
+		if IsEncrypted(value) {
+			rewrapped, changed, err := k.Rewrap(value)
+			if err != nil {
+				return fmt.Errorf("row %s: %w", row.ID, err)
+			}
+			if !changed {
+				stats.Unchanged++
+				continue
+			}
+			updated = rewrapped
+			stats.Rewrapped++
+		} else {
+			encrypted, err := k.Encrypt([]byte(value))
+			if err != nil {
+				return fmt.Errorf("row %s: %w", row.ID, err)
+			}
+			updated = encrypted
+			stats.Encrypted++
+		}
+
+		if err := db.Table(table).Where(pk+" = ?", row.ID).UpdateColumn(column, updated).Error; err != nil {
+			return fmt.Errorf("row %s: %w", row.ID, err)
+		}
+	}
+	return nil
+}
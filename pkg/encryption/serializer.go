@@ -0,0 +1,150 @@
+package encryption
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"time"
+
+	"gorm.io/gorm/schema"
+)
+
+// SerializerName is the GORM serializer that encrypts a field: `gorm:"type:text;serializer:encrypted"`
+const SerializerName = "encrypted"
+
+func init() {
+	schema.RegisterSerializer(SerializerName, Serializer{})
+}
+
+// Serializer transparently encrypts fields on write and decrypts them on read.
+// Strings are encrypted as they are, all other types as JSON. Values written before
+// encryption was enabled are read as plaintext until the next key rotation.
+type Serializer struct{}
+
+// Scan decrypts a database value into the field
+func (Serializer) Scan(ctx context.Context, field *schema.Field, dst reflect.Value, dbValue interface{}) error {
+	value := reflect.New(field.FieldType)
+	if dbValue != nil {
+		if err := decodeValue(dbValue, value); err != nil {
+			return fmt.Errorf("failed to read encrypted field %s: %w", field.Name, err)
+		}
+	}
+
+	field.ReflectValueOf(ctx, dst).Set(value.Elem())
+	return nil
+}
+
+// Value encrypts the field value with the default keyring. Nil values stay NULL
+// and empty strings stay empty, so presence checks keep working.
+func (Serializer) Value(ctx context.Context, field *schema.Field, dst reflect.Value, fieldValue interface{}) (interface{}, error) {
+	plaintext, ok, err := encodePlain(fieldValue)
+	if err != nil || !ok {
+		return nil, err
+	}
+	if len(plaintext) == 0 {
+		return "", nil
+	}
+
+	keyring := Default()
+	if keyring == nil {
+		return string(plaintext), nil
+	}
+	return keyring.Encrypt(plaintext)
+}
+
+// encodePlain returns the plaintext representation of a value. It reports false for nil.
+func encodePlain(value interface{}) ([]byte, bool, error) {
+	rv := reflect.ValueOf(value)
+	if !rv.IsValid() || (rv.Kind() == reflect.Ptr && rv.IsNil()) {
+		return nil, false, nil
+	}
+	rv = reflect.Indirect(rv)
+
+	if rv.Kind() == reflect.String {
+		return []byte(rv.String()), true, nil
+	}
+	data, err := json.Marshal(rv.Interface())
+	return data, true, err
+}
+
+// decodeValue decodes a stored, possibly encrypted value into target, a pointer to the field type
+func decodeValue(dbValue interface{}, target reflect.Value) error {
+	var raw string
+	switch v := dbValue.(type) {
+	case string:
+		raw = v
+	case []byte:
+		raw = string(v)
+	case time.Time:
+		// Plaintext from a timestamp column that was not yet converted to text
+		return assign(target.Elem(), reflect.ValueOf(v))
+	default:
+		return fmt.Errorf("unsupported database value %T", dbValue)
+	}
+
+	if raw == "" {
+		return nil
+	}
+	if IsEncrypted(raw) {
+		keyring := Default()
+		if keyring == nil {
+			return ErrDisabled
+		}
+		plaintext, err := keyring.Decrypt(raw)
+		if err != nil {
+			return err
+		}
+		raw = string(plaintext)
+	}
+
+	return decodePlain(raw, target)
+}
+
+// legacyTimeLayouts are the formats timestamps were stored in before encryption
+var legacyTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999-07:00",
+	"2006-01-02 15:04:05.999999999-07",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02",
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func decodePlain(raw string, target reflect.Value) error {
+	base := target.Elem().Type()
+	if base.Kind() == reflect.Ptr {
+		base = base.Elem()
+	}
+
+	switch {
+	case base.Kind() == reflect.String:
+		return assign(target.Elem(), reflect.ValueOf(raw).Convert(base))
+	case base == timeType:
+		var t time.Time
+		if err := json.Unmarshal([]byte(raw), &t); err == nil {
+			return assign(target.Elem(), reflect.ValueOf(t))
+		}
+		for _, layout := range legacyTimeLayouts {
+			if t, err := time.Parse(layout, raw); err == nil {
+				return assign(target.Elem(), reflect.ValueOf(t))
+			}
+		}
+		return fmt.Errorf("invalid time value")
+	default:
+		return json.Unmarshal([]byte(raw), target.Interface())
+	}
+}
+
+// assign sets dst to v, allocating a pointer if the field is a pointer
+func assign(dst, v reflect.Value) error {
+	if dst.Kind() == reflect.Ptr {
+		ptr := reflect.New(dst.Type().Elem())
+		ptr.Elem().Set(v)
+		dst.Set(ptr)
+		return nil
+	}
+	dst.Set(v)
+	return nil
+}
@@ -0,0 +1,708 @@
+package i18n
+
+// germanMessages translates the English message IDs used in API responses and emails
+var germanMessages = map[string]string{
+	// Authentication and authorization
+	"A refund must be approved by a second admin": "Eine Erstattung muss von einem zweiten Admin freigegeben werden",
+	"Access denied":                                       "Zugriff verweigert",
+	"Access token lacks the %s scope":                     "Dem Zugriffstoken fehlt der Bereich %s",
+	"Account has been deactivated":                        "Konto wurde deaktiviert",
+	"API key is not allowed from this IP address":         "Der API-Schlüssel ist von dieser IP-Adresse aus nicht erlaubt",
+	"API key is required":                                 "API-Schlüssel erforderlich",
+	"API key lacks the %s scope":                          "Dem API-Schlüssel fehlt der Scope %s",
+	"Authorization header is required":                    "Authorization-Header erforderlich",
+	"Captcha verification failed":                         "Captcha-Prüfung fehlgeschlagen",
+	"Captcha verification is required":                    "Captcha-Prüfung erforderlich",
+	"Failed to process password":                          "Passwort konnte nicht verarbeitet werden",
+	"Insufficient permissions":                            "Unzureichende Berechtigungen",
+	"Invalid API key":                                     "Ungültiger API-Schlüssel",
+	"Invalid authorization header format":                 "Ungültiges Format des Authorization-Headers",
+	"Invalid credentials":                                 "Ungültige Anmeldedaten",
+	"Invalid CSRF token":                                  "Ungültiges CSRF-Token",
+	"Invalid refresh token":                               "Ungültiges Refresh-Token",
+	"Invalid token":                                       "Ungültiges Token",
+	"Invalid user ID type":                                "Ungültiger Typ der Benutzer-ID",
+	"Invalid user or user cannot be assigned leads":       "Ungültiger Benutzer oder dem Benutzer können keine Leads zugewiesen werden",
+	"Invalid user role type":                              "Ungültiger Typ der Benutzerrolle",
+	"Only Berater can claim leads":                        "Nur Berater können Leads übernehmen",
+	"Only team members can share saved views":             "Nur Teammitglieder können Ansichten teilen",
+	"Only the author or an admin can change this comment": "Nur die verfassende Person oder ein Admin kann diesen Kommentar ändern",
+	"Only the claimer or an admin can release this item":  "Nur wer die Anfrage übernommen hat oder ein Admin kann sie freigeben",
+	"Only the owner can change this saved view":           "Nur die Person, die die Ansicht angelegt hat, kann sie ändern",
+	"Only the owner or an admin can change this snippet":  "Nur der Ersteller oder ein Admin kann diesen Textbaustein ändern",
+	"Rate limit exceeded":                                 "Anfragelimit überschritten",
+	"Refresh token is required":                           "Refresh-Token ist erforderlich",
+	"Scope exceeds your permissions":                      "Der Scope übersteigt Ihre Berechtigungen",
+	"This endpoint cannot be used with an access token":   "Dieser Endpunkt kann nicht mit einem Zugriffstoken verwendet werden",
+	"Token has been revoked":                              "Token wurde widerrufen",
+	"Token has expired":                                   "Token ist abgelaufen",
+	"User ID not found in context":                        "Benutzer-ID nicht im Kontext gefunden",
+	"User not authenticated":                              "Benutzer nicht angemeldet",
+	"User role not found in context":                      "Benutzerrolle nicht im Kontext gefunden",
+	"User with this email already exists":                 "Ein Benutzer mit dieser E-Mail-Adresse existiert bereits",
+	"You may not change who sees this document":           "Sie dürfen die Sichtbarkeit dieses Dokuments nicht ändern",
+
+	"Internal server error": "Interner Serverfehler",
+
+	// Request validation
+	"A coupon needs either a percentage or an amount off":                             "Ein Rabattcode braucht entweder einen Prozent- oder einen Festbetragsrabatt",
+	"A justification is required":                                                     "Eine Begründung ist erforderlich",
+	"A payment link needs an amount and a title":                                      "Ein Zahlungslink benötigt einen Betrag und einen Titel",
+	"Amount must be positive":                                                         "Der Betrag muss positiv sein",
+	"An experiment needs at least two variants with unique keys and positive weights": "Ein Experiment benötigt mindestens zwei Varianten mit eindeutigen Schlüsseln und positiver Gewichtung",
+	"Attached document not found on lead":                                             "Angehängtes Dokument wurde beim Lead nicht gefunden",
+	"Attendance can only be recorded for confirmed bookings":                          "Die Teilnahme kann nur für bestätigte Termine erfasst werden",
+	"Author must be a Berater":                                                        "Autor muss ein Berater sein",
+	"Avatar must be an image":                                                         "Das Profilbild muss ein Bild sein",
+	"Comment content is required":                                                     "Kommentarinhalt ist erforderlich",
+	"Confirm the permanent delete with the record ID":                                 "Bestätigen Sie das endgültige Löschen mit der ID des Datensatzes",
+	"Cost must not be negative":                                                       "Die Kosten dürfen nicht negativ sein",
+	"Current password is incorrect":                                                   "Aktuelles Passwort ist falsch",
+	"Document cannot be watermarked":                                                  "Das Dokument kann nicht mit einem Wasserzeichen versehen werden",
+	"End date must not be before start date":                                          "Das Enddatum darf nicht vor dem Startdatum liegen",
+	"Evaluation needs ratings, a recommendation or notes":                             "Die Bewertung benötigt Punkte, eine Empfehlung oder Notizen",
+	"Expiry date must be in the future":                                               "Das Ablaufdatum muss in der Zukunft liegen",
+	"Expiry date must be in the future and at most one year ahead":                    "Das Ablaufdatum muss in der Zukunft und höchstens ein Jahr entfernt liegen",
+	"Failed to read request body":                                                     "Anfrage konnte nicht gelesen werden",
+	"File size exceeds maximum allowed size":                                          "Die Datei überschreitet die maximal zulässige Größe",
+	"File type not allowed":                                                           "Dateityp nicht erlaubt",
+	"Grace period must be between 0 and 168 hours":                                    "Die Übergangsfrist muss zwischen 0 und 168 Stunden liegen",
+	"Guides need a valid Bundesland, other content none":                              "Leitfäden brauchen ein gültiges Bundesland, andere Inhalte keines",
+	"Interview slot must be in the future":                                            "Der Gesprächstermin muss in der Zukunft liegen",
+	"Interviewer must be an active staff member":                                      "Gesprächspartner muss ein aktives Teammitglied sein",
+	"Invalid access token ID":                                                         "Ungültige Zugriffstoken-ID",
+	"Invalid activity ID":                                                             "Ungültige Aktivitäts-ID",
+	"Invalid activity type":                                                           "Ungültiger Aktivitätstyp",
+	"Invalid announcement audience":                                                   "Ungültige Zielgruppe der Ankündigung",
+	"Invalid announcement ID":                                                         "Ungültige Ankündigungs-ID",
+	"Invalid answers":                                                                 "Ungültige Antworten",
+	"Invalid API key ID":                                                              "Ungültige API-Schlüssel-ID",
+	"Invalid application ID":                                                          "Ungültige Bewerbungs-ID",
+	"Invalid application link":                                                        "Ungültiger Bewerbungslink",
+	"Invalid attachment encoding":                                                     "Ungültige Kodierung des Anhangs",
+	"Invalid author ID":                                                               "Ungültige Autor-ID",
+	"Invalid availability":                                                            "Ungültige Verfügbarkeit",
+	"Invalid Berater ID":                                                              "Ungültige Berater-ID",
+	"Invalid booking ID":                                                              "Ungültige Buchungs-ID",
+	"Invalid Bundesland":                                                              "Ungültiges Bundesland",
+	"Invalid chat channel ID":                                                         "Ungültige Chat-Kanal-ID",
+	"Invalid chat provider":                                                           "Ungültiger Chat-Anbieter",
+	"Invalid checkout step":                                                           "Ungültiger Checkout-Schritt",
+	"Invalid comment ID":                                                              "Ungültige Kommentar-ID",
+	"Invalid contact form ID":                                                         "Ungültige Kontaktanfrage-ID",
+	"Invalid content ID":                                                              "Ungültige Inhalts-ID",
+	"Invalid content kind":                                                            "Ungültige Inhaltsart",
+	"Invalid country":                                                                 "Ungültiges Land",
+	"Invalid criterion ID":                                                            "Ungültige Kriteriums-ID",
+	"Invalid cursor":                                                                  "Ungültiger Cursor",
+	"Invalid date format. Use YYYY-MM-DD":                                             "Ungültiges Datumsformat. Bitte JJJJ-MM-TT verwenden",
+	"Invalid digest mode":                                                             "Ungültiger Zusammenfassungsmodus",
+	"Invalid document ID":                                                             "Ungültige Dokument-ID",
+	"Invalid document link":                                                           "Ungültiger Dokumentlink",
+	"Invalid document request ID":                                                     "Ungültige Nachforderungs-ID",
+	"Invalid document visibility":                                                     "Ungültige Sichtbarkeit des Dokuments",
+	"Invalid event":                                                                   "Ungültiges Ereignis",
+	"Invalid experiment ID":                                                           "Ungültige Experiment-ID",
+	"Invalid experiment key":                                                          "Ungültiger Experiment-Schlüssel",
+	"Invalid export format. Use pdf or zip":                                           "Ungültiges Exportformat. Verwenden Sie pdf oder zip",
+	"Invalid family ID":                                                               "Ungültige Familien-ID",
+	"Invalid form data":                                                               "Ungültige Formulardaten",
+	"Invalid holiday override ID":                                                     "Ungültige Feiertagsausnahme-ID",
+	"Invalid inbound email ID":                                                        "Ungültige E-Mail-ID",
+	"Invalid invitation ID":                                                           "Ungültige Einladungs-ID",
+	"Invalid IP address or range":                                                     "Ungültige IP-Adresse oder ungültiger Adressbereich",
+	"Invalid job ID":                                                                  "Ungültige Stellen-ID",
+	"Invalid language":                                                                "Ungültige Sprache",
+	"Invalid lead aging rule ID":                                                      "Ungültige Regel-ID",
+	"Invalid lead ID":                                                                 "Ungültige Lead-ID",
+	"Invalid marketing spend ID":                                                      "Ungültige ID der Marketingausgabe",
+	"Invalid mobile number":                                                           "Ungültige Mobilnummer",
+	"Invalid notification category":                                                   "Ungültige Benachrichtigungskategorie",
+	"Invalid outbox message ID":                                                       "Ungültige Outbox-Nachrichten-ID",
+	"Invalid package ID":                                                              "Ungültige Paket-ID",
+	"Invalid payment ID":                                                              "Ungültige Zahlungs-ID",
+	"Invalid payment link ID":                                                         "Ungültige Zahlungslink-ID",
+	"Invalid PDF data":                                                                "Ungültige PDF-Daten",
+	"Invalid PDF job ID":                                                              "Ungültige PDF-Auftrags-ID",
+	"Invalid period":                                                                  "Ungültiger Zeitraum",
+	"Invalid post ID":                                                                 "Ungültige Beitrags-ID",
+	"Invalid postal code":                                                             "Ungültige Postleitzahl",
+	"Invalid priority":                                                                "Ungültige Priorität",
+	"Invalid product update audience":                                                 "Ungültige Zielgruppe des Produkt-Updates",
+	"Invalid product update ID":                                                       "Ungültige ID des Produkt-Updates",
+	"Invalid product update kind":                                                     "Ungültige Art des Produkt-Updates",
+	"Invalid question":                                                                "Ungültige Frage",
+	"Invalid question ID":                                                             "Ungültige Fragen-ID",
+	"Invalid questionnaire ID":                                                        "Ungültige Fragebogen-ID",
+	"Invalid record ID":                                                               "Ungültige Datensatz-ID",
+	"Invalid refund request ID":                                                       "Ungültige Erstattungsantrags-ID",
+	"Invalid refund status":                                                           "Ungültiger Erstattungsstatus",
+	"Invalid request body":                                                            "Ungültiger Anfrageinhalt",
+	"Invalid request data":                                                            "Ungültige Anfragedaten",
+	"Invalid review status":                                                           "Ungültiger Prüfstatus",
+	"Invalid role":                                                                    "Ungültige Rolle",
+	"Invalid role format":                                                             "Ungültiges Rollenformat",
+	"Invalid routing rule ID":                                                         "Ungültige Regel-ID",
+	"Invalid saved view configuration":                                                "Ungültige Konfiguration der gespeicherten Ansicht",
+	"Invalid saved view ID":                                                           "Ungültige ID der gespeicherten Ansicht",
+	"Invalid scenario ID":                                                             "Ungültige Szenario-ID",
+	"Invalid scenario plan":                                                           "Ungültiger Szenario-Plan",
+	"Invalid score":                                                                   "Ungültige Bewertung",
+	"Invalid service key ID":                                                          "Ungültige Dienstschlüssel-ID",
+	"Invalid session":                                                                 "Ungültige Sitzung",
+	"Invalid session metadata":                                                        "Ungültige Sitzungsdaten",
+	"Invalid setting value":                                                           "Ungültiger Wert für die Einstellung",
+	"Invalid signature":                                                               "Ungültige Signatur",
+	"Invalid snippet ID":                                                              "Ungültige Textbaustein-ID",
+	"Invalid sort":                                                                    "Ungültige Sortierung",
+	"Invalid specialization":                                                          "Ungültiges Fachgebiet",
+	"Invalid status":                                                                  "Ungültiger Status",
+	"Invalid status format":                                                           "Ungültiges Statusformat",
+	"Invalid submission ID":                                                           "Ungültige Einreichungs-ID",
+	"Invalid time format. Use RFC3339":                                                "Ungültiges Zeitformat. Verwenden Sie RFC3339",
+	"Invalid timeslot ID":                                                             "Ungültige Termin-ID",
+	"Invalid user ID":                                                                 "Ungültige Benutzer-ID",
+	"Invalid verification link":                                                       "Ungültiger Bestätigungslink",
+	"Invalid webhook event ID":                                                        "Ungültige Webhook-Ereignis-ID",
+	"Invalid webhook URL for this provider":                                           "Ungültige Webhook-URL für diesen Anbieter",
+	"Invalid year":                                                                    "Ungültiges Jahr",
+	"Kindergeld rates are not available for this year":                                "Für dieses Jahr sind keine Kindergeldsätze hinterlegt",
+	"Lead aging rules only apply to open lead statuses":                               "Regeln zur Lead-Alterung gelten nur für offene Lead-Status",
+	"Leads must not be closed before the last win-back email":                         "Leads dürfen nicht vor der letzten Reaktivierungs-E-Mail geschlossen werden",
+	"Maintenance windows need a start before their end":                               "Wartungsfenster brauchen einen Beginn vor ihrem Ende",
+	"Message is required":                                                             "Nachricht ist erforderlich",
+	"No file uploaded":                                                                "Keine Datei hochgeladen",
+	"No valid fields to update":                                                       "Keine gültigen Felder zum Aktualisieren",
+	"Onboarding is incomplete":                                                        "Das Onboarding ist noch nicht vollständig",
+	"Package ID is required":                                                          "Paket-ID erforderlich",
+	"Password does not meet the requirements":                                         "Das Passwort erfüllt die Anforderungen nicht",
+	"Password must be at least %d characters long":                                    "Das Passwort muss mindestens %d Zeichen lang sein",
+	"Password must contain a digit":                                                   "Das Passwort muss eine Ziffer enthalten",
+	"Password must contain a lowercase letter":                                        "Das Passwort muss einen Kleinbuchstaben enthalten",
+	"Password must contain a special character":                                       "Das Passwort muss ein Sonderzeichen enthalten",
+	"Password must contain an uppercase letter":                                       "Das Passwort muss einen Großbuchstaben enthalten",
+	"Payment cannot be refunded":                                                      "Die Zahlung kann nicht erstattet werden",
+	"Query must be at least %d characters long":                                       "Die Suche muss mindestens %d Zeichen lang sein",
+	"Rate limit must be between 0 and %d requests per minute":                         "Das Anfragelimit muss zwischen 0 und %d Anfragen pro Minute liegen",
+	"Ratings must cover distinct active criteria":                                     "Bewertungen müssen verschiedene aktive Kriterien betreffen",
+	"Refund exceeds the refundable amount":                                            "Die Erstattung übersteigt den erstattbaren Betrag",
+	"Reply subject and body are required":                                             "Betreff und Text der Antwort sind erforderlich",
+	"Request body too large":                                                          "Anfrage ist zu groß",
+	"Role is required":                                                                "Rolle erforderlich",
+	"Saved views are only available for leads and bookings":                           "Gespeicherte Ansichten gibt es nur für Leads und Buchungen",
+	"Session ID is required":                                                          "Sitzungs-ID erforderlich",
+	"Slug may only contain lowercase letters, digits and dashes":                      "Der Slug darf nur Kleinbuchstaben, Ziffern und Bindestriche enthalten",
+	"Status is required":                                                              "Status erforderlich",
+	"Storage quota exceeded":                                                          "Speicherkontingent überschritten",
+	"Talent pool requires the consent of the applicant":                               "Für den Talentpool ist die Einwilligung des Bewerbers erforderlich",
+	"Text recognition is not available for this document":                             "Für dieses Dokument ist keine Texterkennung verfügbar",
+	"The booking has not ended yet":                                                   "Der Termin ist noch nicht beendet",
+	"The summary needs at least one topic, recommendation or next step":               "Das Protokoll benötigt mindestens ein Thema, eine Empfehlung oder einen nächsten Schritt",
+	"The timeslot reservation has expired. Please book again.":                        "Die Reservierung des Termins ist abgelaufen. Bitte buchen Sie erneut.",
+	"The VAT ID is not valid":                                                         "Die USt-IdNr. ist nicht gültig",
+	"This password appeared in a data breach, please choose a different one": "Dieses Passwort ist in einem Datenleck aufgetaucht, bitte wählen Sie ein anderes",
+	"Timeslot does not belong to the selected Berater":                       "Der Termin gehört nicht zum ausgewählten Berater",
+	"Too many attachments": "Zu viele Anhänge",
+	"Too many verification emails requested, please try again later": "Zu viele Bestätigungs-E-Mails angefordert, bitte versuchen Sie es später erneut",
+	"Unknown access token scope":                                     "Unbekannter Zugriffstoken-Bereich",
+	"Unknown API key scope":                                          "Unbekannter API-Schlüssel-Scope",
+	"Unknown PDF template":                                           "Unbekannte PDF-Vorlage",
+	"Unknown placeholder in snippet":                                 "Unbekannter Platzhalter im Textbaustein",
+	"Unknown reaction":                                               "Unbekannte Reaktion",
+	"Unknown service key scope":                                      "Unbekannter Dienstschlüssel-Bereich",
+	"Variant config must be a JSON object":                           "Die Varianten-Konfiguration muss ein JSON-Objekt sein",
+	"Verification token is required":                                 "Bestätigungstoken ist erforderlich",
+	"Visitor ID is required":                                         "Besucher-ID ist erforderlich",
+
+	// Not found and conflicts
+	"A lead aging rule for this status already exists":                 "Für diesen Status existiert bereits eine Regel",
+	"A lead can have at most %d scenarios":                             "Ein Lead kann höchstens %d Szenarien haben",
+	"A saved view with this name already exists":                       "Eine gespeicherte Ansicht mit diesem Namen existiert bereits",
+	"Access token not found":                                           "Zugriffstoken nicht gefunden",
+	"Activity not found":                                               "Aktivität nicht gefunden",
+	"Already a member of the family":                                   "Bereits Mitglied der Familie",
+	"Announcement has already been published":                          "Die Ankündigung wurde bereits veröffentlicht",
+	"Announcement not found":                                           "Ankündigung nicht gefunden",
+	"API key not found":                                                "API-Schlüssel nicht gefunden",
+	"Application cannot be invited to an interview":                    "Zu dieser Bewerbung kann nicht mehr zum Gespräch eingeladen werden",
+	"Application has been anonymized":                                  "Die Bewerbung wurde anonymisiert",
+	"Application not found":                                            "Bewerbung nicht gefunden",
+	"Avatar not found":                                                 "Profilbild nicht gefunden",
+	"Backup is corrupt":                                                "Die Sicherung ist beschädigt",
+	"Backup not found":                                                 "Sicherung nicht gefunden",
+	"Berater is already deactivated":                                   "Berater ist bereits deaktiviert",
+	"Berater is not available for bookings":                            "Berater ist nicht für Buchungen verfügbar",
+	"Berater not found":                                                "Berater nicht gefunden",
+	"Booking already paid":                                             "Buchung wurde bereits bezahlt",
+	"Booking has already been rated":                                   "Die Buchung wurde bereits bewertet",
+	"Booking not found":                                                "Buchung nicht gefunden",
+	"Bookings cannot be reassigned to the same Berater":                "Buchungen können nicht an denselben Berater übertragen werden",
+	"Cannot restore into the live system":                              "In das Live-System kann nicht wiederhergestellt werden",
+	"Chat channel not found":                                           "Chat-Kanal nicht gefunden",
+	"Child not found":                                                  "Kind nicht gefunden",
+	"Comment not found":                                                "Kommentar nicht gefunden",
+	"Consultation summary not found":                                   "Beratungsprotokoll nicht gefunden",
+	"Contact form not found":                                           "Kontaktanfrage nicht gefunden",
+	"Contact form, lead or booking not found":                          "Kontaktanfrage, Lead oder Buchung nicht gefunden",
+	"Content not found":                                                "Inhalt nicht gefunden",
+	"Coupon code already exists":                                       "Rabattcode existiert bereits",
+	"Coupon has expired or was used up":                                "Der Rabattcode ist abgelaufen oder bereits aufgebraucht",
+	"Coupon not found":                                                 "Rabattcode nicht gefunden",
+	"Document already shared":                                          "Dokument bereits geteilt",
+	"Document link has expired":                                        "Der Dokumentlink ist abgelaufen",
+	"Document not found":                                               "Dokument nicht gefunden",
+	"Document request not found":                                       "Nachforderung nicht gefunden",
+	"Email belongs to a staff account":                                 "Die E-Mail gehört zu einem Mitarbeiterkonto",
+	"Evaluation criterion already exists":                              "Bewertungskriterium existiert bereits",
+	"Evaluation criterion has ratings, deactivate it instead":          "Das Bewertungskriterium wurde bereits verwendet, deaktivieren Sie es stattdessen",
+	"Evaluation criterion not found":                                   "Bewertungskriterium nicht gefunden",
+	"Experiment has already been started":                              "Das Experiment wurde bereits gestartet",
+	"Experiment is not running":                                        "Das Experiment läuft nicht",
+	"Experiment key already exists":                                    "Der Experiment-Schlüssel existiert bereits",
+	"Experiment not found":                                             "Experiment nicht gefunden",
+	"Family member not found":                                          "Familienmitglied nicht gefunden",
+	"Family not found":                                                 "Familie nicht gefunden",
+	"Holiday override not found":                                       "Feiertagsausnahme nicht gefunden",
+	"Inbound email is already attached to a lead":                      "E-Mail ist bereits einem Lead zugeordnet",
+	"Inbound email or lead not found":                                  "E-Mail oder Lead nicht gefunden",
+	"Interview slot is already booked":                                 "Der Gesprächstermin ist bereits gebucht",
+	"Interview slot is no longer available":                            "Der Gesprächstermin ist nicht mehr verfügbar",
+	"Interview slot not found":                                         "Gesprächstermin nicht gefunden",
+	"Invitation is no longer valid":                                    "Die Einladung ist nicht mehr gültig",
+	"Invitation not found":                                             "Einladung nicht gefunden",
+	"Job not found":                                                    "Stelle nicht gefunden",
+	"Lead aging rule not found":                                        "Regel nicht gefunden",
+	"Lead not found":                                                   "Lead nicht gefunden",
+	"Link has expired":                                                 "Link ist abgelaufen",
+	"Link not found":                                                   "Link nicht gefunden",
+	"Marketing spend not found":                                        "Marketingausgabe nicht gefunden",
+	"No Berater available for this lead":                               "Für diesen Lead ist kein Berater verfügbar",
+	"No questionnaire has been answered yet":                           "Es wurde noch kein Fragebogen beantwortet",
+	"No scenario has been chosen yet":                                  "Es wurde noch kein Szenario ausgewählt",
+	"No Stripe payment intent found":                                   "Keine Stripe-Zahlung gefunden",
+	"Not allowed in the current onboarding status":                     "Im aktuellen Onboarding-Status nicht erlaubt",
+	"One or more add-ons not found":                                    "Ein oder mehrere Zusatzleistungen nicht gefunden",
+	"Only completed bookings can be rated":                             "Nur abgeschlossene Buchungen können bewertet werden",
+	"Outbox message is not dead-lettered":                              "Outbox-Nachricht ist nicht als unzustellbar markiert",
+	"Outbox message not found":                                         "Outbox-Nachricht nicht gefunden",
+	"Package not found":                                                "Paket nicht gefunden",
+	"Payment is not completed":                                         "Zahlung ist nicht abgeschlossen",
+	"Payment link not found":                                           "Zahlungslink nicht gefunden",
+	"Payment not found":                                                "Zahlung nicht gefunden",
+	"PDF has not been generated yet":                                   "Das PDF wurde noch nicht erstellt",
+	"PDF job not found":                                                "PDF-Auftrag nicht gefunden",
+	"Post not found":                                                   "Beitrag nicht gefunden",
+	"Postal code not found":                                            "Postleitzahl nicht gefunden",
+	"Preview not available":                                            "Keine Vorschau verfügbar",
+	"Product update not found":                                         "Produkt-Update nicht gefunden",
+	"Question key already used":                                        "Fragenschlüssel bereits vergeben",
+	"Question not found":                                               "Frage nicht gefunden",
+	"Questionnaire already published, revise it instead":               "Fragebogen bereits veröffentlicht, erstellen Sie eine neue Version",
+	"Questionnaire has no questions":                                   "Der Fragebogen enthält keine Fragen",
+	"Questionnaire not found":                                          "Fragebogen nicht gefunden",
+	"Record not found in trash":                                        "Datensatz nicht im Papierkorb gefunden",
+	"Records with payments or documents cannot be deleted permanently": "Datensätze mit Zahlungen oder Dokumenten können nicht endgültig gelöscht werden",
+	"Refund request not found":                                         "Erstattungsantrag nicht gefunden",
+	"Refund request was already decided":                               "Über den Erstattungsantrag wurde bereits entschieden",
+	"Routing rule not found":                                           "Regel nicht gefunden",
+	"Saved view not found":                                             "Gespeicherte Ansicht nicht gefunden",
+	"Scenario not found":                                               "Szenario nicht gefunden",
+	"Scheduled job not found":                                          "Geplanter Job nicht gefunden",
+	"Service key not found":                                            "Dienstschlüssel nicht gefunden",
+	"Setting not found":                                                "Einstellung nicht gefunden",
+	"Slug already exists":                                              "Der Slug ist bereits vergeben",
+	"Snippet not found":                                                "Textbaustein nicht gefunden",
+	"Someone else has already claimed this item":                       "Die Anfrage wurde bereits von jemand anderem übernommen",
+	"Submission not found":                                             "Einreichung nicht gefunden",
+	"Submission was already reviewed":                                  "Einreichung wurde bereits geprüft",
+	"Summaries can only be written for consultations that took place":  "Protokolle können nur für stattgefundene Beratungen erstellt werden",
+	"Target user not found":                                            "Zielbenutzer nicht gefunden",
+	"The applicant cannot be removed":                                  "Der Antragsteller kann nicht entfernt werden",
+	"The application has not been submitted yet":                       "Der Antrag wurde noch nicht eingereicht",
+	"The Berater has no more appointments available on this day":       "Der Berater hat an diesem Tag keine freien Termine mehr",
+	"The Bescheid has already been received":                           "Der Bescheid liegt bereits vor",
+	"The customer has not opted in to WhatsApp messages":               "Der Kunde hat WhatsApp-Nachrichten nicht zugestimmt",
+	"The lead belongs to a customer outside the family":                "Der Lead gehört zu einem Kunden außerhalb der Familie",
+	"The package already has a draft questionnaire":                    "Das Paket hat bereits einen Fragebogen-Entwurf",
+	"The payment link was already paid or cancelled":                   "Der Zahlungslink wurde bereits bezahlt oder storniert",
+	"This job does not accept direct applications":                     "Für diese Stelle sind keine direkten Bewerbungen möglich",
+	"This package requires timeslot selection":                         "Für dieses Paket muss ein Termin ausgewählt werden",
+	"This payment link has expired or was already paid":                "Dieser Zahlungslink ist abgelaufen oder wurde bereits bezahlt",
+	"Timeslot falls on a public holiday":                               "Der Termin fällt auf einen Feiertag",
+	"Timeslot is no longer available":                                  "Termin ist nicht mehr verfügbar",
+	"Timeslot is too close to another appointment of the Berater":      "Der Termin liegt zu nah an einem anderen Termin des Beraters",
+	"Timeslot not found or not available":                              "Termin nicht gefunden oder nicht verfügbar",
+	"Todo not found":                                                   "Aufgabe nicht gefunden",
+	"User not found":                                                   "Benutzer nicht gefunden",
+	"Users cannot update lead status":                                  "Benutzer können den Lead-Status nicht ändern",
+	"Verification link has expired":                                    "Der Bestätigungslink ist abgelaufen",
+	"Voucher already redeemed":                                         "Gutschein wurde bereits eingelöst",
+	"Voucher code already exists":                                      "Gutscheincode existiert bereits",
+	"Voucher has expired or was used up":                               "Der Gutschein ist abgelaufen oder bereits aufgebraucht",
+	"Voucher not found":                                                "Gutschein nicht gefunden",
+	"Webhook event is being processed":                                 "Webhook-Ereignis wird gerade verarbeitet",
+	"Webhook event not found":                                          "Webhook-Ereignis nicht gefunden",
+	"WhatsApp messages can only be sent within 24 hours of the customer's last message": "WhatsApp-Nachrichten können nur innerhalb von 24 Stunden nach der letzten Nachricht des Kunden gesendet werden",
+	"You have reached the maximum number of access tokens":                              "Sie haben die maximale Anzahl an Zugriffstokens erreicht",
+
+	// Server errors
+	"Address autocomplete is not available":       "Die Adressvervollständigung ist nicht verfügbar",
+	"Address validation is currently unavailable": "Die Adressprüfung ist derzeit nicht verfügbar",
+	"Backups are not configured":                  "Sicherungen sind nicht eingerichtet",
+	"Captcha service is unavailable":              "Captcha-Dienst ist nicht verfügbar",
+	"Failed to add question":                      "Frage konnte nicht hinzugefügt werden",
+	"Failed to apply lead aging rules":            "Regeln konnten nicht angewendet werden",
+	"Failed to approve berater":                   "Berater konnte nicht freigegeben werden",
+	"Failed to approve refund":                    "Erstattung konnte nicht freigegeben werden",
+	"Failed to approve submission":                "Einreichung konnte nicht freigegeben werden",
+	"Failed to assign experiment variant":         "Experiment-Variante konnte nicht zugewiesen werden",
+	"Failed to assign inbound email":              "E-Mail konnte nicht zugeordnet werden",
+	"Failed to assign lead":                       "Lead konnte nicht zugewiesen werden",
+	"Failed to build capacity report":             "Kapazitätsbericht konnte nicht erstellt werden",
+	"Failed to build funnel report":               "Funnel-Bericht konnte nicht erstellt werden",
+	"Failed to build lead funnel":                 "Trichteranalyse der Leads konnte nicht erstellt werden",
+	"Failed to build region report":               "Regionsbericht konnte nicht erstellt werden",
+	"Failed to build revenue forecast":            "Umsatzprognose konnte nicht erstellt werden",
+	"Failed to build ROI report":                  "ROI-Bericht konnte nicht erstellt werden",
+	"Failed to build sitemap":                     "Sitemap konnte nicht erstellt werden",
+	"Failed to calculate":                         "Berechnung fehlgeschlagen",
+	"Failed to calculate price":                   "Preis konnte nicht berechnet werden",
+	"Failed to cancel payment link":               "Zahlungslink konnte nicht storniert werden",
+	"Failed to change password":                   "Passwort konnte nicht geändert werden",
+	"Failed to choose scenario":                   "Szenario konnte nicht ausgewählt werden",
+	"Failed to claim contact form":                "Kontaktanfrage konnte nicht übernommen werden",
+	"Failed to claim lead":                        "Lead konnte nicht übernommen werden",
+	"Failed to classify document":                 "Dokument konnte nicht klassifiziert werden",
+	"Failed to compare scenarios":                 "Szenarien konnten nicht verglichen werden",
+	"Failed to complete todo":                     "Aufgabe konnte nicht abgeschlossen werden",
+	"Failed to create access token":               "Zugriffstoken konnte nicht erstellt werden",
+	"Failed to create announcement":               "Ankündigung konnte nicht erstellt werden",
+	"Failed to create API key":                    "API-Schlüssel konnte nicht erstellt werden",
+	"Failed to create booking":                    "Buchung konnte nicht erstellt werden",
+	"Failed to create chat channel":               "Chat-Kanal konnte nicht erstellt werden",
+	"Failed to create checkout session":           "Bezahlvorgang konnte nicht gestartet werden",
+	"Failed to create comment":                    "Kommentar konnte nicht erstellt werden",
+	"Failed to create content":                    "Inhalt konnte nicht erstellt werden",
+	"Failed to create coupon":                     "Rabattcode konnte nicht erstellt werden",
+	"Failed to create evaluation criterion":       "Bewertungskriterium konnte nicht erstellt werden",
+	"Failed to create experiment":                 "Experiment konnte nicht erstellt werden",
+	"Failed to create holiday override":           "Feiertagsausnahme konnte nicht erstellt werden",
+	"Failed to create interview slot":             "Gesprächstermin konnte nicht erstellt werden",
+	"Failed to create invitation":                 "Einladung konnte nicht erstellt werden",
+	"Failed to create lead":                       "Lead konnte nicht erstellt werden",
+	"Failed to create lead aging rule":            "Regel konnte nicht erstellt werden",
+	"Failed to create marketing spend":            "Marketingausgabe konnte nicht erstellt werden",
+	"Failed to create payment":                    "Zahlung konnte nicht erstellt werden",
+	"Failed to create payment link":               "Zahlungslink konnte nicht erstellt werden",
+	"Failed to create payout plan":                "Bezugsplan konnte nicht erstellt werden",
+	"Failed to create post":                       "Beitrag konnte nicht erstellt werden",
+	"Failed to create product update":             "Produkt-Update konnte nicht erstellt werden",
+	"Failed to create questionnaire":              "Fragebogen konnte nicht erstellt werden",
+	"Failed to create refund":                     "Rückerstattung konnte nicht erstellt werden",
+	"Failed to create routing rule":               "Regel konnte nicht erstellt werden",
+	"Failed to create saved view":                 "Ansicht konnte nicht gespeichert werden",
+	"Failed to create scenario":                   "Szenario konnte nicht erstellt werden",
+	"Failed to create service key":                "Dienstschlüssel konnte nicht erstellt werden",
+	"Failed to create snippet":                    "Textbaustein konnte nicht erstellt werden",
+	"Failed to create todo":                       "Aufgabe konnte nicht erstellt werden",
+	"Failed to create user":                       "Benutzer konnte nicht erstellt werden",
+	"Failed to create voucher":                    "Gutschein konnte nicht erstellt werden",
+	"Failed to deactivate berater":                "Berater konnte nicht deaktiviert werden",
+	"Failed to delete announcement":               "Ankündigung konnte nicht gelöscht werden",
+	"Failed to delete avatar":                     "Profilbild konnte nicht gelöscht werden",
+	"Failed to delete chat channel":               "Chat-Kanal konnte nicht gelöscht werden",
+	"Failed to delete content":                    "Inhalt konnte nicht gelöscht werden",
+	"Failed to delete document":                   "Dokument konnte nicht gelöscht werden",
+	"Failed to delete evaluation criterion":       "Bewertungskriterium konnte nicht gelöscht werden",
+	"Failed to delete family":                     "Fehler beim Löschen der Familie",
+	"Failed to delete holiday override":           "Feiertagsausnahme konnte nicht gelöscht werden",
+	"Failed to delete interview slot":             "Gesprächstermin konnte nicht gelöscht werden",
+	"Failed to delete lead":                       "Lead konnte nicht gelöscht werden",
+	"Failed to delete lead aging rule":            "Regel konnte nicht gelöscht werden",
+	"Failed to delete marketing spend":            "Marketingausgabe konnte nicht gelöscht werden",
+	"Failed to delete post":                       "Beitrag konnte nicht gelöscht werden",
+	"Failed to delete product update":             "Produkt-Update konnte nicht gelöscht werden",
+	"Failed to delete question":                   "Frage konnte nicht gelöscht werden",
+	"Failed to delete questionnaire":              "Fragebogen konnte nicht gelöscht werden",
+	"Failed to delete record permanently":         "Datensatz konnte nicht endgültig gelöscht werden",
+	"Failed to delete routing rule":               "Regel konnte nicht gelöscht werden",
+	"Failed to delete saved view":                 "Gespeicherte Ansicht konnte nicht gelöscht werden",
+	"Failed to delete scenario":                   "Szenario konnte nicht gelöscht werden",
+	"Failed to delete snippet":                    "Textbaustein konnte nicht gelöscht werden",
+	"Failed to delete todo":                       "Aufgabe konnte nicht gelöscht werden",
+	"Failed to delete user":                       "Benutzer konnte nicht gelöscht werden",
+	"Failed to export case file":                  "Fallakte konnte nicht exportiert werden",
+	"Failed to export leads":                      "Leads konnten nicht exportiert werden",
+	"Failed to fetch access tokens":               "Zugriffstokens konnten nicht geladen werden",
+	"Failed to fetch activities":                  "Aktivitäten konnten nicht geladen werden",
+	"Failed to fetch activity":                    "Aktivität konnte nicht geladen werden",
+	"Failed to fetch activity feed":               "Aktivitäten konnten nicht geladen werden",
+	"Failed to fetch add-ons":                     "Zusatzleistungen konnten nicht geladen werden",
+	"Failed to fetch announcement report":         "Lesebestätigungen der Ankündigung konnten nicht geladen werden",
+	"Failed to fetch announcements":               "Ankündigungen konnten nicht geladen werden",
+	"Failed to fetch API keys":                    "API-Schlüssel konnten nicht geladen werden",
+	"Failed to fetch application status":          "Bewerbungsstatus konnte nicht geladen werden",
+	"Failed to fetch applications":                "Bewerbungen konnten nicht geladen werden",
+	"Failed to fetch backups":                     "Sicherungen konnten nicht geladen werden",
+	"Failed to fetch beraters":                    "Berater konnten nicht abgerufen werden",
+	"Failed to fetch booking":                     "Buchung konnte nicht geladen werden",
+	"Failed to fetch bookings":                    "Buchungen konnten nicht geladen werden",
+	"Failed to fetch categories":                  "Kategorien konnten nicht geladen werden",
+	"Failed to fetch chat channels":               "Chat-Kanäle konnten nicht geladen werden",
+	"Failed to fetch comments":                    "Kommentare konnten nicht geladen werden",
+	"Failed to fetch consultation summaries":      "Beratungsprotokolle konnten nicht geladen werden",
+	"Failed to fetch consultation summary":        "Beratungsprotokoll konnte nicht geladen werden",
+	"Failed to fetch contact form":                "Kontaktanfrage konnte nicht geladen werden",
+	"Failed to fetch contact forms":               "Kontaktanfragen konnten nicht geladen werden",
+	"Failed to fetch content":                     "Inhalte konnten nicht geladen werden",
+	"Failed to fetch coupons":                     "Rabattcodes konnten nicht geladen werden",
+	"Failed to fetch credit":                      "Guthaben konnte nicht abgerufen werden",
+	"Failed to fetch document":                    "Dokument konnte nicht geladen werden",
+	"Failed to fetch documents":                   "Dokumente konnten nicht geladen werden",
+	"Failed to fetch evaluation criteria":         "Bewertungskriterien konnten nicht geladen werden",
+	"Failed to fetch evaluations":                 "Bewertungen konnten nicht geladen werden",
+	"Failed to fetch experiment":                  "Experiment konnte nicht abgerufen werden",
+	"Failed to fetch experiment results":          "Experiment-Ergebnisse konnten nicht abgerufen werden",
+	"Failed to fetch experiments":                 "Experimente konnten nicht abgerufen werden",
+	"Failed to fetch families":                    "Fehler beim Laden der Familien",
+	"Failed to fetch family":                      "Fehler beim Laden der Familie",
+	"Failed to fetch flagged submissions":         "Markierte Einreichungen konnten nicht geladen werden",
+	"Failed to fetch holiday overrides":           "Feiertagsausnahmen konnten nicht abgerufen werden",
+	"Failed to fetch holidays":                    "Feiertage konnten nicht abgerufen werden",
+	"Failed to fetch inbound emails":              "E-Mails konnten nicht geladen werden",
+	"Failed to fetch interview slots":             "Gesprächstermine konnten nicht geladen werden",
+	"Failed to fetch invitation":                  "Einladung konnte nicht geladen werden",
+	"Failed to fetch invitations":                 "Einladungen konnten nicht abgerufen werden",
+	"Failed to fetch job":                         "Stelle konnte nicht geladen werden",
+	"Failed to fetch job runs":                    "Job-Ausführungen konnten nicht geladen werden",
+	"Failed to fetch lead":                        "Lead konnte nicht geladen werden",
+	"Failed to fetch lead aging rules":            "Regeln konnten nicht geladen werden",
+	"Failed to fetch leads":                       "Leads konnten nicht geladen werden",
+	"Failed to fetch link statistics":             "Link-Statistiken konnten nicht geladen werden",
+	"Failed to fetch marketing spend":             "Marketingausgaben konnten nicht abgerufen werden",
+	"Failed to fetch notification preferences":    "Benachrichtigungseinstellungen konnten nicht geladen werden",
+	"Failed to fetch offboarding worklist":        "Offboarding-Arbeitsliste konnte nicht geladen werden",
+	"Failed to fetch onboarding progress":         "Onboarding-Fortschritt konnte nicht abgerufen werden",
+	"Failed to fetch outbox messages":             "Outbox-Nachrichten konnten nicht abgerufen werden",
+	"Failed to fetch package":                     "Paket konnte nicht geladen werden",
+	"Failed to fetch packages":                    "Pakete konnten nicht geladen werden",
+	"Failed to fetch payment":                     "Zahlung konnte nicht geladen werden",
+	"Failed to fetch payment links":               "Zahlungslinks konnten nicht geladen werden",
+	"Failed to fetch payments":                    "Zahlungen konnten nicht geladen werden",
+	"Failed to fetch PDF brandings":               "PDF-Briefköpfe konnten nicht geladen werden",
+	"Failed to fetch PDF job":                     "PDF-Auftrag konnte nicht geladen werden",
+	"Failed to fetch post":                        "Beitrag konnte nicht geladen werden",
+	"Failed to fetch posts":                       "Beiträge konnten nicht geladen werden",
+	"Failed to fetch product update":              "Produkt-Update konnte nicht geladen werden",
+	"Failed to fetch product updates":             "Produkt-Updates konnten nicht geladen werden",
+	"Failed to fetch questionnaire":               "Fragebogen konnte nicht geladen werden",
+	"Failed to fetch questionnaire answers":       "Antworten des Fragebogens konnten nicht geladen werden",
+	"Failed to fetch questionnaires":              "Fragebögen konnten nicht geladen werden",
+	"Failed to fetch refund requests":             "Erstattungsanträge konnten nicht geladen werden",
+	"Failed to fetch saved views":                 "Gespeicherte Ansichten konnten nicht abgerufen werden",
+	"Failed to fetch scenarios":                   "Szenarien konnten nicht geladen werden",
+	"Failed to fetch scheduled jobs":              "Geplante Jobs konnten nicht geladen werden",
+	"Failed to fetch service keys":                "Dienstschlüssel konnten nicht geladen werden",
+	"Failed to fetch settings":                    "Einstellungen konnten nicht geladen werden",
+	"Failed to fetch snippets":                    "Textbausteine konnten nicht geladen werden",
+	"Failed to fetch statement":                   "Kontoauszug konnte nicht geladen werden",
+	"Failed to fetch storage usage":               "Speicherbelegung konnte nicht geladen werden",
+	"Failed to fetch submission":                  "Antragsstatus konnte nicht geladen werden",
+	"Failed to fetch submissions":                 "Eingereichte Anträge konnten nicht geladen werden",
+	"Failed to fetch talent pool":                 "Talentpool konnte nicht geladen werden",
+	"Failed to fetch team inbox":                  "Team-Postfach konnte nicht geladen werden",
+	"Failed to fetch timeslot":                    "Termin konnte nicht geladen werden",
+	"Failed to fetch timeslots":                   "Termine konnten nicht geladen werden",
+	"Failed to fetch todo":                        "Aufgabe konnte nicht geladen werden",
+	"Failed to fetch todos":                       "Aufgaben konnten nicht geladen werden",
+	"Failed to fetch trash":                       "Papierkorb konnte nicht geladen werden",
+	"Failed to fetch updated booking":             "Aktualisierte Buchung konnte nicht geladen werden",
+	"Failed to fetch updated document":            "Aktualisiertes Dokument konnte nicht geladen werden",
+	"Failed to fetch updated lead":                "Aktualisierter Lead konnte nicht geladen werden",
+	"Failed to fetch updated todo":                "Aktualisierte Aufgabe konnte nicht geladen werden",
+	"Failed to fetch updated user":                "Aktualisierter Benutzer konnte nicht geladen werden",
+	"Failed to fetch user":                        "Benutzer konnte nicht geladen werden",
+	"Failed to fetch users":                       "Benutzer konnten nicht geladen werden",
+	"Failed to fetch vouchers":                    "Gutscheine konnten nicht abgerufen werden",
+	"Failed to fetch webhook events":              "Webhook-Ereignisse konnten nicht geladen werden",
+	"Failed to fetch WhatsApp consent":            "WhatsApp-Einwilligung konnte nicht abgerufen werden",
+	"Failed to fetch WhatsApp messages":           "WhatsApp-Nachrichten konnten nicht abgerufen werden",
+	"Failed to grant credit":                      "Guthaben konnte nicht gutgeschrieben werden",
+	"Failed to issue CSRF token":                  "CSRF-Token konnte nicht ausgestellt werden",
+	"Failed to log in":                            "Anmeldung fehlgeschlagen",
+	"Failed to log out":                           "Abmeldung fehlgeschlagen",
+	"Failed to look up postal code":               "Postleitzahl konnte nicht nachgeschlagen werden",
+	"Failed to mark announcement read":            "Ankündigung konnte nicht als gelesen markiert werden",
+	"Failed to match beraters":                    "Berater konnten nicht zugeordnet werden",
+	"Failed to open document":                     "Dokument konnte nicht geöffnet werden",
+	"Failed to pause scheduled job":               "Geplanter Job konnte nicht pausiert werden",
+	"Failed to process booking":                   "Buchung konnte nicht verarbeitet werden",
+	"Failed to process comment":                   "Kommentar konnte nicht verarbeitet werden",
+	"Failed to process contact form":              "Kontaktanfrage konnte nicht verarbeitet werden",
+	"Failed to process inbound email":             "E-Mail konnte nicht verarbeitet werden",
+	"Failed to process invitation":                "Einladung konnte nicht verarbeitet werden",
+	"Failed to publish content":                   "Inhalt konnte nicht veröffentlicht werden",
+	"Failed to publish post":                      "Beitrag konnte nicht veröffentlicht werden",
+	"Failed to publish questionnaire":             "Fragebogen konnte nicht veröffentlicht werden",
+	"Failed to queue PDF":                         "PDF konnte nicht in Auftrag gegeben werden",
+	"Failed to rate booking":                      "Bewertung konnte nicht gespeichert werden",
+	"Failed to read PDF":                          "PDF konnte nicht gelesen werden",
+	"Failed to reassign bookings":                 "Buchungen konnten nicht übertragen werden",
+	"Failed to receive webhook":                   "Webhook konnte nicht empfangen werden",
+	"Failed to record attendance":                 "Teilnahme konnte nicht erfasst werden",
+	"Failed to redeem voucher":                    "Gutschein konnte nicht eingelöst werden",
+	"Failed to refresh session":                   "Sitzung konnte nicht erneuert werden",
+	"Failed to reject berater":                    "Berater konnte nicht abgelehnt werden",
+	"Failed to reject refund":                     "Erstattung konnte nicht abgelehnt werden",
+	"Failed to reject submission":                 "Einreichung konnte nicht abgelehnt werden",
+	"Failed to release contact form":              "Kontaktanfrage konnte nicht freigegeben werden",
+	"Failed to release lead":                      "Lead konnte nicht freigegeben werden",
+	"Failed to render feed":                       "Feed konnte nicht erstellt werden",
+	"Failed to render preview":                    "Vorschau konnte nicht erstellt werden",
+	"Failed to render snippet":                    "Textbaustein konnte nicht ausgefüllt werden",
+	"Failed to replay webhook event":              "Webhook-Ereignis konnte nicht erneut verarbeitet werden",
+	"Failed to resolve link":                      "Link konnte nicht aufgelöst werden",
+	"Failed to resolve merge fields":              "Platzhalter konnten nicht ermittelt werden",
+	"Failed to restore record":                    "Datensatz konnte nicht wiederhergestellt werden",
+	"Failed to resume scheduled job":              "Geplanter Job konnte nicht fortgesetzt werden",
+	"Failed to retry outbox message":              "Outbox-Nachricht konnte nicht erneut zugestellt werden",
+	"Failed to revise questionnaire":              "Neue Version des Fragebogens konnte nicht erstellt werden",
+	"Failed to revoke access token":               "Zugriffstoken konnte nicht widerrufen werden",
+	"Failed to revoke API key":                    "API-Schlüssel konnte nicht widerrufen werden",
+	"Failed to revoke service key":                "Dienstschlüssel konnte nicht widerrufen werden",
+	"Failed to rotate service key":                "Dienstschlüssel konnte nicht erneuert werden",
+	"Failed to save Bescheid":                     "Bescheid konnte nicht gespeichert werden",
+	"Failed to save consultation summary":         "Beratungsprotokoll konnte nicht gespeichert werden",
+	"Failed to save contact form":                 "Kontaktanfrage konnte nicht gespeichert werden",
+	"Failed to save document":                     "Dokument konnte nicht gespeichert werden",
+	"Failed to save document request":             "Nachforderung konnte nicht gespeichert werden",
+	"Failed to save evaluation":                   "Bewertung konnte nicht gespeichert werden",
+	"Failed to save family":                       "Fehler beim Speichern der Familie",
+	"Failed to save questionnaire answers":        "Antworten des Fragebogens konnten nicht gespeichert werden",
+	"Failed to save submission":                   "Einreichung konnte nicht gespeichert werden",
+	"Failed to schedule interview":                "Vorstellungsgespräch konnte nicht gebucht werden",
+	"Failed to send reply":                        "Antwort konnte nicht gesendet werden",
+	"Failed to send verification email":           "Bestätigungs-E-Mail konnte nicht gesendet werden",
+	"Failed to send WhatsApp message":             "WhatsApp-Nachricht konnte nicht gesendet werden",
+	"Failed to set default saved view":            "Standardansicht konnte nicht festgelegt werden",
+	"Failed to share payout plan":                 "Bezugsplan konnte nicht geteilt werden",
+	"Failed to share saved view":                  "Gespeicherte Ansicht konnte nicht geteilt werden",
+	"Failed to start backup":                      "Sicherung konnte nicht gestartet werden",
+	"Failed to start experiment":                  "Experiment konnte nicht gestartet werden",
+	"Failed to start restore":                     "Wiederherstellung konnte nicht gestartet werden",
+	"Failed to stop experiment":                   "Experiment konnte nicht beendet werden",
+	"Failed to store avatar":                      "Profilbild konnte nicht gespeichert werden",
+	"Failed to store file":                        "Datei konnte nicht gespeichert werden",
+	"Failed to submit application":                "Bewerbung konnte nicht übermittelt werden",
+	"Failed to submit onboarding":                 "Onboarding konnte nicht eingereicht werden",
+	"Failed to track events":                      "Ereignisse konnten nicht gespeichert werden",
+	"Failed to trigger scheduled job":             "Geplanter Job konnte nicht gestartet werden",
+	"Failed to unpublish content":                 "Veröffentlichung konnte nicht zurückgenommen werden",
+	"Failed to unpublish post":                    "Veröffentlichung des Beitrags konnte nicht zurückgenommen werden",
+	"Failed to update announcement":               "Ankündigung konnte nicht aktualisiert werden",
+	"Failed to update availability":               "Verfügbarkeit konnte nicht aktualisiert werden",
+	"Failed to update booking":                    "Termin konnte nicht aktualisiert werden",
+	"Failed to update chat channel":               "Chat-Kanal konnte nicht aktualisiert werden",
+	"Failed to update contact information":        "Kontaktdaten konnten nicht aktualisiert werden",
+	"Failed to update content":                    "Inhalt konnte nicht gespeichert werden",
+	"Failed to update document":                   "Dokument konnte nicht aktualisiert werden",
+	"Failed to update evaluation criterion":       "Bewertungskriterium konnte nicht aktualisiert werden",
+	"Failed to update experiment":                 "Experiment konnte nicht aktualisiert werden",
+	"Failed to update lead":                       "Lead konnte nicht aktualisiert werden",
+	"Failed to update lead aging rule":            "Regel konnte nicht aktualisiert werden",
+	"Failed to update lead status":                "Lead-Status konnte nicht aktualisiert werden",
+	"Failed to update marketing spend":            "Marketingausgabe konnte nicht aktualisiert werden",
+	"Failed to update notification preferences":   "Benachrichtigungseinstellungen konnten nicht aktualisiert werden",
+	"Failed to update PDF branding":               "PDF-Briefkopf konnte nicht gespeichert werden",
+	"Failed to update post":                       "Beitrag konnte nicht aktualisiert werden",
+	"Failed to update product update":             "Produkt-Update konnte nicht aktualisiert werden",
+	"Failed to update profile":                    "Profil konnte nicht aktualisiert werden",
+	"Failed to update question":                   "Frage konnte nicht aktualisiert werden",
+	"Failed to update questionnaire":              "Fragebogen konnte nicht aktualisiert werden",
+	"Failed to update saved view":                 "Gespeicherte Ansicht konnte nicht aktualisiert werden",
+	"Failed to update scenario":                   "Szenario konnte nicht aktualisiert werden",
+	"Failed to update service key":                "Dienstschlüssel konnte nicht aktualisiert werden",
+	"Failed to update setting":                    "Einstellung konnte nicht gespeichert werden",
+	"Failed to update snippet":                    "Textbaustein konnte nicht aktualisiert werden",
+	"Failed to update status":                     "Status konnte nicht aktualisiert werden",
+	"Failed to update talent pool":                "Talentpool konnte nicht aktualisiert werden",
+	"Failed to update todo":                       "Aufgabe konnte nicht aktualisiert werden",
+	"Failed to update user":                       "Benutzer konnte nicht aktualisiert werden",
+	"Failed to update user role":                  "Benutzerrolle konnte nicht aktualisiert werden",
+	"Failed to update user status":                "Benutzerstatus konnte nicht aktualisiert werden",
+	"Failed to update WhatsApp consent":           "WhatsApp-Einwilligung konnte nicht aktualisiert werden",
+	"Failed to validate password":                 "Passwort konnte nicht geprüft werden",
+	"Failed to verify assigned user":              "Zugewiesener Benutzer konnte nicht geprüft werden",
+	"Failed to verify email":                      "E-Mail-Adresse konnte nicht bestätigt werden",
+	"Failed to verify target user":                "Zielbenutzer konnte nicht geprüft werden",
+	"Failed to verify timeslot":                   "Termin konnte nicht geprüft werden",
+	"No restore target configured":                "Kein Wiederherstellungsziel eingerichtet",
+	"Refund created but failed to update record":  "Rückerstattung erstellt, Datensatz konnte aber nicht aktualisiert werden",
+	"Test message could not be delivered":         "Testnachricht konnte nicht zugestellt werden",
+	"The refund failed":                           "Die Erstattung ist fehlgeschlagen",
+	"WhatsApp messaging is not configured":        "WhatsApp-Nachrichten sind nicht eingerichtet",
+
+	// Confirmations
+	"A new verification email has been sent":                  "Eine neue Bestätigungs-E-Mail wurde gesendet",
+	"Access token revoked":                                    "Zugriffstoken widerrufen",
+	"Announcement deleted":                                    "Ankündigung gelöscht",
+	"API key revoked":                                         "API-Schlüssel widerrufen",
+	"Application received, please confirm your email address": "Bewerbung erhalten, bitte bestätigen Sie Ihre E-Mail-Adresse",
+	"Avatar deleted successfully":                             "Profilbild erfolgreich gelöscht",
+	"Chat channel deleted":                                    "Chat-Kanal gelöscht",
+	"Child removed successfully":                              "Kind erfolgreich entfernt",
+	"Comment deleted":                                         "Kommentar gelöscht",
+	"Content deleted successfully":                            "Inhalt erfolgreich gelöscht",
+	"Default saved view cleared":                              "Standardansicht zurückgesetzt",
+	"Document deleted successfully":                           "Dokument erfolgreich gelöscht",
+	"Email verified successfully":                             "E-Mail-Adresse erfolgreich bestätigt",
+	"Evaluation criterion deleted":                            "Bewertungskriterium gelöscht",
+	"Family deleted successfully":                             "Familie erfolgreich gelöscht",
+	"Family member removed successfully":                      "Familienmitglied erfolgreich entfernt",
+	"Holiday override deleted successfully":                   "Feiertagsausnahme erfolgreich gelöscht",
+	"If the account exists and is not verified yet, a new verification email has been sent": "Falls das Konto existiert und noch nicht bestätigt ist, wurde eine neue Bestätigungs-E-Mail gesendet",
+	"Interview scheduled":                             "Vorstellungsgespräch gebucht",
+	"Interview slot deleted":                          "Gesprächstermin gelöscht",
+	"Invitation revoked successfully":                 "Einladung erfolgreich widerrufen",
+	"Lead aging rule deleted":                         "Regel gelöscht",
+	"Lead deleted successfully":                       "Lead erfolgreich gelöscht",
+	"Logged out successfully":                         "Erfolgreich abgemeldet",
+	"Marketing spend deleted":                         "Marketingausgabe gelöscht",
+	"Not implemented":                                 "Nicht implementiert",
+	"Password changed successfully":                   "Passwort erfolgreich geändert",
+	"Payment refunded as credit":                      "Zahlung wurde als Guthaben erstattet",
+	"Payment was cancelled. You can try again later.": "Die Zahlung wurde abgebrochen. Sie können es später erneut versuchen.",
+	"Post deleted successfully":                       "Beitrag erfolgreich gelöscht",
+	"Product update deleted":                          "Produkt-Update gelöscht",
+	"Question deleted":                                "Frage gelöscht",
+	"Questionnaire deleted":                           "Fragebogen gelöscht",
+	"Record deleted permanently":                      "Datensatz endgültig gelöscht",
+	"Record restored":                                 "Datensatz wiederhergestellt",
+	"Routing rule deleted":                            "Regel gelöscht",
+	"Saved view deleted":                              "Gespeicherte Ansicht gelöscht",
+	"Scenario deleted successfully":                   "Szenario erfolgreich gelöscht",
+	"Service key revoked":                             "Dienstschlüssel widerrufen",
+	"Snippet deleted successfully":                    "Textbaustein erfolgreich gelöscht",
+	"Test message sent":                               "Testnachricht gesendet",
+	"Todo deleted successfully":                       "Aufgabe erfolgreich gelöscht",
+	"User deleted successfully":                       "Benutzer erfolgreich gelöscht",
+	"User registered successfully":                    "Benutzer erfolgreich registriert",
+
+	// Email subjects
+	"Welcome to Elterngeld-Portal - Please confirm your email": "Willkommen beim Elterngeld-Portal - E-Mail bestätigen",
+	"Booking confirmation - %s":                                "Buchungsbestätigung - %s",
+	"New task assigned: %s":                                    "Neue Aufgabe zugewiesen: %s",
+	"New lead assigned: %s":                                    "Neuer Lead zugewiesen: %s",
+	"Your personal advisor: %s - %s":                           "Ihr persönlicher Berater: %s - %s",
+	"Your offer: %s - %s":                                      "Ihr Angebot: %s - %s",
+	"Payment confirmation - %s":                                "Zahlungsbestätigung - %s",
+	"Reset your password - Elterngeld-Portal":                  "Passwort zurücksetzen - Elterngeld-Portal",
+	"Contact request received - Elterngeld-Portal":             "Kontaktanfrage erhalten - Elterngeld-Portal",
+	"Your invitation as a Berater - Elterngeld-Portal":         "Ihre Einladung als Berater - Elterngeld-Portal",
+	"Your daily digest: %d new activities":                     "Ihre tägliche Übersicht: %d neue Aktivitäten",
+	"We missed you - book a new appointment":                   "Wir haben Sie vermisst - jetzt neuen Termin buchen",
+	"Invitation to an interview - %s":                          "Einladung zum Vorstellungsgespräch - %s",
+	"Interview confirmed - %s":                                 "Vorstellungsgespräch bestätigt - %s",
+	"Please confirm your application - %s":                     "Bitte bestätigen Sie Ihre Bewerbung - %s",
+	"Your notification summary: %d new notifications":          "Ihre Benachrichtigungen: %d neue Benachrichtigungen",
+}
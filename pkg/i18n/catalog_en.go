@@ -0,0 +1,123 @@
+package i18n
+
+// englishMessages holds English display names of enum values. German display names
+// are defined by the GetDisplayName methods of the model types.
+var englishMessages = map[string]string{
+	// Lead status and priority
+	"lead_status.neu":                "New",
+	"lead_status.in_bearbeitung":     "In progress",
+	"lead_status.rückfrage":          "Query pending",
+	"lead_status.abgeschlossen":      "Completed",
+	"lead_status.storniert":          "Cancelled",
+	"lead_status.zahlung_ausstehend": "Payment pending",
+	"priority.niedrig":               "Low",
+	"priority.mittel":                "Medium",
+	"priority.hoch":                  "High",
+	"priority.dringend":              "Urgent",
+
+	// Activities
+	"activity_type.lead_created":        "Lead created",
+	"activity_type.lead_updated":        "Lead updated",
+	"activity_type.lead_status_changed": "Lead status changed",
+	"activity_type.lead_assigned":       "Lead assigned",
+	"activity_type.comment_added":       "Comment added",
+	"activity_type.document_uploaded":   "Document uploaded",
+	"activity_type.document_deleted":    "Document deleted",
+	"activity_type.payment_created":     "Payment created",
+	"activity_type.payment_completed":   "Payment completed",
+	"activity_type.payment_failed":      "Payment failed",
+	"activity_type.user_registered":     "User registered",
+	"activity_type.user_login":          "User logged in",
+	"activity_type.user_logout":         "User logged out",
+	"activity_type.password_changed":    "Password changed",
+	"activity_type.email_sent":          "Email sent",
+	"activity_type.email_received":      "Email received",
+	"activity_type.link_clicked":        "Link clicked",
+	"activity_type.system":              "System activity",
+
+	// Bookings and packages
+	"booking_status.pending":    "Pending",
+	"booking_status.confirmed":  "Confirmed",
+	"booking_status.completed":  "Completed",
+	"booking_status.cancelled":  "Cancelled",
+	"booking_status.no_show":    "No-show",
+	"booking_type.consultation": "Consultation",
+	"booking_type.pre_talk":     "Introductory call",
+	"booking_type.follow_up":    "Follow-up appointment",
+	"package_type.basic":        "Basic",
+	"package_type.premium":      "Premium",
+	"package_type.complete":     "Complete",
+
+	// Payments
+	"payment_status.pending":       "Pending",
+	"payment_status.processing":    "Processing",
+	"payment_status.succeeded":     "Succeeded",
+	"payment_status.failed":        "Failed",
+	"payment_status.canceled":      "Canceled",
+	"payment_status.refunded":      "Refunded",
+	"payment_method.stripe":        "Credit card/online",
+	"payment_method.bank_transfer": "Bank transfer",
+	"payment_method.cash":          "Cash",
+
+	// Emails and links
+	"inbound_email_status.matched":    "Matched",
+	"inbound_email_status.unmatched":  "Unmatched",
+	"inbound_email_status.failed":     "Failed",
+	"short_link_purpose.verification": "Email verification",
+	"short_link_purpose.offer":        "Offer",
+	"short_link_purpose.reminder":     "Reminder",
+	"short_link_purpose.other":        "Other",
+
+	// Jobs and applications
+	"job_status.draft":             "Draft",
+	"job_status.published":         "Published",
+	"job_status.paused":            "Paused",
+	"job_status.closed":            "Closed",
+	"job_status.archived":          "Archived",
+	"job_type.full_time":           "Full-time",
+	"job_type.part_time":           "Part-time",
+	"job_type.contract":            "Contract",
+	"job_type.internship":          "Internship",
+	"job_type.freelance":           "Freelance",
+	"job_level.entry":              "Entry level",
+	"job_level.junior":             "Junior",
+	"job_level.mid":                "Mid level",
+	"job_level.senior":             "Senior",
+	"job_level.lead":               "Lead",
+	"work_location.remote":         "Remote",
+	"work_location.on_site":        "On site",
+	"work_location.hybrid":         "Hybrid",
+	"application_status.submitted": "Submitted",
+	"application_status.reviewing": "Under review",
+	"application_status.screening": "Screening",
+	"application_status.interview": "Interview",
+	"application_status.offered":   "Offer made",
+	"application_status.accepted":  "Accepted",
+	"application_status.rejected":  "Rejected",
+	"application_status.withdrawn": "Withdrawn",
+
+	// Employment types of the user profile
+	"employment_type.angestellt":      "Employed",
+	"employment_type.selbststaendig":  "Self-employed",
+	"employment_type.beamtet":         "Civil servant",
+	"employment_type.mischeinkuenfte": "Employed and self-employed",
+	"employment_type.minijob":         "Mini job",
+	"employment_type.ohne_einkommen":  "No income",
+
+	// Roles of the parents of a family
+	"family_role.antragsteller": "Applicant",
+	"family_role.elternteil":    "Parent",
+
+	// Visibility of documents on a lead
+	"document_visibility.kunde":   "Customer only",
+	"document_visibility.berater": "Berater only",
+	"document_visibility.geteilt": "Shared",
+
+	// Types of the questions of intake questionnaires
+	"question_type.text":            "Text",
+	"question_type.zahl":            "Number",
+	"question_type.datum":           "Date",
+	"question_type.auswahl":         "Choice",
+	"question_type.mehrfachauswahl": "Multiple choice",
+	"question_type.ja_nein":         "Yes/No",
+}
@@ -0,0 +1,86 @@
+package i18n
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FormatDate formats a date in the conventional style of the language
+func FormatDate(lang Language, t time.Time) string {
+	switch lang {
+	case English:
+		return t.Format("January 2, 2006")
+	default:
+		return t.Format("02.01.2006")
+	}
+}
+
+// FormatDateTime formats a date with time of day in the conventional style of the language
+func FormatDateTime(lang Language, t time.Time) string {
+	switch lang {
+	case English:
+		return t.Format("January 2, 2006 at 3:04 PM")
+	default:
+		return t.Format("02.01.2006 um 15:04 Uhr")
+	}
+}
+
+// FormatNumber formats a number with two decimals and the language's separators
+func FormatNumber(lang Language, value float64) string {
+	thousands, decimal := ".", ","
+	if lang == English {
+		thousands, decimal = ",", "."
+	}
+
+	negative := value < 0
+	cents := int64(math.Round(math.Abs(value) * 100))
+	whole := strconv.FormatInt(cents/100, 10)
+
+	var b strings.Builder
+	if negative {
+		b.WriteByte('-')
+	}
+	for i, digit := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			b.WriteString(thousands)
+		}
+		b.WriteRune(digit)
+	}
+	b.WriteString(decimal)
+	fraction := strconv.FormatInt(cents%100, 10)
+	if len(fraction) < 2 {
+		b.WriteByte('0')
+	}
+	b.WriteString(fraction)
+
+	return b.String()
+}
+
+// FormatCurrency formats an amount with its currency, e.g. "1.234,50 €" (de) or "€1,234.50" (en)
+func FormatCurrency(lang Language, amount float64, currency string) string {
+	currency = strings.ToUpper(currency)
+	if currency == "" {
+		currency = "EUR"
+	}
+
+	symbol := currency
+	switch currency {
+	case "EUR":
+		symbol = "€"
+	case "USD":
+		symbol = "$"
+	case "GBP":
+		symbol = "£"
+	}
+
+	number := FormatNumber(lang, amount)
+	if lang == English && symbol != currency {
+		if strings.HasPrefix(number, "-") {
+			return "-" + symbol + number[1:]
+		}
+		return symbol + number
+	}
+	return number + " " + symbol
+}
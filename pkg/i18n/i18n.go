@@ -0,0 +1,115 @@
+package i18n
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Language represents a supported language code
+type Language string
+
+const (
+	German  Language = "de"
+	English Language = "en"
+)
+
+// DefaultLanguage is used for emails and documents of recipients without a
+// supported language. API responses stay untranslated instead.
+const DefaultLanguage = German
+
+// catalogs holds the translations per language
+var catalogs = map[Language]map[string]string{
+	German:  germanMessages,
+	English: englishMessages,
+}
+
+// SupportedLanguages lists all languages with a message catalog
+var SupportedLanguages = []Language{German, English}
+
+// Parse converts a language tag like "en", "en-US" or "de_DE" into a supported language
+func Parse(value string) (Language, bool) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if i := strings.IndexAny(value, "-_"); i >= 0 {
+		value = value[:i]
+	}
+
+	for _, lang := range SupportedLanguages {
+		if string(lang) == value {
+			return lang, true
+		}
+	}
+	return "", false
+}
+
+// ParseOrDefault converts a language tag into a supported language, falling back to DefaultLanguage
+func ParseOrDefault(value string) Language {
+	if lang, ok := Parse(value); ok {
+		return lang
+	}
+	return DefaultLanguage
+}
+
+// FromAcceptLanguage picks the preferred supported language from an Accept-Language header
+func FromAcceptLanguage(header string) (Language, bool) {
+	type candidate struct {
+		tag     string
+		quality float64
+	}
+
+	var candidates []candidate
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		if fields[0] == "" {
+			continue
+		}
+
+		quality := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if q, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					quality = q
+				}
+			}
+		}
+		if quality > 0 {
+			candidates = append(candidates, candidate{tag: fields[0], quality: quality})
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].quality > candidates[j].quality
+	})
+
+	for _, c := range candidates {
+		if lang, ok := Parse(c.tag); ok {
+			return lang, true
+		}
+	}
+	return "", false
+}
+
+// T translates a message into the given language. Message IDs are the English
+// source texts, so untranslated messages and unknown languages fall back to the ID.
+// Additional arguments are applied with fmt.Sprintf.
+func T(lang Language, message string, args ...interface{}) string {
+	if translated, ok := catalogs[lang][message]; ok {
+		message = translated
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf(message, args...)
+	}
+	return message
+}
+
+// DisplayName returns the translated display name of an enum value. The key has the
+// form "<kind>.<value>", e.g. "booking_status.confirmed". The German display names
+// live on the model types themselves and are passed in as fallback.
+func DisplayName(lang Language, key string, fallback string) string {
+	if translated, ok := catalogs[lang][key]; ok {
+		return translated
+	}
+	return fallback
+}
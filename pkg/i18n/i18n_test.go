@@ -0,0 +1,103 @@
+package i18n
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected Language
+		ok       bool
+	}{
+		{"de", German, true},
+		{"de-DE", German, true},
+		{"EN_us", English, true},
+		{" en ", English, true},
+		{"fr", "", false},
+		{"", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			lang, ok := Parse(tt.value)
+			assert.Equal(t, tt.expected, lang)
+			assert.Equal(t, tt.ok, ok)
+		})
+	}
+
+	assert.Equal(t, German, ParseOrDefault("fr"))
+}
+
+func TestFromAcceptLanguage(t *testing.T) {
+	tests := []struct {
+		name     string
+		header   string
+		expected Language
+		ok       bool
+	}{
+		{"single", "en-US", English, true},
+		{"ordered", "de-DE,de;q=0.9,en;q=0.8", German, true},
+		{"quality_wins", "en;q=0.5, de;q=0.9", German, true},
+		{"skips_unsupported", "fr-FR,fr;q=0.9,en;q=0.7", English, true},
+		{"ignores_zero_quality", "en;q=0, fr", "", false},
+		{"empty", "", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lang, ok := FromAcceptLanguage(tt.header)
+			assert.Equal(t, tt.expected, lang)
+			assert.Equal(t, tt.ok, ok)
+		})
+	}
+}
+
+func TestT(t *testing.T) {
+	assert.Equal(t, "Lead nicht gefunden", T(German, "Lead not found"))
+	assert.Equal(t, "Lead not found", T(English, "Lead not found"))
+	assert.Equal(t, "Lead not found", T("", "Lead not found"))
+	assert.Equal(t, "Unknown message", T(German, "Unknown message"))
+	assert.Equal(t, "Buchungsbestätigung - BK-1", T(German, "Booking confirmation - %s", "BK-1"))
+	assert.Equal(t, "Booking confirmation - BK-1", T(English, "Booking confirmation - %s", "BK-1"))
+}
+
+func TestDisplayName(t *testing.T) {
+	assert.Equal(t, "Confirmed", DisplayName(English, "booking_status.confirmed", "Bestätigt"))
+	assert.Equal(t, "Bestätigt", DisplayName(German, "booking_status.confirmed", "Bestätigt"))
+	assert.Equal(t, "Unbekannt", DisplayName(English, "booking_status.unknown", "Unbekannt"))
+}
+
+func TestFormatDate(t *testing.T) {
+	date := time.Date(2024, time.March, 5, 14, 30, 0, 0, time.UTC)
+
+	assert.Equal(t, "05.03.2024", FormatDate(German, date))
+	assert.Equal(t, "March 5, 2024", FormatDate(English, date))
+	assert.Equal(t, "05.03.2024 um 14:30 Uhr", FormatDateTime(German, date))
+	assert.Equal(t, "March 5, 2024 at 2:30 PM", FormatDateTime(English, date))
+}
+
+func TestFormatCurrency(t *testing.T) {
+	tests := []struct {
+		lang     Language
+		amount   float64
+		currency string
+		expected string
+	}{
+		{German, 1234.5, "EUR", "1.234,50 €"},
+		{English, 1234.5, "EUR", "€1,234.50"},
+		{German, 99, "eur", "99,00 €"},
+		{English, -12.345, "USD", "-$12.35"},
+		{German, 1000000, "CHF", "1.000.000,00 CHF"},
+		{English, 0.05, "", "€0.05"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expected, func(t *testing.T) {
+			assert.Equal(t, tt.expected, FormatCurrency(tt.lang, tt.amount, tt.currency))
+		})
+	}
+}
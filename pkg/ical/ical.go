@@ -0,0 +1,108 @@
+// Package ical renders calendar invitations (RFC 5545) that mail clients
+// show with accept and decline buttons and add to the recipient's calendar.
+package ical
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ContentType is the MIME type of invitations sent by email
+const ContentType = "text/calendar; charset=utf-8; method=REQUEST"
+
+// Attendee is a participant of an event
+type Attendee struct {
+	Name  string
+	Email string
+}
+
+// Event is a single appointment. Sending an event again with the same UID
+// and a higher Sequence updates it in the recipients' calendars.
+type Event struct {
+	UID         string
+	Sequence    int
+	Summary     string
+	Description string
+	Location    string
+	URL         string
+	Start       time.Time
+	End         time.Time
+	Organizer   Attendee
+	Attendees   []Attendee
+	Created     time.Time
+}
+
+// Invite renders the event as an iCalendar REQUEST
+func Invite(event Event) []byte {
+	var b builder
+	b.line("BEGIN:VCALENDAR")
+	b.line("VERSION:2.0")
+	b.line("PRODID:-//Elterngeld-Portal//DE")
+	b.line("CALSCALE:GREGORIAN")
+	b.line("METHOD:REQUEST")
+	b.line("BEGIN:VEVENT")
+	b.line("UID:" + escape(event.UID))
+	b.line(fmt.Sprintf("SEQUENCE:%d", event.Sequence))
+	b.line("DTSTAMP:" + timestamp(event.Created))
+	b.line("DTSTART:" + timestamp(event.Start))
+	b.line("DTEND:" + timestamp(event.End))
+	b.line("SUMMARY:" + escape(event.Summary))
+	if event.Description != "" {
+		b.line("DESCRIPTION:" + escape(event.Description))
+	}
+	if event.Location != "" {
+		b.line("LOCATION:" + escape(event.Location))
+	}
+	if event.URL != "" {
+		b.line("URL:" + event.URL)
+	}
+	if event.Organizer.Email != "" {
+		b.line(fmt.Sprintf("ORGANIZER;CN=%s:mailto:%s", quote(event.Organizer.Name), event.Organizer.Email))
+	}
+	for _, attendee := range event.Attendees {
+		b.line(fmt.Sprintf("ATTENDEE;CN=%s;ROLE=REQ-PARTICIPANT;PARTSTAT=NEEDS-ACTION;RSVP=TRUE:mailto:%s",
+			quote(attendee.Name), attendee.Email))
+	}
+	b.line("STATUS:CONFIRMED")
+	b.line("END:VEVENT")
+	b.line("END:VCALENDAR")
+	return []byte(b.String())
+}
+
+// builder writes content lines folded at 75 octets with CRLF line endings
+type builder struct {
+	strings.Builder
+}
+
+func (b *builder) line(content string) {
+	// Continuation lines start with a space, which counts towards the limit
+	limit := 75
+	for len(content) > limit {
+		cut := limit
+		// Do not split multi-byte UTF-8 characters
+		for cut > 0 && content[cut]&0xC0 == 0x80 {
+			cut--
+		}
+		b.WriteString(content[:cut] + "\r\n ")
+		content = content[cut:]
+		limit = 74
+	}
+	b.WriteString(content + "\r\n")
+}
+
+func timestamp(t time.Time) string {
+	return t.UTC().Format("20060102T150405Z")
+}
+
+var escaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+
+// escape escapes text values
+func escape(value string) string {
+	return escaper.Replace(value)
+}
+
+// quote quotes parameter values, which must not contain double quotes
+func quote(value string) string {
+	return `"` + strings.ReplaceAll(value, `"`, "'") + `"`
+}
@@ -0,0 +1,40 @@
+package ical
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInvite(t *testing.T) {
+	berlin, _ := time.LoadLocation("Europe/Berlin")
+	start := time.Date(2025, 3, 10, 14, 0, 0, 0, berlin)
+
+	invite := string(Invite(Event{
+		UID:         "interview-123@elterngeld.example",
+		Summary:     "Vorstellungsgespräch: Elterngeldberater (m/w/d)",
+		Description: "Wir freuen uns auf Sie.\nBitte seien Sie pünktlich; danke, Ihr Team",
+		Location:    "Online",
+		Start:       start,
+		End:         start.Add(45 * time.Minute),
+		Organizer:   Attendee{Name: "Petra Personal", Email: "hr@elterngeld.example"},
+		Attendees:   []Attendee{{Name: `Max "Maxi" Mustermann`, Email: "max@example.com"}},
+		Created:     start.Add(-48 * time.Hour),
+	}))
+
+	assert.True(t, strings.HasPrefix(invite, "BEGIN:VCALENDAR\r\nVERSION:2.0\r\n"))
+	assert.Contains(t, invite, "METHOD:REQUEST\r\n")
+	assert.Contains(t, invite, "DTSTART:20250310T130000Z\r\n")
+	assert.Contains(t, invite, "DTEND:20250310T134500Z\r\n")
+	assert.Contains(t, invite, "DESCRIPTION:Wir freuen uns auf Sie.\\nBitte seien Sie pünktlich\\; danke\\, I\r\n hr Team\r\n")
+	assert.Contains(t, invite, `ATTENDEE;CN="Max 'Maxi' Mustermann";ROLE=REQ-PARTICIPANT`)
+	assert.True(t, strings.HasSuffix(invite, "END:VEVENT\r\nEND:VCALENDAR\r\n"))
+
+	for _, line := range strings.Split(strings.TrimSuffix(invite, "\r\n"), "\r\n") {
+		assert.LessOrEqual(t, len(line), 75, line)
+		assert.True(t, utf8.ValidString(line), line)
+	}
+}
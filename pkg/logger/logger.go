@@ -45,8 +45,8 @@ func Init(cfg *config.Config) error {
 		zapConfig.Encoding = "console"
 	}
 
-	// Build logger
-	logger, err := zapConfig.Build(zap.AddCallerSkip(1))
+	// Build logger, masking personal data in all output
+	logger, err := zapConfig.Build(zap.AddCallerSkip(1), zap.WrapCore(NewRedactingCore))
 	if err != nil {
 		return err
 	}
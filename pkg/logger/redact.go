@@ -0,0 +1,66 @@
+package logger
+
+import (
+	"fmt"
+
+	"elterngeld-portal/pkg/pii"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// redactingCore masks personal data in log messages and fields before they are written
+type redactingCore struct {
+	zapcore.Core
+}
+
+// NewRedactingCore wraps a core so emails, phone numbers and addresses never reach
+// the log output. Fields named like personal data (email, phone, customer_address, ...)
+// are masked completely, other strings and errors are scrubbed of emails and phone numbers.
+func NewRedactingCore(core zapcore.Core) zapcore.Core {
+	return &redactingCore{Core: core}
+}
+
+func (c *redactingCore) With(fields []zapcore.Field) zapcore.Core {
+	return &redactingCore{Core: c.Core.With(redactFields(fields))}
+}
+
+func (c *redactingCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return checked.AddCore(entry, c)
+	}
+	return checked
+}
+
+func (c *redactingCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	entry.Message = pii.Scrub(entry.Message)
+	return c.Core.Write(entry, redactFields(fields))
+}
+
+func redactFields(fields []zapcore.Field) []zapcore.Field {
+	redacted := make([]zapcore.Field, len(fields))
+	for i, field := range fields {
+		redacted[i] = redactField(field)
+	}
+	return redacted
+}
+
+func redactField(field zapcore.Field) zapcore.Field {
+	switch field.Type {
+	case zapcore.StringType:
+		if kind, ok := pii.FieldKind(field.Key); ok {
+			field.String = pii.Mask(kind, field.String)
+		} else {
+			field.String = pii.Scrub(field.String)
+		}
+	case zapcore.ErrorType:
+		if err, ok := field.Interface.(error); ok && err != nil {
+			return zap.String(field.Key, pii.Scrub(err.Error()))
+		}
+	case zapcore.StringerType:
+		if stringer, ok := field.Interface.(fmt.Stringer); ok {
+			return redactField(zap.String(field.Key, stringer.String()))
+		}
+	}
+	return field
+}
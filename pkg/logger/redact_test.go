@@ -0,0 +1,37 @@
+package logger
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestRedactingCore(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	log := zap.New(NewRedactingCore(core)).With(zap.String("user_email", "anna@example.com"))
+
+	log.Info("Password reset requested by anna@example.com",
+		zap.String("email", "anna@example.com"),
+		zap.String("customer_address", "Hauptstraße 5"),
+		zap.String("note", "call 0171 1234567"),
+		zap.String("request_id", "550e8400-e29b-41d4-a716-446655440000"),
+		zap.Error(errors.New("no user with email anna@example.com")),
+	)
+	log.Debug("filtered by level", zap.String("email", "anna@example.com"))
+
+	require.Equal(t, 1, logs.Len())
+	entry := logs.All()[0]
+	assert.Equal(t, "Password reset requested by a***@example.com", entry.Message)
+
+	fields := entry.ContextMap()
+	assert.Equal(t, "a***@example.com", fields["user_email"])
+	assert.Equal(t, "a***@example.com", fields["email"])
+	assert.Equal(t, "***", fields["customer_address"])
+	assert.Equal(t, "call ***67", fields["note"])
+	assert.Equal(t, "550e8400-e29b-41d4-a716-446655440000", fields["request_id"])
+	assert.Equal(t, "no user with email a***@example.com", fields["error"])
+}
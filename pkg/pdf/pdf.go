@@ -0,0 +1,235 @@
+// Package pdf writes simple text documents as PDF without external tools. It
+// supports headings, wrapped paragraphs and page numbers in the standard
+// Helvetica fonts, which covers the Latin-1 characters used in German text.
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// A4 page size and layout in points
+const (
+	pageWidth    = 595.28
+	pageHeight   = 841.89
+	margin       = 56.0
+	footerOffset = 30.0
+
+	bodySize    = 10.0
+	headingSize = 13.0
+	titleSize   = 18.0
+	lineSpacing = 1.4
+
+	// averageCharWidth is the average Helvetica glyph width relative to the
+	// font size; it is slightly generous so wrapped lines never overflow
+	averageCharWidth = 0.53
+)
+
+const (
+	fontRegular = "F1"
+	fontBold    = "F2"
+)
+
+type line struct {
+	text string
+	font string
+	size float64
+	y    float64
+}
+
+// Document is a PDF under construction
+type Document struct {
+	pages  [][]line
+	y      float64
+	footer string
+}
+
+// New creates an empty A4 document
+func New() *Document {
+	d := &Document{}
+	d.newPage()
+	return d
+}
+
+// SetFooter sets the text printed at the bottom left of every page, next to the page number
+func (d *Document) SetFooter(text string) {
+	d.footer = text
+}
+
+// Title adds the document title
+func (d *Document) Title(text string) {
+	d.add(text, fontBold, titleSize)
+	d.Space()
+}
+
+// Heading adds a section heading, starting a new page if it would end up last on the page
+func (d *Document) Heading(text string) {
+	if d.y-3*headingSize*lineSpacing < margin {
+		d.newPage()
+	}
+	d.Space()
+	d.add(text, fontBold, headingSize)
+}
+
+// Text adds a paragraph, wrapped to the page width. Line breaks are kept.
+func (d *Document) Text(text string) {
+	for _, paragraph := range strings.Split(text, "\n") {
+		for _, wrapped := range wrap(paragraph, bodySize) {
+			d.add(wrapped, fontRegular, bodySize)
+		}
+	}
+}
+
+// Field adds a "label: value" line
+func (d *Document) Field(label, value string) {
+	d.Text(label + ": " + value)
+}
+
+// Space adds an empty line
+func (d *Document) Space() {
+	d.y -= bodySize * lineSpacing
+}
+
+// PageCount returns the number of pages
+func (d *Document) PageCount() int {
+	return len(d.pages)
+}
+
+func (d *Document) add(text, font string, size float64) {
+	height := size * lineSpacing
+	if d.y-height < margin {
+		d.newPage()
+	}
+	d.y -= height
+	d.pages[len(d.pages)-1] = append(d.pages[len(d.pages)-1], line{text: text, font: font, size: size, y: d.y})
+}
+
+func (d *Document) newPage() {
+	d.pages = append(d.pages, nil)
+	d.y = pageHeight - margin
+}
+
+// wrap splits text into lines that fit the page width at the given font size
+func wrap(text string, size float64) []string {
+	maxChars := lineLength(size)
+	words := strings.Fields(text)
+	if len(words) == 0 {
+		return []string{""}
+	}
+
+	var lines []string
+	current := ""
+	for _, word := range words {
+		for utf8.RuneCountInString(word) > maxChars {
+			if current != "" {
+				lines = append(lines, current)
+				current = ""
+			}
+			runes := []rune(word)
+			lines = append(lines, string(runes[:maxChars]))
+			word = string(runes[maxChars:])
+		}
+		switch {
+		case current == "":
+			current = word
+		case utf8.RuneCountInString(current)+1+utf8.RuneCountInString(word) <= maxChars:
+			current += " " + word
+		default:
+			lines = append(lines, current)
+			current = word
+		}
+	}
+	return append(lines, current)
+}
+
+// lineLength returns the number of characters that fit the page width
+func lineLength(size float64) int {
+	return int((pageWidth - 2*margin) / (size * averageCharWidth))
+}
+
+// Bytes renders the document
+func (d *Document) Bytes() []byte {
+	var buf bytes.Buffer
+	var offsets []int
+	object := func(body string) {
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	// Objects 1-4 are the catalog, the page tree and the fonts; each page
+	// follows as a page object and its content stream
+	kids := make([]string, len(d.pages))
+	for i := range d.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 5+2*i)
+	}
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages)))
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+
+	for i, page := range d.pages {
+		var content bytes.Buffer
+		for _, l := range page {
+			writeText(&content, l.font, l.size, margin, l.y, l.text)
+		}
+		pageNumber := fmt.Sprintf("%d / %d", i+1, len(d.pages))
+		writeText(&content, fontRegular, 8, pageWidth-margin-float64(len(pageNumber))*8*averageCharWidth, footerOffset, pageNumber)
+		if d.footer != "" {
+			writeText(&content, fontRegular, 8, margin, footerOffset, d.footer)
+		}
+
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.2f %.2f] "+
+			"/Resources << /Font << /%s 3 0 R /%s 4 0 R >> >> /Contents %d 0 R >>",
+			pageWidth, pageHeight, fontRegular, fontBold, 6+2*i))
+		object(fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", content.Len(), content.String()))
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+
+	return buf.Bytes()
+}
+
+func writeText(w *bytes.Buffer, font string, size, x, y float64, text string) {
+	fmt.Fprintf(w, "BT /%s %.1f Tf %.2f %.2f Td (%s) Tj ET\n", font, size, x, y, encode(text))
+}
+
+// winAnsi maps the characters outside Latin-1 that WinAnsiEncoding supports
+var winAnsi = map[rune]byte{
+	'€': 0x80, '‚': 0x82, '„': 0x84, '…': 0x85, '‘': 0x91, '’': 0x92,
+	'“': 0x93, '”': 0x94, '•': 0x95, '–': 0x96, '—': 0x97,
+}
+
+// encode converts text to an escaped WinAnsi PDF string; unsupported
+// characters are replaced with a question mark
+func encode(text string) string {
+	var b strings.Builder
+	for _, r := range text {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r == '\t':
+			b.WriteByte(' ')
+		case r >= 0x20 && r < 0x7f:
+			b.WriteRune(r)
+		case r >= 0xa0 && r <= 0xff:
+			fmt.Fprintf(&b, "\\%03o", r)
+		case winAnsi[r] != 0:
+			fmt.Fprintf(&b, "\\%03o", winAnsi[r])
+		case r < 0x20:
+			// Drop control characters
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
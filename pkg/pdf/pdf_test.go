@@ -0,0 +1,61 @@
+package pdf
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncode(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		expected string
+	}{
+		{"ascii", "Elterngeld 2026", "Elterngeld 2026"},
+		{"escapes", `(a\b)`, `\(a\\b\)`},
+		{"umlauts", "Gebühr für Ärzte", `Geb\374hr f\374r \304rzte`},
+		{"euro and dash", "149 € – bezahlt", `149 \200 \226 bezahlt`},
+		{"unsupported", "日本", "??"},
+		{"control characters", "a\tb\x01c", "a bc"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, encode(tt.text))
+		})
+	}
+}
+
+func TestWrap(t *testing.T) {
+	lines := wrap(strings.Repeat("Elterngeld ", 40), bodySize)
+	assert.Greater(t, len(lines), 1)
+	for _, l := range lines {
+		assert.LessOrEqual(t, len(l), lineLength(bodySize))
+	}
+
+	long := wrap(strings.Repeat("x", 200), bodySize)
+	assert.Len(t, long, 3)
+	assert.Equal(t, []string{""}, wrap("   ", bodySize))
+}
+
+func TestBytes(t *testing.T) {
+	doc := New()
+	doc.SetFooter("Fallakte EG-2026-0001")
+	doc.Title("Fallakte")
+	doc.Heading("Verlauf")
+	for i := 0; i < 120; i++ {
+		doc.Field("Eintrag", "Dokument hochgeladen")
+	}
+
+	assert.Equal(t, 3, doc.PageCount())
+
+	data := doc.Bytes()
+	assert.True(t, bytes.HasPrefix(data, []byte("%PDF-1.4")))
+	assert.True(t, bytes.HasSuffix(data, []byte("%%EOF\n")))
+	assert.Contains(t, string(data), "/Count 3")
+	assert.Contains(t, string(data), "(3 / 3) Tj")
+	assert.Contains(t, string(data), "(Fallakte EG-2026-0001) Tj")
+}
@@ -0,0 +1,146 @@
+// Package pii masks personal data (emails, phone numbers and addresses) for API
+// responses and scrubs it from log output.
+package pii
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Kind is a category of personal data
+type Kind string
+
+const (
+	KindEmail   Kind = "email"
+	KindPhone   Kind = "phone"
+	KindAddress Kind = "address"
+)
+
+// mask replaces hidden characters
+const mask = "***"
+
+// fieldKinds maps JSON and log field names to the personal data they contain
+var fieldKinds = map[string]Kind{
+	"email":            KindEmail,
+	"billing_email":    KindEmail,
+	"contact_email":    KindEmail,
+	"customer_email":   KindEmail,
+	"from_email":       KindEmail,
+	"recipient_email":  KindEmail,
+	"to_email":         KindEmail,
+	"user_email":       KindEmail,
+	"phone":            KindPhone,
+	"contact_phone":    KindPhone,
+	"customer_phone":   KindPhone,
+	"address":          KindAddress,
+	"billing_address":  KindAddress,
+	"customer_address": KindAddress,
+}
+
+var (
+	emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
+	// Phone numbers start with + or 0 and must not continue an identifier, so ids,
+	// timestamps and amounts in log lines stay intact
+	phonePattern = regexp.MustCompile(`(^|[^\w.:-])((?:\+|0)\d[\d ()/-]{5,}\d)`)
+)
+
+// FieldKind returns the kind of personal data a field with the given name holds
+func FieldKind(name string) (Kind, bool) {
+	kind, ok := fieldKinds[strings.ToLower(name)]
+	return kind, ok
+}
+
+// Mask masks a value of the given kind
+func Mask(kind Kind, value string) string {
+	switch kind {
+	case KindEmail:
+		return MaskEmail(value)
+	case KindPhone:
+		return MaskPhone(value)
+	default:
+		return MaskAddress(value)
+	}
+}
+
+// MaskEmail keeps the first character and the domain: a***@example.com
+func MaskEmail(email string) string {
+	local, domain, ok := strings.Cut(strings.TrimSpace(email), "@")
+	if !ok || local == "" {
+		return maskNonEmpty(email)
+	}
+	return string([]rune(local)[:1]) + mask + "@" + domain
+}
+
+// MaskPhone keeps the last two digits: ***67
+func MaskPhone(phone string) string {
+	var digits []rune
+	for _, r := range phone {
+		if unicode.IsDigit(r) {
+			digits = append(digits, r)
+		}
+	}
+	if len(digits) < 6 {
+		return maskNonEmpty(phone)
+	}
+	return mask + string(digits[len(digits)-2:])
+}
+
+// MaskAddress hides the address completely
+func MaskAddress(address string) string {
+	return maskNonEmpty(address)
+}
+
+// Scrub masks email addresses and phone numbers in free text
+func Scrub(text string) string {
+	if text == "" {
+		return text
+	}
+	text = emailPattern.ReplaceAllStringFunc(text, MaskEmail)
+	return phonePattern.ReplaceAllStringFunc(text, func(match string) string {
+		groups := phonePattern.FindStringSubmatch(match)
+		return groups[1] + MaskPhone(groups[2])
+	})
+}
+
+// MaskJSON masks all known personal data fields in a JSON document
+func MaskJSON(data []byte) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var document interface{}
+	if err := decoder.Decode(&document); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(maskValue(document))
+}
+
+func maskValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, field := range v {
+			if s, ok := field.(string); ok {
+				if kind, ok := FieldKind(key); ok {
+					v[key] = Mask(kind, s)
+				}
+				continue
+			}
+			v[key] = maskValue(field)
+		}
+	case []interface{}:
+		for i := range v {
+			v[i] = maskValue(v[i])
+		}
+	}
+	return value
+}
+
+func maskNonEmpty(value string) string {
+	if strings.TrimSpace(value) == "" {
+		return value
+	}
+	return mask
+}
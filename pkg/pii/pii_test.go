@@ -0,0 +1,61 @@
+package pii
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMask(t *testing.T) {
+	assert.Equal(t, "a***@example.com", MaskEmail("anna.schmidt@example.com"))
+	assert.Equal(t, "Ä***@example.com", MaskEmail("Änne@example.com"))
+	assert.Equal(t, "***", MaskEmail("not-an-email"))
+	assert.Equal(t, "", MaskEmail(""))
+
+	assert.Equal(t, "***67", MaskPhone("+49 171 1234567"))
+	assert.Equal(t, "***", MaskPhone("110"))
+	assert.Equal(t, "", MaskPhone(""))
+
+	assert.Equal(t, "***", MaskAddress("Hauptstraße 5, 10115 Berlin"))
+	assert.Equal(t, "", MaskAddress(""))
+}
+
+func TestScrub(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		expected string
+	}{
+		{"email", "Email sent to anna@example.com", "Email sent to a***@example.com"},
+		{"phone", "Rückruf unter 0171/1234567 erbeten", "Rückruf unter ***67 erbeten"},
+		{"international phone", "call +49 30 1234567", "call ***67"},
+		{"uuid", "lead 550e8400-e29b-41d4-a716-446655440000 updated", "lead 550e8400-e29b-41d4-a716-446655440000 updated"},
+		{"timestamp", "at 2025-03-01 09:00:00", "at 2025-03-01 09:00:00"},
+		{"amount", "paid 0.99 EUR, ref 2025000123", "paid 0.99 EUR, ref 2025000123"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, Scrub(tt.text))
+		})
+	}
+}
+
+func TestMaskJSON(t *testing.T) {
+	masked, err := MaskJSON([]byte(`{
+		"data": [{"id": 7, "email": "anna@example.com", "customer_phone": "0171 1234567",
+			"address": "Hauptstraße 5", "first_name": "Anna", "email_verified": true}],
+		"total": 1
+	}`))
+	require.NoError(t, err)
+
+	assert.JSONEq(t, `{
+		"data": [{"id": 7, "email": "a***@example.com", "customer_phone": "***67",
+			"address": "***", "first_name": "Anna", "email_verified": true}],
+		"total": 1
+	}`, string(masked))
+
+	_, err = MaskJSON([]byte("not json"))
+	assert.Error(t, err)
+}
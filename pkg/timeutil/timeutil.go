@@ -0,0 +1,100 @@
+package timeutil
+
+import (
+	"sync"
+	"time"
+	_ "time/tzdata" // container images often ship without a timezone database
+)
+
+// DefaultTimezone is used for Beraters and clients without an explicit timezone
+const DefaultTimezone = "Europe/Berlin"
+
+// DateLayout is the layout of calendar dates in requests and responses
+const DateLayout = "2006-01-02"
+
+var (
+	locationsMu sync.RWMutex
+	locations   = map[string]*time.Location{}
+)
+
+// LoadLocation returns the named IANA location. Invalid, empty and "Local" names
+// fall back to DefaultTimezone and, if the timezone database is unavailable, to UTC.
+func LoadLocation(name string) *time.Location {
+	if name == "" || name == "Local" {
+		name = DefaultTimezone
+	}
+
+	locationsMu.RLock()
+	loc, ok := locations[name]
+	locationsMu.RUnlock()
+	if ok {
+		return loc
+	}
+
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		if name != DefaultTimezone {
+			return LoadLocation(DefaultTimezone)
+		}
+		loc = time.UTC
+	}
+
+	locationsMu.Lock()
+	locations[name] = loc
+	locationsMu.Unlock()
+
+	return loc
+}
+
+// IsValidTimezone checks if name is a known IANA timezone. The server's
+// "Local" zone is rejected on purpose.
+func IsValidTimezone(name string) bool {
+	if name == "" || name == "Local" {
+		return false
+	}
+	_, err := time.LoadLocation(name)
+	return err == nil
+}
+
+// At returns the UTC instant of the wall clock time hour:minute on the calendar
+// date of day in loc. Wall clock times skipped by a DST transition are moved
+// forward by the length of the gap (02:30 becomes 03:30 in spring).
+func At(day time.Time, hour, minute int, loc *time.Location) time.Time {
+	local := day.In(loc)
+	return time.Date(local.Year(), local.Month(), local.Day(), hour, minute, 0, 0, loc).UTC()
+}
+
+// StartOfDay returns the UTC instant of local midnight of the day containing t
+func StartOfDay(t time.Time, loc *time.Location) time.Time {
+	return At(t, 0, 0, loc)
+}
+
+// ParseDate parses a calendar date (YYYY-MM-DD) and returns the UTC instant of
+// its local midnight in loc
+func ParseDate(value string, loc *time.Location) (time.Time, error) {
+	date, err := time.ParseInLocation(DateLayout, value, loc)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return date.UTC(), nil
+}
+
+// DayRange returns the UTC bounds [start, end) of the given number of local calendar
+// days beginning with the day containing from. Days around DST transitions are
+// 23 or 25 hours long.
+func DayRange(from time.Time, days int, loc *time.Location) (time.Time, time.Time) {
+	local := from.In(loc)
+	start := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc)
+	end := start.AddDate(0, 0, days)
+	return start.UTC(), end.UTC()
+}
+
+// AddDays moves t by the given number of calendar days in loc, keeping the wall clock time
+func AddDays(t time.Time, days int, loc *time.Location) time.Time {
+	return t.In(loc).AddDate(0, 0, days).UTC()
+}
+
+// FormatDate formats the local calendar date of t in loc
+func FormatDate(t time.Time, loc *time.Location) string {
+	return t.In(loc).Format(DateLayout)
+}
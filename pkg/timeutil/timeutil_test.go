@@ -0,0 +1,98 @@
+package timeutil
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadLocation(t *testing.T) {
+	assert.Equal(t, "Europe/Berlin", LoadLocation("").String())
+	assert.Equal(t, "Europe/Berlin", LoadLocation("Local").String())
+	assert.Equal(t, "Europe/Berlin", LoadLocation("Mars/Olympus").String())
+	assert.Equal(t, "America/New_York", LoadLocation("America/New_York").String())
+
+	assert.True(t, IsValidTimezone("Europe/Vienna"))
+	assert.False(t, IsValidTimezone("Local"))
+	assert.False(t, IsValidTimezone("Mars/Olympus"))
+	assert.False(t, IsValidTimezone(""))
+}
+
+func TestAt_DSTTransitions(t *testing.T) {
+	berlin := LoadLocation("Europe/Berlin")
+
+	tests := []struct {
+		name     string
+		day      time.Time
+		hour     int
+		minute   int
+		expected time.Time
+	}{
+		{"winter", time.Date(2024, time.January, 15, 12, 0, 0, 0, time.UTC), 9, 0, time.Date(2024, time.January, 15, 8, 0, 0, 0, time.UTC)},
+		{"spring_forward_day", time.Date(2024, time.March, 31, 12, 0, 0, 0, time.UTC), 9, 0, time.Date(2024, time.March, 31, 7, 0, 0, 0, time.UTC)},
+		{"spring_forward_gap", time.Date(2024, time.March, 31, 12, 0, 0, 0, time.UTC), 2, 30, time.Date(2024, time.March, 31, 1, 30, 0, 0, time.UTC)},
+		{"fall_back_day", time.Date(2024, time.October, 27, 12, 0, 0, 0, time.UTC), 9, 0, time.Date(2024, time.October, 27, 8, 0, 0, 0, time.UTC)},
+		{"local_day_differs_from_utc", time.Date(2024, time.June, 30, 23, 0, 0, 0, time.UTC), 9, 0, time.Date(2024, time.July, 1, 7, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := At(tt.day, tt.hour, tt.minute, berlin)
+			assert.Equal(t, tt.expected, got)
+			assert.Equal(t, time.UTC, got.Location())
+		})
+	}
+}
+
+func TestDayRange_DSTTransitions(t *testing.T) {
+	berlin := LoadLocation("Europe/Berlin")
+
+	start, end := DayRange(time.Date(2024, time.March, 31, 12, 0, 0, 0, time.UTC), 1, berlin)
+	assert.Equal(t, time.Date(2024, time.March, 30, 23, 0, 0, 0, time.UTC), start)
+	assert.Equal(t, 23*time.Hour, end.Sub(start))
+
+	start, end = DayRange(time.Date(2024, time.October, 27, 12, 0, 0, 0, time.UTC), 1, berlin)
+	assert.Equal(t, time.Date(2024, time.October, 26, 22, 0, 0, 0, time.UTC), start)
+	assert.Equal(t, 25*time.Hour, end.Sub(start))
+
+	start, end = DayRange(time.Date(2024, time.March, 30, 12, 0, 0, 0, time.UTC), 3, berlin)
+	assert.Equal(t, time.Date(2024, time.March, 29, 23, 0, 0, 0, time.UTC), start)
+	assert.Equal(t, time.Date(2024, time.April, 1, 22, 0, 0, 0, time.UTC), end)
+}
+
+func TestParseDate(t *testing.T) {
+	date, err := ParseDate("2024-10-27", LoadLocation("Europe/Berlin"))
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2024, time.October, 26, 22, 0, 0, 0, time.UTC), date)
+
+	date, err = ParseDate("2024-10-27", LoadLocation("America/New_York"))
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2024, time.October, 27, 4, 0, 0, 0, time.UTC), date)
+
+	_, err = ParseDate("27.10.2024", LoadLocation("Europe/Berlin"))
+	assert.Error(t, err)
+}
+
+func TestAddDays_KeepsWallClockAcrossDST(t *testing.T) {
+	berlin := LoadLocation("Europe/Berlin")
+
+	// 10:00 CEST on the Sunday clocks went forward is 10:00 CET the day before
+	appointment := time.Date(2024, time.March, 31, 8, 0, 0, 0, time.UTC)
+	dayBefore := AddDays(appointment, -1, berlin)
+	assert.Equal(t, time.Date(2024, time.March, 30, 9, 0, 0, 0, time.UTC), dayBefore)
+	assert.Equal(t, 23*time.Hour, appointment.Sub(dayBefore))
+
+	// Across the autumn transition the same wall clock time is 25 hours later
+	appointment = time.Date(2024, time.October, 26, 8, 0, 0, 0, time.UTC)
+	nextDay := AddDays(appointment, 1, berlin)
+	assert.Equal(t, time.Date(2024, time.October, 27, 9, 0, 0, 0, time.UTC), nextDay)
+	assert.Equal(t, 25*time.Hour, nextDay.Sub(appointment))
+}
+
+func TestFormatDate(t *testing.T) {
+	instant := time.Date(2024, time.June, 30, 23, 0, 0, 0, time.UTC)
+	assert.Equal(t, "2024-07-01", FormatDate(instant, LoadLocation("Europe/Berlin")))
+	assert.Equal(t, "2024-06-30", FormatDate(instant, LoadLocation("America/New_York")))
+}
@@ -0,0 +1,162 @@
+//go:build integration
+
+package integration
+
+import (
+	"net/http"
+	"testing"
+
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/pkg/timeutil"
+	"elterngeld-portal/tests/testutils"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestBookingFlow walks a customer from registration through booking and
+// payment to the lead being assigned to a Berater
+func TestBookingFlow(t *testing.T) {
+	h := newHarness(t)
+
+	berater := h.createUser(t, models.RoleBerater)
+	admin := h.createUser(t, models.RoleAdmin)
+	servicePackage := &models.Package{
+		Name:             "Basis-Beratung",
+		Description:      "Elterngeld-Beratung per Video",
+		Type:             models.PackageTypeBasic,
+		Price:            149,
+		Currency:         "EUR",
+		IsActive:         true,
+		RequiresTimeslot: true,
+		ConsultationTime: 60,
+	}
+	require.NoError(t, h.db.Create(servicePackage).Error)
+	slot := h.bookableTimeslot(t, berater)
+
+	email := testutils.RandomEmail()
+	var customerToken string
+	var bookingID, leadID uuid.UUID
+	var sessionID string
+
+	t.Run("register", func(t *testing.T) {
+		w := h.client.POST("/api/v1/auth/register", jsonBody(t, map[string]interface{}{
+			"email":      email,
+			"password":   testPassword,
+			"first_name": "Anna",
+			"last_name":  "Kunde",
+		}), nil)
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		testutils.AssertRecordExists(t, h.db, &models.User{}, "email = ?", email)
+
+		customerToken = h.login(t, email)
+	})
+
+	t.Run("timeslot_availability", func(t *testing.T) {
+		date := timeutil.FormatDate(slot.StartTime, slot.TimeLocation())
+		w := h.client.GET("/api/v1/timeslots/available?days=1&package_id="+servicePackage.ID.String()+"&date="+date, nil)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		var response struct {
+			Timeslots []struct {
+				ID uuid.UUID `json:"id"`
+			} `json:"timeslots"`
+		}
+		testutils.ParseJSONResponse(t, w, &response)
+		require.Len(t, response.Timeslots, 1)
+		assert.Equal(t, slot.ID, response.Timeslots[0].ID)
+	})
+
+	t.Run("book", func(t *testing.T) {
+		require.NotEmpty(t, customerToken)
+		w := h.client.POST("/api/v1/bookings", jsonBody(t, map[string]interface{}{
+			"package_id":  servicePackage.ID,
+			"timeslot_id": slot.ID,
+		}), testutils.WithAuth(customerToken))
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+		var response struct {
+			ID   uuid.UUID `json:"id"`
+			Lead struct {
+				ID uuid.UUID `json:"id"`
+			} `json:"lead"`
+		}
+		testutils.ParseJSONResponse(t, w, &response)
+		bookingID, leadID = response.ID, response.Lead.ID
+		require.NotEqual(t, uuid.Nil, bookingID)
+		require.NotEqual(t, uuid.Nil, leadID)
+	})
+
+	t.Run("checkout", func(t *testing.T) {
+		require.NotEqual(t, uuid.Nil, bookingID)
+		w := h.client.POST("/api/v1/payments/checkout", jsonBody(t, map[string]interface{}{
+			"booking_id": bookingID,
+		}), testutils.WithAuth(customerToken))
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		var response struct {
+			SessionID   string `json:"session_id"`
+			CheckoutURL string `json:"checkout_url"`
+		}
+		testutils.ParseJSONResponse(t, w, &response)
+		sessionID = response.SessionID
+		require.NotEmpty(t, sessionID)
+		assert.NotEmpty(t, response.CheckoutURL)
+		testutils.AssertRecordExists(t, h.db, &models.Payment{}, "stripe_session_id = ?", sessionID)
+	})
+
+	t.Run("pay_via_webhook", func(t *testing.T) {
+		require.NotEmpty(t, sessionID)
+		h.sendStripeEvent(t, "checkout.session.completed", map[string]interface{}{
+			"id":             sessionID,
+			"object":         "checkout.session",
+			"payment_intent": "pi_integration",
+			"payment_status": "paid",
+			"metadata": map[string]string{
+				"booking_id": bookingID.String(),
+			},
+		})
+
+		// The webhook is stored and handled by the receiver workers
+		eventually(t, func() bool {
+			var payment models.Payment
+			return h.db.Where("stripe_session_id = ?", sessionID).First(&payment).Error == nil && payment.IsPaid()
+		}, "payment was not completed")
+		eventually(t, func() bool {
+			var booking models.Booking
+			return h.db.First(&booking, "id = ?", bookingID).Error == nil && booking.Status == models.BookingStatusConfirmed
+		}, "booking was not confirmed")
+	})
+
+	t.Run("assign_lead", func(t *testing.T) {
+		require.NotEqual(t, uuid.Nil, leadID)
+		body := jsonBody(t, map[string]interface{}{"assigned_to_id": berater.ID})
+
+		// Customers cannot assign leads
+		w := h.client.POST("/api/v1/leads/"+leadID.String()+"/assign", body, testutils.WithAuth(customerToken))
+		assert.Equal(t, http.StatusForbidden, w.Code, w.Body.String())
+
+		w = h.client.POST("/api/v1/leads/"+leadID.String()+"/assign", body, testutils.WithAuth(h.login(t, admin.Email)))
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		var lead models.Lead
+		require.NoError(t, h.db.First(&lead, "id = ?", leadID).Error)
+		require.NotNil(t, lead.BeraterID)
+		assert.Equal(t, berater.ID, *lead.BeraterID)
+	})
+}
+
+// TestStripeWebhook_RejectsUnsigned checks that payments cannot be completed
+// with forged webhook requests
+func TestStripeWebhook_RejectsUnsigned(t *testing.T) {
+	h := newHarness(t)
+
+	payload := jsonBody(t, map[string]interface{}{
+		"id":   "evt_forged",
+		"type": "checkout.session.completed",
+	})
+	w := h.client.POST("/api/v1/webhooks/stripe", payload, map[string]string{"Stripe-Signature": "t=1,v1=00"})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	testutils.AssertRecordCount(t, h.db, &models.WebhookEvent{}, 0)
+}
@@ -0,0 +1,204 @@
+//go:build integration
+
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"elterngeld-portal/config"
+	"elterngeld-portal/internal/database"
+	"elterngeld-portal/internal/holidays"
+	"elterngeld-portal/internal/models"
+	"elterngeld-portal/internal/server"
+	"elterngeld-portal/tests/testutils"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"github.com/stripe/stripe-go/v76/webhook"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// The tests in this build tag run the full router with its background workers
+// against PostgreSQL and stripe-mock. Start them and run the suite with
+//
+//	make test-integration
+//
+// which reads the connection settings below from the environment.
+
+const (
+	testWebhookSecret = "whsec_integration"
+	testPassword      = "Integration-Test-1"
+)
+
+// flowTables are created by the SQL migrations rather than by AutoMigrate;
+// the flows need them in the empty test database
+var flowTables = []interface{}{
+	&models.Package{},
+	&models.Addon{},
+	&models.PackageAddon{},
+	&models.Timeslot{},
+	&models.BookingAddon{},
+	&models.Todo{},
+	&models.Reminder{},
+}
+
+// harness is a running server on a freshly migrated database
+type harness struct {
+	db     *gorm.DB
+	client *testutils.TestHTTPClient
+}
+
+func newHarness(t *testing.T) *harness {
+	t.Helper()
+	testutils.SetupGinTestMode()
+
+	require.NoError(t, config.Load())
+	cfg := config.Cfg
+	if cfg.Database.Driver != "postgres" || cfg.Stripe.APIURL == "" {
+		t.Skip("integration tests need PostgreSQL and stripe-mock, run make test-integration")
+	}
+	cfg.Server.Env = "test"
+	cfg.Dev.AutoMigrate = false
+	cfg.Dev.SeedData = false
+	cfg.Stripe.WebhookSecret = testWebhookSecret
+	cfg.RateLimit.Requests = 1000
+
+	logger := zap.NewNop()
+	require.NoError(t, database.Connect(cfg, logger))
+	db := database.DB
+
+	// Every test starts on an empty schema
+	require.NoError(t, db.Exec("DROP SCHEMA public CASCADE").Error)
+	require.NoError(t, db.Exec("CREATE SCHEMA public").Error)
+	require.NoError(t, database.AutoMigrate())
+	require.NoError(t, db.AutoMigrate(flowTables...))
+
+	srv := server.New(cfg, logger)
+	ctx, cancel := context.WithCancel(context.Background())
+	srv.StartBackgroundJobs(ctx)
+	t.Cleanup(func() {
+		cancel()
+		drainCtx, drainCancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer drainCancel()
+		srv.DrainBackgroundJobs(drainCtx)
+		database.Close()
+		database.DB = nil
+	})
+
+	return &harness{db: db, client: testutils.NewTestHTTPClient(srv.Router)}
+}
+
+// createUser stores an active, verified account that can log in with testPassword
+func (h *harness) createUser(t *testing.T, role models.UserRole) *models.User {
+	t.Helper()
+	hashed, err := bcrypt.GenerateFromPassword([]byte(testPassword), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	user := &models.User{
+		ID:            uuid.New(),
+		Email:         testutils.RandomEmail(),
+		Password:      string(hashed),
+		FirstName:     "Test",
+		LastName:      string(role),
+		Role:          role,
+		IsActive:      true,
+		EmailVerified: true,
+	}
+	if role == models.RoleBerater {
+		user.OnboardingStatus = models.OnboardingStatusApproved
+		user.Bundesland = models.BundeslandBerlin
+	}
+	require.NoError(t, h.db.Create(user).Error)
+	return user
+}
+
+// login signs in through the API and returns the access token
+func (h *harness) login(t *testing.T, email string) string {
+	t.Helper()
+	w := h.client.POST("/api/v1/auth/login", jsonBody(t, map[string]interface{}{
+		"email":    email,
+		"password": testPassword,
+	}), nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var response struct {
+		AccessToken string `json:"access_token"`
+	}
+	testutils.ParseJSONResponse(t, w, &response)
+	require.NotEmpty(t, response.AccessToken)
+	return response.AccessToken
+}
+
+// sendStripeEvent posts a Stripe event signed with the webhook secret
+func (h *harness) sendStripeEvent(t *testing.T, eventType string, object map[string]interface{}) {
+	t.Helper()
+	payload := jsonBody(t, map[string]interface{}{
+		"id":          "evt_" + uuid.NewString(),
+		"object":      "event",
+		"type":        eventType,
+		"api_version": "2023-10-16",
+		"created":     time.Now().Unix(),
+		"data":        map[string]interface{}{"object": object},
+	})
+	signed := webhook.GenerateTestSignedPayload(&webhook.UnsignedPayload{
+		Payload: []byte(payload),
+		Secret:  testWebhookSecret,
+	})
+
+	w := h.client.POST("/api/v1/webhooks/stripe", payload, map[string]string{"Stripe-Signature": signed.Header})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+}
+
+// bookableTimeslot creates a consultation slot of the Berater on the next
+// working day in a week that is not a public holiday
+func (h *harness) bookableTimeslot(t *testing.T, berater *models.User) *models.Timeslot {
+	t.Helper()
+	loc, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+	holidayService := holidays.NewService(h.db, zap.NewNop())
+
+	day := time.Now().In(loc).AddDate(0, 0, 7)
+	start := time.Date(day.Year(), day.Month(), day.Day(), 10, 0, 0, 0, loc)
+	for {
+		holiday, err := holidayService.IsHoliday(start, loc, berater.Bundesland)
+		require.NoError(t, err)
+		if holiday == nil && start.Weekday() != time.Saturday && start.Weekday() != time.Sunday {
+			break
+		}
+		start = start.AddDate(0, 0, 1)
+	}
+
+	slot := &models.Timeslot{
+		BeraterID:   berater.ID,
+		Date:        time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, loc),
+		StartTime:   start,
+		EndTime:     start.Add(time.Hour),
+		Duration:    60,
+		Timezone:    loc.String(),
+		Purpose:     models.TimeslotPurposeConsultation,
+		IsAvailable: true,
+		MaxBookings: 1,
+		Title:       "Beratung",
+	}
+	require.NoError(t, h.db.Create(slot).Error)
+	return slot
+}
+
+// eventually waits for the background workers to reach a state
+func eventually(t *testing.T, condition func() bool, message string) {
+	t.Helper()
+	require.Eventually(t, condition, 15*time.Second, 100*time.Millisecond, message)
+}
+
+func jsonBody(t *testing.T, v interface{}) string {
+	t.Helper()
+	body, err := json.Marshal(v)
+	require.NoError(t, err)
+	return string(body)
+}
@@ -186,14 +186,15 @@ func TestWebhookAuthentication(t *testing.T) {
 	ctx := testutils.SetupTestContext(t)
 	defer testutils.CleanupTestContext(ctx)
 
-	// Set webhook secret
+	// Set webhook secrets
 	ctx.Config.Stripe.WebhookSecret = "test-webhook-secret"
+	ctx.Config.Email.InboundWebhookSecret = "test-inbound-secret"
 
 	srv := server.New(ctx.Config, ctx.Logger)
 	client := testutils.NewTestHTTPClient(srv.Router)
 
 	t.Run("webhook_without_api_key", func(t *testing.T) {
-		w := client.POST("/api/v1/webhooks/stripe", `{"test": "payload"}`, nil)
+		w := client.POST("/api/v1/webhooks/inbound-email", `{"test": "payload"}`, nil)
 		
 		assert.Equal(t, http.StatusUnauthorized, w.Code)
 		testutils.AssertErrorResponse(t, w, http.StatusUnauthorized, "API key is required")
@@ -203,22 +204,20 @@ func TestWebhookAuthentication(t *testing.T) {
 		headers := map[string]string{
 			"X-API-Key": "invalid-key",
 		}
-		w := client.POST("/api/v1/webhooks/stripe", `{"test": "payload"}`, headers)
+		w := client.POST("/api/v1/webhooks/inbound-email", `{"test": "payload"}`, headers)
 		
 		assert.Equal(t, http.StatusUnauthorized, w.Code)
 		testutils.AssertErrorResponse(t, w, http.StatusUnauthorized, "Invalid API key")
 	})
 
-	t.Run("webhook_with_valid_api_key", func(t *testing.T) {
+	t.Run("stripe_webhook_without_signature", func(t *testing.T) {
 		headers := map[string]string{
 			"X-API-Key": ctx.Config.Stripe.WebhookSecret,
 		}
-		w := client.POST("/api/v1/webhooks/stripe", `{"test": "payload"}`, headers)
+		w := client.POST("/api/v1/webhooks/stripe", `{"id": "evt_test", "type": "payment_intent.succeeded"}`, headers)
 		
-		assert.Equal(t, http.StatusOK, w.Code)
-		testutils.AssertJSONResponse(t, w, http.StatusOK, map[string]interface{}{
-			"message": "Endpoint not yet implemented",
-		})
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		testutils.AssertErrorResponse(t, w, http.StatusUnauthorized, "Invalid signature")
 	})
 }
 
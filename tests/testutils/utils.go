@@ -19,7 +19,9 @@ import (
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
+	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
 )
 
 // TestContext holds common test dependencies
@@ -98,8 +100,21 @@ func CleanupTestContext(ctx *TestContext) {
 	}
 }
 
+// NewSQLiteDB opens an empty SQLite database in the test's temporary
+// directory and migrates the given models, for tests of a single service
+func NewSQLiteDB(t *testing.T, dst ...interface{}) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(dst...))
+	return db
+}
+
 // CreateTestUser creates a test user in the database
 func CreateTestUser(t *testing.T, db *gorm.DB, role models.UserRole) *models.User {
+	t.Helper()
 	user := &models.User{
 		ID:            uuid.New(),
 		Email:         "test-" + uuid.New().String()[:8] + "@example.com",